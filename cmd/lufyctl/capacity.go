@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/phuhao00/lufy/internal/rollup"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+func init() {
+	var capacityWeek string
+	register(&command{
+		name:  "capacity",
+		short: "查看容量规划周报，默认为上一个完整的周",
+		flags: func(fs *pflag.FlagSet) {
+			fs.StringVar(&capacityWeek, "week", "", "周一的日期(UTC)，如2026-10-05")
+		},
+		run: func(ctx *cmdContext, args []string) error {
+			gm, err := ctx.gm()
+			if err != nil {
+				return err
+			}
+			rpcCtx, cancel := ctx.rpcContext()
+			defer cancel()
+
+			resp, err := gm.GetCapacityReport(rpcCtx, &proto.CapacityReportQuery{Week: capacityWeek})
+			if err != nil {
+				return err
+			}
+			if resp.Code != 0 {
+				return ctx.printer.response(resp)
+			}
+
+			var report rollup.Report
+			if err := json.Unmarshal(resp.Data, &report); err != nil {
+				return fmt.Errorf("failed to decode capacity report: %v", err)
+			}
+			return printCapacityReport(ctx.printer, &report)
+		},
+	})
+}
+
+// printCapacityReport 输出容量规划周报，table格式先输出汇总，再按天和游戏类型列出
+func printCapacityReport(p *printer, report *rollup.Report) error {
+	if p.format == outputJSON {
+		return p.json(report)
+	}
+
+	fmt.Fprintf(p.out, "week of %s (UTC)\n", report.WeekStart)
+	fmt.Fprintf(p.out, "peak ccu:       %d at %s (previous week %d, %s)\n",
+		report.PeakCCU, formatHour(report.PeakCCUAt), report.PreviousPeakCCU, formatGrowth(report.PreviousPeakCCU, report.CCUGrowth))
+	fmt.Fprintf(p.out, "games:          %d (previous week %d, %s)\n",
+		report.Games, report.PreviousGames, formatGrowth(report.PreviousGames, report.GamesGrowth))
+	fmt.Fprintf(p.out, "peak games/h:   %d at %s\n", report.PeakGamesPerHour, formatHour(report.PeakGamesAt))
+	fmt.Fprintf(p.out, "projected peak: %d next week\n", report.ProjectedPeakCCU)
+	fmt.Fprintln(p.out)
+
+	rows := make([][]string, 0, len(report.Days))
+	for _, day := range report.Days {
+		rows = append(rows, []string{day.Date, strconv.FormatInt(day.PeakCCU, 10),
+			strconv.FormatInt(day.Games, 10), formatSeconds(day.AvgDuration)})
+	}
+	if err := p.print(nil, []string{"DATE", "PEAK_CCU", "GAMES", "AVG_LENGTH"}, rows); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(p.out)
+	rows = make([][]string, 0, len(report.GameTypes))
+	for _, gameType := range report.GameTypes {
+		rows = append(rows, []string{strconv.Itoa(int(gameType.GameType)), strconv.FormatInt(gameType.Games, 10),
+			strconv.FormatFloat(gameType.GamesPerHour, 'f', 1, 64), formatSeconds(gameType.AvgDuration)})
+	}
+	return p.print(nil, []string{"GAME_TYPE", "GAMES", "GAMES/H", "AVG_LENGTH"}, rows)
+}
+
+// formatHour 格式化峰值所在的小时(UTC)，没有小时汇总时为"-"
+func formatHour(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format("2006-01-02 15:00")
+}
+
+// formatGrowth 格式化增长率，上一周没有数据时为"n/a"
+func formatGrowth(previous int64, growth float64) string {
+	if previous == 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%+.1f%%", growth*100)
+}
+
+// formatSeconds 格式化平均时长
+func formatSeconds(seconds float64) string {
+	return fmt.Sprintf("%.0fs", seconds)
+}
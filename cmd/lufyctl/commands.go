@@ -0,0 +1,474 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/phuhao00/lufy/internal/server"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// rolloutTimeout 分阶段更新配置的最小RPC超时
+const rolloutTimeout = 30 * time.Second
+
+// rpcContext 单次调用的上下文
+func (c *cmdContext) rpcContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), c.profile.Timeout)
+}
+
+// parseUserID 解析用户ID参数
+func parseUserID(args []string) (uint64, error) {
+	if len(args) != 1 {
+		return 0, usageError("exactly one user id is required")
+	}
+	userID, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil || userID == 0 {
+		return 0, usageError(fmt.Sprintf("invalid user id %q", args[0]))
+	}
+	return userID, nil
+}
+
+// formatUnix 格式化Unix时间戳
+func formatUnix(ts int64) string {
+	if ts == 0 {
+		return "-"
+	}
+	return time.Unix(ts, 0).Format("2006-01-02 15:04:05")
+}
+
+func init() {
+	var serviceType string
+	register(&command{
+		name:  "services",
+		short: "列出集群中注册的服务",
+		flags: func(fs *pflag.FlagSet) {
+			fs.StringVar(&serviceType, "type", "", "只列出该类型的服务，如game")
+		},
+		run: func(ctx *cmdContext, args []string) error {
+			center, err := ctx.center()
+			if err != nil {
+				return err
+			}
+			rpcCtx, cancel := ctx.rpcContext()
+			defer cancel()
+
+			resp, err := center.GetServiceList(rpcCtx, &proto.BaseRequest{})
+			if err != nil {
+				return err
+			}
+
+			services := make([]*proto.ServiceInfo, 0, len(resp.Services))
+			for _, service := range resp.Services {
+				if serviceType == "" || service.ServiceType == serviceType {
+					services = append(services, service)
+				}
+			}
+			sort.Slice(services, func(i, j int) bool {
+				if services[i].ServiceType != services[j].ServiceType {
+					return services[i].ServiceType < services[j].ServiceType
+				}
+				return services[i].ServiceId < services[j].ServiceId
+			})
+
+			rows := make([][]string, 0, len(services))
+			for _, service := range services {
+				rows = append(rows, []string{
+					service.ServiceId,
+					service.ServiceType,
+					fmt.Sprintf("%s:%d", service.Address, service.Port),
+					service.Status,
+					fmt.Sprintf("%s (%s)", service.Version, service.GitCommit),
+					canaryText(service),
+					formatUnix(int64(service.LastHeartbeat)),
+				})
+			}
+			return ctx.printer.print(services, []string{"ID", "TYPE", "ADDRESS", "STATUS", "VERSION", "CANARY", "LAST HEARTBEAT"}, rows)
+		},
+	})
+
+	register(&command{
+		name:  "status",
+		short: "查看集群状态",
+		run: func(ctx *cmdContext, args []string) error {
+			center, err := ctx.center()
+			if err != nil {
+				return err
+			}
+			rpcCtx, cancel := ctx.rpcContext()
+			defer cancel()
+
+			resp, err := center.GetClusterStatus(rpcCtx, &proto.BaseRequest{})
+			if err != nil {
+				return err
+			}
+
+			if ctx.printer.format == outputJSON {
+				return ctx.printer.json(resp)
+			}
+
+			fmt.Fprintf(ctx.printer.out, "services: %d total, %d online\n", resp.TotalServices, resp.OnlineServices)
+			if info := resp.SystemInfo; info != nil {
+				fmt.Fprintf(ctx.printer.out, "center:   cpu %.1f%%, memory %.1f%%, uptime %s\n",
+					info.CpuUsage, info.MemoryUsage, time.Duration(info.Uptime)*time.Second)
+			}
+			fmt.Fprintln(ctx.printer.out)
+
+			types := make([]string, 0, len(resp.ServiceStats))
+			for serviceType := range resp.ServiceStats {
+				types = append(types, serviceType)
+			}
+			sort.Strings(types)
+
+			rows := make([][]string, 0, len(types))
+			for _, serviceType := range types {
+				rows = append(rows, []string{serviceType, strconv.Itoa(int(resp.ServiceStats[serviceType]))})
+			}
+			if err := ctx.printer.print(nil, []string{"TYPE", "ONLINE"}, rows); err != nil {
+				return err
+			}
+
+			versions := make([]string, 0, len(resp.Versions))
+			for build := range resp.Versions {
+				versions = append(versions, build)
+			}
+			sort.Strings(versions)
+
+			fmt.Fprintln(ctx.printer.out)
+			rows = make([][]string, 0, len(versions))
+			for _, build := range versions {
+				rows = append(rows, []string{build, strconv.Itoa(int(resp.Versions[build]))})
+			}
+			if err := ctx.printer.print(nil, []string{"VERSION", "NODES"}, rows); err != nil {
+				return err
+			}
+			for _, warning := range resp.VersionWarnings {
+				fmt.Fprintf(ctx.printer.out, "warning: %s\n", warning)
+			}
+			return nil
+		},
+	})
+
+	var (
+		noticeTitle   string
+		noticeContent string
+		noticeType    int32
+		noticeUsers   []int64
+	)
+	register(&command{
+		name:  "notice",
+		short: "发送公告，不指定用户时全服广播",
+		flags: func(fs *pflag.FlagSet) {
+			fs.StringVar(&noticeTitle, "title", "", "公告标题")
+			fs.StringVar(&noticeContent, "content", "", "公告内容")
+			fs.Int32Var(&noticeType, "type", 0, "公告类型")
+			fs.Int64SliceVar(&noticeUsers, "users", nil, "只发给这些用户，逗号分隔")
+		},
+		run: func(ctx *cmdContext, args []string) error {
+			if noticeTitle == "" || noticeContent == "" {
+				return usageError("--title and --content are required")
+			}
+
+			req := &proto.SendNoticeRequest{
+				Title:      noticeTitle,
+				Content:    noticeContent,
+				NoticeType: noticeType,
+			}
+			for _, userID := range noticeUsers {
+				if userID <= 0 {
+					return usageError(fmt.Sprintf("invalid user id %d", userID))
+				}
+				req.TargetUsers = append(req.TargetUsers, uint64(userID))
+			}
+
+			gm, err := ctx.gm()
+			if err != nil {
+				return err
+			}
+			rpcCtx, cancel := ctx.rpcContext()
+			defer cancel()
+
+			resp, err := gm.SendNotice(rpcCtx, req)
+			if err != nil {
+				return err
+			}
+			return ctx.printer.response(resp)
+		},
+	})
+
+	var (
+		banReason   string
+		banDuration time.Duration
+	)
+	register(&command{
+		name:  "ban",
+		usage: "<user_id>",
+		short: "封禁用户",
+		flags: func(fs *pflag.FlagSet) {
+			fs.StringVar(&banReason, "reason", "", "封禁原因")
+			fs.DurationVar(&banDuration, "duration", 24*time.Hour, "封禁时长")
+		},
+		run: func(ctx *cmdContext, args []string) error {
+			userID, err := parseUserID(args)
+			if err != nil {
+				return err
+			}
+			if banDuration < time.Second {
+				return usageError("--duration must be at least 1s")
+			}
+
+			gm, err := ctx.gm()
+			if err != nil {
+				return err
+			}
+			rpcCtx, cancel := ctx.rpcContext()
+			defer cancel()
+
+			resp, err := gm.BanUser(rpcCtx, &proto.BanUserRequest{
+				TargetUserId: userID,
+				Reason:       banReason,
+				Duration:     uint32(banDuration / time.Second),
+			})
+			if err != nil {
+				return err
+			}
+			return ctx.printer.response(resp)
+		},
+	})
+
+	register(&command{
+		name:  "unban",
+		usage: "<user_id>",
+		short: "解封用户",
+		run: func(ctx *cmdContext, args []string) error {
+			userID, err := parseUserID(args)
+			if err != nil {
+				return err
+			}
+
+			gm, err := ctx.gm()
+			if err != nil {
+				return err
+			}
+			rpcCtx, cancel := ctx.rpcContext()
+			defer cancel()
+
+			resp, err := gm.UnbanUser(rpcCtx, &proto.UnbanUserRequest{TargetUserId: userID})
+			if err != nil {
+				return err
+			}
+			return ctx.printer.response(resp)
+		},
+	})
+
+	var (
+		reloadNodes  []string
+		reloadDryRun bool
+	)
+	register(&command{
+		name:  "reload",
+		short: "分阶段更新配置，所有节点校验通过后提交，--nodes先提交部分节点",
+		flags: func(fs *pflag.FlagSet) {
+			fs.StringSliceVar(&reloadNodes, "nodes", nil, "只提交到这些节点，校验仍在所有节点进行，如game-1,game-2")
+			fs.BoolVar(&reloadDryRun, "dry-run", false, "只校验并显示各节点的配置差异，不提交")
+		},
+		run: func(ctx *cmdContext, args []string) error {
+			if len(args) != 0 {
+				return usageError("reload takes no arguments, use --nodes to select nodes")
+			}
+
+			// 两个阶段都要等待所有节点回报，超时至少留出两倍的节点等待时间
+			if ctx.profile.Timeout < rolloutTimeout {
+				ctx.profile.Timeout = rolloutTimeout
+			}
+			gm, err := ctx.gm()
+			if err != nil {
+				return err
+			}
+			rpcCtx, cancel := ctx.rpcContext()
+			defer cancel()
+
+			resp, err := gm.RolloutConfig(rpcCtx, &proto.ConfigRolloutRequest{
+				Nodes:  reloadNodes,
+				DryRun: reloadDryRun,
+			})
+			if err != nil {
+				return err
+			}
+
+			var report server.ConfigRolloutReport
+			if len(resp.Data) == 0 || json.Unmarshal(resp.Data, &report) != nil {
+				return ctx.printer.response(resp)
+			}
+			if err := printRolloutReport(ctx.printer, &report); err != nil {
+				return err
+			}
+			if resp.Code != 0 {
+				return fmt.Errorf("error %d: %s", resp.Code, resp.Message)
+			}
+			return nil
+		},
+	})
+
+	var (
+		drainType   string
+		drainResume bool
+	)
+	register(&command{
+		name:  "drain",
+		usage: "[node_id]",
+		short: "排空节点，负载均衡不再选择该节点，--resume恢复",
+		flags: func(fs *pflag.FlagSet) {
+			fs.StringVar(&drainType, "type", "", "排空该类型的所有节点")
+			fs.BoolVar(&drainResume, "resume", false, "恢复节点接收新流量")
+		},
+		run: func(ctx *cmdContext, args []string) error {
+			req := &proto.ServiceOperationRequest{ServiceType: drainType, Operation: "drain"}
+			if drainResume {
+				req.Operation = "resume"
+			}
+			switch {
+			case len(args) == 1 && drainType == "":
+				req.ServiceId = args[0]
+			case len(args) == 0 && drainType != "":
+			default:
+				return usageError("either a node id or --type is required")
+			}
+
+			center, err := ctx.center()
+			if err != nil {
+				return err
+			}
+			rpcCtx, cancel := ctx.rpcContext()
+			defer cancel()
+
+			resp, err := center.DrainService(rpcCtx, req)
+			if err != nil {
+				return err
+			}
+			return ctx.printer.response(resp)
+		},
+	})
+
+	register(&command{
+		name:  "canary",
+		usage: "<node_id> <weight|off>",
+		short: "把节点设为灰度节点并按权重(0-100)分配新流量，off恢复为普通节点",
+		run: func(ctx *cmdContext, args []string) error {
+			if len(args) != 2 {
+				return usageError("expected a node id and a weight or off")
+			}
+
+			req := &proto.CanaryRequest{ServiceId: args[0]}
+			if args[1] != "off" {
+				weight, err := strconv.Atoi(args[1])
+				if err != nil || weight < 0 || weight > 100 {
+					return usageError("weight must be an integer between 0 and 100")
+				}
+				req.Canary, req.Weight = true, int32(weight)
+			}
+
+			center, err := ctx.center()
+			if err != nil {
+				return err
+			}
+			rpcCtx, cancel := ctx.rpcContext()
+			defer cancel()
+
+			resp, err := center.SetCanary(rpcCtx, req)
+			if err != nil {
+				return err
+			}
+			return ctx.printer.response(resp)
+		},
+	})
+
+	var maintenanceMessage string
+	register(&command{
+		name:  "maintenance",
+		usage: "<on|off>",
+		short: "开启或关闭维护模式，维护期间拒绝新的登录",
+		flags: func(fs *pflag.FlagSet) {
+			fs.StringVar(&maintenanceMessage, "message", "", "返回给登录玩家的维护说明")
+		},
+		run: func(ctx *cmdContext, args []string) error {
+			if len(args) != 1 || (args[0] != "on" && args[0] != "off") {
+				return usageError("expected on or off")
+			}
+
+			center, err := ctx.center()
+			if err != nil {
+				return err
+			}
+			rpcCtx, cancel := ctx.rpcContext()
+			defer cancel()
+
+			resp, err := center.SetMaintenance(rpcCtx, &proto.MaintenanceRequest{
+				Enabled: args[0] == "on",
+				Message: maintenanceMessage,
+			})
+			if err != nil {
+				return err
+			}
+			return ctx.printer.response(resp)
+		},
+	})
+
+	register(&command{
+		name:  "profiles",
+		short: "列出配置文件中的环境profile",
+		run: func(ctx *cmdContext, args []string) error {
+			path := profilePath(ctx.configFile)
+			file, err := loadProfiles(path)
+			if err != nil {
+				return err
+			}
+			if len(file.Profiles) == 0 {
+				fmt.Fprintf(os.Stderr, "no profiles in %s\n", path)
+			}
+
+			names := make([]string, 0, len(file.Profiles))
+			for name := range file.Profiles {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			type profileView struct {
+				Name     string `json:"name"`
+				Current  bool   `json:"current"`
+				Selected bool   `json:"selected"`
+				Center   string `json:"center"`
+				GM       string `json:"gm"`
+				HasToken bool   `json:"has_token"`
+			}
+
+			views := make([]profileView, 0, len(names))
+			rows := make([][]string, 0, len(names))
+			for _, name := range names {
+				profile := file.Profiles[name]
+				view := profileView{
+					Name:     name,
+					Current:  name == file.Current,
+					Selected: name == ctx.profileName,
+					Center:   profile.Center,
+					GM:       profile.GM,
+					HasToken: profile.Token != "",
+				}
+				views = append(views, view)
+
+				marker := ""
+				if view.Selected {
+					marker = "*"
+				}
+				rows = append(rows, []string{marker, name, profile.Center, profile.GM, strconv.FormatBool(view.HasToken)})
+			}
+			return ctx.printer.print(views, []string{"", "NAME", "CENTER", "GM", "TOKEN"}, rows)
+		},
+	})
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// command 子命令
+type command struct {
+	name  string
+	usage string // 参数说明，如"<user_id>"
+	short string
+	flags func(fs *pflag.FlagSet) // 注册子命令自己的参数
+	run   func(ctx *cmdContext, args []string) error
+}
+
+// commands 所有子命令，各文件通过init注册
+var commands = map[string]*command{}
+
+// register 注册子命令
+func register(cmd *command) {
+	commands[cmd.name] = cmd
+}
+
+// globalOptions 所有子命令共用的参数
+type globalOptions struct {
+	configFile string
+	profile    string
+	output     string
+	center     string
+	gm         string
+	token      string
+	timeout    string
+}
+
+// bind 注册共用参数
+func (o *globalOptions) bind(fs *pflag.FlagSet) {
+	fs.StringVar(&o.configFile, "config", "", "profile配置文件，默认$LUFYCTL_CONFIG或~/.lufyctl.yaml")
+	fs.StringVarP(&o.profile, "profile", "p", "", "使用的环境profile，默认配置文件中的current")
+	fs.StringVarP(&o.output, "output", "o", "table", "输出格式: table, json")
+	fs.StringVar(&o.center, "center", "", "覆盖profile中的中心服RPC地址(host:port)")
+	fs.StringVar(&o.gm, "gm", "", "覆盖profile中的GM服RPC地址(host:port)")
+	fs.StringVar(&o.token, "token", "", "覆盖profile中的运维令牌，也可通过$LUFYCTL_TOKEN设置")
+	fs.StringVar(&o.timeout, "timeout", "", "覆盖profile中的RPC超时，如5s")
+}
+
+// 集群运维命令行工具，通过中心服和GM服的RPC接口管理集群
+//
+//	lufyctl -p prod services
+//	lufyctl -p prod ban 10001 --reason cheating --duration 72h
+//	lufyctl -p prod drain game-2
+//	lufyctl -p prod maintenance on --message "版本更新"
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// run 解析参数并执行子命令，返回进程退出码
+func run(args []string) int {
+	// RPC客户端的日志只输出错误
+	logger.InitGlobalLogger(&logger.LogConfig{
+		Level:  "error",
+		Format: "console",
+		Output: "stderr",
+	})
+
+	var opts globalOptions
+	global := pflag.NewFlagSet("lufyctl", pflag.ContinueOnError)
+	global.SetInterspersed(false)
+	global.Usage = printUsage
+	opts.bind(global)
+
+	if err := global.Parse(args); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	if global.NArg() == 0 || global.Arg(0) == "help" {
+		printUsage()
+		return 0
+	}
+
+	cmd, exists := commands[global.Arg(0)]
+	if !exists {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", global.Arg(0))
+		printUsage()
+		return 2
+	}
+
+	// 共用参数也可以写在子命令之后，两个FlagSet绑定同一组变量，先记下子命令之前设置的值
+	changed := map[string]string{}
+	global.Visit(func(flag *pflag.Flag) {
+		changed[flag.Name] = flag.Value.String()
+	})
+
+	fs := pflag.NewFlagSet(cmd.name, pflag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: lufyctl %s [flags] %s\n\n%s\n\nflags:\n", cmd.name, cmd.usage, cmd.short)
+		fs.PrintDefaults()
+	}
+	opts.bind(fs)
+	if cmd.flags != nil {
+		cmd.flags(fs)
+	}
+	for name, value := range changed {
+		fs.Set(name, value)
+	}
+
+	if err := fs.Parse(global.Args()[1:]); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	ctx, err := newCmdContext(&opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer ctx.close()
+
+	if err := cmd.run(ctx, fs.Args()); err != nil {
+		var usageErr usageError
+		if errors.As(err, &usageErr) {
+			fmt.Fprintln(os.Stderr, err)
+			fs.Usage()
+			return 2
+		}
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	return 0
+}
+
+// usageError 参数错误，输出子命令用法
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+// printUsage 输出命令列表
+func printUsage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("usage: lufyctl [flags] <command> [args]\n\ncommands:\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %-12s %s\n", name, commands[name].short)
+	}
+	b.WriteString("\nflags:\n")
+	fmt.Fprint(os.Stderr, b.String())
+
+	var opts globalOptions
+	fs := pflag.NewFlagSet("lufyctl", pflag.ContinueOnError)
+	opts.bind(fs)
+	fs.PrintDefaults()
+	fmt.Fprintln(os.Stderr, "\nrun 'lufyctl <command> --help' for command flags")
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/phuhao00/lufy/internal/server"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// 输出格式
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// printer 按输出格式打印结果
+type printer struct {
+	format string
+	out    io.Writer
+}
+
+// newPrinter 创建输出器
+func newPrinter(format string) (*printer, error) {
+	switch format {
+	case outputTable, outputJSON:
+		return &printer{format: format, out: os.Stdout}, nil
+	default:
+		return nil, fmt.Errorf("unsupported output format %q, expected table or json", format)
+	}
+}
+
+// print 输出结果，json格式输出value，table格式输出headers和rows
+func (p *printer) print(value interface{}, headers []string, rows [][]string) error {
+	if p.format == outputJSON {
+		return p.json(value)
+	}
+
+	w := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+// json 以缩进JSON输出
+func (p *printer) json(value interface{}) error {
+	encoder := json.NewEncoder(p.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// commonResult CommonResponse的输出形式，Data为JSON时原样嵌入
+type commonResult struct {
+	Code    int32       `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// response 输出CommonResponse，响应码非0时返回错误
+func (p *printer) response(resp *proto.CommonResponse) error {
+	result := commonResult{Code: resp.Code, Message: resp.Message}
+	if len(resp.Data) > 0 {
+		var data interface{}
+		if err := json.Unmarshal(resp.Data, &data); err != nil {
+			data = string(resp.Data)
+		}
+		result.Data = data
+	}
+
+	if resp.Code != 0 {
+		if p.format == outputJSON {
+			p.json(result)
+		}
+		return fmt.Errorf("error %d: %s", resp.Code, resp.Message)
+	}
+
+	if p.format == outputJSON {
+		return p.json(result)
+	}
+
+	fmt.Fprintln(p.out, resp.Message)
+	fields, ok := result.Data.(map[string]interface{})
+	if !ok {
+		if result.Data != nil {
+			fmt.Fprintln(p.out, result.Data)
+		}
+		return nil
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	rows := make([][]string, 0, len(keys))
+	for _, key := range keys {
+		rows = append(rows, []string{key, formatValue(fields[key])})
+	}
+	fmt.Fprintln(p.out)
+	return p.print(nil, []string{"FIELD", "VALUE"}, rows)
+}
+
+// formatValue 表格中的字段值，复合类型以JSON显示
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case map[string]interface{}, []interface{}:
+		data, _ := json.Marshal(v)
+		return string(data)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// printRolloutReport 输出配置分阶段更新报告，table格式在表格后列出各节点的配置差异
+func printRolloutReport(p *printer, report *server.ConfigRolloutReport) error {
+	if p.format == outputJSON {
+		return p.json(report)
+	}
+
+	rows := make([][]string, 0, len(report.Nodes))
+	for _, nodeID := range report.Nodes {
+		validate, version, changes := "-", "-", "-"
+		if result := report.Prepared[nodeID]; result != nil {
+			validate = rolloutStatus(result)
+			if result.Success {
+				version = result.Version
+				changes = strconv.Itoa(len(result.Changes))
+			}
+		}
+		commit := "-"
+		if result := report.Committed[nodeID]; result != nil {
+			commit = rolloutStatus(result)
+		}
+		rows = append(rows, []string{nodeID, validate, version, changes, commit})
+	}
+	if err := p.print(report, []string{"NODE", "VALIDATE", "VERSION", "CHANGES", "COMMIT"}, rows); err != nil {
+		return err
+	}
+
+	for _, nodeID := range report.Nodes {
+		result := report.Prepared[nodeID]
+		if result == nil || len(result.Changes) == 0 {
+			continue
+		}
+		fmt.Fprintf(p.out, "\n%s:\n", nodeID)
+		for _, change := range result.Changes {
+			fmt.Fprintf(p.out, "  %s\n", change)
+		}
+	}
+
+	switch {
+	case report.Aborted:
+		fmt.Fprintf(p.out, "\naborted: %s\n", report.Reason)
+	case report.DryRun:
+		fmt.Fprintln(p.out, "\ndry run, nothing committed")
+	}
+	return nil
+}
+
+// rolloutStatus 节点阶段结果
+func rolloutStatus(result *server.ConfigRolloutResult) string {
+	if result.Success {
+		return "ok"
+	}
+	return "failed: " + result.Error
+}
+
+// canaryText 灰度节点显示权重，普通节点显示-
+func canaryText(service *proto.ServiceInfo) string {
+	if !service.Canary {
+		return "-"
+	}
+	return fmt.Sprintf("%d%%", service.CanaryWeight)
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/phuhao00/lufy/internal/rpc"
+	"github.com/phuhao00/lufy/internal/server"
+)
+
+// defaultTimeout 默认RPC超时
+const defaultTimeout = 10 * time.Second
+
+// Profile 单个环境的连接配置
+type Profile struct {
+	Center  string        `yaml:"center"`  // 中心服RPC地址，host:port
+	GM      string        `yaml:"gm"`      // GM服RPC地址，host:port
+	Token   string        `yaml:"token"`   // 运维令牌，对应服务端rpc.admin.tokens
+	Timeout time.Duration `yaml:"timeout"` // RPC超时
+}
+
+// ProfileFile profile配置文件
+type ProfileFile struct {
+	Current  string              `yaml:"current"`
+	Profiles map[string]*Profile `yaml:"profiles"`
+}
+
+// profilePath 获取配置文件路径
+func profilePath(configFile string) string {
+	if configFile != "" {
+		return configFile
+	}
+	if path := os.Getenv("LUFYCTL_CONFIG"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".lufyctl.yaml"
+	}
+	return filepath.Join(home, ".lufyctl.yaml")
+}
+
+// loadProfiles 读取配置文件，文件不存在时返回空配置，此时只能通过参数指定地址
+func loadProfiles(path string) (*ProfileFile, error) {
+	file := &ProfileFile{Profiles: map[string]*Profile{}}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return file, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	if err := yaml.Unmarshal(data, file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	if file.Profiles == nil {
+		file.Profiles = map[string]*Profile{}
+	}
+
+	return file, nil
+}
+
+// resolveProfile 选出profile并应用命令行参数和环境变量的覆盖
+func resolveProfile(opts *globalOptions) (string, *Profile, error) {
+	file, err := loadProfiles(profilePath(opts.configFile))
+	if err != nil {
+		return "", nil, err
+	}
+
+	name := opts.profile
+	if name == "" {
+		name = os.Getenv("LUFYCTL_PROFILE")
+	}
+	if name == "" {
+		name = file.Current
+	}
+
+	profile := &Profile{}
+	if name != "" {
+		selected, exists := file.Profiles[name]
+		if !exists {
+			return "", nil, fmt.Errorf("profile %q not found", name)
+		}
+		*profile = *selected
+	}
+
+	if opts.center != "" {
+		profile.Center = opts.center
+	}
+	if opts.gm != "" {
+		profile.GM = opts.gm
+	}
+	if token := os.Getenv("LUFYCTL_TOKEN"); token != "" {
+		profile.Token = token
+	}
+	if opts.token != "" {
+		profile.Token = opts.token
+	}
+	if opts.timeout != "" {
+		timeout, err := time.ParseDuration(opts.timeout)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid timeout %q: %v", opts.timeout, err)
+		}
+		profile.Timeout = timeout
+	}
+	if profile.Timeout <= 0 {
+		profile.Timeout = defaultTimeout
+	}
+
+	return name, profile, nil
+}
+
+// cmdContext 子命令执行上下文，RPC连接在首次使用时建立
+type cmdContext struct {
+	configFile  string
+	profileName string
+	profile     *Profile
+	printer     *printer
+	clients     []*rpc.RPCClient
+}
+
+// newCmdContext 创建执行上下文
+func newCmdContext(opts *globalOptions) (*cmdContext, error) {
+	printer, err := newPrinter(opts.output)
+	if err != nil {
+		return nil, err
+	}
+
+	name, profile, err := resolveProfile(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cmdContext{
+		configFile:  opts.configFile,
+		profileName: name,
+		profile:     profile,
+		printer:     printer,
+	}, nil
+}
+
+// connect 连接到指定地址的RPC服务，带上运维令牌
+func (c *cmdContext) connect(role, address string) (*rpc.RPCClient, error) {
+	if address == "" {
+		return nil, fmt.Errorf("%s address is not configured, set it in the profile or pass --%s", role, role)
+	}
+
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s address %q: %v", role, address, err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s port %q: %v", role, portStr, err)
+	}
+
+	client := rpc.NewRPCClient(host, port)
+	if c.profile.Token != "" {
+		client.SetMetadata(rpc.MetadataAuthorization, "Bearer "+c.profile.Token)
+	}
+	if err := client.Connect(); err != nil {
+		return nil, err
+	}
+
+	c.clients = append(c.clients, client)
+	return client, nil
+}
+
+// center 获取中心服客户端
+func (c *cmdContext) center() (*server.CenterServiceClient, error) {
+	client, err := c.connect("center", c.profile.Center)
+	if err != nil {
+		return nil, err
+	}
+	return server.NewCenterServiceClient(client, c.profile.Timeout), nil
+}
+
+// gm 获取GM服客户端
+func (c *cmdContext) gm() (*server.GMServiceClient, error) {
+	client, err := c.connect("gm", c.profile.GM)
+	if err != nil {
+		return nil, err
+	}
+	return server.NewGMServiceClient(client, c.profile.Timeout), nil
+}
+
+// close 断开所有连接
+func (c *cmdContext) close() {
+	for _, client := range c.clients {
+		client.Disconnect()
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/phuhao00/lufy/internal/server"
+	"github.com/phuhao00/lufy/internal/version"
 )
 
 func main() {
@@ -13,9 +14,15 @@ func main() {
 		configFile = flag.String("config", "config/config.yaml", "配置文件路径")
 		nodeType   = flag.String("node", "gateway", "节点类型")
 		nodeID     = flag.String("id", "node1", "节点ID")
+		showVer    = flag.Bool("version", false, "输出版本信息后退出")
 	)
 	flag.Parse()
 
+	if *showVer {
+		fmt.Println(version.Get())
+		return
+	}
+
 	if *configFile == "" || *nodeType == "" || *nodeID == "" {
 		fmt.Println("使用方法: -config=config/config.yaml -node=gateway -id=node1")
 		os.Exit(1)
@@ -0,0 +1,180 @@
+package activity
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+)
+
+// 活动状态变化，随系统消息广播到各节点
+const (
+	StateStarted = "started"
+	StateEnded   = "ended"
+)
+
+// announcedKey 已广播开始的活动，字段为活动ID，值为结束时间
+const announcedKey = "activity:announced"
+
+// Config 活动日历配置，活动的开始和结束时间写在配置中，修改后随配置热更新生效
+type Config struct {
+	Events []Event `yaml:"events"`
+}
+
+// Event 限时活动
+type Event struct {
+	ID          string             `yaml:"id"`
+	Name        string             `yaml:"name"`
+	Description string             `yaml:"description"`
+	Start       time.Time          `yaml:"start"`
+	End         time.Time          `yaml:"end"`
+	Multipliers map[string]float64 `yaml:"multipliers"` // 奖励倍率，键为奖励类型(experience, gold)
+	Games       []int32            `yaml:"games"`       // 只对指定游戏类型生效，为空时对所有游戏生效
+}
+
+// Contains 活动在at时是否进行中，开始时间包含在内，结束时间不包含
+func (e *Event) Contains(at time.Time) bool {
+	return !at.Before(e.Start) && at.Before(e.End)
+}
+
+// AppliesTo 活动是否对游戏类型生效
+func (e *Event) AppliesTo(gameType int32) bool {
+	if len(e.Games) == 0 {
+		return true
+	}
+	for _, t := range e.Games {
+		if t == gameType {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate 检查活动配置
+func (c *Config) Validate() error {
+	ids := make(map[string]bool, len(c.Events))
+	for _, event := range c.Events {
+		if event.ID == "" {
+			return fmt.Errorf("activity id is required")
+		}
+		if ids[event.ID] {
+			return fmt.Errorf("duplicate activity id: %s", event.ID)
+		}
+		ids[event.ID] = true
+
+		if !event.End.After(event.Start) {
+			return fmt.Errorf("activity %s ends before it starts", event.ID)
+		}
+		for kind, multiplier := range event.Multipliers {
+			if multiplier <= 0 {
+				return fmt.Errorf("activity %s has invalid %s multiplier: %v", event.ID, kind, multiplier)
+			}
+		}
+	}
+	return nil
+}
+
+// Calendar 活动日历，按配置的时间窗口判断活动是否进行中，各节点使用相同的配置得到相同的结果
+type Calendar struct {
+	events []Event
+	mutex  sync.RWMutex
+}
+
+// NewCalendar 创建活动日历
+func NewCalendar(config *Config) *Calendar {
+	calendar := &Calendar{}
+	calendar.Update(config)
+	return calendar
+}
+
+// Update 配置热更新后替换活动列表
+func (c *Calendar) Update(config *Config) {
+	events := make([]Event, len(config.Events))
+	copy(events, config.Events)
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Start.Before(events[j].Start) })
+
+	c.mutex.Lock()
+	c.events = events
+	c.mutex.Unlock()
+}
+
+// Active 获取at时进行中的活动，按开始时间排序
+func (c *Calendar) Active(at time.Time) []Event {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	var active []Event
+	for _, event := range c.events {
+		if event.Contains(at) {
+			active = append(active, event)
+		}
+	}
+	return active
+}
+
+// Multiplier 获取at时对游戏类型生效的奖励倍率，多个活动同时进行时取最高倍率，不叠加
+// 结算按游戏结束时间计算，重复结算得到相同的结果
+func (c *Calendar) Multiplier(kind string, gameType int32, at time.Time) float64 {
+	multiplier := 1.0
+	for _, event := range c.Active(at) {
+		if !event.AppliesTo(gameType) {
+			continue
+		}
+		if m, exists := event.Multipliers[kind]; exists && m > multiplier {
+			multiplier = m
+		}
+	}
+	return multiplier
+}
+
+// Transitions 对比已广播的活动，返回需要广播开始和结束的活动，并记录广播状态
+// 由集群内唯一执行的定时任务调用，每个活动的开始和结束只广播一次
+func (c *Calendar) Transitions(redis *database.RedisManager, now time.Time) (started, ended []Event, err error) {
+	announced, err := redis.HGetAll(announcedKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get announced activities: %v", err)
+	}
+
+	active := c.Active(now)
+	activeIDs := make(map[string]bool, len(active))
+	for _, event := range active {
+		activeIDs[event.ID] = true
+		if _, exists := announced[event.ID]; exists {
+			continue
+		}
+		if err := redis.HSet(announcedKey, event.ID, event.End.Unix()); err != nil {
+			return started, ended, fmt.Errorf("failed to record activity %s: %v", event.ID, err)
+		}
+		started = append(started, event)
+	}
+
+	c.mutex.RLock()
+	configured := make(map[string]Event, len(c.events))
+	for _, event := range c.events {
+		configured[event.ID] = event
+	}
+	c.mutex.RUnlock()
+
+	for id, endText := range announced {
+		if activeIDs[id] {
+			continue
+		}
+		if err := redis.HDel(announcedKey, id); err != nil {
+			return started, ended, fmt.Errorf("failed to clear activity %s: %v", id, err)
+		}
+		// 活动已从配置中删除时只知道ID和原定的结束时间
+		event, exists := configured[id]
+		if !exists {
+			event = Event{ID: id}
+			if end, err := strconv.ParseInt(endText, 10, 64); err == nil {
+				event.End = time.Unix(end, 0)
+			}
+		}
+		ended = append(ended, event)
+	}
+
+	return started, ended, nil
+}
@@ -0,0 +1,258 @@
+package announcement
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+)
+
+// 公告展示位置
+const (
+	ChannelPopup   = "popup"   // 登录弹窗
+	ChannelMarquee = "marquee" // 跑马灯，开始时推送给在线玩家
+	ChannelMailbox = "mailbox" // 邮箱公告栏
+)
+
+// 公告默认设置
+const (
+	defaultDuration = 24 * time.Hour
+	defaultCacheTTL = 30 * time.Second
+	defaultLanguage = "en"
+)
+
+// Config 公告设置
+type Config struct {
+	Duration time.Duration `yaml:"duration"` // 未指定结束时间时的展示时长，未配置时为24小时
+	Cache    time.Duration `yaml:"cache"`    // 进行中公告的本地缓存时间，未配置时为30秒
+	Language string        `yaml:"language"` // 未指定时的默认语言，未配置时为en
+}
+
+// withDefaults 补齐未配置的设置
+func (c Config) withDefaults() Config {
+	if c.Duration <= 0 {
+		c.Duration = defaultDuration
+	}
+	if c.Cache <= 0 {
+		c.Cache = defaultCacheTTL
+	}
+	if c.Language == "" {
+		c.Language = defaultLanguage
+	}
+	return c
+}
+
+// Store 公告存储，database.AnnouncementRepository实现了该接口
+type Store interface {
+	Create(announcement *database.Announcement) error
+	Get(announcementID string) (*database.Announcement, error)
+	Active(from, until time.Time) ([]*database.Announcement, error)
+	List(limit int64) ([]*database.Announcement, error)
+	Delete(announcementID string) (bool, error)
+	DuePush(now time.Time) ([]*database.Announcement, error)
+	MarkPushed(announcement *database.Announcement) (bool, error)
+}
+
+// Localized 按玩家语言选择内容后的公告
+type Localized struct {
+	ID       string
+	Title    string
+	Content  string
+	Channels []string
+	Priority int32
+	StartAt  time.Time
+	EndAt    time.Time
+}
+
+// Service 公告服务，进行中的公告在本地缓存一小段时间
+type Service struct {
+	config Config
+	store  Store
+
+	mutex    sync.Mutex
+	active   []*database.Announcement
+	loadedAt time.Time
+}
+
+// NewService 创建公告服务
+func NewService(config *Config, store Store) *Service {
+	return &Service{
+		config: config.withDefaults(),
+		store:  store,
+	}
+}
+
+// Language 未指定语言时公告使用的默认语言
+func (s *Service) Language() string {
+	return s.config.Language
+}
+
+// Create 检查并创建公告，未指定开始时间时立即开始，未指定结束时间时按配置的时长结束
+func (s *Service) Create(announcement *database.Announcement) error {
+	if len(announcement.Channels) == 0 {
+		return fmt.Errorf("announcement requires at least one channel")
+	}
+	for _, channel := range announcement.Channels {
+		switch channel {
+		case ChannelPopup, ChannelMarquee, ChannelMailbox:
+		default:
+			return fmt.Errorf("unknown announcement channel: %s", channel)
+		}
+	}
+
+	if announcement.Language == "" {
+		announcement.Language = s.config.Language
+	}
+	if _, exists := announcement.Contents[announcement.Language]; !exists {
+		return fmt.Errorf("missing content for default language %s", announcement.Language)
+	}
+	for language, content := range announcement.Contents {
+		if content.Content == "" {
+			return fmt.Errorf("content for %s is empty", language)
+		}
+	}
+
+	if announcement.StartAt.IsZero() {
+		announcement.StartAt = time.Now()
+	}
+	if announcement.EndAt.IsZero() {
+		announcement.EndAt = announcement.StartAt.Add(s.config.Duration)
+	}
+	if !announcement.EndAt.After(announcement.StartAt) {
+		return fmt.Errorf("announcement ends before it starts")
+	}
+
+	if err := s.store.Create(announcement); err != nil {
+		return err
+	}
+	s.invalidate()
+	return nil
+}
+
+// Delete 删除公告，公告不存在时返回false
+func (s *Service) Delete(announcementID string) (bool, error) {
+	deleted, err := s.store.Delete(announcementID)
+	if deleted {
+		s.invalidate()
+	}
+	return deleted, err
+}
+
+// List 获取最近创建的公告
+func (s *Service) List(limit int64) ([]*database.Announcement, error) {
+	return s.store.List(limit)
+}
+
+// Get 获取公告，不存在时返回nil
+func (s *Service) Get(announcementID string) (*database.Announcement, error) {
+	return s.store.Get(announcementID)
+}
+
+// Active 获取进行中的公告，channel为空时获取所有位置，按优先级从高到低排列
+func (s *Service) Active(channel, language string, now time.Time) ([]*Localized, error) {
+	announcements, err := s.cached(now)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Localized
+	for _, announcement := range announcements {
+		if now.Before(announcement.StartAt) || !now.Before(announcement.EndAt) {
+			continue
+		}
+		if channel != "" && !hasChannel(announcement, channel) {
+			continue
+		}
+		result = append(result, Localize(announcement, language))
+	}
+	return result, nil
+}
+
+// cached 获取缓存的进行中公告，过期时从存储重新加载
+func (s *Service) cached(now time.Time) ([]*database.Announcement, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.active != nil && now.Sub(s.loadedAt) < s.config.Cache {
+		return s.active, nil
+	}
+	// 多加载一个缓存周期内开始的公告，缓存期间开始的公告也能展示
+	announcements, err := s.store.Active(now, now.Add(s.config.Cache))
+	if err != nil {
+		return nil, err
+	}
+	if announcements == nil {
+		announcements = []*database.Announcement{}
+	}
+	s.active = announcements
+	s.loadedAt = now
+	return announcements, nil
+}
+
+// Warm 预先加载进行中的公告，节点上线后第一批请求不用等待加载
+func (s *Service) Warm(now time.Time) error {
+	_, err := s.cached(now)
+	return err
+}
+
+// invalidate 清除本地缓存
+func (s *Service) invalidate() {
+	s.mutex.Lock()
+	s.active = nil
+	s.mutex.Unlock()
+}
+
+// DuePush 标记并返回已经开始但还没推送的跑马灯公告，标记失败的公告已由其他节点推送
+func (s *Service) DuePush(now time.Time) ([]*database.Announcement, error) {
+	due, err := s.store.DuePush(now)
+	if err != nil {
+		return nil, err
+	}
+
+	var marked []*database.Announcement
+	for _, announcement := range due {
+		ok, err := s.store.MarkPushed(announcement)
+		if err != nil {
+			return marked, err
+		}
+		if ok {
+			marked = append(marked, announcement)
+		}
+	}
+	return marked, nil
+}
+
+// Localize 选择玩家语言的内容，依次匹配完整语言、主语言和公告的默认语言
+func Localize(announcement *database.Announcement, language string) *Localized {
+	content, exists := announcement.Contents[language]
+	if !exists {
+		if i := strings.IndexAny(language, "-_"); i > 0 {
+			content, exists = announcement.Contents[language[:i]]
+		}
+	}
+	if !exists {
+		content = announcement.Contents[announcement.Language]
+	}
+
+	return &Localized{
+		ID:       announcement.ID.Hex(),
+		Title:    content.Title,
+		Content:  content.Content,
+		Channels: announcement.Channels,
+		Priority: announcement.Priority,
+		StartAt:  announcement.StartAt,
+		EndAt:    announcement.EndAt,
+	}
+}
+
+// hasChannel 检查公告是否在指定位置展示
+func hasChannel(announcement *database.Announcement, channel string) bool {
+	for _, c := range announcement.Channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,344 @@
+package autoscale
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/discovery"
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// 自动扩缩容默认设置
+const (
+	defaultScaleOutCooldown = 3 * time.Minute
+	defaultScaleInCooldown  = 10 * time.Minute
+	defaultScaleInThreshold = 0.5
+	defaultTimeout          = 10 * time.Second
+	staleAfter              = 60 // 超过该秒数未上报的节点不参与计算
+)
+
+// Config 自动扩缩容配置
+type Config struct {
+	Enabled          bool                  `yaml:"enabled"`
+	ScaleOutCooldown time.Duration         `yaml:"scale_out_cooldown"` // 两次扩容的最小间隔，未配置时为3分钟
+	ScaleInCooldown  time.Duration         `yaml:"scale_in_cooldown"`  // 扩容或缩容后再次缩容的最小间隔，未配置时为10分钟
+	Pools            map[string]PoolConfig `yaml:"pools"`              // 节点类型 -> 扩缩容设置，未配置的节点类型不处理
+	Webhook          WebhookConfig         `yaml:"webhook"`
+	Metrics          int                   `yaml:"metrics"` // 中心服导出扩缩容指标的Prometheus端口，供HPA通过指标适配器读取，0为不导出
+}
+
+// PoolConfig 一类节点的扩缩容设置，房间数和连接数按配置了目标值的项分别计算所需节点数，取较大者
+type PoolConfig struct {
+	MinNodes           int     `yaml:"min_nodes"`
+	MaxNodes           int     `yaml:"max_nodes"`            // 0为不限制
+	RoomsPerNode       int64   `yaml:"rooms_per_node"`       // 每个节点的目标房间数，0为不按房间计算
+	ConnectionsPerNode int64   `yaml:"connections_per_node"` // 每个节点的目标连接数，0为不按连接计算
+	ScaleInThreshold   float64 `yaml:"scale_in_threshold"`   // 利用率低于该值才缩容，避免在目标值附近反复扩缩，未配置时为0.5
+}
+
+// NodeLoad 节点的负载
+type NodeLoad struct {
+	NodeID      string `json:"node_id"`
+	Status      string `json:"status"`
+	Rooms       int64  `json:"rooms"`
+	Connections int64  `json:"connections"`
+	Reported    bool   `json:"reported"` // 是否上报了房间数和连接数，旧版本节点不上报，不会被缩容
+}
+
+// idle 没有房间和连接，可以安全移除
+func (n *NodeLoad) idle() bool {
+	return n.Reported && n.Rooms == 0 && n.Connections == 0
+}
+
+// Decision 一类节点的扩缩容建议
+// 缩容不直接减少节点：先排空负载最低的节点，排空后不再分配新房间和连接，已有房间迁移或自然结束，
+// 节点空闲后才列入Remove并计入Desired，保证有房间的节点不会被移除
+type Decision struct {
+	NodeType    string      `json:"node_type"`
+	Nodes       int         `json:"nodes"`            // 在线节点数，含排空中的节点
+	Active      int         `json:"active"`           // 接受新负载的节点数
+	Rooms       int64       `json:"rooms"`            // 房间总数
+	Connections int64       `json:"connections"`      // 连接总数
+	Utilization float64     `json:"utilization"`      // 当前负载与接受新负载的节点目标容量之比
+	Target      int         `json:"target"`           // 按负载计算的接受新负载的节点数
+	Desired     int         `json:"desired"`          // 建议的节点总数，缩容时只减去已空闲的节点
+	Drain       []string    `json:"drain,omitempty"`  // 本次需要排空的节点
+	Resume      []string    `json:"resume,omitempty"` // 扩容时优先恢复的排空中节点
+	Remove      []string    `json:"remove,omitempty"` // 已排空且空闲、可以移除的节点
+	Reason      string      `json:"reason"`
+	Loads       []*NodeLoad `json:"loads"`
+	Time        time.Time   `json:"time"`
+}
+
+// Changed 是否需要调整节点
+func (d *Decision) Changed() bool {
+	return d.Desired != d.Nodes || len(d.Drain) > 0 || len(d.Resume) > 0
+}
+
+// Provisioner 执行扩缩容的外部系统
+type Provisioner interface {
+	Name() string
+	// Scale 按建议调整节点，每次评估都会调用，需要自行忽略没有变化的建议
+	Scale(ctx context.Context, decision *Decision) error
+}
+
+// pool 一类节点的扩缩容状态
+type pool struct {
+	lastScaleOut time.Time
+	lastScaleIn  time.Time
+	scaledOutTo  int             // 最近一次扩容建议的节点总数，新节点注册前保持该建议
+	draining     map[string]bool // 由扩缩容排空的节点，其他原因排空的节点不会被移除
+	decision     *Decision
+}
+
+// Advisor 扩缩容顾问，中心服定期用各节点上报的房间数和连接数评估，并把建议交给执行者
+type Advisor struct {
+	config       Config
+	provisioners []Provisioner
+	pools        map[string]*pool
+	mutex        sync.RWMutex
+}
+
+// NewAdvisor 创建扩缩容顾问，配置了webhook时把建议发送给外部扩缩容服务
+func NewAdvisor(config *Config) *Advisor {
+	cfg := *config
+	if cfg.ScaleOutCooldown <= 0 {
+		cfg.ScaleOutCooldown = defaultScaleOutCooldown
+	}
+	if cfg.ScaleInCooldown <= 0 {
+		cfg.ScaleInCooldown = defaultScaleInCooldown
+	}
+
+	pools := make(map[string]*pool)
+	for nodeType := range cfg.Pools {
+		pools[nodeType] = &pool{draining: make(map[string]bool)}
+	}
+
+	advisor := &Advisor{
+		config: cfg,
+		pools:  pools,
+	}
+	if cfg.Webhook.URL != "" {
+		advisor.AddProvisioner(NewWebhookProvisioner(&cfg.Webhook))
+	}
+	return advisor
+}
+
+// AddProvisioner 添加执行者
+func (a *Advisor) AddProvisioner(provisioner Provisioner) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.provisioners = append(a.provisioners, provisioner)
+}
+
+// NodeTypes 配置了扩缩容的节点类型
+func (a *Advisor) NodeTypes() []string {
+	nodeTypes := make([]string, 0, len(a.config.Pools))
+	for nodeType := range a.config.Pools {
+		nodeTypes = append(nodeTypes, nodeType)
+	}
+	sort.Strings(nodeTypes)
+	return nodeTypes
+}
+
+// Evaluate 评估一类节点的负载并给出建议，services为该类型的所有注册节点
+func (a *Advisor) Evaluate(nodeType string, services []*discovery.ServiceInfo, now time.Time) (*Decision, error) {
+	config, exists := a.config.Pools[nodeType]
+	if !exists {
+		return nil, fmt.Errorf("node type %s has no autoscale pool", nodeType)
+	}
+	threshold := config.ScaleInThreshold
+	if threshold <= 0 {
+		threshold = defaultScaleInThreshold
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	state := a.pools[nodeType]
+
+	decision := &Decision{NodeType: nodeType, Time: now}
+	var active, draining []*NodeLoad
+	starting := 0
+	present := make(map[string]bool)
+	for _, service := range services {
+		if now.Unix()-service.UpdateTime > staleAfter {
+			continue
+		}
+		load := &NodeLoad{NodeID: service.NodeID, Status: service.Status}
+		if service.Metrics != nil && service.Metrics.Window > 0 {
+			load.Rooms = service.Metrics.Rooms
+			load.Connections = service.Metrics.Connections
+			load.Reported = true
+		}
+		present[service.NodeID] = true
+		decision.Loads = append(decision.Loads, load)
+		decision.Rooms += load.Rooms
+		decision.Connections += load.Connections
+
+		switch service.Status {
+		case discovery.StatusOnline:
+			active = append(active, load)
+		case discovery.StatusDraining:
+			draining = append(draining, load)
+		case discovery.StatusStarting:
+			starting++
+		}
+	}
+	decision.Nodes = len(decision.Loads)
+	decision.Active = len(active)
+
+	// 已下线的节点和被恢复的节点不再跟踪
+	for nodeID := range state.draining {
+		if !present[nodeID] {
+			delete(state.draining, nodeID)
+		}
+	}
+	for _, load := range active {
+		delete(state.draining, load.NodeID)
+	}
+
+	decision.Target = config.target(decision.Rooms, decision.Connections)
+	decision.Utilization = config.utilization(decision.Rooms, decision.Connections, decision.Active)
+
+	var owned []*NodeLoad
+	for _, load := range draining {
+		if state.draining[load.NodeID] {
+			owned = append(owned, load)
+		}
+	}
+
+	added := 0
+	switch {
+	case decision.Target > decision.Active+starting:
+		if now.Sub(state.lastScaleOut) < a.config.ScaleOutCooldown {
+			decision.Reason = "scale out cooling down"
+			break
+		}
+		// 启动中的节点很快会接受新负载
+		need := decision.Target - decision.Active - starting
+		// 优先恢复扩缩容排空的节点，比新建节点更快
+		sortByLoad(owned)
+		for len(owned) > 0 && len(decision.Resume) < need {
+			last := owned[len(owned)-1]
+			owned = owned[:len(owned)-1]
+			decision.Resume = append(decision.Resume, last.NodeID)
+			delete(state.draining, last.NodeID)
+		}
+		added = need - len(decision.Resume)
+		state.scaledOutTo = decision.Nodes + added
+		state.lastScaleOut = now
+		state.lastScaleIn = now
+		decision.Reason = fmt.Sprintf("utilization %.2f requires %d active nodes", decision.Utilization, decision.Target)
+
+	case decision.Target < decision.Active && decision.Utilization < threshold:
+		if now.Sub(state.lastScaleIn) < a.config.ScaleInCooldown {
+			decision.Reason = "scale in cooling down"
+			break
+		}
+		// 排空房间和连接最少的节点，迁移的代价最小
+		sortByLoad(active)
+		for _, load := range active[:decision.Active-decision.Target] {
+			if !load.Reported {
+				continue
+			}
+			decision.Drain = append(decision.Drain, load.NodeID)
+			state.draining[load.NodeID] = true
+		}
+		if len(decision.Drain) > 0 {
+			state.lastScaleIn = now
+		}
+		decision.Reason = fmt.Sprintf("utilization %.2f below %.2f, draining %d nodes", decision.Utilization, threshold, len(decision.Drain))
+
+	default:
+		decision.Reason = fmt.Sprintf("utilization %.2f within range", decision.Utilization)
+	}
+
+	// 扩缩容排空的节点空闲后才可以移除
+	for _, load := range owned {
+		if load.idle() {
+			decision.Remove = append(decision.Remove, load.NodeID)
+		}
+	}
+	sort.Strings(decision.Remove)
+
+	decision.Desired = decision.Nodes - len(decision.Remove) + added
+	if now.Sub(state.lastScaleOut) < a.config.ScaleOutCooldown {
+		decision.Desired = max(decision.Desired, state.scaledOutTo)
+	}
+	state.decision = decision
+	return decision, nil
+}
+
+// Decisions 各类节点最近一次的建议
+func (a *Advisor) Decisions() []*Decision {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	decisions := make([]*Decision, 0, len(a.pools))
+	for _, nodeType := range a.NodeTypes() {
+		if decision := a.pools[nodeType].decision; decision != nil {
+			decisions = append(decisions, decision)
+		}
+	}
+	return decisions
+}
+
+// Provision 把建议交给所有执行者，单个执行者失败不影响其他执行者
+func (a *Advisor) Provision(ctx context.Context, decision *Decision) {
+	a.mutex.RLock()
+	provisioners := a.provisioners
+	a.mutex.RUnlock()
+
+	for _, provisioner := range provisioners {
+		if err := provisioner.Scale(ctx, decision); err != nil {
+			logger.Error(fmt.Sprintf("Autoscale provisioner %s failed for %s: %v", provisioner.Name(), decision.NodeType, err))
+		}
+	}
+}
+
+// target 按负载计算所需的节点数，限制在最小和最大节点数之间
+func (c PoolConfig) target(rooms, connections int64) int {
+	target := 0
+	if c.RoomsPerNode > 0 {
+		target = max(target, int(math.Ceil(float64(rooms)/float64(c.RoomsPerNode))))
+	}
+	if c.ConnectionsPerNode > 0 {
+		target = max(target, int(math.Ceil(float64(connections)/float64(c.ConnectionsPerNode))))
+	}
+	target = max(target, c.MinNodes, 1)
+	if c.MaxNodes > 0 {
+		target = min(target, c.MaxNodes)
+	}
+	return target
+}
+
+// utilization 负载与目标容量之比，取房间和连接中较高者
+func (c PoolConfig) utilization(rooms, connections int64, nodes int) float64 {
+	// 没有接受新负载的节点时按一个节点计算
+	nodes = max(nodes, 1)
+	utilization := 0.0
+	if c.RoomsPerNode > 0 {
+		utilization = math.Max(utilization, float64(rooms)/float64(c.RoomsPerNode*int64(nodes)))
+	}
+	if c.ConnectionsPerNode > 0 {
+		utilization = math.Max(utilization, float64(connections)/float64(c.ConnectionsPerNode*int64(nodes)))
+	}
+	return utilization
+}
+
+// sortByLoad 按房间数、连接数从低到高排序
+func sortByLoad(loads []*NodeLoad) {
+	sort.Slice(loads, func(i, j int) bool {
+		if loads[i].Rooms != loads[j].Rooms {
+			return loads[i].Rooms < loads[j].Rooms
+		}
+		if loads[i].Connections != loads[j].Connections {
+			return loads[i].Connections < loads[j].Connections
+		}
+		return loads[i].NodeID < loads[j].NodeID
+	})
+}
@@ -0,0 +1,152 @@
+package autoscale
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// WebhookConfig 外部扩缩容服务的webhook
+type WebhookConfig struct {
+	URL     string        `yaml:"url"`     // 为空时不发送
+	Token   string        `yaml:"token"`   // 以Bearer令牌发送
+	Timeout time.Duration `yaml:"timeout"` // 未配置时为10秒
+}
+
+// WebhookProvisioner 把有变化的建议以JSON POST给外部扩缩容服务，
+// 外部服务按Desired调整节点数，缩容时只能移除Remove中的节点
+type WebhookProvisioner struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewWebhookProvisioner 创建webhook执行者
+func NewWebhookProvisioner(config *WebhookConfig) *WebhookProvisioner {
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &WebhookProvisioner{
+		url:    config.URL,
+		token:  config.Token,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Name 实现Provisioner接口
+func (p *WebhookProvisioner) Name() string {
+	return "webhook"
+}
+
+// Scale 实现Provisioner接口，没有变化的建议不发送
+func (p *WebhookProvisioner) Scale(ctx context.Context, decision *Decision) error {
+	if !decision.Changed() && len(decision.Remove) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(decision)
+	if err != nil {
+		return fmt.Errorf("failed to marshal decision: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send decision: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("autoscale webhook returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Collector 扩缩容指标，实现prometheus.Collector接口。Kubernetes通过Prometheus指标适配器把
+// lufy_autoscale_desired_nodes作为外部指标提供给HPA，目标值设为每个Pod 1即可让副本数跟随建议；
+// 建议只在节点空闲后减少，但HPA缩容时选择的Pod不一定是空闲节点，需要配合Pod删除开销注解
+// 或由控制器按lufy_autoscale_node_removable删除指定的Pod
+type Collector struct {
+	advisor *Advisor
+
+	nodes       *prometheus.Desc
+	desired     *prometheus.Desc
+	utilization *prometheus.Desc
+	rooms       *prometheus.Desc
+	connections *prometheus.Desc
+	removable   *prometheus.Desc
+}
+
+// NewCollector 创建扩缩容指标
+func NewCollector(advisor *Advisor) *Collector {
+	return &Collector{
+		advisor: advisor,
+		nodes: prometheus.NewDesc("lufy_autoscale_nodes",
+			"Number of online nodes by node type and status",
+			[]string{"node_type", "status"}, nil),
+		desired: prometheus.NewDesc("lufy_autoscale_desired_nodes",
+			"Recommended number of nodes, only lowered once drained nodes are idle",
+			[]string{"node_type"}, nil),
+		utilization: prometheus.NewDesc("lufy_autoscale_utilization",
+			"Load relative to the target capacity of active nodes",
+			[]string{"node_type"}, nil),
+		rooms: prometheus.NewDesc("lufy_autoscale_rooms",
+			"Number of active rooms reported by nodes",
+			[]string{"node_type"}, nil),
+		connections: prometheus.NewDesc("lufy_autoscale_connections",
+			"Number of client connections reported by nodes",
+			[]string{"node_type"}, nil),
+		removable: prometheus.NewDesc("lufy_autoscale_node_removable",
+			"Whether the node was drained for scale in and is now idle",
+			[]string{"node_type", "node_id"}, nil),
+	}
+}
+
+// Describe 实现prometheus.Collector接口
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.nodes
+	ch <- c.desired
+	ch <- c.utilization
+	ch <- c.rooms
+	ch <- c.connections
+	ch <- c.removable
+}
+
+// Collect 实现prometheus.Collector接口，导出各类节点最近一次的建议
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, decision := range c.advisor.Decisions() {
+		nodeType := decision.NodeType
+		ch <- prometheus.MustNewConstMetric(c.nodes, prometheus.GaugeValue, float64(decision.Active), nodeType, "active")
+		ch <- prometheus.MustNewConstMetric(c.nodes, prometheus.GaugeValue, float64(decision.Nodes-decision.Active), nodeType, "inactive")
+		ch <- prometheus.MustNewConstMetric(c.desired, prometheus.GaugeValue, float64(decision.Desired), nodeType)
+		ch <- prometheus.MustNewConstMetric(c.utilization, prometheus.GaugeValue, decision.Utilization, nodeType)
+		ch <- prometheus.MustNewConstMetric(c.rooms, prometheus.GaugeValue, float64(decision.Rooms), nodeType)
+		ch <- prometheus.MustNewConstMetric(c.connections, prometheus.GaugeValue, float64(decision.Connections), nodeType)
+
+		removable := make(map[string]bool, len(decision.Remove))
+		for _, nodeID := range decision.Remove {
+			removable[nodeID] = true
+		}
+		for _, load := range decision.Loads {
+			value := 0.0
+			if removable[load.NodeID] {
+				value = 1
+			}
+			ch <- prometheus.MustNewConstMetric(c.removable, prometheus.GaugeValue, value, nodeType, load.NodeID)
+		}
+	}
+}
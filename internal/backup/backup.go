@@ -0,0 +1,255 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/profiling"
+)
+
+// 备份默认设置
+const (
+	DefaultSchedule = "0 4 * * *"
+	defaultPrefix   = "backups"
+	defaultDir      = "backups"
+	manifestName    = "manifest.json"
+	idLayout        = "20060102T150405Z"
+)
+
+// Config 备份配置
+type Config struct {
+	Enabled     bool                  `yaml:"enabled"`     // 是否定时备份
+	Schedule    string                `yaml:"schedule"`    // 定时备份的调度表达式，未配置时为每天4点
+	Collections []string              `yaml:"collections"` // 备份的集合，未配置时为主库的所有集合，独立存放的集合需显式列出
+	Prefix      string                `yaml:"prefix"`      // 对象键前缀，未配置时为backups
+	Store       profiling.StoreConfig `yaml:"store"`       // 对象存储，local类型未配置目录时为backups
+}
+
+// Manifest 一次备份的清单，所有集合上传完成后最后写入，没有清单的备份不完整
+type Manifest struct {
+	ID          string              `json:"id"`
+	CreatedAt   time.Time           `json:"created_at"`
+	Duration    string              `json:"duration"`
+	Collections []*CollectionBackup `json:"collections"`
+}
+
+// CollectionBackup 单个集合的备份，文件为gzip压缩的BSON文档序列，与mongodump的.bson格式相同
+type CollectionBackup struct {
+	Name      string     `json:"name"`
+	Database  string     `json:"database"`
+	Key       string     `json:"key"`
+	Documents int64      `json:"documents"`
+	Bytes     int64      `json:"bytes"`                // 压缩前的大小
+	OplogTime *OplogTime `json:"oplog_time,omitempty"` // 快照对应的oplog时间点，单机模式没有快照读取时为空
+}
+
+// OplogTime oplog时间点，恢复后可从该时间点重放oplog补齐之后的修改
+type OplogTime struct {
+	T uint32 `json:"t"`
+	I uint32 `json:"i"`
+}
+
+// Find 清单中的集合
+func (m *Manifest) Find(name string) *CollectionBackup {
+	for _, collection := range m.Collections {
+		if collection.Name == name {
+			return collection
+		}
+	}
+	return nil
+}
+
+// Service 导出集合到对象存储，以及从备份恢复
+type Service struct {
+	config Config
+	mongo  *database.MongoManager
+	store  profiling.ObjectStore
+}
+
+// NewService 创建备份服务
+func NewService(config *Config, mongo *database.MongoManager) (*Service, error) {
+	c := *config
+	if c.Prefix == "" {
+		c.Prefix = defaultPrefix
+	}
+	if (c.Store.Type == "" || c.Store.Type == profiling.StoreTypeLocal) && c.Store.Dir == "" {
+		c.Store.Dir = defaultDir
+	}
+	store, err := profiling.NewObjectStore(&c.Store)
+	if err != nil {
+		return nil, err
+	}
+	return &Service{config: c, mongo: mongo, store: store}, nil
+}
+
+// Run 定时任务入口，按配置备份
+func (s *Service) Run(ctx context.Context) error {
+	manifest, err := s.Backup(ctx, nil)
+	if err != nil {
+		return err
+	}
+	logger.Info(fmt.Sprintf("Backup %s finished: %d collections in %s", manifest.ID, len(manifest.Collections), manifest.Duration))
+	return nil
+}
+
+// Backup 导出集合，collections为空时按配置。同一数据库的集合在同一个快照中读取，
+// 副本集和分片集群上各集合是同一时间点的一致数据，单机模式逐个集合读取
+func (s *Service) Backup(ctx context.Context, collections []string) (*Manifest, error) {
+	if len(collections) == 0 {
+		collections = s.config.Collections
+	}
+	if len(collections) == 0 {
+		names, err := s.mongo.GetDatabase().ListCollectionNames(ctx, bson.M{"name": bson.M{"$not": bson.M{"$regex": "^system\\."}}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list collections: %v", err)
+		}
+		sort.Strings(names)
+		collections = names
+	}
+
+	start := time.Now()
+	manifest := &Manifest{ID: start.UTC().Format(idLayout), CreatedAt: start}
+
+	// 按所在数据库分组，每组使用一个快照会话
+	groups := make(map[*mongo.Database][]string)
+	var order []*mongo.Database
+	for _, name := range collections {
+		db := s.mongo.GetCollection(name).Database()
+		if _, ok := groups[db]; !ok {
+			order = append(order, db)
+		}
+		groups[db] = append(groups[db], name)
+	}
+	for _, db := range order {
+		backups, err := s.dumpDatabase(ctx, db, groups[db], manifest.ID)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Collections = append(manifest.Collections, backups...)
+	}
+	manifest.Duration = time.Since(start).Round(time.Second).String()
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.Put(s.key(manifest.ID, manifestName), data); err != nil {
+		return nil, fmt.Errorf("failed to upload backup manifest: %v", err)
+	}
+	return manifest, nil
+}
+
+// dumpDatabase 在同一个快照中导出数据库中的集合
+func (s *Service) dumpDatabase(ctx context.Context, db *mongo.Database, names []string, id string) ([]*CollectionBackup, error) {
+	snapshot := supportsSnapshot(ctx, db)
+	if !snapshot {
+		logger.Warn(fmt.Sprintf("Database %s does not support snapshot reads, collections are dumped one by one", db.Name()))
+	}
+	session, err := db.Client().StartSession(options.Session().SetSnapshot(snapshot))
+	if err != nil {
+		return nil, fmt.Errorf("failed to start backup session: %v", err)
+	}
+	defer session.EndSession(ctx)
+
+	var backups []*CollectionBackup
+	err = mongo.WithSession(ctx, session, func(sc mongo.SessionContext) error {
+		for _, name := range names {
+			backup, err := s.dumpCollection(sc, db.Collection(name), id)
+			if err != nil {
+				return err
+			}
+			backups = append(backups, backup)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 快照读取的集群时间即各集合数据对应的oplog时间点
+	if ts := session.OperationTime(); snapshot && ts != nil {
+		for _, backup := range backups {
+			backup.OplogTime = &OplogTime{T: ts.T, I: ts.I}
+		}
+	}
+	return backups, nil
+}
+
+// dumpCollection 导出集合并上传
+func (s *Service) dumpCollection(ctx context.Context, collection *mongo.Collection, id string) (*CollectionBackup, error) {
+	cursor, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", collection.Name(), err)
+	}
+	defer cursor.Close(ctx)
+
+	backup := &CollectionBackup{
+		Name:     collection.Name(),
+		Database: collection.Database().Name(),
+		Key:      s.key(id, collection.Database().Name()+"."+collection.Name()+".bson.gz"),
+	}
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	for cursor.Next(ctx) {
+		if _, err := writer.Write(cursor.Current); err != nil {
+			return nil, err
+		}
+		backup.Documents++
+		backup.Bytes += int64(len(cursor.Current))
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", collection.Name(), err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := s.store.Put(backup.Key, buf.Bytes()); err != nil {
+		return nil, fmt.Errorf("failed to upload backup of %s: %v", collection.Name(), err)
+	}
+	logger.Info(fmt.Sprintf("Backed up %d documents of %s.%s", backup.Documents, backup.Database, backup.Name))
+	return backup, nil
+}
+
+// Manifest 读取备份清单
+func (s *Service) Manifest(id string) (*Manifest, error) {
+	data, err := s.store.Get(s.key(id, manifestName))
+	if err != nil {
+		return nil, fmt.Errorf("backup %s not found or incomplete: %v", id, err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest of backup %s: %v", id, err)
+	}
+	return &manifest, nil
+}
+
+// key 对象键
+func (s *Service) key(id, name string) string {
+	return path.Join(strings.Trim(s.config.Prefix, "/"), id, name)
+}
+
+// supportsSnapshot 副本集和分片集群支持快照读取
+func supportsSnapshot(ctx context.Context, db *mongo.Database) bool {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	if err := db.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return false
+	}
+	return hello.SetName != "" || hello.Msg == "isdbgrid"
+}
@@ -0,0 +1,199 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// restoreBatchSize 恢复时每批写入的文档数
+const restoreBatchSize = 500
+
+// RestoreOptions 恢复选项
+type RestoreOptions struct {
+	ID          string   // 备份ID
+	Collections []string // 恢复的集合，为空时恢复备份中的所有集合
+	DryRun      bool     // 只比较差异，不写入
+	Prune       bool     // 删除当前有、备份中没有的文档，否则保留
+}
+
+// CollectionDiff 集合当前数据与备份的差异，恢复时按差异写入
+type CollectionDiff struct {
+	Name      string `json:"name"`
+	Inserted  int64  `json:"inserted"`  // 备份中有、当前没有的文档
+	Updated   int64  `json:"updated"`   // 两边都有但内容不同的文档
+	Unchanged int64  `json:"unchanged"` // 内容相同的文档
+	Extra     int64  `json:"extra"`     // 当前有、备份中没有的文档，prune时删除
+	Deleted   int64  `json:"deleted"`   // 实际删除的文档数
+}
+
+// Restore 按文档_id比较备份与当前数据，DryRun时只返回差异，否则写入新增和不同的文档
+func (s *Service) Restore(ctx context.Context, opts *RestoreOptions) ([]*CollectionDiff, error) {
+	manifest, err := s.Manifest(opts.ID)
+	if err != nil {
+		return nil, err
+	}
+	names := opts.Collections
+	if len(names) == 0 {
+		for _, collection := range manifest.Collections {
+			names = append(names, collection.Name)
+		}
+	}
+
+	diffs := make([]*CollectionDiff, 0, len(names))
+	for _, name := range names {
+		backup := manifest.Find(name)
+		if backup == nil {
+			return diffs, fmt.Errorf("collection %s is not in backup %s", name, opts.ID)
+		}
+		diff, err := s.restoreCollection(ctx, backup, opts)
+		if err != nil {
+			return diffs, err
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs, nil
+}
+
+// restoreCollection 比较并恢复单个集合
+func (s *Service) restoreCollection(ctx context.Context, backup *CollectionBackup, opts *RestoreOptions) (*CollectionDiff, error) {
+	docs, err := s.load(backup)
+	if err != nil {
+		return nil, err
+	}
+
+	collection := s.mongo.GetCollection(backup.Name)
+	diff := &CollectionDiff{Name: backup.Name}
+	var extra []interface{}
+
+	cursor, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", backup.Name, err)
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		id := cursor.Current.Lookup("_id")
+		key := documentKey(id)
+		doc, ok := docs[key]
+		switch {
+		case !ok:
+			diff.Extra++
+			if opts.Prune {
+				extra = append(extra, id)
+			}
+		case bytes.Equal(doc, cursor.Current):
+			diff.Unchanged++
+			delete(docs, key)
+		default:
+			diff.Updated++
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", backup.Name, err)
+	}
+	// 比较后docs中剩下备份中有、当前没有的和内容不同的文档
+	diff.Inserted = int64(len(docs)) - diff.Updated
+
+	if opts.DryRun {
+		return diff, nil
+	}
+
+	models := make([]mongo.WriteModel, 0, restoreBatchSize)
+	flush := func() error {
+		if len(models) == 0 {
+			return nil
+		}
+		if _, err := collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false)); err != nil {
+			return fmt.Errorf("failed to restore %s: %v", backup.Name, err)
+		}
+		models = models[:0]
+		return nil
+	}
+	for _, doc := range docs {
+		models = append(models, mongo.NewReplaceOneModel().
+			SetFilter(bson.D{{Key: "_id", Value: doc.Lookup("_id")}}).
+			SetReplacement(doc).
+			SetUpsert(true))
+		if len(models) == restoreBatchSize {
+			if err := flush(); err != nil {
+				return diff, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return diff, err
+	}
+
+	for start := 0; start < len(extra); start += restoreBatchSize {
+		end := min(start+restoreBatchSize, len(extra))
+		result, err := collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": extra[start:end]}})
+		if err != nil {
+			return diff, fmt.Errorf("failed to prune %s: %v", backup.Name, err)
+		}
+		diff.Deleted += result.DeletedCount
+	}
+
+	logger.Info(fmt.Sprintf("Restored %s from backup: %+v", backup.Name, *diff))
+	return diff, nil
+}
+
+// load 下载并解析集合的备份，按_id索引
+func (s *Service) load(backup *CollectionBackup) (map[string]bson.Raw, error) {
+	data, err := s.store.Get(backup.Key)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup of %s: %v", backup.Name, err)
+	}
+	defer reader.Close()
+
+	docs := make(map[string]bson.Raw, backup.Documents)
+	for {
+		doc, err := readDocument(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid backup of %s: %v", backup.Name, err)
+		}
+		docs[documentKey(doc.Lookup("_id"))] = doc
+	}
+	if int64(len(docs)) != backup.Documents {
+		return nil, fmt.Errorf("backup of %s has %d documents, manifest says %d", backup.Name, len(docs), backup.Documents)
+	}
+	return docs, nil
+}
+
+// readDocument 读取一个BSON文档，文档以4字节小端长度开头
+func readDocument(r io.Reader) (bson.Raw, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	length := int(binary.LittleEndian.Uint32(header[:]))
+	if length < 5 {
+		return nil, fmt.Errorf("invalid document length %d", length)
+	}
+	doc := make([]byte, length)
+	copy(doc, header[:])
+	if _, err := io.ReadFull(r, doc[4:]); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return doc, nil
+}
+
+// documentKey _id的类型和内容，用于比较不同类型的_id
+func documentKey(id bson.RawValue) string {
+	return string(rune(id.Type)) + string(id.Value)
+}
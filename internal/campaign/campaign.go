@@ -0,0 +1,386 @@
+package campaign
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// 活动操作失败原因
+var (
+	ErrNotFound = errors.New("mail campaign not found")
+	ErrState    = errors.New("mail campaign cannot be changed in its current state")
+)
+
+// 邮件活动默认设置
+const (
+	defaultBatch    = 200
+	defaultInterval = 5 * time.Second
+	defaultExpire   = 30 * 24 * time.Hour
+	defaultLanguage = "en"
+	activeLimit     = 20 // 每次最多推进的活动数
+)
+
+// Config 邮件活动发送设置，每个间隔每个活动发送一批，用于限制发送速度
+type Config struct {
+	Batch    int64         `yaml:"batch"`    // 每批检查的玩家数，未配置时为200
+	Interval time.Duration `yaml:"interval"` // 发送间隔，未配置时为5秒
+	Expire   time.Duration `yaml:"expire"`   // 活动邮件的有效期，未配置时为30天
+	Language string        `yaml:"language"` // 未指定时使用的模板语言，未配置时为en
+}
+
+// withDefaults 补齐未配置的设置
+func (c Config) withDefaults() Config {
+	if c.Batch <= 0 {
+		c.Batch = defaultBatch
+	}
+	if c.Interval <= 0 {
+		c.Interval = defaultInterval
+	}
+	if c.Expire <= 0 {
+		c.Expire = defaultExpire
+	}
+	if c.Language == "" {
+		c.Language = defaultLanguage
+	}
+	return c
+}
+
+// Store 活动存储，database.MailCampaignRepository实现了该接口
+type Store interface {
+	Create(campaign *database.MailCampaign) error
+	Get(campaignID string) (*database.MailCampaign, error)
+	List(status string, limit int64) ([]*database.MailCampaign, error)
+	Advance(id primitive.ObjectID, from, to uint64, scanned, sent, failed int64, done bool) (bool, error)
+	SetStatus(campaignID string, from []string, to string) (bool, error)
+}
+
+// MailStore 邮件存储，在发放邮件之外按批次查询收件人和统计，database.MailRepository实现了该接口
+type MailStore interface {
+	database.MailStore
+	GetBatchRecipients(batchID string, userIDs []uint64) (map[uint64]bool, error)
+	BatchStats(batchID string) (total, read, claimed int64, err error)
+}
+
+// UserSource 用户来源，database.UserRepository实现了该接口
+type UserSource interface {
+	ListAfter(afterUserID uint64, limit int64) ([]*database.User, error)
+}
+
+// Segments 玩家分群，segment.Service实现了该接口
+type Segments interface {
+	Has(name string) bool
+	MatchUser(name string, user *database.User, now time.Time) (bool, error)
+}
+
+// NotifyFunc 通知玩家收到活动邮件
+type NotifyFunc func(userID, mailID uint64, title string, gift bool)
+
+// Stats 活动进度和邮件统计
+type Stats struct {
+	*database.MailCampaign
+	Remaining int64 `json:"remaining"` // 仍保留在邮箱中的邮件数
+	Read      int64 `json:"read"`
+	Claimed   int64 `json:"claimed"`
+}
+
+// Service 邮件活动服务，按用户ID顺序分批发送，进度保存在活动中，中断后从上次的位置继续
+type Service struct {
+	config   Config
+	store    Store
+	mails    MailStore
+	users    UserSource
+	segments Segments
+	mailIDs  database.MailIDGenerator
+	notify   NotifyFunc
+	stepping int32
+}
+
+// NewService 创建邮件活动服务
+func NewService(config *Config, store Store, mails MailStore, users UserSource, segments Segments,
+	mailIDs database.MailIDGenerator, notify NotifyFunc) *Service {
+	return &Service{
+		config:   config.withDefaults(),
+		store:    store,
+		mails:    mails,
+		users:    users,
+		segments: segments,
+		mailIDs:  mailIDs,
+		notify:   notify,
+	}
+}
+
+// Interval 发送间隔
+func (s *Service) Interval() time.Duration {
+	return s.config.Interval
+}
+
+// Language 未指定时使用的模板语言
+func (s *Service) Language() string {
+	return s.config.Language
+}
+
+// Create 检查并创建活动，未指定默认语言时使用配置的语言
+func (s *Service) Create(campaign *database.MailCampaign) error {
+	if !s.segments.Has(campaign.Segment) {
+		return fmt.Errorf("unknown segment: %s", campaign.Segment)
+	}
+	if campaign.Language == "" {
+		campaign.Language = s.config.Language
+	}
+	if _, exists := campaign.Templates[campaign.Language]; !exists {
+		return fmt.Errorf("missing template for default language %s", campaign.Language)
+	}
+	for language, mailTemplate := range campaign.Templates {
+		if mailTemplate.Title == "" || mailTemplate.Content == "" {
+			return fmt.Errorf("template %s requires title and content", language)
+		}
+		if _, err := render(mailTemplate, &database.User{}); err != nil {
+			return fmt.Errorf("invalid template %s: %v", language, err)
+		}
+	}
+	return s.store.Create(campaign)
+}
+
+// Step 每个发送中的活动发送一批，由集群内唯一执行的定时任务按发送间隔调用
+func (s *Service) Step() error {
+	// 上一批还没发完时跳过，避免同时给同一个玩家发送
+	if !atomic.CompareAndSwapInt32(&s.stepping, 0, 1) {
+		return nil
+	}
+	defer atomic.StoreInt32(&s.stepping, 0)
+
+	campaigns, err := s.store.List(database.MailCampaignRunning, activeLimit)
+	if err != nil {
+		return err
+	}
+	for _, campaign := range campaigns {
+		if err := s.sendBatch(campaign); err != nil {
+			logger.Error(fmt.Sprintf("Mail campaign %s stopped at user %d: %v", campaign.ID.Hex(), campaign.Cursor, err))
+		}
+	}
+	return nil
+}
+
+// sendBatch 检查进度之后的一批玩家并给分群内的玩家发送邮件
+// 已经发过的玩家跳过，写入邮件失败时只保存失败之前的进度，下次从失败的玩家继续
+func (s *Service) sendBatch(campaign *database.MailCampaign) error {
+	users, err := s.users.ListAfter(campaign.Cursor, s.config.Batch)
+	if err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		return s.save(campaign, campaign.Cursor, 0, 0, 0, true)
+	}
+
+	now := time.Now()
+	var members []*database.User
+	var memberIDs []uint64
+	for _, user := range users {
+		if member, _ := s.segments.MatchUser(campaign.Segment, user, now); member {
+			members = append(members, user)
+			memberIDs = append(memberIDs, user.UserID)
+		}
+	}
+
+	batchID := campaign.BatchID()
+	sent := make(map[uint64]bool)
+	if len(memberIDs) > 0 {
+		if sent, err = s.mails.GetBatchRecipients(batchID, memberIDs); err != nil {
+			return err
+		}
+	}
+
+	cursor := campaign.Cursor
+	var scanned, delivered, failed int64
+	var sendErr error
+	next := 0
+	for _, user := range users {
+		if next < len(members) && members[next] == user {
+			next++
+			if !sent[user.UserID] {
+				ok, err := s.send(campaign, batchID, user, now)
+				if err != nil {
+					sendErr = err
+					break
+				}
+				if ok {
+					delivered++
+				} else {
+					failed++
+				}
+			}
+		}
+		cursor = user.UserID
+		scanned++
+	}
+
+	done := sendErr == nil && int64(len(users)) < s.config.Batch
+	if err := s.save(campaign, cursor, scanned, delivered, failed, done); err != nil {
+		return err
+	}
+	return sendErr
+}
+
+// save 保存一批的发送进度
+func (s *Service) save(campaign *database.MailCampaign, cursor uint64, scanned, sent, failed int64, done bool) error {
+	saved, err := s.store.Advance(campaign.ID, campaign.Cursor, cursor, scanned, sent, failed, done)
+	if err != nil {
+		return err
+	}
+	if !saved {
+		logger.Warn(fmt.Sprintf("Mail campaign %s changed while sending, progress at user %d not saved", campaign.ID.Hex(), cursor))
+	} else if done {
+		logger.Info(fmt.Sprintf("Mail campaign %s completed: %d sent, %d failed",
+			campaign.ID.Hex(), campaign.Sent+sent, campaign.Failed+failed))
+	}
+	return nil
+}
+
+// send 给玩家发送活动邮件，模板渲染失败时跳过该玩家并返回false，写入失败时返回错误
+func (s *Service) send(campaign *database.MailCampaign, batchID string, user *database.User, now time.Time) (bool, error) {
+	content, err := render(pick(campaign, user.Language), user)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Mail campaign %s failed to render for user %d: %v", campaign.ID.Hex(), user.UserID, err))
+		return false, nil
+	}
+
+	mailID, err := s.mailIDs()
+	if err != nil {
+		return false, err
+	}
+	if err := s.mails.CreateMail(&database.Mail{
+		MailID:   mailID,
+		ToUserID: user.UserID,
+		Title:    content.Title,
+		Content:  content.Content,
+		Rewards:  campaign.Rewards,
+		BatchID:  batchID,
+		ExpireAt: now.Add(s.config.Expire),
+	}); err != nil {
+		return false, fmt.Errorf("failed to create mail for user %d: %v", user.UserID, err)
+	}
+
+	if s.notify != nil {
+		s.notify(user.UserID, mailID, content.Title, len(campaign.Rewards) > 0)
+	}
+	return true, nil
+}
+
+// pick 选择玩家语言的模板，依次匹配完整语言、主语言和活动的默认语言
+func pick(campaign *database.MailCampaign, language string) database.MailTemplate {
+	if mailTemplate, exists := campaign.Templates[language]; exists {
+		return mailTemplate
+	}
+	if i := strings.IndexAny(language, "-_"); i > 0 {
+		if mailTemplate, exists := campaign.Templates[language[:i]]; exists {
+			return mailTemplate
+		}
+	}
+	return campaign.Templates[campaign.Language]
+}
+
+// render 用玩家信息渲染模板
+func render(mailTemplate database.MailTemplate, user *database.User) (database.MailTemplate, error) {
+	data := map[string]interface{}{
+		"Nickname": user.Nickname,
+		"Level":    user.Level,
+	}
+
+	var result database.MailTemplate
+	for _, field := range []struct {
+		text string
+		out  *string
+	}{
+		{mailTemplate.Title, &result.Title},
+		{mailTemplate.Content, &result.Content},
+	} {
+		tmpl, err := template.New("mail").Option("missingkey=error").Parse(field.text)
+		if err != nil {
+			return result, err
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return result, err
+		}
+		*field.out = buf.String()
+	}
+	return result, nil
+}
+
+// Pause 暂停发送中的活动
+func (s *Service) Pause(campaignID string) error {
+	return s.transition(campaignID, []string{database.MailCampaignRunning}, database.MailCampaignPaused)
+}
+
+// Resume 从暂停的位置继续发送
+func (s *Service) Resume(campaignID string) error {
+	return s.transition(campaignID, []string{database.MailCampaignPaused}, database.MailCampaignRunning)
+}
+
+// Cancel 取消未完成的活动，已发出的邮件可以用批次ID回滚
+func (s *Service) Cancel(campaignID string) error {
+	return s.transition(campaignID, []string{database.MailCampaignRunning, database.MailCampaignPaused}, database.MailCampaignCancelled)
+}
+
+// transition 修改活动状态，活动不存在时返回ErrNotFound，状态不允许时返回ErrState
+func (s *Service) transition(campaignID string, from []string, to string) error {
+	if !primitive.IsValidObjectID(campaignID) {
+		return ErrNotFound
+	}
+	changed, err := s.store.SetStatus(campaignID, from, to)
+	if err != nil {
+		return err
+	}
+	if changed {
+		return nil
+	}
+
+	campaign, err := s.store.Get(campaignID)
+	if err != nil {
+		return err
+	}
+	if campaign == nil {
+		return ErrNotFound
+	}
+	return ErrState
+}
+
+// Stats 获取活动进度和已读、领取统计，活动不存在时返回ErrNotFound
+func (s *Service) Stats(campaignID string) (*Stats, error) {
+	if !primitive.IsValidObjectID(campaignID) {
+		return nil, ErrNotFound
+	}
+	campaign, err := s.store.Get(campaignID)
+	if err != nil {
+		return nil, err
+	}
+	if campaign == nil {
+		return nil, ErrNotFound
+	}
+
+	remaining, read, claimed, err := s.mails.BatchStats(campaign.BatchID())
+	if err != nil {
+		return nil, err
+	}
+	return &Stats{
+		MailCampaign: campaign,
+		Remaining:    remaining,
+		Read:         read,
+		Claimed:      claimed,
+	}, nil
+}
+
+// List 获取最近的活动
+func (s *Service) List(limit int64) ([]*database.MailCampaign, error) {
+	return s.store.List("", limit)
+}
@@ -0,0 +1,284 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/phuhao00/lufy/internal/errcode"
+	"github.com/phuhao00/lufy/internal/eventbus"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/rpc"
+)
+
+// defaultMaxDuration 规则未指定持续时间时的默认值，也是允许的最长时间
+const defaultMaxDuration = 10 * time.Minute
+
+// ErrInjected 注入的故障，只出现在日志中，返回给调用方的是errcode.Unavailable
+var ErrInjected = errors.New("chaos: injected failure")
+
+// Config 故障注入配置，生产环境应保持关闭
+type Config struct {
+	Enabled     bool          `yaml:"enabled"`      // 关闭时拒绝所有注入命令
+	MaxDuration time.Duration `yaml:"max_duration"` // 规则的最长持续时间，未配置时为10分钟
+}
+
+// Rules 故障注入规则，各比例为0到100
+type Rules struct {
+	LatencyPercent    int           // 增加延迟的RPC调用和消息处理比例
+	Latency           time.Duration // 增加的延迟
+	ErrorPercent      int           // 返回错误的RPC调用和消息处理比例
+	DropPercent       int           // 丢弃的消息比例，丢弃的消息视为已处理，不会重新投递
+	DisconnectPercent int           // 断开的客户端连接比例，按收到的消息计算
+	Services          []string      // 只影响这些RPC服务，为空时不限制
+	Topics            []string      // 只影响这些主题，不含命名空间，为空时不限制
+	Duration          time.Duration // 持续时间，0为配置的最长时间
+}
+
+// Active 是否有需要注入的故障
+func (r *Rules) Active() bool {
+	return r.LatencyPercent > 0 || r.ErrorPercent > 0 || r.DropPercent > 0 || r.DisconnectPercent > 0
+}
+
+// Validate 检查规则
+func (r *Rules) Validate() error {
+	for name, percent := range map[string]int{
+		"latency":    r.LatencyPercent,
+		"error":      r.ErrorPercent,
+		"drop":       r.DropPercent,
+		"disconnect": r.DisconnectPercent,
+	} {
+		if percent < 0 || percent > 100 {
+			return fmt.Errorf("chaos %s percent must be between 0 and 100", name)
+		}
+	}
+	if r.LatencyPercent > 0 && r.Latency <= 0 {
+		return fmt.Errorf("chaos latency is required when latency percent is set")
+	}
+	if r.Duration < 0 {
+		return fmt.Errorf("chaos duration must not be negative")
+	}
+	return nil
+}
+
+// String 规则摘要，用于日志
+func (r *Rules) String() string {
+	var parts []string
+	if r.LatencyPercent > 0 {
+		parts = append(parts, fmt.Sprintf("latency=%d%%:%v", r.LatencyPercent, r.Latency))
+	}
+	if r.ErrorPercent > 0 {
+		parts = append(parts, fmt.Sprintf("error=%d%%", r.ErrorPercent))
+	}
+	if r.DropPercent > 0 {
+		parts = append(parts, fmt.Sprintf("drop=%d%%", r.DropPercent))
+	}
+	if r.DisconnectPercent > 0 {
+		parts = append(parts, fmt.Sprintf("disconnect=%d%%", r.DisconnectPercent))
+	}
+	if len(r.Services) > 0 {
+		parts = append(parts, "services="+strings.Join(r.Services, ","))
+	}
+	if len(r.Topics) > 0 {
+		parts = append(parts, "topics="+strings.Join(r.Topics, ","))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Injector 按当前规则在RPC调用、消息处理和客户端连接上注入故障，用于验证重试、熔断等容错逻辑
+type Injector struct {
+	config    Config
+	rules     *Rules
+	expiresAt time.Time
+	exempt    map[string]bool // 不注入故障的主题，如系统命令，保证可以通过命令清除规则
+	random    *rand.Rand
+	mutex     sync.Mutex
+}
+
+// NewInjector 创建故障注入器，exempt为不注入故障的完整主题名
+func NewInjector(config *Config, exempt ...string) *Injector {
+	c := *config
+	if c.MaxDuration <= 0 {
+		c.MaxDuration = defaultMaxDuration
+	}
+	set := make(map[string]bool, len(exempt))
+	for _, topic := range exempt {
+		set[topic] = true
+	}
+	return &Injector{
+		config: c,
+		exempt: set,
+		random: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Apply 设置规则，替换之前的规则，规则不含任何故障时等同于Clear，返回规则的到期时间
+func (in *Injector) Apply(rules *Rules) (time.Time, error) {
+	if !rules.Active() {
+		in.Clear()
+		return time.Time{}, nil
+	}
+	if !in.config.Enabled {
+		return time.Time{}, fmt.Errorf("chaos injection is disabled on this node")
+	}
+	if err := rules.Validate(); err != nil {
+		return time.Time{}, err
+	}
+
+	duration := rules.Duration
+	if duration == 0 || duration > in.config.MaxDuration {
+		duration = in.config.MaxDuration
+	}
+	r := *rules
+	r.Duration = duration
+
+	in.mutex.Lock()
+	defer in.mutex.Unlock()
+
+	in.rules = &r
+	in.expiresAt = time.Now().Add(duration)
+	return in.expiresAt, nil
+}
+
+// Clear 清除规则
+func (in *Injector) Clear() {
+	in.mutex.Lock()
+	defer in.mutex.Unlock()
+
+	in.rules = nil
+	in.expiresAt = time.Time{}
+}
+
+// Current 当前生效的规则和到期时间，没有规则时返回nil
+func (in *Injector) Current() (*Rules, time.Time) {
+	in.mutex.Lock()
+	defer in.mutex.Unlock()
+
+	rules := in.active()
+	if rules == nil {
+		return nil, time.Time{}
+	}
+	r := *rules
+	return &r, in.expiresAt
+}
+
+// active 未到期的规则，需持有锁
+func (in *Injector) active() *Rules {
+	if in.rules == nil {
+		return nil
+	}
+	if time.Now().After(in.expiresAt) {
+		logger.Info(fmt.Sprintf("Chaos rules expired: %s", in.rules))
+		in.rules = nil
+		return nil
+	}
+	return in.rules
+}
+
+// fault 一次操作要注入的故障
+type fault struct {
+	latency    time.Duration
+	err        bool
+	drop       bool
+	disconnect bool
+}
+
+// roll 按规则为一次操作抽取故障，match判断规则是否适用于该操作
+func (in *Injector) roll(match func(r *Rules) bool) fault {
+	in.mutex.Lock()
+	defer in.mutex.Unlock()
+
+	r := in.active()
+	if r == nil || !match(r) {
+		return fault{}
+	}
+	var f fault
+	if in.hit(r.LatencyPercent) {
+		f.latency = r.Latency
+	}
+	f.err = in.hit(r.ErrorPercent)
+	f.drop = in.hit(r.DropPercent)
+	f.disconnect = in.hit(r.DisconnectPercent)
+	return f
+}
+
+// hit 按比例抽取，需持有锁
+func (in *Injector) hit(percent int) bool {
+	return percent > 0 && in.random.Intn(100) < percent
+}
+
+// Interceptor RPC拦截器，按规则增加延迟或返回错误，需放在统计拦截器内层，注入的延迟和错误计入统计
+func (in *Injector) Interceptor() rpc.Interceptor {
+	return func(ctx context.Context, call *rpc.CallInfo, next rpc.Handler) (proto.Message, error) {
+		f := in.roll(func(r *Rules) bool {
+			return matches(r.Services, call.Service)
+		})
+		if f.latency > 0 {
+			select {
+			case <-time.After(f.latency):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		if f.err {
+			return nil, errcode.Unavailable.Wrap(fmt.Errorf("%w in %s.%s", ErrInjected, call.Service, call.Method))
+		}
+		return next(ctx, call)
+	}
+}
+
+// WrapHandler 包装消息处理器，按规则增加延迟、丢弃消息或返回错误让消息重新投递，
+// 用于eventbus.DeferredBus.Intercept
+func (in *Injector) WrapHandler(topic, group string, handler eventbus.Handler) eventbus.Handler {
+	if in.exempt[topic] {
+		return handler
+	}
+	return eventbus.HandlerFunc(func(topic, group string, data []byte) error {
+		f := in.roll(func(r *Rules) bool {
+			return matches(r.Topics, bareTopic(topic))
+		})
+		if f.latency > 0 {
+			time.Sleep(f.latency)
+		}
+		if f.drop {
+			logger.Debug(fmt.Sprintf("Chaos dropped message on %s/%s", topic, group))
+			return nil
+		}
+		if f.err {
+			return fmt.Errorf("%w on %s/%s", ErrInjected, topic, group)
+		}
+		return handler.HandleMessage(topic, group, data)
+	})
+}
+
+// Disconnect 是否断开当前客户端连接，网关收到客户端消息时调用
+func (in *Injector) Disconnect() bool {
+	return in.roll(func(r *Rules) bool { return true }).disconnect
+}
+
+// matches names为空或包含name
+func matches(names []string, name string) bool {
+	if len(names) == 0 {
+		return true
+	}
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// bareTopic 去掉命名空间前缀的主题名
+func bareTopic(topic string) string {
+	if i := strings.LastIndex(topic, "."); i >= 0 {
+		return topic[i+1:]
+	}
+	return topic
+}
@@ -0,0 +1,274 @@
+package chatpolicy
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/tuning"
+)
+
+// 违规原因
+const (
+	ViolationRate  = "rate"  // 超过每分钟消息数
+	ViolationLevel = "level" // 等级不足
+	ViolationLink  = "link"  // 消息包含链接
+
+	ViolationProfanity = "profanity" // 消息包含屏蔽词
+)
+
+// Redis键和缓存设置
+const (
+	overridesKey     = "chat:policy"
+	overridesTTL     = 5 * time.Second // 各节点最多延迟这么久看到GM的修改
+	metricsRetention = 30 * 24 * time.Hour
+)
+
+// linkPattern 匹配网址和常见域名
+var linkPattern = regexp.MustCompile(`(?i)(https?://|www\.)\S+|\b[a-z0-9-]+\.(com|net|org|io|gg|me|co|cn|ru|xyz|top)\b`)
+
+// Policy 频道的发言限制，各项为0或false时不限制
+type Policy struct {
+	PerMinute  int   `yaml:"per_minute" json:"per_minute"`   // 每个玩家每分钟最多发送的消息数(慢速模式)
+	MinLevel   int32 `yaml:"min_level" json:"min_level"`     // 发言需要的最低等级
+	BlockLinks bool  `yaml:"block_links" json:"block_links"` // 禁止发送链接
+
+	BlockProfanity bool `yaml:"block_profanity" json:"block_profanity"` // 禁止发送包含屏蔽词的消息
+}
+
+// Validate 检查发言限制
+func (p *Policy) Validate() error {
+	if p.PerMinute < 0 {
+		return fmt.Errorf("per_minute must not be negative")
+	}
+	if p.MinLevel < 0 {
+		return fmt.Errorf("min_level must not be negative")
+	}
+	return nil
+}
+
+// Config 聊天频道发言限制配置，键为频道名：world、room、friend、guild
+type Config struct {
+	Channels     map[string]Policy `yaml:"channels"`
+	EditWindow   time.Duration     `yaml:"edit_window"`   // 发送后多久内可以编辑，0为不能编辑
+	RecallWindow time.Duration     `yaml:"recall_window"` // 发送后多久内可以撤回，0为不能撤回
+	Reactions    []int32           `yaml:"reactions"`     // 可用于回应消息的表情ID，为空时不能回应
+	BlockedWords []string          `yaml:"blocked_words"` // 屏蔽词，昵称和房间名同样不能包含
+
+	// 所有房间的聊天都只转发不保存，关闭时由创建房间时的设置决定
+	EphemeralRooms bool `yaml:"ephemeral_rooms"`
+}
+
+// Validate 检查各频道的发言限制
+func (c *Config) Validate() error {
+	if c.EditWindow < 0 || c.RecallWindow < 0 {
+		return fmt.Errorf("chat edit and recall windows must not be negative")
+	}
+	for _, emojiID := range c.Reactions {
+		if emojiID <= 0 {
+			return fmt.Errorf("invalid chat reaction emoji id: %d", emojiID)
+		}
+	}
+	for channel, policy := range c.Channels {
+		if err := policy.Validate(); err != nil {
+			return fmt.Errorf("invalid chat policy for channel %s: %v", channel, err)
+		}
+	}
+	return nil
+}
+
+// Service 在保存聊天消息之前检查频道的发言限制。
+// GM运行时的修改保存在Redis中，覆盖配置文件中的设置，所有聊天节点共享
+type Service struct {
+	config Config
+	redis  *database.RedisManager
+	words  *WordFilter
+
+	overrides map[string]Policy
+	loadedAt  time.Time
+	mutex     sync.Mutex
+}
+
+// NewService 创建频道发言限制服务
+func NewService(config *Config, redis *database.RedisManager) *Service {
+	return &Service{
+		config: *config,
+		redis:  redis,
+		words:  NewWordFilter(config.BlockedWords),
+	}
+}
+
+// Policy 获取频道当前的发言限制，GM修改过的频道使用修改后的设置
+func (s *Service) Policy(channel string) Policy {
+	if policy, ok := s.loadOverrides()[channel]; ok {
+		return policy
+	}
+	return s.config.Channels[channel]
+}
+
+// Policies 所有频道当前的发言限制
+func (s *Service) Policies() map[string]Policy {
+	policies := make(map[string]Policy, len(s.config.Channels))
+	for channel, policy := range s.config.Channels {
+		policies[channel] = policy
+	}
+	for channel, policy := range s.loadOverrides() {
+		policies[channel] = policy
+	}
+	return policies
+}
+
+// SetPolicy 修改频道的发言限制
+func (s *Service) SetPolicy(channel string, policy Policy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+	if err := s.redis.HSet(overridesKey, channel, string(data)); err != nil {
+		return fmt.Errorf("failed to save chat policy: %v", err)
+	}
+	s.invalidate()
+	return nil
+}
+
+// ResetPolicy 撤销GM的修改，频道恢复使用配置文件中的设置
+func (s *Service) ResetPolicy(channel string) error {
+	if err := s.redis.HDel(overridesKey, channel); err != nil {
+		return fmt.Errorf("failed to reset chat policy: %v", err)
+	}
+	s.invalidate()
+	return nil
+}
+
+// Words 屏蔽词过滤
+func (s *Service) Words() *WordFilter {
+	return s.words
+}
+
+// invalidate 清除本节点缓存的GM修改
+func (s *Service) invalidate() {
+	s.mutex.Lock()
+	s.loadedAt = time.Time{}
+	s.mutex.Unlock()
+}
+
+// EditDeadline 在now编辑消息时消息的最早发送时间，不能编辑时返回false
+func (s *Service) EditDeadline(now time.Time) (time.Time, bool) {
+	return now.Add(-s.config.EditWindow), s.config.EditWindow > 0
+}
+
+// RecallDeadline 在now撤回消息时消息的最早发送时间，不能撤回时返回false
+func (s *Service) RecallDeadline(now time.Time) (time.Time, bool) {
+	return now.Add(-s.config.RecallWindow), s.config.RecallWindow > 0
+}
+
+// ReactionAllowed 是否可以用该表情回应消息
+func (s *Service) ReactionAllowed(emojiID int32) bool {
+	for _, allowed := range s.config.Reactions {
+		if allowed == emojiID {
+			return true
+		}
+	}
+	return false
+}
+
+// loadOverrides 读取GM的修改，读取失败时继续使用上次的结果
+func (s *Service) loadOverrides() map[string]Policy {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if time.Since(s.loadedAt) < overridesTTL {
+		return s.overrides
+	}
+	values, err := s.redis.HGetAll(overridesKey)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Failed to load chat policy overrides: %v", err))
+		return s.overrides
+	}
+
+	overrides := make(map[string]Policy, len(values))
+	for channel, value := range values {
+		var policy Policy
+		if err := json.Unmarshal([]byte(value), &policy); err != nil {
+			logger.Warn(fmt.Sprintf("Invalid chat policy override for channel %s: %v", channel, err))
+			continue
+		}
+		overrides[channel] = policy
+	}
+	s.overrides = overrides
+	s.loadedAt = time.Now()
+	return overrides
+}
+
+// Check 检查玩家能否在频道中发送消息，违反限制时返回违规原因并记录统计
+func (s *Service) Check(channel string, userID uint64, level int32, content string, now time.Time) (string, error) {
+	policy := s.Policy(channel)
+
+	violation := ""
+	switch {
+	case level < policy.MinLevel:
+		violation = ViolationLevel
+	case policy.BlockLinks && linkPattern.MatchString(content):
+		violation = ViolationLink
+	case policy.BlockProfanity && s.words.Contains(content):
+		violation = ViolationProfanity
+	case policy.PerMinute > 0:
+		key := fmt.Sprintf("chat:rate:%s:%d:%d", channel, userID, now.Unix()/60)
+		count, err := s.redis.Incr(key)
+		if err != nil {
+			return "", fmt.Errorf("failed to count chat messages: %v", err)
+		}
+		if count == 1 {
+			s.redis.Expire(key, 2*time.Minute)
+		}
+		if count > tuning.ScaleLimit(int64(policy.PerMinute)) {
+			violation = ViolationRate
+		}
+	}
+
+	if violation != "" {
+		s.recordViolation(channel, violation, now)
+	}
+	return violation, nil
+}
+
+// recordViolation 记录全集群每日的违规统计
+func (s *Service) recordViolation(channel, violation string, now time.Time) {
+	key := metricsKey(now)
+	if _, err := s.redis.HIncrBy(key, channel+":"+violation, 1); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to record chat violation: %v", err))
+		return
+	}
+	s.redis.Expire(key, metricsRetention)
+}
+
+// GetMetrics 获取某天全集群的违规统计，键为 频道:违规原因
+func GetMetrics(redis *database.RedisManager, day time.Time) (map[string]int64, error) {
+	values, err := redis.HGetAll(metricsKey(day))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat violation metrics: %v", err)
+	}
+
+	metrics := make(map[string]int64, len(values))
+	for field, value := range values {
+		count, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		metrics[field] = count
+	}
+	return metrics, nil
+}
+
+// metricsKey 每日违规统计的键
+func metricsKey(day time.Time) string {
+	return "chat:violations:" + day.Format("20060102")
+}
@@ -0,0 +1,56 @@
+package chatpolicy
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// WordFilter 屏蔽词过滤，聊天消息、昵称和房间名共用同一份词表。
+// 匹配前统一全半角和大小写并去掉空白、标点，用分隔符拆开的屏蔽词同样能匹配
+type WordFilter struct {
+	words []string
+}
+
+// NewWordFilter 创建屏蔽词过滤，忽略规范化后为空的词
+func NewWordFilter(words []string) *WordFilter {
+	filter := &WordFilter{}
+	for _, word := range words {
+		if normalized := NormalizeWord(word); normalized != "" {
+			filter.words = append(filter.words, normalized)
+		}
+	}
+	return filter
+}
+
+// Contains 文本中是否包含屏蔽词
+func (f *WordFilter) Contains(text string) bool {
+	return f.Match(text) != ""
+}
+
+// Match 返回文本中包含的第一个屏蔽词(规范化后的形式)，不包含时返回空字符串
+func (f *WordFilter) Match(text string) string {
+	if f == nil || len(f.words) == 0 {
+		return ""
+	}
+	normalized := NormalizeWord(text)
+	for _, word := range f.words {
+		if strings.Contains(normalized, word) {
+			return word
+		}
+	}
+	return ""
+}
+
+// NormalizeWord 兼容分解后折叠大小写，只保留字母和数字
+func NormalizeWord(text string) string {
+	folded := cases.Fold().String(norm.NFKC.String(text))
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			return r
+		}
+		return -1
+	}, folded)
+}
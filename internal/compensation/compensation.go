@@ -0,0 +1,283 @@
+package compensation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// Config 补偿回滚配置
+type Config struct {
+	NotifyTitle   string        `yaml:"notify_title"`   // 通知邮件标题
+	NotifyContent string        `yaml:"notify_content"` // 通知邮件正文，%s替换为回滚明细
+	NotifyExpire  time.Duration `yaml:"notify_expire"`  // 通知邮件有效期
+}
+
+// LedgerStore 账本存储，database.LedgerRepository实现了该接口
+type LedgerStore interface {
+	GetByBatch(batchID string) ([]*database.LedgerEntry, error)
+	Reverse(entry *database.LedgerEntry, batchID, reason string) (bool, error)
+}
+
+// MailStore 邮件存储，在发放邮件之外按批次查询和撤回邮件，database.MailRepository实现了该接口
+type MailStore interface {
+	database.MailStore
+	GetMailsByBatch(batchID string) ([]*database.Mail, error)
+	RevokeMail(mailID uint64) (bool, error)
+}
+
+// 单项处理结果
+const (
+	StatusReversed        = "reversed"         // 流水已冲正
+	StatusRevoked         = "revoked"          // 未领取的邮件已撤回
+	StatusWouldReverse    = "would_reverse"    // 预览: 将冲正
+	StatusWouldRevoke     = "would_revoke"     // 预览: 将撤回
+	StatusAlreadyReversed = "already_reversed" // 之前已冲正
+	StatusClaimed         = "claimed"          // 邮件附件已领取，无法自动撤回，需人工处理
+	StatusFailed          = "failed"
+)
+
+// TransactionResult 单条流水的回滚结果
+type TransactionResult struct {
+	TxID     string `json:"tx_id"`
+	Currency string `json:"currency"`
+	Amount   int64  `json:"amount"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+// MailResult 单封邮件的回滚结果
+type MailResult struct {
+	MailID  uint64                `json:"mail_id"`
+	Title   string                `json:"title"`
+	Rewards []database.MailReward `json:"rewards"`
+	Status  string                `json:"status"`
+	Error   string                `json:"error,omitempty"`
+}
+
+// UserResult 单个玩家的回滚结果
+type UserResult struct {
+	UserID       uint64              `json:"user_id"`
+	Transactions []TransactionResult `json:"transactions,omitempty"`
+	Mails        []MailResult        `json:"mails,omitempty"`
+	Notified     bool                `json:"notified"`
+	NotifyError  string              `json:"notify_error,omitempty"`
+}
+
+// Report 回滚报告
+type Report struct {
+	BatchID         string           `json:"batch_id"`
+	RollbackBatchID string           `json:"rollback_batch_id"` // 冲正流水和通知邮件的批次ID
+	DryRun          bool             `json:"dry_run"`
+	Users           []*UserResult    `json:"users"`
+	Reversed        map[string]int64 `json:"reversed"` // 按货币汇总的冲正金额
+	RevokedMails    int              `json:"revoked_mails"`
+	ClaimedMails    int              `json:"claimed_mails"`
+	Failures        int              `json:"failures"`
+}
+
+// Rollback 发放回滚工具，按批次ID冲正账本流水并撤回未领取的邮件附件
+// 每项操作都是条件更新，中途失败后可以安全地重新执行，已处理的项会标记为already_reversed或被跳过
+type Rollback struct {
+	config  Config
+	ledger  LedgerStore
+	mails   MailStore
+	mailIDs database.MailIDGenerator
+}
+
+// NewRollback 创建发放回滚工具
+func NewRollback(config *Config, ledger LedgerStore, mails MailStore, mailIDs database.MailIDGenerator) *Rollback {
+	cfg := *config
+	if cfg.NotifyTitle == "" {
+		cfg.NotifyTitle = "奖励发放更正通知"
+	}
+	if cfg.NotifyContent == "" {
+		cfg.NotifyContent = "由于发放错误，以下奖励已被收回:\n%s\n给您带来不便，敬请谅解。"
+	}
+	if cfg.NotifyExpire <= 0 {
+		cfg.NotifyExpire = 30 * 24 * time.Hour
+	}
+
+	return &Rollback{
+		config:  cfg,
+		ledger:  ledger,
+		mails:   mails,
+		mailIDs: mailIDs,
+	}
+}
+
+// RollbackBatchID 回滚产生的冲正流水和通知邮件所属的批次
+func RollbackBatchID(batchID string) string {
+	return "rollback:" + batchID
+}
+
+// Run 回滚批次，dryRun时只预览不修改；notify时给实际被回滚的玩家发送通知邮件
+func (r *Rollback) Run(batchID, reason string, dryRun, notify bool) (*Report, error) {
+	if batchID == "" {
+		return nil, fmt.Errorf("batch id is required")
+	}
+	if strings.HasPrefix(batchID, "rollback:") {
+		return nil, fmt.Errorf("cannot roll back a rollback batch")
+	}
+
+	entries, err := r.ledger.GetByBatch(batchID)
+	if err != nil {
+		return nil, err
+	}
+	mails, err := r.mails.GetMailsByBatch(batchID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		BatchID:         batchID,
+		RollbackBatchID: RollbackBatchID(batchID),
+		DryRun:          dryRun,
+		Reversed:        make(map[string]int64),
+	}
+	users := make(map[uint64]*UserResult)
+	userResult := func(userID uint64) *UserResult {
+		result, exists := users[userID]
+		if !exists {
+			result = &UserResult{UserID: userID}
+			users[userID] = result
+		}
+		return result
+	}
+
+	for _, entry := range entries {
+		result := r.reverseEntry(entry, report.RollbackBatchID, reason, dryRun)
+		if result.Status == StatusReversed || result.Status == StatusWouldReverse {
+			report.Reversed[entry.Currency] += entry.Amount
+		}
+		if result.Status == StatusFailed {
+			report.Failures++
+		}
+		user := userResult(entry.UserID)
+		user.Transactions = append(user.Transactions, result)
+	}
+
+	for _, mail := range mails {
+		result := r.revokeMail(mail, dryRun)
+		switch result.Status {
+		case StatusRevoked, StatusWouldRevoke:
+			report.RevokedMails++
+		case StatusClaimed:
+			report.ClaimedMails++
+		case StatusFailed:
+			report.Failures++
+		}
+		user := userResult(mail.ToUserID)
+		user.Mails = append(user.Mails, result)
+	}
+
+	for _, user := range users {
+		report.Users = append(report.Users, user)
+	}
+	sort.Slice(report.Users, func(i, j int) bool { return report.Users[i].UserID < report.Users[j].UserID })
+
+	if !dryRun && notify {
+		for _, user := range report.Users {
+			r.notify(user, report.RollbackBatchID)
+		}
+	}
+
+	logger.Info(fmt.Sprintf("Rollback of batch %s (dry run %v): %d users, reversed %v, %d mails revoked, %d claimed, %d failures",
+		batchID, dryRun, len(report.Users), report.Reversed, report.RevokedMails, report.ClaimedMails, report.Failures))
+	return report, nil
+}
+
+// reverseEntry 冲正单条流水
+func (r *Rollback) reverseEntry(entry *database.LedgerEntry, rollbackBatchID, reason string, dryRun bool) TransactionResult {
+	result := TransactionResult{TxID: entry.TxID, Currency: entry.Currency, Amount: entry.Amount}
+
+	if entry.ReversedBy != "" {
+		result.Status = StatusAlreadyReversed
+		return result
+	}
+	if dryRun {
+		result.Status = StatusWouldReverse
+		return result
+	}
+
+	reversed, err := r.ledger.Reverse(entry, rollbackBatchID, reason)
+	switch {
+	case err != nil:
+		result.Status = StatusFailed
+		result.Error = err.Error()
+	case reversed:
+		result.Status = StatusReversed
+	default:
+		result.Status = StatusAlreadyReversed
+	}
+	return result
+}
+
+// revokeMail 撤回单封邮件，已领取的附件无法撤回
+func (r *Rollback) revokeMail(mail *database.Mail, dryRun bool) MailResult {
+	result := MailResult{MailID: mail.MailID, Title: mail.Title, Rewards: mail.Rewards}
+
+	if mail.IsClaimed {
+		result.Status = StatusClaimed
+		return result
+	}
+	if dryRun {
+		result.Status = StatusWouldRevoke
+		return result
+	}
+
+	revoked, err := r.mails.RevokeMail(mail.MailID)
+	switch {
+	case err != nil:
+		result.Status = StatusFailed
+		result.Error = err.Error()
+	case revoked:
+		result.Status = StatusRevoked
+	default:
+		// 查询后玩家领取了附件
+		result.Status = StatusClaimed
+	}
+	return result
+}
+
+// notify 给本次被回滚的玩家发送通知邮件，重新执行时之前已处理的项不会再次通知
+func (r *Rollback) notify(user *UserResult, rollbackBatchID string) {
+	var lines []string
+	for _, tx := range user.Transactions {
+		if tx.Status == StatusReversed {
+			lines = append(lines, fmt.Sprintf("%s x%d", tx.Currency, tx.Amount))
+		}
+	}
+	for _, mail := range user.Mails {
+		if mail.Status == StatusRevoked {
+			lines = append(lines, fmt.Sprintf("邮件《%s》的附件", mail.Title))
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+
+	mailID, err := r.mailIDs()
+	if err != nil {
+		user.NotifyError = err.Error()
+		return
+	}
+
+	now := time.Now()
+	if err := r.mails.CreateMail(&database.Mail{
+		MailID:   mailID,
+		ToUserID: user.UserID,
+		Title:    r.config.NotifyTitle,
+		Content:  fmt.Sprintf(r.config.NotifyContent, strings.Join(lines, "\n")),
+		BatchID:  rollbackBatchID,
+		ExpireAt: now.Add(r.config.NotifyExpire),
+	}); err != nil {
+		user.NotifyError = err.Error()
+		return
+	}
+	user.Notified = true
+}
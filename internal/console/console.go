@@ -0,0 +1,453 @@
+package console
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// Config 管理控制台配置
+type Config struct {
+	Enabled        bool            `yaml:"enabled"`
+	Address        string          `yaml:"address"`         // 监听地址，如127.0.0.1:2222
+	HostKeyFile    string          `yaml:"host_key_file"`   // SSH主机私钥，不存在时自动生成
+	AuthorizedKeys []AuthorizedKey `yaml:"authorized_keys"` // 允许登录的操作员公钥
+	HistorySize    int             `yaml:"history_size"`    // 每个会话保留的历史命令数
+	IdleTimeout    time.Duration   `yaml:"idle_timeout"`    // 无输入超时后断开
+	Prompt         string          `yaml:"prompt"`
+}
+
+// AuthorizedKey 操作员公钥
+type AuthorizedKey struct {
+	Name       string `yaml:"name"`
+	PublicKey  string `yaml:"public_key"`  // authorized_keys格式，如"ssh-ed25519 AAAA..."
+	OperatorID uint64 `yaml:"operator_id"` // 审计日志中记录的操作员ID
+}
+
+// Operator 已认证的操作员
+type Operator struct {
+	Name       string
+	ID         uint64
+	RemoteAddr string
+}
+
+// Handler 控制台命令处理
+type Handler interface {
+	// Commands 可用命令及用法，用于Tab补全和help
+	Commands() map[string]string
+	// Execute 执行命令
+	Execute(operator *Operator, command string, args []string) (string, error)
+	// Audit 记录审计日志
+	Audit(operator *Operator, action, details string)
+}
+
+// 权限扩展字段，认证时写入，会话中读取操作员信息
+const (
+	extensionOperator   = "operator"
+	extensionOperatorID = "operator_id"
+)
+
+// Console 基于SSH的交互式管理控制台
+type Console struct {
+	config    Config
+	handler   Handler
+	sshConfig *ssh.ServerConfig
+	listener  net.Listener
+	conns     map[net.Conn]struct{}
+	wg        sync.WaitGroup
+	mutex     sync.Mutex
+	closed    bool
+}
+
+// NewConsole 创建管理控制台
+func NewConsole(config *Config, handler Handler) (*Console, error) {
+	cfg := *config
+	if cfg.HistorySize <= 0 {
+		cfg.HistorySize = 200
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = 15 * time.Minute
+	}
+	if cfg.Prompt == "" {
+		cfg.Prompt = "gm> "
+	}
+
+	keys := make(map[string]AuthorizedKey, len(cfg.AuthorizedKeys))
+	for _, authorized := range cfg.AuthorizedKeys {
+		publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(authorized.PublicKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key for %s: %v", authorized.Name, err)
+		}
+		keys[string(publicKey.Marshal())] = authorized
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no authorized keys configured")
+	}
+
+	hostKey, err := loadHostKey(cfg.HostKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	sshConfig := &ssh.ServerConfig{
+		PublicKeyCallback: func(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			authorized, exists := keys[string(key.Marshal())]
+			if !exists {
+				logger.Warn(fmt.Sprintf("Console login rejected from %s: unknown key %s",
+					meta.RemoteAddr(), ssh.FingerprintSHA256(key)))
+				return nil, fmt.Errorf("unknown public key")
+			}
+			return &ssh.Permissions{Extensions: map[string]string{
+				extensionOperator:   authorized.Name,
+				extensionOperatorID: strconv.FormatUint(authorized.OperatorID, 10),
+			}}, nil
+		},
+	}
+	sshConfig.AddHostKey(hostKey)
+
+	return &Console{
+		config:    cfg,
+		handler:   handler,
+		sshConfig: sshConfig,
+		conns:     make(map[net.Conn]struct{}),
+	}, nil
+}
+
+// loadHostKey 读取主机私钥，文件不存在时生成ed25519密钥并保存，保证重启后指纹不变
+func loadHostKey(path string) (ssh.Signer, error) {
+	if path == "" {
+		return nil, fmt.Errorf("host key file is required")
+	}
+
+	data, err := os.ReadFile(path)
+	if err == nil {
+		signer, err := ssh.ParsePrivateKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse host key %s: %v", path, err)
+		}
+		return signer, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read host key %s: %v", path, err)
+	}
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate host key: %v", err)
+	}
+	block, err := ssh.MarshalPrivateKey(privateKey, "lufy gm console")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal host key: %v", err)
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write host key %s: %v", path, err)
+	}
+
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create host key signer: %v", err)
+	}
+	logger.Info(fmt.Sprintf("Generated console host key %s (%s)", path, ssh.FingerprintSHA256(signer.PublicKey())))
+	return signer, nil
+}
+
+// Start 开始监听
+func (c *Console) Start() error {
+	listener, err := net.Listen("tcp", c.config.Address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", c.config.Address, err)
+	}
+	c.listener = listener
+
+	c.wg.Add(1)
+	go c.acceptLoop()
+
+	logger.Info(fmt.Sprintf("GM console listening on %s", listener.Addr()))
+	return nil
+}
+
+// Addr 获取监听地址
+func (c *Console) Addr() net.Addr {
+	return c.listener.Addr()
+}
+
+// Stop 停止监听并断开所有会话
+func (c *Console) Stop() {
+	c.mutex.Lock()
+	c.closed = true
+	if c.listener != nil {
+		c.listener.Close()
+	}
+	for conn := range c.conns {
+		conn.Close()
+	}
+	c.mutex.Unlock()
+
+	c.wg.Wait()
+}
+
+// acceptLoop 接受连接
+func (c *Console) acceptLoop() {
+	defer c.wg.Done()
+
+	for {
+		conn, err := c.listener.Accept()
+		if err != nil {
+			c.mutex.Lock()
+			closed := c.closed
+			c.mutex.Unlock()
+			if closed {
+				return
+			}
+			logger.Error(fmt.Sprintf("Console accept error: %v", err))
+			continue
+		}
+
+		c.mutex.Lock()
+		if c.closed {
+			c.mutex.Unlock()
+			conn.Close()
+			return
+		}
+		c.conns[conn] = struct{}{}
+		c.wg.Add(1)
+		c.mutex.Unlock()
+
+		go c.handleConn(conn)
+	}
+}
+
+// idleConn 每次读取前刷新超时，长时间无输入的会话自动断开
+type idleConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (ic *idleConn) Read(p []byte) (int, error) {
+	ic.Conn.SetReadDeadline(time.Now().Add(ic.timeout))
+	return ic.Conn.Read(p)
+}
+
+// handleConn 处理一个SSH连接
+func (c *Console) handleConn(conn net.Conn) {
+	defer c.wg.Done()
+	defer func() {
+		conn.Close()
+		c.mutex.Lock()
+		delete(c.conns, conn)
+		c.mutex.Unlock()
+	}()
+
+	serverConn, channels, requests, err := ssh.NewServerConn(&idleConn{Conn: conn, timeout: c.config.IdleTimeout}, c.sshConfig)
+	if err != nil {
+		logger.Debug(fmt.Sprintf("Console handshake with %s failed: %v", conn.RemoteAddr(), err))
+		return
+	}
+	defer serverConn.Close()
+
+	operatorID, _ := strconv.ParseUint(serverConn.Permissions.Extensions[extensionOperatorID], 10, 64)
+	operator := &Operator{
+		Name:       serverConn.Permissions.Extensions[extensionOperator],
+		ID:         operatorID,
+		RemoteAddr: conn.RemoteAddr().String(),
+	}
+
+	c.handler.Audit(operator, "console_login", fmt.Sprintf("来源: %s", operator.RemoteAddr))
+	defer c.handler.Audit(operator, "console_logout", fmt.Sprintf("来源: %s", operator.RemoteAddr))
+
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "only session channels are supported")
+			continue
+		}
+
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			logger.Warn(fmt.Sprintf("Console failed to accept channel: %v", err))
+			continue
+		}
+
+		c.handleSession(operator, channel, channelRequests)
+	}
+}
+
+// handleSession 处理会话，shell请求进入交互模式，exec请求执行单条命令
+func (c *Console) handleSession(operator *Operator, channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer channel.Close()
+
+	for req := range requests {
+		switch req.Type {
+		case "pty-req", "env", "window-change":
+			req.Reply(true, nil)
+
+		case "shell":
+			req.Reply(true, nil)
+			go ssh.DiscardRequests(requests)
+			c.repl(operator, channel)
+			sendExitStatus(channel, 0)
+			return
+
+		case "exec":
+			command := parseExecPayload(req.Payload)
+			req.Reply(true, nil)
+			go ssh.DiscardRequests(requests)
+			term := newTerminal(channel, "", 0, nil)
+			status := uint32(0)
+			if !c.run(operator, term, command) {
+				status = 1
+			}
+			sendExitStatus(channel, status)
+			return
+
+		default:
+			req.Reply(false, nil)
+		}
+	}
+}
+
+// parseExecPayload 解析exec请求中的命令字符串
+func parseExecPayload(payload []byte) string {
+	if len(payload) < 4 {
+		return ""
+	}
+	length := binary.BigEndian.Uint32(payload)
+	if int(length) > len(payload)-4 {
+		return ""
+	}
+	return strings.TrimSpace(string(payload[4 : 4+length]))
+}
+
+// sendExitStatus 发送命令退出码
+func sendExitStatus(channel ssh.Channel, status uint32) {
+	payload := make([]byte, 4)
+	binary.BigEndian.PutUint32(payload, status)
+	channel.SendRequest("exit-status", false, payload)
+}
+
+// repl 交互式命令循环
+func (c *Console) repl(operator *Operator, channel ssh.Channel) {
+	term := newTerminal(channel, c.config.Prompt, c.config.HistorySize, c.completer())
+	fmt.Fprintf(term, "欢迎 %s，输入help查看可用命令，exit退出\n", operator.Name)
+
+	for {
+		line, err := term.ReadLine()
+		if err == errInterrupted {
+			continue
+		}
+		if err != nil {
+			if err != io.EOF {
+				logger.Debug(fmt.Sprintf("Console session of %s ended: %v", operator.Name, err))
+			}
+			return
+		}
+
+		switch line {
+		case "":
+			continue
+		case "exit", "quit":
+			return
+		case "history":
+			for i, entry := range term.History() {
+				fmt.Fprintf(term, "%4d  %s\n", i+1, entry)
+			}
+			continue
+		}
+
+		c.run(operator, term, line)
+	}
+}
+
+// run 执行一条命令并输出结果，返回是否成功
+func (c *Console) run(operator *Operator, out io.Writer, line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return true
+	}
+
+	command, args := strings.ToLower(fields[0]), fields[1:]
+	if command == "help" {
+		fmt.Fprint(out, c.help())
+		return true
+	}
+
+	result, err := c.handler.Execute(operator, command, args)
+	details := fmt.Sprintf("命令: %s, 参数: %v", command, args)
+	if err != nil {
+		c.handler.Audit(operator, "console_command", fmt.Sprintf("%s, 错误: %v", details, err))
+		fmt.Fprintf(out, "错误: %v\n", err)
+		return false
+	}
+
+	c.handler.Audit(operator, "console_command", fmt.Sprintf("%s, 结果: %s", details, result))
+	if result != "" {
+		fmt.Fprintln(out, result)
+	}
+	return true
+}
+
+// builtinCommands 控制台内置命令
+var builtinCommands = map[string]string{
+	"help":    "help - 查看可用命令",
+	"history": "history - 查看本会话的历史命令",
+	"exit":    "exit - 退出控制台",
+}
+
+// commandNames 所有命令名
+func (c *Console) commandNames() []string {
+	names := make([]string, 0)
+	for name := range c.handler.Commands() {
+		names = append(names, name)
+	}
+	for name := range builtinCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// completer 命令名补全，只补全第一个词
+func (c *Console) completer() func(line string) []string {
+	names := c.commandNames()
+	return func(line string) []string {
+		if strings.Contains(line, " ") {
+			return nil
+		}
+		candidates := make([]string, 0)
+		for _, name := range names {
+			if strings.HasPrefix(name, line) {
+				candidates = append(candidates, name+" ")
+			}
+		}
+		return candidates
+	}
+}
+
+// help 命令说明
+func (c *Console) help() string {
+	commands := c.handler.Commands()
+	var b strings.Builder
+	for _, name := range c.commandNames() {
+		usage, exists := commands[name]
+		if !exists {
+			usage = builtinCommands[name]
+		}
+		b.WriteString("  " + usage + "\n")
+	}
+	return b.String()
+}
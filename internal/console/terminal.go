@@ -0,0 +1,275 @@
+package console
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// 终端控制字符
+const (
+	keyCtrlC     = 0x03
+	keyCtrlD     = 0x04
+	keyBackspace = 0x08
+	keyTab       = 0x09
+	keyLF        = 0x0a
+	keyCR        = 0x0d
+	keyCtrlU     = 0x15
+	keyEscape    = 0x1b
+	keyDelete    = 0x7f
+)
+
+// errInterrupted 用户按下Ctrl-C放弃当前输入
+var errInterrupted = errors.New("interrupted")
+
+// terminal 简单的行编辑器，支持退格、Tab补全和上下键翻阅历史
+//
+// 光标始终位于行尾，每次编辑后重绘整行，不需要处理宽字符的列宽
+type terminal struct {
+	rw       io.ReadWriter
+	prompt   string
+	complete func(line string) []string // 返回补全后的候选整行
+
+	history     []string
+	historySize int
+
+	buf     []byte // 未处理的输入
+	readBuf [256]byte
+	skipLF  bool // 上一行以CR结束，紧随的LF不再作为空行
+}
+
+// newTerminal 创建行编辑器
+func newTerminal(rw io.ReadWriter, prompt string, historySize int, complete func(line string) []string) *terminal {
+	return &terminal{
+		rw:          rw,
+		prompt:      prompt,
+		complete:    complete,
+		historySize: historySize,
+	}
+}
+
+// Write 输出内容，把换行转换为终端需要的回车换行
+func (t *terminal) Write(data []byte) (int, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	if _, err := io.WriteString(t.rw, strings.ReplaceAll(text, "\n", "\r\n")); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// History 获取历史命令，越新越靠后
+func (t *terminal) History() []string {
+	return append([]string(nil), t.history...)
+}
+
+// ReadLine 读取一行输入，连接关闭或空行按下Ctrl-D时返回io.EOF
+func (t *terminal) ReadLine() (string, error) {
+	line := []rune{}
+	historyIndex := len(t.history)
+	draft := ""
+
+	t.redraw(line)
+	for {
+		key, r, err := t.readKey()
+		if err != nil {
+			return "", err
+		}
+
+		skipLF := t.skipLF
+		t.skipLF = false
+		if key == keyLF && skipLF {
+			continue
+		}
+
+		switch key {
+		case keyCR, keyLF:
+			t.skipLF = key == keyCR
+			io.WriteString(t.rw, "\r\n")
+			text := strings.TrimSpace(string(line))
+			t.addHistory(text)
+			return text, nil
+
+		case keyCtrlC:
+			io.WriteString(t.rw, "^C\r\n")
+			return "", errInterrupted
+
+		case keyCtrlD:
+			if len(line) == 0 {
+				io.WriteString(t.rw, "\r\n")
+				return "", io.EOF
+			}
+
+		case keyCtrlU:
+			line = line[:0]
+
+		case keyBackspace, keyDelete:
+			if len(line) > 0 {
+				line = line[:len(line)-1]
+			}
+
+		case keyTab:
+			line = t.completeLine(line)
+
+		case keyUp:
+			if historyIndex > 0 {
+				if historyIndex == len(t.history) {
+					draft = string(line)
+				}
+				historyIndex--
+				line = []rune(t.history[historyIndex])
+			}
+
+		case keyDown:
+			if historyIndex < len(t.history) {
+				historyIndex++
+				if historyIndex == len(t.history) {
+					line = []rune(draft)
+				} else {
+					line = []rune(t.history[historyIndex])
+				}
+			}
+
+		case keyRune:
+			line = append(line, r)
+
+		default:
+			continue
+		}
+
+		t.redraw(line)
+	}
+}
+
+// completeLine Tab补全，唯一候选时直接补全，多个候选时补全公共前缀并列出候选
+func (t *terminal) completeLine(line []rune) []rune {
+	if t.complete == nil {
+		return line
+	}
+
+	candidates := t.complete(string(line))
+	switch len(candidates) {
+	case 0:
+		return line
+	case 1:
+		return []rune(candidates[0])
+	}
+
+	prefix := commonPrefix(candidates)
+	if len(prefix) > len(string(line)) {
+		return []rune(prefix)
+	}
+
+	// 只显示候选的最后一个词
+	words := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		fields := strings.Fields(candidate)
+		if len(fields) > 0 {
+			words = append(words, fields[len(fields)-1])
+		}
+	}
+	sort.Strings(words)
+	io.WriteString(t.rw, "\r\n"+strings.Join(words, "  ")+"\r\n")
+	return line
+}
+
+// redraw 重绘提示符和当前输入
+func (t *terminal) redraw(line []rune) {
+	io.WriteString(t.rw, "\r\x1b[K"+t.prompt+string(line))
+}
+
+// addHistory 追加历史，忽略空行和与上一条相同的命令
+func (t *terminal) addHistory(line string) {
+	if line == "" || (len(t.history) > 0 && t.history[len(t.history)-1] == line) {
+		return
+	}
+	t.history = append(t.history, line)
+	if t.historySize > 0 && len(t.history) > t.historySize {
+		t.history = t.history[len(t.history)-t.historySize:]
+	}
+}
+
+// 按键类型，控制字符直接使用字符值
+const (
+	keyRune = 0x100 + iota
+	keyUp
+	keyDown
+	keyUnknown
+)
+
+// readKey 读取一个按键
+func (t *terminal) readKey() (int, rune, error) {
+	for {
+		if key, r, n := parseKey(t.buf); n > 0 {
+			t.buf = t.buf[n:]
+			return key, r, nil
+		}
+
+		n, err := t.rw.Read(t.readBuf[:])
+		if n > 0 {
+			t.buf = append(t.buf, t.readBuf[:n]...)
+			continue
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+}
+
+// parseKey 从输入中解析一个按键，返回消耗的字节数，输入不完整时返回0
+func parseKey(buf []byte) (int, rune, int) {
+	if len(buf) == 0 {
+		return 0, 0, 0
+	}
+
+	if buf[0] == keyEscape {
+		if len(buf) < 2 {
+			return 0, 0, 0
+		}
+		if buf[1] == '[' || buf[1] == 'O' {
+			if len(buf) < 3 {
+				return 0, 0, 0
+			}
+			switch buf[2] {
+			case 'A':
+				return keyUp, 0, 3
+			case 'B':
+				return keyDown, 0, 3
+			}
+			// 其他控制序列以字母或~结尾，整体忽略
+			for i := 2; i < len(buf); i++ {
+				if (buf[i] >= 'A' && buf[i] <= 'Z') || (buf[i] >= 'a' && buf[i] <= 'z') || buf[i] == '~' {
+					return keyUnknown, 0, i + 1
+				}
+			}
+			return 0, 0, 0
+		}
+		return keyUnknown, 0, 1
+	}
+
+	if buf[0] < 0x20 || buf[0] == keyDelete {
+		return int(buf[0]), 0, 1
+	}
+
+	if !utf8.FullRune(buf) {
+		return 0, 0, 0
+	}
+	r, size := utf8.DecodeRune(buf)
+	return keyRune, r, size
+}
+
+// commonPrefix 候选的公共前缀
+func commonPrefix(values []string) string {
+	prefix := values[0]
+	for _, value := range values[1:] {
+		for !strings.HasPrefix(value, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	// 避免截断在多字节字符中间
+	for len(prefix) > 0 && !utf8.ValidString(prefix) {
+		prefix = prefix[:len(prefix)-1]
+	}
+	return prefix
+}
@@ -0,0 +1,132 @@
+package crash
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// Config 崩溃上报配置
+type Config struct {
+	DSN         string        `yaml:"dsn"` // Sentry兼容的DSN，为空时只记录日志
+	Environment string        `yaml:"environment"`
+	Release     string        `yaml:"release"`
+	Timeout     time.Duration `yaml:"timeout"`    // 单次上报超时
+	QueueSize   int           `yaml:"queue_size"` // 待上报队列长度，满时丢弃
+}
+
+// Report 崩溃报告
+type Report struct {
+	Source    string // 发生位置，如rpc、tcp
+	Value     interface{}
+	Stack     []byte
+	Tags      map[string]string
+	Timestamp time.Time
+}
+
+// Reporter 崩溃报告器，记录panic堆栈并异步上报
+type Reporter struct {
+	config   Config
+	nodeType string
+	nodeID   string
+	sender   *sentrySender
+	queue    chan *Report
+	hooks    []func(report *Report)
+	total    int64
+	dropped  int64
+	mutex    sync.RWMutex
+}
+
+// NewReporter 创建崩溃报告器
+func NewReporter(config *Config, nodeType, nodeID string) (*Reporter, error) {
+	cfg := *config
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 100
+	}
+
+	r := &Reporter{
+		config:   cfg,
+		nodeType: nodeType,
+		nodeID:   nodeID,
+	}
+
+	if cfg.DSN != "" {
+		sender, err := newSentrySender(cfg.DSN, cfg.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		r.sender = sender
+		r.queue = make(chan *Report, cfg.QueueSize)
+		go r.sendLoop()
+	}
+
+	return r, nil
+}
+
+// OnPanic 注册panic回调，用于记录指标等
+func (r *Reporter) OnPanic(hook func(report *Report)) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.hooks = append(r.hooks, hook)
+}
+
+// Capture 记录一次已恢复的panic
+func (r *Reporter) Capture(source string, value interface{}, stack []byte, tags map[string]string) {
+	if stack == nil {
+		stack = debug.Stack()
+	}
+
+	report := &Report{
+		Source:    source,
+		Value:     value,
+		Stack:     stack,
+		Tags:      tags,
+		Timestamp: time.Now(),
+	}
+
+	atomic.AddInt64(&r.total, 1)
+	logger.Error(fmt.Sprintf("Recovered panic in %s %v: %v\n%s", source, tags, value, stack))
+
+	r.mutex.RLock()
+	hooks := r.hooks
+	r.mutex.RUnlock()
+	for _, hook := range hooks {
+		hook(report)
+	}
+
+	if r.queue == nil {
+		return
+	}
+	select {
+	case r.queue <- report:
+	default:
+		atomic.AddInt64(&r.dropped, 1)
+	}
+}
+
+// sendLoop 上报循环
+func (r *Reporter) sendLoop() {
+	for report := range r.queue {
+		event := newSentryEvent(report, r.nodeType, r.nodeID, r.config.Environment, r.config.Release)
+		if err := r.sender.send(event); err != nil {
+			logger.Warn(fmt.Sprintf("Failed to send crash report: %v", err))
+		}
+	}
+}
+
+// GetStats 获取统计信息
+func (r *Reporter) GetStats() map[string]interface{} {
+	return map[string]interface{}{
+		"panics":    atomic.LoadInt64(&r.total),
+		"dropped":   atomic.LoadInt64(&r.dropped),
+		"reporting": r.sender != nil,
+	}
+}
@@ -0,0 +1,125 @@
+package crash
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// sentrySender 通过Sentry store接口上报事件，兼容Sentry协议的服务均可使用
+type sentrySender struct {
+	endpoint  string
+	publicKey string
+	client    *http.Client
+}
+
+// newSentrySender 解析DSN，格式为 scheme://public_key@host[/path]/project_id
+func newSentrySender(dsn string, timeout time.Duration) (*sentrySender, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid crash report dsn: %v", err)
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return nil, fmt.Errorf("crash report dsn missing public key")
+	}
+
+	path := strings.Trim(u.Path, "/")
+	idx := strings.LastIndex(path, "/")
+	projectID := path[idx+1:]
+	if projectID == "" {
+		return nil, fmt.Errorf("crash report dsn missing project id")
+	}
+	prefix := ""
+	if idx >= 0 {
+		prefix = "/" + path[:idx]
+	}
+
+	return &sentrySender{
+		endpoint:  fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, prefix, projectID),
+		publicKey: u.User.Username(),
+		client:    &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// sentryEvent Sentry事件
+type sentryEvent struct {
+	EventID     string                 `json:"event_id"`
+	Timestamp   string                 `json:"timestamp"`
+	Level       string                 `json:"level"`
+	Platform    string                 `json:"platform"`
+	Logger      string                 `json:"logger"`
+	ServerName  string                 `json:"server_name"`
+	Environment string                 `json:"environment,omitempty"`
+	Release     string                 `json:"release,omitempty"`
+	Message     string                 `json:"message"`
+	Tags        map[string]string      `json:"tags"`
+	Extra       map[string]interface{} `json:"extra"`
+}
+
+// newSentryEvent 根据崩溃报告创建事件
+func newSentryEvent(report *Report, nodeType, nodeID, environment, release string) *sentryEvent {
+	tags := map[string]string{
+		"source":    report.Source,
+		"node_type": nodeType,
+	}
+	for k, v := range report.Tags {
+		tags[k] = v
+	}
+
+	return &sentryEvent{
+		EventID:     newEventID(),
+		Timestamp:   report.Timestamp.UTC().Format("2006-01-02T15:04:05"),
+		Level:       "fatal",
+		Platform:    "go",
+		Logger:      "lufy",
+		ServerName:  nodeID,
+		Environment: environment,
+		Release:     release,
+		Message:     fmt.Sprintf("panic: %v", report.Value),
+		Tags:        tags,
+		Extra: map[string]interface{}{
+			"stack": string(report.Stack),
+		},
+	}
+}
+
+// send 发送事件
+func (s *sentrySender) send(event *sentryEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", fmt.Sprintf(
+		"Sentry sentry_version=7, sentry_client=lufy/1.0, sentry_timestamp=%d, sentry_key=%s",
+		time.Now().Unix(), s.publicKey))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send event: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("crash report endpoint returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// newEventID 生成32位十六进制事件ID
+func newEventID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
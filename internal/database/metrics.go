@@ -0,0 +1,61 @@
+package database
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// operationBuckets 操作耗时分布的区间，单位秒
+var operationBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// OperationMetrics 按集合和操作统计MongoDB的请求数、耗时和错误数。
+// 由驱动的命令监听记录，所有仓库的读写都会被统计，不需要开启MongoDB的profiling
+type OperationMetrics struct {
+	operations *prometheus.CounterVec
+	errors     *prometheus.CounterVec
+	duration   *prometheus.HistogramVec
+}
+
+// NewOperationMetrics 创建MongoDB操作指标
+func NewOperationMetrics() *OperationMetrics {
+	labels := []string{"collection", "operation"}
+	return &OperationMetrics{
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "lufy_mongo_operations_total",
+			Help: "Number of MongoDB commands by collection and operation, including failures",
+		}, labels),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "lufy_mongo_operation_errors_total",
+			Help: "Number of MongoDB commands that failed or returned write errors",
+		}, labels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "lufy_mongo_operation_duration_seconds",
+			Help:    "Duration of MongoDB commands in seconds",
+			Buckets: operationBuckets,
+		}, labels),
+	}
+}
+
+// Describe 实现prometheus.Collector接口
+func (m *OperationMetrics) Describe(ch chan<- *prometheus.Desc) {
+	m.operations.Describe(ch)
+	m.errors.Describe(ch)
+	m.duration.Describe(ch)
+}
+
+// Collect 实现prometheus.Collector接口
+func (m *OperationMetrics) Collect(ch chan<- prometheus.Metric) {
+	m.operations.Collect(ch)
+	m.errors.Collect(ch)
+	m.duration.Collect(ch)
+}
+
+// observe 记录命令结束
+func (m *OperationMetrics) observe(collection, operation string, duration time.Duration, failed bool) {
+	m.operations.WithLabelValues(collection, operation).Inc()
+	m.duration.WithLabelValues(collection, operation).Observe(duration.Seconds())
+	if failed {
+		m.errors.WithLabelValues(collection, operation).Inc()
+	}
+}
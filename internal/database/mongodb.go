@@ -2,9 +2,12 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -46,11 +49,20 @@ type MongoConfig struct {
 	WriteConcern   string `yaml:"write_concern"`   // majority, 1, 2, etc.
 	ReadConcern    string `yaml:"read_concern"`    // local, available, majority, etc.
 
+	// 读写分离，历史记录、统计等读取可以单独选择从副本读取
+	ReadSplitting ReadSplittingConfig `yaml:"read_splitting"`
+
 	// SSL/TLS配置
 	TLSEnabled  bool   `yaml:"tls_enabled"`
 	TLSCertFile string `yaml:"tls_cert_file"`
 	TLSKeyFile  string `yaml:"tls_key_file"`
 	TLSCAFile   string `yaml:"tls_ca_file"`
+
+	// 慢查询记录
+	SlowQuery SlowQueryConfig `yaml:"slow_query"`
+
+	// 冷热数据分库，按名称配置，如logs、analytics，未指定的集合都在主库
+	Stores map[string]StoreConfig `yaml:"stores"`
 }
 
 // MongoManager MongoDB管理器
@@ -60,15 +72,42 @@ type MongoManager struct {
 	config   *MongoConfig
 	ctx      context.Context
 	mode     string // "single", "replica_set", "sharded"
+	metrics  *OperationMetrics
+
+	slowQueries *SlowQueryLogger
+	readers     sync.Map // 按读偏好读取的集合，集合名/读偏好 -> *mongo.Collection
+
+	stores []*dataStore                // 独立存放部分集合的数据库
+	routes map[string]*mongo.Database // 集合 -> 所在的独立数据库
+
+	fieldEncryptor FieldEncryptor // 敏感字段加密，为空时明文存储
+}
+
+// FieldEncryptor 敏感字段加解密接口
+type FieldEncryptor interface {
+	EncryptField(plaintext string) (string, error)
+	DecryptField(value string) (string, error)
+	NeedsReencrypt(value string) bool
+	BlindIndex(value string) string // 等值查询使用的盲索引
 }
 
 // NewMongoManager 创建MongoDB管理器
 func NewMongoManager(config *MongoConfig) (*MongoManager, error) {
 	ctx := context.Background()
 
+	if err := config.ReadSplitting.Validate(); err != nil {
+		return nil, err
+	}
+	if err := validateStores(config.Stores); err != nil {
+		return nil, err
+	}
+
 	manager := &MongoManager{
-		config: config,
-		ctx:    ctx,
+		config:  config,
+		ctx:     ctx,
+		metrics: NewOperationMetrics(),
+
+		slowQueries: newSlowQueryLogger(&config.SlowQuery),
 	}
 
 	var clientOptions *options.ClientOptions
@@ -89,6 +128,8 @@ func NewMongoManager(config *MongoConfig) (*MongoManager, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to build client options: %v", err)
 	}
+	monitor := commandMonitor(manager.metrics, manager.slowQueries)
+	clientOptions.SetMonitor(monitor)
 
 	// 连接MongoDB
 	client, err := mongo.Connect(ctx, clientOptions)
@@ -104,11 +145,22 @@ func NewMongoManager(config *MongoConfig) (*MongoManager, error) {
 
 	manager.client = client
 	manager.database = client.Database(config.Database)
+	manager.slowQueries.addDatabase(manager.database)
+	if err := manager.connectStores(ctx, monitor); err != nil {
+		client.Disconnect(ctx)
+		return nil, err
+	}
+	manager.slowQueries.start(manager.GetCollection(slowQueryCollection))
 
-	logger.Infof("MongoDB connected in %s mode", manager.mode)
+	logger.Infof("MongoDB connected in %s mode with %d separate stores", manager.mode, len(manager.stores))
 	return manager, nil
 }
 
+// Metrics 按集合和操作统计的请求数、耗时和错误数，由监控管理器导出
+func (mm *MongoManager) Metrics() *OperationMetrics {
+	return mm.metrics
+}
+
 // buildSingleOptions 构建单机模式选项
 func (mm *MongoManager) buildSingleOptions() (*options.ClientOptions, error) {
 	opts := options.Client().
@@ -263,17 +315,29 @@ func (mm *MongoManager) GetDatabase() *mongo.Database {
 
 // GetCollection 获取集合
 func (mm *MongoManager) GetCollection(name string) *mongo.Collection {
-	return mm.database.Collection(name)
+	return mm.databaseFor(name).Collection(name)
+}
+
+// SetFieldEncryptor 设置敏感字段加密器，需在创建仓库之前调用
+func (mm *MongoManager) SetFieldEncryptor(encryptor FieldEncryptor) {
+	mm.fieldEncryptor = encryptor
 }
 
 // Close 关闭MongoDB连接
 func (mm *MongoManager) Close() error {
-	return mm.client.Disconnect(mm.ctx)
+	mm.slowQueries.stop()
+	storesErr := mm.closeStores(mm.ctx)
+	if err := mm.client.Disconnect(mm.ctx); err != nil {
+		return err
+	}
+	return storesErr
 }
 
 // UserRepository 用户数据仓库
 type UserRepository struct {
 	collection *mongo.Collection
+	secondary  *mongo.Collection // 可以接受副本延迟的读取，如搜索
+	encryptor  FieldEncryptor
 }
 
 // User 用户模型
@@ -283,18 +347,45 @@ type User struct {
 	Username    string             `bson:"username" json:"username"`
 	Password    string             `bson:"password" json:"password"`
 	Nickname    string             `bson:"nickname" json:"nickname"`
-	Email       string             `bson:"email,omitempty" json:"email"`
-	Phone       string             `bson:"phone,omitempty" json:"phone"`
+	Email       string             `bson:"email,omitempty" json:"email"` // 加密存储
+	EmailIndex  string             `bson:"email_bidx,omitempty" json:"-"` // 邮箱的盲索引，启用加密时用于查询和查重
+	Phone       string             `bson:"phone,omitempty" json:"phone"` // 加密存储
 	Level       int32              `bson:"level" json:"level"`
 	Experience  int64              `bson:"experience" json:"experience"`
 	Gold        int64              `bson:"gold" json:"gold"`
 	Diamond     int64              `bson:"diamond" json:"diamond"`
+	Spent       int64              `bson:"spent" json:"spent"` // 累计消耗的钻石，用于玩家分群
 	Avatar      string             `bson:"avatar,omitempty" json:"avatar"`
-	Status      int32              `bson:"status" json:"status"` // 0-正常 1-封禁
+	Status      int32              `bson:"status" json:"status"` // 0-正常 1-封禁 2-冻结待审核
 	LastLoginIP string             `bson:"last_login_ip" json:"last_login_ip"`
+	Region      string             `bson:"region,omitempty" json:"region"`     // 最近登录所在区域
+	Language    string             `bson:"language,omitempty" json:"language"` // 最近登录的客户端语言
+	WorldID     uint32             `bson:"world_id" json:"world_id"`           // 所属世界，注册时确定
 	LastLoginAt time.Time          `bson:"last_login_at" json:"last_login_at"`
 	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+
+	Guest         bool      `bson:"guest,omitempty" json:"guest"`                   // 游客账号，绑定后清除
+	GuestKey      string    `bson:"guest_key,omitempty" json:"-"`                   // 游客ID的哈希，绑定后保留，防止游客ID被再次使用
+	OAuthProvider string    `bson:"oauth_provider,omitempty" json:"oauth_provider"` // 绑定的第三方登录平台
+	OAuthSubject  string    `bson:"oauth_subject,omitempty" json:"-"`               // 第三方平台上的用户标识
+	LinkedAt      time.Time `bson:"linked_at,omitempty" json:"linked_at,omitempty"` // 游客账号绑定的时间
+
+	// 影子封禁：聊天消息只有自己可见，优先与其他影子封禁的玩家匹配，玩家本人无感知
+	ShadowBanned bool `bson:"shadow_banned,omitempty" json:"shadow_banned,omitempty"`
+
+	// VIP等级，由计费系统开通和续费，过期后按0级处理
+	VIPTier      int32     `bson:"vip_tier,omitempty" json:"vip_tier"`
+	VIPExpiresAt time.Time `bson:"vip_expires_at,omitempty" json:"vip_expires_at,omitempty"` // 为空时不过期
+	VIPOrderID   string    `bson:"vip_order_id,omitempty" json:"-"`                          // 最近一次变更的订单号，用于重复通知去重
+}
+
+// ActiveVIPTier 玩家当前有效的VIP等级，已过期时为0
+func (u *User) ActiveVIPTier(now time.Time) int32 {
+	if !u.VIPExpiresAt.IsZero() && !now.Before(u.VIPExpiresAt) {
+		return 0
+	}
+	return u.VIPTier
 }
 
 // NewUserRepository 创建用户仓库
@@ -312,15 +403,97 @@ func NewUserRepository(mm *MongoManager) *UserRepository {
 			Options: options.Index().SetUnique(true),
 		},
 		{
+			// 未启用加密时按明文邮箱查询
 			Keys: bson.D{{Key: "email", Value: 1}},
 		},
+		{
+			Keys: bson.D{{Key: "world_id", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "guest_key", Value: 1}},
+			Options: options.Index().SetUnique(true).SetSparse(true),
+		},
+		{
+			Keys:    bson.D{{Key: "oauth_provider", Value: 1}, {Key: "oauth_subject", Value: 1}},
+			Options: options.Index().SetUnique(true).SetSparse(true),
+		},
+		{
+			// 昵称不区分大小写查重，查询时需使用相同的排序规则
+			Keys:    bson.D{{Key: "nickname", Value: 1}},
+			Options: options.Index().SetCollation(nicknameCollation),
+		},
+		{
+			// 按名称搜索玩家，名称不做词干处理
+			Keys: bson.D{{Key: "nickname", Value: "text"}, {Key: "username", Value: "text"}},
+			Options: options.Index().
+				SetWeights(bson.D{{Key: "nickname", Value: 3}, {Key: "username", Value: 1}}).
+				SetDefaultLanguage("none"),
+		},
 	}
 
 	collection.Indexes().CreateMany(context.Background(), indexes)
 
+	// 邮箱加密后密文随机，按盲索引查询和查重。存量数据可能有重复邮箱，先建为普通索引，
+	// 由MigrateSensitiveFields回填并确认没有重复后改为唯一索引；已是唯一索引时这里会因选项冲突失败，保留原索引
+	collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "email_bidx", Value: 1}},
+		Options: options.Index().SetName(emailIndexName).SetSparse(true),
+	})
+
 	return &UserRepository{
 		collection: collection,
+		secondary:  mm.Reader("users", ReadSecondaryPreferred),
+		encryptor:  mm.fieldEncryptor,
+	}
+}
+
+// sensitiveUserFields 需要加密存储的用户字段
+var sensitiveUserFields = []string{"email", "phone"}
+
+// emailIndexName 邮箱盲索引的索引名
+const emailIndexName = "email_bidx_1"
+
+// encryptUser 返回敏感字段已加密的用户副本
+func (ur *UserRepository) encryptUser(user *User) (*User, error) {
+	if ur.encryptor == nil {
+		return user, nil
+	}
+
+	encrypted := *user
+	encrypted.EmailIndex = ""
+	if user.Email != "" {
+		encrypted.EmailIndex = ur.encryptor.BlindIndex(user.Email)
+	}
+	for _, field := range []*string{&encrypted.Email, &encrypted.Phone} {
+		if *field == "" {
+			continue
+		}
+		value, err := ur.encryptor.EncryptField(*field)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt user fields: %v", err)
+		}
+		*field = value
+	}
+	return &encrypted, nil
+}
+
+// decryptUser 解密用户的敏感字段
+func (ur *UserRepository) decryptUser(user *User) error {
+	if ur.encryptor == nil {
+		return nil
+	}
+
+	for _, field := range []*string{&user.Email, &user.Phone} {
+		if *field == "" {
+			continue
+		}
+		value, err := ur.encryptor.DecryptField(*field)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt user fields: %v", err)
+		}
+		*field = value
 	}
+	return nil
 }
 
 // Create 创建用户
@@ -328,7 +501,12 @@ func (ur *UserRepository) Create(user *User) error {
 	user.CreatedAt = time.Now()
 	user.UpdatedAt = time.Now()
 
-	result, err := ur.collection.InsertOne(context.Background(), user)
+	doc, err := ur.encryptUser(user)
+	if err != nil {
+		return err
+	}
+
+	result, err := ur.collection.InsertOne(context.Background(), doc)
 	if err != nil {
 		return fmt.Errorf("failed to create user: %v", err)
 	}
@@ -347,6 +525,9 @@ func (ur *UserRepository) GetByUserID(userID uint64) (*User, error) {
 		}
 		return nil, fmt.Errorf("failed to get user: %v", err)
 	}
+	if err := ur.decryptUser(&user); err != nil {
+		return nil, err
+	}
 	return &user, nil
 }
 
@@ -360,17 +541,84 @@ func (ur *UserRepository) GetByUsername(username string) (*User, error) {
 		}
 		return nil, fmt.Errorf("failed to get user: %v", err)
 	}
+	if err := ur.decryptUser(&user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetByEmail 根据邮箱获取用户，启用加密时按盲索引查询
+func (ur *UserRepository) GetByEmail(email string) (*User, error) {
+	if ur.encryptor == nil {
+		return ur.findOne(bson.M{"email": email})
+	}
+	return ur.findOne(bson.M{"email_bidx": ur.encryptor.BlindIndex(email)})
+}
+
+// GetByGuestKey 根据游客ID的哈希获取用户，包括已经绑定的游客账号
+func (ur *UserRepository) GetByGuestKey(guestKey string) (*User, error) {
+	return ur.findOne(bson.M{"guest_key": guestKey})
+}
+
+// GetByOAuth 根据第三方平台的用户标识获取用户
+func (ur *UserRepository) GetByOAuth(provider, subject string) (*User, error) {
+	return ur.findOne(bson.M{"oauth_provider": provider, "oauth_subject": subject})
+}
+
+// findOne 按条件获取用户并解密敏感字段
+func (ur *UserRepository) findOne(filter bson.M) (*User, error) {
+	var user User
+	err := ur.collection.FindOne(context.Background(), filter).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user: %v", err)
+	}
+	if err := ur.decryptUser(&user); err != nil {
+		return nil, err
+	}
 	return &user, nil
 }
 
+// ErrIdentityTaken 用户名或第三方账号已被其他用户使用
+var ErrIdentityTaken = errors.New("identity already linked to another account")
+
+// UpgradeGuest 把游客账号就地转为正式账号，等级、货币等进度都在同一文档中随之保留。
+// 只有仍为游客时才会更新，已绑定时返回false；用户名或第三方账号已被使用时返回ErrIdentityTaken
+func (ur *UserRepository) UpgradeGuest(userID uint64, identity bson.M) (bool, error) {
+	now := time.Now()
+	set := bson.M{"linked_at": now, "updated_at": now}
+	for key, value := range identity {
+		set[key] = value
+	}
+
+	filter := bson.M{"user_id": userID, "guest": true}
+	update := bson.M{"$set": set, "$unset": bson.M{"guest": ""}}
+
+	result, err := ur.collection.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, ErrIdentityTaken
+		}
+		return false, fmt.Errorf("failed to upgrade guest user: %v", err)
+	}
+	return result.MatchedCount == 1, nil
+}
+
 // Update 更新用户
 func (ur *UserRepository) Update(user *User) error {
 	user.UpdatedAt = time.Now()
 
+	doc, err := ur.encryptUser(user)
+	if err != nil {
+		return err
+	}
+
 	filter := bson.M{"user_id": user.UserID}
-	update := bson.M{"$set": user}
+	update := bson.M{"$set": doc}
 
-	_, err := ur.collection.UpdateOne(context.Background(), filter, update)
+	_, err = ur.collection.UpdateOne(context.Background(), filter, update)
 	if err != nil {
 		return fmt.Errorf("failed to update user: %v", err)
 	}
@@ -381,6 +629,23 @@ func (ur *UserRepository) Update(user *User) error {
 func (ur *UserRepository) UpdateFields(userID uint64, fields bson.M) error {
 	fields["updated_at"] = time.Now()
 
+	if ur.encryptor != nil {
+		for _, name := range sensitiveUserFields {
+			value, ok := fields[name].(string)
+			if !ok || value == "" {
+				continue
+			}
+			encrypted, err := ur.encryptor.EncryptField(value)
+			if err != nil {
+				return fmt.Errorf("failed to encrypt user fields: %v", err)
+			}
+			fields[name] = encrypted
+			if name == "email" {
+				fields["email_bidx"] = ur.encryptor.BlindIndex(value)
+			}
+		}
+	}
+
 	filter := bson.M{"user_id": userID}
 	update := bson.M{"$set": fields}
 
@@ -391,26 +656,26 @@ func (ur *UserRepository) UpdateFields(userID uint64, fields bson.M) error {
 	return nil
 }
 
-// Delete 删除用户
-func (ur *UserRepository) Delete(userID uint64) error {
-	filter := bson.M{"user_id": userID}
-	_, err := ur.collection.DeleteOne(context.Background(), filter)
+// nicknameCollation 昵称查重的排序规则，忽略大小写
+var nicknameCollation = &options.Collation{Locale: "en", Strength: 2}
+
+// NicknameInUse 是否有其他用户使用该昵称(不区分大小写)
+func (ur *UserRepository) NicknameInUse(nickname string, exceptUserID uint64) (bool, error) {
+	filter := bson.M{"nickname": nickname, "user_id": bson.M{"$ne": exceptUserID}}
+	count, err := ur.collection.CountDocuments(context.Background(), filter,
+		options.Count().SetCollation(nicknameCollation).SetLimit(1))
 	if err != nil {
-		return fmt.Errorf("failed to delete user: %v", err)
+		return false, fmt.Errorf("failed to check nickname: %v", err)
 	}
-	return nil
+	return count > 0, nil
 }
 
-// List 获取用户列表
-func (ur *UserRepository) List(offset, limit int64) ([]*User, error) {
-	options := options.Find().
-		SetSkip(offset).
-		SetLimit(limit).
-		SetSort(bson.D{{Key: "created_at", Value: -1}})
-
-	cursor, err := ur.collection.Find(context.Background(), bson.M{}, options)
+// FindByNickname 按昵称查找用户(不区分大小写)
+func (ur *UserRepository) FindByNickname(nickname string, limit int64) ([]*User, error) {
+	options := options.Find().SetCollation(nicknameCollation).SetLimit(limit)
+	cursor, err := ur.collection.Find(context.Background(), bson.M{"nickname": nickname}, options)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list users: %v", err)
+		return nil, fmt.Errorf("failed to find users by nickname: %v", err)
 	}
 	defer cursor.Close(context.Background())
 
@@ -418,116 +683,570 @@ func (ur *UserRepository) List(offset, limit int64) ([]*User, error) {
 	if err := cursor.All(context.Background(), &users); err != nil {
 		return nil, fmt.Errorf("failed to decode users: %v", err)
 	}
-
+	for _, user := range users {
+		if err := ur.decryptUser(user); err != nil {
+			return nil, err
+		}
+	}
 	return users, nil
 }
 
-// FriendRepository 好友关系仓库
-type FriendRepository struct {
-	collection *mongo.Collection
+// NameMatch 按名称搜索的候选结果，Score为文本索引的相关度，只按前缀匹配到的为0
+type NameMatch struct {
+	ID    uint64
+	Score float64
 }
 
-// Friend 好友关系模型
-type Friend struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	UserID    uint64             `bson:"user_id" json:"user_id"`
-	FriendID  uint64             `bson:"friend_id" json:"friend_id"`
-	Status    int32              `bson:"status" json:"status"` // 0-待确认 1-已确认 2-已拒绝
-	Message   string             `bson:"message,omitempty" json:"message"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
-}
+// searchByName 按名称前缀(不区分大小写)和文本索引搜索，两种方式各取limit个候选，按ID合并
+func searchByName(collection *mongo.Collection, idField string, fields []string, filter bson.M, query string, limit int64) ([]NameMatch, error) {
+	ctx := context.Background()
+	scores := make(map[uint64]float64)
+	var order []uint64
+	add := func(cursor *mongo.Cursor) error {
+		defer cursor.Close(ctx)
+		for cursor.Next(ctx) {
+			var doc bson.M
+			if err := cursor.Decode(&doc); err != nil {
+				return err
+			}
+			id := toUint64(doc[idField])
+			score, _ := doc["score"].(float64)
+			if existing, seen := scores[id]; !seen {
+				order = append(order, id)
+			} else if existing > score {
+				score = existing
+			}
+			scores[id] = score
+		}
+		return cursor.Err()
+	}
 
-// NewFriendRepository 创建好友仓库
-func NewFriendRepository(mm *MongoManager) *FriendRepository {
-	collection := mm.GetCollection("friends")
+	prefix := make(bson.A, 0, len(fields))
+	for _, field := range fields {
+		prefix = append(prefix, bson.M{field: primitive.Regex{Pattern: "^" + regexp.QuoteMeta(query), Options: "i"}})
+	}
+	prefixFilter := bson.M{"$or": prefix}
+	textFilter := bson.M{"$text": bson.M{"$search": query}}
+	for key, value := range filter {
+		prefixFilter[key] = value
+		textFilter[key] = value
+	}
 
-	// 创建索引
-	indexes := []mongo.IndexModel{
-		{
-			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "friend_id", Value: 1}},
-		},
-		{
-			Keys: bson.D{{Key: "user_id", Value: 1}},
-		},
-		{
-			Keys: bson.D{{Key: "friend_id", Value: 1}},
-		},
+	cursor, err := collection.Find(ctx, prefixFilter,
+		options.Find().SetProjection(bson.M{idField: 1}).SetLimit(limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search by name prefix: %v", err)
+	}
+	if err := add(cursor); err != nil {
+		return nil, fmt.Errorf("failed to decode name prefix matches: %v", err)
 	}
 
-	collection.Indexes().CreateMany(context.Background(), indexes)
+	score := bson.M{"$meta": "textScore"}
+	cursor, err = collection.Find(ctx, textFilter, options.Find().
+		SetProjection(bson.M{idField: 1, "score": score}).
+		SetSort(bson.M{"score": score}).
+		SetLimit(limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search by name: %v", err)
+	}
+	if err := add(cursor); err != nil {
+		return nil, fmt.Errorf("failed to decode name matches: %v", err)
+	}
 
-	return &FriendRepository{
-		collection: collection,
+	matches := make([]NameMatch, len(order))
+	for i, id := range order {
+		matches[i] = NameMatch{ID: id, Score: scores[id]}
 	}
+	return matches, nil
 }
 
-// AddFriend 添加好友请求
-func (fr *FriendRepository) AddFriend(userID, friendID uint64, message string) error {
-	friend := &Friend{
-		UserID:    userID,
-		FriendID:  friendID,
-		Status:    0, // 待确认
-		Message:   message,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+// toUint64 读取bson.M中的整数ID
+func toUint64(v interface{}) uint64 {
+	switch n := v.(type) {
+	case int64:
+		return uint64(n)
+	case int32:
+		return uint64(n)
+	case float64:
+		return uint64(n)
 	}
+	return 0
+}
 
-	_, err := fr.collection.InsertOne(context.Background(), friend)
+// SearchByName 按昵称或用户名搜索正常状态的玩家
+func (ur *UserRepository) SearchByName(query string, limit int64) ([]NameMatch, error) {
+	return searchByName(ur.secondary, "user_id", []string{"nickname", "username"}, bson.M{"status": 0}, query, limit)
+}
+
+// GetByUserIDs 批量获取用户，不存在的用户不返回
+func (ur *UserRepository) GetByUserIDs(userIDs []uint64) ([]*User, error) {
+	cursor, err := ur.collection.Find(context.Background(), bson.M{"user_id": bson.M{"$in": userIDs}})
 	if err != nil {
-		return fmt.Errorf("failed to add friend: %v", err)
+		return nil, fmt.Errorf("failed to get users: %v", err)
 	}
-	return nil
+	defer cursor.Close(context.Background())
+
+	var users []*User
+	if err := cursor.All(context.Background(), &users); err != nil {
+		return nil, fmt.Errorf("failed to decode users: %v", err)
+	}
+	for _, user := range users {
+		if err := ur.decryptUser(user); err != nil {
+			return nil, err
+		}
+	}
+	return users, nil
 }
 
-// AcceptFriend 接受好友请求
-func (fr *FriendRepository) AcceptFriend(userID, friendID uint64) error {
-	// 更新请求状态
-	filter := bson.M{"user_id": friendID, "friend_id": userID, "status": 0}
-	update := bson.M{"$set": bson.M{"status": 1, "updated_at": time.Now()}}
+// SetName 昵称或用户名仍为from时修改为to，已被修改时返回false；用户名已被其他用户使用时返回ErrIdentityTaken
+func (ur *UserRepository) SetName(userID uint64, field, from, to string) (bool, error) {
+	switch field {
+	case NameFieldNickname, NameFieldUsername:
+	default:
+		return false, fmt.Errorf("unknown name field: %s", field)
+	}
 
-	_, err := fr.collection.UpdateOne(context.Background(), filter, update)
+	filter := bson.M{"user_id": userID, field: from}
+	update := bson.M{"$set": bson.M{field: to, "updated_at": time.Now()}}
+	result, err := ur.collection.UpdateOne(context.Background(), filter, update)
 	if err != nil {
-		return fmt.Errorf("failed to accept friend request: %v", err)
+		if mongo.IsDuplicateKeyError(err) {
+			return false, ErrIdentityTaken
+		}
+		return false, fmt.Errorf("failed to set %s: %v", field, err)
 	}
+	return result.ModifiedCount > 0, nil
+}
 
-	// 添加反向关系
-	friend := &Friend{
-		UserID:    userID,
-		FriendID:  friendID,
-		Status:    1, // 已确认
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+// RaiseLevel 等级仍为from时提升到to，等级已被修改时返回false
+func (ur *UserRepository) RaiseLevel(userID uint64, from, to int32) (bool, error) {
+	filter := bson.M{"user_id": userID, "level": from}
+	update := bson.M{"$set": bson.M{"level": to, "updated_at": time.Now()}}
+
+	result, err := ur.collection.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to raise user level: %v", err)
 	}
+	return result.ModifiedCount == 1, nil
+}
 
-	_, err = fr.collection.InsertOne(context.Background(), friend)
+// UpdateStatus 状态仍为from时修改为to，状态已被修改时返回false
+func (ur *UserRepository) UpdateStatus(userID uint64, from, to int32) (bool, error) {
+	filter := bson.M{"user_id": userID, "status": from}
+	update := bson.M{"$set": bson.M{"status": to, "updated_at": time.Now()}}
+
+	result, err := ur.collection.UpdateOne(context.Background(), filter, update)
 	if err != nil {
-		return fmt.Errorf("failed to add reverse friend relation: %v", err)
+		return false, fmt.Errorf("failed to update user status: %v", err)
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// SetShadowBanned 设置或解除影子封禁，状态没有变化时返回false
+func (ur *UserRepository) SetShadowBanned(userID uint64, banned bool) (bool, error) {
+	filter := bson.M{"user_id": userID, "shadow_banned": bson.M{"$ne": banned}}
+	update := bson.M{"$set": bson.M{"shadow_banned": banned, "updated_at": time.Now()}}
+
+	result, err := ur.collection.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to update shadow ban: %v", err)
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// SetVIP 设置玩家的VIP等级和到期时间，同一订单只生效一次。
+// 返回false表示玩家不存在或订单已处理过
+func (ur *UserRepository) SetVIP(userID uint64, tier int32, expiresAt time.Time, orderID string) (bool, error) {
+	filter := bson.M{"user_id": userID, "vip_order_id": bson.M{"$ne": orderID}}
+	update := bson.M{"$set": bson.M{
+		"vip_tier":       tier,
+		"vip_expires_at": expiresAt,
+		"vip_order_id":   orderID,
+		"updated_at":     time.Now(),
+	}}
+
+	result, err := ur.collection.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to update vip tier: %v", err)
 	}
+	return result.ModifiedCount == 1, nil
+}
 
+// Delete 删除用户
+func (ur *UserRepository) Delete(userID uint64) error {
+	filter := bson.M{"user_id": userID}
+	_, err := ur.collection.DeleteOne(context.Background(), filter)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %v", err)
+	}
 	return nil
 }
 
-// GetFriends 获取好友列表
-func (fr *FriendRepository) GetFriends(userID uint64) ([]*Friend, error) {
-	filter := bson.M{"user_id": userID, "status": 1}
-	cursor, err := fr.collection.Find(context.Background(), filter)
+// List 获取用户列表
+func (ur *UserRepository) List(offset, limit int64) ([]*User, error) {
+	options := options.Find().
+		SetSkip(offset).
+		SetLimit(limit).
+		SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := ur.collection.Find(context.Background(), bson.M{}, options)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get friends: %v", err)
+		return nil, fmt.Errorf("failed to list users: %v", err)
 	}
 	defer cursor.Close(context.Background())
 
-	var friends []*Friend
-	if err := cursor.All(context.Background(), &friends); err != nil {
-		return nil, fmt.Errorf("failed to decode friends: %v", err)
+	var users []*User
+	if err := cursor.All(context.Background(), &users); err != nil {
+		return nil, fmt.Errorf("failed to decode users: %v", err)
 	}
 
-	return friends, nil
+	for _, user := range users {
+		if err := ur.decryptUser(user); err != nil {
+			return nil, err
+		}
+	}
+
+	return users, nil
 }
 
-// MailRepository 邮件仓库
+// ListAfter 按用户ID顺序获取afterUserID之后的用户，用于分批遍历
+func (ur *UserRepository) ListAfter(afterUserID uint64, limit int64) ([]*User, error) {
+	options := options.Find().
+		SetSort(bson.D{{Key: "user_id", Value: 1}}).
+		SetLimit(limit)
+
+	cursor, err := ur.collection.Find(context.Background(), bson.M{"user_id": bson.M{"$gt": afterUserID}}, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %v", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var users []*User
+	if err := cursor.All(context.Background(), &users); err != nil {
+		return nil, fmt.Errorf("failed to decode users: %v", err)
+	}
+
+	for _, user := range users {
+		if err := ur.decryptUser(user); err != nil {
+			return nil, err
+		}
+	}
+	return users, nil
+}
+
+// ForEach 遍历所有用户，fn返回错误时停止遍历
+func (ur *UserRepository) ForEach(fn func(user *User) error) error {
+	ctx := context.Background()
+	cursor, err := ur.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "user_id", Value: 1}}))
+	if err != nil {
+		return fmt.Errorf("failed to scan users: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var user User
+		if err := cursor.Decode(&user); err != nil {
+			logger.Error(fmt.Sprintf("Failed to decode user during scan: %v", err))
+			continue
+		}
+		if err := ur.decryptUser(&user); err != nil {
+			return err
+		}
+		if err := fn(&user); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// MigrateSensitiveFields 将明文或旧版本密钥加密的敏感字段用当前密钥重新加密，并补齐缺失的邮箱盲索引
+// 用于首次启用加密后的数据回填以及密钥轮换，返回更新的用户数。回填后检查重复邮箱，
+// 存在重复时逐个报告并返回错误，没有重复时把盲索引改为唯一索引
+func (ur *UserRepository) MigrateSensitiveFields() (int64, error) {
+	if ur.encryptor == nil {
+		return 0, fmt.Errorf("field encryption not enabled")
+	}
+
+	ctx := context.Background()
+	filter := bson.M{
+		"$or": []bson.M{
+			{"email": bson.M{"$exists": true, "$ne": ""}},
+			{"phone": bson.M{"$exists": true, "$ne": ""}},
+		},
+	}
+
+	cursor, err := ur.collection.Find(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to scan users: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var migrated int64
+	for cursor.Next(ctx) {
+		var user User
+		if err := cursor.Decode(&user); err != nil {
+			logger.Error(fmt.Sprintf("Failed to decode user during migration: %v", err))
+			continue
+		}
+
+		missingIndex := user.Email != "" && user.EmailIndex == ""
+		if !missingIndex && !ur.encryptor.NeedsReencrypt(user.Email) && !ur.encryptor.NeedsReencrypt(user.Phone) {
+			continue
+		}
+
+		if err := ur.decryptUser(&user); err != nil {
+			logger.Error(fmt.Sprintf("Failed to decrypt user %d during migration: %v", user.UserID, err))
+			continue
+		}
+
+		fields := bson.M{}
+		if user.Email != "" {
+			fields["email"] = user.Email
+		}
+		if user.Phone != "" {
+			fields["phone"] = user.Phone
+		}
+		if err := ur.UpdateFields(user.UserID, fields); err != nil {
+			logger.Error(fmt.Sprintf("Failed to migrate user %d: %v", user.UserID, err))
+			continue
+		}
+		migrated++
+	}
+
+	if err := cursor.Err(); err != nil {
+		return migrated, fmt.Errorf("failed to scan users: %v", err)
+	}
+
+	logger.Info(fmt.Sprintf("Migrated sensitive fields for %d users", migrated))
+
+	duplicates, err := ur.findDuplicateEmails(ctx)
+	if err != nil {
+		return migrated, err
+	}
+	if len(duplicates) > 0 {
+		for _, userIDs := range duplicates {
+			logger.Warn(fmt.Sprintf("Users %v share the same email", userIDs))
+		}
+		return migrated, fmt.Errorf("migrated %d users, but %d emails are shared by multiple users, resolve them before enforcing unique emails",
+			migrated, len(duplicates))
+	}
+
+	if err := ur.enforceUniqueEmail(ctx); err != nil {
+		return migrated, err
+	}
+	return migrated, nil
+}
+
+// findDuplicateEmails 按盲索引分组，返回邮箱相同的各组用户ID
+func (ur *UserRepository) findDuplicateEmails(ctx context.Context) ([][]uint64, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"email_bidx": bson.M{"$exists": true, "$ne": ""}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      "$email_bidx",
+			"user_ids": bson.M{"$push": "$user_id"},
+			"count":    bson.M{"$sum": 1},
+		}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+	}
+
+	cursor, err := ur.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check duplicate emails: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		UserIDs []uint64 `bson:"user_ids"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, fmt.Errorf("failed to check duplicate emails: %v", err)
+	}
+
+	duplicates := make([][]uint64, 0, len(groups))
+	for _, group := range groups {
+		duplicates = append(duplicates, group.UserIDs)
+	}
+	return duplicates, nil
+}
+
+// enforceUniqueEmail 把邮箱盲索引由普通索引改为唯一索引，已是唯一索引时不做处理
+func (ur *UserRepository) enforceUniqueEmail(ctx context.Context) error {
+	specs, err := ur.collection.Indexes().ListSpecifications(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list user indexes: %v", err)
+	}
+	exists := false
+	for _, spec := range specs {
+		if spec.Name != emailIndexName {
+			continue
+		}
+		if spec.Unique != nil && *spec.Unique {
+			return nil
+		}
+		exists = true
+	}
+
+	// 索引选项不能原地修改，删除后重建，下次启动时若重建失败会恢复为普通索引
+	if exists {
+		if _, err := ur.collection.Indexes().DropOne(ctx, emailIndexName); err != nil {
+			return fmt.Errorf("failed to drop email index: %v", err)
+		}
+	}
+	_, err = ur.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "email_bidx", Value: 1}},
+		Options: options.Index().SetName(emailIndexName).SetUnique(true).SetSparse(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create unique email index: %v", err)
+	}
+
+	logger.Info("Email blind index is now unique")
+	return nil
+}
+
+// worldCollections 按世界隔离数据的集合
+var worldCollections = []string{"users", "rooms", "chat_messages"}
+
+// AssignDefaultWorld 把划分世界之前的数据归入默认世界，返回各集合更新的文档数
+func (mm *MongoManager) AssignDefaultWorld(worldID uint32) (map[string]int64, error) {
+	filter := bson.M{
+		"$or": []bson.M{
+			{"world_id": bson.M{"$exists": false}},
+			{"world_id": 0},
+		},
+	}
+	update := bson.M{"$set": bson.M{"world_id": worldID}}
+
+	updated := make(map[string]int64, len(worldCollections))
+	for _, name := range worldCollections {
+		result, err := mm.GetCollection(name).UpdateMany(context.Background(), filter, update)
+		if err != nil {
+			return updated, fmt.Errorf("failed to assign world for %s: %v", name, err)
+		}
+		updated[name] = result.ModifiedCount
+	}
+
+	logger.Info(fmt.Sprintf("Assigned world %d to legacy documents: %v", worldID, updated))
+	return updated, nil
+}
+
+// FriendRepository 好友关系仓库
+type FriendRepository struct {
+	collection *mongo.Collection
+}
+
+// Friend 好友关系模型
+type Friend struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    uint64             `bson:"user_id" json:"user_id"`
+	FriendID  uint64             `bson:"friend_id" json:"friend_id"`
+	Status    int32              `bson:"status" json:"status"` // 0-待确认 1-已确认 2-已拒绝
+	Message   string             `bson:"message,omitempty" json:"message"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// NewFriendRepository 创建好友仓库
+func NewFriendRepository(mm *MongoManager) *FriendRepository {
+	collection := mm.GetCollection("friends")
+
+	// 创建索引
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "friend_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "friend_id", Value: 1}},
+		},
+	}
+
+	collection.Indexes().CreateMany(context.Background(), indexes)
+
+	return &FriendRepository{
+		collection: collection,
+	}
+}
+
+// AddFriend 添加好友请求
+func (fr *FriendRepository) AddFriend(userID, friendID uint64, message string) error {
+	friend := &Friend{
+		UserID:    userID,
+		FriendID:  friendID,
+		Status:    0, // 待确认
+		Message:   message,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	_, err := fr.collection.InsertOne(context.Background(), friend)
+	if err != nil {
+		return fmt.Errorf("failed to add friend: %v", err)
+	}
+	return nil
+}
+
+// AcceptFriend 接受好友请求
+func (fr *FriendRepository) AcceptFriend(userID, friendID uint64) error {
+	// 更新请求状态
+	filter := bson.M{"user_id": friendID, "friend_id": userID, "status": 0}
+	update := bson.M{"$set": bson.M{"status": 1, "updated_at": time.Now()}}
+
+	_, err := fr.collection.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to accept friend request: %v", err)
+	}
+
+	// 添加反向关系
+	friend := &Friend{
+		UserID:    userID,
+		FriendID:  friendID,
+		Status:    1, // 已确认
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	_, err = fr.collection.InsertOne(context.Background(), friend)
+	if err != nil {
+		return fmt.Errorf("failed to add reverse friend relation: %v", err)
+	}
+
+	return nil
+}
+
+// GetFriends 获取好友列表
+func (fr *FriendRepository) GetFriends(userID uint64) ([]*Friend, error) {
+	filter := bson.M{"user_id": userID, "status": 1}
+	cursor, err := fr.collection.Find(context.Background(), filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get friends: %v", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var friends []*Friend
+	if err := cursor.All(context.Background(), &friends); err != nil {
+		return nil, fmt.Errorf("failed to decode friends: %v", err)
+	}
+
+	return friends, nil
+}
+
+// IsFriend 两个玩家是否为已确认的好友
+func (fr *FriendRepository) IsFriend(userID, friendID uint64) (bool, error) {
+	filter := bson.M{"user_id": userID, "friend_id": friendID, "status": 1}
+	count, err := fr.collection.CountDocuments(context.Background(), filter)
+	if err != nil {
+		return false, fmt.Errorf("failed to check friend: %v", err)
+	}
+	return count > 0, nil
+}
+
+// MailRepository 邮件仓库
 type MailRepository struct {
 	collection *mongo.Collection
+	secondary  *mongo.Collection // 可以接受副本延迟的读取，如批次统计
 }
 
 // Mail 邮件模型
@@ -539,6 +1258,7 @@ type Mail struct {
 	Title      string             `bson:"title" json:"title"`
 	Content    string             `bson:"content" json:"content"`
 	Rewards    []MailReward       `bson:"rewards,omitempty" json:"rewards"`
+	BatchID    string             `bson:"batch_id,omitempty" json:"batch_id,omitempty"` // 批量发放的批次ID，用于追溯和回滚
 	IsRead     bool               `bson:"is_read" json:"is_read"`
 	IsClaimed  bool               `bson:"is_claimed" json:"is_claimed"`
 	ExpireAt   time.Time          `bson:"expire_at" json:"expire_at"`
@@ -554,6 +1274,14 @@ type MailReward struct {
 	Name   string `bson:"name,omitempty" json:"name"`
 }
 
+// MailStore 发放奖励邮件的服务依赖的邮件存储，MailRepository实现了该接口
+type MailStore interface {
+	CreateMail(mail *Mail) error
+}
+
+// MailIDGenerator 生成邮件ID
+type MailIDGenerator func() (uint64, error)
+
 // NewMailRepository 创建邮件仓库
 func NewMailRepository(mm *MongoManager) *MailRepository {
 	collection := mm.GetCollection("mails")
@@ -569,12 +1297,17 @@ func NewMailRepository(mm *MongoManager) *MailRepository {
 		{
 			Keys: bson.D{{Key: "expire_at", Value: 1}},
 		},
+		{
+			Keys:    bson.D{{Key: "batch_id", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
 	}
 
 	collection.Indexes().CreateMany(context.Background(), indexes)
 
 	return &MailRepository{
 		collection: collection,
+		secondary:  mm.Reader("mails", ReadSecondaryPreferred),
 	}
 }
 
@@ -688,6 +1421,9 @@ func NewGameRecordRepository(mm *MongoManager) *GameRecordRepository {
 		{
 			Keys: bson.D{{Key: "created_at", Value: -1}},
 		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "updated_at", Value: 1}},
+		},
 	}
 
 	collection.Indexes().CreateMany(context.Background(), indexes)
@@ -798,6 +1534,7 @@ func (fr *FriendRepository) RejectFriend(userID, friendID uint64) error {
 // RoomRepository 房间数据仓库
 type RoomRepository struct {
 	collection *mongo.Collection
+	secondary  *mongo.Collection // 可以接受副本延迟的读取，如搜索
 }
 
 // Room 房间模型
@@ -810,9 +1547,15 @@ type Room struct {
 	CurrentPlayers int32              `bson:"current_players" json:"current_players"`
 	Status         int32              `bson:"status" json:"status"` // 0-等待中 1-游戏中 2-已结束
 	IsPrivate      bool               `bson:"is_private" json:"is_private"`
-	Password       string             `bson:"password,omitempty" json:"password,omitempty"`
+	Password       string             `bson:"password,omitempty" json:"-"` // 私有房间密码的哈希，不返回给客户端和GM
 	OwnerID        uint64             `bson:"owner_id" json:"owner_id"`
 	Players        []RoomPlayer       `bson:"players" json:"players"`
+	Region         string             `bson:"region,omitempty" json:"region"` // 房间所在区域
+	WorldID        uint32             `bson:"world_id" json:"world_id"`       // 房间所属世界，只有同一世界的玩家可以加入
+	Node           string             `bson:"node,omitempty" json:"node"`     // 匹配时选择的延迟最低的游戏节点，开始游戏后为运行游戏的节点
+	GameID         uint64             `bson:"game_id,omitempty" json:"game_id"`
+	Spectators     []RoomPlayer       `bson:"spectators,omitempty" json:"spectators,omitempty"`
+	EphemeralChat  bool               `bson:"ephemeral_chat,omitempty" json:"ephemeral_chat,omitempty"` // 房间聊天只转发不保存
 	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
 }
@@ -824,6 +1567,7 @@ type RoomPlayer struct {
 	Level    int32  `bson:"level" json:"level"`
 	Status   int32  `bson:"status" json:"status"` // 0-等待 1-准备 2-游戏中
 	JoinTime int64  `bson:"join_time" json:"join_time"`
+	PartyID  uint64 `bson:"party_id,omitempty" json:"party_id,omitempty"` // 组队匹配时所在的队伍，同队成员座位相邻
 }
 
 // ChatMessage 聊天消息数据模型
@@ -837,7 +1581,24 @@ type ChatMessage struct {
 	MessageType int32              `bson:"message_type" json:"message_type"`
 	Content     string             `bson:"content" json:"content"`
 	SendTime    uint32             `bson:"send_time" json:"send_time"`
-	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	Region      string             `bson:"region,omitempty" json:"region"` // 发送者所在区域，用于区域保留策略
+	WorldID     uint32             `bson:"world_id" json:"world_id"`       // 发送者所属世界，频道消息按世界隔离
+	Shadow      bool               `bson:"shadow,omitempty" json:"shadow,omitempty"` // 发送者被影子封禁，只有发送者自己可见
+
+	// 编辑和撤回，撤回后清空内容，保留记录作为墓碑以便客户端显示"消息已撤回"
+	EditedAt   uint32 `bson:"edited_at,omitempty" json:"edited_at,omitempty"`
+	Recalled   bool   `bson:"recalled,omitempty" json:"recalled,omitempty"`
+	RecalledBy uint64 `bson:"recalled_by,omitempty" json:"recalled_by,omitempty"` // 撤回者，GM删除时为GM的用户ID
+	RecalledAt uint32 `bson:"recalled_at,omitempty" json:"recalled_at,omitempty"`
+
+	// 各表情的回应人数，键为表情ID，谁回应了什么保存在chat_reactions集合
+	Reactions map[string]int32 `bson:"reactions,omitempty" json:"reactions,omitempty"`
+
+	// 匿名化后用户ID清零，以假名替代
+	FromPseudonym string    `bson:"from_pseudonym,omitempty" json:"from_pseudonym,omitempty"`
+	ToPseudonym   string    `bson:"to_pseudonym,omitempty" json:"to_pseudonym,omitempty"`
+	Anonymized    bool      `bson:"anonymized,omitempty" json:"anonymized,omitempty"`
+	CreatedAt     time.Time `bson:"created_at" json:"created_at"`
 }
 
 // BlockedUser 屏蔽用户数据模型
@@ -849,17 +1610,54 @@ type BlockedUser struct {
 	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
 }
 
+// ChatReaction 玩家对聊天消息的回应，每人每条消息一个。消息量大，字段名用单字母压缩存储
+type ChatReaction struct {
+	MessageID uint64 `bson:"m" json:"message_id"`
+	UserID    uint64 `bson:"u" json:"user_id"`
+	EmojiID   int32  `bson:"e" json:"emoji_id"`
+	Shadow    bool   `bson:"s,omitempty" json:"shadow,omitempty"` // 回应时被影子封禁，不计入消息的回应人数
+}
+
 // ChatRepository 聊天数据访问层
 type ChatRepository struct {
-	messageCollection *mongo.Collection
-	blockedCollection *mongo.Collection
+	messageCollection  *mongo.Collection
+	blockedCollection  *mongo.Collection
+	reactionCollection *mongo.Collection
+	historyReader      *mongo.Collection // 频道历史消息从副本读取
 }
 
 // NewChatRepository 创建聊天Repository
 func NewChatRepository(mm *MongoManager) *ChatRepository {
+	messageCollection := mm.GetCollection("chat_messages")
+
+	// 编辑和撤回按消息ID查找
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "message_id", Value: 1}},
+		},
+	}
+
+	messageCollection.Indexes().CreateMany(context.Background(), indexes)
+
+	// 每个玩家对每条消息只能有一个回应
+	reactionCollection := mm.GetCollection("chat_reactions")
+	reactionIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "m", Value: 1}, {Key: "u", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "u", Value: 1}},
+		},
+	}
+
+	reactionCollection.Indexes().CreateMany(context.Background(), reactionIndexes)
+
 	return &ChatRepository{
-		messageCollection: mm.GetCollection("chat_messages"),
-		blockedCollection: mm.GetCollection("blocked_users"),
+		messageCollection:  messageCollection,
+		blockedCollection:  mm.GetCollection("blocked_users"),
+		reactionCollection: reactionCollection,
+		historyReader:      mm.Reader("chat_messages", ReadSecondaryPreferred),
 	}
 }
 
@@ -1075,7 +1873,7 @@ func (r *MailRepository) GetMailsByUserID(userID uint64, mailType int32, limit,
 
 	// 获取邮件列表
 	opts := options.Find()
-	opts.SetSort(bson.D{{"send_time", -1}}) // 按发送时间倒序
+	opts.SetSort(bson.D{{Key: "send_time", Value: -1}}) // 按发送时间倒序
 	opts.SetLimit(int64(limit))
 	opts.SetSkip(int64(offset))
 
@@ -1192,29 +1990,191 @@ func (r *ChatRepository) SaveMessage(message *ChatMessage) error {
 	return err
 }
 
-// GetChatHistory 获取聊天历史
-func (r *ChatRepository) GetChatHistory(channelType int32, channelID uint64, limit, offset int32) ([]*ChatMessage, int64, error) {
+// GetMessage 根据消息ID获取聊天消息，不存在时返回nil
+func (r *ChatRepository) GetMessage(messageID uint64) (*ChatMessage, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	filter := bson.M{
-		"channel_type": channelType,
-		"channel_id":   channelID,
+	var message ChatMessage
+	err := r.messageCollection.FindOne(ctx, bson.M{"message_id": messageID}).Decode(&message)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
 	}
-
-	// 获取总数
-	total, err := r.messageCollection.CountDocuments(ctx, filter)
 	if err != nil {
-		return nil, 0, err
+		return nil, fmt.Errorf("failed to get chat message: %v", err)
 	}
+	return &message, nil
+}
 
-	// 获取消息列表
-	opts := options.Find()
-	opts.SetSort(bson.D{{"send_time", -1}}) // 按发送时间倒序
-	opts.SetLimit(int64(limit))
-	opts.SetSkip(int64(offset))
+// EditMessage 修改发送者自己在sentAfter之后发送且未撤回的消息，条件不满足时返回nil
+func (r *ChatRepository) EditMessage(messageID, fromUserID uint64, content string, sentAfter, editedAt uint32) (*ChatMessage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
-	cursor, err := r.messageCollection.Find(ctx, filter, opts)
+	filter := bson.M{
+		"message_id":   messageID,
+		"from_user_id": fromUserID,
+		"send_time":    bson.M{"$gte": sentAfter},
+		"recalled":     bson.M{"$ne": true},
+	}
+	update := bson.M{"$set": bson.M{
+		"content":   content,
+		"edited_at": editedAt,
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var message ChatMessage
+	err := r.messageCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&message)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to edit chat message: %v", err)
+	}
+	return &message, nil
+}
+
+// RecallMessage 撤回未撤回的消息，清空内容并保留墓碑记录。
+// fromUserID不为0时只能撤回该玩家在sentAfter之后发送的消息，为0时用于GM删除任意消息。条件不满足时返回nil
+func (r *ChatRepository) RecallMessage(messageID, fromUserID uint64, sentAfter uint32, recalledBy uint64, recalledAt uint32) (*ChatMessage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"message_id": messageID,
+		"recalled":   bson.M{"$ne": true},
+	}
+	if fromUserID != 0 {
+		filter["from_user_id"] = fromUserID
+		filter["send_time"] = bson.M{"$gte": sentAfter}
+	}
+	update := bson.M{"$set": bson.M{
+		"content":     "",
+		"recalled":    true,
+		"recalled_by": recalledBy,
+		"recalled_at": recalledAt,
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var message ChatMessage
+	err := r.messageCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&message)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to recall chat message: %v", err)
+	}
+	return &message, nil
+}
+
+// SetReaction 设置玩家对消息的回应，emojiID为0时取消回应，返回更新回应人数后的消息。
+// shadow为true时只保存回应，不计入人数
+func (r *ChatRepository) SetReaction(messageID, userID uint64, emojiID int32, shadow bool) (*ChatMessage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"m": messageID, "u": userID}
+	var previous ChatReaction
+	var err error
+	if emojiID == 0 {
+		err = r.reactionCollection.FindOneAndDelete(ctx, filter).Decode(&previous)
+	} else {
+		update := bson.M{"$set": bson.M{"e": emojiID, "s": shadow}}
+		opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before)
+		err = r.reactionCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&previous)
+	}
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("failed to save chat reaction: %v", err)
+	}
+
+	// 只调整变化的表情的人数
+	counts := make(map[string]int32)
+	if err == nil && !previous.Shadow && previous.EmojiID != 0 {
+		counts[strconv.Itoa(int(previous.EmojiID))]--
+	}
+	if emojiID != 0 && !shadow {
+		counts[strconv.Itoa(int(emojiID))]++
+	}
+	inc := bson.M{}
+	for emoji, delta := range counts {
+		if delta != 0 {
+			inc["reactions."+emoji] = delta
+		}
+	}
+	if len(inc) == 0 {
+		return r.GetMessage(messageID)
+	}
+
+	var message ChatMessage
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = r.messageCollection.FindOneAndUpdate(ctx, bson.M{"message_id": messageID}, bson.M{"$inc": inc}, opts).Decode(&message)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update chat reaction counts: %v", err)
+	}
+	return &message, nil
+}
+
+// GetUserReactions 获取玩家对一批消息的回应，键为消息ID
+func (r *ChatRepository) GetUserReactions(userID uint64, messageIDs []uint64) (map[uint64]int32, error) {
+	reactions := make(map[uint64]int32)
+	if len(messageIDs) == 0 {
+		return reactions, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := r.reactionCollection.Find(ctx, bson.M{"u": userID, "m": bson.M{"$in": messageIDs}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat reactions: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var reaction ChatReaction
+		if err := cursor.Decode(&reaction); err != nil {
+			continue
+		}
+		reactions[reaction.MessageID] = reaction.EmojiID
+	}
+	return reactions, nil
+}
+
+// visibleTo 只保留viewerID可见的消息，影子封禁的消息只有发送者可见
+func visibleTo(filter bson.M, viewerID uint64) bson.M {
+	return bson.M{"$and": []bson.M{
+		filter,
+		{"$or": []bson.M{{"shadow": bson.M{"$ne": true}}, {"from_user_id": viewerID}}},
+	}}
+}
+
+// GetChatHistory 获取世界内频道中viewerID可见的聊天历史
+func (r *ChatRepository) GetChatHistory(viewerID uint64, worldID uint32, channelType int32, channelID uint64, limit, offset int32) ([]*ChatMessage, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := visibleTo(bson.M{
+		"world_id":     worldID,
+		"channel_type": channelType,
+		"channel_id":   channelID,
+	}, viewerID)
+
+	// 获取总数
+	total, err := r.historyReader.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 获取消息列表
+	opts := options.Find()
+	opts.SetSort(bson.D{{Key: "send_time", Value: -1}}) // 按发送时间倒序
+	opts.SetLimit(int64(limit))
+	opts.SetSkip(int64(offset))
+
+	cursor, err := r.historyReader.Find(ctx, filter, opts)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -1232,12 +2192,12 @@ func (r *ChatRepository) GetChatHistory(channelType int32, channelID uint64, lim
 	return messages, total, nil
 }
 
-// GetPrivateMessages 获取私聊消息
+// GetPrivateMessages 获取userID1可见的与userID2的私聊消息
 func (r *ChatRepository) GetPrivateMessages(userID1, userID2 uint64, limit, offset int32) ([]*ChatMessage, int64, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	filter := bson.M{
+	filter := visibleTo(bson.M{
 		"$or": []bson.M{
 			{
 				"from_user_id": userID1,
@@ -1248,8 +2208,7 @@ func (r *ChatRepository) GetPrivateMessages(userID1, userID2 uint64, limit, offs
 				"to_user_id":   userID1,
 			},
 		},
-		"channel_type": 1, // 私聊类型
-	}
+	}, userID1)
 
 	// 获取总数
 	total, err := r.messageCollection.CountDocuments(ctx, filter)
@@ -1259,7 +2218,7 @@ func (r *ChatRepository) GetPrivateMessages(userID1, userID2 uint64, limit, offs
 
 	// 获取消息列表
 	opts := options.Find()
-	opts.SetSort(bson.D{{"send_time", -1}})
+	opts.SetSort(bson.D{{Key: "send_time", Value: -1}})
 	opts.SetLimit(int64(limit))
 	opts.SetSkip(int64(offset))
 
@@ -1357,6 +2316,29 @@ func (r *ChatRepository) IsUserBlocked(userID, targetID uint64) (bool, error) {
 	return true, nil
 }
 
+// GetBlockedUsers 获取用户屏蔽的所有用户
+func (r *ChatRepository) GetBlockedUsers(userID uint64) ([]uint64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := r.blockedCollection.Find(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var blocked []BlockedUser
+	if err := cursor.All(ctx, &blocked); err != nil {
+		return nil, err
+	}
+
+	targetIDs := make([]uint64, 0, len(blocked))
+	for _, b := range blocked {
+		targetIDs = append(targetIDs, b.TargetID)
+	}
+	return targetIDs, nil
+}
+
 // NewRoomRepository 创建房间仓库
 func NewRoomRepository(mm *MongoManager) *RoomRepository {
 	collection := mm.GetCollection("rooms")
@@ -1367,6 +2349,9 @@ func NewRoomRepository(mm *MongoManager) *RoomRepository {
 			Keys:    bson.D{{Key: "room_id", Value: 1}},
 			Options: options.Index().SetUnique(true),
 		},
+		{
+			Keys: bson.D{{Key: "world_id", Value: 1}, {Key: "status", Value: 1}},
+		},
 		{
 			Keys: bson.D{{Key: "status", Value: 1}},
 		},
@@ -1376,15 +2361,27 @@ func NewRoomRepository(mm *MongoManager) *RoomRepository {
 		{
 			Keys: bson.D{{Key: "owner_id", Value: 1}},
 		},
+		{
+			Keys: bson.D{{Key: "players.user_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "spectators.user_id", Value: 1}},
+		},
 		{
 			Keys: bson.D{{Key: "created_at", Value: -1}},
 		},
+		{
+			// 按房间名搜索，房间名不做词干处理
+			Keys:    bson.D{{Key: "room_name", Value: "text"}},
+			Options: options.Index().SetDefaultLanguage("none"),
+		},
 	}
 
 	collection.Indexes().CreateMany(context.Background(), indexes)
 
 	return &RoomRepository{
 		collection: collection,
+		secondary:  mm.Reader("rooms", ReadSecondaryPreferred),
 	}
 }
 
@@ -1415,9 +2412,9 @@ func (rr *RoomRepository) GetRoomByID(roomID uint64) (*Room, error) {
 	return &room, nil
 }
 
-// GetRoomList 获取房间列表
-func (rr *RoomRepository) GetRoomList(gameType int32, limit int64, offset int64) ([]*Room, error) {
-	filter := bson.M{}
+// GetRoomList 获取世界内的房间列表
+func (rr *RoomRepository) GetRoomList(worldID uint32, gameType int32, limit int64, offset int64) ([]*Room, error) {
+	filter := bson.M{"world_id": worldID}
 	if gameType > 0 {
 		filter["game_type"] = gameType
 	}
@@ -1443,6 +2440,27 @@ func (rr *RoomRepository) GetRoomList(gameType int32, limit int64, offset int64)
 	return rooms, nil
 }
 
+// SearchByName 按房间名搜索世界内等待中的公开房间
+func (rr *RoomRepository) SearchByName(query string, worldID uint32, limit int64) ([]NameMatch, error) {
+	filter := bson.M{"world_id": worldID, "status": 0, "is_private": false}
+	return searchByName(rr.secondary, "room_id", []string{"room_name"}, filter, query, limit)
+}
+
+// GetRoomsByIDs 批量获取房间，不存在的房间不返回
+func (rr *RoomRepository) GetRoomsByIDs(roomIDs []uint64) ([]*Room, error) {
+	cursor, err := rr.collection.Find(context.Background(), bson.M{"room_id": bson.M{"$in": roomIDs}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rooms: %v", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var rooms []*Room
+	if err := cursor.All(context.Background(), &rooms); err != nil {
+		return nil, fmt.Errorf("failed to decode rooms: %v", err)
+	}
+	return rooms, nil
+}
+
 // UpdateRoom 更新房间信息
 func (rr *RoomRepository) UpdateRoom(room *Room) error {
 	room.UpdatedAt = time.Now()
@@ -1489,6 +2507,122 @@ func (rr *RoomRepository) RemovePlayerFromRoom(roomID uint64, userID uint64) err
 	return nil
 }
 
+// FindRoomByPlayer 查找玩家所在的未结束房间，玩家不在房间中时返回nil
+func (rr *RoomRepository) FindRoomByPlayer(userID uint64) (*Room, error) {
+	filter := bson.M{"players.user_id": userID, "status": bson.M{"$ne": 2}}
+	opts := options.FindOne().SetSort(bson.D{{Key: "updated_at", Value: -1}})
+
+	var room Room
+	err := rr.collection.FindOne(context.Background(), filter, opts).Decode(&room)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find room of player: %v", err)
+	}
+	return &room, nil
+}
+
+// AddSpectator 添加观战者，房间已结束、观战人数已满或已在观战时返回false
+func (rr *RoomRepository) AddSpectator(roomID uint64, spectator RoomPlayer, maxSpectators int) (bool, error) {
+	filter := bson.M{
+		"room_id":            roomID,
+		"status":             bson.M{"$ne": 2},
+		"spectators.user_id": bson.M{"$ne": spectator.UserID},
+		fmt.Sprintf("spectators.%d", maxSpectators-1): bson.M{"$exists": false},
+	}
+	update := bson.M{
+		"$push": bson.M{"spectators": spectator},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+
+	result, err := rr.collection.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to add spectator: %v", err)
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// RemoveSpectator 把玩家从所有观战的房间中移除
+func (rr *RoomRepository) RemoveSpectator(userID uint64) (int64, error) {
+	filter := bson.M{"spectators.user_id": userID}
+	update := bson.M{
+		"$pull": bson.M{"spectators": bson.M{"user_id": userID}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+
+	result, err := rr.collection.UpdateMany(context.Background(), filter, update)
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove spectator: %v", err)
+	}
+	return result.ModifiedCount, nil
+}
+
+// IsSpectator 玩家是否在观战房间
+func (rr *RoomRepository) IsSpectator(roomID, userID uint64) (bool, error) {
+	count, err := rr.collection.CountDocuments(context.Background(), bson.M{"room_id": roomID, "spectators.user_id": userID})
+	if err != nil {
+		return false, fmt.Errorf("failed to check spectator: %v", err)
+	}
+	return count > 0, nil
+}
+
+// SetRoomGame 记录房间开始的游戏和运行游戏的节点，房间进入游戏中状态
+func (rr *RoomRepository) SetRoomGame(roomID, gameID uint64, node string) error {
+	filter := bson.M{"room_id": roomID, "status": bson.M{"$ne": 2}}
+	update := bson.M{"$set": bson.M{
+		"status":     1,
+		"game_id":    gameID,
+		"node":       node,
+		"updated_at": time.Now(),
+	}}
+
+	if _, err := rr.collection.UpdateOne(context.Background(), filter, update); err != nil {
+		return fmt.Errorf("failed to set room game: %v", err)
+	}
+	return nil
+}
+
+// EndRoomGame 房间的游戏结束，房间进入已结束状态并清空观战者
+func (rr *RoomRepository) EndRoomGame(roomID, gameID uint64) error {
+	filter := bson.M{"room_id": roomID, "game_id": gameID}
+	update := bson.M{
+		"$set":   bson.M{"status": 2, "updated_at": time.Now()},
+		"$unset": bson.M{"spectators": ""},
+	}
+
+	if _, err := rr.collection.UpdateOne(context.Background(), filter, update); err != nil {
+		return fmt.Errorf("failed to end room game: %v", err)
+	}
+	return nil
+}
+
+// MoveRoomGame 房间的游戏迁移到其他节点，只有房间的游戏仍在from节点时才修改，返回是否修改
+func (rr *RoomRepository) MoveRoomGame(roomID, gameID uint64, from, to string) (bool, error) {
+	filter := bson.M{"room_id": roomID, "game_id": gameID, "node": from, "status": 1}
+	update := bson.M{"$set": bson.M{"node": to, "updated_at": time.Now()}}
+
+	result, err := rr.collection.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to move room game: %v", err)
+	}
+	return result.MatchedCount == 1, nil
+}
+
+// ResetRoomGame 开始游戏失败时撤销SetRoomGame，房间回到等待中状态；房间已开始其他游戏时不修改
+func (rr *RoomRepository) ResetRoomGame(roomID, gameID uint64) error {
+	filter := bson.M{"room_id": roomID, "game_id": gameID, "status": 1}
+	update := bson.M{
+		"$set":   bson.M{"status": 0, "updated_at": time.Now()},
+		"$unset": bson.M{"game_id": ""},
+	}
+
+	if _, err := rr.collection.UpdateOne(context.Background(), filter, update); err != nil {
+		return fmt.Errorf("failed to reset room game: %v", err)
+	}
+	return nil
+}
+
 // DeleteRoom 删除房间
 func (rr *RoomRepository) DeleteRoom(roomID uint64) error {
 	filter := bson.M{"room_id": roomID}
@@ -1499,9 +2633,26 @@ func (rr *RoomRepository) DeleteRoom(roomID uint64) error {
 	return nil
 }
 
-// CountRooms 统计房间数量
-func (rr *RoomRepository) CountRooms(gameType int32) (int64, error) {
-	filter := bson.M{}
+// DeleteIdleRooms 删除在指定时间之前不再活跃的房间，包括已结束和无人的房间
+func (rr *RoomRepository) DeleteIdleRooms(before time.Time) (int64, error) {
+	filter := bson.M{
+		"updated_at": bson.M{"$lt": before},
+		"$or": []bson.M{
+			{"status": 2},
+			{"current_players": bson.M{"$lte": 0}},
+		},
+	}
+
+	result, err := rr.collection.DeleteMany(context.Background(), filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete idle rooms: %v", err)
+	}
+	return result.DeletedCount, nil
+}
+
+// CountRooms 统计世界内的房间数量
+func (rr *RoomRepository) CountRooms(worldID uint32, gameType int32) (int64, error) {
+	filter := bson.M{"world_id": worldID}
 	if gameType > 0 {
 		filter["game_type"] = gameType
 	}
@@ -1513,3 +2664,3121 @@ func (rr *RoomRepository) CountRooms(gameType int32) (int64, error) {
 	}
 	return count, nil
 }
+
+// ReplayRepository 房间事件回放仓库
+type ReplayRepository struct {
+	collection *mongo.Collection
+}
+
+// RoomEvent 房间事件模型
+type RoomEvent struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	RoomID    uint64             `bson:"room_id" json:"room_id"`
+	Seq       uint64             `bson:"seq" json:"seq"` // 房间内事件序号
+	Type      string             `bson:"type" json:"type"`
+	PlayerID  uint64             `bson:"player_id" json:"player_id"`
+	Data      interface{}        `bson:"data,omitempty" json:"data"`
+	Timestamp time.Time          `bson:"timestamp" json:"timestamp"`
+}
+
+// NewReplayRepository 创建房间事件回放仓库
+func NewReplayRepository(mm *MongoManager) *ReplayRepository {
+	collection := mm.GetCollection("room_events")
+
+	// 创建索引，唯一索引保证重试写入时不会重复
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "room_id", Value: 1}, {Key: "seq", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "timestamp", Value: -1}},
+		},
+	}
+
+	collection.Indexes().CreateMany(context.Background(), indexes)
+
+	return &ReplayRepository{
+		collection: collection,
+	}
+}
+
+// SaveEvents 批量写入房间事件，已存在的序号将被跳过
+func (rr *ReplayRepository) SaveEvents(events []*RoomEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, len(events))
+	for i, event := range events {
+		docs[i] = event
+	}
+
+	_, err := rr.collection.InsertMany(context.Background(), docs, options.InsertMany().SetOrdered(false))
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("failed to save room events: %v", err)
+	}
+	return nil
+}
+
+// GetRoomEvents 按序号获取房间事件
+func (rr *ReplayRepository) GetRoomEvents(roomID uint64, fromSeq uint64, limit int64) ([]*RoomEvent, error) {
+	filter := bson.M{
+		"room_id": roomID,
+		"seq":     bson.M{"$gte": fromSeq},
+	}
+	options := options.Find().
+		SetLimit(limit).
+		SetSort(bson.D{{Key: "seq", Value: 1}})
+
+	cursor, err := rr.collection.Find(context.Background(), filter, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get room events: %v", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var events []*RoomEvent
+	if err := cursor.All(context.Background(), &events); err != nil {
+		return nil, fmt.Errorf("failed to decode room events: %v", err)
+	}
+
+	return events, nil
+}
+
+// JobRunRepository 定时任务执行记录仓库
+type JobRunRepository struct {
+	collection *mongo.Collection
+}
+
+// JobRun 定时任务执行记录
+type JobRun struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	JobName     string             `bson:"job_name" json:"job_name"`
+	NodeID      string             `bson:"node_id" json:"node_id"`
+	ScheduledAt time.Time          `bson:"scheduled_at" json:"scheduled_at"` // 计划执行时间，集群内同一次执行相同
+	StartedAt   time.Time          `bson:"started_at" json:"started_at"`
+	FinishedAt  time.Time          `bson:"finished_at" json:"finished_at"`
+	DurationMs  int64              `bson:"duration_ms" json:"duration_ms"`
+	Status      string             `bson:"status" json:"status"` // success, failed
+	Error       string             `bson:"error,omitempty" json:"error,omitempty"`
+}
+
+// NewJobRunRepository 创建定时任务执行记录仓库，retention大于0时按开始时间自动过期
+func NewJobRunRepository(mm *MongoManager, retention time.Duration) *JobRunRepository {
+	collection := mm.GetCollection("job_runs")
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "job_name", Value: 1}, {Key: "started_at", Value: -1}},
+		},
+	}
+	if retention > 0 {
+		indexes = append(indexes, mongo.IndexModel{
+			Keys:    bson.D{{Key: "started_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(retention.Seconds())),
+		})
+	}
+
+	collection.Indexes().CreateMany(context.Background(), indexes)
+
+	return &JobRunRepository{
+		collection: collection,
+	}
+}
+
+// SaveRun 保存执行记录
+func (jr *JobRunRepository) SaveRun(run *JobRun) error {
+	if _, err := jr.collection.InsertOne(context.Background(), run); err != nil {
+		return fmt.Errorf("failed to save job run: %v", err)
+	}
+	return nil
+}
+
+// GetRuns 获取任务最近的执行记录，jobName为空时返回所有任务
+func (jr *JobRunRepository) GetRuns(jobName string, limit int64) ([]*JobRun, error) {
+	filter := bson.M{}
+	if jobName != "" {
+		filter["job_name"] = jobName
+	}
+	options := options.Find().
+		SetLimit(limit).
+		SetSort(bson.D{{Key: "started_at", Value: -1}})
+
+	cursor, err := jr.collection.Find(context.Background(), filter, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job runs: %v", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var runs []*JobRun
+	if err := cursor.All(context.Background(), &runs); err != nil {
+		return nil, fmt.Errorf("failed to decode job runs: %v", err)
+	}
+
+	return runs, nil
+}
+
+// GetRecord 根据游戏ID获取游戏记录
+func (grr *GameRecordRepository) GetRecord(gameID uint64) (*GameRecord, error) {
+	var record GameRecord
+	err := grr.collection.FindOne(context.Background(), bson.M{"game_id": gameID}).Decode(&record)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("game record not found")
+		}
+		return nil, fmt.Errorf("failed to get game record: %v", err)
+	}
+	return &record, nil
+}
+
+// AbortRecord 将进行中的游戏记录标记为异常结束，记录不存在时不报错
+func (grr *GameRecordRepository) AbortRecord(gameID uint64) error {
+	filter := bson.M{"game_id": gameID, "status": 0}
+	update := bson.M{"$set": bson.M{"status": 2, "updated_at": time.Now()}}
+
+	if _, err := grr.collection.UpdateOne(context.Background(), filter, update); err != nil {
+		return fmt.Errorf("failed to abort game record: %v", err)
+	}
+	return nil
+}
+
+// GetEndedRecords 获取在时间范围内结束的游戏记录，按结束时间排序
+func (grr *GameRecordRepository) GetEndedRecords(since, until time.Time, limit int64) ([]*GameRecord, error) {
+	filter := bson.M{
+		"status":     1,
+		"updated_at": bson.M{"$gte": since, "$lt": until},
+	}
+	options := options.Find().
+		SetLimit(limit).
+		SetSort(bson.D{{Key: "updated_at", Value: 1}})
+
+	cursor, err := grr.collection.Find(context.Background(), filter, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ended game records: %v", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var records []*GameRecord
+	if err := cursor.All(context.Background(), &records); err != nil {
+		return nil, fmt.Errorf("failed to decode game records: %v", err)
+	}
+
+	return records, nil
+}
+
+// 结算状态
+const (
+	SettlementProcessing = "processing"
+	SettlementSettled    = "settled"
+	SettlementFailed     = "failed"
+)
+
+// SettlementRepository 游戏结算记录仓库
+type SettlementRepository struct {
+	collection *mongo.Collection
+}
+
+// Settlement 游戏结算记录，每局游戏的每个结算步骤(积分、奖励、统计等)一条，以游戏ID和步骤名去重
+type Settlement struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	GameID     uint64             `bson:"game_id" json:"game_id"`
+	Step       string             `bson:"step" json:"step"`
+	Status     string             `bson:"status" json:"status"` // processing, settled, failed
+	NodeID     string             `bson:"node_id" json:"node_id"`
+	Attempts   int32              `bson:"attempts" json:"attempts"`
+	Error      string             `bson:"error,omitempty" json:"error,omitempty"`
+	LeaseUntil time.Time          `bson:"lease_until" json:"lease_until"` // 处理中的记录超过该时间视为节点故障，可被接管
+	SettledAt  time.Time          `bson:"settled_at,omitempty" json:"settled_at,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// NewSettlementRepository 创建游戏结算记录仓库
+func NewSettlementRepository(mm *MongoManager) *SettlementRepository {
+	collection := mm.GetCollection("game_settlements")
+
+	// 唯一索引保证同一局游戏的同一步骤只能被一个节点领取
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "game_id", Value: 1}, {Key: "step", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "updated_at", Value: 1}},
+		},
+	}
+
+	collection.Indexes().CreateMany(context.Background(), indexes)
+
+	return &SettlementRepository{
+		collection: collection,
+	}
+}
+
+// Claim 领取结算步骤，首次领取通过条件插入完成；已失败或处理超时的记录可重新领取，已结算或正在处理时返回false
+func (sr *SettlementRepository) Claim(gameID uint64, step, nodeID string, lease time.Duration) (bool, error) {
+	now := time.Now()
+	settlement := &Settlement{
+		GameID:     gameID,
+		Step:       step,
+		Status:     SettlementProcessing,
+		NodeID:     nodeID,
+		Attempts:   1,
+		LeaseUntil: now.Add(lease),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	_, err := sr.collection.InsertOne(context.Background(), settlement)
+	if err == nil {
+		return true, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return false, fmt.Errorf("failed to claim settlement: %v", err)
+	}
+
+	filter := bson.M{
+		"game_id": gameID,
+		"step":    step,
+		"$or": bson.A{
+			bson.M{"status": SettlementFailed},
+			bson.M{"status": SettlementProcessing, "lease_until": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":      SettlementProcessing,
+			"node_id":     nodeID,
+			"lease_until": now.Add(lease),
+			"updated_at":  now,
+		},
+		"$inc":   bson.M{"attempts": 1},
+		"$unset": bson.M{"error": ""},
+	}
+
+	result, err := sr.collection.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim settlement: %v", err)
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// Complete 标记结算步骤完成，只有当前领取者可以标记
+func (sr *SettlementRepository) Complete(gameID uint64, step, nodeID string) error {
+	return sr.finish(gameID, step, nodeID, bson.M{
+		"status":     SettlementSettled,
+		"settled_at": time.Now(),
+	})
+}
+
+// Fail 标记结算步骤失败，等待重新投递或对账时重试
+func (sr *SettlementRepository) Fail(gameID uint64, step, nodeID, reason string) error {
+	return sr.finish(gameID, step, nodeID, bson.M{
+		"status": SettlementFailed,
+		"error":  reason,
+	})
+}
+
+// finish 更新当前领取者持有的结算记录
+func (sr *SettlementRepository) finish(gameID uint64, step, nodeID string, fields bson.M) error {
+	fields["updated_at"] = time.Now()
+	filter := bson.M{
+		"game_id": gameID,
+		"step":    step,
+		"node_id": nodeID,
+		"status":  SettlementProcessing,
+	}
+
+	result, err := sr.collection.UpdateOne(context.Background(), filter, bson.M{"$set": fields})
+	if err != nil {
+		return fmt.Errorf("failed to update settlement: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("settlement %d/%s is no longer held by %s", gameID, step, nodeID)
+	}
+	return nil
+}
+
+// GetSettlements 获取游戏的所有结算记录
+func (sr *SettlementRepository) GetSettlements(gameID uint64) ([]*Settlement, error) {
+	cursor, err := sr.collection.Find(context.Background(), bson.M{"game_id": gameID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get settlements: %v", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var settlements []*Settlement
+	if err := cursor.All(context.Background(), &settlements); err != nil {
+		return nil, fmt.Errorf("failed to decode settlements: %v", err)
+	}
+
+	return settlements, nil
+}
+
+// GetMailsByBatch 获取批次发放的所有邮件
+func (r *MailRepository) GetMailsByBatch(batchID string) ([]*Mail, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := r.collection.Find(ctx, bson.M{"batch_id": batchID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch mails: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var mails []*Mail
+	if err := cursor.All(ctx, &mails); err != nil {
+		return nil, fmt.Errorf("failed to decode mails: %v", err)
+	}
+	return mails, nil
+}
+
+// GetBatchRecipients 获取批次中已经发给这些玩家的邮件，用于中断后继续发送时去重
+func (r *MailRepository) GetBatchRecipients(batchID string, userIDs []uint64) (map[uint64]bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"batch_id": batchID, "to_user_id": bson.M{"$in": userIDs}}
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"to_user_id": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch recipients: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	recipients := make(map[uint64]bool)
+	for cursor.Next(ctx) {
+		var mail Mail
+		if err := cursor.Decode(&mail); err != nil {
+			return nil, fmt.Errorf("failed to decode mail: %v", err)
+		}
+		recipients[mail.ToUserID] = true
+	}
+	return recipients, cursor.Err()
+}
+
+// BatchStats 统计批次中仍保留的邮件数、已读数和已领取数，玩家删除或过期的邮件不计入
+func (r *MailRepository) BatchStats(batchID string) (total, read, claimed int64, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"batch_id": batchID}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":     nil,
+			"total":   bson.M{"$sum": 1},
+			"read":    bson.M{"$sum": bson.M{"$cond": bson.A{"$is_read", 1, 0}}},
+			"claimed": bson.M{"$sum": bson.M{"$cond": bson.A{"$is_claimed", 1, 0}}},
+		}}},
+	}
+	cursor, err := r.secondary.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to aggregate batch mails: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var result struct {
+		Total   int64 `bson:"total"`
+		Read    int64 `bson:"read"`
+		Claimed int64 `bson:"claimed"`
+	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&result); err != nil {
+			return 0, 0, 0, fmt.Errorf("failed to decode batch stats: %v", err)
+		}
+	}
+	return result.Total, result.Read, result.Claimed, nil
+}
+
+// RevokeMail 撤回未领取的邮件，已领取或已删除时返回false
+func (r *MailRepository) RevokeMail(mailID uint64) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.collection.DeleteOne(ctx, bson.M{"mail_id": mailID, "is_claimed": false})
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke mail: %v", err)
+	}
+	return result.DeletedCount == 1, nil
+}
+
+// 账本货币类型，对应用户模型中的字段
+const (
+	CurrencyGold       = "gold"
+	CurrencyDiamond    = "diamond"
+	CurrencyExperience = "experience"
+)
+
+// LedgerRepository 货币账本仓库，所有货币变动以流水记录并原子更新用户余额
+type LedgerRepository struct {
+	collection *mongo.Collection
+	users      *mongo.Collection
+}
+
+// LedgerEntry 账本流水，TxID全局唯一，重复写入同一流水不会重复入账
+type LedgerEntry struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TxID       string             `bson:"tx_id" json:"tx_id"`
+	BatchID    string             `bson:"batch_id" json:"batch_id"` // 发放批次，同一次发放的流水共享批次ID
+	UserID     uint64             `bson:"user_id" json:"user_id"`
+	Currency   string             `bson:"currency" json:"currency"`
+	Amount     int64              `bson:"amount" json:"amount"` // 正数为发放，负数为扣除
+	Reason     string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	ReversalOf string             `bson:"reversal_of,omitempty" json:"reversal_of,omitempty"` // 冲正流水对应的原流水
+	ReversedBy string             `bson:"reversed_by,omitempty" json:"reversed_by,omitempty"` // 原流水被冲正时的冲正流水
+	// 玩家之间转移货币时的对方玩家，转出和转入各记一条流水
+	Counterparty uint64    `bson:"counterparty,omitempty" json:"counterparty,omitempty"`
+	CreatedAt    time.Time `bson:"created_at" json:"created_at"`
+}
+
+// NewLedgerRepository 创建货币账本仓库
+func NewLedgerRepository(mm *MongoManager) *LedgerRepository {
+	collection := mm.GetCollection("ledger")
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "tx_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "batch_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "created_at", Value: 1}},
+		},
+	}
+
+	collection.Indexes().CreateMany(context.Background(), indexes)
+
+	return &LedgerRepository{
+		collection: collection,
+		users:      mm.GetCollection("users"),
+	}
+}
+
+// ErrInsufficientBalance 余额不足以扣除
+var ErrInsufficientBalance = errors.New("insufficient balance")
+
+// Apply 写入流水并更新用户余额，流水已存在时返回false且不重复入账
+func (lr *LedgerRepository) Apply(entry *LedgerEntry) (bool, error) {
+	return lr.apply(entry, false)
+}
+
+// Spend 写入扣除流水并扣减余额，余额不足时不入账并返回ErrInsufficientBalance，消耗的钻石计入累计消耗
+func (lr *LedgerRepository) Spend(entry *LedgerEntry) (bool, error) {
+	if entry.Amount >= 0 {
+		return false, fmt.Errorf("invalid spend amount: %d", entry.Amount)
+	}
+	return lr.apply(entry, true)
+}
+
+// apply 写入流水并更新余额，checkBalance时只有余额足够才扣除
+func (lr *LedgerRepository) apply(entry *LedgerEntry, checkBalance bool) (bool, error) {
+	switch entry.Currency {
+	case CurrencyGold, CurrencyDiamond, CurrencyExperience:
+	default:
+		return false, fmt.Errorf("unknown currency: %s", entry.Currency)
+	}
+
+	entry.CreatedAt = time.Now()
+	result, err := lr.collection.InsertOne(context.Background(), entry)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to write ledger entry: %v", err)
+	}
+	entry.ID = result.InsertedID.(primitive.ObjectID)
+
+	update := bson.M{
+		"$inc": bson.M{entry.Currency: entry.Amount},
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+	filter := bson.M{"user_id": entry.UserID}
+	if checkBalance {
+		filter[entry.Currency] = bson.M{"$gte": -entry.Amount}
+		if entry.Currency == CurrencyDiamond {
+			update["$inc"].(bson.M)["spent"] = -entry.Amount
+		}
+	}
+	updated, err := lr.users.UpdateOne(context.Background(), filter, update)
+	if err == nil && updated.MatchedCount == 0 {
+		err = fmt.Errorf("user %d not found", entry.UserID)
+		if checkBalance {
+			err = ErrInsufficientBalance
+		}
+	}
+	if err != nil {
+		// 余额未更新，删除流水以便重试
+		lr.collection.DeleteOne(context.Background(), bson.M{"tx_id": entry.TxID})
+		if err == ErrInsufficientBalance {
+			return false, err
+		}
+		return false, fmt.Errorf("failed to update balance: %v", err)
+	}
+	return true, nil
+}
+
+// GetByBatch 获取批次的所有流水
+func (lr *LedgerRepository) GetByBatch(batchID string) ([]*LedgerEntry, error) {
+	options := options.Find().SetSort(bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: 1}})
+
+	cursor, err := lr.collection.Find(context.Background(), bson.M{"batch_id": batchID}, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ledger entries: %v", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var entries []*LedgerEntry
+	if err := cursor.All(context.Background(), &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode ledger entries: %v", err)
+	}
+	return entries, nil
+}
+
+// GetRange 按时间顺序获取(since, until]之间的流水
+func (lr *LedgerRepository) GetRange(since, until time.Time, limit int64) ([]*LedgerEntry, error) {
+	filter := bson.M{"created_at": bson.M{"$gt": since, "$lte": until}}
+	options := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}).SetLimit(limit)
+
+	cursor, err := lr.collection.Find(context.Background(), filter, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ledger entries: %v", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var entries []*LedgerEntry
+	if err := cursor.All(context.Background(), &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode ledger entries: %v", err)
+	}
+	return entries, nil
+}
+
+// GetLargeByUser 按时间倒序获取玩家since之后金额绝对值不小于minAmount的流水
+func (lr *LedgerRepository) GetLargeByUser(userID uint64, since time.Time, minAmount, limit int64) ([]*LedgerEntry, error) {
+	filter := bson.M{
+		"user_id":    userID,
+		"created_at": bson.M{"$gte": since},
+		"$or": bson.A{
+			bson.M{"amount": bson.M{"$gte": minAmount}},
+			bson.M{"amount": bson.M{"$lte": -minAmount}},
+		},
+	}
+	options := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit)
+
+	cursor, err := lr.collection.Find(context.Background(), filter, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ledger entries: %v", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var entries []*LedgerEntry
+	if err := cursor.All(context.Background(), &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode ledger entries: %v", err)
+	}
+	return entries, nil
+}
+
+// Reverse 冲正流水，写入金额相反的冲正流水并扣回余额(余额可能变为负数)，已冲正时返回false
+func (lr *LedgerRepository) Reverse(entry *LedgerEntry, batchID, reason string) (bool, error) {
+	if entry.ReversalOf != "" {
+		return false, fmt.Errorf("ledger entry %s is a reversal", entry.TxID)
+	}
+
+	reversalID := entry.TxID + ":reversal"
+	filter := bson.M{"tx_id": entry.TxID, "reversed_by": bson.M{"$exists": false}}
+	result, err := lr.collection.UpdateOne(context.Background(), filter, bson.M{"$set": bson.M{"reversed_by": reversalID}})
+	if err != nil {
+		return false, fmt.Errorf("failed to mark ledger entry reversed: %v", err)
+	}
+	if result.ModifiedCount == 0 {
+		return false, nil
+	}
+
+	applied, err := lr.Apply(&LedgerEntry{
+		TxID:       reversalID,
+		BatchID:    batchID,
+		UserID:     entry.UserID,
+		Currency:   entry.Currency,
+		Amount:     -entry.Amount,
+		Reason:     reason,
+		ReversalOf: entry.TxID,
+	})
+	if err != nil {
+		lr.collection.UpdateOne(context.Background(), bson.M{"tx_id": entry.TxID}, bson.M{"$unset": bson.M{"reversed_by": ""}})
+		return false, err
+	}
+	return applied, nil
+}
+
+// 风控案件状态
+const (
+	FraudCasePending   = "pending"
+	FraudCaseConfirmed = "confirmed"
+	FraudCaseDismissed = "dismissed"
+)
+
+// FraudCaseRepository 风控案件仓库，规则命中后创建案件等待GM审核
+type FraudCaseRepository struct {
+	collection *mongo.Collection
+}
+
+// FraudCase 风控案件，同一玩家同一规则同时只有一个待审核案件
+type FraudCase struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     uint64             `bson:"user_id" json:"user_id"`
+	Rule       string             `bson:"rule" json:"rule"`
+	Detail     string             `bson:"detail" json:"detail"`
+	Frozen     bool               `bson:"frozen" json:"frozen"` // 是否已自动冻结账号
+	Status     string             `bson:"status" json:"status"`
+	Hits       int64              `bson:"hits" json:"hits"` // 待审核期间再次命中的次数
+	ReviewedBy uint64             `bson:"reviewed_by,omitempty" json:"reviewed_by,omitempty"`
+	ReviewedAt time.Time          `bson:"reviewed_at,omitempty" json:"reviewed_at,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// NewFraudCaseRepository 创建风控案件仓库
+func NewFraudCaseRepository(mm *MongoManager) *FraudCaseRepository {
+	collection := mm.GetCollection("fraud_cases")
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "rule", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"status": FraudCasePending}),
+		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+	}
+
+	collection.Indexes().CreateMany(context.Background(), indexes)
+
+	return &FraudCaseRepository{
+		collection: collection,
+	}
+}
+
+// Open 创建待审核案件，已有待审核案件时累加命中次数并返回false
+func (fr *FraudCaseRepository) Open(fraudCase *FraudCase) (bool, error) {
+	now := time.Now()
+	filter := bson.M{"user_id": fraudCase.UserID, "rule": fraudCase.Rule, "status": FraudCasePending}
+	update := bson.M{
+		"$setOnInsert": bson.M{
+			"detail":     fraudCase.Detail,
+			"frozen":     fraudCase.Frozen,
+			"created_at": now,
+		},
+		"$inc": bson.M{"hits": 1},
+		"$set": bson.M{"updated_at": now},
+	}
+
+	result, err := fr.collection.UpdateOne(context.Background(), filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return false, fmt.Errorf("failed to open fraud case: %v", err)
+	}
+	return result.UpsertedCount == 1, nil
+}
+
+// List 按创建时间倒序获取指定状态的案件，status为空时获取所有案件
+func (fr *FraudCaseRepository) List(status string, limit int64) ([]*FraudCase, error) {
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+	options := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit)
+
+	cursor, err := fr.collection.Find(context.Background(), filter, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fraud cases: %v", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var cases []*FraudCase
+	if err := cursor.All(context.Background(), &cases); err != nil {
+		return nil, fmt.Errorf("failed to decode fraud cases: %v", err)
+	}
+	return cases, nil
+}
+
+// Resolve 审核待审核的案件，返回审核前的案件，案件不存在或已审核时返回nil
+func (fr *FraudCaseRepository) Resolve(caseID string, status string, reviewer uint64) (*FraudCase, error) {
+	id, err := primitive.ObjectIDFromHex(caseID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid case id: %s", caseID)
+	}
+
+	filter := bson.M{"_id": id, "status": FraudCasePending}
+	update := bson.M{"$set": bson.M{
+		"status":      status,
+		"reviewed_by": reviewer,
+		"reviewed_at": time.Now(),
+		"updated_at":  time.Now(),
+	}}
+
+	var fraudCase FraudCase
+	err = fr.collection.FindOneAndUpdate(context.Background(), filter, update).Decode(&fraudCase)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve fraud case: %v", err)
+	}
+	return &fraudCase, nil
+}
+
+// CountFrozen 统计玩家仍处于冻结的待审核案件数
+func (fr *FraudCaseRepository) CountFrozen(userID uint64) (int64, error) {
+	count, err := fr.collection.CountDocuments(context.Background(),
+		bson.M{"user_id": userID, "status": FraudCasePending, "frozen": true})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count fraud cases: %v", err)
+	}
+	return count, nil
+}
+
+// 邮件活动状态
+const (
+	MailCampaignRunning   = "running"
+	MailCampaignPaused    = "paused"
+	MailCampaignCancelled = "cancelled"
+	MailCampaignCompleted = "completed"
+)
+
+// MailCampaignRepository 邮件活动仓库，发送进度保存在活动中以便中断后继续
+type MailCampaignRepository struct {
+	collection *mongo.Collection
+}
+
+// MailTemplate 某个语言的邮件模板，可以使用{{.Nickname}}和{{.Level}}
+type MailTemplate struct {
+	Title   string `bson:"title" json:"title"`
+	Content string `bson:"content" json:"content"`
+}
+
+// MailCampaign 向玩家分群批量发送的邮件活动，邮件的批次ID为"campaign:活动ID"
+type MailCampaign struct {
+	ID        primitive.ObjectID      `bson:"_id,omitempty" json:"id"`
+	Segment   string                  `bson:"segment" json:"segment"`
+	Language  string                  `bson:"language" json:"language"` // 没有玩家语言的模板时使用的语言
+	Templates map[string]MailTemplate `bson:"templates" json:"templates"`
+	Rewards   []MailReward            `bson:"rewards,omitempty" json:"rewards"`
+	Status    string                  `bson:"status" json:"status"`
+	Cursor    uint64                  `bson:"cursor" json:"cursor"`   // 已处理到的用户ID，按用户ID顺序发送
+	Scanned   int64                   `bson:"scanned" json:"scanned"` // 已检查的玩家数
+	Sent      int64                   `bson:"sent" json:"sent"`
+	Failed    int64                   `bson:"failed" json:"failed"`
+	CreatedBy uint64                  `bson:"created_by" json:"created_by"`
+	CreatedAt time.Time               `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time               `bson:"updated_at" json:"updated_at"`
+	EndedAt   time.Time               `bson:"ended_at,omitempty" json:"ended_at,omitempty"`
+}
+
+// BatchID 活动邮件的批次ID，可用于回滚
+func (c *MailCampaign) BatchID() string {
+	return "campaign:" + c.ID.Hex()
+}
+
+// NewMailCampaignRepository 创建邮件活动仓库
+func NewMailCampaignRepository(mm *MongoManager) *MailCampaignRepository {
+	collection := mm.GetCollection("mail_campaigns")
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}},
+		},
+	}
+
+	collection.Indexes().CreateMany(context.Background(), indexes)
+
+	return &MailCampaignRepository{
+		collection: collection,
+	}
+}
+
+// Create 创建邮件活动，创建后立即开始发送
+func (cr *MailCampaignRepository) Create(campaign *MailCampaign) error {
+	now := time.Now()
+	campaign.ID = primitive.NewObjectID()
+	campaign.Status = MailCampaignRunning
+	campaign.CreatedAt = now
+	campaign.UpdatedAt = now
+
+	if _, err := cr.collection.InsertOne(context.Background(), campaign); err != nil {
+		return fmt.Errorf("failed to create mail campaign: %v", err)
+	}
+	return nil
+}
+
+// Get 获取邮件活动，不存在时返回nil
+func (cr *MailCampaignRepository) Get(campaignID string) (*MailCampaign, error) {
+	id, err := primitive.ObjectIDFromHex(campaignID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid campaign id: %s", campaignID)
+	}
+
+	var campaign MailCampaign
+	err = cr.collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&campaign)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mail campaign: %v", err)
+	}
+	return &campaign, nil
+}
+
+// List 按创建时间获取指定状态的活动，status为空时获取最近的活动
+func (cr *MailCampaignRepository) List(status string, limit int64) ([]*MailCampaign, error) {
+	filter := bson.M{}
+	sort := -1
+	if status != "" {
+		filter["status"] = status
+		sort = 1
+	}
+	options := options.Find().SetSort(bson.D{{Key: "created_at", Value: sort}}).SetLimit(limit)
+
+	cursor, err := cr.collection.Find(context.Background(), filter, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list mail campaigns: %v", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var campaigns []*MailCampaign
+	if err := cursor.All(context.Background(), &campaigns); err != nil {
+		return nil, fmt.Errorf("failed to decode mail campaigns: %v", err)
+	}
+	return campaigns, nil
+}
+
+// Advance 保存一批的发送进度，只有进度仍为from且活动仍在发送时更新，done为true时标记为完成
+func (cr *MailCampaignRepository) Advance(id primitive.ObjectID, from, to uint64, scanned, sent, failed int64, done bool) (bool, error) {
+	now := time.Now()
+	set := bson.M{"cursor": to, "updated_at": now}
+	if done {
+		set["status"] = MailCampaignCompleted
+		set["ended_at"] = now
+	}
+	update := bson.M{
+		"$set": set,
+		"$inc": bson.M{"scanned": scanned, "sent": sent, "failed": failed},
+	}
+
+	result, err := cr.collection.UpdateOne(context.Background(),
+		bson.M{"_id": id, "cursor": from, "status": MailCampaignRunning}, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to save campaign progress: %v", err)
+	}
+	return result.MatchedCount == 1, nil
+}
+
+// SetStatus 修改活动状态，只有当前状态在from中时修改
+func (cr *MailCampaignRepository) SetStatus(campaignID string, from []string, to string) (bool, error) {
+	id, err := primitive.ObjectIDFromHex(campaignID)
+	if err != nil {
+		return false, fmt.Errorf("invalid campaign id: %s", campaignID)
+	}
+
+	set := bson.M{"status": to, "updated_at": time.Now()}
+	if to == MailCampaignCancelled {
+		set["ended_at"] = time.Now()
+	}
+	result, err := cr.collection.UpdateOne(context.Background(),
+		bson.M{"_id": id, "status": bson.M{"$in": from}}, bson.M{"$set": set})
+	if err != nil {
+		return false, fmt.Errorf("failed to update campaign status: %v", err)
+	}
+	return result.MatchedCount == 1, nil
+}
+
+// AnnouncementRepository 公告仓库
+type AnnouncementRepository struct {
+	collection *mongo.Collection
+}
+
+// AnnouncementContent 某个语言的公告内容
+type AnnouncementContent struct {
+	Title   string `bson:"title" json:"title"`
+	Content string `bson:"content" json:"content"`
+}
+
+// Announcement 定时公告，在开始和结束时间之间按展示位置展示
+type Announcement struct {
+	ID        primitive.ObjectID             `bson:"_id,omitempty" json:"id"`
+	Channels  []string                       `bson:"channels" json:"channels"` // popup, marquee, mailbox
+	Language  string                         `bson:"language" json:"language"` // 没有玩家语言的内容时使用的语言
+	Contents  map[string]AnnouncementContent `bson:"contents" json:"contents"`
+	Priority  int32                          `bson:"priority" json:"priority"` // 越大越靠前
+	StartAt   time.Time                      `bson:"start_at" json:"start_at"`
+	EndAt     time.Time                      `bson:"end_at" json:"end_at"`
+	Pushed    bool                           `bson:"pushed" json:"pushed"` // 跑马灯是否已推送给在线玩家
+	CreatedBy uint64                         `bson:"created_by" json:"created_by"`
+	CreatedAt time.Time                      `bson:"created_at" json:"created_at"`
+}
+
+// NewAnnouncementRepository 创建公告仓库
+func NewAnnouncementRepository(mm *MongoManager) *AnnouncementRepository {
+	collection := mm.GetCollection("announcements")
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "end_at", Value: 1}, {Key: "start_at", Value: 1}},
+		},
+	}
+
+	collection.Indexes().CreateMany(context.Background(), indexes)
+
+	return &AnnouncementRepository{
+		collection: collection,
+	}
+}
+
+// Create 创建公告
+func (ar *AnnouncementRepository) Create(announcement *Announcement) error {
+	announcement.ID = primitive.NewObjectID()
+	announcement.CreatedAt = time.Now()
+
+	if _, err := ar.collection.InsertOne(context.Background(), announcement); err != nil {
+		return fmt.Errorf("failed to create announcement: %v", err)
+	}
+	return nil
+}
+
+// Get 获取公告，不存在时返回nil
+func (ar *AnnouncementRepository) Get(announcementID string) (*Announcement, error) {
+	id, err := primitive.ObjectIDFromHex(announcementID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid announcement id: %s", announcementID)
+	}
+
+	var announcement Announcement
+	err = ar.collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&announcement)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get announcement: %v", err)
+	}
+	return &announcement, nil
+}
+
+// Active 获取在until之前开始、from之后结束的公告，按优先级和开始时间倒序排列
+func (ar *AnnouncementRepository) Active(from, until time.Time) ([]*Announcement, error) {
+	filter := bson.M{
+		"start_at": bson.M{"$lte": until},
+		"end_at":   bson.M{"$gt": from},
+	}
+	options := options.Find().SetSort(bson.D{{Key: "priority", Value: -1}, {Key: "start_at", Value: -1}})
+	return ar.find(filter, options)
+}
+
+// List 获取最近创建的公告
+func (ar *AnnouncementRepository) List(limit int64) ([]*Announcement, error) {
+	options := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(limit)
+	return ar.find(bson.M{}, options)
+}
+
+// DuePush 获取已经开始、还没结束且还没推送的跑马灯公告
+func (ar *AnnouncementRepository) DuePush(now time.Time) ([]*Announcement, error) {
+	filter := bson.M{
+		"channels": "marquee",
+		"pushed":   false,
+		"start_at": bson.M{"$lte": now},
+		"end_at":   bson.M{"$gt": now},
+	}
+	return ar.find(filter, options.Find().SetSort(bson.D{{Key: "start_at", Value: 1}}))
+}
+
+// MarkPushed 标记跑马灯已推送，已被标记时返回false
+func (ar *AnnouncementRepository) MarkPushed(announcement *Announcement) (bool, error) {
+	result, err := ar.collection.UpdateOne(context.Background(),
+		bson.M{"_id": announcement.ID, "pushed": false}, bson.M{"$set": bson.M{"pushed": true}})
+	if err != nil {
+		return false, fmt.Errorf("failed to mark announcement pushed: %v", err)
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// Delete 删除公告，公告不存在时返回false
+func (ar *AnnouncementRepository) Delete(announcementID string) (bool, error) {
+	id, err := primitive.ObjectIDFromHex(announcementID)
+	if err != nil {
+		return false, fmt.Errorf("invalid announcement id: %s", announcementID)
+	}
+
+	result, err := ar.collection.DeleteOne(context.Background(), bson.M{"_id": id})
+	if err != nil {
+		return false, fmt.Errorf("failed to delete announcement: %v", err)
+	}
+	return result.DeletedCount == 1, nil
+}
+
+// find 按条件查询公告
+func (ar *AnnouncementRepository) find(filter bson.M, opts *options.FindOptions) ([]*Announcement, error) {
+	cursor, err := ar.collection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find announcements: %v", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var announcements []*Announcement
+	if err := cursor.All(context.Background(), &announcements); err != nil {
+		return nil, fmt.Errorf("failed to decode announcements: %v", err)
+	}
+	return announcements, nil
+}
+
+// 问卷题型
+const (
+	SurveyQuestionSingle   = "single"   // 单选
+	SurveyQuestionMultiple = "multiple" // 多选
+	SurveyQuestionRating   = "rating"   // 评分，答案为1到选项数之间的数字
+	SurveyQuestionText     = "text"     // 文本
+)
+
+// SurveyRepository 问卷仓库，问卷和玩家的回答分别保存，每个玩家对同一问卷只保留第一次提交的回答
+type SurveyRepository struct {
+	collection *mongo.Collection
+	responses  *mongo.Collection
+}
+
+// SurveyQuestion 问卷题目
+type SurveyQuestion struct {
+	ID       string   `bson:"id" json:"id"`
+	Type     string   `bson:"type" json:"type"`
+	Text     string   `bson:"text" json:"text"`
+	Options  []string `bson:"options,omitempty" json:"options,omitempty"` // 单选、多选的选项，评分的刻度说明
+	Required bool     `bson:"required" json:"required"`
+}
+
+// Survey GM创建的问卷，只有目标分群内的玩家可以看到
+type Survey struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Title     string             `bson:"title" json:"title"`
+	Segment   string             `bson:"segment" json:"segment"`
+	Questions []SurveyQuestion   `bson:"questions" json:"questions"`
+	StartAt   time.Time          `bson:"start_at" json:"start_at"`
+	EndAt     time.Time          `bson:"end_at" json:"end_at"`
+	CreatedBy uint64             `bson:"created_by" json:"created_by"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// SurveyResponse 玩家的回答，按题目ID保存，单选、评分和文本只有一个答案
+type SurveyResponse struct {
+	ID        primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	SurveyID  primitive.ObjectID  `bson:"survey_id" json:"survey_id"`
+	UserID    uint64              `bson:"user_id" json:"user_id"`
+	Answers   map[string][]string `bson:"answers" json:"answers"`
+	CreatedAt time.Time           `bson:"created_at" json:"created_at"`
+}
+
+// NewSurveyRepository 创建问卷仓库
+func NewSurveyRepository(mm *MongoManager) *SurveyRepository {
+	collection := mm.GetCollection("surveys")
+	collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "end_at", Value: 1}, {Key: "start_at", Value: 1}},
+	})
+
+	responses := mm.GetCollection("survey_responses")
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "survey_id", Value: 1}, {Key: "user_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+		},
+	}
+	responses.Indexes().CreateMany(context.Background(), indexes)
+
+	return &SurveyRepository{
+		collection: collection,
+		responses:  responses,
+	}
+}
+
+// Create 创建问卷
+func (sr *SurveyRepository) Create(survey *Survey) error {
+	survey.ID = primitive.NewObjectID()
+	survey.CreatedAt = time.Now()
+
+	if _, err := sr.collection.InsertOne(context.Background(), survey); err != nil {
+		return fmt.Errorf("failed to create survey: %v", err)
+	}
+	return nil
+}
+
+// Get 获取问卷，不存在时返回nil
+func (sr *SurveyRepository) Get(surveyID string) (*Survey, error) {
+	id, err := primitive.ObjectIDFromHex(surveyID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid survey id: %s", surveyID)
+	}
+
+	var survey Survey
+	err = sr.collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&survey)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get survey: %v", err)
+	}
+	return &survey, nil
+}
+
+// Active 获取进行中的问卷，按开始时间倒序排列
+func (sr *SurveyRepository) Active(now time.Time) ([]*Survey, error) {
+	filter := bson.M{
+		"start_at": bson.M{"$lte": now},
+		"end_at":   bson.M{"$gt": now},
+	}
+	return sr.find(filter, options.Find().SetSort(bson.D{{Key: "start_at", Value: -1}}))
+}
+
+// List 获取最近创建的问卷
+func (sr *SurveyRepository) List(limit int64) ([]*Survey, error) {
+	return sr.find(bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(limit))
+}
+
+// find 按条件查询问卷
+func (sr *SurveyRepository) find(filter bson.M, opts *options.FindOptions) ([]*Survey, error) {
+	cursor, err := sr.collection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find surveys: %v", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var surveys []*Survey
+	if err := cursor.All(context.Background(), &surveys); err != nil {
+		return nil, fmt.Errorf("failed to decode surveys: %v", err)
+	}
+	return surveys, nil
+}
+
+// SaveResponse 保存玩家的回答，玩家已经回答过该问卷时返回false且不覆盖
+func (sr *SurveyRepository) SaveResponse(response *SurveyResponse) (bool, error) {
+	response.CreatedAt = time.Now()
+
+	result, err := sr.responses.InsertOne(context.Background(), response)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to save survey response: %v", err)
+	}
+	response.ID = result.InsertedID.(primitive.ObjectID)
+	return true, nil
+}
+
+// Answered 获取玩家在指定问卷中已经回答过的问卷
+func (sr *SurveyRepository) Answered(userID uint64, surveyIDs []primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	answered := make(map[primitive.ObjectID]bool)
+	if len(surveyIDs) == 0 {
+		return answered, nil
+	}
+
+	filter := bson.M{"user_id": userID, "survey_id": bson.M{"$in": surveyIDs}}
+	cursor, err := sr.responses.Find(context.Background(), filter,
+		options.Find().SetProjection(bson.M{"survey_id": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find survey responses: %v", err)
+	}
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(context.Background()) {
+		var response SurveyResponse
+		if err := cursor.Decode(&response); err != nil {
+			return nil, fmt.Errorf("failed to decode survey response: %v", err)
+		}
+		answered[response.SurveyID] = true
+	}
+	return answered, cursor.Err()
+}
+
+// CountResponses 统计问卷的回答数
+func (sr *SurveyRepository) CountResponses(surveyID primitive.ObjectID) (int64, error) {
+	count, err := sr.responses.CountDocuments(context.Background(), bson.M{"survey_id": surveyID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count survey responses: %v", err)
+	}
+	return count, nil
+}
+
+// ForEachResponse 按提交时间遍历问卷的所有回答，fn返回错误时停止
+func (sr *SurveyRepository) ForEachResponse(surveyID primitive.ObjectID, fn func(response *SurveyResponse) error) error {
+	cursor, err := sr.responses.Find(context.Background(), bson.M{"survey_id": surveyID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return fmt.Errorf("failed to find survey responses: %v", err)
+	}
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(context.Background()) {
+		var response SurveyResponse
+		if err := cursor.Decode(&response); err != nil {
+			return fmt.Errorf("failed to decode survey response: %v", err)
+		}
+		if err := fn(&response); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// 客服工单状态
+const (
+	TicketStatusOpen     = "open"     // 等待分配
+	TicketStatusAssigned = "assigned" // 已分配给GM
+	TicketStatusResolved = "resolved" // 已解决
+)
+
+// SupportTicketRepository 客服工单仓库
+type SupportTicketRepository struct {
+	collection *mongo.Collection
+}
+
+// TicketReply GM的回复，同时作为邮件发给玩家
+type TicketReply struct {
+	GMUserID  uint64    `bson:"gm_user_id" json:"gm_user_id"`
+	Content   string    `bson:"content" json:"content"`
+	MailID    uint64    `bson:"mail_id" json:"mail_id"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+// SupportTicket 玩家提交的客服工单
+type SupportTicket struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID       uint64             `bson:"user_id" json:"user_id"`
+	Category     string             `bson:"category" json:"category"`
+	Subject      string             `bson:"subject" json:"subject"`
+	Text         string             `bson:"text" json:"text"`
+	Attachments  []string           `bson:"attachments,omitempty" json:"attachments,omitempty"` // 客户端上传后的附件地址
+	Language     string             `bson:"language,omitempty" json:"language,omitempty"`
+	Status       string             `bson:"status" json:"status"`
+	AssignedTo   uint64             `bson:"assigned_to,omitempty" json:"assigned_to,omitempty"`
+	Replies      []TicketReply      `bson:"replies,omitempty" json:"replies,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+	FirstReplyAt time.Time          `bson:"first_reply_at,omitempty" json:"first_reply_at,omitempty"`
+	ResolvedAt   time.Time          `bson:"resolved_at,omitempty" json:"resolved_at,omitempty"`
+}
+
+// TicketFilter 工单查询条件，零值的条件不检查
+type TicketFilter struct {
+	UserID     uint64
+	Status     string
+	AssignedTo uint64
+}
+
+// TicketCount 按分类和状态统计的工单数，只按分类统计时状态为空
+type TicketCount struct {
+	Category string `bson:"category" json:"category"`
+	Status   string `bson:"status,omitempty" json:"status,omitempty"`
+	Count    int64  `bson:"count" json:"count"`
+}
+
+// TicketResolution 按分类统计的已解决工单数和平均处理时长
+type TicketResolution struct {
+	Category string  `bson:"_id" json:"category"`
+	Resolved int64   `bson:"resolved" json:"resolved"`
+	AvgMs    float64 `bson:"avg_ms" json:"avg_ms"` // 从提交到解决的平均毫秒数
+}
+
+// NewSupportTicketRepository 创建客服工单仓库
+func NewSupportTicketRepository(mm *MongoManager) *SupportTicketRepository {
+	collection := mm.GetCollection("support_tickets")
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "status", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "assigned_to", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "resolved_at", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "created_at", Value: 1}},
+		},
+	}
+
+	collection.Indexes().CreateMany(context.Background(), indexes)
+
+	return &SupportTicketRepository{
+		collection: collection,
+	}
+}
+
+// Create 创建工单
+func (tr *SupportTicketRepository) Create(ticket *SupportTicket) error {
+	ticket.ID = primitive.NewObjectID()
+	ticket.Status = TicketStatusOpen
+	ticket.CreatedAt = time.Now()
+	ticket.UpdatedAt = ticket.CreatedAt
+
+	if _, err := tr.collection.InsertOne(context.Background(), ticket); err != nil {
+		return fmt.Errorf("failed to create support ticket: %v", err)
+	}
+	return nil
+}
+
+// Get 获取工单，不存在时返回nil
+func (tr *SupportTicketRepository) Get(ticketID string) (*SupportTicket, error) {
+	id, err := primitive.ObjectIDFromHex(ticketID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ticket id: %s", ticketID)
+	}
+
+	var ticket SupportTicket
+	err = tr.collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&ticket)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get support ticket: %v", err)
+	}
+	return &ticket, nil
+}
+
+// List 按条件获取最近提交的工单
+func (tr *SupportTicketRepository) List(filter TicketFilter, limit int64) ([]*SupportTicket, error) {
+	query := bson.M{}
+	if filter.UserID != 0 {
+		query["user_id"] = filter.UserID
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	if filter.AssignedTo != 0 {
+		query["assigned_to"] = filter.AssignedTo
+	}
+
+	cursor, err := tr.collection.Find(context.Background(), query,
+		options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find support tickets: %v", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var tickets []*SupportTicket
+	if err := cursor.All(context.Background(), &tickets); err != nil {
+		return nil, fmt.Errorf("failed to decode support tickets: %v", err)
+	}
+	return tickets, nil
+}
+
+// CountUnresolved 统计玩家未解决的工单数
+func (tr *SupportTicketRepository) CountUnresolved(userID uint64) (int64, error) {
+	count, err := tr.collection.CountDocuments(context.Background(), bson.M{
+		"user_id": userID,
+		"status":  bson.M{"$ne": TicketStatusResolved},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count support tickets: %v", err)
+	}
+	return count, nil
+}
+
+// Assign 把未解决的工单分配给GM，工单已解决时返回false
+func (tr *SupportTicketRepository) Assign(ticketID primitive.ObjectID, gmUserID uint64) (bool, error) {
+	result, err := tr.collection.UpdateOne(context.Background(),
+		bson.M{"_id": ticketID, "status": bson.M{"$ne": TicketStatusResolved}},
+		bson.M{"$set": bson.M{"status": TicketStatusAssigned, "assigned_to": gmUserID, "updated_at": time.Now()}})
+	if err != nil {
+		return false, fmt.Errorf("failed to assign support ticket: %v", err)
+	}
+	return result.MatchedCount == 1, nil
+}
+
+// AddReply 记录GM的回复，工单已解决时返回false，resolve时同时标记为已解决
+func (tr *SupportTicketRepository) AddReply(ticketID primitive.ObjectID, reply *TicketReply, resolve bool) (bool, error) {
+	set := bson.M{"updated_at": reply.CreatedAt}
+	if resolve {
+		set["status"] = TicketStatusResolved
+		set["resolved_at"] = reply.CreatedAt
+	}
+
+	result, err := tr.collection.UpdateOne(context.Background(),
+		bson.M{"_id": ticketID, "status": bson.M{"$ne": TicketStatusResolved}},
+		bson.M{
+			"$push": bson.M{"replies": reply},
+			"$min":  bson.M{"first_reply_at": reply.CreatedAt},
+			"$set":  set,
+		})
+	if err != nil {
+		return false, fmt.Errorf("failed to add ticket reply: %v", err)
+	}
+	return result.MatchedCount == 1, nil
+}
+
+// Resolve 标记工单已解决，工单已解决时返回false
+func (tr *SupportTicketRepository) Resolve(ticketID primitive.ObjectID) (bool, error) {
+	now := time.Now()
+	result, err := tr.collection.UpdateOne(context.Background(),
+		bson.M{"_id": ticketID, "status": bson.M{"$ne": TicketStatusResolved}},
+		bson.M{"$set": bson.M{"status": TicketStatusResolved, "resolved_at": now, "updated_at": now}})
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve support ticket: %v", err)
+	}
+	return result.MatchedCount == 1, nil
+}
+
+// Reopen 重新打开已解决的工单，有负责的GM时回到已分配状态，工单未解决时返回false
+func (tr *SupportTicketRepository) Reopen(ticket *SupportTicket) (bool, error) {
+	status := TicketStatusOpen
+	if ticket.AssignedTo != 0 {
+		status = TicketStatusAssigned
+	}
+
+	result, err := tr.collection.UpdateOne(context.Background(),
+		bson.M{"_id": ticket.ID, "status": TicketStatusResolved},
+		bson.M{
+			"$set":   bson.M{"status": status, "updated_at": time.Now()},
+			"$unset": bson.M{"resolved_at": ""},
+		})
+	if err != nil {
+		return false, fmt.Errorf("failed to reopen support ticket: %v", err)
+	}
+	return result.MatchedCount == 1, nil
+}
+
+// CountByStatus 按分类和状态统计工单数
+func (tr *SupportTicketRepository) CountByStatus() ([]TicketCount, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"category": "$category", "status": "$status"},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":      0,
+			"category": "$_id.category",
+			"status":   "$_id.status",
+			"count":    1,
+		}}},
+	}
+
+	cursor, err := tr.collection.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count support tickets: %v", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var counts []TicketCount
+	if err := cursor.All(context.Background(), &counts); err != nil {
+		return nil, fmt.Errorf("failed to decode support ticket counts: %v", err)
+	}
+	return counts, nil
+}
+
+// CountCreated 按分类统计since之后提交的工单数
+func (tr *SupportTicketRepository) CountCreated(since time.Time) ([]TicketCount, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"created_at": bson.M{"$gte": since}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$category", "count": bson.M{"$sum": 1}}}},
+		{{Key: "$project", Value: bson.M{"_id": 0, "category": "$_id", "count": 1}}},
+	}
+
+	cursor, err := tr.collection.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count created support tickets: %v", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var counts []TicketCount
+	if err := cursor.All(context.Background(), &counts); err != nil {
+		return nil, fmt.Errorf("failed to decode support ticket counts: %v", err)
+	}
+	return counts, nil
+}
+
+// Resolutions 按分类统计since之后解决的工单数和平均处理时长
+func (tr *SupportTicketRepository) Resolutions(since time.Time) ([]TicketResolution, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"status": TicketStatusResolved, "resolved_at": bson.M{"$gte": since}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      "$category",
+			"resolved": bson.M{"$sum": 1},
+			"avg_ms":   bson.M{"$avg": bson.M{"$subtract": bson.A{"$resolved_at", "$created_at"}}},
+		}}},
+	}
+
+	cursor, err := tr.collection.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate ticket resolutions: %v", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var resolutions []TicketResolution
+	if err := cursor.All(context.Background(), &resolutions); err != nil {
+		return nil, fmt.Errorf("failed to decode ticket resolutions: %v", err)
+	}
+	return resolutions, nil
+}
+
+// NotificationRepository 离线通知仓库，暂存发给玩家的通知，由网关在玩家登录或在线时投递
+type NotificationRepository struct {
+	collection *mongo.Collection
+}
+
+// Notification 待投递的通知
+type Notification struct {
+	ID         primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	UserID     uint64                 `bson:"user_id" json:"user_id"`
+	Type       string                 `bson:"type" json:"type"`
+	DedupKey   string                 `bson:"dedup_key" json:"dedup_key"` // 同一玩家去重键相同的通知只保留最新的一条
+	FromUserID uint64                 `bson:"from_user_id,omitempty" json:"from_user_id"`
+	Data       map[string]interface{} `bson:"data,omitempty" json:"data"`
+	CreatedAt  time.Time              `bson:"created_at" json:"created_at"`
+	ExpireAt   time.Time              `bson:"expire_at" json:"expire_at"` // 过期后由TTL索引删除
+}
+
+// NewNotificationRepository 创建离线通知仓库
+func NewNotificationRepository(mm *MongoManager) *NotificationRepository {
+	collection := mm.GetCollection("notifications")
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "dedup_key", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "expire_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+
+	collection.Indexes().CreateMany(context.Background(), indexes)
+
+	return &NotificationRepository{
+		collection: collection,
+	}
+}
+
+// Enqueue 保存通知，已有相同去重键的通知时更新内容和过期时间，返回是否为新通知
+func (nr *NotificationRepository) Enqueue(notification *Notification) (bool, error) {
+	now := time.Now()
+	filter := bson.M{"user_id": notification.UserID, "dedup_key": notification.DedupKey}
+	update := bson.M{
+		"$set": bson.M{
+			"type":         notification.Type,
+			"from_user_id": notification.FromUserID,
+			"data":         notification.Data,
+			"created_at":   now,
+			"expire_at":    notification.ExpireAt,
+		},
+	}
+
+	result, err := nr.collection.UpdateOne(context.Background(), filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return false, fmt.Errorf("failed to enqueue notification: %v", err)
+	}
+	return result.UpsertedCount > 0, nil
+}
+
+// GetPending 按创建时间获取玩家未过期的通知
+func (nr *NotificationRepository) GetPending(userID uint64, limit int64) ([]*Notification, error) {
+	filter := bson.M{"user_id": userID, "expire_at": bson.M{"$gt": time.Now()}}
+	options := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}).SetLimit(limit)
+
+	cursor, err := nr.collection.Find(context.Background(), filter, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notifications: %v", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var notifications []*Notification
+	if err := cursor.All(context.Background(), &notifications); err != nil {
+		return nil, fmt.Errorf("failed to decode notifications: %v", err)
+	}
+	return notifications, nil
+}
+
+// Remove 删除已投递的通知
+func (nr *NotificationRepository) Remove(ids []primitive.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if _, err := nr.collection.DeleteMany(context.Background(), bson.M{"_id": bson.M{"$in": ids}}); err != nil {
+		return fmt.Errorf("failed to remove notifications: %v", err)
+	}
+	return nil
+}
+
+// DeviceTokenRepository 移动推送设备令牌仓库
+type DeviceTokenRepository struct {
+	collection *mongo.Collection
+}
+
+// DeviceToken 设备推送令牌，同一令牌只属于最近在该设备登录的玩家
+type DeviceToken struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    uint64             `bson:"user_id" json:"user_id"`
+	DeviceID  string             `bson:"device_id,omitempty" json:"device_id"`
+	Platform  string             `bson:"platform" json:"platform"` // fcm, apns
+	Token     string             `bson:"token" json:"token"`
+	Language  string             `bson:"language,omitempty" json:"language"`
+	UTCOffset int32              `bson:"utc_offset" json:"utc_offset"` // 设备时区相对UTC的分钟数，用于免打扰时段
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// NewDeviceTokenRepository 创建设备令牌仓库
+func NewDeviceTokenRepository(mm *MongoManager) *DeviceTokenRepository {
+	collection := mm.GetCollection("device_tokens")
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+		},
+	}
+
+	collection.Indexes().CreateMany(context.Background(), indexes)
+
+	return &DeviceTokenRepository{
+		collection: collection,
+	}
+}
+
+// Register 登记设备令牌，令牌已登记时更新所属玩家和设备设置
+func (dr *DeviceTokenRepository) Register(device *DeviceToken) error {
+	update := bson.M{
+		"$set": bson.M{
+			"user_id":    device.UserID,
+			"device_id":  device.DeviceID,
+			"platform":   device.Platform,
+			"language":   device.Language,
+			"utc_offset": device.UTCOffset,
+			"updated_at": time.Now(),
+		},
+	}
+
+	_, err := dr.collection.UpdateOne(context.Background(), bson.M{"token": device.Token}, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to register device token: %v", err)
+	}
+	return nil
+}
+
+// GetByUserID 获取玩家的所有设备令牌
+func (dr *DeviceTokenRepository) GetByUserID(userID uint64) ([]*DeviceToken, error) {
+	cursor, err := dr.collection.Find(context.Background(), bson.M{"user_id": userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device tokens: %v", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var devices []*DeviceToken
+	if err := cursor.All(context.Background(), &devices); err != nil {
+		return nil, fmt.Errorf("failed to decode device tokens: %v", err)
+	}
+	return devices, nil
+}
+
+// RemoveToken 删除推送服务判定失效的令牌
+func (dr *DeviceTokenRepository) RemoveToken(token string) error {
+	if _, err := dr.collection.DeleteOne(context.Background(), bson.M{"token": token}); err != nil {
+		return fmt.Errorf("failed to remove device token: %v", err)
+	}
+	return nil
+}
+
+// AsyncGameRepository 异步对局仓库，每次行动后保存完整的回合状态，任意游戏节点都可以继续对局
+type AsyncGameRepository struct {
+	collection *mongo.Collection
+}
+
+// AsyncGame 异步对局
+type AsyncGame struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	GameID        uint64             `bson:"game_id" json:"game_id"`
+	RoomID        uint64             `bson:"room_id" json:"room_id"`
+	GameType      int32              `bson:"game_type" json:"game_type"`
+	Status        int32              `bson:"status" json:"status"` // 1-进行中 2-已结束
+	Players       []AsyncGamePlayer  `bson:"players" json:"players"`
+	PlayerIDs     []uint64           `bson:"player_ids" json:"player_ids"` // 用于按玩家查询
+	CurrentPlayer uint64             `bson:"current_player" json:"current_player"`
+	TurnTimeout   int64              `bson:"turn_timeout" json:"turn_timeout"` // 每回合时限（秒）
+	TurnDeadline  time.Time          `bson:"turn_deadline" json:"turn_deadline"`
+	Reminded      bool               `bson:"reminded" json:"reminded"` // 本回合是否已发送截止提醒
+	Winner        uint64             `bson:"winner,omitempty" json:"winner"`
+	State         []byte             `bson:"state" json:"-"`         // 编码后的游戏状态
+	Version       int64              `bson:"version" json:"version"` // 每次保存递增，用于并发检查
+	StartTime     time.Time          `bson:"start_time" json:"start_time"`
+	EndTime       time.Time          `bson:"end_time,omitempty" json:"end_time"`
+	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// AsyncGamePlayer 异步对局玩家
+type AsyncGamePlayer struct {
+	UserID   uint64 `bson:"user_id" json:"user_id"`
+	Nickname string `bson:"nickname" json:"nickname"`
+	Level    int32  `bson:"level" json:"level"`
+	Score    int64  `bson:"score" json:"score"`
+	Status   int32  `bson:"status" json:"status"` // 2-游戏中 3-已离开
+}
+
+// NewAsyncGameRepository 创建异步对局仓库
+func NewAsyncGameRepository(mm *MongoManager) *AsyncGameRepository {
+	collection := mm.GetCollection("async_games")
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "game_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "player_ids", Value: 1}, {Key: "status", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "turn_deadline", Value: 1}},
+		},
+	}
+
+	collection.Indexes().CreateMany(context.Background(), indexes)
+
+	return &AsyncGameRepository{
+		collection: collection,
+	}
+}
+
+// Create 创建异步对局
+func (agr *AsyncGameRepository) Create(game *AsyncGame) error {
+	game.Version = 1
+	game.UpdatedAt = time.Now()
+
+	result, err := agr.collection.InsertOne(context.Background(), game)
+	if err != nil {
+		return fmt.Errorf("failed to create async game: %v", err)
+	}
+
+	game.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// GetByGameID 获取异步对局，对局不存在时返回nil
+func (agr *AsyncGameRepository) GetByGameID(gameID uint64) (*AsyncGame, error) {
+	var game AsyncGame
+	err := agr.collection.FindOne(context.Background(), bson.M{"game_id": gameID}).Decode(&game)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get async game: %v", err)
+	}
+	return &game, nil
+}
+
+// Save 保存对局，只有版本与读取时一致才写入，返回false表示对局已被其他请求修改
+func (agr *AsyncGameRepository) Save(game *AsyncGame) (bool, error) {
+	expected := game.Version
+	game.Version++
+	game.UpdatedAt = time.Now()
+
+	filter := bson.M{"game_id": game.GameID, "version": expected}
+	result, err := agr.collection.ReplaceOne(context.Background(), filter, game)
+	if err != nil {
+		game.Version = expected
+		return false, fmt.Errorf("failed to save async game: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		game.Version = expected
+		return false, nil
+	}
+	return true, nil
+}
+
+// GetActiveByPlayer 获取玩家参与的进行中对局，按回合截止时间排序
+func (agr *AsyncGameRepository) GetActiveByPlayer(userID uint64, limit int64) ([]*AsyncGame, error) {
+	filter := bson.M{"player_ids": userID, "status": 1}
+	options := options.Find().
+		SetSort(bson.D{{Key: "turn_deadline", Value: 1}}).
+		SetLimit(limit).
+		SetProjection(bson.M{"state": 0})
+
+	return agr.find(filter, options)
+}
+
+// GetExpired 获取回合已超时的进行中对局
+func (agr *AsyncGameRepository) GetExpired(now time.Time, limit int64) ([]*AsyncGame, error) {
+	filter := bson.M{"status": 1, "turn_deadline": bson.M{"$lte": now}}
+	options := options.Find().SetSort(bson.D{{Key: "turn_deadline", Value: 1}}).SetLimit(limit)
+
+	return agr.find(filter, options)
+}
+
+// GetUnreminded 获取回合截止时间早于before且尚未提醒的进行中对局
+func (agr *AsyncGameRepository) GetUnreminded(before time.Time, limit int64) ([]*AsyncGame, error) {
+	filter := bson.M{"status": 1, "reminded": false, "turn_deadline": bson.M{"$lt": before}}
+	options := options.Find().SetSort(bson.D{{Key: "turn_deadline", Value: 1}}).SetLimit(limit)
+
+	return agr.find(filter, options)
+}
+
+// find 查询对局
+func (agr *AsyncGameRepository) find(filter bson.M, options *options.FindOptions) ([]*AsyncGame, error) {
+	cursor, err := agr.collection.Find(context.Background(), filter, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get async games: %v", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var games []*AsyncGame
+	if err := cursor.All(context.Background(), &games); err != nil {
+		return nil, fmt.Errorf("failed to decode async games: %v", err)
+	}
+	return games, nil
+}
+
+// UserSettings 玩家的隐私设置和通知偏好，隐私设置各项为允许的范围：everyone、friends、nobody
+type UserSettings struct {
+	UserID         uint64          `bson:"user_id" json:"user_id"`
+	OnlineStatus   string          `bson:"online_status" json:"online_status"`     // 谁可以看到在线状态
+	FriendRequests string          `bson:"friend_requests" json:"friend_requests"` // 谁可以发送好友请求
+	Spectate       string          `bson:"spectate" json:"spectate"`               // 谁可以观战
+	Whisper        string          `bson:"whisper" json:"whisper"`                 // 谁可以发送私聊
+	Searchable     string          `bson:"searchable" json:"searchable"`           // 谁可以按名称搜索到玩家
+	Mutes          map[string]bool `bson:"mutes,omitempty" json:"mutes,omitempty"` // 通知频道的开关，true为已关闭
+	UpdatedAt      time.Time       `bson:"updated_at" json:"updated_at"`
+}
+
+// UserSettingsRepository 玩家设置仓库
+type UserSettingsRepository struct {
+	collection *mongo.Collection
+}
+
+// NewUserSettingsRepository 创建玩家设置仓库
+func NewUserSettingsRepository(mm *MongoManager) *UserSettingsRepository {
+	collection := mm.GetCollection("user_settings")
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+
+	collection.Indexes().CreateMany(context.Background(), indexes)
+
+	return &UserSettingsRepository{
+		collection: collection,
+	}
+}
+
+// Get 获取玩家设置，玩家没有保存过设置时返回nil
+func (usr *UserSettingsRepository) Get(userID uint64) (*UserSettings, error) {
+	var settings UserSettings
+	err := usr.collection.FindOne(context.Background(), bson.M{"user_id": userID}).Decode(&settings)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user settings: %v", err)
+	}
+	return &settings, nil
+}
+
+// Save 保存玩家设置
+func (usr *UserSettingsRepository) Save(settings *UserSettings) error {
+	settings.UpdatedAt = time.Now()
+
+	filter := bson.M{"user_id": settings.UserID}
+	if _, err := usr.collection.ReplaceOne(context.Background(), filter, settings, options.Replace().SetUpsert(true)); err != nil {
+		return fmt.Errorf("failed to save user settings: %v", err)
+	}
+	return nil
+}
+
+// 玩家活动时间线事件类型
+const (
+	ActivityLogin    = "login"     // 登录，记录IP和设备
+	ActivityRoomJoin = "room_join" // 创建、加入或匹配进入房间
+	ActivityChat     = "chat"      // 聊天消息，按天和频道计数
+)
+
+// ActivityEvent 玩家活动时间线事件，聊天按天和频道合并为一条计数记录
+type ActivityEvent struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	UserID    uint64             `bson:"user_id" json:"user_id"`
+	Kind      string             `bson:"kind" json:"kind"`
+	Day       string             `bson:"day,omitempty" json:"day,omitempty"` // 计数记录的日期(UTC)
+	IP        string             `bson:"ip,omitempty" json:"ip,omitempty"`
+	DeviceID  string             `bson:"device_id,omitempty" json:"device_id,omitempty"`
+	Platform  string             `bson:"platform,omitempty" json:"platform,omitempty"`
+	RoomID    uint64             `bson:"room_id,omitempty" json:"room_id,omitempty"`
+	Detail    string             `bson:"detail,omitempty" json:"detail,omitempty"` // 进入房间的方式或聊天频道
+	Count     int64              `bson:"count,omitempty" json:"count,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	ExpireAt  time.Time          `bson:"expire_at" json:"-"` // 过期后由TTL索引删除
+}
+
+// ActivityRepository 玩家活动时间线仓库
+type ActivityRepository struct {
+	collection *mongo.Collection
+}
+
+// NewActivityRepository 创建玩家活动时间线仓库
+func NewActivityRepository(mm *MongoManager) *ActivityRepository {
+	collection := mm.GetCollection("activity_timeline")
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "kind", Value: 1}, {Key: "day", Value: 1}, {Key: "detail", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "expire_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+
+	collection.Indexes().CreateMany(context.Background(), indexes)
+
+	return &ActivityRepository{
+		collection: collection,
+	}
+}
+
+// Insert 记录活动事件
+func (ar *ActivityRepository) Insert(event *ActivityEvent) error {
+	if _, err := ar.collection.InsertOne(context.Background(), event); err != nil {
+		return fmt.Errorf("failed to insert activity event: %v", err)
+	}
+	return nil
+}
+
+// IncrementDaily 累加玩家当天的计数记录，当天没有记录时创建
+func (ar *ActivityRepository) IncrementDaily(userID uint64, kind, day, detail string, delta int64, expireAt time.Time) error {
+	filter := bson.M{"user_id": userID, "kind": kind, "day": day, "detail": detail}
+	update := bson.M{
+		"$inc":         bson.M{"count": delta},
+		"$set":         bson.M{"expire_at": expireAt},
+		"$setOnInsert": bson.M{"created_at": time.Now()},
+	}
+	if _, err := ar.collection.UpdateOne(context.Background(), filter, update, options.Update().SetUpsert(true)); err != nil {
+		return fmt.Errorf("failed to increment activity count: %v", err)
+	}
+	return nil
+}
+
+// GetSince 按时间倒序获取玩家since之后的活动事件
+func (ar *ActivityRepository) GetSince(userID uint64, since time.Time, limit int64) ([]*ActivityEvent, error) {
+	filter := bson.M{"user_id": userID, "created_at": bson.M{"$gte": since}}
+	options := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit)
+
+	cursor, err := ar.collection.Find(context.Background(), filter, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get activity events: %v", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var events []*ActivityEvent
+	if err := cursor.All(context.Background(), &events); err != nil {
+		return nil, fmt.Errorf("failed to decode activity events: %v", err)
+	}
+	return events, nil
+}
+
+// Saga状态
+const (
+	SagaRunning      = "running"      // 正在执行步骤
+	SagaCompensating = "compensating" // 步骤失败，正在逆序执行补偿
+	SagaCompleted    = "completed"    // 所有步骤执行成功
+	SagaCompensated  = "compensated"  // 补偿完成
+	SagaFailed       = "failed"       // 补偿多次失败，需要人工处理
+)
+
+// SagaRepository 跨服务流程(saga)的执行记录仓库
+type SagaRepository struct {
+	collection *mongo.Collection
+}
+
+// Saga 跨服务流程的执行记录，每完成一个步骤更新一次，节点故障后由其他节点按记录继续补偿
+type Saga struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	SagaID     string             `bson:"saga_id" json:"saga_id"`
+	Type       string             `bson:"type" json:"type"`
+	Status     string             `bson:"status" json:"status"`
+	Step       int32              `bson:"step" json:"step"` // 已完成的步骤数，执行中时也是正在执行的步骤下标
+	Data       string             `bson:"data" json:"data"` // 流程参数(JSON)
+	NodeID     string             `bson:"node_id" json:"node_id"`
+	Attempts   int32              `bson:"attempts" json:"attempts"` // 领取次数，节点接管或补偿重试时增加
+	Error      string             `bson:"error,omitempty" json:"error,omitempty"`
+	LeaseUntil time.Time          `bson:"lease_until" json:"lease_until"` // 执行中的记录超过该时间视为节点故障，可被接管
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// NewSagaRepository 创建saga执行记录仓库
+func NewSagaRepository(mm *MongoManager) *SagaRepository {
+	collection := mm.GetCollection("sagas")
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "saga_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "lease_until", Value: 1}},
+		},
+	}
+
+	collection.Indexes().CreateMany(context.Background(), indexes)
+
+	return &SagaRepository{
+		collection: collection,
+	}
+}
+
+// CreateSaga 创建saga执行记录
+func (sr *SagaRepository) CreateSaga(saga *Saga) error {
+	now := time.Now()
+	saga.CreatedAt = now
+	saga.UpdatedAt = now
+
+	result, err := sr.collection.InsertOne(context.Background(), saga)
+	if err != nil {
+		return fmt.Errorf("failed to create saga: %v", err)
+	}
+	saga.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// UpdateSaga 更新执行进度并续期，只有当前持有者可以更新
+func (sr *SagaRepository) UpdateSaga(saga *Saga) error {
+	saga.UpdatedAt = time.Now()
+	filter := bson.M{"saga_id": saga.SagaID, "node_id": saga.NodeID}
+	update := bson.M{"$set": bson.M{
+		"status":      saga.Status,
+		"step":        saga.Step,
+		"error":       saga.Error,
+		"lease_until": saga.LeaseUntil,
+		"updated_at":  saga.UpdatedAt,
+	}}
+
+	result, err := sr.collection.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to update saga: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("saga %s is no longer held by %s", saga.SagaID, saga.NodeID)
+	}
+	return nil
+}
+
+// ClaimSaga 接管租期已过的未结束saga，返回接管后的记录，已被其他节点接管或已结束时返回nil
+func (sr *SagaRepository) ClaimSaga(sagaID, nodeID string, lease time.Duration) (*Saga, error) {
+	now := time.Now()
+	filter := bson.M{
+		"saga_id":     sagaID,
+		"status":      bson.M{"$in": bson.A{SagaRunning, SagaCompensating}},
+		"lease_until": bson.M{"$lt": now},
+	}
+	update := bson.M{
+		"$set": bson.M{"node_id": nodeID, "lease_until": now.Add(lease), "updated_at": now},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var saga Saga
+	if err := sr.collection.FindOneAndUpdate(context.Background(), filter, update, opts).Decode(&saga); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim saga: %v", err)
+	}
+	return &saga, nil
+}
+
+// GetStaleSagas 获取租期已过的未结束saga
+func (sr *SagaRepository) GetStaleSagas(limit int64) ([]*Saga, error) {
+	filter := bson.M{
+		"status":      bson.M{"$in": bson.A{SagaRunning, SagaCompensating}},
+		"lease_until": bson.M{"$lt": time.Now()},
+	}
+	options := options.Find().SetSort(bson.D{{Key: "lease_until", Value: 1}}).SetLimit(limit)
+
+	cursor, err := sr.collection.Find(context.Background(), filter, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stale sagas: %v", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var sagas []*Saga
+	if err := cursor.All(context.Background(), &sagas); err != nil {
+		return nil, fmt.Errorf("failed to decode sagas: %v", err)
+	}
+	return sagas, nil
+}
+
+// GetSaga 根据ID获取saga执行记录，不存在时返回nil
+func (sr *SagaRepository) GetSaga(sagaID string) (*Saga, error) {
+	var saga Saga
+	if err := sr.collection.FindOne(context.Background(), bson.M{"saga_id": sagaID}).Decode(&saga); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get saga: %v", err)
+	}
+	return &saga, nil
+}
+
+// ErrNicknameTaken 昵称已被其他用户占用
+var ErrNicknameTaken = errors.New("nickname already taken")
+
+// 改名的字段
+const (
+	NameFieldNickname = "nickname"
+	NameFieldUsername = "username"
+)
+
+// 改名的发起人
+const (
+	RenameByPlayer = "player"
+	RenameByGM     = "gm"
+)
+
+// NameChange 昵称或用户名的修改记录，GM可按曾用名查找玩家
+type NameChange struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    uint64             `bson:"user_id" json:"user_id"`
+	Field     string             `bson:"field" json:"field"` // nickname或username
+	OldName   string             `bson:"old_name" json:"old_name"`
+	NewName   string             `bson:"new_name" json:"new_name"`
+	By        string             `bson:"by" json:"by"`                                     // player或gm
+	GMUserID  uint64             `bson:"gm_user_id,omitempty" json:"gm_user_id,omitempty"` // GM修改时的GM用户ID
+	Reason    string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	Currency  string             `bson:"currency,omitempty" json:"currency,omitempty"` // 改名消耗的货币
+	Cost      int64              `bson:"cost,omitempty" json:"cost,omitempty"`
+	TxID      string             `bson:"tx_id,omitempty" json:"tx_id,omitempty"` // 扣费流水
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// NameRepository 昵称占用和改名记录仓库。
+// 占用记录以规范化后的昵称为_id，并发修改为同一昵称时只有一个能成功
+type NameRepository struct {
+	names   *mongo.Collection
+	changes *mongo.Collection
+	history *mongo.Collection // 改名记录的查询从副本读取，冷却时间和免费次数从主节点读取
+}
+
+// NewNameRepository 创建昵称仓库
+func NewNameRepository(mm *MongoManager) *NameRepository {
+	names := mm.GetCollection("nicknames")
+	names.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}},
+	})
+
+	changes := mm.GetCollection("name_changes")
+	changes.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "field", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			// 按曾用名查找，不区分大小写
+			Keys:    bson.D{{Key: "old_name", Value: 1}},
+			Options: options.Index().SetCollation(nicknameCollation),
+		},
+	})
+
+	return &NameRepository{
+		names:   names,
+		changes: changes,
+		history: mm.Reader("name_changes", ReadSecondaryPreferred),
+	}
+}
+
+// Reserve 为用户占用昵称，key为规范化后的昵称；已被其他用户占用时返回ErrNicknameTaken
+func (nr *NameRepository) Reserve(key string, userID uint64) error {
+	_, err := nr.names.InsertOne(context.Background(), bson.M{"_id": key, "user_id": userID, "created_at": time.Now()})
+	if err == nil {
+		return nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("failed to reserve nickname: %v", err)
+	}
+
+	var owner struct {
+		UserID uint64 `bson:"user_id"`
+	}
+	if err := nr.names.FindOne(context.Background(), bson.M{"_id": key}).Decode(&owner); err != nil {
+		return fmt.Errorf("failed to get nickname owner: %v", err)
+	}
+	if owner.UserID != userID {
+		return ErrNicknameTaken
+	}
+	return nil
+}
+
+// Release 释放用户占用的昵称
+func (nr *NameRepository) Release(key string, userID uint64) error {
+	if _, err := nr.names.DeleteOne(context.Background(), bson.M{"_id": key, "user_id": userID}); err != nil {
+		return fmt.Errorf("failed to release nickname: %v", err)
+	}
+	return nil
+}
+
+// RecordChange 记录改名
+func (nr *NameRepository) RecordChange(change *NameChange) error {
+	if change.CreatedAt.IsZero() {
+		change.CreatedAt = time.Now()
+	}
+	result, err := nr.changes.InsertOne(context.Background(), change)
+	if err != nil {
+		return fmt.Errorf("failed to record name change: %v", err)
+	}
+	change.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// LastChange 用户最近一次由by发起的改名，没有时返回nil
+func (nr *NameRepository) LastChange(userID uint64, field, by string) (*NameChange, error) {
+	var change NameChange
+	options := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	filter := bson.M{"user_id": userID, "field": field, "by": by}
+	err := nr.changes.FindOne(context.Background(), filter, options).Decode(&change)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get name change: %v", err)
+	}
+	return &change, nil
+}
+
+// CountChanges 用户由by发起的改名次数
+func (nr *NameRepository) CountChanges(userID uint64, field, by string) (int64, error) {
+	count, err := nr.changes.CountDocuments(context.Background(), bson.M{"user_id": userID, "field": field, "by": by})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count name changes: %v", err)
+	}
+	return count, nil
+}
+
+// History 用户的改名记录，按时间倒序
+func (nr *NameRepository) History(userID uint64, limit int64) ([]*NameChange, error) {
+	options := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit)
+	return nr.find(bson.M{"user_id": userID}, options)
+}
+
+// FindByOldName 按曾用名查找改名记录(不区分大小写)，按时间倒序
+func (nr *NameRepository) FindByOldName(name string, limit int64) ([]*NameChange, error) {
+	options := options.Find().
+		SetCollation(nicknameCollation).
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetLimit(limit)
+	return nr.find(bson.M{"old_name": name}, options)
+}
+
+// find 查询改名记录
+func (nr *NameRepository) find(filter bson.M, options *options.FindOptions) ([]*NameChange, error) {
+	cursor, err := nr.history.Find(context.Background(), filter, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get name changes: %v", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var changes []*NameChange
+	if err := cursor.All(context.Background(), &changes); err != nil {
+		return nil, fmt.Errorf("failed to decode name changes: %v", err)
+	}
+	return changes, nil
+}
+
+// RedeemRepository 兑换码仓库，批次、兑换码、玩家兑换次数和兑换记录分别保存
+type RedeemRepository struct {
+	batches     *mongo.Collection
+	codes       *mongo.Collection
+	usages      *mongo.Collection
+	redemptions *mongo.Collection
+}
+
+// RedeemBatch GM生成的一批兑换码，同一批次的兑换码发放相同的奖励
+type RedeemBatch struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	BatchID   string             `bson:"batch_id" json:"batch_id"`
+	Name      string             `bson:"name" json:"name"`
+	Title     string             `bson:"title" json:"title"` // 奖励邮件的标题和正文
+	Content   string             `bson:"content" json:"content"`
+	Rewards   []MailReward       `bson:"rewards" json:"rewards"`
+	Codes     int64              `bson:"codes" json:"codes"`       // 生成的兑换码数量
+	MaxUses   int64              `bson:"max_uses" json:"max_uses"` // 每个兑换码可兑换的次数，0为不限制
+	PerUser   int64              `bson:"per_user" json:"per_user"` // 每个玩家在该批次中最多兑换的次数
+	StartAt   time.Time          `bson:"start_at,omitempty" json:"start_at,omitempty"`
+	ExpireAt  time.Time          `bson:"expire_at,omitempty" json:"expire_at,omitempty"`
+	Disabled  bool               `bson:"disabled,omitempty" json:"disabled"`
+	CreatedBy uint64             `bson:"created_by" json:"created_by"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// RedeemCode 兑换码
+type RedeemCode struct {
+	Code      string    `bson:"_id" json:"code"`
+	BatchID   string    `bson:"batch_id" json:"batch_id"`
+	Uses      int64     `bson:"uses" json:"uses"`
+	MaxUses   int64     `bson:"max_uses" json:"max_uses"` // 0为不限制
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+// Redemption 一次成功的兑换
+type Redemption struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	BatchID   string             `bson:"batch_id" json:"batch_id"`
+	Code      string             `bson:"code" json:"code"`
+	UserID    uint64             `bson:"user_id" json:"user_id"`
+	MailID    uint64             `bson:"mail_id" json:"mail_id"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// RedeemStats 批次的兑换统计
+type RedeemStats struct {
+	Codes       int64 `json:"codes"`       // 生成的兑换码数量
+	UsedCodes   int64 `json:"used_codes"`  // 至少兑换过一次的兑换码数量
+	Redemptions int64 `json:"redemptions"` // 兑换总次数
+	Users       int64 `json:"users"`       // 兑换过的玩家数
+	LastDay     int64 `json:"last_day"`    // 最近24小时的兑换次数
+}
+
+// NewRedeemRepository 创建兑换码仓库
+func NewRedeemRepository(mm *MongoManager) *RedeemRepository {
+	rr := &RedeemRepository{
+		batches:     mm.GetCollection("redeem_batches"),
+		codes:       mm.GetCollection("redeem_codes"),
+		usages:      mm.GetCollection("redeem_usages"),
+		redemptions: mm.GetCollection("redemptions"),
+	}
+
+	rr.batches.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "batch_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	rr.codes.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "batch_id", Value: 1}, {Key: "uses", Value: 1}},
+	})
+	// 同一玩家在同一批次只有一条计数
+	rr.usages.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "batch_id", Value: 1}, {Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	rr.redemptions.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "batch_id", Value: 1}, {Key: "created_at", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+
+	return rr
+}
+
+// CreateBatch 创建批次
+func (rr *RedeemRepository) CreateBatch(batch *RedeemBatch) error {
+	batch.ID = primitive.NewObjectID()
+	batch.CreatedAt = time.Now()
+	if _, err := rr.batches.InsertOne(context.Background(), batch); err != nil {
+		return fmt.Errorf("failed to create redeem batch: %v", err)
+	}
+	return nil
+}
+
+// InsertCodes 写入兑换码，已存在的兑换码跳过，返回写入成功的兑换码
+func (rr *RedeemRepository) InsertCodes(codes []*RedeemCode) ([]*RedeemCode, error) {
+	documents := make([]interface{}, len(codes))
+	for i, code := range codes {
+		code.CreatedAt = time.Now()
+		documents[i] = code
+	}
+
+	_, err := rr.codes.InsertMany(context.Background(), documents, options.InsertMany().SetOrdered(false))
+	if err == nil {
+		return codes, nil
+	}
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) {
+		return nil, fmt.Errorf("failed to insert redeem codes: %v", err)
+	}
+	failed := make(map[int]bool, len(bulkErr.WriteErrors))
+	for _, writeErr := range bulkErr.WriteErrors {
+		if !mongo.IsDuplicateKeyError(writeErr) {
+			return nil, fmt.Errorf("failed to insert redeem codes: %v", err)
+		}
+		failed[writeErr.Index] = true
+	}
+	inserted := make([]*RedeemCode, 0, len(codes)-len(failed))
+	for i, code := range codes {
+		if !failed[i] {
+			inserted = append(inserted, code)
+		}
+	}
+	return inserted, nil
+}
+
+// GetBatch 获取批次，不存在时返回nil
+func (rr *RedeemRepository) GetBatch(batchID string) (*RedeemBatch, error) {
+	var batch RedeemBatch
+	err := rr.batches.FindOne(context.Background(), bson.M{"batch_id": batchID}).Decode(&batch)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get redeem batch: %v", err)
+	}
+	return &batch, nil
+}
+
+// ListBatches 最近创建的批次
+func (rr *RedeemRepository) ListBatches(limit int64) ([]*RedeemBatch, error) {
+	cursor, err := rr.batches.Find(context.Background(), bson.M{},
+		options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find redeem batches: %v", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var batches []*RedeemBatch
+	if err := cursor.All(context.Background(), &batches); err != nil {
+		return nil, fmt.Errorf("failed to decode redeem batches: %v", err)
+	}
+	return batches, nil
+}
+
+// SetBatchDisabled 停用或启用批次
+func (rr *RedeemRepository) SetBatchDisabled(batchID string, disabled bool) (bool, error) {
+	result, err := rr.batches.UpdateOne(context.Background(),
+		bson.M{"batch_id": batchID}, bson.M{"$set": bson.M{"disabled": disabled}})
+	if err != nil {
+		return false, fmt.Errorf("failed to update redeem batch: %v", err)
+	}
+	return result.MatchedCount == 1, nil
+}
+
+// GetCode 获取兑换码，不存在时返回nil
+func (rr *RedeemRepository) GetCode(code string) (*RedeemCode, error) {
+	var redeemCode RedeemCode
+	err := rr.codes.FindOne(context.Background(), bson.M{"_id": code}).Decode(&redeemCode)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get redeem code: %v", err)
+	}
+	return &redeemCode, nil
+}
+
+// UseCode 占用兑换码的一次兑换次数，次数已用完时返回false
+func (rr *RedeemRepository) UseCode(code string) (bool, error) {
+	filter := bson.M{"_id": code, "$or": bson.A{
+		bson.M{"max_uses": 0},
+		bson.M{"$expr": bson.M{"$lt": bson.A{"$uses", "$max_uses"}}},
+	}}
+	result, err := rr.codes.UpdateOne(context.Background(), filter, bson.M{"$inc": bson.M{"uses": 1}})
+	if err != nil {
+		return false, fmt.Errorf("failed to use redeem code: %v", err)
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// ReleaseCode 退回占用的兑换次数，用于兑换失败的情况
+func (rr *RedeemRepository) ReleaseCode(code string) error {
+	_, err := rr.codes.UpdateOne(context.Background(),
+		bson.M{"_id": code, "uses": bson.M{"$gt": 0}}, bson.M{"$inc": bson.M{"uses": -1}})
+	if err != nil {
+		return fmt.Errorf("failed to release redeem code: %v", err)
+	}
+	return nil
+}
+
+// UseQuota 占用玩家在批次中的一次兑换次数，已达到perUser次时返回false
+func (rr *RedeemRepository) UseQuota(batchID string, userID uint64, perUser int64) (bool, error) {
+	filter := bson.M{"batch_id": batchID, "user_id": userID, "count": bson.M{"$lt": perUser}}
+	_, err := rr.usages.UpdateOne(context.Background(), filter,
+		bson.M{"$inc": bson.M{"count": 1}}, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// 计数已达到上限，条件不匹配时尝试插入新文档与唯一索引冲突
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to use redeem quota: %v", err)
+	}
+	return true, nil
+}
+
+// ReleaseQuota 退回玩家占用的兑换次数
+func (rr *RedeemRepository) ReleaseQuota(batchID string, userID uint64) error {
+	_, err := rr.usages.UpdateOne(context.Background(),
+		bson.M{"batch_id": batchID, "user_id": userID, "count": bson.M{"$gt": 0}}, bson.M{"$inc": bson.M{"count": -1}})
+	if err != nil {
+		return fmt.Errorf("failed to release redeem quota: %v", err)
+	}
+	return nil
+}
+
+// RecordRedemption 记录成功的兑换
+func (rr *RedeemRepository) RecordRedemption(redemption *Redemption) error {
+	redemption.ID = primitive.NewObjectID()
+	redemption.CreatedAt = time.Now()
+	if _, err := rr.redemptions.InsertOne(context.Background(), redemption); err != nil {
+		return fmt.Errorf("failed to record redemption: %v", err)
+	}
+	return nil
+}
+
+// Stats 批次的兑换统计
+func (rr *RedeemRepository) Stats(batchID string, now time.Time) (*RedeemStats, error) {
+	ctx := context.Background()
+	stats := &RedeemStats{}
+	var err error
+	if stats.Codes, err = rr.codes.CountDocuments(ctx, bson.M{"batch_id": batchID}); err != nil {
+		return nil, fmt.Errorf("failed to count redeem codes: %v", err)
+	}
+	if stats.UsedCodes, err = rr.codes.CountDocuments(ctx, bson.M{"batch_id": batchID, "uses": bson.M{"$gt": 0}}); err != nil {
+		return nil, fmt.Errorf("failed to count used redeem codes: %v", err)
+	}
+	if stats.Redemptions, err = rr.redemptions.CountDocuments(ctx, bson.M{"batch_id": batchID}); err != nil {
+		return nil, fmt.Errorf("failed to count redemptions: %v", err)
+	}
+	if stats.Users, err = rr.usages.CountDocuments(ctx, bson.M{"batch_id": batchID, "count": bson.M{"$gt": 0}}); err != nil {
+		return nil, fmt.Errorf("failed to count redeem users: %v", err)
+	}
+	lastDay := bson.M{"batch_id": batchID, "created_at": bson.M{"$gte": now.Add(-24 * time.Hour)}}
+	if stats.LastDay, err = rr.redemptions.CountDocuments(ctx, lastDay); err != nil {
+		return nil, fmt.Errorf("failed to count recent redemptions: %v", err)
+	}
+	return stats, nil
+}
+
+// 奖励触发的发放状态
+const (
+	TriggerGrantPending = "pending" // 已占用，奖励邮件发送中或发送时节点中断
+	TriggerGrantGranted = "granted" // 奖励邮件已发送
+)
+
+// TriggerGrantRepository 奖励触发的发放记录仓库，同一键只能发放一次
+type TriggerGrantRepository struct {
+	collection *mongo.Collection
+}
+
+// TriggerGrant 一次奖励触发的发放记录
+type TriggerGrant struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	Key       string             `bson:"key" json:"key"` // 去重键，一次性的规则为 规则ID:玩家ID，可重复的规则再加上事件标识
+	RuleID    string             `bson:"rule_id" json:"rule_id"`
+	UserID    uint64             `bson:"user_id" json:"user_id"`
+	Event     string             `bson:"event" json:"event"`
+	Ref       string             `bson:"ref,omitempty" json:"ref,omitempty"` // 触发事件的标识，如订单号
+	Status    string             `bson:"status" json:"status"`
+	MailID    uint64             `bson:"mail_id,omitempty" json:"mail_id,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	GrantedAt time.Time          `bson:"granted_at,omitempty" json:"granted_at,omitempty"`
+}
+
+// NewTriggerGrantRepository 创建奖励触发的发放记录仓库
+func NewTriggerGrantRepository(mm *MongoManager) *TriggerGrantRepository {
+	collection := mm.GetCollection("trigger_grants")
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "key", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+	}
+
+	collection.Indexes().CreateMany(context.Background(), indexes)
+
+	return &TriggerGrantRepository{
+		collection: collection,
+	}
+}
+
+// Claim 占用发放记录，键已存在时返回false
+func (tr *TriggerGrantRepository) Claim(grant *TriggerGrant) (bool, error) {
+	grant.ID = primitive.NewObjectID()
+	grant.Status = TriggerGrantPending
+	grant.CreatedAt = time.Now()
+
+	_, err := tr.collection.InsertOne(context.Background(), grant)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to claim trigger grant: %v", err)
+	}
+	return true, nil
+}
+
+// Complete 记录奖励邮件已发送
+func (tr *TriggerGrantRepository) Complete(key string, mailID uint64) error {
+	update := bson.M{"$set": bson.M{"status": TriggerGrantGranted, "mail_id": mailID, "granted_at": time.Now()}}
+	if _, err := tr.collection.UpdateOne(context.Background(), bson.M{"key": key}, update); err != nil {
+		return fmt.Errorf("failed to complete trigger grant: %v", err)
+	}
+	return nil
+}
+
+// Release 删除未完成的发放记录，用于奖励邮件发送失败后允许再次触发
+func (tr *TriggerGrantRepository) Release(key string) error {
+	filter := bson.M{"key": key, "status": TriggerGrantPending}
+	if _, err := tr.collection.DeleteOne(context.Background(), filter); err != nil {
+		return fmt.Errorf("failed to release trigger grant: %v", err)
+	}
+	return nil
+}
+
+// ListByUser 玩家最近的发放记录
+func (tr *TriggerGrantRepository) ListByUser(userID uint64, limit int64) ([]*TriggerGrant, error) {
+	cursor, err := tr.collection.Find(context.Background(), bson.M{"user_id": userID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find trigger grants: %v", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var grants []*TriggerGrant
+	if err := cursor.All(context.Background(), &grants); err != nil {
+		return nil, fmt.Errorf("failed to decode trigger grants: %v", err)
+	}
+	return grants, nil
+}
+
+// NewsRepository 资讯仓库
+type NewsRepository struct {
+	collection *mongo.Collection
+}
+
+// NewsContent 某个语言的资讯内容
+type NewsContent struct {
+	Title   string `bson:"title" json:"title"`
+	Summary string `bson:"summary" json:"summary"`
+	Body    string `bson:"body" json:"body"`
+	Image   string `bson:"image" json:"image"` // 图片地址或CDN上的相对路径
+	Link    string `bson:"link" json:"link"`
+}
+
+// NewsItem 资讯、推广和更新说明，在开始和结束时间之间向目标分群的玩家展示
+type NewsItem struct {
+	ID        primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	Kind      string                 `bson:"kind" json:"kind"`         // news, promotion, patch_notes
+	Language  string                 `bson:"language" json:"language"` // 没有玩家语言的内容时使用的语言
+	Contents  map[string]NewsContent `bson:"contents" json:"contents"`
+	Segment   string                 `bson:"segment" json:"segment"`
+	Priority  int32                  `bson:"priority" json:"priority"` // 越大越靠前
+	StartAt   time.Time              `bson:"start_at" json:"start_at"`
+	EndAt     time.Time              `bson:"end_at" json:"end_at"`
+	CreatedBy uint64                 `bson:"created_by" json:"created_by"`
+	CreatedAt time.Time              `bson:"created_at" json:"created_at"`
+}
+
+// NewNewsRepository 创建资讯仓库
+func NewNewsRepository(mm *MongoManager) *NewsRepository {
+	collection := mm.GetCollection("news_items")
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "end_at", Value: 1}, {Key: "start_at", Value: 1}},
+		},
+	}
+
+	collection.Indexes().CreateMany(context.Background(), indexes)
+
+	return &NewsRepository{
+		collection: collection,
+	}
+}
+
+// Create 创建资讯
+func (nr *NewsRepository) Create(item *NewsItem) error {
+	item.ID = primitive.NewObjectID()
+	item.CreatedAt = time.Now()
+
+	if _, err := nr.collection.InsertOne(context.Background(), item); err != nil {
+		return fmt.Errorf("failed to create news item: %v", err)
+	}
+	return nil
+}
+
+// Get 获取资讯，不存在时返回nil
+func (nr *NewsRepository) Get(itemID string) (*NewsItem, error) {
+	id, err := primitive.ObjectIDFromHex(itemID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid news item id: %s", itemID)
+	}
+
+	var item NewsItem
+	err = nr.collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&item)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get news item: %v", err)
+	}
+	return &item, nil
+}
+
+// Active 获取在until之前开始、from之后结束的资讯，按优先级和开始时间倒序排列
+func (nr *NewsRepository) Active(from, until time.Time) ([]*NewsItem, error) {
+	filter := bson.M{
+		"start_at": bson.M{"$lte": until},
+		"end_at":   bson.M{"$gt": from},
+	}
+	options := options.Find().SetSort(bson.D{{Key: "priority", Value: -1}, {Key: "start_at", Value: -1}})
+	return nr.find(filter, options)
+}
+
+// List 获取最近创建的资讯
+func (nr *NewsRepository) List(limit int64) ([]*NewsItem, error) {
+	options := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(limit)
+	return nr.find(bson.M{}, options)
+}
+
+// Delete 删除资讯，资讯不存在时返回false
+func (nr *NewsRepository) Delete(itemID string) (bool, error) {
+	id, err := primitive.ObjectIDFromHex(itemID)
+	if err != nil {
+		return false, fmt.Errorf("invalid news item id: %s", itemID)
+	}
+
+	result, err := nr.collection.DeleteOne(context.Background(), bson.M{"_id": id})
+	if err != nil {
+		return false, fmt.Errorf("failed to delete news item: %v", err)
+	}
+	return result.DeletedCount == 1, nil
+}
+
+// find 按条件查询资讯
+func (nr *NewsRepository) find(filter bson.M, opts *options.FindOptions) ([]*NewsItem, error) {
+	cursor, err := nr.collection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find news items: %v", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var items []*NewsItem
+	if err := cursor.All(context.Background(), &items); err != nil {
+		return nil, fmt.Errorf("failed to decode news items: %v", err)
+	}
+	return items, nil
+}
+
+// denormTarget 保存玩家昵称和等级副本的集合，fields为玩家数组字段，timeField用于限定更新范围
+type denormTarget struct {
+	fields    []string
+	timeField string
+}
+
+// denormTargets 支持更新副本的集合
+var denormTargets = map[string]denormTarget{
+	"rooms":        {fields: []string{"players", "spectators"}, timeField: "created_at"},
+	"game_records": {fields: []string{"players"}, timeField: "created_at"},
+}
+
+// DenormRepository 玩家昵称和等级副本仓库
+type DenormRepository struct {
+	mm *MongoManager
+}
+
+// NewDenormRepository 创建玩家昵称和等级副本仓库
+func NewDenormRepository(mm *MongoManager) *DenormRepository {
+	return &DenormRepository{mm: mm}
+}
+
+// RefreshPlayers 更新since之后创建的文档中玩家的昵称和等级副本，昵称为空或等级为0时不更新该字段，返回更新的文档数
+func (dr *DenormRepository) RefreshPlayers(collection string, userID uint64, nickname string, level int32, since time.Time) (int64, error) {
+	target, exists := denormTargets[collection]
+	if !exists {
+		return 0, fmt.Errorf("collection %s does not support refreshing player copies", collection)
+	}
+
+	var updated int64
+	for _, field := range target.fields {
+		set := bson.M{}
+		if nickname != "" {
+			set[field+".$[p].nickname"] = nickname
+		}
+		if level > 0 {
+			set[field+".$[p].level"] = level
+		}
+		if len(set) == 0 {
+			return 0, nil
+		}
+
+		filter := bson.M{field + ".user_id": userID, target.timeField: bson.M{"$gte": since}}
+		options := options.Update().SetArrayFilters(options.ArrayFilters{
+			Filters: []interface{}{bson.M{"p.user_id": userID}},
+		})
+		result, err := dr.mm.GetCollection(collection).UpdateMany(context.Background(), filter, bson.M{"$set": set}, options)
+		if err != nil {
+			return updated, fmt.Errorf("failed to refresh %s %s: %v", collection, field, err)
+		}
+		updated += result.ModifiedCount
+	}
+	return updated, nil
+}
+
+// RoomOccupancy 一种游戏类型未结束的房间数、游戏中的房间数和房间中的玩家数
+type RoomOccupancy struct {
+	GameType int32 `bson:"_id"`
+	Rooms    int64 `bson:"rooms"`
+	Games    int64 `bson:"games"`
+	Players  int64 `bson:"players"`
+}
+
+// CountOccupancy 按游戏类型统计未结束的房间
+func (rr *RoomRepository) CountOccupancy() ([]RoomOccupancy, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"status": bson.M{"$ne": 2}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":     "$game_type",
+			"rooms":   bson.M{"$sum": 1},
+			"games":   bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$status", 1}}, 1, 0}}},
+			"players": bson.M{"$sum": "$current_players"},
+		}}},
+	}
+	cursor, err := rr.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate room occupancy: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var occupancy []RoomOccupancy
+	if err := cursor.All(ctx, &occupancy); err != nil {
+		return nil, fmt.Errorf("failed to decode room occupancy: %v", err)
+	}
+	return occupancy, nil
+}
+
+// CountEndedByType 按游戏类型统计在指定时间段内结束的游戏数和总时长
+func (grr *GameRecordRepository) CountEndedByType(since, until time.Time) ([]GameTypeRollup, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"status":     1,
+			"updated_at": bson.M{"$gte": since, "$lt": until},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      "$game_type",
+			"games":    bson.M{"$sum": 1},
+			"duration": bson.M{"$sum": "$duration"},
+		}}},
+		{{Key: "$project", Value: bson.M{"_id": 0, "game_type": "$_id", "games": 1, "duration": 1}}},
+		{{Key: "$sort", Value: bson.M{"game_type": 1}}},
+	}
+	cursor, err := grr.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate ended games: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var rollups []GameTypeRollup
+	if err := cursor.All(ctx, &rollups); err != nil {
+		return nil, fmt.Errorf("failed to decode ended games: %v", err)
+	}
+	return rollups, nil
+}
+
+// 统计汇总的周期
+const (
+	RollupHour = "hour"
+	RollupDay  = "day"
+)
+
+// StatsRollup 一个小时或一天的运营指标汇总，时间按UTC
+type StatsRollup struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	Period    string             `bson:"period" json:"period"` // hour, day
+	Start     time.Time          `bson:"start" json:"start"`
+	PeakCCU   int64              `bson:"peak_ccu" json:"peak_ccu"` // 采样到的最高在线人数
+	Games     int64              `bson:"games" json:"games"`       // 期间结束的游戏数
+	GameTypes []GameTypeRollup   `bson:"game_types" json:"game_types"`
+	ExpiresAt *time.Time         `bson:"expires_at,omitempty" json:"-"` // 小时汇总的过期时间，日汇总不过期
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// GameTypeRollup 一种游戏类型结束的游戏数和总时长
+type GameTypeRollup struct {
+	GameType int32 `bson:"game_type" json:"game_type"`
+	Games    int64 `bson:"games" json:"games"`
+	Duration int64 `bson:"duration" json:"duration"` // 总时长（秒）
+}
+
+// StatsRollupRepository 运营指标汇总仓库
+type StatsRollupRepository struct {
+	collection *mongo.Collection
+}
+
+// NewStatsRollupRepository 创建运营指标汇总仓库
+func NewStatsRollupRepository(mm *MongoManager) *StatsRollupRepository {
+	collection := mm.GetCollection("stats_rollups")
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "period", Value: 1}, {Key: "start", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+	collection.Indexes().CreateMany(context.Background(), indexes)
+
+	return &StatsRollupRepository{collection: collection}
+}
+
+// rollupUpdate 按周期和开始时间写入汇总，expiresAt为nil时不过期
+func (sr *StatsRollupRepository) rollupUpdate(period string, start time.Time, expiresAt *time.Time, update bson.M) error {
+	filter := bson.M{"period": period, "start": start}
+	update["$set"].(bson.M)["updated_at"] = time.Now()
+	if expiresAt != nil {
+		update["$setOnInsert"] = bson.M{"expires_at": *expiresAt}
+	}
+
+	_, err := sr.collection.UpdateOne(context.Background(), filter, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// RecordPeak 记录采样的在线人数，只保留最高值
+func (sr *StatsRollupRepository) RecordPeak(period string, start time.Time, ccu int64, expiresAt *time.Time) error {
+	update := bson.M{"$set": bson.M{}, "$max": bson.M{"peak_ccu": ccu}}
+	if err := sr.rollupUpdate(period, start, expiresAt, update); err != nil {
+		return fmt.Errorf("failed to record peak ccu: %v", err)
+	}
+	return nil
+}
+
+// SetGames 覆盖期间结束的游戏统计
+func (sr *StatsRollupRepository) SetGames(period string, start time.Time, gameTypes []GameTypeRollup, expiresAt *time.Time) error {
+	var games int64
+	for _, gameType := range gameTypes {
+		games += gameType.Games
+	}
+	if gameTypes == nil {
+		gameTypes = []GameTypeRollup{}
+	}
+	update := bson.M{"$set": bson.M{"games": games, "game_types": gameTypes}}
+	if err := sr.rollupUpdate(period, start, expiresAt, update); err != nil {
+		return fmt.Errorf("failed to set rollup games: %v", err)
+	}
+	return nil
+}
+
+// GetRollups 获取开始时间在指定时间段内的汇总，按开始时间排序
+func (sr *StatsRollupRepository) GetRollups(period string, since, until time.Time) ([]*StatsRollup, error) {
+	filter := bson.M{"period": period, "start": bson.M{"$gte": since, "$lt": until}}
+	options := options.Find().SetSort(bson.D{{Key: "start", Value: 1}})
+
+	cursor, err := sr.collection.Find(context.Background(), filter, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats rollups: %v", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var rollups []*StatsRollup
+	if err := cursor.All(context.Background(), &rollups); err != nil {
+		return nil, fmt.Errorf("failed to decode stats rollups: %v", err)
+	}
+	return rollups, nil
+}
+
+// DesyncReport 客户端状态校验和与服务端不一致的报告，双方视角的状态以JSON保存
+type DesyncReport struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	RoomID      uint64             `bson:"room_id" json:"room_id"`
+	GameType    string             `bson:"game_type" json:"game_type"`
+	UserID      uint64             `bson:"user_id" json:"user_id"`
+	ActionType  string             `bson:"action_type" json:"action_type"` // 被拒绝的操作
+	Expected    string             `bson:"expected" json:"expected"`       // 服务端的校验和
+	Actual      string             `bson:"actual" json:"actual"`           // 客户端提交的校验和
+	ServerState string             `bson:"server_state" json:"server_state"`
+	ClientState string             `bson:"client_state,omitempty" json:"client_state,omitempty"` // 客户端重新同步前上报的本地状态
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	ReportedAt  *time.Time         `bson:"reported_at,omitempty" json:"reported_at,omitempty"`
+}
+
+// DesyncRepository 状态不一致报告仓库
+type DesyncRepository struct {
+	collection *mongo.Collection
+}
+
+// NewDesyncRepository 创建状态不一致报告仓库，报告按创建时间在retention后过期
+func NewDesyncRepository(mm *MongoManager, retention time.Duration) *DesyncRepository {
+	collection := mm.GetCollection("desync_reports")
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "room_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(retention.Seconds())),
+		},
+	}
+	collection.Indexes().CreateMany(context.Background(), indexes)
+
+	return &DesyncRepository{collection: collection}
+}
+
+// CreateReport 保存报告
+func (dr *DesyncRepository) CreateReport(report *DesyncReport) error {
+	report.CreatedAt = time.Now()
+	result, err := dr.collection.InsertOne(context.Background(), report)
+	if err != nil {
+		return fmt.Errorf("failed to create desync report: %v", err)
+	}
+	report.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// AttachClientState 附加客户端上报的本地状态，只接受报告所属玩家的第一次上报，返回是否附加
+func (dr *DesyncRepository) AttachClientState(reportID string, userID uint64, state string) (bool, error) {
+	id, err := primitive.ObjectIDFromHex(reportID)
+	if err != nil {
+		return false, fmt.Errorf("invalid desync report id: %s", reportID)
+	}
+
+	filter := bson.M{"_id": id, "user_id": userID, "reported_at": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"client_state": state, "reported_at": time.Now()}}
+	result, err := dr.collection.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to attach client state: %v", err)
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// ListReports 获取最近的报告，roomID为0时不限房间
+func (dr *DesyncRepository) ListReports(roomID uint64, limit int64) ([]*DesyncReport, error) {
+	filter := bson.M{}
+	if roomID != 0 {
+		filter["room_id"] = roomID
+	}
+	options := options.Find().
+		SetLimit(limit).
+		SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := dr.collection.Find(context.Background(), filter, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list desync reports: %v", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var reports []*DesyncReport
+	if err := cursor.All(context.Background(), &reports); err != nil {
+		return nil, fmt.Errorf("failed to decode desync reports: %v", err)
+	}
+	return reports, nil
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// startedCommand 已开始未结束的命令
+type startedCommand struct {
+	database   string
+	collection string
+	operation  string
+	command    bson.Raw // 只在开启慢查询记录时保存
+}
+
+// commandMonitor 驱动的命令监听，命令结束时记录操作指标和慢查询。
+// 握手、心跳等不属于集合的命令不记录
+func commandMonitor(metrics *OperationMetrics, slowQueries *SlowQueryLogger) *event.CommandMonitor {
+	var pending sync.Map // RequestID -> *startedCommand
+
+	finish := func(requestID int64, failed bool, e *event.CommandFinishedEvent) {
+		value, ok := pending.LoadAndDelete(requestID)
+		if !ok {
+			return
+		}
+		started := value.(*startedCommand)
+		metrics.observe(started.collection, started.operation, e.Duration, failed)
+		slowQueries.observe(started, e.Duration, failed)
+	}
+
+	return &event.CommandMonitor{
+		Started: func(_ context.Context, e *event.CommandStartedEvent) {
+			collection := commandCollection(e.CommandName, e.Command)
+			if collection == "" {
+				return
+			}
+			started := &startedCommand{database: e.DatabaseName, collection: collection, operation: e.CommandName}
+			if slowQueries.enabled() {
+				started.command = e.Command
+			}
+			pending.Store(e.RequestID, started)
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			finish(e.RequestID, hasWriteErrors(e.Reply), &e.CommandFinishedEvent)
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			finish(e.RequestID, true, &e.CommandFinishedEvent)
+		},
+	}
+}
+
+// commandCollection 命令操作的集合。集合命令的第一个字段为集合名，getMore的集合名在collection字段，
+// 数据库级别的命令如aggregate: 1返回空
+func commandCollection(name string, command bson.Raw) string {
+	if name == "getMore" {
+		collection, _ := command.Lookup("collection").StringValueOK()
+		return collection
+	}
+	elements, err := command.Elements()
+	if err != nil || len(elements) == 0 {
+		return ""
+	}
+	collection, _ := elements[0].Value().StringValueOK()
+	return collection
+}
+
+// hasWriteErrors 写命令执行成功但部分文档写入失败，如唯一索引冲突
+func hasWriteErrors(reply bson.Raw) bool {
+	if _, err := reply.LookupErr("writeErrors"); err == nil {
+		return true
+	}
+	_, err := reply.LookupErr("writeConcernError")
+	return err == nil
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// minMaxStaleness MongoDB允许的最小max_staleness
+const minMaxStaleness = 90 * time.Second
+
+// ReadMode 单次读取的读偏好
+type ReadMode int
+
+const (
+	// ReadPrimary 按全局的read_preference读取，写入后立即读取、余额、冷却时间等一致性要求高的读取使用
+	ReadPrimary ReadMode = iota
+	// ReadSecondaryPreferred 优先从副本读取，可以接受副本延迟的读取使用，如历史记录、统计和搜索
+	ReadSecondaryPreferred
+)
+
+// ReadSplittingConfig 读写分离配置
+type ReadSplittingConfig struct {
+	Enabled      bool          `yaml:"enabled"`       // 关闭时所有读取都按全局的read_preference
+	MaxStaleness time.Duration `yaml:"max_staleness"` // 副本落后主节点超过该时间时不从该副本读取，0为不限制，不能小于90s
+}
+
+// Validate 检查读写分离配置
+func (c *ReadSplittingConfig) Validate() error {
+	if c.MaxStaleness != 0 && c.MaxStaleness < minMaxStaleness {
+		return fmt.Errorf("read_splitting.max_staleness must be at least %v", minMaxStaleness)
+	}
+	return nil
+}
+
+// Reader 按读偏好读取的集合。写入和一致性要求高的读取使用GetCollection，
+// 可以接受副本延迟的读取由仓库按调用选择ReadSecondaryPreferred，未开启读写分离时两者相同
+func (mm *MongoManager) Reader(name string, mode ReadMode) *mongo.Collection {
+	if mode == ReadPrimary || !mm.config.ReadSplitting.Enabled {
+		return mm.GetCollection(name)
+	}
+
+	key := fmt.Sprintf("%s/%d", name, mode)
+	if collection, ok := mm.readers.Load(key); ok {
+		return collection.(*mongo.Collection)
+	}
+	var opts []readpref.Option
+	if staleness := mm.config.ReadSplitting.MaxStaleness; staleness > 0 {
+		opts = append(opts, readpref.WithMaxStaleness(staleness))
+	}
+	collection := mm.databaseFor(name).Collection(name, options.Collection().SetReadPreference(readpref.SecondaryPreferred(opts...)))
+	actual, _ := mm.readers.LoadOrStore(key, collection)
+	return actual.(*mongo.Collection)
+}
@@ -328,6 +328,10 @@ func (rm *RedisManager) HExists(key, field string) (bool, error) {
 	return rm.client.HExists(rm.ctx, key, field).Result()
 }
 
+func (rm *RedisManager) HIncrBy(key, field string, value int64) (int64, error) {
+	return rm.client.HIncrBy(rm.ctx, key, field, value).Result()
+}
+
 // List操作
 func (rm *RedisManager) LPush(key string, values ...interface{}) error {
 	return rm.client.LPush(rm.ctx, key, values...).Err()
@@ -426,6 +430,24 @@ func (rm *RedisManager) Unlock(key string) error {
 	return rm.client.Del(rm.ctx, lockKey).Err()
 }
 
+// unlockOwnedScript 锁的值与持有者一致时才删除
+var unlockOwnedScript = redis.NewScript(`if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("del", KEYS[1]) end return 0`)
+
+// LockOwned 获取记录持有者的分布式锁，持有超时后锁自动释放，之后原持有者的UnlockOwned不会删除其他持有者的锁
+func (rm *RedisManager) LockOwned(key, owner string, expiration time.Duration) (bool, error) {
+	return rm.client.SetNX(rm.ctx, fmt.Sprintf("lock:%s", key), owner, expiration).Result()
+}
+
+// UnlockOwned 释放自己持有的分布式锁
+func (rm *RedisManager) UnlockOwned(key, owner string) error {
+	return unlockOwnedScript.Run(rm.ctx, rm.client, []string{fmt.Sprintf("lock:%s", key)}, owner).Err()
+}
+
+// IsLocked 检查分布式锁是否被持有
+func (rm *RedisManager) IsLocked(key string) (bool, error) {
+	return rm.Exists(fmt.Sprintf("lock:%s", key))
+}
+
 // Pub/Sub操作
 func (rm *RedisManager) Publish(channel string, message interface{}) error {
 	return rm.client.Publish(rm.ctx, channel, message).Err()
@@ -0,0 +1,264 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// 慢查询记录默认设置
+const (
+	slowQueryCollection       = "slow_queries"
+	defaultSlowQueryPerMinute = 10
+	defaultSlowQueryRetention = 7 * 24 * time.Hour
+	slowQueryQueueSize        = 100
+	slowQueryExplainTimeout   = 5 * time.Second
+)
+
+// explainableCommands 可以执行explain的命令
+var explainableCommands = map[string]bool{
+	"find":          true,
+	"aggregate":     true,
+	"count":         true,
+	"distinct":      true,
+	"findAndModify": true,
+	"update":        true,
+	"delete":        true,
+}
+
+// SlowQueryConfig 慢查询记录配置
+type SlowQueryConfig struct {
+	Threshold    time.Duration `yaml:"threshold"`      // 耗时超过该值的命令记为慢查询，0为不记录
+	Explain      bool          `yaml:"explain"`        // 是否对慢查询执行explain记录执行计划，只生成计划不会再次执行
+	MaxPerMinute int           `yaml:"max_per_minute"` // 每分钟最多记录的条数，超过的只计数，未配置时为10
+	Retention    time.Duration `yaml:"retention"`      // 记录保存时间，未配置时为7天
+}
+
+// SlowQuery 慢查询记录，用于分析需要补充的索引
+type SlowQuery struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"`
+	Database     string             `bson:"database"`
+	Collection   string             `bson:"collection"`
+	Operation    string             `bson:"operation"`
+	DurationMs   int64              `bson:"duration_ms"`
+	Failed       bool               `bson:"failed,omitempty"`
+	Command      bson.Raw           `bson:"command,omitempty"` // 去掉会话信息和写入文档后的命令，包含查询条件
+	Plan         bson.Raw           `bson:"plan,omitempty"`    // explain的queryPlanner部分
+	ExplainError string             `bson:"explain_error,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at"`
+}
+
+// SlowQueryLogger 记录耗时超过阈值的命令，写入日志和slow_queries集合。
+// 按每分钟条数限流，explain和写入在后台执行，不阻塞原命令
+type SlowQueryLogger struct {
+	config     SlowQueryConfig
+	collection *mongo.Collection
+	databases  map[string]*mongo.Database // 数据库名 -> 数据库，explain在命令所在的数据库执行
+	queue      chan *SlowQuery
+	done       chan struct{}
+	stopOnce   sync.Once
+
+	mutex      sync.Mutex
+	window     time.Time // 当前限流窗口的开始时间
+	count      int       // 当前窗口已记录的条数
+	suppressed int       // 当前窗口因限流未记录的条数
+}
+
+// newSlowQueryLogger 创建慢查询记录器，连接数据库后调用start开始写入
+func newSlowQueryLogger(config *SlowQueryConfig) *SlowQueryLogger {
+	l := &SlowQueryLogger{
+		config:    *config,
+		databases: make(map[string]*mongo.Database),
+		queue:     make(chan *SlowQuery, slowQueryQueueSize),
+		done:      make(chan struct{}),
+	}
+	if l.config.MaxPerMinute <= 0 {
+		l.config.MaxPerMinute = defaultSlowQueryPerMinute
+	}
+	if l.config.Retention <= 0 {
+		l.config.Retention = defaultSlowQueryRetention
+	}
+	return l
+}
+
+// enabled 是否记录慢查询
+func (l *SlowQueryLogger) enabled() bool {
+	return l.config.Threshold > 0
+}
+
+// addDatabase 登记可以执行explain的数据库，需在start之前调用
+func (l *SlowQueryLogger) addDatabase(db *mongo.Database) {
+	l.databases[db.Name()] = db
+}
+
+// start 创建过期索引并开始后台写入，collection为slow_queries集合
+func (l *SlowQueryLogger) start(collection *mongo.Collection) {
+	if !l.enabled() {
+		return
+	}
+	l.collection = collection
+	l.collection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(l.config.Retention.Seconds())),
+		},
+		{
+			Keys: bson.D{{Key: "collection", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+	})
+	go l.run()
+}
+
+// stop 停止后台写入，队列中未写入的记录丢弃
+func (l *SlowQueryLogger) stop() {
+	l.stopOnce.Do(func() { close(l.done) })
+}
+
+// observe 命令结束时检查耗时，慢查询集合本身的命令不记录
+func (l *SlowQueryLogger) observe(started *startedCommand, duration time.Duration, failed bool) {
+	if !l.enabled() || duration < l.config.Threshold || started.collection == slowQueryCollection {
+		return
+	}
+	if !l.allow(time.Now()) {
+		return
+	}
+
+	query := &SlowQuery{
+		Database:   started.database,
+		Collection: started.collection,
+		Operation:  started.operation,
+		DurationMs: duration.Milliseconds(),
+		Failed:     failed,
+		Command:    sanitizeCommand(started.command),
+		CreatedAt:  time.Now(),
+	}
+	logger.Warn(fmt.Sprintf("Slow mongo %s on %s took %v: %s", query.Operation, query.Collection, duration, query.Command))
+
+	select {
+	case l.queue <- query:
+	default:
+		logger.Warn(fmt.Sprintf("Slow query queue full, dropped %s on %s", query.Operation, query.Collection))
+	}
+}
+
+// allow 按每分钟条数限流，进入新窗口时打印上一窗口未记录的条数
+func (l *SlowQueryLogger) allow(now time.Time) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if now.Sub(l.window) >= time.Minute {
+		if l.suppressed > 0 {
+			logger.Warn(fmt.Sprintf("Suppressed %d slow queries in the last minute", l.suppressed))
+		}
+		l.window, l.count, l.suppressed = now, 0, 0
+	}
+	if l.count >= l.config.MaxPerMinute {
+		l.suppressed++
+		return false
+	}
+	l.count++
+	return true
+}
+
+// run 后台执行explain并写入记录
+func (l *SlowQueryLogger) run() {
+	for {
+		select {
+		case query := <-l.queue:
+			if l.config.Explain && explainableCommands[query.Operation] && query.Command != nil {
+				query.Plan, query.ExplainError = l.explain(query.Database, query.Command)
+			}
+			if _, err := l.collection.InsertOne(context.Background(), query); err != nil {
+				logger.Error(fmt.Sprintf("Failed to save slow query: %v", err))
+			}
+		case <-l.done:
+			return
+		}
+	}
+}
+
+// explain 生成命令的执行计划，返回queryPlanner部分，失败时返回错误信息
+func (l *SlowQueryLogger) explain(database string, command bson.Raw) (bson.Raw, string) {
+	db, ok := l.databases[database]
+	if !ok {
+		return nil, fmt.Sprintf("unknown database %s", database)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), slowQueryExplainTimeout)
+	defer cancel()
+
+	reply, err := db.RunCommand(ctx, bson.D{
+		{Key: "explain", Value: command},
+		{Key: "verbosity", Value: "queryPlanner"},
+	}).DecodeBytes()
+	if err != nil {
+		return nil, err.Error()
+	}
+	if planner, ok := reply.Lookup("queryPlanner").DocumentOK(); ok {
+		return planner, ""
+	}
+	// 分片集群和部分聚合的执行计划不在queryPlanner中，保存完整结果
+	return reply, ""
+}
+
+// sanitizeCommand 去掉会话、事务和集群时间等驱动添加的字段，以及插入的文档，保留的部分可以直接explain
+func sanitizeCommand(command bson.Raw) bson.Raw {
+	elements, err := command.Elements()
+	if err != nil {
+		return nil
+	}
+	sanitized := make(bson.D, 0, len(elements))
+	for _, element := range elements {
+		key := element.Key()
+		switch {
+		case strings.HasPrefix(key, "$"):
+		case key == "lsid", key == "txnNumber", key == "autocommit", key == "startTransaction",
+			key == "readConcern", key == "writeConcern", key == "documents":
+		default:
+			sanitized = append(sanitized, bson.E{Key: key, Value: element.Value()})
+		}
+	}
+	data, err := bson.Marshal(sanitized)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// SlowQueryRepository 慢查询记录查询
+type SlowQueryRepository struct {
+	collection *mongo.Collection
+}
+
+// NewSlowQueryRepository 创建慢查询记录仓库，只用于查询，从副本读取
+func NewSlowQueryRepository(mm *MongoManager) *SlowQueryRepository {
+	return &SlowQueryRepository{collection: mm.Reader(slowQueryCollection, ReadSecondaryPreferred)}
+}
+
+// Recent 最近的慢查询记录，collection为空时不限集合
+func (r *SlowQueryRepository) Recent(collection string, limit int64) ([]*SlowQuery, error) {
+	filter := bson.M{}
+	if collection != "" {
+		filter["collection"] = collection
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit)
+	cursor, err := r.collection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find slow queries: %v", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var queries []*SlowQuery
+	if err := cursor.All(context.Background(), &queries); err != nil {
+		return nil, fmt.Errorf("failed to decode slow queries: %v", err)
+	}
+	return queries, nil
+}
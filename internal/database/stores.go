@@ -0,0 +1,118 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// StoreConfig 独立存放部分集合的数据库，用于把聊天、对局记录等写入量大的数据和用户等核心数据分开
+type StoreConfig struct {
+	URI         string   `yaml:"uri"`           // 独立集群的连接地址，为空时与主库共用连接，只使用不同的数据库
+	Database    string   `yaml:"database"`      // 数据库名
+	MaxPoolSize uint64   `yaml:"max_pool_size"` // 独立集群的连接池大小，未配置时与主库相同
+	Collections []string `yaml:"collections"`   // 存放在该库的集合，未列出的集合都在主库
+}
+
+// dataStore 已连接的独立数据库，client为nil时与主库共用连接
+type dataStore struct {
+	name     string
+	client   *mongo.Client
+	database *mongo.Database
+}
+
+// validateStores 检查独立数据库配置，同一集合只能指定到一个库
+func validateStores(stores map[string]StoreConfig) error {
+	assigned := make(map[string]string)
+	for name, store := range stores {
+		if store.Database == "" {
+			return fmt.Errorf("mongodb store %s: database is required", name)
+		}
+		if len(store.Collections) == 0 {
+			return fmt.Errorf("mongodb store %s: no collections assigned", name)
+		}
+		for _, collection := range store.Collections {
+			if other, ok := assigned[collection]; ok {
+				return fmt.Errorf("collection %s assigned to both mongodb store %s and %s", collection, other, name)
+			}
+			assigned[collection] = name
+		}
+	}
+	return nil
+}
+
+// connectStores 连接独立数据库并建立集合到数据库的映射，失败时断开已建立的连接
+func (mm *MongoManager) connectStores(ctx context.Context, monitor *event.CommandMonitor) error {
+	mm.routes = make(map[string]*mongo.Database)
+	for name, config := range mm.config.Stores {
+		store := &dataStore{name: name}
+		if config.URI == "" {
+			store.database = mm.client.Database(config.Database)
+		} else {
+			client, err := mm.connectStore(ctx, &config, monitor)
+			if err != nil {
+				mm.closeStores(ctx)
+				return fmt.Errorf("failed to connect mongodb store %s: %v", name, err)
+			}
+			store.client = client
+			store.database = client.Database(config.Database)
+		}
+		mm.stores = append(mm.stores, store)
+		for _, collection := range config.Collections {
+			mm.routes[collection] = store.database
+		}
+		mm.slowQueries.addDatabase(store.database)
+	}
+	return nil
+}
+
+// connectStore 连接独立集群，连接池和超时设置未配置时沿用主库
+func (mm *MongoManager) connectStore(ctx context.Context, config *StoreConfig, monitor *event.CommandMonitor) (*mongo.Client, error) {
+	poolSize := config.MaxPoolSize
+	if poolSize == 0 {
+		poolSize = mm.config.MaxPoolSize
+	}
+	opts := options.Client().
+		ApplyURI(config.URI).
+		SetConnectTimeout(mm.config.ConnectTimeout).
+		SetMaxPoolSize(poolSize).
+		SetMinPoolSize(mm.config.MinPoolSize).
+		SetMaxConnIdleTime(mm.config.MaxConnIdleTime).
+		SetMonitor(monitor)
+
+	client, err := mongo.Connect(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		client.Disconnect(ctx)
+		return nil, err
+	}
+	return client, nil
+}
+
+// closeStores 断开独立集群的连接
+func (mm *MongoManager) closeStores(ctx context.Context) error {
+	var firstErr error
+	for _, store := range mm.stores {
+		if store.client == nil {
+			continue
+		}
+		if err := store.client.Disconnect(ctx); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to disconnect mongodb store %s: %v", store.name, err)
+		}
+	}
+	mm.stores = nil
+	return firstErr
+}
+
+// databaseFor 集合所在的数据库
+func (mm *MongoManager) databaseFor(collection string) *mongo.Database {
+	if database, ok := mm.routes[collection]; ok {
+		return database
+	}
+	return mm.database
+}
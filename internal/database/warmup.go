@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Warmup 并发发送n次ping，让连接池预先建立n个连接，避免新节点的第一批请求等待建立连接；
+// 独立集群的数据库各建立n个连接
+func (mm *MongoManager) Warmup(ctx context.Context, n int) error {
+	if err := concurrently(n, func() error {
+		return mm.client.Ping(ctx, nil)
+	}); err != nil {
+		return err
+	}
+	for _, store := range mm.stores {
+		if store.client == nil {
+			continue
+		}
+		client := store.client
+		if err := concurrently(n, func() error {
+			return client.Ping(ctx, nil)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Warmup 并发发送n次ping，让连接池预先建立n个连接；集群模式下每个分片各建立n个连接
+func (rm *RedisManager) Warmup(ctx context.Context, n int) error {
+	if rm.clusterClient != nil {
+		return rm.clusterClient.ForEachShard(ctx, func(ctx context.Context, shard *redis.Client) error {
+			return concurrently(n, func() error {
+				return shard.Ping(ctx).Err()
+			})
+		})
+	}
+	return concurrently(n, func() error {
+		return rm.client.Ping(ctx).Err()
+	})
+}
+
+// concurrently 并发执行n次，返回第一个错误
+func concurrently(n int, fn func() error) error {
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := fn(); err != nil {
+				once.Do(func() { firstErr = err })
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return fmt.Errorf("warmup failed: %v", firstErr)
+	}
+	return nil
+}
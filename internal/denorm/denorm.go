@@ -0,0 +1,242 @@
+package denorm
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// 副本的更新方式
+const (
+	ModeRefresh = "refresh" // 玩家资料变化时更新窗口内文档中的副本
+	ModeRead    = "read"    // 列表响应按玩家ID读取最新资料，副本只在读取失败时使用
+	ModeOff     = "off"     // 保留写入时的快照
+)
+
+// 保存玩家昵称和等级副本的集合
+const (
+	CollectionRooms       = "rooms"        // 房间的玩家和观战者，回放按房间的玩家展示
+	CollectionGameRecords = "game_records" // 游戏记录的玩家
+	CollectionAsyncGames  = "async_games"  // 异步对局的玩家
+)
+
+// 默认设置
+const defaultCacheTTL = 30 * time.Second
+
+// collections 各集合支持的更新方式和默认设置。
+// 进行中的异步对局由游戏节点在内存中维护玩家信息，每步落库时会覆盖副本，只能读取时获取最新资料
+var collections = map[string]struct {
+	modes    []string
+	defaults Collection
+}{
+	CollectionRooms:       {[]string{ModeRefresh, ModeRead, ModeOff}, Collection{Mode: ModeRefresh, Window: 24 * time.Hour}},
+	CollectionGameRecords: {[]string{ModeRefresh, ModeOff}, Collection{Mode: ModeRefresh, Window: 30 * 24 * time.Hour}},
+	CollectionAsyncGames:  {[]string{ModeRead, ModeOff}, Collection{Mode: ModeRead}},
+}
+
+// Config 昵称和等级副本的更新配置
+type Config struct {
+	Collections map[string]Collection `yaml:"collections"` // 按集合配置，未配置的集合使用默认设置
+	Cache       time.Duration         `yaml:"cache"`       // read方式的玩家资料本地缓存时间，未配置时为30秒
+}
+
+// Collection 一个集合的副本更新方式
+type Collection struct {
+	Mode   string        `yaml:"mode"`
+	Window time.Duration `yaml:"window"` // refresh: 只更新该时间内创建的文档，未配置时使用默认设置
+}
+
+// Validate 检查副本更新配置
+func (c *Config) Validate() error {
+	for name, collection := range c.Collections {
+		supported, exists := collections[name]
+		if !exists {
+			return fmt.Errorf("unknown collection: %s", name)
+		}
+		if collection.Mode != "" && !contains(supported.modes, collection.Mode) {
+			return fmt.Errorf("collection %s does not support mode %s", name, collection.Mode)
+		}
+		if collection.Window < 0 {
+			return fmt.Errorf("window of collection %s must not be negative", name)
+		}
+	}
+	if c.Cache < 0 {
+		return fmt.Errorf("cache must not be negative")
+	}
+	return nil
+}
+
+// Change 玩家资料的变化，零值的字段没有变化
+type Change struct {
+	UserID   uint64
+	Nickname string
+	Level    int32
+}
+
+// Profile 玩家的最新昵称和等级
+type Profile struct {
+	Nickname string
+	Level    int32
+}
+
+// Store 副本存储，database.DenormRepository实现了该接口
+type Store interface {
+	RefreshPlayers(collection string, userID uint64, nickname string, level int32, since time.Time) (int64, error)
+}
+
+// UserStore 用户存储，database.UserRepository实现了该接口
+type UserStore interface {
+	GetByUserIDs(userIDs []uint64) ([]*database.User, error)
+}
+
+// cachedProfile 缓存的玩家资料
+type cachedProfile struct {
+	profile   Profile
+	expiresAt time.Time
+}
+
+// Service 昵称和等级副本的更新服务。refresh方式的集合在资料变化时更新，
+// read方式的集合在列表响应时通过Profiles读取最新资料
+type Service struct {
+	collections map[string]Collection
+	cacheTTL    time.Duration
+	store       Store
+	users       UserStore
+
+	mutex sync.Mutex
+	cache map[uint64]cachedProfile
+}
+
+// NewService 创建副本更新服务
+func NewService(config *Config, store Store, users UserStore) *Service {
+	s := &Service{
+		collections: make(map[string]Collection, len(collections)),
+		cacheTTL:    config.Cache,
+		store:       store,
+		users:       users,
+		cache:       make(map[uint64]cachedProfile),
+	}
+	if s.cacheTTL <= 0 {
+		s.cacheTTL = defaultCacheTTL
+	}
+	for name, supported := range collections {
+		collection := supported.defaults
+		if configured, exists := config.Collections[name]; exists {
+			if configured.Mode != "" {
+				collection.Mode = configured.Mode
+			}
+			if configured.Window > 0 {
+				collection.Window = configured.Window
+			}
+		}
+		s.collections[name] = collection
+	}
+	return s
+}
+
+// Mode 集合的更新方式
+func (s *Service) Mode(collection string) string {
+	return s.collections[collection].Mode
+}
+
+// Refresh 更新refresh方式的集合中玩家的副本，并清除本地缓存的玩家资料，单个集合失败不影响其他集合
+func (s *Service) Refresh(change Change) error {
+	s.Forget(change.UserID)
+	if change.Nickname == "" && change.Level == 0 {
+		return nil
+	}
+
+	var failed error
+	now := time.Now()
+	for name, collection := range s.collections {
+		if collection.Mode != ModeRefresh {
+			continue
+		}
+		updated, err := s.store.RefreshPlayers(name, change.UserID, change.Nickname, change.Level, now.Add(-collection.Window))
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to refresh player copies of user %d in %s: %v", change.UserID, name, err))
+			failed = err
+			continue
+		}
+		if updated > 0 {
+			logger.Debug(fmt.Sprintf("Refreshed player copies of user %d in %d %s", change.UserID, updated, name))
+		}
+	}
+	return failed
+}
+
+// Fire 在后台更新副本，不阻塞调用方
+func (s *Service) Fire(change Change) {
+	go s.Refresh(change)
+}
+
+// Forget 清除本地缓存的玩家资料
+func (s *Service) Forget(userID uint64) {
+	s.mutex.Lock()
+	delete(s.cache, userID)
+	s.mutex.Unlock()
+}
+
+// Profiles 获取玩家的最新资料，集合不是read方式时返回nil，调用方使用副本。
+// 读取失败的玩家不在结果中，调用方对这些玩家使用副本
+func (s *Service) Profiles(collection string, userIDs []uint64) map[uint64]Profile {
+	if s.Mode(collection) != ModeRead || len(userIDs) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	profiles := make(map[uint64]Profile, len(userIDs))
+	var missing []uint64
+	s.mutex.Lock()
+	for _, userID := range userIDs {
+		if cached, exists := s.cache[userID]; exists && now.Before(cached.expiresAt) {
+			profiles[userID] = cached.profile
+		} else {
+			missing = append(missing, userID)
+		}
+	}
+	s.mutex.Unlock()
+	if len(missing) == 0 {
+		return profiles
+	}
+
+	users, err := s.users.GetByUserIDs(missing)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Failed to load profiles for %s: %v", collection, err))
+		return profiles
+	}
+	s.mutex.Lock()
+	for _, user := range users {
+		profile := Profile{Nickname: user.Nickname, Level: user.Level}
+		profiles[user.UserID] = profile
+		s.cache[user.UserID] = cachedProfile{profile: profile, expiresAt: now.Add(s.cacheTTL)}
+	}
+	s.evict(now)
+	s.mutex.Unlock()
+	return profiles
+}
+
+// evict 清除过期的缓存，需持有锁
+func (s *Service) evict(now time.Time) {
+	if len(s.cache) < 10000 {
+		return
+	}
+	for userID, cached := range s.cache {
+		if !now.Before(cached.expiresAt) {
+			delete(s.cache, userID)
+		}
+	}
+}
+
+// contains 检查列表中是否包含值
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
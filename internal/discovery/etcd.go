@@ -7,12 +7,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math/rand"
+	"strconv"
 	"sync"
 	"time"
 
 	clientv3 "go.etcd.io/etcd/client/v3"
 
 	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/world"
 )
 
 // ServiceInfo 服务信息
@@ -24,9 +27,85 @@ type ServiceInfo struct {
 	Load       int               `json:"load"`
 	Status     string            `json:"status"`
 	Metadata   map[string]string `json:"metadata"`
+	Metrics    *ServiceMetrics   `json:"metrics,omitempty"`
 	UpdateTime int64             `json:"update_time"`
 }
 
+// ServiceMetrics 节点最近一个统计周期的RPC指标，随负载一起上报
+type ServiceMetrics struct {
+	Requests     int64   `json:"requests"`
+	Errors       int64   `json:"errors"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+	Window       int64   `json:"window"`      // 统计周期(秒)
+	Rooms        int64   `json:"rooms"`       // 节点上进行中的房间数，中心服据此扩缩容
+	Connections  int64   `json:"connections"` // 客户端连接数
+}
+
+// ErrorRate 错误率
+func (m *ServiceMetrics) ErrorRate() float64 {
+	if m == nil || m.Requests == 0 {
+		return 0
+	}
+	return float64(m.Errors) / float64(m.Requests)
+}
+
+// 服务元数据键
+const (
+	MetadataRegion       = "region"        // 节点所在区域
+	MetadataTCPPort      = "tcp_port"      // 网关对外TCP端口
+	MetadataVersion      = "version"       // 二进制版本
+	MetadataGitCommit    = "git_commit"    // 构建时的git提交
+	MetadataBuildTime    = "build_time"    // 构建时间
+	MetadataProtoSchema  = "proto_schema"  // 节点间消息定义的版本
+	MetadataCanary       = "canary"        // 灰度节点标记，值为true
+	MetadataCanaryWeight = "canary_weight" // 灰度节点分到的新流量百分比(0-100)
+	MetadataWorlds       = "worlds"        // 节点服务的世界，逗号分隔，未设置时服务所有世界
+)
+
+// ServesWorld 节点是否服务指定世界
+func ServesWorld(service *ServiceInfo, worldID uint32) bool {
+	value := service.Metadata[MetadataWorlds]
+	if value == "" {
+		return true
+	}
+	worldIDs, err := world.ParseIDs(value)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Service %s has invalid worlds metadata: %v", service.NodeID, err))
+		return false
+	}
+	for _, id := range worldIDs {
+		if id == worldID {
+			return true
+		}
+	}
+	return false
+}
+
+// MaxCanaryWeight 灰度权重上限
+const MaxCanaryWeight = 100
+
+// CanaryWeight 获取灰度权重，第二个返回值表示是否为灰度节点
+func CanaryWeight(service *ServiceInfo) (int, bool) {
+	if service.Metadata[MetadataCanary] != "true" {
+		return 0, false
+	}
+	weight, err := strconv.Atoi(service.Metadata[MetadataCanaryWeight])
+	if err != nil || weight < 0 {
+		return 0, true
+	}
+	if weight > MaxCanaryWeight {
+		weight = MaxCanaryWeight
+	}
+	return weight, true
+}
+
+// 服务状态
+const (
+	StatusOnline   = "online"   // 正常接收新流量
+	StatusDraining = "draining" // 排空中，负载均衡不再选择，已有连接和会话不受影响
+	StatusStarting = "starting" // 启动中，非关键依赖尚未就绪，负载均衡不选择
+)
+
 // ServiceRegistry 服务注册接口
 type ServiceRegistry interface {
 	Register(info *ServiceInfo) error
@@ -246,6 +325,11 @@ func (r *ETCDRegistry) Register(info *ServiceInfo) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
+	return r.put(info)
+}
+
+// put 写入服务信息，调用方需持有锁
+func (r *ETCDRegistry) put(info *ServiceInfo) error {
 	info.UpdateTime = time.Now().Unix()
 	key := r.keyPrefix + info.NodeType + "/" + info.NodeID
 
@@ -299,9 +383,62 @@ func (r *ETCDRegistry) UpdateLoad(nodeID string, load int) error {
 	}
 
 	info.Load = load
-	info.UpdateTime = time.Now().Unix()
 
-	return r.Register(info)
+	return r.put(info)
+}
+
+// UpdateMetrics 更新服务的RPC指标
+func (r *ETCDRegistry) UpdateMetrics(nodeID string, metrics *ServiceMetrics) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	info, exists := r.services[nodeID]
+	if !exists {
+		return fmt.Errorf("service %s not found", nodeID)
+	}
+
+	info.Metrics = metrics
+
+	return r.put(info)
+}
+
+// UpdateMetadata 更新服务元数据，值为空时删除对应的键
+func (r *ETCDRegistry) UpdateMetadata(nodeID string, metadata map[string]string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	info, exists := r.services[nodeID]
+	if !exists {
+		return fmt.Errorf("service %s not found", nodeID)
+	}
+
+	if info.Metadata == nil {
+		info.Metadata = make(map[string]string)
+	}
+	for key, value := range metadata {
+		if value == "" {
+			delete(info.Metadata, key)
+			continue
+		}
+		info.Metadata[key] = value
+	}
+
+	return r.put(info)
+}
+
+// UpdateStatus 更新服务状态，如排空或恢复
+func (r *ETCDRegistry) UpdateStatus(nodeID, status string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	info, exists := r.services[nodeID]
+	if !exists {
+		return fmt.Errorf("service %s not found", nodeID)
+	}
+
+	info.Status = status
+
+	return r.put(info)
 }
 
 // GetServices 获取指定类型的所有服务
@@ -444,6 +581,7 @@ func NewRoundRobinLoadBalancer() *RoundRobinLoadBalancer {
 
 // Select 选择服务
 func (lb *RoundRobinLoadBalancer) Select(services []*ServiceInfo) *ServiceInfo {
+	services = splitCanary(routable(services))
 	if len(services) == 0 {
 		return nil
 	}
@@ -471,6 +609,7 @@ func NewWeightedLoadBalancer() *WeightedLoadBalancer {
 
 // Select 基于负载选择服务
 func (lb *WeightedLoadBalancer) Select(services []*ServiceInfo) *ServiceInfo {
+	services = splitCanary(routable(services))
 	if len(services) == 0 {
 		return nil
 	}
@@ -480,7 +619,7 @@ func (lb *WeightedLoadBalancer) Select(services []*ServiceInfo) *ServiceInfo {
 	minLoad := int(^uint(0) >> 1) // 最大int值
 
 	for _, service := range services {
-		if service.Status == "online" && service.Load < minLoad {
+		if service.Status == StatusOnline && service.Load < minLoad {
 			minLoad = service.Load
 			selected = service
 		}
@@ -494,6 +633,46 @@ func (lb *WeightedLoadBalancer) Select(services []*ServiceInfo) *ServiceInfo {
 	return selected
 }
 
+// routable 过滤掉排空中和启动中的服务
+func routable(services []*ServiceInfo) []*ServiceInfo {
+	result := make([]*ServiceInfo, 0, len(services))
+	for _, service := range services {
+		if service.Status != StatusDraining && service.Status != StatusStarting {
+			result = append(result, service)
+		}
+	}
+	return result
+}
+
+// splitCanary 按灰度权重分流，命中灰度节点时只返回该节点，否则只返回非灰度节点
+//
+// 每个灰度节点分到约权重百分比的选择次数，权重为0的灰度节点不再分到新流量；
+// 没有非灰度节点时不分流，避免流量无处可去
+func splitCanary(services []*ServiceInfo) []*ServiceInfo {
+	stable := make([]*ServiceInfo, 0, len(services))
+	canaries := make([]*ServiceInfo, 0)
+	for _, service := range services {
+		if _, canary := CanaryWeight(service); canary {
+			canaries = append(canaries, service)
+		} else {
+			stable = append(stable, service)
+		}
+	}
+	if len(canaries) == 0 || len(stable) == 0 {
+		return services
+	}
+
+	roll := rand.Intn(MaxCanaryWeight)
+	for _, service := range canaries {
+		weight, _ := CanaryWeight(service)
+		if roll < weight {
+			return []*ServiceInfo{service}
+		}
+		roll -= weight
+	}
+	return stable
+}
+
 // NewServiceDiscovery 创建服务发现器
 func NewServiceDiscovery(registry ServiceRegistry, nodeType string, loadBalancer LoadBalancer) *ServiceDiscovery {
 	if loadBalancer == nil {
@@ -546,6 +725,55 @@ func (sd *ServiceDiscovery) GetService(nodeType string) *ServiceInfo {
 	return sd.loadBalancer.Select(services)
 }
 
+// GetServiceInRegion 优先选择指定区域的服务实例，区域内无可用实例时回退到全部实例
+func (sd *ServiceDiscovery) GetServiceInRegion(nodeType, region string) *ServiceInfo {
+	if region == "" {
+		return sd.GetService(nodeType)
+	}
+
+	services := sd.GetAllServices(nodeType)
+	regional := make([]*ServiceInfo, 0, len(services))
+	for _, service := range services {
+		if service.Metadata[MetadataRegion] == region {
+			regional = append(regional, service)
+		}
+	}
+
+	if len(regional) == 0 {
+		logger.Debug(fmt.Sprintf("No %s service in region %s, falling back to any region", nodeType, region))
+		return sd.loadBalancer.Select(services)
+	}
+
+	return sd.loadBalancer.Select(regional)
+}
+
+// GetServiceInWorld 选择服务指定世界的服务实例，同样优先选择指定区域
+//
+// 世界之间的数据互相隔离，没有服务该世界的实例时返回nil，不会回退到其他世界的实例
+func (sd *ServiceDiscovery) GetServiceInWorld(nodeType string, worldID uint32, region string) *ServiceInfo {
+	services := sd.GetAllServices(nodeType)
+	inWorld := make([]*ServiceInfo, 0, len(services))
+	regional := make([]*ServiceInfo, 0, len(services))
+	for _, service := range services {
+		if !ServesWorld(service, worldID) {
+			continue
+		}
+		inWorld = append(inWorld, service)
+		if region != "" && service.Metadata[MetadataRegion] == region {
+			regional = append(regional, service)
+		}
+	}
+
+	if len(inWorld) == 0 {
+		logger.Warn(fmt.Sprintf("No %s service serves world %d", nodeType, worldID))
+		return nil
+	}
+	if len(regional) == 0 {
+		return sd.loadBalancer.Select(inWorld)
+	}
+	return sd.loadBalancer.Select(regional)
+}
+
 // GetAllServices 获取所有服务实例
 func (sd *ServiceDiscovery) GetAllServices(nodeType string) []*ServiceInfo {
 	sd.cacheMutex.RLock()
@@ -0,0 +1,204 @@
+package entitlement
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// 玩家VIP等级的缓存
+const (
+	defaultCacheTTL = time.Minute
+	maxCacheEntries = 10000 // 超过时清除过期的条目
+)
+
+// Config VIP等级的权益，未配置的等级没有任何权益。配额按等级的上限在quota.tiers中配置
+type Config struct {
+	Tiers    []Tier        `yaml:"tiers"`
+	CacheTTL time.Duration `yaml:"cache_ttl"` // 玩家等级的缓存时间，未配置时为1分钟，等级变更在此时间内生效
+}
+
+// Tier 一个VIP等级的权益
+type Tier struct {
+	Tier               int32         `yaml:"tier" json:"tier"`
+	Name               string        `yaml:"name" json:"name"`
+	MailRewardBonus    int64         `yaml:"mail_reward_bonus" json:"mail_reward_bonus"`       // 奖励邮件中物品数量增加的百分比，向下取整
+	MailRetention      time.Duration `yaml:"mail_retention" json:"mail_retention"`             // 奖励邮件延长的有效期
+	ExchangeLimitBonus int64         `yaml:"exchange_limit_bonus" json:"exchange_limit_bonus"` // 货币兑换每日上限增加的百分比
+	MatchPriority      time.Duration `yaml:"match_priority" json:"match_priority"`             // 匹配排队时按多等待了该时长排在前面
+}
+
+// Validate 检查VIP配置
+func (c *Config) Validate() error {
+	tiers := make(map[int32]bool, len(c.Tiers))
+	for _, tier := range c.Tiers {
+		if tier.Tier <= 0 {
+			return fmt.Errorf("vip tier must be positive: %d", tier.Tier)
+		}
+		if tiers[tier.Tier] {
+			return fmt.Errorf("duplicate vip tier: %d", tier.Tier)
+		}
+		tiers[tier.Tier] = true
+		if tier.MailRewardBonus < 0 || tier.ExchangeLimitBonus < 0 {
+			return fmt.Errorf("bonuses of vip tier %d must not be negative", tier.Tier)
+		}
+		if tier.MailRetention < 0 || tier.MatchPriority < 0 {
+			return fmt.Errorf("durations of vip tier %d must not be negative", tier.Tier)
+		}
+	}
+	return nil
+}
+
+// UserStore 用户存储，database.UserRepository实现了该接口
+type UserStore interface {
+	GetByUserID(userID uint64) (*database.User, error)
+}
+
+// cachedTier 缓存的玩家等级
+type cachedTier struct {
+	tier      int32
+	expiresAt time.Time
+}
+
+// Service 权益服务，配额、邮件、兑换和匹配都通过它获取玩家的VIP等级和权益。
+// 等级从用户文档读取并缓存，读取失败时按0级处理，不影响正常操作
+type Service struct {
+	config Config
+	tiers  map[int32]*Tier
+	users  UserStore
+	cache  map[uint64]cachedTier
+	mutex  sync.Mutex
+}
+
+// NewService 创建权益服务
+func NewService(config *Config, users UserStore) *Service {
+	c := *config
+	if c.CacheTTL <= 0 {
+		c.CacheTTL = defaultCacheTTL
+	}
+	tiers := make(map[int32]*Tier, len(c.Tiers))
+	for i := range c.Tiers {
+		tiers[c.Tiers[i].Tier] = &c.Tiers[i]
+	}
+	return &Service{
+		config: c,
+		tiers:  tiers,
+		users:  users,
+		cache:  make(map[uint64]cachedTier),
+	}
+}
+
+// Tier 玩家当前有效的VIP等级，可作为quota.TierResolver
+func (s *Service) Tier(userID uint64) int32 {
+	now := time.Now()
+	s.mutex.Lock()
+	cached, exists := s.cache[userID]
+	s.mutex.Unlock()
+	if exists && now.Before(cached.expiresAt) {
+		return cached.tier
+	}
+
+	user, err := s.users.GetByUserID(userID)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Failed to get vip tier of user %d: %v", userID, err))
+		return 0
+	}
+	tier := user.ActiveVIPTier(now)
+	expiresAt := now.Add(s.config.CacheTTL)
+	// 会员在缓存期间到期时，缓存随之失效
+	if tier > 0 && !user.VIPExpiresAt.IsZero() && user.VIPExpiresAt.Before(expiresAt) {
+		expiresAt = user.VIPExpiresAt
+	}
+
+	s.mutex.Lock()
+	s.cache[userID] = cachedTier{tier: tier, expiresAt: expiresAt}
+	s.evict(now)
+	s.mutex.Unlock()
+	return tier
+}
+
+// Benefits 玩家当前的权益，没有VIP等级或等级未配置时返回零值
+func (s *Service) Benefits(userID uint64) Tier {
+	tier := s.Tier(userID)
+	if benefits, exists := s.tiers[tier]; exists {
+		return *benefits
+	}
+	return Tier{Tier: tier}
+}
+
+// Invalidate 清除玩家等级的缓存，本节点变更等级后调用
+func (s *Service) Invalidate(userID uint64) {
+	s.mutex.Lock()
+	delete(s.cache, userID)
+	s.mutex.Unlock()
+}
+
+// ExchangeLimit 按玩家的权益调整货币兑换的每日上限，0为不限制，原样返回
+func (s *Service) ExchangeLimit(userID uint64, limit int64) int64 {
+	if limit <= 0 {
+		return limit
+	}
+	bonus := s.Benefits(userID).ExchangeLimitBonus
+	return limit + limit*bonus/100
+}
+
+// MatchPriority 票据中玩家的匹配优先时长，队伍取成员中最高的一档
+func (s *Service) MatchPriority(userIDs []uint64) time.Duration {
+	var priority time.Duration
+	for _, userID := range userIDs {
+		priority = max(priority, s.Benefits(userID).MatchPriority)
+	}
+	return priority
+}
+
+// ApplyMail 按收件人的权益调整奖励邮件，没有奖励的邮件不调整
+func (s *Service) ApplyMail(mail *database.Mail) {
+	if len(mail.Rewards) == 0 {
+		return
+	}
+	benefits := s.Benefits(mail.ToUserID)
+	if benefits.MailRewardBonus > 0 {
+		rewards := make([]database.MailReward, len(mail.Rewards))
+		for i, reward := range mail.Rewards {
+			reward.Count += reward.Count * benefits.MailRewardBonus / 100
+			rewards[i] = reward
+		}
+		// 奖励列表可能与其他收件人的邮件共用，复制后再修改
+		mail.Rewards = rewards
+	}
+	if benefits.MailRetention > 0 && !mail.ExpireAt.IsZero() {
+		mail.ExpireAt = mail.ExpireAt.Add(benefits.MailRetention)
+	}
+}
+
+// evict 缓存较多时清除过期的条目，需持有锁
+func (s *Service) evict(now time.Time) {
+	if len(s.cache) < maxCacheEntries {
+		return
+	}
+	for userID, cached := range s.cache {
+		if !now.Before(cached.expiresAt) {
+			delete(s.cache, userID)
+		}
+	}
+}
+
+// Mails 按收件人权益调整奖励邮件的邮件仓库，用于发放奖励的服务
+type Mails struct {
+	*database.MailRepository
+	service *Service
+}
+
+// Mails 包装邮件仓库，创建邮件前应用收件人的权益
+func (s *Service) Mails(repo *database.MailRepository) *Mails {
+	return &Mails{MailRepository: repo, service: s}
+}
+
+// CreateMail 应用收件人的权益后创建邮件
+func (m *Mails) CreateMail(mail *database.Mail) error {
+	m.service.ApplyMail(mail)
+	return m.MailRepository.CreateMail(mail)
+}
@@ -0,0 +1,159 @@
+package errcode
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// RequestInfo 请求上下文信息，由请求入口写入，构造响应时读取
+// 消息头中已有的字段优先，缺失时使用上下文中的值，仍缺失时请求ID和追踪ID自动生成
+type RequestInfo struct {
+	RequestID string
+	TraceID   string
+	Language  string
+}
+
+type requestInfoKey struct{}
+
+// WithRequestInfo 在上下文中携带请求信息
+func WithRequestInfo(ctx context.Context, info RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+// RequestInfoFrom 获取上下文中的请求信息
+func RequestInfoFrom(ctx context.Context) RequestInfo {
+	if ctx == nil {
+		return RequestInfo{}
+	}
+	info, _ := ctx.Value(requestInfoKey{}).(RequestInfo)
+	return info
+}
+
+// Builder 响应构造器，统一回显消息头并附加请求ID、追踪ID、服务器时间和本地化消息，错误响应另附结构化的错误信息
+type Builder struct {
+	localizer Localizer
+}
+
+// NewBuilder 创建响应构造器，localizer为nil时使用默认消息
+func NewBuilder(localizer Localizer) *Builder {
+	return &Builder{localizer: localizer}
+}
+
+// defaultBuilder 不做本地化的构造器，供Response和CommonResponse使用
+var defaultBuilder = NewBuilder(nil)
+
+// Success 构造成功响应，message同时作为本地化消息ID，未配置翻译时原样返回
+func (b *Builder) Success(ctx context.Context, header *proto.MessageHeader, message string, data []byte) *proto.BaseResponse {
+	info := b.resolve(ctx, header, "")
+	return &proto.BaseResponse{
+		Header:     header,
+		Code:       OK.Value,
+		Msg:        b.translate(message, info.Language),
+		Data:       data,
+		Category:   string(CategoryOK),
+		RequestId:  info.RequestID,
+		TraceId:    info.TraceID,
+		ServerTime: time.Now().UnixMilli(),
+	}
+}
+
+// Error 构造错误响应
+func (b *Builder) Error(ctx context.Context, header *proto.MessageHeader, err error) *proto.BaseResponse {
+	return b.errorResponse(ctx, header, err, "")
+}
+
+// CommonSuccess 构造成功的CommonResponse
+func (b *Builder) CommonSuccess(ctx context.Context, message string, data []byte) *proto.CommonResponse {
+	info := b.resolve(ctx, nil, "")
+	return &proto.CommonResponse{
+		Code:       OK.Value,
+		Message:    b.translate(message, info.Language),
+		Data:       data,
+		Category:   string(CategoryOK),
+		RequestId:  info.RequestID,
+		TraceId:    info.TraceID,
+		ServerTime: time.Now().UnixMilli(),
+	}
+}
+
+// CommonError 构造错误的CommonResponse
+func (b *Builder) CommonError(ctx context.Context, err error) *proto.CommonResponse {
+	info := b.resolve(ctx, nil, "")
+	code := From(err)
+	return &proto.CommonResponse{
+		Code:       code.Value,
+		Message:    message(code, detailOf(err), paramsOf(err), b.localizer, info.Language),
+		Category:   string(code.Category),
+		RequestId:  info.RequestID,
+		TraceId:    info.TraceID,
+		ServerTime: time.Now().UnixMilli(),
+		ErrorInfo:  errorInfo(code, err),
+	}
+}
+
+// errorResponse 构造错误响应，langCode为空时按消息头和上下文确定语言
+func (b *Builder) errorResponse(ctx context.Context, header *proto.MessageHeader, err error, langCode string) *proto.BaseResponse {
+	info := b.resolve(ctx, header, langCode)
+	code := From(err)
+	return &proto.BaseResponse{
+		Header:     header,
+		Code:       code.Value,
+		Msg:        message(code, detailOf(err), paramsOf(err), b.localizer, info.Language),
+		Category:   string(code.Category),
+		RequestId:  info.RequestID,
+		TraceId:    info.TraceID,
+		ServerTime: time.Now().UnixMilli(),
+		ErrorInfo:  errorInfo(code, err),
+	}
+}
+
+// resolve 合并消息头和上下文中的请求信息
+func (b *Builder) resolve(ctx context.Context, header *proto.MessageHeader, langCode string) RequestInfo {
+	info := RequestInfoFrom(ctx)
+
+	if id := header.GetClientMsgId(); id != "" {
+		info.RequestID = id
+	}
+	if id := header.GetTraceId(); id != "" {
+		info.TraceID = id
+	}
+	if lang := header.GetLanguage(); lang != "" {
+		info.Language = lang
+	}
+	if langCode != "" {
+		info.Language = langCode
+	}
+
+	if info.RequestID == "" {
+		info.RequestID = NewID()
+	}
+	if info.TraceID == "" {
+		info.TraceID = NewID()
+	}
+	return info
+}
+
+// translate 本地化成功消息，缺少翻译时返回原消息
+func (b *Builder) translate(msg, langCode string) string {
+	if b.localizer == nil || msg == "" {
+		return msg
+	}
+	if translated := b.localizer.Translate(langCode, msg, nil); translated != msg {
+		return translated
+	}
+	return msg
+}
+
+// NewID 生成随机的请求ID或追踪ID
+func NewID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%016x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
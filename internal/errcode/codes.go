@@ -0,0 +1,179 @@
+package errcode
+
+// 错误码按业务域分段：通用1xxx，大厅2xxx，游戏3xxx，邮件4xxx，GM 5xxx，聊天6xxx
+// 已发布的错误码值不能修改或复用
+
+// 通用
+var (
+	OK               = define(0, DomainCommon, CategoryOK, "success.ok", "Success")
+	NotLoggedIn      = define(1001, DomainCommon, CategoryUnauthenticated, "error.not_logged_in", "User not logged in")
+	InvalidRequest   = define(1002, DomainCommon, CategoryInvalidArgument, "error.invalid_request", "Invalid request data")
+	Internal         = define(1003, DomainCommon, CategoryInternal, "error.server_error", "Server error")
+	UserNotFound     = define(1004, DomainCommon, CategoryNotFound, "error.user_not_found", "User not found")
+	PermissionDenied = define(1005, DomainCommon, CategoryPermissionDenied, "error.permission_denied", "Permission denied")
+	RateLimited      = define(1006, DomainCommon, CategoryRateLimited, "error.rate_limit_exceeded", "Rate limit exceeded")
+	SecurityRejected = define(1007, DomainCommon, CategoryPermissionDenied, "error.security_rejected", "Request rejected by security check")
+	MissingToken     = define(1008, DomainCommon, CategoryUnauthenticated, "error.missing_token", "Authentication token is required")
+	FeatureLocked    = define(1009, DomainCommon, CategoryPermissionDenied, "error.feature_locked", "Feature is not unlocked at your level")
+	Unavailable      = define(1010, DomainCommon, CategoryUnavailable, "error.service_unavailable", "Service temporarily unavailable, please retry")
+	QuotaExceeded    = define(1011, DomainCommon, CategoryRateLimited, "error.quota_exceeded", "Daily limit for this action reached")
+	ActionInProgress = define(1012, DomainCommon, CategoryRateLimited, "error.action_in_progress", "Previous action is still in progress, please retry")
+)
+
+// 大厅
+var (
+	RoomListFailed       = define(2001, DomainLobby, CategoryUnavailable, "error.lobby.room_list_failed", "Failed to get room list")
+	RoomNameRequired     = define(2002, DomainLobby, CategoryInvalidArgument, "error.lobby.room_name_required", "Room name cannot be empty")
+	InvalidMaxPlayers    = define(2003, DomainLobby, CategoryInvalidArgument, "error.lobby.invalid_max_players", "Max players must be between 2 and 8")
+	RoomPasswordRequired = define(2004, DomainLobby, CategoryInvalidArgument, "error.lobby.room_password_required", "Private room requires password")
+	CreateRoomFailed     = define(2005, DomainLobby, CategoryInternal, "error.lobby.create_room_failed", "Failed to create room")
+	InvalidRoomID        = define(2006, DomainLobby, CategoryInvalidArgument, "error.lobby.invalid_room_id", "Invalid room id")
+	RoomNotFound         = define(2007, DomainLobby, CategoryNotFound, "error.lobby.room_not_found", "Room not found")
+	RoomUnavailable      = define(2008, DomainLobby, CategoryConflict, "error.lobby.room_unavailable", "Room is not available")
+	RoomFull             = define(2009, DomainLobby, CategoryConflict, "error.lobby.room_full", "Room is full")
+	AlreadyInRoom        = define(2010, DomainLobby, CategoryConflict, "error.lobby.already_in_room", "Already in room")
+	WrongRoomPassword    = define(2011, DomainLobby, CategoryPermissionDenied, "error.lobby.wrong_room_password", "Wrong room password")
+	RoomRegionMismatch   = define(2012, DomainLobby, CategoryPermissionDenied, "error.lobby.room_region_mismatch", "Room is in a different region")
+	JoinRoomFailed       = define(2013, DomainLobby, CategoryInternal, "error.lobby.join_room_failed", "Failed to join room")
+	NotInRoom            = define(2014, DomainLobby, CategoryConflict, "error.lobby.not_in_room", "Not in room")
+	LeaveRoomFailed      = define(2015, DomainLobby, CategoryInternal, "error.lobby.leave_room_failed", "Failed to leave room")
+	RoomWorldMismatch    = define(2016, DomainLobby, CategoryPermissionDenied, "error.lobby.room_world_mismatch", "Room belongs to a different world")
+	ExchangeUnavailable  = define(2017, DomainLobby, CategoryInvalidArgument, "error.lobby.exchange_unavailable", "Exchange between these currencies is not available")
+	ExchangeTooSmall     = define(2018, DomainLobby, CategoryInvalidArgument, "error.lobby.exchange_too_small", "Exchange amount is too small")
+	ExchangeDailyLimit   = define(2019, DomainLobby, CategoryRateLimited, "error.lobby.exchange_daily_limit", "Daily exchange limit reached")
+	InsufficientBalance  = define(2020, DomainLobby, CategoryConflict, "error.lobby.insufficient_balance", "Insufficient balance")
+	ExchangeFailed       = define(2021, DomainLobby, CategoryInternal, "error.lobby.exchange_failed", "Exchange failed")
+	SurveyNotFound       = define(2022, DomainLobby, CategoryNotFound, "error.lobby.survey_not_found", "Survey not found")
+	SurveyClosed         = define(2023, DomainLobby, CategoryConflict, "error.lobby.survey_closed", "Survey is not open")
+	SurveyNotEligible    = define(2024, DomainLobby, CategoryPermissionDenied, "error.lobby.survey_not_eligible", "Survey is not available to you")
+	SurveyAnswered       = define(2025, DomainLobby, CategoryConflict, "error.lobby.survey_answered", "Survey already answered")
+	SurveyAnswerInvalid  = define(2026, DomainLobby, CategoryInvalidArgument, "error.lobby.survey_answer_invalid", "Invalid survey answer")
+	TicketInvalid        = define(2027, DomainLobby, CategoryInvalidArgument, "error.lobby.ticket_invalid", "Invalid support ticket")
+	TicketLimitReached   = define(2028, DomainLobby, CategoryRateLimited, "error.lobby.ticket_limit_reached", "Too many unresolved support tickets")
+	MatchQueued          = define(2029, DomainLobby, CategoryConflict, "error.lobby.match_queued", "Already in matchmaking queue")
+	MatchNotQueued       = define(2030, DomainLobby, CategoryNotFound, "error.lobby.match_not_queued", "Not in matchmaking queue")
+	MatchInvalid         = define(2031, DomainLobby, CategoryInvalidArgument, "error.lobby.match_invalid", "Invalid matchmaking request")
+	NotInParty           = define(2032, DomainLobby, CategoryNotFound, "error.lobby.not_in_party", "Not in a party")
+	AlreadyInParty       = define(2033, DomainLobby, CategoryConflict, "error.lobby.already_in_party", "Already in a party")
+	NotPartyLeader       = define(2034, DomainLobby, CategoryPermissionDenied, "error.lobby.not_party_leader", "Only the party leader can do this")
+	PartyFull            = define(2035, DomainLobby, CategoryConflict, "error.lobby.party_full", "Party is full")
+	PartyInviteInvalid   = define(2036, DomainLobby, CategoryNotFound, "error.lobby.party_invite_invalid", "Party invite has expired or does not exist")
+	PartyMessageInvalid  = define(2037, DomainLobby, CategoryInvalidArgument, "error.lobby.party_message_invalid", "Invalid party message")
+	NotFriends           = define(2038, DomainLobby, CategoryPermissionDenied, "error.lobby.not_friends", "Not friends with this player")
+	FriendNotInRoom      = define(2039, DomainLobby, CategoryNotFound, "error.lobby.friend_not_in_room", "Friend is not in a room")
+	SpectateDenied       = define(2040, DomainLobby, CategoryPermissionDenied, "error.lobby.spectate_denied", "Cannot spectate this room")
+	SpectatorsFull       = define(2041, DomainLobby, CategoryConflict, "error.lobby.spectators_full", "Room has no spectator seats left")
+	InvalidSettings      = define(2042, DomainLobby, CategoryInvalidArgument, "error.lobby.invalid_settings", "Invalid settings")
+	InvalidName          = define(2043, DomainLobby, CategoryInvalidArgument, "error.lobby.invalid_name", "Name is not allowed")
+	NicknameTaken        = define(2044, DomainLobby, CategoryConflict, "error.lobby.nickname_taken", "Nickname is already taken")
+	RenameCooldown       = define(2045, DomainLobby, CategoryRateLimited, "error.lobby.rename_cooldown", "Nickname was changed recently, please try again later")
+	UsernameTaken        = define(2046, DomainLobby, CategoryConflict, "error.lobby.username_taken", "Username is already taken")
+	RenameNotAllowed     = define(2047, DomainLobby, CategoryPermissionDenied, "error.lobby.rename_not_allowed", "This name cannot be changed")
+	SearchQueryTooShort  = define(2048, DomainLobby, CategoryInvalidArgument, "error.lobby.search_query_too_short", "Search text is too short")
+	RedeemCodeInvalid    = define(2049, DomainLobby, CategoryNotFound, "error.lobby.redeem_code_invalid", "Invalid redeem code")
+	RedeemCodeInactive   = define(2050, DomainLobby, CategoryConflict, "error.lobby.redeem_code_inactive", "This redeem code is not active")
+	RedeemCodeUsedUp     = define(2051, DomainLobby, CategoryConflict, "error.lobby.redeem_code_used_up", "This redeem code has been used up")
+	RedeemLimitReached   = define(2052, DomainLobby, CategoryConflict, "error.lobby.redeem_limit_reached", "You have already redeemed this reward")
+	RedeemThrottled      = define(2053, DomainLobby, CategoryRateLimited, "error.lobby.redeem_throttled", "Too many invalid redeem codes, please try again later")
+	RedeemFailed         = define(2054, DomainLobby, CategoryInternal, "error.lobby.redeem_failed", "Failed to redeem code")
+	RoomJoinThrottled    = define(2055, DomainLobby, CategoryRateLimited, "error.lobby.room_join_throttled", "Too many wrong room passwords, please try again later")
+	RoomLocked           = define(2056, DomainLobby, CategoryRateLimited, "error.lobby.room_locked", "Room is temporarily locked after too many wrong passwords")
+)
+
+// 游戏
+var (
+	InvalidGameID     = define(3001, DomainGame, CategoryInvalidArgument, "error.game.invalid_game_id", "Invalid game id")
+	GameNotFound      = define(3002, DomainGame, CategoryNotFound, "error.game.game_not_found", "Game not found")
+	NotInGame         = define(3003, DomainGame, CategoryPermissionDenied, "error.game.not_in_game", "User not in game")
+	GameAlreadyEnded  = define(3004, DomainGame, CategoryConflict, "error.game.game_already_ended", "Game already ended")
+	GameNotInProgress = define(3005, DomainGame, CategoryConflict, "error.game.game_not_in_progress", "Game not in progress")
+	NotYourTurn       = define(3006, DomainGame, CategoryConflict, "error.game.not_your_turn", "Not your turn")
+	UnknownAction     = define(3007, DomainGame, CategoryInvalidArgument, "error.game.unknown_action", "Unknown action type")
+	ActionFailed      = define(3008, DomainGame, CategoryInvalidArgument, "error.game.action_failed", "Action failed")
+	NotEnoughPlayers  = define(3009, DomainGame, CategoryConflict, "error.game.not_enough_players", "Async game needs at least two players in the room")
+	GameStateChanged  = define(3010, DomainGame, CategoryConflict, "error.game.state_changed", "Game state changed, please refresh")
+	QuickChatDisabled = define(3011, DomainGame, CategoryUnavailable, "error.game.quick_chat_disabled", "Quick chat is disabled")
+	QuickChatUnknown  = define(3012, DomainGame, CategoryInvalidArgument, "error.game.quick_chat_unknown", "Unknown quick chat message")
+	QuickChatTooFast  = define(3013, DomainGame, CategoryRateLimited, "error.game.quick_chat_too_fast", "Sending quick chat too fast")
+	QuickChatMuted    = define(3014, DomainGame, CategoryRateLimited, "error.game.quick_chat_muted", "Quick chat is temporarily muted for spamming")
+	InvalidRoomRules  = define(3015, DomainGame, CategoryInvalidArgument, "error.game.invalid_room_rules", "Invalid room rules")
+	UnknownGameType   = define(3016, DomainGame, CategoryNotFound, "error.game.unknown_game_type", "Unknown game type")
+	InvalidActionData = define(3017, DomainGame, CategoryInvalidArgument, "error.game.invalid_action_data", "Invalid action data")
+	GameStartFailed   = define(3018, DomainGame, CategoryInternal, "error.game.start_failed", "Failed to start game, please try again")
+	GameMigrating     = define(3019, DomainGame, CategoryUnavailable, "error.game.migrating", "Game is moving to another server, please retry")
+	StateDesync       = define(3020, DomainGame, CategoryConflict, "error.game.state_desync", "Game state out of sync, please resync")
+	DesyncNotFound    = define(3021, DomainGame, CategoryNotFound, "error.game.desync_report_not_found", "Desync report not found or already submitted")
+)
+
+// 邮件
+var (
+	MailIDRequired          = define(4001, DomainMail, CategoryInvalidArgument, "error.mail.mail_id_required", "Mail id cannot be empty")
+	MailNotFound            = define(4002, DomainMail, CategoryNotFound, "error.mail.mail_not_found", "Mail not found")
+	MailAccessDenied        = define(4003, DomainMail, CategoryPermissionDenied, "error.mail.access_denied", "No permission to access this mail")
+	MailExpired             = define(4004, DomainMail, CategoryConflict, "error.mail.mail_expired", "Mail has expired")
+	MailNoRewards           = define(4005, DomainMail, CategoryConflict, "error.mail.no_rewards", "This mail has no rewards")
+	RewardsAlreadyClaimed   = define(4006, DomainMail, CategoryConflict, "error.mail.rewards_already_claimed", "Rewards already claimed")
+	MailUpdateFailed        = define(4007, DomainMail, CategoryInternal, "error.mail.update_failed", "Failed to update mail status")
+	MailHasUnclaimedRewards = define(4008, DomainMail, CategoryConflict, "error.mail.unclaimed_rewards", "Mail has unclaimed rewards and cannot be deleted")
+	DeleteMailFailed        = define(4009, DomainMail, CategoryInternal, "error.mail.delete_failed", "Failed to delete mail")
+	RecipientRequired       = define(4010, DomainMail, CategoryInvalidArgument, "error.mail.recipient_required", "Recipient id cannot be empty")
+	CannotMailSelf          = define(4011, DomainMail, CategoryInvalidArgument, "error.mail.cannot_mail_self", "Cannot send mail to yourself")
+	MailTitleRequired       = define(4012, DomainMail, CategoryInvalidArgument, "error.mail.title_required", "Mail title cannot be empty")
+	MailContentRequired     = define(4013, DomainMail, CategoryInvalidArgument, "error.mail.content_required", "Mail content cannot be empty")
+	SendMailFailed          = define(4014, DomainMail, CategoryInternal, "error.mail.send_failed", "Failed to send mail")
+	CampaignInvalid         = define(4015, DomainMail, CategoryInvalidArgument, "error.mail.campaign_invalid", "Invalid mail campaign")
+	CampaignNotFound        = define(4016, DomainMail, CategoryNotFound, "error.mail.campaign_not_found", "Mail campaign not found")
+	CampaignStateConflict   = define(4017, DomainMail, CategoryConflict, "error.mail.campaign_state_conflict", "Mail campaign cannot be changed in its current state")
+)
+
+// GM
+var (
+	CommandRequired             = define(5001, DomainGM, CategoryInvalidArgument, "error.gm.command_required", "Command cannot be empty")
+	CommandFailed               = define(5002, DomainGM, CategoryInternal, "error.gm.command_failed", "Command execution failed")
+	TargetUserRequired          = define(5003, DomainGM, CategoryInvalidArgument, "error.gm.target_user_required", "Target user id cannot be empty")
+	CannotTargetSelf            = define(5004, DomainGM, CategoryInvalidArgument, "error.gm.cannot_target_self", "Cannot perform this operation on yourself")
+	UserStatusCheckFailed       = define(5005, DomainGM, CategoryUnavailable, "error.gm.user_status_check_failed", "Failed to check user status")
+	UserAlreadyBanned           = define(5006, DomainGM, CategoryConflict, "error.gm.user_already_banned", "User is already banned")
+	BanUserFailed               = define(5007, DomainGM, CategoryInternal, "error.gm.ban_failed", "Failed to ban user")
+	UserNotBanned               = define(5008, DomainGM, CategoryConflict, "error.gm.user_not_banned", "User is not banned")
+	UnbanUserFailed             = define(5009, DomainGM, CategoryInternal, "error.gm.unban_failed", "Failed to unban user")
+	AnnouncementTitleRequired   = define(5010, DomainGM, CategoryInvalidArgument, "error.gm.announcement_title_required", "Announcement title cannot be empty")
+	AnnouncementContentRequired = define(5011, DomainGM, CategoryInvalidArgument, "error.gm.announcement_content_required", "Announcement content cannot be empty")
+	ExportUserDataFailed        = define(5012, DomainGM, CategoryInternal, "error.gm.export_user_data_failed", "Failed to export user data")
+	DeleteUserDataFailed        = define(5013, DomainGM, CategoryInternal, "error.gm.delete_user_data_failed", "Failed to delete user data")
+	BatchIDRequired             = define(5014, DomainGM, CategoryInvalidArgument, "error.gm.batch_id_required", "Batch id cannot be empty")
+	RollbackGrantFailed         = define(5015, DomainGM, CategoryInternal, "error.gm.rollback_grant_failed", "Failed to roll back grant")
+	ConfigRolloutFailed         = define(5016, DomainGM, CategoryInternal, "error.gm.config_rollout_failed", "Failed to roll out config")
+	ConfigRolloutAborted        = define(5017, DomainGM, CategoryConflict, "error.gm.config_rollout_aborted", "Config rollout aborted because validation failed")
+	AnnouncementInvalid         = define(5018, DomainGM, CategoryInvalidArgument, "error.gm.announcement_invalid", "Invalid announcement")
+	AnnouncementNotFound        = define(5019, DomainGM, CategoryNotFound, "error.gm.announcement_not_found", "Announcement not found")
+	SurveyInvalid               = define(5020, DomainGM, CategoryInvalidArgument, "error.gm.survey_invalid", "Invalid survey")
+	SurveyExportFailed          = define(5021, DomainGM, CategoryInternal, "error.gm.survey_export_failed", "Failed to export survey responses")
+	TicketNotFound              = define(5022, DomainGM, CategoryNotFound, "error.gm.ticket_not_found", "Support ticket not found")
+	TicketStateConflict         = define(5023, DomainGM, CategoryConflict, "error.gm.ticket_state_conflict", "Support ticket state does not allow this operation")
+	TicketRequestInvalid        = define(5024, DomainGM, CategoryInvalidArgument, "error.gm.ticket_request_invalid", "Invalid support ticket request")
+	VIPRequestInvalid           = define(5025, DomainGM, CategoryInvalidArgument, "error.gm.vip_request_invalid", "Invalid VIP tier request")
+	RedeemBatchInvalid          = define(5026, DomainGM, CategoryInvalidArgument, "error.gm.redeem_batch_invalid", "Invalid redeem code batch")
+	RedeemBatchNotFound         = define(5027, DomainGM, CategoryNotFound, "error.gm.redeem_batch_not_found", "Redeem code batch not found")
+	NewsItemInvalid             = define(5028, DomainGM, CategoryInvalidArgument, "error.gm.news_item_invalid", "Invalid news item")
+	NewsItemNotFound            = define(5029, DomainGM, CategoryNotFound, "error.gm.news_item_not_found", "News item not found")
+)
+
+// 聊天
+var (
+	ChatContentInvalid  = define(6001, DomainChat, CategoryInvalidArgument, "error.chat.content_invalid", "Message is empty or too long")
+	ChatChannelInvalid  = define(6002, DomainChat, CategoryInvalidArgument, "error.chat.channel_invalid", "Invalid chat channel")
+	ChatNotAllowed      = define(6003, DomainChat, CategoryPermissionDenied, "error.chat.not_allowed", "The recipient does not accept your messages")
+	SendMessageFailed   = define(6004, DomainChat, CategoryInternal, "error.chat.send_failed", "Failed to send message")
+	ChatHistoryFailed   = define(6005, DomainChat, CategoryUnavailable, "error.chat.history_failed", "Failed to get chat history")
+	ChatRateLimited     = define(6006, DomainChat, CategoryRateLimited, "error.chat.rate_limited", "Slow mode is on, please wait before sending another message")
+	ChatLevelTooLow     = define(6007, DomainChat, CategoryPermissionDenied, "error.chat.level_too_low", "Your level is too low to speak in this channel")
+	ChatLinkBlocked     = define(6008, DomainChat, CategoryInvalidArgument, "error.chat.link_blocked", "Links are not allowed in this channel")
+	ChatMessageNotFound = define(6009, DomainChat, CategoryNotFound, "error.chat.message_not_found", "Chat message not found")
+	ChatNotMessageOwner = define(6010, DomainChat, CategoryPermissionDenied, "error.chat.not_message_owner", "You can only change your own messages")
+	ChatMessageRecalled = define(6011, DomainChat, CategoryConflict, "error.chat.message_recalled", "The message has been recalled")
+	ChatEditExpired     = define(6012, DomainChat, CategoryConflict, "error.chat.edit_expired", "The message can no longer be edited")
+	ChatRecallExpired   = define(6013, DomainChat, CategoryConflict, "error.chat.recall_expired", "The message can no longer be recalled")
+	ChatReactionInvalid = define(6014, DomainChat, CategoryInvalidArgument, "error.chat.reaction_invalid", "This reaction is not available")
+	ChatProfanity       = define(6015, DomainChat, CategoryInvalidArgument, "error.chat.profanity", "Message contains blocked words")
+)
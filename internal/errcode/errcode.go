@@ -0,0 +1,182 @@
+package errcode
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Domain 错误码所属业务域
+type Domain string
+
+const (
+	DomainCommon Domain = "common"
+	DomainLobby  Domain = "lobby"
+	DomainGame   Domain = "game"
+	DomainMail   Domain = "mail"
+	DomainGM     Domain = "gm"
+	DomainChat   Domain = "chat"
+)
+
+// Category 面向客户端的错误分类，客户端据此决定提示方式和是否重试
+type Category string
+
+const (
+	CategoryOK               Category = "ok"
+	CategoryInvalidArgument  Category = "invalid_argument"  // 请求参数错误，修改后重试
+	CategoryUnauthenticated  Category = "unauthenticated"   // 未登录或凭证失效，需重新登录
+	CategoryPermissionDenied Category = "permission_denied" // 无权限
+	CategoryNotFound         Category = "not_found"         // 资源不存在
+	CategoryConflict         Category = "conflict"          // 资源状态不允许该操作
+	CategoryRateLimited      Category = "rate_limited"      // 请求过于频繁，稍后重试
+	CategoryUnavailable      Category = "unavailable"       // 依赖暂不可用，可重试
+	CategoryInternal         Category = "internal"          // 服务端内部错误
+)
+
+// Retryable 该分类的错误是否可以原样稍后重试
+func (c Category) Retryable() bool {
+	return c == CategoryRateLimited || c == CategoryUnavailable
+}
+
+// Code 错误码定义
+type Code struct {
+	Value     int32
+	Domain    Domain
+	Category  Category
+	MessageID string // i18n消息ID
+	Message   string // 默认消息，未配置本地化时使用
+}
+
+// Error 实现error接口
+func (c *Code) Error() string {
+	return fmt.Sprintf("%s(%d): %s", c.Domain, c.Value, c.Message)
+}
+
+// Wrap 附带底层错误，底层错误只用于日志，不返回给客户端
+func (c *Code) Wrap(cause error) *Error {
+	return &Error{Code: c, Cause: cause}
+}
+
+// WithDetail 附带返回给客户端的补充说明
+func (c *Code) WithDetail(detail string) *Error {
+	return &Error{Code: c, Detail: detail}
+}
+
+// WithParams 附带消息参数，用于填充本地化消息模板，并原样返回给客户端自行格式化
+func (c *Code) WithParams(params map[string]string) *Error {
+	return &Error{Code: c, Params: params}
+}
+
+// Error 带错误码的错误
+type Error struct {
+	Code   *Code
+	Detail string
+	Params map[string]string // 消息参数，模板中按{{.name}}引用
+	Cause  error
+}
+
+// WithParams 附带消息参数，返回新的错误
+func (e *Error) WithParams(params map[string]string) *Error {
+	copied := *e
+	copied.Params = params
+	return &copied
+}
+
+// Error 实现error接口
+func (e *Error) Error() string {
+	msg := e.Code.Error()
+	if e.Detail != "" {
+		msg = fmt.Sprintf("%s: %s", msg, e.Detail)
+	}
+	if e.Cause != nil {
+		msg = fmt.Sprintf("%s: %v", msg, e.Cause)
+	}
+	return msg
+}
+
+// Unwrap 返回底层错误
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+var catalog = make(map[int32]*Code)
+
+// define 登记错误码，重复的值在启动时直接panic
+func define(value int32, domain Domain, category Category, messageID, message string) *Code {
+	if _, exists := catalog[value]; exists {
+		panic(fmt.Sprintf("duplicate error code: %d", value))
+	}
+
+	code := &Code{
+		Value:     value,
+		Domain:    domain,
+		Category:  category,
+		MessageID: messageID,
+		Message:   message,
+	}
+	catalog[value] = code
+	return code
+}
+
+// Lookup 根据错误码值查找定义
+func Lookup(value int32) (*Code, bool) {
+	code, exists := catalog[value]
+	return code, exists
+}
+
+// All 获取所有错误码定义，按值排序
+func All() []*Code {
+	codes := make([]*Code, 0, len(catalog))
+	for _, code := range catalog {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		return codes[i].Value < codes[j].Value
+	})
+	return codes
+}
+
+// From 提取错误对应的错误码，nil为成功，未登记的错误归为内部错误
+func From(err error) *Code {
+	if err == nil {
+		return OK
+	}
+
+	var coded *Error
+	if errors.As(err, &coded) {
+		return coded.Code
+	}
+
+	var code *Code
+	if errors.As(err, &code) {
+		return code
+	}
+
+	return Internal
+}
+
+// CategoryOf 获取错误码值对应的分类
+func CategoryOf(value int32) Category {
+	if code, exists := Lookup(value); exists {
+		return code.Category
+	}
+	return CategoryInternal
+}
+
+// detailOf 提取返回给客户端的补充说明
+func detailOf(err error) string {
+	var coded *Error
+	if errors.As(err, &coded) {
+		return coded.Detail
+	}
+	return ""
+}
+
+// paramsOf 提取消息参数
+func paramsOf(err error) map[string]string {
+	var coded *Error
+	if errors.As(err, &coded) {
+		return coded.Params
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package errcode
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// Localizer 消息本地化，i18n.I18nManager实现了该接口
+type Localizer interface {
+	Translate(langCode, messageID string, templateData map[string]interface{}) string
+}
+
+// Response 根据错误构造BaseResponse
+func Response(header *proto.MessageHeader, err error) *proto.BaseResponse {
+	return defaultBuilder.Error(context.Background(), header, err)
+}
+
+// LocalizedResponse 根据错误构造BaseResponse，消息按客户端语言本地化
+func LocalizedResponse(header *proto.MessageHeader, err error, localizer Localizer, langCode string) *proto.BaseResponse {
+	return NewBuilder(localizer).errorResponse(context.Background(), header, err, langCode)
+}
+
+// CommonResponse 根据错误构造CommonResponse
+func CommonResponse(err error) *proto.CommonResponse {
+	return defaultBuilder.CommonError(context.Background(), err)
+}
+
+// message 生成返回给客户端的消息，翻译缺失时使用默认消息，消息参数用于填充翻译的模板
+func message(code *Code, detail string, params map[string]string, localizer Localizer, langCode string) string {
+	msg := code.Message
+	if localizer != nil {
+		var data map[string]interface{}
+		if len(params) > 0 {
+			data = make(map[string]interface{}, len(params))
+			for name, value := range params {
+				data[name] = value
+			}
+		}
+		if translated := localizer.Translate(langCode, code.MessageID, data); translated != code.MessageID {
+			msg = translated
+		}
+	}
+
+	if detail != "" {
+		msg = fmt.Sprintf("%s: %s", msg, detail)
+	}
+	return msg
+}
+
+// errorInfo 结构化的错误信息，客户端可按消息ID和参数重新本地化，不必解析msg
+func errorInfo(code *Code, err error) *proto.ErrorInfo {
+	return &proto.ErrorInfo{
+		MessageId: code.MessageID,
+		Params:    paramsOf(err),
+		Retryable: code.Category.Retryable(),
+		Detail:    detailOf(err),
+	}
+}
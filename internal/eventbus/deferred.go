@@ -0,0 +1,156 @@
+package eventbus
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrNotReady 消息中间件尚未连接
+var ErrNotReady = errors.New("event bus not ready")
+
+// subscription 连接前登记的订阅
+type subscription struct {
+	topic   string
+	group   string
+	handler Handler
+}
+
+// DeferredBus 延迟连接的事件总线，用于消息中间件启动时不可用的情况
+//
+// 连接前发布返回ErrNotReady，订阅先登记下来，Attach后依次在真正的总线上订阅
+type DeferredBus struct {
+	bus           Bus
+	subscriptions []subscription
+	intercept     Interceptor
+	closed        bool
+	mutex         sync.RWMutex
+}
+
+// Interceptor 订阅时包装消息处理器，如故障注入
+type Interceptor func(topic, group string, handler Handler) Handler
+
+// NewDeferredBus 创建延迟连接的事件总线
+func NewDeferredBus() *DeferredBus {
+	return &DeferredBus{}
+}
+
+// Attach 连接成功后设置真正的总线并补上之前登记的订阅，订阅失败时关闭传入的总线，可以重新连接后再次Attach
+func (db *DeferredBus) Attach(bus Bus) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if db.closed {
+		bus.Close()
+		return errors.New("event bus closed")
+	}
+
+	for _, sub := range db.subscriptions {
+		if err := bus.Subscribe(sub.topic, sub.group, sub.handler); err != nil {
+			bus.Close()
+			return err
+		}
+	}
+	db.subscriptions = nil
+	db.bus = bus
+	return nil
+}
+
+// Intercept 设置订阅时包装消息处理器的拦截器，只对之后的订阅生效，需在订阅前设置
+func (db *DeferredBus) Intercept(interceptor Interceptor) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	db.intercept = interceptor
+}
+
+// Ready 是否已连接
+func (db *DeferredBus) Ready() bool {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	return db.bus != nil
+}
+
+// Attached 获取已连接的总线，未连接时返回nil
+func (db *DeferredBus) Attached() Bus {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	return db.bus
+}
+
+// current 获取已连接的总线
+func (db *DeferredBus) current() (Bus, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if db.bus == nil {
+		return nil, ErrNotReady
+	}
+	return db.bus, nil
+}
+
+// Publish 发布消息
+func (db *DeferredBus) Publish(topic string, data []byte) error {
+	bus, err := db.current()
+	if err != nil {
+		return err
+	}
+	return bus.Publish(topic, data)
+}
+
+// PublishWithKey 按键发布消息，真正的总线不支持按键时退化为Publish
+func (db *DeferredBus) PublishWithKey(topic string, key, data []byte) error {
+	bus, err := db.current()
+	if err != nil {
+		return err
+	}
+	if keyed, ok := bus.(KeyedBus); ok {
+		return keyed.PublishWithKey(topic, key, data)
+	}
+	return bus.Publish(topic, data)
+}
+
+// Subscribe 订阅主题，未连接时先登记
+func (db *DeferredBus) Subscribe(topic, group string, handler Handler) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if db.intercept != nil {
+		handler = db.intercept(topic, group, handler)
+	}
+	if db.bus != nil {
+		return db.bus.Subscribe(topic, group, handler)
+	}
+	db.subscriptions = append(db.subscriptions, subscription{topic: topic, group: group, handler: handler})
+	return nil
+}
+
+// Unsubscribe 取消订阅
+func (db *DeferredBus) Unsubscribe(topic, group string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if db.bus != nil {
+		return db.bus.Unsubscribe(topic, group)
+	}
+	for i, sub := range db.subscriptions {
+		if sub.topic == topic && sub.group == group {
+			db.subscriptions = append(db.subscriptions[:i], db.subscriptions[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// Close 关闭总线
+func (db *DeferredBus) Close() error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	db.closed = true
+	if db.bus != nil {
+		return db.bus.Close()
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package eventbus
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Handler 消息处理器，group为订阅组，同一组内的订阅者分摊消息，不同组各收到一份
+type Handler interface {
+	HandleMessage(topic, group string, data []byte) error
+}
+
+// HandlerFunc 函数形式的消息处理器
+type HandlerFunc func(topic, group string, data []byte) error
+
+// HandleMessage 实现Handler接口
+func (f HandlerFunc) HandleMessage(topic, group string, data []byte) error {
+	return f(topic, group, data)
+}
+
+// Bus 事件总线，屏蔽具体的消息中间件，mq.NSQManager为默认实现
+type Bus interface {
+	Publish(topic string, data []byte) error
+	Subscribe(topic, group string, handler Handler) error
+	Unsubscribe(topic, group string) error
+	Close() error
+}
+
+// KeyedBus 支持按键发布的事件总线，同一键的消息保证顺序，mq.KafkaBroker实现了该接口
+type KeyedBus interface {
+	Bus
+	PublishWithKey(topic string, key, data []byte) error
+}
+
+// Topic 类型化主题，约束发布和订阅的消息类型一致
+type Topic[T any] struct {
+	Name string
+}
+
+// NewTopic 创建类型化主题
+func NewTopic[T any](name string) Topic[T] {
+	return Topic[T]{Name: name}
+}
+
+// Publish 以JSON编码发布类型化消息
+func Publish[T any](bus Bus, topic Topic[T], event *T) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s event: %v", topic.Name, err)
+	}
+	return bus.Publish(topic.Name, data)
+}
+
+// PublishKeyed 按键发布类型化消息，总线不支持按键或key为空时退化为Publish
+func PublishKeyed[T any](bus Bus, topic Topic[T], key string, event *T) error {
+	keyed, ok := bus.(KeyedBus)
+	if !ok || key == "" {
+		return Publish(bus, topic, event)
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s event: %v", topic.Name, err)
+	}
+	return keyed.PublishWithKey(topic.Name, []byte(key), data)
+}
+
+// Subscribe 订阅类型化主题，消息解码后交给handler
+func Subscribe[T any](bus Bus, topic Topic[T], group string, handler func(event *T) error) error {
+	return bus.Subscribe(topic.Name, group, HandlerFunc(func(name, group string, data []byte) error {
+		event := new(T)
+		if err := json.Unmarshal(data, event); err != nil {
+			return fmt.Errorf("failed to unmarshal %s event: %v", name, err)
+		}
+		return handler(event)
+	}))
+}
@@ -0,0 +1,88 @@
+package eventbus
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// MemoryBus 进程内事件总线，用于测试和单进程部署
+// 发布时同步调用每个订阅组的处理器，处理错误只记录日志
+type MemoryBus struct {
+	topics map[string]map[string]Handler // 主题 -> 订阅组 -> 处理器
+	closed bool
+	mutex  sync.RWMutex
+}
+
+// NewMemoryBus 创建进程内事件总线
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{
+		topics: make(map[string]map[string]Handler),
+	}
+}
+
+// Publish 发布消息
+func (mb *MemoryBus) Publish(topic string, data []byte) error {
+	mb.mutex.RLock()
+	if mb.closed {
+		mb.mutex.RUnlock()
+		return fmt.Errorf("event bus closed")
+	}
+	groups := make(map[string]Handler, len(mb.topics[topic]))
+	for group, handler := range mb.topics[topic] {
+		groups[group] = handler
+	}
+	mb.mutex.RUnlock()
+
+	// 释放锁后再调用，处理器中可以继续发布或订阅
+	for group, handler := range groups {
+		if err := handler.HandleMessage(topic, group, data); err != nil {
+			logger.Warn(fmt.Sprintf("Failed to handle message from %s/%s: %v", topic, group, err))
+		}
+	}
+	return nil
+}
+
+// Subscribe 订阅主题
+func (mb *MemoryBus) Subscribe(topic, group string, handler Handler) error {
+	mb.mutex.Lock()
+	defer mb.mutex.Unlock()
+
+	if mb.closed {
+		return fmt.Errorf("event bus closed")
+	}
+
+	groups, exists := mb.topics[topic]
+	if !exists {
+		groups = make(map[string]Handler)
+		mb.topics[topic] = groups
+	}
+	if _, exists := groups[group]; exists {
+		return fmt.Errorf("already subscribed to %s/%s", topic, group)
+	}
+	groups[group] = handler
+	return nil
+}
+
+// Unsubscribe 取消订阅
+func (mb *MemoryBus) Unsubscribe(topic, group string) error {
+	mb.mutex.Lock()
+	defer mb.mutex.Unlock()
+
+	if _, exists := mb.topics[topic][group]; !exists {
+		return fmt.Errorf("not subscribed to %s/%s", topic, group)
+	}
+	delete(mb.topics[topic], group)
+	return nil
+}
+
+// Close 关闭事件总线
+func (mb *MemoryBus) Close() error {
+	mb.mutex.Lock()
+	defer mb.mutex.Unlock()
+
+	mb.closed = true
+	mb.topics = make(map[string]map[string]Handler)
+	return nil
+}
@@ -0,0 +1,295 @@
+package exchange
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/monitoring"
+)
+
+// 兑换失败原因
+var (
+	ErrNoRule       = errors.New("exchange not available")
+	ErrTooSmall     = errors.New("exchange amount too small")
+	ErrDailyLimit   = errors.New("daily exchange limit reached")
+	ErrInsufficient = database.ErrInsufficientBalance
+)
+
+// 统计数据保留时间
+const (
+	counterRetention = 48 * time.Hour
+	metricsRetention = 30 * 24 * time.Hour
+)
+
+// Config 货币兑换配置
+type Config struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule 兑换规则，每cost个from货币兑换yield个to货币，只按整份兑换
+// 税按比例从兑换所得中扣除且不发放给任何人，作为货币回收
+type Rule struct {
+	From  string  `yaml:"from"`
+	To    string  `yaml:"to"`
+	Cost  int64   `yaml:"cost"`
+	Yield int64   `yaml:"yield"`
+	Tax   float64 `yaml:"tax"`   // 税率，0到1之间，扣除数量向上取整
+	Min   int64   `yaml:"min"`   // 单次最少兑换的from数量
+	Daily int64   `yaml:"daily"` // 每个玩家每天最多兑换的from数量，0为不限制
+	Alert int64   `yaml:"alert"` // 单个玩家当天兑换的from数量超过时告警，0为不告警
+	Burst int64   `yaml:"burst"` // 全服一小时内兑换的from数量超过时告警，0为不告警
+}
+
+// Validate 检查兑换配置
+func (c *Config) Validate() error {
+	pairs := make(map[string]bool, len(c.Rules))
+	for _, rule := range c.Rules {
+		for _, currency := range []string{rule.From, rule.To} {
+			if currency != database.CurrencyGold && currency != database.CurrencyDiamond {
+				return fmt.Errorf("exchange currency must be gold or diamond: %s", currency)
+			}
+		}
+		if rule.From == rule.To {
+			return fmt.Errorf("exchange from and to are both %s", rule.From)
+		}
+		pair := rule.From + ":" + rule.To
+		if pairs[pair] {
+			return fmt.Errorf("duplicate exchange rule: %s", pair)
+		}
+		pairs[pair] = true
+
+		if rule.Cost <= 0 || rule.Yield <= 0 {
+			return fmt.Errorf("exchange %s cost and yield must be positive", pair)
+		}
+		if rule.Tax < 0 || rule.Tax >= 1 {
+			return fmt.Errorf("exchange %s tax must be in [0, 1): %v", pair, rule.Tax)
+		}
+		if rule.Min < 0 || rule.Daily < 0 || rule.Alert < 0 || rule.Burst < 0 {
+			return fmt.Errorf("exchange %s limits must not be negative", pair)
+		}
+	}
+	return nil
+}
+
+// Ledger 账本，database.LedgerRepository实现了该接口
+type Ledger interface {
+	Apply(entry *database.LedgerEntry) (bool, error)
+	Spend(entry *database.LedgerEntry) (bool, error)
+	Reverse(entry *database.LedgerEntry, batchID, reason string) (bool, error)
+}
+
+// Result 兑换结果
+type Result struct {
+	TxID     string `json:"tx_id"`
+	Spent    int64  `json:"spent"`    // 扣除的from数量
+	Received int64  `json:"received"` // 到账的to数量
+	Tax      int64  `json:"tax"`      // 回收的to数量
+	Used     int64  `json:"used"`     // 当天已兑换的from数量
+	Limit    int64  `json:"limit"`    // 每天可兑换的from数量，0为不限制
+}
+
+// LimitResolver 按玩家调整规则的每日上限，如VIP权益
+type LimitResolver func(userID uint64, limit int64) int64
+
+// Service 货币兑换服务，扣除和发放都通过账本记录
+type Service struct {
+	rules    map[string]Rule
+	limit    LimitResolver
+	ledger   Ledger
+	redis    *database.RedisManager
+	channels []monitoring.AlertChannel
+	nodeID   string
+	nodeType string
+}
+
+// NewService 创建货币兑换服务
+func NewService(config *Config, ledger Ledger, redis *database.RedisManager, nodeID, nodeType string) *Service {
+	rules := make(map[string]Rule, len(config.Rules))
+	for _, rule := range config.Rules {
+		rules[rule.From+":"+rule.To] = rule
+	}
+	return &Service{
+		rules:    rules,
+		limit:    func(_ uint64, limit int64) int64 { return limit },
+		ledger:   ledger,
+		redis:    redis,
+		channels: []monitoring.AlertChannel{&monitoring.LogAlertChannel{}},
+		nodeID:   nodeID,
+		nodeType: nodeType,
+	}
+}
+
+// SetLimitResolver 设置按玩家调整每日上限的方法
+func (s *Service) SetLimitResolver(resolver LimitResolver) {
+	s.limit = resolver
+}
+
+// AddAlertChannel 添加异常兑换的告警通道，默认只写日志
+func (s *Service) AddAlertChannel(channel monitoring.AlertChannel) {
+	s.channels = append(s.channels, channel)
+}
+
+// Exchange 把amount个from货币按规则兑换为to货币，不足一份的部分不扣除
+// 先占用当天额度再扣除和发放，任何一步失败时退回额度，发放失败时冲正扣除
+func (s *Service) Exchange(userID uint64, from, to string, amount int64) (*Result, error) {
+	rule, exists := s.rules[from+":"+to]
+	if !exists {
+		return nil, ErrNoRule
+	}
+
+	lots := amount / rule.Cost
+	spent := lots * rule.Cost
+	gross := lots * rule.Yield
+	tax := int64(math.Ceil(float64(gross) * rule.Tax))
+	if lots <= 0 || spent < rule.Min || gross-tax <= 0 {
+		return nil, ErrTooSmall
+	}
+
+	daily := s.limit(userID, rule.Daily)
+	now := time.Now()
+	pair := from + ":" + to
+	dailyKey := fmt.Sprintf("exchange:daily:%s:%d", now.Format("20060102"), userID)
+	used, err := s.redis.HIncrBy(dailyKey, pair, spent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update daily exchange counter: %v", err)
+	}
+	s.redis.Expire(dailyKey, counterRetention)
+	if daily > 0 && used > daily {
+		s.refund(dailyKey, pair, spent)
+		return nil, ErrDailyLimit
+	}
+
+	txID := fmt.Sprintf("exchange:%d:%d", userID, now.UnixNano())
+	debit := &database.LedgerEntry{
+		TxID:     txID + ":debit",
+		BatchID:  txID,
+		UserID:   userID,
+		Currency: from,
+		Amount:   -spent,
+		Reason:   "exchange to " + to,
+	}
+	if _, err := s.ledger.Spend(debit); err != nil {
+		s.refund(dailyKey, pair, spent)
+		return nil, err
+	}
+
+	if _, err := s.ledger.Apply(&database.LedgerEntry{
+		TxID:     txID + ":credit",
+		BatchID:  txID,
+		UserID:   userID,
+		Currency: to,
+		Amount:   gross - tax,
+		Reason:   "exchange from " + from,
+	}); err != nil {
+		if _, reverseErr := s.ledger.Reverse(debit, txID, "exchange failed"); reverseErr != nil {
+			logger.Error(fmt.Sprintf("Failed to reverse exchange debit %s: %v", debit.TxID, reverseErr))
+		}
+		s.refund(dailyKey, pair, spent)
+		return nil, fmt.Errorf("failed to credit exchange: %v", err)
+	}
+
+	s.record(now, pair, spent, gross-tax, tax)
+	s.checkVolume(now, userID, pair, &rule, used, spent)
+
+	return &Result{
+		TxID:     txID,
+		Spent:    spent,
+		Received: gross - tax,
+		Tax:      tax,
+		Used:     used,
+		Limit:    daily,
+	}, nil
+}
+
+// refund 退回占用的当天额度
+func (s *Service) refund(key, pair string, spent int64) {
+	if _, err := s.redis.HIncrBy(key, pair, -spent); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to refund exchange counter %s %s: %v", key, pair, err))
+	}
+}
+
+// record 记录每日兑换统计，字段为 from:to:指标
+func (s *Service) record(now time.Time, pair string, spent, received, tax int64) {
+	key := metricsKey(now)
+	for field, value := range map[string]int64{
+		pair + ":count":    1,
+		pair + ":spent":    spent,
+		pair + ":received": received,
+		pair + ":tax":      tax,
+	} {
+		if _, err := s.redis.HIncrBy(key, field, value); err != nil {
+			logger.Warn(fmt.Sprintf("Failed to record exchange metrics: %v", err))
+			return
+		}
+	}
+	s.redis.Expire(key, metricsRetention)
+}
+
+// checkVolume 玩家当天或全服一小时内的兑换量首次超过阈值时告警
+func (s *Service) checkVolume(now time.Time, userID uint64, pair string, rule *Rule, used, spent int64) {
+	if rule.Alert > 0 && used > rule.Alert && used-spent <= rule.Alert {
+		s.alert(now, "exchange_user_volume", fmt.Sprintf("user %d exchanged %d %s today (%s), threshold %d",
+			userID, used, rule.From, pair, rule.Alert))
+	}
+
+	if rule.Burst <= 0 {
+		return
+	}
+	key := fmt.Sprintf("exchange:hourly:%s", now.Format("2006010215"))
+	total, err := s.redis.HIncrBy(key, pair, spent)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Failed to update hourly exchange volume: %v", err))
+		return
+	}
+	s.redis.Expire(key, 2*time.Hour)
+	if total > rule.Burst && total-spent <= rule.Burst {
+		s.alert(now, "exchange_burst_volume", fmt.Sprintf("%d %s exchanged in the last hour (%s), threshold %d",
+			total, rule.From, pair, rule.Burst))
+	}
+}
+
+// alert 发送告警
+func (s *Service) alert(now time.Time, rule, message string) {
+	alert := monitoring.Alert{
+		ID:        fmt.Sprintf("%s_%d", rule, now.UnixNano()),
+		Rule:      rule,
+		Level:     monitoring.AlertLevelWarning,
+		Message:   message,
+		Timestamp: now,
+		NodeID:    s.nodeID,
+		NodeType:  s.nodeType,
+	}
+	for _, channel := range s.channels {
+		if err := channel.Send(alert); err != nil {
+			logger.Error(fmt.Sprintf("Failed to send alert: %v", err))
+		}
+	}
+}
+
+// GetMetrics 获取某天全集群的兑换统计，键为 from:to:指标
+func GetMetrics(redis *database.RedisManager, day time.Time) (map[string]int64, error) {
+	values, err := redis.HGetAll(metricsKey(day))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exchange metrics: %v", err)
+	}
+
+	metrics := make(map[string]int64, len(values))
+	for field, value := range values {
+		count, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		metrics[field] = count
+	}
+	return metrics, nil
+}
+
+// metricsKey 每日兑换统计的键
+func metricsKey(day time.Time) string {
+	return "exchange:metrics:" + day.Format("20060102")
+}
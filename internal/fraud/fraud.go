@@ -0,0 +1,259 @@
+package fraud
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+)
+
+// 规则类型
+const (
+	RuleVelocity = "velocity" // 窗口内流水笔数或入账总额过高
+	RuleBalance  = "balance"  // 余额为负或超过合理上限
+	RuleTransfer = "transfer" // 窗口内与过多账户往来或往来金额过大
+)
+
+// 规则命中后的处理方式
+const (
+	ModeFreeze = "freeze" // 冻结账号等待GM审核
+	ModeFlag   = "flag"   // 只记录待审核案件
+	ModeOff    = "off"    // 线上不执行，只用于回测
+)
+
+// Config 经济风控配置，规则随配置热更新生效
+type Config struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule 风控规则，Count和Amount为0时不检查对应条件
+type Rule struct {
+	Name     string        `yaml:"name"`
+	Type     string        `yaml:"type"`
+	Mode     string        `yaml:"mode"`     // freeze, flag, off，未配置时为flag
+	Currency string        `yaml:"currency"` // 为空时检查所有货币
+	Window   time.Duration `yaml:"window"`   // velocity和transfer的统计窗口
+	Count    int64         `yaml:"count"`    // velocity: 流水笔数上限; transfer: 往来账户数上限
+	Amount   int64         `yaml:"amount"`   // velocity: 入账总额上限; transfer: 往来总额上限; balance: 余额上限
+}
+
+// Validate 检查风控配置
+func (c *Config) Validate() error {
+	names := make(map[string]bool, len(c.Rules))
+	for _, rule := range c.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("fraud rule name is required")
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("duplicate fraud rule: %s", rule.Name)
+		}
+		names[rule.Name] = true
+
+		switch rule.Mode {
+		case "", ModeFreeze, ModeFlag, ModeOff:
+		default:
+			return fmt.Errorf("fraud rule %s has unknown mode: %s", rule.Name, rule.Mode)
+		}
+
+		switch rule.Type {
+		case RuleVelocity, RuleTransfer:
+			if rule.Window <= 0 {
+				return fmt.Errorf("fraud rule %s requires a window", rule.Name)
+			}
+			if rule.Count <= 0 && rule.Amount <= 0 {
+				return fmt.Errorf("fraud rule %s requires count or amount", rule.Name)
+			}
+		case RuleBalance:
+			if rule.Currency == "" {
+				return fmt.Errorf("fraud rule %s requires a currency", rule.Name)
+			}
+		default:
+			return fmt.Errorf("fraud rule %s has unknown type: %s", rule.Name, rule.Type)
+		}
+	}
+	return nil
+}
+
+// MaxWindow 规则中最长的统计窗口，扫描时需要回看这么长时间的流水
+func (c *Config) MaxWindow() time.Duration {
+	var window time.Duration
+	for _, rule := range c.Rules {
+		if rule.Window > window {
+			window = rule.Window
+		}
+	}
+	return window
+}
+
+// Hit 规则命中
+type Hit struct {
+	Rule   string    `json:"rule"`
+	Mode   string    `json:"mode"`
+	UserID uint64    `json:"user_id"`
+	At     time.Time `json:"at"` // 触发命中的流水时间
+	Detail string    `json:"detail"`
+}
+
+// UserSource 用户余额来源，database.UserRepository实现了该接口
+type UserSource interface {
+	GetByUserID(userID uint64) (*database.User, error)
+}
+
+// Evaluate 按规则检查按时间排序的流水，只返回由after之后的流水触发的命中，每个玩家每条规则最多命中一次
+// live为true时跳过off模式的规则；余额规则使用玩家的当前余额
+func Evaluate(rules []Rule, entries []*database.LedgerEntry, users UserSource, after time.Time, live bool) ([]*Hit, error) {
+	var hits []*Hit
+	for i := range rules {
+		rule := &rules[i]
+		mode := rule.Mode
+		if mode == "" {
+			mode = ModeFlag
+		}
+		if live && mode == ModeOff {
+			continue
+		}
+
+		byUser := groupByUser(rule, entries)
+		userIDs := make([]uint64, 0, len(byUser))
+		for userID := range byUser {
+			userIDs = append(userIDs, userID)
+		}
+		sort.Slice(userIDs, func(a, b int) bool { return userIDs[a] < userIDs[b] })
+
+		for _, userID := range userIDs {
+			var at time.Time
+			var detail string
+			switch rule.Type {
+			case RuleVelocity:
+				at, detail = checkVelocity(rule, byUser[userID], after)
+			case RuleTransfer:
+				at, detail = checkTransfer(rule, byUser[userID], after)
+			case RuleBalance:
+				var err error
+				at, detail, err = checkBalance(rule, byUser[userID], users, after)
+				if err != nil {
+					return hits, err
+				}
+			}
+			if detail != "" {
+				hits = append(hits, &Hit{Rule: rule.Name, Mode: mode, UserID: userID, At: at, Detail: detail})
+			}
+		}
+	}
+	return hits, nil
+}
+
+// groupByUser 按玩家分组规则关注的流水，冲正流水不参与检查
+func groupByUser(rule *Rule, entries []*database.LedgerEntry) map[uint64][]*database.LedgerEntry {
+	byUser := make(map[uint64][]*database.LedgerEntry)
+	for _, entry := range entries {
+		if entry.ReversalOf != "" {
+			continue
+		}
+		if rule.Currency != "" && entry.Currency != rule.Currency {
+			continue
+		}
+		if rule.Type == RuleTransfer && entry.Counterparty == 0 {
+			continue
+		}
+		byUser[entry.UserID] = append(byUser[entry.UserID], entry)
+	}
+	return byUser
+}
+
+// checkVelocity 滑动窗口内的流水笔数或入账总额超过上限
+func checkVelocity(rule *Rule, entries []*database.LedgerEntry, after time.Time) (time.Time, string) {
+	start := 0
+	gained := int64(0)
+	for end, entry := range entries {
+		if entry.Amount > 0 {
+			gained += entry.Amount
+		}
+		for entry.CreatedAt.Sub(entries[start].CreatedAt) > rule.Window {
+			if entries[start].Amount > 0 {
+				gained -= entries[start].Amount
+			}
+			start++
+		}
+		if !entry.CreatedAt.After(after) {
+			continue
+		}
+
+		count := int64(end - start + 1)
+		if rule.Count > 0 && count > rule.Count {
+			return entry.CreatedAt, fmt.Sprintf("%d transactions within %v", count, rule.Window)
+		}
+		if rule.Amount > 0 && gained > rule.Amount {
+			return entry.CreatedAt, fmt.Sprintf("gained %d within %v", gained, rule.Window)
+		}
+	}
+	return time.Time{}, ""
+}
+
+// checkTransfer 滑动窗口内往来账户数或往来总额超过上限，转入和转出都计算
+func checkTransfer(rule *Rule, entries []*database.LedgerEntry, after time.Time) (time.Time, string) {
+	start := 0
+	total := int64(0)
+	counterparties := make(map[uint64]int)
+	for _, entry := range entries {
+		total += abs(entry.Amount)
+		counterparties[entry.Counterparty]++
+		for entry.CreatedAt.Sub(entries[start].CreatedAt) > rule.Window {
+			total -= abs(entries[start].Amount)
+			if counterparties[entries[start].Counterparty]--; counterparties[entries[start].Counterparty] == 0 {
+				delete(counterparties, entries[start].Counterparty)
+			}
+			start++
+		}
+		if !entry.CreatedAt.After(after) {
+			continue
+		}
+
+		if rule.Count > 0 && int64(len(counterparties)) > rule.Count {
+			return entry.CreatedAt, fmt.Sprintf("transfers with %d accounts within %v", len(counterparties), rule.Window)
+		}
+		if rule.Amount > 0 && total > rule.Amount {
+			return entry.CreatedAt, fmt.Sprintf("transferred %d within %v", total, rule.Window)
+		}
+	}
+	return time.Time{}, ""
+}
+
+// checkBalance 有新流水的玩家余额为负或超过上限
+func checkBalance(rule *Rule, entries []*database.LedgerEntry, users UserSource, after time.Time) (time.Time, string, error) {
+	last := entries[len(entries)-1].CreatedAt
+	if !last.After(after) {
+		return time.Time{}, "", nil
+	}
+
+	user, err := users.GetByUserID(entries[0].UserID)
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("failed to get user %d: %v", entries[0].UserID, err)
+	}
+
+	var balance int64
+	switch rule.Currency {
+	case database.CurrencyGold:
+		balance = user.Gold
+	case database.CurrencyDiamond:
+		balance = user.Diamond
+	case database.CurrencyExperience:
+		balance = user.Experience
+	}
+	if balance < 0 {
+		return last, fmt.Sprintf("negative %s balance %d", rule.Currency, balance), nil
+	}
+	if rule.Amount > 0 && balance > rule.Amount {
+		return last, fmt.Sprintf("%s balance %d exceeds %d", rule.Currency, balance, rule.Amount), nil
+	}
+	return time.Time{}, "", nil
+}
+
+// abs 绝对值
+func abs(value int64) int64 {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
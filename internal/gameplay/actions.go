@@ -0,0 +1,255 @@
+package gameplay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// 操作载荷字段的类型
+const (
+	FieldTypeString = "string"
+	FieldTypeInt    = "int"
+	FieldTypeNumber = "number"
+	FieldTypeBool   = "bool"
+	FieldTypeObject = "object"
+	FieldTypeArray  = "array"
+)
+
+// ErrInvalidAction 操作类型未声明或载荷不符合玩法模块的操作表
+var ErrInvalidAction = errors.New("invalid action")
+
+// ActionField 操作载荷中的一个字段
+type ActionField struct {
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`
+	Required bool     `json:"required,omitempty"`
+	Min      int      `json:"min,omitempty"`     // int为取值下限(默认不接受负数)，string和array为长度下限
+	Max      int      `json:"max,omitempty"`     // int为取值上限，string和array为长度上限，0为不限制
+	Options  []string `json:"options,omitempty"` // string类型的可选值，为空时不限制
+}
+
+// ActionSchema 一种操作的载荷格式。设置Message时载荷为protobuf编码，按该消息类型解码，Fields不生效；
+// 否则载荷为JSON对象，未声明的字段视为错误
+type ActionSchema struct {
+	Fields  []ActionField        `json:"fields,omitempty"`
+	Message func() proto.Message `json:"-"`
+}
+
+// ActionSchemas 玩法模块的操作表，键为操作类型
+type ActionSchemas map[string]ActionSchema
+
+// FieldError 载荷中一个字段的错误
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// ActionValidationError 操作载荷的校验错误，列出所有不合法的字段，可用errors.Is判断ErrInvalidAction
+type ActionValidationError struct {
+	Action string       `json:"action"`
+	Fields []FieldError `json:"fields,omitempty"`
+	Reason string       `json:"reason,omitempty"` // 与具体字段无关的错误，如操作类型未声明、载荷无法解码
+}
+
+// Error 实现error接口
+func (e *ActionValidationError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("invalid action %s: %s", e.Action, e.Reason)
+	}
+	parts := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		parts = append(parts, field.Field+": "+field.Reason)
+	}
+	return fmt.Sprintf("invalid action %s: %s", e.Action, strings.Join(parts, "; "))
+}
+
+// Is 与ErrInvalidAction匹配
+func (e *ActionValidationError) Is(target error) bool {
+	return target == ErrInvalidAction
+}
+
+// Decode 按操作表校验并解码操作的载荷。data可以是JSON或protobuf编码的字节，也可以是已解析的JSON对象，
+// JSON载荷返回map[string]interface{}，数值统一转换为int或float64；protobuf载荷返回解码后的消息
+func (s ActionSchemas) Decode(actionType string, data interface{}) (interface{}, error) {
+	schema, exists := s[actionType]
+	if !exists {
+		return nil, &ActionValidationError{Action: actionType, Reason: "undeclared action type"}
+	}
+
+	if schema.Message != nil {
+		raw, ok := data.([]byte)
+		if !ok && data != nil {
+			return nil, &ActionValidationError{Action: actionType, Reason: fmt.Sprintf("expected protobuf payload, got %T", data)}
+		}
+		message := schema.Message()
+		if err := proto.Unmarshal(raw, message); err != nil {
+			return nil, &ActionValidationError{Action: actionType, Reason: fmt.Sprintf("malformed protobuf payload: %v", err)}
+		}
+		return message, nil
+	}
+
+	var payload map[string]interface{}
+	switch v := data.(type) {
+	case nil:
+	case map[string]interface{}:
+		payload = v
+	case []byte:
+		if len(v) > 0 {
+			if err := json.Unmarshal(v, &payload); err != nil {
+				return nil, &ActionValidationError{Action: actionType, Reason: fmt.Sprintf("malformed JSON payload: %v", err)}
+			}
+		}
+	default:
+		return nil, &ActionValidationError{Action: actionType, Reason: fmt.Sprintf("expected JSON object, got %T", data)}
+	}
+	return schema.apply(actionType, payload)
+}
+
+// apply 校验JSON载荷的各字段
+func (s ActionSchema) apply(actionType string, payload map[string]interface{}) (map[string]interface{}, error) {
+	declared := make(map[string]bool, len(s.Fields))
+	for _, field := range s.Fields {
+		declared[field.Name] = true
+	}
+
+	var errs []FieldError
+	for name := range payload {
+		if !declared[name] {
+			errs = append(errs, FieldError{Field: name, Reason: "undeclared field"})
+		}
+	}
+
+	applied := make(map[string]interface{}, len(s.Fields))
+	for _, field := range s.Fields {
+		value, exists := payload[field.Name]
+		if !exists || value == nil {
+			if field.Required {
+				errs = append(errs, FieldError{Field: field.Name, Reason: "required"})
+			}
+			continue
+		}
+		normalized, err := field.normalize(value)
+		if err != nil {
+			errs = append(errs, FieldError{Field: field.Name, Reason: err.Error()})
+			continue
+		}
+		applied[field.Name] = normalized
+	}
+
+	if len(errs) > 0 {
+		sort.Slice(errs, func(i, j int) bool {
+			return errs[i].Field < errs[j].Field
+		})
+		return nil, &ActionValidationError{Action: actionType, Fields: errs}
+	}
+	return applied, nil
+}
+
+// normalize 按字段类型校验取值
+func (f ActionField) normalize(value interface{}) (interface{}, error) {
+	switch f.Type {
+	case FieldTypeString:
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string, got %T", value)
+		}
+		if err := f.checkLength(len([]rune(str))); err != nil {
+			return nil, err
+		}
+		if len(f.Options) > 0 {
+			for _, option := range f.Options {
+				if option == str {
+					return str, nil
+				}
+			}
+			return nil, fmt.Errorf("%q is not one of %v", str, f.Options)
+		}
+		return str, nil
+	case FieldTypeInt:
+		// 复用房间规则的整数校验
+		return RoomRule{Type: RuleTypeInt, Min: f.Min, Max: f.intMax()}.normalize(value)
+	case FieldTypeNumber:
+		switch v := value.(type) {
+		case float64:
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return nil, fmt.Errorf("expected finite number, got %v", v)
+			}
+			return v, nil
+		case int:
+			return float64(v), nil
+		}
+		return nil, fmt.Errorf("expected number, got %T", value)
+	case FieldTypeBool:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected bool, got %T", value)
+		}
+		return b, nil
+	case FieldTypeObject:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected object, got %T", value)
+		}
+		return obj, nil
+	case FieldTypeArray:
+		arr, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected array, got %T", value)
+		}
+		if err := f.checkLength(len(arr)); err != nil {
+			return nil, err
+		}
+		return arr, nil
+	}
+	return nil, fmt.Errorf("unknown field type %s", f.Type)
+}
+
+// intMax int字段的取值上限，未设置时不限制
+func (f ActionField) intMax() int {
+	if f.Max == 0 {
+		return math.MaxInt32
+	}
+	return f.Max
+}
+
+// checkLength 检查字符串或数组的长度
+func (f ActionField) checkLength(n int) error {
+	if n < f.Min {
+		return fmt.Errorf("length %d is less than %d", n, f.Min)
+	}
+	if f.Max > 0 && n > f.Max {
+		return fmt.Errorf("length %d exceeds %d", n, f.Max)
+	}
+	return nil
+}
+
+// Validate 检查操作表本身
+func (s ActionSchemas) Validate() error {
+	for actionType, schema := range s {
+		if actionType == "" {
+			return fmt.Errorf("empty action type")
+		}
+		names := make(map[string]bool, len(schema.Fields))
+		for _, field := range schema.Fields {
+			if field.Name == "" || names[field.Name] {
+				return fmt.Errorf("action %s: invalid or duplicate field name %q", actionType, field.Name)
+			}
+			names[field.Name] = true
+			switch field.Type {
+			case FieldTypeString, FieldTypeInt, FieldTypeNumber, FieldTypeBool, FieldTypeObject, FieldTypeArray:
+			default:
+				return fmt.Errorf("action %s: field %s has unknown type %s", actionType, field.Name, field.Type)
+			}
+			if field.Max != 0 && field.Max < field.Min {
+				return fmt.Errorf("action %s: field %s has max %d less than min %d", actionType, field.Name, field.Max, field.Min)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package gameplay
+
+import (
+	"testing"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+func init() {
+	logger.InitGlobalLogger(&logger.LogConfig{
+		Level:  "error",
+		Format: "console",
+		Output: "stderr",
+	})
+}
+
+// newBenchRoom 创建进行中的卡牌房间
+func newBenchRoom(b *testing.B, players int) (*GameplayManager, *GameRoom) {
+	manager := NewGameplayManager(&GameplayConfig{}, nil)
+	if err := manager.RegisterModule(NewCardGameModule()); err != nil {
+		b.Fatal(err)
+	}
+
+	room, err := manager.CreateRoom("card_game", &RoomConfig{
+		MaxPlayers: players,
+		MinPlayers: 2,
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for i := 1; i <= players; i++ {
+		player := &Player{UserID: uint64(i), Nickname: "bench"}
+		if err := manager.JoinRoom(room.ID, player); err != nil {
+			b.Fatal(err)
+		}
+		player.Status = PlayerStatusPlaying
+	}
+	room.SetState(GameStateRunning)
+
+	return manager, room
+}
+
+// BenchmarkProcessAction 单房间出牌操作的校验、处理和事件记录
+func BenchmarkProcessAction(b *testing.B) {
+	manager, room := newBenchRoom(b, 4)
+	action := &GameAction{
+		Type:      "play_card",
+		PlayerID:  1,
+		Data:      map[string]interface{}{"card_id": 12},
+		Timestamp: time.Now(),
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := manager.ProcessAction(room.ID, action); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkProcessActionParallel 多个房间并发处理操作，衡量管理器锁竞争
+func BenchmarkProcessActionParallel(b *testing.B) {
+	manager := NewGameplayManager(&GameplayConfig{}, nil)
+	if err := manager.RegisterModule(NewCardGameModule()); err != nil {
+		b.Fatal(err)
+	}
+
+	roomIDs := make([]uint64, 0, 64)
+	seen := make(map[uint64]bool, cap(roomIDs))
+	for len(roomIDs) < cap(roomIDs) {
+		room, err := manager.CreateRoom("card_game", &RoomConfig{MaxPlayers: 2, MinPlayers: 2})
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, exists := manager.GetRoom(room.ID); !exists || seen[room.ID] {
+			continue // 房间ID基于时间生成，跳过冲突
+		}
+		player := &Player{UserID: 1, Nickname: "bench"}
+		if err := manager.JoinRoom(room.ID, player); err != nil {
+			b.Fatal(err)
+		}
+		player.Status = PlayerStatusPlaying
+		room.SetState(GameStateRunning)
+		seen[room.ID] = true
+		roomIDs = append(roomIDs, room.ID)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		action := &GameAction{
+			Type:      "play_card",
+			PlayerID:  1,
+			Data:      map[string]interface{}{"card_id": 12},
+			Timestamp: time.Now(),
+		}
+		i := 0
+		for pb.Next() {
+			if _, err := manager.ProcessAction(roomIDs[i%len(roomIDs)], action); err != nil {
+				b.Error(err)
+				return
+			}
+			i++
+		}
+	})
+}
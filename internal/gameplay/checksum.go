@@ -0,0 +1,153 @@
+package gameplay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// ErrDesync 客户端提交的状态校验和与服务端不一致，具体的校验和与服务端状态见DesyncError
+var ErrDesync = errors.New("game state desync")
+
+// DesyncConfig 状态不一致报告的配置
+type DesyncConfig struct {
+	Retention time.Duration `yaml:"retention"` // 报告的保留时间，未配置时为14天
+	MaxState  int           `yaml:"max_state"` // 客户端上报的本地状态的最大字节数，未配置时为64KB
+}
+
+// StateHasher 玩法模块可选实现，把玩家视角的玩法状态按确定的顺序写出用于计算校验和，
+// 客户端按同样的规则计算本地状态的校验和。未实现时写出兴趣管理策略过滤后的玩法数据的JSON，
+// 对象的字段按定义顺序、map的键按字母顺序排列
+type StateHasher interface {
+	WriteState(room *GameRoom, viewerID uint64, w io.Writer) error
+}
+
+// DesyncError 状态不一致的详情
+type DesyncError struct {
+	RoomID   uint64
+	PlayerID uint64
+	Expected string      // 服务端计算的校验和
+	Actual   string      // 客户端提交的校验和
+	State    interface{} // 服务端计算校验和时玩家视角的玩法数据
+}
+
+// Error 实现error接口
+func (e *DesyncError) Error() string {
+	return fmt.Sprintf("%v: room %d player %d expected %s, got %s", ErrDesync, e.RoomID, e.PlayerID, e.Expected, e.Actual)
+}
+
+// Unwrap 可用errors.Is判断ErrDesync
+func (e *DesyncError) Unwrap() error {
+	return ErrDesync
+}
+
+// Checksum 计算玩家视角的房间状态校验和，返回校验和和参与计算的玩法数据。
+// 校验和为FNV-1a 64位的16位小写十六进制，输入为房间状态码（十进制）加换行，后跟玩法模块写出的状态
+func (gm *GameplayManager) Checksum(roomID, viewerID uint64) (string, interface{}, error) {
+	gm.mutex.RLock()
+	room, exists := gm.rooms[roomID]
+	if !exists {
+		gm.mutex.RUnlock()
+		return "", nil, fmt.Errorf("room %d not found", roomID)
+	}
+	module, exists := gm.modules[room.GameType]
+	gm.mutex.RUnlock()
+	if !exists {
+		return "", nil, fmt.Errorf("game module %s not found", room.GameType)
+	}
+
+	return checksum(module, room, viewerID)
+}
+
+// viewChecksum 请求驱动的房间中玩家视角的状态校验和，固定帧率房间的状态每帧变化，不计算校验和
+func (gm *GameplayManager) viewChecksum(room *GameRoom, viewerID uint64) string {
+	gm.mutex.RLock()
+	module, exists := gm.modules[room.GameType]
+	_, ticking := gm.roomActors[room.ID]
+	gm.mutex.RUnlock()
+	if !exists || ticking {
+		return ""
+	}
+
+	sum, _, err := checksum(module, room, viewerID)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Failed to compute checksum of room %d: %v", room.ID, err))
+		return ""
+	}
+	return sum
+}
+
+// checksum 计算玩家视角的房间状态校验和
+func checksum(module GameplayModule, room *GameRoom, viewerID uint64) (string, interface{}, error) {
+	policy := module.GetInterestPolicy()
+	if policy == nil {
+		policy = PublicInterestPolicy{}
+	}
+
+	room.mutex.RLock()
+	defer room.mutex.RUnlock()
+
+	state := policy.FilterState(room, viewerID)
+	h := fnv.New64a()
+	io.WriteString(h, strconv.Itoa(int(room.State))+"\n")
+	if hasher, ok := module.(StateHasher); ok {
+		if err := hasher.WriteState(room, viewerID, h); err != nil {
+			return "", nil, fmt.Errorf("failed to write state of room %d: %v", room.ID, err)
+		}
+	} else if err := json.NewEncoder(h).Encode(state); err != nil {
+		return "", nil, fmt.Errorf("failed to encode state of room %d: %v", room.ID, err)
+	}
+	return fmt.Sprintf("%016x", h.Sum64()), state, nil
+}
+
+// verifyChecksum 校验客户端提交的状态校验和，不一致时返回DesyncError
+func verifyChecksum(module GameplayModule, room *GameRoom, playerID uint64, actual string) error {
+	expected, state, err := checksum(module, room, playerID)
+	if err != nil {
+		return err
+	}
+	if expected == actual {
+		return nil
+	}
+	return &DesyncError{RoomID: room.ID, PlayerID: playerID, Expected: expected, Actual: actual, State: state}
+}
+
+// WriteState 按行写出卡牌游戏玩家视角的状态：回合玩家、轮数、牌堆剩余数量、自己的手牌ID、
+// 各玩家按ID排序的手牌数量和桌面的牌ID，列表以逗号分隔
+func (cgm *CardGameModule) WriteState(room *GameRoom, viewerID uint64, w io.Writer) error {
+	view, ok := CardInterestPolicy{}.FilterState(room, viewerID).(*CardGameView)
+	if !ok {
+		return fmt.Errorf("unexpected card game data %T", room.GameData)
+	}
+
+	playerIDs := make([]uint64, 0, len(view.HandCounts))
+	for playerID := range view.HandCounts {
+		playerIDs = append(playerIDs, playerID)
+	}
+	sort.Slice(playerIDs, func(i, j int) bool { return playerIDs[i] < playerIDs[j] })
+	counts := make([]string, len(playerIDs))
+	for i, playerID := range playerIDs {
+		counts[i] = fmt.Sprintf("%d:%d", playerID, view.HandCounts[playerID])
+	}
+
+	_, err := fmt.Fprintf(w, "%d\n%d\n%d\n%s\n%s\n%s\n",
+		view.Turn, view.Round, view.DeckCount, cardIDs(view.Hand), strings.Join(counts, ","), cardIDs(view.Board))
+	return err
+}
+
+// cardIDs 以逗号分隔的牌ID
+func cardIDs(cards []Card) string {
+	ids := make([]string, len(cards))
+	for i, card := range cards {
+		ids[i] = strconv.Itoa(card.ID)
+	}
+	return strings.Join(ids, ",")
+}
@@ -2,6 +2,7 @@ package gameplay
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -10,11 +11,29 @@ import (
 	"github.com/phuhao00/lufy/internal/logger"
 )
 
+// ErrInvalidRules 房间自定义规则不符合玩法模块的规则表
+var ErrInvalidRules = errors.New("invalid room rules")
+
 // GameplayManager 玩法管理器
 type GameplayManager struct {
-	modules map[string]GameplayModule
-	rooms   map[uint64]*GameRoom
-	mutex   sync.RWMutex
+	modules       map[string]GameplayModule
+	rooms         map[uint64]*GameRoom
+	roomActors    map[uint64]*RoomActor
+	historyConfig EventHistoryConfig
+	tickConfig    TickConfig
+	replayStore   ReplayStore
+	flushChan     chan struct{}
+	statsHooks    []func(stats []RoomHistoryStats)
+	tickHooks     []func(stats *TickStats)
+	viewFilters   []EventFilter
+	mutex         sync.RWMutex
+}
+
+// GameplayConfig 玩法配置
+type GameplayConfig struct {
+	EventHistory EventHistoryConfig `yaml:"event_history"`
+	Tick         TickConfig         `yaml:"tick"`
+	Desync       DesyncConfig       `yaml:"desync"`
 }
 
 // GameplayModule 玩法模块接口
@@ -23,9 +42,15 @@ type GameplayModule interface {
 	GetVersion() string
 	Initialize() error
 	CreateRoom(config *RoomConfig) (*GameRoom, error)
+	// GetActionSchemas 声明每种操作的载荷格式，未声明的操作类型和不符合格式的载荷在ValidateAction之前被拒绝
+	GetActionSchemas() ActionSchemas
 	ValidateAction(room *GameRoom, player *Player, action *GameAction) error
 	ProcessAction(room *GameRoom, player *Player, action *GameAction) (*GameResult, error)
 	GetRoomState(room *GameRoom) interface{}
+	GetInterestPolicy() InterestPolicy
+	// OnTick 固定帧率房间的每帧更新，inputs为本帧缓冲的玩家输入，已按确定顺序排列
+	// 相同的房间状态、帧号和输入必须产生相同的结果，请求驱动的玩法返回nil即可
+	OnTick(room *GameRoom, tick uint64, inputs []*GameAction) (*GameResult, error)
 	Cleanup() error
 }
 
@@ -39,7 +64,7 @@ type GameRoom struct {
 	StartTime time.Time
 	EndTime   time.Time
 	GameData  interface{}
-	Events    []GameEvent
+	Events    *EventHistory
 	mutex     sync.RWMutex
 }
 
@@ -61,6 +86,7 @@ type GameAction struct {
 	PlayerID  uint64
 	Data      interface{}
 	Timestamp time.Time
+	StateHash string // 客户端提交操作时本地状态的校验和，为空时不校验，固定帧率房间不校验
 }
 
 // GameResult 游戏结果
@@ -70,6 +96,7 @@ type GameResult struct {
 	Data      interface{}
 	Events    []GameEvent
 	NextState GameState
+	Checksum  string // 请求驱动的房间处理操作后操作者视角的状态校验和
 }
 
 // GameEvent 游戏事件
@@ -82,12 +109,14 @@ type GameEvent struct {
 
 // RoomConfig 房间配置
 type RoomConfig struct {
-	MaxPlayers   int
-	MinPlayers   int
-	RoomPassword string
-	AutoStart    bool
-	TimeLimit    time.Duration
-	CustomConfig map[string]interface{}
+	MaxPlayers    int
+	MinPlayers    int
+	RoomPassword  string
+	AutoStart     bool
+	TimeLimit     time.Duration
+	TickRate      int                    // 固定帧率(Hz)，0表示使用玩法类型的默认配置
+	EphemeralChat bool                   // 房间聊天只转发不保存
+	CustomConfig  map[string]interface{} // 自定义规则，创建房间时按玩法模块的规则表校验
 }
 
 // GameState 游戏状态
@@ -112,11 +141,32 @@ const (
 	PlayerStatusDisconnected
 )
 
-// NewGameplayManager 创建玩法管理器
-func NewGameplayManager() *GameplayManager {
+// NewGameplayManager 创建玩法管理器，replayStore为nil时不落盘事件
+func NewGameplayManager(config *GameplayConfig, replayStore ReplayStore) *GameplayManager {
+	historyConfig := config.EventHistory
+	if historyConfig.Capacity <= 0 {
+		historyConfig.Capacity = 256
+	}
+	if historyConfig.FlushInterval <= 0 {
+		historyConfig.FlushInterval = 10 * time.Second
+	}
+
+	tickConfig := config.Tick
+	if tickConfig.MaxRate <= 0 {
+		tickConfig.MaxRate = 30
+	}
+	if tickConfig.InputBufferSize <= 0 {
+		tickConfig.InputBufferSize = 256
+	}
+
 	return &GameplayManager{
-		modules: make(map[string]GameplayModule),
-		rooms:   make(map[uint64]*GameRoom),
+		modules:       make(map[string]GameplayModule),
+		rooms:         make(map[uint64]*GameRoom),
+		roomActors:    make(map[uint64]*RoomActor),
+		historyConfig: historyConfig,
+		tickConfig:    tickConfig,
+		replayStore:   replayStore,
+		flushChan:     make(chan struct{}, 1),
 	}
 }
 
@@ -130,6 +180,16 @@ func (gm *GameplayManager) RegisterModule(module GameplayModule) error {
 		return fmt.Errorf("module %s already registered", name)
 	}
 
+	if provider, ok := module.(RuleProvider); ok {
+		if err := provider.GetRoomRules().Validate(); err != nil {
+			return fmt.Errorf("invalid room rules of module %s: %v", name, err)
+		}
+	}
+
+	if err := module.GetActionSchemas().Validate(); err != nil {
+		return fmt.Errorf("invalid action schemas of module %s: %v", name, err)
+	}
+
 	if err := module.Initialize(); err != nil {
 		return fmt.Errorf("failed to initialize module %s: %v", name, err)
 	}
@@ -151,17 +211,45 @@ func (gm *GameplayManager) CreateRoom(gameType string, config *RoomConfig) (*Gam
 		return nil, fmt.Errorf("game type %s not found", gameType)
 	}
 
+	// 自定义规则按模块的规则表校验并补齐默认值后再交给模块
+	if err := applyRoomRules(module, config); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRules, err)
+	}
+
 	room, err := module.CreateRoom(config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create room: %v", err)
 	}
 
+	room.Events = NewEventHistory(gm.historyConfig.Capacity)
 	gm.rooms[room.ID] = room
 	logger.Info(fmt.Sprintf("Created game room: %d (type: %s)", room.ID, gameType))
 
+	// 实时玩法由房间Actor按固定帧率驱动
+	if rate := gm.tickRate(gameType, config); rate > 0 {
+		roomActor := NewRoomActor(gm, module, room, rate, gm.tickConfig.InputBufferSize)
+		if err := roomActor.Start(roomActor); err != nil {
+			delete(gm.rooms, room.ID)
+			return nil, fmt.Errorf("failed to start room actor: %v", err)
+		}
+		gm.roomActors[room.ID] = roomActor
+	}
+
 	return room, nil
 }
 
+// tickRate 获取房间帧率，超过上限时取上限
+func (gm *GameplayManager) tickRate(gameType string, config *RoomConfig) int {
+	rate := gm.tickConfig.GameTypes[gameType]
+	if config != nil && config.TickRate > 0 {
+		rate = config.TickRate
+	}
+	if rate > gm.tickConfig.MaxRate {
+		rate = gm.tickConfig.MaxRate
+	}
+	return rate
+}
+
 // JoinRoom 加入游戏房间
 func (gm *GameplayManager) JoinRoom(roomID uint64, player *Player) error {
 	gm.mutex.Lock()
@@ -198,6 +286,7 @@ func (gm *GameplayManager) ProcessAction(roomID uint64, action *GameAction) (*Ga
 	}
 
 	module, moduleExists := gm.modules[room.GameType]
+	roomActor := gm.roomActors[roomID]
 	gm.mutex.RUnlock()
 
 	if !moduleExists {
@@ -209,26 +298,144 @@ func (gm *GameplayManager) ProcessAction(roomID uint64, action *GameAction) (*Ga
 		return nil, fmt.Errorf("player %d not in room", action.PlayerID)
 	}
 
+	// 载荷按模块的操作表校验并解码，模块只处理符合格式的载荷，调用方的操作对象保持不变
+	data, err := module.GetActionSchemas().Decode(action.Type, action.Data)
+	if err != nil {
+		return nil, err
+	}
+	decoded := *action
+	decoded.Data = data
+	action = &decoded
+
+	// 请求驱动的房间先校验客户端的状态，不一致时拒绝操作，客户端重新同步后再提交
+	if roomActor == nil && action.StateHash != "" {
+		if err := verifyChecksum(module, room, player.UserID, action.StateHash); err != nil {
+			return nil, err
+		}
+	}
+
 	// 验证操作
 	if err := module.ValidateAction(room, player, action); err != nil {
 		return nil, fmt.Errorf("invalid action: %v", err)
 	}
 
+	// 固定帧率房间的输入缓冲到下一帧处理
+	if roomActor != nil {
+		if err := roomActor.Submit(action); err != nil {
+			return nil, fmt.Errorf("failed to queue action: %v", err)
+		}
+		return &GameResult{
+			Success:   true,
+			Message:   "Action queued",
+			NextState: room.GetState(),
+		}, nil
+	}
+
 	// 处理操作
 	result, err := module.ProcessAction(room, player, action)
 	if err != nil {
 		return nil, fmt.Errorf("failed to process action: %v", err)
 	}
 
+	gm.applyResult(room, result)
+
+	// 每次操作后计算状态校验和，客户端用于核对本地状态
+	if sum, _, err := checksum(module, room, player.UserID); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to compute checksum of room %d: %v", roomID, err))
+	} else {
+		result.Checksum = sum
+	}
+
+	return result, nil
+}
+
+// PublishEvent 直接向房间推送事件，不经过玩法模块处理，用于聊天、表情等不影响玩法状态的消息。
+// 事件和玩法事件一样记录在房间事件历史中，玩家通过房间状态的增量同步收到
+func (gm *GameplayManager) PublishEvent(roomID uint64, event GameEvent) error {
+	gm.mutex.RLock()
+	room, exists := gm.rooms[roomID]
+	gm.mutex.RUnlock()
+	if !exists {
+		return fmt.Errorf("room %d not found", roomID)
+	}
+
+	if room.GetState() == GameStateEnded {
+		return fmt.Errorf("room %d has ended", roomID)
+	}
+	if _, ok := room.GetPlayer(event.PlayerID); !ok {
+		return fmt.Errorf("player %d not in room", event.PlayerID)
+	}
+
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	gm.recordEvents(room, []GameEvent{event})
+	return nil
+}
+
+// applyResult 应用操作或帧更新的结果
+func (gm *GameplayManager) applyResult(room *GameRoom, result *GameResult) {
 	// 更新房间状态
-	if result.NextState != room.State {
+	if result.NextState != room.GetState() {
 		room.SetState(result.NextState)
 	}
 
-	// 记录事件
-	room.AddEvents(result.Events)
+	gm.recordEvents(room, result.Events)
+}
 
-	return result, nil
+// recordEvents 记录事件，未落盘事件接近缓冲区容量时提前触发写入
+func (gm *GameplayManager) recordEvents(room *GameRoom, events []GameEvent) {
+	room.AddEvents(events)
+	if gm.replayStore != nil && room.Events.UnflushedCount() >= gm.historyConfig.Capacity*3/4 {
+		select {
+		case gm.flushChan <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// OnTickStats 注册帧统计回调，每帧更新后调用
+func (gm *GameplayManager) OnTickStats(hook func(stats *TickStats)) {
+	gm.mutex.Lock()
+	defer gm.mutex.Unlock()
+
+	gm.tickHooks = append(gm.tickHooks, hook)
+}
+
+// reportTick 上报帧统计
+func (gm *GameplayManager) reportTick(stats *TickStats) {
+	gm.mutex.RLock()
+	hooks := gm.tickHooks
+	gm.mutex.RUnlock()
+
+	if stats.Duration > stats.Interval {
+		logger.Warn(fmt.Sprintf("Room %d tick %d overran: %v > %v", stats.RoomID, stats.Tick, stats.Duration, stats.Interval))
+	}
+	for _, hook := range hooks {
+		hook(stats)
+	}
+}
+
+// removeRoomActor 移除房间Actor
+func (gm *GameplayManager) removeRoomActor(roomID uint64) {
+	gm.mutex.Lock()
+	defer gm.mutex.Unlock()
+
+	delete(gm.roomActors, roomID)
+}
+
+// Shutdown 停止所有房间Actor
+func (gm *GameplayManager) Shutdown() {
+	gm.mutex.Lock()
+	roomActors := gm.roomActors
+	gm.roomActors = make(map[uint64]*RoomActor)
+	gm.mutex.Unlock()
+
+	for _, roomActor := range roomActors {
+		if err := roomActor.Stop(roomActor); err != nil {
+			logger.Error(fmt.Sprintf("Failed to stop room actor %s: %v", roomActor.GetID(), err))
+		}
+	}
 }
 
 // GetRoom 获取游戏房间
@@ -240,6 +447,62 @@ func (gm *GameplayManager) GetRoom(roomID uint64) (*GameRoom, bool) {
 	return room, exists
 }
 
+// OnHistoryStats 注册事件历史统计回调，每次写入回放存储后调用
+func (gm *GameplayManager) OnHistoryStats(hook func(stats []RoomHistoryStats)) {
+	gm.mutex.Lock()
+	defer gm.mutex.Unlock()
+
+	gm.statsHooks = append(gm.statsHooks, hook)
+}
+
+// RunHistoryFlush 定期将房间的未落盘事件写入回放存储，直到ctx取消
+func (gm *GameplayManager) RunHistoryFlush(ctx context.Context) {
+	ticker := time.NewTicker(gm.historyConfig.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			gm.FlushHistory()
+		case <-gm.flushChan:
+			gm.FlushHistory()
+		case <-ctx.Done():
+			gm.FlushHistory()
+			return
+		}
+	}
+}
+
+// FlushHistory 将所有房间的未落盘事件写入回放存储
+func (gm *GameplayManager) FlushHistory() {
+	gm.mutex.RLock()
+	rooms := make([]*GameRoom, 0, len(gm.rooms))
+	for _, room := range gm.rooms {
+		rooms = append(rooms, room)
+	}
+	hooks := gm.statsHooks
+	gm.mutex.RUnlock()
+
+	stats := make([]RoomHistoryStats, 0, len(rooms))
+	for _, room := range rooms {
+		if gm.replayStore != nil {
+			startSeq, events := room.Events.Unflushed()
+			if len(events) > 0 {
+				if err := gm.replayStore.SaveEvents(room.ID, startSeq, events); err != nil {
+					logger.Error(fmt.Sprintf("Failed to flush %d events of room %d: %v", len(events), room.ID, err))
+				} else {
+					room.Events.MarkFlushed(startSeq + uint64(len(events)))
+				}
+			}
+		}
+		stats = append(stats, room.Events.Stats(room.ID))
+	}
+
+	for _, hook := range hooks {
+		hook(stats)
+	}
+}
+
 // AddPlayer 添加玩家到房间
 func (gr *GameRoom) AddPlayer(player *Player) error {
 	gr.mutex.Lock()
@@ -312,10 +575,15 @@ func (gr *GameRoom) SetState(state GameState) {
 
 // AddEvents 添加事件
 func (gr *GameRoom) AddEvents(events []GameEvent) {
-	gr.mutex.Lock()
-	defer gr.mutex.Unlock()
+	gr.Events.Append(events)
+}
 
-	gr.Events = append(gr.Events, events...)
+// GetState 获取房间状态
+func (gr *GameRoom) GetState() GameState {
+	gr.mutex.RLock()
+	defer gr.mutex.RUnlock()
+
+	return gr.State
 }
 
 // GetPlayerCount 获取玩家数量
@@ -367,16 +635,35 @@ func (cgm *CardGameModule) CreateRoom(config *RoomConfig) (*GameRoom, error) {
 		State:    GameStateWaiting,
 		Config:   config,
 		GameData: &CardGameData{
-			Deck:  generateDeck(),
+			Deck:  generateDeck(config.IntRule("decks", 1)),
 			Hands: make(map[uint64][]Card),
 			Board: make([]Card, 0),
 		},
-		Events: make([]GameEvent, 0),
 	}
 
 	return room, nil
 }
 
+// GetRoomRules 卡牌游戏的房间规则
+func (cgm *CardGameModule) GetRoomRules() RuleSchema {
+	return RuleSchema{
+		{Name: "decks", Type: RuleTypeInt, Label: "room_rule.card.decks", Default: 1, Min: 1, Max: 2},
+		{Name: "max_hand", Type: RuleTypeInt, Label: "room_rule.card.max_hand", Default: 10, Min: 5, Max: 20},
+		{Name: "open_draws", Type: RuleTypeBool, Label: "room_rule.card.open_draws", Default: false},
+	}
+}
+
+// GetActionSchemas 卡牌游戏的操作表
+func (cgm *CardGameModule) GetActionSchemas() ActionSchemas {
+	return ActionSchemas{
+		"play_card": {Fields: []ActionField{
+			{Name: "card_id", Type: FieldTypeInt, Required: true, Min: 1},
+			{Name: "target_id", Type: FieldTypeInt},
+		}},
+		"draw_card": {},
+	}
+}
+
 // ValidateAction 验证操作
 func (cgm *CardGameModule) ValidateAction(room *GameRoom, player *Player, action *GameAction) error {
 	switch action.Type {
@@ -406,6 +693,16 @@ func (cgm *CardGameModule) GetRoomState(room *GameRoom) interface{} {
 	return room.GameData
 }
 
+// OnTick 卡牌游戏由请求驱动，不需要帧更新
+func (cgm *CardGameModule) OnTick(room *GameRoom, tick uint64, inputs []*GameAction) (*GameResult, error) {
+	return nil, nil
+}
+
+// GetInterestPolicy 获取兴趣管理策略
+func (cgm *CardGameModule) GetInterestPolicy() InterestPolicy {
+	return CardInterestPolicy{}
+}
+
 // Cleanup 清理模块
 func (cgm *CardGameModule) Cleanup() error {
 	logger.Info("Card game module cleaned up")
@@ -454,6 +751,10 @@ func (cgm *CardGameModule) validateDrawCard(room *GameRoom, player *Player, acti
 		return fmt.Errorf("deck is empty")
 	}
 
+	if maxHand := room.Config.IntRule("max_hand", 10); len(gameData.Hands[player.UserID]) >= maxHand {
+		return fmt.Errorf("hand is full (%d cards)", maxHand)
+	}
+
 	return nil
 }
 
@@ -470,9 +771,10 @@ func (cgm *CardGameModule) processPlayCard(room *GameRoom, player *Player, actio
 	}
 
 	return &GameResult{
-		Success: true,
-		Message: "Card played successfully",
-		Events:  events,
+		Success:   true,
+		Message:   "Card played successfully",
+		Events:    events,
+		NextState: room.State,
 	}, nil
 }
 
@@ -500,34 +802,38 @@ func (cgm *CardGameModule) processDrawCard(room *GameRoom, player *Player, actio
 		}
 
 		return &GameResult{
-			Success: true,
-			Message: "Card drawn successfully",
-			Data:    card,
-			Events:  events,
+			Success:   true,
+			Message:   "Card drawn successfully",
+			Data:      card,
+			Events:    events,
+			NextState: room.State,
 		}, nil
 	}
 
 	return &GameResult{
-		Success: false,
-		Message: "No cards left in deck",
+		Success:   false,
+		Message:   "No cards left in deck",
+		NextState: room.State,
 	}, nil
 }
 
-// generateDeck 生成牌堆
-func generateDeck() []Card {
+// generateDeck 按副数生成牌堆
+func generateDeck(decks int) []Card {
 	suits := []string{"spades", "hearts", "diamonds", "clubs"}
-	deck := make([]Card, 0, 52)
+	deck := make([]Card, 0, 52*decks)
 
 	id := 1
-	for _, suit := range suits {
-		for value := 1; value <= 13; value++ {
-			deck = append(deck, Card{
-				ID:    id,
-				Suit:  suit,
-				Value: value,
-				Name:  fmt.Sprintf("%s_%d", suit, value),
-			})
-			id++
+	for i := 0; i < decks; i++ {
+		for _, suit := range suits {
+			for value := 1; value <= 13; value++ {
+				deck = append(deck, Card{
+					ID:    id,
+					Suit:  suit,
+					Value: value,
+					Name:  fmt.Sprintf("%s_%d", suit, value),
+				})
+				id++
+			}
 		}
 	}
 
@@ -0,0 +1,190 @@
+package gameplay
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// EventHistoryConfig 房间事件历史配置
+type EventHistoryConfig struct {
+	Capacity      int           `yaml:"capacity"`       // 每个房间内存中保留的事件数
+	FlushInterval time.Duration `yaml:"flush_interval"` // 未落盘事件写入回放存储的间隔
+}
+
+// ReplayStore 回放存储接口，按房间追加事件
+type ReplayStore interface {
+	SaveEvents(roomID uint64, startSeq uint64, events []GameEvent) error
+}
+
+// RoomHistoryStats 房间事件历史统计
+type RoomHistoryStats struct {
+	RoomID      uint64
+	Events      int    // 内存中的事件数
+	Capacity    int    // 环形缓冲区容量
+	MemoryBytes int64  // 估算的内存占用
+	Total       uint64 // 累计产生的事件数
+	Flushed     uint64 // 已写入回放存储的事件数
+	Dropped     uint64 // 未落盘即被覆盖的事件数
+}
+
+// eventSlotSize 单个事件槽位的固定内存占用
+const eventSlotSize = int64(unsafe.Sizeof(GameEvent{}))
+
+// EventHistory 房间事件历史，固定容量的环形缓冲区
+// 缓冲区满后覆盖最旧的事件，较早的事件由GameplayManager定期写入回放存储
+type EventHistory struct {
+	events    []GameEvent
+	head      int    // 最旧事件的位置
+	size      int    // 当前事件数
+	total     uint64 // 累计事件数，也是下一个事件的序号
+	flushed   uint64 // 该序号之前的事件已写入回放存储
+	dropped   uint64
+	typeBytes int64 // 缓冲区内事件类型字符串的长度之和
+	mutex     sync.RWMutex
+}
+
+// NewEventHistory 创建房间事件历史
+func NewEventHistory(capacity int) *EventHistory {
+	if capacity <= 0 {
+		capacity = 256
+	}
+	return &EventHistory{
+		events: make([]GameEvent, capacity),
+	}
+}
+
+// Append 追加事件，缓冲区满时覆盖最旧的事件
+func (eh *EventHistory) Append(events []GameEvent) {
+	eh.mutex.Lock()
+	defer eh.mutex.Unlock()
+
+	capacity := len(eh.events)
+	for _, event := range events {
+		if eh.size == capacity {
+			oldest := eh.total - uint64(capacity)
+			if oldest >= eh.flushed {
+				// 最旧的事件还未落盘，覆盖后丢失
+				eh.dropped++
+				eh.flushed = oldest + 1
+			}
+			eh.typeBytes -= int64(len(eh.events[eh.head].Type))
+			eh.events[eh.head] = event
+			eh.head = (eh.head + 1) % capacity
+		} else {
+			eh.events[(eh.head+eh.size)%capacity] = event
+			eh.size++
+		}
+		eh.typeBytes += int64(len(event.Type))
+		eh.total++
+	}
+}
+
+// Recent 获取最近的n个事件，按时间顺序排列，n<=0时返回全部
+func (eh *EventHistory) Recent(n int) []GameEvent {
+	eh.mutex.RLock()
+	defer eh.mutex.RUnlock()
+
+	if n <= 0 || n > eh.size {
+		n = eh.size
+	}
+	return eh.copyRange(eh.size-n, n)
+}
+
+// Since 获取序号seq之后的事件及当前版本，seq之后的事件已被覆盖时返回false，调用方应改用完整快照
+func (eh *EventHistory) Since(seq uint64) ([]GameEvent, uint64, bool) {
+	eh.mutex.RLock()
+	defer eh.mutex.RUnlock()
+
+	if seq > eh.total || seq < eh.total-uint64(eh.size) {
+		return nil, eh.total, false
+	}
+	count := int(eh.total - seq)
+	return eh.copyRange(eh.size-count, count), eh.total, true
+}
+
+// Snapshot 获取内存中的全部事件及当前版本
+func (eh *EventHistory) Snapshot() ([]GameEvent, uint64) {
+	eh.mutex.RLock()
+	defer eh.mutex.RUnlock()
+
+	return eh.copyRange(0, eh.size), eh.total
+}
+
+// Version 获取当前版本，即累计事件数
+func (eh *EventHistory) Version() uint64 {
+	eh.mutex.RLock()
+	defer eh.mutex.RUnlock()
+
+	return eh.total
+}
+
+// Unflushed 获取未写入回放存储的事件及其起始序号
+func (eh *EventHistory) Unflushed() (uint64, []GameEvent) {
+	eh.mutex.RLock()
+	defer eh.mutex.RUnlock()
+
+	count := int(eh.total - eh.flushed)
+	if count == 0 {
+		return eh.flushed, nil
+	}
+	return eh.flushed, eh.copyRange(eh.size-count, count)
+}
+
+// UnflushedCount 获取未写入回放存储的事件数
+func (eh *EventHistory) UnflushedCount() int {
+	eh.mutex.RLock()
+	defer eh.mutex.RUnlock()
+
+	return int(eh.total - eh.flushed)
+}
+
+// MarkFlushed 标记序号seq之前的事件已写入回放存储
+func (eh *EventHistory) MarkFlushed(seq uint64) {
+	eh.mutex.Lock()
+	defer eh.mutex.Unlock()
+
+	if seq > eh.flushed && seq <= eh.total {
+		eh.flushed = seq
+	}
+}
+
+// Len 获取内存中的事件数
+func (eh *EventHistory) Len() int {
+	eh.mutex.RLock()
+	defer eh.mutex.RUnlock()
+
+	return eh.size
+}
+
+// Stats 获取统计信息
+func (eh *EventHistory) Stats(roomID uint64) RoomHistoryStats {
+	eh.mutex.RLock()
+	defer eh.mutex.RUnlock()
+
+	return RoomHistoryStats{
+		RoomID:      roomID,
+		Events:      eh.size,
+		Capacity:    len(eh.events),
+		MemoryBytes: int64(len(eh.events))*eventSlotSize + eh.typeBytes,
+		Total:       eh.total,
+		Flushed:     eh.flushed,
+		Dropped:     eh.dropped,
+	}
+}
+
+// MarshalJSON 序列化为内存中的事件列表
+func (eh *EventHistory) MarshalJSON() ([]byte, error) {
+	return json.Marshal(eh.Recent(0))
+}
+
+// copyRange 按时间顺序复制从第offset个事件开始的count个事件，调用方需持有锁
+func (eh *EventHistory) copyRange(offset, count int) []GameEvent {
+	result := make([]GameEvent, count)
+	capacity := len(eh.events)
+	for i := 0; i < count; i++ {
+		result[i] = eh.events[(eh.head+offset+i)%capacity]
+	}
+	return result
+}
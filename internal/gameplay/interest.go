@@ -0,0 +1,213 @@
+package gameplay
+
+import (
+	"fmt"
+)
+
+// InterestPolicy 兴趣管理策略，决定每个玩家能看到的事件和玩法数据
+// 由玩法模块提供，大房间可以只保留与玩家相关的事件（自己的手牌、附近的实体等）
+type InterestPolicy interface {
+	// FilterEvent 返回viewer可见的事件，可以裁剪事件数据，返回false表示事件对viewer不可见
+	FilterEvent(room *GameRoom, viewerID uint64, event GameEvent) (GameEvent, bool)
+	// FilterState 返回viewer可见的玩法数据
+	FilterState(room *GameRoom, viewerID uint64) interface{}
+}
+
+// EventFilter 在兴趣管理策略之后按玩家过滤事件，返回false表示事件对viewer不可见，
+// 用于玩家屏蔽其他玩家的聊天等与玩法无关的规则
+type EventFilter func(room *GameRoom, viewerID uint64, event GameEvent) bool
+
+// PublicInterestPolicy 所有事件和数据对全部玩家可见
+type PublicInterestPolicy struct{}
+
+// FilterEvent 事件全部可见
+func (PublicInterestPolicy) FilterEvent(room *GameRoom, viewerID uint64, event GameEvent) (GameEvent, bool) {
+	return event, true
+}
+
+// FilterState 返回完整玩法数据
+func (PublicInterestPolicy) FilterState(room *GameRoom, viewerID uint64) interface{} {
+	return room.GameData
+}
+
+// RoomView 玩家视角的房间状态
+type RoomView struct {
+	ID       uint64      `json:"id"`
+	GameType string      `json:"game_type"`
+	State    GameState   `json:"state"`
+	Players  []*Player   `json:"players,omitempty"`
+	GameData interface{} `json:"game_data,omitempty"`
+	Events   []GameEvent `json:"events"`
+	Version  uint64      `json:"version"`
+	Checksum string      `json:"checksum,omitempty"` // 请求驱动的房间中玩家视角的状态校验和，同步后客户端据此核对本地状态
+}
+
+// FilterEvents 按策略过滤事件列表
+func FilterEvents(policy InterestPolicy, room *GameRoom, viewerID uint64, events []GameEvent) []GameEvent {
+	visible := make([]GameEvent, 0, len(events))
+	for _, event := range events {
+		if filtered, ok := policy.FilterEvent(room, viewerID, event); ok {
+			visible = append(visible, filtered)
+		}
+	}
+	return visible
+}
+
+// GetRoomView 获取玩家视角的房间完整快照
+func (gm *GameplayManager) GetRoomView(roomID, viewerID uint64) (*RoomView, error) {
+	room, policy, err := gm.roomPolicy(roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	events, version := room.Events.Snapshot()
+
+	room.mutex.RLock()
+	view := &RoomView{
+		ID:       room.ID,
+		GameType: room.GameType,
+		State:    room.State,
+		Players:  room.playerList(),
+		GameData: policy.FilterState(room, viewerID),
+	}
+	room.mutex.RUnlock()
+
+	view.Events = gm.filterEvents(policy, room, viewerID, events)
+	view.Version = version
+	view.Checksum = gm.viewChecksum(room, viewerID)
+	return view, nil
+}
+
+// GetRoomDelta 获取玩家视角的增量状态，只包含seq之后的事件，事件已被覆盖时返回false
+func (gm *GameplayManager) GetRoomDelta(roomID, viewerID, seq uint64) (*RoomView, bool, error) {
+	room, policy, err := gm.roomPolicy(roomID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	events, version, ok := room.Events.Since(seq)
+	if !ok {
+		return nil, false, nil
+	}
+
+	room.mutex.RLock()
+	view := &RoomView{
+		ID:       room.ID,
+		GameType: room.GameType,
+		State:    room.State,
+		Players:  room.playerList(),
+	}
+	room.mutex.RUnlock()
+
+	view.Events = gm.filterEvents(policy, room, viewerID, events)
+	view.Version = version
+	view.Checksum = gm.viewChecksum(room, viewerID)
+	return view, true, nil
+}
+
+// AddEventFilter 注册事件过滤，应在创建房间之前调用
+func (gm *GameplayManager) AddEventFilter(filter EventFilter) {
+	gm.mutex.Lock()
+	defer gm.mutex.Unlock()
+
+	gm.viewFilters = append(gm.viewFilters, filter)
+}
+
+// filterEvents 按兴趣管理策略和注册的事件过滤得到viewer可见的事件
+func (gm *GameplayManager) filterEvents(policy InterestPolicy, room *GameRoom, viewerID uint64, events []GameEvent) []GameEvent {
+	visible := FilterEvents(policy, room, viewerID, events)
+
+	gm.mutex.RLock()
+	filters := gm.viewFilters
+	gm.mutex.RUnlock()
+	if len(filters) == 0 {
+		return visible
+	}
+
+	kept := visible[:0]
+next:
+	for _, event := range visible {
+		for _, filter := range filters {
+			if !filter(room, viewerID, event) {
+				continue next
+			}
+		}
+		kept = append(kept, event)
+	}
+	return kept
+}
+
+// roomPolicy 获取房间及其玩法模块的兴趣管理策略
+func (gm *GameplayManager) roomPolicy(roomID uint64) (*GameRoom, InterestPolicy, error) {
+	gm.mutex.RLock()
+	defer gm.mutex.RUnlock()
+
+	room, exists := gm.rooms[roomID]
+	if !exists {
+		return nil, nil, fmt.Errorf("room %d not found", roomID)
+	}
+
+	module, exists := gm.modules[room.GameType]
+	if !exists {
+		return nil, nil, fmt.Errorf("game module %s not found", room.GameType)
+	}
+
+	policy := module.GetInterestPolicy()
+	if policy == nil {
+		policy = PublicInterestPolicy{}
+	}
+	return room, policy, nil
+}
+
+// playerList 获取玩家列表，调用方需持有锁
+func (gr *GameRoom) playerList() []*Player {
+	players := make([]*Player, 0, len(gr.Players))
+	for _, player := range gr.Players {
+		players = append(players, player)
+	}
+	return players
+}
+
+// CardInterestPolicy 卡牌游戏兴趣管理策略
+// 玩家只能看到自己的手牌，其他玩家只暴露手牌数量，牌堆只暴露剩余数量
+type CardInterestPolicy struct{}
+
+// CardGameView 玩家视角的卡牌游戏数据
+type CardGameView struct {
+	DeckCount  int            `json:"deck_count"`
+	Hand       []Card         `json:"hand"`
+	HandCounts map[uint64]int `json:"hand_counts"`
+	Board      []Card         `json:"board"`
+	Turn       uint64         `json:"turn"`
+	Round      int            `json:"round"`
+}
+
+// FilterEvent 其他玩家抽牌时隐藏牌面，房间规则开启明牌抽牌时不隐藏
+func (CardInterestPolicy) FilterEvent(room *GameRoom, viewerID uint64, event GameEvent) (GameEvent, bool) {
+	if event.Type == "card_drawn" && event.PlayerID != viewerID && !room.Config.BoolRule("open_draws", false) {
+		event.Data = nil
+	}
+	return event, true
+}
+
+// FilterState 构造玩家视角的卡牌数据
+func (CardInterestPolicy) FilterState(room *GameRoom, viewerID uint64) interface{} {
+	gameData, ok := room.GameData.(*CardGameData)
+	if !ok {
+		return nil
+	}
+
+	handCounts := make(map[uint64]int, len(gameData.Hands))
+	for playerID, hand := range gameData.Hands {
+		handCounts[playerID] = len(hand)
+	}
+
+	return &CardGameView{
+		DeckCount:  len(gameData.Deck),
+		Hand:       append([]Card(nil), gameData.Hands[viewerID]...),
+		HandCounts: handCounts,
+		Board:      append([]Card(nil), gameData.Board...),
+		Turn:       gameData.Turn,
+		Round:      gameData.Round,
+	}
+}
@@ -0,0 +1,59 @@
+package gameplay
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// NewReplayState 回放时房间开始的牌局数据，牌堆按房间规则生成，与创建房间时一致
+func (cgm *CardGameModule) NewReplayState(config *RoomConfig) interface{} {
+	return &CardGameData{
+		Deck:  generateDeck(config.IntRule("decks", 1)),
+		Hands: make(map[uint64][]Card),
+		Board: make([]Card, 0),
+	}
+}
+
+// ApplyEvent 按事件更新回放的牌局数据，与处理操作时对牌局数据的修改一致
+func (cgm *CardGameModule) ApplyEvent(state interface{}, event *GameEvent) error {
+	gameData, ok := state.(*CardGameData)
+	if !ok {
+		return fmt.Errorf("unexpected replay state %T", state)
+	}
+
+	switch event.Type {
+	case "card_drawn":
+		var card Card
+		if err := decodeEventData(event.Data, &card); err != nil {
+			return err
+		}
+		index := -1
+		for i, c := range gameData.Deck {
+			if c.ID == card.ID {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return fmt.Errorf("card %d drawn by player %d is not in the deck", card.ID, event.PlayerID)
+		}
+		gameData.Deck = append(gameData.Deck[:index], gameData.Deck[index+1:]...)
+		gameData.Hands[event.PlayerID] = append(gameData.Hands[event.PlayerID], card)
+
+	case "card_played":
+		// 出牌目前只产生事件，不修改牌局数据
+	}
+	return nil
+}
+
+// decodeEventData 解析事件数据，从回放存储读出的数据按JSON字段名(不区分大小写)对应到结构体
+func decodeEventData(data interface{}, v interface{}) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode event data: %v", err)
+	}
+	if err := json.Unmarshal(encoded, v); err != nil {
+		return fmt.Errorf("failed to decode event data: %v", err)
+	}
+	return nil
+}
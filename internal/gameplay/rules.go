@@ -0,0 +1,192 @@
+package gameplay
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// 房间规则的类型
+const (
+	RuleTypeBool   = "bool"
+	RuleTypeInt    = "int"
+	RuleTypeChoice = "choice" // 从Options中选择一项
+)
+
+// RoomRule 房间自定义规则，客户端按类型渲染开关、数值或选项选择器
+type RoomRule struct {
+	Name    string      `json:"name"`
+	Type    string      `json:"type"`
+	Label   string      `json:"label"` // 客户端本地化文本的键
+	Default interface{} `json:"default"`
+	Min     int         `json:"min,omitempty"` // 仅int类型
+	Max     int         `json:"max,omitempty"` // 仅int类型
+	Options []string    `json:"options,omitempty"`
+}
+
+// RuleSchema 玩法模块的房间规则表
+type RuleSchema []RoomRule
+
+// RuleProvider 提供房间规则的玩法模块，未实现的模块不接受自定义规则
+type RuleProvider interface {
+	GetRoomRules() RuleSchema
+}
+
+// Apply 校验房间的自定义规则并补齐默认值，返回新的规则表，未声明的规则视为错误。
+// 数值统一转换为int，布尔和选项保持原类型
+func (s RuleSchema) Apply(custom map[string]interface{}) (map[string]interface{}, error) {
+	declared := make(map[string]bool, len(s))
+	for _, rule := range s {
+		declared[rule.Name] = true
+	}
+	var unknown []string
+	for name := range custom {
+		if !declared[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown room rules: %v", unknown)
+	}
+
+	applied := make(map[string]interface{}, len(s))
+	for _, rule := range s {
+		value, exists := custom[rule.Name]
+		if !exists {
+			value = rule.Default
+		}
+		normalized, err := rule.normalize(value)
+		if err != nil {
+			return nil, fmt.Errorf("room rule %s: %v", rule.Name, err)
+		}
+		applied[rule.Name] = normalized
+	}
+	return applied, nil
+}
+
+// normalize 按规则类型校验取值
+func (r RoomRule) normalize(value interface{}) (interface{}, error) {
+	switch r.Type {
+	case RuleTypeBool:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected bool, got %T", value)
+		}
+		return b, nil
+	case RuleTypeInt:
+		var n int
+		switch v := value.(type) {
+		case int:
+			n = v
+		case float64:
+			// JSON数值解析为float64，只接受整数
+			if v != math.Trunc(v) || v < math.MinInt32 || v > math.MaxInt32 {
+				return nil, fmt.Errorf("expected integer, got %v", v)
+			}
+			n = int(v)
+		default:
+			return nil, fmt.Errorf("expected integer, got %T", value)
+		}
+		if n < r.Min || n > r.Max {
+			return nil, fmt.Errorf("%d out of range [%d, %d]", n, r.Min, r.Max)
+		}
+		return n, nil
+	case RuleTypeChoice:
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string, got %T", value)
+		}
+		for _, option := range r.Options {
+			if option == str {
+				return str, nil
+			}
+		}
+		return nil, fmt.Errorf("%q is not one of %v", str, r.Options)
+	}
+	return nil, fmt.Errorf("unknown rule type %s", r.Type)
+}
+
+// Validate 检查规则表本身，默认值必须是合法取值
+func (s RuleSchema) Validate() error {
+	names := make(map[string]bool, len(s))
+	for _, rule := range s {
+		if rule.Name == "" || names[rule.Name] {
+			return fmt.Errorf("invalid or duplicate room rule name %q", rule.Name)
+		}
+		names[rule.Name] = true
+		if _, err := rule.normalize(rule.Default); err != nil {
+			return fmt.Errorf("room rule %s has invalid default: %v", rule.Name, err)
+		}
+	}
+	return nil
+}
+
+// IntRule 获取房间的整数规则，规则未设置时返回def
+func (c *RoomConfig) IntRule(name string, def int) int {
+	if c == nil {
+		return def
+	}
+	if n, ok := c.CustomConfig[name].(int); ok {
+		return n
+	}
+	return def
+}
+
+// BoolRule 获取房间的布尔规则，规则未设置时返回def
+func (c *RoomConfig) BoolRule(name string, def bool) bool {
+	if c == nil {
+		return def
+	}
+	if b, ok := c.CustomConfig[name].(bool); ok {
+		return b
+	}
+	return def
+}
+
+// ChoiceRule 获取房间的选项规则，规则未设置时返回def
+func (c *RoomConfig) ChoiceRule(name string, def string) string {
+	if c == nil {
+		return def
+	}
+	if str, ok := c.CustomConfig[name].(string); ok {
+		return str
+	}
+	return def
+}
+
+// RoomRules 获取玩法类型的房间规则表，模块不支持自定义规则时返回空表
+func (gm *GameplayManager) RoomRules(gameType string) (RuleSchema, error) {
+	gm.mutex.RLock()
+	module, exists := gm.modules[gameType]
+	gm.mutex.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("game type %s not found", gameType)
+	}
+
+	if provider, ok := module.(RuleProvider); ok {
+		return provider.GetRoomRules(), nil
+	}
+	return RuleSchema{}, nil
+}
+
+// applyRoomRules 校验并补齐房间的自定义规则
+func applyRoomRules(module GameplayModule, config *RoomConfig) error {
+	if config == nil {
+		return nil
+	}
+	provider, ok := module.(RuleProvider)
+	if !ok {
+		if len(config.CustomConfig) > 0 {
+			return fmt.Errorf("game type %s does not accept room rules", module.GetName())
+		}
+		return nil
+	}
+
+	applied, err := provider.GetRoomRules().Apply(config.CustomConfig)
+	if err != nil {
+		return err
+	}
+	config.CustomConfig = applied
+	return nil
+}
@@ -0,0 +1,190 @@
+package gameplay
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/actor"
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// 房间Actor消息类型
+const (
+	msgTypeRoomInput = "room_input"
+	msgTypeRoomTick  = "room_tick"
+)
+
+// TickConfig 实时玩法固定帧率配置
+type TickConfig struct {
+	MaxRate         int            `yaml:"max_rate"`          // 允许的最高帧率(Hz)
+	InputBufferSize int            `yaml:"input_buffer_size"` // 每个房间缓冲的最大输入数，超出后拒绝
+	GameTypes       map[string]int `yaml:"game_types"`        // 玩法类型 -> 默认帧率，未配置的玩法按请求驱动
+}
+
+// TickStats 单帧统计
+type TickStats struct {
+	RoomID   uint64
+	GameType string
+	Tick     uint64
+	Inputs   int
+	Duration time.Duration // 本帧更新耗时
+	Interval time.Duration // 帧间隔，耗时超过间隔即为超帧
+}
+
+// roomMessage 房间Actor消息
+type roomMessage struct {
+	msgType string
+	action  *GameAction
+}
+
+// GetType 获取消息类型
+func (m *roomMessage) GetType() string {
+	return m.msgType
+}
+
+// GetData 获取消息数据
+func (m *roomMessage) GetData() []byte {
+	return nil
+}
+
+// RoomActor 房间Actor，按固定帧率推进实时玩法房间
+// 输入先缓冲在Actor中，每帧按确定的顺序交给玩法模块的OnTick处理
+type RoomActor struct {
+	*actor.BaseActor
+	room      *GameRoom
+	module    GameplayModule
+	manager   *GameplayManager
+	interval  time.Duration
+	inputs    []*GameAction
+	pending   int64 // 已提交未处理的输入数
+	maxInputs int64
+	tick      uint64
+	stopChan  chan struct{}
+	stopOnce  sync.Once
+}
+
+// NewRoomActor 创建房间Actor
+func NewRoomActor(manager *GameplayManager, module GameplayModule, room *GameRoom, rate int, maxInputs int) *RoomActor {
+	baseActor := actor.NewBaseActor(fmt.Sprintf("room_%d", room.ID), "room", maxInputs+16)
+
+	return &RoomActor{
+		BaseActor: baseActor,
+		room:      room,
+		module:    module,
+		manager:   manager,
+		interval:  time.Second / time.Duration(rate),
+		inputs:    make([]*GameAction, 0, maxInputs),
+		maxInputs: int64(maxInputs),
+		stopChan:  make(chan struct{}),
+	}
+}
+
+// Submit 提交玩家输入，在下一帧处理
+func (ra *RoomActor) Submit(action *GameAction) error {
+	if atomic.AddInt64(&ra.pending, 1) > ra.maxInputs {
+		atomic.AddInt64(&ra.pending, -1)
+		return fmt.Errorf("input buffer full for room %d", ra.room.ID)
+	}
+
+	if err := ra.Tell(&roomMessage{msgType: msgTypeRoomInput, action: action}); err != nil {
+		atomic.AddInt64(&ra.pending, -1)
+		return err
+	}
+	return nil
+}
+
+// OnReceive 处理消息
+func (ra *RoomActor) OnReceive(ctx context.Context, msg actor.Message) error {
+	switch msg.GetType() {
+	case msgTypeRoomInput:
+		ra.inputs = append(ra.inputs, msg.(*roomMessage).action)
+	case msgTypeRoomTick:
+		ra.step()
+	default:
+		logger.Debug(fmt.Sprintf("Unknown message type: %s", msg.GetType()))
+	}
+
+	return nil
+}
+
+// OnStart 启动时处理
+func (ra *RoomActor) OnStart(ctx context.Context) error {
+	go ra.tickLoop()
+	logger.Info(fmt.Sprintf("Room %d tick loop started at %v interval", ra.room.ID, ra.interval))
+	return nil
+}
+
+// OnStop 停止时处理
+func (ra *RoomActor) OnStop(ctx context.Context) error {
+	ra.stopOnce.Do(func() {
+		close(ra.stopChan)
+	})
+	logger.Info(fmt.Sprintf("Room %d tick loop stopped after %d ticks", ra.room.ID, ra.tick))
+	return nil
+}
+
+// tickLoop 按固定间隔向Actor投递帧消息，保证输入处理和帧更新在同一协程中串行执行
+func (ra *RoomActor) tickLoop() {
+	ticker := time.NewTicker(ra.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := ra.Tell(&roomMessage{msgType: msgTypeRoomTick}); err != nil {
+				logger.Warn(fmt.Sprintf("Room %d failed to schedule tick: %v", ra.room.ID, err))
+			}
+		case <-ra.stopChan:
+			return
+		}
+	}
+}
+
+// step 执行一帧更新
+func (ra *RoomActor) step() {
+	state := ra.room.GetState()
+	if state == GameStateEnded {
+		ra.manager.removeRoomActor(ra.room.ID)
+		go ra.Stop(ra)
+		return
+	}
+	if state != GameStateRunning {
+		return
+	}
+
+	// 同一帧内的输入按时间和玩家排序，保证相同输入得到相同结果
+	inputs := ra.inputs
+	sort.SliceStable(inputs, func(i, j int) bool {
+		if !inputs[i].Timestamp.Equal(inputs[j].Timestamp) {
+			return inputs[i].Timestamp.Before(inputs[j].Timestamp)
+		}
+		return inputs[i].PlayerID < inputs[j].PlayerID
+	})
+
+	start := time.Now()
+	result, err := ra.module.OnTick(ra.room, ra.tick, inputs)
+	duration := time.Since(start)
+
+	atomic.AddInt64(&ra.pending, -int64(len(inputs)))
+	ra.inputs = ra.inputs[:0]
+
+	if err != nil {
+		logger.Error(fmt.Sprintf("Room %d tick %d failed: %v", ra.room.ID, ra.tick, err))
+	} else if result != nil {
+		ra.manager.applyResult(ra.room, result)
+	}
+
+	ra.manager.reportTick(&TickStats{
+		RoomID:   ra.room.ID,
+		GameType: ra.room.GameType,
+		Tick:     ra.tick,
+		Inputs:   len(inputs),
+		Duration: duration,
+		Interval: ra.interval,
+	})
+	ra.tick++
+}
@@ -0,0 +1,104 @@
+package geo
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// GeoConfig 区域配置
+type GeoConfig struct {
+	Region           string         `yaml:"region"`             // 当前节点所在区域
+	DefaultRegion    string         `yaml:"default_region"`     // 无法识别IP时使用的区域
+	AllowCrossRegion bool           `yaml:"allow_cross_region"` // 是否允许跨区域匹配
+	Regions          []RegionConfig `yaml:"regions"`            // 区域与IP段映射
+}
+
+// RegionConfig 区域IP段配置
+type RegionConfig struct {
+	Name  string   `yaml:"name"`
+	CIDRs []string `yaml:"cidrs"`
+}
+
+// regionNetwork 区域IP段
+type regionNetwork struct {
+	region  string
+	network *net.IPNet
+}
+
+// GeoIPResolver 基于配置IP段的地理位置解析器
+type GeoIPResolver struct {
+	networks      []regionNetwork
+	defaultRegion string
+	mutex         sync.RWMutex
+}
+
+// NewGeoIPResolver 创建地理位置解析器
+func NewGeoIPResolver(config *GeoConfig) (*GeoIPResolver, error) {
+	resolver := &GeoIPResolver{
+		defaultRegion: config.DefaultRegion,
+	}
+
+	if err := resolver.Load(config.Regions); err != nil {
+		return nil, err
+	}
+
+	return resolver, nil
+}
+
+// Load 加载区域IP段，配置热更新时可重新调用
+func (r *GeoIPResolver) Load(regions []RegionConfig) error {
+	networks := make([]regionNetwork, 0)
+	for _, region := range regions {
+		for _, cidr := range region.CIDRs {
+			_, network, err := net.ParseCIDR(cidr)
+			if err != nil {
+				return fmt.Errorf("invalid cidr %s for region %s: %v", cidr, region.Name, err)
+			}
+			networks = append(networks, regionNetwork{
+				region:  region.Name,
+				network: network,
+			})
+		}
+	}
+
+	r.mutex.Lock()
+	r.networks = networks
+	r.mutex.Unlock()
+
+	logger.Info(fmt.Sprintf("GeoIP resolver loaded %d networks for %d regions", len(networks), len(regions)))
+	return nil
+}
+
+// Lookup 查询IP所在区域，地址可带端口
+func (r *GeoIPResolver) Lookup(address string) string {
+	host := address
+	if h, _, err := net.SplitHostPort(address); err == nil {
+		host = h
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return r.defaultRegion
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	// 取最长前缀匹配
+	region := r.defaultRegion
+	bestPrefix := -1
+	for _, n := range r.networks {
+		if !n.network.Contains(ip) {
+			continue
+		}
+		if prefix, _ := n.network.Mask.Size(); prefix > bestPrefix {
+			bestPrefix = prefix
+			region = n.region
+		}
+	}
+
+	return region
+}
@@ -0,0 +1,89 @@
+package geo
+
+import (
+	"testing"
+
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+func init() {
+	logger.InitGlobalLogger(&logger.LogConfig{
+		Level:  "error",
+		Format: "console",
+		Output: "stderr",
+	})
+}
+
+// testConfig 区域IP段有嵌套，10.1.0.0/16比10.0.0.0/8更具体
+func testConfig() *GeoConfig {
+	return &GeoConfig{
+		DefaultRegion: "default",
+		Regions: []RegionConfig{
+			{Name: "cn", CIDRs: []string{"10.0.0.0/8", "2001:db8::/32"}},
+			{Name: "sg", CIDRs: []string{"10.1.0.0/16", "2001:db8:1::/48"}},
+			{Name: "us", CIDRs: []string{"10.1.2.0/24"}},
+		},
+	}
+}
+
+// TestLookup 最长前缀匹配、IPv6、带端口地址和无法识别时回退到默认区域
+func TestLookup(t *testing.T) {
+	resolver, err := NewGeoIPResolver(testConfig())
+	if err != nil {
+		t.Fatalf("failed to create resolver: %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		address string
+		region  string
+	}{
+		{"ipv4", "10.9.9.9", "cn"},
+		{"longer_prefix", "10.1.9.9", "sg"},
+		{"longest_prefix", "10.1.2.3", "us"},
+		{"ipv4_with_port", "10.1.2.3:8080", "us"},
+		{"ipv4_mapped_ipv6", "::ffff:10.1.9.9", "sg"},
+		{"ipv6", "2001:db8:2::1", "cn"},
+		{"ipv6_longer_prefix", "2001:db8:1::1", "sg"},
+		{"ipv6_with_port", "[2001:db8:1::1]:8080", "sg"},
+		{"unmatched_ipv4", "192.168.1.1", "default"},
+		{"unmatched_ipv6", "2001:db9::1", "default"},
+		{"invalid", "not-an-ip", "default"},
+		{"empty", "", "default"},
+		{"hostname_with_port", "localhost:8080", "default"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if region := resolver.Lookup(tc.address); region != tc.region {
+				t.Fatalf("Lookup(%q) = %q, want %q", tc.address, region, tc.region)
+			}
+		})
+	}
+}
+
+// TestLoad 重新加载替换原有IP段，无效CIDR返回错误且不影响当前配置
+func TestLoad(t *testing.T) {
+	resolver, err := NewGeoIPResolver(testConfig())
+	if err != nil {
+		t.Fatalf("failed to create resolver: %v", err)
+	}
+
+	if err := resolver.Load([]RegionConfig{{Name: "eu", CIDRs: []string{"10.0.0.0/8"}}}); err != nil {
+		t.Fatalf("failed to reload: %v", err)
+	}
+	if region := resolver.Lookup("10.1.2.3"); region != "eu" {
+		t.Fatalf("Lookup after reload = %q, want eu", region)
+	}
+
+	if err := resolver.Load([]RegionConfig{{Name: "bad", CIDRs: []string{"10.0.0.0/33"}}}); err == nil {
+		t.Fatal("expected error for invalid cidr")
+	}
+	if region := resolver.Lookup("10.1.2.3"); region != "eu" {
+		t.Fatalf("Lookup after failed reload = %q, want eu", region)
+	}
+
+	if _, err := NewGeoIPResolver(&GeoConfig{Regions: []RegionConfig{{Name: "bad", CIDRs: []string{"10.0.0.1"}}}}); err == nil {
+		t.Fatal("expected error for address without prefix length")
+	}
+}
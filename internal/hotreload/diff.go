@@ -0,0 +1,179 @@
+package hotreload
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// ChangeType 配置项变更类型
+type ChangeType string
+
+const (
+	ChangeAdded    ChangeType = "added"
+	ChangeRemoved  ChangeType = "removed"
+	ChangeModified ChangeType = "modified"
+)
+
+// redactedValue 敏感配置项在日志中的显示值
+const redactedValue = "******"
+
+// sensitiveKeys 名称包含这些词的配置项不输出具体值
+var sensitiveKeys = []string{"password", "secret", "token", "private_key", "access_key", "credential"}
+
+// ConfigChange 单个配置项的变更
+type ConfigChange struct {
+	Path     string      `json:"path"` // 以点分隔的配置路径，数组下标写作[i]
+	Type     ChangeType  `json:"type"`
+	OldValue interface{} `json:"old_value,omitempty"`
+	NewValue interface{} `json:"new_value,omitempty"`
+}
+
+// String 变更的单行描述，敏感配置项的值会被隐藏
+func (c ConfigChange) String() string {
+	oldValue, newValue := c.OldValue, c.NewValue
+	if isSensitivePath(c.Path) {
+		oldValue, newValue = redactedValue, redactedValue
+	}
+
+	switch c.Type {
+	case ChangeAdded:
+		return fmt.Sprintf("+ %s = %v", c.Path, newValue)
+	case ChangeRemoved:
+		return fmt.Sprintf("- %s (was %v)", c.Path, oldValue)
+	default:
+		return fmt.Sprintf("~ %s: %v -> %v", c.Path, oldValue, newValue)
+	}
+}
+
+// DiffConfig 比较新旧配置，返回按路径排序的变更列表
+//
+// 配置需要是解析后的通用结构(map、slice和标量)，数组长度不同时按下标逐项比较
+func DiffConfig(oldData, newData interface{}) []ConfigChange {
+	changes := diffValue("", normalize(oldData), normalize(newData), nil)
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+// diffValue 递归比较配置值，新增或删除的整段配置展开到每个配置项，避免嵌套的敏感值随整段输出
+func diffValue(path string, oldValue, newValue interface{}, changes []ConfigChange) []ConfigChange {
+	oldMap, oldIsMap := oldValue.(map[string]interface{})
+	newMap, newIsMap := newValue.(map[string]interface{})
+	if (oldIsMap || newIsMap) && (oldIsMap || oldValue == nil) && (newIsMap || newValue == nil) {
+		count := len(changes)
+		for key, value := range oldMap {
+			changes = diffValue(joinPath(path, key), value, newMap[key], changes)
+		}
+		for key, value := range newMap {
+			if _, exists := oldMap[key]; !exists {
+				changes = diffValue(joinPath(path, key), nil, value, changes)
+			}
+		}
+		return appendEmptyChange(path, oldValue, newValue, count, changes)
+	}
+
+	oldSlice, oldIsSlice := oldValue.([]interface{})
+	newSlice, newIsSlice := newValue.([]interface{})
+	if (oldIsSlice || newIsSlice) && (oldIsSlice || oldValue == nil) && (newIsSlice || newValue == nil) {
+		count := len(changes)
+		for i := 0; i < len(oldSlice) || i < len(newSlice); i++ {
+			var oldItem, newItem interface{}
+			if i < len(oldSlice) {
+				oldItem = oldSlice[i]
+			}
+			if i < len(newSlice) {
+				newItem = newSlice[i]
+			}
+			changes = diffValue(fmt.Sprintf("%s[%d]", path, i), oldItem, newItem, changes)
+		}
+		return appendEmptyChange(path, oldValue, newValue, count, changes)
+	}
+
+	switch {
+	case oldValue == nil && newValue == nil:
+	case oldValue == nil:
+		changes = append(changes, ConfigChange{Path: path, Type: ChangeAdded, NewValue: newValue})
+	case newValue == nil:
+		changes = append(changes, ConfigChange{Path: path, Type: ChangeRemoved, OldValue: oldValue})
+	case !reflect.DeepEqual(oldValue, newValue):
+		changes = append(changes, ConfigChange{Path: path, Type: ChangeModified, OldValue: oldValue, NewValue: newValue})
+	}
+	return changes
+}
+
+// appendEmptyChange 新增或删除的是空map或空数组时没有下级配置项，记录为该路径本身的变更
+func appendEmptyChange(path string, oldValue, newValue interface{}, count int, changes []ConfigChange) []ConfigChange {
+	if len(changes) > count || path == "" {
+		return changes
+	}
+	switch {
+	case oldValue == nil:
+		changes = append(changes, ConfigChange{Path: path, Type: ChangeAdded, NewValue: newValue})
+	case newValue == nil:
+		changes = append(changes, ConfigChange{Path: path, Type: ChangeRemoved, OldValue: oldValue})
+	}
+	return changes
+}
+
+// normalize 把map[interface{}]interface{}等结构统一为map[string]interface{}和[]interface{}
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			result[key] = normalize(child)
+		}
+		return result
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			result[fmt.Sprint(key)] = normalize(child)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, child := range v {
+			result[i] = normalize(child)
+		}
+		return result
+	default:
+		return value
+	}
+}
+
+// joinPath 拼接配置路径
+func joinPath(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// isSensitivePath 配置项是否包含密码、密钥等敏感信息
+func isSensitivePath(path string) bool {
+	lower := strings.ToLower(path)
+	for _, key := range sensitiveKeys {
+		if strings.Contains(lower, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// LogChanges 输出配置变更，每个变更一行
+func LogChanges(name string, changes []ConfigChange) {
+	if len(changes) == 0 {
+		logger.Info(fmt.Sprintf("Config %s has no changes", name))
+		return
+	}
+
+	logger.Info(fmt.Sprintf("Config %s has %d changes", name, len(changes)))
+	for _, change := range changes {
+		logger.Info(fmt.Sprintf("  %s", change))
+	}
+}
@@ -2,6 +2,9 @@ package hotreload
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -11,10 +14,17 @@ import (
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v3"
 
 	"github.com/phuhao00/lufy/internal/logger"
 )
 
+// Config 配置热更新设置
+type Config struct {
+	AutoApply      bool          `yaml:"auto_apply"`      // 配置文件变化后直接应用，关闭时需要通过GM命令分阶段提交
+	RolloutTimeout time.Duration `yaml:"rollout_timeout"` // 分阶段更新时等待节点回报校验和提交结果的超时
+}
+
 // HotReloadManager 热更新管理器
 type HotReloadManager struct {
 	watcher   *fsnotify.Watcher
@@ -24,6 +34,8 @@ type HotReloadManager struct {
 	ctx       context.Context
 	cancel    context.CancelFunc
 	callbacks map[string][]ReloadCallback
+	staged    map[string]*StagedConfig // 已校验但未应用的配置，按文件路径索引
+	autoApply bool                     // 文件变化后是否直接应用，否则只暂存等待Commit
 }
 
 // Module 可热更新的模块
@@ -42,6 +54,17 @@ type ConfigFile struct {
 	LastModTime time.Time
 	Parser      ConfigParser
 	Data        interface{}
+	Version     string // 当前生效内容的校验和
+}
+
+// StagedConfig 两阶段更新中已解析和校验、尚未应用的配置
+type StagedConfig struct {
+	Path     string
+	Version  string // 文件内容的校验和，提交时用于确认应用的是校验过的版本
+	Data     interface{}
+	Changes  []ConfigChange // 与当前生效配置的差异
+	ModTime  time.Time
+	StagedAt time.Time
 }
 
 // ReloadCallback 重新加载回调函数
@@ -69,6 +92,8 @@ func NewHotReloadManager() (*HotReloadManager, error) {
 		ctx:       ctx,
 		cancel:    cancel,
 		callbacks: make(map[string][]ReloadCallback),
+		staged:    make(map[string]*StagedConfig),
+		autoApply: true,
 	}
 
 	go manager.watchLoop()
@@ -154,14 +179,125 @@ func (hrm *HotReloadManager) RegisterConfig(path string, parser ConfigParser) er
 	return nil
 }
 
-// RegisterCallback 注册重新加载回调
+// RegisterCallback 注册重新加载回调，配置文件的回调可以使用注册时的相对路径
 func (hrm *HotReloadManager) RegisterCallback(name string, callback ReloadCallback) {
 	hrm.mutex.Lock()
 	defer hrm.mutex.Unlock()
 
+	if absPath, err := filepath.Abs(name); err == nil {
+		if _, exists := hrm.configs[absPath]; exists {
+			name = absPath
+		}
+	}
+
 	hrm.callbacks[name] = append(hrm.callbacks[name], callback)
 }
 
+// SetAutoApply 设置文件变化后是否直接应用配置
+//
+// 关闭后文件变化只会校验并输出差异，需要调用Commit应用，用于集群分阶段更新
+func (hrm *HotReloadManager) SetAutoApply(autoApply bool) {
+	hrm.mutex.Lock()
+	defer hrm.mutex.Unlock()
+
+	hrm.autoApply = autoApply
+}
+
+// Prepare 两阶段更新的第一阶段，读取、解析和校验配置文件并暂存，不影响当前生效的配置
+func (hrm *HotReloadManager) Prepare(path string) (*StagedConfig, error) {
+	hrm.mutex.Lock()
+	defer hrm.mutex.Unlock()
+
+	config, err := hrm.lookupConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	staged, err := hrm.prepareConfig(config)
+	if err != nil {
+		delete(hrm.staged, config.Path)
+		return nil, err
+	}
+	hrm.staged[config.Path] = staged
+
+	LogChanges(config.Path, staged.Changes)
+	return staged, nil
+}
+
+// Commit 两阶段更新的第二阶段，应用Prepare暂存的配置
+//
+// version不为空时必须与暂存的版本一致，避免应用未经校验的内容
+func (hrm *HotReloadManager) Commit(path, version string) (*StagedConfig, error) {
+	hrm.mutex.Lock()
+	defer hrm.mutex.Unlock()
+
+	config, err := hrm.lookupConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	staged, exists := hrm.staged[config.Path]
+	if !exists {
+		return nil, fmt.Errorf("no staged config for %s", path)
+	}
+	if version != "" && staged.Version != version {
+		return nil, fmt.Errorf("staged config version %s does not match %s", staged.Version, version)
+	}
+	delete(hrm.staged, config.Path)
+
+	if err := hrm.applyConfig(config, staged); err != nil {
+		return staged, err
+	}
+	return staged, nil
+}
+
+// Discard 丢弃暂存的配置
+func (hrm *HotReloadManager) Discard(path string) {
+	hrm.mutex.Lock()
+	defer hrm.mutex.Unlock()
+
+	if config, err := hrm.lookupConfig(path); err == nil {
+		delete(hrm.staged, config.Path)
+	}
+}
+
+// Reload 立即校验并应用配置文件，相当于连续执行Prepare和Commit
+func (hrm *HotReloadManager) Reload(path string) (*StagedConfig, error) {
+	hrm.mutex.Lock()
+	defer hrm.mutex.Unlock()
+
+	config, err := hrm.lookupConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	staged, err := hrm.prepareConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	delete(hrm.staged, config.Path)
+
+	LogChanges(config.Path, staged.Changes)
+	if err := hrm.applyConfig(config, staged); err != nil {
+		return staged, err
+	}
+	return staged, nil
+}
+
+// lookupConfig 查找已注册的配置文件
+func (hrm *HotReloadManager) lookupConfig(path string) (*ConfigFile, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %v", err)
+	}
+
+	config, exists := hrm.configs[absPath]
+	if !exists {
+		return nil, fmt.Errorf("config not registered: %s", path)
+	}
+	return config, nil
+}
+
 // loadModule 加载模块
 func (hrm *HotReloadManager) loadModule(module *Module) error {
 	// 构建Go插件
@@ -184,35 +320,74 @@ func (hrm *HotReloadManager) loadModule(module *Module) error {
 
 // loadConfig 加载配置文件
 func (hrm *HotReloadManager) loadConfig(config *ConfigFile) error {
+	staged, err := hrm.prepareConfig(config)
+	if err != nil {
+		return err
+	}
+
+	if err := hrm.applyConfig(config, staged); err != nil {
+		logger.Error(err.Error())
+	}
+	return nil
+}
+
+// prepareConfig 读取、解析和校验配置文件，计算与当前配置的差异
+func (hrm *HotReloadManager) prepareConfig(config *ConfigFile) (*StagedConfig, error) {
+	fileInfo, err := os.Stat(config.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat config file: %v", err)
+	}
+
 	data, err := os.ReadFile(config.Path)
 	if err != nil {
-		return fmt.Errorf("failed to read config file: %v", err)
+		return nil, fmt.Errorf("failed to read config file: %v", err)
 	}
 
 	// 解析配置
 	parsedData, err := config.Parser.Parse(data)
 	if err != nil {
-		return fmt.Errorf("failed to parse config: %v", err)
+		return nil, fmt.Errorf("failed to parse config: %v", err)
 	}
 
 	// 验证配置
 	if err := config.Parser.Validate(parsedData); err != nil {
-		return fmt.Errorf("config validation failed: %v", err)
-	}
+		return nil, fmt.Errorf("config validation failed: %v", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return &StagedConfig{
+		Path:     config.Path,
+		Version:  hex.EncodeToString(sum[:])[:12],
+		Data:     parsedData,
+		Changes:  DiffConfig(config.Data, parsedData),
+		ModTime:  fileInfo.ModTime(),
+		StagedAt: time.Now(),
+	}, nil
+}
 
+// applyConfig 应用校验过的配置并执行回调，所有回调都会执行，返回第一个失败的回调错误
+func (hrm *HotReloadManager) applyConfig(config *ConfigFile, staged *StagedConfig) error {
 	oldData := config.Data
-	config.Data = parsedData
+	config.Data = staged.Data
+	config.Version = staged.Version
+	if staged.ModTime.After(config.LastModTime) {
+		config.LastModTime = staged.ModTime
+	}
 
 	// 执行回调
+	var firstErr error
 	callbacks := hrm.callbacks[config.Path]
 	for _, callback := range callbacks {
-		if err := callback(config.Path, oldData, parsedData); err != nil {
+		if err := callback(config.Path, oldData, staged.Data); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("config reload callback failed: %v", err)
+			}
 			logger.Error(fmt.Sprintf("Config reload callback failed: %v", err))
 		}
 	}
 
-	logger.Info(fmt.Sprintf("Loaded config: %s", config.Path))
-	return nil
+	logger.Info(fmt.Sprintf("Loaded config: %s (version: %s)", config.Path, config.Version))
+	return firstErr
 }
 
 // buildPlugin 构建Go插件
@@ -329,10 +504,21 @@ func (hrm *HotReloadManager) reloadConfig(config *ConfigFile) {
 
 	config.LastModTime = fileInfo.ModTime()
 
-	// 重新加载配置
-	if err := hrm.loadConfig(config); err != nil {
+	staged, err := hrm.prepareConfig(config)
+	if err != nil {
 		logger.Error(fmt.Sprintf("Failed to reload config: %v", err))
+		return
+	}
+	LogChanges(config.Path, staged.Changes)
+
+	// 关闭自动应用时只暂存，等待集群所有节点校验通过后再提交
+	if !hrm.autoApply {
+		hrm.staged[config.Path] = staged
+		logger.Info(fmt.Sprintf("Config %s staged (version: %s), waiting for commit", config.Path, staged.Version))
+		return
 	}
+
+	hrm.applyConfig(config, staged)
 }
 
 // GetModule 获取模块
@@ -344,6 +530,18 @@ func (hrm *HotReloadManager) GetModule(name string) (*Module, bool) {
 	return module, exists
 }
 
+// GetConfigVersion 获取配置当前生效的版本
+func (hrm *HotReloadManager) GetConfigVersion(path string) (string, bool) {
+	hrm.mutex.RLock()
+	defer hrm.mutex.RUnlock()
+
+	config, err := hrm.lookupConfig(path)
+	if err != nil {
+		return "", false
+	}
+	return config.Version, true
+}
+
 // GetConfig 获取配置
 func (hrm *HotReloadManager) GetConfig(path string) (interface{}, bool) {
 	hrm.mutex.RLock()
@@ -404,16 +602,20 @@ func (hrm *HotReloadManager) Close() error {
 // YAMLConfigParser YAML配置解析器
 type YAMLConfigParser struct{}
 
-// Parse 解析YAML配置
+// Parse 解析YAML配置为map[string]interface{}
 func (p *YAMLConfigParser) Parse(data []byte) (interface{}, error) {
-	// 这里应该使用YAML库解析
-	// 简化实现，返回字符串
-	return string(data), nil
+	result := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
 // Validate 验证配置
 func (p *YAMLConfigParser) Validate(data interface{}) error {
-	// 这里实现配置验证逻辑
+	if config, ok := data.(map[string]interface{}); !ok || len(config) == 0 {
+		return fmt.Errorf("config is empty")
+	}
 	return nil
 }
 
@@ -422,11 +624,17 @@ type JSONConfigParser struct{}
 
 // Parse 解析JSON配置
 func (p *JSONConfigParser) Parse(data []byte) (interface{}, error) {
-	// 这里应该使用JSON库解析
-	return string(data), nil
+	var result interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
 // Validate 验证配置
 func (p *JSONConfigParser) Validate(data interface{}) error {
+	if data == nil {
+		return fmt.Errorf("config is empty")
+	}
 	return nil
 }
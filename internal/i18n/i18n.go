@@ -134,6 +134,7 @@ func (im *I18nManager) getDefaultTranslations(langCode string) []Translation {
 		{ID: "error.permission_denied", One: "Permission denied"},
 		{ID: "error.server_error", One: "Server error"},
 		{ID: "error.rate_limit_exceeded", One: "Rate limit exceeded"},
+		{ID: "error.update_required", One: "Client version is outdated, please update to continue"},
 
 		{ID: "success.login", One: "Login successful"},
 		{ID: "success.logout", One: "Logout successful"},
@@ -185,6 +186,7 @@ func (im *I18nManager) addChineseTranslations(translations []Translation) {
 		"error.permission_denied":   "权限不足",
 		"error.server_error":        "服务器错误",
 		"error.rate_limit_exceeded": "请求过于频繁",
+		"error.update_required":     "客户端版本过旧，请更新后继续",
 
 		"success.login":        "登录成功",
 		"success.logout":       "登出成功",
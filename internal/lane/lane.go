@@ -0,0 +1,209 @@
+package lane
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// 请求未执行的原因
+var (
+	ErrBusy    = errors.New("too many queued actions for user")
+	ErrTimeout = errors.New("timed out waiting for previous action of user")
+)
+
+// 默认设置
+const (
+	defaultDepth   = 4
+	defaultWait    = 5 * time.Second
+	defaultLockTTL = 30 * time.Second
+)
+
+// waitBuckets 排队时长分布的区间，单位秒
+var waitBuckets = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// Config 按玩家串行执行的配置，支持热更新
+type Config struct {
+	Methods []string      `yaml:"methods"`  // 需要串行执行的方法，格式为Service.Method，Service.*表示服务的所有方法
+	Depth   int           `yaml:"depth"`    // 每个玩家排队等待的最大请求数，不含执行中的请求，超过时直接拒绝，未配置时为4
+	Wait    time.Duration `yaml:"wait"`     // 排队的最长时间，超过时拒绝，未配置时为5秒
+	LockTTL time.Duration `yaml:"lock_ttl"` // 跨节点锁的最长持有时间，节点在执行中退出时锁在此时间后释放，未配置时为30秒
+}
+
+// Validate 检查配置
+func (c *Config) Validate() error {
+	for _, method := range c.Methods {
+		service, name, ok := strings.Cut(method, ".")
+		if !ok || service == "" || name == "" {
+			return fmt.Errorf("method must be Service.Method or Service.*: %s", method)
+		}
+	}
+	if c.Depth < 0 || c.Wait < 0 || c.LockTTL < 0 {
+		return fmt.Errorf("depth, wait and lock_ttl must not be negative")
+	}
+	return nil
+}
+
+// Locker 跨节点的玩家锁。同一玩家的请求可能被路由到不同节点，节点内排到后还需获取该锁
+type Locker interface {
+	// Lock 在deadline前获取玩家的锁，持有超过ttl后自动释放。等到deadline仍未获取时返回ErrTimeout，
+	// 其他错误表示锁不可用，请求不加锁执行
+	Lock(userID uint64, ttl time.Duration, deadline time.Time) (unlock func(), err error)
+}
+
+// lane 一个玩家的执行通道，slot被占用时后续请求排队
+type lane struct {
+	slot chan struct{}
+	refs int // 执行中和排队中的请求数，为0时删除通道
+}
+
+// Lanes 按玩家串行执行修改状态的请求，同一玩家的请求依次执行，避免并发请求造成冲突，如同时加入两个房间。
+// 节点内通过通道排队，设置Locker后再获取跨节点的玩家锁，玩家的请求被路由到不同节点时同样串行
+type Lanes struct {
+	mutex   sync.Mutex
+	methods map[string]bool
+	depth   int
+	wait    time.Duration
+	lockTTL time.Duration
+	locker  Locker
+	lanes   map[uint64]*lane
+
+	calls  *prometheus.CounterVec
+	waits  *prometheus.HistogramVec
+	active *prometheus.Desc
+}
+
+// NewLanes 创建按玩家串行执行的通道
+func NewLanes(config *Config) *Lanes {
+	l := &Lanes{
+		lanes: make(map[uint64]*lane),
+		calls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "lufy_user_lane_calls_total",
+			Help: "Number of serialized user actions by method and result (ok, busy, timeout)",
+		}, []string{"method", "result"}),
+		waits: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "lufy_user_lane_wait_seconds",
+			Help:    "Time serialized user actions waited for previous actions of the same user",
+			Buckets: waitBuckets,
+		}, []string{"method"}),
+		active: prometheus.NewDesc("lufy_user_lanes_active",
+			"Number of users with running or queued serialized actions", nil, nil),
+	}
+	l.Update(config)
+	return l
+}
+
+// Update 更新配置，排队中的请求按原配置等待
+func (l *Lanes) Update(config *Config) {
+	methods := make(map[string]bool, len(config.Methods))
+	for _, method := range config.Methods {
+		methods[method] = true
+	}
+	depth := config.Depth
+	if depth <= 0 {
+		depth = defaultDepth
+	}
+	wait := config.Wait
+	if wait <= 0 {
+		wait = defaultWait
+	}
+	lockTTL := config.LockTTL
+	if lockTTL <= 0 {
+		lockTTL = defaultLockTTL
+	}
+
+	l.mutex.Lock()
+	l.methods, l.depth, l.wait, l.lockTTL = methods, depth, wait, lockTTL
+	l.mutex.Unlock()
+}
+
+// SetLocker 设置跨节点的玩家锁
+func (l *Lanes) SetLocker(locker Locker) {
+	l.mutex.Lock()
+	l.locker = locker
+	l.mutex.Unlock()
+}
+
+// Serialized 方法是否需要按玩家串行执行
+func (l *Lanes) Serialized(service, method string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.methods[service+"."+method] || l.methods[service+".*"]
+}
+
+// Run 在玩家的通道中执行fn，前面的请求执行完后才开始。排队已满时返回ErrBusy，等待超时返回ErrTimeout，这两种情况fn都不会执行
+func (l *Lanes) Run(userID uint64, method string, fn func()) error {
+	l.mutex.Lock()
+	ln := l.lanes[userID]
+	if ln == nil {
+		ln = &lane{slot: make(chan struct{}, 1)}
+		l.lanes[userID] = ln
+	}
+	if ln.refs > l.depth {
+		l.mutex.Unlock()
+		l.calls.WithLabelValues(method, "busy").Inc()
+		return ErrBusy
+	}
+	ln.refs++
+	wait, lockTTL, locker := l.wait, l.lockTTL, l.locker
+	l.mutex.Unlock()
+	defer l.release(userID, ln)
+
+	start := time.Now()
+	timer := time.NewTimer(wait)
+	select {
+	case ln.slot <- struct{}{}:
+		timer.Stop()
+	case <-timer.C:
+		l.calls.WithLabelValues(method, "timeout").Inc()
+		return ErrTimeout
+	}
+	defer func() { <-ln.slot }()
+
+	if locker != nil {
+		unlock, err := locker.Lock(userID, lockTTL, start.Add(wait))
+		if err == ErrTimeout {
+			l.calls.WithLabelValues(method, "timeout").Inc()
+			return ErrTimeout
+		}
+		if err == nil {
+			defer unlock()
+		}
+	}
+
+	l.waits.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	l.calls.WithLabelValues(method, "ok").Inc()
+	fn()
+	return nil
+}
+
+// release 请求结束后释放通道，没有其他请求时删除
+func (l *Lanes) release(userID uint64, ln *lane) {
+	l.mutex.Lock()
+	ln.refs--
+	if ln.refs == 0 {
+		delete(l.lanes, userID)
+	}
+	l.mutex.Unlock()
+}
+
+// Describe 实现prometheus.Collector接口
+func (l *Lanes) Describe(ch chan<- *prometheus.Desc) {
+	l.calls.Describe(ch)
+	l.waits.Describe(ch)
+	ch <- l.active
+}
+
+// Collect 实现prometheus.Collector接口
+func (l *Lanes) Collect(ch chan<- prometheus.Metric) {
+	l.calls.Collect(ch)
+	l.waits.Collect(ch)
+	l.mutex.Lock()
+	active := len(l.lanes)
+	l.mutex.Unlock()
+	ch <- prometheus.MustNewConstMetric(l.active, prometheus.GaugeValue, float64(active))
+}
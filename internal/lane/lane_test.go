@@ -0,0 +1,89 @@
+package lane
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryLocker 模拟多个节点共享的玩家锁
+type memoryLocker struct {
+	mutex  sync.Mutex
+	locked map[uint64]bool
+}
+
+func (m *memoryLocker) Lock(userID uint64, ttl time.Duration, deadline time.Time) (func(), error) {
+	for {
+		m.mutex.Lock()
+		if !m.locked[userID] {
+			m.locked[userID] = true
+			m.mutex.Unlock()
+			return func() {
+				m.mutex.Lock()
+				delete(m.locked, userID)
+				m.mutex.Unlock()
+			}, nil
+		}
+		m.mutex.Unlock()
+		if time.Now().After(deadline) {
+			return nil, ErrTimeout
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestLanesSerializeAcrossNodes 两个节点的Lanes共享玩家锁，同一玩家的请求不会同时执行
+func TestLanesSerializeAcrossNodes(t *testing.T) {
+	locker := &memoryLocker{locked: make(map[uint64]bool)}
+	config := &Config{Methods: []string{"LobbyService.JoinRoom"}, Wait: time.Second}
+	nodes := []*Lanes{NewLanes(config), NewLanes(config)}
+	for _, node := range nodes {
+		node.SetLocker(locker)
+	}
+
+	var running, overlaps int32
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(node *Lanes) {
+			defer wg.Done()
+			err := node.Run(1001, "LobbyService.JoinRoom", func() {
+				mutex.Lock()
+				running++
+				if running > 1 {
+					overlaps++
+				}
+				mutex.Unlock()
+				time.Sleep(time.Millisecond)
+				mutex.Lock()
+				running--
+				mutex.Unlock()
+			})
+			if err != nil && !errors.Is(err, ErrBusy) {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}(nodes[i%len(nodes)])
+	}
+	wg.Wait()
+
+	if overlaps != 0 {
+		t.Fatalf("requests of the same user ran concurrently %d times", overlaps)
+	}
+}
+
+// TestLanesLockTimeout 其他节点持有玩家锁直到超时，请求不执行
+func TestLanesLockTimeout(t *testing.T) {
+	locker := &memoryLocker{locked: map[uint64]bool{1001: true}}
+	lanes := NewLanes(&Config{Wait: 20 * time.Millisecond})
+	lanes.SetLocker(locker)
+
+	ran := false
+	if err := lanes.Run(1001, "LobbyService.JoinRoom", func() { ran = true }); err != ErrTimeout {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+	if ran {
+		t.Fatal("action ran without holding the user lock")
+	}
+}
@@ -20,6 +20,7 @@ type Logger struct {
 	*zap.Logger
 	sugar  *zap.SugaredLogger
 	fields []zap.Field
+	level  zap.AtomicLevel // 运行时可调整的日志级别，WithField等派生的记录器共用
 	mutex  sync.RWMutex
 }
 
@@ -48,7 +49,7 @@ type SamplingConfig struct {
 // NewLogger 创建新的日志记录器
 func NewLogger(config *LogConfig) *Logger {
 	// 解析日志级别
-	level := parseLogLevel(config.Level)
+	level := zap.NewAtomicLevelAt(parseLogLevel(config.Level))
 
 	// 创建编码器配置
 	encoderConfig := getEncoderConfig(config.Development)
@@ -77,6 +78,7 @@ func NewLogger(config *LogConfig) *Logger {
 		Logger: zapLogger,
 		sugar:  zapLogger.Sugar(),
 		fields: make([]zap.Field, 0),
+		level:  level,
 	}
 
 	return logger
@@ -198,6 +200,7 @@ func (l *Logger) WithField(key string, value interface{}) *Logger {
 		Logger: l.Logger,
 		sugar:  l.sugar,
 		fields: newFields,
+		level:  l.level,
 	}
 }
 
@@ -219,6 +222,7 @@ func (l *Logger) WithFields(fields map[string]interface{}) *Logger {
 		Logger: l.Logger,
 		sugar:  l.sugar,
 		fields: newFields,
+		level:  l.level,
 	}
 }
 
@@ -671,10 +675,20 @@ func Close() error {
 	return nil
 }
 
-// SetGlobalLevel 设置全局日志级别
-func SetGlobalLevel(level string) {
-	// 这需要重新创建logger，zap不支持动态调整级别
-	// 在生产环境中，建议使用配置文件或环境变量
+// SetGlobalLevel 设置全局日志级别，立即对全局日志记录器及其派生的记录器生效
+func SetGlobalLevel(level string) error {
+	switch level {
+	case "debug", "info", "warn", "warning", "error":
+	default:
+		return fmt.Errorf("invalid log level: %s", level)
+	}
+	GetGlobalLogger().level.SetLevel(parseLogLevel(level))
+	return nil
+}
+
+// GlobalLevel 当前的全局日志级别
+func GlobalLevel() string {
+	return GetGlobalLogger().level.Level().String()
 }
 
 // IsDebugEnabled 检查是否启用调试级别
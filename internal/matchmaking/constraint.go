@@ -0,0 +1,221 @@
+package matchmaking
+
+import (
+	"math"
+	"time"
+)
+
+// ConstraintConfig 匹配条件设置，阈值从Base开始，等待超过After后每隔Every放宽Step，最多放宽Max级
+type ConstraintConfig struct {
+	Disabled bool          `yaml:"disabled"`
+	Base     int32         `yaml:"base"`
+	Step     int32         `yaml:"step"`
+	After    time.Duration `yaml:"after"`
+	Every    time.Duration `yaml:"every"`
+	Max      int           `yaml:"max"` // 0为不放宽
+}
+
+// Level 等待wait后的放宽级别
+func (c ConstraintConfig) Level(wait time.Duration) int {
+	if c.Max <= 0 || wait < c.After {
+		return 0
+	}
+	if c.Every <= 0 {
+		return c.Max
+	}
+	level := int((wait-c.After)/c.Every) + 1
+	if level > c.Max {
+		level = c.Max
+	}
+	return level
+}
+
+// Threshold 放宽level级后的阈值
+func (c ConstraintConfig) Threshold(level int) int32 {
+	return c.Base + c.Step*int32(level)
+}
+
+// CheckFunc 检查候选票据能否加入已选的票据，level为当前的放宽级别
+type CheckFunc func(group []*Ticket, candidate *Ticket, level int) bool
+
+// Constraint 可插拔的匹配条件，每个条件按自己的设置随等待时间放宽
+type Constraint struct {
+	Name   string
+	Config ConstraintConfig
+	Check  CheckFunc
+}
+
+// RatingConstraint 组内评分最高和最低的票据相差不超过阈值
+func RatingConstraint(config ConstraintConfig) Constraint {
+	return Constraint{
+		Name:   "rating",
+		Config: config,
+		Check: func(group []*Ticket, candidate *Ticket, level int) bool {
+			low, high := candidate.Rating(), candidate.Rating()
+			for _, ticket := range group {
+				low = math.Min(low, ticket.Rating())
+				high = math.Max(high, ticket.Rating())
+			}
+			return high-low <= float64(config.Threshold(level))
+		},
+	}
+}
+
+// RegionConstraint 未放宽时只匹配同一区域的玩家，放宽一级后允许跨区域，区域未知的玩家不限制
+func RegionConstraint(config ConstraintConfig) Constraint {
+	return Constraint{
+		Name:   "region",
+		Config: config,
+		Check: func(group []*Ticket, candidate *Ticket, level int) bool {
+			if level > 0 {
+				return true
+			}
+			region := ""
+			for _, ticket := range withCandidate(group, candidate) {
+				for _, player := range ticket.Players {
+					if player.Region == "" {
+						continue
+					}
+					if region == "" {
+						region = player.Region
+					} else if player.Region != region {
+						return false
+					}
+				}
+			}
+			return true
+		},
+	}
+}
+
+// LatencyConstraint 至少有一个游戏节点，所有上报了延迟的玩家到该节点的延迟都不超过阈值(毫秒)
+func LatencyConstraint(config ConstraintConfig) Constraint {
+	return Constraint{
+		Name:   "latency",
+		Config: config,
+		Check: func(group []*Ticket, candidate *Ticket, level int) bool {
+			tickets := withCandidate(group, candidate)
+			node, worst := BestNode(tickets)
+			if node == "" {
+				return !measured(tickets)
+			}
+			return worst <= config.Threshold(level)
+		},
+	}
+}
+
+// BlockConstraint 组内的玩家之间没有屏蔽关系，该条件不随等待时间放宽
+func BlockConstraint() Constraint {
+	return Constraint{
+		Name: "block",
+		Check: func(group []*Ticket, candidate *Ticket, level int) bool {
+			for _, ticket := range group {
+				for _, a := range ticket.Players {
+					for _, b := range candidate.Players {
+						if a.Blocked[b.UserID] || b.Blocked[a.UserID] {
+							return false
+						}
+					}
+				}
+			}
+			return true
+		},
+	}
+}
+
+// PartyConstraint 组队和单人的票据分开匹配，组内队伍人数相差不超过阈值
+func PartyConstraint(config ConstraintConfig) Constraint {
+	return Constraint{
+		Name:   "party",
+		Config: config,
+		Check: func(group []*Ticket, candidate *Ticket, level int) bool {
+			size := int32(len(candidate.Players))
+			for _, ticket := range group {
+				diff := int32(len(ticket.Players)) - size
+				if diff < 0 {
+					diff = -diff
+				}
+				if diff > config.Threshold(level) {
+					return false
+				}
+			}
+			return true
+		},
+	}
+}
+
+// ShadowConstraint 未放宽时影子封禁的玩家只与其他影子封禁的玩家匹配，放宽一级后允许混合。
+// 队伍中有影子封禁的成员时整个队伍视为影子封禁
+func ShadowConstraint(config ConstraintConfig) Constraint {
+	return Constraint{
+		Name:   "shadow",
+		Config: config,
+		Check: func(group []*Ticket, candidate *Ticket, level int) bool {
+			if level > 0 {
+				return true
+			}
+			for _, ticket := range group {
+				if ticket.Shadow() != candidate.Shadow() {
+					return false
+				}
+			}
+			return true
+		},
+	}
+}
+
+// withCandidate 返回包含候选票据的新切片，不修改group
+func withCandidate(group []*Ticket, candidate *Ticket) []*Ticket {
+	tickets := make([]*Ticket, 0, len(group)+1)
+	return append(append(tickets, group...), candidate)
+}
+
+// measured 是否有玩家上报了到游戏节点的延迟
+func measured(tickets []*Ticket) bool {
+	for _, ticket := range tickets {
+		for _, player := range ticket.Players {
+			if len(player.Latency) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// BestNode 选择所有上报了延迟的玩家都测量过、最大延迟最低的游戏节点，没有共同节点时返回空
+func BestNode(tickets []*Ticket) (string, int32) {
+	var candidates map[string]int32
+	for _, ticket := range tickets {
+		for _, player := range ticket.Players {
+			if len(player.Latency) == 0 {
+				continue
+			}
+			if candidates == nil {
+				candidates = make(map[string]int32, len(player.Latency))
+				for node, latency := range player.Latency {
+					candidates[node] = latency
+				}
+				continue
+			}
+			for node, worst := range candidates {
+				latency, ok := player.Latency[node]
+				if !ok {
+					delete(candidates, node)
+				} else if latency > worst {
+					candidates[node] = latency
+				}
+			}
+		}
+	}
+
+	best, bestLatency := "", int32(math.MaxInt32)
+	for node, worst := range candidates {
+		if worst < bestLatency || (worst == bestLatency && node < best) {
+			best, bestLatency = node, worst
+		}
+	}
+	if best == "" {
+		return "", 0
+	}
+	return best, bestLatency
+}
@@ -0,0 +1,367 @@
+package matchmaking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// 匹配队列的错误
+var (
+	ErrQueued   = errors.New("player is already in matchmaking queue")
+	ErrTooLarge = errors.New("party is larger than the match size")
+	ErrInvalid  = errors.New("invalid matchmaking ticket")
+)
+
+// 匹配默认设置
+const (
+	defaultInterval = time.Second
+	defaultTimeout  = 2 * time.Minute
+	defaultPlayers  = 2
+)
+
+// Config 匹配设置
+type Config struct {
+	Interval time.Duration   `yaml:"interval"` // 匹配间隔，未配置时为1秒
+	Timeout  time.Duration   `yaml:"timeout"`  // 超过该时间未匹配到时移出队列，未配置时为2分钟
+	Players  map[int32]int32 `yaml:"players"`  // 各游戏类型每局的人数，未配置的类型为2人
+
+	Rating  ConstraintConfig `yaml:"rating"`  // 评分差距
+	Region  ConstraintConfig `yaml:"region"`  // 同区域匹配，放宽后允许跨区域
+	Latency ConstraintConfig `yaml:"latency"` // 到游戏节点的延迟上限(毫秒)
+	Party   ConstraintConfig `yaml:"party"`   // 队伍人数差距
+	Shadow  ConstraintConfig `yaml:"shadow"`  // 影子封禁的玩家与其他玩家分开匹配，放宽后允许混合
+}
+
+// withDefaults 补齐未配置的设置
+func (c Config) withDefaults() Config {
+	if c.Interval <= 0 {
+		c.Interval = defaultInterval
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaultTimeout
+	}
+	return c
+}
+
+// Player 排队的玩家
+type Player struct {
+	UserID  uint64
+	Rating  float64
+	Region  string
+	Latency map[string]int32 // 客户端测量的到各游戏节点的延迟(毫秒)
+	Blocked map[uint64]bool  // 该玩家屏蔽的用户
+	Shadow  bool             // 被影子封禁
+}
+
+// Ticket 匹配票据，单人或整个队伍作为一个票据排队，匹配时不会被拆开
+type Ticket struct {
+	ID         string
+	PartyID    uint64 // 队伍票据的队伍ID，单人为0
+	GameType   int32
+	WorldID    uint32
+	Players    []*Player
+	Priority   time.Duration // 排队优先，按多等待了该时长排序，不影响条件放宽和超时
+	EnqueuedAt time.Time
+}
+
+// Rating 票据中玩家的平均评分
+func (t *Ticket) Rating() float64 {
+	if len(t.Players) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, player := range t.Players {
+		total += player.Rating
+	}
+	return total / float64(len(t.Players))
+}
+
+// Shadow 票据中是否有被影子封禁的玩家
+func (t *Ticket) Shadow() bool {
+	for _, player := range t.Players {
+		if player.Shadow {
+			return true
+		}
+	}
+	return false
+}
+
+// Match 匹配结果
+type Match struct {
+	ID       string
+	GameType int32
+	WorldID  uint32
+	Tickets  []*Ticket
+	Node     string // 延迟最低的游戏节点，玩家没有上报延迟时为空
+}
+
+// UserIDs 匹配到的所有玩家
+func (m *Match) UserIDs() []uint64 {
+	var userIDs []uint64
+	for _, ticket := range m.Tickets {
+		for _, player := range ticket.Players {
+			userIDs = append(userIDs, player.UserID)
+		}
+	}
+	return userIDs
+}
+
+// MatchFunc 处理匹配结果，返回错误时票据重新回到队列
+type MatchFunc func(match *Match) error
+
+// Matchmaker 匹配队列，按游戏类型和世界分组，每轮从等待最久的票据开始组局。
+// 队列保存在当前大厅节点的内存中
+type Matchmaker struct {
+	config      Config
+	constraints []Constraint
+	onMatch     MatchFunc
+
+	tickets map[string]*Ticket
+	users   map[uint64]string // 用户ID到票据ID
+	nextID  uint64
+	mutex   sync.Mutex
+}
+
+// NewMatchmaker 创建匹配队列，按配置注册内置的匹配条件
+func NewMatchmaker(config *Config, onMatch MatchFunc) *Matchmaker {
+	cfg := config.withDefaults()
+	m := &Matchmaker{
+		config:  cfg,
+		onMatch: onMatch,
+		tickets: make(map[string]*Ticket),
+		users:   make(map[uint64]string),
+	}
+
+	m.Use(BlockConstraint())
+	for _, constraint := range []Constraint{
+		RatingConstraint(cfg.Rating),
+		RegionConstraint(cfg.Region),
+		LatencyConstraint(cfg.Latency),
+		PartyConstraint(cfg.Party),
+		ShadowConstraint(cfg.Shadow),
+	} {
+		if !constraint.Config.Disabled {
+			m.Use(constraint)
+		}
+	}
+	return m
+}
+
+// Use 注册匹配条件，应在Run之前调用
+func (m *Matchmaker) Use(constraint Constraint) {
+	m.constraints = append(m.constraints, constraint)
+}
+
+// matchSize 游戏类型每局的人数
+func (m *Matchmaker) matchSize(gameType int32) int {
+	if players := m.config.Players[gameType]; players > 0 {
+		return int(players)
+	}
+	return defaultPlayers
+}
+
+// Enqueue 票据加入队列，票据中的玩家不能已经在队列中
+func (m *Matchmaker) Enqueue(ticket *Ticket) error {
+	if len(ticket.Players) == 0 {
+		return fmt.Errorf("%w: no players", ErrInvalid)
+	}
+	if len(ticket.Players) > m.matchSize(ticket.GameType) {
+		return ErrTooLarge
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, player := range ticket.Players {
+		if _, queued := m.users[player.UserID]; queued {
+			return ErrQueued
+		}
+	}
+
+	m.nextID++
+	ticket.ID = fmt.Sprintf("%d-%d", time.Now().UnixNano(), m.nextID)
+	ticket.EnqueuedAt = time.Now()
+	m.add(ticket)
+	return nil
+}
+
+// add 把票据放入队列，调用方持有锁
+func (m *Matchmaker) add(ticket *Ticket) {
+	m.tickets[ticket.ID] = ticket
+	for _, player := range ticket.Players {
+		m.users[player.UserID] = ticket.ID
+	}
+}
+
+// remove 把票据移出队列，调用方持有锁
+func (m *Matchmaker) remove(ticket *Ticket) {
+	delete(m.tickets, ticket.ID)
+	for _, player := range ticket.Players {
+		delete(m.users, player.UserID)
+	}
+}
+
+// Cancel 取消玩家所在的票据，队伍中任何成员取消时整个队伍离开队列
+func (m *Matchmaker) Cancel(userID uint64) (*Ticket, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	ticket, ok := m.tickets[m.users[userID]]
+	if !ok {
+		return nil, false
+	}
+	m.remove(ticket)
+	return ticket, true
+}
+
+// Queued 获取玩家所在的票据
+func (m *Matchmaker) Queued(userID uint64) (*Ticket, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	ticket, ok := m.tickets[m.users[userID]]
+	return ticket, ok
+}
+
+// Run 定期组局，直到ctx结束
+func (m *Matchmaker) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.Tick(time.Now())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Tick 移出超时的票据并组局，处理失败的匹配中的票据回到队列
+func (m *Matchmaker) Tick(now time.Time) []*Match {
+	m.mutex.Lock()
+	var expired []*Ticket
+	buckets := make(map[string][]*Ticket)
+	for _, ticket := range m.tickets {
+		if now.Sub(ticket.EnqueuedAt) > m.config.Timeout {
+			expired = append(expired, ticket)
+			continue
+		}
+		key := fmt.Sprintf("%d:%d", ticket.WorldID, ticket.GameType)
+		buckets[key] = append(buckets[key], ticket)
+	}
+	for _, ticket := range expired {
+		m.remove(ticket)
+	}
+
+	var matches []*Match
+	for _, tickets := range buckets {
+		for _, match := range m.form(tickets, now) {
+			for _, ticket := range match.Tickets {
+				m.remove(ticket)
+			}
+			matches = append(matches, match)
+		}
+	}
+	m.mutex.Unlock()
+
+	for _, ticket := range expired {
+		logger.Debug(fmt.Sprintf("Matchmaking ticket %s timed out after %v", ticket.ID, now.Sub(ticket.EnqueuedAt)))
+	}
+
+	// 处理匹配结果时不持有锁
+	var created []*Match
+	for _, match := range matches {
+		if m.onMatch != nil {
+			if err := m.onMatch(match); err != nil {
+				logger.Error(fmt.Sprintf("Failed to create match %s: %v", match.ID, err))
+				m.requeue(match.Tickets)
+				continue
+			}
+		}
+		created = append(created, match)
+	}
+	return created
+}
+
+// requeue 处理失败的票据保留原排队时间回到队列，已经重新排队的玩家所在的票据丢弃
+func (m *Matchmaker) requeue(tickets []*Ticket) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+next:
+	for _, ticket := range tickets {
+		for _, player := range ticket.Players {
+			if _, queued := m.users[player.UserID]; queued {
+				continue next
+			}
+		}
+		m.add(ticket)
+	}
+}
+
+// form 从等待最久的票据开始，依次加入满足所有条件的票据直到人数凑齐，有优先时长的票据按更早入队排序。
+// 各条件的放宽级别按组内第一个票据的实际等待时间计算
+func (m *Matchmaker) form(tickets []*Ticket, now time.Time) []*Match {
+	sort.Slice(tickets, func(i, j int) bool {
+		return tickets[i].EnqueuedAt.Add(-tickets[i].Priority).Before(tickets[j].EnqueuedAt.Add(-tickets[j].Priority))
+	})
+
+	used := make(map[string]bool)
+	var matches []*Match
+	for i, anchor := range tickets {
+		if used[anchor.ID] {
+			continue
+		}
+		size := m.matchSize(anchor.GameType)
+		wait := now.Sub(anchor.EnqueuedAt)
+
+		group := []*Ticket{anchor}
+		players := len(anchor.Players)
+		for _, candidate := range tickets[i+1:] {
+			if players == size {
+				break
+			}
+			if used[candidate.ID] || players+len(candidate.Players) > size {
+				continue
+			}
+			if m.allows(group, candidate, wait) {
+				group = append(group, candidate)
+				players += len(candidate.Players)
+			}
+		}
+		if players < size {
+			continue
+		}
+
+		for _, ticket := range group {
+			used[ticket.ID] = true
+		}
+		node, _ := BestNode(group)
+		matches = append(matches, &Match{
+			ID:       anchor.ID,
+			GameType: anchor.GameType,
+			WorldID:  anchor.WorldID,
+			Tickets:  group,
+			Node:     node,
+		})
+	}
+	return matches
+}
+
+// allows 候选票据是否满足所有匹配条件
+func (m *Matchmaker) allows(group []*Ticket, candidate *Ticket, wait time.Duration) bool {
+	for _, constraint := range m.constraints {
+		if !constraint.Check(group, candidate, constraint.Config.Level(wait)) {
+			return false
+		}
+	}
+	return true
+}
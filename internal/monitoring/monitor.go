@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"runtime"
+	"strconv"
 	"sync"
 	"time"
 
@@ -16,6 +17,7 @@ import (
 	"github.com/shirou/gopsutil/v3/process"
 
 	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/version"
 )
 
 // MonitoringManager 监控管理器
@@ -29,10 +31,14 @@ type MonitoringManager struct {
 	cancel     context.CancelFunc
 	nodeID     string
 	nodeType   string
+	region     string
 }
 
 // MetricsCollector 指标收集器
 type MetricsCollector struct {
+	// 节点信息
+	nodeInfo *prometheus.GaugeVec
+
 	// 系统指标
 	cpuUsage    *prometheus.GaugeVec
 	memoryUsage *prometheus.GaugeVec
@@ -46,9 +52,19 @@ type MetricsCollector struct {
 	actorCount      *prometheus.GaugeVec
 	messageCount    *prometheus.CounterVec
 	errorCount      *prometheus.CounterVec
+	panicCount      *prometheus.CounterVec
 	requestDuration *prometheus.SummaryVec
 	dbConnections   *prometheus.GaugeVec
 
+	// 房间事件历史指标
+	roomEvents        *prometheus.GaugeVec
+	roomEventBytes    *prometheus.GaugeVec
+	roomEventsDropped *prometheus.GaugeVec
+
+	// 实时玩法帧指标
+	tickDuration *prometheus.SummaryVec
+	tickOverruns *prometheus.CounterVec
+
 	// 自定义指标
 	customMetrics map[string]prometheus.Metric
 	mutex         sync.RWMutex
@@ -156,6 +172,14 @@ func NewMonitoringManager(nodeID, nodeType string, port int) (*MonitoringManager
 func NewMetricsCollector(nodeID, nodeType string) (*MetricsCollector, error) {
 
 	return &MetricsCollector{
+		nodeInfo: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "lufy_node_info",
+				Help: "Node information labelled with region",
+			},
+			[]string{"node_id", "node_type", "region"},
+		),
+
 		cpuUsage: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Name: "lufy_cpu_usage_percent",
@@ -236,6 +260,14 @@ func NewMetricsCollector(nodeID, nodeType string) (*MetricsCollector, error) {
 			[]string{"node_id", "node_type", "error_type"},
 		),
 
+		panicCount: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "lufy_panics_total",
+				Help: "Total number of recovered panics",
+			},
+			[]string{"node_id", "node_type", "source"},
+		),
+
 		requestDuration: prometheus.NewSummaryVec(
 			prometheus.SummaryOpts{
 				Name: "lufy_request_duration_seconds",
@@ -244,12 +276,53 @@ func NewMetricsCollector(nodeID, nodeType string) (*MetricsCollector, error) {
 			[]string{"node_id", "node_type", "method", "endpoint"},
 		),
 
+		roomEvents: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "lufy_room_events",
+				Help: "Number of events held in memory per room",
+			},
+			[]string{"node_id", "node_type", "room_id"},
+		),
+
+		roomEventBytes: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "lufy_room_event_history_bytes",
+				Help: "Estimated memory used by the event history per room",
+			},
+			[]string{"node_id", "node_type", "room_id"},
+		),
+
+		roomEventsDropped: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "lufy_room_events_dropped",
+				Help: "Number of events overwritten before being flushed to the replay store per room",
+			},
+			[]string{"node_id", "node_type", "room_id"},
+		),
+
+		tickDuration: prometheus.NewSummaryVec(
+			prometheus.SummaryOpts{
+				Name: "lufy_room_tick_duration_seconds",
+				Help: "Duration of fixed-tick room updates in seconds",
+			},
+			[]string{"node_id", "node_type", "game_type"},
+		),
+
+		tickOverruns: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "lufy_room_tick_overruns_total",
+				Help: "Total number of room ticks that took longer than the tick interval",
+			},
+			[]string{"node_id", "node_type", "game_type"},
+		),
+
 		customMetrics: make(map[string]prometheus.Metric),
 	}, nil
 }
 
 // Describe 实现prometheus.Collector接口
 func (mc *MetricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	mc.nodeInfo.Describe(ch)
 	mc.cpuUsage.Describe(ch)
 	mc.memoryUsage.Describe(ch)
 	mc.goroutines.Describe(ch)
@@ -260,11 +333,18 @@ func (mc *MetricsCollector) Describe(ch chan<- *prometheus.Desc) {
 	mc.actorCount.Describe(ch)
 	mc.messageCount.Describe(ch)
 	mc.errorCount.Describe(ch)
+	mc.panicCount.Describe(ch)
 	mc.requestDuration.Describe(ch)
+	mc.roomEvents.Describe(ch)
+	mc.roomEventBytes.Describe(ch)
+	mc.roomEventsDropped.Describe(ch)
+	mc.tickDuration.Describe(ch)
+	mc.tickOverruns.Describe(ch)
 }
 
 // Collect 实现prometheus.Collector接口
 func (mc *MetricsCollector) Collect(ch chan<- prometheus.Metric) {
+	mc.nodeInfo.Collect(ch)
 	mc.cpuUsage.Collect(ch)
 	mc.memoryUsage.Collect(ch)
 	mc.goroutines.Collect(ch)
@@ -275,7 +355,13 @@ func (mc *MetricsCollector) Collect(ch chan<- prometheus.Metric) {
 	mc.actorCount.Collect(ch)
 	mc.messageCount.Collect(ch)
 	mc.errorCount.Collect(ch)
+	mc.panicCount.Collect(ch)
 	mc.requestDuration.Collect(ch)
+	mc.roomEvents.Collect(ch)
+	mc.roomEventBytes.Collect(ch)
+	mc.roomEventsDropped.Collect(ch)
+	mc.tickDuration.Collect(ch)
+	mc.tickOverruns.Collect(ch)
 
 	// 收集自定义指标
 	mc.mutex.RLock()
@@ -385,6 +471,7 @@ func (mm *MonitoringManager) healthCheck(c *gin.Context) {
 		"status":    "healthy",
 		"node_id":   mm.nodeID,
 		"node_type": mm.nodeType,
+		"build":     version.Get(),
 		"timestamp": time.Now().Unix(),
 	})
 }
@@ -451,6 +538,13 @@ func (mm *MonitoringManager) getSystemInfo(c *gin.Context) {
 	c.JSON(http.StatusOK, systemInfo)
 }
 
+// SetRegion 设置节点所在区域标签
+func (mm *MonitoringManager) SetRegion(region string) {
+	mm.metrics.nodeInfo.Reset()
+	mm.region = region
+	mm.metrics.nodeInfo.WithLabelValues(mm.nodeID, mm.nodeType, region).Set(1)
+}
+
 // RecordMessage 记录消息指标
 func (mm *MonitoringManager) RecordMessage(messageType string) {
 	mm.metrics.messageCount.WithLabelValues(mm.nodeID, mm.nodeType, messageType).Inc()
@@ -461,11 +555,44 @@ func (mm *MonitoringManager) RecordError(errorType string) {
 	mm.metrics.errorCount.WithLabelValues(mm.nodeID, mm.nodeType, errorType).Inc()
 }
 
+// RecordPanic 记录恢复的panic
+func (mm *MonitoringManager) RecordPanic(source string) {
+	mm.metrics.panicCount.WithLabelValues(mm.nodeID, mm.nodeType, source).Inc()
+}
+
 // RecordRequestDuration 记录请求时长
 func (mm *MonitoringManager) RecordRequestDuration(method, endpoint string, duration time.Duration) {
 	mm.metrics.requestDuration.WithLabelValues(mm.nodeID, mm.nodeType, method, endpoint).Observe(duration.Seconds())
 }
 
+// ResetRoomEventHistory 清空房间事件历史指标，用于移除已不存在的房间
+func (mm *MonitoringManager) ResetRoomEventHistory() {
+	mm.metrics.roomEvents.Reset()
+	mm.metrics.roomEventBytes.Reset()
+	mm.metrics.roomEventsDropped.Reset()
+}
+
+// SetRoomEventHistory 设置房间事件历史指标
+func (mm *MonitoringManager) SetRoomEventHistory(roomID uint64, events int, memoryBytes int64, dropped uint64) {
+	room := strconv.FormatUint(roomID, 10)
+	mm.metrics.roomEvents.WithLabelValues(mm.nodeID, mm.nodeType, room).Set(float64(events))
+	mm.metrics.roomEventBytes.WithLabelValues(mm.nodeID, mm.nodeType, room).Set(float64(memoryBytes))
+	mm.metrics.roomEventsDropped.WithLabelValues(mm.nodeID, mm.nodeType, room).Set(float64(dropped))
+}
+
+// RecordTick 记录房间帧更新耗时
+func (mm *MonitoringManager) RecordTick(gameType string, duration, interval time.Duration) {
+	mm.metrics.tickDuration.WithLabelValues(mm.nodeID, mm.nodeType, gameType).Observe(duration.Seconds())
+	if duration > interval {
+		mm.metrics.tickOverruns.WithLabelValues(mm.nodeID, mm.nodeType, gameType).Inc()
+	}
+}
+
+// Register 注册其他模块的指标，和节点指标一起导出
+func (mm *MonitoringManager) Register(collector prometheus.Collector) error {
+	return mm.registry.Register(collector)
+}
+
 // SetConnectionCount 设置连接数
 func (mm *MonitoringManager) SetConnectionCount(count int) {
 	mm.metrics.connectionCount.WithLabelValues(mm.nodeID, mm.nodeType).Set(float64(count))
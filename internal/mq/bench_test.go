@@ -0,0 +1,61 @@
+package mq
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+func init() {
+	logger.InitGlobalLogger(&logger.LogConfig{
+		Level:  "error",
+		Format: "console",
+		Output: "stderr",
+	})
+}
+
+// BenchmarkGameMessageDispatch 游戏事件的反序列化和处理器分发
+func BenchmarkGameMessageDispatch(b *testing.B) {
+	handler := NewGameMessageHandler()
+	handler.RegisterHandler(MSG_PLAYER_ACTION, func(msg *GameMessage) error {
+		return nil
+	})
+
+	data, err := json.Marshal(NewGameMessage(MSG_PLAYER_ACTION, 1001, 10001, map[string]interface{}{
+		"action_type": "play_card",
+		"card_id":     12,
+	}))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := handler.HandleMessage("game_events", "bench", data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkSystemMessageDispatch 系统消息的反序列化、目标节点过滤和分发
+func BenchmarkSystemMessageDispatch(b *testing.B) {
+	handler := NewSystemMessageHandler("bench1")
+	handler.RegisterHandler(SYS_CMD_UPDATE_LOAD, func(msg *SystemMessage) error {
+		return nil
+	})
+
+	data, err := json.Marshal(NewSystemMessage("unicast", "bench1", SYS_CMD_UPDATE_LOAD, nil))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := handler.HandleMessage("system_messages", "bench1", data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
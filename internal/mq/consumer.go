@@ -0,0 +1,341 @@
+package mq
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nsqio/go-nsq"
+
+	"github.com/phuhao00/lufy/internal/eventbus"
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// 订阅默认设置
+const (
+	defaultConcurrency = 1
+	throttleInterval   = 5 * time.Second // 检查处理耗时并调整in-flight上限的间隔
+	statsSmoothing     = 0.2             // 处理耗时和延迟滑动平均中新样本的权重
+)
+
+// SubscriptionConfig 单个订阅的处理并发和背压设置，未配置的项使用nsq下的全局设置
+type SubscriptionConfig struct {
+	Concurrency int            `yaml:"concurrency"`   // 处理消息的协程数，大于1时同一订阅的消息不再按顺序处理
+	MaxInFlight int            `yaml:"max_in_flight"` // 已投递未确认的消息上限
+	Throttle    ThrottleConfig `yaml:"throttle"`
+}
+
+// ThrottleConfig 下游变慢时的动态节流。处理耗时主要是MongoDB写入，
+// 平均耗时超过阈值时in-flight上限减半，回落到阈值一半以下或没有消息时逐步恢复
+type ThrottleConfig struct {
+	LatencyThreshold time.Duration `yaml:"latency_threshold"` // 0为不节流
+	MinInFlight      int           `yaml:"min_in_flight"`     // 节流时in-flight上限的下限，默认1
+}
+
+// ConsumerStats 订阅的处理统计
+type ConsumerStats struct {
+	Topic          string        `json:"topic"`
+	Channel        string        `json:"channel"`
+	Concurrency    int           `json:"concurrency"`
+	MaxInFlight    int           `json:"max_in_flight"`   // 当前的in-flight上限，节流时低于配置值
+	ConfiguredMax  int           `json:"configured_max"`  // 配置的in-flight上限
+	InFlight       int64         `json:"in_flight"`       // 正在处理的消息数
+	Processed      uint64        `json:"processed"`       // 已处理的消息数，含失败
+	Failed         uint64        `json:"failed"`          // 处理失败后重新入队的消息数
+	ProcessingTime time.Duration `json:"processing_time"` // 累计处理耗时
+	Latency        time.Duration `json:"latency"`         // 处理耗时的滑动平均
+	Lag            time.Duration `json:"lag"`             // 消息从发布到开始处理的滑动平均
+	Throttled      bool          `json:"throttled"`
+}
+
+// ConsumerStatsProvider 能提供订阅处理统计的事件总线，NSQManager实现了该接口
+type ConsumerStatsProvider interface {
+	ConsumerStats() []ConsumerStats
+}
+
+// ConcurrencyController 能在运行时调整订阅处理并发的事件总线，NSQManager实现了该接口
+type ConcurrencyController interface {
+	Concurrency(topic, channel string) (int, error)
+	SetConcurrency(topic, channel string, concurrency int) error
+}
+
+// subscriptionConfig 按主题/channel或主题查找订阅设置并补齐全局设置，主题可以带或不带命名空间
+func (c *NSQConfig) subscriptionConfig(topic, channel string) SubscriptionConfig {
+	var config SubscriptionConfig
+	for _, key := range []string{topic + "/" + channel, bareTopic(topic) + "/" + channel, topic, bareTopic(topic)} {
+		if subscription, exists := c.Subscriptions[key]; exists {
+			config = subscription
+			break
+		}
+	}
+
+	if config.Concurrency <= 0 {
+		config.Concurrency = c.Concurrency
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = defaultConcurrency
+	}
+	if config.MaxInFlight <= 0 {
+		config.MaxInFlight = c.MaxInFlight
+	}
+	// in-flight上限小于并发数时多出的协程不会拿到消息
+	if config.MaxInFlight < config.Concurrency {
+		config.MaxInFlight = config.Concurrency
+	}
+	if config.Throttle.MinInFlight <= 0 {
+		config.Throttle.MinInFlight = 1
+	}
+	return config
+}
+
+// consumerState 订阅的消费者和处理统计
+type consumerState struct {
+	topic    string
+	channel  string
+	config   SubscriptionConfig
+	consumer *nsq.Consumer
+
+	maxInFlight     int64
+	inFlight        int64
+	processed       uint64
+	failed          uint64
+	processingNanos int64
+
+	mutex         sync.Mutex
+	latency       float64 // 处理耗时的滑动平均(纳秒)
+	lag           float64 // 延迟的滑动平均(纳秒)
+	lastProcessed uint64  // 上次调整时的已处理数
+}
+
+// newConsumerState 创建订阅的消费者
+func newConsumerState(topic, channel string, config SubscriptionConfig, nsqConfig *nsq.Config, handler MessageHandler) (*consumerState, error) {
+	nsqConfig.MaxInFlight = config.MaxInFlight
+	consumer, err := nsq.NewConsumer(topic, channel, nsqConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consumer: %v", err)
+	}
+
+	cs := &consumerState{
+		topic:       topic,
+		channel:     channel,
+		config:      config,
+		consumer:    consumer,
+		maxInFlight: int64(config.MaxInFlight),
+	}
+	consumer.AddConcurrentHandlers(&messageHandlerWrapper{handler: handler, state: cs}, config.Concurrency)
+	return cs, nil
+}
+
+// record 记录一条消息的处理结果
+func (cs *consumerState) record(lag, duration time.Duration, err error) {
+	atomic.AddUint64(&cs.processed, 1)
+	if err != nil {
+		atomic.AddUint64(&cs.failed, 1)
+	}
+	atomic.AddInt64(&cs.processingNanos, int64(duration))
+
+	cs.mutex.Lock()
+	cs.latency = smooth(cs.latency, float64(duration))
+	cs.lag = smooth(cs.lag, float64(lag))
+	cs.mutex.Unlock()
+}
+
+// smooth 更新滑动平均，第一个样本直接作为平均值
+func smooth(average, sample float64) float64 {
+	if average == 0 {
+		return sample
+	}
+	return average + statsSmoothing*(sample-average)
+}
+
+// adjust 按平均处理耗时调整in-flight上限
+func (cs *consumerState) adjust() {
+	throttle := cs.config.Throttle
+	if throttle.LatencyThreshold <= 0 {
+		return
+	}
+
+	processed := atomic.LoadUint64(&cs.processed)
+	cs.mutex.Lock()
+	latency := time.Duration(cs.latency)
+	idle := processed == cs.lastProcessed
+	cs.lastProcessed = processed
+	cs.mutex.Unlock()
+
+	current := int(atomic.LoadInt64(&cs.maxInFlight))
+	configured := cs.config.MaxInFlight
+	next := current
+	switch {
+	case !idle && latency > throttle.LatencyThreshold:
+		next = max(current/2, throttle.MinInFlight)
+	case idle || latency < throttle.LatencyThreshold/2:
+		next = min(current+max(configured/10, 1), configured)
+	}
+	if next == current {
+		return
+	}
+
+	atomic.StoreInt64(&cs.maxInFlight, int64(next))
+	cs.consumer.ChangeMaxInFlight(next)
+	if next < current {
+		logger.Warn(fmt.Sprintf("Throttling %s/%s: average processing time %v exceeds %v, max in flight %d -> %d",
+			cs.topic, cs.channel, latency, throttle.LatencyThreshold, current, next))
+	} else {
+		logger.Info(fmt.Sprintf("Relaxing throttle on %s/%s: max in flight %d -> %d", cs.topic, cs.channel, current, next))
+	}
+}
+
+// stats 当前的处理统计
+func (cs *consumerState) stats() ConsumerStats {
+	cs.mutex.Lock()
+	latency, lag := cs.latency, cs.lag
+	cs.mutex.Unlock()
+
+	maxInFlight := int(atomic.LoadInt64(&cs.maxInFlight))
+	return ConsumerStats{
+		Topic:          cs.topic,
+		Channel:        cs.channel,
+		Concurrency:    cs.config.Concurrency,
+		MaxInFlight:    maxInFlight,
+		ConfiguredMax:  cs.config.MaxInFlight,
+		InFlight:       atomic.LoadInt64(&cs.inFlight),
+		Processed:      atomic.LoadUint64(&cs.processed),
+		Failed:         atomic.LoadUint64(&cs.failed),
+		ProcessingTime: time.Duration(atomic.LoadInt64(&cs.processingNanos)),
+		Latency:        time.Duration(latency),
+		Lag:            time.Duration(lag),
+		Throttled:      maxInFlight < cs.config.MaxInFlight,
+	}
+}
+
+// ConsumerStats 各订阅的处理统计，按主题和channel排序
+func (nm *NSQManager) ConsumerStats() []ConsumerStats {
+	nm.mutex.RLock()
+	stats := make([]ConsumerStats, 0, len(nm.consumers))
+	for _, state := range nm.consumers {
+		stats = append(stats, state.stats())
+	}
+	nm.mutex.RUnlock()
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Topic != stats[j].Topic {
+			return stats[i].Topic < stats[j].Topic
+		}
+		return stats[i].Channel < stats[j].Channel
+	})
+	return stats
+}
+
+// findConsumer 按主题和channel查找订阅，主题可以带或不带命名空间，需持有锁
+func (nm *NSQManager) findConsumer(topic, channel string) (string, *consumerState) {
+	for key, state := range nm.consumers {
+		if (state.topic == topic || bareTopic(state.topic) == topic) && state.channel == channel {
+			return key, state
+		}
+	}
+	return "", nil
+}
+
+// Concurrency 订阅当前的处理协程数
+func (nm *NSQManager) Concurrency(topic, channel string) (int, error) {
+	nm.mutex.RLock()
+	defer nm.mutex.RUnlock()
+
+	_, state := nm.findConsumer(topic, channel)
+	if state == nil {
+		return 0, fmt.Errorf("not subscribed to %s/%s", topic, channel)
+	}
+	return state.config.Concurrency, nil
+}
+
+// SetConcurrency 调整订阅的处理协程数。nsq消费者连接后不能再增加处理器，因此按新的并发数创建消费者，
+// 连接成功后停止原来的消费者，原消费者未确认的消息处理完后才返回，处理统计累计到新的消费者
+func (nm *NSQManager) SetConcurrency(topic, channel string, concurrency int) error {
+	if concurrency <= 0 {
+		return fmt.Errorf("concurrency must be positive")
+	}
+
+	nm.mutex.Lock()
+	defer nm.mutex.Unlock()
+
+	key, state := nm.findConsumer(topic, channel)
+	if state == nil {
+		return fmt.Errorf("not subscribed to %s/%s", topic, channel)
+	}
+	if state.config.Concurrency == concurrency {
+		return nil
+	}
+
+	config := state.config
+	config.Concurrency = concurrency
+	if config.MaxInFlight < concurrency {
+		config.MaxInFlight = concurrency
+	}
+	nsqConfig := nsq.NewConfig()
+	nsqConfig.MsgTimeout = nm.config.MessageTimeout
+	next, err := newConsumerState(state.topic, state.channel, config, nsqConfig, nm.handlers[key])
+	if err != nil {
+		return err
+	}
+	if err := nm.connectConsumer(next.consumer); err != nil {
+		next.consumer.Stop()
+		return err
+	}
+
+	state.consumer.Stop()
+	<-state.consumer.StopChan
+	atomic.AddUint64(&next.processed, atomic.LoadUint64(&state.processed))
+	atomic.AddUint64(&next.failed, atomic.LoadUint64(&state.failed))
+	atomic.AddInt64(&next.processingNanos, atomic.LoadInt64(&state.processingNanos))
+	nm.consumers[key] = next
+
+	logger.Info(fmt.Sprintf("Changed concurrency of %s/%s: %d -> %d, max in flight: %d",
+		state.topic, state.channel, state.config.Concurrency, concurrency, config.MaxInFlight))
+	return nil
+}
+
+// throttleLoop 定期按处理耗时调整各订阅的in-flight上限
+func (nm *NSQManager) throttleLoop() {
+	ticker := time.NewTicker(throttleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-nm.ctx.Done():
+			return
+		case <-ticker.C:
+			nm.mutex.RLock()
+			for _, state := range nm.consumers {
+				state.adjust()
+			}
+			nm.mutex.RUnlock()
+		}
+	}
+}
+
+// messageHandlerWrapper NSQ消息处理器包装器
+type messageHandlerWrapper struct {
+	handler eventbus.Handler
+	state   *consumerState
+}
+
+// HandleMessage 实现nsq.Handler接口
+func (mhw *messageHandlerWrapper) HandleMessage(message *nsq.Message) error {
+	start := time.Now()
+	lag := start.Sub(time.Unix(0, message.Timestamp))
+	if lag < 0 {
+		lag = 0
+	}
+
+	atomic.AddInt64(&mhw.state.inFlight, 1)
+	err := mhw.handler.HandleMessage(mhw.state.topic, mhw.state.channel, message.Body)
+	atomic.AddInt64(&mhw.state.inFlight, -1)
+
+	duration := time.Since(start)
+	mhw.state.record(lag, duration, err)
+	logger.Debug(fmt.Sprintf("Handled message from %s/%s in %v", mhw.state.topic, mhw.state.channel, duration))
+
+	return err
+}
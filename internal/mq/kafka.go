@@ -0,0 +1,355 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/phuhao00/lufy/internal/eventbus"
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// KafkaConfig Kafka配置
+type KafkaConfig struct {
+	Brokers     []string `yaml:"brokers"`      // 初始broker地址，其余broker从元数据获取
+	ClientID    string   `yaml:"client_id"`    // 客户端标识
+	GroupPrefix string   `yaml:"group_prefix"` // 消费组前缀，消费组为 前缀+订阅组.主题
+	Acks        string   `yaml:"acks"`         // 发送确认: none, leader(默认), all
+
+	DialTimeout       time.Duration `yaml:"dial_timeout"`
+	RequestTimeout    time.Duration `yaml:"request_timeout"`
+	BatchTimeout      time.Duration `yaml:"batch_timeout"`      // 生产者攒批的最长等待时间，同步发布的延迟上限
+	MetadataRefresh   time.Duration `yaml:"metadata_refresh"`   // 元数据刷新间隔
+	SessionTimeout    time.Duration `yaml:"session_timeout"`    // 消费组会话超时
+	RebalanceTimeout  time.Duration `yaml:"rebalance_timeout"`  // 重平衡时等待成员重新加入的时间
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval"` // 心跳间隔，需小于会话超时的1/3
+	CommitInterval    time.Duration `yaml:"commit_interval"`    // offset提交间隔
+	InitialOffset     string        `yaml:"initial_offset"`     // 没有已提交offset时的起始位置: newest, oldest
+	FetchMaxWait      time.Duration `yaml:"fetch_max_wait"`     // 拉取的长轮询等待时间
+	FetchMaxBytes     int32         `yaml:"fetch_max_bytes"`    // 单次拉取的最大字节数
+	MaxRetries        int           `yaml:"max_retries"`        // 发送失败和处理失败的重试次数
+}
+
+// KafkaBroker Kafka消息队列，基于kafka-go实现事件总线接口
+// 发布时按键分区(murmur2，与Java客户端一致)，无键消息随机分区；每个订阅组对应一个消费组，由消费组协调者分配分区并保存offset
+type KafkaBroker struct {
+	config    KafkaConfig
+	transport *kafka.Transport
+	writer    *kafka.Writer
+	consumers map[string]*kafkaConsumer
+	closed    bool
+	mutex     sync.Mutex
+}
+
+// KafkaBroker 实现事件总线接口
+var _ eventbus.KeyedBus = (*KafkaBroker)(nil)
+
+// NewKafkaBroker 创建Kafka消息队列，启动时获取一次元数据以确认broker可用
+func NewKafkaBroker(config *KafkaConfig) (*KafkaBroker, error) {
+	cfg := *config
+	if len(cfg.Brokers) == 0 {
+		return nil, fmt.Errorf("kafka brokers not configured")
+	}
+	if cfg.ClientID == "" {
+		cfg.ClientID = "lufy"
+	}
+	acks := kafka.RequireOne
+	switch cfg.Acks {
+	case "", "leader":
+		cfg.Acks = "leader"
+	case "none":
+		acks = kafka.RequireNone
+	case "all":
+		acks = kafka.RequireAll
+	default:
+		return nil, fmt.Errorf("invalid kafka acks %q", cfg.Acks)
+	}
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = 5 * time.Second
+	}
+	if cfg.RequestTimeout <= 0 {
+		cfg.RequestTimeout = 10 * time.Second
+	}
+	if cfg.BatchTimeout <= 0 {
+		cfg.BatchTimeout = 5 * time.Millisecond
+	}
+	if cfg.MetadataRefresh <= 0 {
+		cfg.MetadataRefresh = 5 * time.Minute
+	}
+	if cfg.SessionTimeout <= 0 {
+		cfg.SessionTimeout = 30 * time.Second
+	}
+	if cfg.RebalanceTimeout <= 0 {
+		cfg.RebalanceTimeout = 60 * time.Second
+	}
+	if cfg.HeartbeatInterval <= 0 {
+		cfg.HeartbeatInterval = 3 * time.Second
+	}
+	if cfg.CommitInterval <= 0 {
+		cfg.CommitInterval = 5 * time.Second
+	}
+	switch cfg.InitialOffset {
+	case "":
+		cfg.InitialOffset = "newest"
+	case "newest", "oldest":
+	default:
+		return nil, fmt.Errorf("invalid kafka initial offset %q", cfg.InitialOffset)
+	}
+	if cfg.FetchMaxWait <= 0 {
+		cfg.FetchMaxWait = 500 * time.Millisecond
+	}
+	if cfg.FetchMaxBytes <= 0 {
+		cfg.FetchMaxBytes = 1024 * 1024
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = 3
+	}
+
+	transport := &kafka.Transport{
+		ClientID:    cfg.ClientID,
+		DialTimeout: cfg.DialTimeout,
+		MetadataTTL: cfg.MetadataRefresh,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.RequestTimeout)
+	defer cancel()
+	client := &kafka.Client{Addr: kafka.TCP(cfg.Brokers...), Timeout: cfg.RequestTimeout, Transport: transport}
+	if _, err := client.Metadata(ctx, &kafka.MetadataRequest{}); err != nil {
+		transport.CloseIdleConnections()
+		return nil, fmt.Errorf("failed to initialize kafka: %v", err)
+	}
+
+	broker := &KafkaBroker{
+		config:    cfg,
+		transport: transport,
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(cfg.Brokers...),
+			Balancer:     kafka.Murmur2Balancer{},
+			MaxAttempts:  cfg.MaxRetries + 1,
+			BatchTimeout: cfg.BatchTimeout,
+			ReadTimeout:  cfg.RequestTimeout,
+			WriteTimeout: cfg.RequestTimeout,
+			RequiredAcks: acks,
+			Transport:    transport,
+			ErrorLogger:  kafka.LoggerFunc(logger.Errorf),
+		},
+		consumers: make(map[string]*kafkaConsumer),
+	}
+
+	logger.Infof("Kafka broker initialized: %s", strings.Join(cfg.Brokers, ","))
+	return broker, nil
+}
+
+// Publish 发布消息，随机选择分区
+func (kb *KafkaBroker) Publish(topic string, data []byte) error {
+	return kb.PublishWithKey(topic, nil, data)
+}
+
+// PublishWithKey 按键发布消息，同一键的消息写入同一分区并保持顺序
+func (kb *KafkaBroker) PublishWithKey(topic string, key, data []byte) error {
+	kb.mutex.Lock()
+	closed := kb.closed
+	kb.mutex.Unlock()
+	if closed {
+		return fmt.Errorf("kafka broker closed")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), kb.config.RequestTimeout)
+	defer cancel()
+
+	if err := kb.writer.WriteMessages(ctx, kafka.Message{Topic: topic, Key: key, Value: data}); err != nil {
+		return fmt.Errorf("failed to publish to %s: %v", topic, err)
+	}
+	return nil
+}
+
+// Subscribe 订阅主题，group对应NSQ的channel，同一订阅组的多个节点分摊分区
+func (kb *KafkaBroker) Subscribe(topic, group string, handler eventbus.Handler) error {
+	kb.mutex.Lock()
+	defer kb.mutex.Unlock()
+
+	if kb.closed {
+		return fmt.Errorf("kafka broker closed")
+	}
+
+	key := fmt.Sprintf("%s_%s", topic, group)
+	if _, exists := kb.consumers[key]; exists {
+		return fmt.Errorf("already subscribed to %s/%s", topic, group)
+	}
+
+	consumer := newKafkaConsumer(&kb.config, topic, group, handler)
+	kb.consumers[key] = consumer
+	go consumer.run()
+
+	logger.Infof("Subscribed to kafka topic: %s, group: %s", topic, consumer.groupID)
+	return nil
+}
+
+// Unsubscribe 取消订阅，提交offset并离开消费组
+func (kb *KafkaBroker) Unsubscribe(topic, group string) error {
+	kb.mutex.Lock()
+	key := fmt.Sprintf("%s_%s", topic, group)
+	consumer, exists := kb.consumers[key]
+	delete(kb.consumers, key)
+	kb.mutex.Unlock()
+
+	if !exists {
+		return fmt.Errorf("not subscribed to %s/%s", topic, group)
+	}
+
+	consumer.stop()
+	logger.Info(fmt.Sprintf("Unsubscribed from kafka topic: %s, group: %s", topic, group))
+	return nil
+}
+
+// Close 停止所有消费者，发送未完成的消息并关闭连接
+func (kb *KafkaBroker) Close() error {
+	kb.mutex.Lock()
+	if kb.closed {
+		kb.mutex.Unlock()
+		return nil
+	}
+	kb.closed = true
+	consumers := kb.consumers
+	kb.consumers = make(map[string]*kafkaConsumer)
+	kb.mutex.Unlock()
+
+	for _, consumer := range consumers {
+		consumer.stop()
+	}
+	if err := kb.writer.Close(); err != nil {
+		logger.Warnf("Failed to close kafka writer: %v", err)
+	}
+	kb.transport.CloseIdleConnections()
+
+	logger.Info("Kafka broker closed")
+	return nil
+}
+
+// kafkaConsumer 消费组成员，按分区顺序处理消息，处理完成后由reader定期提交offset
+type kafkaConsumer struct {
+	config  *KafkaConfig
+	topic   string
+	group   string
+	groupID string
+	handler eventbus.Handler
+	reader  *kafka.Reader
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// newKafkaConsumer 创建消费者，分区使用range分配
+func newKafkaConsumer(config *KafkaConfig, topic, group string, handler eventbus.Handler) *kafkaConsumer {
+	groupID := fmt.Sprintf("%s%s.%s", config.GroupPrefix, group, topic)
+
+	startOffset := kafka.LastOffset
+	if config.InitialOffset == "oldest" {
+		startOffset = kafka.FirstOffset
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:           config.Brokers,
+		GroupID:           groupID,
+		Topic:             topic,
+		Dialer:            &kafka.Dialer{ClientID: config.ClientID, Timeout: config.DialTimeout},
+		MaxBytes:          int(config.FetchMaxBytes),
+		MaxWait:           config.FetchMaxWait,
+		GroupBalancers:    []kafka.GroupBalancer{kafka.RangeGroupBalancer{}},
+		HeartbeatInterval: config.HeartbeatInterval,
+		CommitInterval:    config.CommitInterval,
+		SessionTimeout:    config.SessionTimeout,
+		RebalanceTimeout:  config.RebalanceTimeout,
+		StartOffset:       startOffset,
+		ErrorLogger:       kafka.LoggerFunc(logger.Warnf),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &kafkaConsumer{
+		config:  config,
+		topic:   topic,
+		group:   group,
+		groupID: groupID,
+		handler: handler,
+		reader:  reader,
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+}
+
+// run 循环拉取消息，拉取失败时退避重试
+func (kc *kafkaConsumer) run() {
+	defer close(kc.done)
+
+	backoff := time.Second
+	for kc.ctx.Err() == nil {
+		message, err := kc.reader.FetchMessage(kc.ctx)
+		if err != nil {
+			if kc.ctx.Err() != nil || errors.Is(err, io.EOF) {
+				return
+			}
+			logger.Warnf("Kafka consumer %s fetch failed: %v", kc.groupID, err)
+			kc.sleep(backoff)
+			if backoff < 30*time.Second {
+				backoff *= 2
+			}
+			continue
+		}
+		backoff = time.Second
+
+		kc.process(message)
+		if kc.ctx.Err() != nil {
+			return // 处理被中断的消息不提交，重新加入后再次消费
+		}
+
+		if err := kc.reader.CommitMessages(kc.ctx, message); err != nil && kc.ctx.Err() == nil {
+			logger.Warnf("Kafka consumer %s failed to commit offset %d/%d: %v", kc.groupID, message.Partition, message.Offset, err)
+		}
+	}
+}
+
+// stop 停止消费，提交已处理的offset并离开消费组
+func (kc *kafkaConsumer) stop() {
+	kc.cancel()
+	<-kc.done
+	if err := kc.reader.Close(); err != nil {
+		logger.Warnf("Kafka consumer %s failed to close: %v", kc.groupID, err)
+	}
+}
+
+// process 处理消息，失败时重试，超过次数后跳过
+func (kc *kafkaConsumer) process(message kafka.Message) {
+	for attempt := 0; ; attempt++ {
+		err := kc.handler.HandleMessage(kc.topic, kc.group, message.Value)
+		if err == nil {
+			return
+		}
+		if attempt >= kc.config.MaxRetries {
+			logger.Error(fmt.Sprintf("Kafka consumer %s dropped message %d/%d after %d retries: %v",
+				kc.groupID, message.Partition, message.Offset, attempt, err))
+			return
+		}
+		kc.sleep(time.Duration(attempt+1) * 100 * time.Millisecond)
+		if kc.ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// sleep 等待指定时间，消费者停止时提前返回
+func (kc *kafkaConsumer) sleep(d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-kc.ctx.Done():
+	}
+}
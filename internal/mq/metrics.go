@@ -0,0 +1,75 @@
+package mq
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ConsumerCollector 订阅处理指标，实现prometheus.Collector接口，抓取时读取各订阅的统计
+type ConsumerCollector struct {
+	source func() []ConsumerStats
+
+	processed   *prometheus.Desc
+	failed      *prometheus.Desc
+	processing  *prometheus.Desc
+	latency     *prometheus.Desc
+	lag         *prometheus.Desc
+	inFlight    *prometheus.Desc
+	maxInFlight *prometheus.Desc
+	throttled   *prometheus.Desc
+}
+
+// NewConsumerCollector 创建订阅处理指标，source返回当前的统计，消息中间件未连接时可返回nil
+func NewConsumerCollector(source func() []ConsumerStats) *ConsumerCollector {
+	labels := []string{"topic", "channel"}
+	return &ConsumerCollector{
+		source: source,
+		processed: prometheus.NewDesc("lufy_mq_consumer_processed_total",
+			"Number of messages handled by the subscription, including failures", labels, nil),
+		failed: prometheus.NewDesc("lufy_mq_consumer_failed_total",
+			"Number of messages whose handler failed and were requeued", labels, nil),
+		processing: prometheus.NewDesc("lufy_mq_consumer_processing_seconds_total",
+			"Total time spent in the subscription's handler", labels, nil),
+		latency: prometheus.NewDesc("lufy_mq_consumer_latency_seconds",
+			"Moving average of the handler processing time", labels, nil),
+		lag: prometheus.NewDesc("lufy_mq_consumer_lag_seconds",
+			"Moving average of the time from publish to the start of handling", labels, nil),
+		inFlight: prometheus.NewDesc("lufy_mq_consumer_in_flight",
+			"Number of messages currently being handled", labels, nil),
+		maxInFlight: prometheus.NewDesc("lufy_mq_consumer_max_in_flight",
+			"Current max in flight of the subscription, lower than configured while throttled", labels, nil),
+		throttled: prometheus.NewDesc("lufy_mq_consumer_throttled",
+			"Whether the subscription is throttled because its handler is slow", labels, nil),
+	}
+}
+
+// Describe 实现prometheus.Collector接口
+func (c *ConsumerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.processed
+	ch <- c.failed
+	ch <- c.processing
+	ch <- c.latency
+	ch <- c.lag
+	ch <- c.inFlight
+	ch <- c.maxInFlight
+	ch <- c.throttled
+}
+
+// Collect 实现prometheus.Collector接口
+func (c *ConsumerCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, stats := range c.source() {
+		labels := []string{stats.Topic, stats.Channel}
+		throttled := 0.0
+		if stats.Throttled {
+			throttled = 1
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.processed, prometheus.CounterValue, float64(stats.Processed), labels...)
+		ch <- prometheus.MustNewConstMetric(c.failed, prometheus.CounterValue, float64(stats.Failed), labels...)
+		ch <- prometheus.MustNewConstMetric(c.processing, prometheus.CounterValue, stats.ProcessingTime.Seconds(), labels...)
+		ch <- prometheus.MustNewConstMetric(c.latency, prometheus.GaugeValue, stats.Latency.Seconds(), labels...)
+		ch <- prometheus.MustNewConstMetric(c.lag, prometheus.GaugeValue, stats.Lag.Seconds(), labels...)
+		ch <- prometheus.MustNewConstMetric(c.inFlight, prometheus.GaugeValue, float64(stats.InFlight), labels...)
+		ch <- prometheus.MustNewConstMetric(c.maxInFlight, prometheus.GaugeValue, float64(stats.MaxInFlight), labels...)
+		ch <- prometheus.MustNewConstMetric(c.throttled, prometheus.GaugeValue, throttled, labels...)
+	}
+}
@@ -0,0 +1,114 @@
+package mq
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/phuhao00/lufy/internal/eventbus"
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// 主题名限制，取NSQ和Kafka中较严格的
+const (
+	maxTopicLength     = 64
+	namespaceSeparator = "."
+)
+
+// namespacePattern 命名空间只能包含字母、数字、下划线和连字符，不能包含分隔符
+var namespacePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// ValidateNamespace 检查主题命名空间，空字符串表示不加前缀
+func ValidateNamespace(namespace string) error {
+	if namespace == "" {
+		return nil
+	}
+	if !namespacePattern.MatchString(namespace) {
+		return fmt.Errorf("invalid message queue namespace %q: only letters, digits, '_' and '-' are allowed", namespace)
+	}
+	return nil
+}
+
+// NamespacedTopic 加上命名空间前缀的主题名，如prod.game_events
+func NamespacedTopic(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + namespaceSeparator + name
+}
+
+// bareTopic 去掉命名空间前缀的主题名
+func bareTopic(topic string) string {
+	if i := strings.LastIndex(topic, namespaceSeparator); i >= 0 {
+		return topic[i+len(namespaceSeparator):]
+	}
+	return topic
+}
+
+// topicName 当前命名空间下的主题名
+func (mb *MessageBroker) topicName(name string) (string, error) {
+	if strings.Contains(name, namespaceSeparator) {
+		return "", fmt.Errorf("topic %q must not contain %q, the namespace is added by the message broker", name, namespaceSeparator)
+	}
+	topic := NamespacedTopic(mb.namespace, name)
+	if len(topic) > maxTopicLength {
+		return "", fmt.Errorf("topic %q exceeds %d characters", topic, maxTopicLength)
+	}
+	return topic, nil
+}
+
+// inNamespace 主题是否属于当前命名空间
+func (mb *MessageBroker) inNamespace(topic string) bool {
+	return topic == NamespacedTopic(mb.namespace, bareTopic(topic))
+}
+
+// namespaced 当前命名空间下的类型化主题
+func namespaced[T any](mb *MessageBroker, topic eventbus.Topic[T]) (eventbus.Topic[T], error) {
+	name, err := mb.topicName(topic.Name)
+	if err != nil {
+		return topic, err
+	}
+	return eventbus.NewTopic[T](name), nil
+}
+
+// publish 在当前命名空间下按键发布消息
+func publish[T any](mb *MessageBroker, topic eventbus.Topic[T], key string, event *T) error {
+	topic, err := namespaced(mb, topic)
+	if err != nil {
+		return err
+	}
+	return eventbus.PublishKeyed(mb.bus, topic, key, event)
+}
+
+// subscribe 在当前命名空间下订阅主题，处理器收到的主题名不含命名空间
+func (mb *MessageBroker) subscribe(name string, handler eventbus.Handler) error {
+	topic, err := mb.topicName(name)
+	if err != nil {
+		return err
+	}
+	return mb.bus.Subscribe(topic, mb.nodeID, &namespaceHandler{broker: mb, handler: handler})
+}
+
+// unsubscribe 取消当前命名空间下的订阅
+func (mb *MessageBroker) unsubscribe(name string) error {
+	topic, err := mb.topicName(name)
+	if err != nil {
+		return err
+	}
+	return mb.bus.Unsubscribe(topic, mb.nodeID)
+}
+
+// namespaceHandler 丢弃不属于当前命名空间的消息，防止共用集群的其他环境的消息被处理
+type namespaceHandler struct {
+	broker  *MessageBroker
+	handler eventbus.Handler
+}
+
+// HandleMessage 实现eventbus.Handler接口
+func (nh *namespaceHandler) HandleMessage(topic, group string, data []byte) error {
+	if !nh.broker.inNamespace(topic) {
+		logger.Error(fmt.Sprintf("Dropping message from topic %s outside namespace %q", topic, nh.broker.namespace))
+		return nil
+	}
+	return nh.handler.HandleMessage(bareTopic(topic), group, data)
+}
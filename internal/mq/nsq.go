@@ -4,11 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/nsqio/go-nsq"
 
+	"github.com/phuhao00/lufy/internal/eventbus"
 	"github.com/phuhao00/lufy/internal/logger"
 )
 
@@ -30,6 +32,10 @@ type NSQConfig struct {
 	WriteTimeout   time.Duration `yaml:"write_timeout"`
 	MessageTimeout time.Duration `yaml:"message_timeout"`
 
+	// 订阅的处理并发和背压
+	Concurrency   int                           `yaml:"concurrency"`   // 每个订阅处理消息的协程数，默认1
+	Subscriptions map[string]SubscriptionConfig `yaml:"subscriptions"` // 按订阅覆盖，键为"主题/channel"或主题
+
 	// 集群配置
 	LoadBalancing       bool          `yaml:"load_balancing"`        // 负载均衡
 	FailoverEnabled     bool          `yaml:"failover_enabled"`      // 故障转移
@@ -37,17 +43,18 @@ type NSQConfig struct {
 	ProducerPoolSize    int           `yaml:"producer_pool_size"`    // 生产者池大小
 }
 
-// MessageHandler 消息处理器接口
-type MessageHandler interface {
-	HandleMessage(topic, channel string, data []byte) error
-}
+// MessageHandler 消息处理器接口，NSQ的channel对应事件总线的订阅组
+type MessageHandler = eventbus.Handler
+
+// NSQManager 实现事件总线接口
+var _ eventbus.Bus = (*NSQManager)(nil)
 
 // NSQManager NSQ管理器
 type NSQManager struct {
 	config          *NSQConfig
 	producers       []*nsq.Producer // 支持多个生产者（集群模式）
 	producer        *nsq.Producer   // 主生产者（兼容性）
-	consumers       map[string]*nsq.Consumer
+	consumers       map[string]*consumerState
 	handlers        map[string]MessageHandler
 	mutex           sync.RWMutex
 	ctx             context.Context
@@ -62,7 +69,7 @@ func NewNSQManager(config *NSQConfig) (*NSQManager, error) {
 
 	manager := &NSQManager{
 		config:    config,
-		consumers: make(map[string]*nsq.Consumer),
+		consumers: make(map[string]*consumerState),
 		handlers:  make(map[string]MessageHandler),
 		ctx:       ctx,
 		cancel:    cancel,
@@ -83,6 +90,8 @@ func NewNSQManager(config *NSQConfig) (*NSQManager, error) {
 		return nil, fmt.Errorf("failed to initialize NSQ: %v", err)
 	}
 
+	go manager.throttleLoop()
+
 	logger.Infof("NSQ manager initialized in %s mode", manager.mode)
 	return manager, nil
 }
@@ -210,6 +219,7 @@ func (nm *NSQManager) GetClusterStats() map[string]interface{} {
 		"producers": len(nm.producers),
 		"consumers": len(nm.consumers),
 	}
+	stats["subscriptions"] = nm.ConsumerStats()
 
 	if nm.mode == "cluster" {
 		stats["nsqd_addresses"] = nm.config.NSQDAddresses
@@ -264,22 +274,28 @@ func (nm *NSQManager) Subscribe(topic, channel string, handler MessageHandler) e
 	}
 
 	config := nsq.NewConfig()
-	config.MaxInFlight = nm.config.MaxInFlight
 	config.MsgTimeout = nm.config.MessageTimeout
 
-	consumer, err := nsq.NewConsumer(topic, channel, config)
+	// 按订阅设置处理协程数和in-flight上限
+	subscription := nm.config.subscriptionConfig(topic, channel)
+	state, err := newConsumerState(topic, channel, subscription, config, handler)
 	if err != nil {
-		return fmt.Errorf("failed to create consumer: %v", err)
+		return err
+	}
+	if err := nm.connectConsumer(state.consumer); err != nil {
+		return err
 	}
 
-	// 设置消息处理器
-	consumer.AddHandler(&messageHandlerWrapper{
-		handler: handler,
-		topic:   topic,
-		channel: channel,
-	})
+	nm.consumers[key] = state
+	nm.handlers[key] = handler
 
-	// 连接到NSQLookupd
+	logger.Infof("Subscribed to topic: %s, channel: %s, concurrency: %d, max in flight: %d",
+		topic, channel, subscription.Concurrency, subscription.MaxInFlight)
+	return nil
+}
+
+// connectConsumer 消费者连接到NSQLookupd
+func (nm *NSQManager) connectConsumer(consumer *nsq.Consumer) error {
 	if nm.mode == "cluster" && len(nm.config.NSQLookupDAddresses) > 0 {
 		// 集群模式：连接到所有NSQLookupd
 		for _, addr := range nm.config.NSQLookupDAddresses {
@@ -292,17 +308,13 @@ func (nm *NSQManager) Subscribe(topic, channel string, handler MessageHandler) e
 				logger.Infof("Connected to NSQLookupd: %s", addr)
 			}
 		}
-	} else {
-		// 单机模式：连接到单个NSQLookupd
-		if err := consumer.ConnectToNSQLookupd(nm.config.NSQLookupDAddress); err != nil {
-			return fmt.Errorf("failed to connect to NSQLookupd: %v", err)
-		}
+		return nil
 	}
 
-	nm.consumers[key] = consumer
-	nm.handlers[key] = handler
-
-	logger.Infof("Subscribed to topic: %s, channel: %s", topic, channel)
+	// 单机模式：连接到单个NSQLookupd
+	if err := consumer.ConnectToNSQLookupd(nm.config.NSQLookupDAddress); err != nil {
+		return fmt.Errorf("failed to connect to NSQLookupd: %v", err)
+	}
 	return nil
 }
 
@@ -312,13 +324,13 @@ func (nm *NSQManager) Unsubscribe(topic, channel string) error {
 	defer nm.mutex.Unlock()
 
 	key := fmt.Sprintf("%s_%s", topic, channel)
-	consumer, exists := nm.consumers[key]
+	state, exists := nm.consumers[key]
 	if !exists {
 		return fmt.Errorf("not subscribed to %s/%s", topic, channel)
 	}
 
-	consumer.Stop()
-	<-consumer.StopChan
+	state.consumer.Stop()
+	<-state.consumer.StopChan
 
 	delete(nm.consumers, key)
 	delete(nm.handlers, key)
@@ -333,9 +345,9 @@ func (nm *NSQManager) Close() error {
 
 	// 停止所有消费者
 	nm.mutex.Lock()
-	for key, consumer := range nm.consumers {
-		consumer.Stop()
-		<-consumer.StopChan
+	for key, state := range nm.consumers {
+		state.consumer.Stop()
+		<-state.consumer.StopChan
 		logger.Debug(fmt.Sprintf("Stopped consumer: %s", key))
 	}
 	nm.mutex.Unlock()
@@ -347,25 +359,6 @@ func (nm *NSQManager) Close() error {
 	return nil
 }
 
-// messageHandlerWrapper NSQ消息处理器包装器
-type messageHandlerWrapper struct {
-	handler MessageHandler
-	topic   string
-	channel string
-}
-
-// HandleMessage 实现nsq.Handler接口
-func (mhw *messageHandlerWrapper) HandleMessage(message *nsq.Message) error {
-	start := time.Now()
-
-	err := mhw.handler.HandleMessage(mhw.topic, mhw.channel, message.Body)
-
-	duration := time.Since(start)
-	logger.Debug(fmt.Sprintf("Handled message from %s/%s in %v", mhw.topic, mhw.channel, duration))
-
-	return err
-}
-
 // GameMessage 游戏消息
 type GameMessage struct {
 	Type      string                 `json:"type"`
@@ -373,6 +366,8 @@ type GameMessage struct {
 	UserID    uint64                 `json:"user_id,omitempty"`
 	Data      map[string]interface{} `json:"data,omitempty"`
 	Timestamp int64                  `json:"timestamp"`
+	Version   int32                  `json:"version,omitempty"` // 类型化载荷的版本，0表示只有Data
+	Payload   []byte                 `json:"payload,omitempty"` // 类型化载荷(protobuf)，见schema.go
 }
 
 // NewGameMessage 创建游戏消息
@@ -429,11 +424,16 @@ func (gmh *GameMessageHandler) HandleMessage(topic, channel string, data []byte)
 
 // ChatMessage 聊天消息
 type ChatMessage struct {
-	FromUserID uint64 `json:"from_user_id"`
-	ToUserID   uint64 `json:"to_user_id"` // 0表示全服聊天
-	Channel    int32  `json:"channel"`    // 聊天频道
-	Content    string `json:"content"`
-	Timestamp  int64  `json:"timestamp"`
+	FromUserID uint64           `json:"from_user_id"`
+	ToUserID   uint64           `json:"to_user_id"` // 0表示全服聊天
+	Channel    int32            `json:"channel"`    // 聊天频道
+	Content    string           `json:"content"`
+	Timestamp  int64            `json:"timestamp"`
+	MessageID  uint64           `json:"message_id,omitempty"` // 聊天服务器保存的消息ID，客户端据此更新已显示的消息
+	ChannelID  uint64           `json:"channel_id,omitempty"`
+	Action     string           `json:"action,omitempty"`    // 为空时为新消息，否则为CHAT_ACTION_*
+	EditTime   int64            `json:"edit_time,omitempty"` // 最后编辑时间
+	Reactions  map[string]int32 `json:"reactions,omitempty"` // 各表情的回应人数，键为表情ID
 }
 
 // NewChatMessage 创建聊天消息
@@ -480,6 +480,8 @@ type SystemMessage struct {
 	Command   string                 `json:"command"`
 	Args      map[string]interface{} `json:"args,omitempty"`
 	Timestamp int64                  `json:"timestamp"`
+	Version   int32                  `json:"version,omitempty"` // 类型化载荷的版本，0表示只有Args
+	Payload   []byte                 `json:"payload,omitempty"` // 类型化载荷(protobuf)，见schema.go
 }
 
 // NewSystemMessage 创建系统消息
@@ -541,36 +543,74 @@ func (smh *SystemMessageHandler) HandleMessage(topic, channel string, data []byt
 	return handler(&sysMsg)
 }
 
-// MessageBroker 消息代理
+// 服务间消息主题
+var (
+	GameEventsTopic     = eventbus.NewTopic[GameMessage]("game_events")
+	ChatMessagesTopic   = eventbus.NewTopic[ChatMessage]("chat_messages")
+	SystemMessagesTopic = eventbus.NewTopic[SystemMessage]("system_messages")
+)
+
+// MessageBroker 消息代理，基于事件总线发布和订阅服务间消息，不依赖具体的消息中间件。
+// 设置命名空间时主题名自动加上前缀，多个环境共用同一个消息集群时互不干扰
 type MessageBroker struct {
-	nsq    *NSQManager
-	nodeID string
+	bus       eventbus.Bus
+	nodeID    string
+	namespace string
 }
 
-// NewMessageBroker 创建消息代理
-func NewMessageBroker(nsq *NSQManager, nodeID string) *MessageBroker {
-	return &MessageBroker{
-		nsq:    nsq,
-		nodeID: nodeID,
+// NewMessageBroker 创建消息代理，namespace为空时主题名不加前缀
+func NewMessageBroker(bus eventbus.Bus, nodeID, namespace string) (*MessageBroker, error) {
+	if err := ValidateNamespace(namespace); err != nil {
+		return nil, err
 	}
+	return &MessageBroker{
+		bus:       bus,
+		nodeID:    nodeID,
+		namespace: namespace,
+	}, nil
+}
+
+// Namespace 主题命名空间
+func (mb *MessageBroker) Namespace() string {
+	return mb.namespace
+}
+
+// Bus 获取底层事件总线
+func (mb *MessageBroker) Bus() eventbus.Bus {
+	return mb.bus
 }
 
 // PublishGameMessage 发布游戏消息
 func (mb *MessageBroker) PublishGameMessage(msgType string, roomID, userID uint64, data map[string]interface{}) error {
 	msg := NewGameMessage(msgType, roomID, userID, data)
-	return mb.nsq.PublishJSON("game_events", msg)
+	return publish(mb, GameEventsTopic, partitionKey(roomID, userID), msg)
 }
 
 // PublishChatMessage 发布聊天消息
 func (mb *MessageBroker) PublishChatMessage(fromUserID, toUserID uint64, channel int32, content string) error {
 	msg := NewChatMessage(fromUserID, toUserID, channel, content)
-	return mb.nsq.PublishJSON("chat_messages", msg)
+	return publish(mb, ChatMessagesTopic, partitionKey(toUserID, fromUserID), msg)
+}
+
+// PublishChat 发布已构造的聊天消息，用于携带消息ID的新消息以及编辑、撤回通知
+func (mb *MessageBroker) PublishChat(msg *ChatMessage) error {
+	return publish(mb, ChatMessagesTopic, partitionKey(msg.ToUserID, msg.FromUserID), msg)
 }
 
 // PublishSystemMessage 发布系统消息
 func (mb *MessageBroker) PublishSystemMessage(msgType, target, command string, args map[string]interface{}) error {
 	msg := NewSystemMessage(msgType, target, command, args)
-	return mb.nsq.PublishJSON("system_messages", msg)
+	return publish(mb, SystemMessagesTopic, target, msg)
+}
+
+// partitionKey 分区键，取第一个非零的ID，同一房间或用户的消息在支持分区的总线上保持顺序
+func partitionKey(ids ...uint64) string {
+	for _, id := range ids {
+		if id != 0 {
+			return strconv.FormatUint(id, 10)
+		}
+	}
+	return ""
 }
 
 // BroadcastSystemMessage 广播系统消息
@@ -585,17 +625,32 @@ func (mb *MessageBroker) SendToNode(target, command string, args map[string]inte
 
 // SubscribeGameEvents 订阅游戏事件
 func (mb *MessageBroker) SubscribeGameEvents(handler *GameMessageHandler) error {
-	return mb.nsq.Subscribe("game_events", mb.nodeID, handler)
+	return mb.subscribe(GameEventsTopic.Name, handler)
 }
 
 // SubscribeChatMessages 订阅聊天消息
 func (mb *MessageBroker) SubscribeChatMessages(handler *ChatMessageHandler) error {
-	return mb.nsq.Subscribe("chat_messages", mb.nodeID, handler)
+	return mb.subscribe(ChatMessagesTopic.Name, handler)
 }
 
 // SubscribeSystemMessages 订阅系统消息
 func (mb *MessageBroker) SubscribeSystemMessages(handler *SystemMessageHandler) error {
-	return mb.nsq.Subscribe("system_messages", mb.nodeID, handler)
+	return mb.subscribe(SystemMessagesTopic.Name, handler)
+}
+
+// UnsubscribeGameEvents 取消订阅游戏事件
+func (mb *MessageBroker) UnsubscribeGameEvents() error {
+	return mb.unsubscribe(GameEventsTopic.Name)
+}
+
+// UnsubscribeChatMessages 取消订阅聊天消息
+func (mb *MessageBroker) UnsubscribeChatMessages() error {
+	return mb.unsubscribe(ChatMessagesTopic.Name)
+}
+
+// UnsubscribeSystemMessages 取消订阅系统消息
+func (mb *MessageBroker) UnsubscribeSystemMessages() error {
+	return mb.unsubscribe(SystemMessagesTopic.Name)
 }
 
 // 消息类型常量
@@ -615,6 +670,11 @@ const (
 	CHAT_CHANNEL_FRIEND = 3 // 好友聊天
 	CHAT_CHANNEL_GUILD  = 4 // 公会聊天
 
+	// 聊天消息变更
+	CHAT_ACTION_EDIT   = "edit"   // 消息被编辑
+	CHAT_ACTION_RECALL = "recall" // 消息被撤回或被GM删除
+	CHAT_ACTION_REACT  = "react"  // 消息的回应人数变化
+
 	// 系统命令
 	SYS_CMD_RELOAD_CONFIG    = "reload_config"
 	SYS_CMD_UPDATE_LOAD      = "update_load"
@@ -622,4 +682,19 @@ const (
 	SYS_CMD_HOT_UPDATE       = "hot_update"
 	SYS_CMD_KICK_USER        = "kick_user"
 	SYS_CMD_BROADCAST_NOTICE = "broadcast_notice"
+	SYS_CMD_CAPTURE_PROFILE  = "capture_profile"
+	SYS_CMD_RECONCILE        = "reconcile_settlements"
+	SYS_CMD_DRAIN            = "drain"
+	SYS_CMD_CANARY           = "canary"
+	SYS_CMD_CHAOS            = "chaos"
+	SYS_CMD_RUNTIME_PARAM    = "runtime_param"
+	SYS_CMD_CONFIG_PREPARE   = "config_prepare"
+	SYS_CMD_CONFIG_COMMIT    = "config_commit"
+	SYS_CMD_CONFIG_ABORT     = "config_abort"
+	SYS_CMD_GAME_MIGRATED    = "game_migrated"
+	SYS_CMD_USER_RENAMED     = "user_renamed"
+
+	SYS_CMD_DELIVER_NOTIFICATIONS = "deliver_notifications"
+	SYS_CMD_ACTIVITY              = "activity"
+	SYS_CMD_NEWS_CHANGED          = "news_changed"
 )
@@ -0,0 +1,282 @@
+package mq
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// EventSchema 游戏事件或系统命令的类型化定义。载荷以protobuf编码放在信封的Payload中，版本号写入Version；
+// 同时按ToLegacy生成旧的map形式数据，滚动升级期间未升级的消费者仍按map处理
+type EventSchema struct {
+	Type    string               // 游戏消息类型或系统命令，如MSG_GAME_ENDED
+	Version int32                // 载荷版本，只允许新增字段；字段含义变化时递增，旧版本的处理写在FromLegacy中
+	New     func() proto.Message // 创建空的载荷消息
+	// ToLegacy 生成旧版本消费者读取的map数据，未设置时按载荷的JSON字段生成
+	ToLegacy func(msg proto.Message) map[string]interface{}
+	// FromLegacy 解析旧版本生产者发出的map数据，未设置时按载荷的JSON字段解析
+	FromLegacy func(data map[string]interface{}, msg proto.Message) error
+}
+
+// SchemaInfo 已注册的事件定义，用于查看和对比各节点的版本
+type SchemaInfo struct {
+	Type    string `json:"type"`
+	Version int32  `json:"version"`
+	Message string `json:"message"`
+}
+
+// SchemaRegistry 事件定义注册表，游戏消息类型和系统命令共用一个命名空间
+type SchemaRegistry struct {
+	schemas map[string]*EventSchema
+	mutex   sync.RWMutex
+}
+
+// NewSchemaRegistry 创建事件定义注册表
+func NewSchemaRegistry() *SchemaRegistry {
+	return &SchemaRegistry{schemas: make(map[string]*EventSchema)}
+}
+
+// Register 注册事件定义，同一类型只能注册一次
+func (r *SchemaRegistry) Register(schema *EventSchema) error {
+	if schema.Type == "" || schema.New == nil {
+		return fmt.Errorf("event schema must have a type and a message")
+	}
+	if schema.Version <= 0 {
+		return fmt.Errorf("event schema %s must have a positive version", schema.Type)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.schemas[schema.Type]; exists {
+		return fmt.Errorf("event schema %s already registered", schema.Type)
+	}
+	r.schemas[schema.Type] = schema
+	return nil
+}
+
+// Lookup 查找事件定义
+func (r *SchemaRegistry) Lookup(eventType string) (*EventSchema, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	schema, exists := r.schemas[eventType]
+	return schema, exists
+}
+
+// Schemas 所有事件定义，按类型排序
+func (r *SchemaRegistry) Schemas() []SchemaInfo {
+	r.mutex.RLock()
+	infos := make([]SchemaInfo, 0, len(r.schemas))
+	for _, schema := range r.schemas {
+		infos = append(infos, SchemaInfo{
+			Type:    schema.Type,
+			Version: schema.Version,
+			Message: reflect.TypeOf(schema.New()).Elem().Name(),
+		})
+	}
+	r.mutex.RUnlock()
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Type < infos[j].Type
+	})
+	return infos
+}
+
+// encode 编码载荷，返回版本、protobuf载荷和旧格式的map数据
+func (r *SchemaRegistry) encode(eventType string, msg proto.Message) (int32, []byte, map[string]interface{}, error) {
+	schema, exists := r.Lookup(eventType)
+	if !exists {
+		return 0, nil, nil, fmt.Errorf("no event schema registered for %s", eventType)
+	}
+	if expected := reflect.TypeOf(schema.New()); reflect.TypeOf(msg) != expected {
+		return 0, nil, nil, fmt.Errorf("event %s expects %v, got %T", eventType, expected, msg)
+	}
+
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("failed to marshal %s payload: %v", eventType, err)
+	}
+
+	var legacy map[string]interface{}
+	if schema.ToLegacy != nil {
+		legacy = schema.ToLegacy(msg)
+	} else if legacy, err = jsonMap(msg); err != nil {
+		return 0, nil, nil, fmt.Errorf("failed to convert %s payload: %v", eventType, err)
+	}
+	return schema.Version, payload, legacy, nil
+}
+
+// decode 解码载荷到msg。有protobuf载荷时直接解码，新版本生产者新增的字段会被忽略；
+// 没有载荷(旧版本生产者)或载荷无法解码时按map数据解析
+func (r *SchemaRegistry) decode(eventType string, version int32, payload []byte, legacy map[string]interface{}, msg proto.Message) error {
+	schema, exists := r.Lookup(eventType)
+	if !exists {
+		return fmt.Errorf("no event schema registered for %s", eventType)
+	}
+	if expected := reflect.TypeOf(schema.New()); reflect.TypeOf(msg) != expected {
+		return fmt.Errorf("event %s expects %v, got %T", eventType, expected, msg)
+	}
+
+	if version > 0 && len(payload) > 0 {
+		if version > schema.Version {
+			logger.Debug(fmt.Sprintf("Event %s version %d is newer than local version %d, unknown fields are ignored",
+				eventType, version, schema.Version))
+		}
+		err := proto.Unmarshal(payload, msg)
+		if err == nil {
+			return nil
+		}
+		logger.Warn(fmt.Sprintf("Failed to decode %s payload version %d, falling back to legacy data: %v", eventType, version, err))
+		msg.Reset()
+	}
+
+	if schema.FromLegacy != nil {
+		return schema.FromLegacy(legacy, msg)
+	}
+	data, err := json.Marshal(legacy)
+	if err != nil {
+		return fmt.Errorf("failed to convert legacy %s data: %v", eventType, err)
+	}
+	if err := json.Unmarshal(data, msg); err != nil {
+		return fmt.Errorf("failed to parse legacy %s data: %v", eventType, err)
+	}
+	return nil
+}
+
+// jsonMap 按JSON字段把载荷转换为map
+func jsonMap(msg proto.Message) (map[string]interface{}, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Schemas 默认的事件定义注册表，MessageBroker发布和解析类型化事件时使用
+var Schemas = NewSchemaRegistry()
+
+// GameEvent 解码游戏消息的类型化载荷
+func GameEvent[T any, PT interface {
+	*T
+	proto.Message
+}](msg *GameMessage) (PT, error) {
+	event := PT(new(T))
+	if err := Schemas.decode(msg.Type, msg.Version, msg.Payload, msg.Data, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// Command 解码系统消息的类型化载荷
+func Command[T any, PT interface {
+	*T
+	proto.Message
+}](msg *SystemMessage) (PT, error) {
+	command := PT(new(T))
+	if err := Schemas.decode(msg.Command, msg.Version, msg.Payload, msg.Args, command); err != nil {
+		return nil, err
+	}
+	return command, nil
+}
+
+// PublishGameEvent 发布类型化的游戏事件
+func (mb *MessageBroker) PublishGameEvent(msgType string, roomID, userID uint64, event proto.Message) error {
+	version, payload, legacy, err := Schemas.encode(msgType, event)
+	if err != nil {
+		return err
+	}
+	msg := NewGameMessage(msgType, roomID, userID, legacy)
+	msg.Version = version
+	msg.Payload = payload
+	return publish(mb, GameEventsTopic, partitionKey(roomID, userID), msg)
+}
+
+// SendCommand 向指定节点发送类型化的系统命令，target为空时广播
+func (mb *MessageBroker) SendCommand(target, command string, args proto.Message) error {
+	version, payload, legacy, err := Schemas.encode(command, args)
+	if err != nil {
+		return err
+	}
+	msgType := "unicast"
+	if target == "" {
+		msgType = "broadcast"
+	}
+	msg := NewSystemMessage(msgType, target, command, legacy)
+	msg.Version = version
+	msg.Payload = payload
+	return publish(mb, SystemMessagesTopic, target, msg)
+}
+
+func init() {
+	for _, schema := range []*EventSchema{
+		{
+			Type:    MSG_GAME_ENDED,
+			Version: 1,
+			New:     func() proto.Message { return &proto.GameEndedEvent{} },
+		},
+		{
+			Type:    SYS_CMD_GAME_MIGRATED,
+			Version: 1,
+			New:     func() proto.Message { return &proto.GameMigratedEvent{} },
+		},
+		{
+			Type:    SYS_CMD_USER_RENAMED,
+			Version: 1,
+			New:     func() proto.Message { return &proto.UserRenamedEvent{} },
+		},
+		{
+			Type:    SYS_CMD_CHAOS,
+			Version: 1,
+			New:     func() proto.Message { return &proto.ChaosCommand{} },
+		},
+		{
+			Type:    SYS_CMD_RUNTIME_PARAM,
+			Version: 1,
+			New:     func() proto.Message { return &proto.RuntimeParamCommand{} },
+		},
+		{
+			Type:    SYS_CMD_RECONCILE,
+			Version: 1,
+			New:     func() proto.Message { return &proto.ReconcileCommand{} },
+		},
+		{
+			// 旧格式的user_id为字符串，避免JSON数字丢失精度
+			Type:    SYS_CMD_KICK_USER,
+			Version: 1,
+			New:     func() proto.Message { return &proto.KickUserCommand{} },
+			ToLegacy: func(msg proto.Message) map[string]interface{} {
+				kick := msg.(*proto.KickUserCommand)
+				return map[string]interface{}{
+					"user_id": strconv.FormatUint(kick.UserId, 10),
+					"reason":  kick.Reason,
+				}
+			},
+			FromLegacy: func(data map[string]interface{}, msg proto.Message) error {
+				kick := msg.(*proto.KickUserCommand)
+				userID, _ := data["user_id"].(string)
+				id, err := strconv.ParseUint(userID, 10, 64)
+				if err != nil {
+					return fmt.Errorf("invalid user id: %q", userID)
+				}
+				kick.UserId = id
+				kick.Reason, _ = data["reason"].(string)
+				return nil
+			},
+		},
+	} {
+		if err := Schemas.Register(schema); err != nil {
+			panic(err)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package naming
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/phuhao00/lufy/internal/chatpolicy"
+	"golang.org/x/text/unicode/norm"
+)
+
+// ErrInvalid 名称不符合长度、字符或屏蔽词规则
+var ErrInvalid = errors.New("invalid name")
+
+// 名称类型
+const (
+	KindNickname = "nickname"
+	KindUsername = "username"
+	KindRoomName = "room_name"
+)
+
+// Rules 名称规则，长度按字符数计算，各项为0或空时使用上一级的设置
+type Rules struct {
+	MinLength int    `yaml:"min_length"`
+	MaxLength int    `yaml:"max_length"`
+	Charset   string `yaml:"charset"` // 允许的字符，正则表达式的字符类内容，如 \p{Han}a-zA-Z0-9_
+}
+
+// merge 用override中已设置的项覆盖r
+func (r Rules) merge(override Rules) Rules {
+	if override.MinLength > 0 {
+		r.MinLength = override.MinLength
+	}
+	if override.MaxLength > 0 {
+		r.MaxLength = override.MaxLength
+	}
+	if override.Charset != "" {
+		r.Charset = override.Charset
+	}
+	return r
+}
+
+// LocaleRules 某种客户端语言的名称规则
+type LocaleRules struct {
+	Nickname Rules `yaml:"nickname"`
+	RoomName Rules `yaml:"room_name"`
+}
+
+// Cost 玩家改名的费用，前Free次免费，GM修改不收费
+type Cost struct {
+	Currency string `yaml:"currency"` // gold或diamond，为空时不收费
+	Amount   int64  `yaml:"amount"`
+	Free     int    `yaml:"free"`
+}
+
+// Config 昵称、用户名和房间名规则配置
+type Config struct {
+	Nickname Rules                  `yaml:"nickname"`
+	Username Rules                  `yaml:"username"` // 用户名不区分客户端语言
+	RoomName Rules                  `yaml:"room_name"`
+	Locales  map[string]LocaleRules `yaml:"locales"`  // 按玩家的客户端语言覆盖默认规则，如 zh-CN、en
+	Reserved []string               `yaml:"reserved"` // 保留名，如 admin、gm、系统，昵称不能与之相同(忽略大小写和分隔符)
+
+	RenameCooldown time.Duration `yaml:"rename_cooldown"` // 玩家两次修改昵称(或用户名)的最小间隔，GM修改不受限制
+	RenameCost     Cost          `yaml:"rename_cost"`
+	UsernameRename bool          `yaml:"username_rename"` // 允许玩家修改用户名，游客账号不能修改
+}
+
+// Validate 检查规则配置
+func (c *Config) Validate() error {
+	check := func(name string, rules Rules) error {
+		if rules.MinLength < 0 || rules.MaxLength < 0 {
+			return fmt.Errorf("%s lengths must not be negative", name)
+		}
+		if rules.MaxLength > 0 && rules.MinLength > rules.MaxLength {
+			return fmt.Errorf("%s min_length must not exceed max_length", name)
+		}
+		if rules.Charset != "" {
+			if _, err := charsetPattern(rules.Charset); err != nil {
+				return fmt.Errorf("invalid %s charset: %v", name, err)
+			}
+		}
+		return nil
+	}
+
+	if c.RenameCooldown < 0 {
+		return fmt.Errorf("rename_cooldown must not be negative")
+	}
+	if c.RenameCost.Amount < 0 || c.RenameCost.Free < 0 {
+		return fmt.Errorf("rename_cost must not be negative")
+	}
+	if c.RenameCost.Amount > 0 && c.RenameCost.Currency == "" {
+		return fmt.Errorf("rename_cost currency is required")
+	}
+	if err := check(KindNickname, c.Nickname); err != nil {
+		return err
+	}
+	if err := check(KindUsername, c.Username); err != nil {
+		return err
+	}
+	if err := check(KindRoomName, c.RoomName); err != nil {
+		return err
+	}
+	for locale, rules := range c.Locales {
+		if err := check(locale+" "+KindNickname, c.Nickname.merge(rules.Nickname)); err != nil {
+			return err
+		}
+		if err := check(locale+" "+KindRoomName, c.RoomName.merge(rules.RoomName)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// charsetPattern 匹配完全由允许字符组成的名称
+func charsetPattern(charset string) (*regexp.Regexp, error) {
+	return regexp.Compile(`^[` + charset + `]*$`)
+}
+
+// Validator 按玩家的客户端语言校验昵称和房间名，屏蔽词与聊天共用
+type Validator struct {
+	config   Config
+	words    *chatpolicy.WordFilter
+	reserved map[string]bool
+	patterns map[string]*regexp.Regexp
+}
+
+// NewValidator 创建名称校验器，配置需已通过Validate检查
+func NewValidator(config *Config, words *chatpolicy.WordFilter) *Validator {
+	v := &Validator{
+		config:   *config,
+		words:    words,
+		reserved: make(map[string]bool, len(config.Reserved)),
+		patterns: make(map[string]*regexp.Regexp),
+	}
+	for _, name := range config.Reserved {
+		v.reserved[Key(name)] = true
+	}
+
+	add := func(rules Rules) {
+		if rules.Charset == "" || v.patterns[rules.Charset] != nil {
+			return
+		}
+		if pattern, err := charsetPattern(rules.Charset); err == nil {
+			v.patterns[rules.Charset] = pattern
+		}
+	}
+	add(config.Nickname)
+	add(config.Username)
+	add(config.RoomName)
+	for _, rules := range config.Locales {
+		add(rules.Nickname)
+		add(rules.RoomName)
+	}
+	return v
+}
+
+// Rules 名称在某种客户端语言下的规则
+func (v *Validator) Rules(kind, locale string) Rules {
+	override, exists := v.config.Locales[locale]
+	if !exists {
+		// zh-TW等未单独配置时使用zh的规则
+		if base, _, found := strings.Cut(locale, "-"); found {
+			override = v.config.Locales[base]
+		}
+	}
+	switch kind {
+	case KindUsername:
+		return v.config.Username
+	case KindRoomName:
+		return v.config.RoomName.merge(override.RoomName)
+	}
+	return v.config.Nickname.merge(override.Nickname)
+}
+
+// Nickname 校验昵称，返回去掉首尾空白并规范化后的昵称
+func (v *Validator) Nickname(name, locale string) (string, error) {
+	name, err := v.check(KindNickname, name, locale)
+	if err != nil {
+		return "", err
+	}
+	key := Key(name)
+	if key == "" {
+		return "", fmt.Errorf("%w: nickname must contain letters or digits", ErrInvalid)
+	}
+	if v.reserved[key] {
+		return "", fmt.Errorf("%w: nickname is reserved", ErrInvalid)
+	}
+	return name, nil
+}
+
+// Username 校验用户名，用户名不区分客户端语言，同样不能包含屏蔽词或与保留名相同
+func (v *Validator) Username(name string) (string, error) {
+	name, err := v.check(KindUsername, name, "")
+	if err != nil {
+		return "", err
+	}
+	if v.reserved[Key(name)] {
+		return "", fmt.Errorf("%w: username is reserved", ErrInvalid)
+	}
+	return name, nil
+}
+
+// RoomName 校验房间名，返回去掉首尾空白并规范化后的房间名
+func (v *Validator) RoomName(name, locale string) (string, error) {
+	return v.check(KindRoomName, name, locale)
+}
+
+// check 检查长度、字符和屏蔽词
+func (v *Validator) check(kind, name, locale string) (string, error) {
+	name = strings.TrimSpace(norm.NFC.String(name))
+	rules := v.Rules(kind, locale)
+
+	length := utf8.RuneCountInString(name)
+	if length == 0 || length < rules.MinLength {
+		return "", fmt.Errorf("%w: %s must be at least %d characters", ErrInvalid, kind, max(rules.MinLength, 1))
+	}
+	if rules.MaxLength > 0 && length > rules.MaxLength {
+		return "", fmt.Errorf("%w: %s must be at most %d characters", ErrInvalid, kind, rules.MaxLength)
+	}
+	for _, r := range name {
+		if r < ' ' || r == 0x7f {
+			return "", fmt.Errorf("%w: %s contains control characters", ErrInvalid, kind)
+		}
+	}
+	if pattern := v.patterns[rules.Charset]; pattern != nil && !pattern.MatchString(name) {
+		return "", fmt.Errorf("%w: %s contains characters that are not allowed", ErrInvalid, kind)
+	}
+	if v.words.Contains(name) {
+		return "", fmt.Errorf("%w: %s contains blocked words", ErrInvalid, kind)
+	}
+	return name, nil
+}
+
+// Key 昵称查重使用的规范化形式，大小写、全半角和分隔符不同的昵称视为同一个
+func Key(name string) string {
+	return chatpolicy.NormalizeWord(name)
+}
@@ -0,0 +1,222 @@
+package naming
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// 改名的错误
+var (
+	ErrTaken        = errors.New("name already taken")
+	ErrCooldown     = errors.New("name changed too recently")
+	ErrUnchanged    = errors.New("name unchanged")
+	ErrConflict     = errors.New("name changed concurrently")
+	ErrNotAllowed   = errors.New("name cannot be changed")
+	ErrInsufficient = errors.New("insufficient balance to rename")
+)
+
+// Service 改名：校验规则、检查冷却时间和费用、占用新名称并记录修改
+type Service struct {
+	validator *Validator
+	users     *database.UserRepository
+	names     *database.NameRepository
+	ledger    *database.LedgerRepository
+	config    Config
+}
+
+// NewService 创建改名服务
+func NewService(config *Config, validator *Validator, users *database.UserRepository, names *database.NameRepository, ledger *database.LedgerRepository) *Service {
+	return &Service{
+		validator: validator,
+		users:     users,
+		names:     names,
+		ledger:    ledger,
+		config:    *config,
+	}
+}
+
+// Validator 名称校验器
+func (s *Service) Validator() *Validator {
+	return s.validator
+}
+
+// NextRename 玩家下次可以修改昵称或用户名的时间，不受限制时返回零值
+func (s *Service) NextRename(userID uint64, field string) (time.Time, error) {
+	if s.config.RenameCooldown <= 0 {
+		return time.Time{}, nil
+	}
+	last, err := s.names.LastChange(userID, field, database.RenameByPlayer)
+	if err != nil || last == nil {
+		return time.Time{}, err
+	}
+	return last.CreatedAt.Add(s.config.RenameCooldown), nil
+}
+
+// Rename 修改昵称或用户名。change.By为player时按玩家的客户端语言校验，检查冷却时间并在免费次数用完后扣费；
+// GM修改只校验默认规则且不收费。冷却中返回ErrCooldown，名称已被使用返回ErrTaken，不符合规则返回ErrInvalid，
+// 余额不足返回ErrInsufficient。成功后change中记录原名称和费用
+func (s *Service) Rename(change *database.NameChange, locale string, now time.Time) error {
+	byPlayer := change.By == database.RenameByPlayer
+	if !byPlayer {
+		locale = ""
+	}
+
+	var name string
+	var err error
+	switch change.Field {
+	case database.NameFieldNickname:
+		name, err = s.validator.Nickname(change.NewName, locale)
+	case database.NameFieldUsername:
+		if byPlayer && !s.config.UsernameRename {
+			return ErrNotAllowed
+		}
+		name, err = s.validator.Username(change.NewName)
+	default:
+		return fmt.Errorf("unknown name field: %s", change.Field)
+	}
+	if err != nil {
+		return err
+	}
+	change.NewName = name
+
+	user, err := s.users.GetByUserID(change.UserID)
+	if err != nil {
+		return err
+	}
+	oldName := user.Nickname
+	if change.Field == database.NameFieldUsername {
+		// 游客账号的用户名由系统生成，绑定账号时才设置正式用户名
+		if user.Guest {
+			return ErrNotAllowed
+		}
+		oldName = user.Username
+	}
+	if oldName == name {
+		return ErrUnchanged
+	}
+	change.OldName = oldName
+
+	if byPlayer {
+		next, err := s.NextRename(change.UserID, change.Field)
+		if err != nil {
+			return err
+		}
+		if now.Before(next) {
+			return fmt.Errorf("%w, next rename at %s", ErrCooldown, next.Format(time.RFC3339))
+		}
+	}
+
+	// 昵称按规范化形式占用，只改大小写或分隔符时规范化形式不变，沿用已占用的昵称；用户名由用户表的唯一索引保证
+	var newKey, oldKey string
+	if change.Field == database.NameFieldNickname {
+		newKey, oldKey = Key(name), Key(oldName)
+	}
+	if newKey != oldKey {
+		if err := s.names.Reserve(newKey, change.UserID); err != nil {
+			if errors.Is(err, database.ErrNicknameTaken) {
+				return ErrTaken
+			}
+			return err
+		}
+	}
+
+	debit, err := s.charge(change, now)
+	if err == nil {
+		err = s.update(change)
+	}
+	if err != nil {
+		if newKey != oldKey {
+			s.names.Release(newKey, change.UserID)
+		}
+		if debit != nil {
+			if _, reverseErr := s.ledger.Reverse(debit, debit.BatchID, "rename failed"); reverseErr != nil {
+				logger.Error(fmt.Sprintf("Failed to reverse rename charge %s: %v", debit.TxID, reverseErr))
+			}
+		}
+		return err
+	}
+
+	if newKey != oldKey {
+		if err := s.names.Release(oldKey, change.UserID); err != nil {
+			logger.Warn(fmt.Sprintf("Failed to release old nickname of user %d: %v", change.UserID, err))
+		}
+	}
+	change.CreatedAt = now
+	if err := s.names.RecordChange(change); err != nil {
+		// 名称已修改，记录失败只影响冷却时间、免费次数和审计
+		logger.Error(fmt.Sprintf("Failed to record %s change of user %d: %v", change.Field, change.UserID, err))
+	}
+	return nil
+}
+
+// charge 玩家的免费改名次数用完后扣费，不需要扣费时返回nil
+func (s *Service) charge(change *database.NameChange, now time.Time) (*database.LedgerEntry, error) {
+	cost := s.config.RenameCost
+	if change.By != database.RenameByPlayer || cost.Amount <= 0 {
+		return nil, nil
+	}
+	count, err := s.names.CountChanges(change.UserID, change.Field, database.RenameByPlayer)
+	if err != nil {
+		return nil, err
+	}
+	if count < int64(cost.Free) {
+		return nil, nil
+	}
+
+	txID := fmt.Sprintf("rename:%d:%d", change.UserID, now.UnixNano())
+	debit := &database.LedgerEntry{
+		TxID:     txID,
+		BatchID:  txID,
+		UserID:   change.UserID,
+		Currency: cost.Currency,
+		Amount:   -cost.Amount,
+		Reason:   "rename " + change.Field,
+	}
+	if _, err := s.ledger.Spend(debit); err != nil {
+		if errors.Is(err, database.ErrInsufficientBalance) {
+			return nil, ErrInsufficient
+		}
+		return nil, err
+	}
+	change.Currency, change.Cost, change.TxID = cost.Currency, cost.Amount, txID
+	return debit, nil
+}
+
+// update 写入新名称，昵称需再查一次占用记录建立前注册的同名用户
+func (s *Service) update(change *database.NameChange) error {
+	if change.Field == database.NameFieldNickname {
+		inUse, err := s.users.NicknameInUse(change.NewName, change.UserID)
+		if err != nil {
+			return err
+		}
+		if inUse {
+			return ErrTaken
+		}
+	}
+
+	updated, err := s.users.SetName(change.UserID, change.Field, change.OldName, change.NewName)
+	if errors.Is(err, database.ErrIdentityTaken) {
+		return ErrTaken
+	}
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrConflict
+	}
+	return nil
+}
+
+// History 玩家的改名记录
+func (s *Service) History(userID uint64, limit int64) ([]*database.NameChange, error) {
+	return s.names.History(userID, limit)
+}
+
+// FindByOldName 按曾用名查找改名记录，GM用于查找改名后的玩家
+func (s *Service) FindByOldName(name string, limit int64) ([]*database.NameChange, error) {
+	return s.names.FindByOldName(name, limit)
+}
@@ -0,0 +1,106 @@
+package network
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/security"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+func init() {
+	logger.InitGlobalLogger(&logger.LogConfig{
+		Level:  "error",
+		Format: "console",
+		Output: "stderr",
+	})
+}
+
+// newBenchFrame 构造网关收到的帧（长度头+消息ID+消息体），消息体为典型大小的请求
+func newBenchFrame(b *testing.B) []byte {
+	body, err := proto.Marshal(&proto.BaseRequest{
+		Header: &proto.MessageHeader{
+			MsgId:     2001,
+			Seq:       42,
+			UserId:    10001,
+			SessionId: "bench-session",
+		},
+		Data: make([]byte, 256),
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	payload := make([]byte, FrameMsgIDSize+len(body))
+	binary.BigEndian.PutUint32(payload, 2001)
+	copy(payload[FrameMsgIDSize:], body)
+
+	frame, err := EncodeFrame(payload)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return frame
+}
+
+// BenchmarkFrameParse 长度头校验、消息ID解析和消息体反序列化
+func BenchmarkFrameParse(b *testing.B) {
+	frame := newBenchFrame(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		length, err := ParseFrameLength(frame[:FrameLengthSize])
+		if err != nil {
+			b.Fatal(err)
+		}
+		_, body, err := ParseFrame(frame[FrameLengthSize : FrameLengthSize+int(length)])
+		if err != nil {
+			b.Fatal(err)
+		}
+		var request proto.BaseRequest
+		if err := DefaultCodec().Unmarshal(body, &request); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkFrameParseHMAC 启用HMAC帧校验时的解析
+func BenchmarkFrameParseHMAC(b *testing.B) {
+	securityManager, err := security.NewSecurityManager()
+	if err != nil {
+		b.Fatal(err)
+	}
+	integrity, err := NewFrameIntegrity(IntegrityHMAC, false,
+		securityManager.GenerateSignatureWithKey, securityManager.VerifySignatureWithKey)
+	if err != nil {
+		b.Fatal(err)
+	}
+	kx, err := NewKeyExchange()
+	if err != nil {
+		b.Fatal(err)
+	}
+	key, err := kx.SessionKey(kx.PublicKey())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	signed := integrity.Sign(key, newBenchFrame(b)[FrameLengthSize:])
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		verified, err := integrity.Verify(key, signed)
+		if err != nil {
+			b.Fatal(err)
+		}
+		_, body, err := ParseFrame(verified)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var request proto.BaseRequest
+		if err := DefaultCodec().Unmarshal(body, &request); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,590 @@
+package network
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	protov1 "github.com/golang/protobuf/proto"
+	flatbuffers "github.com/google/flatbuffers/go"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// 编解码格式
+const (
+	CodecProtobuf    = "protobuf"
+	CodecJSON        = "json"
+	CodecFlatBuffers = "flatbuffers"
+)
+
+// 帧格式常量
+const (
+	FrameLengthSize = 4           // 长度头字节数
+	FrameMsgIDSize  = 4           // 消息ID字节数
+	MaxFrameSize    = 1024 * 1024 // 最大帧长度1MB
+)
+
+// Codec 消息编解码器接口
+type Codec interface {
+	Name() string
+	Marshal(msg proto.Message) ([]byte, error)
+	Unmarshal(data []byte, msg proto.Message) error
+}
+
+// ProtobufCodec Protobuf编解码器
+type ProtobufCodec struct{}
+
+// Name 获取编解码器名称
+func (c *ProtobufCodec) Name() string {
+	return CodecProtobuf
+}
+
+// Marshal 编码
+func (c *ProtobufCodec) Marshal(msg proto.Message) ([]byte, error) {
+	return proto.Marshal(msg)
+}
+
+// Unmarshal 解码
+func (c *ProtobufCodec) Unmarshal(data []byte, msg proto.Message) error {
+	return proto.Unmarshal(data, msg)
+}
+
+// JSONCodec JSON编解码器，便于调试抓包
+type JSONCodec struct{}
+
+// Name 获取编解码器名称
+func (c *JSONCodec) Name() string {
+	return CodecJSON
+}
+
+// Marshal 编码
+func (c *JSONCodec) Marshal(msg proto.Message) ([]byte, error) {
+	return json.Marshal(msg)
+}
+
+// Unmarshal 解码
+func (c *JSONCodec) Unmarshal(data []byte, msg proto.Message) error {
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, msg)
+}
+
+// FlatBuffers编码限制
+const (
+	flatBuffersMaxDepth  = 64              // 最大嵌套层数，防止恶意数据构造循环引用
+	flatBuffersMaxField  = (1<<16 - 4) / 2 // vtable偏移为16位，字段编号不能超过该值
+	flatBuffersEntrySize = 2               // map条目表的槽位数: 0为键，1为值
+)
+
+// FlatBuffersCodec FlatBuffers编解码器，按protobuf消息定义映射为FlatBuffers表，无需额外维护schema
+//
+// 字段编号n对应表的第n-1个槽位，嵌套消息为子表，repeated字段为向量，map为按键排序的条目表向量
+type FlatBuffersCodec struct{}
+
+// Name 获取编解码器名称
+func (c *FlatBuffersCodec) Name() string {
+	return CodecFlatBuffers
+}
+
+// Marshal 编码
+func (c *FlatBuffersCodec) Marshal(msg proto.Message) ([]byte, error) {
+	builder := flatbuffers.NewBuilder(256)
+	root, err := buildFlatTable(builder, protov1.MessageReflect(msg), 0)
+	if err != nil {
+		return nil, err
+	}
+	builder.Finish(root)
+	return builder.FinishedBytes(), nil
+}
+
+// Unmarshal 解码，数据中的偏移越界等错误返回error
+func (c *FlatBuffersCodec) Unmarshal(data []byte, msg proto.Message) (err error) {
+	msg.Reset()
+	if len(data) == 0 {
+		return nil
+	}
+	if len(data) < flatbuffers.SizeUOffsetT {
+		return fmt.Errorf("flatbuffers data too short: %d bytes", len(data))
+	}
+
+	// flatbuffers运行时不做边界检查，越界访问以panic的形式出现
+	defer func() {
+		if r := recover(); r != nil {
+			msg.Reset()
+			err = fmt.Errorf("malformed flatbuffers data: %v", r)
+		}
+	}()
+
+	table := &flatbuffers.Table{Bytes: data, Pos: flatbuffers.GetUOffsetT(data)}
+	d := &flatDecoder{bytes: len(data), elements: len(data)}
+	return d.readFlatTable(table, protov1.MessageReflect(msg), 0)
+}
+
+// flatDecoder 单次解码的配额。向量元素可以指向相同的偏移，少量数据即可展开为大量分配，
+// 正常编码的数据中每个元素、子表和字符串字节都至少占用一个不同的输入字节，因此两者都不超过输入长度
+type flatDecoder struct {
+	bytes    int // 剩余可复制的字符串和字节数组字节数
+	elements int // 剩余可解码的元素、map条目和子表个数
+}
+
+// consume 扣减配额，超出时返回错误
+func (d *flatDecoder) consume(elements, bytes int) error {
+	d.elements -= elements
+	d.bytes -= bytes
+	if d.elements < 0 || d.bytes < 0 {
+		return fmt.Errorf("flatbuffers data expands beyond its size")
+	}
+	return nil
+}
+
+// isFlatScalar 字段是否直接存放在表中，其余类型存放偏移
+func isFlatScalar(kind protoreflect.Kind) bool {
+	switch kind {
+	case protoreflect.StringKind, protoreflect.BytesKind, protoreflect.MessageKind, protoreflect.GroupKind:
+		return false
+	}
+	return true
+}
+
+// flatScalarSize 标量在向量中的字节数
+func flatScalarSize(kind protoreflect.Kind) int {
+	switch kind {
+	case protoreflect.BoolKind:
+		return flatbuffers.SizeBool
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind, protoreflect.DoubleKind:
+		return flatbuffers.SizeInt64
+	}
+	return flatbuffers.SizeInt32
+}
+
+// flatSlot 字段编号对应的槽位
+func flatSlot(fd protoreflect.FieldDescriptor) (int, error) {
+	if fd.Number() > flatBuffersMaxField {
+		return 0, fmt.Errorf("field %s number %d exceeds flatbuffers limit", fd.FullName(), fd.Number())
+	}
+	return int(fd.Number()) - 1, nil
+}
+
+// buildFlatTable 编码消息为表，偏移类型的字段需要在开始表之前写入
+func buildFlatTable(b *flatbuffers.Builder, m protoreflect.Message, depth int) (flatbuffers.UOffsetT, error) {
+	if depth > flatBuffersMaxDepth {
+		return 0, fmt.Errorf("message %s nested too deep", m.Descriptor().FullName())
+	}
+
+	fields := m.Descriptor().Fields()
+	offsets := make(map[protoreflect.FieldNumber]flatbuffers.UOffsetT)
+	slots := 0
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !m.Has(fd) {
+			continue
+		}
+		slot, err := flatSlot(fd)
+		if err != nil {
+			return 0, err
+		}
+		if slot+1 > slots {
+			slots = slot + 1
+		}
+
+		var offset flatbuffers.UOffsetT
+		switch {
+		case fd.IsMap():
+			offset, err = buildFlatMap(b, fd, m.Get(fd).Map(), depth)
+		case fd.IsList():
+			offset, err = buildFlatList(b, fd, m.Get(fd).List(), depth)
+		case !isFlatScalar(fd.Kind()):
+			offset, err = buildFlatValue(b, fd, m.Get(fd), depth)
+		default:
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		offsets[fd.Number()] = offset
+	}
+
+	b.StartObject(slots)
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !m.Has(fd) {
+			continue
+		}
+		slot := int(fd.Number()) - 1
+		if offset, exists := offsets[fd.Number()]; exists {
+			b.PrependUOffsetTSlot(slot, offset, 0)
+		} else {
+			prependFlatScalarSlot(b, slot, fd.Kind(), m.Get(fd))
+		}
+	}
+	return b.EndObject(), nil
+}
+
+// buildFlatValue 编码字符串、字节数组和嵌套消息，返回偏移
+func buildFlatValue(b *flatbuffers.Builder, fd protoreflect.FieldDescriptor, v protoreflect.Value, depth int) (flatbuffers.UOffsetT, error) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return b.CreateString(v.String()), nil
+	case protoreflect.BytesKind:
+		return b.CreateByteVector(v.Bytes()), nil
+	default:
+		return buildFlatTable(b, v.Message(), depth+1)
+	}
+}
+
+// buildFlatList 编码repeated字段，标量直接存放，其余类型存放偏移
+func buildFlatList(b *flatbuffers.Builder, fd protoreflect.FieldDescriptor, list protoreflect.List, depth int) (flatbuffers.UOffsetT, error) {
+	n := list.Len()
+	if isFlatScalar(fd.Kind()) {
+		size := flatScalarSize(fd.Kind())
+		b.StartVector(size, n, size)
+		for i := n - 1; i >= 0; i-- {
+			prependFlatScalar(b, fd.Kind(), list.Get(i))
+		}
+		return b.EndVector(n), nil
+	}
+
+	offsets := make([]flatbuffers.UOffsetT, n)
+	for i := 0; i < n; i++ {
+		offset, err := buildFlatValue(b, fd, list.Get(i), depth)
+		if err != nil {
+			return 0, err
+		}
+		offsets[i] = offset
+	}
+	return b.CreateVectorOfTables(offsets), nil
+}
+
+// buildFlatMap 编码map字段，条目按键排序保证编码结果稳定
+func buildFlatMap(b *flatbuffers.Builder, fd protoreflect.FieldDescriptor, entries protoreflect.Map, depth int) (flatbuffers.UOffsetT, error) {
+	keyFd, valueFd := fd.MapKey(), fd.MapValue()
+
+	keys := make([]protoreflect.MapKey, 0, entries.Len())
+	entries.Range(func(key protoreflect.MapKey, _ protoreflect.Value) bool {
+		keys = append(keys, key)
+		return true
+	})
+	sort.Slice(keys, func(i, j int) bool {
+		return lessMapKey(keys[i], keys[j])
+	})
+
+	offsets := make([]flatbuffers.UOffsetT, len(keys))
+	for i, key := range keys {
+		value := entries.Get(key)
+
+		var keyOffset, valueOffset flatbuffers.UOffsetT
+		var err error
+		if !isFlatScalar(keyFd.Kind()) {
+			keyOffset = b.CreateString(key.String())
+		}
+		if !isFlatScalar(valueFd.Kind()) {
+			if valueOffset, err = buildFlatValue(b, valueFd, value, depth); err != nil {
+				return 0, err
+			}
+		}
+
+		b.StartObject(flatBuffersEntrySize)
+		if isFlatScalar(keyFd.Kind()) {
+			prependFlatScalarSlot(b, 0, keyFd.Kind(), key.Value())
+		} else {
+			b.PrependUOffsetTSlot(0, keyOffset, 0)
+		}
+		if isFlatScalar(valueFd.Kind()) {
+			prependFlatScalarSlot(b, 1, valueFd.Kind(), value)
+		} else {
+			b.PrependUOffsetTSlot(1, valueOffset, 0)
+		}
+		offsets[i] = b.EndObject()
+	}
+	return b.CreateVectorOfTables(offsets), nil
+}
+
+// lessMapKey 比较map键，键只能是整数、布尔或字符串
+func lessMapKey(a, b protoreflect.MapKey) bool {
+	switch v := a.Interface().(type) {
+	case string:
+		return v < b.String()
+	case bool:
+		return !v && b.Bool()
+	case int32, int64:
+		return a.Int() < b.Int()
+	default:
+		return a.Uint() < b.Uint()
+	}
+}
+
+// prependFlatScalarSlot 写入标量字段，零值不写入
+func prependFlatScalarSlot(b *flatbuffers.Builder, slot int, kind protoreflect.Kind, v protoreflect.Value) {
+	switch kind {
+	case protoreflect.BoolKind:
+		b.PrependBoolSlot(slot, v.Bool(), false)
+	case protoreflect.EnumKind:
+		b.PrependInt32Slot(slot, int32(v.Enum()), 0)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		b.PrependInt32Slot(slot, int32(v.Int()), 0)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		b.PrependUint32Slot(slot, uint32(v.Uint()), 0)
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		b.PrependInt64Slot(slot, v.Int(), 0)
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		b.PrependUint64Slot(slot, v.Uint(), 0)
+	case protoreflect.FloatKind:
+		b.PrependFloat32Slot(slot, float32(v.Float()), 0)
+	case protoreflect.DoubleKind:
+		b.PrependFloat64Slot(slot, v.Float(), 0)
+	}
+}
+
+// prependFlatScalar 写入向量中的标量元素
+func prependFlatScalar(b *flatbuffers.Builder, kind protoreflect.Kind, v protoreflect.Value) {
+	switch kind {
+	case protoreflect.BoolKind:
+		b.PrependBool(v.Bool())
+	case protoreflect.EnumKind:
+		b.PrependInt32(int32(v.Enum()))
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		b.PrependInt32(int32(v.Int()))
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		b.PrependUint32(uint32(v.Uint()))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		b.PrependInt64(v.Int())
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		b.PrependUint64(v.Uint())
+	case protoreflect.FloatKind:
+		b.PrependFloat32(float32(v.Float()))
+	case protoreflect.DoubleKind:
+		b.PrependFloat64(v.Float())
+	}
+}
+
+// readFlatTable 从表中解码消息，未知槽位忽略
+func (d *flatDecoder) readFlatTable(t *flatbuffers.Table, m protoreflect.Message, depth int) error {
+	if depth > flatBuffersMaxDepth {
+		return fmt.Errorf("message %s nested too deep", m.Descriptor().FullName())
+	}
+	if err := d.consume(1, 0); err != nil {
+		return err
+	}
+
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		slot, err := flatSlot(fd)
+		if err != nil {
+			return err
+		}
+		offset := t.Offset(flatbuffers.VOffsetT(4 + 2*slot))
+		if offset == 0 {
+			continue
+		}
+		pos := t.Pos + flatbuffers.UOffsetT(offset)
+
+		switch {
+		case fd.IsMap():
+			err = d.readFlatMap(t, pos, fd, m.Mutable(fd).Map(), depth)
+		case fd.IsList():
+			err = d.readFlatList(t, pos, fd, m.Mutable(fd).List(), depth)
+		default:
+			var value protoreflect.Value
+			value, err = d.readFlatValue(t, pos, fd, func() protoreflect.Value { return m.NewField(fd) }, depth)
+			if err == nil {
+				m.Set(fd, value)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readFlatVector 读取向量的元素个数和起始位置，检查向量不超出数据范围
+func readFlatVector(t *flatbuffers.Table, pos flatbuffers.UOffsetT, elemSize int) (int, flatbuffers.UOffsetT, error) {
+	vector := t.Indirect(pos)
+	n := int(flatbuffers.GetUOffsetT(t.Bytes[vector:]))
+	start := vector + flatbuffers.UOffsetT(flatbuffers.SizeUOffsetT)
+	if int(start)+n*elemSize > len(t.Bytes) {
+		return 0, 0, fmt.Errorf("vector of %d elements out of range", n)
+	}
+	return n, start, nil
+}
+
+// readFlatList 解码repeated字段
+func (d *flatDecoder) readFlatList(t *flatbuffers.Table, pos flatbuffers.UOffsetT, fd protoreflect.FieldDescriptor, list protoreflect.List, depth int) error {
+	elemSize := flatbuffers.SizeUOffsetT
+	if isFlatScalar(fd.Kind()) {
+		elemSize = flatScalarSize(fd.Kind())
+	}
+	n, start, err := readFlatVector(t, pos, elemSize)
+	if err != nil {
+		return err
+	}
+	if err := d.consume(n, 0); err != nil {
+		return err
+	}
+
+	for i := 0; i < n; i++ {
+		value, err := d.readFlatValue(t, start+flatbuffers.UOffsetT(i*elemSize), fd, list.NewElement, depth)
+		if err != nil {
+			return err
+		}
+		list.Append(value)
+	}
+	return nil
+}
+
+// readFlatMap 解码map字段，条目缺少键或值时使用零值
+func (d *flatDecoder) readFlatMap(t *flatbuffers.Table, pos flatbuffers.UOffsetT, fd protoreflect.FieldDescriptor, entries protoreflect.Map, depth int) error {
+	n, start, err := readFlatVector(t, pos, flatbuffers.SizeUOffsetT)
+	if err != nil {
+		return err
+	}
+	if err := d.consume(n, 0); err != nil {
+		return err
+	}
+
+	keyFd, valueFd := fd.MapKey(), fd.MapValue()
+	for i := 0; i < n; i++ {
+		entry := &flatbuffers.Table{Bytes: t.Bytes, Pos: t.Indirect(start + flatbuffers.UOffsetT(i*flatbuffers.SizeUOffsetT))}
+
+		key := keyFd.Default()
+		if offset := entry.Offset(4); offset != 0 {
+			if key, err = d.readFlatValue(entry, entry.Pos+flatbuffers.UOffsetT(offset), keyFd, nil, depth); err != nil {
+				return err
+			}
+		}
+
+		var value protoreflect.Value
+		if offset := entry.Offset(6); offset != 0 {
+			if value, err = d.readFlatValue(entry, entry.Pos+flatbuffers.UOffsetT(offset), valueFd, entries.NewValue, depth); err != nil {
+				return err
+			}
+		} else if isFlatScalar(valueFd.Kind()) {
+			value = valueFd.Default()
+		} else {
+			value = entries.NewValue()
+		}
+
+		entries.Set(key.MapKey(), value)
+	}
+	return nil
+}
+
+// readFlatValue 解码单个值，字符串和字节数组会复制并计入配额，解码结果不引用输入数据
+func (d *flatDecoder) readFlatValue(t *flatbuffers.Table, pos flatbuffers.UOffsetT, fd protoreflect.FieldDescriptor, newValue func() protoreflect.Value, depth int) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return protoreflect.ValueOfBool(t.GetBool(pos)), nil
+	case protoreflect.EnumKind:
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(t.GetInt32(pos))), nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return protoreflect.ValueOfInt32(t.GetInt32(pos)), nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return protoreflect.ValueOfUint32(t.GetUint32(pos)), nil
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return protoreflect.ValueOfInt64(t.GetInt64(pos)), nil
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return protoreflect.ValueOfUint64(t.GetUint64(pos)), nil
+	case protoreflect.FloatKind:
+		return protoreflect.ValueOfFloat32(t.GetFloat32(pos)), nil
+	case protoreflect.DoubleKind:
+		return protoreflect.ValueOfFloat64(t.GetFloat64(pos)), nil
+	case protoreflect.StringKind:
+		data := t.ByteVector(pos)
+		if err := d.consume(0, len(data)); err != nil {
+			return protoreflect.Value{}, err
+		}
+		return protoreflect.ValueOfString(string(data)), nil
+	case protoreflect.BytesKind:
+		data := t.ByteVector(pos)
+		if err := d.consume(0, len(data)); err != nil {
+			return protoreflect.Value{}, err
+		}
+		return protoreflect.ValueOfBytes(append([]byte{}, data...)), nil
+	default:
+		value := newValue()
+		child := &flatbuffers.Table{Bytes: t.Bytes, Pos: t.Indirect(pos)}
+		if err := d.readFlatTable(child, value.Message(), depth+1); err != nil {
+			return protoreflect.Value{}, err
+		}
+		return value, nil
+	}
+}
+
+var (
+	codecs     = make(map[string]Codec)
+	codecMutex sync.RWMutex
+)
+
+func init() {
+	RegisterCodec(&ProtobufCodec{})
+	RegisterCodec(&JSONCodec{})
+	RegisterCodec(&FlatBuffersCodec{})
+}
+
+// RegisterCodec 注册编解码器，可用于接入其他格式
+func RegisterCodec(codec Codec) {
+	codecMutex.Lock()
+	defer codecMutex.Unlock()
+
+	codecs[codec.Name()] = codec
+}
+
+// GetCodec 按名称获取编解码器
+func GetCodec(name string) (Codec, bool) {
+	codecMutex.RLock()
+	defer codecMutex.RUnlock()
+
+	codec, exists := codecs[name]
+	return codec, exists
+}
+
+// DefaultCodec 获取默认编解码器
+func DefaultCodec() Codec {
+	codec, _ := GetCodec(CodecProtobuf)
+	return codec
+}
+
+// ParseFrame 解析帧内容（不含长度头），返回消息ID和消息体
+func ParseFrame(frame []byte) (uint32, []byte, error) {
+	if len(frame) < FrameMsgIDSize {
+		return 0, nil, fmt.Errorf("frame too short: %d bytes", len(frame))
+	}
+	if len(frame) > MaxFrameSize {
+		return 0, nil, fmt.Errorf("frame too large: %d bytes", len(frame))
+	}
+
+	msgID := binary.BigEndian.Uint32(frame[:FrameMsgIDSize])
+	return msgID, frame[FrameMsgIDSize:], nil
+}
+
+// EncodeFrame 添加长度头
+func EncodeFrame(payload []byte) ([]byte, error) {
+	if len(payload) > MaxFrameSize {
+		return nil, fmt.Errorf("payload too large: %d bytes", len(payload))
+	}
+
+	message := make([]byte, FrameLengthSize+len(payload))
+	binary.BigEndian.PutUint32(message[:FrameLengthSize], uint32(len(payload)))
+	copy(message[FrameLengthSize:], payload)
+	return message, nil
+}
+
+// ParseFrameLength 解析并校验长度头
+func ParseFrameLength(header []byte) (uint32, error) {
+	if len(header) != FrameLengthSize {
+		return 0, fmt.Errorf("invalid length header size: %d", len(header))
+	}
+
+	length := binary.BigEndian.Uint32(header)
+	if length == 0 || length > MaxFrameSize {
+		return 0, fmt.Errorf("invalid message length: %d", length)
+	}
+	return length, nil
+}
@@ -0,0 +1,167 @@
+package network
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	protov1 "github.com/golang/protobuf/proto"
+	flatbuffers "github.com/google/flatbuffers/go"
+
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// codecSamples 覆盖嵌套消息、repeated、map、字节数组和各种标量的消息
+func codecSamples() []proto.Message {
+	return []proto.Message{
+		&proto.BaseRequest{Header: &proto.MessageHeader{MsgId: 1002, Seq: 7, UserId: 1 << 40, Nonce: 3}, Data: []byte{0x0a, 0x00, 0xff}},
+		&proto.LoginRequest{Username: "player", Password: "secret", DeviceId: "device", Platform: "ios", Version: "1.2.0"},
+		&proto.MatchRequest{GameType: 2, Latencies: map[string]int32{"game1": 30, "game2": 85, "": -1}},
+		&proto.ActivityInfo{Id: "double_xp", StartTime: -1, EndTime: 1 << 40, Multipliers: map[string]float64{"xp": 2, "gold": 1.5}, GameTypes: []int32{1, -2, 3}},
+		&proto.SystemInfo{CpuUsage: 0.75, MemoryUsage: 12.5, Uptime: 3600},
+		&proto.MailInfo{MailId: 5, Title: "奖励", Rewards: []*proto.Reward{{ItemId: 1001, ItemType: 2, Quantity: 3}, {}}, IsRead: true},
+	}
+}
+
+func TestFlatBuffersRoundTrip(t *testing.T) {
+	codec, exists := GetCodec(CodecFlatBuffers)
+	if !exists {
+		t.Fatal("flatbuffers codec not registered")
+	}
+
+	for _, msg := range codecSamples() {
+		data, err := codec.Marshal(msg)
+		if err != nil {
+			t.Fatalf("failed to marshal %T: %v", msg, err)
+		}
+
+		decoded := reflect.New(reflect.TypeOf(msg).Elem()).Interface().(proto.Message)
+		if err := codec.Unmarshal(data, decoded); err != nil {
+			t.Fatalf("failed to unmarshal %T: %v", msg, err)
+		}
+		if !protov1.Equal(msg, decoded) {
+			t.Fatalf("%T does not round trip: %v != %v", msg, msg, decoded)
+		}
+
+		again, err := codec.Marshal(decoded)
+		if err != nil {
+			t.Fatalf("failed to re-marshal %T: %v", msg, err)
+		}
+		if string(again) != string(data) {
+			t.Fatalf("%T encoding is not stable", msg)
+		}
+	}
+}
+
+func TestFlatBuffersRejectsMalformed(t *testing.T) {
+	codec, _ := GetCodec(CodecFlatBuffers)
+	data, err := codec.Marshal(codecSamples()[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, input := range [][]byte{{0x01}, {0xff, 0xff, 0xff, 0x7f}, data[:len(data)/2]} {
+		var request proto.BaseRequest
+		if err := codec.Unmarshal(input, &request); err == nil {
+			t.Fatalf("expected error for malformed input %x", input)
+		}
+	}
+}
+
+// aliasedFlatVector 构造只有一个向量字段的表，向量的n个元素都指向target写入的同一个偏移
+func aliasedFlatVector(slot, n int, target func(b *flatbuffers.Builder) flatbuffers.UOffsetT) []byte {
+	b := flatbuffers.NewBuilder(0)
+	offset := target(b)
+	b.StartVector(flatbuffers.SizeUOffsetT, n, flatbuffers.SizeUOffsetT)
+	for i := 0; i < n; i++ {
+		b.PrependUOffsetT(offset)
+	}
+	vector := b.EndVector(n)
+	b.StartObject(slot + 1)
+	b.PrependUOffsetTSlot(slot, vector, 0)
+	b.Finish(b.EndObject())
+	return b.FinishedBytes()
+}
+
+// aliasedArgs GM命令参数向量的元素都指向同一个字符串
+func aliasedArgs(n, size int) []byte {
+	return aliasedFlatVector(1, n, func(b *flatbuffers.Builder) flatbuffers.UOffsetT {
+		return b.CreateByteString(bytes.Repeat([]byte{'x'}, size))
+	})
+}
+
+func TestFlatBuffersLimitsAliasedOffsets(t *testing.T) {
+	codec, _ := GetCodec(CodecFlatBuffers)
+
+	var request proto.GMCommandRequest
+	if err := codec.Unmarshal(aliasedArgs(2, 4), &request); err != nil {
+		t.Fatalf("small aliased vector rejected: %v", err)
+	}
+	if !reflect.DeepEqual(request.Args, []string{"xxxx", "xxxx"}) {
+		t.Fatalf("unexpected args %v", request.Args)
+	}
+
+	data := aliasedArgs(1000, 1000)
+	if err := codec.Unmarshal(data, &request); err == nil {
+		t.Fatalf("%d bytes expanded to %d bytes of strings without error", len(data), 1000*1000)
+	}
+}
+
+// fuzzDecode 用示例消息的编码作为初始输入，检查解码不会panic，
+// 解码成功的消息能重新编码并解码为相同的消息
+func fuzzDecode(f *testing.F, name string, newMessage func() proto.Message) {
+	codec, exists := GetCodec(name)
+	if !exists {
+		f.Fatalf("codec %s not registered", name)
+	}
+	for _, msg := range codecSamples() {
+		data, err := codec.Marshal(msg)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	f.Add([]byte{})
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		msg := newMessage()
+		if err := codec.Unmarshal(data, msg); err != nil {
+			return
+		}
+		encoded, err := codec.Marshal(msg)
+		if err != nil {
+			t.Fatalf("failed to re-encode decoded message: %v", err)
+		}
+		decoded := newMessage()
+		if err := codec.Unmarshal(encoded, decoded); err != nil {
+			t.Fatalf("failed to decode re-encoded message: %v", err)
+		}
+		if !protov1.Equal(msg, decoded) {
+			t.Fatalf("message does not round trip: %v != %v", msg, decoded)
+		}
+	})
+}
+
+// FuzzDecodeProtobuf 解码任意输入为带嵌套消息和repeated字段的消息
+func FuzzDecodeProtobuf(f *testing.F) {
+	fuzzDecode(f, CodecProtobuf, func() proto.Message { return &proto.MailInfo{} })
+}
+
+// FuzzDecodeJSON JSON编解码器的解码模糊测试
+func FuzzDecodeJSON(f *testing.F) {
+	fuzzDecode(f, CodecJSON, func() proto.Message { return &proto.MatchRequest{} })
+}
+
+// FuzzDecodeFlatBuffers FlatBuffers数据中的偏移和长度来自输入，越界时必须返回错误而不是panic
+func FuzzDecodeFlatBuffers(f *testing.F) {
+	// 奖励向量的元素都指向同一个子表
+	f.Add(aliasedFlatVector(7, 64, func(b *flatbuffers.Builder) flatbuffers.UOffsetT {
+		offset, err := buildFlatTable(b, protov1.MessageReflect(&proto.Reward{ItemId: 1001, Quantity: 3}), 0)
+		if err != nil {
+			f.Fatal(err)
+		}
+		return offset
+	}))
+	fuzzDecode(f, CodecFlatBuffers, func() proto.Message { return &proto.MailInfo{} })
+}
@@ -0,0 +1,120 @@
+package network
+
+import (
+	"sync"
+	"time"
+)
+
+// DedupCache 客户端重试消息去重缓存
+// 按会话记录最近处理过的客户端消息ID，重复提交时直接回放缓存的响应
+type DedupCache struct {
+	sessions  map[string]map[string]*dedupEntry
+	ttl       time.Duration
+	lastSweep time.Time
+	mutex     sync.Mutex
+}
+
+// dedupEntry 去重记录
+type dedupEntry struct {
+	response []byte // 为nil表示原请求仍在处理中
+	expireAt time.Time
+}
+
+// NewDedupCache 创建去重缓存
+func NewDedupCache(ttl time.Duration) *DedupCache {
+	return &DedupCache{
+		sessions:  make(map[string]map[string]*dedupEntry),
+		ttl:       ttl,
+		lastSweep: time.Now(),
+	}
+}
+
+// Begin 开始处理消息
+// 返回duplicate为true表示该消息已处理过或正在处理，cached为已缓存的响应（处理中时为nil）
+func (dc *DedupCache) Begin(sessionKey, clientMsgID string) (cached []byte, duplicate bool) {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	now := time.Now()
+	if now.Sub(dc.lastSweep) > dc.ttl {
+		dc.sweep(now)
+	}
+
+	entries, exists := dc.sessions[sessionKey]
+	if !exists {
+		entries = make(map[string]*dedupEntry)
+		dc.sessions[sessionKey] = entries
+	}
+
+	if entry, exists := entries[clientMsgID]; exists && now.Before(entry.expireAt) {
+		return entry.response, true
+	}
+
+	entries[clientMsgID] = &dedupEntry{
+		expireAt: now.Add(dc.ttl),
+	}
+	return nil, false
+}
+
+// Complete 记录消息的响应，供重试时回放
+func (dc *DedupCache) Complete(sessionKey, clientMsgID string, response []byte) {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	entries, exists := dc.sessions[sessionKey]
+	if !exists {
+		return
+	}
+
+	if entry, exists := entries[clientMsgID]; exists {
+		entry.response = response
+		entry.expireAt = time.Now().Add(dc.ttl)
+	}
+}
+
+// Abort 放弃消息记录，允许客户端重试时重新执行
+func (dc *DedupCache) Abort(sessionKey, clientMsgID string) {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	if entries, exists := dc.sessions[sessionKey]; exists {
+		if entry, exists := entries[clientMsgID]; exists && entry.response == nil {
+			delete(entries, clientMsgID)
+		}
+	}
+}
+
+// ClearSession 清理会话的全部去重记录
+func (dc *DedupCache) ClearSession(sessionKey string) {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	delete(dc.sessions, sessionKey)
+}
+
+// Size 获取缓存的记录数
+func (dc *DedupCache) Size() int {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	count := 0
+	for _, entries := range dc.sessions {
+		count += len(entries)
+	}
+	return count
+}
+
+// sweep 清理过期记录（调用方需持有锁）
+func (dc *DedupCache) sweep(now time.Time) {
+	for sessionKey, entries := range dc.sessions {
+		for msgID, entry := range entries {
+			if now.After(entry.expireAt) {
+				delete(entries, msgID)
+			}
+		}
+		if len(entries) == 0 {
+			delete(dc.sessions, sessionKey)
+		}
+	}
+	dc.lastSweep = now
+}
@@ -0,0 +1,84 @@
+package network
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// frameSeed 按网关的帧格式编码消息，作为模糊测试的初始输入
+func frameSeed(t testing.TB, msgID uint32, body []byte) []byte {
+	frame := make([]byte, FrameMsgIDSize+len(body))
+	binary.BigEndian.PutUint32(frame, msgID)
+	copy(frame[FrameMsgIDSize:], body)
+	encoded, err := EncodeFrame(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encoded
+}
+
+// FuzzFrame 按网关的读取方式从输入中依次解析长度头和帧内容，
+// 再用各编解码器解析消息体，检查帧的编码能够还原
+func FuzzFrame(f *testing.F) {
+	request := &proto.BaseRequest{Header: &proto.MessageHeader{MsgId: 1002, Seq: 7, Nonce: 3}}
+	for _, name := range []string{CodecProtobuf, CodecJSON, CodecFlatBuffers} {
+		codec, _ := GetCodec(name)
+		body, err := codec.Marshal(request)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(frameSeed(f, 1002, body))
+	}
+	f.Add(append(frameSeed(f, 1000, nil), frameSeed(f, 1003, []byte{0x0a, 0x00})...))
+	f.Add([]byte{0, 0, 0, 2, 0, 1})
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		reader := bytes.NewReader(data)
+		header := make([]byte, FrameLengthSize)
+		for {
+			if _, err := io.ReadFull(reader, header); err != nil {
+				return
+			}
+			length, err := ParseFrameLength(header)
+			if err != nil {
+				return
+			}
+			frame := make([]byte, length)
+			if _, err := io.ReadFull(reader, frame); err != nil {
+				return
+			}
+
+			msgID, body, err := ParseFrame(frame)
+			if err != nil {
+				continue
+			}
+			if len(body) != len(frame)-FrameMsgIDSize {
+				t.Fatalf("message %d body has %d bytes, frame has %d", msgID, len(body), len(frame))
+			}
+
+			encoded, err := EncodeFrame(frame)
+			if err != nil {
+				t.Fatalf("failed to encode frame: %v", err)
+			}
+			if decoded, err := ParseFrameLength(encoded[:FrameLengthSize]); err != nil || decoded != length {
+				t.Fatalf("frame length does not round trip: %d != %d (%v)", decoded, length, err)
+			}
+
+			for _, name := range []string{CodecProtobuf, CodecJSON, CodecFlatBuffers} {
+				codec, _ := GetCodec(name)
+				var request proto.BaseRequest
+				if err := codec.Unmarshal(body, &request); err != nil {
+					continue
+				}
+				if _, err := codec.Marshal(&request); err != nil {
+					t.Fatalf("%s codec failed to re-encode decoded request: %v", name, err)
+				}
+			}
+		}
+	})
+}
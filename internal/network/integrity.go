@@ -0,0 +1,197 @@
+package network
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"hash/crc32"
+)
+
+// 帧校验模式
+const (
+	IntegrityNone = "none" // 不校验
+	IntegrityCRC  = "crc"  // CRC32校验，仅检测传输损坏
+	IntegrityHMAC = "hmac" // 基于会话密钥的HMAC签名，检测篡改
+)
+
+// 帧校验尾部长度（十六进制字符数）
+const (
+	crcSignatureSize  = 8
+	hmacSignatureSize = 64
+)
+
+// SignFunc 签名函数
+type SignFunc func(key, data []byte) string
+
+// VerifyFunc 验签函数
+type VerifyFunc func(key, data []byte, signature string) bool
+
+// FrameIntegrity 帧完整性校验与重放保护
+type FrameIntegrity struct {
+	mode             string
+	replayProtection bool
+	sign             SignFunc
+	verify           VerifyFunc
+}
+
+// NewFrameIntegrity 创建帧校验器，HMAC模式需要提供签名和验签函数
+func NewFrameIntegrity(mode string, replayProtection bool, sign SignFunc, verify VerifyFunc) (*FrameIntegrity, error) {
+	switch mode {
+	case "", IntegrityNone:
+		mode = IntegrityNone
+	case IntegrityCRC:
+	case IntegrityHMAC:
+		if sign == nil || verify == nil {
+			return nil, fmt.Errorf("hmac frame integrity requires sign and verify functions")
+		}
+	default:
+		return nil, fmt.Errorf("unknown frame integrity mode: %s", mode)
+	}
+
+	return &FrameIntegrity{
+		mode:             mode,
+		replayProtection: replayProtection,
+		sign:             sign,
+		verify:           verify,
+	}, nil
+}
+
+// Mode 获取校验模式
+func (fi *FrameIntegrity) Mode() string {
+	return fi.mode
+}
+
+// Enabled 是否启用帧校验
+func (fi *FrameIntegrity) Enabled() bool {
+	return fi.mode != IntegrityNone
+}
+
+// RequiresSessionKey 是否需要会话密钥
+func (fi *FrameIntegrity) RequiresSessionKey() bool {
+	return fi.mode == IntegrityHMAC
+}
+
+// signatureSize 获取校验尾部长度
+func (fi *FrameIntegrity) signatureSize() int {
+	switch fi.mode {
+	case IntegrityCRC:
+		return crcSignatureSize
+	case IntegrityHMAC:
+		return hmacSignatureSize
+	}
+	return 0
+}
+
+// Sign 为帧内容追加校验尾部
+func (fi *FrameIntegrity) Sign(key, frame []byte) []byte {
+	var signature string
+	switch fi.mode {
+	case IntegrityCRC:
+		signature = fmt.Sprintf("%08x", crc32.ChecksumIEEE(frame))
+	case IntegrityHMAC:
+		signature = fi.sign(key, frame)
+	default:
+		return frame
+	}
+
+	signed := make([]byte, 0, len(frame)+len(signature))
+	signed = append(signed, frame...)
+	return append(signed, signature...)
+}
+
+// Verify 校验帧内容并去除校验尾部
+func (fi *FrameIntegrity) Verify(key, frame []byte) ([]byte, error) {
+	size := fi.signatureSize()
+	if size == 0 {
+		return frame, nil
+	}
+	if len(frame) < size+FrameMsgIDSize {
+		return nil, fmt.Errorf("frame too short for %s signature: %d bytes", fi.mode, len(frame))
+	}
+
+	payload := frame[:len(frame)-size]
+	signature := string(frame[len(frame)-size:])
+
+	switch fi.mode {
+	case IntegrityCRC:
+		expected := fmt.Sprintf("%08x", crc32.ChecksumIEEE(payload))
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(signature)) != 1 {
+			return nil, fmt.Errorf("frame checksum mismatch")
+		}
+	case IntegrityHMAC:
+		if len(key) == 0 {
+			return nil, fmt.Errorf("session key not established")
+		}
+		if !fi.verify(key, payload, signature) {
+			return nil, fmt.Errorf("frame signature mismatch")
+		}
+	}
+
+	return payload, nil
+}
+
+// CheckNonce 检查消息序号是否单调递增，防止重放
+func (fi *FrameIntegrity) CheckNonce(conn *Connection, nonce uint64) error {
+	if !fi.replayProtection {
+		return nil
+	}
+	if nonce <= conn.LastNonce {
+		return fmt.Errorf("replayed or out-of-order nonce %d (last %d)", nonce, conn.LastNonce)
+	}
+
+	conn.LastNonce = nonce
+	return nil
+}
+
+// sessionKeyInfo 派生帧签名会话密钥的HKDF info
+const sessionKeyInfo = "lufy frame integrity v1"
+
+// KeyExchange 协商帧签名会话密钥的X25519临时密钥对。双方在握手时交换公钥，
+// 各自按共享密钥经HKDF-SHA256派生会话密钥，会话密钥不在网络上传输
+type KeyExchange struct {
+	private *ecdh.PrivateKey
+}
+
+// NewKeyExchange 生成临时密钥对，每次握手使用新的密钥对
+func NewKeyExchange() (*KeyExchange, error) {
+	private, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key pair: %v", err)
+	}
+	return &KeyExchange{private: private}, nil
+}
+
+// PublicKey 发送给对方的公钥
+func (kx *KeyExchange) PublicKey() []byte {
+	return kx.private.PublicKey().Bytes()
+}
+
+// SessionKey 按对方的公钥派生会话密钥，盐为按字节序排列的双方公钥，双方得到相同的密钥
+func (kx *KeyExchange) SessionKey(peerPublic []byte) ([]byte, error) {
+	peer, err := ecdh.X25519().NewPublicKey(peerPublic)
+	if err != nil {
+		return nil, fmt.Errorf("invalid peer public key: %v", err)
+	}
+	shared, err := kx.private.ECDH(peer)
+	if err != nil {
+		return nil, fmt.Errorf("key exchange failed: %v", err)
+	}
+
+	own := kx.PublicKey()
+	salt := append(append([]byte{}, own...), peerPublic...)
+	if bytes.Compare(peerPublic, own) < 0 {
+		salt = append(append([]byte{}, peerPublic...), own...)
+	}
+
+	// HKDF-SHA256，输出32字节只需展开一个块
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(shared)
+	expand := hmac.New(sha256.New, extract.Sum(nil))
+	expand.Write([]byte(sessionKeyInfo))
+	expand.Write([]byte{1})
+	return expand.Sum(nil), nil
+}
@@ -0,0 +1,441 @@
+package network
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xtaci/kcp-go/v5"
+
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// 基于kcp-go的可靠UDP传输，用于实时玩法的低延迟流量
+// 会话使用流模式，上层沿用TCP的长度前缀帧格式
+const (
+	kcpTokenSize   = 32
+	kcpConnIDBase  = 1 << 32
+	kcpFastResend  = 2               // 被跳过确认的次数达到该值时快速重传
+	kcpMinMTU      = 64              // 小于该值的MTU按默认值处理
+	kcpMaxMTU      = 1500            // kcp-go支持的最大MTU
+	kcpCleanupTick = 1 * time.Second // 清理过期票据和失去认证的会话的间隔
+)
+
+// KCPConfig KCP传输配置
+type KCPConfig struct {
+	Enabled     bool     `yaml:"enabled"`
+	Port        int      `yaml:"port"`
+	MTU         int      `yaml:"mtu"`          // 单个UDP包的最大字节数，超出的数据拆分为多个分片
+	Interval    int      `yaml:"interval"`     // 刷新间隔(毫秒)
+	WindowSize  int      `yaml:"window_size"`  // 收发窗口(分片数)
+	IdleTimeout int      `yaml:"idle_timeout"` // 会话空闲超时(秒)
+	BindTimeout int      `yaml:"bind_timeout"` // 绑定票据有效期(秒)，客户端在此时间内未绑定成功应回退到TCP
+	GameTypes   []string `yaml:"game_types"`   // 使用KCP的玩法类型，为空时所有玩法都可以使用
+}
+
+// KCPSession KCP会话，实现net.Conn，可直接作为Connection的底层连接
+type KCPSession struct {
+	*kcp.UDPSession
+	bound *Connection // 绑定的TCP连接
+}
+
+// kcpTicket 会话绑定票据，将KCP会话绑定到已认证的TCP连接
+type kcpTicket struct {
+	token   []byte
+	conn    *Connection
+	userID  uint64
+	expires time.Time
+}
+
+// KCPServer KCP服务器，KCP会话必须先用TCP连接签发的票据绑定，继承TCP连接的认证状态
+type KCPServer struct {
+	address      string
+	config       KCPConfig
+	listener     *kcp.Listener
+	handler      MessageHandler
+	panicHandler func(conn *Connection, value interface{}, stack []byte)
+	sessions     map[*KCPSession]struct{}
+	tickets      map[uint32]*kcpTicket
+	connections  sync.Map // userID -> *Connection
+	connCounter  uint64
+	running      int32
+	ctx          context.Context
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+	mutex        sync.Mutex
+}
+
+// NewKCPServer 创建KCP服务器
+func NewKCPServer(address string, config KCPConfig, handler MessageHandler) *KCPServer {
+	if config.MTU < kcpMinMTU || config.MTU > kcpMaxMTU {
+		config.MTU = 1400
+	}
+	if config.Interval <= 0 {
+		config.Interval = 10
+	}
+	if config.WindowSize <= 0 {
+		config.WindowSize = 128
+	}
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = 30
+	}
+	if config.BindTimeout <= 0 {
+		config.BindTimeout = 10
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &KCPServer{
+		address:  address,
+		config:   config,
+		handler:  handler,
+		sessions: make(map[*KCPSession]struct{}),
+		tickets:  make(map[uint32]*kcpTicket),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Start 启动KCP服务器
+func (s *KCPServer) Start() error {
+	address := net.JoinHostPort(s.address, fmt.Sprint(s.config.Port))
+	listener, err := kcp.ListenWithOptions(address, nil, 0, 0)
+	if err != nil {
+		return fmt.Errorf("failed to listen on udp %s: %v", address, err)
+	}
+
+	s.listener = listener
+	atomic.StoreInt32(&s.running, 1)
+
+	logger.Info(fmt.Sprintf("KCP server listening on %s (mtu %d)", address, s.config.MTU))
+
+	s.wg.Add(2)
+	go s.acceptLoop()
+	go s.cleanupLoop()
+
+	return nil
+}
+
+// Stop 停止KCP服务器
+func (s *KCPServer) Stop() error {
+	if !atomic.CompareAndSwapInt32(&s.running, 1, 0) {
+		return nil
+	}
+
+	s.cancel()
+	s.listener.Close()
+
+	for _, session := range s.snapshotSessions() {
+		session.Close()
+	}
+
+	s.wg.Wait()
+	logger.Info("KCP server stopped")
+
+	return nil
+}
+
+// Port 获取监听端口
+func (s *KCPServer) Port() int {
+	return s.config.Port
+}
+
+// MTU 获取单个UDP包的最大字节数
+func (s *KCPServer) MTU() int {
+	return s.config.MTU
+}
+
+// BindTimeout 获取绑定票据有效期
+func (s *KCPServer) BindTimeout() time.Duration {
+	return time.Duration(s.config.BindTimeout) * time.Second
+}
+
+// SupportsGameType 检查玩法是否使用KCP传输
+func (s *KCPServer) SupportsGameType(gameType string) bool {
+	if len(s.config.GameTypes) == 0 {
+		return true
+	}
+	for _, t := range s.config.GameTypes {
+		if t == gameType {
+			return true
+		}
+	}
+	return false
+}
+
+// SetPanicHandler 设置消息处理panic时的回调
+func (s *KCPServer) SetPanicHandler(handler func(conn *Connection, value interface{}, stack []byte)) {
+	s.panicHandler = handler
+}
+
+// IssueTicket 为已认证的TCP连接签发KCP绑定票据，返回会话号和令牌
+// 客户端用会话号建立KCP会话，并把令牌作为会话的第一段数据发送
+func (s *KCPServer) IssueTicket(conn *Connection) (uint32, []byte, error) {
+	if conn.UserID == 0 {
+		return 0, nil, fmt.Errorf("connection %d is not authenticated", conn.ID)
+	}
+
+	token := make([]byte, kcpTokenSize)
+	if _, err := rand.Read(token); err != nil {
+		return 0, nil, fmt.Errorf("failed to generate kcp token: %v", err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var convBuf [4]byte
+	for {
+		if _, err := rand.Read(convBuf[:]); err != nil {
+			return 0, nil, fmt.Errorf("failed to generate kcp conv: %v", err)
+		}
+		conv := binary.LittleEndian.Uint32(convBuf[:])
+		if conv == 0 {
+			continue
+		}
+		if _, exists := s.tickets[conv]; exists {
+			continue
+		}
+
+		s.tickets[conv] = &kcpTicket{
+			token:   token,
+			conn:    conn,
+			userID:  conn.UserID,
+			expires: time.Now().Add(s.BindTimeout()),
+		}
+		return conv, token, nil
+	}
+}
+
+// GetConnectionByUserID 根据用户ID获取已绑定的KCP连接
+func (s *KCPServer) GetConnectionByUserID(userID uint64) (*Connection, bool) {
+	value, ok := s.connections.Load(userID)
+	if !ok {
+		return nil, false
+	}
+	return value.(*Connection), true
+}
+
+// GetConnectionCount 获取已绑定的KCP连接数
+func (s *KCPServer) GetConnectionCount() int {
+	count := 0
+	s.connections.Range(func(key, value interface{}) bool {
+		count++
+		return true
+	})
+	return count
+}
+
+// SendToUser 通过KCP发送消息给特定用户
+func (s *KCPServer) SendToUser(userID uint64, data []byte) error {
+	conn, ok := s.GetConnectionByUserID(userID)
+	if !ok || conn.IsClosed() {
+		return fmt.Errorf("user %d has no kcp session", userID)
+	}
+	return conn.Write(data)
+}
+
+// acceptLoop 接受KCP会话，按会话号查找票据，未签发票据或票据过期的会话直接关闭
+func (s *KCPServer) acceptLoop() {
+	defer s.wg.Done()
+
+	for atomic.LoadInt32(&s.running) == 1 {
+		udpSession, err := s.listener.AcceptKCP()
+		if err != nil {
+			if atomic.LoadInt32(&s.running) == 1 {
+				logger.Error(fmt.Sprintf("KCP accept error: %v", err))
+			}
+			return
+		}
+
+		conv := udpSession.GetConv()
+		s.mutex.Lock()
+		ticket, issued := s.tickets[conv]
+		if !issued || time.Now().After(ticket.expires) {
+			s.mutex.Unlock()
+			udpSession.Close()
+			continue
+		}
+		// 票据只能使用一次
+		delete(s.tickets, conv)
+		session := s.newSession(udpSession)
+		s.sessions[session] = struct{}{}
+		s.mutex.Unlock()
+
+		s.wg.Add(1)
+		go s.serveSession(session, ticket)
+	}
+}
+
+// newSession 按配置设置流模式、MTU、窗口和刷新间隔
+func (s *KCPServer) newSession(udpSession *kcp.UDPSession) *KCPSession {
+	udpSession.SetStreamMode(true)
+	udpSession.SetMtu(s.config.MTU)
+	udpSession.SetWindowSize(s.config.WindowSize, s.config.WindowSize)
+	udpSession.SetNoDelay(1, s.config.Interval, kcpFastResend, 1)
+	udpSession.SetWriteDelay(false)
+	return &KCPSession{UDPSession: udpSession}
+}
+
+// cleanupLoop 定时关闭绑定的TCP连接已断开的KCP连接，清理过期票据
+func (s *KCPServer) cleanupLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(kcpCleanupTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.connections.Range(func(key, value interface{}) bool {
+				if conn, ok := value.(*Connection); ok && !conn.IsClosed() {
+					if session, ok := conn.Conn.(*KCPSession); ok && session.bound != nil && session.bound.IsClosed() {
+						conn.Close()
+					}
+				}
+				return true
+			})
+
+			now := time.Now()
+			s.mutex.Lock()
+			for conv, ticket := range s.tickets {
+				if now.After(ticket.expires) {
+					delete(s.tickets, conv)
+				}
+			}
+			s.mutex.Unlock()
+
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// snapshotSessions 获取当前会话列表
+func (s *KCPServer) snapshotSessions() []*KCPSession {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	sessions := make([]*KCPSession, 0, len(s.sessions))
+	for session := range s.sessions {
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
+
+// removeSession 移除会话
+func (s *KCPServer) removeSession(session *KCPSession) {
+	s.mutex.Lock()
+	delete(s.sessions, session)
+	s.mutex.Unlock()
+}
+
+// bindSession 校验会话的第一段数据是否为票据令牌，成功后创建继承TCP连接认证状态的连接
+func (s *KCPServer) bindSession(session *KCPSession, ticket *kcpTicket) (*Connection, error) {
+	session.SetReadDeadline(ticket.expires)
+
+	token := make([]byte, kcpTokenSize)
+	if _, err := io.ReadFull(session, token); err != nil {
+		return nil, fmt.Errorf("failed to read bind token: %v", err)
+	}
+	if subtle.ConstantTimeCompare(token, ticket.token) != 1 {
+		return nil, fmt.Errorf("invalid bind token")
+	}
+
+	tcpConn := ticket.conn
+	if tcpConn.IsClosed() || tcpConn.UserID != ticket.userID {
+		return nil, fmt.Errorf("tcp connection %d is no longer authenticated", tcpConn.ID)
+	}
+
+	session.bound = tcpConn
+	conn := NewConnection(kcpConnIDBase+atomic.AddUint64(&s.connCounter, 1), session)
+	conn.UserID = tcpConn.UserID
+	conn.SessionID = tcpConn.SessionID
+	conn.ProtocolVersion = tcpConn.ProtocolVersion
+	conn.Language = tcpConn.Language
+	conn.Codec = tcpConn.Codec
+	conn.SessionKey = tcpConn.SessionKey
+	conn.Region = tcpConn.Region
+	conn.WorldID = tcpConn.WorldID
+
+	return conn, nil
+}
+
+// serveSession 处理会话，绑定成功后按长度前缀帧读取消息
+func (s *KCPServer) serveSession(session *KCPSession, ticket *kcpTicket) {
+	defer s.wg.Done()
+	defer s.removeSession(session)
+	defer session.Close()
+
+	conn, err := s.bindSession(session, ticket)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("KCP session %d from %s rejected: %v", session.GetConv(), session.RemoteAddr(), err))
+		return
+	}
+
+	// 同一用户只保留最新的KCP连接
+	if previous, loaded := s.connections.Swap(conn.UserID, conn); loaded {
+		previous.(*Connection).Close()
+	}
+	defer s.connections.CompareAndDelete(conn.UserID, conn)
+	defer conn.Close()
+
+	logger.Debug(fmt.Sprintf("KCP session %d bound to user %d (tcp connection %d)", session.GetConv(), conn.UserID, session.bound.ID))
+
+	idleTimeout := time.Duration(s.config.IdleTimeout) * time.Second
+	session.SetReadDeadline(time.Now().Add(idleTimeout))
+
+	for !conn.IsClosed() && atomic.LoadInt32(&s.running) == 1 {
+		lengthBuf := make([]byte, FrameLengthSize)
+		if _, err := conn.Read(lengthBuf); err != nil {
+			if !conn.IsClosed() {
+				logger.Debug(fmt.Sprintf("Read length error for kcp connection %d: %v", conn.ID, err))
+			}
+			break
+		}
+
+		msgLen, err := ParseFrameLength(lengthBuf)
+		if err != nil {
+			logger.Warn(fmt.Sprintf("Invalid frame for kcp connection %d: %v", conn.ID, err))
+			break
+		}
+
+		msgBuf := make([]byte, msgLen)
+		if _, err := conn.Read(msgBuf); err != nil {
+			logger.Debug(fmt.Sprintf("Read message error for kcp connection %d: %v", conn.ID, err))
+			break
+		}
+
+		if err := s.handleMessage(conn, msgBuf); err != nil {
+			logger.Error(fmt.Sprintf("Handle message error for kcp connection %d: %v", conn.ID, err))
+		}
+
+		// 实时流量走KCP时保持TCP连接活跃，KCP不可用时可直接回退
+		session.bound.LastActivity = time.Now()
+		session.SetReadDeadline(time.Now().Add(idleTimeout))
+	}
+}
+
+// handleMessage 处理单条消息，恢复处理器中的panic
+func (s *KCPServer) handleMessage(conn *Connection, data []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			stack := debug.Stack()
+			if s.panicHandler != nil {
+				s.panicHandler(conn, r, stack)
+			} else {
+				logger.Error(fmt.Sprintf("Recovered panic for kcp connection %d: %v\n%s", conn.ID, r, stack))
+			}
+			err = fmt.Errorf("panic in message handler: %v", r)
+		}
+	}()
+
+	return s.handler.HandleMessage(conn, data)
+}
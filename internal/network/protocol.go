@@ -0,0 +1,104 @@
+package network
+
+import (
+	"fmt"
+	"sync"
+)
+
+// 协议版本
+const (
+	ProtocolVersionMin     uint32 = 1 // 最低支持的协议版本
+	ProtocolVersionCurrent uint32 = 2 // 当前协议版本
+)
+
+// FieldAdapter 字段适配函数，按连接的编解码器将旧版本消息体转换为当前版本格式
+type FieldAdapter func(codec Codec, data []byte) ([]byte, error)
+
+// CompatRule 兼容规则
+type CompatRule struct {
+	OldMsgID uint32       // 旧版本消息ID
+	NewMsgID uint32       // 当前版本消息ID
+	Adapter  FieldAdapter // 消息体适配（可选）
+}
+
+// ProtocolRegistry 协议版本兼容注册表
+type ProtocolRegistry struct {
+	minVersion     uint32
+	currentVersion uint32
+	rules          map[uint32]map[uint32]*CompatRule // version -> oldMsgID -> rule
+	mutex          sync.RWMutex
+}
+
+// NewProtocolRegistry 创建协议版本兼容注册表
+func NewProtocolRegistry(minVersion, currentVersion uint32) *ProtocolRegistry {
+	return &ProtocolRegistry{
+		minVersion:     minVersion,
+		currentVersion: currentVersion,
+		rules:          make(map[uint32]map[uint32]*CompatRule),
+	}
+}
+
+// MinVersion 获取最低支持版本
+func (pr *ProtocolRegistry) MinVersion() uint32 {
+	return pr.minVersion
+}
+
+// CurrentVersion 获取当前版本
+func (pr *ProtocolRegistry) CurrentVersion() uint32 {
+	return pr.currentVersion
+}
+
+// IsSupported 检查协议版本是否受支持
+func (pr *ProtocolRegistry) IsSupported(version uint32) bool {
+	return version >= pr.minVersion && version <= pr.currentVersion
+}
+
+// Negotiate 协商协议版本
+// 客户端版本高于服务器时降级到服务器当前版本，低于最低版本时返回错误
+func (pr *ProtocolRegistry) Negotiate(clientVersion uint32) (uint32, error) {
+	if clientVersion < pr.minVersion {
+		return 0, fmt.Errorf("protocol version %d not supported, minimum is %d", clientVersion, pr.minVersion)
+	}
+	if clientVersion > pr.currentVersion {
+		return pr.currentVersion, nil
+	}
+	return clientVersion, nil
+}
+
+// RegisterCompat 注册旧版本消息的兼容规则
+func (pr *ProtocolRegistry) RegisterCompat(version uint32, rule *CompatRule) {
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+
+	rules, exists := pr.rules[version]
+	if !exists {
+		rules = make(map[uint32]*CompatRule)
+		pr.rules[version] = rules
+	}
+	rules[rule.OldMsgID] = rule
+}
+
+// Translate 将旧版本消息转换为当前版本的消息ID和消息体
+func (pr *ProtocolRegistry) Translate(version, msgID uint32, codec Codec, data []byte) (uint32, []byte, error) {
+	if version >= pr.currentVersion {
+		return msgID, data, nil
+	}
+
+	pr.mutex.RLock()
+	rule, exists := pr.rules[version][msgID]
+	pr.mutex.RUnlock()
+
+	if !exists {
+		return msgID, data, nil
+	}
+
+	if rule.Adapter != nil {
+		adapted, err := rule.Adapter(codec, data)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to adapt message %d from protocol version %d: %v", msgID, version, err)
+		}
+		data = adapted
+	}
+
+	return rule.NewMsgID, data, nil
+}
@@ -3,7 +3,9 @@ package network
 import (
 	"context"
 	"fmt"
+	"io"
 	"net"
+	"runtime/debug"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -14,15 +16,22 @@ import (
 
 // Connection TCP连接
 type Connection struct {
-	ID           uint64
-	Conn         net.Conn
-	UserID       uint64
-	SessionID    string
-	LastActivity time.Time
-	closed       int32
-	writeMutex   sync.Mutex
-	readBuffer   []byte
-	writeBuffer  []byte
+	ID              uint64
+	Conn            net.Conn
+	UserID          uint64
+	SessionID       string
+	ProtocolVersion uint32 // 握手协商的协议版本
+	Language        string // 客户端语言
+	Codec           Codec  // 握手协商的编解码器
+	SessionKey      []byte // 帧签名使用的会话密钥
+	LastNonce       uint64 // 最近一次消息序号，用于重放保护
+	Region          string // 玩家所在区域
+	WorldID         uint32 // 玩家所属世界
+	LastActivity    time.Time
+	closed          int32
+	writeMutex      sync.Mutex
+	readBuffer      []byte
+	writeBuffer     []byte
 }
 
 // NewConnection 创建新连接
@@ -57,7 +66,15 @@ func (c *Connection) Read(buf []byte) (int, error) {
 	}
 
 	c.LastActivity = time.Now()
-	return c.Conn.Read(buf)
+	return io.ReadFull(c.Conn, buf)
+}
+
+// GetCodec 获取连接的编解码器，未协商时使用默认编解码器
+func (c *Connection) GetCodec() Codec {
+	if c.Codec != nil {
+		return c.Codec
+	}
+	return DefaultCodec()
 }
 
 // Close 关闭连接
@@ -78,6 +95,13 @@ func (c *Connection) IsClosed() bool {
 func (c *Connection) Reset() {
 	c.UserID = 0
 	c.SessionID = ""
+	c.ProtocolVersion = 0
+	c.Language = ""
+	c.Codec = nil
+	c.SessionKey = nil
+	c.LastNonce = 0
+	c.Region = ""
+	c.WorldID = 0
 	c.LastActivity = time.Time{}
 	atomic.StoreInt32(&c.closed, 0)
 }
@@ -99,6 +123,7 @@ type TCPServer struct {
 	cancel       context.CancelFunc
 	wg           sync.WaitGroup
 	handler      MessageHandler
+	panicHandler func(conn *Connection, value interface{}, stack []byte)
 	maxConns     int
 	readTimeout  time.Duration
 	writeTimeout time.Duration
@@ -219,7 +244,7 @@ func (s *TCPServer) handleConnection(conn *Connection) {
 
 	for !conn.IsClosed() && s.running {
 		// 读取消息长度 (4字节)
-		lengthBuf := make([]byte, 4)
+		lengthBuf := make([]byte, FrameLengthSize)
 		if _, err := conn.Read(lengthBuf); err != nil {
 			if !conn.IsClosed() {
 				logger.Debug(fmt.Sprintf("Read length error for connection %d: %v", conn.ID, err))
@@ -227,12 +252,10 @@ func (s *TCPServer) handleConnection(conn *Connection) {
 			break
 		}
 
-		// 解析消息长度
-		msgLen := uint32(lengthBuf[0])<<24 | uint32(lengthBuf[1])<<16 | uint32(lengthBuf[2])<<8 | uint32(lengthBuf[3])
-
-		// 检查消息长度合法性
-		if msgLen == 0 || msgLen > 1024*1024 { // 最大1MB
-			logger.Warn(fmt.Sprintf("Invalid message length %d for connection %d", msgLen, conn.ID))
+		// 解析并检查消息长度合法性
+		msgLen, err := ParseFrameLength(lengthBuf)
+		if err != nil {
+			logger.Warn(fmt.Sprintf("Invalid frame for connection %d: %v", conn.ID, err))
 			break
 		}
 
@@ -244,7 +267,7 @@ func (s *TCPServer) handleConnection(conn *Connection) {
 		}
 
 		// 处理消息
-		if err := s.handler.HandleMessage(conn, msgBuf); err != nil {
+		if err := s.handleMessage(conn, msgBuf); err != nil {
 			logger.Error(fmt.Sprintf("Handle message error for connection %d: %v", conn.ID, err))
 		}
 
@@ -254,6 +277,28 @@ func (s *TCPServer) handleConnection(conn *Connection) {
 	}
 }
 
+// SetPanicHandler 设置消息处理panic时的回调
+func (s *TCPServer) SetPanicHandler(handler func(conn *Connection, value interface{}, stack []byte)) {
+	s.panicHandler = handler
+}
+
+// handleMessage 处理单条消息，恢复处理器中的panic，避免整个连接被断开
+func (s *TCPServer) handleMessage(conn *Connection, data []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			stack := debug.Stack()
+			if s.panicHandler != nil {
+				s.panicHandler(conn, r, stack)
+			} else {
+				logger.Error(fmt.Sprintf("Recovered panic for connection %d: %v\n%s", conn.ID, r, stack))
+			}
+			err = fmt.Errorf("panic in message handler: %v", r)
+		}
+	}()
+
+	return s.handler.HandleMessage(conn, data)
+}
+
 // heartbeatLoop 心跳检测循环
 func (s *TCPServer) heartbeatLoop() {
 	defer s.wg.Done()
@@ -324,6 +369,16 @@ func (s *TCPServer) GetConnectionCount() int {
 	return count
 }
 
+// ForEachConnection 遍历未关闭的连接，fn返回false时停止遍历
+func (s *TCPServer) ForEachConnection(fn func(conn *Connection) bool) {
+	s.connections.Range(func(key, value interface{}) bool {
+		if conn, ok := value.(*Connection); ok && !conn.IsClosed() {
+			return fn(conn)
+		}
+		return true
+	})
+}
+
 // Broadcast 广播消息
 func (s *TCPServer) Broadcast(data []byte) {
 	s.connections.Range(func(key, value interface{}) bool {
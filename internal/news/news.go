@@ -0,0 +1,294 @@
+package news
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/segment"
+)
+
+// 资讯类型
+const (
+	KindNews       = "news"        // 新闻
+	KindPromotion  = "promotion"   // 推广，如商城活动、交叉推广的其他游戏
+	KindPatchNotes = "patch_notes" // 更新说明
+)
+
+// 资讯默认设置
+const (
+	defaultDuration = 7 * 24 * time.Hour
+	defaultCacheTTL = 5 * time.Minute
+	defaultLanguage = "en"
+	defaultLimit    = 20
+)
+
+// Config 资讯设置
+type Config struct {
+	CDN      string        `yaml:"cdn"`      // 图片CDN地址，图片为相对路径时拼接在前面
+	Duration time.Duration `yaml:"duration"` // 未指定结束时间时的展示时长，未配置时为7天
+	Cache    time.Duration `yaml:"cache"`    // 进行中资讯的本地缓存时间，未配置时为5分钟
+	Language string        `yaml:"language"` // 未指定时的默认语言，未配置时为en
+	Limit    int           `yaml:"limit"`    // 每次返回的最大条数，未配置时为20
+}
+
+// Validate 检查资讯设置
+func (c *Config) Validate() error {
+	if c.CDN != "" {
+		u, err := url.Parse(c.CDN)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("cdn must be an absolute http(s) url: %s", c.CDN)
+		}
+	}
+	if c.Duration < 0 || c.Cache < 0 || c.Limit < 0 {
+		return fmt.Errorf("duration, cache and limit must not be negative")
+	}
+	return nil
+}
+
+// withDefaults 补齐未配置的设置
+func (c Config) withDefaults() Config {
+	if c.Duration <= 0 {
+		c.Duration = defaultDuration
+	}
+	if c.Cache <= 0 {
+		c.Cache = defaultCacheTTL
+	}
+	if c.Language == "" {
+		c.Language = defaultLanguage
+	}
+	if c.Limit <= 0 {
+		c.Limit = defaultLimit
+	}
+	c.CDN = strings.TrimRight(c.CDN, "/")
+	return c
+}
+
+// Store 资讯存储，database.NewsRepository实现了该接口
+type Store interface {
+	Create(item *database.NewsItem) error
+	Get(itemID string) (*database.NewsItem, error)
+	Active(from, until time.Time) ([]*database.NewsItem, error)
+	List(limit int64) ([]*database.NewsItem, error)
+	Delete(itemID string) (bool, error)
+}
+
+// Segments 玩家分群，segment.Service实现了该接口
+type Segments interface {
+	Has(name string) bool
+	InSegment(userID uint64, name string) (bool, error)
+}
+
+// Localized 按玩家语言选择内容后的资讯
+type Localized struct {
+	ID       string
+	Kind     string
+	Title    string
+	Summary  string
+	Body     string
+	ImageURL string
+	Link     string
+	Priority int32
+	StartAt  time.Time
+	EndAt    time.Time
+}
+
+// Service 资讯服务，进行中的资讯在本地缓存，网关直接用缓存响应客户端，
+// 资讯变更后通过系统消息让各节点清除缓存，没有收到消息的节点最迟在缓存到期后更新
+type Service struct {
+	config   Config
+	store    Store
+	segments Segments
+
+	mutex    sync.Mutex
+	active   []*database.NewsItem
+	loadedAt time.Time
+}
+
+// NewService 创建资讯服务
+func NewService(config *Config, store Store, segments Segments) *Service {
+	return &Service{
+		config:   config.withDefaults(),
+		store:    store,
+		segments: segments,
+	}
+}
+
+// Create 检查并创建资讯，未指定分群时面向所有玩家，未指定开始时间时立即开始
+func (s *Service) Create(item *database.NewsItem) error {
+	switch item.Kind {
+	case KindNews, KindPromotion, KindPatchNotes:
+	default:
+		return fmt.Errorf("unknown news kind: %s", item.Kind)
+	}
+	if item.Segment == "" {
+		item.Segment = segment.SegmentAll
+	}
+	if !s.segments.Has(item.Segment) {
+		return fmt.Errorf("unknown segment: %s", item.Segment)
+	}
+
+	if item.Language == "" {
+		item.Language = s.config.Language
+	}
+	if _, exists := item.Contents[item.Language]; !exists {
+		return fmt.Errorf("missing content for default language %s", item.Language)
+	}
+	for language, content := range item.Contents {
+		if content.Title == "" {
+			return fmt.Errorf("title for %s is empty", language)
+		}
+	}
+
+	if item.StartAt.IsZero() {
+		item.StartAt = time.Now()
+	}
+	if item.EndAt.IsZero() {
+		item.EndAt = item.StartAt.Add(s.config.Duration)
+	}
+	if !item.EndAt.After(item.StartAt) {
+		return fmt.Errorf("news item ends before it starts")
+	}
+
+	if err := s.store.Create(item); err != nil {
+		return err
+	}
+	s.Invalidate()
+	return nil
+}
+
+// Delete 删除资讯，资讯不存在时返回false
+func (s *Service) Delete(itemID string) (bool, error) {
+	deleted, err := s.store.Delete(itemID)
+	if deleted {
+		s.Invalidate()
+	}
+	return deleted, err
+}
+
+// List 获取最近创建的资讯
+func (s *Service) List(limit int64) ([]*database.NewsItem, error) {
+	return s.store.List(limit)
+}
+
+// Get 获取资讯，不存在时返回nil
+func (s *Service) Get(itemID string) (*database.NewsItem, error) {
+	return s.store.Get(itemID)
+}
+
+// Feed 获取玩家可以看到的进行中资讯和资讯版本，kind为空时获取所有类型，未登录的玩家只能看到面向所有玩家的资讯。
+// 资讯创建后不再修改，版本由返回的资讯和语言计算，客户端据此判断是否需要刷新
+func (s *Service) Feed(userID uint64, kind, language string, now time.Time) ([]*Localized, string, error) {
+	items, err := s.cached(now)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var result []*Localized
+	hash := fnv.New64a()
+	hash.Write([]byte(language))
+	for _, item := range items {
+		if len(result) >= s.config.Limit {
+			break
+		}
+		if now.Before(item.StartAt) || !now.Before(item.EndAt) {
+			continue
+		}
+		if kind != "" && item.Kind != kind {
+			continue
+		}
+		if item.Segment != segment.SegmentAll {
+			if userID == 0 {
+				continue
+			}
+			member, err := s.segments.InSegment(userID, item.Segment)
+			if err != nil || !member {
+				continue // 分群已从配置中删除的资讯不再展示
+			}
+		}
+		result = append(result, s.Localize(item, language))
+		hash.Write(item.ID[:])
+	}
+	return result, fmt.Sprintf("%016x", hash.Sum64()), nil
+}
+
+// cached 获取缓存的进行中资讯，过期时从存储重新加载
+func (s *Service) cached(now time.Time) ([]*database.NewsItem, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.active != nil && now.Sub(s.loadedAt) < s.config.Cache {
+		return s.active, nil
+	}
+	// 多加载一个缓存周期内开始的资讯，缓存期间开始的资讯也能展示
+	items, err := s.store.Active(now, now.Add(s.config.Cache))
+	if err != nil {
+		return nil, err
+	}
+	if items == nil {
+		items = []*database.NewsItem{}
+	}
+	s.active = items
+	s.loadedAt = now
+	return items, nil
+}
+
+// Warm 预先加载进行中的资讯，节点上线后第一批请求不用等待加载
+func (s *Service) Warm(now time.Time) error {
+	_, err := s.cached(now)
+	return err
+}
+
+// Invalidate 清除本地缓存，下次获取时重新加载
+func (s *Service) Invalidate() {
+	s.mutex.Lock()
+	s.active = nil
+	s.mutex.Unlock()
+}
+
+// Localize 选择玩家语言的内容，依次匹配完整语言、主语言和资讯的默认语言，没有图片时使用默认语言的图片
+func (s *Service) Localize(item *database.NewsItem, language string) *Localized {
+	fallback := item.Contents[item.Language]
+	content, exists := item.Contents[language]
+	if !exists {
+		if i := strings.IndexAny(language, "-_"); i > 0 {
+			content, exists = item.Contents[language[:i]]
+		}
+	}
+	if !exists {
+		content = fallback
+	}
+	image := content.Image
+	if image == "" {
+		image = fallback.Image
+	}
+	link := content.Link
+	if link == "" {
+		link = fallback.Link
+	}
+
+	return &Localized{
+		ID:       item.ID.Hex(),
+		Kind:     item.Kind,
+		Title:    content.Title,
+		Summary:  content.Summary,
+		Body:     content.Body,
+		ImageURL: s.imageURL(image),
+		Link:     link,
+		Priority: item.Priority,
+		StartAt:  item.StartAt,
+		EndAt:    item.EndAt,
+	}
+}
+
+// imageURL 相对路径的图片拼接CDN地址，完整地址原样返回
+func (s *Service) imageURL(image string) string {
+	if image == "" || s.config.CDN == "" || strings.Contains(image, "://") {
+		return image
+	}
+	return s.config.CDN + "/" + strings.TrimLeft(image, "/")
+}
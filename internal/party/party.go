@@ -0,0 +1,237 @@
+package party
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// 队伍操作的错误
+var (
+	ErrNotInParty = errors.New("not in a party")
+	ErrInParty    = errors.New("already in a party")
+	ErrNotLeader  = errors.New("only the party leader can do this")
+	ErrFull       = errors.New("party is full")
+	ErrNoInvite   = errors.New("no pending invite to this party")
+)
+
+// 队伍默认设置
+const (
+	defaultMaxSize   = 4
+	defaultInviteTTL = time.Minute
+)
+
+// Config 队伍设置
+type Config struct {
+	MaxSize   int           `yaml:"max_size"`   // 队伍最多人数，未配置时为4
+	InviteTTL time.Duration `yaml:"invite_ttl"` // 邀请的有效期，未配置时为1分钟
+}
+
+// Member 队伍成员
+type Member struct {
+	UserID   uint64
+	Nickname string
+	Level    int32
+	JoinedAt time.Time
+}
+
+// Party 队伍，成员按加入顺序排列，队长离开时由最早加入的成员接任
+type Party struct {
+	ID        uint64
+	LeaderID  uint64
+	Members   []Member
+	CreatedAt time.Time
+}
+
+// UserIDs 队伍成员的用户ID
+func (p *Party) UserIDs() []uint64 {
+	userIDs := make([]uint64, 0, len(p.Members))
+	for _, member := range p.Members {
+		userIDs = append(userIDs, member.UserID)
+	}
+	return userIDs
+}
+
+// clone 返回队伍的副本，避免调用方在锁外读到正在修改的成员列表
+func (p *Party) clone() *Party {
+	copied := *p
+	copied.Members = append([]Member(nil), p.Members...)
+	return &copied
+}
+
+// Manager 队伍管理，队伍保存在当前大厅节点的内存中，和匹配队列在同一节点
+type Manager struct {
+	config  Config
+	parties map[uint64]*Party
+	members map[uint64]uint64               // 用户ID到队伍ID
+	invites map[uint64]map[uint64]time.Time // 被邀请的用户ID到队伍ID和邀请过期时间
+	nextID  uint64
+	mutex   sync.Mutex
+}
+
+// NewManager 创建队伍管理
+func NewManager(config *Config) *Manager {
+	cfg := *config
+	if cfg.MaxSize <= 1 {
+		cfg.MaxSize = defaultMaxSize
+	}
+	if cfg.InviteTTL <= 0 {
+		cfg.InviteTTL = defaultInviteTTL
+	}
+	return &Manager{
+		config:  cfg,
+		parties: make(map[uint64]*Party),
+		members: make(map[uint64]uint64),
+		invites: make(map[uint64]map[uint64]time.Time),
+		nextID:  1,
+	}
+}
+
+// Create 创建队伍，创建者为队长
+func (m *Manager) Create(leader Member) (*Party, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, exists := m.members[leader.UserID]; exists {
+		return nil, ErrInParty
+	}
+
+	leader.JoinedAt = time.Now()
+	party := &Party{
+		ID:        m.nextID,
+		LeaderID:  leader.UserID,
+		Members:   []Member{leader},
+		CreatedAt: leader.JoinedAt,
+	}
+	m.nextID++
+	m.parties[party.ID] = party
+	m.members[leader.UserID] = party.ID
+	return party.clone(), nil
+}
+
+// Get 获取玩家所在的队伍
+func (m *Manager) Get(userID uint64) (*Party, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	party, ok := m.parties[m.members[userID]]
+	if !ok {
+		return nil, false
+	}
+	return party.clone(), true
+}
+
+// Invite 队长邀请玩家加入队伍
+func (m *Manager) Invite(leaderID, inviteeID uint64) (*Party, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	party, ok := m.parties[m.members[leaderID]]
+	if !ok {
+		return nil, ErrNotInParty
+	}
+	if party.LeaderID != leaderID {
+		return nil, ErrNotLeader
+	}
+	if _, exists := m.members[inviteeID]; exists {
+		return nil, ErrInParty
+	}
+	if len(party.Members) >= m.config.MaxSize {
+		return nil, ErrFull
+	}
+
+	invites := m.invites[inviteeID]
+	if invites == nil {
+		invites = make(map[uint64]time.Time)
+		m.invites[inviteeID] = invites
+	}
+	invites[party.ID] = time.Now().Add(m.config.InviteTTL)
+	return party.clone(), nil
+}
+
+// Join 接受邀请加入队伍，加入后该玩家的其他邀请失效
+func (m *Manager) Join(partyID uint64, member Member) (*Party, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, exists := m.members[member.UserID]; exists {
+		return nil, ErrInParty
+	}
+	expireAt, invited := m.invites[member.UserID][partyID]
+	party, ok := m.parties[partyID]
+	if !invited || !ok || time.Now().After(expireAt) {
+		return nil, ErrNoInvite
+	}
+	if len(party.Members) >= m.config.MaxSize {
+		return nil, ErrFull
+	}
+
+	member.JoinedAt = time.Now()
+	party.Members = append(party.Members, member)
+	m.members[member.UserID] = party.ID
+	delete(m.invites, member.UserID)
+	return party.clone(), nil
+}
+
+// Leave 离开队伍，返回离开后的队伍，最后一名成员离开时队伍解散并返回nil
+func (m *Manager) Leave(userID uint64) (*Party, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	party, ok := m.parties[m.members[userID]]
+	if !ok {
+		return nil, ErrNotInParty
+	}
+
+	delete(m.members, userID)
+	for i, member := range party.Members {
+		if member.UserID == userID {
+			party.Members = append(party.Members[:i], party.Members[i+1:]...)
+			break
+		}
+	}
+
+	if len(party.Members) == 0 {
+		delete(m.parties, party.ID)
+		return nil, nil
+	}
+	if party.LeaderID == userID {
+		party.LeaderID = party.Members[0].UserID
+	}
+	return party.clone(), nil
+}
+
+// Rename 更新队伍成员的昵称，玩家不在本节点的队伍中时返回false
+func (m *Manager) Rename(userID uint64, nickname string) (*Party, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	party, ok := m.parties[m.members[userID]]
+	if !ok {
+		return nil, false
+	}
+	for i := range party.Members {
+		if party.Members[i].UserID == userID {
+			party.Members[i].Nickname = nickname
+		}
+	}
+	return party.clone(), true
+}
+
+// Cleanup 清理过期的邀请
+func (m *Manager) Cleanup() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := time.Now()
+	for userID, invites := range m.invites {
+		for partyID, expireAt := range invites {
+			if now.After(expireAt) || m.parties[partyID] == nil {
+				delete(invites, partyID)
+			}
+		}
+		if len(invites) == 0 {
+			delete(m.invites, userID)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package privacy
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// 匿名化模式
+const (
+	AnonymizeModeStrip = "strip" // 敏感内容替换为占位符
+	AnonymizeModeHash  = "hash"  // 敏感内容替换为哈希值，便于关联同一内容
+)
+
+// ChatAnonymizationConfig 聊天记录匿名化配置
+type ChatAnonymizationConfig struct {
+	Enabled         bool           `yaml:"enabled"`
+	Interval        time.Duration  `yaml:"interval"`         // 任务执行间隔
+	RetentionDays   int            `yaml:"retention_days"`   // 默认保留天数
+	Mode            string         `yaml:"mode"`             // strip, hash
+	PseudonymSecret string         `yaml:"pseudonym_secret"` // 用户假名密钥
+	BatchSize       int            `yaml:"batch_size"`
+	Regions         map[string]int `yaml:"regions"` // 区域 -> 保留天数
+}
+
+// 聊天内容中的个人信息
+var piiPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`), // 邮箱
+	regexp.MustCompile(`\+?\d[\d\- ]{7,}\d`),                               // 电话号码
+	regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`),                      // IP地址
+}
+
+// ChatAnonymizer 聊天记录匿名化任务
+// 超过保留期的消息将用户ID替换为稳定的假名，并去除内容中的个人信息，
+// 保留消息本身以供审核追溯
+type ChatAnonymizer struct {
+	collection *mongo.Collection
+	config     ChatAnonymizationConfig
+}
+
+// NewChatAnonymizer 创建聊天记录匿名化任务
+func NewChatAnonymizer(mm *database.MongoManager, config *ChatAnonymizationConfig) *ChatAnonymizer {
+	cfg := *config
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Hour
+	}
+	if cfg.RetentionDays <= 0 {
+		cfg.RetentionDays = 30
+	}
+	if cfg.Mode == "" {
+		cfg.Mode = AnonymizeModeHash
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.PseudonymSecret == "" {
+		logger.Warn("Chat anonymization pseudonym secret not set, pseudonyms can be reversed by brute force")
+	}
+
+	return &ChatAnonymizer{
+		collection: mm.GetCollection("chat_messages"),
+		config:     cfg,
+	}
+}
+
+// Run 按间隔执行匿名化，直到ctx取消
+func (ca *ChatAnonymizer) Run(ctx context.Context) {
+	if !ca.config.Enabled {
+		return
+	}
+
+	ticker := time.NewTicker(ca.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			count, err := ca.AnonymizeOnce(ctx)
+			if err != nil {
+				logger.Error(fmt.Sprintf("Chat anonymization failed: %v", err))
+				continue
+			}
+			if count > 0 {
+				logger.Info(fmt.Sprintf("Anonymized %d chat messages", count))
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// AnonymizeOnce 对所有区域执行一次匿名化，返回处理的消息数
+func (ca *ChatAnonymizer) AnonymizeOnce(ctx context.Context) (int64, error) {
+	var total int64
+	now := time.Now()
+
+	// 按区域策略处理
+	regions := make([]string, 0, len(ca.config.Regions))
+	for region, days := range ca.config.Regions {
+		regions = append(regions, region)
+		filter := bson.M{
+			"region":     region,
+			"anonymized": bson.M{"$ne": true},
+			"created_at": bson.M{"$lt": now.AddDate(0, 0, -days)},
+		}
+		count, err := ca.anonymize(ctx, filter)
+		total += count
+		if err != nil {
+			return total, fmt.Errorf("region %s: %v", region, err)
+		}
+	}
+
+	// 未配置区域的消息使用默认保留期
+	filter := bson.M{
+		"region":     bson.M{"$nin": regions},
+		"anonymized": bson.M{"$ne": true},
+		"created_at": bson.M{"$lt": now.AddDate(0, 0, -ca.config.RetentionDays)},
+	}
+	count, err := ca.anonymize(ctx, filter)
+	total += count
+	if err != nil {
+		return total, err
+	}
+
+	return total, nil
+}
+
+// anonymize 分批处理匹配的消息
+func (ca *ChatAnonymizer) anonymize(ctx context.Context, filter bson.M) (int64, error) {
+	var total int64
+
+	for {
+		opts := options.Find().SetLimit(int64(ca.config.BatchSize))
+		cursor, err := ca.collection.Find(ctx, filter, opts)
+		if err != nil {
+			return total, err
+		}
+
+		var messages []*database.ChatMessage
+		err = cursor.All(ctx, &messages)
+		cursor.Close(ctx)
+		if err != nil {
+			return total, err
+		}
+		if len(messages) == 0 {
+			return total, nil
+		}
+
+		models := make([]mongo.WriteModel, 0, len(messages))
+		for _, message := range messages {
+			update := bson.M{
+				"$set": bson.M{
+					"from_user_id":   uint64(0),
+					"to_user_id":     uint64(0),
+					"from_pseudonym": ca.Pseudonym(message.FromUserID),
+					"to_pseudonym":   ca.Pseudonym(message.ToUserID),
+					"content":        ca.scrub(message.Content),
+					"anonymized":     true,
+				},
+			}
+			models = append(models, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"_id": message.ID}).
+				SetUpdate(update))
+		}
+
+		result, err := ca.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+		if err != nil {
+			return total, err
+		}
+		total += result.ModifiedCount
+
+		if len(messages) < ca.config.BatchSize {
+			return total, nil
+		}
+	}
+}
+
+// Pseudonym 将用户ID映射为稳定的假名，同一用户始终得到相同结果
+func (ca *ChatAnonymizer) Pseudonym(userID uint64) string {
+	if userID == 0 {
+		return ""
+	}
+	return "u_" + ca.hash(strconv.FormatUint(userID, 10))[:16]
+}
+
+// scrub 去除内容中的个人信息
+func (ca *ChatAnonymizer) scrub(content string) string {
+	for _, pattern := range piiPatterns {
+		content = pattern.ReplaceAllStringFunc(content, func(match string) string {
+			if ca.config.Mode == AnonymizeModeStrip {
+				return "[redacted]"
+			}
+			return "[" + ca.hash(match)[:12] + "]"
+		})
+	}
+	return content
+}
+
+// hash 计算带密钥的哈希值
+func (ca *ChatAnonymizer) hash(value string) string {
+	mac := hmac.New(sha256.New, []byte(ca.config.PseudonymSecret))
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,535 @@
+package privacy
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// 隐私请求类型
+const (
+	RequestTypeExport = "export"
+	RequestTypeDelete = "delete"
+)
+
+// 隐私请求状态
+const (
+	RequestStatusRunning   = "running"
+	RequestStatusCompleted = "completed"
+	RequestStatusFailed    = "failed"
+)
+
+// DeletedNickname 删除用户后保留在历史记录中的昵称
+const DeletedNickname = "deleted_user"
+
+// AnonymousUserIDBit 匿名化后的玩家ID带有最高位，与真实玩家ID区分
+const AnonymousUserIDBit uint64 = 1 << 63
+
+// PrivacyConfig 隐私配置
+type PrivacyConfig struct {
+	ExportDir         string                  `yaml:"export_dir"` // 导出归档存放目录
+	ChatAnonymization ChatAnonymizationConfig `yaml:"chat_anonymization"`
+}
+
+// PrivacyAudit 隐私操作审计记录
+type PrivacyAudit struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID      uint64             `bson:"user_id" json:"user_id"`
+	GMUserID    uint64             `bson:"gm_user_id" json:"gm_user_id"`
+	RequestType string             `bson:"request_type" json:"request_type"`
+	Status      string             `bson:"status" json:"status"`
+	Reason      string             `bson:"reason,omitempty" json:"reason"`
+	ArchivePath string             `bson:"archive_path,omitempty" json:"archive_path"`
+	Affected    map[string]int64   `bson:"affected,omitempty" json:"affected"` // 各集合影响的文档数
+	Error       string             `bson:"error,omitempty" json:"error"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	CompletedAt time.Time          `bson:"completed_at,omitempty" json:"completed_at"`
+}
+
+// UserDataExport 用户数据导出内容
+type UserDataExport struct {
+	User            *database.User             `json:"user"`
+	Friends         []*database.Friend         `json:"friends"`
+	ChatMessages    []*database.ChatMessage    `json:"chat_messages"`
+	Reactions       []*database.ChatReaction   `json:"chat_reactions"`
+	BlockedUsers    []*database.BlockedUser    `json:"blocked_users"`
+	Mails           []*database.Mail           `json:"mails"`
+	GameRecords     []*database.GameRecord     `json:"game_records"`
+	BanRecords      []*database.BanRecord      `json:"ban_records"`
+	Settings        []*database.UserSettings   `json:"settings"`
+	Activity        []*database.ActivityEvent  `json:"activity"`
+	DeviceTokens    []*database.DeviceToken    `json:"device_tokens"`
+	SupportTickets  []*database.SupportTicket  `json:"support_tickets"`
+	SurveyResponses []*database.SurveyResponse `json:"survey_responses"`
+	NameChanges     []*database.NameChange     `json:"name_changes"`
+	ExportedAt      time.Time                  `json:"exported_at"`
+}
+
+// PrivacyManager 用户数据导出与删除
+type PrivacyManager struct {
+	mongo     *database.MongoManager
+	userCache *database.UserCache
+	audit     *mongo.Collection
+	exportDir string
+}
+
+// NewPrivacyManager 创建隐私管理器
+func NewPrivacyManager(mm *database.MongoManager, userCache *database.UserCache, config *PrivacyConfig) *PrivacyManager {
+	exportDir := config.ExportDir
+	if exportDir == "" {
+		exportDir = "data/exports"
+	}
+
+	audit := mm.GetCollection("privacy_audits")
+	audit.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+	})
+
+	return &PrivacyManager{
+		mongo:     mm,
+		userCache: userCache,
+		audit:     audit,
+		exportDir: exportDir,
+	}
+}
+
+// ExportUserData 汇总用户的全部文档并打包为zip归档，返回归档路径
+func (pm *PrivacyManager) ExportUserData(userID, gmUserID uint64, reason string) (string, error) {
+	audit, err := pm.startAudit(userID, gmUserID, RequestTypeExport, reason)
+	if err != nil {
+		return "", err
+	}
+
+	export, err := pm.collectUserData(userID)
+	if err != nil {
+		pm.finishAudit(audit, err)
+		return "", err
+	}
+
+	archivePath, err := pm.writeArchive(userID, export)
+	if err != nil {
+		pm.finishAudit(audit, err)
+		return "", err
+	}
+
+	audit.ArchivePath = archivePath
+	audit.Affected = exportCounts(export)
+	pm.finishAudit(audit, nil)
+
+	logger.Info(fmt.Sprintf("Exported data for user %d to %s", userID, archivePath))
+	return archivePath, nil
+}
+
+// DeleteUserData 删除或匿名化用户在各集合中的数据
+// 个人资料、好友、屏蔽、邮件、推送设备令牌、客服工单、待投递和由该用户发出的离线通知、昵称占用直接删除；
+// 聊天、对局记录、问卷回答和昵称、用户名的修改记录保留但去除身份信息，
+// 封禁记录保留用于风控
+func (pm *PrivacyManager) DeleteUserData(userID, gmUserID uint64, reason string) (map[string]int64, error) {
+	audit, err := pm.startAudit(userID, gmUserID, RequestTypeDelete, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	affected := make(map[string]int64)
+	steps := []struct {
+		name string
+		run  func(ctx context.Context) (int64, error)
+	}{
+		{"friends", func(ctx context.Context) (int64, error) {
+			return pm.deleteMany(ctx, "friends", bson.M{"$or": []bson.M{{"user_id": userID}, {"friend_id": userID}}})
+		}},
+		{"blocked_users", func(ctx context.Context) (int64, error) {
+			return pm.deleteMany(ctx, "blocked_users", bson.M{"$or": []bson.M{{"user_id": userID}, {"target_id": userID}}})
+		}},
+		{"mails", func(ctx context.Context) (int64, error) {
+			return pm.deleteMany(ctx, "mails", bson.M{"to_user_id": userID})
+		}},
+		{"chat_messages", func(ctx context.Context) (int64, error) {
+			return pm.updateMany(ctx, "chat_messages", bson.M{"from_user_id": userID}, bson.M{
+				"$set": bson.M{"content": ""},
+			})
+		}},
+		{"chat_reactions", func(ctx context.Context) (int64, error) {
+			return pm.deleteReactions(ctx, userID)
+		}},
+		{"game_records", func(ctx context.Context) (int64, error) {
+			return pm.updateMany(ctx, "game_records", bson.M{"players.user_id": userID}, bson.M{
+				"$set": bson.M{"players.$[p].nickname": DeletedNickname},
+			}, options.Update().SetArrayFilters(options.ArrayFilters{
+				Filters: []interface{}{bson.M{"p.user_id": userID}},
+			}))
+		}},
+		{"rooms", func(ctx context.Context) (int64, error) {
+			return pm.updateMany(ctx, "rooms", bson.M{"players.user_id": userID}, bson.M{
+				"$pull": bson.M{"players": bson.M{"user_id": userID}},
+			})
+		}},
+		{"user_settings", func(ctx context.Context) (int64, error) {
+			return pm.deleteMany(ctx, "user_settings", bson.M{"user_id": userID})
+		}},
+		{"activity_timeline", func(ctx context.Context) (int64, error) {
+			return pm.deleteMany(ctx, "activity_timeline", bson.M{"user_id": userID})
+		}},
+		{"device_tokens", func(ctx context.Context) (int64, error) {
+			return pm.deleteMany(ctx, "device_tokens", bson.M{"user_id": userID})
+		}},
+		{"notifications", func(ctx context.Context) (int64, error) {
+			return pm.deleteMany(ctx, "notifications", bson.M{"$or": []bson.M{{"user_id": userID}, {"from_user_id": userID}}})
+		}},
+		{"support_tickets", func(ctx context.Context) (int64, error) {
+			return pm.deleteMany(ctx, "support_tickets", bson.M{"user_id": userID})
+		}},
+		{"survey_responses", func(ctx context.Context) (int64, error) {
+			return pm.anonymizeSurveyResponses(ctx, userID)
+		}},
+		{"nicknames", func(ctx context.Context) (int64, error) {
+			return pm.deleteMany(ctx, "nicknames", bson.M{"user_id": userID})
+		}},
+		{"name_changes", func(ctx context.Context) (int64, error) {
+			return pm.updateMany(ctx, "name_changes", bson.M{"user_id": userID}, bson.M{
+				"$set": bson.M{"old_name": DeletedNickname, "new_name": DeletedNickname},
+			})
+		}},
+		{"users", func(ctx context.Context) (int64, error) {
+			return pm.deleteMany(ctx, "users", bson.M{"user_id": userID})
+		}},
+	}
+
+	for _, step := range steps {
+		count, err := step.run(ctx)
+		if err != nil {
+			audit.Affected = affected
+			err = fmt.Errorf("failed to erase %s: %v", step.name, err)
+			pm.finishAudit(audit, err)
+			return affected, err
+		}
+		affected[step.name] = count
+	}
+
+	if pm.userCache != nil {
+		pm.userCache.DeleteUserInfo(userID)
+	}
+
+	audit.Affected = affected
+	pm.finishAudit(audit, nil)
+
+	logger.Info(fmt.Sprintf("Deleted data for user %d: %v", userID, affected))
+	return affected, nil
+}
+
+// GetAuditTrail 获取用户的隐私操作审计记录
+func (pm *PrivacyManager) GetAuditTrail(userID uint64) ([]*PrivacyAudit, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := pm.audit.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query privacy audits: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var audits []*PrivacyAudit
+	if err := cursor.All(ctx, &audits); err != nil {
+		return nil, fmt.Errorf("failed to decode privacy audits: %v", err)
+	}
+	return audits, nil
+}
+
+// collectUserData 查询用户在各集合中的文档
+func (pm *PrivacyManager) collectUserData(userID uint64) (*UserDataExport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	export := &UserDataExport{
+		ExportedAt: time.Now(),
+	}
+
+	// 通过仓库读取以解密敏感字段
+	user, err := database.NewUserRepository(pm.mongo).GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	user.Password = ""
+	export.User = user
+
+	queries := []struct {
+		collection string
+		filter     bson.M
+		dest       interface{}
+	}{
+		{"friends", bson.M{"$or": []bson.M{{"user_id": userID}, {"friend_id": userID}}}, &export.Friends},
+		{"chat_messages", bson.M{"$or": []bson.M{{"from_user_id": userID}, {"to_user_id": userID}}}, &export.ChatMessages},
+		{"chat_reactions", bson.M{"u": userID}, &export.Reactions},
+		{"blocked_users", bson.M{"user_id": userID}, &export.BlockedUsers},
+		{"mails", bson.M{"to_user_id": userID}, &export.Mails},
+		{"game_records", bson.M{"players.user_id": userID}, &export.GameRecords},
+		{"ban_records", bson.M{"user_id": userID}, &export.BanRecords},
+		{"user_settings", bson.M{"user_id": userID}, &export.Settings},
+		{"activity_timeline", bson.M{"user_id": userID}, &export.Activity},
+		{"device_tokens", bson.M{"user_id": userID}, &export.DeviceTokens},
+		{"support_tickets", bson.M{"user_id": userID}, &export.SupportTickets},
+		{"survey_responses", bson.M{"user_id": userID}, &export.SurveyResponses},
+		{"name_changes", bson.M{"user_id": userID}, &export.NameChanges},
+	}
+
+	for _, q := range queries {
+		cursor, err := pm.mongo.GetCollection(q.collection).Find(ctx, q.filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query %s: %v", q.collection, err)
+		}
+		err = cursor.All(ctx, q.dest)
+		cursor.Close(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %v", q.collection, err)
+		}
+	}
+
+	return export, nil
+}
+
+// exportCounts 导出的各集合文档数，记录在审计中
+func exportCounts(export *UserDataExport) map[string]int64 {
+	return map[string]int64{
+		"users":             1,
+		"friends":           int64(len(export.Friends)),
+		"chat_messages":     int64(len(export.ChatMessages)),
+		"chat_reactions":    int64(len(export.Reactions)),
+		"blocked_users":     int64(len(export.BlockedUsers)),
+		"mails":             int64(len(export.Mails)),
+		"game_records":      int64(len(export.GameRecords)),
+		"ban_records":       int64(len(export.BanRecords)),
+		"user_settings":     int64(len(export.Settings)),
+		"activity_timeline": int64(len(export.Activity)),
+		"device_tokens":     int64(len(export.DeviceTokens)),
+		"support_tickets":   int64(len(export.SupportTickets)),
+		"survey_responses":  int64(len(export.SurveyResponses)),
+		"name_changes":      int64(len(export.NameChanges)),
+	}
+}
+
+// archiveEntry 归档中的一个文件
+type archiveEntry struct {
+	name string
+	data interface{}
+}
+
+// archiveEntries 导出内容对应的归档文件，UserDataExport新增字段时需要同步添加
+func archiveEntries(export *UserDataExport) []archiveEntry {
+	return []archiveEntry{
+		{"profile.json", export.User},
+		{"friends.json", export.Friends},
+		{"chat_messages.json", export.ChatMessages},
+		{"chat_reactions.json", export.Reactions},
+		{"blocked_users.json", export.BlockedUsers},
+		{"mails.json", export.Mails},
+		{"game_records.json", export.GameRecords},
+		{"ban_records.json", export.BanRecords},
+		{"settings.json", export.Settings},
+		{"activity.json", export.Activity},
+		{"device_tokens.json", export.DeviceTokens},
+		{"support_tickets.json", export.SupportTickets},
+		{"survey_responses.json", export.SurveyResponses},
+		{"name_changes.json", export.NameChanges},
+	}
+}
+
+// writeArchive 将导出内容按集合写入zip归档
+func (pm *PrivacyManager) writeArchive(userID uint64, export *UserDataExport) (string, error) {
+	if err := os.MkdirAll(pm.exportDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create export dir: %v", err)
+	}
+
+	archivePath := filepath.Join(pm.exportDir, fmt.Sprintf("user_%d_%d.zip", userID, export.ExportedAt.Unix()))
+	file, err := os.OpenFile(archivePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", fmt.Errorf("failed to create archive: %v", err)
+	}
+	defer file.Close()
+
+	writer := zip.NewWriter(file)
+	entries := archiveEntries(export)
+
+	for _, entry := range entries {
+		data, err := json.MarshalIndent(entry.data, "", "  ")
+		if err != nil {
+			writer.Close()
+			return "", fmt.Errorf("failed to marshal %s: %v", entry.name, err)
+		}
+		w, err := writer.Create(entry.name)
+		if err != nil {
+			writer.Close()
+			return "", fmt.Errorf("failed to add %s: %v", entry.name, err)
+		}
+		if _, err := w.Write(data); err != nil {
+			writer.Close()
+			return "", fmt.Errorf("failed to write %s: %v", entry.name, err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("failed to finalize archive: %v", err)
+	}
+
+	return archivePath, nil
+}
+
+// anonymizeSurveyResponses 问卷回答保留用于统计和导出，玩家ID替换为随机的匿名ID。
+// 每份回答使用不同的匿名ID，满足问卷和玩家ID的唯一索引，也无法关联同一玩家的多份回答
+func (pm *PrivacyManager) anonymizeSurveyResponses(ctx context.Context, userID uint64) (int64, error) {
+	collection := pm.mongo.GetCollection("survey_responses")
+	cursor, err := collection.Find(ctx, bson.M{"user_id": userID}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return 0, err
+	}
+	var responses []*database.SurveyResponse
+	err = cursor.All(ctx, &responses)
+	cursor.Close(ctx)
+	if err != nil || len(responses) == 0 {
+		return 0, err
+	}
+
+	models := make([]mongo.WriteModel, 0, len(responses))
+	buf := make([]byte, 8)
+	for _, response := range responses {
+		if _, err := rand.Read(buf); err != nil {
+			return 0, fmt.Errorf("failed to generate anonymous id: %v", err)
+		}
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": response.ID}).
+			SetUpdate(bson.M{"$set": bson.M{"user_id": binary.BigEndian.Uint64(buf) | AnonymousUserIDBit}}))
+	}
+
+	result, err := collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// deleteReactions 删除用户的聊天回应，并扣减被回应消息上的回应人数。
+// 逐条删除，只有本次删除成功的回应才扣减，避免与玩家同时取消回应时重复扣减；影子封禁时的回应未计入人数，无需扣减
+func (pm *PrivacyManager) deleteReactions(ctx context.Context, userID uint64) (int64, error) {
+	reactions := pm.mongo.GetCollection("chat_reactions")
+	cursor, err := reactions.Find(ctx, bson.M{"u": userID})
+	if err != nil {
+		return 0, err
+	}
+	var found []*database.ChatReaction
+	err = cursor.All(ctx, &found)
+	cursor.Close(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	models := make([]mongo.WriteModel, 0, len(found))
+	for _, reaction := range found {
+		result, err := reactions.DeleteOne(ctx, bson.M{"m": reaction.MessageID, "u": userID})
+		if err != nil {
+			return deleted, err
+		}
+		if result.DeletedCount == 0 {
+			continue
+		}
+		deleted++
+		if reaction.Shadow || reaction.EmojiID == 0 {
+			continue
+		}
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"message_id": reaction.MessageID}).
+			SetUpdate(bson.M{"$inc": bson.M{fmt.Sprintf("reactions.%d", reaction.EmojiID): -1}}))
+	}
+
+	if len(models) > 0 {
+		if _, err := pm.mongo.GetCollection("chat_messages").BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false)); err != nil {
+			return deleted, fmt.Errorf("failed to adjust reaction counts: %v", err)
+		}
+	}
+	return deleted, nil
+}
+
+// deleteMany 删除集合中匹配的文档
+func (pm *PrivacyManager) deleteMany(ctx context.Context, collection string, filter bson.M) (int64, error) {
+	result, err := pm.mongo.GetCollection(collection).DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// updateMany 更新集合中匹配的文档
+func (pm *PrivacyManager) updateMany(ctx context.Context, collection string, filter, update bson.M, opts ...*options.UpdateOptions) (int64, error) {
+	result, err := pm.mongo.GetCollection(collection).UpdateMany(ctx, filter, update, opts...)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// startAudit 写入审计记录
+func (pm *PrivacyManager) startAudit(userID, gmUserID uint64, requestType, reason string) (*PrivacyAudit, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	audit := &PrivacyAudit{
+		UserID:      userID,
+		GMUserID:    gmUserID,
+		RequestType: requestType,
+		Status:      RequestStatusRunning,
+		Reason:      reason,
+		CreatedAt:   time.Now(),
+	}
+
+	result, err := pm.audit.InsertOne(ctx, audit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write privacy audit: %v", err)
+	}
+	audit.ID = result.InsertedID.(primitive.ObjectID)
+
+	return audit, nil
+}
+
+// finishAudit 更新审计记录的最终状态
+func (pm *PrivacyManager) finishAudit(audit *PrivacyAudit, opErr error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	audit.Status = RequestStatusCompleted
+	if opErr != nil {
+		audit.Status = RequestStatusFailed
+		audit.Error = opErr.Error()
+	}
+	audit.CompletedAt = time.Now()
+
+	update := bson.M{
+		"$set": bson.M{
+			"status":       audit.Status,
+			"archive_path": audit.ArchivePath,
+			"affected":     audit.Affected,
+			"error":        audit.Error,
+			"completed_at": audit.CompletedAt,
+		},
+	}
+
+	if _, err := pm.audit.UpdateOne(ctx, bson.M{"_id": audit.ID}, update); err != nil {
+		logger.Error(fmt.Sprintf("Failed to update privacy audit %s: %v", audit.ID.Hex(), err))
+	}
+}
@@ -0,0 +1,85 @@
+package privacy
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fullExport 每个字段都填充一条记录的导出内容
+func fullExport() *UserDataExport {
+	export := &UserDataExport{ExportedAt: time.Unix(1700000000, 0)}
+	value := reflect.ValueOf(export).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		switch field.Kind() {
+		case reflect.Ptr:
+			field.Set(reflect.New(field.Type().Elem()))
+		case reflect.Slice:
+			elem := reflect.New(field.Type().Elem().Elem())
+			field.Set(reflect.Append(reflect.MakeSlice(field.Type(), 0, 1), elem))
+		}
+	}
+	return export
+}
+
+// TestArchiveContainsAllCollectedData 收集到的每个字段都必须写入归档并计入审计
+func TestArchiveContainsAllCollectedData(t *testing.T) {
+	export := fullExport()
+	pm := &PrivacyManager{exportDir: t.TempDir()}
+
+	archivePath, err := pm.writeArchive(1001, export)
+	if err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer reader.Close()
+
+	contents := make(map[string]string)
+	for _, file := range reader.File {
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", file.Name, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", file.Name, err)
+		}
+		contents[string(data)] = file.Name
+	}
+
+	collected := 0
+	value := reflect.ValueOf(export).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.Name == "ExportedAt" {
+			continue
+		}
+		collected++
+
+		data, err := json.MarshalIndent(value.Field(i).Interface(), "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, exists := contents[string(data)]; !exists {
+			t.Errorf("field %s is collected but not written to the archive", field.Name)
+		}
+	}
+
+	if counts := exportCounts(export); len(counts) != collected {
+		t.Errorf("audit counts %d collections, export collects %d", len(counts), collected)
+	}
+	for name, count := range exportCounts(export) {
+		if count != 1 {
+			t.Errorf("audit count for %s is %d, want 1", name, count)
+		}
+	}
+}
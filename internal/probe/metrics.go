@@ -0,0 +1,96 @@
+package probe
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// durationBuckets 探测和步骤耗时分布的区间，单位秒
+var durationBuckets = []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Metrics 探测的成功率和耗时，告警规则见monitoring/lufy_rules.yml
+type Metrics struct {
+	runs        *prometheus.CounterVec
+	duration    prometheus.Histogram
+	steps       *prometheus.HistogramVec
+	stepErrors  *prometheus.CounterVec
+	failures    prometheus.Gauge
+	lastSuccess prometheus.Gauge
+}
+
+// NewMetrics 创建探测指标
+func NewMetrics() *Metrics {
+	return &Metrics{
+		runs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "lufy_probe_runs_total",
+			Help: "Number of probe scenario runs by result",
+		}, []string{"result"}),
+		duration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "lufy_probe_duration_seconds",
+			Help:    "Duration of probe scenario runs in seconds, excluding cleanup steps",
+			Buckets: durationBuckets,
+		}),
+		steps: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "lufy_probe_step_duration_seconds",
+			Help:    "Duration of probe scenario steps in seconds",
+			Buckets: durationBuckets,
+		}, []string{"step"}),
+		stepErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "lufy_probe_step_failures_total",
+			Help: "Number of failed probe scenario steps",
+		}, []string{"step"}),
+		failures: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "lufy_probe_consecutive_failures",
+			Help: "Number of probe scenario runs failed in a row",
+		}),
+		lastSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "lufy_probe_last_success_timestamp_seconds",
+			Help: "Unix time of the last successful probe scenario run",
+		}),
+	}
+}
+
+// Describe 实现prometheus.Collector接口
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.runs.Describe(ch)
+	m.duration.Describe(ch)
+	m.steps.Describe(ch)
+	m.stepErrors.Describe(ch)
+	m.failures.Describe(ch)
+	m.lastSuccess.Describe(ch)
+}
+
+// Collect 实现prometheus.Collector接口
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.runs.Collect(ch)
+	m.duration.Collect(ch)
+	m.steps.Collect(ch)
+	m.stepErrors.Collect(ch)
+	m.failures.Collect(ch)
+	m.lastSuccess.Collect(ch)
+}
+
+// observeStep 记录一步的耗时和结果
+func (m *Metrics) observeStep(step string, duration time.Duration, failed bool) {
+	m.steps.WithLabelValues(step).Observe(duration.Seconds())
+	if failed {
+		m.stepErrors.WithLabelValues(step).Inc()
+	}
+}
+
+// observeRun 记录一次探测的结果
+func (m *Metrics) observeRun(result *Result) {
+	if !result.Success {
+		m.runs.WithLabelValues("failure").Inc()
+		return
+	}
+	m.runs.WithLabelValues("success").Inc()
+	m.duration.Observe(result.Duration.Seconds())
+	m.lastSuccess.Set(float64(result.Time.Unix()))
+}
+
+// setFailures 设置连续失败次数
+func (m *Metrics) setFailures(n int) {
+	m.failures.Set(float64(n))
+}
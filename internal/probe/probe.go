@@ -0,0 +1,304 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// 探测默认设置
+const (
+	defaultInterval   = time.Minute
+	defaultTimeout    = 30 * time.Second
+	defaultAlertAfter = 3
+	webhookTimeout    = 5 * time.Second
+)
+
+// Config 合成监控配置，探测节点按间隔执行一次完整的玩家流程
+type Config struct {
+	Interval     time.Duration `yaml:"interval"`      // 两次探测的间隔，未配置时为1分钟
+	Timeout      time.Duration `yaml:"timeout"`       // 单次探测的时限，未配置时为30秒
+	AlertAfter   int           `yaml:"alert_after"`   // 连续失败多少次后告警，未配置时为3
+	AlertWebhook string        `yaml:"alert_webhook"` // 告警和恢复时POST JSON到该地址，为空时只记录日志和导出指标
+	MetricsPort  int           `yaml:"metrics_port"`  // 指标端口，0为不导出
+	Gateway      string        `yaml:"gateway"`       // 网关地址，探测握手和登录，为空时跳过网关
+	Accounts     []Account     `yaml:"accounts"`      // 探测账号，第一个创建房间，第二个加入
+	GameType     int32         `yaml:"game_type"`     // 对局的玩法类型
+	Script       []Action      `yaml:"script"`        // 对局操作，由两个账号交替执行，最后一步应结束对局
+	AdminToken   string        `yaml:"admin_token"`   // 领取邮件使用的RPC管理令牌，令牌的operator_id需为第一个探测账号，为空时跳过邮件
+}
+
+// Account 探测账号，需预先注册并达到开房间和异步对局的解锁等级
+type Account struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// Action 对局脚本中的一步操作
+type Action struct {
+	Player int                    `yaml:"player"` // 执行操作的账号序号，0或1
+	Action string                 `yaml:"action"` // play_card、use_skill、end_turn、surrender
+	Data   map[string]interface{} `yaml:"data"`
+}
+
+// Validate 检查探测配置
+func (c *Config) Validate() error {
+	if len(c.Accounts) != 2 {
+		return fmt.Errorf("probe requires exactly 2 accounts, got %d", len(c.Accounts))
+	}
+	for i, account := range c.Accounts {
+		if account.Username == "" || account.Password == "" {
+			return fmt.Errorf("probe account %d: username and password are required", i)
+		}
+	}
+	if len(c.Script) == 0 {
+		return fmt.Errorf("probe script is empty")
+	}
+	for i, action := range c.Script {
+		if action.Player != 0 && action.Player != 1 {
+			return fmt.Errorf("probe script step %d: player must be 0 or 1", i)
+		}
+	}
+	return nil
+}
+
+// withDefaults 补全未配置的项
+func (c Config) withDefaults() Config {
+	if c.Interval <= 0 {
+		c.Interval = defaultInterval
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaultTimeout
+	}
+	if c.AlertAfter <= 0 {
+		c.AlertAfter = defaultAlertAfter
+	}
+	return c
+}
+
+// Step 流程中的一步
+type Step struct {
+	Name    string
+	Run     func(ctx context.Context) error
+	Cleanup bool // 清理步骤，前面的步骤失败时也执行，失败只计入步骤指标，不影响探测结果
+}
+
+// Scenario 生成一次探测的步骤，步骤间通过闭包共享状态，每次探测重新生成
+type Scenario func() []Step
+
+// StepResult 单个步骤的结果
+type StepResult struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Result 一次探测的结果
+type Result struct {
+	Success  bool          `json:"success"`
+	Duration time.Duration `json:"duration"` // 不含清理步骤
+	Steps    []*StepResult `json:"steps"`
+	Failed   *StepResult   `json:"failed,omitempty"` // 第一个失败的步骤
+	Time     time.Time     `json:"time"`
+}
+
+// alert 发送到告警地址的内容
+type alert struct {
+	Node     string    `json:"node"`
+	Status   string    `json:"status"` // failing, recovered
+	Failures int       `json:"failures"`
+	Step     string    `json:"step,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// Runner 按间隔执行探测流程，导出成功率和耗时，连续失败时告警
+type Runner struct {
+	config   Config
+	nodeID   string
+	scenario Scenario
+	metrics  *Metrics
+	client   *http.Client
+
+	failures int // 连续失败次数
+	alerting bool
+	last     *Result
+	mutex    sync.Mutex
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewRunner 创建探测执行器
+func NewRunner(config *Config, nodeID string, scenario Scenario) *Runner {
+	return &Runner{
+		config:   config.withDefaults(),
+		nodeID:   nodeID,
+		scenario: scenario,
+		metrics:  NewMetrics(),
+		client:   &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// Metrics 探测指标
+func (r *Runner) Metrics() *Metrics {
+	return r.metrics
+}
+
+// Last 最近一次探测的结果，尚未探测时为nil
+func (r *Runner) Last() *Result {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.last
+}
+
+// Start 启动后立即探测一次，之后按间隔执行
+func (r *Runner) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(r.config.Interval)
+		defer ticker.Stop()
+		for {
+			r.RunOnce(ctx)
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止探测，等待进行中的探测结束
+func (r *Runner) Stop() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.wg.Wait()
+}
+
+// RunOnce 执行一次探测，某一步失败后跳过之后的步骤，只执行清理步骤
+func (r *Runner) RunOnce(parent context.Context) *Result {
+	ctx, cancel := context.WithTimeout(parent, r.config.Timeout)
+	defer cancel()
+
+	result := &Result{Success: true, Time: time.Now()}
+	for _, step := range r.scenario() {
+		if !result.Success && !step.Cleanup {
+			continue
+		}
+		start := time.Now()
+		err := r.runStep(ctx, step)
+		stepResult := &StepResult{Name: step.Name, Duration: time.Since(start)}
+		if err != nil {
+			stepResult.Error = err.Error()
+		}
+		result.Steps = append(result.Steps, stepResult)
+		r.metrics.observeStep(step.Name, stepResult.Duration, err != nil)
+
+		if step.Cleanup {
+			if err != nil {
+				logger.Warn(fmt.Sprintf("Probe cleanup %s failed: %v", step.Name, err))
+			}
+			continue
+		}
+		result.Duration += stepResult.Duration
+		if err != nil {
+			result.Success = false
+			result.Failed = stepResult
+		}
+	}
+
+	// 节点停止中断的探测不计入结果
+	if parent.Err() != nil {
+		return result
+	}
+	r.record(result)
+	return result
+}
+
+// runStep 执行一步，清理步骤使用单独的时限，超时导致失败时仍有时间清理
+func (r *Runner) runStep(ctx context.Context, step Step) error {
+	if step.Cleanup {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), r.config.Timeout)
+		defer cancel()
+	}
+	return step.Run(ctx)
+}
+
+// record 记录结果，连续失败达到阈值时告警，告警后成功一次时发送恢复
+func (r *Runner) record(result *Result) {
+	r.metrics.observeRun(result)
+
+	r.mutex.Lock()
+	r.last = result
+	if result.Success {
+		r.failures = 0
+	} else {
+		r.failures++
+	}
+	failures := r.failures
+	notify := ""
+	switch {
+	case !result.Success && !r.alerting && failures >= r.config.AlertAfter:
+		r.alerting, notify = true, "failing"
+	case result.Success && r.alerting:
+		r.alerting, notify = false, "recovered"
+	}
+	r.mutex.Unlock()
+
+	r.metrics.setFailures(failures)
+
+	if result.Success {
+		logger.Debug(fmt.Sprintf("Probe succeeded in %v", result.Duration))
+	} else {
+		logger.Warn(fmt.Sprintf("Probe failed at %s (%d in a row): %s", result.Failed.Name, failures, result.Failed.Error))
+	}
+
+	switch notify {
+	case "failing":
+		logger.Error(fmt.Sprintf("Probe scenario failing %d times in a row, last failed step %s: %s",
+			failures, result.Failed.Name, result.Failed.Error))
+	case "recovered":
+		logger.Info("Probe scenario recovered")
+	}
+	if notify != "" && r.config.AlertWebhook != "" {
+		a := &alert{Node: r.nodeID, Status: notify, Failures: failures, Time: result.Time}
+		if result.Failed != nil {
+			a.Step, a.Error = result.Failed.Name, result.Failed.Error
+		}
+		if err := r.sendAlert(a); err != nil {
+			logger.Error(fmt.Sprintf("Failed to send probe alert: %v", err))
+		}
+	}
+}
+
+// sendAlert 发送告警到配置的地址
+func (r *Runner) sendAlert(a *alert) error {
+	data, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	resp, err := r.client.Post(r.config.AlertWebhook, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("alert webhook returned %s", resp.Status)
+	}
+	return nil
+}
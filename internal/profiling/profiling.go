@@ -0,0 +1,124 @@
+package profiling
+
+import (
+	"bytes"
+	"fmt"
+	"runtime/pprof"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// 支持的profile类型
+const (
+	ProfileCPU       = "cpu"
+	ProfileHeap      = "heap"
+	ProfileAllocs    = "allocs"
+	ProfileGoroutine = "goroutine"
+	ProfileBlock     = "block"
+	ProfileMutex     = "mutex"
+)
+
+// ProfilingConfig 按需采集profile配置
+type ProfilingConfig struct {
+	MaxSeconds int         `yaml:"max_seconds"` // CPU采集最长时间
+	Store      StoreConfig `yaml:"store"`
+}
+
+// Profiler profile采集器
+type Profiler struct {
+	config   ProfilingConfig
+	store    ObjectStore
+	nodeID   string
+	cpuMutex sync.Mutex // 同一时间只能进行一次CPU采集
+}
+
+// NewProfiler 创建profile采集器
+func NewProfiler(config *ProfilingConfig, nodeID string) (*Profiler, error) {
+	cfg := *config
+	if cfg.MaxSeconds <= 0 {
+		cfg.MaxSeconds = 120
+	}
+
+	store, err := NewObjectStore(&cfg.Store)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Profiler{
+		config: cfg,
+		store:  store,
+		nodeID: nodeID,
+	}, nil
+}
+
+// ValidateRequest 校验采集参数
+func (p *Profiler) ValidateRequest(profileType string, seconds int) error {
+	switch profileType {
+	case ProfileCPU:
+		if seconds <= 0 || seconds > p.config.MaxSeconds {
+			return fmt.Errorf("seconds must be between 1 and %d", p.config.MaxSeconds)
+		}
+	case ProfileHeap, ProfileAllocs, ProfileGoroutine, ProfileBlock, ProfileMutex:
+	default:
+		return fmt.Errorf("unsupported profile type: %s", profileType)
+	}
+	return nil
+}
+
+// ObjectKey 生成profile的对象键
+func (p *Profiler) ObjectKey(nodeID, profileType string) string {
+	return fmt.Sprintf("profiles/%s/%s-%s.pb.gz", nodeID, profileType, time.Now().Format("20060102-150405"))
+}
+
+// URL 获取对象的下载地址
+func (p *Profiler) URL(key string) string {
+	return p.store.URL(key)
+}
+
+// CaptureAndUpload 采集profile并上传到对象存储
+func (p *Profiler) CaptureAndUpload(profileType string, seconds int, key string) error {
+	data, err := p.Capture(profileType, seconds)
+	if err != nil {
+		return err
+	}
+
+	if err := p.store.Put(key, data); err != nil {
+		return err
+	}
+
+	logger.Info(fmt.Sprintf("Profile %s uploaded for %s: %s (%d bytes)", profileType, p.nodeID, key, len(data)))
+	return nil
+}
+
+// Capture 采集profile，CPU类型阻塞指定秒数
+func (p *Profiler) Capture(profileType string, seconds int) ([]byte, error) {
+	if err := p.ValidateRequest(profileType, seconds); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if profileType == ProfileCPU {
+		if !p.cpuMutex.TryLock() {
+			return nil, fmt.Errorf("cpu profile already in progress")
+		}
+		defer p.cpuMutex.Unlock()
+
+		if err := pprof.StartCPUProfile(&buf); err != nil {
+			return nil, fmt.Errorf("failed to start cpu profile: %v", err)
+		}
+		time.Sleep(time.Duration(seconds) * time.Second)
+		pprof.StopCPUProfile()
+		return buf.Bytes(), nil
+	}
+
+	profile := pprof.Lookup(profileType)
+	if profile == nil {
+		return nil, fmt.Errorf("profile %s not found", profileType)
+	}
+	if err := profile.WriteTo(&buf, 0); err != nil {
+		return nil, fmt.Errorf("failed to write %s profile: %v", profileType, err)
+	}
+	return buf.Bytes(), nil
+}
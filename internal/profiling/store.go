@@ -0,0 +1,171 @@
+package profiling
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 对象存储类型
+const (
+	StoreTypeLocal = "local" // 本地目录，通常挂载共享存储并由静态文件服务提供下载
+	StoreTypeHTTP  = "http"  // 通过HTTP PUT上传，兼容MinIO、WebDAV等
+)
+
+// StoreConfig 对象存储配置
+type StoreConfig struct {
+	Type       string `yaml:"type"`        // local, http
+	Dir        string `yaml:"dir"`         // local类型的存储目录
+	UploadURL  string `yaml:"upload_url"`  // http类型的上传地址前缀
+	AuthToken  string `yaml:"auth_token"`  // http类型上传时的Bearer令牌
+	PublicURL  string `yaml:"public_url"`  // 下载地址前缀
+	TimeoutSec int    `yaml:"timeout_sec"` // 上传超时
+}
+
+// ObjectStore 对象存储接口
+type ObjectStore interface {
+	Put(key string, data []byte) error
+	Get(key string) ([]byte, error)
+	URL(key string) string
+}
+
+// NewObjectStore 根据配置创建对象存储
+func NewObjectStore(config *StoreConfig) (ObjectStore, error) {
+	switch config.Type {
+	case "", StoreTypeLocal:
+		dir := config.Dir
+		if dir == "" {
+			dir = "profiles"
+		}
+		return &LocalStore{dir: dir, publicURL: config.PublicURL}, nil
+
+	case StoreTypeHTTP:
+		if config.UploadURL == "" {
+			return nil, fmt.Errorf("upload_url is required for http store")
+		}
+		timeout := time.Duration(config.TimeoutSec) * time.Second
+		if timeout <= 0 {
+			timeout = 30 * time.Second
+		}
+		publicURL := config.PublicURL
+		if publicURL == "" {
+			publicURL = config.UploadURL
+		}
+		return &HTTPStore{
+			uploadURL: config.UploadURL,
+			publicURL: publicURL,
+			authToken: config.AuthToken,
+			client:    &http.Client{Timeout: timeout},
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported object store type: %s", config.Type)
+	}
+}
+
+// LocalStore 本地目录存储
+type LocalStore struct {
+	dir       string
+	publicURL string
+}
+
+// Put 写入文件
+func (ls *LocalStore) Put(key string, data []byte) error {
+	path := filepath.Join(ls.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create profile directory: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write profile: %v", err)
+	}
+	return nil
+}
+
+// Get 读取文件
+func (ls *LocalStore) Get(key string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(ls.dir, filepath.FromSlash(key)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object %s: %v", key, err)
+	}
+	return data, nil
+}
+
+// URL 获取下载地址，未配置下载地址前缀时返回本地路径
+func (ls *LocalStore) URL(key string) string {
+	if ls.publicURL == "" {
+		return filepath.Join(ls.dir, filepath.FromSlash(key))
+	}
+	return joinURL(ls.publicURL, key)
+}
+
+// HTTPStore HTTP PUT存储
+type HTTPStore struct {
+	uploadURL string
+	publicURL string
+	authToken string
+	client    *http.Client
+}
+
+// Put 上传对象
+func (hs *HTTPStore) Put(key string, data []byte) error {
+	req, err := http.NewRequest(http.MethodPut, joinURL(hs.uploadURL, key), bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create upload request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if hs.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+hs.authToken)
+	}
+
+	resp, err := hs.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload profile: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("profile upload returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Get 从下载地址读取对象
+func (hs *HTTPStore) Get(key string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, joinURL(hs.publicURL, key), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create download request: %v", err)
+	}
+	if hs.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+hs.authToken)
+	}
+
+	resp, err := hs.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download object %s: %v", key, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("object download %s returned %d", key, resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object %s: %v", key, err)
+	}
+	return data, nil
+}
+
+// URL 获取下载地址
+func (hs *HTTPStore) URL(key string) string {
+	return joinURL(hs.publicURL, key)
+}
+
+// joinURL 拼接地址前缀和对象键
+func joinURL(base, key string) string {
+	return strings.TrimRight(base, "/") + "/" + strings.TrimLeft(key, "/")
+}
@@ -0,0 +1,355 @@
+package progression
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// Config 等级成长配置，等级曲线可以直接配置每级的累计经验，也可以按公式生成
+type Config struct {
+	Levels     int32            `yaml:"levels"`     // 最高等级，配置thresholds时为thresholds长度加1
+	Thresholds []int64          `yaml:"thresholds"` // 升到2级、3级...所需的累计经验，配置后忽略公式
+	Base       int64            `yaml:"base"`       // 公式: 1级升到2级所需经验
+	Growth     float64          `yaml:"growth"`     // 公式: 每级所需经验相对上一级的倍数
+	Rewards    []LevelReward    `yaml:"rewards"`    // 升级奖励，通过邮件发放
+	Features   map[string]int32 `yaml:"features"`   // 功能解锁等级，功能名不区分大小写，未配置的功能不限制
+	Mail       MailConfig       `yaml:"mail"`
+}
+
+// LevelReward 达到指定等级时发放的奖励
+type LevelReward struct {
+	Level int32  `yaml:"level"`
+	Items []Item `yaml:"items"`
+}
+
+// Item 奖励物品，对应邮件附件
+type Item struct {
+	Type  int32  `yaml:"type"`
+	ID    int32  `yaml:"id"`
+	Count int64  `yaml:"count"`
+	Name  string `yaml:"name"`
+}
+
+// MailConfig 升级奖励邮件
+type MailConfig struct {
+	Title   string        `yaml:"title"`
+	Content string        `yaml:"content"` // %d替换为等级
+	Expire  time.Duration `yaml:"expire"`
+}
+
+// Validate 检查等级配置
+func (c *Config) Validate() error {
+	for i, threshold := range c.Thresholds {
+		if threshold <= 0 || (i > 0 && threshold <= c.Thresholds[i-1]) {
+			return fmt.Errorf("progression thresholds must be positive and increasing")
+		}
+	}
+	if c.Base < 0 {
+		return fmt.Errorf("invalid progression base: %d", c.Base)
+	}
+	if c.Growth != 0 && c.Growth < 1 {
+		return fmt.Errorf("invalid progression growth: %v", c.Growth)
+	}
+
+	config := c.withDefaults()
+	levels := make(map[int32]bool, len(c.Rewards))
+	for _, reward := range c.Rewards {
+		if reward.Level < 2 || reward.Level > config.Levels {
+			return fmt.Errorf("level reward out of range: %d", reward.Level)
+		}
+		if levels[reward.Level] {
+			return fmt.Errorf("duplicate level reward: %d", reward.Level)
+		}
+		levels[reward.Level] = true
+	}
+	for feature, level := range c.Features {
+		if level < 1 || level > config.Levels {
+			return fmt.Errorf("feature %s unlock level out of range: %d", feature, level)
+		}
+	}
+	return nil
+}
+
+// withDefaults 补全未配置的项
+func (c *Config) withDefaults() Config {
+	config := *c
+	if len(config.Thresholds) > 0 {
+		config.Levels = int32(len(config.Thresholds)) + 1
+	}
+	if config.Levels <= 0 {
+		config.Levels = 100
+	}
+	if config.Base <= 0 {
+		config.Base = 100
+	}
+	if config.Growth <= 0 {
+		config.Growth = 1.2
+	}
+	if config.Mail.Title == "" {
+		config.Mail.Title = "等级奖励"
+	}
+	if config.Mail.Content == "" {
+		config.Mail.Content = "恭喜升到%d级，请领取等级奖励"
+	}
+	if config.Mail.Expire <= 0 {
+		config.Mail.Expire = 30 * 24 * time.Hour
+	}
+	return config
+}
+
+// LedgerStore 账本存储，database.LedgerRepository实现了该接口
+type LedgerStore interface {
+	Apply(entry *database.LedgerEntry) (bool, error)
+}
+
+// UserStore 用户存储，database.UserRepository实现了该接口
+type UserStore interface {
+	GetByUserID(userID uint64) (*database.User, error)
+	RaiseLevel(userID uint64, from, to int32) (bool, error)
+}
+
+// LevelUpHandler 玩家升级后的回调，from为升级前的等级
+type LevelUpHandler func(userID uint64, from, to int32)
+
+// Progress 玩家的等级进度
+type Progress struct {
+	Level               int32   `json:"level"`
+	Experience          int64   `json:"experience"`
+	LevelExperience     int64   `json:"level_experience"`      // 当前等级起始的累计经验
+	NextLevelExperience int64   `json:"next_level_experience"` // 升到下一级所需的累计经验，已满级时为0
+	Gained              []int32 `json:"gained,omitempty"`      // 本次升到的等级
+}
+
+// Service 等级成长服务，经验通过账本入账，等级由累计经验计算，只升不降
+type Service struct {
+	config     Config
+	thresholds []int64 // thresholds[i]为升到i+2级所需的累计经验
+	rewards    map[int32][]database.MailReward
+	ledger     LedgerStore
+	users      UserStore
+	mails      database.MailStore
+	mailIDs    database.MailIDGenerator
+	onLevelUp  LevelUpHandler
+}
+
+// NewService 创建等级成长服务
+func NewService(config *Config, ledger LedgerStore, users UserStore, mails database.MailStore, mailIDs database.MailIDGenerator) *Service {
+	cfg := config.withDefaults()
+	features := make(map[string]int32, len(cfg.Features))
+	for feature, level := range cfg.Features {
+		features[strings.ToLower(feature)] = level
+	}
+	cfg.Features = features
+
+	service := &Service{
+		config:     cfg,
+		thresholds: buildThresholds(&cfg),
+		rewards:    make(map[int32][]database.MailReward, len(cfg.Rewards)),
+		ledger:     ledger,
+		users:      users,
+		mails:      mails,
+		mailIDs:    mailIDs,
+	}
+	for _, reward := range cfg.Rewards {
+		for _, item := range reward.Items {
+			service.rewards[reward.Level] = append(service.rewards[reward.Level], database.MailReward{
+				Type:   item.Type,
+				ItemID: item.ID,
+				Count:  item.Count,
+				Name:   item.Name,
+			})
+		}
+	}
+	return service
+}
+
+// SetLevelUpHandler 设置升级回调，只在提升等级成功的节点上调用一次
+func (s *Service) SetLevelUpHandler(handler LevelUpHandler) {
+	s.onLevelUp = handler
+}
+
+// buildThresholds 生成每级的累计经验
+func buildThresholds(config *Config) []int64 {
+	if len(config.Thresholds) > 0 {
+		return append([]int64(nil), config.Thresholds...)
+	}
+
+	thresholds := make([]int64, 0, config.Levels-1)
+	total := int64(0)
+	for level := int32(1); level < config.Levels; level++ {
+		need := math.Round(float64(config.Base) * math.Pow(config.Growth, float64(level-1)))
+		if need >= float64(math.MaxInt64-total) {
+			total = math.MaxInt64
+		} else {
+			total += int64(need)
+		}
+		thresholds = append(thresholds, total)
+	}
+	return thresholds
+}
+
+// MaxLevel 最高等级
+func (s *Service) MaxLevel() int32 {
+	return s.config.Levels
+}
+
+// LevelFor 累计经验对应的等级
+func (s *Service) LevelFor(experience int64) int32 {
+	reached := sort.Search(len(s.thresholds), func(i int) bool { return s.thresholds[i] > experience })
+	return int32(reached) + 1
+}
+
+// Threshold 达到等级所需的累计经验
+func (s *Service) Threshold(level int32) int64 {
+	if level <= 1 {
+		return 0
+	}
+	if len(s.thresholds) == 0 {
+		return 0
+	}
+	if int(level-2) >= len(s.thresholds) {
+		return s.thresholds[len(s.thresholds)-1]
+	}
+	return s.thresholds[level-2]
+}
+
+// Progress 计算玩家当前的等级进度
+func (s *Service) Progress(user *database.User) *Progress {
+	level := user.Level
+	if level < 1 {
+		level = 1
+	}
+	progress := &Progress{
+		Level:           level,
+		Experience:      user.Experience,
+		LevelExperience: s.Threshold(level),
+	}
+	if level < s.config.Levels {
+		progress.NextLevelExperience = s.Threshold(level + 1)
+	}
+	return progress
+}
+
+// Grant 发放经验并检查升级，txID相同的发放只入账一次，重复调用会重新检查升级，用于补齐中断的升级
+// 游戏结算、任务等经验来源以各自的流水ID调用，reason记录来源
+func (s *Service) Grant(userID uint64, amount int64, txID, batchID, reason string) (*Progress, error) {
+	if amount < 0 {
+		return nil, fmt.Errorf("invalid experience amount: %d", amount)
+	}
+	if amount > 0 {
+		if _, err := s.ledger.Apply(&database.LedgerEntry{
+			TxID:     txID,
+			BatchID:  batchID,
+			UserID:   userID,
+			Currency: database.CurrencyExperience,
+			Amount:   amount,
+			Reason:   reason,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return s.Sync(userID)
+}
+
+// Sync 按累计经验提升等级并发放升级奖励
+// 等级以条件更新提升，多个节点同时检查时只有一个能提升成功并发放奖励；经验被冲正时等级不降低
+func (s *Service) Sync(userID uint64) (*Progress, error) {
+	for attempt := 0; attempt < 3; attempt++ {
+		user, err := s.users.GetByUserID(userID)
+		if err != nil {
+			return nil, err
+		}
+
+		from := user.Level
+		to := s.LevelFor(user.Experience)
+		if to <= from || (from == 0 && to == 1) {
+			return s.Progress(user), nil
+		}
+
+		raised, err := s.users.RaiseLevel(userID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		if !raised {
+			continue // 等级已被其他节点修改，重新读取
+		}
+
+		user.Level = to
+		progress := s.Progress(user)
+		if from < 1 {
+			from = 1
+		}
+		for level := from + 1; level <= to; level++ {
+			progress.Gained = append(progress.Gained, level)
+			s.sendReward(userID, level)
+		}
+
+		logger.Info(fmt.Sprintf("User %d leveled up %d -> %d", userID, from, to))
+		if s.onLevelUp != nil {
+			s.onLevelUp(userID, from, to)
+		}
+		return progress, nil
+	}
+	return nil, fmt.Errorf("user %d level changed concurrently", userID)
+}
+
+// sendReward 发送升级奖励邮件，失败只记录日志，需要时由GM补发
+func (s *Service) sendReward(userID uint64, level int32) {
+	rewards, exists := s.rewards[level]
+	if !exists {
+		return
+	}
+
+	mailID, err := s.mailIDs()
+	if err == nil {
+		err = s.mails.CreateMail(&database.Mail{
+			MailID:   mailID,
+			ToUserID: userID,
+			Title:    s.config.Mail.Title,
+			Content:  fmt.Sprintf(s.config.Mail.Content, level),
+			Rewards:  rewards,
+			BatchID:  fmt.Sprintf("level:%d:%d", userID, level),
+			ExpireAt: time.Now().Add(s.config.Mail.Expire),
+		})
+	}
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to send level %d reward to user %d: %v", level, userID, err))
+	}
+}
+
+// RequiredLevel 功能解锁等级，未配置的功能返回1
+func (s *Service) RequiredLevel(feature string) int32 {
+	if level, exists := s.config.Features[strings.ToLower(feature)]; exists {
+		return level
+	}
+	return 1
+}
+
+// Features 所有配置了解锁等级的功能
+func (s *Service) Features() map[string]int32 {
+	features := make(map[string]int32, len(s.config.Features))
+	for feature, level := range s.config.Features {
+		features[feature] = level
+	}
+	return features
+}
+
+// Check 检查玩家是否已解锁功能，返回功能的解锁等级
+func (s *Service) Check(userID uint64, feature string) (bool, int32, error) {
+	required := s.RequiredLevel(feature)
+	if required <= 1 {
+		return true, required, nil
+	}
+
+	user, err := s.users.GetByUserID(userID)
+	if err != nil {
+		return false, required, err
+	}
+	return user.Level >= required, required, nil
+}
@@ -0,0 +1,181 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APNs默认地址，令牌签名一小时内有效，提前刷新
+const (
+	defaultAPNsEndpoint        = "https://api.push.apple.com"
+	defaultAPNsSandboxEndpoint = "https://api.sandbox.push.apple.com"
+	apnsTokenRefresh           = 50 * time.Minute
+)
+
+// APNsConfig Apple推送服务配置，使用基于令牌的鉴权
+type APNsConfig struct {
+	KeyFile  string `yaml:"key_file"` // .p8签名密钥文件，为空时不启用
+	KeyID    string `yaml:"key_id"`
+	TeamID   string `yaml:"team_id"`
+	Topic    string `yaml:"topic"` // 应用的Bundle ID
+	Sandbox  bool   `yaml:"sandbox"`
+	Endpoint string `yaml:"endpoint"` // 未配置时按sandbox选择官方地址
+}
+
+// apnsProvider 通过APNs HTTP/2接口推送
+type apnsProvider struct {
+	endpoint string
+	keyID    string
+	teamID   string
+	topic    string
+	key      *ecdsa.PrivateKey
+	client   *http.Client
+
+	token    string
+	issuedAt time.Time
+	mutex    sync.Mutex
+}
+
+// newAPNsProvider 读取签名密钥
+func newAPNsProvider(config *APNsConfig, timeout time.Duration) (*apnsProvider, error) {
+	if config.KeyID == "" || config.TeamID == "" || config.Topic == "" {
+		return nil, fmt.Errorf("apns key_id, team_id and topic are required")
+	}
+
+	data, err := os.ReadFile(config.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read apns key: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("apns key file is not PEM encoded")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse apns key: %v", err)
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("apns key is not an ECDSA key")
+	}
+
+	endpoint := strings.TrimRight(config.Endpoint, "/")
+	if endpoint == "" {
+		endpoint = defaultAPNsEndpoint
+		if config.Sandbox {
+			endpoint = defaultAPNsSandboxEndpoint
+		}
+	}
+
+	return &apnsProvider{
+		endpoint: endpoint,
+		keyID:    config.KeyID,
+		teamID:   config.TeamID,
+		topic:    config.Topic,
+		key:      key,
+		client:   &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Send 推送到设备
+func (p *apnsProvider) Send(ctx context.Context, token string, msg *Message) error {
+	authToken, err := p.getToken()
+	if err != nil {
+		return err
+	}
+
+	payload := map[string]interface{}{
+		"aps": map[string]interface{}{
+			"alert": map[string]string{
+				"title": msg.Title,
+				"body":  msg.Body,
+			},
+			"sound": "default",
+		},
+	}
+	for key, value := range msg.Data {
+		if key != "aps" {
+			payload[key] = value
+		}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal apns payload: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint+"/3/device/"+token, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "bearer "+authToken)
+	req.Header.Set("apns-topic", p.topic)
+	req.Header.Set("apns-push-type", "alert")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send apns message: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode == http.StatusGone ||
+		(resp.StatusCode == http.StatusBadRequest && bytes.Contains(respBody, []byte("BadDeviceToken"))) {
+		return ErrInvalidToken
+	}
+	return fmt.Errorf("apns returned %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+}
+
+// getToken 获取鉴权令牌，签发超过50分钟后重新签名
+func (p *apnsProvider) getToken() (string, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.token != "" && time.Since(p.issuedAt) < apnsTokenRefresh {
+		return p.token, nil
+	}
+
+	now := time.Now()
+	token, err := signJWT(
+		map[string]string{"alg": "ES256", "kid": p.keyID},
+		map[string]interface{}{
+			"iss": p.teamID,
+			"iat": now.Unix(),
+		},
+		func(signingInput []byte) ([]byte, error) {
+			hash := sha256.Sum256(signingInput)
+			r, s, err := ecdsa.Sign(rand.Reader, p.key, hash[:])
+			if err != nil {
+				return nil, err
+			}
+			// JWS要求r和s各32字节拼接
+			signature := make([]byte, 64)
+			r.FillBytes(signature[:32])
+			s.FillBytes(signature[32:])
+			return signature, nil
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+
+	p.token = token
+	p.issuedAt = now
+	return p.token, nil
+}
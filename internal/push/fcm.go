@@ -0,0 +1,209 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FCM默认地址和授权范围
+const (
+	defaultFCMEndpoint = "https://fcm.googleapis.com"
+	defaultFCMTokenURI = "https://oauth2.googleapis.com/token"
+	fcmScope           = "https://www.googleapis.com/auth/firebase.messaging"
+)
+
+// FCMConfig Firebase Cloud Messaging配置，使用HTTP v1接口
+type FCMConfig struct {
+	CredentialsFile string `yaml:"credentials_file"` // 服务账号JSON密钥文件，为空时不启用
+	Endpoint        string `yaml:"endpoint"`         // 未配置时为 https://fcm.googleapis.com
+}
+
+// fcmCredentials 服务账号密钥
+type fcmCredentials struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// fcmProvider 通过FCM HTTP v1接口推送，访问令牌由服务账号签名换取并在过期前复用
+type fcmProvider struct {
+	endpoint    string
+	projectID   string
+	clientEmail string
+	tokenURI    string
+	key         *rsa.PrivateKey
+	client      *http.Client
+
+	accessToken string
+	expiresAt   time.Time
+	mutex       sync.Mutex
+}
+
+// newFCMProvider 读取服务账号密钥
+func newFCMProvider(config *FCMConfig, timeout time.Duration) (*fcmProvider, error) {
+	data, err := os.ReadFile(config.CredentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fcm credentials: %v", err)
+	}
+
+	var credentials fcmCredentials
+	if err := json.Unmarshal(data, &credentials); err != nil {
+		return nil, fmt.Errorf("failed to parse fcm credentials: %v", err)
+	}
+	if credentials.ProjectID == "" || credentials.ClientEmail == "" {
+		return nil, fmt.Errorf("fcm credentials missing project_id or client_email")
+	}
+
+	block, _ := pem.Decode([]byte(credentials.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("fcm credentials missing private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse fcm private key: %v", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("fcm private key is not an RSA key")
+	}
+
+	endpoint := strings.TrimRight(config.Endpoint, "/")
+	if endpoint == "" {
+		endpoint = defaultFCMEndpoint
+	}
+	tokenURI := credentials.TokenURI
+	if tokenURI == "" {
+		tokenURI = defaultFCMTokenURI
+	}
+
+	return &fcmProvider{
+		endpoint:    endpoint,
+		projectID:   credentials.ProjectID,
+		clientEmail: credentials.ClientEmail,
+		tokenURI:    tokenURI,
+		key:         key,
+		client:      &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Send 推送到设备
+func (p *fcmProvider) Send(ctx context.Context, token string, msg *Message) error {
+	accessToken, err := p.getAccessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"message": map[string]interface{}{
+			"token": token,
+			"notification": map[string]string{
+				"title": msg.Title,
+				"body":  msg.Body,
+			},
+			"data": msg.Data,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal fcm message: %v", err)
+	}
+
+	url := fmt.Sprintf("%s/v1/projects/%s/messages:send", p.endpoint, p.projectID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send fcm message: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode == http.StatusNotFound || bytes.Contains(respBody, []byte("UNREGISTERED")) {
+		return ErrInvalidToken
+	}
+	return fmt.Errorf("fcm returned %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+}
+
+// getAccessToken 获取访问令牌，过期前一分钟重新换取
+func (p *fcmProvider) getAccessToken(ctx context.Context) (string, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.accessToken != "" && time.Now().Before(p.expiresAt.Add(-time.Minute)) {
+		return p.accessToken, nil
+	}
+
+	now := time.Now()
+	assertion, err := signJWT(
+		map[string]string{"alg": "RS256", "typ": "JWT"},
+		map[string]interface{}{
+			"iss":   p.clientEmail,
+			"scope": fcmScope,
+			"aud":   p.tokenURI,
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Hour).Unix(),
+		},
+		func(signingInput []byte) ([]byte, error) {
+			hash := sha256.Sum256(signingInput)
+			return rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, hash[:])
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.tokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get fcm access token: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("fcm token endpoint returned %d", resp.StatusCode)
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode fcm access token: %v", err)
+	}
+
+	p.accessToken = result.AccessToken
+	p.expiresAt = now.Add(time.Duration(result.ExpiresIn) * time.Second)
+	return p.accessToken, nil
+}
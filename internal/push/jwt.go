@@ -0,0 +1,26 @@
+package push
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// signJWT 编码JWT，sign对 header.claims 签名并返回签名字节
+func signJWT(header, claims interface{}, sign func(signingInput []byte) ([]byte, error)) (string, error) {
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal jwt header: %v", err)
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal jwt claims: %v", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+	signature, err := sign([]byte(signingInput))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign jwt: %v", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,323 @@
+package push
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/tuning"
+)
+
+// 推送平台
+const (
+	PlatformFCM  = "fcm"
+	PlatformAPNs = "apns"
+)
+
+// 推送类型，与玩家通知类型一致
+const (
+	KindMail          = "mail"
+	KindGift          = "gift"
+	KindFriendRequest = "friend_request"
+	KindTurnReminder  = "turn_reminder" // 异步对局轮到玩家操作
+)
+
+// 推送结果，按平台计入每日统计
+const (
+	ResultSent         = "sent"
+	ResultFailed       = "failed"
+	ResultInvalidToken = "invalid_token" // 令牌已失效并被删除
+	ResultRateLimited  = "rate_limited"
+	ResultQuietHours   = "quiet_hours"
+	ResultNoDevice     = "no_device"
+	ResultNoProvider   = "no_provider" // 设备平台未配置推送服务
+	ResultMuted        = "muted"       // 玩家关闭了该类型的推送
+)
+
+// 推送默认设置
+const (
+	defaultRateLimit = 10
+	defaultTimeout   = 10 * time.Second
+	metricsRetention = 7 * 24 * time.Hour
+)
+
+// ErrInvalidToken 推送服务判定设备令牌失效，令牌需要删除
+var ErrInvalidToken = errors.New("invalid device token")
+
+// defaultTexts 语言包缺少推送文案时使用的英文文案
+var defaultTexts = map[string][2]string{
+	KindMail:          {"New mail", "You have received a new mail."},
+	KindGift:          {"New gift", "You have received a gift. Open your mailbox to claim it."},
+	KindFriendRequest: {"Friend request", "Someone wants to be your friend."},
+	KindTurnReminder:  {"Your turn", "It's your turn to move."},
+}
+
+// Config 移动推送配置
+type Config struct {
+	Enabled    bool          `yaml:"enabled"`
+	RateLimit  int64         `yaml:"rate_limit"` // 每个玩家每小时最多推送次数，未配置时为10
+	QuietHours QuietHours    `yaml:"quiet_hours"`
+	Timeout    time.Duration `yaml:"timeout"` // 单次请求超时，未配置时为10秒
+	FCM        FCMConfig     `yaml:"fcm"`
+	APNs       APNsConfig    `yaml:"apns"`
+}
+
+// QuietHours 免打扰时段，按设备本地时间的小时计算，Start和End相同时不限制
+type QuietHours struct {
+	Start int `yaml:"start"`
+	End   int `yaml:"end"`
+}
+
+// Contains 本地时间是否在免打扰时段内，时段可以跨越午夜
+func (q QuietHours) Contains(local time.Time) bool {
+	if q.Start == q.End {
+		return false
+	}
+	hour := local.Hour()
+	if q.Start < q.End {
+		return hour >= q.Start && hour < q.End
+	}
+	return hour >= q.Start || hour < q.End
+}
+
+// Message 推送内容
+type Message struct {
+	Title string
+	Body  string
+	Data  map[string]string
+}
+
+// Provider 推送服务
+type Provider interface {
+	// Send 推送到设备，令牌失效时返回ErrInvalidToken
+	Send(ctx context.Context, token string, msg *Message) error
+}
+
+// Localizer 推送文案本地化，i18n.I18nManager实现了该接口
+type Localizer interface {
+	Translate(langCode, messageID string, templateData map[string]interface{}) string
+}
+
+// Mutes 玩家的通知偏好，settings.Service实现了该接口
+type Mutes interface {
+	Muted(userID uint64, channel string) (bool, error)
+}
+
+// Channel 推送类型对应的通知偏好频道
+func Channel(kind string) string {
+	return "push_" + kind
+}
+
+// Service 移动推送服务，按玩家登记的设备令牌发送本地化推送
+type Service struct {
+	config    Config
+	devices   *database.DeviceTokenRepository
+	redis     *database.RedisManager
+	localizer Localizer
+	mutes     Mutes
+	providers map[string]Provider
+}
+
+// NewService 创建移动推送服务，只启用配置了凭证的平台。mutes为nil时不检查玩家的通知偏好
+func NewService(config *Config, devices *database.DeviceTokenRepository, redis *database.RedisManager, localizer Localizer, mutes Mutes) (*Service, error) {
+	cfg := *config
+	if cfg.RateLimit <= 0 {
+		cfg.RateLimit = defaultRateLimit
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+
+	providers := make(map[string]Provider)
+	if cfg.FCM.CredentialsFile != "" {
+		provider, err := newFCMProvider(&cfg.FCM, cfg.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		providers[PlatformFCM] = provider
+	}
+	if cfg.APNs.KeyFile != "" {
+		provider, err := newAPNsProvider(&cfg.APNs, cfg.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		providers[PlatformAPNs] = provider
+	}
+
+	return &Service{
+		config:    cfg,
+		devices:   devices,
+		redis:     redis,
+		localizer: localizer,
+		mutes:     mutes,
+		providers: providers,
+	}, nil
+}
+
+// PlatformFor 客户端平台对应的推送平台
+func PlatformFor(clientPlatform string) (string, bool) {
+	switch strings.ToLower(clientPlatform) {
+	case "android", PlatformFCM:
+		return PlatformFCM, true
+	case "ios", "ipados", PlatformAPNs:
+		return PlatformAPNs, true
+	default:
+		return "", false
+	}
+}
+
+// Supports 是否为可推送的类型
+func Supports(kind string) bool {
+	_, exists := defaultTexts[kind]
+	return exists
+}
+
+// Notify 给玩家的所有设备发送推送，data同时作为文案模板参数和推送附带数据
+func (s *Service) Notify(userID uint64, kind string, data map[string]interface{}) {
+	if !Supports(kind) {
+		return
+	}
+
+	if s.mutes != nil {
+		if muted, err := s.mutes.Muted(userID, Channel(kind)); err != nil {
+			logger.Warn(fmt.Sprintf("Failed to check push preferences for user %d: %v", userID, err))
+		} else if muted {
+			s.record("", ResultMuted)
+			return
+		}
+	}
+
+	devices, err := s.devices.GetByUserID(userID)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to get device tokens for user %d: %v", userID, err))
+		return
+	}
+	if len(devices) == 0 {
+		s.record("", ResultNoDevice)
+		return
+	}
+
+	if limited, err := s.rateLimited(userID); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to check push rate for user %d: %v", userID, err))
+	} else if limited {
+		s.record("", ResultRateLimited)
+		return
+	}
+
+	for _, device := range devices {
+		s.record(device.Platform, s.send(device, kind, data))
+	}
+}
+
+// send 推送到单台设备，返回推送结果
+func (s *Service) send(device *database.DeviceToken, kind string, data map[string]interface{}) string {
+	provider, exists := s.providers[device.Platform]
+	if !exists {
+		return ResultNoProvider
+	}
+
+	local := time.Now().UTC().Add(time.Duration(device.UTCOffset) * time.Minute)
+	if s.config.QuietHours.Contains(local) {
+		return ResultQuietHours
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+	defer cancel()
+
+	err := provider.Send(ctx, device.Token, s.message(device.Language, kind, data))
+	switch {
+	case err == nil:
+		return ResultSent
+	case errors.Is(err, ErrInvalidToken):
+		if err := s.devices.RemoveToken(device.Token); err != nil {
+			logger.Error(fmt.Sprintf("Failed to remove invalid device token: %v", err))
+		}
+		return ResultInvalidToken
+	default:
+		logger.Warn(fmt.Sprintf("Failed to push %s to user %d via %s: %v", kind, device.UserID, device.Platform, err))
+		return ResultFailed
+	}
+}
+
+// message 按设备语言生成推送内容，语言包缺少文案时使用英文文案
+func (s *Service) message(language, kind string, data map[string]interface{}) *Message {
+	if language == "" {
+		language = "en"
+	}
+	texts := defaultTexts[kind]
+	msg := &Message{
+		Title: texts[0],
+		Body:  texts[1],
+		Data:  map[string]string{"kind": kind},
+	}
+
+	if s.localizer != nil {
+		titleID := fmt.Sprintf("push.%s.title", kind)
+		if title := s.localizer.Translate(language, titleID, data); title != titleID {
+			msg.Title = title
+		}
+		bodyID := fmt.Sprintf("push.%s.body", kind)
+		if body := s.localizer.Translate(language, bodyID, data); body != bodyID {
+			msg.Body = body
+		}
+	}
+
+	for key, value := range data {
+		msg.Data[key] = fmt.Sprintf("%v", value)
+	}
+	return msg
+}
+
+// rateLimited 按小时统计玩家的推送次数，超过上限时不再推送
+func (s *Service) rateLimited(userID uint64) (bool, error) {
+	key := fmt.Sprintf("push:rate:%d:%d", userID, time.Now().Unix()/3600)
+	count, err := s.redis.Incr(key)
+	if err != nil {
+		return false, err
+	}
+	if count == 1 {
+		s.redis.Expire(key, time.Hour)
+	}
+	return count > tuning.ScaleLimit(s.config.RateLimit), nil
+}
+
+// record 记录推送结果
+func (s *Service) record(platform, result string) {
+	if platform == "" {
+		platform = "all"
+	}
+	key := metricsKey(time.Now())
+	if _, err := s.redis.HIncrBy(key, platform+":"+result, 1); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to record push result: %v", err))
+		return
+	}
+	s.redis.Expire(key, metricsRetention)
+}
+
+// GetMetrics 获取某天全集群的推送结果统计，键为 平台:结果
+func GetMetrics(redis *database.RedisManager, day time.Time) (map[string]int64, error) {
+	values, err := redis.HGetAll(metricsKey(day))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get push metrics: %v", err)
+	}
+
+	metrics := make(map[string]int64, len(values))
+	for field, value := range values {
+		count, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		metrics[field] = count
+	}
+	return metrics, nil
+}
+
+// metricsKey 每日推送统计的键
+func metricsKey(day time.Time) string {
+	return "push:metrics:" + day.Format("20060102")
+}
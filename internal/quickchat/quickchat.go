@@ -0,0 +1,221 @@
+package quickchat
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 快捷消息的错误
+var (
+	ErrDisabled  = errors.New("quick chat is disabled")
+	ErrUnknown   = errors.New("unknown quick chat entry")
+	ErrCooldown  = errors.New("quick chat entry is cooling down")
+	ErrThrottled = errors.New("too many quick chat messages")
+	ErrMuted     = errors.New("quick chat is muted for spamming")
+)
+
+// 快捷消息的类型
+const (
+	KindPhrase = "phrase" // 预设短语，客户端按Key显示本地化文本
+	KindEmote  = "emote"  // 表情，客户端按Key播放表情资源
+)
+
+// 快捷消息默认设置
+const (
+	defaultBurst  = 5
+	defaultWindow = 10 * time.Second
+)
+
+// Entry 快捷消息配置表中的条目
+type Entry struct {
+	ID       int32         `yaml:"id" json:"id"`
+	Kind     string        `yaml:"kind" json:"kind"`
+	Key      string        `yaml:"key" json:"key"`
+	Cooldown time.Duration `yaml:"cooldown" json:"-"` // 同一玩家再次发送该条目的间隔，未配置时使用全局冷却
+}
+
+// MuteConfig 刷屏禁言设置，在窗口内连续被限流达到次数后禁言一段时间
+type MuteConfig struct {
+	After    int           `yaml:"after"` // 0为不自动禁言
+	Duration time.Duration `yaml:"duration"`
+}
+
+// Config 快捷消息设置
+type Config struct {
+	Enabled  bool          `yaml:"enabled"`
+	Cooldown time.Duration `yaml:"cooldown"` // 任意两条快捷消息之间的最短间隔
+	Burst    int           `yaml:"burst"`    // 窗口内最多发送的条数，未配置时为5
+	Window   time.Duration `yaml:"window"`   // 限流窗口，未配置时为10秒
+	Mute     MuteConfig    `yaml:"mute"`
+	Entries  []Entry       `yaml:"entries"`
+}
+
+// sender 发送者的限流状态
+type sender struct {
+	sent       []time.Time         // 窗口内的发送时间
+	lastSent   time.Time           // 最近一次发送的时间
+	lastEntry  map[int32]time.Time // 各条目最近一次发送的时间
+	throttled  int                 // 窗口内被限流的次数
+	mutedUntil time.Time
+}
+
+// Service 快捷消息校验，条目来自配置表，发送频率按玩家限流。
+// 限流和屏蔽状态保存在当前游戏节点的内存中
+type Service struct {
+	config  Config
+	entries map[int32]Entry
+	senders map[uint64]*sender
+	blocked map[uint64]map[uint64]bool // 接收者屏蔽的发送者
+	mutex   sync.Mutex
+}
+
+// NewService 创建快捷消息服务，配置表中的条目ID不能重复
+func NewService(config *Config) (*Service, error) {
+	cfg := *config
+	if cfg.Burst <= 0 {
+		cfg.Burst = defaultBurst
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = defaultWindow
+	}
+
+	entries := make(map[int32]Entry, len(cfg.Entries))
+	for _, entry := range cfg.Entries {
+		if _, exists := entries[entry.ID]; exists {
+			return nil, fmt.Errorf("duplicate quick chat entry %d", entry.ID)
+		}
+		if entry.Kind != KindPhrase && entry.Kind != KindEmote {
+			return nil, fmt.Errorf("quick chat entry %d has invalid kind %q", entry.ID, entry.Kind)
+		}
+		if entry.Key == "" {
+			return nil, fmt.Errorf("quick chat entry %d has no key", entry.ID)
+		}
+		entries[entry.ID] = entry
+	}
+
+	return &Service{
+		config:  cfg,
+		entries: entries,
+		senders: make(map[uint64]*sender),
+		blocked: make(map[uint64]map[uint64]bool),
+	}, nil
+}
+
+// Entries 配置表中的所有条目
+func (s *Service) Entries() []Entry {
+	return append([]Entry(nil), s.config.Entries...)
+}
+
+// Send 校验玩家发送的条目，通过时记录发送时间
+func (s *Service) Send(userID uint64, entryID int32, now time.Time) (Entry, error) {
+	if !s.config.Enabled {
+		return Entry{}, ErrDisabled
+	}
+	entry, ok := s.entries[entryID]
+	if !ok {
+		return Entry{}, ErrUnknown
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	state := s.senders[userID]
+	if state == nil {
+		state = &sender{lastEntry: make(map[int32]time.Time)}
+		s.senders[userID] = state
+	}
+	if now.Before(state.mutedUntil) {
+		return Entry{}, ErrMuted
+	}
+
+	// 只保留窗口内的发送记录
+	sent := state.sent[:0]
+	for _, at := range state.sent {
+		if now.Sub(at) < s.config.Window {
+			sent = append(sent, at)
+		}
+	}
+	state.sent = sent
+	if len(sent) == 0 {
+		state.throttled = 0
+	}
+
+	cooldown := entry.Cooldown
+	if cooldown <= 0 {
+		cooldown = s.config.Cooldown
+	}
+	if last, ok := state.lastEntry[entryID]; ok && now.Sub(last) < cooldown {
+		return Entry{}, ErrCooldown
+	}
+	if now.Sub(state.lastSent) < s.config.Cooldown || len(sent) >= s.config.Burst {
+		state.throttled++
+		if s.config.Mute.After > 0 && state.throttled >= s.config.Mute.After {
+			state.mutedUntil = now.Add(s.config.Mute.Duration)
+			state.throttled = 0
+			return Entry{}, ErrMuted
+		}
+		return Entry{}, ErrThrottled
+	}
+
+	state.sent = append(state.sent, now)
+	state.lastSent = now
+	state.lastEntry[entryID] = now
+	return entry, nil
+}
+
+// Block 接收者屏蔽或取消屏蔽某个发送者的快捷消息
+func (s *Service) Block(viewerID, senderID uint64, blocked bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !blocked {
+		delete(s.blocked[viewerID], senderID)
+		if len(s.blocked[viewerID]) == 0 {
+			delete(s.blocked, viewerID)
+		}
+		return
+	}
+	if s.blocked[viewerID] == nil {
+		s.blocked[viewerID] = make(map[uint64]bool)
+	}
+	s.blocked[viewerID][senderID] = true
+}
+
+// Blocked 接收者是否屏蔽了发送者
+func (s *Service) Blocked(viewerID, senderID uint64) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.blocked[viewerID][senderID]
+}
+
+// Forget 玩家离开房间时清除其屏蔽列表
+func (s *Service) Forget(userID uint64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.blocked, userID)
+}
+
+// Cleanup 清理已过窗口且未被禁言的发送者状态
+func (s *Service) Cleanup(now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	idle := s.config.Window
+	if s.config.Cooldown > idle {
+		idle = s.config.Cooldown
+	}
+	for _, entry := range s.config.Entries {
+		if entry.Cooldown > idle {
+			idle = entry.Cooldown
+		}
+	}
+	for userID, state := range s.senders {
+		if now.Sub(state.lastSent) > idle && now.After(state.mutedUntil) {
+			delete(s.senders, userID)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package quota
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// 需要配额的操作
+const (
+	OpCreateRoom    = "create_room"
+	OpFriendRequest = "friend_request"
+	OpReport        = "report"
+	OpSearch        = "search"
+)
+
+// Unlimited 等级配置中表示不限制的上限
+const Unlimited = -1
+
+// counterRetention 每日计数的保留时间，跨过重置时间后仍可查询前一天的用量
+const counterRetention = 48 * time.Hour
+
+// ErrExceeded 当天的配额已用完，具体的用量和重置时间见ExceededError
+var ErrExceeded = errors.New("daily quota exceeded")
+
+// Config 按玩家每天的操作配额，每天UTC零点重置
+type Config struct {
+	Limits map[string]int64 `yaml:"limits"` // 操作的每日上限，未配置或为0的操作不限制
+	Tiers  []TierLimits     `yaml:"tiers"`  // 按VIP等级覆盖上限
+}
+
+// TierLimits 某一VIP等级及以上的上限，玩家使用不高于其等级的最高一档中配置了该操作的上限
+type TierLimits struct {
+	Tier   int32            `yaml:"tier"`
+	Limits map[string]int64 `yaml:"limits"` // 为-1时不限制
+}
+
+// Validate 检查配额配置
+func (c *Config) Validate() error {
+	for op, limit := range c.Limits {
+		if limit < 0 {
+			return fmt.Errorf("quota limit of %s must not be negative", op)
+		}
+	}
+	tiers := make(map[int32]bool, len(c.Tiers))
+	for _, tier := range c.Tiers {
+		if tier.Tier <= 0 {
+			return fmt.Errorf("quota tier must be positive: %d", tier.Tier)
+		}
+		if tiers[tier.Tier] {
+			return fmt.Errorf("duplicate quota tier: %d", tier.Tier)
+		}
+		tiers[tier.Tier] = true
+		for op, limit := range tier.Limits {
+			if limit < Unlimited {
+				return fmt.Errorf("quota limit of %s for tier %d must be -1 or above", op, tier.Tier)
+			}
+		}
+	}
+	return nil
+}
+
+// TierResolver 获取玩家的VIP等级，未设置时所有玩家为0级
+type TierResolver func(userID uint64) int32
+
+// Usage 玩家某一操作当天的用量
+type Usage struct {
+	Operation string    `json:"operation"`
+	Used      int64     `json:"used"`
+	Limit     int64     `json:"limit"` // 0为不限制
+	ResetAt   time.Time `json:"reset_at"`
+}
+
+// ExceededError 配额用完的错误，包含用量和重置时间
+type ExceededError struct {
+	Usage
+}
+
+// Error 实现error接口
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("daily quota of %s exceeded (%d/%d), resets at %s",
+		e.Operation, e.Used, e.Limit, e.ResetAt.Format(time.RFC3339))
+}
+
+// Is 匹配ErrExceeded
+func (e *ExceededError) Is(target error) bool {
+	return target == ErrExceeded
+}
+
+// Service 每日配额，计数保存在Redis中，所有节点共用。每个玩家每种操作每天有一桶次数，
+// 执行前取出一次，操作失败时退回，次日零点重新装满。Redis不可用时放行，不影响正常操作
+type Service struct {
+	config Config
+	tiers  []TierLimits // 按等级从高到低
+	redis  *database.RedisManager
+	tier   TierResolver
+}
+
+// NewService 创建配额服务
+func NewService(config *Config, redis *database.RedisManager) *Service {
+	tiers := make([]TierLimits, len(config.Tiers))
+	copy(tiers, config.Tiers)
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].Tier > tiers[j].Tier })
+	return &Service{
+		config: *config,
+		tiers:  tiers,
+		redis:  redis,
+		tier:   func(uint64) int32 { return 0 },
+	}
+}
+
+// SetTierResolver 设置获取玩家VIP等级的方法
+func (s *Service) SetTierResolver(resolver TierResolver) {
+	s.tier = resolver
+}
+
+// Limit 玩家某一操作的每日上限，0为不限制
+func (s *Service) Limit(userID uint64, op string) int64 {
+	tier := s.tier(userID)
+	for _, t := range s.tiers {
+		if t.Tier > tier {
+			continue
+		}
+		if limit, exists := t.Limits[op]; exists {
+			if limit == Unlimited {
+				return 0
+			}
+			return limit
+		}
+	}
+	return s.config.Limits[op]
+}
+
+// Consume 取出一次配额，当天已用完时返回ExceededError且不计数
+func (s *Service) Consume(userID uint64, op string) (*Usage, error) {
+	now := time.Now()
+	usage := &Usage{Operation: op, Limit: s.Limit(userID, op), ResetAt: resetAt(now)}
+	if usage.Limit <= 0 {
+		return usage, nil
+	}
+
+	key := counterKey(now, userID)
+	used, err := s.redis.HIncrBy(key, op, 1)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Failed to count %s quota of user %d, allowing: %v", op, userID, err))
+		return usage, nil
+	}
+	if used == 1 {
+		s.redis.Expire(key, counterRetention)
+	}
+	if used > usage.Limit {
+		s.redis.HIncrBy(key, op, -1)
+		usage.Used = usage.Limit
+		return nil, &ExceededError{Usage: *usage}
+	}
+	usage.Used = used
+	return usage, nil
+}
+
+// Refund 退回当天取出的一次配额，用于取出配额后操作失败的情况
+func (s *Service) Refund(userID uint64, op string) {
+	if s.Limit(userID, op) <= 0 {
+		return
+	}
+	if _, err := s.redis.HIncrBy(counterKey(time.Now(), userID), op, -1); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to refund %s quota of user %d: %v", op, userID, err))
+	}
+}
+
+// Usages 玩家当天各操作的用量，只包含有上限的操作，按操作名排序
+func (s *Service) Usages(userID uint64) ([]*Usage, error) {
+	now := time.Now()
+	counts, err := s.redis.HGetAll(counterKey(now, userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quota usage: %v", err)
+	}
+
+	ops := make(map[string]bool, len(s.config.Limits))
+	for op := range s.config.Limits {
+		ops[op] = true
+	}
+	for _, tier := range s.tiers {
+		for op := range tier.Limits {
+			ops[op] = true
+		}
+	}
+
+	var usages []*Usage
+	for op := range ops {
+		limit := s.Limit(userID, op)
+		if limit <= 0 {
+			continue
+		}
+		used, _ := strconv.ParseInt(counts[op], 10, 64)
+		usages = append(usages, &Usage{Operation: op, Used: min(used, limit), Limit: limit, ResetAt: resetAt(now)})
+	}
+	sort.Slice(usages, func(i, j int) bool { return usages[i].Operation < usages[j].Operation })
+	return usages, nil
+}
+
+// counterKey 玩家当天的计数
+func counterKey(now time.Time, userID uint64) string {
+	return fmt.Sprintf("quota:%s:%d", now.UTC().Format("20060102"), userID)
+}
+
+// resetAt 下一次重置的时间，即次日UTC零点
+func resetAt(now time.Time) time.Time {
+	return now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+}
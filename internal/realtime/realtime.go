@@ -0,0 +1,255 @@
+package realtime
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Redis中的计数
+const (
+	onlineNodesKey  = "realtime:online_nodes" // 上报过在线人数的网关节点
+	onlineKeyPrefix = "realtime:online:"      // 各网关节点的在线人数
+	lobbyKey        = "realtime:lobby"        // 房间和游戏计数的哈希
+
+	fieldRooms         = "rooms_open"
+	fieldGames         = "games_in_progress"
+	fieldPlayersPrefix = "players:" // 按游戏类型统计房间中的玩家数
+)
+
+// 默认设置
+const defaultInterval = 30 * time.Second
+
+// Config 实时计数的配置
+type Config struct {
+	Interval time.Duration `yaml:"interval"` // 网关上报在线人数和大厅校准房间计数的间隔，未配置时为30秒
+}
+
+// Validate 检查实时计数配置
+func (c *Config) Validate() error {
+	if c.Interval < 0 {
+		return fmt.Errorf("interval must not be negative")
+	}
+	return nil
+}
+
+// Stats 集群的实时计数
+type Stats struct {
+	Online          int64           `json:"online"`            // 在线玩家数
+	RoomsOpen       int64           `json:"rooms_open"`        // 未结束的房间数，包括等待中和游戏中
+	GamesInProgress int64           `json:"games_in_progress"` // 游戏中的房间数
+	Players         map[int32]int64 `json:"players"`           // 按游戏类型统计房间中的玩家数
+	UpdatedAt       time.Time       `json:"updated_at"`
+}
+
+// Delta 房间事件引起的计数变化
+type Delta struct {
+	GameType int32
+	Rooms    int64
+	Games    int64
+	Players  int64
+}
+
+// Counters 集群范围的实时计数。在线人数由各网关在登录登出时增减并定期上报实际连接数，
+// 房间和游戏计数由大厅和游戏节点在房间事件时增减，并由大厅定期按数据库校准。
+// 增减计数失败只记录日志，偏差在下次上报或校准时修正
+type Counters struct {
+	redis    *database.RedisManager
+	interval time.Duration
+
+	online  *prometheus.Desc
+	rooms   *prometheus.Desc
+	games   *prometheus.Desc
+	players *prometheus.Desc
+}
+
+// NewCounters 创建实时计数
+func NewCounters(config *Config, redis *database.RedisManager) *Counters {
+	interval := config.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	return &Counters{
+		redis:    redis,
+		interval: interval,
+		online:   prometheus.NewDesc("lufy_online_users", "Number of users online across the cluster", nil, nil),
+		rooms:    prometheus.NewDesc("lufy_rooms_open", "Number of waiting and in-game rooms across the cluster", nil, nil),
+		games:    prometheus.NewDesc("lufy_games_in_progress", "Number of rooms with a game in progress across the cluster", nil, nil),
+		players:  prometheus.NewDesc("lufy_room_players", "Number of users in open rooms by game type", []string{"game_type"}, nil),
+	}
+}
+
+// Interval 上报和校准的间隔
+func (c *Counters) Interval() time.Duration {
+	return c.interval
+}
+
+// onlineKey 网关节点在线人数的键
+func onlineKey(nodeID string) string {
+	return onlineKeyPrefix + nodeID
+}
+
+// UserOnline 网关节点有玩家登录
+func (c *Counters) UserOnline(nodeID string) {
+	c.adjustOnline(nodeID, 1)
+}
+
+// UserOffline 网关节点有玩家登出
+func (c *Counters) UserOffline(nodeID string) {
+	c.adjustOnline(nodeID, -1)
+}
+
+// adjustOnline 增减网关节点的在线人数，键过期后重新创建时同样设置过期时间
+func (c *Counters) adjustOnline(nodeID string, delta int64) {
+	key := onlineKey(nodeID)
+	if _, err := c.redis.IncrBy(key, delta); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to update online count of %s: %v", nodeID, err))
+		return
+	}
+	if err := c.redis.Expire(key, 3*c.interval); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to refresh online count of %s: %v", nodeID, err))
+	}
+}
+
+// ReportOnline 网关节点上报实际的在线人数，覆盖登录登出时增减的计数。
+// 节点停止上报后计数在三个间隔后过期，不再计入集群在线人数
+func (c *Counters) ReportOnline(nodeID string, count int64) error {
+	if err := c.redis.Set(onlineKey(nodeID), count, 3*c.interval); err != nil {
+		return fmt.Errorf("failed to report online count: %v", err)
+	}
+	if err := c.redis.SAdd(onlineNodesKey, nodeID); err != nil {
+		return fmt.Errorf("failed to register online node: %v", err)
+	}
+	return nil
+}
+
+// Adjust 按房间事件增减房间、游戏和玩家计数
+func (c *Counters) Adjust(delta Delta) {
+	fields := []struct {
+		name  string
+		value int64
+	}{
+		{fieldRooms, delta.Rooms},
+		{fieldGames, delta.Games},
+		{fieldPlayersPrefix + strconv.Itoa(int(delta.GameType)), delta.Players},
+	}
+	for _, field := range fields {
+		if field.value == 0 {
+			continue
+		}
+		if _, err := c.redis.HIncrBy(lobbyKey, field.name, field.value); err != nil {
+			logger.Warn(fmt.Sprintf("Failed to update realtime counter %s: %v", field.name, err))
+		}
+	}
+}
+
+// Reconcile 按数据库统计的房间覆盖房间、游戏和玩家计数，统计期间发生的增减可能丢失，下次校准时修正
+func (c *Counters) Reconcile(occupancy []database.RoomOccupancy) error {
+	values := map[string]int64{fieldRooms: 0, fieldGames: 0}
+	for _, o := range occupancy {
+		values[fieldRooms] += o.Rooms
+		values[fieldGames] += o.Games
+		values[fieldPlayersPrefix+strconv.Itoa(int(o.GameType))] = o.Players
+	}
+
+	current, err := c.redis.HGetAll(lobbyKey)
+	if err != nil {
+		return fmt.Errorf("failed to load realtime counters: %v", err)
+	}
+	var stale []string
+	for field := range current {
+		if _, exists := values[field]; !exists {
+			stale = append(stale, field)
+		}
+	}
+	if len(stale) > 0 {
+		if err := c.redis.HDel(lobbyKey, stale...); err != nil {
+			return fmt.Errorf("failed to remove realtime counters: %v", err)
+		}
+	}
+	for field, value := range values {
+		if err := c.redis.HSet(lobbyKey, field, value); err != nil {
+			return fmt.Errorf("failed to reconcile realtime counter %s: %v", field, err)
+		}
+	}
+	return nil
+}
+
+// Stats 读取集群的实时计数，已停止上报的网关节点从节点列表中移除。
+// 计数在校准前可能因事件丢失而短暂为负，返回时按0处理
+func (c *Counters) Stats() (*Stats, error) {
+	stats := &Stats{Players: make(map[int32]int64), UpdatedAt: time.Now()}
+
+	nodes, err := c.redis.SMembers(onlineNodesKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load online nodes: %v", err)
+	}
+	for _, nodeID := range nodes {
+		value, err := c.redis.GetString(onlineKey(nodeID))
+		if err != nil {
+			if exists, err := c.redis.Exists(onlineKey(nodeID)); err == nil && !exists {
+				c.redis.SRem(onlineNodesKey, nodeID)
+			}
+			continue
+		}
+		count, _ := strconv.ParseInt(value, 10, 64)
+		stats.Online += nonNegative(count)
+	}
+
+	fields, err := c.redis.HGetAll(lobbyKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load realtime counters: %v", err)
+	}
+	for field, value := range fields {
+		count, _ := strconv.ParseInt(value, 10, 64)
+		count = nonNegative(count)
+		switch {
+		case field == fieldRooms:
+			stats.RoomsOpen = count
+		case field == fieldGames:
+			stats.GamesInProgress = count
+		case strings.HasPrefix(field, fieldPlayersPrefix):
+			gameType, err := strconv.Atoi(strings.TrimPrefix(field, fieldPlayersPrefix))
+			if err == nil && count > 0 {
+				stats.Players[int32(gameType)] = count
+			}
+		}
+	}
+	return stats, nil
+}
+
+// nonNegative 负数按0处理
+func nonNegative(n int64) int64 {
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// Describe 实现prometheus.Collector接口
+func (c *Counters) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.online
+	ch <- c.rooms
+	ch <- c.games
+	ch <- c.players
+}
+
+// Collect 实现prometheus.Collector接口，读取失败时不导出
+func (c *Counters) Collect(ch chan<- prometheus.Metric) {
+	stats, err := c.Stats()
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Failed to collect realtime stats: %v", err))
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.online, prometheus.GaugeValue, float64(stats.Online))
+	ch <- prometheus.MustNewConstMetric(c.rooms, prometheus.GaugeValue, float64(stats.RoomsOpen))
+	ch <- prometheus.MustNewConstMetric(c.games, prometheus.GaugeValue, float64(stats.GamesInProgress))
+	for gameType, count := range stats.Players {
+		ch <- prometheus.MustNewConstMetric(c.players, prometheus.GaugeValue, float64(count), strconv.Itoa(int(gameType)))
+	}
+}
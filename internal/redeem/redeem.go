@@ -0,0 +1,404 @@
+package redeem
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// 兑换失败原因
+var (
+	ErrInvalid   = errors.New("invalid redeem code")
+	ErrInactive  = errors.New("redeem code is not active")
+	ErrUsedUp    = errors.New("redeem code has been used up")
+	ErrRedeemed  = errors.New("redeem limit of this batch reached")
+	ErrThrottled = errors.New("too many failed redeem attempts")
+	ErrBatch     = errors.New("invalid redeem batch")
+	ErrNotFound  = errors.New("redeem batch not found")
+)
+
+// 兑换码默认设置
+const (
+	defaultLength   = 10
+	defaultExpire   = 7 * 24 * time.Hour
+	defaultFailures = 5
+	defaultWindow   = time.Hour
+	defaultMaxCodes = 10000
+	minCodeLength   = 4
+	maxCodeLength   = 32
+	generateRetries = 5
+)
+
+// alphabet 生成兑换码使用的字符，去掉了容易混淆的0、O、1、I
+const alphabet = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+
+// Config 兑换码设置
+type Config struct {
+	Length   int           `yaml:"length"`    // 生成的兑换码长度，未配置时为10
+	Expire   time.Duration `yaml:"expire"`    // 奖励邮件的有效期，未配置时为7天
+	Failures int64         `yaml:"failures"`  // 窗口内允许输错的次数，超过后在窗口结束前不能兑换，未配置时为5
+	Window   time.Duration `yaml:"window"`    // 输错次数的统计窗口，未配置时为1小时
+	MaxCodes int64         `yaml:"max_codes"` // 每个批次最多生成的兑换码数量，未配置时为10000
+}
+
+// withDefaults 补齐未配置的设置
+func (c Config) withDefaults() Config {
+	if c.Length < minCodeLength || c.Length > maxCodeLength {
+		c.Length = defaultLength
+	}
+	if c.Expire <= 0 {
+		c.Expire = defaultExpire
+	}
+	if c.Failures <= 0 {
+		c.Failures = defaultFailures
+	}
+	if c.Window <= 0 {
+		c.Window = defaultWindow
+	}
+	if c.MaxCodes <= 0 {
+		c.MaxCodes = defaultMaxCodes
+	}
+	return c
+}
+
+// Store 兑换码存储，database.RedeemRepository实现了该接口
+type Store interface {
+	CreateBatch(batch *database.RedeemBatch) error
+	InsertCodes(codes []*database.RedeemCode) ([]*database.RedeemCode, error)
+	GetBatch(batchID string) (*database.RedeemBatch, error)
+	ListBatches(limit int64) ([]*database.RedeemBatch, error)
+	SetBatchDisabled(batchID string, disabled bool) (bool, error)
+	GetCode(code string) (*database.RedeemCode, error)
+	UseCode(code string) (bool, error)
+	ReleaseCode(code string) error
+	UseQuota(batchID string, userID uint64, perUser int64) (bool, error)
+	ReleaseQuota(batchID string, userID uint64) error
+	RecordRedemption(redemption *database.Redemption) error
+	Stats(batchID string, now time.Time) (*database.RedeemStats, error)
+}
+
+// NotifyFunc 通知玩家收到奖励邮件
+type NotifyFunc func(userID, mailID uint64, title string)
+
+// Result 兑换结果，奖励通过邮件发放
+type Result struct {
+	BatchID string                `json:"batch_id"`
+	MailID  uint64                `json:"mail_id"`
+	Title   string                `json:"title"`
+	Rewards []database.MailReward `json:"rewards"`
+}
+
+// BatchStats 批次和兑换统计
+type BatchStats struct {
+	*database.RedeemBatch
+	Stats *database.RedeemStats `json:"stats"`
+}
+
+// Service 兑换码服务。兑换时先占用玩家在批次中的次数和兑换码的次数，再发放奖励邮件，
+// 任何一步失败时退回已占用的次数。输错次数保存在Redis中，Redis不可用时不限制
+type Service struct {
+	config  Config
+	store   Store
+	mails   database.MailStore
+	redis   *database.RedisManager
+	mailIDs database.MailIDGenerator
+	notify  NotifyFunc
+}
+
+// NewService 创建兑换码服务，notify为空时不通知玩家
+func NewService(config *Config, store Store, mails database.MailStore, redis *database.RedisManager,
+	mailIDs database.MailIDGenerator, notify NotifyFunc) *Service {
+	return &Service{
+		config:  config.withDefaults(),
+		store:   store,
+		mails:   mails,
+		redis:   redis,
+		mailIDs: mailIDs,
+		notify:  notify,
+	}
+}
+
+// CreateBatch 检查并创建批次，code不为空时批次只有这一个兑换码，多个玩家共用，
+// 否则按batch.Codes生成一次性兑换码。返回生成的兑换码
+func (s *Service) CreateBatch(batch *database.RedeemBatch, code string) ([]string, error) {
+	batch.Name = strings.TrimSpace(batch.Name)
+	if batch.Name == "" || batch.Title == "" {
+		return nil, fmt.Errorf("%w: name and title are required", ErrBatch)
+	}
+	if len(batch.Rewards) == 0 {
+		return nil, fmt.Errorf("%w: rewards are required", ErrBatch)
+	}
+	for _, reward := range batch.Rewards {
+		if reward.Count <= 0 {
+			return nil, fmt.Errorf("%w: reward count must be positive", ErrBatch)
+		}
+	}
+	if batch.MaxUses < 0 || batch.PerUser < 0 {
+		return nil, fmt.Errorf("%w: limits must not be negative", ErrBatch)
+	}
+	if batch.PerUser == 0 {
+		batch.PerUser = 1
+	}
+	if !batch.ExpireAt.IsZero() && (!batch.ExpireAt.After(time.Now()) || !batch.ExpireAt.After(batch.StartAt)) {
+		return nil, fmt.Errorf("%w: expire time must be after now and the start time", ErrBatch)
+	}
+
+	var codes []*database.RedeemCode
+	if code != "" {
+		code = normalize(code)
+		if len(code) < minCodeLength || len(code) > maxCodeLength || strings.IndexFunc(code, invalidRune) >= 0 {
+			return nil, fmt.Errorf("%w: code must be %d-%d letters or digits", ErrBatch, minCodeLength, maxCodeLength)
+		}
+		if batch.Codes > 1 {
+			return nil, fmt.Errorf("%w: a batch with a custom code has only one code", ErrBatch)
+		}
+		batch.Codes = 1
+		codes = []*database.RedeemCode{{Code: code, MaxUses: batch.MaxUses}}
+	} else {
+		if batch.Codes <= 0 || batch.Codes > s.config.MaxCodes {
+			return nil, fmt.Errorf("%w: code count must be between 1 and %d", ErrBatch, s.config.MaxCodes)
+		}
+		if batch.MaxUses == 0 {
+			batch.MaxUses = 1
+		}
+	}
+
+	batch.BatchID = "redeem_" + primitive.NewObjectID().Hex()
+	inserted, err := s.insertCodes(batch, codes)
+	if err != nil {
+		return nil, err
+	}
+	if code != "" && len(inserted) == 0 {
+		return nil, fmt.Errorf("%w: code %s already exists", ErrBatch, code)
+	}
+	batch.Codes = int64(len(inserted))
+	// 兑换码先于批次写入，批次创建失败时留下的兑换码因找不到批次而无法兑换
+	if err := s.store.CreateBatch(batch); err != nil {
+		return nil, err
+	}
+
+	result := make([]string, len(inserted))
+	for i, c := range inserted {
+		result[i] = c.Code
+	}
+	return result, nil
+}
+
+// insertCodes 写入指定的兑换码，未指定时生成batch.Codes个，与已有兑换码重复的重新生成
+func (s *Service) insertCodes(batch *database.RedeemBatch, codes []*database.RedeemCode) ([]*database.RedeemCode, error) {
+	for _, code := range codes {
+		code.BatchID = batch.BatchID
+	}
+	if len(codes) > 0 {
+		return s.store.InsertCodes(codes)
+	}
+
+	var inserted []*database.RedeemCode
+	for attempt := 0; attempt < generateRetries && int64(len(inserted)) < batch.Codes; attempt++ {
+		need := batch.Codes - int64(len(inserted))
+		generated := make([]*database.RedeemCode, 0, need)
+		seen := make(map[string]bool, need)
+		for int64(len(generated)) < need {
+			code, err := generate(s.config.Length)
+			if err != nil {
+				return nil, err
+			}
+			if seen[code] {
+				continue
+			}
+			seen[code] = true
+			generated = append(generated, &database.RedeemCode{Code: code, BatchID: batch.BatchID, MaxUses: batch.MaxUses})
+		}
+		added, err := s.store.InsertCodes(generated)
+		if err != nil {
+			return nil, err
+		}
+		inserted = append(inserted, added...)
+	}
+	if int64(len(inserted)) < batch.Codes {
+		logger.Warn(fmt.Sprintf("Generated %d of %d redeem codes for batch %s", len(inserted), batch.Codes, batch.BatchID))
+	}
+	return inserted, nil
+}
+
+// Redeem 兑换，奖励通过邮件发放。输入不存在的兑换码计为一次输错
+func (s *Service) Redeem(userID uint64, input string) (*Result, error) {
+	failureKey := fmt.Sprintf("redeem:failures:%d", userID)
+	if value, err := s.redis.GetString(failureKey); err == nil {
+		if failures, _ := strconv.ParseInt(value, 10, 64); failures >= s.config.Failures {
+			return nil, ErrThrottled
+		}
+	}
+
+	code := normalize(input)
+	redeemCode, err := s.store.GetCode(code)
+	if err != nil {
+		return nil, err
+	}
+	var batch *database.RedeemBatch
+	if redeemCode != nil {
+		if batch, err = s.store.GetBatch(redeemCode.BatchID); err != nil {
+			return nil, err
+		}
+	}
+	if batch == nil {
+		s.recordFailure(failureKey, userID)
+		return nil, ErrInvalid
+	}
+
+	now := time.Now()
+	if batch.Disabled || now.Before(batch.StartAt) || (!batch.ExpireAt.IsZero() && !now.Before(batch.ExpireAt)) {
+		return nil, ErrInactive
+	}
+
+	ok, err := s.store.UseQuota(batch.BatchID, userID, batch.PerUser)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrRedeemed
+	}
+	if ok, err = s.store.UseCode(code); err != nil || !ok {
+		s.release(batch.BatchID, userID, "")
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrUsedUp
+	}
+
+	mailID, err := s.mailIDs()
+	if err == nil {
+		err = s.mails.CreateMail(&database.Mail{
+			MailID:   mailID,
+			ToUserID: userID,
+			Title:    batch.Title,
+			Content:  batch.Content,
+			Rewards:  batch.Rewards,
+			BatchID:  batch.BatchID,
+			ExpireAt: now.Add(s.config.Expire),
+		})
+	}
+	if err != nil {
+		s.release(batch.BatchID, userID, code)
+		return nil, fmt.Errorf("failed to send redeem mail: %v", err)
+	}
+
+	if err := s.store.RecordRedemption(&database.Redemption{
+		BatchID: batch.BatchID,
+		Code:    code,
+		UserID:  userID,
+		MailID:  mailID,
+	}); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to record redemption of %s by user %d: %v", code, userID, err))
+	}
+	if s.notify != nil {
+		s.notify(userID, mailID, batch.Title)
+	}
+
+	return &Result{BatchID: batch.BatchID, MailID: mailID, Title: batch.Title, Rewards: batch.Rewards}, nil
+}
+
+// release 退回兑换失败时占用的次数，code为空时只退回玩家的次数
+func (s *Service) release(batchID string, userID uint64, code string) {
+	if err := s.store.ReleaseQuota(batchID, userID); err != nil {
+		logger.Error(fmt.Sprintf("Failed to release redeem quota of user %d in %s: %v", userID, batchID, err))
+	}
+	if code == "" {
+		return
+	}
+	if err := s.store.ReleaseCode(code); err != nil {
+		logger.Error(fmt.Sprintf("Failed to release redeem code %s: %v", code, err))
+	}
+}
+
+// recordFailure 记录一次输错，达到上限时记录日志便于排查撞码
+func (s *Service) recordFailure(key string, userID uint64) {
+	failures, err := s.redis.Incr(key)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Failed to count redeem failures of user %d: %v", userID, err))
+		return
+	}
+	if failures == 1 {
+		s.redis.Expire(key, s.config.Window)
+	}
+	if failures == s.config.Failures {
+		logger.Warn(fmt.Sprintf("User %d entered %d invalid redeem codes, blocked for up to %v",
+			userID, failures, s.config.Window))
+	}
+}
+
+// Stats 批次和兑换统计，batchID为空时返回最近创建的批次
+func (s *Service) Stats(batchID string, limit int64) ([]*BatchStats, error) {
+	var batches []*database.RedeemBatch
+	if batchID != "" {
+		batch, err := s.store.GetBatch(batchID)
+		if err != nil {
+			return nil, err
+		}
+		if batch == nil {
+			return nil, ErrNotFound
+		}
+		batches = []*database.RedeemBatch{batch}
+	} else {
+		var err error
+		if batches, err = s.store.ListBatches(limit); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	result := make([]*BatchStats, 0, len(batches))
+	for _, batch := range batches {
+		stats, err := s.store.Stats(batch.BatchID, now)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &BatchStats{RedeemBatch: batch, Stats: stats})
+	}
+	return result, nil
+}
+
+// SetDisabled 停用或重新启用批次，停用后批次中的兑换码不能兑换
+func (s *Service) SetDisabled(batchID string, disabled bool) error {
+	found, err := s.store.SetBatchDisabled(batchID, disabled)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// normalize 兑换码不区分大小写，忽略空格和连字符
+func normalize(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// generate 随机生成兑换码
+func generate(length int) (string, error) {
+	var b strings.Builder
+	size := big.NewInt(int64(len(alphabet)))
+	for i := 0; i < length; i++ {
+		n, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate redeem code: %v", err)
+		}
+		b.WriteByte(alphabet[n.Int64()])
+	}
+	return b.String(), nil
+}
+
+// invalidRune 自定义兑换码只能包含字母和数字
+func invalidRune(r rune) bool {
+	return (r < 'A' || r > 'Z') && (r < '0' || r > '9')
+}
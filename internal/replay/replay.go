@@ -0,0 +1,252 @@
+package replay
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/phuhao00/lufy/internal/gameplay"
+)
+
+// maxDiffs 分歧点记录的字段差异上限
+const maxDiffs = 50
+
+// Reducer 按房间事件重建玩法状态，玩法模块实现该接口后可以回放调试
+// 重建只依赖事件内容，相同的事件序列必须得到相同的状态；不影响玩法状态的事件(如聊天)直接忽略
+type Reducer interface {
+	// NewReplayState 创建房间开始时的状态
+	NewReplayState(config *gameplay.RoomConfig) interface{}
+	// ApplyEvent 把一个事件应用到状态上
+	ApplyEvent(state interface{}, event *gameplay.GameEvent) error
+}
+
+// Frame 应用某个事件后的房间状态
+type Frame struct {
+	Index       int                 `json:"index"` // 事件在序列中的位置，-1为房间开始时的状态
+	Total       int                 `json:"total"` // 序列中的事件数
+	Event       *gameplay.GameEvent `json:"event,omitempty"`
+	State       interface{}         `json:"state"`
+	Fingerprint string              `json:"fingerprint"`
+}
+
+// FieldDiff 两个状态中不一致的字段，Path为JSON路径
+type FieldDiff struct {
+	Path string      `json:"path"`
+	A    interface{} `json:"a"`
+	B    interface{} `json:"b"`
+}
+
+// 分歧原因
+const (
+	DivergedEvent  = "event"  // 同一位置的事件不同
+	DivergedState  = "state"  // 事件相同但应用后的状态不同，通常是玩法逻辑依赖了事件以外的输入
+	DivergedLength = "length" // 一个序列是另一个的前缀
+)
+
+// Divergence 两次运行的第一个分歧点
+type Divergence struct {
+	Index  int                 `json:"index"`
+	Reason string              `json:"reason"`
+	EventA *gameplay.GameEvent `json:"event_a,omitempty"`
+	EventB *gameplay.GameEvent `json:"event_b,omitempty"`
+	Before interface{}         `json:"before"` // 分歧前两次运行一致的状态
+	StateA interface{}         `json:"state_a"`
+	StateB interface{}         `json:"state_b"`
+	Diffs  []FieldDiff         `json:"diffs,omitempty"`
+}
+
+// StateAt 从房间开始依次应用事件，返回应用第index个事件后的状态，index为-1时返回开始时的状态
+func StateAt(reducer Reducer, config *gameplay.RoomConfig, events []gameplay.GameEvent, index int) (*Frame, error) {
+	if index < -1 || index >= len(events) {
+		return nil, fmt.Errorf("event index %d out of range [-1, %d)", index, len(events))
+	}
+
+	state := reducer.NewReplayState(config)
+	for i := 0; i <= index; i++ {
+		if err := reducer.ApplyEvent(state, &events[i]); err != nil {
+			return nil, fmt.Errorf("failed to apply event %d (%s): %v", i, events[i].Type, err)
+		}
+	}
+
+	frame := &Frame{Index: index, Total: len(events), State: state}
+	if index >= 0 {
+		frame.Event = &events[index]
+	}
+	fingerprint, err := Fingerprint(state)
+	if err != nil {
+		return nil, err
+	}
+	frame.Fingerprint = fingerprint
+	return frame, nil
+}
+
+// Compare 同时回放两次运行的事件，返回第一个分歧点，完全一致时返回nil
+// 事件按类型、玩家和数据比较，时间戳不参与比较
+func Compare(reducer Reducer, config *gameplay.RoomConfig, a, b []gameplay.GameEvent) (*Divergence, error) {
+	stateA := reducer.NewReplayState(config)
+	stateB := reducer.NewReplayState(config)
+
+	for i := 0; i < len(a) || i < len(b); i++ {
+		before, err := normalize(stateA)
+		if err != nil {
+			return nil, err
+		}
+
+		if i >= len(a) || i >= len(b) {
+			divergence := &Divergence{Index: i, Reason: DivergedLength, Before: before, StateA: before, StateB: before}
+			if i < len(a) {
+				divergence.EventA = &a[i]
+			} else {
+				divergence.EventB = &b[i]
+			}
+			return divergence, nil
+		}
+
+		sameEvent, err := sameEvent(&a[i], &b[i])
+		if err != nil {
+			return nil, err
+		}
+		if !sameEvent {
+			return &Divergence{
+				Index:  i,
+				Reason: DivergedEvent,
+				EventA: &a[i],
+				EventB: &b[i],
+				Before: before,
+				StateA: before,
+				StateB: before,
+			}, nil
+		}
+
+		if err := reducer.ApplyEvent(stateA, &a[i]); err != nil {
+			return nil, fmt.Errorf("failed to apply event %d (%s) of run a: %v", i, a[i].Type, err)
+		}
+		if err := reducer.ApplyEvent(stateB, &b[i]); err != nil {
+			return nil, fmt.Errorf("failed to apply event %d (%s) of run b: %v", i, b[i].Type, err)
+		}
+
+		afterA, err := normalize(stateA)
+		if err != nil {
+			return nil, err
+		}
+		afterB, err := normalize(stateB)
+		if err != nil {
+			return nil, err
+		}
+		if diffs := Diff(afterA, afterB); len(diffs) > 0 {
+			return &Divergence{
+				Index:  i,
+				Reason: DivergedState,
+				EventA: &a[i],
+				EventB: &b[i],
+				Before: before,
+				StateA: afterA,
+				StateB: afterB,
+				Diffs:  diffs,
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+// Fingerprint 状态的摘要
+func Fingerprint(state interface{}) (string, error) {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode room state: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// Diff 比较两个按JSON结构表示的状态，返回不一致的字段，最多maxDiffs项
+func Diff(a, b interface{}) []FieldDiff {
+	var diffs []FieldDiff
+	diffValue("$", a, b, &diffs)
+	return diffs
+}
+
+// diffValue 递归比较JSON值
+func diffValue(path string, a, b interface{}, diffs *[]FieldDiff) {
+	if len(*diffs) >= maxDiffs {
+		return
+	}
+
+	switch va := a.(type) {
+	case map[string]interface{}:
+		vb, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make(map[string]bool, len(va)+len(vb))
+		for key := range va {
+			keys[key] = true
+		}
+		for key := range vb {
+			keys[key] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for key := range keys {
+			sorted = append(sorted, key)
+		}
+		sort.Strings(sorted)
+		for _, key := range sorted {
+			diffValue(path+"."+key, va[key], vb[key], diffs)
+		}
+		return
+
+	case []interface{}:
+		vb, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+		for i := 0; i < len(va) || i < len(vb); i++ {
+			var ea, eb interface{}
+			if i < len(va) {
+				ea = va[i]
+			}
+			if i < len(vb) {
+				eb = vb[i]
+			}
+			diffValue(path+"["+strconv.Itoa(i)+"]", ea, eb, diffs)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*diffs = append(*diffs, FieldDiff{Path: path, A: a, B: b})
+	}
+}
+
+// sameEvent 事件的类型、玩家和数据是否相同
+func sameEvent(a, b *gameplay.GameEvent) (bool, error) {
+	if a.Type != b.Type || a.PlayerID != b.PlayerID {
+		return false, nil
+	}
+	dataA, err := normalize(a.Data)
+	if err != nil {
+		return false, err
+	}
+	dataB, err := normalize(b.Data)
+	if err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(dataA, dataB), nil
+}
+
+// normalize 转换为JSON结构，数字统一为float64，便于比较
+func normalize(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode replay value: %v", err)
+	}
+	var result interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode replay value: %v", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,145 @@
+package rollup
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+)
+
+// DateLayout 周报日期的格式
+const DateLayout = "2006-01-02"
+
+// week 一周
+const week = 7 * day
+
+// Report 容量规划周报，时间按UTC，一周从周一开始
+type Report struct {
+	WeekStart        string            `json:"week_start"`
+	Days             []DaySummary      `json:"days"`
+	PeakCCU          int64             `json:"peak_ccu"`
+	PeakCCUAt        time.Time         `json:"peak_ccu_at"` // 峰值在线所在的小时
+	Games            int64             `json:"games"`
+	PeakGamesPerHour int64             `json:"peak_games_per_hour"`
+	PeakGamesAt      time.Time         `json:"peak_games_at"` // 结束游戏最多的小时
+	GameTypes        []GameTypeSummary `json:"game_types"`
+	PreviousPeakCCU  int64             `json:"previous_peak_ccu"`
+	PreviousGames    int64             `json:"previous_games"`
+	CCUGrowth        float64           `json:"ccu_growth"`         // 峰值在线相比上周的增长率，上周没有数据时为0
+	GamesGrowth      float64           `json:"games_growth"`       // 游戏数相比上周的增长率，上周没有数据时为0
+	ProjectedPeakCCU int64             `json:"projected_peak_ccu"` // 按本周的增长率推算的下周峰值在线
+}
+
+// DaySummary 一天的汇总
+type DaySummary struct {
+	Date        string  `json:"date"`
+	PeakCCU     int64   `json:"peak_ccu"`
+	Games       int64   `json:"games"`
+	AvgDuration float64 `json:"avg_duration"` // 平均游戏时长（秒）
+}
+
+// GameTypeSummary 一种游戏类型一周的汇总
+type GameTypeSummary struct {
+	GameType     int32   `json:"game_type"`
+	Games        int64   `json:"games"`
+	GamesPerHour float64 `json:"games_per_hour"`
+	AvgDuration  float64 `json:"avg_duration"` // 平均游戏时长（秒）
+}
+
+// ParseWeek 解析周报的开始日期，必须是周一；为空时为now之前最近一个完整的周
+func ParseWeek(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		today := startOfDay(now)
+		monday := today.Add(-time.Duration((int(today.Weekday())+6)%7) * day)
+		return monday.Add(-week), nil
+	}
+
+	start, err := time.Parse(DateLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid week %q, expected %s", value, DateLayout)
+	}
+	if start.Weekday() != time.Monday {
+		return time.Time{}, fmt.Errorf("week %s does not start on monday", value)
+	}
+	return start, nil
+}
+
+// Report 生成从start开始一周的容量规划周报，并与上一周比较
+func (s *Service) Report(start time.Time) (*Report, error) {
+	start = startOfDay(start)
+	end := start.Add(week)
+
+	days, err := s.store.GetRollups(database.RollupDay, start.Add(-week), end)
+	if err != nil {
+		return nil, err
+	}
+	hours, err := s.store.GetRollups(database.RollupHour, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{WeekStart: start.Format(DateLayout), Days: []DaySummary{}}
+	var current []*database.StatsRollup
+	for _, rollup := range days {
+		if rollup.Start.Before(start) {
+			report.PreviousPeakCCU = max(report.PreviousPeakCCU, rollup.PeakCCU)
+			report.PreviousGames += rollup.Games
+			continue
+		}
+		current = append(current, rollup)
+		report.Days = append(report.Days, DaySummary{
+			Date:        rollup.Start.UTC().Format(DateLayout),
+			PeakCCU:     rollup.PeakCCU,
+			Games:       rollup.Games,
+			AvgDuration: average(rollup.GameTypes),
+		})
+		report.PeakCCU = max(report.PeakCCU, rollup.PeakCCU)
+		report.Games += rollup.Games
+	}
+
+	// 峰值所在的小时以小时汇总为准，小时汇总已过期时只有日峰值
+	var peakCCU int64
+	for _, rollup := range hours {
+		if rollup.PeakCCU > peakCCU {
+			peakCCU, report.PeakCCUAt = rollup.PeakCCU, rollup.Start.UTC()
+		}
+		if rollup.Games > report.PeakGamesPerHour {
+			report.PeakGamesPerHour, report.PeakGamesAt = rollup.Games, rollup.Start.UTC()
+		}
+	}
+
+	for _, gameType := range mergeGameTypes(current) {
+		report.GameTypes = append(report.GameTypes, GameTypeSummary{
+			GameType:     gameType.GameType,
+			Games:        gameType.Games,
+			GamesPerHour: float64(gameType.Games) / week.Hours(),
+			AvgDuration:  average([]database.GameTypeRollup{gameType}),
+		})
+	}
+
+	report.CCUGrowth = growth(report.PreviousPeakCCU, report.PeakCCU)
+	report.GamesGrowth = growth(report.PreviousGames, report.Games)
+	report.ProjectedPeakCCU = int64(float64(report.PeakCCU) * (1 + report.CCUGrowth))
+	return report, nil
+}
+
+// average 游戏的平均时长（秒），没有游戏时为0
+func average(gameTypes []database.GameTypeRollup) float64 {
+	var games, duration int64
+	for _, gameType := range gameTypes {
+		games += gameType.Games
+		duration += gameType.Duration
+	}
+	if games == 0 {
+		return 0
+	}
+	return float64(duration) / float64(games)
+}
+
+// growth 相比上一周期的增长率，上一周期为0时返回0
+func growth(previous, current int64) float64 {
+	if previous == 0 {
+		return 0
+	}
+	return float64(current-previous) / float64(previous)
+}
@@ -0,0 +1,160 @@
+package rollup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// 默认设置
+const (
+	defaultInterval  = 5 * time.Minute
+	defaultRetention = 90 * 24 * time.Hour
+)
+
+// day 一天，汇总和周报的时间都按UTC
+const day = 24 * time.Hour
+
+// Config 运营指标汇总的配置
+type Config struct {
+	Interval  time.Duration `yaml:"interval"`  // 采样在线人数和更新汇总的间隔，未配置时为5分钟
+	Retention time.Duration `yaml:"retention"` // 小时汇总的保留时间，未配置时为90天，日汇总不过期
+}
+
+// Validate 检查汇总配置
+func (c *Config) Validate() error {
+	if c.Interval < 0 || c.Retention < 0 {
+		return fmt.Errorf("interval and retention must not be negative")
+	}
+	if c.Retention > 0 && c.Retention < 14*day {
+		return fmt.Errorf("retention must be at least 14 days to cover weekly reports")
+	}
+	return nil
+}
+
+// Store 汇总存储，database.StatsRollupRepository实现了该接口
+type Store interface {
+	RecordPeak(period string, start time.Time, ccu int64, expiresAt *time.Time) error
+	SetGames(period string, start time.Time, gameTypes []database.GameTypeRollup, expiresAt *time.Time) error
+	GetRollups(period string, since, until time.Time) ([]*database.StatsRollup, error)
+}
+
+// GameStore 游戏记录存储，database.GameRecordRepository实现了该接口
+type GameStore interface {
+	CountEndedByType(since, until time.Time) ([]database.GameTypeRollup, error)
+}
+
+// OnlineFunc 获取集群当前的在线人数
+type OnlineFunc func() (int64, error)
+
+// Service 运营指标汇总。定期采样在线人数记录每小时和每天的峰值，
+// 并统计结束的游戏数和各游戏类型的平均时长，汇总保存在MongoDB中供容量规划周报使用
+type Service struct {
+	interval  time.Duration
+	retention time.Duration
+	store     Store
+	games     GameStore
+	online    OnlineFunc
+}
+
+// NewService 创建运营指标汇总
+func NewService(config *Config, store Store, games GameStore, online OnlineFunc) *Service {
+	s := &Service{
+		interval:  config.Interval,
+		retention: config.Retention,
+		store:     store,
+		games:     games,
+		online:    online,
+	}
+	if s.interval <= 0 {
+		s.interval = defaultInterval
+	}
+	if s.retention <= 0 {
+		s.retention = defaultRetention
+	}
+	return s
+}
+
+// Interval 汇总任务的执行间隔
+func (s *Service) Interval() time.Duration {
+	return s.interval
+}
+
+// Run 采样在线人数并更新当前和上一小时、所在日的汇总，由集群定时任务调用。
+// 上一小时在跨过整点后重新统计一次，补上整点前最后一个间隔内结束的游戏
+func (s *Service) Run(ctx context.Context) error {
+	now := time.Now().UTC()
+	hour := now.Truncate(time.Hour)
+	expiresAt := hour.Add(s.retention)
+
+	if ccu, err := s.online(); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to sample online users for rollup: %v", err))
+	} else {
+		if err := s.store.RecordPeak(database.RollupHour, hour, ccu, &expiresAt); err != nil {
+			return err
+		}
+		if err := s.store.RecordPeak(database.RollupDay, startOfDay(now), ccu, nil); err != nil {
+			return err
+		}
+	}
+
+	days := map[time.Time]bool{}
+	for _, start := range []time.Time{hour.Add(-time.Hour), hour} {
+		gameTypes, err := s.games.CountEndedByType(start, start.Add(time.Hour))
+		if err != nil {
+			return err
+		}
+		expiresAt := start.Add(s.retention)
+		if err := s.store.SetGames(database.RollupHour, start, gameTypes, &expiresAt); err != nil {
+			return err
+		}
+		days[startOfDay(start)] = true
+	}
+	for start := range days {
+		if err := s.rollupDay(start); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rollupDay 按当天的小时汇总更新日汇总的游戏统计
+func (s *Service) rollupDay(start time.Time) error {
+	hours, err := s.store.GetRollups(database.RollupHour, start, start.Add(day))
+	if err != nil {
+		return err
+	}
+	return s.store.SetGames(database.RollupDay, start, mergeGameTypes(hours), nil)
+}
+
+// mergeGameTypes 合并多个汇总的游戏类型统计，按游戏类型排序
+func mergeGameTypes(rollups []*database.StatsRollup) []database.GameTypeRollup {
+	merged := make(map[int32]*database.GameTypeRollup)
+	for _, rollup := range rollups {
+		for _, gameType := range rollup.GameTypes {
+			m := merged[gameType.GameType]
+			if m == nil {
+				m = &database.GameTypeRollup{GameType: gameType.GameType}
+				merged[gameType.GameType] = m
+			}
+			m.Games += gameType.Games
+			m.Duration += gameType.Duration
+		}
+	}
+
+	result := make([]database.GameTypeRollup, 0, len(merged))
+	for _, m := range merged {
+		result = append(result, *m)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].GameType < result[j].GameType })
+	return result
+}
+
+// startOfDay UTC零点
+func startOfDay(t time.Time) time.Time {
+	return t.UTC().Truncate(day)
+}
@@ -0,0 +1,115 @@
+package rpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// MetadataAuthorization 运维令牌的元数据键，值为"Bearer <token>"
+const MetadataAuthorization = "authorization"
+
+// AdminToken 运维令牌，调用方凭令牌以对应的操作员身份调用RPC
+type AdminToken struct {
+	Name       string `yaml:"name"`        // 操作员名称，写入日志
+	Token      string `yaml:"token"`       // 令牌内容
+	OperatorID uint64 `yaml:"operator_id"` // 操作员用户ID，记录在GM操作日志中
+}
+
+// AdminAuthConfig 运维令牌认证配置
+type AdminAuthConfig struct {
+	Tokens   []AdminToken `yaml:"tokens"`
+	Services []string     `yaml:"services"` // 接受令牌的服务，为空时只有GMService和CenterService
+}
+
+// AdminAuth 运维令牌认证拦截器，配置可在运行时更新
+//
+// 请求元数据中带有令牌时校验令牌，通过后把操作员ID写入ctx的user_id，
+// 令牌无效时直接拒绝；不带令牌的请求原样放行，由具体方法自行校验身份
+type AdminAuth struct {
+	tokens   []AdminToken
+	services map[string]bool
+	mutex    sync.RWMutex
+}
+
+// NewAdminAuth 创建运维令牌认证拦截器
+func NewAdminAuth(config *AdminAuthConfig) *AdminAuth {
+	aa := &AdminAuth{}
+	aa.UpdateConfig(config)
+	return aa
+}
+
+// UpdateConfig 更新配置
+func (aa *AdminAuth) UpdateConfig(config *AdminAuthConfig) {
+	services := config.Services
+	if len(services) == 0 {
+		services = []string{"GMService", "CenterService"}
+	}
+
+	serviceSet := make(map[string]bool, len(services))
+	for _, service := range services {
+		serviceSet[service] = true
+	}
+
+	tokens := make([]AdminToken, 0, len(config.Tokens))
+	for _, token := range config.Tokens {
+		if token.Token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+
+	aa.mutex.Lock()
+	aa.tokens = tokens
+	aa.services = serviceSet
+	aa.mutex.Unlock()
+}
+
+// Interceptor 获取RPC拦截器
+func (aa *AdminAuth) Interceptor() Interceptor {
+	return func(ctx context.Context, call *CallInfo, next Handler) (proto.Message, error) {
+		header, exists := call.Metadata[MetadataAuthorization]
+		if !exists {
+			return next(ctx, call)
+		}
+
+		token, err := aa.authenticate(call.Service, header)
+		if err != nil {
+			logger.Warn(fmt.Sprintf("Rejected admin call %s.%s: %v", call.Service, call.Method, err))
+			return nil, err
+		}
+
+		logger.Info(fmt.Sprintf("Admin call %s.%s by %s", call.Service, call.Method, token.Name))
+
+		// GM等服务从ctx的user_id读取调用方身份
+		return next(context.WithValue(ctx, "user_id", token.OperatorID), call)
+	}
+}
+
+// authenticate 校验令牌，返回匹配的令牌配置
+func (aa *AdminAuth) authenticate(service, header string) (AdminToken, error) {
+	value := strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+	if value == "" {
+		return AdminToken{}, fmt.Errorf("empty admin token")
+	}
+
+	aa.mutex.RLock()
+	defer aa.mutex.RUnlock()
+
+	if !aa.services[service] {
+		return AdminToken{}, fmt.Errorf("admin token not accepted by %s", service)
+	}
+
+	for _, token := range aa.tokens {
+		if subtle.ConstantTimeCompare([]byte(token.Token), []byte(value)) == 1 {
+			return token, nil
+		}
+	}
+
+	return AdminToken{}, fmt.Errorf("invalid admin token")
+}
@@ -0,0 +1,123 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/phuhao00/lufy/internal/logger"
+	pb "github.com/phuhao00/lufy/pkg/proto"
+)
+
+func init() {
+	logger.InitGlobalLogger(&logger.LogConfig{
+		Level:  "error",
+		Format: "console",
+		Output: "stderr",
+	})
+}
+
+// benchService 基准测试用的RPC服务
+type benchService struct{}
+
+// serviceDesc 服务描述
+func (bs *benchService) serviceDesc() *ServiceDesc {
+	return &ServiceDesc{
+		Name: "BenchService",
+		Methods: map[string]*MethodDesc{
+			"Echo": NewMethod(bs.Echo),
+		},
+	}
+}
+
+// Echo 原样返回请求数据
+func (bs *benchService) Echo(ctx context.Context, req *pb.BaseRequest) (*pb.BaseResponse, error) {
+	return &pb.BaseResponse{
+		Header: req.Header,
+		Code:   0,
+		Msg:    "success",
+		Data:   req.Data,
+	}, nil
+}
+
+// newBenchRequest 创建典型大小的请求
+func newBenchRequest() *pb.BaseRequest {
+	return &pb.BaseRequest{
+		Header: &pb.MessageHeader{
+			MsgId:     2001,
+			Seq:       42,
+			UserId:    10001,
+			SessionId: "bench-session",
+		},
+		Data: make([]byte, 256),
+	}
+}
+
+// BenchmarkMarshal 请求和响应的序列化与反序列化
+func BenchmarkMarshal(b *testing.B) {
+	req := newBenchRequest()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		data, err := proto.Marshal(req)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var decoded pb.BaseRequest
+		if err := proto.Unmarshal(data, &decoded); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// runDispatch 执行分发并校验响应
+func runDispatch(b *testing.B, server *RPCServer) {
+	args, err := proto.Marshal(newBenchRequest())
+	if err != nil {
+		b.Fatal(err)
+	}
+	frame, err := json.Marshal(&RPCRequest{
+		ID:      1,
+		Service: "BenchService",
+		Method:  "Echo",
+		Args:    args,
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var resp RPCResponse
+		if err := json.Unmarshal(server.Dispatch(frame), &resp); err != nil {
+			b.Fatal(err)
+		}
+		if resp.Error != "" {
+			b.Fatal(resp.Error)
+		}
+	}
+}
+
+// BenchmarkDispatch 请求解析、方法查找、反射调用和响应序列化
+func BenchmarkDispatch(b *testing.B) {
+	server := NewRPCServer("127.0.0.1", 0)
+	if err := server.RegisterServiceDesc((&benchService{}).serviceDesc()); err != nil {
+		b.Fatal(err)
+	}
+	runDispatch(b, server)
+}
+
+// BenchmarkDispatchWithInterceptors 带线上默认拦截器（请求日志关闭、panic恢复）的分发
+func BenchmarkDispatchWithInterceptors(b *testing.B) {
+	server := NewRPCServer("127.0.0.1", 0)
+	if err := server.RegisterServiceDesc((&benchService{}).serviceDesc()); err != nil {
+		b.Fatal(err)
+	}
+	server.Use(NewRequestLogger(&LoggingConfig{}).Interceptor())
+	server.Use(RecoveryInterceptor(nil))
+	runDispatch(b, server)
+}
@@ -0,0 +1,48 @@
+package rpc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// 帧格式常量
+const (
+	frameLengthSize = 4           // 长度头字节数
+	MaxFrameSize    = 1024 * 1024 // 请求和响应帧的最大长度1MB
+)
+
+// ReadFrame 读取一个长度前缀的帧，长度头和内容都读满才返回，长度为0或超过MaxFrameSize时返回错误
+func ReadFrame(r io.Reader) ([]byte, error) {
+	var header [frameLengthSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[:])
+	if length == 0 || length > MaxFrameSize {
+		return nil, fmt.Errorf("invalid rpc frame length: %d", length)
+	}
+
+	frame := make([]byte, length)
+	if _, err := io.ReadFull(r, frame); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return frame, nil
+}
+
+// WriteFrame 添加长度头后一次写出，避免长度头和内容被其他写入隔开
+func WriteFrame(w io.Writer, payload []byte) error {
+	if len(payload) == 0 || len(payload) > MaxFrameSize {
+		return fmt.Errorf("invalid rpc frame length: %d", len(payload))
+	}
+
+	message := make([]byte, frameLengthSize+len(payload))
+	binary.BigEndian.PutUint32(message[:frameLengthSize], uint32(len(payload)))
+	copy(message[frameLengthSize:], payload)
+	_, err := w.Write(message)
+	return err
+}
@@ -0,0 +1,82 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	pb "github.com/phuhao00/lufy/pkg/proto"
+)
+
+// newFuzzServer 创建模糊测试用的服务端，只注册一个回显方法
+func newFuzzServer() *RPCServer {
+	server := NewRPCServer("127.0.0.1", 0)
+	server.RegisterServiceDesc(&ServiceDesc{
+		Name: "FuzzService",
+		Methods: map[string]*MethodDesc{
+			"Echo": NewMethod(func(ctx context.Context, req *pb.BaseRequest) (*pb.BaseRequest, error) {
+				return req, nil
+			}),
+		},
+	})
+	return server
+}
+
+// fuzzSeed 把请求编码为一个帧，作为模糊测试的初始输入
+func fuzzSeed(t testing.TB, request *RPCRequest) []byte {
+	data, err := json.Marshal(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteFrame(&buf, data); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// FuzzFrame 把输入当作连接上的字节流，依次读取请求帧并分发，
+// 检查读出的帧长度合法、响应能重新成帧并解析
+func FuzzFrame(f *testing.F) {
+	args, err := proto.Marshal(&pb.BaseRequest{Header: &pb.MessageHeader{MsgId: 1001, Seq: 1}})
+	if err != nil {
+		f.Fatal(err)
+	}
+	echo := fuzzSeed(f, &RPCRequest{ID: 1, Service: "FuzzService", Method: "Echo", Args: args, Timeout: 1000})
+	f.Add(echo)
+	f.Add(append(echo, fuzzSeed(f, &RPCRequest{ID: 2, Service: "FuzzService", Method: "Missing"})...))
+	f.Add(fuzzSeed(f, &RPCRequest{ID: 3, Service: "FuzzService", Method: "Echo", Args: []byte{0xff, 0x01}}))
+	f.Add([]byte{0, 0, 0, 0})
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff, '{'})
+
+	server := newFuzzServer()
+	f.Fuzz(func(t *testing.T, data []byte) {
+		reader := bytes.NewReader(data)
+		for {
+			frame, err := ReadFrame(reader)
+			if err != nil {
+				return
+			}
+			if len(frame) == 0 || len(frame) > MaxFrameSize {
+				t.Fatalf("ReadFrame returned frame of %d bytes", len(frame))
+			}
+
+			response := server.Dispatch(frame)
+			var buf bytes.Buffer
+			if err := WriteFrame(&buf, response); err != nil {
+				t.Fatalf("failed to frame response: %v", err)
+			}
+			decoded, err := ReadFrame(&buf)
+			if err != nil || !bytes.Equal(decoded, response) {
+				t.Fatalf("response frame does not round trip: %v", err)
+			}
+			var resp RPCResponse
+			if err := json.Unmarshal(decoded, &resp); err != nil {
+				t.Fatalf("malformed response: %v", err)
+			}
+		}
+	})
+}
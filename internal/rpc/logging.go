@@ -0,0 +1,185 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/phuhao00/lufy/internal/logger"
+	pb "github.com/phuhao00/lufy/pkg/proto"
+)
+
+// redactedValue 脱敏后的字段值
+const redactedValue = "***"
+
+// defaultRedactFields 默认脱敏字段，匹配时忽略大小写
+var defaultRedactFields = []string{"password", "token", "session_id", "email", "phone", "secret"}
+
+// LoggingConfig 请求日志配置
+type LoggingConfig struct {
+	Enabled         bool                            `yaml:"enabled"`
+	SampleRate      float64                         `yaml:"sample_rate"`       // 采样率，0-1
+	AlwaysLogErrors bool                            `yaml:"always_log_errors"` // 失败的调用不受采样限制
+	MaxPayloadBytes int                             `yaml:"max_payload_bytes"` // 请求和响应内容的截断长度
+	RedactFields    []string                        `yaml:"redact_fields"`     // 追加到默认脱敏字段
+	Services        map[string]ServiceLoggingConfig `yaml:"services"`          // 按服务覆盖全局开关和采样率
+}
+
+// ServiceLoggingConfig 单个服务的请求日志配置
+type ServiceLoggingConfig struct {
+	Enabled    bool    `yaml:"enabled"`
+	SampleRate float64 `yaml:"sample_rate"`
+}
+
+// RequestLogger 请求日志拦截器，配置可在运行时更新
+type RequestLogger struct {
+	config       LoggingConfig
+	redactFields map[string]bool
+	mutex        sync.RWMutex
+}
+
+// NewRequestLogger 创建请求日志拦截器
+func NewRequestLogger(config *LoggingConfig) *RequestLogger {
+	rl := &RequestLogger{}
+	rl.UpdateConfig(config)
+	return rl
+}
+
+// UpdateConfig 更新配置
+func (rl *RequestLogger) UpdateConfig(config *LoggingConfig) {
+	cfg := *config
+	if cfg.MaxPayloadBytes <= 0 {
+		cfg.MaxPayloadBytes = 512
+	}
+
+	redactFields := make(map[string]bool)
+	for _, field := range append(append([]string{}, defaultRedactFields...), cfg.RedactFields...) {
+		redactFields[strings.ToLower(field)] = true
+	}
+
+	rl.mutex.Lock()
+	rl.config = cfg
+	rl.redactFields = redactFields
+	rl.mutex.Unlock()
+
+	logger.Info(fmt.Sprintf("RPC request logging updated: enabled=%v, sample_rate=%.2f, services=%d",
+		cfg.Enabled, cfg.SampleRate, len(cfg.Services)))
+}
+
+// Interceptor 获取RPC拦截器
+func (rl *RequestLogger) Interceptor() Interceptor {
+	return func(ctx context.Context, call *CallInfo, next Handler) (proto.Message, error) {
+		enabled, sampleRate := rl.serviceConfig(call.Service)
+		if !enabled {
+			return next(ctx, call)
+		}
+
+		start := time.Now()
+		resp, err := next(ctx, call)
+		duration := time.Since(start)
+
+		code := int32(0)
+		if coded, ok := resp.(interface{ GetCode() int32 }); ok {
+			code = coded.GetCode()
+		}
+
+		failed := err != nil || code != 0
+		if !(failed && rl.alwaysLogErrors()) && rand.Float64() >= sampleRate {
+			return resp, err
+		}
+
+		msg := fmt.Sprintf("RPC %s.%s user=%d duration=%v code=%d req=%s resp=%s",
+			call.Service, call.Method, requestUserID(call.Request), duration, code,
+			rl.formatPayload(call.Request), rl.formatPayload(resp))
+		if err != nil {
+			logger.Warn(fmt.Sprintf("%s error=%v", msg, err))
+		} else {
+			logger.Info(msg)
+		}
+
+		return resp, err
+	}
+}
+
+// serviceConfig 获取服务的开关和采样率
+func (rl *RequestLogger) serviceConfig(service string) (bool, float64) {
+	rl.mutex.RLock()
+	defer rl.mutex.RUnlock()
+
+	if override, exists := rl.config.Services[service]; exists {
+		return override.Enabled, override.SampleRate
+	}
+	return rl.config.Enabled, rl.config.SampleRate
+}
+
+// alwaysLogErrors 失败的调用是否总是记录
+func (rl *RequestLogger) alwaysLogErrors() bool {
+	rl.mutex.RLock()
+	defer rl.mutex.RUnlock()
+	return rl.config.AlwaysLogErrors
+}
+
+// formatPayload 序列化消息内容，脱敏后截断
+func (rl *RequestLogger) formatPayload(msg proto.Message) string {
+	if msg == nil {
+		return "-"
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Sprintf("<%T>", msg)
+	}
+
+	var payload interface{}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return fmt.Sprintf("<%T>", msg)
+	}
+
+	rl.mutex.RLock()
+	payload = redact(payload, rl.redactFields)
+	maxBytes := rl.config.MaxPayloadBytes
+	rl.mutex.RUnlock()
+
+	data, err = json.Marshal(payload)
+	if err != nil {
+		return fmt.Sprintf("<%T>", msg)
+	}
+
+	if len(data) > maxBytes {
+		return fmt.Sprintf("%s...(%d bytes)", data[:maxBytes], len(data))
+	}
+	return string(data)
+}
+
+// redact 递归替换敏感字段的值
+func redact(value interface{}, fields map[string]bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if fields[strings.ToLower(key)] {
+				v[key] = redactedValue
+				continue
+			}
+			v[key] = redact(item, fields)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redact(item, fields)
+		}
+	}
+	return value
+}
+
+// requestUserID 从请求头中获取用户ID
+func requestUserID(msg proto.Message) uint64 {
+	if req, ok := msg.(interface{ GetHeader() *pb.MessageHeader }); ok {
+		return req.GetHeader().GetUserId()
+	}
+	return 0
+}
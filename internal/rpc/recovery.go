@@ -0,0 +1,34 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// PanicHandler panic处理函数
+type PanicHandler func(call *CallInfo, value interface{}, stack []byte)
+
+// RecoveryInterceptor 恢复RPC方法中的panic并转换为错误响应，避免连接协程退出
+func RecoveryInterceptor(handler PanicHandler) Interceptor {
+	return func(ctx context.Context, call *CallInfo, next Handler) (resp proto.Message, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				stack := debug.Stack()
+				if handler != nil {
+					handler(call, r, stack)
+				} else {
+					logger.Error(fmt.Sprintf("Recovered panic in RPC %s.%s: %v\n%s", call.Service, call.Method, r, stack))
+				}
+				resp = nil
+				err = fmt.Errorf("internal error in %s.%s", call.Service, call.Method)
+			}
+		}()
+
+		return next(ctx, call)
+	}
+}
@@ -4,13 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"reflect"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
 
-	"google.golang.org/protobuf/proto"
+	"github.com/golang/protobuf/proto"
 
 	"github.com/phuhao00/lufy/internal/logger"
 )
@@ -28,6 +30,7 @@ type RPCRequest struct {
 	Method   string            `json:"method"`
 	Args     []byte            `json:"args"`
 	Timeout  int64             `json:"timeout"`
+	Metadata map[string]string `json:"metadata,omitempty"` // 调用方附带的元数据，如运维令牌
 	Callback chan *RPCResponse `json:"-"`
 }
 
@@ -43,55 +46,123 @@ type RPCServer struct {
 	address   string
 	port      int
 	listener  net.Listener
-	services  map[string]RPCService
-	methods   map[string]reflect.Value
+	services  map[string]*ServiceDesc
+	methods   map[string]*MethodDesc
 	running   bool
 	ctx       context.Context
 	cancel    context.CancelFunc
 	wg        sync.WaitGroup
 	mutex     sync.RWMutex
 	connCount int64
+
+	interceptors []Interceptor
+
+	transport TransportConfig
+	conns     map[net.Conn]struct{} // 活跃连接，停止时统一关闭
+	connMutex sync.Mutex
+	reaped    int64 // 因超时或空闲被回收的连接数
+}
+
+// CallInfo RPC调用信息
+type CallInfo struct {
+	Service  string
+	Method   string
+	Request  proto.Message
+	Metadata map[string]string
 }
 
+// Handler RPC方法处理函数
+type Handler func(ctx context.Context, call *CallInfo) (proto.Message, error)
+
+// Interceptor RPC拦截器，需调用next继续执行
+type Interceptor func(ctx context.Context, call *CallInfo, next Handler) (proto.Message, error)
+
 // NewRPCServer 创建RPC服务器
 func NewRPCServer(address string, port int) *RPCServer {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &RPCServer{
-		address:  address,
-		port:     port,
-		services: make(map[string]RPCService),
-		methods:  make(map[string]reflect.Value),
-		ctx:      ctx,
-		cancel:   cancel,
+		address:   address,
+		port:      port,
+		services:  make(map[string]*ServiceDesc),
+		methods:   make(map[string]*MethodDesc),
+		ctx:       ctx,
+		cancel:    cancel,
+		transport: TransportConfig{}.withDefaults(),
+		conns:     make(map[net.Conn]struct{}),
 	}
 }
 
-// RegisterService 注册服务
+// SetTransportConfig 设置连接的超时和保活，可在运行时调用，对已有连接的下一次读写生效
+func (s *RPCServer) SetTransportConfig(config *TransportConfig) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.transport = config.withDefaults()
+}
+
+// transportConfig 当前的连接设置
+func (s *RPCServer) transportConfig() TransportConfig {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.transport
+}
+
+// ReapedConnections 因读超时或空闲被回收的连接数
+func (s *RPCServer) ReapedConnections() int64 {
+	return atomic.LoadInt64(&s.reaped)
+}
+
+// RegisterService 通过反射注册服务，新服务应使用tools/rpcgen生成的注册函数
 func (s *RPCServer) RegisterService(service RPCService) error {
+	desc := &ServiceDesc{
+		Name:    service.GetName(),
+		Methods: make(map[string]*MethodDesc),
+	}
+
+	for methodName, method := range service.RegisterMethods() {
+		methodDesc, err := methodFromValue(method)
+		if err != nil {
+			return fmt.Errorf("invalid method %s.%s: %v", desc.Name, methodName, err)
+		}
+		desc.Methods[methodName] = methodDesc
+	}
+
+	return s.RegisterServiceDesc(desc)
+}
+
+// RegisterServiceDesc 按服务描述注册服务
+func (s *RPCServer) RegisterServiceDesc(desc *ServiceDesc) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	name := service.GetName()
-	if _, exists := s.services[name]; exists {
-		return fmt.Errorf("service %s already registered", name)
+	if _, exists := s.services[desc.Name]; exists {
+		return fmt.Errorf("service %s already registered", desc.Name)
 	}
 
-	s.services[name] = service
+	s.services[desc.Name] = desc
 
 	// 注册方法
-	methods := service.RegisterMethods()
-	for methodName, method := range methods {
-		fullName := fmt.Sprintf("%s.%s", name, methodName)
+	for methodName, method := range desc.Methods {
+		fullName := fmt.Sprintf("%s.%s", desc.Name, methodName)
 		s.methods[fullName] = method
 	}
 
-	logger.Info(fmt.Sprintf("RPC service %s registered with %d methods", name, len(methods)))
+	logger.Info(fmt.Sprintf("RPC service %s registered with %d methods", desc.Name, len(desc.Methods)))
 	return nil
 }
 
+// Use 添加拦截器，先添加的在外层
+func (s *RPCServer) Use(interceptor Interceptor) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.interceptors = append(s.interceptors, interceptor)
+}
+
 // Start 启动RPC服务器
 func (s *RPCServer) Start() error {
-	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", s.address, s.port))
+	listener, err := net.Listen("tcp", net.JoinHostPort(s.address, strconv.Itoa(s.port)))
 	if err != nil {
 		return fmt.Errorf("failed to listen on %s:%d: %v", s.address, s.port, err)
 	}
@@ -120,6 +191,13 @@ func (s *RPCServer) Stop() error {
 		s.listener.Close()
 	}
 
+	// 关闭活跃连接，阻塞在读取上的处理协程随之退出
+	s.connMutex.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.connMutex.Unlock()
+
 	s.wg.Wait()
 	logger.Info("RPC server stopped")
 
@@ -149,50 +227,83 @@ func (s *RPCServer) acceptLoop() {
 func (s *RPCServer) handleConnection(conn net.Conn) {
 	defer s.wg.Done()
 	defer func() {
+		s.connMutex.Lock()
+		delete(s.conns, conn)
+		s.connMutex.Unlock()
 		conn.Close()
 		atomic.AddInt64(&s.connCount, -1)
 	}()
 
+	s.connMutex.Lock()
+	s.conns[conn] = struct{}{}
+	s.connMutex.Unlock()
+
+	transport := s.transportConfig()
+	setKeepAlive(conn, transport.KeepAlive)
+
 	logger.Debug(fmt.Sprintf("New RPC connection from %s", conn.RemoteAddr()))
 
+	lastRequest := time.Now()
 	for s.running {
-		// 读取请求长度
-		lengthBuf := make([]byte, 4)
-		if _, err := conn.Read(lengthBuf); err != nil {
-			break
+		transport = s.transportConfig()
+
+		// 读截止时间取读超时和空闲回收时间中较早的一个
+		readDeadline := deadline(transport.ReadTimeout)
+		idleDeadline := time.Time{}
+		if transport.IdleTimeout > 0 {
+			idleDeadline = lastRequest.Add(transport.IdleTimeout)
+			if readDeadline.IsZero() || idleDeadline.Before(readDeadline) {
+				readDeadline = idleDeadline
+			}
 		}
+		conn.SetReadDeadline(readDeadline)
 
-		// 解析消息长度
-		msgLen := uint32(lengthBuf[0])<<24 | uint32(lengthBuf[1])<<16 | uint32(lengthBuf[2])<<8 | uint32(lengthBuf[3])
-
-		// 检查消息长度
-		if msgLen == 0 || msgLen > 1024*1024 {
-			logger.Warn(fmt.Sprintf("Invalid RPC message length: %d", msgLen))
+		// 读取请求帧，短读会等到读满或连接出错
+		requestBuf, err := ReadFrame(conn)
+		if err != nil {
+			if isTimeout(err) {
+				atomic.AddInt64(&s.reaped, 1)
+				if !idleDeadline.IsZero() && !time.Now().Before(idleDeadline) {
+					logger.Info(fmt.Sprintf("Closing idle RPC connection from %s, no request for %v", conn.RemoteAddr(), transport.IdleTimeout))
+				} else {
+					logger.Warn(fmt.Sprintf("RPC connection from %s timed out, nothing received for %v", conn.RemoteAddr(), transport.ReadTimeout))
+				}
+			} else if err != io.EOF && s.running {
+				logger.Warn(fmt.Sprintf("Read RPC request from %s error: %v", conn.RemoteAddr(), err))
+			}
 			break
 		}
 
-		// 读取请求数据
-		requestBuf := make([]byte, msgLen)
-		if _, err := conn.Read(requestBuf); err != nil {
-			break
+		// 心跳帧直接应答，不计入空闲时间
+		responseData := heartbeatResponse
+		if !isHeartbeat(requestBuf) {
+			responseData = s.Dispatch(requestBuf)
+			lastRequest = time.Now()
 		}
 
-		// 处理请求
-		response := s.handleRequest(requestBuf)
-
 		// 发送响应
-		responseData, _ := json.Marshal(response)
-		responseLen := make([]byte, 4)
-		responseLen[0] = byte(len(responseData) >> 24)
-		responseLen[1] = byte(len(responseData) >> 16)
-		responseLen[2] = byte(len(responseData) >> 8)
-		responseLen[3] = byte(len(responseData))
-
-		conn.Write(responseLen)
-		conn.Write(responseData)
+		conn.SetWriteDeadline(deadline(transport.WriteTimeout))
+		if err := WriteFrame(conn, responseData); err != nil {
+			logger.Warn(fmt.Sprintf("Write RPC response to %s error: %v", conn.RemoteAddr(), err))
+			break
+		}
 	}
 }
 
+// Dispatch 处理一个请求帧的内容，返回序列化后的响应
+func (s *RPCServer) Dispatch(data []byte) []byte {
+	response := s.handleRequest(data)
+	responseData, _ := json.Marshal(response)
+	if len(responseData) > MaxFrameSize {
+		// 响应超过帧长度上限时改为返回错误，避免调用方一直等到超时
+		responseData, _ = json.Marshal(&RPCResponse{
+			ID:    response.ID,
+			Error: fmt.Sprintf("response too large: %d bytes", len(responseData)),
+		})
+	}
+	return responseData
+}
+
 // handleRequest 处理RPC请求
 func (s *RPCServer) handleRequest(data []byte) *RPCResponse {
 	var request RPCRequest
@@ -207,6 +318,7 @@ func (s *RPCServer) handleRequest(data []byte) *RPCResponse {
 	methodKey := fmt.Sprintf("%s.%s", request.Service, request.Method)
 	s.mutex.RLock()
 	method, exists := s.methods[methodKey]
+	interceptors := s.interceptors
 	s.mutex.RUnlock()
 
 	if !exists {
@@ -218,7 +330,7 @@ func (s *RPCServer) handleRequest(data []byte) *RPCResponse {
 
 	// 调用方法
 	start := time.Now()
-	result, err := s.callMethod(method, request.Args)
+	result, err := s.callMethod(method, &request, interceptors)
 	duration := time.Since(start)
 
 	logger.Debug(fmt.Sprintf("RPC call %s took %v", methodKey, duration))
@@ -234,44 +346,46 @@ func (s *RPCServer) handleRequest(data []byte) *RPCResponse {
 }
 
 // callMethod 调用方法
-func (s *RPCServer) callMethod(method reflect.Value, args []byte) ([]byte, error) {
-	methodType := method.Type()
-	if methodType.NumIn() != 2 {
-		return nil, fmt.Errorf("method must have exactly 2 parameters")
-	}
-
-	// 创建参数
-	argsType := methodType.In(1)
-	argsValue := reflect.New(argsType.Elem())
-
-	// 反序列化参数
-	if len(args) > 0 {
-		if err := proto.Unmarshal(args, argsValue.Interface().(proto.Message)); err != nil {
+func (s *RPCServer) callMethod(method *MethodDesc, rpcRequest *RPCRequest, interceptors []Interceptor) ([]byte, error) {
+	// 创建并反序列化参数
+	request := method.NewRequest()
+	if len(rpcRequest.Args) > 0 {
+		if err := proto.Unmarshal(rpcRequest.Args, request); err != nil {
 			return nil, fmt.Errorf("unmarshal args error: %v", err)
 		}
 	}
 
 	// 调用方法
-	results := method.Call([]reflect.Value{
-		reflect.ValueOf(context.Background()),
-		argsValue,
-	})
+	handler := func(ctx context.Context, call *CallInfo) (proto.Message, error) {
+		return method.Handler(ctx, call.Request)
+	}
+
+	// 由内向外包装拦截器
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], handler
+		handler = func(ctx context.Context, call *CallInfo) (proto.Message, error) {
+			return interceptor(ctx, call, next)
+		}
+	}
 
-	if len(results) != 2 {
-		return nil, fmt.Errorf("method must return exactly 2 values")
+	call := &CallInfo{
+		Service:  rpcRequest.Service,
+		Method:   rpcRequest.Method,
+		Request:  request,
+		Metadata: rpcRequest.Metadata,
 	}
 
-	// 检查错误
-	if !results[1].IsNil() {
-		return nil, results[1].Interface().(error)
+	result, err := handler(context.Background(), call)
+	if err != nil {
+		return nil, err
 	}
 
 	// 序列化结果
-	if results[0].IsNil() {
+	if result == nil {
 		return nil, nil
 	}
 
-	return proto.Marshal(results[0].Interface().(proto.Message))
+	return proto.Marshal(result)
 }
 
 // GetConnectionCount 获取连接数
@@ -292,6 +406,11 @@ type RPCClient struct {
 	cancel    context.CancelFunc
 	wg        sync.WaitGroup
 	pool      *RPCConnectionPool
+	metadata  map[string]string
+
+	transport    TransportConfig
+	lastWrite    int64     // 最近一次写出帧的时间(纳秒)，空闲超过心跳间隔时发送心跳
+	callDeadline time.Time // 已发出调用中最晚的等待截止时间，读超时不早于该时间
 }
 
 // NewRPCClient 创建RPC客户端
@@ -303,57 +422,154 @@ func NewRPCClient(address string, port int) *RPCClient {
 		callbacks: make(map[uint64]chan *RPCResponse),
 		ctx:       ctx,
 		cancel:    cancel,
+		transport: TransportConfig{}.withDefaults(),
 	}
 }
 
+// SetTransportConfig 设置连接的超时和保活，需在Connect前调用
+func (c *RPCClient) SetTransportConfig(config *TransportConfig) {
+	c.transport = config.withDefaults()
+}
+
 // Connect 连接到RPC服务器
 func (c *RPCClient) Connect() error {
-	conn, err := net.Dial("tcp", fmt.Sprintf("%s:%d", c.address, c.port))
+	dialer := &net.Dialer{KeepAlive: c.transport.KeepAlive}
+	if c.transport.DialTimeout > 0 {
+		dialer.Timeout = c.transport.DialTimeout
+	}
+	address := net.JoinHostPort(c.address, strconv.Itoa(c.port))
+	conn, err := dialer.Dial("tcp", address)
 	if err != nil {
-		return fmt.Errorf("failed to connect to %s:%d: %v", c.address, c.port, err)
+		return fmt.Errorf("failed to connect to %s: %v", address, err)
 	}
 
 	c.conn = conn
 	c.running = true
+	atomic.StoreInt64(&c.lastWrite, time.Now().UnixNano())
 
 	// 启动响应处理goroutine
 	c.wg.Add(1)
 	go c.responseLoop()
 
+	// 空闲时发送心跳，让服务端的读超时只回收失效的连接
+	if c.transport.HeartbeatInterval > 0 {
+		c.wg.Add(1)
+		go c.heartbeatLoop()
+	}
+
 	logger.Debug(fmt.Sprintf("Connected to RPC server %s:%d", c.address, c.port))
 	return nil
 }
 
 // Disconnect 断开连接
 func (c *RPCClient) Disconnect() error {
-	if !c.running {
-		return nil
+	closed := c.shutdown()
+	c.wg.Wait()
+	if closed {
+		logger.Debug("Disconnected from RPC server")
 	}
 
-	c.running = false
-	c.cancel()
+	return nil
+}
 
-	if c.conn != nil {
-		c.conn.Close()
+// IsConnected 连接是否可用，读写出错或超时后连接会被关闭
+func (c *RPCClient) IsConnected() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.running
+}
+
+// shutdown 关闭连接并让等待中的调用立即返回，已关闭时返回false
+func (c *RPCClient) shutdown() bool {
+	c.mutex.Lock()
+	if !c.running {
+		c.mutex.Unlock()
+		return false
 	}
+	c.running = false
 
 	// 清理回调
-	c.mutex.Lock()
 	for _, callback := range c.callbacks {
 		close(callback)
 	}
 	c.callbacks = make(map[uint64]chan *RPCResponse)
 	c.mutex.Unlock()
 
-	c.wg.Wait()
-	logger.Debug("Disconnected from RPC server")
+	c.cancel()
+	if c.conn != nil {
+		c.conn.Close()
+	}
+	return true
+}
 
+// fail 连接读写出错时关闭连接
+func (c *RPCClient) fail(err error) {
+	if c.shutdown() {
+		logger.Warn(fmt.Sprintf("RPC connection to %s:%d closed: %v", c.address, c.port, err))
+	}
+}
+
+// send 写出一个帧
+func (c *RPCClient) send(frame []byte) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.conn.SetWriteDeadline(deadline(c.transport.WriteTimeout))
+	if err := WriteFrame(c.conn, frame); err != nil {
+		return err
+	}
+	atomic.StoreInt64(&c.lastWrite, time.Now().UnixNano())
 	return nil
 }
 
+// armReadDeadline 设置读截止时间，不早于已发出调用的等待截止时间，调用方需持有锁
+func (c *RPCClient) armReadDeadline() {
+	readDeadline := deadline(c.transport.ReadTimeout)
+	if readDeadline.IsZero() {
+		return
+	}
+	if readDeadline.Before(c.callDeadline) {
+		readDeadline = c.callDeadline
+	}
+	c.conn.SetReadDeadline(readDeadline)
+}
+
+// heartbeatLoop 一个心跳间隔内没有写出任何帧时发送心跳
+func (c *RPCClient) heartbeatLoop() {
+	defer c.wg.Done()
+
+	interval := c.transport.HeartbeatInterval
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			if time.Since(time.Unix(0, atomic.LoadInt64(&c.lastWrite))) < interval {
+				continue
+			}
+			if err := c.send(heartbeatFrame); err != nil {
+				c.fail(fmt.Errorf("send heartbeat error: %v", err))
+				return
+			}
+		}
+	}
+}
+
+// SetMetadata 设置之后每次调用都附带的元数据，需在发起调用前设置
+func (c *RPCClient) SetMetadata(key, value string) {
+	if c.metadata == nil {
+		c.metadata = make(map[string]string)
+	}
+	c.metadata[key] = value
+}
+
 // Call 同步调用RPC方法
 func (c *RPCClient) Call(service, method string, args proto.Message, timeout time.Duration) ([]byte, error) {
-	if !c.running {
+	if !c.IsConnected() {
 		return nil, fmt.Errorf("client not connected")
 	}
 
@@ -370,45 +586,47 @@ func (c *RPCClient) Call(service, method string, args proto.Message, timeout tim
 	// 创建请求
 	requestID := atomic.AddUint64(&c.requestID, 1)
 	request := &RPCRequest{
-		ID:      requestID,
-		Service: service,
-		Method:  method,
-		Args:    argsData,
-		Timeout: int64(timeout / time.Millisecond),
+		ID:       requestID,
+		Service:  service,
+		Method:   method,
+		Args:     argsData,
+		Timeout:  int64(timeout / time.Millisecond),
+		Metadata: c.metadata,
 	}
 
-	// 创建回调通道
+	// 创建回调通道，连接关闭时由shutdown关闭通道
 	callback := make(chan *RPCResponse, 1)
 	c.mutex.Lock()
+	if !c.running {
+		c.mutex.Unlock()
+		return nil, fmt.Errorf("client not connected")
+	}
 	c.callbacks[requestID] = callback
-	c.mutex.Unlock()
 
-	// 发送请求
-	requestData, _ := json.Marshal(request)
-	requestLen := make([]byte, 4)
-	requestLen[0] = byte(len(requestData) >> 24)
-	requestLen[1] = byte(len(requestData) >> 16)
-	requestLen[2] = byte(len(requestData) >> 8)
-	requestLen[3] = byte(len(requestData))
-
-	c.mutex.Lock()
-	_, err = c.conn.Write(requestLen)
-	if err == nil {
-		_, err = c.conn.Write(requestData)
+	// 等待响应期间读超时不能先于调用超时触发
+	if callDeadline := time.Now().Add(timeout); callDeadline.After(c.callDeadline) {
+		c.callDeadline = callDeadline
+		c.armReadDeadline()
 	}
 	c.mutex.Unlock()
 
-	if err != nil {
+	// 发送请求
+	requestData, _ := json.Marshal(request)
+	if err := c.send(requestData); err != nil {
 		c.mutex.Lock()
 		delete(c.callbacks, requestID)
 		c.mutex.Unlock()
-		close(callback)
+		c.fail(fmt.Errorf("send request error: %v", err))
 		return nil, fmt.Errorf("send request error: %v", err)
 	}
 
 	// 等待响应
 	select {
-	case response := <-callback:
+	case response, ok := <-callback:
+		if !ok {
+			return nil, fmt.Errorf("rpc connection closed")
+		}
+
 		c.mutex.Lock()
 		delete(c.callbacks, requestID)
 		c.mutex.Unlock()
@@ -422,32 +640,46 @@ func (c *RPCClient) Call(service, method string, args proto.Message, timeout tim
 		c.mutex.Lock()
 		delete(c.callbacks, requestID)
 		c.mutex.Unlock()
-		close(callback)
 		return nil, fmt.Errorf("rpc call timeout")
 	}
 }
 
+// Invoke 调用RPC方法并把结果解析到reply，ctx的截止时间早于timeout时以ctx为准
+func (c *RPCClient) Invoke(ctx context.Context, service, method string, args, reply proto.Message, timeout time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline); remaining < timeout {
+			timeout = remaining
+		}
+	}
+
+	data, err := c.Call(service, method, args, timeout)
+	if err != nil {
+		return err
+	}
+	if reply != nil && len(data) > 0 {
+		if err := proto.Unmarshal(data, reply); err != nil {
+			return fmt.Errorf("unmarshal reply error: %v", err)
+		}
+	}
+	return nil
+}
+
 // responseLoop 响应处理循环
 func (c *RPCClient) responseLoop() {
 	defer c.wg.Done()
 
-	for c.running {
-		// 读取响应长度
-		lengthBuf := make([]byte, 4)
-		if _, err := c.conn.Read(lengthBuf); err != nil {
-			if c.running {
-				logger.Error(fmt.Sprintf("Read response length error: %v", err))
-			}
-			break
-		}
-
-		// 解析消息长度
-		msgLen := uint32(lengthBuf[0])<<24 | uint32(lengthBuf[1])<<16 | uint32(lengthBuf[2])<<8 | uint32(lengthBuf[3])
+	for {
+		c.mutex.Lock()
+		c.armReadDeadline()
+		c.mutex.Unlock()
 
-		// 读取响应数据
-		responseBuf := make([]byte, msgLen)
-		if _, err := c.conn.Read(responseBuf); err != nil {
-			logger.Error(fmt.Sprintf("Read response data error: %v", err))
+		// 读取响应帧，超时说明服务端已失效，关闭连接让等待中的调用立即返回
+		responseBuf, err := ReadFrame(c.conn)
+		if err != nil {
+			c.fail(fmt.Errorf("read response error: %v", err))
 			break
 		}
 
@@ -481,6 +713,8 @@ type RPCConnectionPool struct {
 	mutex   sync.Mutex
 	ctx     context.Context
 	cancel  context.CancelFunc
+
+	transport *TransportConfig
 }
 
 // NewRPCConnectionPool 创建RPC连接池
@@ -496,14 +730,36 @@ func NewRPCConnectionPool(address string, port int, maxSize int) *RPCConnectionP
 	}
 }
 
-// Get 获取连接
+// SetTransportConfig 设置之后新建连接的超时和保活
+func (p *RPCConnectionPool) SetTransportConfig(config *TransportConfig) {
+	p.transport = config
+}
+
+// Get 获取连接，池中已失效的连接会被丢弃
 func (p *RPCConnectionPool) Get() (*RPCClient, error) {
-	select {
-	case client := <-p.pool:
-		return client, nil
-	default:
+	for {
+		if p.ctx.Err() != nil {
+			return nil, fmt.Errorf("connection pool closed")
+		}
+
+		select {
+		case client := <-p.pool:
+			if client == nil {
+				continue
+			}
+			if !client.IsConnected() {
+				p.discard(client)
+				continue
+			}
+			return client, nil
+		default:
+		}
+
 		if atomic.LoadInt64(&p.created) < int64(p.maxSize) {
 			client := NewRPCClient(p.address, p.port)
+			if p.transport != nil {
+				client.SetTransportConfig(p.transport)
+			}
 			if err := client.Connect(); err != nil {
 				return nil, err
 			}
@@ -515,6 +771,13 @@ func (p *RPCConnectionPool) Get() (*RPCClient, error) {
 		// 等待连接可用
 		select {
 		case client := <-p.pool:
+			if client == nil {
+				continue
+			}
+			if !client.IsConnected() {
+				p.discard(client)
+				continue
+			}
 			return client, nil
 		case <-time.After(5 * time.Second):
 			return nil, fmt.Errorf("connection pool timeout")
@@ -522,27 +785,46 @@ func (p *RPCConnectionPool) Get() (*RPCClient, error) {
 	}
 }
 
+// discard 丢弃失效的连接
+func (p *RPCConnectionPool) discard(client *RPCClient) {
+	client.Disconnect()
+	atomic.AddInt64(&p.created, -1)
+}
+
 // Put 归还连接
 func (p *RPCConnectionPool) Put(client *RPCClient) {
 	if client == nil {
 		return
 	}
+	if !client.IsConnected() {
+		p.discard(client)
+		return
+	}
+
+	// 连接池关闭后归还的连接直接关闭
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.ctx.Err() != nil {
+		p.discard(client)
+		return
+	}
 
 	select {
 	case p.pool <- client:
 	default:
 		// 池已满，关闭连接
-		client.Disconnect()
-		atomic.AddInt64(&p.created, -1)
+		p.discard(client)
 	}
 }
 
 // Close 关闭连接池
 func (p *RPCConnectionPool) Close() {
+	p.mutex.Lock()
 	p.cancel()
 
 	// 关闭所有连接
 	close(p.pool)
+	p.mutex.Unlock()
 	for client := range p.pool {
 		client.Disconnect()
 	}
@@ -557,3 +839,22 @@ func (p *RPCConnectionPool) Size() int {
 func (p *RPCConnectionPool) Created() int64 {
 	return atomic.LoadInt64(&p.created)
 }
+
+// Warm 预先建立连接放入池中，最多建立到n个或池的上限，返回池中新增的连接数
+func (p *RPCConnectionPool) Warm(n int) (int, error) {
+	warmed := 0
+	for i := 0; i < n && atomic.LoadInt64(&p.created) < int64(p.maxSize); i++ {
+		client := NewRPCClient(p.address, p.port)
+		if p.transport != nil {
+			client.SetTransportConfig(p.transport)
+		}
+		if err := client.Connect(); err != nil {
+			return warmed, err
+		}
+		client.pool = p
+		atomic.AddInt64(&p.created, 1)
+		p.Put(client)
+		warmed++
+	}
+	return warmed, nil
+}
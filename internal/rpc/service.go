@@ -0,0 +1,93 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// ServiceDesc 服务描述，由tools/rpcgen根据服务接口生成，注册时不需要反射
+type ServiceDesc struct {
+	Name    string
+	Methods map[string]*MethodDesc
+}
+
+// MethodDesc 方法描述
+type MethodDesc struct {
+	NewRequest func() proto.Message                                                // 创建请求消息
+	Handler    func(ctx context.Context, req proto.Message) (proto.Message, error) // 调用方法实现
+}
+
+// NewMethod 根据类型化的方法实现创建方法描述，请求类型由方法签名推导
+func NewMethod[Req any, Resp any, PReq interface {
+	*Req
+	proto.Message
+}, PResp interface {
+	*Resp
+	proto.Message
+}](method func(ctx context.Context, req PReq) (PResp, error)) *MethodDesc {
+	return &MethodDesc{
+		NewRequest: func() proto.Message {
+			return PReq(new(Req))
+		},
+		Handler: func(ctx context.Context, req proto.Message) (proto.Message, error) {
+			resp, err := method(ctx, req.(PReq))
+			if err != nil {
+				return nil, err
+			}
+			if resp == nil {
+				return nil, nil
+			}
+			return resp, nil
+		},
+	}
+}
+
+// methodFromValue 将通过反射注册的方法转换为方法描述，注册时检查方法签名
+func methodFromValue(method reflect.Value) (*MethodDesc, error) {
+	messageType := reflect.TypeOf((*proto.Message)(nil)).Elem()
+	errorType := reflect.TypeOf((*error)(nil)).Elem()
+	contextType := reflect.TypeOf((*context.Context)(nil)).Elem()
+
+	methodType := method.Type()
+	if methodType.NumIn() != 2 {
+		return nil, fmt.Errorf("method must have exactly 2 parameters")
+	}
+	if methodType.NumOut() != 2 {
+		return nil, fmt.Errorf("method must return exactly 2 values")
+	}
+	if methodType.In(0) != contextType {
+		return nil, fmt.Errorf("first parameter must be context.Context")
+	}
+	argsType := methodType.In(1)
+	if argsType.Kind() != reflect.Ptr || !argsType.Implements(messageType) {
+		return nil, fmt.Errorf("second parameter must be a protobuf message pointer")
+	}
+	if !methodType.Out(0).Implements(messageType) || methodType.Out(1) != errorType {
+		return nil, fmt.Errorf("method must return (protobuf message, error)")
+	}
+
+	return &MethodDesc{
+		NewRequest: func() proto.Message {
+			return reflect.New(argsType.Elem()).Interface().(proto.Message)
+		},
+		Handler: func(ctx context.Context, req proto.Message) (proto.Message, error) {
+			results := method.Call([]reflect.Value{
+				reflect.ValueOf(ctx),
+				reflect.ValueOf(req),
+			})
+
+			// 检查错误
+			if !results[1].IsNil() {
+				return nil, results[1].Interface().(error)
+			}
+
+			if results[0].IsNil() {
+				return nil, nil
+			}
+			return results[0].Interface().(proto.Message), nil
+		},
+	}, nil
+}
@@ -0,0 +1,77 @@
+package rpc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// CallStatsSnapshot 一个统计周期内的RPC调用统计
+type CallStatsSnapshot struct {
+	Requests     int64
+	Errors       int64
+	TotalLatency time.Duration
+	Window       time.Duration // 统计周期长度
+}
+
+// AvgLatency 平均耗时
+func (s CallStatsSnapshot) AvgLatency() time.Duration {
+	if s.Requests == 0 {
+		return 0
+	}
+	return s.TotalLatency / time.Duration(s.Requests)
+}
+
+// CallStats 按周期统计RPC调用次数、错误数和耗时，用于比较节点间的服务质量
+type CallStats struct {
+	requests     int64
+	errors       int64
+	totalLatency time.Duration
+	windowStart  time.Time
+	mutex        sync.Mutex
+}
+
+// NewCallStats 创建RPC调用统计
+func NewCallStats() *CallStats {
+	return &CallStats{windowStart: time.Now()}
+}
+
+// Interceptor 统计拦截器，需放在恢复拦截器之前，panic转换的错误也计入错误数
+func (cs *CallStats) Interceptor() Interceptor {
+	return func(ctx context.Context, call *CallInfo, next Handler) (proto.Message, error) {
+		start := time.Now()
+		resp, err := next(ctx, call)
+		cs.record(time.Since(start), err)
+		return resp, err
+	}
+}
+
+// record 记录一次调用
+func (cs *CallStats) record(latency time.Duration, err error) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	cs.requests++
+	cs.totalLatency += latency
+	if err != nil {
+		cs.errors++
+	}
+}
+
+// Collect 获取当前周期的统计并开始新的周期
+func (cs *CallStats) Collect() CallStatsSnapshot {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	now := time.Now()
+	snapshot := CallStatsSnapshot{
+		Requests:     cs.requests,
+		Errors:       cs.errors,
+		TotalLatency: cs.totalLatency,
+		Window:       now.Sub(cs.windowStart),
+	}
+	cs.requests, cs.errors, cs.totalLatency, cs.windowStart = 0, 0, 0, now
+	return snapshot
+}
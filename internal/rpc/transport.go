@@ -0,0 +1,89 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"time"
+)
+
+// 连接默认设置
+const (
+	defaultDialTimeout       = 5 * time.Second
+	defaultReadTimeout       = 90 * time.Second
+	defaultWriteTimeout      = 10 * time.Second
+	defaultKeepAlive         = 30 * time.Second
+	defaultHeartbeatInterval = 30 * time.Second
+)
+
+// heartbeatService 心跳帧使用的服务名，不会被注册为普通服务
+const heartbeatService = "_heartbeat"
+
+// 心跳请求和响应的帧内容，ID固定为0，客户端的调用ID从1开始，不会与之冲突
+var (
+	heartbeatFrame, _    = json.Marshal(&RPCRequest{Service: heartbeatService})
+	heartbeatResponse, _ = json.Marshal(&RPCResponse{})
+)
+
+// TransportConfig RPC连接的超时和保活设置，未配置的项使用默认值，配置为负数时关闭该项
+type TransportConfig struct {
+	DialTimeout       time.Duration `yaml:"dial_timeout"`       // 客户端建立连接的超时，默认5秒
+	ReadTimeout       time.Duration `yaml:"read_timeout"`       // 连续未收到任何帧(含心跳)超过该时长时断开，默认90秒
+	WriteTimeout      time.Duration `yaml:"write_timeout"`      // 单个帧的写超时，默认10秒
+	KeepAlive         time.Duration `yaml:"keepalive"`          // TCP keepalive探测间隔，默认30秒
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval"` // 客户端空闲时发送心跳帧的间隔，需小于服务端ReadTimeout的一半，默认30秒
+	IdleTimeout       time.Duration `yaml:"-"`                  // 服务端回收长时间没有请求(心跳不计入)的连接，由rpc.idle_timeout设置，0为不回收
+}
+
+// withDefaults 补齐未配置的设置
+func (c TransportConfig) withDefaults() TransportConfig {
+	if c.DialTimeout == 0 {
+		c.DialTimeout = defaultDialTimeout
+	}
+	if c.ReadTimeout == 0 {
+		c.ReadTimeout = defaultReadTimeout
+	}
+	if c.WriteTimeout == 0 {
+		c.WriteTimeout = defaultWriteTimeout
+	}
+	if c.KeepAlive == 0 {
+		c.KeepAlive = defaultKeepAlive
+	}
+	if c.HeartbeatInterval == 0 {
+		c.HeartbeatInterval = defaultHeartbeatInterval
+	}
+	return c
+}
+
+// deadline 按超时计算截止时间，关闭时返回零值表示不设截止时间
+func deadline(timeout time.Duration) time.Time {
+	if timeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(timeout)
+}
+
+// setKeepAlive 为TCP连接开启keepalive，间隔为负数时关闭
+func setKeepAlive(conn net.Conn, period time.Duration) {
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return
+	}
+	if period < 0 {
+		tcpConn.SetKeepAlive(false)
+		return
+	}
+	tcpConn.SetKeepAlive(true)
+	tcpConn.SetKeepAlivePeriod(period)
+}
+
+// isHeartbeat 是否为心跳帧
+func isHeartbeat(frame []byte) bool {
+	return bytes.Equal(frame, heartbeatFrame)
+}
+
+// isTimeout 是否为超时错误
+func isTimeout(err error) bool {
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
@@ -0,0 +1,267 @@
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// Config saga协调器配置
+type Config struct {
+	Lease        time.Duration `yaml:"lease"`         // 单个步骤的处理时限，执行记录超过租期未更新视为节点故障，由其他节点接管补偿
+	MaxAttempts  int32         `yaml:"max_attempts"`  // 补偿的最大尝试次数，超过后标记为failed等待人工处理
+	RecoverLimit int64         `yaml:"recover_limit"` // 单次恢复检查的最大记录数
+}
+
+// Store saga执行记录存储，database.SagaRepository实现了该接口
+type Store interface {
+	CreateSaga(saga *database.Saga) error
+	UpdateSaga(saga *database.Saga) error
+	ClaimSaga(sagaID, nodeID string, lease time.Duration) (*database.Saga, error)
+	GetStaleSagas(limit int64) ([]*database.Saga, error)
+}
+
+// StepFunc 步骤的执行或补偿，参数通过Bind读取
+// 节点故障时正在执行的步骤是否生效无法确定，恢复时会连同该步骤一起补偿，补偿函数需要能处理动作未生效的情况，且可重复执行
+type StepFunc func(ctx context.Context, saga *database.Saga) error
+
+// Step 流程步骤
+type Step struct {
+	Name       string
+	Action     StepFunc
+	Compensate StepFunc // 可为nil，表示该步骤不需要补偿
+}
+
+// Definition 流程定义，步骤按顺序执行，任一步骤失败时逆序补偿已执行的步骤
+type Definition struct {
+	Type  string
+	Steps []Step
+}
+
+// Coordinator saga协调器
+// 执行记录在流程开始前写入，每完成一步更新一次；执行中的节点故障后记录租期过期，
+// 由任一节点的Recover接管并补偿，避免房间等资源停留在中间状态
+type Coordinator struct {
+	config      Config
+	nodeID      string
+	store       Store
+	definitions map[string]*Definition
+	mutex       sync.RWMutex
+}
+
+// NewCoordinator 创建saga协调器
+func NewCoordinator(config *Config, nodeID string, store Store) *Coordinator {
+	cfg := *config
+	if cfg.Lease <= 0 {
+		cfg.Lease = 30 * time.Second
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.RecoverLimit <= 0 {
+		cfg.RecoverLimit = 100
+	}
+
+	return &Coordinator{
+		config:      cfg,
+		nodeID:      nodeID,
+		store:       store,
+		definitions: make(map[string]*Definition),
+	}
+}
+
+// Register 注册流程定义，步骤的顺序和数量上线后不应修改，否则未结束的记录会按新定义补偿
+func (c *Coordinator) Register(definition *Definition) error {
+	if definition.Type == "" || len(definition.Steps) == 0 {
+		return fmt.Errorf("saga definition must have a type and steps")
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, exists := c.definitions[definition.Type]; exists {
+		return fmt.Errorf("saga %s already registered", definition.Type)
+	}
+	c.definitions[definition.Type] = definition
+
+	logger.Info(fmt.Sprintf("Saga %s registered with %d steps", definition.Type, len(definition.Steps)))
+	return nil
+}
+
+// definition 获取流程定义
+func (c *Coordinator) definition(sagaType string) (*Definition, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	definition, exists := c.definitions[sagaType]
+	return definition, exists
+}
+
+// Execute 执行流程，data为流程参数，会以JSON保存在执行记录中
+// 所有步骤成功时返回nil；步骤失败时补偿已执行的步骤后返回该步骤的错误
+func (c *Coordinator) Execute(ctx context.Context, sagaType string, data interface{}) error {
+	definition, exists := c.definition(sagaType)
+	if !exists {
+		return fmt.Errorf("saga %s not registered", sagaType)
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal saga %s data: %v", sagaType, err)
+	}
+
+	saga := &database.Saga{
+		SagaID:     primitive.NewObjectID().Hex(),
+		Type:       sagaType,
+		Status:     database.SagaRunning,
+		Data:       string(payload),
+		NodeID:     c.nodeID,
+		Attempts:   1,
+		LeaseUntil: time.Now().Add(c.config.Lease),
+	}
+	if err := c.store.CreateSaga(saga); err != nil {
+		return err
+	}
+
+	for int(saga.Step) < len(definition.Steps) {
+		step := definition.Steps[saga.Step]
+		if err := c.invoke(ctx, saga, step.Name, step.Action); err != nil {
+			stepErr := fmt.Errorf("saga %s step %s failed: %v", saga.SagaID, step.Name, err)
+			logger.Warn(fmt.Sprintf("Saga %s/%s step %s failed, compensating: %v", sagaType, saga.SagaID, step.Name, err))
+			saga.Status = database.SagaCompensating
+			saga.Error = stepErr.Error()
+			c.compensate(ctx, definition, saga)
+			return stepErr
+		}
+
+		saga.Step++
+		if int(saga.Step) == len(definition.Steps) {
+			saga.Status = database.SagaCompleted
+		}
+		if err := c.save(saga); err != nil {
+			// 记录未能更新时租期过期后由恢复补偿，这里不再继续后续步骤
+			return err
+		}
+	}
+
+	logger.Debug(fmt.Sprintf("Saga %s/%s completed", sagaType, saga.SagaID))
+	return nil
+}
+
+// compensate 逆序执行已完成步骤的补偿，Step为下一个需要补偿的步骤数；
+// 补偿失败时记录保持compensating并让出租期，由恢复任务重试
+func (c *Coordinator) compensate(ctx context.Context, definition *Definition, saga *database.Saga) {
+	for saga.Step > 0 {
+		step := definition.Steps[saga.Step-1]
+		if step.Compensate != nil {
+			if err := c.invoke(ctx, saga, step.Name, step.Compensate); err != nil {
+				logger.Error(fmt.Sprintf("Saga %s/%s compensation of step %s failed (attempt %d): %v",
+					saga.Type, saga.SagaID, step.Name, saga.Attempts, err))
+				saga.Error = fmt.Sprintf("compensation of step %s failed: %v", step.Name, err)
+				if saga.Attempts >= c.config.MaxAttempts {
+					saga.Status = database.SagaFailed
+				}
+				saga.LeaseUntil = time.Now()
+				if err := c.store.UpdateSaga(saga); err != nil {
+					logger.Error(fmt.Sprintf("Failed to update saga %s: %v", saga.SagaID, err))
+				}
+				return
+			}
+		}
+
+		saga.Step--
+		if saga.Step == 0 {
+			saga.Status = database.SagaCompensated
+		}
+		if err := c.save(saga); err != nil {
+			logger.Error(fmt.Sprintf("Failed to update saga %s: %v", saga.SagaID, err))
+			return
+		}
+	}
+
+	// 第一步就失败时没有需要补偿的步骤
+	if saga.Status != database.SagaCompensated {
+		saga.Status = database.SagaCompensated
+		if err := c.save(saga); err != nil {
+			logger.Error(fmt.Sprintf("Failed to update saga %s: %v", saga.SagaID, err))
+			return
+		}
+	}
+	logger.Info(fmt.Sprintf("Saga %s/%s compensated", saga.Type, saga.SagaID))
+}
+
+// save 保存进度并续期
+func (c *Coordinator) save(saga *database.Saga) error {
+	saga.LeaseUntil = time.Now().Add(c.config.Lease)
+	return c.store.UpdateSaga(saga)
+}
+
+// invoke 在处理时限内执行步骤，panic视为失败
+func (c *Coordinator) invoke(ctx context.Context, saga *database.Saga, name string, fn StepFunc) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, c.config.Lease)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error(fmt.Sprintf("Saga %s/%s step %s panic: %v\n%s", saga.Type, saga.SagaID, name, r, debug.Stack()))
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return fn(ctx, saga)
+}
+
+// Recover 接管租期已过的未结束流程并补偿，用作定时任务
+// 执行中的流程连同正在执行的步骤一起补偿，不会继续向前执行
+func (c *Coordinator) Recover(ctx context.Context) error {
+	sagas, err := c.store.GetStaleSagas(c.config.RecoverLimit)
+	if err != nil {
+		return fmt.Errorf("failed to recover sagas: %v", err)
+	}
+
+	for _, stale := range sagas {
+		definition, exists := c.definition(stale.Type)
+		if !exists {
+			// 其他类型的节点注册的流程由它们恢复
+			continue
+		}
+
+		saga, err := c.store.ClaimSaga(stale.SagaID, c.nodeID, c.config.Lease)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to claim saga %s: %v", stale.SagaID, err))
+			continue
+		}
+		if saga == nil {
+			continue
+		}
+
+		if saga.Status == database.SagaRunning {
+			// 正在执行的步骤也可能已生效
+			if int(saga.Step) < len(definition.Steps) {
+				saga.Step++
+			}
+			saga.Status = database.SagaCompensating
+			saga.Error = fmt.Sprintf("node %s stopped while running", stale.NodeID)
+			logger.Warn(fmt.Sprintf("Saga %s/%s abandoned by node %s at step %d, compensating",
+				saga.Type, saga.SagaID, stale.NodeID, stale.Step))
+		}
+		c.compensate(ctx, definition, saga)
+	}
+	return nil
+}
+
+// Bind 解析流程参数
+func Bind(saga *database.Saga, v interface{}) error {
+	if err := json.Unmarshal([]byte(saga.Data), v); err != nil {
+		return fmt.Errorf("failed to parse saga %s data: %v", saga.SagaID, err)
+	}
+	return nil
+}
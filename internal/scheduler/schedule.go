@@ -0,0 +1,195 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule 调度计划，返回t之后的下一次执行时间
+// 所有节点按相同规则计算执行时间，同一次执行在各节点上的时间点一致，用于集群内去重
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+// ParseSchedule 解析调度表达式，支持:
+//
+//	@every 10m                 固定间隔，按Unix时间对齐
+//	@hourly / @daily / @weekly 常用别名
+//	*/5 * * * *                五段cron表达式: 分 时 日 月 周
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("empty schedule")
+	}
+
+	if strings.HasPrefix(spec, "@every ") {
+		interval, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval %q: %v", spec, err)
+		}
+		if interval < time.Second {
+			return nil, fmt.Errorf("interval %v is shorter than 1s", interval)
+		}
+		return Every(interval), nil
+	}
+
+	switch spec {
+	case "@hourly":
+		spec = "0 * * * *"
+	case "@daily", "@midnight":
+		spec = "0 0 * * *"
+	case "@weekly":
+		spec = "0 0 * * 0"
+	case "@monthly":
+		spec = "0 0 1 * *"
+	}
+
+	return parseCron(spec)
+}
+
+// intervalSchedule 固定间隔调度
+type intervalSchedule struct {
+	interval time.Duration
+}
+
+// Every 创建固定间隔调度，执行时间对齐到间隔的整数倍
+func Every(interval time.Duration) Schedule {
+	return &intervalSchedule{interval: interval}
+}
+
+// Next 实现Schedule接口
+func (s *intervalSchedule) Next(t time.Time) time.Time {
+	return t.Truncate(s.interval).Add(s.interval)
+}
+
+// cronSchedule 五段cron调度，各字段以位图表示允许的取值
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+// cronField cron字段取值范围
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+// parseCron 解析五段cron表达式
+func parseCron(spec string) (Schedule, error) {
+	parts := strings.Fields(spec)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("cron %q must have %d fields", spec, len(cronFields))
+	}
+
+	bits := make([]uint64, len(cronFields))
+	for i, part := range parts {
+		value, err := parseCronField(part, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron %q: %v", spec, err)
+		}
+		bits[i] = value
+	}
+
+	return &cronSchedule{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		domAny: parts[2] == "*",
+		dowAny: parts[4] == "*",
+	}, nil
+}
+
+// parseCronField 解析单个字段，支持 * a a-b a,b */n a-b/n
+func parseCronField(field string, def cronField) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rangePart, step := item, 1
+		if index := strings.Index(item, "/"); index >= 0 {
+			value, err := strconv.Atoi(item[index+1:])
+			if err != nil || value <= 0 {
+				return 0, fmt.Errorf("invalid step in %s field %q", def.name, item)
+			}
+			rangePart, step = item[:index], value
+		}
+
+		low, high := def.min, def.max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			value, err := strconv.Atoi(bounds[0])
+			if err != nil {
+				return 0, fmt.Errorf("invalid %s field %q", def.name, item)
+			}
+			low, high = value, value
+			if len(bounds) == 2 {
+				if high, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("invalid %s field %q", def.name, item)
+				}
+			} else if step > 1 {
+				high = def.max // a/n 表示从a开始每隔n
+			}
+		}
+
+		if low < def.min || high > def.max || low > high {
+			return 0, fmt.Errorf("%s field %q out of range %d-%d", def.name, item, def.min, def.max)
+		}
+		for value := low; value <= high; value += step {
+			bits |= 1 << uint(value)
+		}
+	}
+	return bits, nil
+}
+
+// Next 实现Schedule接口，逐级跳过不匹配的月、日、时、分
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0) // 不存在的日期(如2月30日)不会无限循环
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// matchDay 日和周同时限定时满足其一即可，与标准cron一致
+func (s *cronSchedule) matchDay(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dowMatch
+	case s.dowAny:
+		return domMatch
+	default:
+		return domMatch || dowMatch
+	}
+}
@@ -0,0 +1,337 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/monitoring"
+)
+
+// Config 定时任务配置
+type Config struct {
+	Enabled      bool                 `yaml:"enabled"`
+	PollInterval time.Duration        `yaml:"poll_interval"` // 检查到期任务的间隔
+	LockTTL      time.Duration        `yaml:"lock_ttl"`      // 执行锁有效期，需大于节点间的时钟偏差
+	Retention    time.Duration        `yaml:"retention"`     // 执行记录保留时间，0表示永久保留
+	AlertAfter   int                  `yaml:"alert_after"`   // 连续失败达到该次数时告警
+	Jobs         map[string]JobConfig `yaml:"jobs"`          // 按任务名覆盖注册时的设置
+}
+
+// JobConfig 单个任务的配置
+type JobConfig struct {
+	Schedule string        `yaml:"schedule"` // 调度表达式，为空时使用注册时的表达式
+	Timeout  time.Duration `yaml:"timeout"`  // 单次执行超时
+	Disabled bool          `yaml:"disabled"`
+}
+
+// JobFunc 任务函数
+type JobFunc func(ctx context.Context) error
+
+// Locker 分布式锁，database.RedisManager实现了该接口
+type Locker interface {
+	Lock(key string, expiration time.Duration) (bool, error)
+}
+
+// HistoryStore 执行记录存储，database.JobRunRepository实现了该接口
+type HistoryStore interface {
+	SaveRun(run *database.JobRun) error
+	GetRuns(jobName string, limit int64) ([]*database.JobRun, error)
+}
+
+// PanicHandler 任务panic处理函数
+type PanicHandler func(jobName string, value interface{}, stack []byte)
+
+// job 已注册的任务
+type job struct {
+	name     string
+	spec     string
+	schedule Schedule
+	fn       JobFunc
+	timeout  time.Duration
+	next     time.Time
+	running  int32
+	failures int
+}
+
+// JobStatus 任务状态
+type JobStatus struct {
+	Name     string    `json:"name"`
+	Schedule string    `json:"schedule"`
+	NextRun  time.Time `json:"next_run"`
+	Running  bool      `json:"running"`
+	Failures int       `json:"failures"` // 连续失败次数
+}
+
+// Scheduler 集群定时任务调度器
+// 各节点按相同的调度计划计算执行时间，执行前以任务名和计划时间争抢分布式锁，
+// 同一次执行在集群内只有一个节点运行，执行结果写入执行记录，连续失败时告警
+type Scheduler struct {
+	config   Config
+	nodeType string
+	nodeID   string
+	locker   Locker
+	history  HistoryStore
+	jobs     map[string]*job
+	channels []monitoring.AlertChannel
+	onPanic  PanicHandler
+	wg       sync.WaitGroup
+	mutex    sync.Mutex
+}
+
+// NewScheduler 创建调度器，history为nil时不记录执行历史
+func NewScheduler(config *Config, nodeType, nodeID string, locker Locker, history HistoryStore) *Scheduler {
+	cfg := *config
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.LockTTL <= 0 {
+		cfg.LockTTL = 10 * time.Minute
+	}
+	if cfg.AlertAfter <= 0 {
+		cfg.AlertAfter = 1
+	}
+
+	return &Scheduler{
+		config:   cfg,
+		nodeType: nodeType,
+		nodeID:   nodeID,
+		locker:   locker,
+		history:  history,
+		jobs:     make(map[string]*job),
+		channels: []monitoring.AlertChannel{&monitoring.LogAlertChannel{}},
+	}
+}
+
+// Register 注册任务，任务名在集群内唯一，多个节点注册同名任务时每次只会执行一次
+func (s *Scheduler) Register(name, spec string, fn JobFunc) error {
+	jobConfig := s.config.Jobs[name]
+	if jobConfig.Disabled {
+		logger.Info(fmt.Sprintf("Scheduled job %s is disabled", name))
+		return nil
+	}
+	if jobConfig.Schedule != "" {
+		spec = jobConfig.Schedule
+	}
+
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		return fmt.Errorf("failed to register job %s: %v", name, err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.jobs[name]; exists {
+		return fmt.Errorf("job %s already registered", name)
+	}
+
+	s.jobs[name] = &job{
+		name:     name,
+		spec:     spec,
+		schedule: schedule,
+		fn:       fn,
+		timeout:  jobConfig.Timeout,
+		next:     schedule.Next(time.Now()),
+	}
+
+	logger.Info(fmt.Sprintf("Scheduled job %s registered: %s", name, spec))
+	return nil
+}
+
+// AddAlertChannel 添加任务失败的告警通道，默认只写日志
+func (s *Scheduler) AddAlertChannel(channel monitoring.AlertChannel) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.channels = append(s.channels, channel)
+}
+
+// SetPanicHandler 设置任务panic处理函数
+func (s *Scheduler) SetPanicHandler(handler PanicHandler) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.onPanic = handler
+}
+
+// Run 运行调度循环，直到ctx取消，返回前等待执行中的任务结束
+func (s *Scheduler) Run(ctx context.Context) {
+	if !s.config.Enabled {
+		return
+	}
+
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			s.dispatch(ctx, now)
+		case <-ctx.Done():
+			s.wg.Wait()
+			return
+		}
+	}
+}
+
+// Jobs 获取已注册任务的状态
+func (s *Scheduler) Jobs() []JobStatus {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	statuses := make([]JobStatus, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		statuses = append(statuses, JobStatus{
+			Name:     job.name,
+			Schedule: job.spec,
+			NextRun:  job.next,
+			Running:  atomic.LoadInt32(&job.running) == 1,
+			Failures: job.failures,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// History 获取任务的执行记录，包括其他节点的执行
+func (s *Scheduler) History(jobName string, limit int64) ([]*database.JobRun, error) {
+	if s.history == nil {
+		return nil, fmt.Errorf("job history is not enabled")
+	}
+	return s.history.GetRuns(jobName, limit)
+}
+
+// dispatch 启动到期的任务
+func (s *Scheduler) dispatch(ctx context.Context, now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, job := range s.jobs {
+		if now.Before(job.next) {
+			continue
+		}
+
+		// 节点停机期间错过的执行不再补跑
+		scheduledAt := job.next
+		job.next = job.schedule.Next(now)
+
+		s.wg.Add(1)
+		go s.execute(ctx, job, scheduledAt)
+	}
+}
+
+// execute 争抢本次执行的锁，成功后运行任务并记录结果
+func (s *Scheduler) execute(ctx context.Context, job *job, scheduledAt time.Time) {
+	defer s.wg.Done()
+
+	// 上一次执行未结束时放弃本次，交给其他节点
+	if !atomic.CompareAndSwapInt32(&job.running, 0, 1) {
+		logger.Warn(fmt.Sprintf("Scheduled job %s is still running, skip run at %s",
+			job.name, scheduledAt.Format(time.RFC3339)))
+		return
+	}
+	defer atomic.StoreInt32(&job.running, 0)
+
+	lockKey := fmt.Sprintf("scheduler:%s:%d", job.name, scheduledAt.Unix())
+	acquired, err := s.locker.Lock(lockKey, s.config.LockTTL)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to acquire lock for job %s: %v", job.name, err))
+		return
+	}
+	if !acquired {
+		return // 其他节点已执行
+	}
+
+	run := &database.JobRun{
+		JobName:     job.name,
+		NodeID:      s.nodeID,
+		ScheduledAt: scheduledAt,
+		StartedAt:   time.Now(),
+	}
+
+	err = s.invoke(ctx, job)
+
+	run.FinishedAt = time.Now()
+	run.DurationMs = run.FinishedAt.Sub(run.StartedAt).Milliseconds()
+	run.Status = "success"
+	if err != nil {
+		run.Status = "failed"
+		run.Error = err.Error()
+	}
+
+	if s.history != nil {
+		if saveErr := s.history.SaveRun(run); saveErr != nil {
+			logger.Warn(fmt.Sprintf("Failed to save run of job %s: %v", job.name, saveErr))
+		}
+	}
+
+	s.recordResult(job, run)
+}
+
+// invoke 运行任务函数，panic视为失败
+func (s *Scheduler) invoke(ctx context.Context, job *job) (err error) {
+	if job.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, job.timeout)
+		defer cancel()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			stack := debug.Stack()
+			s.mutex.Lock()
+			handler := s.onPanic
+			s.mutex.Unlock()
+			if handler != nil {
+				handler(job.name, r, stack)
+			}
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return job.fn(ctx)
+}
+
+// recordResult 更新连续失败次数，达到阈值时告警
+func (s *Scheduler) recordResult(job *job, run *database.JobRun) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if run.Status == "success" {
+		if job.failures > 0 {
+			logger.Info(fmt.Sprintf("Scheduled job %s recovered after %d failures", job.name, job.failures))
+		}
+		job.failures = 0
+		logger.Debug(fmt.Sprintf("Scheduled job %s finished in %dms", job.name, run.DurationMs))
+		return
+	}
+
+	job.failures++
+	logger.Error(fmt.Sprintf("Scheduled job %s failed (%d in a row): %s", job.name, job.failures, run.Error))
+
+	if job.failures%s.config.AlertAfter != 0 {
+		return
+	}
+
+	alert := monitoring.Alert{
+		ID:        fmt.Sprintf("job_%s_%d", job.name, run.StartedAt.Unix()),
+		Rule:      "scheduled_job_failed",
+		Level:     monitoring.AlertLevelError,
+		Message:   fmt.Sprintf("job %s failed %d times in a row: %s", job.name, job.failures, run.Error),
+		Timestamp: run.FinishedAt,
+		NodeID:    s.nodeID,
+		NodeType:  s.nodeType,
+	}
+	for _, channel := range s.channels {
+		if err := channel.Send(alert); err != nil {
+			logger.Error(fmt.Sprintf("Failed to send alert: %v", err))
+		}
+	}
+}
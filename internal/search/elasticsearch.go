@@ -0,0 +1,260 @@
+package search
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+)
+
+// Elasticsearch默认设置
+const (
+	defaultUserIndex            = "lufy_users"
+	defaultRoomIndex            = "lufy_rooms"
+	defaultElasticsearchTimeout = 3 * time.Second
+)
+
+// ElasticsearchConfig Elasticsearch连接配置
+type ElasticsearchConfig struct {
+	URL        string `yaml:"url"` // 如 http://localhost:9200
+	Username   string `yaml:"username"`
+	Password   string `yaml:"password"`
+	UserIndex  string `yaml:"user_index"`  // 玩家索引，未配置时为lufy_users
+	RoomIndex  string `yaml:"room_index"`  // 房间索引，未配置时为lufy_rooms
+	TimeoutSec int    `yaml:"timeout_sec"` // 请求超时，未配置时为3秒
+}
+
+// userMapping 玩家索引的映射，昵称使用search_as_you_type支持前缀匹配
+const userMapping = `{"mappings":{"properties":{
+	"nickname":{"type":"search_as_you_type"},
+	"username":{"type":"search_as_you_type"}
+}}}`
+
+// roomMapping 房间索引的映射，房间状态变化频繁，不写入索引，由Service按当前状态过滤
+const roomMapping = `{"mappings":{"properties":{
+	"room_name":{"type":"search_as_you_type"},
+	"world_id":{"type":"long"},
+	"is_private":{"type":"boolean"}
+}}}`
+
+// Elasticsearch 使用Elasticsearch搜索，支持前缀和拼写容错。
+// 文档ID为玩家ID或房间ID，玩家注册、改名和创建房间时写入，已有数据需另行导入
+type Elasticsearch struct {
+	url       string
+	username  string
+	password  string
+	userIndex string
+	roomIndex string
+	client    *http.Client
+}
+
+// NewElasticsearch 创建Elasticsearch搜索后端，索引不存在时按映射创建
+func NewElasticsearch(config *ElasticsearchConfig) (*Elasticsearch, error) {
+	timeout := time.Duration(config.TimeoutSec) * time.Second
+	if timeout <= 0 {
+		timeout = defaultElasticsearchTimeout
+	}
+	es := &Elasticsearch{
+		url:       strings.TrimRight(config.URL, "/"),
+		username:  config.Username,
+		password:  config.Password,
+		userIndex: config.UserIndex,
+		roomIndex: config.RoomIndex,
+		client:    &http.Client{Timeout: timeout},
+	}
+	if es.userIndex == "" {
+		es.userIndex = defaultUserIndex
+	}
+	if es.roomIndex == "" {
+		es.roomIndex = defaultRoomIndex
+	}
+
+	if err := es.ensureIndex(es.userIndex, userMapping); err != nil {
+		return nil, err
+	}
+	if err := es.ensureIndex(es.roomIndex, roomMapping); err != nil {
+		return nil, err
+	}
+	return es, nil
+}
+
+// ensureIndex 创建索引，已存在时忽略
+func (es *Elasticsearch) ensureIndex(index, mapping string) error {
+	status, _, err := es.do(http.MethodHead, "/"+index, nil)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusOK {
+		return nil
+	}
+	status, body, err := es.do(http.MethodPut, "/"+index, []byte(mapping))
+	if err != nil {
+		return err
+	}
+	if status >= 300 && !strings.Contains(string(body), "resource_already_exists_exception") {
+		return fmt.Errorf("failed to create search index %s: %d %s", index, status, body)
+	}
+	return nil
+}
+
+// Users 按昵称或用户名搜索玩家，前缀匹配和拼写容错匹配任一满足即可
+func (es *Elasticsearch) Users(query string, limit int) ([]database.NameMatch, error) {
+	return es.search(es.userIndex, map[string]interface{}{
+		"bool": map[string]interface{}{
+			"should": []interface{}{
+				prefixQuery(query, "nickname", "username"),
+				fuzzyQuery(query, "nickname^3", "username"),
+			},
+			"minimum_should_match": 1,
+		},
+	}, limit)
+}
+
+// Rooms 按房间名搜索世界内的公开房间
+func (es *Elasticsearch) Rooms(query string, worldID uint32, limit int) ([]database.NameMatch, error) {
+	return es.search(es.roomIndex, map[string]interface{}{
+		"bool": map[string]interface{}{
+			"filter": []interface{}{
+				map[string]interface{}{"term": map[string]interface{}{"world_id": worldID}},
+				map[string]interface{}{"term": map[string]interface{}{"is_private": false}},
+			},
+			"should": []interface{}{
+				prefixQuery(query, "room_name"),
+				fuzzyQuery(query, "room_name"),
+			},
+			"minimum_should_match": 1,
+		},
+	}, limit)
+}
+
+// IndexUser 写入玩家的名称
+func (es *Elasticsearch) IndexUser(user *database.User) error {
+	return es.index(es.userIndex, user.UserID, map[string]interface{}{
+		"nickname": user.Nickname,
+		"username": user.Username,
+	})
+}
+
+// IndexRoom 写入房间名
+func (es *Elasticsearch) IndexRoom(room *database.Room) error {
+	return es.index(es.roomIndex, room.RoomID, map[string]interface{}{
+		"room_name":  room.RoomName,
+		"world_id":   room.WorldID,
+		"is_private": room.IsPrivate,
+	})
+}
+
+// prefixQuery search_as_you_type字段的前缀匹配
+func prefixQuery(query string, fields ...string) map[string]interface{} {
+	expanded := make([]string, 0, len(fields)*3)
+	for _, field := range fields {
+		expanded = append(expanded, field, field+"._2gram", field+"._3gram")
+	}
+	return map[string]interface{}{
+		"multi_match": map[string]interface{}{
+			"query":  query,
+			"type":   "bool_prefix",
+			"fields": expanded,
+		},
+	}
+}
+
+// fuzzyQuery 允许拼写错误的匹配，错误字符数按词长自动确定
+func fuzzyQuery(query string, fields ...string) map[string]interface{} {
+	return map[string]interface{}{
+		"multi_match": map[string]interface{}{
+			"query":     query,
+			"fields":    fields,
+			"fuzziness": "AUTO",
+		},
+	}
+}
+
+// search 执行搜索，只返回文档ID和相关度
+func (es *Elasticsearch) search(index string, query map[string]interface{}, limit int) ([]database.NameMatch, error) {
+	request, err := json.Marshal(map[string]interface{}{
+		"size":    limit,
+		"_source": false,
+		"query":   query,
+	})
+	if err != nil {
+		return nil, err
+	}
+	status, body, err := es.do(http.MethodPost, "/"+index+"/_search", request)
+	if err != nil {
+		return nil, err
+	}
+	if status >= 300 {
+		return nil, fmt.Errorf("search %s returned %d: %s", index, status, body)
+	}
+
+	var result struct {
+		Hits struct {
+			Hits []struct {
+				ID    string  `json:"_id"`
+				Score float64 `json:"_score"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode search response: %v", err)
+	}
+
+	matches := make([]database.NameMatch, 0, len(result.Hits.Hits))
+	for _, hit := range result.Hits.Hits {
+		id, err := strconv.ParseUint(hit.ID, 10, 64)
+		if err != nil {
+			continue
+		}
+		matches = append(matches, database.NameMatch{ID: id, Score: hit.Score})
+	}
+	return matches, nil
+}
+
+// index 写入或覆盖文档
+func (es *Elasticsearch) index(index string, id uint64, doc map[string]interface{}) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	status, body, err := es.do(http.MethodPut, fmt.Sprintf("/%s/_doc/%d", index, id), data)
+	if err != nil {
+		return err
+	}
+	if status >= 300 {
+		return fmt.Errorf("index %s/%d returned %d: %s", index, id, status, body)
+	}
+	return nil
+}
+
+// do 发送请求，返回状态码和响应内容
+func (es *Elasticsearch) do(method, path string, body []byte) (int, []byte, error) {
+	req, err := http.NewRequest(method, es.url+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to create search request: %v", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if es.username != "" {
+		req.SetBasicAuth(es.username, es.password)
+	}
+
+	resp, err := es.client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("search request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read search response: %v", err)
+	}
+	return resp.StatusCode, data, nil
+}
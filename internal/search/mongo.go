@@ -0,0 +1,19 @@
+package search
+
+import "github.com/phuhao00/lufy/internal/database"
+
+// MongoBackend 使用MongoDB搜索：名称前缀匹配(不区分大小写)加文本索引的分词匹配，不支持拼写容错
+type MongoBackend struct {
+	users *database.UserRepository
+	rooms *database.RoomRepository
+}
+
+// Users 按昵称或用户名搜索玩家
+func (mb *MongoBackend) Users(query string, limit int) ([]database.NameMatch, error) {
+	return mb.users.SearchByName(query, int64(limit))
+}
+
+// Rooms 按房间名搜索世界内的房间
+func (mb *MongoBackend) Rooms(query string, worldID uint32, limit int) ([]database.NameMatch, error) {
+	return mb.rooms.SearchByName(query, worldID, int64(limit))
+}
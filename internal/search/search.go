@@ -0,0 +1,350 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/phuhao00/lufy/internal/chatpolicy"
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/settings"
+)
+
+// ErrQueryTooShort 搜索词短于min_query_length
+var ErrQueryTooShort = errors.New("search query too short")
+
+// 搜索后端类型
+const (
+	BackendMongo         = "mongo"         // MongoDB文本索引加名称前缀匹配
+	BackendElasticsearch = "elasticsearch" // 支持前缀和拼写容错，需同步玩家和房间数据
+)
+
+// 搜索默认设置
+const (
+	defaultPageSize       = 20
+	defaultMaxPageSize    = 50
+	defaultMaxResults     = 100
+	defaultMinQueryLength = 2
+)
+
+// Config 搜索配置
+type Config struct {
+	Backend        string              `yaml:"backend"`          // mongo或elasticsearch，为空时为mongo
+	PageSize       int                 `yaml:"page_size"`        // 未指定时每页条数，未配置时为20
+	MaxPageSize    int                 `yaml:"max_page_size"`    // 每页最大条数，未配置时为50
+	MaxResults     int                 `yaml:"max_results"`      // 每次搜索的候选数，翻页不超过该范围，未配置时为100
+	MinQueryLength int                 `yaml:"min_query_length"` // 搜索词最少字符数，未配置时为2
+	Elasticsearch  ElasticsearchConfig `yaml:"elasticsearch"`
+}
+
+// Validate 检查搜索配置
+func (c *Config) Validate() error {
+	switch c.Backend {
+	case "", BackendMongo:
+	case BackendElasticsearch:
+		if c.Elasticsearch.URL == "" {
+			return fmt.Errorf("elasticsearch url is required")
+		}
+	default:
+		return fmt.Errorf("unsupported search backend: %s", c.Backend)
+	}
+	if c.PageSize < 0 || c.MaxPageSize < 0 || c.MaxResults < 0 || c.MinQueryLength < 0 {
+		return fmt.Errorf("search limits must not be negative")
+	}
+	return nil
+}
+
+// withDefaults 补齐未配置的项
+func (c Config) withDefaults() Config {
+	if c.PageSize <= 0 {
+		c.PageSize = defaultPageSize
+	}
+	if c.MaxPageSize <= 0 {
+		c.MaxPageSize = defaultMaxPageSize
+	}
+	if c.MaxResults <= 0 {
+		c.MaxResults = defaultMaxResults
+	}
+	if c.MinQueryLength <= 0 {
+		c.MinQueryLength = defaultMinQueryLength
+	}
+	return c
+}
+
+// Backend 搜索后端，返回按相关度排列的候选ID，候选在Service中重新读取并过滤
+type Backend interface {
+	Users(query string, limit int) ([]database.NameMatch, error)
+	Rooms(query string, worldID uint32, limit int) ([]database.NameMatch, error)
+}
+
+// Indexer 需要同步数据的搜索后端实现该接口，玩家注册、改名和创建房间时写入
+type Indexer interface {
+	IndexUser(user *database.User) error
+	IndexRoom(room *database.Room) error
+}
+
+// NewBackend 根据配置创建搜索后端
+func NewBackend(config *Config, users *database.UserRepository, rooms *database.RoomRepository) (Backend, error) {
+	switch config.Backend {
+	case "", BackendMongo:
+		return &MongoBackend{users: users, rooms: rooms}, nil
+	case BackendElasticsearch:
+		return NewElasticsearch(&config.Elasticsearch)
+	default:
+		return nil, fmt.Errorf("unsupported search backend: %s", config.Backend)
+	}
+}
+
+// Service 按名称搜索玩家和房间。后端返回的候选按匹配程度重新排序，并过滤封禁的玩家、
+// 隐私设置不允许被搜索到的玩家以及已开始、已结束或私密的房间
+type Service struct {
+	config   Config
+	backend  Backend
+	users    *database.UserRepository
+	rooms    *database.RoomRepository
+	friends  *database.FriendRepository
+	settings *settings.Service
+}
+
+// NewService 创建搜索服务
+func NewService(config *Config, backend Backend, users *database.UserRepository, rooms *database.RoomRepository,
+	friends *database.FriendRepository, settings *settings.Service) *Service {
+	return &Service{
+		config:   config.withDefaults(),
+		backend:  backend,
+		users:    users,
+		rooms:    rooms,
+		friends:  friends,
+		settings: settings,
+	}
+}
+
+// Page 一页搜索结果
+type Page[T any] struct {
+	Items   []T
+	HasMore bool
+}
+
+// Users 搜索玩家，offset为已返回的条数，limit为0时使用默认每页条数
+func (s *Service) Users(viewerID uint64, query string, offset, limit int) (*Page[*database.User], error) {
+	query, limit, err := s.prepare(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	page := &Page[*database.User]{}
+	if offset < 0 || offset >= s.config.MaxResults {
+		return page, nil
+	}
+
+	matches, err := s.backend.Users(query, s.config.MaxResults)
+	if err != nil || len(matches) == 0 {
+		return page, err
+	}
+	users, err := s.users.GetByUserIDs(ids(matches))
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ranked[*database.User], 0, len(users))
+	scores := scoreByID(matches)
+	for _, user := range users {
+		if user.Status != 0 {
+			continue
+		}
+		tier := max(matchTier(query, user.Nickname), matchTier(query, user.Username))
+		results = append(results, rankedItem(user, tier, scores[user.UserID], float64(user.Level), user.UserID))
+	}
+	sortRanked(results)
+
+	// 按排序结果逐个检查隐私设置，够一页即停止，避免读取所有候选的设置
+	var friends map[uint64]bool
+	visible := 0
+	for _, r := range results {
+		user := r.item
+		if user.UserID != viewerID {
+			if friends == nil {
+				if friends, err = s.friendSet(viewerID); err != nil {
+					return nil, err
+				}
+			}
+			allowed, err := s.settings.Allows(user.UserID, settings.ActionSearchable, viewerID, friends[user.UserID])
+			if err != nil {
+				logger.Warn(fmt.Sprintf("Failed to check search privacy of user %d: %v", user.UserID, err))
+				continue
+			}
+			if !allowed {
+				continue
+			}
+		}
+		if visible >= offset {
+			if len(page.Items) == limit {
+				page.HasMore = true
+				break
+			}
+			page.Items = append(page.Items, user)
+		}
+		visible++
+	}
+	return page, nil
+}
+
+// Rooms 搜索世界内等待中的公开房间
+func (s *Service) Rooms(worldID uint32, query string, offset, limit int) (*Page[*database.Room], error) {
+	query, limit, err := s.prepare(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	page := &Page[*database.Room]{}
+	if offset < 0 || offset >= s.config.MaxResults {
+		return page, nil
+	}
+
+	matches, err := s.backend.Rooms(query, worldID, s.config.MaxResults)
+	if err != nil || len(matches) == 0 {
+		return page, err
+	}
+	rooms, err := s.rooms.GetRoomsByIDs(ids(matches))
+	if err != nil {
+		return nil, err
+	}
+
+	// 后端的数据可能已过期，按房间当前状态再过滤一次
+	results := make([]ranked[*database.Room], 0, len(rooms))
+	scores := scoreByID(matches)
+	for _, room := range rooms {
+		if room.Status != 0 || room.IsPrivate || room.WorldID != worldID {
+			continue
+		}
+		tier := matchTier(query, room.RoomName)
+		results = append(results, rankedItem(room, tier, scores[room.RoomID], float64(room.CurrentPlayers), room.RoomID))
+	}
+	sortRanked(results)
+
+	if offset < len(results) {
+		end := min(offset+limit, len(results))
+		for _, r := range results[offset:end] {
+			page.Items = append(page.Items, r.item)
+		}
+		page.HasMore = end < len(results)
+	}
+	return page, nil
+}
+
+// IndexUser 同步玩家的名称到需要同步数据的搜索后端，失败只打印日志
+func (s *Service) IndexUser(user *database.User) {
+	if indexer, ok := s.backend.(Indexer); ok {
+		if err := indexer.IndexUser(user); err != nil {
+			logger.Warn(fmt.Sprintf("Failed to index user %d for search: %v", user.UserID, err))
+		}
+	}
+}
+
+// IndexRoom 同步房间名到需要同步数据的搜索后端，失败只打印日志
+func (s *Service) IndexRoom(room *database.Room) {
+	if indexer, ok := s.backend.(Indexer); ok {
+		if err := indexer.IndexRoom(room); err != nil {
+			logger.Warn(fmt.Sprintf("Failed to index room %d for search: %v", room.RoomID, err))
+		}
+	}
+}
+
+// prepare 检查搜索词长度并限制每页条数
+func (s *Service) prepare(query string, limit int) (string, int, error) {
+	query = strings.TrimSpace(query)
+	if utf8.RuneCountInString(query) < s.config.MinQueryLength {
+		return "", 0, fmt.Errorf("%w: at least %d characters", ErrQueryTooShort, s.config.MinQueryLength)
+	}
+	if limit <= 0 {
+		limit = s.config.PageSize
+	}
+	return query, min(limit, s.config.MaxPageSize), nil
+}
+
+// friendSet 玩家的好友
+func (s *Service) friendSet(userID uint64) (map[uint64]bool, error) {
+	friends, err := s.friends.GetFriends(userID)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[uint64]bool, len(friends))
+	for _, friend := range friends {
+		set[friend.FriendID] = true
+	}
+	return set, nil
+}
+
+// 名称与搜索词的匹配程度，忽略大小写、全半角和分隔符
+const (
+	tierOther    = iota // 只由后端的分词或拼写容错匹配
+	tierContains        // 名称包含搜索词
+	tierPrefix          // 名称以搜索词开头
+	tierExact           // 名称与搜索词相同
+)
+
+// matchTier 名称与搜索词的匹配程度
+func matchTier(query, name string) int {
+	q, n := chatpolicy.NormalizeWord(query), chatpolicy.NormalizeWord(name)
+	switch {
+	case q == "":
+		return tierOther
+	case n == q:
+		return tierExact
+	case strings.HasPrefix(n, q):
+		return tierPrefix
+	case strings.Contains(n, q):
+		return tierContains
+	}
+	return tierOther
+}
+
+// ranked 排序中的搜索结果
+type ranked[T any] struct {
+	item  T
+	tier  int
+	score float64 // 后端的相关度
+	boost float64 // 匹配程度和相关度相同时的排序依据，如玩家等级、房间人数
+	id    uint64
+}
+
+// rankedItem 创建排序中的搜索结果
+func rankedItem[T any](item T, tier int, score, boost float64, id uint64) ranked[T] {
+	return ranked[T]{item: item, tier: tier, score: score, boost: boost, id: id}
+}
+
+// sortRanked 按匹配程度、相关度、boost排序，都相同时按ID排序保证翻页稳定
+func sortRanked[T any](items []ranked[T]) {
+	sort.Slice(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if a.tier != b.tier {
+			return a.tier > b.tier
+		}
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.boost != b.boost {
+			return a.boost > b.boost
+		}
+		return a.id < b.id
+	})
+}
+
+// ids 候选的ID
+func ids(matches []database.NameMatch) []uint64 {
+	result := make([]uint64, len(matches))
+	for i, match := range matches {
+		result[i] = match.ID
+	}
+	return result
+}
+
+// scoreByID 候选的相关度
+func scoreByID(matches []database.NameMatch) map[uint64]float64 {
+	scores := make(map[uint64]float64, len(matches))
+	for _, match := range matches {
+		scores[match.ID] = match.Score
+	}
+	return scores
+}
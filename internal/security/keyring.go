@@ -0,0 +1,172 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 加密字段前缀，格式为 enc:v<版本>:<Base64密文>
+const encryptedFieldPrefix = "enc:v"
+
+// FieldEncryptionConfig 字段加密配置
+type FieldEncryptionConfig struct {
+	Enabled        bool             `yaml:"enabled"`
+	CurrentVersion uint32           `yaml:"current_version"` // 新数据使用的密钥版本
+	Keys           []FieldKeyConfig `yaml:"keys"`
+	IndexKey       string           `yaml:"index_key"` // Base64编码的盲索引密钥，至少32字节，不随加密密钥轮换，更换后需重建盲索引
+}
+
+// FieldKeyConfig 字段加密密钥
+type FieldKeyConfig struct {
+	Version uint32 `yaml:"version"`
+	Key     string `yaml:"key"` // Base64编码的32字节密钥
+}
+
+// KeyRing 带版本的字段加密密钥环
+// 旧版本密钥仅用于解密，轮换时新增密钥并切换当前版本即可
+type KeyRing struct {
+	keys     map[uint32]*EncryptionManager
+	current  uint32
+	indexKey []byte
+	mutex    sync.RWMutex
+}
+
+// NewKeyRing 根据配置创建密钥环
+func NewKeyRing(config *FieldEncryptionConfig) (*KeyRing, error) {
+	kr := &KeyRing{
+		keys: make(map[uint32]*EncryptionManager),
+	}
+
+	for _, keyConfig := range config.Keys {
+		key, err := base64.StdEncoding.DecodeString(keyConfig.Key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key for version %d: %v", keyConfig.Version, err)
+		}
+		if err := kr.AddKey(keyConfig.Version, key); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := kr.SetCurrentVersion(config.CurrentVersion); err != nil {
+		return nil, err
+	}
+
+	indexKey, err := base64.StdEncoding.DecodeString(config.IndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid index key: %v", err)
+	}
+	if len(indexKey) < 32 {
+		return nil, fmt.Errorf("index key must be at least 32 bytes")
+	}
+	kr.indexKey = indexKey
+
+	return kr, nil
+}
+
+// AddKey 添加指定版本的密钥
+func (kr *KeyRing) AddKey(version uint32, key []byte) error {
+	if version == 0 {
+		return fmt.Errorf("key version must be positive")
+	}
+
+	em, err := NewEncryptionManager(key)
+	if err != nil {
+		return fmt.Errorf("invalid key for version %d: %v", version, err)
+	}
+
+	kr.mutex.Lock()
+	kr.keys[version] = em
+	kr.mutex.Unlock()
+	return nil
+}
+
+// SetCurrentVersion 切换加密使用的密钥版本
+func (kr *KeyRing) SetCurrentVersion(version uint32) error {
+	kr.mutex.Lock()
+	defer kr.mutex.Unlock()
+
+	if _, exists := kr.keys[version]; !exists {
+		return fmt.Errorf("key version %d not found", version)
+	}
+	kr.current = version
+	return nil
+}
+
+// CurrentVersion 获取当前密钥版本
+func (kr *KeyRing) CurrentVersion() uint32 {
+	kr.mutex.RLock()
+	defer kr.mutex.RUnlock()
+	return kr.current
+}
+
+// EncryptField 使用当前版本密钥加密字段
+func (kr *KeyRing) EncryptField(plaintext string) (string, error) {
+	kr.mutex.RLock()
+	version := kr.current
+	em := kr.keys[version]
+	kr.mutex.RUnlock()
+
+	ciphertext, err := em.EncryptString(plaintext)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%d:%s", encryptedFieldPrefix, version, ciphertext), nil
+}
+
+// DecryptField 解密字段，未加密的旧数据原样返回
+func (kr *KeyRing) DecryptField(value string) (string, error) {
+	version, ciphertext, ok := parseEncryptedField(value)
+	if !ok {
+		return value, nil
+	}
+
+	kr.mutex.RLock()
+	em, exists := kr.keys[version]
+	kr.mutex.RUnlock()
+	if !exists {
+		return "", fmt.Errorf("key version %d not found", version)
+	}
+
+	return em.DecryptString(ciphertext)
+}
+
+// NeedsReencrypt 判断字段是否为明文或使用了旧版本密钥
+func (kr *KeyRing) NeedsReencrypt(value string) bool {
+	if value == "" {
+		return false
+	}
+	version, _, ok := parseEncryptedField(value)
+	return !ok || version != kr.CurrentVersion()
+}
+
+// BlindIndex 计算字段的盲索引，用于等值查询和唯一约束。
+// 加密字段的密文是随机的，相同的明文需按盲索引查询；值不区分大小写和首尾空白
+func (kr *KeyRing) BlindIndex(value string) string {
+	mac := hmac.New(sha256.New, kr.indexKey)
+	mac.Write([]byte(strings.ToLower(strings.TrimSpace(value))))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// parseEncryptedField 解析加密字段的版本和密文
+func parseEncryptedField(value string) (uint32, string, bool) {
+	if !strings.HasPrefix(value, encryptedFieldPrefix) {
+		return 0, "", false
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(value, encryptedFieldPrefix), ":", 2)
+	if len(parts) != 2 {
+		return 0, "", false
+	}
+
+	version, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return 0, "", false
+	}
+	return uint32(version), parts[1], true
+}
@@ -0,0 +1,180 @@
+package security
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// 登录失败原因
+const (
+	AuthFailureUnknownUser = "unknown_user"
+	AuthFailureBadPassword = "bad_password"
+	AuthFailureThrottled   = "throttled"
+	AuthFailureBlockedIP   = "blocked_ip"
+)
+
+// LoginGuardConfig 登录防护配置
+type LoginGuardConfig struct {
+	FreeAttempts    int           `yaml:"free_attempts"`     // 不延迟的连续失败次数
+	BaseDelay       time.Duration `yaml:"base_delay"`        // 首次延迟，之后每次失败翻倍
+	MaxDelay        time.Duration `yaml:"max_delay"`         // 最大延迟
+	FailureWindow   time.Duration `yaml:"failure_window"`    // IP失败统计窗口
+	IPMaxFailures   int           `yaml:"ip_max_failures"`   // 窗口内IP最大失败次数
+	IPMaxAccounts   int           `yaml:"ip_max_accounts"`   // 窗口内IP尝试的最大账号数（撞库特征）
+	IPBlockDuration time.Duration `yaml:"ip_block_duration"` // IP封禁时长
+}
+
+// LoginGuard 登录防护，按账号递增延迟，按IP识别撞库并加入黑名单。
+// 失败计数和延迟保存在Redis中，多个登录节点共享，封禁写入共享的IP黑名单；Redis不可用时不限制
+type LoginGuard struct {
+	config    LoginGuardConfig
+	redis     *database.RedisManager
+	blacklist *IPBlacklist
+	failures  map[string]int64 // 本节点按原因统计的失败次数
+	blocks    int64
+	mutex     sync.Mutex
+}
+
+// NewLoginGuard 创建登录防护
+func NewLoginGuard(config *LoginGuardConfig, redis *database.RedisManager, blacklist *IPBlacklist) *LoginGuard {
+	cfg := *config
+	if cfg.FreeAttempts <= 0 {
+		cfg.FreeAttempts = 3
+	}
+	if cfg.BaseDelay <= 0 {
+		cfg.BaseDelay = time.Second
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = 15 * time.Minute
+	}
+	if cfg.FailureWindow <= 0 {
+		cfg.FailureWindow = 10 * time.Minute
+	}
+	if cfg.IPMaxFailures <= 0 {
+		cfg.IPMaxFailures = 50
+	}
+	if cfg.IPMaxAccounts <= 0 {
+		cfg.IPMaxAccounts = 10
+	}
+	if cfg.IPBlockDuration <= 0 {
+		cfg.IPBlockDuration = time.Hour
+	}
+
+	return &LoginGuard{
+		config:    cfg,
+		redis:     redis,
+		blacklist: blacklist,
+		failures:  make(map[string]int64),
+	}
+}
+
+// Check 登录前检查，返回非空错误时应直接拒绝
+func (lg *LoginGuard) Check(username, ip string) error {
+	if ip != "" && lg.blacklist.IsBlocked(ip) {
+		lg.recordReason(AuthFailureBlockedIP)
+		return fmt.Errorf("ip is blocked")
+	}
+
+	if wait, err := lg.redis.TTL(lg.throttleKey(username)); err == nil && wait > 0 {
+		lg.recordReason(AuthFailureThrottled)
+		return fmt.Errorf("too many failed attempts, retry after %d seconds", int(wait.Seconds())+1)
+	}
+
+	return nil
+}
+
+// RecordFailure 记录登录失败
+func (lg *LoginGuard) RecordFailure(username, ip, reason string) {
+	lg.recordReason(reason)
+
+	// 账号递增延迟，连续失败次数在最后一次失败后保留MaxDelay
+	countKey := lg.accountKey(username)
+	count, err := lg.redis.Incr(countKey)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Failed to count login failures for %s: %v", username, err))
+		return
+	}
+	lg.redis.Expire(countKey, lg.config.MaxDelay)
+	if over := count - int64(lg.config.FreeAttempts); over > 0 {
+		delay := lg.config.MaxDelay
+		if over <= 62 {
+			if d := lg.config.BaseDelay << uint(over-1); d > 0 && d < delay {
+				delay = d
+			}
+		}
+		lg.redis.Set(lg.throttleKey(username), "1", delay)
+	}
+
+	// IP撞库识别，窗口从第一次失败开始计算
+	if ip == "" {
+		return
+	}
+	failuresKey := fmt.Sprintf("login:ip_failures:%s", ip)
+	accountsKey := fmt.Sprintf("login:ip_accounts:%s", ip)
+	failures, err := lg.redis.Incr(failuresKey)
+	if err != nil {
+		return
+	}
+	if failures == 1 {
+		lg.redis.Expire(failuresKey, lg.config.FailureWindow)
+	}
+	lg.redis.SAdd(accountsKey, username)
+	if failures == 1 {
+		lg.redis.Expire(accountsKey, lg.config.FailureWindow)
+	}
+	accounts, _ := lg.redis.SCard(accountsKey)
+
+	if failures >= int64(lg.config.IPMaxFailures) || accounts >= int64(lg.config.IPMaxAccounts) {
+		logger.Warn(fmt.Sprintf("Credential stuffing suspected from %s: %d failures across %d accounts",
+			ip, failures, accounts))
+		lg.redis.Delete(failuresKey, accountsKey)
+		lg.blacklist.BlockIP(ip, lg.config.IPBlockDuration)
+
+		lg.mutex.Lock()
+		lg.blocks++
+		lg.mutex.Unlock()
+	}
+}
+
+// RecordSuccess 登录成功后清除账号失败记录
+func (lg *LoginGuard) RecordSuccess(username string) {
+	lg.redis.Delete(lg.accountKey(username), lg.throttleKey(username))
+}
+
+// GetMetrics 获取本节点的登录失败统计
+func (lg *LoginGuard) GetMetrics() map[string]interface{} {
+	lg.mutex.Lock()
+	defer lg.mutex.Unlock()
+
+	failures := make(map[string]int64, len(lg.failures))
+	for reason, count := range lg.failures {
+		failures[reason] = count
+	}
+
+	return map[string]interface{}{
+		"failures":  failures,
+		"ip_blocks": lg.blocks,
+		"timestamp": time.Now().Unix(),
+	}
+}
+
+// recordReason 只统计失败原因
+func (lg *LoginGuard) recordReason(reason string) {
+	lg.mutex.Lock()
+	lg.failures[reason]++
+	lg.mutex.Unlock()
+}
+
+// accountKey 账号连续失败次数的键
+func (lg *LoginGuard) accountKey(username string) string {
+	return fmt.Sprintf("login:failures:%s", username)
+}
+
+// throttleKey 账号延迟登录的键，过期后允许再次尝试
+func (lg *LoginGuard) throttleKey(username string) string {
+	return fmt.Sprintf("login:throttled:%s", username)
+}
@@ -0,0 +1,103 @@
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ErrOAuthProvider 未配置的第三方登录平台
+var ErrOAuthProvider = errors.New("unsupported oauth provider")
+
+// OAuthProviderConfig 第三方登录平台配置，使用平台的用户信息接口校验访问令牌
+type OAuthProviderConfig struct {
+	UserInfoURL  string `yaml:"userinfo_url"`  // 用户信息接口，以Bearer方式携带访问令牌
+	SubjectField string `yaml:"subject_field"` // 返回JSON中用户唯一标识的字段，未配置时为sub
+}
+
+// OAuthConfig 第三方登录配置
+type OAuthConfig struct {
+	Providers map[string]OAuthProviderConfig `yaml:"providers"` // 按平台名称配置，如google、apple
+	Timeout   time.Duration                  `yaml:"timeout"`   // 请求用户信息接口的超时，未配置时为5秒
+}
+
+// OAuthVerifier 校验客户端取得的第三方访问令牌，返回平台上的用户标识
+type OAuthVerifier struct {
+	providers map[string]OAuthProviderConfig
+	client    *http.Client
+}
+
+// NewOAuthVerifier 创建第三方登录校验
+func NewOAuthVerifier(config *OAuthConfig) *OAuthVerifier {
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+
+	providers := make(map[string]OAuthProviderConfig, len(config.Providers))
+	for name, provider := range config.Providers {
+		if provider.UserInfoURL == "" {
+			continue
+		}
+		if provider.SubjectField == "" {
+			provider.SubjectField = "sub"
+		}
+		providers[name] = provider
+	}
+
+	return &OAuthVerifier{
+		providers: providers,
+		client:    &http.Client{Timeout: timeout},
+	}
+}
+
+// Verify 用访问令牌请求平台的用户信息，令牌无效时返回错误
+func (v *OAuthVerifier) Verify(ctx context.Context, provider, accessToken string) (string, error) {
+	config, exists := v.providers[provider]
+	if !exists {
+		return "", fmt.Errorf("%w: %s", ErrOAuthProvider, provider)
+	}
+	if accessToken == "" {
+		return "", fmt.Errorf("missing oauth access token")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, config.UserInfoURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to build oauth request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request %s userinfo: %v", provider, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s userinfo: %v", provider, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s rejected oauth token: status %d", provider, resp.StatusCode)
+	}
+
+	var info map[string]interface{}
+	if err := json.Unmarshal(body, &info); err != nil {
+		return "", fmt.Errorf("failed to parse %s userinfo: %v", provider, err)
+	}
+
+	// 部分平台的用户标识为数字
+	switch subject := info[config.SubjectField].(type) {
+	case string:
+		if subject != "" {
+			return subject, nil
+		}
+	case float64:
+		return fmt.Sprintf("%.0f", subject), nil
+	}
+	return "", fmt.Errorf("%s userinfo missing %s", provider, config.SubjectField)
+}
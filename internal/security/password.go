@@ -0,0 +1,202 @@
+package security
+
+import (
+	"bufio"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// PasswordPolicyConfig 密码策略配置
+type PasswordPolicyConfig struct {
+	MinLength      int     `yaml:"min_length"`
+	MaxLength      int     `yaml:"max_length"`
+	RequireUpper   bool    `yaml:"require_upper"`
+	RequireLower   bool    `yaml:"require_lower"`
+	RequireDigit   bool    `yaml:"require_digit"`
+	RequireSymbol  bool    `yaml:"require_symbol"`
+	BreachedList   string  `yaml:"breached_list"`    // 泄露密码列表文件，每行一个密码
+	BreachedFPRate float64 `yaml:"breached_fp_rate"` // 布隆过滤器误判率
+}
+
+// PasswordPolicy 密码策略
+type PasswordPolicy struct {
+	config   PasswordPolicyConfig
+	breached *BloomFilter
+}
+
+// NewPasswordPolicy 创建密码策略，配置了泄露密码列表时加载到布隆过滤器
+func NewPasswordPolicy(config *PasswordPolicyConfig) (*PasswordPolicy, error) {
+	cfg := *config
+	if cfg.MinLength <= 0 {
+		cfg.MinLength = 8
+	}
+	if cfg.MaxLength <= 0 {
+		cfg.MaxLength = 64
+	}
+	if cfg.BreachedFPRate <= 0 {
+		cfg.BreachedFPRate = 0.001
+	}
+
+	policy := &PasswordPolicy{
+		config: cfg,
+	}
+
+	if cfg.BreachedList != "" {
+		filter, err := loadBreachedPasswords(cfg.BreachedList, cfg.BreachedFPRate)
+		if err != nil {
+			return nil, err
+		}
+		policy.breached = filter
+	}
+
+	return policy, nil
+}
+
+// Validate 校验密码是否满足策略
+func (pp *PasswordPolicy) Validate(username, password string) error {
+	length := len([]rune(password))
+	if length < pp.config.MinLength {
+		return fmt.Errorf("password must be at least %d characters", pp.config.MinLength)
+	}
+	if length > pp.config.MaxLength {
+		return fmt.Errorf("password must be at most %d characters", pp.config.MaxLength)
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+
+	if pp.config.RequireUpper && !hasUpper {
+		return fmt.Errorf("password must contain an uppercase letter")
+	}
+	if pp.config.RequireLower && !hasLower {
+		return fmt.Errorf("password must contain a lowercase letter")
+	}
+	if pp.config.RequireDigit && !hasDigit {
+		return fmt.Errorf("password must contain a digit")
+	}
+	if pp.config.RequireSymbol && !hasSymbol {
+		return fmt.Errorf("password must contain a symbol")
+	}
+
+	if username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		return fmt.Errorf("password must not contain the username")
+	}
+
+	if pp.IsBreached(password) {
+		return fmt.Errorf("password has appeared in a data breach")
+	}
+
+	return nil
+}
+
+// IsBreached 检查密码是否在泄露列表中（可能误判，不会漏判）
+func (pp *PasswordPolicy) IsBreached(password string) bool {
+	if pp.breached == nil {
+		return false
+	}
+	return pp.breached.Test(password)
+}
+
+// loadBreachedPasswords 从文件加载泄露密码
+func loadBreachedPasswords(path string, fpRate float64) (*BloomFilter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %v", err)
+	}
+	defer file.Close()
+
+	passwords := make([]string, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" {
+			passwords = append(passwords, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read breached password list: %v", err)
+	}
+
+	filter := NewBloomFilter(len(passwords), fpRate)
+	for _, password := range passwords {
+		filter.Add(password)
+	}
+
+	logger.Info(fmt.Sprintf("Loaded %d breached passwords", len(passwords)))
+	return filter, nil
+}
+
+// BloomFilter 布隆过滤器
+type BloomFilter struct {
+	bits   []uint64
+	size   uint64
+	hashes uint64
+}
+
+// NewBloomFilter 根据预期元素数和误判率创建布隆过滤器
+func NewBloomFilter(expected int, fpRate float64) *BloomFilter {
+	if expected < 1 {
+		expected = 1
+	}
+
+	n := float64(expected)
+	m := math.Ceil(-n * math.Log(fpRate) / (math.Ln2 * math.Ln2))
+	k := math.Max(1, math.Round(m/n*math.Ln2))
+
+	size := uint64(m)
+	return &BloomFilter{
+		bits:   make([]uint64, (size+63)/64),
+		size:   size,
+		hashes: uint64(k),
+	}
+}
+
+// Add 添加元素
+func (bf *BloomFilter) Add(value string) {
+	h1, h2 := bloomHash(value)
+	for i := uint64(0); i < bf.hashes; i++ {
+		pos := (h1 + i*h2) % bf.size
+		bf.bits[pos/64] |= 1 << (pos % 64)
+	}
+}
+
+// Test 检查元素是否可能存在
+func (bf *BloomFilter) Test(value string) bool {
+	h1, h2 := bloomHash(value)
+	for i := uint64(0); i < bf.hashes; i++ {
+		pos := (h1 + i*h2) % bf.size
+		if bf.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// bloomHash 双重哈希
+func bloomHash(value string) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(value))
+	h1 := h.Sum64()
+
+	h.Write([]byte{0})
+	h2 := h.Sum64() | 1
+
+	return h1, h2
+}
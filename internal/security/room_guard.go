@@ -0,0 +1,128 @@
+package security
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// 私有房间加入被拒绝的原因
+var (
+	ErrRoomJoinThrottled = errors.New("too many wrong room passwords")
+	ErrRoomLocked        = errors.New("room is locked after repeated wrong passwords")
+)
+
+// RoomGuardConfig 私有房间防护配置
+type RoomGuardConfig struct {
+	UserAttempts int64         `yaml:"user_attempts"` // 窗口内每个玩家对同一房间允许输错的次数，未配置时为5
+	RoomFailures int64         `yaml:"room_failures"` // 窗口内房间累计输错的次数，达到后锁定房间，未配置时为20
+	Window       time.Duration `yaml:"window"`        // 输错次数的统计窗口，未配置时为10分钟
+	LockDuration time.Duration `yaml:"lock_duration"` // 房间锁定时长，锁定期间任何人都不能通过密码加入，未配置时为10分钟
+}
+
+// HashRoomPassword 哈希房间密码
+func HashRoomPassword(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// VerifyRoomPassword 验证房间密码，兼容改为哈希存储前创建的明文密码房间
+func VerifyRoomPassword(stored, password string) bool {
+	if strings.HasPrefix(stored, "$2") {
+		return bcrypt.CompareHashAndPassword([]byte(stored), []byte(password)) == nil
+	}
+	return subtle.ConstantTimeCompare([]byte(stored), []byte(password)) == 1
+}
+
+// RoomGuard 私有房间防护，限制每个玩家对同一房间的输错次数，房间累计输错过多时锁定房间。
+// 计数保存在Redis中，多个大厅节点共享，Redis不可用时不限制
+type RoomGuard struct {
+	config RoomGuardConfig
+	redis  *database.RedisManager
+}
+
+// NewRoomGuard 创建私有房间防护
+func NewRoomGuard(config *RoomGuardConfig, redis *database.RedisManager) *RoomGuard {
+	cfg := *config
+	if cfg.UserAttempts <= 0 {
+		cfg.UserAttempts = 5
+	}
+	if cfg.RoomFailures <= 0 {
+		cfg.RoomFailures = 20
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 10 * time.Minute
+	}
+	if cfg.LockDuration <= 0 {
+		cfg.LockDuration = 10 * time.Minute
+	}
+	return &RoomGuard{config: cfg, redis: redis}
+}
+
+// Check 验证密码前检查，房间锁定或玩家输错过多时返回错误
+func (rg *RoomGuard) Check(roomID, userID uint64) error {
+	if _, err := rg.redis.GetString(rg.lockKey(roomID)); err == nil {
+		return ErrRoomLocked
+	}
+	if value, err := rg.redis.GetString(rg.userKey(roomID, userID)); err == nil {
+		if failures, _ := strconv.ParseInt(value, 10, 64); failures >= rg.config.UserAttempts {
+			return ErrRoomJoinThrottled
+		}
+	}
+	return nil
+}
+
+// RecordFailure 记录输错的密码，房间累计输错达到上限时锁定房间
+func (rg *RoomGuard) RecordFailure(roomID, userID uint64) {
+	rg.count(rg.userKey(roomID, userID))
+
+	roomKey := fmt.Sprintf("room:password_failures:%d", roomID)
+	if failures := rg.count(roomKey); failures >= rg.config.RoomFailures {
+		if err := rg.redis.Set(rg.lockKey(roomID), strconv.FormatUint(userID, 10), rg.config.LockDuration); err != nil {
+			logger.Warn(fmt.Sprintf("Failed to lock room %d: %v", roomID, err))
+			return
+		}
+		rg.redis.Delete(roomKey)
+		logger.Warn(fmt.Sprintf("Room %d locked for %s after %d wrong passwords, last by user %d",
+			roomID, rg.config.LockDuration, failures, userID))
+	}
+}
+
+// Reset 密码正确后清除玩家的输错次数
+func (rg *RoomGuard) Reset(roomID, userID uint64) {
+	rg.redis.Delete(rg.userKey(roomID, userID))
+}
+
+// count 递增窗口内的计数，首次计数时设置过期时间
+func (rg *RoomGuard) count(key string) int64 {
+	count, err := rg.redis.Incr(key)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Failed to count room password failures %s: %v", key, err))
+		return 0
+	}
+	if count == 1 {
+		rg.redis.Expire(key, rg.config.Window)
+	}
+	return count
+}
+
+// userKey 玩家对房间输错次数的键
+func (rg *RoomGuard) userKey(roomID, userID uint64) string {
+	return fmt.Sprintf("room:password_failures:%d:%d", roomID, userID)
+}
+
+// lockKey 房间锁定的键
+func (rg *RoomGuard) lockKey(roomID uint64) string {
+	return fmt.Sprintf("room:locked:%d", roomID)
+}
@@ -0,0 +1,117 @@
+package security
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// schemaCache 按请求结构类型缓存解析后的参数结构
+var schemaCache sync.Map
+
+// StructSchema 按结构体的json和validate标签生成参数结构，未导出或json为"-"的字段忽略。
+// validate标签以逗号分隔：required、min=N、max=N、max_length=N、context=text|identifier|html|url，
+// 参数类型由字段类型决定，生成的结构拒绝未声明的参数
+func StructSchema(t reflect.Type) (*ParamSchema, error) {
+	if cached, ok := schemaCache.Load(t); ok {
+		return cached.(*ParamSchema), nil
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("request type %s is not a struct", t)
+	}
+
+	schema := &ParamSchema{Fields: make(map[string]ParamRule, t.NumField()), Strict: true}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		rule := ParamRule{Type: paramType(field.Type)}
+		if tag := field.Tag.Get("validate"); tag != "" {
+			if err := parseRuleTag(&rule, tag); err != nil {
+				return nil, fmt.Errorf("%s.%s: %v", t, field.Name, err)
+			}
+		}
+		schema.Fields[name] = rule
+	}
+
+	schemaCache.Store(t, schema)
+	return schema, nil
+}
+
+// rawMessageType json.RawMessage可以是任意参数类型
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+// paramType 字段类型对应的参数类型
+func paramType(t reflect.Type) string {
+	if t == rawMessageType {
+		return ParamTypeAny
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return ParamTypeString
+	case reflect.Bool:
+		return ParamTypeBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return ParamTypeNumber
+	case reflect.Map, reflect.Struct:
+		return ParamTypeObject
+	case reflect.Slice, reflect.Array:
+		return ParamTypeArray
+	}
+	return ParamTypeAny
+}
+
+// parseRuleTag 解析validate标签
+func parseRuleTag(rule *ParamRule, tag string) error {
+	for _, option := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(option), "=")
+		switch key {
+		case "required":
+			rule.Required = true
+		case "min", "max":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %s", key, value)
+			}
+			if key == "min" {
+				rule.Min = &n
+			} else {
+				rule.Max = &n
+			}
+		case "max_length":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid max_length: %s", value)
+			}
+			rule.MaxLength = n
+		case "context":
+			switch value {
+			case ContextText, ContextIdentifier, ContextHTML, ContextURL:
+				rule.Context = value
+			default:
+				return fmt.Errorf("unknown context: %s", value)
+			}
+		case "":
+		default:
+			return fmt.Errorf("unknown validate option: %s", key)
+		}
+	}
+	return nil
+}
@@ -18,7 +18,9 @@ import (
 	"github.com/go-playground/validator/v10"
 	"golang.org/x/crypto/bcrypt"
 
+	"github.com/phuhao00/lufy/internal/database"
 	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/tuning"
 )
 
 // SecurityManager 安全管理器
@@ -61,9 +63,10 @@ type RateLimiter struct {
 	maxRequests int
 }
 
-// IPBlacklist IP黑名单
+// IPBlacklist IP黑名单，设置Redis后封禁在所有节点间共享，本地只缓存已知的封禁
 type IPBlacklist struct {
 	blocked map[string]time.Time
+	redis   *database.RedisManager
 	mutex   sync.RWMutex
 }
 
@@ -365,8 +368,8 @@ func (rlm *RateLimitManager) CheckLimit(key string, maxRequests int, window time
 		limiter.lastReset = now
 	}
 
-	// 检查是否超限
-	if limiter.requests >= limiter.maxRequests {
+	// 检查是否超限，上限按运行时调整的限流倍数计算
+	if int64(limiter.requests) >= tuning.ScaleLimit(int64(limiter.maxRequests)) {
 		return false
 	}
 
@@ -381,43 +384,70 @@ func NewIPBlacklist() *IPBlacklist {
 	}
 }
 
+// NewSharedIPBlacklist 创建保存在Redis中的IP黑名单，任一节点的封禁对所有节点生效，Redis不可用时只在本节点生效
+func NewSharedIPBlacklist(redis *database.RedisManager) *IPBlacklist {
+	blacklist := NewIPBlacklist()
+	blacklist.redis = redis
+	return blacklist
+}
+
 // IsBlocked 检查IP是否被阻止
 func (bl *IPBlacklist) IsBlocked(ip string) bool {
-	bl.mutex.RLock()
-	defer bl.mutex.RUnlock()
+	bl.mutex.Lock()
+	expireTime, exists := bl.blocked[ip]
+	if exists && time.Now().After(expireTime) {
+		// 检查是否已过期
+		delete(bl.blocked, ip)
+		exists = false
+	}
+	bl.mutex.Unlock()
 
-	blockTime, exists := bl.blocked[ip]
-	if !exists {
-		return false
+	if exists || bl.redis == nil {
+		return exists
 	}
 
-	// 检查是否已过期
-	if time.Since(blockTime) > 24*time.Hour {
-		delete(bl.blocked, ip)
+	// 其他节点的封禁，缓存到本地直到过期
+	ttl, err := bl.redis.TTL(ipBlockKey(ip))
+	if err != nil || ttl <= 0 {
 		return false
 	}
-
+	bl.mutex.Lock()
+	bl.blocked[ip] = time.Now().Add(ttl)
+	bl.mutex.Unlock()
 	return true
 }
 
 // BlockIP 阻止IP
 func (bl *IPBlacklist) BlockIP(ip string, duration time.Duration) {
 	bl.mutex.Lock()
-	defer bl.mutex.Unlock()
-
 	bl.blocked[ip] = time.Now().Add(duration)
+	bl.mutex.Unlock()
+
+	if bl.redis != nil {
+		if err := bl.redis.Set(ipBlockKey(ip), "1", duration); err != nil {
+			logger.Warn(fmt.Sprintf("Failed to share IP block %s: %v", ip, err))
+		}
+	}
 	logger.Warn(fmt.Sprintf("IP blocked: %s for %v", ip, duration))
 }
 
-// UnblockIP 解除IP阻止
+// UnblockIP 解除IP阻止，其他节点缓存的封禁在过期前仍然有效
 func (bl *IPBlacklist) UnblockIP(ip string) {
 	bl.mutex.Lock()
-	defer bl.mutex.Unlock()
-
 	delete(bl.blocked, ip)
+	bl.mutex.Unlock()
+
+	if bl.redis != nil {
+		bl.redis.Delete(ipBlockKey(ip))
+	}
 	logger.Info(fmt.Sprintf("IP unblocked: %s", ip))
 }
 
+// ipBlockKey IP封禁的键
+func ipBlockKey(ip string) string {
+	return fmt.Sprintf("security:ip_blocked:%s", ip)
+}
+
 // NewAntiCheatSystem 创建反作弊系统
 func NewAntiCheatSystem() *AntiCheatSystem {
 	acs := &AntiCheatSystem{
@@ -550,6 +580,14 @@ func (acs *AntiCheatSystem) CheckCheat(userID uint64) (bool, []string) {
 	return len(detectedPatterns) > 0, detectedPatterns
 }
 
+// SetBlacklist 替换IP黑名单，用于与登录防护等其他组件共享同一个黑名单，需在处理请求前调用
+func (sm *SecurityManager) SetBlacklist(blacklist *IPBlacklist) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	sm.blacklist = blacklist
+}
+
 // ValidateInput 验证输入数据
 func (sm *SecurityManager) ValidateInput(data interface{}) error {
 	return sm.validator.Struct(data)
@@ -594,33 +632,31 @@ func isPrivateIP(ip string) bool {
 	return false
 }
 
-// SanitizeInput 清理输入数据
+// SanitizeInput 清理输入数据，对HTML特殊字符编码而不是删除子串
 func (sm *SecurityManager) SanitizeInput(input string) string {
-	// 移除潜在的恶意字符
-	dangerous := []string{
-		"<script", "</script>", "javascript:", "onload=", "onerror=",
-		"<iframe", "</iframe>", "eval(", "alert(", "confirm(",
-	}
-
-	sanitized := input
-	for _, danger := range dangerous {
-		sanitized = strings.ReplaceAll(sanitized, danger, "")
-		sanitized = strings.ReplaceAll(sanitized, strings.ToUpper(danger), "")
-	}
-
-	return strings.TrimSpace(sanitized)
+	return EncodeOutput(strings.TrimSpace(input))
 }
 
 // GenerateSignature 生成数据签名
 func (sm *SecurityManager) GenerateSignature(data []byte) string {
-	mac := hmac.New(sha256.New, sm.jwtSecret)
-	mac.Write(data)
-	return hex.EncodeToString(mac.Sum(nil))
+	return sm.GenerateSignatureWithKey(sm.jwtSecret, data)
 }
 
 // VerifySignature 验证数据签名
 func (sm *SecurityManager) VerifySignature(data []byte, signature string) bool {
-	expectedSignature := sm.GenerateSignature(data)
+	return sm.VerifySignatureWithKey(sm.jwtSecret, data, signature)
+}
+
+// GenerateSignatureWithKey 使用指定密钥（如会话密钥）生成数据签名
+func (sm *SecurityManager) GenerateSignatureWithKey(key, data []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignatureWithKey 使用指定密钥验证数据签名
+func (sm *SecurityManager) VerifySignatureWithKey(key, data []byte, signature string) bool {
+	expectedSignature := sm.GenerateSignatureWithKey(key, data)
 	return hmac.Equal([]byte(signature), []byte(expectedSignature))
 }
 
@@ -0,0 +1,342 @@
+package security
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// 参数类型
+const (
+	ParamTypeString = "string"
+	ParamTypeNumber = "number"
+	ParamTypeBool   = "bool"
+	ParamTypeObject = "object"
+	ParamTypeArray  = "array"
+	ParamTypeAny    = "any"
+)
+
+// 字符串参数的使用场景，规则按场景生效
+const (
+	ContextText       = "text"       // 自由文本，如聊天、昵称，输出时编码
+	ContextIdentifier = "identifier" // 标识符，如操作类型、模块名
+	ContextHTML       = "html"       // 会被渲染为富文本的内容，如公告
+	ContextURL        = "url"
+)
+
+// 规则命中后的处理
+const (
+	RuleActionReject = "reject"
+	RuleActionLog    = "log"
+)
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z0-9_.\-]+$`)
+
+// ParamRule 参数校验规则
+type ParamRule struct {
+	Type      string
+	Required  bool
+	Context   string // 字符串参数场景，默认text
+	MaxLength int
+	Min       *float64
+	Max       *float64
+}
+
+// ParamSchema 方法的参数结构
+type ParamSchema struct {
+	Fields map[string]ParamRule
+	Strict bool // 拒绝未声明的参数
+}
+
+// InputRuleConfig 输入规则配置
+type InputRuleConfig struct {
+	Name     string   `yaml:"name"`
+	Pattern  string   `yaml:"pattern"`
+	Contexts []string `yaml:"contexts"` // 生效场景
+	Action   string   `yaml:"action"`   // reject, log
+}
+
+// AllowlistConfig 规则例外配置，Method/Param为空表示匹配全部
+type AllowlistConfig struct {
+	Rule   string `yaml:"rule"`
+	Method string `yaml:"method"`
+	Param  string `yaml:"param"`
+}
+
+// InputValidationConfig 输入校验配置
+type InputValidationConfig struct {
+	MaxParams      int               `yaml:"max_params"`
+	MaxStringBytes int               `yaml:"max_string_bytes"`
+	MaxDepth       int               `yaml:"max_depth"`
+	Rules          []InputRuleConfig `yaml:"rules"`     // 追加到默认规则
+	Allowlist      []AllowlistConfig `yaml:"allowlist"` // 规则例外
+}
+
+// inputRule 编译后的规则
+type inputRule struct {
+	name     string
+	pattern  *regexp.Regexp
+	contexts map[string]bool
+	action   string
+}
+
+// defaultInputRules 默认规则，只针对会被渲染的场景；普通文本依靠输出编码
+var defaultInputRules = []InputRuleConfig{
+	{Name: "control_chars", Pattern: `[\x00-\x08\x0B\x0C\x0E-\x1F\x7F]`, Contexts: []string{ContextText, ContextIdentifier, ContextHTML, ContextURL}, Action: RuleActionReject},
+	{Name: "script_tag", Pattern: `(?i)<\s*/?\s*(script|iframe|object|embed)\b`, Contexts: []string{ContextHTML}, Action: RuleActionReject},
+	{Name: "event_handler", Pattern: `(?i)<[^>]*\bon[a-z]+\s*=`, Contexts: []string{ContextHTML}, Action: RuleActionReject},
+	{Name: "script_uri", Pattern: `(?i)^\s*(javascript|vbscript|data)\s*:`, Contexts: []string{ContextHTML, ContextURL}, Action: RuleActionReject},
+}
+
+// InputValidator 基于参数结构和场景的输入校验
+type InputValidator struct {
+	config    InputValidationConfig
+	rules     []inputRule
+	allowlist []AllowlistConfig
+	schemas   map[string]*ParamSchema
+	mutex     sync.RWMutex
+}
+
+// NewInputValidator 创建输入校验器
+func NewInputValidator(config *InputValidationConfig) (*InputValidator, error) {
+	cfg := *config
+	if cfg.MaxParams <= 0 {
+		cfg.MaxParams = 50
+	}
+	if cfg.MaxStringBytes <= 0 {
+		cfg.MaxStringBytes = 10000
+	}
+	if cfg.MaxDepth <= 0 {
+		cfg.MaxDepth = 5
+	}
+
+	iv := &InputValidator{
+		config:    cfg,
+		allowlist: cfg.Allowlist,
+		schemas:   make(map[string]*ParamSchema),
+	}
+
+	for _, ruleConfig := range append(append([]InputRuleConfig{}, defaultInputRules...), cfg.Rules...) {
+		pattern, err := regexp.Compile(ruleConfig.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for rule %s: %v", ruleConfig.Name, err)
+		}
+
+		contexts := make(map[string]bool)
+		for _, c := range ruleConfig.Contexts {
+			contexts[c] = true
+		}
+
+		action := ruleConfig.Action
+		if action == "" {
+			action = RuleActionReject
+		}
+
+		iv.rules = append(iv.rules, inputRule{
+			name:     ruleConfig.Name,
+			pattern:  pattern,
+			contexts: contexts,
+			action:   action,
+		})
+	}
+
+	return iv, nil
+}
+
+// RegisterSchema 注册方法的参数结构
+func (iv *InputValidator) RegisterSchema(method string, schema *ParamSchema) {
+	iv.mutex.Lock()
+	defer iv.mutex.Unlock()
+
+	iv.schemas[method] = schema
+}
+
+// Validate 校验方法参数
+func (iv *InputValidator) Validate(method string, params map[string]interface{}) error {
+	iv.mutex.RLock()
+	schema := iv.schemas[method]
+	iv.mutex.RUnlock()
+
+	return iv.ValidateSchema(method, schema, params)
+}
+
+// ValidateSchema 按指定的参数结构校验方法参数，schema为nil时只做通用校验
+func (iv *InputValidator) ValidateSchema(method string, schema *ParamSchema, params map[string]interface{}) error {
+	if len(params) > iv.config.MaxParams {
+		return fmt.Errorf("too many parameters: %d", len(params))
+	}
+
+	if schema != nil {
+		for name, rule := range schema.Fields {
+			if _, exists := params[name]; !exists && rule.Required {
+				return fmt.Errorf("missing required parameter: %s", name)
+			}
+		}
+	}
+
+	for key, value := range params {
+		if err := iv.validateKey(key); err != nil {
+			return err
+		}
+
+		rule := ParamRule{Type: ParamTypeAny, Context: ContextText}
+		if schema != nil {
+			declared, exists := schema.Fields[key]
+			if !exists && schema.Strict {
+				return fmt.Errorf("unexpected parameter: %s", key)
+			}
+			if exists {
+				rule = declared
+			}
+		}
+
+		if err := iv.validateValue(method, key, value, rule, 0); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateKey 校验参数名，拒绝$开头的键以防止MongoDB操作符注入
+func (iv *InputValidator) validateKey(key string) error {
+	if len(key) == 0 || len(key) > 100 {
+		return fmt.Errorf("invalid parameter key length")
+	}
+	if strings.HasPrefix(key, "$") || strings.Contains(key, "\x00") {
+		return fmt.Errorf("invalid parameter key: %q", key)
+	}
+	return nil
+}
+
+// validateValue 按规则校验参数值
+func (iv *InputValidator) validateValue(method, key string, value interface{}, rule ParamRule, depth int) error {
+	if depth > iv.config.MaxDepth {
+		return fmt.Errorf("parameter nested too deep: %s", key)
+	}
+
+	switch v := value.(type) {
+	case string:
+		if rule.Type != ParamTypeAny && rule.Type != ParamTypeString {
+			return fmt.Errorf("parameter %s must be %s", key, rule.Type)
+		}
+		return iv.validateString(method, key, v, rule)
+
+	case float64:
+		if rule.Type != ParamTypeAny && rule.Type != ParamTypeNumber {
+			return fmt.Errorf("parameter %s must be %s", key, rule.Type)
+		}
+		if rule.Min != nil && v < *rule.Min {
+			return fmt.Errorf("parameter %s below minimum", key)
+		}
+		if rule.Max != nil && v > *rule.Max {
+			return fmt.Errorf("parameter %s above maximum", key)
+		}
+		if v < -1e15 || v > 1e15 {
+			return fmt.Errorf("numeric value out of range for key: %s", key)
+		}
+
+	case bool:
+		if rule.Type != ParamTypeAny && rule.Type != ParamTypeBool {
+			return fmt.Errorf("parameter %s must be %s", key, rule.Type)
+		}
+
+	case map[string]interface{}:
+		if rule.Type != ParamTypeAny && rule.Type != ParamTypeObject {
+			return fmt.Errorf("parameter %s must be %s", key, rule.Type)
+		}
+		if len(v) > iv.config.MaxParams {
+			return fmt.Errorf("too many fields in parameter: %s", key)
+		}
+		nested := ParamRule{Type: ParamTypeAny, Context: rule.Context}
+		for k, item := range v {
+			if err := iv.validateKey(k); err != nil {
+				return fmt.Errorf("%s: %v", key, err)
+			}
+			if err := iv.validateValue(method, key+"."+k, item, nested, depth+1); err != nil {
+				return err
+			}
+		}
+
+	case []interface{}:
+		if rule.Type != ParamTypeAny && rule.Type != ParamTypeArray {
+			return fmt.Errorf("parameter %s must be %s", key, rule.Type)
+		}
+		if rule.MaxLength > 0 && len(v) > rule.MaxLength {
+			return fmt.Errorf("parameter %s has too many items", key)
+		}
+		nested := ParamRule{Type: ParamTypeAny, Context: rule.Context}
+		for _, item := range v {
+			if err := iv.validateValue(method, key, item, nested, depth+1); err != nil {
+				return err
+			}
+		}
+
+	case nil:
+		if rule.Required {
+			return fmt.Errorf("missing required parameter: %s", key)
+		}
+	}
+
+	return nil
+}
+
+// validateString 按场景校验字符串
+func (iv *InputValidator) validateString(method, key, value string, rule ParamRule) error {
+	if len(value) > iv.config.MaxStringBytes {
+		return fmt.Errorf("parameter value too long for key: %s", key)
+	}
+	if rule.MaxLength > 0 && len([]rune(value)) > rule.MaxLength {
+		return fmt.Errorf("parameter value too long for key: %s", key)
+	}
+
+	context := rule.Context
+	if context == "" {
+		context = ContextText
+	}
+	if context == ContextIdentifier && value != "" && !identifierPattern.MatchString(value) {
+		return fmt.Errorf("parameter %s must be an identifier", key)
+	}
+
+	for _, r := range iv.rules {
+		if !r.contexts[context] || !r.pattern.MatchString(value) {
+			continue
+		}
+		if iv.isAllowed(r.name, method, key) {
+			continue
+		}
+		if r.action == RuleActionLog {
+			logger.Warn(fmt.Sprintf("Input rule %s matched %s.%s", r.name, method, key))
+			continue
+		}
+		return fmt.Errorf("parameter %s rejected by rule %s", key, r.name)
+	}
+
+	return nil
+}
+
+// isAllowed 检查是否命中规则例外
+func (iv *InputValidator) isAllowed(rule, method, key string) bool {
+	for _, entry := range iv.allowlist {
+		if entry.Rule != "" && entry.Rule != rule {
+			continue
+		}
+		if entry.Method != "" && entry.Method != method {
+			continue
+		}
+		if entry.Param != "" && entry.Param != key {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// EncodeOutput 对展示给其他玩家的文本进行HTML编码
+func EncodeOutput(content string) string {
+	return html.EscapeString(content)
+}
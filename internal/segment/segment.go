@@ -0,0 +1,357 @@
+package segment
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// SegmentAll 内置分群，包含所有玩家
+const SegmentAll = "all"
+
+// defaultCacheTTL 分群结果默认缓存时间
+const defaultCacheTTL = 10 * time.Minute
+
+// Config 玩家分群配置，分群可以作为邮件活动、定向公告、功能开关和A/B实验的目标
+type Config struct {
+	Cache       time.Duration       `yaml:"cache"` // 玩家分群结果的缓存时间，未配置时为10分钟
+	Segments    []Segment           `yaml:"segments"`
+	Flags       map[string][]string `yaml:"flags"` // 功能开关对哪些分群开启，开关名会转为小写
+	Experiments []Experiment        `yaml:"experiments"`
+}
+
+// Segment 分群条件，未配置的条件不检查，所有条件都满足时属于该分群
+type Segment struct {
+	Name     string        `yaml:"name"`
+	Level    Range         `yaml:"level"`
+	Spend    Range         `yaml:"spend"`    // 累计消耗的钻石
+	Active   time.Duration `yaml:"active"`   // 最近这段时间内登录过
+	Inactive time.Duration `yaml:"inactive"` // 超过这段时间没有登录
+	Locales  []string      `yaml:"locales"`  // 客户端语言，按前缀匹配，zh匹配zh-CN
+}
+
+// Range 闭区间，0为不限制
+type Range struct {
+	Min int64 `yaml:"min"`
+	Max int64 `yaml:"max"`
+}
+
+// Experiment A/B实验，分群内的玩家按用户ID稳定地分到各个组
+type Experiment struct {
+	Name     string   `yaml:"name"`
+	Segment  string   `yaml:"segment"` // 参与实验的分群，未配置时为所有玩家
+	Variants []string `yaml:"variants"`
+}
+
+// Validate 检查分群配置
+func (c *Config) Validate() error {
+	names := map[string]bool{SegmentAll: true}
+	for _, segment := range c.Segments {
+		if segment.Name == "" {
+			return fmt.Errorf("segment name is required")
+		}
+		if names[segment.Name] {
+			return fmt.Errorf("duplicate segment: %s", segment.Name)
+		}
+		names[segment.Name] = true
+
+		for _, r := range []Range{segment.Level, segment.Spend} {
+			if r.Min < 0 || r.Max < 0 || (r.Max > 0 && r.Max < r.Min) {
+				return fmt.Errorf("segment %s has invalid range [%d, %d]", segment.Name, r.Min, r.Max)
+			}
+		}
+		if segment.Active < 0 || segment.Inactive < 0 {
+			return fmt.Errorf("segment %s login durations must not be negative", segment.Name)
+		}
+		if segment.Active > 0 && segment.Inactive >= segment.Active {
+			return fmt.Errorf("segment %s can never match: inactive %v >= active %v", segment.Name, segment.Inactive, segment.Active)
+		}
+	}
+
+	for flag, segments := range c.Flags {
+		for _, name := range segments {
+			if !names[name] {
+				return fmt.Errorf("flag %s targets unknown segment: %s", flag, name)
+			}
+		}
+	}
+
+	experiments := make(map[string]bool, len(c.Experiments))
+	for _, experiment := range c.Experiments {
+		if experiment.Name == "" {
+			return fmt.Errorf("experiment name is required")
+		}
+		if experiments[experiment.Name] {
+			return fmt.Errorf("duplicate experiment: %s", experiment.Name)
+		}
+		experiments[experiment.Name] = true
+		if experiment.Segment != "" && !names[experiment.Segment] {
+			return fmt.Errorf("experiment %s targets unknown segment: %s", experiment.Name, experiment.Segment)
+		}
+		if len(experiment.Variants) < 2 {
+			return fmt.Errorf("experiment %s requires at least 2 variants", experiment.Name)
+		}
+	}
+	return nil
+}
+
+// Match 检查玩家是否满足分群条件
+func (s *Segment) Match(user *database.User, now time.Time) bool {
+	if !s.Level.contains(int64(user.Level)) || !s.Spend.contains(user.Spent) {
+		return false
+	}
+	if s.Active > 0 && user.LastLoginAt.Before(now.Add(-s.Active)) {
+		return false
+	}
+	if s.Inactive > 0 && user.LastLoginAt.After(now.Add(-s.Inactive)) {
+		return false
+	}
+	if len(s.Locales) == 0 {
+		return true
+	}
+	language := strings.ToLower(user.Language)
+	for _, locale := range s.Locales {
+		if strings.HasPrefix(language, strings.ToLower(locale)) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains 检查数值是否在区间内
+func (r Range) contains(value int64) bool {
+	if r.Min > 0 && value < r.Min {
+		return false
+	}
+	if r.Max > 0 && value > r.Max {
+		return false
+	}
+	return true
+}
+
+// UserSource 用户数据来源，database.UserRepository实现了该接口
+type UserSource interface {
+	GetByUserID(userID uint64) (*database.User, error)
+	ForEach(fn func(user *database.User) error) error
+}
+
+// Service 玩家分群服务，玩家所属的分群缓存在Redis中，配置变化后缓存自动失效
+type Service struct {
+	segments    map[string]*Segment
+	order       []string
+	flags       map[string][]string
+	experiments map[string]*Experiment
+	users       UserSource
+	redis       *database.RedisManager
+	ttl         time.Duration
+	version     string
+}
+
+// NewService 创建玩家分群服务
+func NewService(config *Config, users UserSource, redis *database.RedisManager) *Service {
+	s := &Service{
+		segments:    make(map[string]*Segment, len(config.Segments)),
+		flags:       make(map[string][]string, len(config.Flags)),
+		experiments: make(map[string]*Experiment, len(config.Experiments)),
+		users:       users,
+		redis:       redis,
+		ttl:         config.Cache,
+	}
+	if s.ttl <= 0 {
+		s.ttl = defaultCacheTTL
+	}
+
+	for i := range config.Segments {
+		segment := &config.Segments[i]
+		s.segments[segment.Name] = segment
+		s.order = append(s.order, segment.Name)
+	}
+	for flag, segments := range config.Flags {
+		s.flags[strings.ToLower(flag)] = segments
+	}
+	for i := range config.Experiments {
+		s.experiments[config.Experiments[i].Name] = &config.Experiments[i]
+	}
+
+	// 缓存键包含分群条件的指纹，修改条件后不会读到旧的分群结果
+	hash := fnv.New32a()
+	fmt.Fprintf(hash, "%v", config.Segments)
+	s.version = fmt.Sprintf("%08x", hash.Sum32())
+	return s
+}
+
+// Segments 获取玩家所属的分群，不包括内置的all
+func (s *Service) Segments(userID uint64) ([]string, error) {
+	key := fmt.Sprintf("segment:%s:%d", s.version, userID)
+	if cached, err := s.redis.GetString(key); err == nil {
+		if cached == "" {
+			return nil, nil
+		}
+		return strings.Split(cached, ","), nil
+	}
+
+	user, err := s.users.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user %d: %v", userID, err)
+	}
+	segments := s.match(user, time.Now())
+
+	if err := s.redis.Set(key, strings.Join(segments, ","), s.ttl); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to cache segments of user %d: %v", userID, err))
+	}
+	return segments, nil
+}
+
+// match 按配置顺序返回玩家满足的分群
+func (s *Service) match(user *database.User, now time.Time) []string {
+	var segments []string
+	for _, name := range s.order {
+		if s.segments[name].Match(user, now) {
+			segments = append(segments, name)
+		}
+	}
+	return segments
+}
+
+// InSegment 检查玩家是否属于分群
+func (s *Service) InSegment(userID uint64, name string) (bool, error) {
+	if name == SegmentAll {
+		return true, nil
+	}
+	if _, exists := s.segments[name]; !exists {
+		return false, fmt.Errorf("unknown segment: %s", name)
+	}
+
+	segments, err := s.Segments(userID)
+	if err != nil {
+		return false, err
+	}
+	for _, segment := range segments {
+		if segment == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Flags 获取玩家的所有功能开关
+func (s *Service) Flags(userID uint64) (map[string]bool, error) {
+	flags := make(map[string]bool, len(s.flags))
+	if len(s.flags) == 0 {
+		return flags, nil
+	}
+
+	segments, err := s.Segments(userID)
+	if err != nil {
+		return nil, err
+	}
+	member := map[string]bool{SegmentAll: true}
+	for _, segment := range segments {
+		member[segment] = true
+	}
+
+	for flag, targets := range s.flags {
+		flags[flag] = false
+		for _, target := range targets {
+			if member[target] {
+				flags[flag] = true
+				break
+			}
+		}
+	}
+	return flags, nil
+}
+
+// Enabled 检查功能开关是否对玩家开启，未配置的开关为关闭
+func (s *Service) Enabled(userID uint64, flag string) (bool, error) {
+	flags, err := s.Flags(userID)
+	if err != nil {
+		return false, err
+	}
+	return flags[strings.ToLower(flag)], nil
+}
+
+// Variants 获取玩家参与的所有实验分组，不在实验分群内的玩家不返回该实验
+func (s *Service) Variants(userID uint64) (map[string]string, error) {
+	variants := make(map[string]string, len(s.experiments))
+	for name, experiment := range s.experiments {
+		if experiment.Segment != "" {
+			member, err := s.InSegment(userID, experiment.Segment)
+			if err != nil {
+				return nil, err
+			}
+			if !member {
+				continue
+			}
+		}
+		variants[name] = assign(experiment, userID)
+	}
+	return variants, nil
+}
+
+// Variant 获取玩家在实验中的分组，不参与实验时返回空字符串
+func (s *Service) Variant(userID uint64, name string) (string, error) {
+	experiment, exists := s.experiments[name]
+	if !exists {
+		return "", fmt.Errorf("unknown experiment: %s", name)
+	}
+	if experiment.Segment != "" {
+		member, err := s.InSegment(userID, experiment.Segment)
+		if err != nil || !member {
+			return "", err
+		}
+	}
+	return assign(experiment, userID), nil
+}
+
+// assign 按实验名和用户ID哈希分组，同一玩家在同一实验中的分组固定不变
+func assign(experiment *Experiment, userID uint64) string {
+	hash := fnv.New32a()
+	fmt.Fprintf(hash, "%s:%d", experiment.Name, userID)
+	return experiment.Variants[hash.Sum32()%uint32(len(experiment.Variants))]
+}
+
+// MatchUser 使用玩家的实时数据判断是否属于分群，不读写缓存
+func (s *Service) MatchUser(name string, user *database.User, now time.Time) (bool, error) {
+	if name == SegmentAll {
+		return true, nil
+	}
+	segment, exists := s.segments[name]
+	if !exists {
+		return false, fmt.Errorf("unknown segment: %s", name)
+	}
+	return segment.Match(user, now), nil
+}
+
+// Members 遍历分群内的所有玩家，使用实时数据判断而不是缓存，用于定向公告
+func (s *Service) Members(name string, fn func(userID uint64) error) error {
+	if !s.Has(name) {
+		return fmt.Errorf("unknown segment: %s", name)
+	}
+
+	now := time.Now()
+	return s.users.ForEach(func(user *database.User) error {
+		if member, _ := s.MatchUser(name, user, now); !member {
+			return nil
+		}
+		return fn(user.UserID)
+	})
+}
+
+// Has 检查分群是否存在
+func (s *Service) Has(name string) bool {
+	_, exists := s.segments[name]
+	return exists || name == SegmentAll
+}
+
+// Names 获取所有分群名，包括内置的all
+func (s *Service) Names() []string {
+	names := append([]string{SegmentAll}, s.order...)
+	sort.Strings(names[1:])
+	return names
+}
@@ -0,0 +1,234 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/security"
+	"github.com/phuhao00/lufy/pkg/proto"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// 游客账号设置
+const (
+	guestUsernamePrefix = "guest_" // 游客账号由系统生成的用户名前缀，注册时不可使用
+	minGuestIDLength    = 16       // 游客ID由客户端随机生成，过短时容易被猜中
+	maxGuestIDLength    = 128
+)
+
+// guestKey 游客ID的哈希，数据库中不保存原始游客ID
+func guestKey(guestID string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte("lufy_guest:"+guestID)))
+}
+
+// GuestLogin 游客登录，游客ID首次登录时创建临时账号；已绑定的游客ID不能再登录
+func (ls *LoginService) GuestLogin(ctx context.Context, req *proto.LoginRequest) (*proto.LoginResponse, error) {
+	if !ls.server.config.Security.GuestLogin {
+		return nil, fmt.Errorf("guest login is disabled")
+	}
+
+	guestID := req.GetGuestId()
+	if len(guestID) < minGuestIDLength || len(guestID) > maxGuestIDLength {
+		return nil, fmt.Errorf("invalid guest id")
+	}
+	key := guestKey(guestID)
+
+	if err := ls.server.loginGuard.Check(key, req.GetClientIp()); err != nil {
+		logger.Warn(fmt.Sprintf("Guest login rejected from %s: %v", req.GetClientIp(), err))
+		return nil, err
+	}
+	if err := ls.checkMaintenance(); err != nil {
+		return nil, err
+	}
+
+	user, err := ls.server.userRepo.GetByGuestKey(key)
+	if err != nil {
+		user, err = ls.createGuest(key, req)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// 绑定后游客ID保留在账号上，防止再次创建游客账号或以游客身份登录
+	if !user.Guest {
+		logger.Warn(fmt.Sprintf("Guest login rejected for linked user %d", user.UserID))
+		return nil, fmt.Errorf("guest account has been linked, please log in with the linked account")
+	}
+
+	return ls.completeLogin(user, req)
+}
+
+// createGuest 创建游客账号，用户名由系统生成
+func (ls *LoginService) createGuest(key string, req *proto.LoginRequest) (*database.User, error) {
+	worldID := ls.server.config.World.Resolve(req.GetWorldId())
+	if err := ls.server.config.World.ValidateRegistration(worldID); err != nil {
+		logger.Warn(fmt.Sprintf("Guest registration rejected: %v", err))
+		return nil, err
+	}
+
+	userID := uint64(time.Now().UnixNano())
+	user := ls.newUser(userID, fmt.Sprintf("%s%d", guestUsernamePrefix, userID), worldID, req.GetLanguage())
+	user.Nickname = fmt.Sprintf("Guest%06d", userID%1000000)
+	user.Guest = true
+	user.GuestKey = key
+
+	if err := ls.server.userRepo.Create(user); err != nil {
+		logger.Error(fmt.Sprintf("Failed to create guest user: %v", err))
+		return nil, fmt.Errorf("failed to create guest user")
+	}
+	ls.server.search.IndexUser(user)
+
+	logger.Info(fmt.Sprintf("Guest user created: %d (world: %d)", userID, worldID))
+	return user, nil
+}
+
+// OAuthLogin 使用已绑定的第三方账号登录
+func (ls *LoginService) OAuthLogin(ctx context.Context, req *proto.LoginRequest) (*proto.LoginResponse, error) {
+	provider := req.GetOauthProvider()
+	if err := ls.server.loginGuard.Check(provider, req.GetClientIp()); err != nil {
+		logger.Warn(fmt.Sprintf("OAuth login rejected from %s: %v", req.GetClientIp(), err))
+		return nil, err
+	}
+	if err := ls.checkMaintenance(); err != nil {
+		return nil, err
+	}
+
+	subject, err := ls.server.oauth.Verify(ctx, provider, req.GetOauthToken())
+	if err != nil {
+		logger.Warn(fmt.Sprintf("OAuth verification failed for %s: %v", provider, err))
+		return nil, fmt.Errorf("invalid oauth credentials")
+	}
+
+	user, err := ls.server.userRepo.GetByOAuth(provider, subject)
+	if err != nil {
+		return nil, fmt.Errorf("oauth account is not linked, please link it from a guest account first")
+	}
+
+	return ls.completeLogin(user, req)
+}
+
+// LinkAccount 把当前登录的游客账号绑定到用户名密码或第三方账号。
+// 绑定在同一个用户文档上原子完成，游客期间的等级、货币、邮件等进度都保留在原用户ID下
+func (ls *LoginService) LinkAccount(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	userID := req.Header.GetUserId()
+	sessionCache := database.NewSessionCache(ls.server.redisManager)
+	if sessionUserID, err := sessionCache.GetSession(req.Header.GetSessionId()); err != nil || sessionUserID != userID {
+		return &proto.BaseResponse{
+			Header: req.Header,
+			Code:   -2,
+			Msg:    "invalid session",
+		}, nil
+	}
+
+	var linkReq proto.LoginRequest
+	if err := proto.Unmarshal(req.Data, &linkReq); err != nil {
+		return &proto.BaseResponse{
+			Header: req.Header,
+			Code:   -1,
+			Msg:    "invalid request",
+		}, nil
+	}
+
+	identity, method, err := ls.linkIdentity(ctx, &linkReq)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Link account rejected for user %d: %v", userID, err))
+		return &proto.BaseResponse{
+			Header: req.Header,
+			Code:   -1,
+			Msg:    err.Error(),
+		}, nil
+	}
+
+	upgraded, err := ls.server.userRepo.UpgradeGuest(userID, identity)
+	if errors.Is(err, database.ErrIdentityTaken) {
+		return &proto.BaseResponse{
+			Header: req.Header,
+			Code:   -4,
+			Msg:    "username or oauth account is already in use",
+		}, nil
+	}
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to link account for user %d: %v", userID, err))
+		return &proto.BaseResponse{
+			Header: req.Header,
+			Code:   -1,
+			Msg:    "failed to link account",
+		}, nil
+	}
+	if !upgraded {
+		return &proto.BaseResponse{
+			Header: req.Header,
+			Code:   -3,
+			Msg:    "account is not a guest account",
+		}, nil
+	}
+
+	user, err := ls.server.userRepo.GetByUserID(userID)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to reload linked user %d: %v", userID, err))
+		return &proto.BaseResponse{
+			Header: req.Header,
+			Code:   -1,
+			Msg:    "failed to link account",
+		}, nil
+	}
+	ls.server.userCache.SetUserInfo(userID, user)
+	ls.server.search.IndexUser(user)
+
+	logger.Info(fmt.Sprintf("Guest user %d linked via %s", userID, method))
+
+	data, err := proto.Marshal(&proto.LoginResponse{
+		UserId:   user.UserID,
+		Token:    req.Header.GetSessionId(),
+		Nickname: user.Nickname,
+		Level:    user.Level,
+		Exp:      user.Experience,
+		Gold:     user.Gold,
+		Diamond:  user.Diamond,
+		Region:   user.Region,
+		WorldId:  ls.server.config.World.Resolve(user.WorldID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal link response: %v", err)
+	}
+
+	return &proto.BaseResponse{
+		Header: req.Header,
+		Code:   0,
+		Msg:    "account linked",
+		Data:   data,
+	}, nil
+}
+
+// linkIdentity 校验要绑定的用户名密码或第三方账号，返回写入用户文档的字段和绑定方式
+func (ls *LoginService) linkIdentity(ctx context.Context, req *proto.LoginRequest) (bson.M, string, error) {
+	if provider := req.GetOauthProvider(); provider != "" {
+		subject, err := ls.server.oauth.Verify(ctx, provider, req.GetOauthToken())
+		if err != nil {
+			if errors.Is(err, security.ErrOAuthProvider) {
+				return nil, "", err
+			}
+			logger.Warn(fmt.Sprintf("OAuth verification failed for %s: %v", provider, err))
+			return nil, "", fmt.Errorf("invalid oauth credentials")
+		}
+		return bson.M{"oauth_provider": provider, "oauth_subject": subject}, provider, nil
+	}
+
+	username := req.GetUsername()
+	if username == "" {
+		return nil, "", fmt.Errorf("username or oauth provider is required")
+	}
+	if strings.HasPrefix(username, guestUsernamePrefix) {
+		return nil, "", fmt.Errorf("username is reserved")
+	}
+	if err := ls.server.passwordPolicy.Validate(username, req.GetPassword()); err != nil {
+		return nil, "", err
+	}
+	return bson.M{"username": username, "password": ls.hashPassword(req.GetPassword())}, "password", nil
+}
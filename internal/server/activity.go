@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/activity"
+	"github.com/phuhao00/lufy/internal/errcode"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/mq"
+	"github.com/phuhao00/lufy/internal/network"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// activityPushMsgID 活动开始或结束推送的消息ID
+const activityPushMsgID = 2002
+
+// activityInfo 转换为客户端使用的活动信息
+func activityInfo(event *activity.Event) *proto.ActivityInfo {
+	info := &proto.ActivityInfo{
+		Id:          event.ID,
+		Name:        event.Name,
+		Description: event.Description,
+		Multipliers: event.Multipliers,
+		GameTypes:   event.Games,
+	}
+	if !event.Start.IsZero() {
+		info.StartTime = event.Start.Unix()
+	}
+	if !event.End.IsZero() {
+		info.EndTime = event.End.Unix()
+	}
+	return info
+}
+
+// announceActivities 广播开始和结束的活动，由中心服的定时任务执行
+func (cs *CenterServer) announceActivities(ctx context.Context) error {
+	started, ended, err := cs.calendar.Transitions(cs.redisManager, time.Now())
+	for _, event := range started {
+		cs.broadcastActivity(activity.StateStarted, &event)
+	}
+	for _, event := range ended {
+		cs.broadcastActivity(activity.StateEnded, &event)
+	}
+	return err
+}
+
+// broadcastActivity 广播活动状态变化
+func (cs *CenterServer) broadcastActivity(state string, event *activity.Event) {
+	err := cs.messageBroker.BroadcastSystemMessage(mq.SYS_CMD_ACTIVITY, map[string]interface{}{
+		"id":    event.ID,
+		"state": state,
+	})
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to broadcast activity %s %s: %v", event.ID, state, err))
+		return
+	}
+	logger.Info(fmt.Sprintf("Activity %s (%s) %s", event.ID, event.Name, state))
+}
+
+// handleActivity 把活动开始或结束推送给本网关的在线玩家
+func (gs *GatewayServer) handleActivity(msg *mq.SystemMessage) error {
+	id, _ := msg.Args["id"].(string)
+	state, _ := msg.Args["state"].(string)
+
+	push := &proto.ActivityStatePush{State: state, Activity: &proto.ActivityInfo{Id: id}}
+	for _, event := range gs.calendar.Active(time.Now()) {
+		if event.ID == id {
+			push.Activity = activityInfo(&event)
+			break
+		}
+	}
+
+	pushed := 0
+	gs.tcpServer.ForEachConnection(func(conn *network.Connection) bool {
+		if conn.UserID == 0 {
+			return true // 未登录
+		}
+		if err := pushMessage(conn, activityPushMsgID, push); err != nil {
+			logger.Debug(fmt.Sprintf("Failed to push activity to user %d: %v", conn.UserID, err))
+			return true
+		}
+		pushed++
+		return true
+	})
+
+	logger.Info(fmt.Sprintf("Activity %s %s pushed to %d players", id, state, pushed))
+	return nil
+}
+
+// GetActiveEvents 获取进行中的限时活动
+func (ls *LobbyService) GetActiveEvents(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	now := time.Now()
+	resp := &proto.ActiveEventsResponse{ServerTime: now.Unix()}
+	for _, event := range ls.server.calendar.Active(now) {
+		resp.Events = append(resp.Events, activityInfo(&event))
+	}
+
+	data, err := proto.Marshal(resp)
+	if err != nil {
+		logger.Error(fmt.Sprintf("GetActiveEvents: failed to marshal response: %v", err))
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+	return ls.responses.Success(ctx, req.Header, "success", data), nil
+}
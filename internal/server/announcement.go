@@ -0,0 +1,246 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/announcement"
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/errcode"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/mq"
+	"github.com/phuhao00/lufy/internal/network"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// announcementPushMsgID 跑马灯公告开始时推送的消息ID
+const announcementPushMsgID = 2003
+
+// newAnnouncements 创建公告服务
+func (bs *BaseServer) newAnnouncements() *announcement.Service {
+	service := announcement.NewService(&bs.config.Announcement, database.NewAnnouncementRepository(bs.mongoManager))
+	bs.AddWarmup("announcements", func(ctx context.Context) error {
+		return service.Warm(time.Now())
+	})
+	return service
+}
+
+// announcementInfo 转换为客户端使用的公告信息
+func announcementInfo(localized *announcement.Localized) *proto.AnnouncementInfo {
+	return &proto.AnnouncementInfo{
+		Id:        localized.ID,
+		Title:     localized.Title,
+		Content:   localized.Content,
+		Channels:  localized.Channels,
+		Priority:  localized.Priority,
+		StartTime: localized.StartAt.Unix(),
+		EndTime:   localized.EndAt.Unix(),
+	}
+}
+
+// GetAnnouncements 获取进行中的公告，未指定展示位置时返回所有位置的公告
+func (ls *LobbyService) GetAnnouncements(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	announcementsReq, err := Bind[proto.AnnouncementsRequest](req.Data)
+	if err != nil {
+		logger.Error(fmt.Sprintf("GetAnnouncements: failed to unmarshal request: %v", err))
+		return ls.responses.Error(ctx, req.Header, errcode.InvalidRequest), nil
+	}
+
+	now := time.Now()
+	announcements, err := ls.server.announcements.Active(announcementsReq.GetChannel(), req.Header.GetLanguage(), now)
+	if err != nil {
+		logger.Error(fmt.Sprintf("GetAnnouncements: failed to load announcements: %v", err))
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+
+	resp := &proto.AnnouncementsResponse{ServerTime: now.Unix()}
+	for _, localized := range announcements {
+		resp.Announcements = append(resp.Announcements, announcementInfo(localized))
+	}
+
+	data, err := proto.Marshal(resp)
+	if err != nil {
+		logger.Error(fmt.Sprintf("GetAnnouncements: failed to marshal response: %v", err))
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+	return ls.responses.Success(ctx, req.Header, "success", data), nil
+}
+
+// pushAnnouncements 广播已经开始的跑马灯公告，由GM服的定时任务执行，创建立即开始的公告后也会执行一次
+func (gs *GMServer) pushAnnouncements(ctx context.Context) error {
+	due, err := gs.announcements.DuePush(time.Now())
+	for _, a := range due {
+		if err := gs.messageBroker.BroadcastSystemMessage(mq.SYS_CMD_BROADCAST_NOTICE, map[string]interface{}{
+			"id": a.ID.Hex(),
+		}); err != nil {
+			logger.Error(fmt.Sprintf("Failed to broadcast announcement %s: %v", a.ID.Hex(), err))
+			continue
+		}
+		logger.Info(fmt.Sprintf("Announcement %s broadcast", a.ID.Hex()))
+	}
+	return err
+}
+
+// createAnnouncement 创建公告并写入GM操作日志，立即开始的跑马灯马上推送
+func (gs *GMServer) createAnnouncement(gmUserID uint64, a *database.Announcement) error {
+	a.CreatedBy = gmUserID
+	if err := gs.announcements.Create(a); err != nil {
+		return errcode.AnnouncementInvalid.WithDetail(err.Error())
+	}
+
+	content := a.Contents[a.Language]
+	gs.gmRepo.LogGMAction(gmUserID, "create_announcement", 0,
+		fmt.Sprintf("公告: %s, 位置: %v, 开始: %s, 结束: %s, 标题: %s",
+			a.ID.Hex(), a.Channels, a.StartAt.Format(time.RFC3339), a.EndAt.Format(time.RFC3339), content.Title))
+
+	if !a.StartAt.After(time.Now()) {
+		if err := gs.pushAnnouncements(context.Background()); err != nil {
+			logger.Warn(fmt.Sprintf("Failed to push announcement %s: %v", a.ID.Hex(), err))
+		}
+	}
+	return nil
+}
+
+// sendNotice 发送全服跑马灯公告，按配置的时长展示
+func (gs *GMServer) sendNotice(gmUserID uint64, title, content string, priority int32) (*database.Announcement, error) {
+	a := &database.Announcement{
+		Channels: []string{announcement.ChannelMarquee},
+		Contents: map[string]database.AnnouncementContent{
+			gs.announcements.Language(): {Title: title, Content: content},
+		},
+		Priority: priority,
+	}
+	if err := gs.createAnnouncement(gmUserID, a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// deleteAnnouncement 删除公告并写入GM操作日志
+func (gs *GMServer) deleteAnnouncement(gmUserID uint64, announcementID string) error {
+	deleted, err := gs.announcements.Delete(announcementID)
+	if err != nil {
+		return errcode.AnnouncementInvalid.WithDetail(err.Error())
+	}
+	if !deleted {
+		return errcode.AnnouncementNotFound
+	}
+	gs.gmRepo.LogGMAction(gmUserID, "delete_announcement", 0, fmt.Sprintf("公告: %s", announcementID))
+	return nil
+}
+
+// announcementsJSON 公告详情，未指定公告时为最近创建的公告列表
+func (gs *GMServer) announcementsJSON(announcementID string) ([]byte, error) {
+	if announcementID == "" {
+		announcements, err := gs.announcements.List(20)
+		if err != nil {
+			return nil, errcode.Internal.Wrap(err)
+		}
+		return json.Marshal(announcements)
+	}
+
+	a, err := gs.announcements.Get(announcementID)
+	if err != nil {
+		return nil, errcode.AnnouncementInvalid.WithDetail(err.Error())
+	}
+	if a == nil {
+		return nil, errcode.AnnouncementNotFound
+	}
+	return json.Marshal(a)
+}
+
+// CreateAnnouncement 创建定时公告，开始时间和结束时间为Unix秒，未指定时立即开始并按配置的时长结束
+func (gs *GMService) CreateAnnouncement(ctx context.Context, req *proto.AnnouncementRequest) (*proto.CommonResponse, error) {
+	gmUserID := ctx.Value("user_id")
+	if gmUserID == nil {
+		return gs.responses.CommonError(ctx, errcode.NotLoggedIn), nil
+	}
+
+	a := &database.Announcement{
+		Channels: req.GetChannels(),
+		Language: req.GetLanguage(),
+		Contents: make(map[string]database.AnnouncementContent, len(req.GetContents())),
+		Priority: req.GetPriority(),
+	}
+	for language, content := range req.GetContents() {
+		a.Contents[language] = database.AnnouncementContent{
+			Title:   content.GetTitle(),
+			Content: content.GetContent(),
+		}
+	}
+	if req.GetStartTime() > 0 {
+		a.StartAt = time.Unix(req.GetStartTime(), 0)
+	}
+	if req.GetEndTime() > 0 {
+		a.EndAt = time.Unix(req.GetEndTime(), 0)
+	}
+
+	if err := gs.server.createAnnouncement(gmUserID.(uint64), a); err != nil {
+		return gs.responses.CommonError(ctx, err), nil
+	}
+	return gs.responses.CommonSuccess(ctx, "公告创建成功",
+		[]byte(fmt.Sprintf("{\"announcement_id\":\"%s\"}", a.ID.Hex()))), nil
+}
+
+// ListAnnouncements 获取公告详情，未指定公告时获取最近创建的公告
+func (gs *GMService) ListAnnouncements(ctx context.Context, req *proto.AnnouncementQuery) (*proto.CommonResponse, error) {
+	if ctx.Value("user_id") == nil {
+		return gs.responses.CommonError(ctx, errcode.NotLoggedIn), nil
+	}
+
+	data, err := gs.server.announcementsJSON(req.GetAnnouncementId())
+	if err != nil {
+		return gs.responses.CommonError(ctx, err), nil
+	}
+	return gs.responses.CommonSuccess(ctx, "success", data), nil
+}
+
+// DeleteAnnouncement 删除公告，客户端下次获取公告时不再返回
+func (gs *GMService) DeleteAnnouncement(ctx context.Context, req *proto.AnnouncementQuery) (*proto.CommonResponse, error) {
+	gmUserID := ctx.Value("user_id")
+	if gmUserID == nil {
+		return gs.responses.CommonError(ctx, errcode.NotLoggedIn), nil
+	}
+
+	if err := gs.server.deleteAnnouncement(gmUserID.(uint64), req.GetAnnouncementId()); err != nil {
+		return gs.responses.CommonError(ctx, err), nil
+	}
+	return gs.responses.CommonSuccess(ctx, "公告已删除", nil), nil
+}
+
+// handleBroadcastNotice 把开始的跑马灯公告按玩家语言推送给本网关的在线玩家
+func (gs *GatewayServer) handleBroadcastNotice(msg *mq.SystemMessage) error {
+	id, _ := msg.Args["id"].(string)
+	a, err := gs.announcements.Get(id)
+	if err != nil {
+		return err
+	}
+	if a == nil {
+		logger.Warn(fmt.Sprintf("Announcement %s was deleted before push", id))
+		return nil
+	}
+
+	pushes := make(map[string]*proto.AnnouncementInfo)
+	pushed := 0
+	gs.tcpServer.ForEachConnection(func(conn *network.Connection) bool {
+		if conn.UserID == 0 {
+			return true // 未登录
+		}
+		push, exists := pushes[conn.Language]
+		if !exists {
+			push = announcementInfo(announcement.Localize(a, conn.Language))
+			pushes[conn.Language] = push
+		}
+		if err := pushMessage(conn, announcementPushMsgID, push); err != nil {
+			logger.Debug(fmt.Sprintf("Failed to push announcement to user %d: %v", conn.UserID, err))
+			return true
+		}
+		pushed++
+		return true
+	})
+
+	logger.Info(fmt.Sprintf("Announcement %s pushed to %d players", id, pushed))
+	return nil
+}
@@ -0,0 +1,477 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/denorm"
+	"github.com/phuhao00/lufy/internal/errcode"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// 异步对局ID从该值开始由Redis分配，不会与各节点内存中递增的实时对局ID冲突
+const (
+	asyncGameIDKey  = "async_game:next_id"
+	asyncGameIDBase = uint64(1) << 48
+)
+
+// 异步对局默认设置
+const (
+	defaultAsyncTurnTimeout    = 24 * time.Hour
+	defaultAsyncMinTurnTimeout = time.Hour
+	defaultAsyncMaxTurnTimeout = 7 * 24 * time.Hour
+	defaultAsyncReminderBefore = time.Hour
+	asyncGameBatchSize         = 100
+)
+
+// AsyncGameConfig 异步对局设置
+type AsyncGameConfig struct {
+	TurnTimeout    time.Duration `yaml:"turn_timeout"`     // 默认每回合时限，未配置时为24小时
+	MinTurnTimeout time.Duration `yaml:"min_turn_timeout"` // 开局时可指定的最短时限，未配置时为1小时
+	MaxTurnTimeout time.Duration `yaml:"max_turn_timeout"` // 开局时可指定的最长时限，未配置时为7天
+	ReminderBefore time.Duration `yaml:"reminder_before"`  // 截止前多久再次提醒，未配置时为1小时
+}
+
+// turnTimeout 开局请求指定的回合时限（秒），超出范围时取边界值，未指定时使用默认时限
+func (c *AsyncGameConfig) turnTimeout(requested int64) time.Duration {
+	timeout := c.TurnTimeout
+	if timeout <= 0 {
+		timeout = defaultAsyncTurnTimeout
+	}
+	if requested <= 0 {
+		return timeout
+	}
+
+	min, max := c.MinTurnTimeout, c.MaxTurnTimeout
+	if min <= 0 {
+		min = defaultAsyncMinTurnTimeout
+	}
+	if max <= 0 {
+		max = defaultAsyncMaxTurnTimeout
+	}
+	timeout = time.Duration(requested) * time.Second
+	if timeout < min {
+		return min
+	}
+	if timeout > max {
+		return max
+	}
+	return timeout
+}
+
+// reminderBefore 截止前提醒的提前量
+func (c *AsyncGameConfig) reminderBefore() time.Duration {
+	if c.ReminderBefore <= 0 {
+		return defaultAsyncReminderBefore
+	}
+	return c.ReminderBefore
+}
+
+// generateAsyncGameID 生成集群内唯一的异步对局ID
+func (gs *GameServer) generateAsyncGameID() (uint64, error) {
+	id, err := gs.redisManager.Incr(asyncGameIDKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate async game id: %v", err)
+	}
+	return asyncGameIDBase + uint64(id), nil
+}
+
+// findGame 获取游戏实例，内存中没有时查找异步对局
+// 异步对局每次都从数据库读取最新状态，不缓存在节点内存中，保存时按版本检查并发修改
+func (gs *GameServer) findGame(gameID uint64) (*GameInstance, bool) {
+	if game, exists := gs.getGame(gameID); exists {
+		return game, true
+	}
+
+	record, err := gs.asyncGameRepo.GetByGameID(gameID)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to load async game %d: %v", gameID, err))
+		return nil, false
+	}
+	if record == nil {
+		return nil, false
+	}
+
+	game, err := asyncGameInstance(record)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to restore async game %d: %v", gameID, err))
+		return nil, false
+	}
+	return game, true
+}
+
+// asyncGameInstance 由保存的异步对局构造游戏实例
+func asyncGameInstance(record *database.AsyncGame) (*GameInstance, error) {
+	gameData, err := RestoreGameData(record.GameType, record.State)
+	if err != nil {
+		return nil, err
+	}
+
+	game := &GameInstance{
+		GameID:        record.GameID,
+		RoomID:        record.RoomID,
+		GameType:      record.GameType,
+		Status:        record.Status,
+		Players:       make(map[uint64]*GamePlayerData, len(record.Players)),
+		CurrentPlayer: record.CurrentPlayer,
+		StartTime:     record.StartTime,
+		EndTime:       record.EndTime,
+		Winner:        record.Winner,
+		GameData:      gameData,
+		Async:         true,
+		TurnDeadline:  record.TurnDeadline,
+		turnTimeout:   time.Duration(record.TurnTimeout) * time.Second,
+		reminded:      record.Reminded,
+		version:       record.Version,
+	}
+	for _, player := range record.Players {
+		game.Players[player.UserID] = &GamePlayerData{
+			UserID:   player.UserID,
+			Nickname: player.Nickname,
+			Level:    player.Level,
+			Score:    player.Score,
+			Status:   player.Status,
+			Data:     make(map[string]interface{}),
+		}
+	}
+	return game, nil
+}
+
+// asyncGameRecord 由游戏实例构造保存的异步对局
+func asyncGameRecord(game *GameInstance) (*database.AsyncGame, error) {
+	state, _, err := game.GameData.Encoded()
+	if err != nil {
+		return nil, err
+	}
+
+	record := &database.AsyncGame{
+		GameID:        game.GameID,
+		RoomID:        game.RoomID,
+		GameType:      game.GameType,
+		Status:        game.Status,
+		CurrentPlayer: game.CurrentPlayer,
+		TurnTimeout:   int64(game.turnTimeout / time.Second),
+		TurnDeadline:  game.TurnDeadline,
+		Reminded:      game.reminded,
+		Winner:        game.Winner,
+		State:         state,
+		Version:       game.version,
+		StartTime:     game.StartTime,
+		EndTime:       game.EndTime,
+	}
+	for _, player := range game.Players {
+		record.Players = append(record.Players, database.AsyncGamePlayer{
+			UserID:   player.UserID,
+			Nickname: player.Nickname,
+			Level:    player.Level,
+			Score:    player.Score,
+			Status:   player.Status,
+		})
+		record.PlayerIDs = append(record.PlayerIDs, player.UserID)
+	}
+	sort.Slice(record.Players, func(i, j int) bool { return record.Players[i].UserID < record.Players[j].UserID })
+	sort.Slice(record.PlayerIDs, func(i, j int) bool { return record.PlayerIDs[i] < record.PlayerIDs[j] })
+	return record, nil
+}
+
+// saveAsyncGame 保存异步对局，轮到新玩家时重新计算回合截止时间并通知该玩家
+// 对局已被其他请求修改时返回false，调用方应放弃本次修改
+func (gs *GameServer) saveAsyncGame(game *GameInstance, previousPlayer uint64) (bool, error) {
+	turnChanged := game.Status == 1 && game.CurrentPlayer != previousPlayer
+	if turnChanged {
+		game.TurnDeadline = time.Now().Add(game.turnTimeout)
+		game.reminded = false
+	}
+
+	record, err := asyncGameRecord(game)
+	if err != nil {
+		return false, err
+	}
+	saved, err := gs.asyncGameRepo.Save(record)
+	if err != nil || !saved {
+		return false, err
+	}
+	game.version = record.Version
+
+	if turnChanged {
+		gs.notifyTurn(game, previousPlayer)
+	}
+	return true, nil
+}
+
+// saveAsyncGame 保存玩家对异步对局的修改，失败时返回错误响应
+func (gs *GameService) saveAsyncGame(ctx context.Context, header *proto.MessageHeader, game *GameInstance, previousPlayer uint64) *proto.BaseResponse {
+	saved, err := gs.server.saveAsyncGame(game, previousPlayer)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to save async game %d: %v", game.GameID, err))
+		return gs.responses.Error(ctx, header, errcode.Internal)
+	}
+	if !saved {
+		logger.Warn(fmt.Sprintf("Async game %d changed concurrently, discarding update", game.GameID))
+		return gs.responses.Error(ctx, header, errcode.GameStateChanged)
+	}
+	return nil
+}
+
+// notifyTurn 通知当前玩家行动，玩家离线时发送移动推送
+func (gs *GameServer) notifyTurn(game *GameInstance, fromUserID uint64) {
+	err := gs.notifier.Notify(&database.Notification{
+		UserID:     game.CurrentPlayer,
+		Type:       NotifyTurnReminder,
+		DedupKey:   fmt.Sprintf("turn_reminder:%d", game.GameID),
+		FromUserID: fromUserID,
+		Data: map[string]interface{}{
+			"game_id":  strconv.FormatUint(game.GameID, 10),
+			"deadline": game.TurnDeadline.Unix(),
+		},
+	})
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to notify user %d of turn in game %d: %v", game.CurrentPlayer, game.GameID, err))
+	}
+}
+
+// startAsyncGame 开始异步对局，房间内的玩家都加入对局，由发起者先行动
+func (gs *GameService) startAsyncGame(ctx context.Context, req *proto.BaseRequest, userID uint64, startGameReq *proto.StartGameRequest) (*proto.BaseResponse, error) {
+	roomID := startGameReq.GetRoomId()
+	room, err := database.NewRoomRepository(gs.server.mongoManager).GetRoomByID(roomID)
+	if err != nil {
+		logger.Error(fmt.Sprintf("StartGame: failed to get room %d: %v", roomID, err))
+		return gs.responses.Error(ctx, req.Header, errcode.RoomNotFound), nil
+	}
+
+	players := make(map[uint64]*GamePlayerData, len(room.Players))
+	for _, roomPlayer := range room.Players {
+		players[roomPlayer.UserID] = &GamePlayerData{
+			UserID:   roomPlayer.UserID,
+			Nickname: roomPlayer.Nickname,
+			Level:    roomPlayer.Level,
+			Status:   2, // 游戏中
+			Data:     make(map[string]interface{}),
+		}
+	}
+	if _, exists := players[userID]; !exists {
+		logger.Error(fmt.Sprintf("StartGame: user %d not in room %d", userID, roomID))
+		return gs.responses.Error(ctx, req.Header, errcode.NotInRoom), nil
+	}
+	if len(players) < 2 {
+		return gs.responses.Error(ctx, req.Header, errcode.NotEnoughPlayers), nil
+	}
+
+	gameID, err := gs.server.generateAsyncGameID()
+	if err != nil {
+		logger.Error(fmt.Sprintf("StartGame: %v", err))
+		return gs.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+
+	now := time.Now()
+	turnTimeout := gs.server.config.AsyncGame.turnTimeout(startGameReq.GetTurnTimeout())
+	game := &GameInstance{
+		GameID:        gameID,
+		RoomID:        roomID,
+		GameType:      startGameReq.GetGameType(),
+		Status:        1, // 进行中
+		Players:       players,
+		CurrentPlayer: userID,
+		StartTime:     now,
+		GameData:      NewGameData(startGameReq.GetGameType()),
+		Async:         true,
+		TurnDeadline:  now.Add(turnTimeout),
+		turnTimeout:   turnTimeout,
+	}
+
+	record, err := asyncGameRecord(game)
+	if err != nil {
+		logger.Error(fmt.Sprintf("StartGame: %v", err))
+		return gs.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+	if err := gs.server.asyncGameRepo.Create(record); err != nil {
+		logger.Error(fmt.Sprintf("StartGame: %v", err))
+		return gs.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+
+	gameRecord := &database.GameRecord{
+		GameID:   gameID,
+		RoomID:   roomID,
+		GameType: game.GameType,
+		Status:   0, // 进行中
+	}
+	for _, player := range record.Players {
+		gameRecord.Players = append(gameRecord.Players, database.GamePlayer{
+			UserID:   player.UserID,
+			Nickname: player.Nickname,
+			Level:    player.Level,
+		})
+	}
+	if err := gs.server.gameRecordRepo.CreateRecord(gameRecord); err != nil {
+		logger.Error(fmt.Sprintf("StartGame: failed to create game record: %v", err))
+	}
+
+	logger.Info(fmt.Sprintf("User %d started async game %d in room %d with %d players, turn timeout %v",
+		userID, gameID, roomID, len(players), turnTimeout))
+
+	responseData := map[string]interface{}{
+		"game_id":       gameID,
+		"room_id":       roomID,
+		"game_type":     game.GameType,
+		"status":        game.Status,
+		"async":         true,
+		"turn_deadline": game.TurnDeadline.Unix(),
+	}
+
+	responseBytes, err := json.Marshal(responseData)
+	if err != nil {
+		logger.Error(fmt.Sprintf("StartGame: failed to marshal response: %v", err))
+		return gs.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+
+	return gs.responses.Success(ctx, req.Header, "game started successfully", responseBytes), nil
+}
+
+// GetActiveAsyncGames 获取玩家进行中的异步对局，轮到玩家行动的对局排在前面
+func (gs *GameService) GetActiveAsyncGames(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	userID := req.Header.GetUserId()
+	if userID == 0 {
+		logger.Error("GetActiveAsyncGames: invalid user id")
+		return gs.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
+	}
+
+	records, err := gs.server.asyncGameRepo.GetActiveByPlayer(userID, asyncGameBatchSize)
+	if err != nil {
+		logger.Error(fmt.Sprintf("GetActiveAsyncGames: %v", err))
+		return gs.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+
+	games := make([]*proto.AsyncGameInfo, 0, len(records))
+	for _, record := range records {
+		info := &proto.AsyncGameInfo{
+			GameId:        record.GameID,
+			RoomId:        record.RoomID,
+			GameType:      record.GameType,
+			CurrentPlayer: record.CurrentPlayer,
+			TurnDeadline:  record.TurnDeadline.Unix(),
+			YourTurn:      record.CurrentPlayer == userID,
+		}
+		for _, player := range record.Players {
+			info.Players = append(info.Players, &proto.GamePlayerInfo{
+				UserId:   player.UserID,
+				Nickname: player.Nickname,
+				Level:    player.Level,
+				Score:    player.Score,
+				Status:   player.Status,
+			})
+		}
+		applyProfiles(gs.server.denorm, denorm.CollectionAsyncGames, info.Players)
+		games = append(games, info)
+	}
+	// 记录已按截止时间排序，保持同组内的顺序
+	sort.SliceStable(games, func(i, j int) bool { return games[i].YourTurn && !games[j].YourTurn })
+
+	responseData, err := proto.Marshal(&proto.AsyncGameListResponse{Games: games})
+	if err != nil {
+		logger.Error(fmt.Sprintf("GetActiveAsyncGames: failed to marshal response: %v", err))
+		return gs.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+
+	return gs.responses.Success(ctx, req.Header, "success", responseData), nil
+}
+
+// checkAsyncTurns 提醒即将超时的玩家，超时未行动的玩家判负
+// 任务在集群内同一时间只在一个节点执行，对局保存时的版本检查避免与玩家行动冲突
+func (gs *GameService) checkAsyncTurns(ctx context.Context) error {
+	now := time.Now()
+	config := &gs.server.config.AsyncGame
+
+	expired, err := gs.server.asyncGameRepo.GetExpired(now, asyncGameBatchSize)
+	if err != nil {
+		return err
+	}
+	forfeited := 0
+	for _, record := range expired {
+		if gs.forfeitTurn(record) {
+			forfeited++
+		}
+	}
+
+	unreminded, err := gs.server.asyncGameRepo.GetUnreminded(now.Add(config.reminderBefore()), asyncGameBatchSize)
+	if err != nil {
+		return err
+	}
+	reminded := 0
+	for _, record := range unreminded {
+		if !record.TurnDeadline.After(now) {
+			continue // 下次检查时判负
+		}
+		record.Reminded = true
+		saved, err := gs.server.asyncGameRepo.Save(record)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to mark async game %d reminded: %v", record.GameID, err))
+			continue
+		}
+		if !saved {
+			continue
+		}
+		game, err := asyncGameInstance(record)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to restore async game %d: %v", record.GameID, err))
+			continue
+		}
+		gs.server.notifyTurn(game, 0)
+		reminded++
+	}
+
+	if forfeited > 0 || reminded > 0 {
+		logger.Info(fmt.Sprintf("Async games: %d turns forfeited, %d reminders sent", forfeited, reminded))
+	}
+	return nil
+}
+
+// forfeitTurn 超时未行动的玩家判负，只剩一名玩家时结束对局，否则轮到下一名玩家
+func (gs *GameService) forfeitTurn(record *database.AsyncGame) bool {
+	game, err := asyncGameInstance(record)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to restore async game %d: %v", record.GameID, err))
+		return false
+	}
+
+	previousPlayer := game.CurrentPlayer
+	if player, exists := game.Players[previousPlayer]; exists {
+		player.Status = 3 // 已离开
+	}
+
+	active := make([]uint64, 0, len(game.Players))
+	for _, player := range game.Players {
+		if player.Status != 3 {
+			active = append(active, player.UserID)
+		}
+	}
+	if len(active) <= 1 {
+		game.Status = 2 // 已结束
+		game.EndTime = time.Now()
+		if len(active) == 1 {
+			game.Winner = active[0]
+		}
+	} else {
+		gs.switchToNextPlayer(game)
+	}
+
+	saved, err := gs.server.saveAsyncGame(game, previousPlayer)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to forfeit turn in async game %d: %v", game.GameID, err))
+		return false
+	}
+	if !saved {
+		return false // 玩家已在检查期间行动
+	}
+
+	logger.Info(fmt.Sprintf("User %d forfeited async game %d after turn deadline", previousPlayer, game.GameID))
+	if game.Status == 2 {
+		gs.server.recordGameEnded(game, previousPlayer)
+	}
+	return true
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/security"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// requestValidator 请求消息自带的校验，proto消息可以在生成代码之外实现
+type requestValidator interface {
+	Validate() error
+}
+
+// Bind 把protobuf编码的请求数据解析为类型为T的消息，消息实现了Validate时一并校验
+func Bind[T any, PT interface {
+	*T
+	proto.Message
+}](data []byte) (*T, error) {
+	msg := PT(new(T))
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %T: %v", msg, err)
+	}
+	if v, ok := any(msg).(requestValidator); ok {
+		if err := v.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return msg, nil
+}
+
+// BindJSON 按T的json和validate标签校验JSON编码的请求数据并解析为T，method用于输入规则的例外匹配。
+// 数据为空时按空对象处理，必填参数缺失时返回错误
+func BindJSON[T any](validator *security.InputValidator, method string, data []byte) (*T, error) {
+	params, err := bindJSON[T](validator, method, data)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Parameter validation failed for %s: %v", method, err))
+	}
+	return params, err
+}
+
+// bindJSON 校验并解析JSON编码的请求数据
+func bindJSON[T any](validator *security.InputValidator, method string, data []byte) (*T, error) {
+	schema, err := security.StructSchema(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		data = []byte("{}")
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse request data: %v", err)
+	}
+	if err := validator.ValidateSchema(method, schema, raw); err != nil {
+		return nil, err
+	}
+
+	params := new(T)
+	if err := json.Unmarshal(data, params); err != nil {
+		return nil, fmt.Errorf("failed to parse request data: %v", err)
+	}
+	return params, nil
+}
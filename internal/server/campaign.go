@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/phuhao00/lufy/internal/campaign"
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/errcode"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// newCampaigns 创建邮件活动服务，奖励按玩家的VIP权益发放，notifier为空时不通知玩家
+func (bs *BaseServer) newCampaigns(notifier *Notifier) *campaign.Service {
+	var notify campaign.NotifyFunc
+	if notifier != nil {
+		notify = func(userID, mailID uint64, title string, gift bool) {
+			notifyType := NotifyMail
+			if gift {
+				notifyType = NotifyGift
+			}
+			if err := notifier.Notify(&database.Notification{
+				UserID:   userID,
+				Type:     notifyType,
+				DedupKey: fmt.Sprintf("%s:%d", notifyType, mailID),
+				Data:     map[string]interface{}{"mail_id": strconv.FormatUint(mailID, 10), "title": title},
+			}); err != nil {
+				logger.Warn(fmt.Sprintf("Failed to notify user %d of campaign mail %d: %v", userID, mailID, err))
+			}
+		}
+	}
+
+	return campaign.NewService(&bs.config.MailCampaign,
+		database.NewMailCampaignRepository(bs.mongoManager), bs.entitlements.Mails(database.NewMailRepository(bs.mongoManager)),
+		database.NewUserRepository(bs.mongoManager), bs.newSegments(), bs.generateMailID, notify)
+}
+
+// campaignError 把邮件活动的错误转换为错误码
+func campaignError(err error) error {
+	switch {
+	case errors.Is(err, campaign.ErrNotFound):
+		return errcode.CampaignNotFound
+	case errors.Is(err, campaign.ErrState):
+		return errcode.CampaignStateConflict
+	}
+	return errcode.Internal.Wrap(err)
+}
+
+// updateCampaign 暂停、继续或取消活动
+func updateCampaign(service *campaign.Service, campaignID, action string) error {
+	switch strings.ToLower(action) {
+	case "pause":
+		return service.Pause(campaignID)
+	case "resume":
+		return service.Resume(campaignID)
+	case "cancel":
+		return service.Cancel(campaignID)
+	}
+	return errcode.CampaignInvalid.WithDetail(fmt.Sprintf("unknown action: %s", action))
+}
+
+// campaignJSON 活动进度和统计，未指定活动时为最近的活动列表
+func campaignJSON(service *campaign.Service, campaignID string) ([]byte, error) {
+	if campaignID == "" {
+		campaigns, err := service.List(20)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(campaigns)
+	}
+
+	stats, err := service.Stats(campaignID)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(stats)
+}
+
+// CreateCampaign 创建邮件活动，由定时任务按配置的速度分批发送
+func (ms *MailService) CreateCampaign(ctx context.Context, req *proto.MailCampaignRequest) (*proto.CommonResponse, error) {
+	userID := ctx.Value("user_id")
+	if userID == nil {
+		return ms.responses.CommonError(ctx, errcode.NotLoggedIn), nil
+	}
+
+	mailCampaign := &database.MailCampaign{
+		Segment:   req.GetSegment(),
+		Language:  req.GetLanguage(),
+		Templates: make(map[string]database.MailTemplate, len(req.GetTemplates())),
+		CreatedBy: userID.(uint64),
+	}
+	for language, mailTemplate := range req.GetTemplates() {
+		mailCampaign.Templates[language] = database.MailTemplate{
+			Title:   mailTemplate.GetTitle(),
+			Content: mailTemplate.GetContent(),
+		}
+	}
+	for _, reward := range req.GetRewards() {
+		mailCampaign.Rewards = append(mailCampaign.Rewards, database.MailReward{
+			Type:   reward.GetItemType(),
+			ItemID: int32(reward.GetItemId()),
+			Count:  int64(reward.GetQuantity()),
+		})
+	}
+
+	if err := ms.server.campaigns.Create(mailCampaign); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to create mail campaign: %v", err))
+		return ms.responses.CommonError(ctx, errcode.CampaignInvalid.WithDetail(err.Error())), nil
+	}
+
+	logger.Info(fmt.Sprintf("Mail campaign %s created for segment %s by %d", mailCampaign.ID.Hex(), mailCampaign.Segment, mailCampaign.CreatedBy))
+	return ms.responses.CommonSuccess(ctx, "邮件活动创建成功",
+		[]byte(fmt.Sprintf("{\"campaign_id\":\"%s\",\"batch_id\":\"%s\"}", mailCampaign.ID.Hex(), mailCampaign.BatchID()))), nil
+}
+
+// GetCampaign 获取邮件活动的发送进度和已读、领取统计
+func (ms *MailService) GetCampaign(ctx context.Context, req *proto.MailCampaignQuery) (*proto.CommonResponse, error) {
+	if ctx.Value("user_id") == nil {
+		return ms.responses.CommonError(ctx, errcode.NotLoggedIn), nil
+	}
+
+	data, err := campaignJSON(ms.server.campaigns, req.GetCampaignId())
+	if err != nil {
+		return ms.responses.CommonError(ctx, campaignError(err)), nil
+	}
+	return ms.responses.CommonSuccess(ctx, "success", data), nil
+}
+
+// UpdateCampaign 暂停、继续或取消邮件活动
+func (ms *MailService) UpdateCampaign(ctx context.Context, req *proto.MailCampaignQuery) (*proto.CommonResponse, error) {
+	if ctx.Value("user_id") == nil {
+		return ms.responses.CommonError(ctx, errcode.NotLoggedIn), nil
+	}
+
+	if err := updateCampaign(ms.server.campaigns, req.GetCampaignId(), req.GetAction()); err != nil {
+		var codeErr *errcode.Error
+		if !errors.As(err, &codeErr) {
+			err = campaignError(err)
+		}
+		return ms.responses.CommonError(ctx, err), nil
+	}
+
+	logger.Info(fmt.Sprintf("Mail campaign %s: %s", req.GetCampaignId(), req.GetAction()))
+	return ms.responses.CommonSuccess(ctx, "邮件活动已更新", nil), nil
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/discovery"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/mq"
+)
+
+// 灰度默认设置
+const (
+	defaultCanaryWeight            = 5
+	defaultCanaryMinRequests       = 100
+	defaultCanaryMaxErrorRateDelta = 0.01
+	defaultCanaryMaxLatencyRatio   = 1.5
+)
+
+// canaryLatencyFloor 灰度节点与其他节点的平均耗时相差不到该值时不按比例判定，避免耗时很低时的抖动触发回滚
+const canaryLatencyFloor = 5 * time.Millisecond
+
+// CanaryConfig 灰度设置
+type CanaryConfig struct {
+	Enabled bool `yaml:"enabled"` // 本节点以灰度节点注册
+	Weight  int  `yaml:"weight"`  // 灰度节点分到的新流量百分比，未配置时为5
+
+	// 以下由中心服使用，灰度节点的指标比同类型其他节点差时把权重回滚为0
+	AutoRollback      bool    `yaml:"auto_rollback"`
+	MinRequests       int64   `yaml:"min_requests"`         // 灰度节点和其他节点在统计周期内的请求数都达到该值才比较
+	MaxErrorRateDelta float64 `yaml:"max_error_rate_delta"` // 错误率最多比其他节点高出多少
+	MaxLatencyRatio   float64 `yaml:"max_latency_ratio"`    // 平均耗时最多是其他节点的多少倍
+}
+
+// withDefaults 补全未配置的中心服设置
+func (c CanaryConfig) withDefaults() CanaryConfig {
+	if c.MinRequests <= 0 {
+		c.MinRequests = defaultCanaryMinRequests
+	}
+	if c.MaxErrorRateDelta <= 0 {
+		c.MaxErrorRateDelta = defaultCanaryMaxErrorRateDelta
+	}
+	if c.MaxLatencyRatio <= 1 {
+		c.MaxLatencyRatio = defaultCanaryMaxLatencyRatio
+	}
+	return c
+}
+
+// canaryMetadata 灰度节点的注册元数据
+func canaryMetadata(weight int) map[string]string {
+	if weight < 0 {
+		weight = 0
+	}
+	if weight > discovery.MaxCanaryWeight {
+		weight = discovery.MaxCanaryWeight
+	}
+	return map[string]string{
+		discovery.MetadataCanary:       "true",
+		discovery.MetadataCanaryWeight: strconv.Itoa(weight),
+	}
+}
+
+// reportMetrics 上报上一个统计周期的RPC指标
+func (bs *BaseServer) reportMetrics() {
+	snapshot := bs.callStats.Collect()
+	metrics := &discovery.ServiceMetrics{
+		Requests:     snapshot.Requests,
+		Errors:       snapshot.Errors,
+		AvgLatencyMs: float64(snapshot.AvgLatency()) / float64(time.Millisecond),
+		Window:       int64(snapshot.Window / time.Second),
+	}
+	if bs.roomCount != nil {
+		metrics.Rooms = int64(bs.roomCount())
+	}
+	if bs.tcpServer != nil {
+		metrics.Connections = int64(bs.tcpServer.GetConnectionCount())
+	}
+	if err := bs.registry.UpdateMetrics(bs.nodeID, metrics); err != nil {
+		logger.Error(fmt.Sprintf("Failed to update metrics: %v", err))
+	}
+}
+
+// fleetMetrics 汇总同类型非灰度节点的指标
+func fleetMetrics(services []*discovery.ServiceInfo) *discovery.ServiceMetrics {
+	fleet := &discovery.ServiceMetrics{}
+	totalLatency := 0.0
+	for _, service := range services {
+		if _, canary := discovery.CanaryWeight(service); canary || service.Metrics == nil {
+			continue
+		}
+		if time.Now().Unix()-service.UpdateTime > 60 {
+			continue
+		}
+		fleet.Requests += service.Metrics.Requests
+		fleet.Errors += service.Metrics.Errors
+		totalLatency += service.Metrics.AvgLatencyMs * float64(service.Metrics.Requests)
+	}
+	if fleet.Requests > 0 {
+		fleet.AvgLatencyMs = totalLatency / float64(fleet.Requests)
+	}
+	return fleet
+}
+
+// canaryRegression 比较灰度节点和其他节点的指标，返回回滚原因，样本不足或没有退化时返回空
+func canaryRegression(canary, fleet *discovery.ServiceMetrics, config CanaryConfig) string {
+	if canary == nil || canary.Requests < config.MinRequests || fleet.Requests < config.MinRequests {
+		return ""
+	}
+
+	if canary.ErrorRate() > fleet.ErrorRate()+config.MaxErrorRateDelta {
+		return fmt.Sprintf("error rate %.2f%% vs fleet %.2f%%", canary.ErrorRate()*100, fleet.ErrorRate()*100)
+	}
+
+	floor := float64(canaryLatencyFloor) / float64(time.Millisecond)
+	if canary.AvgLatencyMs-fleet.AvgLatencyMs > floor && canary.AvgLatencyMs > fleet.AvgLatencyMs*config.MaxLatencyRatio {
+		return fmt.Sprintf("avg latency %.1fms vs fleet %.1fms", canary.AvgLatencyMs, fleet.AvgLatencyMs)
+	}
+	return ""
+}
+
+// checkCanaries 比较各灰度节点和同类型其他节点的指标，退化时把灰度权重回滚为0
+func (cs *CenterServer) checkCanaries(services []*discovery.ServiceInfo) {
+	cs.mutex.RLock()
+	config := cs.config.Canary.withDefaults()
+	cs.mutex.RUnlock()
+	if !config.AutoRollback {
+		return
+	}
+
+	byType := make(map[string][]*discovery.ServiceInfo)
+	for _, service := range services {
+		byType[service.NodeType] = append(byType[service.NodeType], service)
+	}
+
+	for nodeType, nodes := range byType {
+		fleet := fleetMetrics(nodes)
+		for _, service := range nodes {
+			weight, canary := discovery.CanaryWeight(service)
+			if !canary || weight == 0 || time.Now().Unix()-service.UpdateTime > 60 {
+				continue
+			}
+
+			reason := canaryRegression(service.Metrics, fleet, config)
+			if reason == "" {
+				continue
+			}
+
+			logger.Warn(fmt.Sprintf("Canary %s (%s) regressed: %s, rolling back weight %d -> 0",
+				service.NodeID, nodeType, reason, weight))
+			if err := cs.messageBroker.SendToNode(service.NodeID, mq.SYS_CMD_CANARY, map[string]interface{}{
+				"canary": true,
+				"weight": 0,
+				"reason": reason,
+			}); err != nil {
+				logger.Error(fmt.Sprintf("Failed to roll back canary %s: %v", service.NodeID, err))
+			}
+		}
+	}
+}
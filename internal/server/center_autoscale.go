@@ -0,0 +1,79 @@
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/autoscale"
+	"github.com/phuhao00/lufy/internal/discovery"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/monitoring"
+	"github.com/phuhao00/lufy/internal/mq"
+)
+
+// initAutoscale 创建扩缩容顾问，配置了指标端口时导出扩缩容指标
+func (cs *CenterServer) initAutoscale() error {
+	config := cs.config.Autoscale
+	if !config.Enabled {
+		return nil
+	}
+
+	cs.autoscaler = autoscale.NewAdvisor(&config)
+	if config.Metrics > 0 {
+		manager, err := monitoring.NewMonitoringManager(cs.nodeID, "center", config.Metrics)
+		if err != nil {
+			return fmt.Errorf("failed to create monitoring manager: %v", err)
+		}
+		if err := manager.Register(autoscale.NewCollector(cs.autoscaler)); err != nil {
+			return fmt.Errorf("failed to register autoscale metrics: %v", err)
+		}
+		cs.monitoring = manager
+		cs.registerMonitoring(manager)
+	}
+
+	logger.Info(fmt.Sprintf("Autoscale enabled for %v", cs.autoscaler.NodeTypes()))
+	return nil
+}
+
+// checkAutoscale 按各类节点的负载给出扩缩容建议，排空或恢复节点后交给执行者
+func (cs *CenterServer) checkAutoscale(services []*discovery.ServiceInfo) {
+	if cs.autoscaler == nil {
+		return
+	}
+
+	byType := make(map[string][]*discovery.ServiceInfo)
+	for _, service := range services {
+		byType[service.NodeType] = append(byType[service.NodeType], service)
+	}
+
+	now := time.Now()
+	for _, nodeType := range cs.autoscaler.NodeTypes() {
+		decision, err := cs.autoscaler.Evaluate(nodeType, byType[nodeType], now)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to evaluate autoscale for %s: %v", nodeType, err))
+			continue
+		}
+
+		if decision.Changed() || len(decision.Remove) > 0 {
+			logger.Info(fmt.Sprintf("Autoscale %s: %d -> %d nodes (%s), drain %v, resume %v, remove %v",
+				nodeType, decision.Nodes, decision.Desired, decision.Reason, decision.Drain, decision.Resume, decision.Remove))
+		}
+
+		// 排空失败的节点仍为在线状态，下次评估时不再视为扩缩容排空的节点
+		cs.sendDrain(decision.Drain, false)
+		cs.sendDrain(decision.Resume, true)
+
+		cs.autoscaler.Provision(cs.ctx, decision)
+	}
+}
+
+// sendDrain 排空或恢复节点
+func (cs *CenterServer) sendDrain(nodeIDs []string, resume bool) {
+	for _, nodeID := range nodeIDs {
+		if err := cs.messageBroker.SendToNode(nodeID, mq.SYS_CMD_DRAIN, map[string]interface{}{
+			"resume": resume,
+		}); err != nil {
+			logger.Error(fmt.Sprintf("Failed to send drain command to %s: %v", nodeID, err))
+		}
+	}
+}
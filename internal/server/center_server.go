@@ -5,18 +5,25 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"reflect"
 	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/phuhao00/lufy/internal/autoscale"
 	"github.com/phuhao00/lufy/internal/discovery"
 	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/monitoring"
+	"github.com/phuhao00/lufy/internal/mq"
 	"github.com/phuhao00/lufy/pkg/proto"
 )
 
 // CenterServer 中心服务器
 type CenterServer struct {
 	*BaseServer
+	autoscaler *autoscale.Advisor           // 未启用自动扩缩容时为nil
+	monitoring *monitoring.MonitoringManager // 导出扩缩容指标
 }
 
 // NewCenterServer 创建中心服务器
@@ -37,18 +44,27 @@ func NewCenterServer(configFile, nodeID string) *CenterServer {
 
 	// 注册中心服务
 	centerService := NewCenterService(centerServer)
-	if err := baseServer.rpcServer.RegisterService(centerService); err != nil {
+	if err := RegisterCenterService(baseServer.rpcServer, centerService); err != nil {
 		logger.Fatal(fmt.Sprintf("Failed to register center service: %v", err))
 	}
 
-	// 启动管理任务
-	go centerServer.managementLoop()
+	// 广播活动开始和结束
+	if err := baseServer.scheduler.Register("activity_calendar", "@every 30s", centerServer.announceActivities); err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to register activity job: %v", err))
+	}
+
+	if err := centerServer.initAutoscale(); err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to init autoscale: %v", err))
+	}
+
+	// 管理任务
+	baseServer.RegisterBackground("management", centerServer.managementLoop)
 
 	return centerServer
 }
 
 // managementLoop 管理循环
-func (cs *CenterServer) managementLoop() {
+func (cs *CenterServer) managementLoop(ctx context.Context) {
 	ticker := time.NewTicker(60 * time.Second)
 	defer ticker.Stop()
 
@@ -59,7 +75,7 @@ func (cs *CenterServer) managementLoop() {
 			cs.performHealthChecks()
 			cs.collectStatistics()
 
-		case <-cs.ctx.Done():
+		case <-ctx.Done():
 			return
 		}
 	}
@@ -68,9 +84,9 @@ func (cs *CenterServer) managementLoop() {
 // performHealthChecks 执行健康检查
 func (cs *CenterServer) performHealthChecks() {
 	// 获取所有注册的服务
-	serviceTypes := []string{"gateway", "login", "lobby", "game", "friend", "chat", "mail", "gm"}
+	allServices := make([]*discovery.ServiceInfo, 0)
 
-	for _, serviceType := range serviceTypes {
+	for _, serviceType := range clusterNodeTypes {
 		services, err := cs.registry.GetServices(serviceType)
 		if err != nil {
 			logger.Error(fmt.Sprintf("Failed to get services for %s: %v", serviceType, err))
@@ -78,7 +94,75 @@ func (cs *CenterServer) performHealthChecks() {
 		}
 
 		logger.Debug(fmt.Sprintf("Health check for %s: %d services online", serviceType, len(services)))
+		allServices = append(allServices, services...)
+	}
+
+	// 滚动发布期间版本不一致是正常的，发布结束后仍不一致说明有节点漏更新
+	_, warnings := checkVersions(allServices)
+	for _, warning := range warnings {
+		logger.Warn(fmt.Sprintf("Cluster version check: %s", warning))
+	}
+
+	// 灰度节点指标退化时自动回滚权重
+	cs.checkCanaries(allServices)
+
+	// 按房间数和连接数扩缩容
+	cs.checkAutoscale(allServices)
+}
+
+// nodeBuild 从注册元数据中读取节点的构建信息，未携带构建信息的旧版本节点记为unknown
+func nodeBuild(service *discovery.ServiceInfo) (string, string, string) {
+	build := func(key string) string {
+		if value := service.Metadata[key]; value != "" {
+			return value
+		}
+		return "unknown"
+	}
+	return build(discovery.MetadataVersion), build(discovery.MetadataGitCommit), build(discovery.MetadataProtoSchema)
+}
+
+// checkVersions 检查在线节点的构建版本和消息定义版本是否一致，返回各版本的节点数和告警
+func checkVersions(services []*discovery.ServiceInfo) (map[string]int32, []string) {
+	versions := make(map[string]int32)
+	builds := make(map[string][]string)  // 版本@提交 -> 节点
+	schemas := make(map[string][]string) // 消息定义版本 -> 节点
+
+	for _, service := range services {
+		if time.Now().Unix()-service.UpdateTime > 60 {
+			continue
+		}
+		ver, commit, schema := nodeBuild(service)
+		key := ver + "@" + commit
+		versions[key]++
+		builds[key] = append(builds[key], service.NodeID)
+		schemas[schema] = append(schemas[schema], service.NodeID)
+	}
+
+	var warnings []string
+	if len(schemas) > 1 {
+		warnings = append(warnings, "proto schema mismatch: "+describeGroups("schema ", schemas))
 	}
+	if len(builds) > 1 {
+		warnings = append(warnings, fmt.Sprintf("nodes run %d different builds: %s", len(builds), describeGroups("", builds)))
+	}
+	return versions, warnings
+}
+
+// describeGroups 按分组列出节点，如"v1@abc on [game-1 game-2]; v2@def on [game-3]"
+func describeGroups(prefix string, groups map[string][]string) string {
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		nodes := groups[key]
+		sort.Strings(nodes)
+		parts = append(parts, fmt.Sprintf("%s%s on %v", prefix, key, nodes))
+	}
+	return strings.Join(parts, "; ")
 }
 
 // collectStatistics 收集统计信息
@@ -99,30 +183,11 @@ func NewCenterService(server *CenterServer) *CenterService {
 	}
 }
 
-// GetName 获取服务名称
-func (cs *CenterService) GetName() string {
-	return "CenterService"
-}
-
-// RegisterMethods 注册方法
-func (cs *CenterService) RegisterMethods() map[string]reflect.Value {
-	methods := make(map[string]reflect.Value)
-
-	methods["GetServiceList"] = reflect.ValueOf(cs.GetServiceList)
-	methods["GetClusterStatus"] = reflect.ValueOf(cs.GetClusterStatus)
-	methods["BroadcastMessage"] = reflect.ValueOf(cs.BroadcastMessage)
-	methods["ShutdownService"] = reflect.ValueOf(cs.ShutdownService)
-	methods["RestartService"] = reflect.ValueOf(cs.RestartService)
-
-	return methods
-}
-
 // GetServiceList 获取服务列表
 func (cs *CenterService) GetServiceList(ctx context.Context, req *proto.BaseRequest) (*proto.ServiceListResponse, error) {
-	serviceTypes := []string{"gateway", "login", "lobby", "game", "friend", "chat", "mail", "gm", "center"}
 	allServices := make([]*discovery.ServiceInfo, 0)
 
-	for _, serviceType := range serviceTypes {
+	for _, serviceType := range clusterNodeTypes {
 		services, err := cs.server.registry.GetServices(serviceType)
 		if err != nil {
 			logger.Error(fmt.Sprintf("Failed to get services for %s: %v", serviceType, err))
@@ -136,11 +201,18 @@ func (cs *CenterService) GetServiceList(ctx context.Context, req *proto.BaseRequ
 	for _, service := range allServices {
 		// 获取端口
 		port := service.Port
-		status := "online"
+		status := discovery.StatusOnline
+		if service.Status == discovery.StatusDraining || service.Status == discovery.StatusStarting {
+			status = service.Status
+		}
 		if time.Now().Unix()-service.UpdateTime > 60 {
 			status = "offline"
 		}
 
+		ver, commit, schema := nodeBuild(service)
+		protoSchema, _ := strconv.ParseUint(schema, 10, 32)
+		canaryWeight, canary := discovery.CanaryWeight(service)
+
 		protoService := &proto.ServiceInfo{
 				ServiceId:     service.NodeID,
 				ServiceType:   service.NodeType,
@@ -148,6 +220,12 @@ func (cs *CenterService) GetServiceList(ctx context.Context, req *proto.BaseRequ
 				Port:          int32(port),
 				Status:        status,
 				LastHeartbeat: uint32(service.UpdateTime),
+				Version:       ver,
+				GitCommit:     commit,
+				BuildTime:     service.Metadata[discovery.MetadataBuildTime],
+				ProtoSchema:   uint32(protoSchema),
+				Canary:        canary,
+				CanaryWeight:  int32(canaryWeight),
 			}
 		protoServices = append(protoServices, protoService)
 	}
@@ -162,10 +240,9 @@ func (cs *CenterService) GetServiceList(ctx context.Context, req *proto.BaseRequ
 
 // GetClusterStatus 获取集群状态
 func (cs *CenterService) GetClusterStatus(ctx context.Context, req *proto.BaseRequest) (*proto.ClusterStatusResponse, error) {
-	serviceTypes := []string{"gateway", "login", "lobby", "game", "friend", "chat", "mail", "gm", "center"}
 	allServices := make([]*discovery.ServiceInfo, 0)
 
-	for _, serviceType := range serviceTypes {
+	for _, serviceType := range clusterNodeTypes {
 		services, err := cs.server.registry.GetServices(serviceType)
 		if err != nil {
 			logger.Error(fmt.Sprintf("Failed to get services for %s: %v", serviceType, err))
@@ -194,13 +271,18 @@ func (cs *CenterService) GetClusterStatus(ctx context.Context, req *proto.BaseRe
 	// 获取系统信息
 	systemInfo := cs.getSystemInfo()
 
+	// 检查版本一致性
+	versions, versionWarnings := checkVersions(allServices)
+
 	log.Printf("获取集群状态成功，总服务数: %d，在线服务数: %d", totalCount, onlineCount)
 
 	return &proto.ClusterStatusResponse{
-		TotalServices:  totalCount,
-		OnlineServices: onlineCount,
-		ServiceStats:   serviceStats,
-		SystemInfo:     systemInfo,
+		TotalServices:   totalCount,
+		OnlineServices:  onlineCount,
+		ServiceStats:    serviceStats,
+		SystemInfo:      systemInfo,
+		Versions:        versions,
+		VersionWarnings: versionWarnings,
 	}, nil
 }
 
@@ -441,3 +523,146 @@ func (cs *CenterService) RestartService(ctx context.Context, req *proto.ServiceO
 		Data:    []byte(fmt.Sprintf("{\"target_count\":%d}", successCount)),
 	}, nil
 }
+
+// DrainService 排空服务，排空后负载均衡不再选择该服务，已有连接和会话不受影响；Operation为resume时恢复
+func (cs *CenterService) DrainService(ctx context.Context, req *proto.ServiceOperationRequest) (*proto.CommonResponse, error) {
+	if req.ServiceId == "" && req.ServiceType == "" {
+		return &proto.CommonResponse{
+			Code:    1001,
+			Message: "服务ID或服务类型不能为空",
+		}, nil
+	}
+
+	resume, action := false, "排空"
+	switch req.Operation {
+	case "", "drain":
+	case "resume":
+		resume, action = true, "恢复"
+	default:
+		return &proto.CommonResponse{
+			Code:    1004,
+			Message: fmt.Sprintf("不支持的操作: %s", req.Operation),
+		}, nil
+	}
+
+	var targetServices []*discovery.ServiceInfo
+	if req.ServiceId != "" {
+		service, err := cs.server.registry.GetService(req.ServiceId)
+		if err == nil {
+			targetServices = append(targetServices, service)
+		}
+	} else {
+		services, err := cs.server.registry.GetServices(req.ServiceType)
+		if err != nil {
+			log.Printf("获取服务类型 %s 失败: %v", req.ServiceType, err)
+			return &proto.CommonResponse{
+				Code:    1002,
+				Message: "获取目标服务失败",
+			}, nil
+		}
+		targetServices = services
+	}
+
+	if len(targetServices) == 0 {
+		return &proto.CommonResponse{
+			Code:    1003,
+			Message: "未找到目标服务",
+		}, nil
+	}
+
+	nodeIDs := make([]string, 0, len(targetServices))
+	for _, service := range targetServices {
+		if err := cs.server.messageBroker.SendToNode(service.NodeID, mq.SYS_CMD_DRAIN, map[string]interface{}{
+			"resume": resume,
+		}); err != nil {
+			log.Printf("发送排空命令给服务 %s 失败: %v", service.NodeID, err)
+			continue
+		}
+		nodeIDs = append(nodeIDs, service.NodeID)
+	}
+
+	log.Printf("操作员 %v 对 %d 个服务执行%s", ctx.Value("user_id"), len(nodeIDs), action)
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"nodes":  nodeIDs,
+		"resume": resume,
+	})
+
+	return &proto.CommonResponse{
+		Code:    0,
+		Message: fmt.Sprintf("命令已发送给 %d 个服务", len(nodeIDs)),
+		Data:    data,
+	}, nil
+}
+
+// SetCanary 设置灰度节点的流量权重，Canary为false时取消灰度标记，节点恢复为普通节点
+func (cs *CenterService) SetCanary(ctx context.Context, req *proto.CanaryRequest) (*proto.CommonResponse, error) {
+	if req.ServiceId == "" {
+		return &proto.CommonResponse{
+			Code:    1001,
+			Message: "服务ID不能为空",
+		}, nil
+	}
+	if req.Weight < 0 || req.Weight > discovery.MaxCanaryWeight {
+		return &proto.CommonResponse{
+			Code:    1004,
+			Message: fmt.Sprintf("灰度权重必须在0到%d之间", discovery.MaxCanaryWeight),
+		}, nil
+	}
+
+	if _, err := cs.server.registry.GetService(req.ServiceId); err != nil {
+		return &proto.CommonResponse{
+			Code:    1003,
+			Message: "未找到目标服务",
+		}, nil
+	}
+
+	if err := cs.server.messageBroker.SendToNode(req.ServiceId, mq.SYS_CMD_CANARY, map[string]interface{}{
+		"canary": req.Canary,
+		"weight": req.Weight,
+	}); err != nil {
+		log.Printf("发送灰度命令给服务 %s 失败: %v", req.ServiceId, err)
+		return &proto.CommonResponse{
+			Code:    1002,
+			Message: "发送灰度命令失败",
+		}, nil
+	}
+
+	log.Printf("操作员 %v 设置服务 %s 灰度: %v，权重 %d%%", ctx.Value("user_id"), req.ServiceId, req.Canary, req.Weight)
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"node":   req.ServiceId,
+		"canary": req.Canary,
+		"weight": req.Weight,
+	})
+
+	return &proto.CommonResponse{
+		Code:    0,
+		Message: "灰度命令已发送",
+		Data:    data,
+	}, nil
+}
+
+// SetMaintenance 开启或关闭维护模式，维护期间登录服务拒绝新的登录
+func (cs *CenterService) SetMaintenance(ctx context.Context, req *proto.MaintenanceRequest) (*proto.CommonResponse, error) {
+	operatorID, _ := ctx.Value("user_id").(uint64)
+
+	state, err := cs.server.setMaintenance(req.Enabled, req.Message, operatorID)
+	if err != nil {
+		log.Printf("设置维护模式失败: %v", err)
+		return &proto.CommonResponse{
+			Code:    1002,
+			Message: "设置维护模式失败",
+		}, nil
+	}
+
+	log.Printf("操作员 %d 设置维护模式: %v", operatorID, req.Enabled)
+
+	data, _ := json.Marshal(state)
+
+	return &proto.CommonResponse{
+		Code:    0,
+		Message: "维护模式已更新",
+		Data:    data,
+	}, nil
+}
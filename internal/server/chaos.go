@@ -0,0 +1,84 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// parseChaosCommand 解析GM的chaos命令参数，如 latency=30%:200ms error=10% drop=5% disconnect=1% duration=5m，
+// off清除规则
+func parseChaosCommand(args []string) (*proto.ChaosCommand, error) {
+	command := &proto.ChaosCommand{}
+	if len(args) == 1 && strings.ToLower(args[0]) == "off" {
+		return command, nil
+	}
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return nil, fmt.Errorf("无效的参数: %s", arg)
+		}
+		switch strings.ToLower(key) {
+		case "latency":
+			percent, latency, ok := strings.Cut(value, ":")
+			if !ok {
+				return nil, fmt.Errorf("latency格式为 比例%%:延迟，如 30%%:200ms")
+			}
+			p, err := parseChaosPercent(percent)
+			if err != nil {
+				return nil, err
+			}
+			d, err := time.ParseDuration(latency)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("无效的延迟: %s", latency)
+			}
+			command.LatencyPercent, command.LatencyMs = p, int32(d/time.Millisecond)
+		case "error":
+			p, err := parseChaosPercent(value)
+			if err != nil {
+				return nil, err
+			}
+			command.ErrorPercent = p
+		case "drop":
+			p, err := parseChaosPercent(value)
+			if err != nil {
+				return nil, err
+			}
+			command.DropPercent = p
+		case "disconnect":
+			p, err := parseChaosPercent(value)
+			if err != nil {
+				return nil, err
+			}
+			command.DisconnectPercent = p
+		case "duration":
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("无效的持续时间: %s", value)
+			}
+			command.DurationSec = int32(d / time.Second)
+		case "services":
+			command.Services = strings.Split(value, ",")
+		case "topics":
+			command.Topics = strings.Split(value, ",")
+		default:
+			return nil, fmt.Errorf("未知的参数: %s", key)
+		}
+	}
+	if command.LatencyPercent == 0 && command.ErrorPercent == 0 && command.DropPercent == 0 && command.DisconnectPercent == 0 {
+		return nil, fmt.Errorf("需要指定latency、error、drop或disconnect，清除规则使用off")
+	}
+	return command, nil
+}
+
+// parseChaosPercent 解析比例，如 10%
+func parseChaosPercent(value string) (int32, error) {
+	p, err := strconv.Atoi(strings.TrimSuffix(value, "%"))
+	if err != nil || p < 0 || p > 100 {
+		return 0, fmt.Errorf("无效的比例: %s", value)
+	}
+	return int32(p), nil
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/mq"
+)
+
+// deleteChatMessage GM删除聊天消息并记录GM操作日志。
+// 与玩家撤回一样清空内容保留墓碑并通知频道内的玩家，审计日志中保留原内容
+func (gs *GMServer) deleteChatMessage(gmUserID, messageID uint64, reason string) (string, error) {
+	message, err := gs.chatRepo.GetMessage(messageID)
+	if err != nil {
+		return "", err
+	}
+	if message == nil {
+		return "", fmt.Errorf("聊天消息不存在: %d", messageID)
+	}
+	if message.Recalled {
+		return fmt.Sprintf("聊天消息 %d 已被撤回", messageID), nil
+	}
+
+	deleted, err := gs.chatRepo.RecallMessage(messageID, 0, 0, gmUserID, uint32(time.Now().Unix()))
+	if err != nil {
+		return "", err
+	}
+	if deleted == nil {
+		return fmt.Sprintf("聊天消息 %d 已被撤回", messageID), nil
+	}
+	gs.publishChat(deleted, mq.CHAT_ACTION_RECALL)
+
+	gs.gmRepo.LogGMAction(gmUserID, "chat_delete", message.FromUserID,
+		fmt.Sprintf("消息ID: %d, 频道: %d, 内容: %s, 原因: %s", messageID, message.ChannelType, message.Content, reason))
+	logger.Info(fmt.Sprintf("Chat message %d of user %d deleted by GM %d: %s", messageID, message.FromUserID, gmUserID, reason))
+	return fmt.Sprintf("聊天消息 %d 已删除，原因: %s", messageID, reason), nil
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/phuhao00/lufy/internal/chatpolicy"
+	"github.com/phuhao00/lufy/internal/errcode"
+)
+
+// chatViolationCodes 违反频道发言限制时返回给客户端的错误码
+var chatViolationCodes = map[string]*errcode.Code{
+	chatpolicy.ViolationRate:  errcode.ChatRateLimited,
+	chatpolicy.ViolationLevel: errcode.ChatLevelTooLow,
+	chatpolicy.ViolationLink:  errcode.ChatLinkBlocked,
+
+	chatpolicy.ViolationProfanity: errcode.ChatProfanity,
+}
+
+// newChatPolicy 创建聊天频道发言限制服务
+func (bs *BaseServer) newChatPolicy() *chatpolicy.Service {
+	return chatpolicy.NewService(&bs.config.ChatPolicy, bs.redisManager)
+}
+
+// knownChatChannel 是否为聊天频道名
+func knownChatChannel(channel string) bool {
+	for _, name := range chatChannelNames {
+		if name == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// chatPolicyCommand 查看或修改频道的发言限制：
+// 无参数时列出所有频道，<频道> reset 恢复配置文件的设置，<频道> <项>=<值>... 修改指定的项
+func (gs *GMServer) chatPolicyCommand(gmUserID uint64, args []string) (string, error) {
+	if len(args) == 0 {
+		data, err := json.Marshal(gs.chatPolicy.Policies())
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+
+	channel := strings.ToLower(args[0])
+	if !knownChatChannel(channel) {
+		return "", fmt.Errorf("无效的聊天频道: %s", args[0])
+	}
+	if len(args) == 1 {
+		data, err := json.Marshal(gs.chatPolicy.Policy(channel))
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+
+	if strings.ToLower(args[1]) == "reset" {
+		if err := gs.chatPolicy.ResetPolicy(channel); err != nil {
+			return "", err
+		}
+		gs.gmRepo.LogGMAction(gmUserID, "chat_policy_reset", 0, fmt.Sprintf("频道: %s", channel))
+		return fmt.Sprintf("频道 %s 已恢复配置文件的发言限制", channel), nil
+	}
+
+	policy := gs.chatPolicy.Policy(channel)
+	for _, arg := range args[1:] {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return "", fmt.Errorf("无效的参数: %s，格式为 项=值", arg)
+		}
+		var err error
+		switch strings.ToLower(key) {
+		case "per_minute":
+			policy.PerMinute, err = strconv.Atoi(value)
+		case "min_level":
+			var level int64
+			level, err = strconv.ParseInt(value, 10, 32)
+			policy.MinLevel = int32(level)
+		case "block_links":
+			policy.BlockLinks, err = strconv.ParseBool(value)
+		case "block_profanity":
+			policy.BlockProfanity, err = strconv.ParseBool(value)
+		default:
+			return "", fmt.Errorf("未知的发言限制项: %s", key)
+		}
+		if err != nil {
+			return "", fmt.Errorf("无效的值: %s", arg)
+		}
+	}
+
+	if err := gs.chatPolicy.SetPolicy(channel, policy); err != nil {
+		return "", err
+	}
+	gs.gmRepo.LogGMAction(gmUserID, "chat_policy", 0, fmt.Sprintf("频道: %s, 每分钟: %d, 最低等级: %d, 禁止链接: %t, 屏蔽词: %t",
+		channel, policy.PerMinute, policy.MinLevel, policy.BlockLinks, policy.BlockProfanity))
+	return fmt.Sprintf("频道 %s 的发言限制已修改: 每分钟 %d 条，最低等级 %d，禁止链接 %t，屏蔽词 %t",
+		channel, policy.PerMinute, policy.MinLevel, policy.BlockLinks, policy.BlockProfanity), nil
+}
@@ -3,24 +3,43 @@ package server
 import (
 	"context"
 	"fmt"
-	"reflect"
-	"sync"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/phuhao00/lufy/internal/chatpolicy"
 	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/errcode"
 	"github.com/phuhao00/lufy/internal/logger"
 	"github.com/phuhao00/lufy/internal/mq"
+	"github.com/phuhao00/lufy/internal/privacy"
+	"github.com/phuhao00/lufy/internal/settings"
+	"github.com/phuhao00/lufy/internal/timeline"
+	"github.com/phuhao00/lufy/internal/world"
 	"github.com/phuhao00/lufy/pkg/proto"
 )
 
 // ChatServer 聊天服务器
 type ChatServer struct {
 	*BaseServer
-	chatRepo      *database.ChatRepository
-	userRepo      *database.UserRepository
-	nextMessageID uint64
-	idMutex       sync.Mutex
+	chatRepo   *database.ChatRepository
+	userRepo   *database.UserRepository
+	friendRepo *database.FriendRepository
+	settings   *settings.Service
+	timeline   *timeline.Recorder
+	chatPolicy *chatpolicy.Service
+	anonymizer *privacy.ChatAnonymizer
 }
 
+// 聊天消息设置
+const (
+	chatMessageIDKey     = "chat:next_message_id"
+	maxChatMessageLength = 500
+	maxChatHistoryLimit  = 100
+)
+
 // NewChatServer 创建聊天服务器
 func NewChatServer(configFile, nodeID string) *ChatServer {
 	baseServer, err := NewBaseServer(configFile, "chat", nodeID)
@@ -35,6 +54,11 @@ func NewChatServer(configFile, nodeID string) *ChatServer {
 	// 初始化数据库仓库
 	chatServer.chatRepo = database.NewChatRepository(baseServer.mongoManager)
 	chatServer.userRepo = database.NewUserRepository(baseServer.mongoManager)
+	chatServer.friendRepo = database.NewFriendRepository(baseServer.mongoManager)
+	chatServer.settings = baseServer.newSettings()
+	chatServer.timeline = baseServer.newTimeline()
+	chatServer.chatPolicy = baseServer.newChatPolicy()
+	chatServer.anonymizer = privacy.NewChatAnonymizer(baseServer.mongoManager, &baseServer.config.Privacy.ChatAnonymization)
 
 	// TODO: 创建聊天消息处理器
 
@@ -45,13 +69,16 @@ func NewChatServer(configFile, nodeID string) *ChatServer {
 
 	// 注册聊天服务
 	chatService := NewChatService(chatServer)
-	if err := baseServer.rpcServer.RegisterService(chatService); err != nil {
+	if err := RegisterChatService(baseServer.rpcServer, chatService); err != nil {
 		logger.Fatal(fmt.Sprintf("Failed to register chat service: %v", err))
 	}
 
 	// 订阅聊天消息 - 简化实现
 	// TODO: 实现消息订阅逻辑
 
+	// 聊天记录匿名化任务
+	baseServer.RegisterBackground("chat_anonymizer", chatServer.anonymizer.Run)
+
 	return chatServer
 }
 
@@ -59,59 +86,463 @@ func NewChatServer(configFile, nodeID string) *ChatServer {
 func (cs *ChatServer) handleChatMessage(msg *mq.ChatMessage) error {
 	logger.Debug(fmt.Sprintf("Received chat message from %d to %d: %s", msg.FromUserID, msg.ToUserID, msg.Content))
 
+	if msg.ToUserID != 0 {
+		sender, err := cs.userRepo.GetByUserID(msg.FromUserID)
+		if err != nil {
+			return fmt.Errorf("failed to get sender %d: %v", msg.FromUserID, err)
+		}
+		recipient, err := cs.userRepo.GetByUserID(msg.ToUserID)
+		if err != nil {
+			return fmt.Errorf("failed to get recipient %d: %v", msg.ToUserID, err)
+		}
+		if !cs.canWhisper(sender, recipient) {
+			return nil
+		}
+	}
+
 	// TODO: 实现聊天消息处理逻辑
 	// 比如过滤敏感词、存储历史记录等
 
 	return nil
 }
 
+// canWhisper 私聊能否送达：跨世界私聊需要在配置中开启，且接收者的隐私设置允许发送者私聊
+func (cs *ChatServer) canWhisper(sender, recipient *database.User) bool {
+	if !cs.config.World.Allows(world.ActionChat, sender.WorldID, recipient.WorldID) {
+		logger.Warn(fmt.Sprintf("Dropped cross-world chat message from %d (world %d) to %d (world %d)",
+			sender.UserID, sender.WorldID, recipient.UserID, recipient.WorldID))
+		return false
+	}
+	if !cs.whisperAllowed(sender.UserID, recipient.UserID) {
+		logger.Debug(fmt.Sprintf("Dropped chat message from %d to %d by privacy settings", sender.UserID, recipient.UserID))
+		return false
+	}
+	return true
+}
+
+// whisperAllowed 接收者的隐私设置是否允许发送者私聊
+func (cs *ChatServer) whisperAllowed(fromUserID, toUserID uint64) bool {
+	friend, err := cs.friendRepo.IsFriend(toUserID, fromUserID)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to check friendship of %d and %d: %v", toUserID, fromUserID, err))
+		return false
+	}
+	allowed, err := cs.settings.Allows(toUserID, settings.ActionWhisper, fromUserID, friend)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to check whisper setting of user %d: %v", toUserID, err))
+		return false
+	}
+	return allowed
+}
+
+// generateMessageID 生成全局唯一的聊天消息ID
+func (cs *ChatServer) generateMessageID() (uint64, error) {
+	id, err := cs.redisManager.Incr(chatMessageIDKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate chat message id: %v", err)
+	}
+	return uint64(id), nil
+}
+
+// chatMessageProto 转换为客户端的聊天消息
+func chatMessageProto(message *database.ChatMessage) *proto.ChatMessage {
+	return &proto.ChatMessage{
+		MessageId:   message.MessageID,
+		FromUserId:  message.FromUserID,
+		ToUserId:    message.ToUserID,
+		ChannelType: message.ChannelType,
+		ChannelId:   message.ChannelID,
+		MessageType: message.MessageType,
+		Content:     message.Content,
+		SendTime:    message.SendTime,
+		EditTime:    message.EditedAt,
+		Recalled:    message.Recalled,
+		Reactions:   reactionCounts(message.Reactions),
+	}
+}
+
+// reactionCounts 转换为客户端的回应人数，按表情ID排序，跳过已没有人回应的表情
+func reactionCounts(counts map[string]int32) []*proto.ReactionCount {
+	var reactions []*proto.ReactionCount
+	for emoji, count := range counts {
+		emojiID, err := strconv.ParseInt(emoji, 10, 32)
+		if err != nil || count <= 0 {
+			continue
+		}
+		reactions = append(reactions, &proto.ReactionCount{EmojiId: int32(emojiID), Count: count})
+	}
+	sort.Slice(reactions, func(i, j int) bool {
+		return reactions[i].EmojiId < reactions[j].EmojiId
+	})
+	return reactions
+}
+
+// publishChat 通过消息队列下发新消息或消息的编辑、撤回，action为空时为新消息。影子封禁的消息不下发
+func (bs *BaseServer) publishChat(message *database.ChatMessage, action string) {
+	if message.Shadow {
+		return
+	}
+	msg := mq.NewChatMessage(message.FromUserID, message.ToUserID, message.ChannelType, message.Content)
+	msg.Timestamp = int64(message.SendTime)
+	msg.MessageID = message.MessageID
+	msg.ChannelID = message.ChannelID
+	msg.Action = action
+	msg.EditTime = int64(message.EditedAt)
+	msg.Reactions = message.Reactions
+	if err := bs.messageBroker.PublishChat(msg); err != nil {
+		logger.Error(fmt.Sprintf("Failed to publish chat message %d: %v", message.MessageID, err))
+	}
+}
+
 // ChatService 聊天RPC服务
 type ChatService struct {
-	server *ChatServer
+	server    *ChatServer
+	responses *errcode.Builder
 }
 
 // NewChatService 创建聊天服务
 func NewChatService(server *ChatServer) *ChatService {
 	return &ChatService{
-		server: server,
+		server:    server,
+		responses: errcode.NewBuilder(nil),
+	}
+}
+
+// SendMessage 发送消息，保存后通过消息队列由网关下发，只转发不保存的房间聊天直接下发。
+// 影子封禁的玩家的消息只保存为自己可见，不下发给其他玩家，发送者收到的响应与正常发送相同
+func (cs *ChatService) SendMessage(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	userID := req.Header.GetUserId()
+	if userID == 0 {
+		return cs.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
+	}
+
+	var sendReq proto.SendMessageRequest
+	if err := proto.Unmarshal(req.Data, &sendReq); err != nil {
+		logger.Error(fmt.Sprintf("SendMessage: failed to unmarshal request: %v", err))
+		return cs.responses.Error(ctx, req.Header, errcode.InvalidRequest), nil
+	}
+	content := strings.TrimSpace(sendReq.GetContent())
+	if content == "" || utf8.RuneCountInString(content) > maxChatMessageLength {
+		return cs.responses.Error(ctx, req.Header, errcode.ChatContentInvalid), nil
+	}
+	channelType := sendReq.GetChannelType()
+	channel, ok := chatChannelNames[channelType]
+	if !ok {
+		return cs.responses.Error(ctx, req.Header, errcode.ChatChannelInvalid), nil
+	}
+
+	sender, err := cs.server.userRepo.GetByUserID(userID)
+	if err != nil {
+		logger.Error(fmt.Sprintf("SendMessage: failed to get user %d: %v", userID, err))
+		return cs.responses.Error(ctx, req.Header, errcode.UserNotFound), nil
+	}
+
+	// 私聊发给指定玩家，其他频道消息发给频道内所有玩家
+	toUserID := uint64(0)
+	if channelType == mq.CHAT_CHANNEL_FRIEND {
+		toUserID = sendReq.GetToUserId()
+		if toUserID == 0 || toUserID == userID {
+			return cs.responses.Error(ctx, req.Header, errcode.ChatChannelInvalid), nil
+		}
+		recipient, err := cs.server.userRepo.GetByUserID(toUserID)
+		if err != nil {
+			return cs.responses.Error(ctx, req.Header, errcode.UserNotFound), nil
+		}
+		if !cs.server.canWhisper(sender, recipient) {
+			return cs.responses.Error(ctx, req.Header, errcode.ChatNotAllowed), nil
+		}
+	}
+
+	// 检查频道的慢速模式、发言等级和链接限制
+	violation, err := cs.server.chatPolicy.Check(channel, userID, sender.Level, content, time.Now())
+	if err != nil {
+		logger.Error(fmt.Sprintf("SendMessage: failed to check chat policy: %v", err))
+		return cs.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+	if violation != "" {
+		logger.Debug(fmt.Sprintf("Rejected chat message from user %d in channel %s: %s", userID, channel, violation))
+		return cs.responses.Error(ctx, req.Header, chatViolationCodes[violation]), nil
+	}
+
+	messageID, err := cs.server.generateMessageID()
+	if err != nil {
+		logger.Error(fmt.Sprintf("SendMessage: %v", err))
+		return cs.responses.Error(ctx, req.Header, errcode.SendMessageFailed), nil
+	}
+	message := &database.ChatMessage{
+		MessageID:   messageID,
+		FromUserID:  userID,
+		ToUserID:    toUserID,
+		ChannelType: channelType,
+		ChannelID:   sendReq.GetChannelId(),
+		MessageType: sendReq.GetMessageType(),
+		Content:     content,
+		SendTime:    uint32(time.Now().Unix()),
+		Region:      sender.Region,
+		WorldID:     sender.WorldID,
+		Shadow:      sender.ShadowBanned,
+	}
+	// 只转发不保存的房间聊天不写入数据库，无法编辑、撤回和回应
+	if channelType != mq.CHAT_CHANNEL_ROOM || !cs.server.ephemeralRoomChat(message.ChannelID) {
+		if err := cs.server.chatRepo.SaveMessage(message); err != nil {
+			logger.Error(fmt.Sprintf("SendMessage: failed to save message from user %d: %v", userID, err))
+			return cs.responses.Error(ctx, req.Header, errcode.SendMessageFailed), nil
+		}
 	}
+	cs.server.timeline.Chat(userID, channel)
+	cs.server.publishChat(message, "")
+
+	return cs.messageResponse(ctx, req.Header, message, "message sent")
 }
 
-// GetName 获取服务名称
-func (cs *ChatService) GetName() string {
-	return "ChatService"
+// messageResponse 构造包含聊天消息的响应
+func (cs *ChatService) messageResponse(ctx context.Context, header *proto.MessageHeader, message *database.ChatMessage, msg string) (*proto.BaseResponse, error) {
+	data, err := proto.Marshal(chatMessageProto(message))
+	if err != nil {
+		logger.Error(fmt.Sprintf("messageResponse: failed to marshal message %d: %v", message.MessageID, err))
+		return cs.responses.Error(ctx, header, errcode.Internal), nil
+	}
+	return cs.responses.Success(ctx, header, msg, data), nil
 }
 
-// RegisterMethods 注册方法
-func (cs *ChatService) RegisterMethods() map[string]reflect.Value {
-	methods := make(map[string]reflect.Value)
+// ownMessage 获取玩家自己发送且未撤回的消息，不满足时返回对应的错误码
+func (cs *ChatService) ownMessage(userID, messageID uint64) (*database.ChatMessage, *errcode.Code) {
+	message, err := cs.server.chatRepo.GetMessage(messageID)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to get chat message %d: %v", messageID, err))
+		return nil, errcode.Internal
+	}
+	switch {
+	case message == nil:
+		return nil, errcode.ChatMessageNotFound
+	case message.FromUserID != userID:
+		return nil, errcode.ChatNotMessageOwner
+	case message.Recalled:
+		return nil, errcode.ChatMessageRecalled
+	}
+	return message, nil
+}
 
-	methods["SendMessage"] = reflect.ValueOf(cs.SendMessage)
-	methods["GetChatHistory"] = reflect.ValueOf(cs.GetChatHistory)
-	methods["BlockUser"] = reflect.ValueOf(cs.BlockUser)
-	methods["UnblockUser"] = reflect.ValueOf(cs.UnblockUser)
+// EditMessage 在编辑时限内修改自己发送的消息，修改后的内容同样受频道发言限制，并通知频道内的玩家更新消息
+func (cs *ChatService) EditMessage(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	userID := req.Header.GetUserId()
+	if userID == 0 {
+		return cs.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
+	}
 
-	return methods
+	var editReq proto.EditMessageRequest
+	if err := proto.Unmarshal(req.Data, &editReq); err != nil {
+		logger.Error(fmt.Sprintf("EditMessage: failed to unmarshal request: %v", err))
+		return cs.responses.Error(ctx, req.Header, errcode.InvalidRequest), nil
+	}
+	content := strings.TrimSpace(editReq.GetContent())
+	if content == "" || utf8.RuneCountInString(content) > maxChatMessageLength {
+		return cs.responses.Error(ctx, req.Header, errcode.ChatContentInvalid), nil
+	}
+
+	message, code := cs.ownMessage(userID, editReq.GetMessageId())
+	if code != nil {
+		return cs.responses.Error(ctx, req.Header, code), nil
+	}
+	now := time.Now()
+	deadline, ok := cs.server.chatPolicy.EditDeadline(now)
+	if !ok || int64(message.SendTime) < deadline.Unix() {
+		return cs.responses.Error(ctx, req.Header, errcode.ChatEditExpired), nil
+	}
+
+	sender, err := cs.server.userRepo.GetByUserID(userID)
+	if err != nil {
+		logger.Error(fmt.Sprintf("EditMessage: failed to get user %d: %v", userID, err))
+		return cs.responses.Error(ctx, req.Header, errcode.UserNotFound), nil
+	}
+	channel := chatChannelNames[message.ChannelType]
+	violation, err := cs.server.chatPolicy.Check(channel, userID, sender.Level, content, now)
+	if err != nil {
+		logger.Error(fmt.Sprintf("EditMessage: failed to check chat policy: %v", err))
+		return cs.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+	if violation != "" {
+		logger.Debug(fmt.Sprintf("Rejected chat message edit from user %d in channel %s: %s", userID, channel, violation))
+		return cs.responses.Error(ctx, req.Header, chatViolationCodes[violation]), nil
+	}
+
+	// 条件更新，期间被撤回或被GM删除时不覆盖墓碑
+	edited, err := cs.server.chatRepo.EditMessage(message.MessageID, userID, content, uint32(deadline.Unix()), uint32(now.Unix()))
+	if err != nil {
+		logger.Error(fmt.Sprintf("EditMessage: %v", err))
+		return cs.responses.Error(ctx, req.Header, errcode.SendMessageFailed), nil
+	}
+	if edited == nil {
+		return cs.responses.Error(ctx, req.Header, errcode.ChatMessageRecalled), nil
+	}
+	cs.server.publishChat(edited, mq.CHAT_ACTION_EDIT)
+
+	return cs.messageResponse(ctx, req.Header, edited, "message edited")
 }
 
-// SendMessage 发送消息
-func (cs *ChatService) SendMessage(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
-	// TODO: 实现发送消息逻辑
-	return &proto.BaseResponse{
-		Header: req.Header,
-		Code:   0,
-		Msg:    "message sent",
-	}, nil
+// RecallMessage 在撤回时限内撤回自己发送的消息，保留不含内容的墓碑记录，并通知频道内的玩家更新消息
+func (cs *ChatService) RecallMessage(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	userID := req.Header.GetUserId()
+	if userID == 0 {
+		return cs.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
+	}
+
+	var recallReq proto.RecallMessageRequest
+	if err := proto.Unmarshal(req.Data, &recallReq); err != nil {
+		logger.Error(fmt.Sprintf("RecallMessage: failed to unmarshal request: %v", err))
+		return cs.responses.Error(ctx, req.Header, errcode.InvalidRequest), nil
+	}
+
+	message, code := cs.ownMessage(userID, recallReq.GetMessageId())
+	if code != nil {
+		return cs.responses.Error(ctx, req.Header, code), nil
+	}
+	now := time.Now()
+	deadline, ok := cs.server.chatPolicy.RecallDeadline(now)
+	if !ok || int64(message.SendTime) < deadline.Unix() {
+		return cs.responses.Error(ctx, req.Header, errcode.ChatRecallExpired), nil
+	}
+
+	recalled, err := cs.server.chatRepo.RecallMessage(message.MessageID, userID, uint32(deadline.Unix()), userID, uint32(now.Unix()))
+	if err != nil {
+		logger.Error(fmt.Sprintf("RecallMessage: %v", err))
+		return cs.responses.Error(ctx, req.Header, errcode.SendMessageFailed), nil
+	}
+	if recalled == nil {
+		return cs.responses.Error(ctx, req.Header, errcode.ChatMessageRecalled), nil
+	}
+	cs.server.publishChat(recalled, mq.CHAT_ACTION_RECALL)
+
+	logger.Info(fmt.Sprintf("User %d recalled chat message %d", userID, message.MessageID))
+	return cs.messageResponse(ctx, req.Header, recalled, "message recalled")
 }
 
-// GetChatHistory 获取聊天历史
+// ReactMessage 用配置中允许的表情回应可见的消息，每人每条消息一个回应，EmojiId为0时取消回应。
+// 回应人数变化后通知频道内的玩家，影子封禁的玩家的回应只保存不计数
+func (cs *ChatService) ReactMessage(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	userID := req.Header.GetUserId()
+	if userID == 0 {
+		return cs.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
+	}
+
+	var reactReq proto.ReactMessageRequest
+	if err := proto.Unmarshal(req.Data, &reactReq); err != nil {
+		logger.Error(fmt.Sprintf("ReactMessage: failed to unmarshal request: %v", err))
+		return cs.responses.Error(ctx, req.Header, errcode.InvalidRequest), nil
+	}
+	emojiID := reactReq.GetEmojiId()
+	if emojiID != 0 && !cs.server.chatPolicy.ReactionAllowed(emojiID) {
+		return cs.responses.Error(ctx, req.Header, errcode.ChatReactionInvalid), nil
+	}
+
+	message, err := cs.server.chatRepo.GetMessage(reactReq.GetMessageId())
+	if err != nil {
+		logger.Error(fmt.Sprintf("ReactMessage: %v", err))
+		return cs.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+	// 影子封禁的消息和别人之间的私聊对该玩家不可见
+	if message == nil || (message.Shadow && message.FromUserID != userID) ||
+		(message.ToUserID != 0 && message.FromUserID != userID && message.ToUserID != userID) {
+		return cs.responses.Error(ctx, req.Header, errcode.ChatMessageNotFound), nil
+	}
+	if message.Recalled {
+		return cs.responses.Error(ctx, req.Header, errcode.ChatMessageRecalled), nil
+	}
+
+	user, err := cs.server.userRepo.GetByUserID(userID)
+	if err != nil {
+		logger.Error(fmt.Sprintf("ReactMessage: failed to get user %d: %v", userID, err))
+		return cs.responses.Error(ctx, req.Header, errcode.UserNotFound), nil
+	}
+
+	updated, err := cs.server.chatRepo.SetReaction(message.MessageID, userID, emojiID, user.ShadowBanned)
+	if err != nil {
+		logger.Error(fmt.Sprintf("ReactMessage: %v", err))
+		return cs.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+	if updated == nil {
+		return cs.responses.Error(ctx, req.Header, errcode.ChatMessageNotFound), nil
+	}
+	if !user.ShadowBanned {
+		cs.server.publishChat(updated, mq.CHAT_ACTION_REACT)
+	}
+
+	result := chatMessageProto(updated)
+	result.MyReaction = emojiID
+	data, err := proto.Marshal(result)
+	if err != nil {
+		logger.Error(fmt.Sprintf("ReactMessage: failed to marshal response: %v", err))
+		return cs.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+	return cs.responses.Success(ctx, req.Header, "success", data), nil
+}
+
+// GetChatHistory 获取聊天历史，私聊的ChannelId为对方的用户ID，影子封禁的消息只有发送者自己可见
 func (cs *ChatService) GetChatHistory(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
-	// TODO: 实现获取聊天历史逻辑
-	return &proto.BaseResponse{
-		Header: req.Header,
-		Code:   0,
-		Msg:    "success",
-	}, nil
+	userID := req.Header.GetUserId()
+	if userID == 0 {
+		return cs.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
+	}
+
+	var historyReq proto.ChatHistoryRequest
+	if err := proto.Unmarshal(req.Data, &historyReq); err != nil {
+		logger.Error(fmt.Sprintf("GetChatHistory: failed to unmarshal request: %v", err))
+		return cs.responses.Error(ctx, req.Header, errcode.InvalidRequest), nil
+	}
+	channelType := historyReq.GetChannelType()
+	if _, ok := chatChannelNames[channelType]; !ok {
+		return cs.responses.Error(ctx, req.Header, errcode.ChatChannelInvalid), nil
+	}
+	limit := historyReq.GetLimit()
+	if limit <= 0 || limit > maxChatHistoryLimit {
+		limit = maxChatHistoryLimit
+	}
+	offset := historyReq.GetOffset()
+	if offset < 0 {
+		offset = 0
+	}
+
+	var (
+		messages []*database.ChatMessage
+		total    int64
+		err      error
+	)
+	if channelType == mq.CHAT_CHANNEL_FRIEND {
+		messages, total, err = cs.server.chatRepo.GetPrivateMessages(userID, historyReq.GetChannelId(), limit, offset)
+	} else {
+		user, userErr := cs.server.userRepo.GetByUserID(userID)
+		if userErr != nil {
+			logger.Error(fmt.Sprintf("GetChatHistory: failed to get user %d: %v", userID, userErr))
+			return cs.responses.Error(ctx, req.Header, errcode.UserNotFound), nil
+		}
+		messages, total, err = cs.server.chatRepo.GetChatHistory(userID, user.WorldID, channelType, historyReq.GetChannelId(), limit, offset)
+	}
+	if err != nil {
+		logger.Error(fmt.Sprintf("GetChatHistory: failed to get chat history of user %d: %v", userID, err))
+		return cs.responses.Error(ctx, req.Header, errcode.ChatHistoryFailed), nil
+	}
+
+	messageIDs := make([]uint64, 0, len(messages))
+	for _, message := range messages {
+		messageIDs = append(messageIDs, message.MessageID)
+	}
+	reactions, err := cs.server.chatRepo.GetUserReactions(userID, messageIDs)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("GetChatHistory: failed to get reactions of user %d: %v", userID, err))
+	}
+
+	history := &proto.ChatHistoryResponse{Total: int32(total)}
+	for _, message := range messages {
+		result := chatMessageProto(message)
+		result.MyReaction = reactions[message.MessageID]
+		history.Messages = append(history.Messages, result)
+	}
+	data, err := proto.Marshal(history)
+	if err != nil {
+		logger.Error(fmt.Sprintf("GetChatHistory: failed to marshal response: %v", err))
+		return cs.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+	return cs.responses.Success(ctx, req.Header, "success", data), nil
 }
 
 // BlockUser 屏蔽用户
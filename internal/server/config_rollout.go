@@ -0,0 +1,390 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/phuhao00/lufy/internal/hotreload"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/mq"
+)
+
+// configRolloutKeyPrefix 分阶段更新中各节点回报结果的Redis哈希，字段为"阶段:节点ID"
+const configRolloutKeyPrefix = "config_rollout:"
+
+// 分阶段更新的阶段
+const (
+	rolloutPhasePrepare = "prepare"
+	rolloutPhaseCommit  = "commit"
+)
+
+// defaultRolloutTimeout 默认等待节点回报结果的超时
+const defaultRolloutTimeout = 10 * time.Second
+
+// clusterNodeTypes 集群中的节点类型
+var clusterNodeTypes = []string{"gateway", "login", "lobby", "game", "friend", "chat", "mail", "gm", "center", "probe"}
+
+// serverConfigParser 服务器配置解析器，校验配置能否解析为ServerConfig
+type serverConfigParser struct {
+	hotreload.YAMLConfigParser
+}
+
+// Validate 验证配置
+func (p *serverConfigParser) Validate(data interface{}) error {
+	if err := p.YAMLConfigParser.Validate(data); err != nil {
+		return err
+	}
+
+	raw, err := yaml.Marshal(data)
+	if err != nil {
+		return err
+	}
+	var config ServerConfig
+	if err := yaml.Unmarshal(raw, &config); err != nil {
+		return fmt.Errorf("invalid server config: %v", err)
+	}
+
+	ports := map[string]int{
+		"network.tcp_port":  config.Network.TCPPort,
+		"network.rpc_port":  config.Network.RPCPort,
+		"network.http_port": config.Network.HTTPPort,
+	}
+	for name, port := range ports {
+		if port < 0 || port > 65535 {
+			return fmt.Errorf("%s out of range: %d", name, port)
+		}
+	}
+	switch config.MessageQueue.Backend {
+	case "", "nsq", "kafka":
+	default:
+		return fmt.Errorf("unknown message queue backend: %s", config.MessageQueue.Backend)
+	}
+	if err := config.World.Validate(); err != nil {
+		return fmt.Errorf("invalid world config: %v", err)
+	}
+	if err := config.Activity.Validate(); err != nil {
+		return fmt.Errorf("invalid activity config: %v", err)
+	}
+	if err := config.Progression.Validate(); err != nil {
+		return fmt.Errorf("invalid progression config: %v", err)
+	}
+	if err := config.Exchange.Validate(); err != nil {
+		return fmt.Errorf("invalid exchange config: %v", err)
+	}
+	if err := config.Fraud.Validate(); err != nil {
+		return fmt.Errorf("invalid fraud config: %v", err)
+	}
+	if err := config.Timeline.Validate(); err != nil {
+		return fmt.Errorf("invalid timeline config: %v", err)
+	}
+	if err := config.Segment.Validate(); err != nil {
+		return fmt.Errorf("invalid segment config: %v", err)
+	}
+	if err := config.ChatPolicy.Validate(); err != nil {
+		return fmt.Errorf("invalid chat policy config: %v", err)
+	}
+	if err := config.Settings.Validate(); err != nil {
+		return fmt.Errorf("invalid settings config: %v", err)
+	}
+	if err := config.Naming.Validate(); err != nil {
+		return fmt.Errorf("invalid naming config: %v", err)
+	}
+	if err := config.Search.Validate(); err != nil {
+		return fmt.Errorf("invalid search config: %v", err)
+	}
+	if err := config.Quota.Validate(); err != nil {
+		return fmt.Errorf("invalid quota config: %v", err)
+	}
+	if err := config.VIP.Validate(); err != nil {
+		return fmt.Errorf("invalid vip config: %v", err)
+	}
+	if err := config.News.Validate(); err != nil {
+		return fmt.Errorf("invalid news config: %v", err)
+	}
+	if err := config.Triggers.Validate(); err != nil {
+		return fmt.Errorf("invalid triggers config: %v", err)
+	}
+	if err := config.Denorm.Validate(); err != nil {
+		return fmt.Errorf("invalid denorm config: %v", err)
+	}
+	if err := config.Realtime.Validate(); err != nil {
+		return fmt.Errorf("invalid realtime config: %v", err)
+	}
+	if err := config.Rollup.Validate(); err != nil {
+		return fmt.Errorf("invalid rollup config: %v", err)
+	}
+	if err := config.RPC.Lanes.Validate(); err != nil {
+		return fmt.Errorf("invalid rpc lanes config: %v", err)
+	}
+
+	return nil
+}
+
+// ConfigRolloutResult 单个节点在某个阶段的执行结果
+type ConfigRolloutResult struct {
+	NodeID  string   `json:"node_id"`
+	Phase   string   `json:"phase"`
+	Success bool     `json:"success"`
+	Version string   `json:"version,omitempty"` // 节点校验通过的配置版本
+	Changes []string `json:"changes,omitempty"` // 与节点当前配置的差异，敏感配置项已隐藏
+	Error   string   `json:"error,omitempty"`
+}
+
+// ConfigRolloutOptions 分阶段更新参数
+type ConfigRolloutOptions struct {
+	Targets []string // 本次提交的节点，为空时提交到所有节点
+	DryRun  bool     // 只在所有节点校验并返回差异，不提交
+}
+
+// ConfigRolloutReport 分阶段更新报告
+type ConfigRolloutReport struct {
+	ID        string                          `json:"id"`
+	Nodes     []string                        `json:"nodes"`   // 参与校验的节点
+	Targets   []string                        `json:"targets"` // 提交的节点
+	DryRun    bool                            `json:"dry_run"`
+	Prepared  map[string]*ConfigRolloutResult `json:"prepared"`
+	Committed map[string]*ConfigRolloutResult `json:"committed,omitempty"`
+	Aborted   bool                            `json:"aborted"`
+	Reason    string                          `json:"reason,omitempty"`
+}
+
+// Summary 报告摘要，每个节点一行
+func (r *ConfigRolloutReport) Summary() string {
+	lines := []string{fmt.Sprintf("配置更新 %s: 校验 %d 个节点，提交 %d 个节点", r.ID, len(r.Nodes), len(r.Committed))}
+	for _, nodeID := range r.Nodes {
+		prepared := r.Prepared[nodeID]
+		line := fmt.Sprintf("%s: 校验%s", nodeID, resultText(prepared))
+		if prepared != nil && prepared.Success {
+			line += fmt.Sprintf("(版本 %s，%d 项变更)", prepared.Version, len(prepared.Changes))
+		}
+		if committed, exists := r.Committed[nodeID]; exists {
+			line += ", 提交" + resultText(committed)
+		}
+		lines = append(lines, line)
+	}
+	switch {
+	case r.Aborted:
+		lines = append(lines, "已中止: "+r.Reason)
+	case r.DryRun:
+		lines = append(lines, "仅校验，未提交")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// resultText 节点结果的描述
+func resultText(result *ConfigRolloutResult) string {
+	switch {
+	case result == nil:
+		return "无结果"
+	case result.Success:
+		return "成功"
+	default:
+		return "失败: " + result.Error
+	}
+}
+
+// listClusterNodes 获取所有在线节点，超过一分钟未更新的节点视为离线
+func (bs *BaseServer) listClusterNodes() []string {
+	nodes := make([]string, 0)
+	for _, nodeType := range clusterNodeTypes {
+		services, err := bs.registry.GetServices(nodeType)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to get services for %s: %v", nodeType, err))
+			continue
+		}
+		for _, service := range services {
+			if time.Now().Unix()-service.UpdateTime > 60 {
+				continue
+			}
+			nodes = append(nodes, service.NodeID)
+		}
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// runConfigRollout 分阶段更新配置
+//
+// 先让所有在线节点校验各自的配置文件，任一节点失败则整体中止；全部通过后只提交到目标节点，
+// 其余节点丢弃暂存的配置，可以先提交少量节点观察，再不带目标节点执行一次完成全量更新
+func (bs *BaseServer) runConfigRollout(opts ConfigRolloutOptions) (*ConfigRolloutReport, error) {
+	nodes := bs.listClusterNodes()
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no online nodes")
+	}
+
+	targets := nodes
+	if len(opts.Targets) > 0 {
+		online := make(map[string]bool, len(nodes))
+		for _, nodeID := range nodes {
+			online[nodeID] = true
+		}
+		for _, nodeID := range opts.Targets {
+			if !online[nodeID] {
+				return nil, fmt.Errorf("node %s is not online", nodeID)
+			}
+		}
+		targets = opts.Targets
+	}
+
+	report := &ConfigRolloutReport{
+		ID:        fmt.Sprintf("%s-%d", bs.nodeID, time.Now().UnixNano()),
+		Nodes:     nodes,
+		Targets:   targets,
+		DryRun:    opts.DryRun,
+		Committed: make(map[string]*ConfigRolloutResult),
+	}
+
+	bs.mutex.RLock()
+	timeout := bs.config.HotReload.RolloutTimeout
+	bs.mutex.RUnlock()
+	if timeout <= 0 {
+		timeout = defaultRolloutTimeout
+	}
+
+	// 第一阶段: 所有节点校验
+	for _, nodeID := range nodes {
+		if err := bs.messageBroker.SendToNode(nodeID, mq.SYS_CMD_CONFIG_PREPARE, map[string]interface{}{
+			"rollout_id": report.ID,
+		}); err != nil {
+			return nil, fmt.Errorf("failed to send prepare to %s: %v", nodeID, err)
+		}
+	}
+	report.Prepared = bs.waitRolloutResults(report.ID, rolloutPhasePrepare, nodes, timeout)
+
+	for _, nodeID := range nodes {
+		if result := report.Prepared[nodeID]; !result.Success {
+			report.Aborted = true
+			report.Reason = fmt.Sprintf("node %s failed to validate: %s", nodeID, result.Error)
+			break
+		}
+	}
+
+	if report.Aborted || opts.DryRun {
+		bs.abortConfigRollout(report.ID, nodes)
+		logger.Info(fmt.Sprintf("Config rollout %s finished without commit: aborted=%v", report.ID, report.Aborted))
+		return report, nil
+	}
+
+	// 第二阶段: 提交到目标节点，每个节点只应用自己校验过的版本
+	targetSet := make(map[string]bool, len(targets))
+	for _, nodeID := range targets {
+		targetSet[nodeID] = true
+		if err := bs.messageBroker.SendToNode(nodeID, mq.SYS_CMD_CONFIG_COMMIT, map[string]interface{}{
+			"rollout_id": report.ID,
+			"version":    report.Prepared[nodeID].Version,
+		}); err != nil {
+			logger.Error(fmt.Sprintf("Failed to send commit to %s: %v", nodeID, err))
+		}
+	}
+
+	skipped := make([]string, 0)
+	for _, nodeID := range nodes {
+		if !targetSet[nodeID] {
+			skipped = append(skipped, nodeID)
+		}
+	}
+	bs.abortConfigRollout(report.ID, skipped)
+
+	report.Committed = bs.waitRolloutResults(report.ID, rolloutPhaseCommit, targets, timeout)
+
+	logger.Info(fmt.Sprintf("Config rollout %s committed to %d/%d nodes", report.ID, len(targets), len(nodes)))
+	return report, nil
+}
+
+// abortConfigRollout 通知节点丢弃暂存的配置
+func (bs *BaseServer) abortConfigRollout(rolloutID string, nodes []string) {
+	for _, nodeID := range nodes {
+		if err := bs.messageBroker.SendToNode(nodeID, mq.SYS_CMD_CONFIG_ABORT, map[string]interface{}{
+			"rollout_id": rolloutID,
+		}); err != nil {
+			logger.Error(fmt.Sprintf("Failed to send abort to %s: %v", nodeID, err))
+		}
+	}
+}
+
+// waitRolloutResults 等待节点回报阶段结果，超时未回报的节点记为失败
+func (bs *BaseServer) waitRolloutResults(rolloutID, phase string, nodes []string, timeout time.Duration) map[string]*ConfigRolloutResult {
+	key := configRolloutKeyPrefix + rolloutID
+	results := make(map[string]*ConfigRolloutResult, len(nodes))
+	deadline := time.Now().Add(timeout)
+
+	for {
+		fields, err := bs.redisManager.HGetAll(key)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to get rollout results: %v", err))
+		}
+		for _, nodeID := range nodes {
+			if _, exists := results[nodeID]; exists {
+				continue
+			}
+			value, exists := fields[phase+":"+nodeID]
+			if !exists {
+				continue
+			}
+			var result ConfigRolloutResult
+			if err := json.Unmarshal([]byte(value), &result); err != nil {
+				result = ConfigRolloutResult{NodeID: nodeID, Phase: phase, Error: fmt.Sprintf("invalid result: %v", err)}
+			}
+			results[nodeID] = &result
+		}
+
+		if len(results) == len(nodes) || time.Now().After(deadline) {
+			break
+		}
+
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-bs.ctx.Done():
+			deadline = time.Now()
+		}
+	}
+
+	for _, nodeID := range nodes {
+		if _, exists := results[nodeID]; !exists {
+			results[nodeID] = &ConfigRolloutResult{NodeID: nodeID, Phase: phase, Error: "timeout"}
+		}
+	}
+	return results
+}
+
+// reportRolloutResult 节点回报阶段结果
+func (bs *BaseServer) reportRolloutResult(rolloutID string, result *ConfigRolloutResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal rollout result: %v", err)
+	}
+
+	key := configRolloutKeyPrefix + rolloutID
+	if err := bs.redisManager.HSet(key, result.Phase+":"+result.NodeID, data); err != nil {
+		return fmt.Errorf("failed to report rollout result: %v", err)
+	}
+	return bs.redisManager.Expire(key, time.Hour)
+}
+
+// changeLines 配置变更的描述
+func changeLines(changes []hotreload.ConfigChange) []string {
+	lines := make([]string, 0, len(changes))
+	for _, change := range changes {
+		lines = append(lines, change.String())
+	}
+	return lines
+}
+
+// rolloutNodeIDs 从参数中解析节点ID列表，逗号或空格分隔
+func rolloutNodeIDs(args []string) []string {
+	nodes := make([]string, 0)
+	for _, arg := range args {
+		for _, nodeID := range strings.Split(arg, ",") {
+			if nodeID = strings.TrimSpace(nodeID); nodeID != "" {
+				nodes = append(nodes, nodeID)
+			}
+		}
+	}
+	return nodes
+}
@@ -0,0 +1,24 @@
+package server
+
+import (
+	"github.com/phuhao00/lufy/internal/denorm"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// applyProfiles 集合为read方式时用玩家的最新昵称和等级替换列表中的副本，读取失败的玩家保留副本
+func applyProfiles(service *denorm.Service, collection string, players []*proto.GamePlayerInfo) {
+	if service.Mode(collection) != denorm.ModeRead || len(players) == 0 {
+		return
+	}
+	userIDs := make([]uint64, len(players))
+	for i, player := range players {
+		userIDs[i] = player.UserId
+	}
+	profiles := service.Profiles(collection, userIDs)
+	for _, player := range players {
+		if profile, exists := profiles[player.UserId]; exists {
+			player.Nickname = profile.Nickname
+			player.Level = profile.Level
+		}
+	}
+}
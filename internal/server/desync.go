@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/errcode"
+	"github.com/phuhao00/lufy/internal/gameplay"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// 状态不一致报告的默认设置
+const (
+	defaultDesyncRetention = 14 * 24 * time.Hour
+	defaultDesyncMaxState  = 64 * 1024
+)
+
+// newDesyncRepository 创建状态不一致报告仓库
+func (bs *BaseServer) newDesyncRepository() *database.DesyncRepository {
+	retention := bs.config.Gameplay.Desync.Retention
+	if retention <= 0 {
+		retention = defaultDesyncRetention
+	}
+	return database.NewDesyncRepository(bs.mongoManager, retention)
+}
+
+// desyncResponse 操作因状态不一致被拒绝时保存报告，并返回报告ID和玩家视角的完整快照，
+// 客户端按快照重新同步，可以通过ReportDesync附上本地状态
+func (egs *EnhancedGameService) desyncResponse(req *proto.BaseRequest, desync *gameplay.DesyncError, gameType, actionType string) (*proto.BaseResponse, error) {
+	report := &database.DesyncReport{
+		RoomID:     desync.RoomID,
+		GameType:   gameType,
+		UserID:     desync.PlayerID,
+		ActionType: actionType,
+		Expected:   desync.Expected,
+		Actual:     desync.Actual,
+	}
+	if state, err := json.Marshal(desync.State); err == nil {
+		report.ServerState = string(state)
+	}
+
+	data := map[string]interface{}{}
+	if err := egs.server.desyncRepo.CreateReport(report); err != nil {
+		logger.Error(fmt.Sprintf("Failed to save desync report of room %d: %v", desync.RoomID, err))
+	} else {
+		data["report_id"] = report.ID.Hex()
+	}
+	logger.Warn(desync.Error())
+
+	view, err := egs.server.gameplay.GetRoomView(desync.RoomID, desync.PlayerID)
+	if err == nil {
+		data["room_state"] = view
+	}
+	return egs.createErrorResponse(req, errcode.StateDesync, data)
+}
+
+// ReportDesync 上报状态不一致时客户端的本地状态，附加到服务端保存的报告中
+func (egs *EnhancedGameService) ReportDesync(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	session, err := egs.validateRequest(req)
+	if err != nil {
+		return egs.createErrorResponse(req, errcode.SecurityRejected, nil)
+	}
+
+	params, err := BindJSON[reportDesyncParams](egs.server.inputValidator, "ReportDesync", req.Data)
+	if err != nil {
+		return egs.createErrorResponse(req, errcode.InvalidRequest, nil)
+	}
+	maxState := egs.server.config.Gameplay.Desync.MaxState
+	if maxState <= 0 {
+		maxState = defaultDesyncMaxState
+	}
+	if len(params.State) > maxState {
+		return egs.createErrorResponse(req, errcode.InvalidRequest.WithDetail(fmt.Sprintf("state exceeds %d bytes", maxState)), nil)
+	}
+
+	attached, err := egs.server.desyncRepo.AttachClientState(params.ReportID, session.UserID, string(params.State))
+	if err != nil {
+		logger.Error(fmt.Sprintf("ReportDesync: %v", err))
+		return egs.createErrorResponse(req, errcode.DesyncNotFound, nil)
+	}
+	if !attached {
+		return egs.createErrorResponse(req, errcode.DesyncNotFound, nil)
+	}
+	return egs.createSuccessResponse(req, "success", nil)
+}
+
+// reportDesyncParams ReportDesync的参数，状态为客户端本地的玩法数据
+type reportDesyncParams struct {
+	ReportID string          `json:"report_id" validate:"required,context=identifier,max_length=24"`
+	State    json.RawMessage `json:"state" validate:"required"`
+}
@@ -3,19 +3,23 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	_ "net/http/pprof"
-	"reflect"
-	"strings"
 	"time"
 
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/errcode"
 	"github.com/phuhao00/lufy/internal/gameplay"
 	"github.com/phuhao00/lufy/internal/hotreload"
 	"github.com/phuhao00/lufy/internal/i18n"
 	"github.com/phuhao00/lufy/internal/logger"
 	"github.com/phuhao00/lufy/internal/monitoring"
+	"github.com/phuhao00/lufy/internal/mq"
+	"github.com/phuhao00/lufy/internal/quickchat"
 	"github.com/phuhao00/lufy/internal/security"
+	"github.com/phuhao00/lufy/internal/timeline"
 	"github.com/phuhao00/lufy/pkg/proto"
 )
 
@@ -25,6 +29,11 @@ type EnhancedGameServer struct {
 	gameplay    *gameplay.GameplayManager
 	security    *security.SecurityManager
 	monitoring  *monitoring.MonitoringManager
+
+	inputValidator *security.InputValidator
+	quickChat      *quickchat.Service
+	timeline       *timeline.Recorder
+	desyncRepo     *database.DesyncRepository
 	i18n        *i18n.I18nManager
 	hotReload   *hotreload.HotReloadManager
 	pprofServer *http.Server
@@ -53,7 +62,7 @@ func NewEnhancedGameServer(configFile, nodeID string) *EnhancedGameServer {
 
 	// 注册增强游戏服务
 	enhancedGameService := NewEnhancedGameService(enhancedServer)
-	if err := baseServer.rpcServer.RegisterService(enhancedGameService); err != nil {
+	if err := RegisterEnhancedGameService(baseServer.rpcServer, enhancedGameService); err != nil {
 		logger.Fatal(fmt.Sprintf("Failed to register enhanced game service: %v", err))
 	}
 
@@ -69,12 +78,18 @@ func (egs *EnhancedGameServer) initEnhancedComponents() error {
 	if err != nil {
 		return fmt.Errorf("failed to init security manager: %v", err)
 	}
+	egs.security.SetBlacklist(egs.ipBlacklist)
 
-	// 初始化监控管理器
-	monitoringPort := egs.config.Network.HTTPPort
-	egs.monitoring, err = monitoring.NewMonitoringManager(egs.nodeID, egs.nodeType, monitoringPort)
+	// 初始化输入校验
+	egs.inputValidator, err = security.NewInputValidator(&egs.config.Security.InputValidation)
 	if err != nil {
-		return fmt.Errorf("failed to init monitoring manager: %v", err)
+		return fmt.Errorf("failed to init input validator: %v", err)
+	}
+
+	// 游戏指标记录在节点的监控管理器上
+	egs.monitoring = egs.nodeMonitoring
+	if err := egs.monitoring.Register(mq.NewConsumerCollector(egs.ConsumerStats)); err != nil {
+		return fmt.Errorf("failed to register consumer metrics: %v", err)
 	}
 
 	// 初始化国际化管理器
@@ -86,8 +101,37 @@ func (egs *EnhancedGameServer) initEnhancedComponents() error {
 		logger.Warn(fmt.Sprintf("Failed to load Japanese language: %v", err))
 	}
 
-	// 初始化玩法管理器
-	egs.gameplay = gameplay.NewGameplayManager()
+	// 初始化玩法管理器，房间事件定期写入回放存储
+	store := &replayStore{repo: database.NewReplayRepository(egs.mongoManager)}
+	egs.gameplay = gameplay.NewGameplayManager(&egs.config.Gameplay, store)
+	egs.gameplay.OnHistoryStats(func(stats []gameplay.RoomHistoryStats) {
+		egs.monitoring.ResetRoomEventHistory()
+		for _, s := range stats {
+			egs.monitoring.SetRoomEventHistory(s.RoomID, s.Events, s.MemoryBytes, s.Dropped)
+		}
+	})
+	egs.gameplay.OnTickStats(func(stats *gameplay.TickStats) {
+		egs.monitoring.RecordTick(stats.GameType, stats.Duration, stats.Interval)
+	})
+	egs.RegisterBackground("history_flush", egs.gameplay.RunHistoryFlush)
+	// 先停止房间帧循环，历史写入任务停止时再落盘一次
+	egs.RegisterOnStop("gameplay", OrderBackground, func(ctx context.Context) error {
+		egs.gameplay.Shutdown()
+		return nil
+	})
+
+	// 初始化快捷消息，接收者屏蔽的发送者的快捷消息不下发
+	egs.quickChat, err = quickchat.NewService(&egs.config.QuickChat)
+	if err != nil {
+		return fmt.Errorf("failed to init quick chat: %v", err)
+	}
+	egs.gameplay.AddEventFilter(func(room *gameplay.GameRoom, viewerID uint64, event gameplay.GameEvent) bool {
+		return event.Type != quickChatEvent || !egs.quickChat.Blocked(viewerID, event.PlayerID)
+	})
+	egs.RegisterBackground("quick_chat_cleanup", egs.quickChatCleanupLoop)
+
+	egs.timeline = egs.newTimeline()
+	egs.desyncRepo = egs.newDesyncRepository()
 
 	// 注册默认游戏模块
 	cardGameModule := gameplay.NewCardGameModule()
@@ -95,78 +139,63 @@ func (egs *EnhancedGameServer) initEnhancedComponents() error {
 		logger.Warn(fmt.Sprintf("Failed to register card game module: %v", err))
 	}
 
-	// 初始化热更新管理器
+	// 初始化模块热更新管理器，配置文件的热更新由BaseServer统一处理
 	egs.hotReload, err = hotreload.NewHotReloadManager()
 	if err != nil {
 		return fmt.Errorf("failed to init hot reload manager: %v", err)
 	}
+	egs.RegisterOnStop("hot_reload", OrderBackground, func(ctx context.Context) error {
+		return egs.hotReload.Close()
+	})
 
-	// 注册配置文件热更新
-	configParser := &hotreload.YAMLConfigParser{}
-	if err := egs.hotReload.RegisterConfig("config/config.yaml", configParser); err != nil {
-		logger.Warn(fmt.Sprintf("Failed to register config hot reload: %v", err))
-	}
-
-	// 启动pprof服务器
-	egs.startPprofServer()
+	// pprof服务器
+	egs.registerPprofServer()
 
 	logger.Info("Enhanced components initialized")
 	return nil
 }
 
-// startPprofServer 启动pprof服务器
-func (egs *EnhancedGameServer) startPprofServer() {
+// registerPprofServer 随节点启动和停止pprof服务器
+func (egs *EnhancedGameServer) registerPprofServer() {
 	pprofPort := egs.config.Network.HTTPPort + 1000
 
 	egs.pprofServer = &http.Server{
 		Addr: fmt.Sprintf(":%d", pprofPort),
 	}
 
-	go func() {
-		logger.Info(fmt.Sprintf("pprof server listening on :%d", pprofPort))
-		if err := egs.pprofServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Error(fmt.Sprintf("pprof server error: %v", err))
-		}
-	}()
+	egs.RegisterOnStart("pprof", OrderListeners, func(ctx context.Context) error {
+		go func() {
+			logger.Info(fmt.Sprintf("pprof server listening on :%d", pprofPort))
+			if err := egs.pprofServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Error(fmt.Sprintf("pprof server error: %v", err))
+			}
+		}()
+		return nil
+	})
+	egs.RegisterOnStop("pprof", OrderListeners, func(ctx context.Context) error {
+		return egs.pprofServer.Shutdown(ctx)
+	})
 }
 
-// Start 启动增强版游戏服务器
-func (egs *EnhancedGameServer) Start() error {
-	// 启动基础服务器
-	if err := egs.BaseServer.Start(); err != nil {
-		return err
-	}
-
-	// 启动监控服务
-	if err := egs.monitoring.Start(); err != nil {
-		logger.Error(fmt.Sprintf("Failed to start monitoring: %v", err))
-	}
-
-	logger.Info(fmt.Sprintf("Enhanced game server %s started", egs.nodeID))
-	return nil
+// replayStore 基于MongoDB的房间事件回放存储
+type replayStore struct {
+	repo *database.ReplayRepository
 }
 
-// Stop 停止增强版游戏服务器
-func (egs *EnhancedGameServer) Stop() error {
-	// 停止监控服务
-	if egs.monitoring != nil {
-		egs.monitoring.Stop()
-	}
-
-	// 停止pprof服务器
-	if egs.pprofServer != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		egs.pprofServer.Shutdown(ctx)
-		cancel()
-	}
-
-	// 停止热更新管理器
-	if egs.hotReload != nil {
-		egs.hotReload.Close()
+// SaveEvents 写入房间事件
+func (rs *replayStore) SaveEvents(roomID uint64, startSeq uint64, events []gameplay.GameEvent) error {
+	records := make([]*database.RoomEvent, len(events))
+	for i, event := range events {
+		records[i] = &database.RoomEvent{
+			RoomID:    roomID,
+			Seq:       startSeq + uint64(i),
+			Type:      event.Type,
+			PlayerID:  event.PlayerID,
+			Data:      event.Data,
+			Timestamp: event.Timestamp,
+		}
 	}
-
-	// 停止基础服务器
-	return egs.BaseServer.Stop()
+	return rs.repo.SaveEvents(records)
 }
 
 // EnhancedGameService 增强游戏RPC服务
@@ -181,61 +210,49 @@ func NewEnhancedGameService(server *EnhancedGameServer) *EnhancedGameService {
 	}
 }
 
-// GetName 获取服务名称
-func (egs *EnhancedGameService) GetName() string {
-	return "EnhancedGameService"
-}
-
-// RegisterMethods 注册方法
-func (egs *EnhancedGameService) RegisterMethods() map[string]reflect.Value {
-	methods := make(map[string]reflect.Value)
-
-	// 基础游戏方法
-	methods["CreateRoom"] = reflect.ValueOf(egs.CreateRoom)
-	methods["JoinRoom"] = reflect.ValueOf(egs.JoinRoom)
-	methods["LeaveRoom"] = reflect.ValueOf(egs.LeaveRoom)
-	methods["GameAction"] = reflect.ValueOf(egs.GameAction)
-	methods["GetRoomState"] = reflect.ValueOf(egs.GetRoomState)
-
-	// 安全相关方法
-	methods["ValidateToken"] = reflect.ValueOf(egs.ValidateToken)
-	methods["CheckSecurity"] = reflect.ValueOf(egs.CheckSecurity)
-
-	// 监控相关方法
-	methods["GetMetrics"] = reflect.ValueOf(egs.GetMetrics)
-	methods["GetAlerts"] = reflect.ValueOf(egs.GetAlerts)
-
-	// 热更新方法
-	methods["HotReload"] = reflect.ValueOf(egs.HotReload)
-
-	return methods
-}
-
 // CreateRoom 创建游戏房间
 func (egs *EnhancedGameService) CreateRoom(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
 	// 安全验证
 	session, err := egs.validateRequest(req)
 	if err != nil {
-		return egs.createErrorResponse(req, -1, "security_validation_failed", nil)
+		return egs.createErrorResponse(req, errcode.SecurityRejected, nil)
 	}
 
 	// 限流检查
 	if err := egs.server.security.CheckIPSecurity(session.IP); err != nil {
-		return egs.createErrorResponse(req, -2, "rate_limit_exceeded", nil)
+		return egs.createErrorResponse(req, errcode.RateLimited, nil)
+	}
+
+	// 解析请求参数，未指定玩法类型时创建卡牌游戏
+	params, err := BindJSON[createRoomParams](egs.server.inputValidator, "CreateRoom", req.Data)
+	if err != nil {
+		return egs.createErrorResponse(req, errcode.InvalidRequest, nil)
+	}
+	gameType := params.GameType
+	if gameType == "" {
+		gameType = "card_game"
 	}
 
 	// 创建房间配置
 	config := &gameplay.RoomConfig{
-		MaxPlayers: 2,
-		MinPlayers: 2,
-		AutoStart:  true,
-		TimeLimit:  30 * time.Minute,
+		MaxPlayers:    2,
+		MinPlayers:    2,
+		AutoStart:     true,
+		TimeLimit:     30 * time.Minute,
+		EphemeralChat: params.EphemeralChat || egs.server.config.ChatPolicy.EphemeralRooms,
+		CustomConfig:  params.Rules,
 	}
 
-	// 创建房间
-	room, err := egs.server.gameplay.CreateRoom("card_game", config)
+	// 创建房间，自定义规则按玩法模块的规则表校验
+	room, err := egs.server.gameplay.CreateRoom(gameType, config)
+	if errors.Is(err, gameplay.ErrInvalidRules) {
+		return egs.createErrorResponse(req, errcode.InvalidRoomRules.WithDetail(err.Error()), nil)
+	}
 	if err != nil {
-		return egs.createErrorResponse(req, -3, "room_creation_failed", nil)
+		return egs.createErrorResponse(req, errcode.CreateRoomFailed, nil)
+	}
+	if room.Config.EphemeralChat {
+		egs.server.markEphemeralRoomChat(room.ID)
 	}
 
 	// 记录监控指标
@@ -244,6 +261,29 @@ func (egs *EnhancedGameService) CreateRoom(ctx context.Context, req *proto.BaseR
 	// 返回本地化响应
 	return egs.createSuccessResponse(req, "success.room_created", map[string]interface{}{
 		"room_id": room.ID,
+		"rules":   room.Config.CustomConfig,
+	})
+}
+
+// GetRoomRules 获取玩法类型可设置的房间规则，大厅界面据此渲染规则选择
+func (egs *EnhancedGameService) GetRoomRules(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	if _, err := egs.validateRequest(req); err != nil {
+		return egs.createErrorResponse(req, errcode.SecurityRejected, nil)
+	}
+
+	params, err := BindJSON[getRoomRulesParams](egs.server.inputValidator, "GetRoomRules", req.Data)
+	if err != nil {
+		return egs.createErrorResponse(req, errcode.InvalidRequest, nil)
+	}
+
+	rules, err := egs.server.gameplay.RoomRules(params.GameType)
+	if err != nil {
+		return egs.createErrorResponse(req, errcode.UnknownGameType, nil)
+	}
+
+	return egs.createSuccessResponse(req, "success", map[string]interface{}{
+		"game_type": params.GameType,
+		"rules":     rules,
 	})
 }
 
@@ -251,25 +291,19 @@ func (egs *EnhancedGameService) CreateRoom(ctx context.Context, req *proto.BaseR
 func (egs *EnhancedGameService) JoinRoom(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
 	session, err := egs.validateRequest(req)
 	if err != nil {
-		return egs.createErrorResponse(req, -1, "security_validation_failed", nil)
+		return egs.createErrorResponse(req, errcode.SecurityRejected, nil)
 	}
 
 	// 解析请求参数
-	params, err := egs.parseRequestParams(req)
+	params, err := BindJSON[joinRoomParams](egs.server.inputValidator, "JoinRoom", req.Data)
 	if err != nil {
-		return egs.createErrorResponse(req, -2, "invalid_request_params", nil)
-	}
-
-	// 获取房间ID
-	roomID, ok := params["room_id"].(float64)
-	if !ok {
-		return egs.createErrorResponse(req, -3, "missing_room_id", nil)
+		return egs.createErrorResponse(req, errcode.InvalidRequest, nil)
 	}
 
 	// 获取用户信息（这里简化处理）
 	nickname := "Player"
-	if userNickname, exists := params["nickname"].(string); exists {
-		nickname = userNickname
+	if params.Nickname != "" {
+		nickname = egs.server.security.SanitizeInput(params.Nickname)
 	}
 
 	// 创建玩家对象
@@ -281,14 +315,17 @@ func (egs *EnhancedGameService) JoinRoom(ctx context.Context, req *proto.BaseReq
 	}
 
 	// 加入房间
-	if err := egs.server.gameplay.JoinRoom(uint64(roomID), player); err != nil {
-		return egs.createErrorResponse(req, -4, "join_room_failed", nil)
+	if err := egs.server.gameplay.JoinRoom(params.RoomID, player); err != nil {
+		return egs.createErrorResponse(req, errcode.JoinRoomFailed, nil)
 	}
+	egs.server.timeline.RoomJoined(session.UserID, params.RoomID, roomJoinJoin)
 
 	egs.server.monitoring.RecordMessage("join_room")
 
+	// 附带快捷消息配置表，客户端按表显示可发送的条目
 	return egs.createSuccessResponse(req, "success.room_joined", map[string]interface{}{
-		"room_id": uint64(roomID),
+		"room_id":    params.RoomID,
+		"quick_chat": egs.server.quickChat.Entries(),
 	})
 }
 
@@ -296,24 +333,19 @@ func (egs *EnhancedGameService) JoinRoom(ctx context.Context, req *proto.BaseReq
 func (egs *EnhancedGameService) LeaveRoom(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
 	session, err := egs.validateRequest(req)
 	if err != nil {
-		return egs.createErrorResponse(req, -1, "security_validation_failed", nil)
+		return egs.createErrorResponse(req, errcode.SecurityRejected, nil)
 	}
 
 	// 解析请求参数
-	params, err := egs.parseRequestParams(req)
+	params, err := BindJSON[roomParams](egs.server.inputValidator, "LeaveRoom", req.Data)
 	if err != nil {
-		return egs.createErrorResponse(req, -2, "invalid_request_params", nil)
-	}
-
-	// 获取房间ID
-	roomID, ok := params["room_id"].(float64)
-	if !ok {
-		return egs.createErrorResponse(req, -3, "missing_room_id", nil)
+		return egs.createErrorResponse(req, errcode.InvalidRequest, nil)
 	}
 
-	if err := egs.server.gameplay.LeaveRoom(uint64(roomID), session.UserID); err != nil {
-		return egs.createErrorResponse(req, -4, "leave_room_failed", nil)
+	if err := egs.server.gameplay.LeaveRoom(params.RoomID, session.UserID); err != nil {
+		return egs.createErrorResponse(req, errcode.LeaveRoomFailed, nil)
 	}
+	egs.server.quickChat.Forget(session.UserID)
 
 	egs.server.monitoring.RecordMessage("leave_room")
 
@@ -330,25 +362,13 @@ func (egs *EnhancedGameService) GameAction(ctx context.Context, req *proto.BaseR
 
 	session, err := egs.validateRequest(req)
 	if err != nil {
-		return egs.createErrorResponse(req, -1, "security_validation_failed", nil)
+		return egs.createErrorResponse(req, errcode.SecurityRejected, nil)
 	}
 
 	// 解析请求参数
-	params, err := egs.parseRequestParams(req)
+	params, err := BindJSON[gameActionParams](egs.server.inputValidator, "GameAction", req.Data)
 	if err != nil {
-		return egs.createErrorResponse(req, -2, "invalid_request_params", nil)
-	}
-
-	// 获取房间ID
-	roomID, ok := params["room_id"].(float64)
-	if !ok {
-		return egs.createErrorResponse(req, -3, "missing_room_id", nil)
-	}
-
-	// 获取操作类型
-	actionType, ok := params["action_type"].(string)
-	if !ok {
-		return egs.createErrorResponse(req, -4, "missing_action_type", nil)
+		return egs.createErrorResponse(req, errcode.InvalidRequest, nil)
 	}
 
 	// 反作弊检查 - 简化实现
@@ -356,16 +376,28 @@ func (egs *EnhancedGameService) GameAction(ctx context.Context, req *proto.BaseR
 
 	// 创建游戏操作对象
 	action := &gameplay.GameAction{
-		Type:      actionType,
+		Type:      params.ActionType,
 		PlayerID:  session.UserID,
 		Timestamp: time.Now(),
-		Data:      params["action_data"],
+		Data:      params.ActionData,
+		StateHash: params.StateHash,
 	}
 
-	result, err := egs.server.gameplay.ProcessAction(uint64(roomID), action)
+	result, err := egs.server.gameplay.ProcessAction(params.RoomID, action)
+	var desync *gameplay.DesyncError
+	if errors.As(err, &desync) {
+		gameType := ""
+		if room, exists := egs.server.gameplay.GetRoom(params.RoomID); exists {
+			gameType = room.GameType
+		}
+		return egs.desyncResponse(req, desync, gameType, params.ActionType)
+	}
+	if errors.Is(err, gameplay.ErrInvalidAction) {
+		return egs.createErrorResponse(req, errcode.InvalidActionData.WithDetail(err.Error()), err)
+	}
 	if err != nil {
 		egs.server.monitoring.RecordError("game_action_failed")
-		return egs.createErrorResponse(req, -6, "action_failed", nil)
+		return egs.createErrorResponse(req, errcode.ActionFailed, nil)
 	}
 
 	egs.server.monitoring.RecordMessage("game_action")
@@ -379,33 +411,48 @@ func (egs *EnhancedGameService) GameAction(ctx context.Context, req *proto.BaseR
 func (egs *EnhancedGameService) GetRoomState(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
 	session, err := egs.validateRequest(req)
 	if err != nil {
-		return egs.createErrorResponse(req, -1, "security_validation_failed", nil)
+		return egs.createErrorResponse(req, errcode.SecurityRejected, nil)
 	}
 
 	// 解析请求参数
-	params, err := egs.parseRequestParams(req)
+	params, err := BindJSON[getRoomStateParams](egs.server.inputValidator, "GetRoomState", req.Data)
 	if err != nil {
-		return egs.createErrorResponse(req, -2, "invalid_request_params", nil)
+		return egs.createErrorResponse(req, errcode.InvalidRequest, nil)
 	}
 
-	// 获取房间ID
-	roomID, ok := params["room_id"].(float64)
-	if !ok {
-		return egs.createErrorResponse(req, -3, "missing_room_id", nil)
-	}
-
-	room, exists := egs.server.gameplay.GetRoom(uint64(roomID))
+	room, exists := egs.server.gameplay.GetRoom(params.RoomID)
 	if !exists {
-		return egs.createErrorResponse(req, -4, "room_not_found", nil)
+		return egs.createErrorResponse(req, errcode.RoomNotFound, nil)
 	}
 
 	// 检查玩家权限
 	if _, exists := room.GetPlayer(session.UserID); !exists {
-		return egs.createErrorResponse(req, -5, "permission_denied", nil)
+		return egs.createErrorResponse(req, errcode.PermissionDenied, nil)
+	}
+
+	// 客户端提供已知版本时只返回之后的事件，事件已被覆盖则返回完整快照
+	// 事件和玩法数据按玩法模块的兴趣管理策略过滤为该玩家可见的部分
+	if params.KnownVersion != nil {
+		view, ok, err := egs.server.gameplay.GetRoomDelta(room.ID, session.UserID, *params.KnownVersion)
+		if err != nil {
+			return egs.createErrorResponse(req, errcode.RoomNotFound, nil)
+		}
+		if ok {
+			return egs.createSuccessResponse(req, "success", map[string]interface{}{
+				"delta":      true,
+				"room_state": view,
+			})
+		}
+	}
+
+	view, err := egs.server.gameplay.GetRoomView(room.ID, session.UserID)
+	if err != nil {
+		return egs.createErrorResponse(req, errcode.RoomNotFound, nil)
 	}
 
 	return egs.createSuccessResponse(req, "success", map[string]interface{}{
-		"room_state": room,
+		"delta":      false,
+		"room_state": view,
 	})
 }
 
@@ -413,7 +460,7 @@ func (egs *EnhancedGameService) GetRoomState(ctx context.Context, req *proto.Bas
 func (egs *EnhancedGameService) ValidateToken(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
 	tokenString := req.Header.SessionId
 	if tokenString == "" {
-		return egs.createErrorResponse(req, -1, "error.missing_token", nil)
+		return egs.createErrorResponse(req, errcode.MissingToken, nil)
 	}
 
 	// TODO: 检查认证状态
@@ -437,11 +484,11 @@ func (egs *EnhancedGameService) GetMetrics(ctx context.Context, req *proto.BaseR
 	// 验证管理员权限
 	session, err := egs.validateRequest(req)
 	if err != nil {
-		return egs.createErrorResponse(req, -1, "security_validation_failed", nil)
+		return egs.createErrorResponse(req, errcode.SecurityRejected, nil)
 	}
 
 	if !egs.hasPermission(session, "admin") {
-		return egs.createErrorResponse(req, -2, "permission_denied", nil)
+		return egs.createErrorResponse(req, errcode.PermissionDenied, nil)
 	}
 
 	// 获取指标数据
@@ -459,11 +506,11 @@ func (egs *EnhancedGameService) GetMetrics(ctx context.Context, req *proto.BaseR
 func (egs *EnhancedGameService) GetAlerts(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
 	session, err := egs.validateRequest(req)
 	if err != nil {
-		return egs.createErrorResponse(req, -1, "security_validation_failed", nil)
+		return egs.createErrorResponse(req, errcode.SecurityRejected, nil)
 	}
 
 	if !egs.hasPermission(session, "admin") {
-		return egs.createErrorResponse(req, -2, "permission_denied", nil)
+		return egs.createErrorResponse(req, errcode.PermissionDenied, nil)
 	}
 
 	// TODO: 从监控系统获取告警信息
@@ -478,28 +525,28 @@ func (egs *EnhancedGameService) GetAlerts(ctx context.Context, req *proto.BaseRe
 func (egs *EnhancedGameService) HotReload(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
 	session, err := egs.validateRequest(req)
 	if err != nil {
-		return egs.createErrorResponse(req, -1, "security_validation_failed", nil)
+		return egs.createErrorResponse(req, errcode.SecurityRejected, nil)
 	}
 
 	if !egs.hasPermission(session, "admin") {
-		return egs.createErrorResponse(req, -2, "permission_denied", nil)
+		return egs.createErrorResponse(req, errcode.PermissionDenied, nil)
 	}
 
 	// 解析请求参数
-	params, err := egs.parseRequestParams(req)
+	params, err := BindJSON[hotReloadParams](egs.server.inputValidator, "HotReload", req.Data)
 	if err != nil {
-		return egs.createErrorResponse(req, -3, "invalid_request_params", nil)
+		return egs.createErrorResponse(req, errcode.InvalidRequest, nil)
 	}
 
 	// 获取更新类型
-	updateType, ok := params["update_type"].(string)
-	if !ok {
+	updateType := params.UpdateType
+	if updateType == "" {
 		updateType = "config" // 默认配置更新
 	}
 
 	// 获取模块名称
-	moduleName, ok := params["module_name"].(string)
-	if !ok {
+	moduleName := params.ModuleName
+	if moduleName == "" {
 		moduleName = "game_config" // 默认游戏配置
 	}
 
@@ -515,7 +562,7 @@ func (egs *EnhancedGameService) HotReload(ctx context.Context, req *proto.BaseRe
 		// TODO: 实现模块热重载
 		logger.Info("Module hot reload requested")
 	default:
-		return egs.createErrorResponse(req, -7, "unsupported_update_type", nil)
+		return egs.createErrorResponse(req, errcode.InvalidRequest, nil)
 	}
 
 	logger.Info(fmt.Sprintf("Hot reload completed: %s/%s by user %d",
@@ -527,6 +574,49 @@ func (egs *EnhancedGameService) HotReload(ctx context.Context, req *proto.BaseRe
 	})
 }
 
+// roomParams 只需要房间ID的请求参数
+type roomParams struct {
+	RoomID uint64 `json:"room_id" validate:"required,min=1"`
+}
+
+// createRoomParams CreateRoom的参数，房间规则的取值由玩法模块的规则表校验，这里只限制为对象
+type createRoomParams struct {
+	GameType      string                 `json:"game_type" validate:"context=identifier,max_length=64"`
+	Rules         map[string]interface{} `json:"rules" validate:"context=identifier"`
+	EphemeralChat bool                   `json:"ephemeral_chat"`
+}
+
+// getRoomRulesParams GetRoomRules的参数
+type getRoomRulesParams struct {
+	GameType string `json:"game_type" validate:"required,context=identifier,max_length=64"`
+}
+
+// joinRoomParams JoinRoom的参数
+type joinRoomParams struct {
+	RoomID   uint64 `json:"room_id" validate:"required,min=1"`
+	Nickname string `json:"nickname" validate:"context=text,max_length=32"`
+}
+
+// getRoomStateParams GetRoomState的参数，未提供已知版本时返回完整快照
+type getRoomStateParams struct {
+	RoomID       uint64  `json:"room_id" validate:"required,min=1"`
+	KnownVersion *uint64 `json:"known_version" validate:"min=0"`
+}
+
+// gameActionParams GameAction的参数，操作数据由玩法模块校验，状态校验和为空时不校验
+type gameActionParams struct {
+	RoomID     uint64      `json:"room_id" validate:"required,min=1"`
+	ActionType string      `json:"action_type" validate:"required,context=identifier,max_length=64"`
+	ActionData interface{} `json:"action_data" validate:"context=text"`
+	StateHash  string      `json:"state_hash" validate:"context=identifier,max_length=16"`
+}
+
+// hotReloadParams HotReload的参数
+type hotReloadParams struct {
+	UpdateType string `json:"update_type" validate:"context=identifier,max_length=32"`
+	ModuleName string `json:"module_name" validate:"context=identifier,max_length=64"`
+}
+
 // validateRequest 验证请求
 func (egs *EnhancedGameService) validateRequest(req *proto.BaseRequest) (*security.Session, error) {
 	// 验证会话
@@ -581,18 +671,10 @@ func (egs *EnhancedGameService) createSuccessResponse(req *proto.BaseRequest, me
 }
 
 // createErrorResponse 创建错误响应
-func (egs *EnhancedGameService) createErrorResponse(req *proto.BaseRequest, code int32, messageID string, data interface{}) (*proto.BaseResponse, error) {
-	// 获取客户端语言
+func (egs *EnhancedGameService) createErrorResponse(req *proto.BaseRequest, err error, data interface{}) (*proto.BaseResponse, error) {
+	// 按客户端语言本地化错误消息
 	langCode := egs.detectLanguage(req)
-
-	// 本地化错误消息
-	message := egs.server.i18n.Translate(langCode, messageID, nil)
-
-	response := &proto.BaseResponse{
-		Header: req.Header,
-		Code:   code,
-		Msg:    message,
-	}
+	response := errcode.LocalizedResponse(req.Header, err, egs.server.i18n, langCode)
 
 	if data != nil {
 		responseData, err := json.Marshal(data)
@@ -603,7 +685,7 @@ func (egs *EnhancedGameService) createErrorResponse(req *proto.BaseRequest, code
 	}
 
 	// 记录错误指标
-	egs.server.monitoring.RecordError(messageID)
+	egs.server.monitoring.RecordError(errcode.From(err).MessageID)
 
 	return response, nil
 }
@@ -645,77 +727,3 @@ func (sm *SecurityMiddleware) ValidateRequest(req *proto.BaseRequest, clientIP s
 
 	return nil
 }
-
-// parseRequestParams 解析请求参数
-func (egs *EnhancedGameService) parseRequestParams(req *proto.BaseRequest) (map[string]interface{}, error) {
-	if req.Data == nil || len(req.Data) == 0 {
-		return make(map[string]interface{}), nil
-	}
-
-	var params map[string]interface{}
-	if err := json.Unmarshal(req.Data, &params); err != nil {
-		return nil, fmt.Errorf("failed to parse request data: %v", err)
-	}
-
-	// 输入验证和清理
-	if err := egs.validateAndSanitizeParams(params); err != nil {
-		return nil, fmt.Errorf("parameter validation failed: %v", err)
-	}
-
-	return params, nil
-}
-
-// validateAndSanitizeParams 验证和清理参数
-func (egs *EnhancedGameService) validateAndSanitizeParams(params map[string]interface{}) error {
-	// 检查参数数量限制
-	if len(params) > 50 {
-		return fmt.Errorf("too many parameters: %d", len(params))
-	}
-
-	// 验证每个参数
-	for key, value := range params {
-		// 检查键名长度
-		if len(key) > 100 {
-			return fmt.Errorf("parameter key too long: %s", key)
-		}
-
-		// 检查字符串值长度
-		if str, ok := value.(string); ok {
-			if len(str) > 10000 {
-				return fmt.Errorf("parameter value too long for key: %s", key)
-			}
-			// 基本的XSS防护
-			if egs.containsSuspiciousContent(str) {
-				return fmt.Errorf("suspicious content detected in parameter: %s", key)
-			}
-		}
-
-		// 检查数值范围
-		if num, ok := value.(float64); ok {
-			if num < -1e15 || num > 1e15 {
-				return fmt.Errorf("numeric value out of range for key: %s", key)
-			}
-		}
-	}
-
-	return nil
-}
-
-// containsSuspiciousContent 检查是否包含可疑内容
-func (egs *EnhancedGameService) containsSuspiciousContent(content string) bool {
-	// 简单的XSS和SQL注入检测
-	suspiciousPatterns := []string{
-		"<script", "</script>", "javascript:", "onload=", "onerror=",
-		"SELECT", "INSERT", "UPDATE", "DELETE", "DROP", "UNION",
-		"--", "/*", "*/", "'", "\"",
-	}
-
-	contentLower := strings.ToLower(content)
-	for _, pattern := range suspiciousPatterns {
-		if strings.Contains(contentLower, strings.ToLower(pattern)) {
-			return true
-		}
-	}
-
-	return false
-}
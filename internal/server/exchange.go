@@ -0,0 +1,59 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/phuhao00/lufy/internal/errcode"
+	"github.com/phuhao00/lufy/internal/exchange"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// ExchangeCurrency 按配置的规则兑换金币和钻石
+func (ls *LobbyService) ExchangeCurrency(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	userID := req.Header.GetUserId()
+	if userID == 0 {
+		return ls.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
+	}
+
+	exchangeReq, err := Bind[proto.ExchangeCurrencyRequest](req.Data)
+	if err != nil {
+		logger.Error(fmt.Sprintf("ExchangeCurrency: failed to unmarshal request: %v", err))
+		return ls.responses.Error(ctx, req.Header, errcode.InvalidRequest), nil
+	}
+
+	result, err := ls.server.exchange.Exchange(userID, exchangeReq.GetFrom(), exchangeReq.GetTo(), exchangeReq.GetAmount())
+	if err != nil {
+		switch {
+		case errors.Is(err, exchange.ErrNoRule):
+			return ls.responses.Error(ctx, req.Header, errcode.ExchangeUnavailable), nil
+		case errors.Is(err, exchange.ErrTooSmall):
+			return ls.responses.Error(ctx, req.Header, errcode.ExchangeTooSmall), nil
+		case errors.Is(err, exchange.ErrDailyLimit):
+			return ls.responses.Error(ctx, req.Header, errcode.ExchangeDailyLimit), nil
+		case errors.Is(err, exchange.ErrInsufficient):
+			return ls.responses.Error(ctx, req.Header, errcode.InsufficientBalance), nil
+		}
+		logger.Error(fmt.Sprintf("ExchangeCurrency: user %d %s -> %s failed: %v",
+			userID, exchangeReq.GetFrom(), exchangeReq.GetTo(), err))
+		return ls.responses.Error(ctx, req.Header, errcode.ExchangeFailed), nil
+	}
+
+	logger.Info(fmt.Sprintf("User %d exchanged %d %s for %d %s (tax %d), tx %s",
+		userID, result.Spent, exchangeReq.GetFrom(), result.Received, exchangeReq.GetTo(), result.Tax, result.TxID))
+
+	data, err := proto.Marshal(&proto.ExchangeCurrencyResponse{
+		Spent:      result.Spent,
+		Received:   result.Received,
+		Tax:        result.Tax,
+		DailyUsed:  result.Used,
+		DailyLimit: result.Limit,
+	})
+	if err != nil {
+		logger.Error(fmt.Sprintf("ExchangeCurrency: failed to marshal response: %v", err))
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+	return ls.responses.Success(ctx, req.Header, "success", data), nil
+}
@@ -0,0 +1,206 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/fraud"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/mq"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// 风控扫描设置
+const (
+	fraudCheckpointKey = "fraud:checkpoint"
+	fraudScanDelay     = 10 * time.Second // 等待各节点写入的流水落库
+	fraudScanLimit     = 50000
+	fraudBacktestLimit = 500000
+	userStatusFrozen   = 2 // 冻结待审核
+)
+
+// fraudConfig 获取当前的风控规则，规则随配置热更新
+func (bs *BaseServer) fraudConfig() fraud.Config {
+	bs.mutex.RLock()
+	defer bs.mutex.RUnlock()
+	return bs.config.Fraud
+}
+
+// scanFraud 检查上次扫描之后的流水，命中规则时创建案件，freeze模式的规则同时冻结账号
+// 由集群内唯一执行的定时任务调用，回看最长的规则窗口以便滑动窗口统计完整
+func (gs *GMServer) scanFraud(ctx context.Context) error {
+	config := gs.fraudConfig()
+	if len(config.Rules) == 0 {
+		return nil
+	}
+
+	until := time.Now().Add(-fraudScanDelay)
+	checkpoint := until.Add(-time.Minute) // 首次执行时不检查历史流水，历史数据使用回测
+	if value, err := gs.redisManager.GetString(fraudCheckpointKey); err == nil {
+		if nanos, err := strconv.ParseInt(value, 10, 64); err == nil {
+			checkpoint = time.Unix(0, nanos)
+		}
+	}
+	if !until.After(checkpoint) {
+		return nil
+	}
+
+	entries, err := gs.ledgerRepo.GetRange(checkpoint.Add(-config.MaxWindow()), until, fraudScanLimit)
+	if err != nil {
+		return err
+	}
+	if len(entries) == fraudScanLimit {
+		until = entries[len(entries)-1].CreatedAt // 流水过多时分批扫描
+	}
+
+	hits, err := fraud.Evaluate(config.Rules, entries, gs.userRepo, checkpoint, true)
+	if err != nil {
+		return err
+	}
+	for _, hit := range hits {
+		if err := gs.openFraudCase(hit); err != nil {
+			return err
+		}
+	}
+
+	return gs.redisManager.Set(fraudCheckpointKey, strconv.FormatInt(until.UnixNano(), 10), 0)
+}
+
+// openFraudCase 创建待审核案件，freeze模式时冻结账号并踢下线
+func (gs *GMServer) openFraudCase(hit *fraud.Hit) error {
+	frozen := hit.Mode == fraud.ModeFreeze
+	if frozen {
+		changed, err := gs.userRepo.UpdateStatus(hit.UserID, 0, userStatusFrozen)
+		if err != nil {
+			return err
+		}
+		if changed {
+			gs.kickUser(hit.UserID, "account frozen pending review")
+			gs.gmRepo.LogGMAction(0, "fraud_freeze", hit.UserID, fmt.Sprintf("规则: %s, %s", hit.Rule, hit.Detail))
+		}
+	}
+
+	created, err := gs.fraudRepo.Open(&database.FraudCase{
+		UserID: hit.UserID,
+		Rule:   hit.Rule,
+		Detail: hit.Detail,
+		Frozen: frozen,
+	})
+	if err != nil {
+		return err
+	}
+	if created {
+		logger.Warn(fmt.Sprintf("Fraud rule %s hit by user %d (%s, frozen: %v)", hit.Rule, hit.UserID, hit.Detail, frozen))
+	}
+	return nil
+}
+
+// kickUser 通知玩家所在网关断开连接
+func (gs *GMServer) kickUser(userID uint64, reason string) {
+	nodeID, err := database.NewUserCache(gs.redisManager).GetUserOnline(userID)
+	if err != nil || nodeID == "" {
+		return
+	}
+	if err := gs.messageBroker.SendCommand(nodeID, mq.SYS_CMD_KICK_USER, &proto.KickUserCommand{
+		UserId: userID,
+		Reason: reason,
+	}); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to kick user %d: %v", userID, err))
+	}
+}
+
+// reviewFraudCase 审核案件，驳回时如果玩家没有其他冻结中的案件则解除冻结；确认时保持冻结，需要时另行封禁
+func (gs *GMServer) reviewFraudCase(caseID string, confirm bool, reviewer uint64) (string, error) {
+	status := database.FraudCaseDismissed
+	if confirm {
+		status = database.FraudCaseConfirmed
+	}
+
+	fraudCase, err := gs.fraudRepo.Resolve(caseID, status, reviewer)
+	if err != nil {
+		return "", err
+	}
+	if fraudCase == nil {
+		return "", fmt.Errorf("案件 %s 不存在或已审核", caseID)
+	}
+	gs.gmRepo.LogGMAction(reviewer, "fraud_review", fraudCase.UserID,
+		fmt.Sprintf("案件: %s, 规则: %s, 结果: %s", caseID, fraudCase.Rule, status))
+
+	if confirm || !fraudCase.Frozen {
+		return fmt.Sprintf("案件 %s 已%s", caseID, status), nil
+	}
+
+	remaining, err := gs.fraudRepo.CountFrozen(fraudCase.UserID)
+	if err != nil {
+		return "", err
+	}
+	if remaining > 0 {
+		return fmt.Sprintf("案件 %s 已驳回，用户 %d 还有 %d 个冻结中的案件", caseID, fraudCase.UserID, remaining), nil
+	}
+	if _, err := gs.userRepo.UpdateStatus(fraudCase.UserID, userStatusFrozen, 0); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("案件 %s 已驳回，用户 %d 已解除冻结", caseID, fraudCase.UserID), nil
+}
+
+// fraudBacktestReport 回测结果
+type fraudBacktestReport struct {
+	Since   time.Time      `json:"since"`
+	Until   time.Time      `json:"until"`
+	Entries int            `json:"entries"`
+	Partial bool           `json:"partial"` // 流水超过上限，只检查了最早的部分
+	Counts  map[string]int `json:"counts"`  // 每条规则的命中玩家数
+	Hits    []*fraud.Hit   `json:"hits"`    // 最多返回100条
+}
+
+// backtestFraud 用当前规则(包括off模式)检查历史流水，不创建案件也不冻结账号
+func (gs *GMServer) backtestFraud(hours int) (*fraudBacktestReport, error) {
+	config := gs.fraudConfig()
+	until := time.Now()
+	since := until.Add(-time.Duration(hours) * time.Hour)
+
+	entries, err := gs.ledgerRepo.GetRange(since, until, fraudBacktestLimit)
+	if err != nil {
+		return nil, err
+	}
+	hits, err := fraud.Evaluate(config.Rules, entries, gs.userRepo, time.Time{}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &fraudBacktestReport{
+		Since:   since,
+		Until:   until,
+		Entries: len(entries),
+		Partial: len(entries) == fraudBacktestLimit,
+		Counts:  make(map[string]int, len(config.Rules)),
+	}
+	for _, rule := range config.Rules {
+		report.Counts[rule.Name] = 0
+	}
+	for _, hit := range hits {
+		report.Counts[hit.Rule]++
+	}
+	if len(hits) > 100 {
+		hits = hits[:100]
+	}
+	report.Hits = hits
+	return report, nil
+}
+
+// fraudCasesJSON 案件列表
+func (gs *GMServer) fraudCasesJSON(status string) (string, error) {
+	cases, err := gs.fraudRepo.List(status, 50)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(cases)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -3,11 +3,14 @@ package server
 import (
 	"context"
 	"fmt"
-	"reflect"
 	"time"
 
 	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/errcode"
 	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/quota"
+	"github.com/phuhao00/lufy/internal/settings"
+	"github.com/phuhao00/lufy/internal/world"
 	"github.com/phuhao00/lufy/pkg/proto"
 )
 
@@ -15,6 +18,9 @@ import (
 type FriendServer struct {
 	*BaseServer
 	friendRepo *database.FriendRepository
+	notifier   *Notifier
+	settings   *settings.Service
+	quotas     *quota.Service
 }
 
 // NewFriendServer 创建好友服务器
@@ -27,6 +33,9 @@ func NewFriendServer(configFile, nodeID string) *FriendServer {
 	friendServer := &FriendServer{
 		BaseServer: baseServer,
 		friendRepo: database.NewFriendRepository(baseServer.mongoManager),
+		notifier:   NewNotifier(baseServer),
+		settings:   baseServer.newSettings(),
+		quotas:     baseServer.newQuotas(),
 	}
 
 	// 注册通用服务
@@ -36,42 +45,38 @@ func NewFriendServer(configFile, nodeID string) *FriendServer {
 
 	// 注册好友服务
 	friendService := NewFriendService(friendServer)
-	if err := baseServer.rpcServer.RegisterService(friendService); err != nil {
+	if err := RegisterFriendService(baseServer.rpcServer, friendService); err != nil {
 		logger.Fatal(fmt.Sprintf("Failed to register friend service: %v", err))
 	}
 
 	return friendServer
 }
 
+// crossWorldAllowed 检查用户和对方能否成为好友，同一世界内总是允许
+func (fs *FriendServer) crossWorldAllowed(userID uint64, other *database.User) bool {
+	userRepo := database.NewUserRepository(fs.mongoManager)
+	user, err := userRepo.GetByUserID(userID)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to get user %d for world check: %v", userID, err))
+		return false
+	}
+	return fs.config.World.Allows(world.ActionFriend, user.WorldID, other.WorldID)
+}
+
 // FriendService 好友RPC服务
 type FriendService struct {
-	server *FriendServer
+	server    *FriendServer
+	responses *errcode.Builder
 }
 
 // NewFriendService 创建好友服务
 func NewFriendService(server *FriendServer) *FriendService {
 	return &FriendService{
-		server: server,
+		server:    server,
+		responses: errcode.NewBuilder(nil),
 	}
 }
 
-// GetName 获取服务名称
-func (fs *FriendService) GetName() string {
-	return "FriendService"
-}
-
-// RegisterMethods 注册方法
-func (fs *FriendService) RegisterMethods() map[string]reflect.Value {
-	methods := make(map[string]reflect.Value)
-
-	methods["AddFriend"] = reflect.ValueOf(fs.AddFriend)
-	methods["AcceptFriend"] = reflect.ValueOf(fs.AcceptFriend)
-	methods["GetFriendList"] = reflect.ValueOf(fs.GetFriendList)
-	methods["DeleteFriend"] = reflect.ValueOf(fs.DeleteFriend)
-
-	return methods
-}
-
 // AddFriend 添加好友
 func (fs *FriendService) AddFriend(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
 	// 验证用户ID
@@ -137,9 +142,38 @@ func (fs *FriendService) AddFriend(ctx context.Context, req *proto.BaseRequest)
 		}, nil
 	}
 
+	// 跨世界好友需要在配置中开启
+	if !fs.server.crossWorldAllowed(userID, targetUser) {
+		logger.Warn(fmt.Sprintf("AddFriend: user %d and %d are in different worlds", userID, friendID))
+		return &proto.BaseResponse{
+			Header: req.Header,
+			Code:   -7,
+			Msg:    "cross-world friend request not allowed",
+		}, nil
+	}
+
+	// 对方的隐私设置不接受好友请求
+	allowed, err := fs.server.settings.Allows(friendID, settings.ActionFriendRequests, userID, false)
+	if err != nil {
+		logger.Error(fmt.Sprintf("AddFriend: failed to check settings of user %d: %v", friendID, err))
+	}
+	if !allowed {
+		return &proto.BaseResponse{
+			Header: req.Header,
+			Code:   -8,
+			Msg:    "friend requests not accepted",
+		}, nil
+	}
+
+	// 每日好友请求次数
+	if resp := consumeQuota(ctx, fs.server.quotas, fs.responses, req.Header, quota.OpFriendRequest); resp != nil {
+		return resp, nil
+	}
+
 	// 添加好友请求
 	if err := fs.server.friendRepo.AddFriend(userID, friendID, message); err != nil {
 		logger.Error(fmt.Sprintf("AddFriend: failed to add friend request: %v", err))
+		fs.server.quotas.Refund(userID, quota.OpFriendRequest)
 		return &proto.BaseResponse{
 			Header: req.Header,
 			Code:   -6,
@@ -147,6 +181,17 @@ func (fs *FriendService) AddFriend(ctx context.Context, req *proto.BaseRequest)
 		}, nil
 	}
 
+	// 重复的好友请求只保留最新的一条通知
+	if err := fs.server.notifier.Notify(&database.Notification{
+		UserID:     friendID,
+		Type:       NotifyFriendRequest,
+		DedupKey:   fmt.Sprintf("%s:%d", NotifyFriendRequest, userID),
+		FromUserID: userID,
+		Data:       map[string]interface{}{"message": message},
+	}); err != nil {
+		logger.Error(fmt.Sprintf("AddFriend: failed to notify user %d: %v", friendID, err))
+	}
+
 	logger.Info(fmt.Sprintf("User %d sent friend request to %s (ID: %d)", userID, targetUser.Nickname, friendID))
 
 	return &proto.BaseResponse{
@@ -204,6 +249,16 @@ func (fs *FriendService) AcceptFriend(ctx context.Context, req *proto.BaseReques
 		}, nil
 	}
 
+	// 请求发出后关闭了跨世界好友时同样拒绝
+	if !fs.server.crossWorldAllowed(userID, requesterUser) {
+		logger.Warn(fmt.Sprintf("AcceptFriend: user %d and %d are in different worlds", userID, friendID))
+		return &proto.BaseResponse{
+			Header: req.Header,
+			Code:   -7,
+			Msg:    "cross-world friend request not allowed",
+		}, nil
+	}
+
 	// 接受好友请求
 	if err := fs.server.friendRepo.AcceptFriend(userID, friendID); err != nil {
 		logger.Error(fmt.Sprintf("AcceptFriend: failed to accept friend request: %v", err))
@@ -214,6 +269,15 @@ func (fs *FriendService) AcceptFriend(ctx context.Context, req *proto.BaseReques
 		}, nil
 	}
 
+	if err := fs.server.notifier.Notify(&database.Notification{
+		UserID:     friendID,
+		Type:       NotifyFriendAccepted,
+		DedupKey:   fmt.Sprintf("%s:%d", NotifyFriendAccepted, userID),
+		FromUserID: userID,
+	}); err != nil {
+		logger.Error(fmt.Sprintf("AcceptFriend: failed to notify user %d: %v", friendID, err))
+	}
+
 	logger.Info(fmt.Sprintf("User %d accepted friend request from %s (ID: %d)", userID, requesterUser.Nickname, friendID))
 
 	return &proto.BaseResponse{
@@ -264,6 +328,16 @@ func (fs *FriendService) GetFriendList(ctx context.Context, req *proto.BaseReque
 		if time.Since(friendUser.LastLoginAt) < 30*time.Minute {
 			online = true
 		}
+		lastLoginTime := uint32(friendUser.LastLoginAt.Unix())
+
+		// 好友隐藏了在线状态时不返回在线和最近登录时间
+		if visible, err := fs.server.settings.Allows(friend.FriendID, settings.ActionOnlineStatus, userID, friend.Status == 1); err != nil || !visible {
+			if err != nil {
+				logger.Warn(fmt.Sprintf("GetFriendList: failed to check settings of user %d: %v", friend.FriendID, err))
+			}
+			online = false
+			lastLoginTime = 0
+		}
 
 		friendInfo := &proto.FriendInfo{
 			UserId:        friendUser.UserID,
@@ -271,7 +345,7 @@ func (fs *FriendService) GetFriendList(ctx context.Context, req *proto.BaseReque
 			Level:         friendUser.Level,
 			Avatar:        friendUser.Avatar,
 			Online:        online,
-			LastLoginTime: uint32(friendUser.LastLoginAt.Unix()),
+			LastLoginTime: lastLoginTime,
 		}
 
 		friendInfos = append(friendInfos, friendInfo)
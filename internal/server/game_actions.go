@@ -0,0 +1,40 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/phuhao00/lufy/internal/gameplay"
+)
+
+// gameActionNames 对局操作类型在操作表中的名称
+var gameActionNames = map[int32]string{
+	1: "play_card",
+	2: "use_skill",
+	3: "end_turn",
+	4: "surrender",
+}
+
+// gameActionSchemas 对局操作的载荷格式，处理操作前校验，不符合格式的载荷不会进入操作处理
+var gameActionSchemas = gameplay.ActionSchemas{
+	"play_card": {Fields: []gameplay.ActionField{
+		{Name: "card_id", Type: gameplay.FieldTypeInt, Required: true, Min: 1},
+		{Name: "target_id", Type: gameplay.FieldTypeInt},
+	}},
+	"use_skill": {Fields: []gameplay.ActionField{
+		{Name: "skill_id", Type: gameplay.FieldTypeInt, Required: true, Min: 1},
+		{Name: "target_id", Type: gameplay.FieldTypeInt},
+	}},
+	"end_turn":  {},
+	"surrender": {},
+}
+
+// actionValidationData 载荷校验错误的结构化内容，放入错误响应的Data，客户端据此提示具体的字段
+func actionValidationData(err error) []byte {
+	var validationErr *gameplay.ActionValidationError
+	if !errors.As(err, &validationErr) {
+		return nil
+	}
+	data, _ := json.Marshal(validationErr)
+	return data
+}
@@ -4,12 +4,19 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"reflect"
+	"math"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/errcode"
 	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/mq"
+	"github.com/phuhao00/lufy/internal/progression"
+	"github.com/phuhao00/lufy/internal/realtime"
+	"github.com/phuhao00/lufy/internal/saga"
+	"github.com/phuhao00/lufy/internal/settlement"
 	"github.com/phuhao00/lufy/pkg/proto"
 )
 
@@ -17,6 +24,14 @@ import (
 type GameServer struct {
 	*BaseServer
 	gameRecordRepo *database.GameRecordRepository
+	ledgerRepo     *database.LedgerRepository
+	asyncGameRepo  *database.AsyncGameRepository
+	roomRepo       *database.RoomRepository
+	progression    *progression.Service
+	settler        *settlement.Settler
+	sagas          *saga.Coordinator
+	migrations     gameMigrations           // 本节点迁出的游戏
+	notifier       *Notifier
 	games          map[uint64]*GameInstance // 游戏实例映射
 	gamesMutex     sync.RWMutex             // 游戏实例锁
 	nextGameID     uint64                   // 下一个游戏ID
@@ -34,7 +49,13 @@ type GameInstance struct {
 	StartTime     time.Time                  `json:"start_time"`
 	EndTime       time.Time                  `json:"end_time"`
 	Winner        uint64                     `json:"winner"`
-	GameData      map[string]interface{}     `json:"game_data"`
+	GameData      *GameData                  `json:"-"`
+	Async         bool                       `json:"async"`         // 异步对局，状态保存在数据库中
+	TurnDeadline  time.Time                  `json:"turn_deadline"` // 异步对局当前回合的截止时间
+	turnTimeout   time.Duration              `json:"-"`
+	reminded      bool                       `json:"-"`
+	version       int64                      `json:"-"`
+	migratedTo    string                     `json:"-"` // 已迁移到的节点，迁移前等待锁的操作据此放弃
 	mutex         sync.RWMutex               `json:"-"`
 }
 
@@ -58,9 +79,17 @@ func NewGameServer(configFile, nodeID string) *GameServer {
 	gameServer := &GameServer{
 		BaseServer:     baseServer,
 		gameRecordRepo: database.NewGameRecordRepository(baseServer.mongoManager),
+		ledgerRepo:     database.NewLedgerRepository(baseServer.mongoManager),
+		asyncGameRepo:  database.NewAsyncGameRepository(baseServer.mongoManager),
+		roomRepo:       database.NewRoomRepository(baseServer.mongoManager),
+		progression:    baseServer.newProgression(),
+		notifier:       NewNotifier(baseServer),
 		games:          make(map[uint64]*GameInstance),
 		nextGameID:     1,
 	}
+	gameServer.settler = settlement.NewSettler(&baseServer.config.Settlement, nodeID,
+		database.NewSettlementRepository(baseServer.mongoManager), gameServer.gameRecordRepo)
+	gameServer.sagas = saga.NewCoordinator(&baseServer.config.Saga, nodeID, database.NewSagaRepository(baseServer.mongoManager))
 
 	// 注册通用服务
 	if err := RegisterCommonServices(baseServer); err != nil {
@@ -69,13 +98,141 @@ func NewGameServer(configFile, nodeID string) *GameServer {
 
 	// 注册游戏服务
 	gameService := NewGameService(gameServer)
-	if err := baseServer.rpcServer.RegisterService(gameService); err != nil {
+	if err := RegisterGameService(baseServer.rpcServer, gameService); err != nil {
 		logger.Fatal(fmt.Sprintf("Failed to register game service: %v", err))
 	}
 
+	if err := gameServer.initSettlement(); err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to init settlement: %v", err))
+	}
+
+	if err := gameServer.initStartGameSaga(); err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to init start game saga: %v", err))
+	}
+
+	// 排空时迁出进行中的游戏
+	baseServer.systemHandler.RegisterHandler(mq.SYS_CMD_DRAIN, gameServer.handleDrain)
+	baseServer.systemHandler.RegisterHandler(mq.SYS_CMD_USER_RENAMED, gameServer.handleUserRenamed)
+
+	// 上报进行中的游戏数，中心服缩容时不移除仍有游戏的节点
+	baseServer.roomCount = gameServer.gameCount
+
+	// 上线前初始化游戏状态的编解码
+	baseServer.AddWarmup("game_states", warmGameStates)
+
+	// 异步对局的截止提醒和超时判负
+	if err := baseServer.scheduler.Register("async_game_turns", "@every 1m", gameService.checkAsyncTurns); err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to register async game job: %v", err))
+	}
+
 	return gameServer
 }
 
+// initSettlement 注册结算步骤，订阅游戏结束事件并定期对账
+// 游戏事件按节点订阅，每个游戏节点都会收到同一局的结束事件，由结算记录保证只结算一次
+func (gs *GameServer) initSettlement() error {
+	if err := gs.settler.Register("rewards", gs.settleRewards); err != nil {
+		return err
+	}
+
+	handler := mq.NewGameMessageHandler()
+	handler.RegisterHandler(mq.MSG_GAME_ENDED, gs.handleGameEnded)
+	gs.RegisterOnStart("game_events", OrderSubscriptions, func(ctx context.Context) error {
+		return gs.messageBroker.SubscribeGameEvents(handler)
+	})
+	gs.RegisterOnStop("game_events", OrderSubscriptions, func(ctx context.Context) error {
+		return gs.messageBroker.UnsubscribeGameEvents()
+	})
+
+	gs.systemHandler.RegisterHandler(mq.SYS_CMD_RECONCILE, gs.handleReconcile)
+
+	return gs.scheduler.Register("reconcile_settlements", "@every 10m", gs.settler.ReconcileRecent)
+}
+
+// handleGameEnded 结算已结束的游戏，失败时返回错误等待消息重新投递
+func (gs *GameServer) handleGameEnded(msg *mq.GameMessage) error {
+	event, err := mq.GameEvent[proto.GameEndedEvent](msg)
+	if err != nil {
+		return err
+	}
+	if event.GameId == 0 {
+		return fmt.Errorf("game ended message without game id")
+	}
+	return gs.settler.SettleGame(context.Background(), event.GameId)
+}
+
+// handleReconcile 处理GM发起的对账命令，结果写入日志
+func (gs *GameServer) handleReconcile(msg *mq.SystemMessage) error {
+	command, err := mq.Command[proto.ReconcileCommand](msg)
+	if err != nil {
+		return err
+	}
+	hours, repair := command.Hours, command.Repair
+	if hours <= 0 {
+		hours = gs.settler.Config().ReconcileWindow.Hours()
+	}
+
+	go func() {
+		until := time.Now()
+		since := until.Add(-time.Duration(hours * float64(time.Hour)))
+		report, err := gs.settler.Reconcile(context.Background(), since, until, repair)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Settlement reconcile failed: %v", err))
+			return
+		}
+		for _, d := range report.Discrepancies {
+			logger.Warn(fmt.Sprintf("Unsettled game %d step %s: %s (attempts %d, repaired %v) %s%s",
+				d.GameID, d.Step, d.Issue, d.Attempts, d.Repaired, d.Error, d.RepairError))
+		}
+		logger.Info(fmt.Sprintf("Settlement reconcile checked %d games, %d fully settled, %d unsettled steps, truncated %v",
+			report.Checked, report.Settled, len(report.Discrepancies), report.Truncated))
+	}()
+
+	return nil
+}
+
+// settleRewards 发放对局奖励，胜者和其他玩家按配置获得经验和金币，游戏结束时进行中的活动按倍率加成，经验入账后检查升级
+// 流水以游戏ID、玩家和货币生成，重复执行不会重复入账，批次ID为 settlement:<游戏ID>，可按批次回滚
+func (gs *GameServer) settleRewards(ctx context.Context, record *database.GameRecord) error {
+	config := gs.settler.Config()
+	batchID := fmt.Sprintf("settlement:%d", record.GameID)
+	endedAt := record.UpdatedAt // 结束时更新记录
+	for _, player := range record.Players {
+		reward := config.LoseReward
+		if player.UserID == record.Winner {
+			reward = config.WinReward
+		}
+
+		for currency, amount := range map[string]int64{
+			database.CurrencyExperience: reward.Experience,
+			database.CurrencyGold:       reward.Gold,
+		} {
+			if amount == 0 {
+				continue
+			}
+			amount = int64(math.Round(float64(amount) * gs.calendar.Multiplier(currency, record.GameType, endedAt)))
+			txID := fmt.Sprintf("%s:%d:%s", batchID, player.UserID, currency)
+			if currency == database.CurrencyExperience {
+				if _, err := gs.progression.Grant(player.UserID, amount, txID, batchID, "game reward"); err != nil {
+					return fmt.Errorf("failed to grant experience to user %d: %v", player.UserID, err)
+				}
+				continue
+			}
+			if _, err := gs.ledgerRepo.Apply(&database.LedgerEntry{
+				TxID:     txID,
+				BatchID:  batchID,
+				UserID:   player.UserID,
+				Currency: currency,
+				Amount:   amount,
+				Reason:   "game reward",
+			}); err != nil {
+				return fmt.Errorf("failed to reward user %d: %v", player.UserID, err)
+			}
+		}
+	}
+	return nil
+}
+
 // generateGameID 生成游戏ID
 func (gs *GameServer) generateGameID() uint64 {
 	gs.idMutex.Lock()
@@ -93,6 +250,13 @@ func (gs *GameServer) getGame(gameID uint64) (*GameInstance, bool) {
 	return game, exists
 }
 
+// gameCount 本节点进行中的游戏数
+func (gs *GameServer) gameCount() int {
+	gs.gamesMutex.RLock()
+	defer gs.gamesMutex.RUnlock()
+	return len(gs.games)
+}
+
 // addGame 添加游戏实例
 func (gs *GameServer) addGame(game *GameInstance) {
 	gs.gamesMutex.Lock()
@@ -100,6 +264,19 @@ func (gs *GameServer) addGame(game *GameInstance) {
 	gs.games[game.GameID] = game
 }
 
+// isSpectator 用户是否在观战游戏所在的房间
+func (gs *GameServer) isSpectator(game *GameInstance, userID uint64) bool {
+	if game.Async {
+		return false
+	}
+	spectating, err := gs.roomRepo.IsSpectator(game.RoomID, userID)
+	if err != nil {
+		logger.Error(fmt.Sprintf("failed to check spectator of game %d: %v", game.GameID, err))
+		return false
+	}
+	return spectating
+}
+
 // removeGame 移除游戏实例
 func (gs *GameServer) removeGame(gameID uint64) {
 	gs.gamesMutex.Lock()
@@ -107,57 +284,89 @@ func (gs *GameServer) removeGame(gameID uint64) {
 	delete(gs.games, gameID)
 }
 
+// recordGameEnded 更新已结束游戏的记录并发布结束事件，返回游戏时长（秒）
+func (gs *GameServer) recordGameEnded(game *GameInstance, userID uint64) int32 {
+	// 计算游戏时长
+	duration := int32(game.EndTime.Sub(game.StartTime).Seconds())
+
+	// 更新游戏记录
+	gameRecord := &database.GameRecord{
+		GameID:   game.GameID,
+		RoomID:   game.RoomID,
+		GameType: game.GameType,
+		Winner:   game.Winner,
+		Duration: duration,
+		Status:   1, // 已结束
+	}
+
+	// 添加玩家信息到记录
+	for _, player := range game.Players {
+		gamePlayer := database.GamePlayer{
+			UserID:   player.UserID,
+			Nickname: player.Nickname,
+			Level:    player.Level,
+			Score:    player.Score,
+			Rank:     1, // 简化处理，实际应该根据分数排名
+		}
+		if player.UserID == game.Winner {
+			gamePlayer.Rank = 1
+		} else {
+			gamePlayer.Rank = 2
+		}
+		gameRecord.Players = append(gameRecord.Players, gamePlayer)
+	}
+
+	if err := gs.gameRecordRepo.UpdateRecord(gameRecord); err != nil {
+		logger.Error(fmt.Sprintf("EndGame: failed to update game record: %v", err))
+		// 不返回错误，继续处理
+	} else if err := gs.messageBroker.PublishGameEvent(mq.MSG_GAME_ENDED, game.RoomID, userID, &proto.GameEndedEvent{
+		GameId: game.GameID,
+		Winner: game.Winner,
+	}); err != nil {
+		// 事件丢失时由对账任务补结算
+		logger.Error(fmt.Sprintf("EndGame: failed to publish game ended event: %v", err))
+	}
+
+	// 房间结束后不再接受观战
+	if !game.Async {
+		if err := gs.roomRepo.EndRoomGame(game.RoomID, game.GameID); err != nil {
+			logger.Error(fmt.Sprintf("EndGame: %v", err))
+		} else {
+			gs.realtime.Adjust(realtime.Delta{GameType: game.GameType, Rooms: -1, Games: -1, Players: -int64(len(game.Players))})
+		}
+	}
+
+	return duration
+}
+
 // GameService 游戏RPC服务
 type GameService struct {
-	server *GameServer
+	server    *GameServer
+	responses *errcode.Builder
 }
 
 // NewGameService 创建游戏服务
 func NewGameService(server *GameServer) *GameService {
 	return &GameService{
-		server: server,
+		server:    server,
+		responses: errcode.NewBuilder(nil),
 	}
 }
 
-// GetName 获取服务名称
-func (gs *GameService) GetName() string {
-	return "GameService"
-}
-
-// RegisterMethods 注册方法
-func (gs *GameService) RegisterMethods() map[string]reflect.Value {
-	methods := make(map[string]reflect.Value)
-
-	methods["StartGame"] = reflect.ValueOf(gs.StartGame)
-	methods["EndGame"] = reflect.ValueOf(gs.EndGame)
-	methods["PlayerAction"] = reflect.ValueOf(gs.PlayerAction)
-	methods["GetGameState"] = reflect.ValueOf(gs.GetGameState)
-
-	return methods
-}
-
 // StartGame 开始游戏
 func (gs *GameService) StartGame(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
 	// 验证用户ID
 	userID := req.Header.GetUserId()
 	if userID == 0 {
 		logger.Error("StartGame: invalid user id")
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -1,
-			Msg:    "invalid user id",
-		}, nil
+		return gs.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
 	}
 
 	// 解析请求数据
 	var startGameReq proto.StartGameRequest
 	if err := proto.Unmarshal(req.Data, &startGameReq); err != nil {
 		logger.Error(fmt.Sprintf("StartGame: failed to unmarshal request: %v", err))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -2,
-			Msg:    "invalid request data",
-		}, nil
+		return gs.responses.Error(ctx, req.Header, errcode.InvalidRequest), nil
 	}
 
 	roomID := startGameReq.GetRoomId()
@@ -166,11 +375,7 @@ func (gs *GameService) StartGame(ctx context.Context, req *proto.BaseRequest) (*
 	// 验证房间ID
 	if roomID == 0 {
 		logger.Error("StartGame: invalid room id")
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -3,
-			Msg:    "invalid room id",
-		}, nil
+		return gs.responses.Error(ctx, req.Header, errcode.InvalidRoomID), nil
 	}
 
 	// 获取用户信息
@@ -178,62 +383,33 @@ func (gs *GameService) StartGame(ctx context.Context, req *proto.BaseRequest) (*
 	user, err := userRepo.GetByUserID(userID)
 	if err != nil {
 		logger.Error(fmt.Sprintf("StartGame: failed to get user %d: %v", userID, err))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -4,
-			Msg:    "user not found",
-		}, nil
-	}
-
-	// 生成游戏ID
-	gameID := gs.server.generateGameID()
-
-	// 创建游戏实例
-	game := &GameInstance{
-		GameID:        gameID,
-		RoomID:        roomID,
-		GameType:      gameType,
-		Status:        0, // 等待开始
-		Players:       make(map[uint64]*GamePlayerData),
-		CurrentPlayer: userID,
-		StartTime:     time.Now(),
-		GameData:      make(map[string]interface{}),
+		return gs.responses.Error(ctx, req.Header, errcode.UserNotFound), nil
 	}
 
-	// 添加创建者为玩家
-	playerData := &GamePlayerData{
-		UserID:   userID,
-		Nickname: user.Nickname,
-		Level:    user.Level,
-		Score:    0,
-		Status:   1, // 准备状态
-		Data:     make(map[string]interface{}),
+	// 异步对局保存在数据库中，不占用节点内存
+	if startGameReq.GetAsync() {
+		if err := requireFeature(gs.server.progression, userID, featureAsyncGame); err != nil {
+			logger.Debug(fmt.Sprintf("StartGame: user %d: %v", userID, err))
+			return gs.responses.Error(ctx, req.Header, err), nil
+		}
+		return gs.startAsyncGame(ctx, req, userID, &startGameReq)
 	}
-	game.Players[userID] = playerData
 
-	// 添加到游戏服务器
-	gs.server.addGame(game)
+	// 生成游戏ID
+	gameID := gs.server.generateGameID()
 
-	// 创建游戏记录
-	gameRecord := &database.GameRecord{
+	// 房间状态、游戏记录和游戏实例由开始游戏流程统一创建，失败时全部撤销
+	if err := gs.server.startGame(ctx, &startGameData{
 		GameID:   gameID,
 		RoomID:   roomID,
 		GameType: gameType,
-		Players: []database.GamePlayer{
-			{
-				UserID:   userID,
-				Nickname: user.Nickname,
-				Level:    user.Level,
-				Score:    0,
-				Rank:     0,
-			},
-		},
-		Status: 0, // 进行中
-	}
-
-	if err := gs.server.gameRecordRepo.CreateRecord(gameRecord); err != nil {
-		logger.Error(fmt.Sprintf("StartGame: failed to create game record: %v", err))
-		// 不返回错误，继续游戏
+		NodeID:   gs.server.nodeID,
+		UserID:   userID,
+		Nickname: user.Nickname,
+		Level:    user.Level,
+	}); err != nil {
+		logger.Error(fmt.Sprintf("StartGame: %v", err))
+		return gs.responses.Error(ctx, req.Header, errcode.GameStartFailed), nil
 	}
 
 	logger.Info(fmt.Sprintf("User %s (ID: %d) started game %d in room %d", user.Nickname, userID, gameID, roomID))
@@ -243,25 +419,16 @@ func (gs *GameService) StartGame(ctx context.Context, req *proto.BaseRequest) (*
 		"game_id":   gameID,
 		"room_id":   roomID,
 		"game_type": gameType,
-		"status":    game.Status,
+		"status":    0, // 等待开始
 	}
 
 	responseBytes, err := json.Marshal(responseData)
 	if err != nil {
 		logger.Error(fmt.Sprintf("StartGame: failed to marshal response: %v", err))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -5,
-			Msg:    "failed to create response",
-		}, nil
-	}
-
-	return &proto.BaseResponse{
-		Header: req.Header,
-		Code:   0,
-		Msg:    "game started successfully",
-		Data:   responseBytes,
-	}, nil
+		return gs.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+
+	return gs.responses.Success(ctx, req.Header, "game started successfully", responseBytes), nil
 }
 
 // EndGame 结束游戏
@@ -270,22 +437,14 @@ func (gs *GameService) EndGame(ctx context.Context, req *proto.BaseRequest) (*pr
 	userID := req.Header.GetUserId()
 	if userID == 0 {
 		logger.Error("EndGame: invalid user id")
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -1,
-			Msg:    "invalid user id",
-		}, nil
+		return gs.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
 	}
 
 	// 解析请求数据
 	var endGameReq proto.EndGameRequest
 	if err := proto.Unmarshal(req.Data, &endGameReq); err != nil {
 		logger.Error(fmt.Sprintf("EndGame: failed to unmarshal request: %v", err))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -2,
-			Msg:    "invalid request data",
-		}, nil
+		return gs.responses.Error(ctx, req.Header, errcode.InvalidRequest), nil
 	}
 
 	gameID := endGameReq.GetGameId()
@@ -294,93 +453,58 @@ func (gs *GameService) EndGame(ctx context.Context, req *proto.BaseRequest) (*pr
 	// 验证游戏ID
 	if gameID == 0 {
 		logger.Error("EndGame: invalid game id")
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -3,
-			Msg:    "invalid game id",
-		}, nil
+		return gs.responses.Error(ctx, req.Header, errcode.InvalidGameID), nil
 	}
 
 	// 获取游戏实例
-	game, exists := gs.server.getGame(gameID)
+	game, exists := gs.server.findGame(gameID)
 	if !exists {
 		logger.Error(fmt.Sprintf("EndGame: game %d not found", gameID))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -4,
-			Msg:    "game not found",
-		}, nil
+		return gs.responses.Error(ctx, req.Header, gs.server.missingGame(gameID)), nil
 	}
 
 	// 检查用户是否在游戏中
 	game.mutex.Lock()
 	defer game.mutex.Unlock()
 
+	if game.migratedTo != "" {
+		return gs.responses.Error(ctx, req.Header, errcode.GameMigrating.WithDetail(game.migratedTo)), nil
+	}
+
 	if _, exists := game.Players[userID]; !exists {
 		logger.Error(fmt.Sprintf("EndGame: user %d not in game %d", userID, gameID))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -5,
-			Msg:    "user not in game",
-		}, nil
+		return gs.responses.Error(ctx, req.Header, errcode.NotInGame), nil
 	}
 
 	// 检查游戏状态
 	if game.Status == 2 {
 		logger.Warn(fmt.Sprintf("EndGame: game %d already ended", gameID))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -6,
-			Msg:    "game already ended",
-		}, nil
+		return gs.responses.Error(ctx, req.Header, errcode.GameAlreadyEnded), nil
 	}
 
 	// 结束游戏
+	previousPlayer := game.CurrentPlayer
 	game.Status = 2 // 已结束
 	game.EndTime = time.Now()
 	game.Winner = winner
 
-	// 计算游戏时长
-	duration := int32(game.EndTime.Sub(game.StartTime).Seconds())
-
-	// 更新游戏记录
-	gameRecord := &database.GameRecord{
-		GameID:   gameID,
-		RoomID:   game.RoomID,
-		GameType: game.GameType,
-		Winner:   winner,
-		Duration: duration,
-		Status:   1, // 已结束
-	}
-
-	// 添加玩家信息到记录
-	for _, player := range game.Players {
-		gamePlayer := database.GamePlayer{
-			UserID:   player.UserID,
-			Nickname: player.Nickname,
-			Level:    player.Level,
-			Score:    player.Score,
-			Rank:     1, // 简化处理，实际应该根据分数排名
+	// 异步对局保存成功后才结算
+	if game.Async {
+		if resp := gs.saveAsyncGame(ctx, req.Header, game, previousPlayer); resp != nil {
+			return resp, nil
 		}
-		if player.UserID == winner {
-			gamePlayer.Rank = 1
-		} else {
-			gamePlayer.Rank = 2
-		}
-		gameRecord.Players = append(gameRecord.Players, gamePlayer)
 	}
 
-	if err := gs.server.gameRecordRepo.UpdateRecord(gameRecord); err != nil {
-		logger.Error(fmt.Sprintf("EndGame: failed to update game record: %v", err))
-		// 不返回错误，继续处理
-	}
+	duration := gs.server.recordGameEnded(game, userID)
 
 	// 从内存中移除游戏实例（延迟移除，给客户端时间获取最终状态）
-	go func() {
-		time.Sleep(5 * time.Minute)
-		gs.server.removeGame(gameID)
-		logger.Info(fmt.Sprintf("Game %d removed from memory", gameID))
-	}()
+	if !game.Async {
+		go func() {
+			time.Sleep(5 * time.Minute)
+			gs.server.removeGame(gameID)
+			logger.Info(fmt.Sprintf("Game %d removed from memory", gameID))
+		}()
+	}
 
 	logger.Info(fmt.Sprintf("Game %d ended, winner: %d, duration: %d seconds", gameID, winner, duration))
 
@@ -395,19 +519,10 @@ func (gs *GameService) EndGame(ctx context.Context, req *proto.BaseRequest) (*pr
 	responseBytes, err := json.Marshal(responseData)
 	if err != nil {
 		logger.Error(fmt.Sprintf("EndGame: failed to marshal response: %v", err))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -7,
-			Msg:    "failed to create response",
-		}, nil
-	}
-
-	return &proto.BaseResponse{
-		Header: req.Header,
-		Code:   0,
-		Msg:    "game ended successfully",
-		Data:   responseBytes,
-	}, nil
+		return gs.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+
+	return gs.responses.Success(ctx, req.Header, "game ended successfully", responseBytes), nil
 }
 
 // PlayerAction 玩家操作
@@ -416,22 +531,14 @@ func (gs *GameService) PlayerAction(ctx context.Context, req *proto.BaseRequest)
 	userID := req.Header.GetUserId()
 	if userID == 0 {
 		logger.Error("PlayerAction: invalid user id")
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -1,
-			Msg:    "invalid user id",
-		}, nil
+		return gs.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
 	}
 
 	// 解析请求数据
 	var actionReq proto.PlayerActionRequest
 	if err := proto.Unmarshal(req.Data, &actionReq); err != nil {
 		logger.Error(fmt.Sprintf("PlayerAction: failed to unmarshal request: %v", err))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -2,
-			Msg:    "invalid request data",
-		}, nil
+		return gs.responses.Error(ctx, req.Header, errcode.InvalidRequest), nil
 	}
 
 	gameID := actionReq.GetGameId()
@@ -441,87 +548,90 @@ func (gs *GameService) PlayerAction(ctx context.Context, req *proto.BaseRequest)
 	// 验证游戏ID
 	if gameID == 0 {
 		logger.Error("PlayerAction: invalid game id")
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -3,
-			Msg:    "invalid game id",
-		}, nil
+		return gs.responses.Error(ctx, req.Header, errcode.InvalidGameID), nil
 	}
 
 	// 获取游戏实例
-	game, exists := gs.server.getGame(gameID)
+	game, exists := gs.server.findGame(gameID)
 	if !exists {
 		logger.Error(fmt.Sprintf("PlayerAction: game %d not found", gameID))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -4,
-			Msg:    "game not found",
-		}, nil
+		return gs.responses.Error(ctx, req.Header, gs.server.missingGame(gameID)), nil
 	}
 
 	// 检查用户是否在游戏中
 	game.mutex.Lock()
 	defer game.mutex.Unlock()
 
+	if game.migratedTo != "" {
+		return gs.responses.Error(ctx, req.Header, errcode.GameMigrating.WithDetail(game.migratedTo)), nil
+	}
+
 	player, exists := game.Players[userID]
 	if !exists {
 		logger.Error(fmt.Sprintf("PlayerAction: user %d not in game %d", userID, gameID))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -5,
-			Msg:    "user not in game",
-		}, nil
+		return gs.responses.Error(ctx, req.Header, errcode.NotInGame), nil
 	}
 
 	// 检查游戏状态
 	if game.Status != 1 {
 		logger.Error(fmt.Sprintf("PlayerAction: game %d not in progress (status: %d)", gameID, game.Status))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -6,
-			Msg:    "game not in progress",
-		}, nil
+		return gs.responses.Error(ctx, req.Header, errcode.GameNotInProgress), nil
 	}
 
 	// 检查是否轮到该玩家
 	if game.CurrentPlayer != userID {
 		logger.Error(fmt.Sprintf("PlayerAction: not player %d's turn in game %d (current: %d)", userID, gameID, game.CurrentPlayer))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -7,
-			Msg:    "not your turn",
-		}, nil
+		return gs.responses.Error(ctx, req.Header, errcode.NotYourTurn), nil
 	}
 
+	// 按操作表校验载荷，不合法的字段在错误响应的Data中列出
+	actionName, known := gameActionNames[actionType]
+	if !known {
+		logger.Error(fmt.Sprintf("PlayerAction: unknown action type %d", actionType))
+		return gs.responses.Error(ctx, req.Header, errcode.UnknownAction), nil
+	}
+	decoded, err := gameActionSchemas.Decode(actionName, actionData)
+	if err != nil {
+		logger.Debug(fmt.Sprintf("PlayerAction: user %d sent invalid action data: %v", userID, err))
+		resp := gs.responses.Error(ctx, req.Header, errcode.InvalidActionData.WithDetail(err.Error()))
+		resp.Data = actionValidationData(err)
+		return resp, nil
+	}
+	payload, _ := decoded.(map[string]interface{})
+
 	// 处理不同类型的操作
+	previousPlayer := game.CurrentPlayer
 	var actionResult map[string]interface{}
-	var err error
 
 	switch actionType {
 	case 1: // 出牌
-		actionResult, err = gs.handlePlayCard(game, player, actionData)
+		actionResult, err = gs.handlePlayCard(game, player, payload)
 	case 2: // 使用技能
-		actionResult, err = gs.handleUseSkill(game, player, actionData)
+		actionResult, err = gs.handleUseSkill(game, player, payload)
 	case 3: // 结束回合
 		actionResult, err = gs.handleEndTurn(game, player)
 	case 4: // 投降
 		actionResult, err = gs.handleSurrender(game, player)
 	default:
 		logger.Error(fmt.Sprintf("PlayerAction: unknown action type %d", actionType))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -8,
-			Msg:    "unknown action type",
-		}, nil
+		return gs.responses.Error(ctx, req.Header, errcode.UnknownAction), nil
 	}
 
 	if err != nil {
 		logger.Error(fmt.Sprintf("PlayerAction: failed to process action: %v", err))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -9,
-			Msg:    fmt.Sprintf("action failed: %v", err),
-		}, nil
+		return gs.responses.Error(ctx, req.Header, errcode.ActionFailed.WithDetail(err.Error())), nil
+	}
+
+	recordGameAction(game, userID, actionType, actionData)
+
+	// 异步对局保存后才生效，投降结束对局时结算
+	if game.Async {
+		if resp := gs.saveAsyncGame(ctx, req.Header, game, previousPlayer); resp != nil {
+			return resp, nil
+		}
+		if game.Status == 2 {
+			gs.server.recordGameEnded(game, userID)
+		}
 	}
 
 	logger.Info(fmt.Sprintf("Player %d performed action %d in game %d", userID, actionType, gameID))
@@ -538,19 +648,10 @@ func (gs *GameService) PlayerAction(ctx context.Context, req *proto.BaseRequest)
 	responseBytes, err := json.Marshal(responseData)
 	if err != nil {
 		logger.Error(fmt.Sprintf("PlayerAction: failed to marshal response: %v", err))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -10,
-			Msg:    "failed to create response",
-		}, nil
-	}
-
-	return &proto.BaseResponse{
-		Header: req.Header,
-		Code:   0,
-		Msg:    "action processed successfully",
-		Data:   responseBytes,
-	}, nil
+		return gs.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+
+	return gs.responses.Success(ctx, req.Header, "action processed successfully", responseBytes), nil
 }
 
 // GetGameState 获取游戏状态
@@ -559,22 +660,14 @@ func (gs *GameService) GetGameState(ctx context.Context, req *proto.BaseRequest)
 	userID := req.Header.GetUserId()
 	if userID == 0 {
 		logger.Error("GetGameState: invalid user id")
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -1,
-			Msg:    "invalid user id",
-		}, nil
+		return gs.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
 	}
 
 	// 解析请求数据
 	var stateReq proto.GameStateRequest
 	if err := proto.Unmarshal(req.Data, &stateReq); err != nil {
 		logger.Error(fmt.Sprintf("GetGameState: failed to unmarshal request: %v", err))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -2,
-			Msg:    "invalid request data",
-		}, nil
+		return gs.responses.Error(ctx, req.Header, errcode.InvalidRequest), nil
 	}
 
 	gameID := stateReq.GetGameId()
@@ -582,37 +675,28 @@ func (gs *GameService) GetGameState(ctx context.Context, req *proto.BaseRequest)
 	// 验证游戏ID
 	if gameID == 0 {
 		logger.Error("GetGameState: invalid game id")
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -3,
-			Msg:    "invalid game id",
-		}, nil
+		return gs.responses.Error(ctx, req.Header, errcode.InvalidGameID), nil
 	}
 
 	// 获取游戏实例
-	game, exists := gs.server.getGame(gameID)
+	game, exists := gs.server.findGame(gameID)
 	if !exists {
 		logger.Error(fmt.Sprintf("GetGameState: game %d not found", gameID))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -4,
-			Msg:    "game not found",
-		}, nil
+		return gs.responses.Error(ctx, req.Header, gs.server.missingGame(gameID)), nil
 	}
 
-	// 检查用户是否在游戏中
+	// 检查用户是否在游戏中，观战者也可以获取游戏状态
 	game.mutex.RLock()
-	defer game.mutex.RUnlock()
-
-	if _, exists := game.Players[userID]; !exists {
+	_, isPlayer := game.Players[userID]
+	game.mutex.RUnlock()
+	if !isPlayer && !gs.server.isSpectator(game, userID) {
 		logger.Error(fmt.Sprintf("GetGameState: user %d not in game %d", userID, gameID))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -5,
-			Msg:    "user not in game",
-		}, nil
+		return gs.responses.Error(ctx, req.Header, errcode.NotInGame), nil
 	}
 
+	game.mutex.RLock()
+	defer game.mutex.RUnlock()
+
 	// 构造玩家信息列表
 	var players []*proto.GamePlayerInfo
 	for _, player := range game.Players {
@@ -626,11 +710,21 @@ func (gs *GameService) GetGameState(ctx context.Context, req *proto.BaseRequest)
 		players = append(players, playerInfo)
 	}
 
-	// 序列化游戏数据
-	gameDataBytes, err := json.Marshal(game.GameData)
+	// 客户端提供已知版本时优先返回增量，版本差过大时返回完整快照
+	var gameDataBytes []byte
+	var version uint64
+	var delta bool
+	var err error
+	if knownVersion := stateReq.GetKnownVersion(); knownVersion > 0 {
+		gameDataBytes, version, delta, err = game.GameData.EncodedDelta(knownVersion)
+	}
+	if err == nil && !delta {
+		// 游戏数据仅在状态变化后重新编码
+		gameDataBytes, version, err = game.GameData.Encoded()
+	}
 	if err != nil {
-		logger.Error(fmt.Sprintf("GetGameState: failed to marshal game data: %v", err))
-		gameDataBytes = []byte("{}")
+		logger.Error(fmt.Sprintf("GetGameState: %v", err))
+		return gs.responses.Error(ctx, req.Header, errcode.Internal), nil
 	}
 
 	// 构造游戏状态响应
@@ -640,36 +734,27 @@ func (gs *GameService) GetGameState(ctx context.Context, req *proto.BaseRequest)
 		CurrentPlayer: game.CurrentPlayer,
 		Players:       players,
 		GameData:      gameDataBytes,
+		GameDataType:  game.GameData.TypeName(),
+		Version:       version,
+		Delta:         delta,
+	}
+	if game.Async {
+		gameStateResp.TurnDeadline = game.TurnDeadline.Unix()
 	}
 
 	responseData, err := proto.Marshal(gameStateResp)
 	if err != nil {
 		logger.Error(fmt.Sprintf("GetGameState: failed to marshal response: %v", err))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -6,
-			Msg:    "failed to create response",
-		}, nil
+		return gs.responses.Error(ctx, req.Header, errcode.Internal), nil
 	}
 
 	logger.Debug(fmt.Sprintf("User %d retrieved game state for game %d", userID, gameID))
 
-	return &proto.BaseResponse{
-		Header: req.Header,
-		Code:   0,
-		Msg:    "success",
-		Data:   responseData,
-	}, nil
+	return gs.responses.Success(ctx, req.Header, "success", responseData), nil
 }
 
 // handlePlayCard 处理出牌操作
-func (gs *GameService) handlePlayCard(game *GameInstance, player *GamePlayerData, actionData []byte) (map[string]interface{}, error) {
-	// 简化实现：解析卡牌数据并处理
-	var cardData map[string]interface{}
-	if err := json.Unmarshal(actionData, &cardData); err != nil {
-		return nil, fmt.Errorf("invalid card data: %v", err)
-	}
-
+func (gs *GameService) handlePlayCard(game *GameInstance, player *GamePlayerData, cardData map[string]interface{}) (map[string]interface{}, error) {
 	// 这里应该实现具体的卡牌逻辑
 	// 简化处理：增加玩家分数
 	player.Score += 10
@@ -685,13 +770,7 @@ func (gs *GameService) handlePlayCard(game *GameInstance, player *GamePlayerData
 }
 
 // handleUseSkill 处理使用技能操作
-func (gs *GameService) handleUseSkill(game *GameInstance, player *GamePlayerData, actionData []byte) (map[string]interface{}, error) {
-	// 简化实现：解析技能数据并处理
-	var skillData map[string]interface{}
-	if err := json.Unmarshal(actionData, &skillData); err != nil {
-		return nil, fmt.Errorf("invalid skill data: %v", err)
-	}
-
+func (gs *GameService) handleUseSkill(game *GameInstance, player *GamePlayerData, skillData map[string]interface{}) (map[string]interface{}, error) {
 	// 这里应该实现具体的技能逻辑
 	// 简化处理：增加玩家分数
 	player.Score += 20
@@ -733,6 +812,8 @@ func (gs *GameService) handleSurrender(game *GameInstance, player *GamePlayerDat
 		game.Status = 2 // 游戏结束
 		game.Winner = lastActivePlayer
 		game.EndTime = time.Now()
+	} else if game.CurrentPlayer == player.UserID {
+		gs.switchToNextPlayer(game)
 	}
 
 	return map[string]interface{}{
@@ -742,9 +823,8 @@ func (gs *GameService) handleSurrender(game *GameInstance, player *GamePlayerDat
 	}, nil
 }
 
-// switchToNextPlayer 切换到下一个玩家
+// switchToNextPlayer 切换到下一个玩家，活跃玩家按ID顺序轮换，当前玩家已离开时轮到ID顺序上的下一名玩家
 func (gs *GameService) switchToNextPlayer(game *GameInstance) {
-	// 简化实现：在活跃玩家中轮换
 	var playerIDs []uint64
 	for _, player := range game.Players {
 		if player.Status != 3 { // 不是已离开状态
@@ -756,18 +836,14 @@ func (gs *GameService) switchToNextPlayer(game *GameInstance) {
 		return // 只有一个或没有活跃玩家
 	}
 
-	// 找到当前玩家的索引
-	currentIndex := -1
-	for i, playerID := range playerIDs {
-		if playerID == game.CurrentPlayer {
-			currentIndex = i
-			break
-		}
-	}
-
 	// 切换到下一个玩家
-	if currentIndex >= 0 {
-		nextIndex := (currentIndex + 1) % len(playerIDs)
-		game.CurrentPlayer = playerIDs[nextIndex]
+	sort.Slice(playerIDs, func(i, j int) bool { return playerIDs[i] < playerIDs[j] })
+	nextIndex := sort.Search(len(playerIDs), func(i int) bool { return playerIDs[i] > game.CurrentPlayer })
+	if nextIndex == len(playerIDs) {
+		nextIndex = 0
+	}
+	game.CurrentPlayer = playerIDs[nextIndex]
+	if nextIndex == 0 {
+		advanceGameRound(game)
 	}
 }
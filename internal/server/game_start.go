@@ -0,0 +1,149 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/realtime"
+	"github.com/phuhao00/lufy/internal/saga"
+)
+
+// sagaStartGame 开始游戏的流程类型
+const sagaStartGame = "start_game"
+
+// startGameData 开始游戏流程的参数
+type startGameData struct {
+	GameID   uint64 `json:"game_id"`
+	RoomID   uint64 `json:"room_id"`
+	GameType int32  `json:"game_type"`
+	NodeID   string `json:"node_id"`
+	UserID   uint64 `json:"user_id"`
+	Nickname string `json:"nickname"`
+	Level    int32  `json:"level"`
+}
+
+// initStartGameSaga 注册开始游戏流程并定期恢复中断的流程
+// 房间进入游戏中、创建游戏记录、创建游戏实例依次执行，任一步失败或节点中途故障时逆序撤销，
+// 房间回到等待中，游戏记录标记为异常结束，不会停留在游戏中却没有游戏实例的状态
+func (gs *GameServer) initStartGameSaga() error {
+	err := gs.sagas.Register(&saga.Definition{
+		Type: sagaStartGame,
+		Steps: []saga.Step{
+			{Name: "room", Action: gs.startRoomGame, Compensate: gs.resetRoomGame},
+			{Name: "record", Action: gs.createGameRecord, Compensate: gs.abortGameRecord},
+			{Name: "instance", Action: gs.createGameInstance, Compensate: gs.removeGameInstance},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return gs.scheduler.Register("recover_sagas", "@every 1m", gs.sagas.Recover)
+}
+
+// startRoomGame 记录房间的游戏和所在节点，观战者据此找到游戏
+func (gs *GameServer) startRoomGame(ctx context.Context, s *database.Saga) error {
+	var data startGameData
+	if err := saga.Bind(s, &data); err != nil {
+		return err
+	}
+	if err := gs.roomRepo.SetRoomGame(data.RoomID, data.GameID, data.NodeID); err != nil {
+		return err
+	}
+	gs.realtime.Adjust(realtime.Delta{GameType: data.GameType, Games: 1})
+	return nil
+}
+
+// resetRoomGame 房间回到等待中
+func (gs *GameServer) resetRoomGame(ctx context.Context, s *database.Saga) error {
+	var data startGameData
+	if err := saga.Bind(s, &data); err != nil {
+		return err
+	}
+	if err := gs.roomRepo.ResetRoomGame(data.RoomID, data.GameID); err != nil {
+		return err
+	}
+	gs.realtime.Adjust(realtime.Delta{GameType: data.GameType, Games: -1})
+	return nil
+}
+
+// createGameRecord 创建进行中的游戏记录
+func (gs *GameServer) createGameRecord(ctx context.Context, s *database.Saga) error {
+	var data startGameData
+	if err := saga.Bind(s, &data); err != nil {
+		return err
+	}
+	return gs.gameRecordRepo.CreateRecord(&database.GameRecord{
+		GameID:   data.GameID,
+		RoomID:   data.RoomID,
+		GameType: data.GameType,
+		Players: []database.GamePlayer{
+			{
+				UserID:   data.UserID,
+				Nickname: data.Nickname,
+				Level:    data.Level,
+			},
+		},
+		Status: 0, // 进行中
+	})
+}
+
+// abortGameRecord 游戏记录标记为异常结束
+func (gs *GameServer) abortGameRecord(ctx context.Context, s *database.Saga) error {
+	var data startGameData
+	if err := saga.Bind(s, &data); err != nil {
+		return err
+	}
+	return gs.gameRecordRepo.AbortRecord(data.GameID)
+}
+
+// createGameInstance 在本节点创建游戏实例，创建者作为第一个玩家
+func (gs *GameServer) createGameInstance(ctx context.Context, s *database.Saga) error {
+	var data startGameData
+	if err := saga.Bind(s, &data); err != nil {
+		return err
+	}
+
+	game := &GameInstance{
+		GameID:        data.GameID,
+		RoomID:        data.RoomID,
+		GameType:      data.GameType,
+		Status:        0, // 等待开始
+		Players:       make(map[uint64]*GamePlayerData),
+		CurrentPlayer: data.UserID,
+		StartTime:     time.Now(),
+		GameData:      NewGameData(data.GameType),
+	}
+	game.Players[data.UserID] = &GamePlayerData{
+		UserID:   data.UserID,
+		Nickname: data.Nickname,
+		Level:    data.Level,
+		Status:   1, // 准备状态
+		Data:     make(map[string]interface{}),
+	}
+	gs.addGame(game)
+	return nil
+}
+
+// removeGameInstance 移除游戏实例，实例在其他节点(节点故障后由本节点恢复)时已随节点消失
+func (gs *GameServer) removeGameInstance(ctx context.Context, s *database.Saga) error {
+	var data startGameData
+	if err := saga.Bind(s, &data); err != nil {
+		return err
+	}
+	if data.NodeID != gs.nodeID {
+		return nil
+	}
+	gs.removeGame(data.GameID)
+	return nil
+}
+
+// startGame 执行开始游戏流程，失败时已完成的步骤已撤销
+func (gs *GameServer) startGame(ctx context.Context, data *startGameData) error {
+	if err := gs.sagas.Execute(ctx, sagaStartGame, data); err != nil {
+		return fmt.Errorf("failed to start game %d in room %d: %v", data.GameID, data.RoomID, err)
+	}
+	return nil
+}
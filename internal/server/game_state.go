@@ -0,0 +1,198 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// maxRecentActions 游戏状态中保留的最近操作数，也是增量同步能覆盖的最大版本差
+const maxRecentActions = 20
+
+// gameStateFactories 游戏类型对应的状态消息，每种游戏类型定义一个protobuf消息，未注册的类型使用卡牌对战状态
+var gameStateFactories = map[int32]func() proto.Message{}
+
+// newGameState 创建游戏类型对应的状态消息
+func newGameState(gameType int32) proto.Message {
+	if factory, exists := gameStateFactories[gameType]; exists {
+		return factory()
+	}
+	return &proto.CardBattleState{}
+}
+
+// GameData 类型化的游戏状态，编码结果缓存到状态变化为止
+type GameData struct {
+	state    proto.Message
+	typeName string
+	encoded  []byte
+	dirty    bool
+
+	// 最近一次增量编码的缓存，同一局的客户端通常落后相同的版本
+	deltaFrom    uint64
+	deltaEncoded []byte
+
+	mutex sync.Mutex
+}
+
+// NewGameData 创建游戏状态
+func NewGameData(gameType int32) *GameData {
+	state := newGameState(gameType)
+	return &GameData{
+		state:    state,
+		typeName: strings.TrimPrefix(fmt.Sprintf("%T", state), "*proto."),
+		dirty:    true,
+	}
+}
+
+// RestoreGameData 从保存的编码状态恢复游戏状态
+func RestoreGameData(gameType int32, data []byte) (*GameData, error) {
+	gameData := NewGameData(gameType)
+	if err := proto.Unmarshal(data, gameData.state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %v", gameData.typeName, err)
+	}
+	return gameData, nil
+}
+
+// Update 修改游戏状态，修改后缓存的编码失效
+func (gd *GameData) Update(fn func(state proto.Message)) {
+	gd.mutex.Lock()
+	defer gd.mutex.Unlock()
+
+	fn(gd.state)
+	gd.dirty = true
+	gd.deltaEncoded = nil
+}
+
+// Encoded 获取编码后的完整状态及其版本，状态未变化时直接返回缓存，调用方不能修改返回值
+func (gd *GameData) Encoded() ([]byte, uint64, error) {
+	gd.mutex.Lock()
+	defer gd.mutex.Unlock()
+
+	version := gameStateVersion(gd.state)
+	if !gd.dirty {
+		return gd.encoded, version, nil
+	}
+
+	data, err := proto.Marshal(gd.state)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to marshal %s: %v", gd.typeName, err)
+	}
+	gd.encoded = data
+	gd.dirty = false
+	return data, version, nil
+}
+
+// EncodedDelta 获取knownVersion之后的增量状态及当前版本，
+// 版本差超过保留的变更或状态类型不支持增量时返回false，调用方应改用完整状态
+func (gd *GameData) EncodedDelta(knownVersion uint64) ([]byte, uint64, bool, error) {
+	gd.mutex.Lock()
+	defer gd.mutex.Unlock()
+
+	version := gameStateVersion(gd.state)
+	if gd.deltaEncoded != nil && gd.deltaFrom == knownVersion {
+		return gd.deltaEncoded, version, true, nil
+	}
+
+	delta, ok := gameStateDelta(gd.state, knownVersion)
+	if !ok {
+		return nil, version, false, nil
+	}
+
+	data, err := proto.Marshal(delta)
+	if err != nil {
+		return nil, 0, false, fmt.Errorf("failed to marshal %s delta: %v", gd.typeName, err)
+	}
+	gd.deltaFrom = knownVersion
+	gd.deltaEncoded = data
+	return data, version, true, nil
+}
+
+// TypeName 获取状态消息类型名，客户端据此解码
+func (gd *GameData) TypeName() string {
+	return gd.typeName
+}
+
+// recordGameAction 在游戏状态中记录玩家操作
+func recordGameAction(game *GameInstance, userID uint64, actionType int32, actionData []byte) {
+	game.GameData.Update(func(state proto.Message) {
+		switch s := state.(type) {
+		case *proto.CardBattleState:
+			s.Turn++
+			s.RecentActions = append(s.RecentActions, &proto.GameAction{
+				UserId:     userID,
+				ActionType: actionType,
+				ActionData: actionData,
+				Timestamp:  uint32(time.Now().Unix()),
+			})
+			if len(s.RecentActions) > maxRecentActions {
+				s.RecentActions = s.RecentActions[len(s.RecentActions)-maxRecentActions:]
+			}
+		}
+	})
+}
+
+// advanceGameRound 所有玩家行动一轮后推进回合
+func advanceGameRound(game *GameInstance) {
+	game.GameData.Update(func(state proto.Message) {
+		switch s := state.(type) {
+		case *proto.CardBattleState:
+			s.Round++
+		}
+	})
+}
+
+// gameStateVersion 获取状态版本，每次玩家操作后递增
+func gameStateVersion(state proto.Message) uint64 {
+	switch s := state.(type) {
+	case *proto.CardBattleState:
+		return uint64(s.Turn)
+	}
+	return 0
+}
+
+// gameStateDelta 构造knownVersion之后的增量状态，标量字段为当前值，操作列表只包含新增的操作
+func gameStateDelta(state proto.Message, knownVersion uint64) (proto.Message, bool) {
+	switch s := state.(type) {
+	case *proto.CardBattleState:
+		version := uint64(s.Turn)
+		if knownVersion > version || version-knownVersion > uint64(len(s.RecentActions)) {
+			return nil, false
+		}
+		gap := int(version - knownVersion)
+		return &proto.CardBattleState{
+			Round:         s.Round,
+			Turn:          s.Turn,
+			RecentActions: s.RecentActions[len(s.RecentActions)-gap:],
+		}, true
+	}
+	return nil, false
+}
+
+// warmGameStates 预先编解码各游戏类型的状态消息，protobuf在第一次编解码时才初始化消息的反射信息，
+// 避免新节点的第一局游戏承担这部分开销
+func warmGameStates(ctx context.Context) error {
+	gameTypes := []int32{0} // 未注册的类型使用的默认状态
+	for gameType := range gameStateFactories {
+		gameTypes = append(gameTypes, gameType)
+	}
+
+	for _, gameType := range gameTypes {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		gameData := NewGameData(gameType)
+		advanceGameRound(&GameInstance{GameData: gameData})
+		encoded, _, err := gameData.Encoded()
+		if err != nil {
+			return err
+		}
+		if _, err := RestoreGameData(gameType, encoded); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -3,13 +3,23 @@ package server
 import (
 	"context"
 	"fmt"
-	"reflect"
+	"net"
+	"strconv"
 	"time"
 
 	"github.com/phuhao00/lufy/internal/actor"
+	"github.com/phuhao00/lufy/internal/announcement"
 	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/discovery"
+	"github.com/phuhao00/lufy/internal/errcode"
+	"github.com/phuhao00/lufy/internal/geo"
+	"github.com/phuhao00/lufy/internal/i18n"
 	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/mq"
 	"github.com/phuhao00/lufy/internal/network"
+	"github.com/phuhao00/lufy/internal/news"
+	"github.com/phuhao00/lufy/internal/security"
+	"github.com/phuhao00/lufy/internal/settings"
 	"github.com/phuhao00/lufy/pkg/proto"
 )
 
@@ -17,6 +27,9 @@ import (
 type GatewayServer struct {
 	*BaseServer
 	messageHandler *GatewayMessageHandler
+	kcpServer      *network.KCPServer
+	announcements  *announcement.Service
+	settings       *settings.Service
 }
 
 // NewGatewayServer 创建网关服务器
@@ -29,6 +42,8 @@ func NewGatewayServer(configFile, nodeID string) *GatewayServer {
 	gatewayServer := &GatewayServer{
 		BaseServer:     baseServer,
 		messageHandler: NewGatewayMessageHandler(baseServer),
+		announcements:  baseServer.newAnnouncements(),
+		settings:       baseServer.newSettings(),
 	}
 
 	// 初始化TCP服务器
@@ -38,16 +53,76 @@ func NewGatewayServer(configFile, nodeID string) *GatewayServer {
 		gatewayServer.messageHandler,
 		baseServer.config.Network.MaxConnections,
 	)
+	tcpServer.SetPanicHandler(func(conn *network.Connection, value interface{}, stack []byte) {
+		baseServer.crashReporter.Capture("tcp", value, stack, map[string]string{
+			"conn_id": fmt.Sprintf("%d", conn.ID),
+			"user_id": fmt.Sprintf("%d", conn.UserID),
+		})
+	})
 	gatewayServer.tcpServer = tcpServer
 
+	// 初始化KCP服务器，实时玩法的流量可以走可靠UDP
+	if kcpConfig := baseServer.config.Network.KCP; kcpConfig.Enabled {
+		kcpServer := network.NewKCPServer("0.0.0.0", kcpConfig, gatewayServer.messageHandler)
+		kcpServer.SetPanicHandler(func(conn *network.Connection, value interface{}, stack []byte) {
+			baseServer.crashReporter.Capture("kcp", value, stack, map[string]string{
+				"conn_id": fmt.Sprintf("%d", conn.ID),
+				"user_id": fmt.Sprintf("%d", conn.UserID),
+			})
+		})
+		gatewayServer.kcpServer = kcpServer
+		gatewayServer.messageHandler.kcpServer = kcpServer
+	}
+
+	// 登录时和玩家在线时投递离线通知
+	gatewayServer.messageHandler.notifier = NewNotifier(baseServer)
+	gatewayServer.messageHandler.news = baseServer.newNews()
+
 	// 注册通用服务
 	if err := RegisterCommonServices(baseServer); err != nil {
 		logger.Fatal(fmt.Sprintf("Failed to register common services: %v", err))
 	}
+	baseServer.systemHandler.RegisterHandler(mq.SYS_CMD_DELIVER_NOTIFICATIONS, gatewayServer.handleDeliverNotifications)
+	baseServer.systemHandler.RegisterHandler(mq.SYS_CMD_ACTIVITY, gatewayServer.handleActivity) // 替换通用处理，推送给在线玩家
+	baseServer.systemHandler.RegisterHandler(mq.SYS_CMD_KICK_USER, gatewayServer.handleKickUser)
+	baseServer.systemHandler.RegisterHandler(mq.SYS_CMD_GAME_MIGRATED, gatewayServer.handleGameMigrated)
+	baseServer.systemHandler.RegisterHandler(mq.SYS_CMD_BROADCAST_NOTICE, gatewayServer.handleBroadcastNotice) // 替换通用处理，推送给在线玩家
+	baseServer.systemHandler.RegisterHandler(mq.SYS_CMD_NEWS_CHANGED, gatewayServer.handleNewsChanged)
+
+	// 世界和公会聊天由网关直接推送给在线玩家
+	baseServer.RegisterOnStart("chat_messages", OrderSubscriptions, func(ctx context.Context) error {
+		return baseServer.messageBroker.SubscribeChatMessages(mq.NewChatMessageHandler(gatewayServer.handleChatMessage))
+	})
+	baseServer.RegisterOnStop("chat_messages", OrderSubscriptions, func(ctx context.Context) error {
+		return baseServer.messageBroker.UnsubscribeChatMessages()
+	})
+
+	// 定期上报本节点的在线人数，修正未登出就断开的连接造成的偏差
+	baseServer.RegisterBackground("realtime_online", gatewayServer.reportOnlineLoop)
+
+	// 客户端连接的监听
+	baseServer.RegisterOnStart("tcp", OrderListeners, func(ctx context.Context) error {
+		if err := tcpServer.Start(); err != nil {
+			return err
+		}
+		logger.Info(fmt.Sprintf("Gateway server %s started on TCP port %d", nodeID, baseServer.config.Network.TCPPort))
+		return nil
+	})
+	baseServer.RegisterOnStop("tcp", OrderListeners, func(ctx context.Context) error {
+		return tcpServer.Stop()
+	})
+	if kcpServer := gatewayServer.kcpServer; kcpServer != nil {
+		baseServer.RegisterOnStart("kcp", OrderListeners, func(ctx context.Context) error {
+			return kcpServer.Start()
+		})
+		baseServer.RegisterOnStop("kcp", OrderListeners, func(ctx context.Context) error {
+			return kcpServer.Stop()
+		})
+	}
 
 	// 注册网关服务
 	gatewayService := NewGatewayService(gatewayServer)
-	if err := baseServer.rpcServer.RegisterService(gatewayService); err != nil {
+	if err := RegisterGatewayService(baseServer.rpcServer, gatewayService); err != nil {
 		logger.Fatal(fmt.Sprintf("Failed to register gateway service: %v", err))
 	}
 
@@ -60,67 +135,318 @@ func NewGatewayServer(configFile, nodeID string) *GatewayServer {
 	return gatewayServer
 }
 
-// Start 启动网关服务器
-func (gs *GatewayServer) Start() error {
-	// 启动基础服务器
-	if err := gs.BaseServer.Start(); err != nil {
-		return err
+// SendRealtime 发送实时消息，优先使用玩家的KCP会话，会话不可用时回退到TCP连接
+func (gs *GatewayServer) SendRealtime(userID uint64, data []byte) error {
+	if gs.kcpServer != nil {
+		if err := gs.kcpServer.SendToUser(userID, data); err == nil {
+			return nil
+		}
+	}
+	return gs.tcpServer.SendToUser(userID, data)
+}
+
+// handleDeliverNotifications 通知在线玩家有新的通知，玩家已不在本节点时保留到下次登录
+func (gs *GatewayServer) handleDeliverNotifications(msg *mq.SystemMessage) error {
+	userIDText, _ := msg.Args["user_id"].(string)
+	userID, err := strconv.ParseUint(userIDText, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid user id: %q", userIDText)
+	}
+
+	conn, ok := gs.tcpServer.GetConnectionByUserID(userID)
+	if !ok {
+		return nil
+	}
+	return gs.messageHandler.notifier.Deliver(conn)
+}
+
+// 聊天推送的消息ID
+const (
+	chatPushMsgID       = 2004 // 新消息
+	chatUpdatePushMsgID = 2005 // 消息被编辑、撤回或回应人数变化，客户端按消息ID更新已显示的消息
+)
+
+// gatewayChatChannels 网关推送的聊天频道及对应的通知频道，私聊和房间聊天由聊天服务器处理
+var gatewayChatChannels = map[int32]string{
+	mq.CHAT_CHANNEL_WORLD: settings.ChannelWorldChat,
+	mq.CHAT_CHANNEL_GUILD: settings.ChannelGuildChat,
+}
+
+// handleChatMessage 把世界聊天推送给本网关的所有在线玩家，公会聊天按成员分别发送，跳过关闭了该频道的玩家。
+// 消息的编辑、撤回和回应以同样的范围推送
+func (gs *GatewayServer) handleChatMessage(msg *mq.ChatMessage) error {
+	channel, ok := gatewayChatChannels[msg.Channel]
+	if !ok {
+		return nil
 	}
 
-	// 启动TCP服务器
-	if err := gs.tcpServer.Start(); err != nil {
-		return fmt.Errorf("failed to start tcp server: %v", err)
+	push := &proto.ChatMessage{
+		MessageId:   msg.MessageID,
+		FromUserId:  msg.FromUserID,
+		ToUserId:    msg.ToUserID,
+		ChannelType: msg.Channel,
+		ChannelId:   msg.ChannelID,
+		Content:     msg.Content,
+		SendTime:    uint32(msg.Timestamp),
+		EditTime:    uint32(msg.EditTime),
+		Recalled:    msg.Action == mq.CHAT_ACTION_RECALL,
+		Reactions:   reactionCounts(msg.Reactions),
+	}
+	msgID := uint32(chatPushMsgID)
+	if msg.Action != "" {
+		msgID = chatUpdatePushMsgID
+	}
+	deliver := func(conn *network.Connection) bool {
+		muted, err := gs.settings.Muted(conn.UserID, channel)
+		if err != nil {
+			logger.Warn(fmt.Sprintf("Failed to get notification preferences of user %d: %v", conn.UserID, err))
+		}
+		if muted {
+			return false
+		}
+		if err := pushMessage(conn, msgID, push); err != nil {
+			logger.Debug(fmt.Sprintf("Failed to push chat message to user %d: %v", conn.UserID, err))
+			return false
+		}
+		return true
 	}
 
-	logger.Info(fmt.Sprintf("Gateway server %s started on TCP port %d",
-		gs.nodeID, gs.config.Network.TCPPort))
+	if msg.ToUserID != 0 {
+		if conn, ok := gs.tcpServer.GetConnectionByUserID(msg.ToUserID); ok {
+			deliver(conn)
+		}
+		return nil
+	}
+	if msg.Channel != mq.CHAT_CHANNEL_WORLD {
+		return nil
+	}
 
+	gs.tcpServer.ForEachConnection(func(conn *network.Connection) bool {
+		if conn.UserID != 0 {
+			deliver(conn)
+		}
+		return true
+	})
 	return nil
 }
 
-// Stop 停止网关服务器
-func (gs *GatewayServer) Stop() error {
-	if gs.tcpServer != nil {
-		gs.tcpServer.Stop()
+// handleKickUser 断开玩家在本网关的连接
+func (gs *GatewayServer) handleKickUser(msg *mq.SystemMessage) error {
+	command, err := mq.Command[proto.KickUserCommand](msg)
+	if err != nil {
+		return err
 	}
 
-	return gs.BaseServer.Stop()
+	conn, ok := gs.tcpServer.GetConnectionByUserID(command.UserId)
+	if !ok {
+		return nil
+	}
+	logger.Info(fmt.Sprintf("Kicking user %d: %s", command.UserId, command.Reason))
+	return conn.Close()
 }
 
 // GatewayMessageHandler 网关消息处理器
 type GatewayMessageHandler struct {
-	server *BaseServer
+	server     *BaseServer
+	dedup      *network.DedupCache
+	protocols  *network.ProtocolRegistry
+	i18n       *i18n.I18nManager
+	security   *security.SecurityManager
+	integrity  *network.FrameIntegrity
+	geoIP      *geo.GeoIPResolver
+	kcpServer  *network.KCPServer // 未启用KCP时为nil
+	notifier   *Notifier
+	news       *news.Service
+	gameRoutes *gameRoutes
 }
 
 // NewGatewayMessageHandler 创建网关消息处理器
 func NewGatewayMessageHandler(server *BaseServer) *GatewayMessageHandler {
+	i18nManager := i18n.NewI18nManager("en")
+	if err := i18nManager.LoadLanguage("zh-CN"); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to load Chinese language: %v", err))
+	}
+
+	securityManager, err := security.NewSecurityManager()
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to create security manager: %v", err))
+	}
+	securityManager.SetBlacklist(server.ipBlacklist)
+
+	integrity, err := network.NewFrameIntegrity(
+		server.config.Security.FrameIntegrity,
+		server.config.Security.ReplayProtection,
+		securityManager.GenerateSignatureWithKey,
+		securityManager.VerifySignatureWithKey,
+	)
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to create frame integrity checker: %v", err))
+	}
+
+	geoIP, err := geo.NewGeoIPResolver(&server.config.Geo)
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to create geoip resolver: %v", err))
+	}
+
 	return &GatewayMessageHandler{
-		server: server,
+		server:     server,
+		dedup:      network.NewDedupCache(30 * time.Second),
+		protocols:  newProtocolRegistry(),
+		i18n:       i18nManager,
+		security:   securityManager,
+		integrity:  integrity,
+		gameRoutes: newGameRoutes(database.NewRoomRepository(server.mongoManager)),
+		geoIP:      geoIP,
 	}
 }
 
+// newProtocolRegistry 创建网关的协议版本注册表，注册旧版本消息的兼容规则
+func newProtocolRegistry() *network.ProtocolRegistry {
+	protocols := network.NewProtocolRegistry(network.ProtocolVersionMin, network.ProtocolVersionCurrent)
+	protocols.RegisterCompat(1, &network.CompatRule{OldMsgID: 1001, NewMsgID: 1001, Adapter: adaptV1Login})
+	return protocols
+}
+
+// adaptV1Login 版本1的登录请求只有账号、密码、设备和客户端版本，
+// 之后新增的世界选择、推送登记、游客和第三方登录字段按未填写处理，不解释旧客户端在这些字段号上的数据
+func adaptV1Login(codec network.Codec, data []byte) ([]byte, error) {
+	var req proto.LoginRequest
+	if err := codec.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+	return codec.Marshal(&proto.LoginRequest{
+		Username: req.Username,
+		Password: req.Password,
+		DeviceId: req.DeviceId,
+		Platform: req.Platform,
+		Version:  req.Version,
+	})
+}
+
+// RegisterCompat 注册旧版本协议的消息兼容规则
+func (gmh *GatewayMessageHandler) RegisterCompat(version uint32, rule *network.CompatRule) {
+	gmh.protocols.RegisterCompat(version, rule)
+}
+
 // HandleMessage 处理消息
 func (gmh *GatewayMessageHandler) HandleMessage(conn *network.Connection, data []byte) error {
-	// 解析消息头
-	if len(data) < 4 {
-		return fmt.Errorf("message too short")
+	// 故障注入，模拟客户端连接中断
+	if gmh.server.chaos.Disconnect() {
+		logger.Warn(fmt.Sprintf("Chaos disconnecting connection %d", conn.ID))
+		conn.Close()
+		return fmt.Errorf("connection %d closed by chaos injection", conn.ID)
 	}
 
-	// 解析消息ID
-	msgID := uint32(data[0])<<24 | uint32(data[1])<<16 | uint32(data[2])<<8 | uint32(data[3])
+	// 帧完整性校验，启用后必须先完成握手
+	if gmh.integrity.Enabled() {
+		if conn.ProtocolVersion == 0 {
+			if msgID, _, err := network.ParseFrame(data); err != nil || msgID != 1000 {
+				return fmt.Errorf("handshake required before messages on connection %d", conn.ID)
+			}
+		} else {
+			verified, err := gmh.integrity.Verify(conn.SessionKey, data)
+			if err != nil {
+				logger.Warn(fmt.Sprintf("Dropping frame from connection %d: %v", conn.ID, err))
+				return fmt.Errorf("frame integrity check failed: %v", err)
+			}
+			data = verified
+		}
+	}
+
+	// 解析消息ID和消息体
+	msgID, body, err := network.ParseFrame(data)
+	if err != nil {
+		return err
+	}
 
-	// 解析Protobuf消息
+	// 按连接协商的编解码器解析消息
 	var request proto.BaseRequest
-	if err := proto.Unmarshal(data[4:], &request); err != nil {
+	if err := conn.GetCodec().Unmarshal(body, &request); err != nil {
 		return fmt.Errorf("failed to unmarshal request: %v", err)
 	}
 
 	logger.Debug(fmt.Sprintf("Received message ID: %d from connection %d", msgID, conn.ID))
 
+	// 重放保护，握手消息同样检查
+	if err := gmh.integrity.CheckNonce(conn, request.Header.GetNonce()); err != nil {
+		logger.Warn(fmt.Sprintf("Dropping message %d from connection %d: %v", msgID, conn.ID, err))
+		return err
+	}
+
+	// 握手消息不做版本检查
+	if msgID == 1000 {
+		return gmh.handleHandshake(conn, &request)
+	}
+
+	// 协议版本检查，消息按连接协商的版本处理，消息头中的版本只用于校验
+	version := gmh.protocolVersion(conn)
+	if headerVersion := request.Header.GetProtocolVersion(); headerVersion != 0 && headerVersion != version {
+		logger.Warn(fmt.Sprintf("Dropping message %d from connection %d: protocol version %d does not match negotiated %d",
+			msgID, conn.ID, headerVersion, version))
+		return fmt.Errorf("protocol version %d does not match negotiated version %d", headerVersion, version)
+	}
+	if !gmh.protocols.IsSupported(version) {
+		logger.Warn(fmt.Sprintf("Rejecting message %d from connection %d: unsupported protocol version %d",
+			msgID, conn.ID, version))
+		return gmh.sendError(conn, &request, -3, gmh.localize(conn, "error.update_required"))
+	}
+
+	// 旧版本消息兼容转换
+	msgID, request.Data, err = gmh.protocols.Translate(version, msgID, conn.GetCodec(), request.Data)
+	if err != nil {
+		return gmh.sendError(conn, &request, -3, gmh.localize(conn, "error.update_required"))
+	}
+
+	// 客户端重试去重
+	clientMsgID := request.Header.GetClientMsgId()
+	if clientMsgID != "" {
+		sessionKey := gmh.sessionKey(conn)
+		if cached, duplicate := gmh.dedup.Begin(sessionKey, clientMsgID); duplicate {
+			if cached == nil {
+				// 原请求仍在处理中，丢弃重复提交
+				logger.Debug(fmt.Sprintf("Dropping in-flight duplicate message %s from connection %d", clientMsgID, conn.ID))
+				return nil
+			}
+			logger.Debug(fmt.Sprintf("Replaying cached response for message %s to connection %d", clientMsgID, conn.ID))
+			return conn.Write(cached)
+		}
+
+		// 未通过sendResponse响应的请求（处理失败或处理器未响应）放弃记录，允许客户端重试，已缓存的响应不受影响
+		err := gmh.routeMessage(conn, msgID, &request)
+		gmh.dedup.Abort(sessionKey, clientMsgID)
+		return err
+	}
+
 	// 路由消息到对应的处理器
 	return gmh.routeMessage(conn, msgID, &request)
 }
 
+// sessionKey 获取去重使用的会话键，登录后按玩家，登录前按连接，不使用客户端提交的消息头字段。
+// 登录请求处理前后会话键不同，其响应不缓存
+func (gmh *GatewayMessageHandler) sessionKey(conn *network.Connection) string {
+	if conn.UserID != 0 {
+		return fmt.Sprintf("user:%d", conn.UserID)
+	}
+	return fmt.Sprintf("conn:%d", conn.ID)
+}
+
+// protocolVersion 获取连接握手协商的协议版本，未握手的旧客户端按最低版本处理
+func (gmh *GatewayMessageHandler) protocolVersion(conn *network.Connection) uint32 {
+	if conn.ProtocolVersion != 0 {
+		return conn.ProtocolVersion
+	}
+	return gmh.protocols.MinVersion()
+}
+
+// localize 按连接语言翻译消息
+func (gmh *GatewayMessageHandler) localize(conn *network.Connection, messageID string) string {
+	langCode := conn.Language
+	if langCode == "" {
+		langCode = "en"
+	}
+	return gmh.i18n.Translate(langCode, messageID, nil)
+}
+
 // routeMessage 路由消息
 func (gmh *GatewayMessageHandler) routeMessage(conn *network.Connection, msgID uint32, request *proto.BaseRequest) error {
 	switch msgID {
@@ -130,45 +456,142 @@ func (gmh *GatewayMessageHandler) routeMessage(conn *network.Connection, msgID u
 		return gmh.handleHeartbeat(conn, request)
 	case 1003: // 用户登出
 		return gmh.handleLogout(conn, request)
+	case 1004: // 传输协商
+		return gmh.handleTransport(conn, request)
+	case 1005: // 资讯，由网关缓存直接响应
+		return gmh.handleNewsFeed(conn, request)
 	default:
 		// 转发到其他服务器
 		return gmh.forwardMessage(conn, msgID, request)
 	}
 }
 
+// handleHandshake 处理握手，协商协议版本。启用帧校验后连接只能握手一次，避免重新握手替换会话密钥
+func (gmh *GatewayMessageHandler) handleHandshake(conn *network.Connection, request *proto.BaseRequest) error {
+	if gmh.integrity.Enabled() && conn.ProtocolVersion != 0 {
+		return fmt.Errorf("repeated handshake on connection %d", conn.ID)
+	}
+
+	var handshakeReq proto.HandshakeRequest
+	if err := conn.GetCodec().Unmarshal(request.Data, &handshakeReq); err != nil {
+		return fmt.Errorf("failed to unmarshal handshake request: %v", err)
+	}
+
+	if handshakeReq.Language != "" {
+		conn.Language = gmh.i18n.DetectLanguage(handshakeReq.Language)
+	}
+
+	version, err := gmh.protocols.Negotiate(handshakeReq.ProtocolVersion)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Handshake rejected for connection %d (client %s): %v",
+			conn.ID, handshakeReq.ClientVersion, err))
+		return gmh.sendError(conn, request, -3, gmh.localize(conn, "error.update_required"))
+	}
+
+	// 协商编解码格式，不支持的格式回退到默认格式
+	codec := network.DefaultCodec()
+	if handshakeReq.Codec != "" {
+		if requested, exists := network.GetCodec(handshakeReq.Codec); exists {
+			codec = requested
+		} else {
+			logger.Warn(fmt.Sprintf("Connection %d requested unknown codec %s, using %s",
+				conn.ID, handshakeReq.Codec, codec.Name()))
+		}
+	}
+
+	handshakeResp := proto.HandshakeResponse{
+		ProtocolVersion:    version,
+		MinProtocolVersion: gmh.protocols.MinVersion(),
+		MaxProtocolVersion: gmh.protocols.CurrentVersion(),
+		Codec:              codec.Name(),
+		FrameIntegrity:     gmh.integrity.Mode(),
+	}
+
+	// 签名模式下按客户端公钥协商会话密钥，响应只携带服务端公钥
+	var sessionKey []byte
+	if gmh.integrity.RequiresSessionKey() {
+		kx, err := network.NewKeyExchange()
+		if err != nil {
+			return err
+		}
+		sessionKey, err = kx.SessionKey(handshakeReq.PublicKey)
+		if err != nil {
+			logger.Warn(fmt.Sprintf("Handshake rejected for connection %d: %v", conn.ID, err))
+			return gmh.sendError(conn, request, -4, gmh.localize(conn, "error.handshake_failed"))
+		}
+		handshakeResp.PublicKey = kx.PublicKey()
+	}
+
+	logger.Debug(fmt.Sprintf("Connection %d negotiated protocol version %d, codec %s", conn.ID, version, codec.Name()))
+
+	// 握手响应使用原编解码器发送，之后的消息切换到协商的格式
+	if err := gmh.sendResponse(conn, request, 0, "handshake success", &handshakeResp); err != nil {
+		return err
+	}
+	conn.ProtocolVersion = version
+	conn.Codec = codec
+	conn.SessionKey = sessionKey
+
+	return nil
+}
+
 // handleLogin 处理登录
 func (gmh *GatewayMessageHandler) handleLogin(conn *network.Connection, request *proto.BaseRequest) error {
 	// 解析登录请求
 	var loginReq proto.LoginRequest
-	if err := proto.Unmarshal(request.Data, &loginReq); err != nil {
+	if err := conn.GetCodec().Unmarshal(request.Data, &loginReq); err != nil {
 		return fmt.Errorf("failed to unmarshal login request: %v", err)
 	}
 
+	// 记录客户端地址和所在区域
+	loginReq.ClientIp = conn.Conn.RemoteAddr().String()
+	if host, _, err := net.SplitHostPort(loginReq.ClientIp); err == nil {
+		loginReq.ClientIp = host
+	}
+	conn.Region = gmh.geoIP.Lookup(loginReq.ClientIp)
+
+	// 登录节点识别出撞库的IP写入共享黑名单，在网关直接拒绝
+	if err := gmh.security.CheckIPSecurity(loginReq.ClientIp); err != nil {
+		return gmh.sendError(conn, request, -1, "login not allowed")
+	}
+
 	// 获取登录服务
-	loginService := gmh.server.discovery.GetService("login")
+	loginService := gmh.server.discovery.GetServiceInRegion("login", conn.Region)
 	if loginService == nil {
 		return gmh.sendError(conn, request, -1, "login service not available")
 	}
 
 	// TODO: 通过RPC调用登录服务
 	// 简化实现：直接返回成功响应
-	logger.Info(fmt.Sprintf("Login request for user: %s", loginReq.Username))
+	logger.Info(fmt.Sprintf("Login request for user: %s (region: %s)", loginReq.Username, conn.Region))
 
 	// 模拟登录成功响应
 	loginResp := proto.LoginResponse{
-		UserId: 12345,
-		Token:  "mock_token_" + loginReq.Username,
+		UserId:  12345,
+		Token:   "mock_token_" + loginReq.Username,
+		Region:  conn.Region,
+		WorldId: gmh.server.config.World.Resolve(loginReq.WorldId),
 	}
 
-	// 绑定连接到用户
+	// 绑定连接到用户和所属世界
 	conn.UserID = loginResp.UserId
+	conn.WorldID = loginResp.WorldId
 
 	// 设置用户在线状态
 	userCache := database.NewUserCache(gmh.server.redisManager)
 	userCache.SetUserOnline(loginResp.UserId, gmh.server.nodeID)
+	gmh.server.realtime.UserOnline(gmh.server.nodeID)
 
 	// 发送响应
-	return gmh.sendResponse(conn, request, 0, "login success", &loginResp)
+	if err := gmh.sendResponse(conn, request, 0, "login success", &loginResp); err != nil {
+		return err
+	}
+
+	// 投递离线期间的通知，失败的通知保留到下次登录
+	if err := gmh.notifier.Deliver(conn); err != nil {
+		logger.Error(fmt.Sprintf("Failed to deliver notifications to user %d: %v", conn.UserID, err))
+	}
+	return nil
 }
 
 // handleHeartbeat 处理心跳
@@ -186,16 +609,58 @@ func (gmh *GatewayMessageHandler) handleLogout(conn *network.Connection, request
 		// 设置用户离线
 		userCache := database.NewUserCache(gmh.server.redisManager)
 		userCache.SetUserOffline(conn.UserID)
+		gmh.server.realtime.UserOffline(gmh.server.nodeID)
+		gmh.gameRoutes.forget(conn.UserID)
 
 		logger.Info(fmt.Sprintf("User %d logged out from connection %d", conn.UserID, conn.ID))
 	}
 
+	// 清理会话去重记录
+	gmh.dedup.ClearSession(gmh.sessionKey(conn))
+
 	// 关闭连接
 	conn.Close()
 
 	return nil
 }
 
+// handleTransport 处理传输协商，为实时玩法签发KCP绑定票据，不支持时继续使用TCP
+func (gmh *GatewayMessageHandler) handleTransport(conn *network.Connection, request *proto.BaseRequest) error {
+	var transportReq proto.TransportRequest
+	if err := conn.GetCodec().Unmarshal(request.Data, &transportReq); err != nil {
+		return fmt.Errorf("failed to unmarshal transport request: %v", err)
+	}
+
+	if conn.UserID == 0 {
+		return gmh.sendError(conn, request, -1, "login required")
+	}
+	if _, isKCP := conn.Conn.(*network.KCPSession); isKCP {
+		return gmh.sendError(conn, request, -1, "transport already negotiated")
+	}
+
+	transportResp := proto.TransportResponse{Transport: "tcp"}
+	if gmh.kcpServer != nil && gmh.kcpServer.SupportsGameType(transportReq.GameType) {
+		conv, token, err := gmh.kcpServer.IssueTicket(conn)
+		if err != nil {
+			logger.Warn(fmt.Sprintf("Failed to issue kcp ticket for connection %d, falling back to tcp: %v", conn.ID, err))
+		} else {
+			transportResp = proto.TransportResponse{
+				Transport:     "kcp",
+				Port:          uint32(gmh.kcpServer.Port()),
+				Conv:          conv,
+				Token:         token,
+				Mtu:           uint32(gmh.kcpServer.MTU()),
+				BindTimeoutMs: uint32(gmh.kcpServer.BindTimeout() / time.Millisecond),
+			}
+		}
+	}
+
+	logger.Debug(fmt.Sprintf("Connection %d negotiated %s transport for game type %s",
+		conn.ID, transportResp.Transport, transportReq.GameType))
+
+	return gmh.sendResponse(conn, request, 0, "transport negotiated", &transportResp)
+}
+
 // forwardMessage 转发消息
 func (gmh *GatewayMessageHandler) forwardMessage(conn *network.Connection, msgID uint32, request *proto.BaseRequest) error {
 	// 根据消息ID确定目标服务
@@ -216,12 +681,29 @@ func (gmh *GatewayMessageHandler) forwardMessage(conn *network.Connection, msgID
 		return gmh.sendError(conn, request, -1, "unknown message type")
 	}
 
-	// 获取目标服务实例
-	service := gmh.server.discovery.GetService(targetService)
+	// 游戏消息转发到玩家所在游戏的节点，游戏迁移后自动转发到新节点
+	var service *discovery.ServiceInfo
+	if targetService == "game" {
+		service = gmh.gameService(conn)
+	}
+	// 获取服务玩家所属世界的目标服务实例，优先选择玩家所在区域
+	if service == nil {
+		service = gmh.server.discovery.GetServiceInWorld(targetService, gmh.server.config.World.Resolve(conn.WorldID), conn.Region)
+	}
 	if service == nil {
 		return gmh.sendError(conn, request, -2, fmt.Sprintf("%s service not available", targetService))
 	}
 
+	// 填写语言和追踪ID，后端服务据此本地化消息并关联日志
+	if request.Header != nil {
+		if request.Header.Language == "" {
+			request.Header.Language = conn.Language
+		}
+		if request.Header.TraceId == "" {
+			request.Header.TraceId = errcode.NewID()
+		}
+	}
+
 	// TODO: 通过RPC转发消息
 	// 简化实现：直接返回成功响应
 	logger.Info(fmt.Sprintf("Forwarding message ID %d to service: %s", msgID, targetService))
@@ -238,27 +720,30 @@ func (gmh *GatewayMessageHandler) sendResponse(conn *network.Connection, request
 		Msg:    msg,
 	}
 
+	codec := conn.GetCodec()
 	if data != nil {
-		responseData, err := proto.Marshal(data)
+		responseData, err := codec.Marshal(data)
 		if err != nil {
 			return fmt.Errorf("failed to marshal response data: %v", err)
 		}
 		response.Data = responseData
 	}
 
-	responseBytes, err := proto.Marshal(response)
+	responseBytes, err := codec.Marshal(response)
 	if err != nil {
 		return fmt.Errorf("failed to marshal response: %v", err)
 	}
 
 	// 添加消息长度头
-	length := len(responseBytes)
-	message := make([]byte, 4+length)
-	message[0] = byte(length >> 24)
-	message[1] = byte(length >> 16)
-	message[2] = byte(length >> 8)
-	message[3] = byte(length)
-	copy(message[4:], responseBytes)
+	message, err := network.EncodeFrame(responseBytes)
+	if err != nil {
+		return err
+	}
+
+	// 缓存响应，供客户端重试时回放
+	if clientMsgID := request.Header.GetClientMsgId(); clientMsgID != "" {
+		gmh.dedup.Complete(gmh.sessionKey(conn), clientMsgID, message)
+	}
 
 	return conn.Write(message)
 }
@@ -280,23 +765,6 @@ func NewGatewayService(server *GatewayServer) *GatewayService {
 	}
 }
 
-// GetName 获取服务名称
-func (gs *GatewayService) GetName() string {
-	return "GatewayService"
-}
-
-// RegisterMethods 注册方法
-func (gs *GatewayService) RegisterMethods() map[string]reflect.Value {
-	methods := make(map[string]reflect.Value)
-
-	methods["GetConnectionCount"] = reflect.ValueOf(gs.GetConnectionCount)
-	methods["SendToUser"] = reflect.ValueOf(gs.SendToUser)
-	methods["BroadcastMessage"] = reflect.ValueOf(gs.BroadcastMessage)
-	methods["KickUser"] = reflect.ValueOf(gs.KickUser)
-
-	return methods
-}
-
 // GetConnectionCount 获取连接数
 func (gs *GatewayService) GetConnectionCount(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
 	count := gs.server.tcpServer.GetConnectionCount()
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/phuhao00/lufy/internal/console"
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// gmConsoleCommands 控制台可用的GM命令，与executeGMCommand保持一致
+var gmConsoleCommands = map[string]string{
+	"kick":             "kick <用户ID> [原因] - 踢出用户",
+	"ban":              "ban <用户ID> <秒数> [原因] - 封禁用户",
+	"unban":            "unban <用户ID> - 解封用户",
+	"shadow_ban":       "shadow_ban <用户ID> [原因] - 影子封禁，聊天只有自己可见，匹配时优先与其他影子封禁的玩家组局",
+	"shadow_unban":     "shadow_unban <用户ID> - 解除影子封禁",
+	"notice":           "notice <内容> - 发送全服跑马灯公告",
+	"announcements":    "announcements [公告ID] | announcements delete <公告ID> - 查看或删除公告",
+	"surveys":          "surveys [问卷ID] - 查看问卷和回答数",
+	"survey_export":    "survey_export <问卷ID> - 把问卷回答导出为CSV",
+	"tickets":          "tickets [工单ID|open|assigned|resolved] - 查看客服工单",
+	"ticket_stats":     "ticket_stats - 查看各分类的工单数和平均处理时长",
+	"reload":           "reload [confirm] [节点ID...] - 所有节点校验配置，confirm时提交到指定节点或全部节点",
+	"encrypt_backfill": "encrypt_backfill - 重新加密用户敏感字段",
+	"world_backfill":   "world_backfill - 把未划分世界的旧数据归入默认世界",
+	"world_transfer":   "world_transfer <用户ID> <目标世界> [confirm] - 迁移玩家到另一个世界",
+	"push_stats":       "push_stats [日期YYYYMMDD] - 查看移动推送统计",
+	"chat_policy":      "chat_policy [频道] [reset | per_minute=N min_level=N block_links=true|false] - 查看或修改频道发言限制",
+	"chat_stats":       "chat_stats [日期YYYYMMDD] - 查看违反频道发言限制的统计",
+	"chat_delete":      "chat_delete <消息ID> [原因] - 删除聊天消息，不受撤回时限限制，玩家看到消息已撤回",
+	"exchange_stats":   "exchange_stats [日期YYYYMMDD] - 查看货币兑换统计",
+	"fraud_cases":      "fraud_cases [pending|confirmed|dismissed|all] - 查看风控案件",
+	"fraud_review":     "fraud_review <案件ID> <confirm|dismiss> - 审核风控案件，驳回时解除冻结",
+	"fraud_backtest":   "fraud_backtest [小时] - 用当前风控规则回测历史流水",
+	"timeline":         "timeline <用户ID> [天数] - 查看玩家最近的登录、房间、聊天量和大额货币变动",
+	"segment_check":    "segment_check <用户ID> - 查看玩家的分群、功能开关和实验分组",
+	"segment_mail":     "segment_mail <分群> <标题> <内容> - 创建向分群发送的邮件活动，返回的批次可用rollback撤回",
+	"mail_campaign":    "mail_campaign [活动ID] [pause|resume|cancel] - 查看邮件活动进度和领取统计，或暂停、继续、取消活动",
+	"segment_notice":   "segment_notice <分群> <内容> - 向分群发送定向公告",
+	"grant_xp":         "grant_xp <用户ID> <经验> [原因] - 发放经验并检查升级",
+	"profile":          "profile <节点ID> <类型> [秒数] - 采集节点profile",
+	"settlement":       "settlement <游戏ID> - 查看游戏结算记录",
+	"reconcile":        "reconcile <节点ID> [小时] [repair] - 结算对账",
+	"rollback":         "rollback <批次ID> [confirm] [原因] - 回滚奖励发放",
+	"status":           "status - 查看服务器状态",
+}
+
+// gmConsoleHandler 把控制台命令转交给GM命令执行，并写入GM操作日志
+type gmConsoleHandler struct {
+	service *GMService
+}
+
+// Commands 可用命令
+func (h *gmConsoleHandler) Commands() map[string]string {
+	return gmConsoleCommands
+}
+
+// Execute 执行GM命令
+func (h *gmConsoleHandler) Execute(operator *console.Operator, command string, args []string) (string, error) {
+	return h.service.executeGMCommand(operator.ID, command, args)
+}
+
+// Audit 记录审计日志
+func (h *gmConsoleHandler) Audit(operator *console.Operator, action, details string) {
+	details = fmt.Sprintf("操作员: %s, %s", operator.Name, details)
+	logger.Info(fmt.Sprintf("GM console %s by %s(%d): %s", action, operator.Name, operator.ID, details))
+
+	if err := h.service.server.gmRepo.LogGMAction(operator.ID, action, 0, details); err != nil {
+		logger.Error(fmt.Sprintf("Failed to log console action: %v", err))
+	}
+}
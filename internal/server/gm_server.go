@@ -5,21 +5,69 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"reflect"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/phuhao00/lufy/internal/announcement"
+	"github.com/phuhao00/lufy/internal/backup"
+	"github.com/phuhao00/lufy/internal/campaign"
+	"github.com/phuhao00/lufy/internal/chatpolicy"
+	"github.com/phuhao00/lufy/internal/compensation"
+	"github.com/phuhao00/lufy/internal/console"
 	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/errcode"
+	"github.com/phuhao00/lufy/internal/exchange"
 	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/monitoring"
+	"github.com/phuhao00/lufy/internal/mq"
+	"github.com/phuhao00/lufy/internal/naming"
+	"github.com/phuhao00/lufy/internal/news"
+	"github.com/phuhao00/lufy/internal/privacy"
+	"github.com/phuhao00/lufy/internal/progression"
+	"github.com/phuhao00/lufy/internal/push"
+	"github.com/phuhao00/lufy/internal/redeem"
+	"github.com/phuhao00/lufy/internal/rollup"
+	"github.com/phuhao00/lufy/internal/search"
+	"github.com/phuhao00/lufy/internal/segment"
+	"github.com/phuhao00/lufy/internal/support"
+	"github.com/phuhao00/lufy/internal/survey"
+	"github.com/phuhao00/lufy/internal/timeline"
+	"github.com/phuhao00/lufy/internal/transfer"
 	"github.com/phuhao00/lufy/pkg/proto"
 )
 
 // GMServer GM服务器
 type GMServer struct {
 	*BaseServer
-	gmRepo   *database.GMRepository
-	userRepo *database.UserRepository
+	gmRepo         *database.GMRepository
+	userRepo       *database.UserRepository
+	settlementRepo *database.SettlementRepository
+	ledgerRepo     *database.LedgerRepository
+	fraudRepo      *database.FraudCaseRepository
+	mailRepo       *database.MailRepository
+	chatRepo       *database.ChatRepository
+	replayRepo     *database.ReplayRepository
+	desyncRepo     *database.DesyncRepository
+	progression    *progression.Service
+	segments       *segment.Service
+	campaigns      *campaign.Service
+	announcements  *announcement.Service
+	news           *news.Service
+	timeline       *timeline.Recorder
+	chatPolicy     *chatpolicy.Service
+	naming         *naming.Service
+	search         *search.Service
+	surveys        *survey.Service
+	support        *support.Service
+	redeem         *redeem.Service
+	rollups        *rollup.Service
+	notifier       *Notifier
+	rollback       *compensation.Rollback
+	privacy        *privacy.PrivacyManager
+	transfer       *transfer.Transfer
+	console        *console.Console
+	monitoring     *monitoring.MonitoringManager
 }
 
 // NewGMServer 创建GM服务器
@@ -30,11 +78,60 @@ func NewGMServer(configFile, nodeID string) *GMServer {
 	}
 
 	gmServer := &GMServer{
-		BaseServer: baseServer,
-		gmRepo:     database.NewGMRepository(baseServer.mongoManager),
-		userRepo:   database.NewUserRepository(baseServer.mongoManager),
+		BaseServer:     baseServer,
+		gmRepo:         database.NewGMRepository(baseServer.mongoManager),
+		userRepo:       database.NewUserRepository(baseServer.mongoManager),
+		settlementRepo: database.NewSettlementRepository(baseServer.mongoManager),
+		ledgerRepo:     database.NewLedgerRepository(baseServer.mongoManager),
+		fraudRepo:      database.NewFraudCaseRepository(baseServer.mongoManager),
+		mailRepo:       database.NewMailRepository(baseServer.mongoManager),
+		chatRepo:       database.NewChatRepository(baseServer.mongoManager),
+		replayRepo:     database.NewReplayRepository(baseServer.mongoManager),
+		desyncRepo:     baseServer.newDesyncRepository(),
+		progression:    baseServer.newProgression(),
+		segments:       baseServer.newSegments(),
+		campaigns:      baseServer.newCampaigns(nil),
+		announcements:  baseServer.newAnnouncements(),
+		news:           baseServer.newNews(),
+		timeline:       baseServer.newTimeline(),
+		chatPolicy:     baseServer.newChatPolicy(),
+		naming:         baseServer.newNaming(),
+		search:         baseServer.newSearch(),
+		rollups:        baseServer.newRollups(),
+		notifier:       NewNotifier(baseServer),
+		privacy: privacy.NewPrivacyManager(baseServer.mongoManager,
+			database.NewUserCache(baseServer.redisManager), &baseServer.config.Privacy),
 	}
 
+	gmServer.surveys = baseServer.newSurveys(gmServer.segments)
+	gmServer.support = baseServer.newSupport(gmServer.notifier)
+	gmServer.redeem = baseServer.newRedeem(nil)
+
+	// 导出客服工单、消息订阅和集群实时计数指标
+	if port := baseServer.config.Support.Metrics; port > 0 {
+		gmServer.monitoring, err = monitoring.NewMonitoringManager(nodeID, "gm", port)
+		if err != nil {
+			logger.Fatal(fmt.Sprintf("Failed to create monitoring manager: %v", err))
+		}
+		if err := gmServer.monitoring.Register(support.NewCollector(gmServer.support)); err != nil {
+			logger.Fatal(fmt.Sprintf("Failed to register support metrics: %v", err))
+		}
+		if err := gmServer.monitoring.Register(mq.NewConsumerCollector(baseServer.ConsumerStats)); err != nil {
+			logger.Fatal(fmt.Sprintf("Failed to register consumer metrics: %v", err))
+		}
+		if err := gmServer.monitoring.Register(baseServer.realtime); err != nil {
+			logger.Fatal(fmt.Sprintf("Failed to register realtime metrics: %v", err))
+		}
+		baseServer.registerMonitoring(gmServer.monitoring)
+	}
+
+	gmServer.rollback = compensation.NewRollback(&baseServer.config.Compensation,
+		gmServer.ledgerRepo, gmServer.mailRepo,
+		baseServer.generateMailID)
+
+	gmServer.transfer = transfer.NewTransfer(&baseServer.config.WorldTransfer, &baseServer.config.World,
+		baseServer.mongoManager, baseServer.redisManager)
+
 	// 注册通用服务
 	if err := RegisterCommonServices(baseServer); err != nil {
 		logger.Fatal(fmt.Sprintf("Failed to register common services: %v", err))
@@ -42,53 +139,86 @@ func NewGMServer(configFile, nodeID string) *GMServer {
 
 	// 注册GM服务
 	gmService := NewGMService(gmServer)
-	if err := baseServer.rpcServer.RegisterService(gmService); err != nil {
+	if err := RegisterGMService(baseServer.rpcServer, gmService); err != nil {
 		logger.Fatal(fmt.Sprintf("Failed to register gm service: %v", err))
 	}
 
+	// 交互式管理控制台
+	if baseServer.config.GMConsole.Enabled {
+		gmConsole, err := console.NewConsole(&baseServer.config.GMConsole, &gmConsoleHandler{service: gmService})
+		if err != nil {
+			logger.Fatal(fmt.Sprintf("Failed to create gm console: %v", err))
+		}
+		gmServer.console = gmConsole
+		baseServer.RegisterOnStart("gm_console", OrderListeners, func(ctx context.Context) error {
+			return gmConsole.Start()
+		})
+		baseServer.RegisterOnStop("gm_console", OrderListeners, func(ctx context.Context) error {
+			gmConsole.Stop()
+			return nil
+		})
+	}
+
+	// 定期解除到期的封禁
+	if err := baseServer.scheduler.Register("clean_expired_bans", "@every 5m", func(ctx context.Context) error {
+		return gmServer.gmRepo.CleanExpiredBans()
+	}); err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to register ban cleanup job: %v", err))
+	}
+
+	// 按风控规则检查新的流水
+	if err := baseServer.scheduler.Register("fraud_scan", "@every 1m", gmServer.scanFraud); err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to register fraud scan job: %v", err))
+	}
+
+	// 推送开始的跑马灯公告
+	if err := baseServer.scheduler.Register("announcement_push", "@every 10s", gmServer.pushAnnouncements); err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to register announcement push job: %v", err))
+	}
+
+	// 汇总每小时和每天的峰值在线、游戏数和平均时长
+	if err := baseServer.scheduler.Register("stats_rollup", "@every "+gmServer.rollups.Interval().String(), gmServer.rollups.Run); err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to register stats rollup job: %v", err))
+	}
+
+	// 定时备份游戏数据到对象存储
+	if config := &baseServer.config.Backup; config.Enabled {
+		backups, err := backup.NewService(config, baseServer.mongoManager)
+		if err != nil {
+			logger.Fatal(fmt.Sprintf("Failed to create backup service: %v", err))
+		}
+		schedule := config.Schedule
+		if schedule == "" {
+			schedule = backup.DefaultSchedule
+		}
+		if err := baseServer.scheduler.Register("backup", schedule, backups.Run); err != nil {
+			logger.Fatal(fmt.Sprintf("Failed to register backup job: %v", err))
+		}
+	}
+
 	return gmServer
 }
 
 // GMService GM RPC服务
 type GMService struct {
-	server *GMServer
+	server    *GMServer
+	responses *errcode.Builder
 }
 
 // NewGMService 创建GM服务
 func NewGMService(server *GMServer) *GMService {
 	return &GMService{
-		server: server,
+		server:    server,
+		responses: errcode.NewBuilder(nil),
 	}
 }
 
-// GetName 获取服务名称
-func (gs *GMService) GetName() string {
-	return "GMService"
-}
-
-// RegisterMethods 注册方法
-func (gs *GMService) RegisterMethods() map[string]reflect.Value {
-	methods := make(map[string]reflect.Value)
-
-	methods["ExecuteCommand"] = reflect.ValueOf(gs.ExecuteCommand)
-	methods["KickUser"] = reflect.ValueOf(gs.KickUser)
-	methods["BanUser"] = reflect.ValueOf(gs.BanUser)
-	methods["UnbanUser"] = reflect.ValueOf(gs.UnbanUser)
-	methods["SendNotice"] = reflect.ValueOf(gs.SendNotice)
-	methods["ReloadConfig"] = reflect.ValueOf(gs.ReloadConfig)
-
-	return methods
-}
-
 // ExecuteCommand 执行GM命令
 func (gs *GMService) ExecuteCommand(ctx context.Context, req *proto.GMCommandRequest) (*proto.CommonResponse, error) {
 	// 验证GM权限
 	gmUserID := ctx.Value("user_id")
 	if gmUserID == nil {
-		return &proto.CommonResponse{
-			Code:    1001,
-			Message: "用户未登录",
-		}, nil
+		return gs.responses.CommonError(ctx, errcode.NotLoggedIn), nil
 	}
 
 	gmID := gmUserID.(uint64)
@@ -104,20 +234,14 @@ func (gs *GMService) ExecuteCommand(ctx context.Context, req *proto.GMCommandReq
 
 	// 验证命令
 	if cmdReq.Command == "" {
-		return &proto.CommonResponse{
-			Code:    1002,
-			Message: "命令不能为空",
-		}, nil
+		return gs.responses.CommonError(ctx, errcode.CommandRequired), nil
 	}
 
 	// 执行GM命令
 	result, err := gs.executeGMCommand(gmID, cmdReq.Command, cmdReq.Args)
 	if err != nil {
 		log.Printf("执行GM命令失败: %v", err)
-		return &proto.CommonResponse{
-			Code:    1003,
-			Message: fmt.Sprintf("命令执行失败: %v", err),
-		}, nil
+		return gs.responses.CommonError(ctx, errcode.CommandFailed.WithDetail(err.Error())), nil
 	}
 
 	// 记录GM操作日志
@@ -126,11 +250,7 @@ func (gs *GMService) ExecuteCommand(ctx context.Context, req *proto.GMCommandReq
 
 	log.Printf("GM用户 %d 执行命令成功: %s", gmID, cmdReq.Command)
 
-	return &proto.CommonResponse{
-		Code:    0,
-		Message: "命令执行成功",
-		Data:    []byte(result),
-	}, nil
+	return gs.responses.CommonSuccess(ctx, "命令执行成功", []byte(result)), nil
 }
 
 // executeGMCommand 执行具体的GM命令
@@ -194,23 +314,719 @@ func (gs *GMService) executeGMCommand(gmUserID uint64, command string, args []st
 		}
 		return fmt.Sprintf("用户 %d 已被解封", userID), nil
 
+	case "shadow_ban":
+		if len(args) < 1 {
+			return "", fmt.Errorf("shadow_ban命令需要用户ID参数")
+		}
+		userID, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("无效的用户ID: %s", args[0])
+		}
+		reason := "GM影子封禁"
+		if len(args) > 1 {
+			reason = strings.Join(args[1:], " ")
+		}
+		return gs.server.setShadowBan(gmUserID, userID, true, reason)
+
+	case "shadow_unban":
+		if len(args) < 1 {
+			return "", fmt.Errorf("shadow_unban命令需要用户ID参数")
+		}
+		userID, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("无效的用户ID: %s", args[0])
+		}
+		return gs.server.setShadowBan(gmUserID, userID, false, "")
+
 	case "notice":
 		if len(args) < 1 {
 			return "", fmt.Errorf("notice命令需要公告内容参数")
 		}
 		content := strings.Join(args, " ")
-		// TODO: 实现全服广播
-		logger.Info(fmt.Sprintf("Broadcasting notice: %s", content))
-		return fmt.Sprintf("全服公告已发送: %s", content), nil
+		notice, err := gs.server.sendNotice(gmUserID, "", content, 0)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("全服公告已发送: %s, 公告: %s", content, notice.ID.Hex()), nil
+
+	case "announcements":
+		// 查看最近的公告或公告详情，或删除公告
+		if len(args) >= 2 && strings.ToLower(args[0]) == "delete" {
+			if err := gs.server.deleteAnnouncement(gmUserID, args[1]); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("公告 %s 已删除", args[1]), nil
+		}
+		announcementID := ""
+		if len(args) == 1 {
+			announcementID = args[0]
+		}
+		data, err := gs.server.announcementsJSON(announcementID)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+
+	case "news":
+		// 查看最近的资讯或资讯详情，或删除资讯: news [资讯ID | delete <资讯ID>]
+		if len(args) >= 2 && strings.ToLower(args[0]) == "delete" {
+			if err := gs.server.deleteNewsItem(gmUserID, args[1]); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("资讯 %s 已删除", args[1]), nil
+		}
+		itemID := ""
+		if len(args) == 1 {
+			itemID = args[0]
+		}
+		data, err := gs.server.newsJSON(itemID, 20)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+
+	case "surveys":
+		// 查看最近的问卷或问卷详情和回答数
+		surveyID := ""
+		if len(args) >= 1 {
+			surveyID = args[0]
+		}
+		data, err := gs.server.surveysJSON(surveyID)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+
+	case "survey_export":
+		// 把问卷回答导出为CSV
+		if len(args) < 1 {
+			return "", fmt.Errorf("survey_export命令需要问卷ID参数")
+		}
+		path, count, err := gs.server.exportSurvey(gmUserID, args[0])
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("问卷 %s 的 %d 份回答已导出到 %s", args[0], count, path), nil
+
+	case "tickets":
+		// 查看工单详情，或按状态查看最近的工单
+		ticketID, filter := "", database.TicketFilter{}
+		if len(args) >= 1 {
+			switch strings.ToLower(args[0]) {
+			case database.TicketStatusOpen, database.TicketStatusAssigned, database.TicketStatusResolved:
+				filter.Status = strings.ToLower(args[0])
+			default:
+				ticketID = args[0]
+			}
+		}
+		data, err := gs.server.ticketsJSON(ticketID, filter)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+
+	case "ticket_stats":
+		// 查看各分类的工单数和平均处理时长
+		stats, err := gs.server.support.Stats(time.Now())
+		if err != nil {
+			return "", err
+		}
+		data, err := json.Marshal(stats)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
 
 	case "reload":
-		// TODO: 重载配置
-		logger.Info("Config reload command sent")
-		return "配置重载命令已发送", nil
+		// 分阶段更新配置，默认只在所有节点校验并返回差异，带confirm参数时提交到指定节点，未指定时提交到所有节点
+		dryRun := len(args) < 1 || strings.ToLower(args[0]) != "confirm"
+		nodes := args
+		if !dryRun {
+			nodes = args[1:]
+		}
+		report, err := gs.server.runConfigRollout(ConfigRolloutOptions{
+			Targets: rolloutNodeIDs(nodes),
+			DryRun:  dryRun,
+		})
+		if err != nil {
+			return "", err
+		}
+		gs.server.gmRepo.LogGMAction(gmUserID, "config_rollout", 0,
+			fmt.Sprintf("更新: %s, 预览: %v, 目标节点: %v, 中止: %v", report.ID, dryRun, report.Targets, report.Aborted))
+		return report.Summary(), nil
+
+	case "encrypt_backfill":
+		// 回填加密敏感字段，密钥轮换后同样使用该命令重新加密
+		count, err := gs.server.userRepo.MigrateSensitiveFields()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("已重新加密 %d 个用户的敏感字段", count), nil
+
+	case "world_backfill":
+		// 把划分世界之前的用户、房间和聊天记录归入默认世界
+		worldID := gs.server.config.World.Default()
+		updated, err := gs.server.mongoManager.AssignDefaultWorld(worldID)
+		if err != nil {
+			return "", err
+		}
+		gs.server.gmRepo.LogGMAction(gmUserID, "world_backfill", 0, fmt.Sprintf("世界: %d, 更新: %v", worldID, updated))
+		return fmt.Sprintf("已归入世界 %d: 用户 %d, 房间 %d, 聊天记录 %d",
+			worldID, updated["users"], updated["rooms"], updated["chat_messages"]), nil
+
+	case "world_transfer":
+		// 把玩家迁移到另一个世界，默认只预览冲突和迁移内容，带confirm参数时执行
+		if len(args) < 2 {
+			return "", fmt.Errorf("world_transfer命令需要用户ID和目标世界参数")
+		}
+		userID, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("无效的用户ID: %s", args[0])
+		}
+		toWorld, err := strconv.ParseUint(args[1], 10, 32)
+		if err != nil {
+			return "", fmt.Errorf("无效的世界ID: %s", args[1])
+		}
+		dryRun := len(args) < 3 || strings.ToLower(args[2]) != "confirm"
+		report, err := gs.server.transfer.Run(userID, uint32(toWorld), dryRun)
+		if report != nil && !dryRun {
+			gs.server.gmRepo.LogGMAction(gmUserID, "world_transfer", userID,
+				fmt.Sprintf("世界: %d -> %d, 昵称: %s, 归档: %s, 撤销: %v, 错误: %v",
+					report.FromWorld, report.ToWorld, report.Nickname, report.ArchivePath, report.RolledBack, err))
+		}
+		if err != nil {
+			return "", err
+		}
+		data, err := json.Marshal(report)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+
+	case "push_stats":
+		// 全集群的移动推送结果统计，默认当天
+		day := time.Now()
+		if len(args) > 0 {
+			parsed, err := time.ParseInLocation("20060102", args[0], time.Local)
+			if err != nil {
+				return "", fmt.Errorf("无效的日期: %s", args[0])
+			}
+			day = parsed
+		}
+		metrics, err := push.GetMetrics(gs.server.redisManager, day)
+		if err != nil {
+			return "", err
+		}
+		data, err := json.Marshal(metrics)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+
+	case "fraud_cases":
+		// 风控案件列表，默认只列出待审核的案件，all列出所有案件
+		status := database.FraudCasePending
+		if len(args) > 0 {
+			status = strings.ToLower(args[0])
+			if status == "all" {
+				status = ""
+			}
+		}
+		return gs.server.fraudCasesJSON(status)
+
+	case "fraud_review":
+		// 审核风控案件，dismiss时解除自动冻结
+		if len(args) < 2 {
+			return "", fmt.Errorf("fraud_review命令需要案件ID和审核结果(confirm/dismiss)参数")
+		}
+		switch strings.ToLower(args[1]) {
+		case "confirm":
+			return gs.server.reviewFraudCase(args[0], true, gmUserID)
+		case "dismiss":
+			return gs.server.reviewFraudCase(args[0], false, gmUserID)
+		}
+		return "", fmt.Errorf("无效的审核结果: %s", args[1])
+
+	case "timeline":
+		// 玩家最近若干天的活动时间线，默认7天，用于调查作弊和欺诈
+		if len(args) < 1 {
+			return "", fmt.Errorf("timeline命令需要用户ID参数")
+		}
+		userID, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("无效的用户ID: %s", args[0])
+		}
+		days := 0
+		if len(args) > 1 {
+			d, err := strconv.Atoi(args[1])
+			if err != nil || d <= 0 {
+				return "", fmt.Errorf("无效的天数: %s", args[1])
+			}
+			days = d
+		}
+		gs.server.gmRepo.LogGMAction(gmUserID, "timeline", userID, fmt.Sprintf("天数: %d", days))
+		return gs.server.timelineJSON(userID, days)
+
+	case "fraud_backtest":
+		// 用当前规则检查最近若干小时的流水，默认24小时
+		hours := 24
+		if len(args) > 0 {
+			h, err := strconv.Atoi(args[0])
+			if err != nil || h <= 0 {
+				return "", fmt.Errorf("无效的小时数: %s", args[0])
+			}
+			hours = h
+		}
+		report, err := gs.server.backtestFraud(hours)
+		if err != nil {
+			return "", err
+		}
+		data, err := json.Marshal(report)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+
+	case "chat_policy":
+		// 查看或在运行时修改频道的发言限制，修改对所有聊天节点生效
+		return gs.server.chatPolicyCommand(gmUserID, args)
+
+	case "chat_delete":
+		if len(args) < 1 {
+			return "", fmt.Errorf("chat_delete命令需要消息ID参数")
+		}
+		messageID, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("无效的消息ID: %s", args[0])
+		}
+		reason := "GM删除"
+		if len(args) > 1 {
+			reason = strings.Join(args[1:], " ")
+		}
+		return gs.server.deleteChatMessage(gmUserID, messageID, reason)
+
+	case "chat_stats":
+		// 全集群违反频道发言限制的统计，默认当天
+		day := time.Now()
+		if len(args) > 0 {
+			parsed, err := time.ParseInLocation("20060102", args[0], time.Local)
+			if err != nil {
+				return "", fmt.Errorf("无效的日期: %s", args[0])
+			}
+			day = parsed
+		}
+		metrics, err := chatpolicy.GetMetrics(gs.server.redisManager, day)
+		if err != nil {
+			return "", err
+		}
+		data, err := json.Marshal(metrics)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+
+	case "exchange_stats":
+		// 全集群的货币兑换量和回收的税
+		day := time.Now()
+		if len(args) > 0 {
+			parsed, err := time.ParseInLocation("20060102", args[0], time.Local)
+			if err != nil {
+				return "", fmt.Errorf("无效的日期: %s", args[0])
+			}
+			day = parsed
+		}
+		metrics, err := exchange.GetMetrics(gs.server.redisManager, day)
+		if err != nil {
+			return "", err
+		}
+		data, err := json.Marshal(metrics)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+
+	case "segment_check":
+		// 查看玩家所属的分群、功能开关和实验分组
+		if len(args) < 1 {
+			return "", fmt.Errorf("segment_check命令需要用户ID参数")
+		}
+		userID, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("无效的用户ID: %s", args[0])
+		}
+		return gs.server.segmentReport(userID)
+
+	case "segment_mail":
+		// 向分群内的所有玩家发送邮件
+		if len(args) < 3 {
+			return "", fmt.Errorf("segment_mail命令需要分群、标题和内容参数")
+		}
+		mailCampaign, err := gs.server.sendCampaignMail(gmUserID, args[0], args[1], strings.Join(args[2:], " "))
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("邮件活动 %s 已创建，由邮件服分批发送，批次: %s", mailCampaign.ID.Hex(), mailCampaign.BatchID()), nil
+
+	case "mail_campaign":
+		// 查看邮件活动进度，或暂停、继续、取消活动
+		if len(args) >= 2 {
+			if err := updateCampaign(gs.server.campaigns, args[0], args[1]); err != nil {
+				return "", err
+			}
+			gs.server.gmRepo.LogGMAction(gmUserID, "mail_campaign", 0, fmt.Sprintf("活动: %s, 操作: %s", args[0], args[1]))
+			return fmt.Sprintf("邮件活动 %s 已%s", args[0], args[1]), nil
+		}
+		campaignID := ""
+		if len(args) == 1 {
+			campaignID = args[0]
+		}
+		data, err := campaignJSON(gs.server.campaigns, campaignID)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+
+	case "segment_notice":
+		// 向分群内的所有玩家发送定向公告
+		if len(args) < 2 {
+			return "", fmt.Errorf("segment_notice命令需要分群和公告内容参数")
+		}
+		batchID, err := gs.server.sendCampaignNotice(gmUserID, args[0], strings.Join(args[1:], " "))
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("开始向分群 %s 发送公告，批次: %s", args[0], batchID), nil
+
+	case "grant_xp":
+		// 补发经验，批次ID为 gm_xp:<时间戳>，可按批次回滚(等级不会降低)
+		if len(args) < 2 {
+			return "", fmt.Errorf("grant_xp命令需要用户ID和经验参数")
+		}
+		userID, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("无效的用户ID: %s", args[0])
+		}
+		amount, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || amount <= 0 {
+			return "", fmt.Errorf("无效的经验: %s", args[1])
+		}
+		reason := "GM补发"
+		if len(args) > 2 {
+			reason = strings.Join(args[2:], " ")
+		}
+		batchID := fmt.Sprintf("gm_xp:%d", time.Now().UnixNano())
+		progress, err := gs.server.progression.Grant(userID, amount, fmt.Sprintf("%s:%d", batchID, userID), batchID, reason)
+		if err != nil {
+			return "", err
+		}
+		gs.server.gmRepo.LogGMAction(gmUserID, "grant_xp", userID,
+			fmt.Sprintf("经验: %d, 批次: %s, 原因: %s", amount, batchID, reason))
+		return fmt.Sprintf("用户 %d 获得 %d 经验，当前等级 %d，经验 %d，批次 %s",
+			userID, amount, progress.Level, progress.Experience, batchID), nil
+
+	case "profile":
+		// 目标节点采集profile并上传到对象存储，无需直接访问pprof端口
+		if len(args) < 2 {
+			return "", fmt.Errorf("profile命令需要节点ID和类型参数")
+		}
+		nodeID, profileType := args[0], strings.ToLower(args[1])
+		seconds := 0
+		if len(args) > 2 {
+			s, err := strconv.Atoi(args[2])
+			if err != nil {
+				return "", fmt.Errorf("无效的时长: %s", args[2])
+			}
+			seconds = s
+		}
+		if err := gs.server.profiler.ValidateRequest(profileType, seconds); err != nil {
+			return "", err
+		}
+
+		key := gs.server.profiler.ObjectKey(nodeID, profileType)
+		if err := gs.server.messageBroker.SendToNode(nodeID, mq.SYS_CMD_CAPTURE_PROFILE, map[string]interface{}{
+			"type":    profileType,
+			"seconds": seconds,
+			"key":     key,
+		}); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("节点 %s 开始采集%s profile，约 %d 秒后可下载: %s",
+			nodeID, profileType, seconds, gs.server.profiler.URL(key)), nil
+
+	case "settlement":
+		// 查看游戏的结算记录
+		if len(args) < 1 {
+			return "", fmt.Errorf("settlement命令需要游戏ID参数")
+		}
+		gameID, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("无效的游戏ID: %s", args[0])
+		}
+		settlements, err := gs.server.settlementRepo.GetSettlements(gameID)
+		if err != nil {
+			return "", err
+		}
+		if len(settlements) == 0 {
+			return fmt.Sprintf("游戏 %d 没有结算记录", gameID), nil
+		}
+		lines := make([]string, 0, len(settlements))
+		for _, record := range settlements {
+			line := fmt.Sprintf("%s: %s, 节点 %s, 尝试 %d 次", record.Step, record.Status, record.NodeID, record.Attempts)
+			if record.Error != "" {
+				line += ", 错误: " + record.Error
+			}
+			lines = append(lines, line)
+		}
+		return strings.Join(lines, "\n"), nil
+
+	case "reconcile":
+		// 在指定游戏节点上对账最近结束的游戏，repair时补结算遗漏的步骤，结果写入该节点日志
+		if len(args) < 1 {
+			return "", fmt.Errorf("reconcile命令需要游戏节点ID参数")
+		}
+		hours := 0
+		if len(args) > 1 {
+			h, err := strconv.Atoi(args[1])
+			if err != nil || h <= 0 {
+				return "", fmt.Errorf("无效的小时数: %s", args[1])
+			}
+			hours = h
+		}
+		repair := len(args) > 2 && strings.ToLower(args[2]) == "repair"
+		if err := gs.server.messageBroker.SendCommand(args[0], mq.SYS_CMD_RECONCILE, &proto.ReconcileCommand{
+			Hours:  float64(hours),
+			Repair: repair,
+		}); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("节点 %s 开始结算对账(修复: %v)，结果见节点日志", args[0], repair), nil
+
+	case "rollback":
+		// 按批次回滚奖励发放，默认只预览，带confirm参数时执行并通知玩家
+		if len(args) < 1 {
+			return "", fmt.Errorf("rollback命令需要批次ID参数")
+		}
+		dryRun := len(args) < 2 || strings.ToLower(args[1]) != "confirm"
+		reason := "GM回滚"
+		if len(args) > 2 {
+			reason = strings.Join(args[2:], " ")
+		}
+		report, err := gs.server.rollback.Run(args[0], reason, dryRun, true)
+		if err != nil {
+			return "", err
+		}
+		gs.server.gmRepo.LogGMAction(gmUserID, "rollback_grant", 0,
+			fmt.Sprintf("批次: %s, 预览: %v, 原因: %s", args[0], dryRun, reason))
+		data, err := json.Marshal(report)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+
+	case "event_schemas":
+		// 本节点的事件载荷定义和版本，滚动升级时对比新旧节点
+		data, err := json.Marshal(mq.Schemas.Schemas())
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+
+	case "chaos":
+		// 故障注入: chaos <节点ID|all> latency=30%:200ms error=10% drop=5% disconnect=1% [duration=5m]，
+		// chaos <节点ID|all> off 清除，节点需开启chaos.enabled
+		if len(args) < 2 {
+			return "", fmt.Errorf("chaos命令需要节点ID和规则参数")
+		}
+		command, err := parseChaosCommand(args[1:])
+		if err != nil {
+			return "", err
+		}
+		command.Operator = strconv.FormatUint(gmUserID, 10)
+		target := args[0]
+		if target == "all" {
+			target = ""
+		}
+		if err := gs.server.messageBroker.SendCommand(target, mq.SYS_CMD_CHAOS, command); err != nil {
+			return "", err
+		}
+		if strings.ToLower(args[1]) == "off" {
+			return fmt.Sprintf("已通知节点 %s 清除故障注入规则", args[0]), nil
+		}
+		return fmt.Sprintf("已向节点 %s 下发故障注入规则，结果见节点日志: %s", args[0], strings.Join(args[1:], " ")), nil
+
+	case "set_runtime_param":
+		// 运行时参数: set_runtime_param <节点ID|all> <参数> <值> [持续时间]，到期自动恢复为调整前的值，
+		// set_runtime_param <节点ID|all> <参数> reset 立即恢复
+		if len(args) < 3 {
+			return "", fmt.Errorf("set_runtime_param命令需要节点ID、参数和值，可调整的参数: %s",
+				strings.Join(gs.server.tuner.Names(), ", "))
+		}
+		command, err := parseRuntimeParamCommand(args[1:])
+		if err != nil {
+			return "", err
+		}
+		command.Operator = strconv.FormatUint(gmUserID, 10)
+		target := args[0]
+		if target == "all" {
+			target = ""
+		}
+		if err := gs.server.messageBroker.SendCommand(target, mq.SYS_CMD_RUNTIME_PARAM, command); err != nil {
+			return "", err
+		}
+		gs.server.gmRepo.LogGMAction(gmUserID, "set_runtime_param", 0, fmt.Sprintf("节点: %s, 参数: %s",
+			args[0], strings.Join(args[1:], " ")))
+		if command.Revert {
+			return fmt.Sprintf("已通知节点 %s 恢复参数 %s", args[0], command.Name), nil
+		}
+		return fmt.Sprintf("已通知节点 %s 调整参数 %s 为 %s，结果见节点日志", args[0], command.Name, command.Value), nil
+
+	case "slow_queries":
+		// 最近的MongoDB慢查询及执行计划: slow_queries [集合] [条数]
+		collection := ""
+		if len(args) > 0 {
+			collection = args[0]
+		}
+		limit := int64(20)
+		if len(args) > 1 {
+			n, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil || n <= 0 {
+				return "", fmt.Errorf("无效的条数: %s", args[1])
+			}
+			limit = n
+		}
+		queries, err := database.NewSlowQueryRepository(gs.server.mongoManager).Recent(collection, limit)
+		if err != nil {
+			return "", err
+		}
+		lines := make([]string, 0, len(queries))
+		for _, q := range queries {
+			line := fmt.Sprintf("%s %s.%s %dms failed=%v command=%s", q.CreatedAt.Format(time.RFC3339),
+				q.Collection, q.Operation, q.DurationMs, q.Failed, q.Command)
+			if q.Plan != nil {
+				line += " plan=" + q.Plan.String()
+			}
+			if q.ExplainError != "" {
+				line += " explain_error=" + q.ExplainError
+			}
+			lines = append(lines, line)
+		}
+		return strings.Join(lines, "\n"), nil
+
+	case "rename":
+		// 修改玩家昵称，不受冷却时间限制
+		return gs.server.renameCommand(gmUserID, database.NameFieldNickname, args)
+
+	case "rename_username":
+		// 修改玩家用户名，不受冷却时间限制
+		return gs.server.renameCommand(gmUserID, database.NameFieldUsername, args)
+
+	case "name_history":
+		// 查看玩家的昵称和用户名修改记录
+		return gs.server.nameHistoryCommand(args)
+
+	case "name_search":
+		// 按当前名称或曾用名查找玩家
+		return gs.server.nameSearchCommand(args)
+
+	case "quota":
+		// 玩家当天各操作的配额用量和重置时间
+		return gs.server.quotaCommand(args)
+
+	case "vip":
+		// 查看或设置玩家的VIP等级: vip <玩家ID> [等级] [时长]
+		return gs.server.vipCommand(gmUserID, args)
+
+	case "trigger_grants":
+		// 玩家最近的生命周期奖励发放记录: trigger_grants <玩家ID>
+		if len(args) != 1 {
+			return "", fmt.Errorf("trigger_grants命令需要玩家ID参数")
+		}
+		userID, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("无效的玩家ID: %s", args[0])
+		}
+		grants, err := gs.server.triggers.Grants(userID, 50)
+		if err != nil {
+			return "", err
+		}
+		data, err := json.Marshal(grants)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+
+	case "room_replay":
+		// 按事件日志重建房间在某个事件后的状态: room_replay <房间ID> [事件位置|start] [玩法] [规则=值...]
+		if len(args) < 1 {
+			return "", fmt.Errorf("room_replay命令需要房间ID参数")
+		}
+		roomID, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("无效的房间ID: %s", args[0])
+		}
+		at, gameType, rules := "", "card_game", []string(nil)
+		if len(args) > 1 {
+			at = args[1]
+		}
+		if len(args) > 2 {
+			gameType, rules = args[2], args[3:]
+		}
+		data, err := gs.server.roomReplayJSON(roomID, at, gameType, rules)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+
+	case "room_diff":
+		// 对比两次运行的房间事件，给出第一个分歧点: room_diff <房间ID> <房间ID> [玩法] [规则=值...]
+		if len(args) < 2 {
+			return "", fmt.Errorf("room_diff命令需要两个房间ID参数")
+		}
+		roomIDs := make([]uint64, 2)
+		for i := range roomIDs {
+			roomID, err := strconv.ParseUint(args[i], 10, 64)
+			if err != nil {
+				return "", fmt.Errorf("无效的房间ID: %s", args[i])
+			}
+			roomIDs[i] = roomID
+		}
+		gameType, rules := "card_game", []string(nil)
+		if len(args) > 2 {
+			gameType, rules = args[2], args[3:]
+		}
+		data, err := gs.server.roomDiffJSON(roomIDs[0], roomIDs[1], gameType, rules)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+
+	case "desync_reports":
+		// 最近的状态不一致报告，包含双方视角的状态: desync_reports [房间ID]
+		var roomID uint64
+		if len(args) > 0 {
+			id, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return "", fmt.Errorf("无效的房间ID: %s", args[0])
+			}
+			roomID = id
+		}
+		reports, err := gs.server.desyncRepo.ListReports(roomID, 20)
+		if err != nil {
+			return "", err
+		}
+		data, err := json.Marshal(reports)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
 
 	case "status":
-		// 获取服务器状态
-		return fmt.Sprintf("服务器运行正常，当前时间: %s", time.Now().Format("2006-01-02 15:04:05")), nil
+		// 获取服务器状态和集群实时计数
+		data, err := gs.server.realtimeStatsJSON()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("服务器运行正常，当前时间: %s\n%s", time.Now().Format("2006-01-02 15:04:05"), data), nil
 
 	default:
 		return "", fmt.Errorf("未知命令: %s", command)
@@ -222,10 +1038,7 @@ func (gs *GMService) KickUser(ctx context.Context, req *proto.KickUserRequest) (
 	// 验证GM权限
 	gmUserID := ctx.Value("user_id")
 	if gmUserID == nil {
-		return &proto.CommonResponse{
-			Code:    1001,
-			Message: "用户未登录",
-		}, nil
+		return gs.responses.CommonError(ctx, errcode.NotLoggedIn), nil
 	}
 
 	gmID := gmUserID.(uint64)
@@ -238,18 +1051,12 @@ func (gs *GMService) KickUser(ctx context.Context, req *proto.KickUserRequest) (
 
 	// 验证目标用户ID
 	if kickReq.TargetUserId == 0 {
-		return &proto.CommonResponse{
-			Code:    1002,
-			Message: "目标用户ID不能为空",
-		}, nil
+		return gs.responses.CommonError(ctx, errcode.TargetUserRequired), nil
 	}
 
 	// 不能踢出自己
 	if kickReq.TargetUserId == gmID {
-		return &proto.CommonResponse{
-			Code:    1003,
-			Message: "不能踢出自己",
-		}, nil
+		return gs.responses.CommonError(ctx, errcode.CannotTargetSelf), nil
 	}
 
 	// TODO: 检查用户是否存在
@@ -273,11 +1080,7 @@ func (gs *GMService) KickUser(ctx context.Context, req *proto.KickUserRequest) (
 
 	log.Printf("GM用户 %d 踢出用户 %d 成功，原因: %s", gmID, kickReq.TargetUserId, reason)
 
-	return &proto.CommonResponse{
-		Code:    0,
-		Message: "用户踢出成功",
-		Data:    []byte(fmt.Sprintf("{\"target_user_id\":%d,\"reason\":\"%s\"}", kickReq.TargetUserId, reason)),
-	}, nil
+	return gs.responses.CommonSuccess(ctx, "用户踢出成功", []byte(fmt.Sprintf("{\"target_user_id\":%d,\"reason\":\"%s\"}", kickReq.TargetUserId, reason))), nil
 }
 
 // BanUser 封禁用户
@@ -285,10 +1088,7 @@ func (gs *GMService) BanUser(ctx context.Context, req *proto.BanUserRequest) (*p
 	// 验证GM权限
 	gmUserID := ctx.Value("user_id")
 	if gmUserID == nil {
-		return &proto.CommonResponse{
-			Code:    1001,
-			Message: "用户未登录",
-		}, nil
+		return gs.responses.CommonError(ctx, errcode.NotLoggedIn), nil
 	}
 
 	gmID := gmUserID.(uint64)
@@ -301,18 +1101,12 @@ func (gs *GMService) BanUser(ctx context.Context, req *proto.BanUserRequest) (*p
 
 	// 验证目标用户ID
 	if banReq.TargetUserId == 0 {
-		return &proto.CommonResponse{
-			Code:    1002,
-			Message: "目标用户ID不能为空",
-		}, nil
+		return gs.responses.CommonError(ctx, errcode.TargetUserRequired), nil
 	}
 
 	// 不能封禁自己
 	if banReq.TargetUserId == gmID {
-		return &proto.CommonResponse{
-			Code:    1003,
-			Message: "不能封禁自己",
-		}, nil
+		return gs.responses.CommonError(ctx, errcode.CannotTargetSelf), nil
 	}
 
 	// TODO: 检查用户是否存在
@@ -334,26 +1128,17 @@ func (gs *GMService) BanUser(ctx context.Context, req *proto.BanUserRequest) (*p
 	banned, _, err := gs.server.gmRepo.IsUserBanned(banReq.TargetUserId)
 	if err != nil {
 		log.Printf("检查用户封禁状态失败: %v", err)
-		return &proto.CommonResponse{
-			Code:    1005,
-			Message: "检查用户状态失败",
-		}, nil
+		return gs.responses.CommonError(ctx, errcode.UserStatusCheckFailed), nil
 	}
 
 	if banned {
-		return &proto.CommonResponse{
-			Code:    1006,
-			Message: "用户已被封禁",
-		}, nil
+		return gs.responses.CommonError(ctx, errcode.UserAlreadyBanned), nil
 	}
 
 	// 封禁用户
 	if err := gs.server.gmRepo.BanUser(banReq.TargetUserId, gmID, reason, duration); err != nil {
 		log.Printf("封禁用户失败: %v", err)
-		return &proto.CommonResponse{
-			Code:    1007,
-			Message: "封禁用户失败",
-		}, nil
+		return gs.responses.CommonError(ctx, errcode.BanUserFailed), nil
 	}
 
 	// TODO: 实现向用户发送封禁消息
@@ -368,11 +1153,7 @@ func (gs *GMService) BanUser(ctx context.Context, req *proto.BanUserRequest) (*p
 
 	log.Printf("GM用户 %d 封禁用户 %d 成功，时长: %d秒，原因: %s", gmID, banReq.TargetUserId, duration, reason)
 
-	return &proto.CommonResponse{
-		Code:    0,
-		Message: "用户封禁成功",
-		Data:    []byte(fmt.Sprintf("{\"target_user_id\":%d,\"duration\":%d,\"reason\":\"%s\"}", banReq.TargetUserId, duration, reason)),
-	}, nil
+	return gs.responses.CommonSuccess(ctx, "用户封禁成功", []byte(fmt.Sprintf("{\"target_user_id\":%d,\"duration\":%d,\"reason\":\"%s\"}", banReq.TargetUserId, duration, reason))), nil
 }
 
 // UnbanUser 解封用户
@@ -380,10 +1161,7 @@ func (gs *GMService) UnbanUser(ctx context.Context, req *proto.UnbanUserRequest)
 	// 验证GM权限
 	gmUserID := ctx.Value("user_id")
 	if gmUserID == nil {
-		return &proto.CommonResponse{
-			Code:    1001,
-			Message: "用户未登录",
-		}, nil
+		return gs.responses.CommonError(ctx, errcode.NotLoggedIn), nil
 	}
 
 	gmID := gmUserID.(uint64)
@@ -396,10 +1174,7 @@ func (gs *GMService) UnbanUser(ctx context.Context, req *proto.UnbanUserRequest)
 
 	// 验证目标用户ID
 	if unbanReq.TargetUserId == 0 {
-		return &proto.CommonResponse{
-			Code:    1002,
-			Message: "目标用户ID不能为空",
-		}, nil
+		return gs.responses.CommonError(ctx, errcode.TargetUserRequired), nil
 	}
 
 	// TODO: 检查用户是否存在
@@ -409,26 +1184,17 @@ func (gs *GMService) UnbanUser(ctx context.Context, req *proto.UnbanUserRequest)
 	banned, banRecord, err := gs.server.gmRepo.IsUserBanned(unbanReq.TargetUserId)
 	if err != nil {
 		log.Printf("检查用户封禁状态失败: %v", err)
-		return &proto.CommonResponse{
-			Code:    1004,
-			Message: "检查用户状态失败",
-		}, nil
+		return gs.responses.CommonError(ctx, errcode.UserStatusCheckFailed), nil
 	}
 
 	if !banned {
-		return &proto.CommonResponse{
-			Code:    1005,
-			Message: "用户未被封禁",
-		}, nil
+		return gs.responses.CommonError(ctx, errcode.UserNotBanned), nil
 	}
 
 	// 解封用户
 	if err := gs.server.gmRepo.UnbanUser(unbanReq.TargetUserId, gmID); err != nil {
 		log.Printf("解封用户失败: %v", err)
-		return &proto.CommonResponse{
-			Code:    1006,
-			Message: "解封用户失败",
-		}, nil
+		return gs.responses.CommonError(ctx, errcode.UnbanUserFailed), nil
 	}
 
 	// 记录GM操作日志
@@ -437,11 +1203,7 @@ func (gs *GMService) UnbanUser(ctx context.Context, req *proto.UnbanUserRequest)
 
 	log.Printf("GM用户 %d 解封用户 %d 成功", gmID, unbanReq.TargetUserId)
 
-	return &proto.CommonResponse{
-		Code:    0,
-		Message: "用户解封成功",
-		Data:    []byte(fmt.Sprintf("{\"target_user_id\":%d}", unbanReq.TargetUserId)),
-	}, nil
+	return gs.responses.CommonSuccess(ctx, "用户解封成功", []byte(fmt.Sprintf("{\"target_user_id\":%d}", unbanReq.TargetUserId))), nil
 }
 
 // SendNotice 发送公告
@@ -449,10 +1211,7 @@ func (gs *GMService) SendNotice(ctx context.Context, req *proto.SendNoticeReques
 	// 验证GM权限
 	gmUserID := ctx.Value("user_id")
 	if gmUserID == nil {
-		return &proto.CommonResponse{
-			Code:    1001,
-			Message: "用户未登录",
-		}, nil
+		return gs.responses.CommonError(ctx, errcode.NotLoggedIn), nil
 	}
 
 	gmID := gmUserID.(uint64)
@@ -465,69 +1224,191 @@ func (gs *GMService) SendNotice(ctx context.Context, req *proto.SendNoticeReques
 
 	// 验证公告标题
 	if noticeReq.Title == "" {
-		return &proto.CommonResponse{
-			Code:    1002,
-			Message: "公告标题不能为空",
-		}, nil
+		return gs.responses.CommonError(ctx, errcode.AnnouncementTitleRequired), nil
 	}
 
 	// 验证公告内容
 	if noticeReq.Content == "" {
-		return &proto.CommonResponse{
-			Code:    1003,
-			Message: "公告内容不能为空",
-		}, nil
+		return gs.responses.CommonError(ctx, errcode.AnnouncementContentRequired), nil
 	}
 
-	// 构造公告消息
-	noticeMsg := map[string]interface{}{
-		"title":      noticeReq.Title,
-		"content":    noticeReq.Content,
-		"notice_type": noticeReq.NoticeType,
-		"send_time":  time.Now().Unix(),
-	}
-
-	var targetCount int
-
-	// 根据目标用户发送公告
+	// 指定用户时发送定向公告，离线玩家在下次登录时收到
 	if len(noticeReq.TargetUsers) > 0 {
-		// 发送给指定用户
+		dedupKey := fmt.Sprintf("notice:%d", time.Now().UnixNano())
+		targetCount := 0
 		for _, userID := range noticeReq.TargetUsers {
-			// TODO: 实现获取用户信息
-			logger.Debug(fmt.Sprintf("Getting user info for ID: %d", userID))
-			// TODO: 实现向用户发送公告
-			logger.Info(fmt.Sprintf("Sending announcement to user %d: %v", userID, noticeMsg))
+			if err := gs.server.notifier.Notify(&database.Notification{
+				UserID:   userID,
+				Type:     NotifyNotice,
+				DedupKey: dedupKey,
+				Data:     map[string]interface{}{"title": noticeReq.Title, "content": noticeReq.Content},
+			}); err != nil {
+				logger.Warn(fmt.Sprintf("Failed to send notice to user %d: %v", userID, err))
+				continue
+			}
 			targetCount++
 		}
-	} else {
-		// TODO: 实现全服公告
-		logger.Info(fmt.Sprintf("Broadcasting announcement: %v", noticeMsg))
-		targetCount = -1 // -1表示全服
+
+		gs.server.gmRepo.LogGMAction(gmID, "send_notice", 0,
+			fmt.Sprintf("发送定向公告给 %d 个用户，标题: %s，内容: %s", targetCount, noticeReq.Title, noticeReq.Content))
+		log.Printf("GM用户 %d 发送公告成功，目标用户数: %d", gmID, targetCount)
+
+		return gs.responses.CommonSuccess(ctx, fmt.Sprintf("公告发送成功，目标用户数: %d", targetCount),
+			[]byte(fmt.Sprintf("{\"target_count\":%d,\"title\":\"%s\"}", targetCount, noticeReq.Title))), nil
+	}
+
+	// 全服公告保存为跑马灯公告，推送给在线玩家，之后登录的玩家也能获取
+	notice, err := gs.server.sendNotice(gmID, noticeReq.Title, noticeReq.Content, noticeReq.NoticeType)
+	if err != nil {
+		return gs.responses.CommonError(ctx, err), nil
+	}
+	log.Printf("GM用户 %d 发送全服公告成功: %s", gmID, notice.ID.Hex())
+
+	return gs.responses.CommonSuccess(ctx, "全服公告发送成功",
+		[]byte(fmt.Sprintf("{\"target_count\":-1,\"title\":\"%s\",\"announcement_id\":\"%s\"}", noticeReq.Title, notice.ID.Hex()))), nil
+}
+
+// ExportUserData 导出用户数据
+func (gs *GMService) ExportUserData(ctx context.Context, req *proto.UserDataRequest) (*proto.CommonResponse, error) {
+	// 验证GM权限
+	gmUserID := ctx.Value("user_id")
+	if gmUserID == nil {
+		return gs.responses.CommonError(ctx, errcode.NotLoggedIn), nil
+	}
+
+	gmID := gmUserID.(uint64)
+
+	// 验证目标用户ID
+	if req.TargetUserId == 0 {
+		return gs.responses.CommonError(ctx, errcode.TargetUserRequired), nil
+	}
+
+	archivePath, err := gs.server.privacy.ExportUserData(req.TargetUserId, gmID, req.Reason)
+	if err != nil {
+		log.Printf("导出用户数据失败: %v", err)
+		return gs.responses.CommonError(ctx, errcode.ExportUserDataFailed), nil
 	}
 
 	// 记录GM操作日志
-	var details string
-	if targetCount == -1 {
-		details = fmt.Sprintf("发送全服公告，标题: %s，内容: %s", noticeReq.Title, noticeReq.Content)
-	} else {
-		details = fmt.Sprintf("发送定向公告给 %d 个用户，标题: %s，内容: %s", targetCount, noticeReq.Title, noticeReq.Content)
+	details := fmt.Sprintf("导出用户 %d 数据，归档: %s，原因: %s", req.TargetUserId, archivePath, req.Reason)
+	gs.server.gmRepo.LogGMAction(gmID, "export_user_data", req.TargetUserId, details)
+
+	log.Printf("GM用户 %d 导出用户 %d 数据成功", gmID, req.TargetUserId)
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"target_user_id": req.TargetUserId,
+		"archive_path":   archivePath,
+	})
+
+	return gs.responses.CommonSuccess(ctx, "用户数据导出成功", data), nil
+}
+
+// RollbackGrant 按批次回滚奖励发放，冲正账本流水并撤回未领取的邮件附件，返回每个玩家的处理结果
+func (gs *GMService) RollbackGrant(ctx context.Context, req *proto.RollbackGrantRequest) (*proto.CommonResponse, error) {
+	// 验证GM权限
+	gmUserID := ctx.Value("user_id")
+	if gmUserID == nil {
+		return gs.responses.CommonError(ctx, errcode.NotLoggedIn), nil
 	}
-	gs.server.gmRepo.LogGMAction(gmID, "send_notice", 0, details)
 
-	log.Printf("GM用户 %d 发送公告成功，目标用户数: %d", gmID, targetCount)
+	gmID := gmUserID.(uint64)
+
+	if req.BatchId == "" {
+		return gs.responses.CommonError(ctx, errcode.BatchIDRequired), nil
+	}
 
-	var resultMsg string
-	if targetCount == -1 {
-		resultMsg = "全服公告发送成功"
-	} else {
-		resultMsg = fmt.Sprintf("公告发送成功，目标用户数: %d", targetCount)
+	report, err := gs.server.rollback.Run(req.BatchId, req.Reason, req.DryRun, !req.SkipNotify)
+	if err != nil {
+		log.Printf("回滚奖励发放失败: %v", err)
+		return gs.responses.CommonError(ctx, errcode.RollbackGrantFailed.WithDetail(err.Error())), nil
+	}
+
+	// 预览不修改数据，不记录操作日志
+	if !req.DryRun {
+		details := fmt.Sprintf("回滚批次 %s，涉及 %d 名玩家，冲正 %v，撤回邮件 %d 封，失败 %d 项，原因: %s",
+			req.BatchId, len(report.Users), report.Reversed, report.RevokedMails, report.Failures, req.Reason)
+		gs.server.gmRepo.LogGMAction(gmID, "rollback_grant", 0, details)
+		log.Printf("GM用户 %d 回滚批次 %s 完成", gmID, req.BatchId)
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return gs.responses.CommonError(ctx, errcode.Internal), nil
+	}
+
+	return gs.responses.CommonSuccess(ctx, "奖励发放回滚完成", data), nil
+}
+
+// RolloutConfig 分阶段更新配置
+//
+// 所有在线节点先校验各自的配置文件并返回差异，全部通过后提交到请求中的节点，未指定节点时提交到所有节点
+func (gs *GMService) RolloutConfig(ctx context.Context, req *proto.ConfigRolloutRequest) (*proto.CommonResponse, error) {
+	// 验证GM权限
+	gmUserID := ctx.Value("user_id")
+	if gmUserID == nil {
+		return gs.responses.CommonError(ctx, errcode.NotLoggedIn), nil
+	}
+
+	gmID := gmUserID.(uint64)
+
+	report, err := gs.server.runConfigRollout(ConfigRolloutOptions{
+		Targets: req.Nodes,
+		DryRun:  req.DryRun,
+	})
+	if err != nil {
+		log.Printf("配置更新失败: %v", err)
+		return gs.responses.CommonError(ctx, errcode.ConfigRolloutFailed.WithDetail(err.Error())), nil
 	}
 
-	return &proto.CommonResponse{
-		Code:    0,
-		Message: resultMsg,
-		Data:    []byte(fmt.Sprintf("{\"target_count\":%d,\"title\":\"%s\"}", targetCount, noticeReq.Title)),
-	}, nil
+	gs.server.gmRepo.LogGMAction(gmID, "config_rollout", 0,
+		fmt.Sprintf("更新: %s, 预览: %v, 目标节点: %v, 中止: %v", report.ID, req.DryRun, report.Targets, report.Aborted))
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return gs.responses.CommonError(ctx, errcode.Internal), nil
+	}
+
+	if report.Aborted {
+		resp := gs.responses.CommonError(ctx, errcode.ConfigRolloutAborted.WithDetail(report.Reason))
+		resp.Data = data
+		return resp, nil
+	}
+	return gs.responses.CommonSuccess(ctx, "配置更新完成", data), nil
+}
+
+// DeleteUserData 删除用户数据
+func (gs *GMService) DeleteUserData(ctx context.Context, req *proto.UserDataRequest) (*proto.CommonResponse, error) {
+	// 验证GM权限
+	gmUserID := ctx.Value("user_id")
+	if gmUserID == nil {
+		return gs.responses.CommonError(ctx, errcode.NotLoggedIn), nil
+	}
+
+	gmID := gmUserID.(uint64)
+
+	// 验证目标用户ID
+	if req.TargetUserId == 0 {
+		return gs.responses.CommonError(ctx, errcode.TargetUserRequired), nil
+	}
+
+	affected, err := gs.server.privacy.DeleteUserData(req.TargetUserId, gmID, req.Reason)
+	if err != nil {
+		log.Printf("删除用户数据失败: %v", err)
+		return gs.responses.CommonError(ctx, errcode.DeleteUserDataFailed), nil
+	}
+
+	// 记录GM操作日志
+	details := fmt.Sprintf("删除用户 %d 数据，影响: %v，原因: %s", req.TargetUserId, affected, req.Reason)
+	gs.server.gmRepo.LogGMAction(gmID, "delete_user_data", req.TargetUserId, details)
+
+	log.Printf("GM用户 %d 删除用户 %d 数据成功", gmID, req.TargetUserId)
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"target_user_id": req.TargetUserId,
+		"affected":       affected,
+	})
+
+	return gs.responses.CommonSuccess(ctx, "用户数据删除成功", data), nil
 }
 
 // ReloadConfig 重新加载配置
@@ -535,9 +1416,5 @@ func (gs *GMService) ReloadConfig(ctx context.Context, req *proto.BaseRequest) (
 	// 广播配置重载命令
 	gs.server.messageBroker.BroadcastSystemMessage("reload_config", nil)
 
-	return &proto.BaseResponse{
-		Header: req.Header,
-		Code:   0,
-		Msg:    "config reload requested",
-	}, nil
+	return gs.responses.Success(ctx, req.Header, "config reload requested", nil), nil
 }
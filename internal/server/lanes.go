@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	protobuf "github.com/golang/protobuf/proto"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/errcode"
+	"github.com/phuhao00/lufy/internal/lane"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/rpc"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// laneInterceptor 按玩家串行执行rpc.lanes.methods中配置的方法，节点内排队后再获取Redis中的玩家锁，
+// 排队已满或等待超时时返回ActionInProgress，请求不带玩家ID的调用（如GM和节点间调用）不受影响
+func (bs *BaseServer) laneInterceptor() rpc.Interceptor {
+	responses := errcode.NewBuilder(nil)
+	return func(ctx context.Context, call *rpc.CallInfo, next rpc.Handler) (protobuf.Message, error) {
+		req, ok := call.Request.(*proto.BaseRequest)
+		if !ok || req.GetHeader().GetUserId() == 0 || !bs.lanes.Serialized(call.Service, call.Method) {
+			return next(ctx, call)
+		}
+
+		userID := req.GetHeader().GetUserId()
+		var resp protobuf.Message
+		var err error
+		if laneErr := bs.lanes.Run(userID, call.Service+"."+call.Method, func() {
+			resp, err = next(ctx, call)
+		}); laneErr != nil {
+			logger.Warn(fmt.Sprintf("Rejected %s.%s of user %d: %v", call.Service, call.Method, userID, laneErr))
+			return responses.Error(ctx, req.Header, errcode.ActionInProgress), nil
+		}
+		return resp, err
+	}
+}
+
+// 获取玩家锁的重试间隔，从最小值开始翻倍
+const (
+	userLockMinRetry = 10 * time.Millisecond
+	userLockMaxRetry = 100 * time.Millisecond
+)
+
+// userLocker 基于Redis的跨节点玩家锁，网关按世界或区域选择大厅节点，同一玩家的请求可能同时到达多个节点
+type userLocker struct {
+	redis   *database.RedisManager
+	nodeID  string
+	counter uint64
+}
+
+// newUserLocker 创建跨节点玩家锁
+func newUserLocker(redis *database.RedisManager, nodeID string) *userLocker {
+	return &userLocker{redis: redis, nodeID: nodeID}
+}
+
+// Lock 实现lane.Locker接口，锁被其他请求持有时重试到deadline
+func (ul *userLocker) Lock(userID uint64, ttl time.Duration, deadline time.Time) (func(), error) {
+	key := fmt.Sprintf("user_lane:%d", userID)
+	owner := fmt.Sprintf("%s:%d", ul.nodeID, atomic.AddUint64(&ul.counter, 1))
+
+	retry := userLockMinRetry
+	for {
+		locked, err := ul.redis.LockOwned(key, owner, ttl)
+		if err != nil {
+			logger.Warn(fmt.Sprintf("Failed to lock user %d across nodes, running without lock: %v", userID, err))
+			return nil, err
+		}
+		if locked {
+			return func() {
+				if err := ul.redis.UnlockOwned(key, owner); err != nil {
+					logger.Warn(fmt.Sprintf("Failed to unlock user %d: %v", userID, err))
+				}
+			}, nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, lane.ErrTimeout
+		}
+		if retry > remaining {
+			retry = remaining
+		}
+		time.Sleep(retry)
+		if retry *= 2; retry > userLockMaxRetry {
+			retry = userLockMaxRetry
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/crash"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/monitoring"
+)
+
+// 生命周期钩子的顺序，启动时从小到大执行，停止时从大到小执行，同一顺序内按注册先后启动、倒序停止
+const (
+	OrderMonitoring    = 100 // 指标导出，最先启动、最后停止，停止过程中仍可采集指标
+	OrderSubscriptions = 200 // 消息订阅
+	OrderListeners     = 300 // 对外监听端口，如网关的TCP和KCP服务、管理控制台
+	OrderBackground    = 400 // 后台任务，最后启动、最先停止
+)
+
+// 钩子默认时限
+const (
+	defaultStartHookTimeout = 30 * time.Second
+	defaultStopHookTimeout  = 10 * time.Second
+)
+
+// LifecycleConfig 生命周期钩子的时限
+type LifecycleConfig struct {
+	StartTimeout time.Duration            `yaml:"start_timeout"` // 单个启动钩子的时限，超时视为启动失败，未配置时为30秒
+	StopTimeout  time.Duration            `yaml:"stop_timeout"`  // 单个停止钩子的时限，超时后不再等待，继续停止其他组件，未配置时为10秒
+	Hooks        map[string]time.Duration `yaml:"hooks"`         // 按钩子名称覆盖启动和停止的时限
+}
+
+// timeout 钩子的时限
+func (c *LifecycleConfig) timeout(name string, fallback, defaultTimeout time.Duration) time.Duration {
+	if timeout, exists := c.Hooks[name]; exists && timeout > 0 {
+		return timeout
+	}
+	if fallback > 0 {
+		return fallback
+	}
+	return defaultTimeout
+}
+
+// HookFunc 生命周期钩子，ctx在钩子的时限到达时取消
+type HookFunc func(ctx context.Context) error
+
+// lifecycleHook 注册的钩子
+type lifecycleHook struct {
+	name  string
+	order int
+	fn    HookFunc
+}
+
+// lifecycle 节点组件的启动和停止钩子。同名的启动和停止钩子属于同一组件，
+// 启动钩子未成功执行的组件不执行停止钩子；没有启动钩子的停止钩子总是执行
+type lifecycle struct {
+	starts  []lifecycleHook
+	stops   []lifecycleHook
+	started map[string]bool // 已成功启动的组件
+	stopped bool
+	mutex   sync.Mutex
+}
+
+// RegisterOnStart 注册启动钩子，需在Start前调用。钩子在RPC服务启动、节点注册后按顺序执行，
+// 任一钩子失败或超时时Start返回错误，已启动的组件按相反顺序停止
+func (bs *BaseServer) RegisterOnStart(name string, order int, fn HookFunc) {
+	bs.lifecycle.mutex.Lock()
+	defer bs.lifecycle.mutex.Unlock()
+
+	bs.lifecycle.starts = append(bs.lifecycle.starts, lifecycleHook{name: name, order: order, fn: fn})
+}
+
+// RegisterOnStop 注册停止钩子，停止钩子在RPC服务和数据库连接关闭前按相反顺序执行，失败或超时只记录日志
+func (bs *BaseServer) RegisterOnStop(name string, order int, fn HookFunc) {
+	bs.lifecycle.mutex.Lock()
+	defer bs.lifecycle.mutex.Unlock()
+
+	bs.lifecycle.stops = append(bs.lifecycle.stops, lifecycleHook{name: name, order: order, fn: fn})
+}
+
+// RegisterBackground 注册后台任务，节点启动后在单独的goroutine中运行，停止时取消ctx并等待任务返回
+func (bs *BaseServer) RegisterBackground(name string, fn func(ctx context.Context)) {
+	var cancel context.CancelFunc
+	done := make(chan struct{})
+
+	bs.RegisterOnStart(name, OrderBackground, func(context.Context) error {
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(bs.ctx)
+		go func() {
+			defer close(done)
+			fn(ctx)
+		}()
+		return nil
+	})
+	bs.RegisterOnStop(name, OrderBackground, func(ctx context.Context) error {
+		cancel()
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// initNodeMonitoring 创建节点的指标导出，所有节点都导出MongoDB操作和按玩家串行执行的排队指标
+func (bs *BaseServer) initNodeMonitoring() error {
+	manager, err := monitoring.NewMonitoringManager(bs.nodeID, bs.nodeType, bs.config.Network.HTTPPort)
+	if err != nil {
+		return fmt.Errorf("failed to init monitoring manager: %v", err)
+	}
+	manager.SetRegion(bs.config.Geo.Region)
+	bs.crashReporter.OnPanic(func(report *crash.Report) {
+		manager.RecordPanic(report.Source)
+	})
+	if err := manager.Register(bs.mongoManager.Metrics()); err != nil {
+		return fmt.Errorf("failed to register mongodb metrics: %v", err)
+	}
+	if err := manager.Register(bs.lanes); err != nil {
+		return fmt.Errorf("failed to register lane metrics: %v", err)
+	}
+	bs.nodeMonitoring = manager
+	bs.registerMonitoring(manager)
+	return nil
+}
+
+// registerMonitoring 随节点启动和停止指标导出，启动失败只记录日志，不影响节点启动
+func (bs *BaseServer) registerMonitoring(manager *monitoring.MonitoringManager) {
+	bs.RegisterOnStart("monitoring", OrderMonitoring, func(ctx context.Context) error {
+		if err := manager.Start(); err != nil {
+			logger.Error(fmt.Sprintf("Failed to start monitoring: %v", err))
+		}
+		return nil
+	})
+	bs.RegisterOnStop("monitoring", OrderMonitoring, func(ctx context.Context) error {
+		return manager.Stop()
+	})
+}
+
+// runStartHooks 按顺序执行启动钩子
+func (bs *BaseServer) runStartHooks() error {
+	bs.lifecycle.mutex.Lock()
+	hooks := sortedHooks(bs.lifecycle.starts, false)
+	bs.lifecycle.started = make(map[string]bool, len(hooks))
+	bs.lifecycle.mutex.Unlock()
+
+	config := &bs.config.Lifecycle
+	for _, hook := range hooks {
+		timeout := config.timeout(hook.name, config.StartTimeout, defaultStartHookTimeout)
+		start := time.Now()
+		if err := runHook(bs.ctx, hook, timeout); err != nil {
+			return fmt.Errorf("failed to start %s: %v", hook.name, err)
+		}
+		logger.Debug(fmt.Sprintf("Started %s in %v", hook.name, time.Since(start)))
+
+		bs.lifecycle.mutex.Lock()
+		bs.lifecycle.started[hook.name] = true
+		bs.lifecycle.mutex.Unlock()
+	}
+	return nil
+}
+
+// runStopHooks 按相反顺序执行停止钩子，只执行一次
+func (bs *BaseServer) runStopHooks() {
+	bs.lifecycle.mutex.Lock()
+	if bs.lifecycle.stopped {
+		bs.lifecycle.mutex.Unlock()
+		return
+	}
+	bs.lifecycle.stopped = true
+	components := make(map[string]bool, len(bs.lifecycle.starts))
+	for _, hook := range bs.lifecycle.starts {
+		components[hook.name] = true
+	}
+	var hooks []lifecycleHook
+	for _, hook := range sortedHooks(bs.lifecycle.stops, true) {
+		if components[hook.name] && !bs.lifecycle.started[hook.name] {
+			continue
+		}
+		hooks = append(hooks, hook)
+	}
+	bs.lifecycle.mutex.Unlock()
+
+	config := &bs.config.Lifecycle
+	for _, hook := range hooks {
+		timeout := config.timeout(hook.name, config.StopTimeout, defaultStopHookTimeout)
+		if err := runHook(context.Background(), hook, timeout); err != nil {
+			logger.Warn(fmt.Sprintf("Failed to stop %s: %v", hook.name, err))
+		}
+	}
+}
+
+// runHook 在时限内执行钩子，超时后不再等待，钩子在ctx取消后自行结束
+func runHook(parent context.Context, hook lifecycleHook, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- hook.fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out after %v", timeout)
+	}
+}
+
+// sortedHooks 按顺序排列钩子，reverse为true时倒序，同一顺序内保持注册顺序或其倒序
+func sortedHooks(hooks []lifecycleHook, reverse bool) []lifecycleHook {
+	sorted := make([]lifecycleHook, len(hooks))
+	copy(sorted, hooks)
+	if reverse {
+		for i, j := 0, len(sorted)-1; i < j; i, j = i+1, j-1 {
+			sorted[i], sorted[j] = sorted[j], sorted[i]
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if reverse {
+			return sorted[i].order > sorted[j].order
+		}
+		return sorted[i].order < sorted[j].order
+	})
+	return sorted
+}
@@ -2,22 +2,61 @@ package server
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"reflect"
 	"sync"
 	"time"
 
+	"github.com/phuhao00/lufy/internal/announcement"
 	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/denorm"
+	"github.com/phuhao00/lufy/internal/errcode"
+	"github.com/phuhao00/lufy/internal/exchange"
 	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/matchmaking"
+	"github.com/phuhao00/lufy/internal/mq"
+	"github.com/phuhao00/lufy/internal/naming"
+	"github.com/phuhao00/lufy/internal/party"
+	"github.com/phuhao00/lufy/internal/progression"
+	"github.com/phuhao00/lufy/internal/quota"
+	"github.com/phuhao00/lufy/internal/realtime"
+	"github.com/phuhao00/lufy/internal/redeem"
+	"github.com/phuhao00/lufy/internal/search"
+	"github.com/phuhao00/lufy/internal/security"
+	"github.com/phuhao00/lufy/internal/segment"
+	"github.com/phuhao00/lufy/internal/settings"
+	"github.com/phuhao00/lufy/internal/support"
+	"github.com/phuhao00/lufy/internal/survey"
+	"github.com/phuhao00/lufy/internal/timeline"
+	"github.com/phuhao00/lufy/internal/world"
 	"github.com/phuhao00/lufy/pkg/proto"
 )
 
 // LobbyServer 游戏大厅服务器
 type LobbyServer struct {
 	*BaseServer
-	roomRepo   *database.RoomRepository
-	nextRoomID uint64
-	idMutex    sync.Mutex
+	roomRepo      *database.RoomRepository
+	progression   *progression.Service
+	exchange      *exchange.Service
+	segments      *segment.Service
+	announcements *announcement.Service
+	surveys       *survey.Service
+	support       *support.Service
+	redeem        *redeem.Service
+	matchmaker    *matchmaking.Matchmaker
+	matchResults  map[uint64]*matchResult
+	matchMutex    sync.Mutex
+	parties       *party.Manager
+	notifier      *Notifier
+	settings      *settings.Service
+	naming        *naming.Service
+	search        *search.Service
+	quotas        *quota.Service
+	roomGuard     *security.RoomGuard
+	timeline      *timeline.Recorder
+	nextRoomID    uint64
+	idMutex       sync.Mutex
 }
 
 // NewLobbyServer 创建游戏大厅服务器
@@ -28,10 +67,32 @@ func NewLobbyServer(configFile, nodeID string) *LobbyServer {
 	}
 
 	lobbyServer := &LobbyServer{
-		BaseServer: baseServer,
-		roomRepo:   database.NewRoomRepository(baseServer.mongoManager),
-		nextRoomID: 1000, // 房间ID从1000开始
-	}
+		BaseServer:  baseServer,
+		roomRepo:    database.NewRoomRepository(baseServer.mongoManager),
+		progression: baseServer.newProgression(),
+		exchange: exchange.NewService(&baseServer.config.Exchange,
+			database.NewLedgerRepository(baseServer.mongoManager), baseServer.redisManager, nodeID, "lobby"),
+		segments:      baseServer.newSegments(),
+		announcements: baseServer.newAnnouncements(),
+		matchResults:  make(map[uint64]*matchResult),
+		parties:       party.NewManager(&baseServer.config.Party),
+		notifier:      NewNotifier(baseServer),
+		settings:      baseServer.newSettings(),
+		naming:        baseServer.newNaming(),
+		search:        baseServer.newSearch(),
+		quotas:        baseServer.newQuotas(),
+		roomGuard:     security.NewRoomGuard(&baseServer.config.Security.RoomGuard, baseServer.redisManager),
+		timeline:      baseServer.newTimeline(),
+		nextRoomID:    1000, // 房间ID从1000开始
+	}
+	lobbyServer.exchange.SetLimitResolver(baseServer.entitlements.ExchangeLimit)
+	lobbyServer.surveys = baseServer.newSurveys(lobbyServer.segments)
+	lobbyServer.support = baseServer.newSupport(nil)
+	lobbyServer.redeem = baseServer.newRedeem(lobbyServer.notifier)
+	lobbyServer.matchmaker = lobbyServer.newMatchmaker()
+	baseServer.RegisterBackground("matchmaker", lobbyServer.matchmaker.Run)
+	baseServer.RegisterBackground("party_cleanup", lobbyServer.partyCleanupLoop)
+	baseServer.systemHandler.RegisterHandler(mq.SYS_CMD_USER_RENAMED, lobbyServer.handleUserRenamed)
 
 	// 注册通用服务
 	if err := RegisterCommonServices(baseServer); err != nil {
@@ -40,10 +101,18 @@ func NewLobbyServer(configFile, nodeID string) *LobbyServer {
 
 	// 注册大厅服务
 	lobbyService := NewLobbyService(lobbyServer)
-	if err := baseServer.rpcServer.RegisterService(lobbyService); err != nil {
+	if err := RegisterLobbyService(baseServer.rpcServer, lobbyService); err != nil {
 		logger.Fatal(fmt.Sprintf("Failed to register lobby service: %v", err))
 	}
 
+	// 定期清理已结束或无人的房间
+	if err := baseServer.scheduler.Register("reconcile_realtime", fmt.Sprintf("@every %s", baseServer.realtime.Interval()), lobbyServer.reconcileRealtime); err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to register realtime reconcile job: %v", err))
+	}
+	if err := baseServer.scheduler.Register("clean_idle_rooms", "@every 10m", lobbyServer.cleanIdleRooms); err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to register room cleanup job: %v", err))
+	}
+
 	return lobbyServer
 }
 
@@ -56,46 +125,55 @@ func (ls *LobbyServer) generateRoomID() uint64 {
 	return id
 }
 
+// partyCleanupLoop 定期清理过期的队伍邀请。队伍保存在各大厅节点的内存中，
+// 不能使用只在一个节点执行的集群定时任务
+func (ls *LobbyServer) partyCleanupLoop(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ls.parties.Cleanup()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// cleanIdleRooms 删除30分钟内无变化的已结束或无人房间
+func (ls *LobbyServer) cleanIdleRooms(ctx context.Context) error {
+	deleted, err := ls.roomRepo.DeleteIdleRooms(time.Now().Add(-30 * time.Minute))
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		logger.Info(fmt.Sprintf("Cleaned %d idle rooms", deleted))
+	}
+	return nil
+}
+
 // LobbyService 大厅RPC服务
 type LobbyService struct {
-	server *LobbyServer
+	server    *LobbyServer
+	responses *errcode.Builder
 }
 
 // NewLobbyService 创建大厅服务
 func NewLobbyService(server *LobbyServer) *LobbyService {
 	return &LobbyService{
-		server: server,
+		server:    server,
+		responses: errcode.NewBuilder(nil),
 	}
 }
 
-// GetName 获取服务名称
-func (ls *LobbyService) GetName() string {
-	return "LobbyService"
-}
-
-// RegisterMethods 注册方法
-func (ls *LobbyService) RegisterMethods() map[string]reflect.Value {
-	methods := make(map[string]reflect.Value)
-
-	methods["GetRoomList"] = reflect.ValueOf(ls.GetRoomList)
-	methods["CreateRoom"] = reflect.ValueOf(ls.CreateRoom)
-	methods["JoinRoom"] = reflect.ValueOf(ls.JoinRoom)
-	methods["LeaveRoom"] = reflect.ValueOf(ls.LeaveRoom)
-
-	return methods
-}
-
 // GetRoomList 获取房间列表
 func (ls *LobbyService) GetRoomList(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
 	// 验证用户ID
 	userID := req.Header.GetUserId()
 	if userID == 0 {
 		logger.Error("GetRoomList: invalid user id")
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -1,
-			Msg:    "invalid user id",
-		}, nil
+		return ls.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
 	}
 
 	// 解析请求参数（可选）
@@ -108,20 +186,22 @@ func (ls *LobbyService) GetRoomList(ctx context.Context, req *proto.BaseRequest)
 		// 这里可以解析分页参数，简化处理
 	}
 
+	// 只列出玩家所属世界的房间
+	userRepo := database.NewUserRepository(ls.server.mongoManager)
+	user, err := userRepo.GetByUserID(userID)
+	if err != nil {
+		logger.Error(fmt.Sprintf("GetRoomList: failed to get user %d: %v", userID, err))
+		return ls.responses.Error(ctx, req.Header, errcode.UserNotFound), nil
+	}
+	worldID := ls.server.config.World.Resolve(user.WorldID)
+
 	// 获取房间列表
-	rooms, err := ls.server.roomRepo.GetRoomList(gameType, limit, offset)
+	rooms, err := ls.server.roomRepo.GetRoomList(worldID, gameType, limit, offset)
 	if err != nil {
 		logger.Error(fmt.Sprintf("GetRoomList: failed to get room list: %v", err))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -2,
-			Msg:    "failed to get room list",
-		}, nil
+		return ls.responses.Error(ctx, req.Header, errcode.RoomListFailed), nil
 	}
 
-	// 获取用户信息用于填充房间详情
-	userRepo := database.NewUserRepository(ls.server.mongoManager)
-
 	// 转换为proto格式
 	var roomInfos []*proto.RoomInfo
 	for _, room := range rooms {
@@ -147,9 +227,11 @@ func (ls *LobbyService) GetRoomList(ctx context.Context, req *proto.BaseRequest)
 				Nickname: player.Nickname,
 				Level:    player.Level,
 				Status:   player.Status,
+				PartyId:  player.PartyID,
 			}
 			players = append(players, playerInfo)
 		}
+		applyProfiles(ls.server.denorm, denorm.CollectionRooms, players)
 
 		roomInfo := &proto.RoomInfo{
 			RoomId:         room.RoomID,
@@ -162,13 +244,14 @@ func (ls *LobbyService) GetRoomList(ctx context.Context, req *proto.BaseRequest)
 			Owner:          ownerInfo,
 			Players:        players,
 			CreatedTime:    uint32(room.CreatedAt.Unix()),
+			Region:         room.Region,
 		}
 
 		roomInfos = append(roomInfos, roomInfo)
 	}
 
 	// 获取总数
-	total, err := ls.server.roomRepo.CountRooms(gameType)
+	total, err := ls.server.roomRepo.CountRooms(worldID, gameType)
 	if err != nil {
 		logger.Error(fmt.Sprintf("GetRoomList: failed to count rooms: %v", err))
 		total = int64(len(roomInfos)) // 使用当前数量作为备选
@@ -183,21 +266,12 @@ func (ls *LobbyService) GetRoomList(ctx context.Context, req *proto.BaseRequest)
 	responseData, err := proto.Marshal(roomListResp)
 	if err != nil {
 		logger.Error(fmt.Sprintf("GetRoomList: failed to marshal response: %v", err))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -3,
-			Msg:    "failed to create response",
-		}, nil
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
 	}
 
 	logger.Info(fmt.Sprintf("User %d retrieved room list with %d rooms", userID, len(roomInfos)))
 
-	return &proto.BaseResponse{
-		Header: req.Header,
-		Code:   0,
-		Msg:    "success",
-		Data:   responseData,
-	}, nil
+	return ls.responses.Success(ctx, req.Header, "success", responseData), nil
 }
 
 // CreateRoom 创建房间
@@ -206,22 +280,14 @@ func (ls *LobbyService) CreateRoom(ctx context.Context, req *proto.BaseRequest)
 	userID := req.Header.GetUserId()
 	if userID == 0 {
 		logger.Error("CreateRoom: invalid user id")
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -1,
-			Msg:    "invalid user id",
-		}, nil
+		return ls.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
 	}
 
 	// 解析请求数据
-	var createRoomReq proto.CreateRoomRequest
-	if err := proto.Unmarshal(req.Data, &createRoomReq); err != nil {
+	createRoomReq, err := Bind[proto.CreateRoomRequest](req.Data)
+	if err != nil {
 		logger.Error(fmt.Sprintf("CreateRoom: failed to unmarshal request: %v", err))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -2,
-			Msg:    "invalid request data",
-		}, nil
+		return ls.responses.Error(ctx, req.Header, errcode.InvalidRequest), nil
 	}
 
 	roomName := createRoomReq.GetRoomName()
@@ -229,33 +295,27 @@ func (ls *LobbyService) CreateRoom(ctx context.Context, req *proto.BaseRequest)
 	maxPlayers := createRoomReq.GetMaxPlayers()
 	isPrivate := createRoomReq.GetIsPrivate()
 	password := createRoomReq.GetPassword()
+	ephemeralChat := createRoomReq.GetEphemeralChat() || ls.server.config.ChatPolicy.EphemeralRooms
 
 	// 验证房间参数
 	if roomName == "" {
 		logger.Error("CreateRoom: room name is empty")
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -3,
-			Msg:    "room name cannot be empty",
-		}, nil
+		return ls.responses.Error(ctx, req.Header, errcode.RoomNameRequired), nil
 	}
 
 	if maxPlayers < 2 || maxPlayers > 8 {
 		logger.Error(fmt.Sprintf("CreateRoom: invalid max players %d", maxPlayers))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -4,
-			Msg:    "max players must be between 2 and 8",
-		}, nil
+		return ls.responses.Error(ctx, req.Header, errcode.InvalidMaxPlayers), nil
 	}
 
 	if isPrivate && password == "" {
 		logger.Error("CreateRoom: private room requires password")
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -5,
-			Msg:    "private room requires password",
-		}, nil
+		return ls.responses.Error(ctx, req.Header, errcode.RoomPasswordRequired), nil
+	}
+
+	if err := requireFeature(ls.server.progression, userID, featureCreateRoom); err != nil {
+		logger.Debug(fmt.Sprintf("CreateRoom: user %d: %v", userID, err))
+		return ls.responses.Error(ctx, req.Header, err), nil
 	}
 
 	// 获取用户信息
@@ -263,11 +323,30 @@ func (ls *LobbyService) CreateRoom(ctx context.Context, req *proto.BaseRequest)
 	user, err := userRepo.GetByUserID(userID)
 	if err != nil {
 		logger.Error(fmt.Sprintf("CreateRoom: failed to get user %d: %v", userID, err))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -6,
-			Msg:    "user not found",
-		}, nil
+		return ls.responses.Error(ctx, req.Header, errcode.UserNotFound), nil
+	}
+
+	// 按房主的客户端语言校验房间名的长度、字符和屏蔽词
+	roomName, err = ls.server.naming.Validator().RoomName(roomName, user.Language)
+	if err != nil {
+		logger.Debug(fmt.Sprintf("CreateRoom: user %d: %v", userID, err))
+		return ls.responses.Error(ctx, req.Header, errcode.InvalidName.WithDetail(err.Error())), nil
+	}
+
+	// 每日开房间次数
+	if resp := consumeQuota(ctx, ls.server.quotas, ls.responses, req.Header, quota.OpCreateRoom); resp != nil {
+		return resp, nil
+	}
+
+	// 私有房间只保存密码的哈希
+	if isPrivate {
+		if password, err = security.HashRoomPassword(password); err != nil {
+			logger.Error(fmt.Sprintf("CreateRoom: failed to hash room password: %v", err))
+			ls.server.quotas.Refund(userID, quota.OpCreateRoom)
+			return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+		}
+	} else {
+		password = ""
 	}
 
 	// 生成房间ID
@@ -284,6 +363,9 @@ func (ls *LobbyService) CreateRoom(ctx context.Context, req *proto.BaseRequest)
 		IsPrivate:      isPrivate,
 		Password:       password,
 		OwnerID:        userID,
+		Region:         user.Region,
+		WorldID:        ls.server.config.World.Resolve(user.WorldID),
+		EphemeralChat:  ephemeralChat,
 		Players: []database.RoomPlayer{
 			{
 				UserID:   userID,
@@ -298,14 +380,17 @@ func (ls *LobbyService) CreateRoom(ctx context.Context, req *proto.BaseRequest)
 	// 保存到数据库
 	if err := ls.server.roomRepo.CreateRoom(room); err != nil {
 		logger.Error(fmt.Sprintf("CreateRoom: failed to create room: %v", err))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -7,
-			Msg:    "failed to create room",
-		}, nil
+		ls.server.quotas.Refund(userID, quota.OpCreateRoom)
+		return ls.responses.Error(ctx, req.Header, errcode.CreateRoomFailed), nil
+	}
+	if ephemeralChat {
+		ls.server.markEphemeralRoomChat(roomID)
 	}
+	ls.server.realtime.Adjust(realtime.Delta{GameType: room.GameType, Rooms: 1, Players: 1})
+	ls.server.search.IndexRoom(room)
 
 	logger.Info(fmt.Sprintf("User %s (ID: %d) created room %d: %s", user.Nickname, userID, roomID, roomName))
+	ls.server.timeline.RoomJoined(userID, roomID, roomJoinCreate)
 
 	// 构造响应数据
 	ownerInfo := &proto.GamePlayerInfo{
@@ -326,24 +411,16 @@ func (ls *LobbyService) CreateRoom(ctx context.Context, req *proto.BaseRequest)
 		Owner:          ownerInfo,
 		Players:        []*proto.GamePlayerInfo{ownerInfo},
 		CreatedTime:    uint32(room.CreatedAt.Unix()),
+		Region:         room.Region,
 	}
 
 	responseData, err := proto.Marshal(roomInfo)
 	if err != nil {
 		logger.Error(fmt.Sprintf("CreateRoom: failed to marshal response: %v", err))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -8,
-			Msg:    "failed to create response",
-		}, nil
-	}
-
-	return &proto.BaseResponse{
-		Header: req.Header,
-		Code:   0,
-		Msg:    "room created successfully",
-		Data:   responseData,
-	}, nil
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+
+	return ls.responses.Success(ctx, req.Header, "room created successfully", responseData), nil
 }
 
 // JoinRoom 加入房间
@@ -352,22 +429,14 @@ func (ls *LobbyService) JoinRoom(ctx context.Context, req *proto.BaseRequest) (*
 	userID := req.Header.GetUserId()
 	if userID == 0 {
 		logger.Error("JoinRoom: invalid user id")
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -1,
-			Msg:    "invalid user id",
-		}, nil
+		return ls.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
 	}
 
 	// 解析请求数据
-	var joinRoomReq proto.JoinRoomRequest
-	if err := proto.Unmarshal(req.Data, &joinRoomReq); err != nil {
+	joinRoomReq, err := Bind[proto.JoinRoomRequest](req.Data)
+	if err != nil {
 		logger.Error(fmt.Sprintf("JoinRoom: failed to unmarshal request: %v", err))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -2,
-			Msg:    "invalid request data",
-		}, nil
+		return ls.responses.Error(ctx, req.Header, errcode.InvalidRequest), nil
 	}
 
 	roomID := joinRoomReq.GetRoomId()
@@ -376,64 +445,51 @@ func (ls *LobbyService) JoinRoom(ctx context.Context, req *proto.BaseRequest) (*
 	// 验证房间ID
 	if roomID == 0 {
 		logger.Error("JoinRoom: invalid room id")
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -3,
-			Msg:    "invalid room id",
-		}, nil
+		return ls.responses.Error(ctx, req.Header, errcode.InvalidRoomID), nil
 	}
 
 	// 获取房间信息
 	room, err := ls.server.roomRepo.GetRoomByID(roomID)
 	if err != nil {
 		logger.Error(fmt.Sprintf("JoinRoom: room %d not found: %v", roomID, err))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -4,
-			Msg:    "room not found",
-		}, nil
+		return ls.responses.Error(ctx, req.Header, errcode.RoomNotFound), nil
 	}
 
 	// 检查房间状态
 	if room.Status != 0 {
 		logger.Error(fmt.Sprintf("JoinRoom: room %d is not waiting (status: %d)", roomID, room.Status))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -5,
-			Msg:    "room is not available",
-		}, nil
+		return ls.responses.Error(ctx, req.Header, errcode.RoomUnavailable), nil
 	}
 
 	// 检查房间是否已满
 	if room.CurrentPlayers >= room.MaxPlayers {
 		logger.Error(fmt.Sprintf("JoinRoom: room %d is full (%d/%d)", roomID, room.CurrentPlayers, room.MaxPlayers))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -6,
-			Msg:    "room is full",
-		}, nil
+		return ls.responses.Error(ctx, req.Header, errcode.RoomFull), nil
 	}
 
 	// 检查用户是否已在房间中
 	for _, player := range room.Players {
 		if player.UserID == userID {
 			logger.Error(fmt.Sprintf("JoinRoom: user %d already in room %d", userID, roomID))
-			return &proto.BaseResponse{
-				Header: req.Header,
-				Code:   -7,
-				Msg:    "already in room",
-			}, nil
+			return ls.responses.Error(ctx, req.Header, errcode.AlreadyInRoom), nil
 		}
 	}
 
-	// 检查私有房间密码
-	if room.IsPrivate && room.Password != password {
-		logger.Error(fmt.Sprintf("JoinRoom: wrong password for private room %d", roomID))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -8,
-			Msg:    "wrong password",
-		}, nil
+	// 检查私有房间密码，输错过多的玩家暂时不能加入，房间累计输错过多时锁定
+	if room.IsPrivate {
+		if err := ls.server.roomGuard.Check(roomID, userID); err != nil {
+			logger.Warn(fmt.Sprintf("JoinRoom: user %d rejected by private room %d: %v", userID, roomID, err))
+			if errors.Is(err, security.ErrRoomLocked) {
+				return ls.responses.Error(ctx, req.Header, errcode.RoomLocked), nil
+			}
+			return ls.responses.Error(ctx, req.Header, errcode.RoomJoinThrottled), nil
+		}
+		if !security.VerifyRoomPassword(room.Password, password) {
+			logger.Warn(fmt.Sprintf("JoinRoom: wrong password from user %d for private room %d", userID, roomID))
+			ls.server.roomGuard.RecordFailure(roomID, userID)
+			return ls.responses.Error(ctx, req.Header, errcode.WrongRoomPassword), nil
+		}
+		ls.server.roomGuard.Reset(roomID, userID)
 	}
 
 	// 获取用户信息
@@ -441,11 +497,21 @@ func (ls *LobbyService) JoinRoom(ctx context.Context, req *proto.BaseRequest) (*
 	user, err := userRepo.GetByUserID(userID)
 	if err != nil {
 		logger.Error(fmt.Sprintf("JoinRoom: failed to get user %d: %v", userID, err))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -9,
-			Msg:    "user not found",
-		}, nil
+		return ls.responses.Error(ctx, req.Header, errcode.UserNotFound), nil
+	}
+
+	// 房间只对所属世界的玩家开放
+	if !ls.server.config.World.Allows(world.ActionRoom, user.WorldID, room.WorldID) {
+		logger.Error(fmt.Sprintf("JoinRoom: user %d world %d does not match room %d world %d",
+			userID, user.WorldID, roomID, room.WorldID))
+		return ls.responses.Error(ctx, req.Header, errcode.RoomWorldMismatch), nil
+	}
+
+	// 区域匹配限制
+	if !ls.server.config.Geo.AllowCrossRegion && room.Region != "" && user.Region != "" && room.Region != user.Region {
+		logger.Error(fmt.Sprintf("JoinRoom: user %d region %s does not match room %d region %s",
+			userID, user.Region, roomID, room.Region))
+		return ls.responses.Error(ctx, req.Header, errcode.RoomRegionMismatch), nil
 	}
 
 	// 创建玩家对象
@@ -460,14 +526,12 @@ func (ls *LobbyService) JoinRoom(ctx context.Context, req *proto.BaseRequest) (*
 	// 添加玩家到房间
 	if err := ls.server.roomRepo.AddPlayerToRoom(roomID, player); err != nil {
 		logger.Error(fmt.Sprintf("JoinRoom: failed to add player to room: %v", err))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -10,
-			Msg:    "failed to join room",
-		}, nil
+		return ls.responses.Error(ctx, req.Header, errcode.JoinRoomFailed), nil
 	}
 
 	logger.Info(fmt.Sprintf("User %s (ID: %d) joined room %d: %s", user.Nickname, userID, roomID, room.RoomName))
+	ls.server.realtime.Adjust(realtime.Delta{GameType: room.GameType, Players: 1})
+	ls.server.timeline.RoomJoined(userID, roomID, roomJoinJoin)
 
 	// 重新获取房间信息（包含更新后的玩家列表）
 	updatedRoom, err := ls.server.roomRepo.GetRoomByID(roomID)
@@ -485,6 +549,7 @@ func (ls *LobbyService) JoinRoom(ctx context.Context, req *proto.BaseRequest) (*
 			Nickname: p.Nickname,
 			Level:    p.Level,
 			Status:   p.Status,
+			PartyId:  p.PartyID,
 		}
 		players = append(players, playerInfo)
 	}
@@ -517,24 +582,16 @@ func (ls *LobbyService) JoinRoom(ctx context.Context, req *proto.BaseRequest) (*
 		Owner:          ownerInfo,
 		Players:        players,
 		CreatedTime:    uint32(updatedRoom.CreatedAt.Unix()),
+		Region:         updatedRoom.Region,
 	}
 
 	responseData, err := proto.Marshal(roomInfo)
 	if err != nil {
 		logger.Error(fmt.Sprintf("JoinRoom: failed to marshal response: %v", err))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -11,
-			Msg:    "failed to create response",
-		}, nil
-	}
-
-	return &proto.BaseResponse{
-		Header: req.Header,
-		Code:   0,
-		Msg:    "joined room successfully",
-		Data:   responseData,
-	}, nil
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+
+	return ls.responses.Success(ctx, req.Header, "joined room successfully", responseData), nil
 }
 
 // LeaveRoom 离开房间
@@ -543,22 +600,14 @@ func (ls *LobbyService) LeaveRoom(ctx context.Context, req *proto.BaseRequest) (
 	userID := req.Header.GetUserId()
 	if userID == 0 {
 		logger.Error("LeaveRoom: invalid user id")
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -1,
-			Msg:    "invalid user id",
-		}, nil
+		return ls.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
 	}
 
-	// 解析请求数据
-	var leaveRoomReq proto.JoinRoomRequest // 复用JoinRoomRequest结构，只需要RoomId
-	if err := proto.Unmarshal(req.Data, &leaveRoomReq); err != nil {
+	// 解析请求数据，复用JoinRoomRequest结构，只需要RoomId
+	leaveRoomReq, err := Bind[proto.JoinRoomRequest](req.Data)
+	if err != nil {
 		logger.Error(fmt.Sprintf("LeaveRoom: failed to unmarshal request: %v", err))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -2,
-			Msg:    "invalid request data",
-		}, nil
+		return ls.responses.Error(ctx, req.Header, errcode.InvalidRequest), nil
 	}
 
 	roomID := leaveRoomReq.GetRoomId()
@@ -566,22 +615,14 @@ func (ls *LobbyService) LeaveRoom(ctx context.Context, req *proto.BaseRequest) (
 	// 验证房间ID
 	if roomID == 0 {
 		logger.Error("LeaveRoom: invalid room id")
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -3,
-			Msg:    "invalid room id",
-		}, nil
+		return ls.responses.Error(ctx, req.Header, errcode.InvalidRoomID), nil
 	}
 
 	// 获取房间信息
 	room, err := ls.server.roomRepo.GetRoomByID(roomID)
 	if err != nil {
 		logger.Error(fmt.Sprintf("LeaveRoom: room %d not found: %v", roomID, err))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -4,
-			Msg:    "room not found",
-		}, nil
+		return ls.responses.Error(ctx, req.Header, errcode.RoomNotFound), nil
 	}
 
 	// 检查用户是否在房间中
@@ -595,11 +636,7 @@ func (ls *LobbyService) LeaveRoom(ctx context.Context, req *proto.BaseRequest) (
 
 	if !userInRoom {
 		logger.Error(fmt.Sprintf("LeaveRoom: user %d not in room %d", userID, roomID))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -5,
-			Msg:    "not in room",
-		}, nil
+		return ls.responses.Error(ctx, req.Header, errcode.NotInRoom), nil
 	}
 
 	// 获取用户信息用于日志
@@ -615,11 +652,7 @@ func (ls *LobbyService) LeaveRoom(ctx context.Context, req *proto.BaseRequest) (
 		if room.CurrentPlayers <= 1 {
 			if err := ls.server.roomRepo.DeleteRoom(roomID); err != nil {
 				logger.Error(fmt.Sprintf("LeaveRoom: failed to delete room: %v", err))
-				return &proto.BaseResponse{
-					Header: req.Header,
-					Code:   -6,
-					Msg:    "failed to leave room",
-				}, nil
+				return ls.responses.Error(ctx, req.Header, errcode.LeaveRoomFailed), nil
 			}
 			logger.Info(fmt.Sprintf("Room %d deleted as owner left", roomID))
 		} else {
@@ -636,11 +669,7 @@ func (ls *LobbyService) LeaveRoom(ctx context.Context, req *proto.BaseRequest) (
 				// 先移除当前玩家
 				if err := ls.server.roomRepo.RemovePlayerFromRoom(roomID, userID); err != nil {
 					logger.Error(fmt.Sprintf("LeaveRoom: failed to remove player: %v", err))
-					return &proto.BaseResponse{
-						Header: req.Header,
-						Code:   -7,
-						Msg:    "failed to leave room",
-					}, nil
+					return ls.responses.Error(ctx, req.Header, errcode.LeaveRoomFailed), nil
 				}
 
 				// 更新房主
@@ -656,11 +685,7 @@ func (ls *LobbyService) LeaveRoom(ctx context.Context, req *proto.BaseRequest) (
 		// 普通玩家离开，直接移除
 		if err := ls.server.roomRepo.RemovePlayerFromRoom(roomID, userID); err != nil {
 			logger.Error(fmt.Sprintf("LeaveRoom: failed to remove player: %v", err))
-			return &proto.BaseResponse{
-				Header: req.Header,
-				Code:   -8,
-				Msg:    "failed to leave room",
-			}, nil
+			return ls.responses.Error(ctx, req.Header, errcode.LeaveRoomFailed), nil
 		}
 	}
 
@@ -670,33 +695,26 @@ func (ls *LobbyService) LeaveRoom(ctx context.Context, req *proto.BaseRequest) (
 		logger.Info(fmt.Sprintf("User %d left room %d: %s", userID, roomID, room.RoomName))
 	}
 
+	// 已结束的房间不再计入实时计数
+	if room.Status != 2 {
+		delta := realtime.Delta{GameType: room.GameType, Players: -1}
+		if room.OwnerID == userID && room.CurrentPlayers <= 1 {
+			delta.Rooms = -1
+		}
+		ls.server.realtime.Adjust(delta)
+	}
+
 	// 构造响应数据
 	responseData := map[string]interface{}{
 		"room_id": roomID,
 		"left_at": time.Now().Unix(),
 	}
 
-	responseBytes, err := proto.Marshal(&proto.BaseResponse{
-		Header: req.Header,
-		Code:   0,
-		Msg:    "left room successfully",
-	})
+	responseBytes, err := json.Marshal(responseData)
 	if err != nil {
 		logger.Error(fmt.Sprintf("LeaveRoom: failed to marshal response: %v", err))
-		return &proto.BaseResponse{
-			Header: req.Header,
-			Code:   -9,
-			Msg:    "failed to create response",
-		}, nil
-	}
-
-	// 简化处理，直接返回成功响应
-	_ = responseData
-	_ = responseBytes
-
-	return &proto.BaseResponse{
-		Header: req.Header,
-		Code:   0,
-		Msg:    "left room successfully",
-	}, nil
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+
+	return ls.responses.Success(ctx, req.Header, "left room successfully", responseBytes), nil
 }
@@ -3,13 +3,24 @@ package server
 import (
 	"context"
 	"crypto/md5"
+	"encoding/json"
 	"fmt"
-	"reflect"
+	"net"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/phuhao00/lufy/internal/actor"
 	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/discovery"
+	"github.com/phuhao00/lufy/internal/geo"
 	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/push"
+	"github.com/phuhao00/lufy/internal/search"
+	"github.com/phuhao00/lufy/internal/security"
+	"github.com/phuhao00/lufy/internal/timeline"
+	"github.com/phuhao00/lufy/internal/transfer"
+	"github.com/phuhao00/lufy/internal/trigger"
 	"github.com/phuhao00/lufy/pkg/proto"
 )
 
@@ -18,6 +29,14 @@ type LoginServer struct {
 	*BaseServer
 	userRepo  *database.UserRepository
 	userCache *database.UserCache
+	geoIP     *geo.GeoIPResolver
+	devices   *database.DeviceTokenRepository
+	timeline  *timeline.Recorder
+	search    *search.Service
+
+	passwordPolicy *security.PasswordPolicy
+	loginGuard     *security.LoginGuard
+	oauth          *security.OAuthVerifier
 }
 
 // NewLoginServer 创建登录服务器
@@ -27,10 +46,27 @@ func NewLoginServer(configFile, nodeID string) *LoginServer {
 		logger.Fatal(fmt.Sprintf("Failed to create base server: %v", err))
 	}
 
+	geoIP, err := geo.NewGeoIPResolver(&baseServer.config.Geo)
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to create geoip resolver: %v", err))
+	}
+
+	passwordPolicy, err := security.NewPasswordPolicy(&baseServer.config.Security.PasswordPolicy)
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to create password policy: %v", err))
+	}
+
 	loginServer := &LoginServer{
-		BaseServer: baseServer,
-		userRepo:   database.NewUserRepository(baseServer.mongoManager),
-		userCache:  database.NewUserCache(baseServer.redisManager),
+		BaseServer:     baseServer,
+		userRepo:       database.NewUserRepository(baseServer.mongoManager),
+		userCache:      database.NewUserCache(baseServer.redisManager),
+		geoIP:          geoIP,
+		devices:        database.NewDeviceTokenRepository(baseServer.mongoManager),
+		timeline:       baseServer.newTimeline(),
+		search:         baseServer.newSearch(),
+		passwordPolicy: passwordPolicy,
+		loginGuard:     security.NewLoginGuard(&baseServer.config.Security.LoginGuard, baseServer.redisManager, baseServer.ipBlacklist),
+		oauth:          security.NewOAuthVerifier(&baseServer.config.Security.OAuth),
 	}
 
 	// 注册通用服务
@@ -40,7 +76,7 @@ func NewLoginServer(configFile, nodeID string) *LoginServer {
 
 	// 注册登录服务
 	loginService := NewLoginService(loginServer)
-	if err := baseServer.rpcServer.RegisterService(loginService); err != nil {
+	if err := RegisterLoginService(baseServer.rpcServer, loginService); err != nil {
 		logger.Fatal(fmt.Sprintf("Failed to register login service: %v", err))
 	}
 
@@ -65,57 +101,109 @@ func NewLoginService(server *LoginServer) *LoginService {
 	}
 }
 
-// GetName 获取服务名称
-func (ls *LoginService) GetName() string {
-	return "LoginService"
-}
-
-// RegisterMethods 注册方法
-func (ls *LoginService) RegisterMethods() map[string]reflect.Value {
-	methods := make(map[string]reflect.Value)
-
-	methods["Login"] = reflect.ValueOf(ls.Login)
-	methods["Register"] = reflect.ValueOf(ls.Register)
-	methods["Logout"] = reflect.ValueOf(ls.Logout)
-	methods["ValidateToken"] = reflect.ValueOf(ls.ValidateToken)
-	methods["RefreshToken"] = reflect.ValueOf(ls.RefreshToken)
-
-	return methods
-}
-
 // Login 用户登录
 func (ls *LoginService) Login(ctx context.Context, req *proto.LoginRequest) (*proto.LoginResponse, error) {
 	logger.Info(fmt.Sprintf("User login attempt: %s", req.Username))
 
+	// 撞库防护：IP黑名单和账号递增延迟
+	if err := ls.server.loginGuard.Check(req.Username, req.GetClientIp()); err != nil {
+		logger.Warn(fmt.Sprintf("Login rejected for user %s from %s: %v", req.Username, req.GetClientIp(), err))
+		return nil, err
+	}
+
+	if err := ls.checkMaintenance(); err != nil {
+		return nil, err
+	}
+
 	// 验证用户名和密码
 	user, err := ls.server.userRepo.GetByUsername(req.Username)
 	if err != nil {
 		logger.Warn(fmt.Sprintf("User not found: %s", req.Username))
+		ls.server.loginGuard.RecordFailure(req.Username, req.GetClientIp(), security.AuthFailureUnknownUser)
 		return nil, fmt.Errorf("invalid username or password")
 	}
 
 	// 验证密码
 	if !ls.verifyPassword(req.Password, user.Password) {
 		logger.Warn(fmt.Sprintf("Password verification failed for user: %s", req.Username))
+		ls.server.loginGuard.RecordFailure(req.Username, req.GetClientIp(), security.AuthFailureBadPassword)
 		return nil, fmt.Errorf("invalid username or password")
 	}
+	ls.server.loginGuard.RecordSuccess(req.Username)
+
+	return ls.completeLogin(user, req)
+}
+
+// checkMaintenance 维护模式下拒绝新的登录，状态读取失败时不影响登录
+func (ls *LoginService) checkMaintenance() error {
+	if maintenance, err := ls.server.getMaintenance(); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to check maintenance state: %v", err))
+	} else if maintenance.Enabled {
+		return fmt.Errorf("server under maintenance: %s", maintenance.Message)
+	}
+	return nil
+}
+
+// completeLogin 校验通过后检查账号状态，创建会话并选择网关
+func (ls *LoginService) completeLogin(user *database.User, req *proto.LoginRequest) (*proto.LoginResponse, error) {
+	// 跨世界迁移期间拒绝登录，状态读取失败时不影响登录
+	if transferring, err := transfer.InProgress(ls.server.redisManager, user.UserID); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to check world transfer state: %v", err))
+	} else if transferring {
+		return nil, fmt.Errorf("account is being transferred to another world, please try again later")
+	}
 
 	// 检查用户状态
+	if user.Status == userStatusFrozen {
+		logger.Warn(fmt.Sprintf("User is frozen pending review: %s", user.Username))
+		return nil, fmt.Errorf("account is frozen pending review")
+	}
 	if user.Status != 0 {
-		logger.Warn(fmt.Sprintf("User is banned: %s", req.Username))
+		logger.Warn(fmt.Sprintf("User is banned: %s", user.Username))
 		return nil, fmt.Errorf("user is banned")
 	}
 
 	// 生成登录令牌
 	token := ls.generateToken(user.UserID)
 
+	// 解析玩家所在区域
+	clientIP := req.GetClientIp()
+	if clientIP == "" {
+		clientIP = "0.0.0.0"
+	}
+	region := ls.server.geoIP.Lookup(clientIP)
+	user.LastLoginIP = clientIP
+	user.Region = region
+
 	// 更新用户登录信息
-	err = ls.server.userRepo.UpdateFields(user.UserID, map[string]interface{}{
+	fields := map[string]interface{}{
 		"last_login_at": time.Now(),
-		"last_login_ip": "0.0.0.0", // 实际应该从请求中获取
-	})
-	if err != nil {
+		"last_login_ip": clientIP,
+		"region":        region,
+	}
+	if language := req.GetLanguage(); language != "" {
+		user.Language = language
+		fields["language"] = language
+	}
+	if err := ls.server.userRepo.UpdateFields(user.UserID, fields); err != nil {
 		logger.Error(fmt.Sprintf("Failed to update user login info: %v", err))
+	} else if !user.LastLoginAt.IsZero() {
+		// 离开较久后回归，以上次登录时间去重，同一次回归只发放一次
+		ls.server.triggers.Fire(trigger.Event{
+			Type:    trigger.EventReturn,
+			UserID:  user.UserID,
+			Absence: time.Since(user.LastLoginAt),
+			Ref:     strconv.FormatInt(user.LastLoginAt.Unix(), 10),
+		})
+	}
+
+	// 选择服务玩家所属世界、优先同区域的网关
+	worldID := ls.server.config.World.Resolve(user.WorldID)
+	gatewayAddr := ""
+	if gateway := ls.server.discovery.GetServiceInWorld("gateway", worldID, region); gateway != nil {
+		if port := gateway.Metadata[discovery.MetadataTCPPort]; port != "" {
+			gatewayAddr = net.JoinHostPort(gateway.Address, port)
+		}
 	}
 
 	// 缓存用户信息
@@ -125,16 +213,26 @@ func (ls *LoginService) Login(ctx context.Context, req *proto.LoginRequest) (*pr
 	sessionCache := database.NewSessionCache(ls.server.redisManager)
 	sessionCache.SetSession(token, user.UserID)
 
-	logger.Info(fmt.Sprintf("User login successful: %s (ID: %d)", req.Username, user.UserID))
+	// 登记移动推送设备
+	if req.GetPushToken() != "" {
+		ls.registerDevice(user.UserID, req)
+	}
+	ls.server.timeline.Login(user.UserID, clientIP, req.GetDeviceId(), req.GetPlatform())
+
+	logger.Info(fmt.Sprintf("User login successful: %s (ID: %d, region: %s, world: %d)", user.Username, user.UserID, region, worldID))
 
 	return &proto.LoginResponse{
-		UserId:   user.UserID,
-		Token:    token,
-		Nickname: user.Nickname,
-		Level:    user.Level,
-		Exp:      user.Experience,
-		Gold:     user.Gold,
-		Diamond:  user.Diamond,
+		UserId:      user.UserID,
+		Token:       token,
+		Nickname:    user.Nickname,
+		Level:       user.Level,
+		Exp:         user.Experience,
+		Gold:        user.Gold,
+		Diamond:     user.Diamond,
+		Region:      region,
+		GatewayAddr: gatewayAddr,
+		WorldId:     worldID,
+		Guest:       user.Guest,
 	}, nil
 }
 
@@ -142,36 +240,43 @@ func (ls *LoginService) Login(ctx context.Context, req *proto.LoginRequest) (*pr
 func (ls *LoginService) Register(ctx context.Context, req *proto.LoginRequest) (*proto.LoginResponse, error) {
 	logger.Info(fmt.Sprintf("User registration attempt: %s", req.Username))
 
+	// 游客账号的用户名前缀保留给系统生成
+	if strings.HasPrefix(req.Username, guestUsernamePrefix) {
+		return nil, fmt.Errorf("username is reserved")
+	}
+
 	// 检查用户名是否已存在
 	existingUser, _ := ls.server.userRepo.GetByUsername(req.Username)
 	if existingUser != nil {
 		return nil, fmt.Errorf("username already exists")
 	}
 
+	// 检查密码强度
+	if err := ls.server.passwordPolicy.Validate(req.Username, req.Password); err != nil {
+		logger.Warn(fmt.Sprintf("Weak password rejected for user %s: %v", req.Username, err))
+		return nil, err
+	}
+
+	// 玩家注册后属于选择的世界，未选择时为默认世界
+	worldID := ls.server.config.World.Resolve(req.GetWorldId())
+	if err := ls.server.config.World.ValidateRegistration(worldID); err != nil {
+		logger.Warn(fmt.Sprintf("Registration rejected for user %s: %v", req.Username, err))
+		return nil, err
+	}
+
 	// 生成用户ID
 	userID := uint64(time.Now().UnixNano())
 
 	// 创建新用户
-	newUser := &database.User{
-		UserID:      userID,
-		Username:    req.Username,
-		Password:    ls.hashPassword(req.Password),
-		Nickname:    req.Username, // 默认昵称为用户名
-		Level:       1,
-		Experience:  0,
-		Gold:        1000, // 初始金币
-		Diamond:     100,  // 初始钻石
-		Status:      0,    // 正常状态
-		LastLoginAt: time.Now(),
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
-	}
+	newUser := ls.newUser(userID, req.Username, worldID, req.GetLanguage())
+	newUser.Password = ls.hashPassword(req.Password)
 
 	// 保存到数据库
 	if err := ls.server.userRepo.Create(newUser); err != nil {
 		logger.Error(fmt.Sprintf("Failed to create user: %v", err))
 		return nil, fmt.Errorf("failed to create user")
 	}
+	ls.server.search.IndexUser(newUser)
 
 	// 生成登录令牌
 	token := ls.generateToken(userID)
@@ -183,7 +288,7 @@ func (ls *LoginService) Register(ctx context.Context, req *proto.LoginRequest) (
 	sessionCache := database.NewSessionCache(ls.server.redisManager)
 	sessionCache.SetSession(token, userID)
 
-	logger.Info(fmt.Sprintf("User registration successful: %s (ID: %d)", req.Username, userID))
+	logger.Info(fmt.Sprintf("User registration successful: %s (ID: %d, world: %d)", req.Username, userID, worldID))
 
 	return &proto.LoginResponse{
 		UserId:   userID,
@@ -193,9 +298,29 @@ func (ls *LoginService) Register(ctx context.Context, req *proto.LoginRequest) (
 		Exp:      newUser.Experience,
 		Gold:     newUser.Gold,
 		Diamond:  newUser.Diamond,
+		WorldId:  worldID,
 	}, nil
 }
 
+// newUser 创建带初始资源的新用户，默认昵称为用户名
+func (ls *LoginService) newUser(userID uint64, username string, worldID uint32, language string) *database.User {
+	return &database.User{
+		UserID:      userID,
+		Username:    username,
+		Nickname:    username,
+		Level:       1,
+		Experience:  0,
+		Gold:        1000, // 初始金币
+		Diamond:     100,  // 初始钻石
+		Status:      0,    // 正常状态
+		WorldID:     worldID,
+		Language:    language,
+		LastLoginAt: time.Now(),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+}
+
 // Logout 用户登出
 func (ls *LoginService) Logout(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
 	userID := req.Header.UserId
@@ -294,6 +419,21 @@ func (ls *LoginService) RefreshToken(ctx context.Context, req *proto.BaseRequest
 	}, nil
 }
 
+// GetAuthMetrics 获取登录失败统计
+func (ls *LoginService) GetAuthMetrics(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	data, err := json.Marshal(ls.server.loginGuard.GetMetrics())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal auth metrics: %v", err)
+	}
+
+	return &proto.BaseResponse{
+		Header: req.Header,
+		Code:   0,
+		Msg:    "success",
+		Data:   data,
+	}, nil
+}
+
 // hashPassword 哈希密码
 func (ls *LoginService) hashPassword(password string) string {
 	hash := md5.Sum([]byte(password + "lufy_game_salt"))
@@ -312,6 +452,26 @@ func (ls *LoginService) generateToken(userID uint64) string {
 	return fmt.Sprintf("%x", hash)
 }
 
+// registerDevice 登记移动推送设备令牌，不支持推送的客户端平台忽略
+func (ls *LoginService) registerDevice(userID uint64, req *proto.LoginRequest) {
+	platform, ok := push.PlatformFor(req.GetPlatform())
+	if !ok {
+		return
+	}
+
+	err := ls.server.devices.Register(&database.DeviceToken{
+		UserID:    userID,
+		DeviceID:  req.GetDeviceId(),
+		Platform:  platform,
+		Token:     req.GetPushToken(),
+		Language:  req.GetLanguage(),
+		UTCOffset: req.GetUtcOffset(),
+	})
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to register device token for user %d: %v", userID, err))
+	}
+}
+
 // LoginActor 登录Actor
 type LoginActor struct {
 	*actor.BaseActor
@@ -5,11 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"reflect"
-	"sync"
+	"strconv"
 	"time"
 
+	"github.com/phuhao00/lufy/internal/campaign"
 	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/errcode"
 	"github.com/phuhao00/lufy/internal/logger"
 	"github.com/phuhao00/lufy/pkg/proto"
 )
@@ -17,10 +18,10 @@ import (
 // MailServer 邮件服务器
 type MailServer struct {
 	*BaseServer
-	mailRepo    *database.MailRepository
-	userRepo    *database.UserRepository
-	nextMailID  uint64
-	idMutex     sync.Mutex
+	mailRepo  *database.MailRepository
+	userRepo  *database.UserRepository
+	notifier  *Notifier
+	campaigns *campaign.Service
 }
 
 // NewMailServer 创建邮件服务器
@@ -34,8 +35,9 @@ func NewMailServer(configFile, nodeID string) *MailServer {
 		BaseServer: baseServer,
 		mailRepo:   database.NewMailRepository(baseServer.mongoManager),
 		userRepo:   database.NewUserRepository(baseServer.mongoManager),
-		nextMailID: 1,
+		notifier:   NewNotifier(baseServer),
 	}
+	mailServer.campaigns = baseServer.newCampaigns(mailServer.notifier)
 
 	// 注册通用服务
 	if err := RegisterCommonServices(baseServer); err != nil {
@@ -44,52 +46,53 @@ func NewMailServer(configFile, nodeID string) *MailServer {
 
 	// 注册邮件服务
 	mailService := NewMailService(mailServer)
-	if err := baseServer.rpcServer.RegisterService(mailService); err != nil {
+	if err := RegisterMailService(baseServer.rpcServer, mailService); err != nil {
 		logger.Fatal(fmt.Sprintf("Failed to register mail service: %v", err))
 	}
 
+	// 定期删除过期邮件
+	if err := baseServer.scheduler.Register("delete_expired_mails", "@hourly", func(ctx context.Context) error {
+		return mailServer.mailRepo.DeleteExpiredMails()
+	}); err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to register mail cleanup job: %v", err))
+	}
+
+	// 按配置的间隔分批发送邮件活动
+	if err := baseServer.scheduler.Register("mail_campaigns", "@every "+mailServer.campaigns.Interval().String(), func(ctx context.Context) error {
+		return mailServer.campaigns.Step()
+	}); err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to register mail campaign job: %v", err))
+	}
+
 	return mailServer
 }
 
-// generateMailID 生成邮件ID
-func (s *MailServer) generateMailID() uint64 {
-	s.idMutex.Lock()
-	defer s.idMutex.Unlock()
-	id := s.nextMailID
-	s.nextMailID++
-	return id
+// mailIDKey 邮件ID计数器，邮件服和GM服发送的邮件共用
+const mailIDKey = "mail:next_id"
+
+// generateMailID 生成集群内唯一的邮件ID
+func (bs *BaseServer) generateMailID() (uint64, error) {
+	id, err := bs.redisManager.Incr(mailIDKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate mail id: %v", err)
+	}
+	return uint64(id), nil
 }
 
 // MailService 邮件RPC服务
 type MailService struct {
-	server *MailServer
+	server    *MailServer
+	responses *errcode.Builder
 }
 
 // NewMailService 创建邮件服务
 func NewMailService(server *MailServer) *MailService {
 	return &MailService{
-		server: server,
+		server:    server,
+		responses: errcode.NewBuilder(nil),
 	}
 }
 
-// GetName 获取服务名称
-func (ms *MailService) GetName() string {
-	return "MailService"
-}
-
-// RegisterMethods 注册方法
-func (ms *MailService) RegisterMethods() map[string]reflect.Value {
-	methods := make(map[string]reflect.Value)
-
-	methods["GetMailList"] = reflect.ValueOf(ms.GetMailList)
-	methods["ReadMail"] = reflect.ValueOf(ms.ReadMail)
-	methods["ClaimRewards"] = reflect.ValueOf(ms.ClaimRewards)
-	methods["DeleteMail"] = reflect.ValueOf(ms.DeleteMail)
-	methods["SendMail"] = reflect.ValueOf(ms.SendMail)
-
-	return methods
-}
-
 // GetMailList 获取邮件列表
 func (ms *MailService) GetMailList(ctx context.Context, req *proto.MailListRequest) (*proto.MailListResponse, error) {
 	// 验证用户ID
@@ -180,10 +183,7 @@ func (ms *MailService) ReadMail(ctx context.Context, req *proto.MailOperationReq
 	// 验证用户ID
 	userID := ctx.Value("user_id")
 	if userID == nil {
-		return &proto.CommonResponse{
-			Code:    1001,
-			Message: "用户未登录",
-		}, nil
+		return ms.responses.CommonError(ctx, errcode.NotLoggedIn), nil
 	}
 
 	toUserID := userID.(uint64)
@@ -196,56 +196,38 @@ func (ms *MailService) ReadMail(ctx context.Context, req *proto.MailOperationReq
 
 	// 验证邮件ID
 	if readReq.MailId == 0 {
-		return &proto.CommonResponse{
-			Code:    1002,
-			Message: "邮件ID不能为空",
-		}, nil
+		return ms.responses.CommonError(ctx, errcode.MailIDRequired), nil
 	}
 
 	// 获取邮件信息
 	mail, err := ms.server.mailRepo.GetMailByID(readReq.MailId)
 	if err != nil {
 		log.Printf("获取邮件信息失败: %v", err)
-		return &proto.CommonResponse{
-			Code:    1003,
-			Message: "邮件不存在",
-		}, nil
+		return ms.responses.CommonError(ctx, errcode.MailNotFound), nil
 	}
 
 	// 检查邮件是否属于当前用户
 	if mail.ToUserID != toUserID {
-		return &proto.CommonResponse{
-			Code:    1004,
-			Message: "无权限访问此邮件",
-		}, nil
+		return ms.responses.CommonError(ctx, errcode.MailAccessDenied), nil
 	}
 
 	// TODO: 检查邮件是否过期
 	// 简化实现：假设邮件未过期
 	if false {
-		return &proto.CommonResponse{
-			Code:    1005,
-			Message: "邮件已过期",
-		}, nil
+		return ms.responses.CommonError(ctx, errcode.MailExpired), nil
 	}
 
 	// 如果邮件未读，标记为已读
 	if !mail.IsRead {
 		if err := ms.server.mailRepo.UpdateMailReadStatus(readReq.MailId, true); err != nil {
 			log.Printf("更新邮件已读状态失败: %v", err)
-			return &proto.CommonResponse{
-				Code:    1006,
-				Message: "更新邮件状态失败",
-			}, nil
+			return ms.responses.CommonError(ctx, errcode.MailUpdateFailed), nil
 		}
 	}
 
 	log.Printf("用户 %d 读取邮件 %d 成功", toUserID, readReq.MailId)
 
-	return &proto.CommonResponse{
-		Code:    0,
-		Message: "邮件读取成功",
-	}, nil
+	return ms.responses.CommonSuccess(ctx, "邮件读取成功", nil), nil
 }
 
 // ClaimRewards 领取奖励
@@ -253,10 +235,7 @@ func (ms *MailService) ClaimRewards(ctx context.Context, req *proto.MailOperatio
 	// 验证用户ID
 	userID := ctx.Value("user_id")
 	if userID == nil {
-		return &proto.CommonResponse{
-			Code:    1001,
-			Message: "用户未登录",
-		}, nil
+		return ms.responses.CommonError(ctx, errcode.NotLoggedIn), nil
 	}
 
 	toUserID := userID.(uint64)
@@ -269,53 +248,35 @@ func (ms *MailService) ClaimRewards(ctx context.Context, req *proto.MailOperatio
 
 	// 验证邮件ID
 	if claimReq.MailId == 0 {
-		return &proto.CommonResponse{
-			Code:    1002,
-			Message: "邮件ID不能为空",
-		}, nil
+		return ms.responses.CommonError(ctx, errcode.MailIDRequired), nil
 	}
 
 	// 获取邮件信息
 	mail, err := ms.server.mailRepo.GetMailByID(claimReq.MailId)
 	if err != nil {
 		log.Printf("获取邮件信息失败: %v", err)
-		return &proto.CommonResponse{
-			Code:    1003,
-			Message: "邮件不存在",
-		}, nil
+		return ms.responses.CommonError(ctx, errcode.MailNotFound), nil
 	}
 
 	// 检查邮件是否属于当前用户
 	if mail.ToUserID != toUserID {
-		return &proto.CommonResponse{
-			Code:    1004,
-			Message: "无权限访问此邮件",
-		}, nil
+		return ms.responses.CommonError(ctx, errcode.MailAccessDenied), nil
 	}
 
 	// TODO: 检查邮件是否过期
 	// 简化实现：假设邮件未过期
 	if false {
-		return &proto.CommonResponse{
-			Code:    1005,
-			Message: "邮件已过期",
-		}, nil
+		return ms.responses.CommonError(ctx, errcode.MailExpired), nil
 	}
 
 	// 检查是否有奖励
 	if len(mail.Rewards) == 0 {
-		return &proto.CommonResponse{
-			Code:    1006,
-			Message: "此邮件没有奖励",
-		}, nil
+		return ms.responses.CommonError(ctx, errcode.MailNoRewards), nil
 	}
 
 	// 检查奖励是否已领取
 	if mail.IsClaimed {
-		return &proto.CommonResponse{
-			Code:    1007,
-			Message: "奖励已领取",
-		}, nil
+		return ms.responses.CommonError(ctx, errcode.RewardsAlreadyClaimed), nil
 	}
 
 	// TODO: 这里应该调用背包系统或物品系统来发放奖励
@@ -328,10 +289,7 @@ func (ms *MailService) ClaimRewards(ctx context.Context, req *proto.MailOperatio
 	// 标记奖励为已领取
 	if err := ms.server.mailRepo.UpdateMailClaimStatus(claimReq.MailId, true); err != nil {
 		log.Printf("更新邮件领取状态失败: %v", err)
-		return &proto.CommonResponse{
-			Code:    1008,
-			Message: "更新邮件状态失败",
-		}, nil
+		return ms.responses.CommonError(ctx, errcode.MailUpdateFailed), nil
 	}
 
 	// 如果邮件未读，同时标记为已读
@@ -341,11 +299,7 @@ func (ms *MailService) ClaimRewards(ctx context.Context, req *proto.MailOperatio
 
 	log.Printf("用户 %d 领取邮件 %d 奖励成功，奖励数量: %d", toUserID, claimReq.MailId, len(mail.Rewards))
 
-	return &proto.CommonResponse{
-		Code:    0,
-		Message: "奖励领取成功",
-		Data:    []byte(fmt.Sprintf("{\"rewards_count\":%d}", len(mail.Rewards))),
-	}, nil
+	return ms.responses.CommonSuccess(ctx, "奖励领取成功", []byte(fmt.Sprintf("{\"rewards_count\":%d}", len(mail.Rewards)))), nil
 }
 
 // DeleteMail 删除邮件
@@ -353,10 +307,7 @@ func (ms *MailService) DeleteMail(ctx context.Context, req *proto.MailOperationR
 	// 验证用户ID
 	userID := ctx.Value("user_id")
 	if userID == nil {
-		return &proto.CommonResponse{
-			Code:    1001,
-			Message: "用户未登录",
-		}, nil
+		return ms.responses.CommonError(ctx, errcode.NotLoggedIn), nil
 	}
 
 	toUserID := userID.(uint64)
@@ -369,59 +320,38 @@ func (ms *MailService) DeleteMail(ctx context.Context, req *proto.MailOperationR
 
 	// 验证邮件ID
 	if deleteReq.MailId == 0 {
-		return &proto.CommonResponse{
-			Code:    1002,
-			Message: "邮件ID不能为空",
-		}, nil
+		return ms.responses.CommonError(ctx, errcode.MailIDRequired), nil
 	}
 
 	// 获取邮件信息
 	mail, err := ms.server.mailRepo.GetMailByID(deleteReq.MailId)
 	if err != nil {
 		log.Printf("获取邮件信息失败: %v", err)
-		return &proto.CommonResponse{
-			Code:    1003,
-			Message: "邮件不存在",
-		}, nil
+		return ms.responses.CommonError(ctx, errcode.MailNotFound), nil
 	}
 
 	// 检查邮件是否属于当前用户
 	if mail.ToUserID != toUserID {
-		return &proto.CommonResponse{
-			Code:    1004,
-			Message: "无权限删除此邮件",
-		}, nil
+		return ms.responses.CommonError(ctx, errcode.MailAccessDenied), nil
 	}
 
 	// 检查是否有未领取的奖励
 	if len(mail.Rewards) > 0 && !mail.IsClaimed {
-		return &proto.CommonResponse{
-			Code:    1005,
-			Message: "邮件有未领取的奖励，无法删除",
-		}, nil
+		return ms.responses.CommonError(ctx, errcode.MailHasUnclaimedRewards), nil
 	}
 
 	// 删除邮件
 	if err := ms.server.mailRepo.DeleteMail(deleteReq.MailId); err != nil {
 		log.Printf("删除邮件失败: %v", err)
 		if err.Error() == "邮件不存在" {
-			return &proto.CommonResponse{
-				Code:    1003,
-				Message: "邮件不存在",
-			}, nil
+			return ms.responses.CommonError(ctx, errcode.MailNotFound), nil
 		}
-		return &proto.CommonResponse{
-			Code:    1006,
-			Message: "删除邮件失败",
-		}, nil
+		return ms.responses.CommonError(ctx, errcode.DeleteMailFailed), nil
 	}
 
 	log.Printf("用户 %d 删除邮件 %d 成功", toUserID, deleteReq.MailId)
 
-	return &proto.CommonResponse{
-		Code:    0,
-		Message: "邮件删除成功",
-	}, nil
+	return ms.responses.CommonSuccess(ctx, "邮件删除成功", nil), nil
 }
 
 // SendMail 发送邮件
@@ -429,10 +359,7 @@ func (ms *MailService) SendMail(ctx context.Context, req *proto.SendMailRequest)
 	// 验证用户ID
 	userID := ctx.Value("user_id")
 	if userID == nil {
-		return &proto.CommonResponse{
-			Code:    1001,
-			Message: "用户未登录",
-		}, nil
+		return ms.responses.CommonError(ctx, errcode.NotLoggedIn), nil
 	}
 
 	fromUserID := userID.(uint64)
@@ -445,41 +372,33 @@ func (ms *MailService) SendMail(ctx context.Context, req *proto.SendMailRequest)
 
 	// 验证收件人ID
 	if sendReq.ToUserId == 0 {
-		return &proto.CommonResponse{
-			Code:    1002,
-			Message: "收件人ID不能为空",
-		}, nil
+		return ms.responses.CommonError(ctx, errcode.RecipientRequired), nil
 	}
 
 	// 不能给自己发邮件
 	if sendReq.ToUserId == fromUserID {
-		return &proto.CommonResponse{
-			Code:    1003,
-			Message: "不能给自己发邮件",
-		}, nil
+		return ms.responses.CommonError(ctx, errcode.CannotMailSelf), nil
 	}
 
 	// 验证邮件标题
 	if sendReq.Title == "" {
-		return &proto.CommonResponse{
-			Code:    1004,
-			Message: "邮件标题不能为空",
-		}, nil
+		return ms.responses.CommonError(ctx, errcode.MailTitleRequired), nil
 	}
 
 	// 验证邮件内容
 	if sendReq.Content == "" {
-		return &proto.CommonResponse{
-			Code:    1005,
-			Message: "邮件内容不能为空",
-		}, nil
+		return ms.responses.CommonError(ctx, errcode.MailContentRequired), nil
 	}
 
 	// TODO: 检查收件人是否存在
 	logger.Debug(fmt.Sprintf("Checking if user %d exists", sendReq.ToUserId))
 
 	// 生成邮件ID
-	mailID := ms.server.generateMailID()
+	mailID, err := ms.server.generateMailID()
+	if err != nil {
+		log.Printf("生成邮件ID失败: %v", err)
+		return ms.responses.CommonError(ctx, errcode.SendMailFailed), nil
+	}
 
 	// 转换奖励列表
 	rewards := make([]database.MailReward, 0, len(sendReq.Rewards))
@@ -511,20 +430,25 @@ func (ms *MailService) SendMail(ctx context.Context, req *proto.SendMailRequest)
 	// 保存邮件到数据库
 	if err := ms.server.mailRepo.CreateMail(mail); err != nil {
 		log.Printf("保存邮件失败: %v", err)
-		return &proto.CommonResponse{
-			Code:    1007,
-			Message: "发送邮件失败",
-		}, nil
+		return ms.responses.CommonError(ctx, errcode.SendMailFailed), nil
 	}
 
-	// TODO: 这里可以发送邮件通知给收件人
-	// 比如通过推送系统通知用户有新邮件
+	// 通知收件人有新邮件，带附件的邮件作为礼物通知
+	notifyType := NotifyMail
+	if len(rewards) > 0 {
+		notifyType = NotifyGift
+	}
+	if err := ms.server.notifier.Notify(&database.Notification{
+		UserID:     sendReq.ToUserId,
+		Type:       notifyType,
+		DedupKey:   fmt.Sprintf("%s:%d", notifyType, mailID),
+		FromUserID: fromUserID,
+		Data:       map[string]interface{}{"mail_id": strconv.FormatUint(mailID, 10), "title": sendReq.Title},
+	}); err != nil {
+		log.Printf("通知收件人 %d 失败: %v", sendReq.ToUserId, err)
+	}
 
 	log.Printf("用户 %d 发送邮件给用户 %d 成功，邮件ID: %d", fromUserID, sendReq.ToUserId, mailID)
 
-	return &proto.CommonResponse{
-		Code:    0,
-		Message: "邮件发送成功",
-		Data:    []byte(fmt.Sprintf("{\"mail_id\":%d}", mailID)),
-	}, nil
+	return ms.responses.CommonSuccess(ctx, "邮件发送成功", []byte(fmt.Sprintf("{\"mail_id\":%d}", mailID))), nil
 }
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"fmt"
+	"time"
+)
+
+// maintenanceKey 集群维护模式状态在Redis中的键，不存在表示未处于维护模式
+const maintenanceKey = "cluster:maintenance"
+
+// MaintenanceState 集群维护模式状态
+type MaintenanceState struct {
+	Enabled    bool   `json:"enabled"`
+	Message    string `json:"message,omitempty"`
+	OperatorID uint64 `json:"operator_id,omitempty"`
+	UpdatedAt  int64  `json:"updated_at"`
+}
+
+// getMaintenance 获取当前维护模式状态
+func (bs *BaseServer) getMaintenance() (*MaintenanceState, error) {
+	exists, err := bs.redisManager.Exists(maintenanceKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check maintenance state: %v", err)
+	}
+	if !exists {
+		return &MaintenanceState{}, nil
+	}
+
+	var state MaintenanceState
+	if err := bs.redisManager.GetObject(maintenanceKey, &state); err != nil {
+		return nil, fmt.Errorf("failed to get maintenance state: %v", err)
+	}
+	return &state, nil
+}
+
+// setMaintenance 开启或关闭维护模式，维护期间登录服务拒绝新的登录
+func (bs *BaseServer) setMaintenance(enabled bool, message string, operatorID uint64) (*MaintenanceState, error) {
+	state := &MaintenanceState{
+		Enabled:    enabled,
+		Message:    message,
+		OperatorID: operatorID,
+		UpdatedAt:  time.Now().Unix(),
+	}
+
+	if !enabled {
+		if err := bs.redisManager.Delete(maintenanceKey); err != nil {
+			return nil, fmt.Errorf("failed to clear maintenance state: %v", err)
+		}
+		return state, nil
+	}
+
+	if err := bs.redisManager.Set(maintenanceKey, state, 0); err != nil {
+		return nil, fmt.Errorf("failed to set maintenance state: %v", err)
+	}
+	return state, nil
+}
@@ -0,0 +1,284 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/errcode"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/matchmaking"
+	"github.com/phuhao00/lufy/internal/realtime"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// matchResultTTL 匹配结果保留的时间，玩家在此期间查询匹配状态可以获取到创建的房间
+const matchResultTTL = 5 * time.Minute
+
+// 匹配状态
+const (
+	matchStatusIdle    = "idle"
+	matchStatusQueued  = "queued"
+	matchStatusMatched = "matched"
+)
+
+// matchResult 玩家的匹配结果
+type matchResult struct {
+	roomID    uint64
+	node      string
+	gameType  int32
+	matchedAt time.Time
+}
+
+// newMatchmaker 创建匹配队列，不允许跨区域时区域条件不放宽
+func (ls *LobbyServer) newMatchmaker() *matchmaking.Matchmaker {
+	config := ls.config.Matchmaking
+	if !ls.config.Geo.AllowCrossRegion {
+		config.Region.Disabled = false
+		config.Region.Max = 0
+	}
+	return matchmaking.NewMatchmaker(&config, ls.createMatchRoom)
+}
+
+// matchPlayer 根据玩家信息和客户端测量的延迟创建排队的玩家，评分使用玩家等级
+func (ls *LobbyServer) matchPlayer(user *database.User, latencies map[string]int32) (*matchmaking.Player, error) {
+	for node, latency := range latencies {
+		if node == "" || latency < 0 {
+			return nil, fmt.Errorf("invalid latency %d for node %q", latency, node)
+		}
+	}
+
+	blocked, err := database.NewChatRepository(ls.mongoManager).GetBlockedUsers(user.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blocked users of %d: %v", user.UserID, err)
+	}
+	blockedSet := make(map[uint64]bool, len(blocked))
+	for _, targetID := range blocked {
+		blockedSet[targetID] = true
+	}
+
+	return &matchmaking.Player{
+		UserID:  user.UserID,
+		Rating:  float64(user.Level),
+		Region:  user.Region,
+		Latency: latencies,
+		Blocked: blockedSet,
+		Shadow:  user.ShadowBanned,
+	}, nil
+}
+
+// createMatchRoom 为匹配到的玩家创建房间并让他们直接入座，等待最久的玩家为房主。
+// 按票据顺序入座，同一队伍的成员座位相邻并带有队伍ID
+func (ls *LobbyServer) createMatchRoom(match *matchmaking.Match) error {
+	userRepo := database.NewUserRepository(ls.mongoManager)
+	var players []database.RoomPlayer
+	region := ""
+	for _, ticket := range match.Tickets {
+		for _, player := range ticket.Players {
+			user, err := userRepo.GetByUserID(player.UserID)
+			if err != nil {
+				return fmt.Errorf("failed to get user %d: %v", player.UserID, err)
+			}
+			status := int32(0)
+			if len(players) == 0 {
+				status = 1 // 房主默认准备状态
+				region = user.Region
+			}
+			players = append(players, database.RoomPlayer{
+				UserID:   player.UserID,
+				Nickname: user.Nickname,
+				Level:    user.Level,
+				Status:   status,
+				JoinTime: time.Now().Unix(),
+				PartyID:  ticket.PartyID,
+			})
+		}
+	}
+
+	roomID := ls.generateRoomID()
+	room := &database.Room{
+		RoomID:         roomID,
+		RoomName:       fmt.Sprintf("Match %d", roomID),
+		GameType:       match.GameType,
+		MaxPlayers:     int32(len(players)),
+		CurrentPlayers: int32(len(players)),
+		Status:         0, // 等待中
+		OwnerID:        players[0].UserID,
+		Players:        players,
+		Region:         region,
+		WorldID:        match.WorldID,
+		Node:           match.Node,
+	}
+	if err := ls.roomRepo.CreateRoom(room); err != nil {
+		return err
+	}
+	ls.realtime.Adjust(realtime.Delta{GameType: room.GameType, Rooms: 1, Players: int64(len(players))})
+
+	now := time.Now()
+	ls.matchMutex.Lock()
+	for userID, result := range ls.matchResults {
+		if now.Sub(result.matchedAt) > matchResultTTL {
+			delete(ls.matchResults, userID)
+		}
+	}
+	for _, player := range players {
+		ls.matchResults[player.UserID] = &matchResult{
+			roomID:    roomID,
+			node:      match.Node,
+			gameType:  match.GameType,
+			matchedAt: now,
+		}
+	}
+	ls.matchMutex.Unlock()
+
+	for _, player := range players {
+		ls.timeline.RoomJoined(player.UserID, roomID, roomJoinMatch)
+	}
+
+	logger.Info(fmt.Sprintf("Matched %d players into room %d (game type %d, node %q)",
+		len(players), roomID, match.GameType, match.Node))
+	return nil
+}
+
+// enqueueMatch 把票据加入匹配队列，并清除玩家之前的匹配结果
+func (ls *LobbyServer) enqueueMatch(ticket *matchmaking.Ticket) error {
+	if err := ls.matchmaker.Enqueue(ticket); err != nil {
+		return err
+	}
+
+	ls.matchMutex.Lock()
+	for _, player := range ticket.Players {
+		delete(ls.matchResults, player.UserID)
+	}
+	ls.matchMutex.Unlock()
+	return nil
+}
+
+// matchError 把匹配队列的错误转换为错误码
+func matchError(err error) error {
+	switch {
+	case errors.Is(err, matchmaking.ErrQueued):
+		return errcode.MatchQueued
+	case errors.Is(err, matchmaking.ErrTooLarge), errors.Is(err, matchmaking.ErrInvalid):
+		return errcode.MatchInvalid.WithDetail(err.Error())
+	}
+	return errcode.Internal.Wrap(err)
+}
+
+// StartMatch 开始匹配，客户端可以附带到各游戏节点的延迟，匹配时选择所有玩家延迟都较低的节点。
+// 在队伍中时只有队长可以开始匹配，整个队伍作为一个票据排队，队员的延迟按队长上报的计算
+func (ls *LobbyService) StartMatch(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	userID := req.Header.GetUserId()
+	if userID == 0 {
+		return ls.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
+	}
+
+	matchReq, err := Bind[proto.MatchRequest](req.Data)
+	if err != nil {
+		logger.Error(fmt.Sprintf("StartMatch: failed to unmarshal request: %v", err))
+		return ls.responses.Error(ctx, req.Header, errcode.InvalidRequest), nil
+	}
+
+	userIDs := []uint64{userID}
+	partyID := uint64(0)
+	if p, ok := ls.server.parties.Get(userID); ok {
+		if p.LeaderID != userID {
+			return ls.responses.Error(ctx, req.Header, errcode.NotPartyLeader), nil
+		}
+		userIDs, partyID = p.UserIDs(), p.ID
+	}
+
+	userRepo := database.NewUserRepository(ls.server.mongoManager)
+	var leader *database.User
+	var players []*matchmaking.Player
+	for _, memberID := range userIDs {
+		user, err := userRepo.GetByUserID(memberID)
+		if err != nil {
+			logger.Error(fmt.Sprintf("StartMatch: failed to get user %d: %v", memberID, err))
+			return ls.responses.Error(ctx, req.Header, errcode.UserNotFound), nil
+		}
+		if leader == nil {
+			leader = user
+		}
+
+		player, err := ls.server.matchPlayer(user, matchReq.GetLatencies())
+		if err != nil {
+			logger.Warn(fmt.Sprintf("StartMatch: user %d: %v", memberID, err))
+			return ls.responses.Error(ctx, req.Header, errcode.MatchInvalid.WithDetail(err.Error())), nil
+		}
+		players = append(players, player)
+	}
+
+	ticket := &matchmaking.Ticket{
+		PartyID:  partyID,
+		GameType: matchReq.GetGameType(),
+		WorldID:  ls.server.config.World.Resolve(leader.WorldID),
+		Players:  players,
+		Priority: ls.server.entitlements.MatchPriority(userIDs),
+	}
+	if err := ls.server.enqueueMatch(ticket); err != nil {
+		return ls.responses.Error(ctx, req.Header, matchError(err)), nil
+	}
+
+	if partyID != 0 {
+		ls.server.notifyParty(partyID, userIDs, userID)
+	}
+
+	logger.Debug(fmt.Sprintf("User %d queued %d players for game type %d (ticket %s)",
+		userID, len(players), ticket.GameType, ticket.ID))
+	return ls.matchStatus(ctx, req.Header, userID)
+}
+
+// CancelMatch 取消匹配，队伍中任何成员取消时整个队伍离开队列
+func (ls *LobbyService) CancelMatch(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	userID := req.Header.GetUserId()
+	if userID == 0 {
+		return ls.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
+	}
+
+	ticket, ok := ls.server.matchmaker.Cancel(userID)
+	if !ok {
+		return ls.responses.Error(ctx, req.Header, errcode.MatchNotQueued), nil
+	}
+
+	logger.Debug(fmt.Sprintf("User %d cancelled matchmaking ticket %s", userID, ticket.ID))
+	return ls.responses.Success(ctx, req.Header, "success", nil), nil
+}
+
+// GetMatchStatus 获取匹配状态，匹配成功后返回创建的房间
+func (ls *LobbyService) GetMatchStatus(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	userID := req.Header.GetUserId()
+	if userID == 0 {
+		return ls.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
+	}
+	return ls.matchStatus(ctx, req.Header, userID)
+}
+
+// matchStatus 构造玩家当前的匹配状态响应
+func (ls *LobbyService) matchStatus(ctx context.Context, header *proto.MessageHeader, userID uint64) (*proto.BaseResponse, error) {
+	status := &proto.MatchStatusResponse{Status: matchStatusIdle}
+	if ticket, ok := ls.server.matchmaker.Queued(userID); ok {
+		status.Status = matchStatusQueued
+		status.GameType = ticket.GameType
+		status.WaitSeconds = int32(time.Since(ticket.EnqueuedAt).Seconds())
+	} else {
+		ls.server.matchMutex.Lock()
+		result, ok := ls.server.matchResults[userID]
+		ls.server.matchMutex.Unlock()
+		if ok && time.Since(result.matchedAt) <= matchResultTTL {
+			status.Status = matchStatusMatched
+			status.GameType = result.gameType
+			status.RoomId = result.roomID
+			status.Node = result.node
+		}
+	}
+
+	data, err := proto.Marshal(status)
+	if err != nil {
+		logger.Error(fmt.Sprintf("matchStatus: failed to marshal response: %v", err))
+		return ls.responses.Error(ctx, header, errcode.Internal), nil
+	}
+	return ls.responses.Success(ctx, header, "success", data), nil
+}
@@ -0,0 +1,289 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/chatpolicy"
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/denorm"
+	"github.com/phuhao00/lufy/internal/errcode"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/mq"
+	"github.com/phuhao00/lufy/internal/naming"
+	"github.com/phuhao00/lufy/internal/search"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// nameHistoryLimit GM查看改名记录和按名称查找玩家的条数
+const nameHistoryLimit = 50
+
+// newNaming 创建改名服务，屏蔽词与聊天共用chat_policy.blocked_words
+func (bs *BaseServer) newNaming() *naming.Service {
+	validator := naming.NewValidator(&bs.config.Naming, chatpolicy.NewWordFilter(bs.config.ChatPolicy.BlockedWords))
+	return naming.NewService(&bs.config.Naming, validator,
+		database.NewUserRepository(bs.mongoManager),
+		database.NewNameRepository(bs.mongoManager),
+		database.NewLedgerRepository(bs.mongoManager))
+}
+
+// renameError 改名失败时返回给客户端的错误，内部错误返回nil
+func renameError(err error, field string) error {
+	switch {
+	case errors.Is(err, naming.ErrInvalid), errors.Is(err, naming.ErrUnchanged):
+		return errcode.InvalidName.WithDetail(err.Error())
+	case errors.Is(err, naming.ErrTaken):
+		if field == database.NameFieldUsername {
+			return errcode.UsernameTaken
+		}
+		return errcode.NicknameTaken
+	case errors.Is(err, naming.ErrCooldown):
+		return errcode.RenameCooldown.WithDetail(err.Error())
+	case errors.Is(err, naming.ErrNotAllowed):
+		return errcode.RenameNotAllowed
+	case errors.Is(err, naming.ErrInsufficient):
+		return errcode.InsufficientBalance
+	}
+	return nil
+}
+
+// checkUsername 游客账号的用户名前缀保留给系统生成
+func checkUsername(change *database.NameChange) error {
+	if change.Field == database.NameFieldUsername && strings.HasPrefix(change.NewName, guestUsernamePrefix) {
+		return fmt.Errorf("%w: username is reserved", naming.ErrInvalid)
+	}
+	return nil
+}
+
+// renamed 改名成功后更新各处保存的名称副本：用户缓存、搜索索引、按配置更新房间和游戏记录中的玩家和观战者、
+// 各大厅节点内存中的队伍成员，并通知好友。修改用户名只需更新缓存和搜索索引
+func (bs *BaseServer) renamed(notifier *Notifier, index *search.Service, change *database.NameChange) {
+	database.NewUserCache(bs.redisManager).DeleteUserInfo(change.UserID)
+	if user, err := database.NewUserRepository(bs.mongoManager).GetByUserID(change.UserID); err == nil {
+		index.IndexUser(user)
+	}
+	if change.Field != database.NameFieldNickname {
+		return
+	}
+
+	bs.denorm.Refresh(denorm.Change{UserID: change.UserID, Nickname: change.NewName})
+
+	event := &proto.UserRenamedEvent{UserId: change.UserID, OldNickname: change.OldName, Nickname: change.NewName}
+	if err := bs.messageBroker.SendCommand("", mq.SYS_CMD_USER_RENAMED, event); err != nil {
+		logger.Error(fmt.Sprintf("Failed to broadcast rename of user %d: %v", change.UserID, err))
+	}
+
+	friends, err := database.NewFriendRepository(bs.mongoManager).GetFriends(change.UserID)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to get friends of user %d: %v", change.UserID, err))
+		return
+	}
+	for _, friend := range friends {
+		if err := notifier.Notify(&database.Notification{
+			UserID:     friend.FriendID,
+			Type:       NotifyFriendRenamed,
+			DedupKey:   fmt.Sprintf("%s:%d", NotifyFriendRenamed, change.UserID),
+			FromUserID: change.UserID,
+			Data: map[string]interface{}{
+				"user_id":      strconv.FormatUint(change.UserID, 10),
+				"old_nickname": change.OldName,
+				"nickname":     change.NewName,
+			},
+		}); err != nil {
+			logger.Error(fmt.Sprintf("Failed to notify user %d of friend %d rename: %v", friend.FriendID, change.UserID, err))
+		}
+	}
+}
+
+// handleUserRenamed 玩家修改昵称后更新本节点队伍中的成员昵称，并通知队伍其他成员
+func (ls *LobbyServer) handleUserRenamed(msg *mq.SystemMessage) error {
+	event, err := mq.Command[proto.UserRenamedEvent](msg)
+	if err != nil {
+		return err
+	}
+	ls.denorm.Forget(event.UserId)
+	if p, ok := ls.parties.Rename(event.UserId, event.Nickname); ok {
+		ls.notifyParty(p.ID, p.UserIDs(), event.UserId)
+	}
+	return nil
+}
+
+// handleUserRenamed 清除本地缓存的玩家资料，异步对局列表立即使用新昵称
+func (gs *GameServer) handleUserRenamed(msg *mq.SystemMessage) error {
+	event, err := mq.Command[proto.UserRenamedEvent](msg)
+	if err != nil {
+		return err
+	}
+	gs.denorm.Forget(event.UserId)
+	return nil
+}
+
+// RenameNickname 修改自己的昵称，按客户端语言的规则校验，两次修改间隔不能小于naming.rename_cooldown
+func (ls *LobbyService) RenameNickname(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	return ls.rename(ctx, req, database.NameFieldNickname)
+}
+
+// RenameUsername 修改自己的用户名，需开启naming.username_rename，游客账号不能修改
+func (ls *LobbyService) RenameUsername(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	return ls.rename(ctx, req, database.NameFieldUsername)
+}
+
+// rename 玩家修改昵称或用户名，免费次数用完后按naming.rename_cost扣费
+func (ls *LobbyService) rename(ctx context.Context, req *proto.BaseRequest, field string) (*proto.BaseResponse, error) {
+	userID := req.Header.GetUserId()
+	if userID == 0 {
+		return ls.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
+	}
+
+	renameReq, err := Bind[proto.RenameRequest](req.Data)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Rename: failed to unmarshal request: %v", err))
+		return ls.responses.Error(ctx, req.Header, errcode.InvalidRequest), nil
+	}
+
+	userRepo := database.NewUserRepository(ls.server.mongoManager)
+	user, err := userRepo.GetByUserID(userID)
+	if err != nil {
+		return ls.responses.Error(ctx, req.Header, errcode.UserNotFound), nil
+	}
+
+	change := &database.NameChange{
+		UserID:  userID,
+		Field:   field,
+		NewName: renameReq.GetNickname(),
+		By:      database.RenameByPlayer,
+	}
+	if field == database.NameFieldUsername {
+		change.NewName = renameReq.GetUsername()
+	}
+	err = checkUsername(change)
+	if err == nil {
+		err = ls.server.naming.Rename(change, user.Language, time.Now())
+	}
+	if err != nil {
+		if code := renameError(err, field); code != nil {
+			logger.Debug(fmt.Sprintf("Rename: user %d %s: %v", userID, field, err))
+			return ls.responses.Error(ctx, req.Header, code), nil
+		}
+		logger.Error(fmt.Sprintf("Rename: failed to rename %s of user %d: %v", field, userID, err))
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+	ls.server.renamed(ls.server.notifier, ls.server.search, change)
+	logger.Info(fmt.Sprintf("User %d renamed %s from %q to %q", userID, field, change.OldName, change.NewName))
+
+	resp := &proto.RenameResponse{Currency: change.Currency, Cost: change.Cost}
+	if field == database.NameFieldUsername {
+		resp.Username = change.NewName
+	} else {
+		resp.Nickname = change.NewName
+	}
+	if next, err := ls.server.naming.NextRename(userID, field); err == nil && !next.IsZero() {
+		resp.NextRenameAt = next.Unix()
+	}
+	data, err := proto.Marshal(resp)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Rename: failed to marshal response: %v", err))
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+	return ls.responses.Success(ctx, req.Header, "success", data), nil
+}
+
+// renameCommand GM修改玩家昵称或用户名，不受冷却时间限制且不收费：
+// rename <用户ID> <昵称> [原因]，rename_username <用户ID> <用户名> [原因]
+func (gs *GMServer) renameCommand(gmUserID uint64, field string, args []string) (string, error) {
+	if len(args) < 2 {
+		return "", fmt.Errorf("改名命令需要用户ID和新名称参数")
+	}
+	userID, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("无效的用户ID: %s", args[0])
+	}
+
+	change := &database.NameChange{
+		UserID:   userID,
+		Field:    field,
+		NewName:  args[1],
+		By:       database.RenameByGM,
+		GMUserID: gmUserID,
+		Reason:   strings.Join(args[2:], " "),
+	}
+	if err := checkUsername(change); err != nil {
+		return "", err
+	}
+	if err := gs.naming.Rename(change, "", time.Now()); err != nil {
+		return "", err
+	}
+	gs.renamed(gs.notifier, gs.search, change)
+
+	gs.gmRepo.LogGMAction(gmUserID, "rename_"+field, userID, fmt.Sprintf("%s -> %s, 原因: %s", change.OldName, change.NewName, change.Reason))
+	return fmt.Sprintf("用户 %d 的%s已从 %s 修改为 %s", userID, field, change.OldName, change.NewName), nil
+}
+
+// nameHistoryCommand 查看玩家的改名记录：name_history <用户ID>
+func (gs *GMServer) nameHistoryCommand(args []string) (string, error) {
+	if len(args) < 1 {
+		return "", fmt.Errorf("name_history命令需要用户ID参数")
+	}
+	userID, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("无效的用户ID: %s", args[0])
+	}
+
+	changes, err := gs.naming.History(userID, nameHistoryLimit)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// nameSearchCommand 按名称查找玩家，包括当前使用该昵称或用户名的玩家和曾用过该名称的玩家：name_search <名称>
+func (gs *GMServer) nameSearchCommand(args []string) (string, error) {
+	if len(args) < 1 {
+		return "", fmt.Errorf("name_search命令需要名称参数")
+	}
+	name := strings.Join(args, " ")
+
+	type match struct {
+		UserID   uint64 `json:"user_id"`
+		Username string `json:"username"`
+		Nickname string `json:"nickname"`
+	}
+	current := make([]match, 0)
+	userRepo := database.NewUserRepository(gs.mongoManager)
+	users, err := userRepo.FindByNickname(name, nameHistoryLimit)
+	if err != nil {
+		return "", err
+	}
+	if user, err := userRepo.GetByUsername(name); err == nil {
+		users = append(users, user)
+	}
+	seen := make(map[uint64]bool, len(users))
+	for _, user := range users {
+		if !seen[user.UserID] {
+			seen[user.UserID] = true
+			current = append(current, match{UserID: user.UserID, Username: user.Username, Nickname: user.Nickname})
+		}
+	}
+
+	history, err := gs.naming.FindByOldName(name, nameHistoryLimit)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"current": current,
+		"history": history,
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,199 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/errcode"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/mq"
+	"github.com/phuhao00/lufy/internal/network"
+	"github.com/phuhao00/lufy/internal/news"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// newNews 创建资讯服务
+func (bs *BaseServer) newNews() *news.Service {
+	service := news.NewService(&bs.config.News, database.NewNewsRepository(bs.mongoManager), bs.newSegments())
+	bs.AddWarmup("news", func(ctx context.Context) error {
+		return service.Warm(time.Now())
+	})
+	return service
+}
+
+// newsItemInfo 转换为客户端使用的资讯信息
+func newsItemInfo(localized *news.Localized) *proto.NewsItemInfo {
+	return &proto.NewsItemInfo{
+		Id:        localized.ID,
+		Kind:      localized.Kind,
+		Title:     localized.Title,
+		Summary:   localized.Summary,
+		Body:      localized.Body,
+		ImageUrl:  localized.ImageURL,
+		Link:      localized.Link,
+		Priority:  localized.Priority,
+		StartTime: localized.StartAt.Unix(),
+		EndTime:   localized.EndAt.Unix(),
+	}
+}
+
+// handleNewsFeed 由网关直接用本地缓存的资讯响应，不转发到后端服务。
+// 客户端带上的版本与当前版本相同时只返回版本
+func (gmh *GatewayMessageHandler) handleNewsFeed(conn *network.Connection, request *proto.BaseRequest) error {
+	var feedReq proto.NewsFeedRequest
+	if err := conn.GetCodec().Unmarshal(request.Data, &feedReq); err != nil {
+		return fmt.Errorf("failed to unmarshal news feed request: %v", err)
+	}
+
+	language := request.Header.GetLanguage()
+	if language == "" {
+		language = conn.Language
+	}
+	now := time.Now()
+	items, version, err := gmh.news.Feed(conn.UserID, feedReq.GetKind(), language, now)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to load news feed for connection %d: %v", conn.ID, err))
+		return gmh.sendError(conn, request, -2, "news service not available")
+	}
+
+	resp := &proto.NewsFeedResponse{Version: version, ServerTime: now.Unix()}
+	if feedReq.GetVersion() == version {
+		resp.NotModified = true
+	} else {
+		for _, localized := range items {
+			resp.Items = append(resp.Items, newsItemInfo(localized))
+		}
+	}
+	return gmh.sendResponse(conn, request, 0, "success", resp)
+}
+
+// handleNewsChanged 资讯变更后清除网关的资讯缓存
+func (gs *GatewayServer) handleNewsChanged(msg *mq.SystemMessage) error {
+	gs.messageHandler.news.Invalidate()
+	return nil
+}
+
+// notifyNewsChanged 通知各网关清除资讯缓存，失败时网关在缓存到期后更新
+func (gs *GMServer) notifyNewsChanged() {
+	if err := gs.messageBroker.BroadcastSystemMessage(mq.SYS_CMD_NEWS_CHANGED, map[string]interface{}{}); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to broadcast news change: %v", err))
+	}
+}
+
+// createNewsItem 创建资讯并写入GM操作日志
+func (gs *GMServer) createNewsItem(gmUserID uint64, item *database.NewsItem) error {
+	item.CreatedBy = gmUserID
+	if err := gs.news.Create(item); err != nil {
+		return errcode.NewsItemInvalid.WithDetail(err.Error())
+	}
+	gs.notifyNewsChanged()
+
+	gs.gmRepo.LogGMAction(gmUserID, "create_news_item", 0,
+		fmt.Sprintf("资讯: %s, 类型: %s, 分群: %s, 开始: %s, 结束: %s, 标题: %s",
+			item.ID.Hex(), item.Kind, item.Segment, item.StartAt.Format(time.RFC3339),
+			item.EndAt.Format(time.RFC3339), item.Contents[item.Language].Title))
+	return nil
+}
+
+// deleteNewsItem 删除资讯并写入GM操作日志
+func (gs *GMServer) deleteNewsItem(gmUserID uint64, itemID string) error {
+	deleted, err := gs.news.Delete(itemID)
+	if err != nil {
+		return errcode.NewsItemInvalid.WithDetail(err.Error())
+	}
+	if !deleted {
+		return errcode.NewsItemNotFound
+	}
+	gs.notifyNewsChanged()
+	gs.gmRepo.LogGMAction(gmUserID, "delete_news_item", 0, fmt.Sprintf("资讯: %s", itemID))
+	return nil
+}
+
+// newsJSON 资讯详情，未指定资讯时为最近创建的资讯列表
+func (gs *GMServer) newsJSON(itemID string, limit int64) ([]byte, error) {
+	if itemID == "" {
+		if limit <= 0 || limit > 100 {
+			limit = 20
+		}
+		items, err := gs.news.List(limit)
+		if err != nil {
+			return nil, errcode.Internal.Wrap(err)
+		}
+		return json.Marshal(items)
+	}
+
+	item, err := gs.news.Get(itemID)
+	if err != nil {
+		return nil, errcode.NewsItemInvalid.WithDetail(err.Error())
+	}
+	if item == nil {
+		return nil, errcode.NewsItemNotFound
+	}
+	return json.Marshal(item)
+}
+
+// CreateNewsItem 创建资讯，开始时间和结束时间为Unix秒，未指定时立即开始并按配置的时长结束
+func (gs *GMService) CreateNewsItem(ctx context.Context, req *proto.NewsItemRequest) (*proto.CommonResponse, error) {
+	gmUserID := ctx.Value("user_id")
+	if gmUserID == nil {
+		return gs.responses.CommonError(ctx, errcode.NotLoggedIn), nil
+	}
+
+	item := &database.NewsItem{
+		Kind:     req.GetKind(),
+		Language: req.GetLanguage(),
+		Contents: make(map[string]database.NewsContent, len(req.GetContents())),
+		Segment:  req.GetSegment(),
+		Priority: req.GetPriority(),
+	}
+	for _, content := range req.GetContents() {
+		item.Contents[content.GetLanguage()] = database.NewsContent{
+			Title:   content.GetTitle(),
+			Summary: content.GetSummary(),
+			Body:    content.GetBody(),
+			Image:   content.GetImage(),
+			Link:    content.GetLink(),
+		}
+	}
+	if req.GetStartTime() > 0 {
+		item.StartAt = time.Unix(req.GetStartTime(), 0)
+	}
+	if req.GetEndTime() > 0 {
+		item.EndAt = time.Unix(req.GetEndTime(), 0)
+	}
+
+	if err := gs.server.createNewsItem(gmUserID.(uint64), item); err != nil {
+		return gs.responses.CommonError(ctx, err), nil
+	}
+	return gs.responses.CommonSuccess(ctx, "资讯创建成功",
+		[]byte(fmt.Sprintf("{\"item_id\":\"%s\"}", item.ID.Hex()))), nil
+}
+
+// ListNewsItems 获取资讯详情，未指定资讯时获取最近创建的资讯
+func (gs *GMService) ListNewsItems(ctx context.Context, req *proto.NewsQuery) (*proto.CommonResponse, error) {
+	if ctx.Value("user_id") == nil {
+		return gs.responses.CommonError(ctx, errcode.NotLoggedIn), nil
+	}
+
+	data, err := gs.server.newsJSON(req.GetItemId(), int64(req.GetLimit()))
+	if err != nil {
+		return gs.responses.CommonError(ctx, err), nil
+	}
+	return gs.responses.CommonSuccess(ctx, "success", data), nil
+}
+
+// DeleteNewsItem 删除资讯，各网关收到通知后不再返回
+func (gs *GMService) DeleteNewsItem(ctx context.Context, req *proto.NewsQuery) (*proto.CommonResponse, error) {
+	gmUserID := ctx.Value("user_id")
+	if gmUserID == nil {
+		return gs.responses.CommonError(ctx, errcode.NotLoggedIn), nil
+	}
+
+	if err := gs.server.deleteNewsItem(gmUserID.(uint64), req.GetItemId()); err != nil {
+		return gs.responses.CommonError(ctx, err), nil
+	}
+	return gs.responses.CommonSuccess(ctx, "资讯已删除", nil), nil
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/i18n"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/mq"
+	"github.com/phuhao00/lufy/internal/network"
+	"github.com/phuhao00/lufy/internal/push"
+	"github.com/phuhao00/lufy/internal/settings"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// 通知类型
+const (
+	NotifyFriendRequest  = "friend_request"
+	NotifyFriendAccepted = "friend_accepted"
+	NotifyMail           = "mail"
+	NotifyGift           = "gift" // 带附件的邮件
+	NotifyRoomInvite     = "room_invite"
+	NotifyTurnReminder   = "turn_reminder" // 异步对局轮到玩家操作
+	NotifyNotice         = "notice"        // 定向公告
+	NotifyPartyInvite    = "party_invite"
+	NotifyPartyUpdate    = "party_update" // 队伍成员或队长变化
+	NotifyPartyChat      = "party_chat"
+	NotifyFriendRenamed  = "friend_renamed" // 好友修改了昵称
+)
+
+// notificationPushMsgID 通知推送的消息ID，服务器主动推送时写在消息头中
+const notificationPushMsgID = 2001
+
+// 离线通知默认设置
+const (
+	defaultNotificationTTL       = 7 * 24 * time.Hour
+	defaultNotificationBatchSize = 100
+)
+
+// NotificationConfig 离线通知设置
+type NotificationConfig struct {
+	TTL       time.Duration `yaml:"ttl"`        // 通知保留时间，未配置时为7天
+	BatchSize int64         `yaml:"batch_size"` // 每次推送的最大条数，未配置时为100
+}
+
+// Notifier 玩家通知，先保存到离线通知队列，玩家在线时通知所在网关立即投递，离线时在下次登录时投递并发送移动推送。
+// 玩家关闭的通知频道不投递也不推送
+type Notifier struct {
+	server   *BaseServer
+	repo     *database.NotificationRepository
+	settings *settings.Service
+	mobile   *push.Service
+}
+
+// NewNotifier 创建玩家通知
+func NewNotifier(server *BaseServer) *Notifier {
+	notifier := &Notifier{
+		server:   server,
+		repo:     database.NewNotificationRepository(server.mongoManager),
+		settings: server.newSettings(),
+	}
+
+	if server.config.Push.Enabled {
+		localizer := i18n.NewI18nManager("en")
+		if err := localizer.LoadLanguage("zh-CN"); err != nil {
+			logger.Warn(fmt.Sprintf("Failed to load zh-CN push texts: %v", err))
+		}
+		service, err := push.NewService(&server.config.Push, database.NewDeviceTokenRepository(server.mongoManager), server.redisManager, localizer, notifier.settings)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to create push service, mobile push disabled: %v", err))
+		} else {
+			notifier.mobile = service
+		}
+	}
+
+	return notifier
+}
+
+// Notify 发送通知，未指定去重键时每条通知单独保存
+func (n *Notifier) Notify(notification *database.Notification) error {
+	ttl := n.server.config.Notification.TTL
+	if ttl <= 0 {
+		ttl = defaultNotificationTTL
+	}
+	notification.ExpireAt = time.Now().Add(ttl)
+	if notification.DedupKey == "" {
+		notification.DedupKey = fmt.Sprintf("%s:%d", notification.Type, time.Now().UnixNano())
+	}
+
+	created, err := n.repo.Enqueue(notification)
+	if err != nil {
+		return err
+	}
+	if !created {
+		logger.Debug(fmt.Sprintf("Merged duplicate %s notification for user %d", notification.Type, notification.UserID))
+	}
+
+	// 离线玩家等待下次登录，合并的重复通知不再推送
+	nodeID, err := database.NewUserCache(n.server.redisManager).GetUserOnline(notification.UserID)
+	if err != nil || nodeID == "" {
+		if created && n.mobile != nil && push.Supports(notification.Type) {
+			go n.mobile.Notify(notification.UserID, notification.Type, notification.Data)
+		}
+		return nil
+	}
+
+	// 用户ID超出JSON数字的精度，按字符串传递
+	return n.server.messageBroker.SendToNode(nodeID, mq.SYS_CMD_DELIVER_NOTIFICATIONS, map[string]interface{}{
+		"user_id": strconv.FormatUint(notification.UserID, 10),
+	})
+}
+
+// notificationChannel 通知类型所属的通知频道，不属于任何频道的通知不能关闭
+func notificationChannel(notificationType string) string {
+	switch notificationType {
+	case NotifyMail, NotifyGift:
+		return settings.ChannelMailBadge
+	}
+	return ""
+}
+
+// Deliver 把连接上玩家的待投递通知推送给客户端，推送成功后从队列删除。
+// 玩家关闭的频道的通知不推送，直接从队列删除
+func (n *Notifier) Deliver(conn *network.Connection) error {
+	batchSize := n.server.config.Notification.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultNotificationBatchSize
+	}
+
+	prefs, err := n.settings.Get(conn.UserID)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Failed to get notification preferences of user %d: %v", conn.UserID, err))
+	}
+
+	for {
+		pending, err := n.repo.GetPending(conn.UserID, batchSize)
+		if err != nil {
+			return err
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+
+		push := &proto.NotificationPush{}
+		ids := make([]primitive.ObjectID, 0, len(pending))
+		for _, notification := range pending {
+			ids = append(ids, notification.ID)
+			if channel := notificationChannel(notification.Type); channel != "" && prefs != nil && prefs.Mutes[channel] {
+				continue
+			}
+
+			data, err := json.Marshal(notification.Data)
+			if err != nil {
+				return fmt.Errorf("failed to marshal notification data: %v", err)
+			}
+			push.Notifications = append(push.Notifications, &proto.Notification{
+				Id:         notification.ID.Hex(),
+				Type:       notification.Type,
+				FromUserId: notification.FromUserID,
+				Data:       data,
+				CreatedAt:  notification.CreatedAt.Unix(),
+			})
+		}
+
+		if len(push.Notifications) > 0 {
+			if err := pushMessage(conn, notificationPushMsgID, push); err != nil {
+				return err
+			}
+		}
+		if err := n.repo.Remove(ids); err != nil {
+			return err
+		}
+		logger.Debug(fmt.Sprintf("Delivered %d notifications to user %d, %d muted", len(push.Notifications), conn.UserID, len(ids)-len(push.Notifications)))
+
+		if int64(len(pending)) < batchSize {
+			return nil
+		}
+	}
+}
+
+// pushMessage 按连接协商的编解码器向客户端推送消息，msgID写在消息头中
+func pushMessage(conn *network.Connection, msgID uint32, msg proto.Message) error {
+	codec := conn.GetCodec()
+	data, err := codec.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal push message %d: %v", msgID, err)
+	}
+
+	response := &proto.BaseResponse{
+		Header: &proto.MessageHeader{
+			MsgId:  msgID,
+			UserId: conn.UserID,
+		},
+		Data:       data,
+		ServerTime: time.Now().Unix(),
+	}
+	responseBytes, err := codec.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("failed to marshal push message %d: %v", msgID, err)
+	}
+
+	message, err := network.EncodeFrame(responseBytes)
+	if err != nil {
+		return err
+	}
+	return conn.Write(message)
+}
@@ -0,0 +1,305 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/errcode"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/party"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// maxPartyMessageLength 队伍聊天消息的最大字符数
+const maxPartyMessageLength = 200
+
+// partyError 把队伍操作的错误转换为错误码
+func partyError(err error) error {
+	switch {
+	case errors.Is(err, party.ErrNotInParty):
+		return errcode.NotInParty
+	case errors.Is(err, party.ErrInParty):
+		return errcode.AlreadyInParty
+	case errors.Is(err, party.ErrNotLeader):
+		return errcode.NotPartyLeader
+	case errors.Is(err, party.ErrFull):
+		return errcode.PartyFull
+	case errors.Is(err, party.ErrNoInvite):
+		return errcode.PartyInviteInvalid
+	}
+	return errcode.Internal.Wrap(err)
+}
+
+// partyMember 根据玩家信息创建队伍成员
+func (ls *LobbyServer) partyMember(userID uint64) (party.Member, error) {
+	user, err := database.NewUserRepository(ls.mongoManager).GetByUserID(userID)
+	if err != nil {
+		return party.Member{}, err
+	}
+	return party.Member{UserID: user.UserID, Nickname: user.Nickname, Level: user.Level}, nil
+}
+
+// leaveMatch 队伍成员变化时取消队伍正在进行的匹配，避免按旧的成员组局
+func (ls *LobbyServer) leaveMatch(userID uint64) {
+	if ticket, ok := ls.matchmaker.Cancel(userID); ok {
+		logger.Debug(fmt.Sprintf("Cancelled matchmaking ticket %s after party change by user %d", ticket.ID, userID))
+	}
+}
+
+// notifyParty 通知队伍成员队伍有变化，不通知发起变化的玩家。
+// 同一队伍的变化通知合并为一条，客户端收到后重新获取队伍
+func (ls *LobbyServer) notifyParty(partyID uint64, userIDs []uint64, fromUserID uint64) {
+	for _, userID := range userIDs {
+		if userID == fromUserID {
+			continue
+		}
+		if err := ls.notifier.Notify(&database.Notification{
+			UserID:     userID,
+			Type:       NotifyPartyUpdate,
+			DedupKey:   fmt.Sprintf("%s:%d", NotifyPartyUpdate, partyID),
+			FromUserID: fromUserID,
+			Data:       map[string]interface{}{"party_id": strconv.FormatUint(partyID, 10)},
+		}); err != nil {
+			logger.Error(fmt.Sprintf("Failed to notify user %d of party %d update: %v", userID, partyID, err))
+		}
+	}
+}
+
+// partyResponse 构造队伍信息响应，不在队伍中时返回空的队伍信息
+func (ls *LobbyService) partyResponse(ctx context.Context, header *proto.MessageHeader, p *party.Party) (*proto.BaseResponse, error) {
+	info := &proto.PartyInfo{}
+	if p != nil {
+		info.PartyId = p.ID
+		info.LeaderId = p.LeaderID
+		for _, member := range p.Members {
+			info.Members = append(info.Members, &proto.PartyMember{
+				UserId:   member.UserID,
+				Nickname: member.Nickname,
+				Level:    member.Level,
+			})
+		}
+		_, info.Queued = ls.server.matchmaker.Queued(p.LeaderID)
+	}
+
+	data, err := proto.Marshal(info)
+	if err != nil {
+		logger.Error(fmt.Sprintf("partyResponse: failed to marshal response: %v", err))
+		return ls.responses.Error(ctx, header, errcode.Internal), nil
+	}
+	return ls.responses.Success(ctx, header, "success", data), nil
+}
+
+// CreateParty 创建队伍，创建者为队长
+func (ls *LobbyService) CreateParty(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	userID := req.Header.GetUserId()
+	if userID == 0 {
+		return ls.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
+	}
+
+	leader, err := ls.server.partyMember(userID)
+	if err != nil {
+		logger.Error(fmt.Sprintf("CreateParty: failed to get user %d: %v", userID, err))
+		return ls.responses.Error(ctx, req.Header, errcode.UserNotFound), nil
+	}
+
+	p, err := ls.server.parties.Create(leader)
+	if err != nil {
+		return ls.responses.Error(ctx, req.Header, partyError(err)), nil
+	}
+	// 单人排队中的玩家创建队伍后需要由队长重新开始匹配
+	ls.server.leaveMatch(userID)
+
+	logger.Info(fmt.Sprintf("User %d created party %d", userID, p.ID))
+	return ls.partyResponse(ctx, req.Header, p)
+}
+
+// InviteToParty 队长邀请玩家加入队伍，未创建队伍时自动创建
+func (ls *LobbyService) InviteToParty(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	userID := req.Header.GetUserId()
+	if userID == 0 {
+		return ls.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
+	}
+
+	partyReq, err := Bind[proto.PartyRequest](req.Data)
+	if err != nil {
+		logger.Error(fmt.Sprintf("InviteToParty: failed to unmarshal request: %v", err))
+		return ls.responses.Error(ctx, req.Header, errcode.InvalidRequest), nil
+	}
+	inviteeID := partyReq.GetUserId()
+	if inviteeID == 0 || inviteeID == userID {
+		return ls.responses.Error(ctx, req.Header, errcode.InvalidRequest), nil
+	}
+
+	blocked, err := database.NewChatRepository(ls.server.mongoManager).GetBlockedUsers(inviteeID)
+	if err != nil {
+		logger.Error(fmt.Sprintf("InviteToParty: failed to get blocked users of %d: %v", inviteeID, err))
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+	for _, blockedID := range blocked {
+		if blockedID == userID {
+			// 不暴露屏蔽关系，按邀请无效处理
+			return ls.responses.Error(ctx, req.Header, errcode.PartyInviteInvalid), nil
+		}
+	}
+
+	if _, ok := ls.server.parties.Get(userID); !ok {
+		leader, err := ls.server.partyMember(userID)
+		if err != nil {
+			logger.Error(fmt.Sprintf("InviteToParty: failed to get user %d: %v", userID, err))
+			return ls.responses.Error(ctx, req.Header, errcode.UserNotFound), nil
+		}
+		if _, err := ls.server.parties.Create(leader); err != nil && !errors.Is(err, party.ErrInParty) {
+			return ls.responses.Error(ctx, req.Header, partyError(err)), nil
+		}
+		ls.server.leaveMatch(userID)
+	}
+
+	p, err := ls.server.parties.Invite(userID, inviteeID)
+	if err != nil {
+		return ls.responses.Error(ctx, req.Header, partyError(err)), nil
+	}
+
+	if err := ls.server.notifier.Notify(&database.Notification{
+		UserID:     inviteeID,
+		Type:       NotifyPartyInvite,
+		DedupKey:   fmt.Sprintf("%s:%d", NotifyPartyInvite, p.ID),
+		FromUserID: userID,
+		Data:       map[string]interface{}{"party_id": strconv.FormatUint(p.ID, 10)},
+	}); err != nil {
+		logger.Error(fmt.Sprintf("InviteToParty: failed to notify user %d: %v", inviteeID, err))
+	}
+
+	logger.Debug(fmt.Sprintf("User %d invited %d to party %d", userID, inviteeID, p.ID))
+	return ls.partyResponse(ctx, req.Header, p)
+}
+
+// JoinParty 接受邀请加入队伍，队伍正在匹配时取消匹配
+func (ls *LobbyService) JoinParty(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	userID := req.Header.GetUserId()
+	if userID == 0 {
+		return ls.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
+	}
+
+	partyReq, err := Bind[proto.PartyRequest](req.Data)
+	if err != nil {
+		logger.Error(fmt.Sprintf("JoinParty: failed to unmarshal request: %v", err))
+		return ls.responses.Error(ctx, req.Header, errcode.InvalidRequest), nil
+	}
+
+	member, err := ls.server.partyMember(userID)
+	if err != nil {
+		logger.Error(fmt.Sprintf("JoinParty: failed to get user %d: %v", userID, err))
+		return ls.responses.Error(ctx, req.Header, errcode.UserNotFound), nil
+	}
+
+	p, err := ls.server.parties.Join(partyReq.GetPartyId(), member)
+	if err != nil {
+		return ls.responses.Error(ctx, req.Header, partyError(err)), nil
+	}
+	ls.server.leaveMatch(userID)
+	ls.server.leaveMatch(p.LeaderID)
+	ls.server.notifyParty(p.ID, p.UserIDs(), userID)
+
+	logger.Info(fmt.Sprintf("User %d joined party %d", userID, p.ID))
+	return ls.partyResponse(ctx, req.Header, p)
+}
+
+// LeaveParty 离开队伍，队长离开时由最早加入的成员接任，队伍正在匹配时取消匹配
+func (ls *LobbyService) LeaveParty(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	userID := req.Header.GetUserId()
+	if userID == 0 {
+		return ls.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
+	}
+
+	// 先取消匹配，离开后队伍票据仍包含该玩家
+	ls.server.leaveMatch(userID)
+	p, err := ls.server.parties.Leave(userID)
+	if err != nil {
+		return ls.responses.Error(ctx, req.Header, partyError(err)), nil
+	}
+
+	if p != nil {
+		ls.server.notifyParty(p.ID, p.UserIDs(), userID)
+		logger.Info(fmt.Sprintf("User %d left party %d", userID, p.ID))
+	} else {
+		logger.Info(fmt.Sprintf("User %d left and disbanded party", userID))
+	}
+	return ls.responses.Success(ctx, req.Header, "success", nil), nil
+}
+
+// GetParty 获取所在的队伍
+func (ls *LobbyService) GetParty(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	userID := req.Header.GetUserId()
+	if userID == 0 {
+		return ls.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
+	}
+
+	p, _ := ls.server.parties.Get(userID)
+	return ls.partyResponse(ctx, req.Header, p)
+}
+
+// SendPartyMessage 发送队伍聊天消息，通过通知发送给其他成员，离线成员在下次登录时收到
+func (ls *LobbyService) SendPartyMessage(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	userID := req.Header.GetUserId()
+	if userID == 0 {
+		return ls.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
+	}
+
+	chatReq, err := Bind[proto.PartyChatRequest](req.Data)
+	if err != nil {
+		logger.Error(fmt.Sprintf("SendPartyMessage: failed to unmarshal request: %v", err))
+		return ls.responses.Error(ctx, req.Header, errcode.InvalidRequest), nil
+	}
+	content := strings.TrimSpace(chatReq.GetContent())
+	if content == "" || utf8.RuneCountInString(content) > maxPartyMessageLength {
+		return ls.responses.Error(ctx, req.Header, errcode.PartyMessageInvalid), nil
+	}
+
+	p, ok := ls.server.parties.Get(userID)
+	if !ok {
+		return ls.responses.Error(ctx, req.Header, errcode.NotInParty), nil
+	}
+
+	ls.server.timeline.Chat(userID, chatChannelParty)
+
+	// 影子封禁的玩家的消息不下发给队友，发送者收到的响应与正常发送相同
+	user, err := database.NewUserRepository(ls.server.mongoManager).GetByUserID(userID)
+	if err != nil {
+		logger.Error(fmt.Sprintf("SendPartyMessage: failed to get user %d: %v", userID, err))
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+	if user.ShadowBanned {
+		return ls.responses.Success(ctx, req.Header, "success", nil), nil
+	}
+
+	sender := ""
+	for _, member := range p.Members {
+		if member.UserID == userID {
+			sender = member.Nickname
+		}
+	}
+	for _, memberID := range p.UserIDs() {
+		if memberID == userID {
+			continue
+		}
+		if err := ls.server.notifier.Notify(&database.Notification{
+			UserID:     memberID,
+			Type:       NotifyPartyChat,
+			FromUserID: userID,
+			Data: map[string]interface{}{
+				"party_id": strconv.FormatUint(p.ID, 10),
+				"nickname": sender,
+				"content":  content,
+			},
+		}); err != nil {
+			logger.Error(fmt.Sprintf("SendPartyMessage: failed to notify user %d: %v", memberID, err))
+		}
+	}
+
+	return ls.responses.Success(ctx, req.Header, "success", nil), nil
+}
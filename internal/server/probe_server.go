@@ -0,0 +1,439 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/errcode"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/monitoring"
+	"github.com/phuhao00/lufy/internal/mq"
+	"github.com/phuhao00/lufy/internal/probe"
+	"github.com/phuhao00/lufy/internal/rpc"
+	"github.com/phuhao00/lufy/pkg/client"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// defaultProbeCallTimeout 探测步骤的上下文没有截止时间时单次调用的时限
+const defaultProbeCallTimeout = 5 * time.Second
+
+// ProbeServer 合成监控节点，按间隔以探测账号走一遍登录、开房间、对局、聊天和领邮件，
+// 在玩家发现之前暴露故障
+type ProbeServer struct {
+	*BaseServer
+	runner     *probe.Runner
+	monitoring *monitoring.MonitoringManager
+	mailRepo   *database.MailRepository
+}
+
+// NewProbeServer 创建探测服务器
+func NewProbeServer(configFile, nodeID string) *ProbeServer {
+	baseServer, err := NewBaseServer(configFile, "probe", nodeID)
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to create base server: %v", err))
+	}
+
+	config := &baseServer.config.Probe
+	if err := config.Validate(); err != nil {
+		logger.Fatal(fmt.Sprintf("Invalid probe config: %v", err))
+	}
+	for i, action := range config.Script {
+		if _, exists := gameActionTypes()[action.Action]; !exists {
+			logger.Fatal(fmt.Sprintf("Invalid probe config: script step %d: unknown action %s", i, action.Action))
+		}
+	}
+
+	probeServer := &ProbeServer{
+		BaseServer: baseServer,
+		mailRepo:   database.NewMailRepository(baseServer.mongoManager),
+	}
+	probeServer.runner = probe.NewRunner(config, nodeID, probeServer.scenario)
+
+	// 导出探测结果
+	if config.MetricsPort > 0 {
+		probeServer.monitoring, err = monitoring.NewMonitoringManager(nodeID, "probe", config.MetricsPort)
+		if err != nil {
+			logger.Fatal(fmt.Sprintf("Failed to create monitoring manager: %v", err))
+		}
+		if err := probeServer.monitoring.Register(probeServer.runner.Metrics()); err != nil {
+			logger.Fatal(fmt.Sprintf("Failed to register probe metrics: %v", err))
+		}
+		baseServer.registerMonitoring(probeServer.monitoring)
+	}
+
+	// 节点启动后开始探测
+	baseServer.RegisterOnStart("probe", OrderBackground, func(ctx context.Context) error {
+		probeServer.runner.Start()
+		return nil
+	})
+	baseServer.RegisterOnStop("probe", OrderBackground, func(ctx context.Context) error {
+		probeServer.runner.Stop()
+		return nil
+	})
+
+	// 注册通用服务
+	if err := RegisterCommonServices(baseServer); err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to register common services: %v", err))
+	}
+
+	return probeServer
+}
+
+// gameActionTypes 对局操作名称到操作类型
+func gameActionTypes() map[string]int32 {
+	types := make(map[string]int32, len(gameActionNames))
+	for actionType, name := range gameActionNames {
+		types[name] = actionType
+	}
+	return types
+}
+
+// probeRun 一次探测中各步骤共享的状态
+type probeRun struct {
+	users    [2]uint64
+	roomID   uint64
+	password string // 私密房间的密码，每次探测随机生成
+	gameID   uint64
+	mailID   uint64
+}
+
+// scenario 生成一次探测的步骤，房主为第一个账号
+func (ps *ProbeServer) scenario() []probe.Step {
+	config := &ps.config.Probe
+	run := &probeRun{password: errcode.NewID()}
+
+	var steps []probe.Step
+	if config.Gateway != "" {
+		steps = append(steps, probe.Step{Name: "gateway", Run: ps.probeGateway})
+	}
+	steps = append(steps,
+		probe.Step{Name: "login", Run: func(ctx context.Context) error {
+			for i, account := range config.Accounts {
+				userID, err := ps.probeLogin(ctx, account)
+				if err != nil {
+					return fmt.Errorf("account %s: %v", account.Username, err)
+				}
+				run.users[i] = userID
+			}
+			return nil
+		}},
+		probe.Step{Name: "create_room", Run: func(ctx context.Context) error {
+			return ps.probeCreateRoom(ctx, run)
+		}},
+		probe.Step{Name: "join_room", Run: func(ctx context.Context) error {
+			return ps.probeLobby(ctx, run.users[1], "JoinRoom", &proto.JoinRoomRequest{RoomId: run.roomID, Password: run.password})
+		}},
+		probe.Step{Name: "start_game", Run: func(ctx context.Context) error {
+			return ps.probeStartGame(ctx, run)
+		}},
+		probe.Step{Name: "play_game", Run: func(ctx context.Context) error {
+			return ps.probePlayGame(ctx, run)
+		}},
+		probe.Step{Name: "chat", Run: func(ctx context.Context) error {
+			return ps.probeChat(ctx, run)
+		}},
+	)
+	if config.AdminToken != "" {
+		steps = append(steps, probe.Step{Name: "claim_mail", Run: func(ctx context.Context) error {
+			return ps.probeClaimMail(ctx, run)
+		}})
+	}
+
+	// 清理：离开房间，删除探测邮件，避免探测账号堆积房间和邮件
+	for i := len(run.users) - 1; i >= 0; i-- {
+		player := i
+		steps = append(steps, probe.Step{Name: "leave_room", Cleanup: true, Run: func(ctx context.Context) error {
+			if run.roomID == 0 || run.users[player] == 0 {
+				return nil
+			}
+			return ps.probeLobby(ctx, run.users[player], "LeaveRoom", &proto.JoinRoomRequest{RoomId: run.roomID})
+		}})
+	}
+	if config.AdminToken != "" {
+		steps = append(steps, probe.Step{Name: "delete_mail", Cleanup: true, Run: func(ctx context.Context) error {
+			if run.mailID == 0 {
+				return nil
+			}
+			return ps.withMailClient(ctx, func(mail *MailServiceClient) error {
+				return commonResponseError(mail.DeleteMail(ctx, &proto.MailOperationRequest{MailId: run.mailID}))
+			})
+		}})
+	}
+	return steps
+}
+
+// probeGateway 通过网关握手、登录、心跳和登出，使用第一个探测账号
+func (ps *ProbeServer) probeGateway(ctx context.Context) error {
+	config := &ps.config.Probe
+	account := config.Accounts[0]
+	timeout := probeCallTimeout(ctx)
+
+	c, err := client.Dial(&client.Config{
+		Address:        config.Gateway,
+		ClientVersion:  ps.config.Server.Version,
+		Platform:       "probe",
+		DialTimeout:    timeout,
+		RequestTimeout: timeout,
+	}, account.Username, account.Password)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if err := c.Heartbeat(); err != nil {
+		return fmt.Errorf("heartbeat: %v", err)
+	}
+	if err := c.Logout(); err != nil {
+		return fmt.Errorf("logout: %v", err)
+	}
+	return nil
+}
+
+// probeLogin 通过登录服务登录，返回用户ID
+func (ps *ProbeServer) probeLogin(ctx context.Context, account probe.Account) (uint64, error) {
+	var userID uint64
+	err := ps.withPeer(ctx, "login", func(c *rpc.RPCClient) error {
+		resp, err := NewLoginServiceClient(c, probeCallTimeout(ctx)).Login(ctx, &proto.LoginRequest{
+			Username: account.Username,
+			Password: account.Password,
+			Platform: "probe",
+			Version:  ps.config.Server.Version,
+		})
+		if err != nil {
+			return err
+		}
+		if resp.GetUserId() == 0 {
+			return fmt.Errorf("login returned no user id")
+		}
+		userID = resp.GetUserId()
+		return nil
+	})
+	return userID, err
+}
+
+// probeCreateRoom 房主创建双人私密房间，避免玩家加入探测房间
+func (ps *ProbeServer) probeCreateRoom(ctx context.Context, run *probeRun) error {
+	data, err := proto.Marshal(&proto.CreateRoomRequest{
+		RoomName:   "probe-" + ps.nodeID,
+		GameType:   ps.config.Probe.GameType,
+		MaxPlayers: 2,
+		IsPrivate:  true,
+		Password:   run.password,
+	})
+	if err != nil {
+		return err
+	}
+	return ps.withPeer(ctx, "lobby", func(c *rpc.RPCClient) error {
+		resp, err := NewLobbyServiceClient(c, probeCallTimeout(ctx)).CreateRoom(ctx, probeRequest(run.users[0], data))
+		if err := baseResponseError(resp, err); err != nil {
+			return err
+		}
+		var room proto.RoomInfo
+		if err := proto.Unmarshal(resp.Data, &room); err != nil {
+			return fmt.Errorf("invalid room info: %v", err)
+		}
+		run.roomID = room.GetRoomId()
+		return nil
+	})
+}
+
+// probeLobby 调用大厅服务中以房间ID为参数的方法
+func (ps *ProbeServer) probeLobby(ctx context.Context, userID uint64, method string, req *proto.JoinRoomRequest) error {
+	data, err := proto.Marshal(req)
+	if err != nil {
+		return err
+	}
+	return ps.withPeer(ctx, "lobby", func(c *rpc.RPCClient) error {
+		lobby := NewLobbyServiceClient(c, probeCallTimeout(ctx))
+		call := lobby.JoinRoom
+		if method == "LeaveRoom" {
+			call = lobby.LeaveRoom
+		}
+		return baseResponseError(call(ctx, probeRequest(userID, data)))
+	})
+}
+
+// probeStartGame 房主开始异步对局，异步对局保存在数据库中，后续操作可以落到任意游戏节点
+func (ps *ProbeServer) probeStartGame(ctx context.Context, run *probeRun) error {
+	data, err := proto.Marshal(&proto.StartGameRequest{
+		RoomId:   run.roomID,
+		GameType: ps.config.Probe.GameType,
+		Async:    true,
+	})
+	if err != nil {
+		return err
+	}
+	return ps.withPeer(ctx, "game", func(c *rpc.RPCClient) error {
+		resp, err := NewGameServiceClient(c, probeCallTimeout(ctx)).StartGame(ctx, probeRequest(run.users[0], data))
+		if err := baseResponseError(resp, err); err != nil {
+			return err
+		}
+		var started struct {
+			GameID uint64 `json:"game_id"`
+		}
+		if err := json.Unmarshal(resp.Data, &started); err != nil || started.GameID == 0 {
+			return fmt.Errorf("start game returned no game id")
+		}
+		run.gameID = started.GameID
+		return nil
+	})
+}
+
+// probePlayGame 按脚本执行对局操作
+func (ps *ProbeServer) probePlayGame(ctx context.Context, run *probeRun) error {
+	actionTypes := gameActionTypes()
+	return ps.withPeer(ctx, "game", func(c *rpc.RPCClient) error {
+		game := NewGameServiceClient(c, probeCallTimeout(ctx))
+		for i, action := range ps.config.Probe.Script {
+			var actionData []byte
+			if len(action.Data) > 0 {
+				var err error
+				if actionData, err = json.Marshal(action.Data); err != nil {
+					return fmt.Errorf("script step %d: %v", i, err)
+				}
+			}
+			data, err := proto.Marshal(&proto.PlayerActionRequest{
+				GameId:     run.gameID,
+				ActionType: actionTypes[action.Action],
+				ActionData: actionData,
+			})
+			if err != nil {
+				return err
+			}
+			if err := baseResponseError(game.PlayerAction(ctx, probeRequest(run.users[action.Player], data))); err != nil {
+				return fmt.Errorf("script step %d %s: %v", i, action.Action, err)
+			}
+		}
+		return nil
+	})
+}
+
+// probeChat 房客在房间频道发送一条消息
+func (ps *ProbeServer) probeChat(ctx context.Context, run *probeRun) error {
+	data, err := proto.Marshal(&proto.SendMessageRequest{
+		ChannelType: mq.CHAT_CHANNEL_ROOM,
+		ChannelId:   run.roomID,
+		Content:     "probe " + time.Now().Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+	return ps.withPeer(ctx, "chat", func(c *rpc.RPCClient) error {
+		return baseResponseError(NewChatServiceClient(c, probeCallTimeout(ctx)).SendMessage(ctx, probeRequest(run.users[1], data)))
+	})
+}
+
+// probeClaimMail 给房主发一封带附件的邮件，再通过邮件服务列出并领取
+func (ps *ProbeServer) probeClaimMail(ctx context.Context, run *probeRun) error {
+	mailID, err := ps.generateMailID()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if err := ps.mailRepo.CreateMail(&database.Mail{
+		MailID:    mailID,
+		ToUserID:  run.users[0],
+		Title:     "probe",
+		Content:   "synthetic monitoring",
+		Rewards:   []database.MailReward{{Type: 1, Count: 1, Name: "probe"}},
+		ExpireAt:  now.Add(time.Hour),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}); err != nil {
+		return fmt.Errorf("seed mail: %v", err)
+	}
+	run.mailID = mailID
+
+	return ps.withMailClient(ctx, func(mail *MailServiceClient) error {
+		list, err := mail.GetMailList(ctx, &proto.MailListRequest{Limit: 20})
+		if err != nil {
+			return err
+		}
+		found := false
+		for _, info := range list.GetMails() {
+			if info.GetMailId() == mailID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("mail %d not in mail list", mailID)
+		}
+		return commonResponseError(mail.ClaimRewards(ctx, &proto.MailOperationRequest{MailId: mailID}))
+	})
+}
+
+// withMailClient 以配置的运维令牌连接邮件服务，令牌的操作员为房主。
+// 令牌写在连接的元数据上，使用单独的连接，不放回共享连接池
+func (ps *ProbeServer) withMailClient(ctx context.Context, fn func(mail *MailServiceClient) error) error {
+	service := ps.discovery.GetService("mail")
+	if service == nil {
+		return fmt.Errorf("no mail node available")
+	}
+	c := rpc.NewRPCClient(service.Address, service.Port)
+	c.SetTransportConfig(ps.rpcTransportConfig(ps.config))
+	if err := c.Connect(); err != nil {
+		return err
+	}
+	defer c.Disconnect()
+
+	c.SetMetadata(rpc.MetadataAuthorization, "Bearer "+ps.config.Probe.AdminToken)
+	return fn(NewMailServiceClient(c, probeCallTimeout(ctx)))
+}
+
+// withPeer 从连接池取一个到该类型节点的连接
+func (ps *ProbeServer) withPeer(ctx context.Context, nodeType string, fn func(c *rpc.RPCClient) error) error {
+	service := ps.discovery.GetService(nodeType)
+	if service == nil {
+		return fmt.Errorf("no %s node available", nodeType)
+	}
+	pool := ps.peerPool(service)
+	c, err := pool.Get()
+	if err != nil {
+		return err
+	}
+	defer pool.Put(c)
+
+	return fn(c)
+}
+
+// probeRequest 构造以探测账号身份发出的请求
+func probeRequest(userID uint64, data []byte) *proto.BaseRequest {
+	return &proto.BaseRequest{
+		Header: &proto.MessageHeader{UserId: userID, TraceId: errcode.NewID()},
+		Data:   data,
+	}
+}
+
+// probeCallTimeout 单次调用的时限，不超过步骤剩余的时间
+func probeCallTimeout(ctx context.Context) time.Duration {
+	if deadline, ok := ctx.Deadline(); ok {
+		return time.Until(deadline)
+	}
+	return defaultProbeCallTimeout
+}
+
+// baseResponseError 调用失败或响应码不为0时返回错误
+func baseResponseError(resp *proto.BaseResponse, err error) error {
+	if err != nil {
+		return err
+	}
+	if resp.GetCode() != 0 {
+		return fmt.Errorf("%s (code %d)", resp.GetMsg(), resp.GetCode())
+	}
+	return nil
+}
+
+// commonResponseError 调用失败或响应码不为0时返回错误
+func commonResponseError(resp *proto.CommonResponse, err error) error {
+	if err != nil {
+		return err
+	}
+	if resp.GetCode() != 0 {
+		return fmt.Errorf("%s (code %d)", resp.GetMessage(), resp.GetCode())
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/denorm"
+	"github.com/phuhao00/lufy/internal/errcode"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/progression"
+	"github.com/phuhao00/lufy/internal/trigger"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// 按等级解锁的功能，解锁等级在progression.features中配置
+const (
+	featureCreateRoom = "create_room"
+	featureAsyncGame  = "async_game"
+)
+
+// newProgression 创建等级成长服务，升级奖励按玩家的VIP权益发放
+func (bs *BaseServer) newProgression() *progression.Service {
+	service := progression.NewService(&bs.config.Progression,
+		database.NewLedgerRepository(bs.mongoManager), database.NewUserRepository(bs.mongoManager),
+		bs.entitlements.Mails(database.NewMailRepository(bs.mongoManager)), bs.generateMailID)
+	service.SetLevelUpHandler(func(userID uint64, from, to int32) {
+		bs.triggers.Fire(trigger.Event{Type: trigger.EventLevel, UserID: userID, Level: to})
+		bs.denorm.Fire(denorm.Change{UserID: userID, Level: to})
+	})
+	return service
+}
+
+// requireFeature 检查玩家是否已解锁功能，未解锁时返回FeatureLocked
+func requireFeature(service *progression.Service, userID uint64, feature string) error {
+	unlocked, required, err := service.Check(userID, feature)
+	if err != nil {
+		return errcode.Internal.Wrap(err)
+	}
+	if !unlocked {
+		return errcode.FeatureLocked.WithDetail(fmt.Sprintf("%s requires level %d", feature, required))
+	}
+	return nil
+}
+
+// GetProgression 获取玩家的等级进度和功能解锁等级
+func (ls *LobbyService) GetProgression(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	userID := req.Header.GetUserId()
+	if userID == 0 {
+		return ls.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
+	}
+
+	// 补齐结算中断时未完成的升级
+	progress, err := ls.server.progression.Sync(userID)
+	if err != nil {
+		logger.Error(fmt.Sprintf("GetProgression: failed to get progression of user %d: %v", userID, err))
+		return ls.responses.Error(ctx, req.Header, errcode.UserNotFound), nil
+	}
+
+	data, err := proto.Marshal(&proto.ProgressionResponse{
+		Level:               progress.Level,
+		Experience:          progress.Experience,
+		LevelExperience:     progress.LevelExperience,
+		NextLevelExperience: progress.NextLevelExperience,
+		MaxLevel:            ls.server.progression.MaxLevel(),
+		Features:            ls.server.progression.Features(),
+	})
+	if err != nil {
+		logger.Error(fmt.Sprintf("GetProgression: failed to marshal response: %v", err))
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+	return ls.responses.Success(ctx, req.Header, "success", data), nil
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/errcode"
+	"github.com/phuhao00/lufy/internal/gameplay"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/quickchat"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// quickChatEvent 快捷消息的房间事件类型
+const quickChatEvent = "quick_chat"
+
+// quickChatCleanupLoop 定期清理快捷消息的限流状态
+func (egs *EnhancedGameServer) quickChatCleanupLoop(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			egs.quickChat.Cleanup(time.Now())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// quickChatError 把快捷消息的错误转换为错误码
+func quickChatError(err error) error {
+	switch {
+	case errors.Is(err, quickchat.ErrDisabled):
+		return errcode.QuickChatDisabled
+	case errors.Is(err, quickchat.ErrUnknown):
+		return errcode.QuickChatUnknown
+	case errors.Is(err, quickchat.ErrCooldown), errors.Is(err, quickchat.ErrThrottled):
+		return errcode.QuickChatTooFast
+	case errors.Is(err, quickchat.ErrMuted):
+		return errcode.QuickChatMuted
+	}
+	return errcode.Internal.Wrap(err)
+}
+
+// quickChatParams QuickChat的参数，只接受配置表中的条目ID，不包含自由文本
+type quickChatParams struct {
+	RoomID  uint64 `json:"room_id" validate:"required,min=1"`
+	EntryID int32  `json:"entry_id" validate:"required,min=1"`
+}
+
+// blockQuickChatParams BlockQuickChat的参数
+type blockQuickChatParams struct {
+	RoomID  uint64 `json:"room_id" validate:"required,min=1"`
+	UserID  uint64 `json:"user_id" validate:"required,min=1"`
+	Blocked bool   `json:"blocked" validate:"required"`
+}
+
+// QuickChat 发送快捷消息或表情。只接受配置表中的条目，作为房间事件推送给房间内的玩家
+func (egs *EnhancedGameService) QuickChat(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	session, err := egs.validateRequest(req)
+	if err != nil {
+		return egs.createErrorResponse(req, errcode.SecurityRejected, nil)
+	}
+
+	params, err := BindJSON[quickChatParams](egs.server.inputValidator, "QuickChat", req.Data)
+	if err != nil {
+		return egs.createErrorResponse(req, errcode.InvalidRequest, nil)
+	}
+
+	room, exists := egs.server.gameplay.GetRoom(params.RoomID)
+	if !exists {
+		return egs.createErrorResponse(req, errcode.RoomNotFound, nil)
+	}
+	if _, exists := room.GetPlayer(session.UserID); !exists {
+		return egs.createErrorResponse(req, errcode.PermissionDenied, nil)
+	}
+
+	entry, err := egs.server.quickChat.Send(session.UserID, params.EntryID, time.Now())
+	if err != nil {
+		if errors.Is(err, quickchat.ErrMuted) {
+			logger.Warn(fmt.Sprintf("Quick chat muted for user %d in room %d", session.UserID, room.ID))
+		}
+		return egs.createErrorResponse(req, quickChatError(err), nil)
+	}
+
+	if err := egs.server.gameplay.PublishEvent(room.ID, gameplay.GameEvent{
+		Type:     quickChatEvent,
+		PlayerID: session.UserID,
+		Data: map[string]interface{}{
+			"entry_id": entry.ID,
+			"kind":     entry.Kind,
+			"key":      entry.Key,
+		},
+	}); err != nil {
+		logger.Warn(fmt.Sprintf("QuickChat: failed to publish to room %d: %v", room.ID, err))
+		return egs.createErrorResponse(req, errcode.RoomNotFound, nil)
+	}
+
+	egs.server.monitoring.RecordMessage("quick_chat")
+	return egs.createSuccessResponse(req, "success", nil)
+}
+
+// BlockQuickChat 屏蔽或取消屏蔽房间内某个玩家的快捷消息，只影响自己收到的消息
+func (egs *EnhancedGameService) BlockQuickChat(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	session, err := egs.validateRequest(req)
+	if err != nil {
+		return egs.createErrorResponse(req, errcode.SecurityRejected, nil)
+	}
+
+	params, err := BindJSON[blockQuickChatParams](egs.server.inputValidator, "BlockQuickChat", req.Data)
+	if err != nil || params.UserID == session.UserID {
+		return egs.createErrorResponse(req, errcode.InvalidRequest, nil)
+	}
+
+	room, exists := egs.server.gameplay.GetRoom(params.RoomID)
+	if !exists {
+		return egs.createErrorResponse(req, errcode.RoomNotFound, nil)
+	}
+	if _, exists := room.GetPlayer(session.UserID); !exists {
+		return egs.createErrorResponse(req, errcode.PermissionDenied, nil)
+	}
+
+	egs.server.quickChat.Block(session.UserID, params.UserID, params.Blocked)
+	return egs.createSuccessResponse(req, "success", map[string]interface{}{
+		"user_id": params.UserID,
+		"blocked": params.Blocked,
+	})
+}
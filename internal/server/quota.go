@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/errcode"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/quota"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// newQuotas 创建每日操作配额服务，按玩家的VIP等级使用对应的上限
+func (bs *BaseServer) newQuotas() *quota.Service {
+	quotas := quota.NewService(&bs.config.Quota, bs.redisManager)
+	quotas.SetTierResolver(bs.entitlements.Tier)
+	return quotas
+}
+
+// consumeQuota 取出一次配额，用完时返回错误响应，消息参数为用量和重置时间，数据为QuotaInfo，客户端据此提示重置时间
+func consumeQuota(ctx context.Context, quotas *quota.Service, responses *errcode.Builder, header *proto.MessageHeader, op string) *proto.BaseResponse {
+	_, err := quotas.Consume(header.GetUserId(), op)
+	var exceeded *quota.ExceededError
+	if !errors.As(err, &exceeded) {
+		return nil
+	}
+	logger.Debug(fmt.Sprintf("User %d: %v", header.GetUserId(), exceeded))
+
+	resp := responses.Error(ctx, header, errcode.QuotaExceeded.WithParams(map[string]string{
+		"operation": exceeded.Operation,
+		"used":      strconv.FormatInt(exceeded.Used, 10),
+		"limit":     strconv.FormatInt(exceeded.Limit, 10),
+		"reset_at":  exceeded.ResetAt.Format(time.RFC3339),
+	}))
+	data, err := proto.Marshal(&proto.QuotaInfo{
+		Operation: exceeded.Operation,
+		Used:      exceeded.Used,
+		Limit:     exceeded.Limit,
+		ResetAt:   exceeded.ResetAt.Unix(),
+	})
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to marshal quota info: %v", err))
+		return resp
+	}
+	resp.Data = data
+	return resp
+}
+
+// quotaCommand 玩家当天的配额用量: quota <玩家ID>
+func (gs *GMServer) quotaCommand(args []string) (string, error) {
+	if len(args) < 1 {
+		return "", fmt.Errorf("quota命令需要玩家ID")
+	}
+	userID, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("无效的玩家ID: %s", args[0])
+	}
+	usages, err := gs.newQuotas().Usages(userID)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(usages)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
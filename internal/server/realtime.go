@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/errcode"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/network"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// reportOnlineLoop 定期上报本网关已登录的连接数
+func (gs *GatewayServer) reportOnlineLoop(ctx context.Context) {
+	ticker := time.NewTicker(gs.realtime.Interval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			var online int64
+			gs.tcpServer.ForEachConnection(func(conn *network.Connection) bool {
+				if conn.UserID != 0 {
+					online++
+				}
+				return true
+			})
+			if err := gs.realtime.ReportOnline(gs.nodeID, online); err != nil {
+				logger.Warn(fmt.Sprintf("Failed to report online users of %s: %v", gs.nodeID, err))
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// reconcileRealtime 按数据库中未结束的房间校准房间、游戏和玩家计数
+func (ls *LobbyServer) reconcileRealtime(ctx context.Context) error {
+	occupancy, err := ls.roomRepo.CountOccupancy()
+	if err != nil {
+		return err
+	}
+	return ls.realtime.Reconcile(occupancy)
+}
+
+// realtimeStatsJSON 集群实时计数的JSON
+func (bs *BaseServer) realtimeStatsJSON() ([]byte, error) {
+	stats, err := bs.realtime.Stats()
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to load realtime stats: %v", err))
+		return nil, errcode.Internal
+	}
+	return json.Marshal(stats)
+}
+
+// GetRealtimeStats 获取集群的在线人数、各游戏类型房间中的玩家数、未结束的房间数和游戏中的房间数
+func (gs *GMService) GetRealtimeStats(ctx context.Context, req *proto.BaseRequest) (*proto.CommonResponse, error) {
+	if ctx.Value("user_id") == nil {
+		return gs.responses.CommonError(ctx, errcode.NotLoggedIn), nil
+	}
+
+	data, err := gs.server.realtimeStatsJSON()
+	if err != nil {
+		return gs.responses.CommonError(ctx, err), nil
+	}
+	return gs.responses.CommonSuccess(ctx, "success", data), nil
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/errcode"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/redeem"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// newRedeem 创建兑换码服务，notifier为空时不通知玩家收到奖励邮件
+func (bs *BaseServer) newRedeem(notifier *Notifier) *redeem.Service {
+	var notify redeem.NotifyFunc
+	if notifier != nil {
+		notify = func(userID, mailID uint64, title string) {
+			if err := notifier.Notify(&database.Notification{
+				UserID:   userID,
+				Type:     NotifyGift,
+				DedupKey: fmt.Sprintf("%s:%d", NotifyGift, mailID),
+				Data:     map[string]interface{}{"mail_id": strconv.FormatUint(mailID, 10), "title": title},
+			}); err != nil {
+				logger.Warn(fmt.Sprintf("Failed to notify user %d of redeem mail %d: %v", userID, mailID, err))
+			}
+		}
+	}
+
+	return redeem.NewService(&bs.config.Redeem, database.NewRedeemRepository(bs.mongoManager),
+		database.NewMailRepository(bs.mongoManager), bs.redisManager, bs.generateMailID, notify)
+}
+
+// redeemError 把兑换的错误转换为错误码
+func redeemError(err error) error {
+	switch {
+	case errors.Is(err, redeem.ErrInvalid):
+		return errcode.RedeemCodeInvalid
+	case errors.Is(err, redeem.ErrInactive):
+		return errcode.RedeemCodeInactive
+	case errors.Is(err, redeem.ErrUsedUp):
+		return errcode.RedeemCodeUsedUp
+	case errors.Is(err, redeem.ErrRedeemed):
+		return errcode.RedeemLimitReached
+	case errors.Is(err, redeem.ErrThrottled):
+		return errcode.RedeemThrottled
+	case errors.Is(err, redeem.ErrBatch):
+		return errcode.RedeemBatchInvalid.WithDetail(err.Error())
+	case errors.Is(err, redeem.ErrNotFound):
+		return errcode.RedeemBatchNotFound
+	}
+	return errcode.RedeemFailed.Wrap(err)
+}
+
+// rewardInfos 转换为客户端使用的奖励信息
+func rewardInfos(rewards []database.MailReward) []*proto.Reward {
+	result := make([]*proto.Reward, len(rewards))
+	for i, reward := range rewards {
+		result[i] = &proto.Reward{ItemId: uint32(reward.ItemID), ItemType: reward.Type, Quantity: uint32(reward.Count)}
+	}
+	return result
+}
+
+// RedeemCode 兑换码兑换，奖励通过邮件发放，连续输错过多时暂时不能兑换
+func (ls *LobbyService) RedeemCode(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	userID := req.Header.GetUserId()
+	if userID == 0 {
+		return ls.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
+	}
+
+	redeemReq, err := Bind[proto.RedeemCodeRequest](req.Data)
+	if err != nil || strings.TrimSpace(redeemReq.GetCode()) == "" {
+		return ls.responses.Error(ctx, req.Header, errcode.InvalidRequest), nil
+	}
+
+	result, err := ls.server.redeem.Redeem(userID, redeemReq.GetCode())
+	if err != nil {
+		code := redeemError(err)
+		if errcode.From(code) == errcode.RedeemFailed {
+			logger.Error(fmt.Sprintf("RedeemCode: user %d failed: %v", userID, err))
+		} else {
+			logger.Debug(fmt.Sprintf("RedeemCode: user %d: %v", userID, err))
+		}
+		return ls.responses.Error(ctx, req.Header, code), nil
+	}
+
+	logger.Info(fmt.Sprintf("User %d redeemed a code of batch %s, mail %d", userID, result.BatchID, result.MailID))
+	data, err := proto.Marshal(&proto.RedeemCodeResponse{
+		MailId:  result.MailID,
+		Title:   result.Title,
+		Rewards: rewardInfos(result.Rewards),
+	})
+	if err != nil {
+		logger.Error(fmt.Sprintf("RedeemCode: failed to marshal response: %v", err))
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+	return ls.responses.Success(ctx, req.Header, "success", data), nil
+}
+
+// CreateRedeemBatch 创建兑换码批次，返回批次ID和生成的兑换码
+func (gs *GMService) CreateRedeemBatch(ctx context.Context, req *proto.RedeemBatchRequest) (*proto.CommonResponse, error) {
+	gmUserID := ctx.Value("user_id")
+	if gmUserID == nil {
+		return gs.responses.CommonError(ctx, errcode.NotLoggedIn), nil
+	}
+
+	batch := &database.RedeemBatch{
+		Name:      req.GetName(),
+		Title:     req.GetTitle(),
+		Content:   req.GetContent(),
+		Codes:     req.GetCount(),
+		MaxUses:   req.GetMaxUses(),
+		PerUser:   req.GetPerUser(),
+		CreatedBy: gmUserID.(uint64),
+	}
+	for _, reward := range req.GetRewards() {
+		batch.Rewards = append(batch.Rewards, database.MailReward{
+			Type:   reward.GetItemType(),
+			ItemID: int32(reward.GetItemId()),
+			Count:  int64(reward.GetQuantity()),
+		})
+	}
+	if req.GetStartTime() > 0 {
+		batch.StartAt = time.Unix(req.GetStartTime(), 0)
+	}
+	if req.GetEndTime() > 0 {
+		batch.ExpireAt = time.Unix(req.GetEndTime(), 0)
+	}
+
+	codes, err := gs.server.redeem.CreateBatch(batch, req.GetCode())
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Failed to create redeem batch: %v", err))
+		return gs.responses.CommonError(ctx, redeemError(err)), nil
+	}
+
+	gs.server.gmRepo.LogGMAction(batch.CreatedBy, "create_redeem_batch", 0,
+		fmt.Sprintf("批次: %s, 名称: %s, 兑换码数: %d, 每码次数: %d, 每人次数: %d",
+			batch.BatchID, batch.Name, batch.Codes, batch.MaxUses, batch.PerUser))
+
+	data, err := json.Marshal(map[string]interface{}{"batch_id": batch.BatchID, "codes": codes})
+	if err != nil {
+		return gs.responses.CommonError(ctx, errcode.Internal), nil
+	}
+	return gs.responses.CommonSuccess(ctx, "兑换码批次创建成功", data), nil
+}
+
+// ListRedeemBatches 获取兑换码批次和兑换统计，未指定批次时获取最近创建的批次
+func (gs *GMService) ListRedeemBatches(ctx context.Context, req *proto.RedeemBatchQuery) (*proto.CommonResponse, error) {
+	if ctx.Value("user_id") == nil {
+		return gs.responses.CommonError(ctx, errcode.NotLoggedIn), nil
+	}
+
+	limit := int64(req.GetLimit())
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	stats, err := gs.server.redeem.Stats(req.GetBatchId(), limit)
+	if err != nil {
+		return gs.responses.CommonError(ctx, redeemError(err)), nil
+	}
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return gs.responses.CommonError(ctx, errcode.Internal), nil
+	}
+	return gs.responses.CommonSuccess(ctx, "success", data), nil
+}
+
+// UpdateRedeemBatch 停用或重新启用兑换码批次
+func (gs *GMService) UpdateRedeemBatch(ctx context.Context, req *proto.RedeemBatchQuery) (*proto.CommonResponse, error) {
+	gmUserID := ctx.Value("user_id")
+	if gmUserID == nil {
+		return gs.responses.CommonError(ctx, errcode.NotLoggedIn), nil
+	}
+
+	if err := gs.server.redeem.SetDisabled(req.GetBatchId(), req.GetDisabled()); err != nil {
+		return gs.responses.CommonError(ctx, redeemError(err)), nil
+	}
+	gs.server.gmRepo.LogGMAction(gmUserID.(uint64), "update_redeem_batch", 0,
+		fmt.Sprintf("批次: %s, 停用: %v", req.GetBatchId(), req.GetDisabled()))
+	return gs.responses.CommonSuccess(ctx, "兑换码批次已更新", nil), nil
+}
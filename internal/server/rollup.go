@@ -0,0 +1,52 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/errcode"
+	"github.com/phuhao00/lufy/internal/rollup"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// newRollups 创建运营指标汇总，在线人数取自集群实时计数
+func (bs *BaseServer) newRollups() *rollup.Service {
+	return rollup.NewService(&bs.config.Rollup,
+		database.NewStatsRollupRepository(bs.mongoManager),
+		database.NewGameRecordRepository(bs.mongoManager),
+		func() (int64, error) {
+			stats, err := bs.realtime.Stats()
+			if err != nil {
+				return 0, err
+			}
+			return stats.Online, nil
+		})
+}
+
+// capacityReportJSON 容量规划周报的JSON
+func (gs *GMServer) capacityReportJSON(week string) ([]byte, error) {
+	start, err := rollup.ParseWeek(week, time.Now())
+	if err != nil {
+		return nil, errcode.InvalidRequest.WithDetail(err.Error())
+	}
+	report, err := gs.rollups.Report(start)
+	if err != nil {
+		return nil, errcode.Internal.Wrap(err)
+	}
+	return json.Marshal(report)
+}
+
+// GetCapacityReport 获取容量规划周报：峰值在线、每小时游戏数、各游戏类型的平均时长和相比上周的增长
+func (gs *GMService) GetCapacityReport(ctx context.Context, req *proto.CapacityReportQuery) (*proto.CommonResponse, error) {
+	if ctx.Value("user_id") == nil {
+		return gs.responses.CommonError(ctx, errcode.NotLoggedIn), nil
+	}
+
+	data, err := gs.server.capacityReportJSON(req.GetWeek())
+	if err != nil {
+		return gs.responses.CommonError(ctx, err), nil
+	}
+	return gs.responses.CommonSuccess(ctx, "success", data), nil
+}
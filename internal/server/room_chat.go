@@ -0,0 +1,34 @@
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// 房间聊天只转发不保存的标记，创建房间的节点写入，聊天服务器发送房间消息时读取
+const (
+	ephemeralRoomChatKey = "chat:ephemeral_room:%d"
+	ephemeralRoomChatTTL = 24 * time.Hour // 不短于房间的存活时间
+)
+
+// markEphemeralRoomChat 标记房间聊天只转发不保存
+func (bs *BaseServer) markEphemeralRoomChat(roomID uint64) {
+	if err := bs.redisManager.Set(fmt.Sprintf(ephemeralRoomChatKey, roomID), "1", ephemeralRoomChatTTL); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to mark chat of room %d as ephemeral: %v", roomID, err))
+	}
+}
+
+// ephemeralRoomChat 房间聊天是否只转发不保存，读取失败时保存
+func (bs *BaseServer) ephemeralRoomChat(roomID uint64) bool {
+	if bs.config.ChatPolicy.EphemeralRooms {
+		return true
+	}
+	exists, err := bs.redisManager.Exists(fmt.Sprintf(ephemeralRoomChatKey, roomID))
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Failed to check chat mode of room %d: %v", roomID, err))
+		return false
+	}
+	return exists
+}
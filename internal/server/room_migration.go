@@ -0,0 +1,448 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/discovery"
+	"github.com/phuhao00/lufy/internal/errcode"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/mq"
+	"github.com/phuhao00/lufy/internal/network"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// RoomMigrationConfig 房间迁移配置，游戏节点排空或缩容时把进行中的游戏迁移到其他游戏节点
+type RoomMigrationConfig struct {
+	OnDrain     bool          `yaml:"on_drain"`    // 收到排空命令后迁出本节点所有进行中的游戏
+	Concurrency int           `yaml:"concurrency"` // 同时迁移的游戏数，默认4
+	Timeout     time.Duration `yaml:"timeout"`     // 单个游戏迁移的超时，期间游戏冻结，默认5秒
+	ForwardTTL  time.Duration `yaml:"forward_ttl"` // 迁出后保留去向的时间，期间按旧路由到达的请求返回可重试错误，默认10分钟
+}
+
+// 房间迁移默认设置
+const (
+	defaultMigrationConcurrency = 4
+	defaultMigrationTimeout     = 5 * time.Second
+	defaultMigrationForwardTTL  = 10 * time.Minute
+)
+
+// migratedGame 已迁出的游戏去向
+type migratedGame struct {
+	node string
+	at   time.Time
+}
+
+// gameMigrations 本节点迁出的游戏
+type gameMigrations struct {
+	games map[uint64]migratedGame
+	mutex sync.Mutex
+}
+
+// add 记录迁出的游戏，同时清理过期的记录
+func (gm *gameMigrations) add(gameID uint64, node string, ttl time.Duration) {
+	gm.mutex.Lock()
+	defer gm.mutex.Unlock()
+
+	if gm.games == nil {
+		gm.games = make(map[uint64]migratedGame)
+	}
+	now := time.Now()
+	for id, migrated := range gm.games {
+		if now.Sub(migrated.at) > ttl {
+			delete(gm.games, id)
+		}
+	}
+	gm.games[gameID] = migratedGame{node: node, at: now}
+}
+
+// lookup 获取迁出游戏的目标节点
+func (gm *gameMigrations) lookup(gameID uint64) (string, bool) {
+	gm.mutex.Lock()
+	defer gm.mutex.Unlock()
+
+	migrated, exists := gm.games[gameID]
+	return migrated.node, exists
+}
+
+// roomMigrationConfig 获取补齐默认值的房间迁移配置
+func (gs *GameServer) roomMigrationConfig() RoomMigrationConfig {
+	config := gs.config.RoomMigration
+	if config.Concurrency <= 0 {
+		config.Concurrency = defaultMigrationConcurrency
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultMigrationTimeout
+	}
+	if config.ForwardTTL <= 0 {
+		config.ForwardTTL = defaultMigrationForwardTTL
+	}
+	return config
+}
+
+// missingGame 本节点找不到游戏时的错误，刚迁出的游戏返回可重试的迁移中错误，网关重新查询路由后重试
+func (gs *GameServer) missingGame(gameID uint64) error {
+	if node, migrated := gs.migrations.lookup(gameID); migrated {
+		return errcode.GameMigrating.WithDetail(node)
+	}
+	return errcode.GameNotFound
+}
+
+// gameSnapshot 游戏实例的完整状态，调用方需持有游戏锁
+func gameSnapshot(game *GameInstance) (*proto.GameSnapshot, error) {
+	state, _, err := game.GameData.Encoded()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &proto.GameSnapshot{
+		GameId:        game.GameID,
+		RoomId:        game.RoomID,
+		GameType:      game.GameType,
+		Status:        game.Status,
+		CurrentPlayer: game.CurrentPlayer,
+		StartTime:     game.StartTime.UnixMilli(),
+		Winner:        game.Winner,
+		State:         state,
+	}
+	for _, player := range game.Players {
+		snapshot.Players = append(snapshot.Players, &proto.GameSnapshotPlayer{
+			UserId:   player.UserID,
+			Nickname: player.Nickname,
+			Level:    player.Level,
+			Score:    player.Score,
+			Status:   player.Status,
+		})
+	}
+	return snapshot, nil
+}
+
+// restoreGame 由迁移的状态构造游戏实例
+func restoreGame(snapshot *proto.GameSnapshot) (*GameInstance, error) {
+	gameData, err := RestoreGameData(snapshot.GameType, snapshot.State)
+	if err != nil {
+		return nil, err
+	}
+
+	game := &GameInstance{
+		GameID:        snapshot.GameId,
+		RoomID:        snapshot.RoomId,
+		GameType:      snapshot.GameType,
+		Status:        snapshot.Status,
+		Players:       make(map[uint64]*GamePlayerData, len(snapshot.Players)),
+		CurrentPlayer: snapshot.CurrentPlayer,
+		StartTime:     time.UnixMilli(snapshot.StartTime),
+		Winner:        snapshot.Winner,
+		GameData:      gameData,
+	}
+	for _, player := range snapshot.Players {
+		game.Players[player.UserId] = &GamePlayerData{
+			UserID:   player.UserId,
+			Nickname: player.Nickname,
+			Level:    player.Level,
+			Score:    player.Score,
+			Status:   player.Status,
+			Data:     make(map[string]interface{}),
+		}
+	}
+	return game, nil
+}
+
+// migrationTarget 选择负载最低的其他在线游戏节点
+func (gs *GameServer) migrationTarget() *discovery.ServiceInfo {
+	var target *discovery.ServiceInfo
+	for _, service := range gs.discovery.GetAllServices("game") {
+		if service.NodeID == gs.nodeID || service.Status != discovery.StatusOnline {
+			continue
+		}
+		if target == nil || service.Load < target.Load {
+			target = service
+		}
+	}
+	return target
+}
+
+// migrateGame 迁移游戏到目标节点。迁移期间持有游戏锁，玩家操作等待迁移完成；
+// 目标节点迁入并把房间记录指向自己后，本节点移除实例并通知网关更新路由，失败时游戏在本节点继续
+func (gs *GameServer) migrateGame(ctx context.Context, game *GameInstance, target *discovery.ServiceInfo) error {
+	config := gs.roomMigrationConfig()
+
+	game.mutex.Lock()
+	defer game.mutex.Unlock()
+
+	if game.migratedTo != "" || game.Status == 2 {
+		return nil
+	}
+
+	snapshot, err := gameSnapshot(game)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, config.Timeout)
+	defer cancel()
+
+	if err := gs.importGame(ctx, target, snapshot, config.Timeout); err != nil {
+		// 调用超时时目标节点可能已经迁入，以房间记录为准
+		room, getErr := gs.roomRepo.GetRoomByID(game.RoomID)
+		if getErr != nil || room.GameID != game.GameID || room.Node != target.NodeID {
+			return fmt.Errorf("failed to migrate game %d to %s: %v", game.GameID, target.NodeID, err)
+		}
+		logger.Warn(fmt.Sprintf("Game %d was imported by %s despite error: %v", game.GameID, target.NodeID, err))
+	}
+
+	game.migratedTo = target.NodeID
+	gs.removeGame(game.GameID)
+	gs.migrations.add(game.GameID, target.NodeID, config.ForwardTTL)
+
+	event := &proto.GameMigratedEvent{GameId: game.GameID, RoomId: game.RoomID, Node: target.NodeID}
+	for userID := range game.Players {
+		event.PlayerIds = append(event.PlayerIds, userID)
+	}
+	gs.notifyGateways(event)
+
+	logger.Info(fmt.Sprintf("Game %d in room %d migrated to %s", game.GameID, game.RoomID, target.NodeID))
+	return nil
+}
+
+// importGame 调用目标节点迁入游戏
+func (gs *GameServer) importGame(ctx context.Context, target *discovery.ServiceInfo, snapshot *proto.GameSnapshot, timeout time.Duration) error {
+	data, err := proto.Marshal(&proto.ImportGameRequest{Snapshot: snapshot, SourceNode: gs.nodeID})
+	if err != nil {
+		return fmt.Errorf("failed to marshal game snapshot: %v", err)
+	}
+
+	pool := gs.peerPool(target)
+	client, err := pool.Get()
+	if err != nil {
+		return err
+	}
+	defer pool.Put(client)
+
+	resp, err := NewGameServiceClient(client, timeout).ImportGame(ctx, &proto.BaseRequest{
+		Header: &proto.MessageHeader{TraceId: errcode.NewID()},
+		Data:   data,
+	})
+	if err != nil {
+		return err
+	}
+	if resp.Code != 0 {
+		return fmt.Errorf("import rejected: %s (code %d)", resp.Msg, resp.Code)
+	}
+	return nil
+}
+
+// notifyGateways 通知所有网关游戏的新位置
+func (gs *GameServer) notifyGateways(event *proto.GameMigratedEvent) {
+	for _, gateway := range gs.discovery.GetAllServices("gateway") {
+		if err := gs.messageBroker.SendCommand(gateway.NodeID, mq.SYS_CMD_GAME_MIGRATED, event); err != nil {
+			logger.Warn(fmt.Sprintf("Failed to notify gateway %s of game %d migration: %v", gateway.NodeID, event.GameId, err))
+		}
+	}
+}
+
+// migrateGames 迁出本节点所有进行中的游戏，返回迁出和失败的数量
+func (gs *GameServer) migrateGames(ctx context.Context) (int, int) {
+	gs.gamesMutex.RLock()
+	games := make([]*GameInstance, 0, len(gs.games))
+	for _, game := range gs.games {
+		games = append(games, game)
+	}
+	gs.gamesMutex.RUnlock()
+
+	var (
+		moved, failed int
+		wg            sync.WaitGroup
+		mutex         sync.Mutex
+	)
+	semaphore := make(chan struct{}, gs.roomMigrationConfig().Concurrency)
+	for _, game := range games {
+		target := gs.migrationTarget()
+		if target == nil {
+			logger.Error(fmt.Sprintf("No game node available to migrate game %d", game.GameID))
+			mutex.Lock()
+			failed++
+			mutex.Unlock()
+			continue
+		}
+
+		semaphore <- struct{}{}
+		wg.Add(1)
+		go func(game *GameInstance, target *discovery.ServiceInfo) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			err := gs.migrateGame(ctx, game, target)
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				logger.Error(err.Error())
+				failed++
+				return
+			}
+			moved++
+		}(game, target)
+	}
+	wg.Wait()
+	return moved, failed
+}
+
+// handleDrain 排空游戏节点，开启on_drain时在后台迁出所有进行中的游戏
+func (gs *GameServer) handleDrain(msg *mq.SystemMessage) error {
+	if err := NewSystemService(gs.BaseServer).HandleDrain(msg); err != nil {
+		return err
+	}
+
+	resume, _ := msg.Args["resume"].(bool)
+	if resume || !gs.config.RoomMigration.OnDrain {
+		return nil
+	}
+
+	go func() {
+		start := time.Now()
+		moved, failed := gs.migrateGames(gs.ctx)
+		logger.Info(fmt.Sprintf("Drain migration finished in %v: %d games migrated, %d failed", time.Since(start), moved, failed))
+	}()
+	return nil
+}
+
+// ImportGame 迁入其他节点迁出的游戏。先加入本节点再把房间记录指向本节点，房间记录已不在迁出节点时放弃
+func (gs *GameService) ImportGame(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	var importReq proto.ImportGameRequest
+	if err := proto.Unmarshal(req.Data, &importReq); err != nil {
+		logger.Error(fmt.Sprintf("ImportGame: failed to unmarshal request: %v", err))
+		return gs.responses.Error(ctx, req.Header, errcode.InvalidRequest), nil
+	}
+	snapshot := importReq.GetSnapshot()
+	if snapshot.GetGameId() == 0 || importReq.GetSourceNode() == "" {
+		return gs.responses.Error(ctx, req.Header, errcode.InvalidRequest), nil
+	}
+
+	// 迁出节点超时重试时游戏可能已经迁入
+	if _, exists := gs.server.getGame(snapshot.GameId); exists {
+		return gs.responses.Success(ctx, req.Header, "game already imported", nil), nil
+	}
+
+	game, err := restoreGame(snapshot)
+	if err != nil {
+		logger.Error(fmt.Sprintf("ImportGame: game %d: %v", snapshot.GameId, err))
+		return gs.responses.Error(ctx, req.Header, errcode.InvalidRequest), nil
+	}
+
+	gs.server.addGame(game)
+	moved, err := gs.server.roomRepo.MoveRoomGame(game.RoomID, game.GameID, importReq.SourceNode, gs.server.nodeID)
+	if err != nil || !moved {
+		gs.server.removeGame(game.GameID)
+		if err != nil {
+			logger.Error(fmt.Sprintf("ImportGame: %v", err))
+			return gs.responses.Error(ctx, req.Header, errcode.Internal), nil
+		}
+		logger.Warn(fmt.Sprintf("ImportGame: room %d of game %d is no longer on %s", game.RoomID, game.GameID, importReq.SourceNode))
+		return gs.responses.Error(ctx, req.Header, errcode.GameStateChanged), nil
+	}
+
+	logger.Info(fmt.Sprintf("Game %d in room %d imported from %s", game.GameID, game.RoomID, importReq.SourceNode))
+	return gs.responses.Success(ctx, req.Header, "game imported", nil), nil
+}
+
+// gameRouteTTL 网关缓存玩家所在游戏节点的时间，过期后按房间记录重新查询，玩家开始新的游戏时不会一直使用旧节点
+const gameRouteTTL = 30 * time.Second
+
+// gameRoute 玩家所在的游戏节点
+type gameRoute struct {
+	node    string
+	expires time.Time
+}
+
+// gameRoutes 网关记录的玩家所在游戏节点，按房间记录查询，游戏迁移时由迁出节点通知更新
+type gameRoutes struct {
+	rooms  *database.RoomRepository
+	routes map[uint64]gameRoute
+	mutex  sync.RWMutex
+}
+
+// newGameRoutes 创建游戏路由表
+func newGameRoutes(rooms *database.RoomRepository) *gameRoutes {
+	return &gameRoutes{
+		rooms:  rooms,
+		routes: make(map[uint64]gameRoute),
+	}
+}
+
+// lookup 获取玩家所在的游戏节点，玩家不在游戏中时返回空
+func (gr *gameRoutes) lookup(userID uint64) string {
+	gr.mutex.RLock()
+	route, exists := gr.routes[userID]
+	gr.mutex.RUnlock()
+	if exists && time.Now().Before(route.expires) {
+		return route.node
+	}
+
+	room, err := gr.rooms.FindRoomByPlayer(userID)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to find game route of user %d: %v", userID, err))
+		return ""
+	}
+	node := ""
+	if room != nil && room.Status == 1 {
+		node = room.Node
+	}
+
+	gr.mutex.Lock()
+	gr.routes[userID] = gameRoute{node: node, expires: time.Now().Add(gameRouteTTL)}
+	gr.mutex.Unlock()
+	return node
+}
+
+// update 游戏迁移后更新玩家的路由
+func (gr *gameRoutes) update(userIDs []uint64, node string) {
+	gr.mutex.Lock()
+	defer gr.mutex.Unlock()
+
+	expires := time.Now().Add(gameRouteTTL)
+	for _, userID := range userIDs {
+		gr.routes[userID] = gameRoute{node: node, expires: expires}
+	}
+}
+
+// forget 清除玩家的路由，下次按房间记录重新查询
+func (gr *gameRoutes) forget(userID uint64) {
+	gr.mutex.Lock()
+	defer gr.mutex.Unlock()
+
+	delete(gr.routes, userID)
+}
+
+// handleGameMigrated 游戏迁移到新节点，之后该游戏玩家的请求直接转发到新节点，客户端无需重连
+func (gs *GatewayServer) handleGameMigrated(msg *mq.SystemMessage) error {
+	event, err := mq.Command[proto.GameMigratedEvent](msg)
+	if err != nil {
+		return err
+	}
+
+	gs.messageHandler.gameRoutes.update(event.PlayerIds, event.Node)
+	logger.Debug(fmt.Sprintf("Game %d of room %d now routed to %s", event.GameId, event.RoomId, event.Node))
+	return nil
+}
+
+// gameService 玩家所在游戏的节点，节点不可用或玩家不在游戏中时返回nil，由负载均衡选择
+func (gmh *GatewayMessageHandler) gameService(conn *network.Connection) *discovery.ServiceInfo {
+	if conn.UserID == 0 {
+		return nil
+	}
+	node := gmh.gameRoutes.lookup(conn.UserID)
+	if node == "" {
+		return nil
+	}
+	for _, service := range gmh.server.discovery.GetAllServices("game") {
+		if service.NodeID == node {
+			return service
+		}
+	}
+	// 节点已下线，房间记录可能已经更新
+	gmh.gameRoutes.forget(conn.UserID)
+	return nil
+}
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/gameplay"
+	"github.com/phuhao00/lufy/internal/replay"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxReplayEvents 单个房间回放读取的事件上限
+const maxReplayEvents = 100000
+
+// replayReducers 支持回放调试的玩法模块
+var replayReducers = map[string]replay.Reducer{
+	"card_game": gameplay.NewCardGameModule(),
+}
+
+// roomReplay 从回放存储读出的房间事件
+type roomReplay struct {
+	RoomID   uint64               `json:"room_id"`
+	Events   []gameplay.GameEvent `json:"-"`
+	Warnings []string             `json:"warnings,omitempty"` // 事件序号不连续时回放结果不可信
+}
+
+// loadRoomReplay 读取房间的全部事件，检查序号是否从0开始且连续
+func loadRoomReplay(repo *database.ReplayRepository, roomID uint64) (*roomReplay, error) {
+	records, err := repo.GetRoomEvents(roomID, 0, maxReplayEvents)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("房间 %d 没有回放事件", roomID)
+	}
+
+	result := &roomReplay{RoomID: roomID, Events: make([]gameplay.GameEvent, len(records))}
+	if len(records) == maxReplayEvents {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("事件超过 %d 条，只回放前 %d 条", maxReplayEvents, maxReplayEvents))
+	}
+	for i, record := range records {
+		expected := uint64(0)
+		if i > 0 {
+			expected = records[i-1].Seq + 1
+		}
+		if record.Seq != expected {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("缺少事件 %d-%d", expected, record.Seq-1))
+		}
+		result.Events[i] = gameplay.GameEvent{
+			Type:      record.Type,
+			PlayerID:  record.PlayerID,
+			Data:      plainValue(record.Data),
+			Timestamp: record.Timestamp,
+		}
+	}
+	return result, nil
+}
+
+// plainValue 把从MongoDB读出的文档和数组转换为map和切片，便于按JSON解析
+func plainValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case primitive.D:
+		result := make(map[string]interface{}, len(value))
+		for _, e := range value {
+			result[e.Key] = plainValue(e.Value)
+		}
+		return result
+	case primitive.M:
+		result := make(map[string]interface{}, len(value))
+		for key, e := range value {
+			result[key] = plainValue(e)
+		}
+		return result
+	case primitive.A:
+		result := make([]interface{}, len(value))
+		for i, e := range value {
+			result[i] = plainValue(e)
+		}
+		return result
+	}
+	return v
+}
+
+// replayRoomConfig 按玩法的规则表解析 规则=值 参数，未指定的规则取默认值
+func replayRoomConfig(gameType string, args []string) (replay.Reducer, *gameplay.RoomConfig, error) {
+	reducer, exists := replayReducers[gameType]
+	if !exists {
+		return nil, nil, fmt.Errorf("玩法 %s 不支持回放", gameType)
+	}
+
+	custom := make(map[string]interface{}, len(args))
+	for _, arg := range args {
+		name, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return nil, nil, fmt.Errorf("无效的房间规则: %s", arg)
+		}
+		if n, err := strconv.Atoi(value); err == nil {
+			custom[name] = n
+		} else if b, err := strconv.ParseBool(value); err == nil {
+			custom[name] = b
+		} else {
+			custom[name] = value
+		}
+	}
+
+	config := &gameplay.RoomConfig{}
+	if provider, ok := reducer.(gameplay.RuleProvider); ok {
+		rules, err := provider.GetRoomRules().Apply(custom)
+		if err != nil {
+			return nil, nil, err
+		}
+		config.CustomConfig = rules
+	} else if len(custom) > 0 {
+		return nil, nil, fmt.Errorf("玩法 %s 不支持自定义规则", gameType)
+	}
+	return reducer, config, nil
+}
+
+// roomReplayJSON 重建房间在某个事件后的状态，at为事件位置，为空时取最后一个事件，为start时取房间开始时的状态
+func (gs *GMServer) roomReplayJSON(roomID uint64, at string, gameType string, rules []string) ([]byte, error) {
+	reducer, config, err := replayRoomConfig(gameType, rules)
+	if err != nil {
+		return nil, err
+	}
+	room, err := loadRoomReplay(gs.replayRepo, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	index := len(room.Events) - 1
+	switch at {
+	case "":
+	case "start":
+		index = -1
+	default:
+		if index, err = strconv.Atoi(at); err != nil {
+			return nil, fmt.Errorf("无效的事件位置: %s", at)
+		}
+	}
+
+	frame, err := replay.StateAt(reducer, config, room.Events, index)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		*roomReplay
+		*replay.Frame
+	}{room, frame})
+}
+
+// roomDiffJSON 对比两个房间的事件，给出第一个分歧点，完全一致时divergence为null
+func (gs *GMServer) roomDiffJSON(roomA, roomB uint64, gameType string, rules []string) ([]byte, error) {
+	reducer, config, err := replayRoomConfig(gameType, rules)
+	if err != nil {
+		return nil, err
+	}
+	a, err := loadRoomReplay(gs.replayRepo, roomA)
+	if err != nil {
+		return nil, err
+	}
+	b, err := loadRoomReplay(gs.replayRepo, roomB)
+	if err != nil {
+		return nil, err
+	}
+
+	divergence, err := replay.Compare(reducer, config, a.Events, b.Events)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]interface{}{
+		"divergence": divergence,
+		"a":          a,
+		"b":          b,
+	})
+}
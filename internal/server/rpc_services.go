@@ -0,0 +1,448 @@
+package server
+
+import (
+	"context"
+
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+//go:generate go run ../../tools/rpcgen -output rpc_services_gen.go
+
+// SystemServiceAPI 系统服务接口
+//
+//rpcgen:service SystemService
+type SystemServiceAPI interface {
+	// GetServerInfo 获取服务器信息
+	GetServerInfo(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// GetServerStats 获取服务器统计信息
+	GetServerStats(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// ReloadConfig 重新加载配置
+	ReloadConfig(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// UpdateLoad 更新负载
+	UpdateLoad(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// Shutdown 关闭服务器
+	Shutdown(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// GetActorStats 获取Actor统计信息
+	GetActorStats(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// GetPoolStats 获取对象池统计信息
+	GetPoolStats(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+}
+
+// GatewayServiceAPI 网关服务接口
+//
+//rpcgen:service GatewayService
+type GatewayServiceAPI interface {
+	// GetConnectionCount 获取连接数
+	GetConnectionCount(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// SendToUser 发送消息给指定用户
+	SendToUser(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// BroadcastMessage 广播消息
+	BroadcastMessage(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// KickUser 踢出用户
+	KickUser(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+}
+
+// LoginServiceAPI 登录服务接口
+//
+//rpcgen:service LoginService
+type LoginServiceAPI interface {
+	// Login 用户登录
+	Login(ctx context.Context, req *proto.LoginRequest) (*proto.LoginResponse, error)
+
+	// Register 用户注册
+	Register(ctx context.Context, req *proto.LoginRequest) (*proto.LoginResponse, error)
+
+	// Logout 用户登出
+	Logout(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// ValidateToken 验证令牌
+	ValidateToken(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// RefreshToken 刷新令牌
+	RefreshToken(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// GetAuthMetrics 获取登录失败统计
+	GetAuthMetrics(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// GuestLogin 游客登录，首次登录时创建游客账号
+	GuestLogin(ctx context.Context, req *proto.LoginRequest) (*proto.LoginResponse, error)
+
+	// OAuthLogin 使用已绑定的第三方账号登录
+	OAuthLogin(ctx context.Context, req *proto.LoginRequest) (*proto.LoginResponse, error)
+
+	// LinkAccount 把游客账号绑定到用户名密码或第三方账号
+	LinkAccount(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+}
+
+// LobbyServiceAPI 大厅服务接口
+//
+//rpcgen:service LobbyService
+type LobbyServiceAPI interface {
+	// GetRoomList 获取房间列表
+	GetRoomList(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// CreateRoom 创建房间
+	CreateRoom(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// JoinRoom 加入房间
+	JoinRoom(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// LeaveRoom 离开房间
+	LeaveRoom(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// GetActiveEvents 获取进行中的限时活动
+	GetActiveEvents(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// GetProgression 获取等级进度和功能解锁等级
+	GetProgression(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// ExchangeCurrency 兑换金币和钻石
+	ExchangeCurrency(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// GetFeatureFlags 获取按玩家分群下发的功能开关和实验分组
+	GetFeatureFlags(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// GetAnnouncements 获取进行中的公告，按玩家语言返回内容
+	GetAnnouncements(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// GetSurveys 获取玩家可以回答的问卷
+	GetSurveys(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// SubmitSurvey 提交问卷回答
+	SubmitSurvey(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// SubmitTicket 提交客服工单
+	SubmitTicket(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// RedeemCode 兑换码兑换
+	RedeemCode(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// GetTickets 获取玩家的客服工单和回复
+	GetTickets(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// StartMatch 开始匹配
+	StartMatch(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// CancelMatch 取消匹配
+	CancelMatch(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// GetMatchStatus 获取匹配状态
+	GetMatchStatus(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// CreateParty 创建队伍
+	CreateParty(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// InviteToParty 邀请玩家加入队伍
+	InviteToParty(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// JoinParty 接受邀请加入队伍
+	JoinParty(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// LeaveParty 离开队伍
+	LeaveParty(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// GetParty 获取所在的队伍
+	GetParty(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// SendPartyMessage 发送队伍聊天消息
+	SendPartyMessage(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// SpectateFriend 观战好友所在的房间
+	SpectateFriend(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// StopSpectating 停止观战
+	StopSpectating(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// GetSettings 获取隐私设置
+	GetSettings(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// UpdateSettings 修改隐私设置
+	UpdateSettings(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// GetNotificationPrefs 获取通知偏好
+	GetNotificationPrefs(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// UpdateNotificationPrefs 修改通知偏好
+	UpdateNotificationPrefs(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// RenameNickname 修改昵称
+	RenameNickname(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// RenameUsername 修改用户名
+	RenameUsername(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// SearchPlayers 按名称搜索玩家
+	SearchPlayers(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// SearchRooms 按名称搜索房间
+	SearchRooms(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+}
+
+// GameServiceAPI 游戏服务接口
+//
+//rpcgen:service GameService
+type GameServiceAPI interface {
+	// StartGame 开始游戏
+	StartGame(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// EndGame 结束游戏
+	EndGame(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// PlayerAction 玩家操作
+	PlayerAction(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// GetGameState 获取游戏状态
+	GetGameState(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// GetActiveAsyncGames 获取进行中的异步对局
+	GetActiveAsyncGames(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// ImportGame 迁入其他节点迁出的游戏，只在游戏节点之间调用
+	ImportGame(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+}
+
+// EnhancedGameServiceAPI 增强游戏服务接口
+//
+//rpcgen:service EnhancedGameService
+type EnhancedGameServiceAPI interface {
+	// CreateRoom 创建游戏房间
+	CreateRoom(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// GetRoomRules 获取玩法类型可设置的房间规则
+	GetRoomRules(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// JoinRoom 加入房间
+	JoinRoom(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// LeaveRoom 离开房间
+	LeaveRoom(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// GameAction 处理游戏操作
+	GameAction(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// ReportDesync 上报状态不一致时客户端的本地状态
+	ReportDesync(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// GetRoomState 获取房间状态
+	GetRoomState(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// QuickChat 发送快捷消息或表情
+	QuickChat(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// BlockQuickChat 屏蔽或取消屏蔽房间内玩家的快捷消息
+	BlockQuickChat(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// ValidateToken 验证令牌
+	ValidateToken(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// CheckSecurity 安全检查
+	CheckSecurity(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// GetMetrics 获取监控指标
+	GetMetrics(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// GetAlerts 获取告警信息
+	GetAlerts(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// HotReload 热更新
+	HotReload(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+}
+
+// FriendServiceAPI 好友服务接口
+//
+//rpcgen:service FriendService
+type FriendServiceAPI interface {
+	// AddFriend 添加好友
+	AddFriend(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// AcceptFriend 接受好友请求
+	AcceptFriend(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// GetFriendList 获取好友列表
+	GetFriendList(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// DeleteFriend 删除好友
+	DeleteFriend(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+}
+
+// ChatServiceAPI 聊天服务接口
+//
+//rpcgen:service ChatService
+type ChatServiceAPI interface {
+	// SendMessage 发送消息
+	SendMessage(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// GetChatHistory 获取聊天历史
+	GetChatHistory(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// EditMessage 编辑自己发送的消息
+	EditMessage(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// RecallMessage 撤回自己发送的消息
+	RecallMessage(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// ReactMessage 用表情回应消息或取消回应
+	ReactMessage(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// BlockUser 屏蔽用户
+	BlockUser(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// UnblockUser 取消屏蔽用户
+	UnblockUser(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+}
+
+// MailServiceAPI 邮件服务接口
+//
+//rpcgen:service MailService
+type MailServiceAPI interface {
+	// GetMailList 获取邮件列表
+	GetMailList(ctx context.Context, req *proto.MailListRequest) (*proto.MailListResponse, error)
+
+	// ReadMail 读取邮件
+	ReadMail(ctx context.Context, req *proto.MailOperationRequest) (*proto.CommonResponse, error)
+
+	// ClaimRewards 领取奖励
+	ClaimRewards(ctx context.Context, req *proto.MailOperationRequest) (*proto.CommonResponse, error)
+
+	// DeleteMail 删除邮件
+	DeleteMail(ctx context.Context, req *proto.MailOperationRequest) (*proto.CommonResponse, error)
+
+	// SendMail 发送邮件
+	SendMail(ctx context.Context, req *proto.SendMailRequest) (*proto.CommonResponse, error)
+
+	// CreateCampaign 创建向玩家分群发送的邮件活动
+	CreateCampaign(ctx context.Context, req *proto.MailCampaignRequest) (*proto.CommonResponse, error)
+
+	// GetCampaign 获取邮件活动的进度和统计，未指定活动时获取最近的活动
+	GetCampaign(ctx context.Context, req *proto.MailCampaignQuery) (*proto.CommonResponse, error)
+
+	// UpdateCampaign 暂停、继续或取消邮件活动
+	UpdateCampaign(ctx context.Context, req *proto.MailCampaignQuery) (*proto.CommonResponse, error)
+}
+
+// GMServiceAPI GM服务接口
+//
+//rpcgen:service GMService
+type GMServiceAPI interface {
+	// ExecuteCommand 执行GM命令
+	ExecuteCommand(ctx context.Context, req *proto.GMCommandRequest) (*proto.CommonResponse, error)
+
+	// KickUser 踢出用户
+	KickUser(ctx context.Context, req *proto.KickUserRequest) (*proto.CommonResponse, error)
+
+	// BanUser 封禁用户
+	BanUser(ctx context.Context, req *proto.BanUserRequest) (*proto.CommonResponse, error)
+
+	// UnbanUser 解封用户
+	UnbanUser(ctx context.Context, req *proto.UnbanUserRequest) (*proto.CommonResponse, error)
+
+	// SendNotice 发送公告
+	SendNotice(ctx context.Context, req *proto.SendNoticeRequest) (*proto.CommonResponse, error)
+
+	// ReloadConfig 重新加载配置
+	ReloadConfig(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error)
+
+	// ExportUserData 导出用户数据
+	ExportUserData(ctx context.Context, req *proto.UserDataRequest) (*proto.CommonResponse, error)
+
+	// DeleteUserData 删除用户数据
+	DeleteUserData(ctx context.Context, req *proto.UserDataRequest) (*proto.CommonResponse, error)
+
+	// RollbackGrant 按批次回滚奖励发放
+	RollbackGrant(ctx context.Context, req *proto.RollbackGrantRequest) (*proto.CommonResponse, error)
+
+	// RolloutConfig 分阶段更新配置，先在所有节点校验，再提交到指定节点
+	RolloutConfig(ctx context.Context, req *proto.ConfigRolloutRequest) (*proto.CommonResponse, error)
+
+	// CreateAnnouncement 创建定时公告
+	CreateAnnouncement(ctx context.Context, req *proto.AnnouncementRequest) (*proto.CommonResponse, error)
+
+	// ListAnnouncements 获取公告，未指定公告时获取最近创建的公告
+	ListAnnouncements(ctx context.Context, req *proto.AnnouncementQuery) (*proto.CommonResponse, error)
+
+	// DeleteAnnouncement 删除公告
+	DeleteAnnouncement(ctx context.Context, req *proto.AnnouncementQuery) (*proto.CommonResponse, error)
+
+	// CreateSurvey 创建面向分群的问卷
+	CreateSurvey(ctx context.Context, req *proto.SurveyRequest) (*proto.CommonResponse, error)
+
+	// ListSurveys 获取问卷和回答数，未指定问卷时获取最近创建的问卷
+	ListSurveys(ctx context.Context, req *proto.SurveyQuery) (*proto.CommonResponse, error)
+
+	// ExportSurvey 把问卷回答导出为CSV
+	ExportSurvey(ctx context.Context, req *proto.SurveyQuery) (*proto.CommonResponse, error)
+
+	// ListTickets 获取客服工单，未指定工单时按条件获取最近的工单
+	ListTickets(ctx context.Context, req *proto.SupportTicketQuery) (*proto.CommonResponse, error)
+
+	// UpdateTicket 分配、解决或重新打开客服工单
+	UpdateTicket(ctx context.Context, req *proto.SupportTicketAction) (*proto.CommonResponse, error)
+
+	// RespondTicket 回复客服工单，回复作为邮件发给玩家
+	RespondTicket(ctx context.Context, req *proto.SupportTicketReply) (*proto.CommonResponse, error)
+
+	// SetVIP 设置玩家的VIP等级，由计费系统调用
+	SetVIP(ctx context.Context, req *proto.SetVIPRequest) (*proto.CommonResponse, error)
+
+	// CreateRedeemBatch 创建兑换码批次
+	CreateRedeemBatch(ctx context.Context, req *proto.RedeemBatchRequest) (*proto.CommonResponse, error)
+
+	// ListRedeemBatches 获取兑换码批次和兑换统计，未指定批次时获取最近创建的批次
+	ListRedeemBatches(ctx context.Context, req *proto.RedeemBatchQuery) (*proto.CommonResponse, error)
+
+	// UpdateRedeemBatch 停用或重新启用兑换码批次
+	UpdateRedeemBatch(ctx context.Context, req *proto.RedeemBatchQuery) (*proto.CommonResponse, error)
+
+	// CreateNewsItem 创建资讯、推广或更新说明
+	CreateNewsItem(ctx context.Context, req *proto.NewsItemRequest) (*proto.CommonResponse, error)
+
+	// ListNewsItems 获取资讯，未指定资讯时获取最近创建的资讯
+	ListNewsItems(ctx context.Context, req *proto.NewsQuery) (*proto.CommonResponse, error)
+
+	// DeleteNewsItem 删除资讯
+	DeleteNewsItem(ctx context.Context, req *proto.NewsQuery) (*proto.CommonResponse, error)
+
+	// GetRealtimeStats 获取集群的在线人数和房间计数
+	GetRealtimeStats(ctx context.Context, req *proto.BaseRequest) (*proto.CommonResponse, error)
+
+	// GetCapacityReport 获取容量规划周报
+	GetCapacityReport(ctx context.Context, req *proto.CapacityReportQuery) (*proto.CommonResponse, error)
+}
+
+// CenterServiceAPI 中心服务接口
+//
+//rpcgen:service CenterService
+type CenterServiceAPI interface {
+	// GetServiceList 获取服务列表
+	GetServiceList(ctx context.Context, req *proto.BaseRequest) (*proto.ServiceListResponse, error)
+
+	// GetClusterStatus 获取集群状态
+	GetClusterStatus(ctx context.Context, req *proto.BaseRequest) (*proto.ClusterStatusResponse, error)
+
+	// BroadcastMessage 广播消息
+	BroadcastMessage(ctx context.Context, req *proto.BroadcastMessageRequest) (*proto.CommonResponse, error)
+
+	// ShutdownService 关闭服务
+	ShutdownService(ctx context.Context, req *proto.ServiceOperationRequest) (*proto.CommonResponse, error)
+
+	// RestartService 重启服务
+	RestartService(ctx context.Context, req *proto.ServiceOperationRequest) (*proto.CommonResponse, error)
+
+	// DrainService 排空或恢复服务
+	DrainService(ctx context.Context, req *proto.ServiceOperationRequest) (*proto.CommonResponse, error)
+
+	// SetMaintenance 开启或关闭维护模式
+	SetMaintenance(ctx context.Context, req *proto.MaintenanceRequest) (*proto.CommonResponse, error)
+
+	// SetCanary 设置或取消灰度节点
+	SetCanary(ctx context.Context, req *proto.CanaryRequest) (*proto.CommonResponse, error)
+}
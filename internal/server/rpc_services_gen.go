@@ -0,0 +1,1511 @@
+// Code generated by rpcgen from rpc_services.go. DO NOT EDIT.
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/rpc"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// RegisterSystemService 注册SystemService服务
+func RegisterSystemService(server *rpc.RPCServer, impl SystemServiceAPI) error {
+	return server.RegisterServiceDesc(&rpc.ServiceDesc{
+		Name: "SystemService",
+		Methods: map[string]*rpc.MethodDesc{
+			"GetServerInfo":  rpc.NewMethod(impl.GetServerInfo),
+			"GetServerStats": rpc.NewMethod(impl.GetServerStats),
+			"ReloadConfig":   rpc.NewMethod(impl.ReloadConfig),
+			"UpdateLoad":     rpc.NewMethod(impl.UpdateLoad),
+			"Shutdown":       rpc.NewMethod(impl.Shutdown),
+			"GetActorStats":  rpc.NewMethod(impl.GetActorStats),
+			"GetPoolStats":   rpc.NewMethod(impl.GetPoolStats),
+		},
+	})
+}
+
+// SystemServiceClient SystemService服务客户端
+type SystemServiceClient struct {
+	client  *rpc.RPCClient
+	timeout time.Duration
+}
+
+// NewSystemServiceClient 创建SystemService服务客户端
+func NewSystemServiceClient(client *rpc.RPCClient, timeout time.Duration) *SystemServiceClient {
+	return &SystemServiceClient{client: client, timeout: timeout}
+}
+
+// GetServerInfo 调用SystemService.GetServerInfo
+func (c *SystemServiceClient) GetServerInfo(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "SystemService", "GetServerInfo", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetServerStats 调用SystemService.GetServerStats
+func (c *SystemServiceClient) GetServerStats(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "SystemService", "GetServerStats", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ReloadConfig 调用SystemService.ReloadConfig
+func (c *SystemServiceClient) ReloadConfig(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "SystemService", "ReloadConfig", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// UpdateLoad 调用SystemService.UpdateLoad
+func (c *SystemServiceClient) UpdateLoad(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "SystemService", "UpdateLoad", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Shutdown 调用SystemService.Shutdown
+func (c *SystemServiceClient) Shutdown(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "SystemService", "Shutdown", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetActorStats 调用SystemService.GetActorStats
+func (c *SystemServiceClient) GetActorStats(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "SystemService", "GetActorStats", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetPoolStats 调用SystemService.GetPoolStats
+func (c *SystemServiceClient) GetPoolStats(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "SystemService", "GetPoolStats", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RegisterGatewayService 注册GatewayService服务
+func RegisterGatewayService(server *rpc.RPCServer, impl GatewayServiceAPI) error {
+	return server.RegisterServiceDesc(&rpc.ServiceDesc{
+		Name: "GatewayService",
+		Methods: map[string]*rpc.MethodDesc{
+			"GetConnectionCount": rpc.NewMethod(impl.GetConnectionCount),
+			"SendToUser":         rpc.NewMethod(impl.SendToUser),
+			"BroadcastMessage":   rpc.NewMethod(impl.BroadcastMessage),
+			"KickUser":           rpc.NewMethod(impl.KickUser),
+		},
+	})
+}
+
+// GatewayServiceClient GatewayService服务客户端
+type GatewayServiceClient struct {
+	client  *rpc.RPCClient
+	timeout time.Duration
+}
+
+// NewGatewayServiceClient 创建GatewayService服务客户端
+func NewGatewayServiceClient(client *rpc.RPCClient, timeout time.Duration) *GatewayServiceClient {
+	return &GatewayServiceClient{client: client, timeout: timeout}
+}
+
+// GetConnectionCount 调用GatewayService.GetConnectionCount
+func (c *GatewayServiceClient) GetConnectionCount(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "GatewayService", "GetConnectionCount", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// SendToUser 调用GatewayService.SendToUser
+func (c *GatewayServiceClient) SendToUser(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "GatewayService", "SendToUser", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// BroadcastMessage 调用GatewayService.BroadcastMessage
+func (c *GatewayServiceClient) BroadcastMessage(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "GatewayService", "BroadcastMessage", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// KickUser 调用GatewayService.KickUser
+func (c *GatewayServiceClient) KickUser(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "GatewayService", "KickUser", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RegisterLoginService 注册LoginService服务
+func RegisterLoginService(server *rpc.RPCServer, impl LoginServiceAPI) error {
+	return server.RegisterServiceDesc(&rpc.ServiceDesc{
+		Name: "LoginService",
+		Methods: map[string]*rpc.MethodDesc{
+			"Login":          rpc.NewMethod(impl.Login),
+			"Register":       rpc.NewMethod(impl.Register),
+			"Logout":         rpc.NewMethod(impl.Logout),
+			"ValidateToken":  rpc.NewMethod(impl.ValidateToken),
+			"RefreshToken":   rpc.NewMethod(impl.RefreshToken),
+			"GetAuthMetrics": rpc.NewMethod(impl.GetAuthMetrics),
+			"GuestLogin":     rpc.NewMethod(impl.GuestLogin),
+			"OAuthLogin":     rpc.NewMethod(impl.OAuthLogin),
+			"LinkAccount":    rpc.NewMethod(impl.LinkAccount),
+		},
+	})
+}
+
+// LoginServiceClient LoginService服务客户端
+type LoginServiceClient struct {
+	client  *rpc.RPCClient
+	timeout time.Duration
+}
+
+// NewLoginServiceClient 创建LoginService服务客户端
+func NewLoginServiceClient(client *rpc.RPCClient, timeout time.Duration) *LoginServiceClient {
+	return &LoginServiceClient{client: client, timeout: timeout}
+}
+
+// Login 调用LoginService.Login
+func (c *LoginServiceClient) Login(ctx context.Context, req *proto.LoginRequest) (*proto.LoginResponse, error) {
+	resp := new(proto.LoginResponse)
+	if err := c.client.Invoke(ctx, "LoginService", "Login", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Register 调用LoginService.Register
+func (c *LoginServiceClient) Register(ctx context.Context, req *proto.LoginRequest) (*proto.LoginResponse, error) {
+	resp := new(proto.LoginResponse)
+	if err := c.client.Invoke(ctx, "LoginService", "Register", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Logout 调用LoginService.Logout
+func (c *LoginServiceClient) Logout(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LoginService", "Logout", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ValidateToken 调用LoginService.ValidateToken
+func (c *LoginServiceClient) ValidateToken(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LoginService", "ValidateToken", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RefreshToken 调用LoginService.RefreshToken
+func (c *LoginServiceClient) RefreshToken(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LoginService", "RefreshToken", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetAuthMetrics 调用LoginService.GetAuthMetrics
+func (c *LoginServiceClient) GetAuthMetrics(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LoginService", "GetAuthMetrics", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GuestLogin 调用LoginService.GuestLogin
+func (c *LoginServiceClient) GuestLogin(ctx context.Context, req *proto.LoginRequest) (*proto.LoginResponse, error) {
+	resp := new(proto.LoginResponse)
+	if err := c.client.Invoke(ctx, "LoginService", "GuestLogin", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// OAuthLogin 调用LoginService.OAuthLogin
+func (c *LoginServiceClient) OAuthLogin(ctx context.Context, req *proto.LoginRequest) (*proto.LoginResponse, error) {
+	resp := new(proto.LoginResponse)
+	if err := c.client.Invoke(ctx, "LoginService", "OAuthLogin", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// LinkAccount 调用LoginService.LinkAccount
+func (c *LoginServiceClient) LinkAccount(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LoginService", "LinkAccount", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RegisterLobbyService 注册LobbyService服务
+func RegisterLobbyService(server *rpc.RPCServer, impl LobbyServiceAPI) error {
+	return server.RegisterServiceDesc(&rpc.ServiceDesc{
+		Name: "LobbyService",
+		Methods: map[string]*rpc.MethodDesc{
+			"GetRoomList":             rpc.NewMethod(impl.GetRoomList),
+			"CreateRoom":              rpc.NewMethod(impl.CreateRoom),
+			"JoinRoom":                rpc.NewMethod(impl.JoinRoom),
+			"LeaveRoom":               rpc.NewMethod(impl.LeaveRoom),
+			"GetActiveEvents":         rpc.NewMethod(impl.GetActiveEvents),
+			"GetProgression":          rpc.NewMethod(impl.GetProgression),
+			"ExchangeCurrency":        rpc.NewMethod(impl.ExchangeCurrency),
+			"GetFeatureFlags":         rpc.NewMethod(impl.GetFeatureFlags),
+			"GetAnnouncements":        rpc.NewMethod(impl.GetAnnouncements),
+			"GetSurveys":              rpc.NewMethod(impl.GetSurveys),
+			"SubmitSurvey":            rpc.NewMethod(impl.SubmitSurvey),
+			"SubmitTicket":            rpc.NewMethod(impl.SubmitTicket),
+			"RedeemCode":              rpc.NewMethod(impl.RedeemCode),
+			"GetTickets":              rpc.NewMethod(impl.GetTickets),
+			"StartMatch":              rpc.NewMethod(impl.StartMatch),
+			"CancelMatch":             rpc.NewMethod(impl.CancelMatch),
+			"GetMatchStatus":          rpc.NewMethod(impl.GetMatchStatus),
+			"CreateParty":             rpc.NewMethod(impl.CreateParty),
+			"InviteToParty":           rpc.NewMethod(impl.InviteToParty),
+			"JoinParty":               rpc.NewMethod(impl.JoinParty),
+			"LeaveParty":              rpc.NewMethod(impl.LeaveParty),
+			"GetParty":                rpc.NewMethod(impl.GetParty),
+			"SendPartyMessage":        rpc.NewMethod(impl.SendPartyMessage),
+			"SpectateFriend":          rpc.NewMethod(impl.SpectateFriend),
+			"StopSpectating":          rpc.NewMethod(impl.StopSpectating),
+			"GetSettings":             rpc.NewMethod(impl.GetSettings),
+			"UpdateSettings":          rpc.NewMethod(impl.UpdateSettings),
+			"GetNotificationPrefs":    rpc.NewMethod(impl.GetNotificationPrefs),
+			"UpdateNotificationPrefs": rpc.NewMethod(impl.UpdateNotificationPrefs),
+			"RenameNickname":          rpc.NewMethod(impl.RenameNickname),
+			"RenameUsername":          rpc.NewMethod(impl.RenameUsername),
+			"SearchPlayers":           rpc.NewMethod(impl.SearchPlayers),
+			"SearchRooms":             rpc.NewMethod(impl.SearchRooms),
+		},
+	})
+}
+
+// LobbyServiceClient LobbyService服务客户端
+type LobbyServiceClient struct {
+	client  *rpc.RPCClient
+	timeout time.Duration
+}
+
+// NewLobbyServiceClient 创建LobbyService服务客户端
+func NewLobbyServiceClient(client *rpc.RPCClient, timeout time.Duration) *LobbyServiceClient {
+	return &LobbyServiceClient{client: client, timeout: timeout}
+}
+
+// GetRoomList 调用LobbyService.GetRoomList
+func (c *LobbyServiceClient) GetRoomList(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LobbyService", "GetRoomList", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// CreateRoom 调用LobbyService.CreateRoom
+func (c *LobbyServiceClient) CreateRoom(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LobbyService", "CreateRoom", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// JoinRoom 调用LobbyService.JoinRoom
+func (c *LobbyServiceClient) JoinRoom(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LobbyService", "JoinRoom", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// LeaveRoom 调用LobbyService.LeaveRoom
+func (c *LobbyServiceClient) LeaveRoom(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LobbyService", "LeaveRoom", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetActiveEvents 调用LobbyService.GetActiveEvents
+func (c *LobbyServiceClient) GetActiveEvents(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LobbyService", "GetActiveEvents", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetProgression 调用LobbyService.GetProgression
+func (c *LobbyServiceClient) GetProgression(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LobbyService", "GetProgression", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ExchangeCurrency 调用LobbyService.ExchangeCurrency
+func (c *LobbyServiceClient) ExchangeCurrency(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LobbyService", "ExchangeCurrency", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetFeatureFlags 调用LobbyService.GetFeatureFlags
+func (c *LobbyServiceClient) GetFeatureFlags(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LobbyService", "GetFeatureFlags", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetAnnouncements 调用LobbyService.GetAnnouncements
+func (c *LobbyServiceClient) GetAnnouncements(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LobbyService", "GetAnnouncements", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetSurveys 调用LobbyService.GetSurveys
+func (c *LobbyServiceClient) GetSurveys(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LobbyService", "GetSurveys", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// SubmitSurvey 调用LobbyService.SubmitSurvey
+func (c *LobbyServiceClient) SubmitSurvey(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LobbyService", "SubmitSurvey", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// SubmitTicket 调用LobbyService.SubmitTicket
+func (c *LobbyServiceClient) SubmitTicket(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LobbyService", "SubmitTicket", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RedeemCode 调用LobbyService.RedeemCode
+func (c *LobbyServiceClient) RedeemCode(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LobbyService", "RedeemCode", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetTickets 调用LobbyService.GetTickets
+func (c *LobbyServiceClient) GetTickets(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LobbyService", "GetTickets", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// StartMatch 调用LobbyService.StartMatch
+func (c *LobbyServiceClient) StartMatch(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LobbyService", "StartMatch", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// CancelMatch 调用LobbyService.CancelMatch
+func (c *LobbyServiceClient) CancelMatch(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LobbyService", "CancelMatch", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetMatchStatus 调用LobbyService.GetMatchStatus
+func (c *LobbyServiceClient) GetMatchStatus(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LobbyService", "GetMatchStatus", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// CreateParty 调用LobbyService.CreateParty
+func (c *LobbyServiceClient) CreateParty(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LobbyService", "CreateParty", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// InviteToParty 调用LobbyService.InviteToParty
+func (c *LobbyServiceClient) InviteToParty(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LobbyService", "InviteToParty", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// JoinParty 调用LobbyService.JoinParty
+func (c *LobbyServiceClient) JoinParty(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LobbyService", "JoinParty", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// LeaveParty 调用LobbyService.LeaveParty
+func (c *LobbyServiceClient) LeaveParty(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LobbyService", "LeaveParty", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetParty 调用LobbyService.GetParty
+func (c *LobbyServiceClient) GetParty(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LobbyService", "GetParty", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// SendPartyMessage 调用LobbyService.SendPartyMessage
+func (c *LobbyServiceClient) SendPartyMessage(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LobbyService", "SendPartyMessage", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// SpectateFriend 调用LobbyService.SpectateFriend
+func (c *LobbyServiceClient) SpectateFriend(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LobbyService", "SpectateFriend", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// StopSpectating 调用LobbyService.StopSpectating
+func (c *LobbyServiceClient) StopSpectating(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LobbyService", "StopSpectating", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetSettings 调用LobbyService.GetSettings
+func (c *LobbyServiceClient) GetSettings(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LobbyService", "GetSettings", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// UpdateSettings 调用LobbyService.UpdateSettings
+func (c *LobbyServiceClient) UpdateSettings(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LobbyService", "UpdateSettings", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetNotificationPrefs 调用LobbyService.GetNotificationPrefs
+func (c *LobbyServiceClient) GetNotificationPrefs(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LobbyService", "GetNotificationPrefs", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// UpdateNotificationPrefs 调用LobbyService.UpdateNotificationPrefs
+func (c *LobbyServiceClient) UpdateNotificationPrefs(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LobbyService", "UpdateNotificationPrefs", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RenameNickname 调用LobbyService.RenameNickname
+func (c *LobbyServiceClient) RenameNickname(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LobbyService", "RenameNickname", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RenameUsername 调用LobbyService.RenameUsername
+func (c *LobbyServiceClient) RenameUsername(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LobbyService", "RenameUsername", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// SearchPlayers 调用LobbyService.SearchPlayers
+func (c *LobbyServiceClient) SearchPlayers(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LobbyService", "SearchPlayers", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// SearchRooms 调用LobbyService.SearchRooms
+func (c *LobbyServiceClient) SearchRooms(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "LobbyService", "SearchRooms", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RegisterGameService 注册GameService服务
+func RegisterGameService(server *rpc.RPCServer, impl GameServiceAPI) error {
+	return server.RegisterServiceDesc(&rpc.ServiceDesc{
+		Name: "GameService",
+		Methods: map[string]*rpc.MethodDesc{
+			"StartGame":           rpc.NewMethod(impl.StartGame),
+			"EndGame":             rpc.NewMethod(impl.EndGame),
+			"PlayerAction":        rpc.NewMethod(impl.PlayerAction),
+			"GetGameState":        rpc.NewMethod(impl.GetGameState),
+			"GetActiveAsyncGames": rpc.NewMethod(impl.GetActiveAsyncGames),
+			"ImportGame":          rpc.NewMethod(impl.ImportGame),
+		},
+	})
+}
+
+// GameServiceClient GameService服务客户端
+type GameServiceClient struct {
+	client  *rpc.RPCClient
+	timeout time.Duration
+}
+
+// NewGameServiceClient 创建GameService服务客户端
+func NewGameServiceClient(client *rpc.RPCClient, timeout time.Duration) *GameServiceClient {
+	return &GameServiceClient{client: client, timeout: timeout}
+}
+
+// StartGame 调用GameService.StartGame
+func (c *GameServiceClient) StartGame(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "GameService", "StartGame", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// EndGame 调用GameService.EndGame
+func (c *GameServiceClient) EndGame(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "GameService", "EndGame", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// PlayerAction 调用GameService.PlayerAction
+func (c *GameServiceClient) PlayerAction(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "GameService", "PlayerAction", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetGameState 调用GameService.GetGameState
+func (c *GameServiceClient) GetGameState(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "GameService", "GetGameState", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetActiveAsyncGames 调用GameService.GetActiveAsyncGames
+func (c *GameServiceClient) GetActiveAsyncGames(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "GameService", "GetActiveAsyncGames", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ImportGame 调用GameService.ImportGame
+func (c *GameServiceClient) ImportGame(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "GameService", "ImportGame", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RegisterEnhancedGameService 注册EnhancedGameService服务
+func RegisterEnhancedGameService(server *rpc.RPCServer, impl EnhancedGameServiceAPI) error {
+	return server.RegisterServiceDesc(&rpc.ServiceDesc{
+		Name: "EnhancedGameService",
+		Methods: map[string]*rpc.MethodDesc{
+			"CreateRoom":     rpc.NewMethod(impl.CreateRoom),
+			"GetRoomRules":   rpc.NewMethod(impl.GetRoomRules),
+			"JoinRoom":       rpc.NewMethod(impl.JoinRoom),
+			"LeaveRoom":      rpc.NewMethod(impl.LeaveRoom),
+			"GameAction":     rpc.NewMethod(impl.GameAction),
+			"ReportDesync":   rpc.NewMethod(impl.ReportDesync),
+			"GetRoomState":   rpc.NewMethod(impl.GetRoomState),
+			"QuickChat":      rpc.NewMethod(impl.QuickChat),
+			"BlockQuickChat": rpc.NewMethod(impl.BlockQuickChat),
+			"ValidateToken":  rpc.NewMethod(impl.ValidateToken),
+			"CheckSecurity":  rpc.NewMethod(impl.CheckSecurity),
+			"GetMetrics":     rpc.NewMethod(impl.GetMetrics),
+			"GetAlerts":      rpc.NewMethod(impl.GetAlerts),
+			"HotReload":      rpc.NewMethod(impl.HotReload),
+		},
+	})
+}
+
+// EnhancedGameServiceClient EnhancedGameService服务客户端
+type EnhancedGameServiceClient struct {
+	client  *rpc.RPCClient
+	timeout time.Duration
+}
+
+// NewEnhancedGameServiceClient 创建EnhancedGameService服务客户端
+func NewEnhancedGameServiceClient(client *rpc.RPCClient, timeout time.Duration) *EnhancedGameServiceClient {
+	return &EnhancedGameServiceClient{client: client, timeout: timeout}
+}
+
+// CreateRoom 调用EnhancedGameService.CreateRoom
+func (c *EnhancedGameServiceClient) CreateRoom(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "EnhancedGameService", "CreateRoom", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetRoomRules 调用EnhancedGameService.GetRoomRules
+func (c *EnhancedGameServiceClient) GetRoomRules(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "EnhancedGameService", "GetRoomRules", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// JoinRoom 调用EnhancedGameService.JoinRoom
+func (c *EnhancedGameServiceClient) JoinRoom(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "EnhancedGameService", "JoinRoom", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// LeaveRoom 调用EnhancedGameService.LeaveRoom
+func (c *EnhancedGameServiceClient) LeaveRoom(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "EnhancedGameService", "LeaveRoom", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GameAction 调用EnhancedGameService.GameAction
+func (c *EnhancedGameServiceClient) GameAction(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "EnhancedGameService", "GameAction", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ReportDesync 调用EnhancedGameService.ReportDesync
+func (c *EnhancedGameServiceClient) ReportDesync(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "EnhancedGameService", "ReportDesync", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetRoomState 调用EnhancedGameService.GetRoomState
+func (c *EnhancedGameServiceClient) GetRoomState(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "EnhancedGameService", "GetRoomState", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// QuickChat 调用EnhancedGameService.QuickChat
+func (c *EnhancedGameServiceClient) QuickChat(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "EnhancedGameService", "QuickChat", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// BlockQuickChat 调用EnhancedGameService.BlockQuickChat
+func (c *EnhancedGameServiceClient) BlockQuickChat(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "EnhancedGameService", "BlockQuickChat", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ValidateToken 调用EnhancedGameService.ValidateToken
+func (c *EnhancedGameServiceClient) ValidateToken(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "EnhancedGameService", "ValidateToken", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// CheckSecurity 调用EnhancedGameService.CheckSecurity
+func (c *EnhancedGameServiceClient) CheckSecurity(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "EnhancedGameService", "CheckSecurity", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetMetrics 调用EnhancedGameService.GetMetrics
+func (c *EnhancedGameServiceClient) GetMetrics(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "EnhancedGameService", "GetMetrics", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetAlerts 调用EnhancedGameService.GetAlerts
+func (c *EnhancedGameServiceClient) GetAlerts(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "EnhancedGameService", "GetAlerts", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// HotReload 调用EnhancedGameService.HotReload
+func (c *EnhancedGameServiceClient) HotReload(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "EnhancedGameService", "HotReload", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RegisterFriendService 注册FriendService服务
+func RegisterFriendService(server *rpc.RPCServer, impl FriendServiceAPI) error {
+	return server.RegisterServiceDesc(&rpc.ServiceDesc{
+		Name: "FriendService",
+		Methods: map[string]*rpc.MethodDesc{
+			"AddFriend":     rpc.NewMethod(impl.AddFriend),
+			"AcceptFriend":  rpc.NewMethod(impl.AcceptFriend),
+			"GetFriendList": rpc.NewMethod(impl.GetFriendList),
+			"DeleteFriend":  rpc.NewMethod(impl.DeleteFriend),
+		},
+	})
+}
+
+// FriendServiceClient FriendService服务客户端
+type FriendServiceClient struct {
+	client  *rpc.RPCClient
+	timeout time.Duration
+}
+
+// NewFriendServiceClient 创建FriendService服务客户端
+func NewFriendServiceClient(client *rpc.RPCClient, timeout time.Duration) *FriendServiceClient {
+	return &FriendServiceClient{client: client, timeout: timeout}
+}
+
+// AddFriend 调用FriendService.AddFriend
+func (c *FriendServiceClient) AddFriend(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "FriendService", "AddFriend", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// AcceptFriend 调用FriendService.AcceptFriend
+func (c *FriendServiceClient) AcceptFriend(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "FriendService", "AcceptFriend", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetFriendList 调用FriendService.GetFriendList
+func (c *FriendServiceClient) GetFriendList(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "FriendService", "GetFriendList", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeleteFriend 调用FriendService.DeleteFriend
+func (c *FriendServiceClient) DeleteFriend(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "FriendService", "DeleteFriend", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RegisterChatService 注册ChatService服务
+func RegisterChatService(server *rpc.RPCServer, impl ChatServiceAPI) error {
+	return server.RegisterServiceDesc(&rpc.ServiceDesc{
+		Name: "ChatService",
+		Methods: map[string]*rpc.MethodDesc{
+			"SendMessage":    rpc.NewMethod(impl.SendMessage),
+			"GetChatHistory": rpc.NewMethod(impl.GetChatHistory),
+			"EditMessage":    rpc.NewMethod(impl.EditMessage),
+			"RecallMessage":  rpc.NewMethod(impl.RecallMessage),
+			"ReactMessage":   rpc.NewMethod(impl.ReactMessage),
+			"BlockUser":      rpc.NewMethod(impl.BlockUser),
+			"UnblockUser":    rpc.NewMethod(impl.UnblockUser),
+		},
+	})
+}
+
+// ChatServiceClient ChatService服务客户端
+type ChatServiceClient struct {
+	client  *rpc.RPCClient
+	timeout time.Duration
+}
+
+// NewChatServiceClient 创建ChatService服务客户端
+func NewChatServiceClient(client *rpc.RPCClient, timeout time.Duration) *ChatServiceClient {
+	return &ChatServiceClient{client: client, timeout: timeout}
+}
+
+// SendMessage 调用ChatService.SendMessage
+func (c *ChatServiceClient) SendMessage(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "ChatService", "SendMessage", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetChatHistory 调用ChatService.GetChatHistory
+func (c *ChatServiceClient) GetChatHistory(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "ChatService", "GetChatHistory", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// EditMessage 调用ChatService.EditMessage
+func (c *ChatServiceClient) EditMessage(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "ChatService", "EditMessage", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RecallMessage 调用ChatService.RecallMessage
+func (c *ChatServiceClient) RecallMessage(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "ChatService", "RecallMessage", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ReactMessage 调用ChatService.ReactMessage
+func (c *ChatServiceClient) ReactMessage(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "ChatService", "ReactMessage", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// BlockUser 调用ChatService.BlockUser
+func (c *ChatServiceClient) BlockUser(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "ChatService", "BlockUser", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// UnblockUser 调用ChatService.UnblockUser
+func (c *ChatServiceClient) UnblockUser(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "ChatService", "UnblockUser", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RegisterMailService 注册MailService服务
+func RegisterMailService(server *rpc.RPCServer, impl MailServiceAPI) error {
+	return server.RegisterServiceDesc(&rpc.ServiceDesc{
+		Name: "MailService",
+		Methods: map[string]*rpc.MethodDesc{
+			"GetMailList":    rpc.NewMethod(impl.GetMailList),
+			"ReadMail":       rpc.NewMethod(impl.ReadMail),
+			"ClaimRewards":   rpc.NewMethod(impl.ClaimRewards),
+			"DeleteMail":     rpc.NewMethod(impl.DeleteMail),
+			"SendMail":       rpc.NewMethod(impl.SendMail),
+			"CreateCampaign": rpc.NewMethod(impl.CreateCampaign),
+			"GetCampaign":    rpc.NewMethod(impl.GetCampaign),
+			"UpdateCampaign": rpc.NewMethod(impl.UpdateCampaign),
+		},
+	})
+}
+
+// MailServiceClient MailService服务客户端
+type MailServiceClient struct {
+	client  *rpc.RPCClient
+	timeout time.Duration
+}
+
+// NewMailServiceClient 创建MailService服务客户端
+func NewMailServiceClient(client *rpc.RPCClient, timeout time.Duration) *MailServiceClient {
+	return &MailServiceClient{client: client, timeout: timeout}
+}
+
+// GetMailList 调用MailService.GetMailList
+func (c *MailServiceClient) GetMailList(ctx context.Context, req *proto.MailListRequest) (*proto.MailListResponse, error) {
+	resp := new(proto.MailListResponse)
+	if err := c.client.Invoke(ctx, "MailService", "GetMailList", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ReadMail 调用MailService.ReadMail
+func (c *MailServiceClient) ReadMail(ctx context.Context, req *proto.MailOperationRequest) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "MailService", "ReadMail", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ClaimRewards 调用MailService.ClaimRewards
+func (c *MailServiceClient) ClaimRewards(ctx context.Context, req *proto.MailOperationRequest) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "MailService", "ClaimRewards", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeleteMail 调用MailService.DeleteMail
+func (c *MailServiceClient) DeleteMail(ctx context.Context, req *proto.MailOperationRequest) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "MailService", "DeleteMail", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// SendMail 调用MailService.SendMail
+func (c *MailServiceClient) SendMail(ctx context.Context, req *proto.SendMailRequest) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "MailService", "SendMail", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// CreateCampaign 调用MailService.CreateCampaign
+func (c *MailServiceClient) CreateCampaign(ctx context.Context, req *proto.MailCampaignRequest) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "MailService", "CreateCampaign", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetCampaign 调用MailService.GetCampaign
+func (c *MailServiceClient) GetCampaign(ctx context.Context, req *proto.MailCampaignQuery) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "MailService", "GetCampaign", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// UpdateCampaign 调用MailService.UpdateCampaign
+func (c *MailServiceClient) UpdateCampaign(ctx context.Context, req *proto.MailCampaignQuery) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "MailService", "UpdateCampaign", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RegisterGMService 注册GMService服务
+func RegisterGMService(server *rpc.RPCServer, impl GMServiceAPI) error {
+	return server.RegisterServiceDesc(&rpc.ServiceDesc{
+		Name: "GMService",
+		Methods: map[string]*rpc.MethodDesc{
+			"ExecuteCommand":     rpc.NewMethod(impl.ExecuteCommand),
+			"KickUser":           rpc.NewMethod(impl.KickUser),
+			"BanUser":            rpc.NewMethod(impl.BanUser),
+			"UnbanUser":          rpc.NewMethod(impl.UnbanUser),
+			"SendNotice":         rpc.NewMethod(impl.SendNotice),
+			"ReloadConfig":       rpc.NewMethod(impl.ReloadConfig),
+			"ExportUserData":     rpc.NewMethod(impl.ExportUserData),
+			"DeleteUserData":     rpc.NewMethod(impl.DeleteUserData),
+			"RollbackGrant":      rpc.NewMethod(impl.RollbackGrant),
+			"RolloutConfig":      rpc.NewMethod(impl.RolloutConfig),
+			"CreateAnnouncement": rpc.NewMethod(impl.CreateAnnouncement),
+			"ListAnnouncements":  rpc.NewMethod(impl.ListAnnouncements),
+			"DeleteAnnouncement": rpc.NewMethod(impl.DeleteAnnouncement),
+			"CreateSurvey":       rpc.NewMethod(impl.CreateSurvey),
+			"ListSurveys":        rpc.NewMethod(impl.ListSurveys),
+			"ExportSurvey":       rpc.NewMethod(impl.ExportSurvey),
+			"ListTickets":        rpc.NewMethod(impl.ListTickets),
+			"UpdateTicket":       rpc.NewMethod(impl.UpdateTicket),
+			"RespondTicket":      rpc.NewMethod(impl.RespondTicket),
+			"SetVIP":             rpc.NewMethod(impl.SetVIP),
+			"CreateRedeemBatch":  rpc.NewMethod(impl.CreateRedeemBatch),
+			"ListRedeemBatches":  rpc.NewMethod(impl.ListRedeemBatches),
+			"UpdateRedeemBatch":  rpc.NewMethod(impl.UpdateRedeemBatch),
+			"CreateNewsItem":     rpc.NewMethod(impl.CreateNewsItem),
+			"ListNewsItems":      rpc.NewMethod(impl.ListNewsItems),
+			"DeleteNewsItem":     rpc.NewMethod(impl.DeleteNewsItem),
+			"GetRealtimeStats":   rpc.NewMethod(impl.GetRealtimeStats),
+			"GetCapacityReport":  rpc.NewMethod(impl.GetCapacityReport),
+		},
+	})
+}
+
+// GMServiceClient GMService服务客户端
+type GMServiceClient struct {
+	client  *rpc.RPCClient
+	timeout time.Duration
+}
+
+// NewGMServiceClient 创建GMService服务客户端
+func NewGMServiceClient(client *rpc.RPCClient, timeout time.Duration) *GMServiceClient {
+	return &GMServiceClient{client: client, timeout: timeout}
+}
+
+// ExecuteCommand 调用GMService.ExecuteCommand
+func (c *GMServiceClient) ExecuteCommand(ctx context.Context, req *proto.GMCommandRequest) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "GMService", "ExecuteCommand", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// KickUser 调用GMService.KickUser
+func (c *GMServiceClient) KickUser(ctx context.Context, req *proto.KickUserRequest) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "GMService", "KickUser", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// BanUser 调用GMService.BanUser
+func (c *GMServiceClient) BanUser(ctx context.Context, req *proto.BanUserRequest) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "GMService", "BanUser", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// UnbanUser 调用GMService.UnbanUser
+func (c *GMServiceClient) UnbanUser(ctx context.Context, req *proto.UnbanUserRequest) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "GMService", "UnbanUser", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// SendNotice 调用GMService.SendNotice
+func (c *GMServiceClient) SendNotice(ctx context.Context, req *proto.SendNoticeRequest) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "GMService", "SendNotice", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ReloadConfig 调用GMService.ReloadConfig
+func (c *GMServiceClient) ReloadConfig(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	resp := new(proto.BaseResponse)
+	if err := c.client.Invoke(ctx, "GMService", "ReloadConfig", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ExportUserData 调用GMService.ExportUserData
+func (c *GMServiceClient) ExportUserData(ctx context.Context, req *proto.UserDataRequest) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "GMService", "ExportUserData", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeleteUserData 调用GMService.DeleteUserData
+func (c *GMServiceClient) DeleteUserData(ctx context.Context, req *proto.UserDataRequest) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "GMService", "DeleteUserData", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RollbackGrant 调用GMService.RollbackGrant
+func (c *GMServiceClient) RollbackGrant(ctx context.Context, req *proto.RollbackGrantRequest) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "GMService", "RollbackGrant", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RolloutConfig 调用GMService.RolloutConfig
+func (c *GMServiceClient) RolloutConfig(ctx context.Context, req *proto.ConfigRolloutRequest) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "GMService", "RolloutConfig", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// CreateAnnouncement 调用GMService.CreateAnnouncement
+func (c *GMServiceClient) CreateAnnouncement(ctx context.Context, req *proto.AnnouncementRequest) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "GMService", "CreateAnnouncement", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ListAnnouncements 调用GMService.ListAnnouncements
+func (c *GMServiceClient) ListAnnouncements(ctx context.Context, req *proto.AnnouncementQuery) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "GMService", "ListAnnouncements", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeleteAnnouncement 调用GMService.DeleteAnnouncement
+func (c *GMServiceClient) DeleteAnnouncement(ctx context.Context, req *proto.AnnouncementQuery) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "GMService", "DeleteAnnouncement", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// CreateSurvey 调用GMService.CreateSurvey
+func (c *GMServiceClient) CreateSurvey(ctx context.Context, req *proto.SurveyRequest) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "GMService", "CreateSurvey", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ListSurveys 调用GMService.ListSurveys
+func (c *GMServiceClient) ListSurveys(ctx context.Context, req *proto.SurveyQuery) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "GMService", "ListSurveys", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ExportSurvey 调用GMService.ExportSurvey
+func (c *GMServiceClient) ExportSurvey(ctx context.Context, req *proto.SurveyQuery) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "GMService", "ExportSurvey", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ListTickets 调用GMService.ListTickets
+func (c *GMServiceClient) ListTickets(ctx context.Context, req *proto.SupportTicketQuery) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "GMService", "ListTickets", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// UpdateTicket 调用GMService.UpdateTicket
+func (c *GMServiceClient) UpdateTicket(ctx context.Context, req *proto.SupportTicketAction) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "GMService", "UpdateTicket", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RespondTicket 调用GMService.RespondTicket
+func (c *GMServiceClient) RespondTicket(ctx context.Context, req *proto.SupportTicketReply) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "GMService", "RespondTicket", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// SetVIP 调用GMService.SetVIP
+func (c *GMServiceClient) SetVIP(ctx context.Context, req *proto.SetVIPRequest) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "GMService", "SetVIP", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// CreateRedeemBatch 调用GMService.CreateRedeemBatch
+func (c *GMServiceClient) CreateRedeemBatch(ctx context.Context, req *proto.RedeemBatchRequest) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "GMService", "CreateRedeemBatch", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ListRedeemBatches 调用GMService.ListRedeemBatches
+func (c *GMServiceClient) ListRedeemBatches(ctx context.Context, req *proto.RedeemBatchQuery) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "GMService", "ListRedeemBatches", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// UpdateRedeemBatch 调用GMService.UpdateRedeemBatch
+func (c *GMServiceClient) UpdateRedeemBatch(ctx context.Context, req *proto.RedeemBatchQuery) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "GMService", "UpdateRedeemBatch", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// CreateNewsItem 调用GMService.CreateNewsItem
+func (c *GMServiceClient) CreateNewsItem(ctx context.Context, req *proto.NewsItemRequest) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "GMService", "CreateNewsItem", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ListNewsItems 调用GMService.ListNewsItems
+func (c *GMServiceClient) ListNewsItems(ctx context.Context, req *proto.NewsQuery) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "GMService", "ListNewsItems", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeleteNewsItem 调用GMService.DeleteNewsItem
+func (c *GMServiceClient) DeleteNewsItem(ctx context.Context, req *proto.NewsQuery) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "GMService", "DeleteNewsItem", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetRealtimeStats 调用GMService.GetRealtimeStats
+func (c *GMServiceClient) GetRealtimeStats(ctx context.Context, req *proto.BaseRequest) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "GMService", "GetRealtimeStats", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetCapacityReport 调用GMService.GetCapacityReport
+func (c *GMServiceClient) GetCapacityReport(ctx context.Context, req *proto.CapacityReportQuery) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "GMService", "GetCapacityReport", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RegisterCenterService 注册CenterService服务
+func RegisterCenterService(server *rpc.RPCServer, impl CenterServiceAPI) error {
+	return server.RegisterServiceDesc(&rpc.ServiceDesc{
+		Name: "CenterService",
+		Methods: map[string]*rpc.MethodDesc{
+			"GetServiceList":   rpc.NewMethod(impl.GetServiceList),
+			"GetClusterStatus": rpc.NewMethod(impl.GetClusterStatus),
+			"BroadcastMessage": rpc.NewMethod(impl.BroadcastMessage),
+			"ShutdownService":  rpc.NewMethod(impl.ShutdownService),
+			"RestartService":   rpc.NewMethod(impl.RestartService),
+			"DrainService":     rpc.NewMethod(impl.DrainService),
+			"SetMaintenance":   rpc.NewMethod(impl.SetMaintenance),
+			"SetCanary":        rpc.NewMethod(impl.SetCanary),
+		},
+	})
+}
+
+// CenterServiceClient CenterService服务客户端
+type CenterServiceClient struct {
+	client  *rpc.RPCClient
+	timeout time.Duration
+}
+
+// NewCenterServiceClient 创建CenterService服务客户端
+func NewCenterServiceClient(client *rpc.RPCClient, timeout time.Duration) *CenterServiceClient {
+	return &CenterServiceClient{client: client, timeout: timeout}
+}
+
+// GetServiceList 调用CenterService.GetServiceList
+func (c *CenterServiceClient) GetServiceList(ctx context.Context, req *proto.BaseRequest) (*proto.ServiceListResponse, error) {
+	resp := new(proto.ServiceListResponse)
+	if err := c.client.Invoke(ctx, "CenterService", "GetServiceList", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetClusterStatus 调用CenterService.GetClusterStatus
+func (c *CenterServiceClient) GetClusterStatus(ctx context.Context, req *proto.BaseRequest) (*proto.ClusterStatusResponse, error) {
+	resp := new(proto.ClusterStatusResponse)
+	if err := c.client.Invoke(ctx, "CenterService", "GetClusterStatus", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// BroadcastMessage 调用CenterService.BroadcastMessage
+func (c *CenterServiceClient) BroadcastMessage(ctx context.Context, req *proto.BroadcastMessageRequest) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "CenterService", "BroadcastMessage", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ShutdownService 调用CenterService.ShutdownService
+func (c *CenterServiceClient) ShutdownService(ctx context.Context, req *proto.ServiceOperationRequest) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "CenterService", "ShutdownService", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RestartService 调用CenterService.RestartService
+func (c *CenterServiceClient) RestartService(ctx context.Context, req *proto.ServiceOperationRequest) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "CenterService", "RestartService", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DrainService 调用CenterService.DrainService
+func (c *CenterServiceClient) DrainService(ctx context.Context, req *proto.ServiceOperationRequest) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "CenterService", "DrainService", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// SetMaintenance 调用CenterService.SetMaintenance
+func (c *CenterServiceClient) SetMaintenance(ctx context.Context, req *proto.MaintenanceRequest) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "CenterService", "SetMaintenance", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// SetCanary 调用CenterService.SetCanary
+func (c *CenterServiceClient) SetCanary(ctx context.Context, req *proto.CanaryRequest) (*proto.CommonResponse, error) {
+	resp := new(proto.CommonResponse)
+	if err := c.client.Invoke(ctx, "CenterService", "SetCanary", req, resp, c.timeout); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/errcode"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/quota"
+	"github.com/phuhao00/lufy/internal/search"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// newSearch 创建玩家和房间搜索服务，Elasticsearch不可用时退回MongoDB搜索
+func (bs *BaseServer) newSearch() *search.Service {
+	users := database.NewUserRepository(bs.mongoManager)
+	rooms := database.NewRoomRepository(bs.mongoManager)
+	backend, err := search.NewBackend(&bs.config.Search, users, rooms)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to create %s search backend, falling back to mongo: %v", bs.config.Search.Backend, err))
+		backend, _ = search.NewBackend(&search.Config{Backend: search.BackendMongo}, users, rooms)
+	}
+	return search.NewService(&bs.config.Search, backend, users, rooms,
+		database.NewFriendRepository(bs.mongoManager), bs.newSettings())
+}
+
+// searchRequest 解析搜索请求
+func (ls *LobbyService) searchRequest(ctx context.Context, req *proto.BaseRequest) (*proto.SearchRequest, *proto.BaseResponse) {
+	if req.Header.GetUserId() == 0 {
+		return nil, ls.responses.Error(ctx, req.Header, errcode.NotLoggedIn)
+	}
+	searchReq, err := Bind[proto.SearchRequest](req.Data)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Search: failed to unmarshal request: %v", err))
+		return nil, ls.responses.Error(ctx, req.Header, errcode.InvalidRequest)
+	}
+	// 翻页不计入每日搜索次数
+	if searchReq.GetOffset() == 0 {
+		if resp := consumeQuota(ctx, ls.server.quotas, ls.responses, req.Header, quota.OpSearch); resp != nil {
+			return nil, resp
+		}
+	}
+	return searchReq, nil
+}
+
+// searchError 搜索失败时返回给客户端的错误
+func (ls *LobbyService) searchError(ctx context.Context, header *proto.MessageHeader, err error) *proto.BaseResponse {
+	if errors.Is(err, search.ErrQueryTooShort) {
+		return ls.responses.Error(ctx, header, errcode.SearchQueryTooShort.WithDetail(err.Error()))
+	}
+	logger.Error(fmt.Sprintf("Search failed: %v", err))
+	return ls.responses.Error(ctx, header, errcode.Internal)
+}
+
+// SearchPlayers 按昵称或用户名搜索玩家，不返回封禁的玩家和隐私设置不允许被搜索到的玩家
+func (ls *LobbyService) SearchPlayers(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	searchReq, errResp := ls.searchRequest(ctx, req)
+	if errResp != nil {
+		return errResp, nil
+	}
+
+	page, err := ls.server.search.Users(req.Header.GetUserId(), searchReq.GetQuery(), int(searchReq.GetOffset()), int(searchReq.GetLimit()))
+	if err != nil {
+		return ls.searchError(ctx, req.Header, err), nil
+	}
+
+	resp := &proto.PlayerSearchResponse{
+		HasMore:    page.HasMore,
+		NextOffset: searchReq.GetOffset() + int32(len(page.Items)),
+	}
+	for _, user := range page.Items {
+		resp.Players = append(resp.Players, &proto.PlayerSearchResult{
+			UserId:   user.UserID,
+			Nickname: user.Nickname,
+			Level:    user.Level,
+			Avatar:   user.Avatar,
+		})
+	}
+	data, err := proto.Marshal(resp)
+	if err != nil {
+		logger.Error(fmt.Sprintf("SearchPlayers: failed to marshal response: %v", err))
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+	return ls.responses.Success(ctx, req.Header, "success", data), nil
+}
+
+// SearchRooms 按房间名搜索玩家所属世界内等待中的公开房间
+func (ls *LobbyService) SearchRooms(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	searchReq, errResp := ls.searchRequest(ctx, req)
+	if errResp != nil {
+		return errResp, nil
+	}
+
+	user, err := database.NewUserRepository(ls.server.mongoManager).GetByUserID(req.Header.GetUserId())
+	if err != nil {
+		return ls.responses.Error(ctx, req.Header, errcode.UserNotFound), nil
+	}
+	worldID := ls.server.config.World.Resolve(user.WorldID)
+
+	page, err := ls.server.search.Rooms(worldID, searchReq.GetQuery(), int(searchReq.GetOffset()), int(searchReq.GetLimit()))
+	if err != nil {
+		return ls.searchError(ctx, req.Header, err), nil
+	}
+
+	resp := &proto.RoomSearchResponse{
+		HasMore:    page.HasMore,
+		NextOffset: searchReq.GetOffset() + int32(len(page.Items)),
+	}
+	for _, room := range page.Items {
+		info := &proto.RoomInfo{
+			RoomId:         room.RoomID,
+			RoomName:       room.RoomName,
+			GameType:       room.GameType,
+			CurrentPlayers: room.CurrentPlayers,
+			MaxPlayers:     room.MaxPlayers,
+			Status:         room.Status,
+			IsPrivate:      room.IsPrivate,
+			CreatedTime:    uint32(room.CreatedAt.Unix()),
+			Region:         room.Region,
+		}
+		for _, player := range room.Players {
+			playerInfo := &proto.GamePlayerInfo{
+				UserId:   player.UserID,
+				Nickname: player.Nickname,
+				Level:    player.Level,
+				Status:   player.Status,
+				PartyId:  player.PartyID,
+			}
+			info.Players = append(info.Players, playerInfo)
+			if player.UserID == room.OwnerID {
+				info.Owner = playerInfo
+			}
+		}
+		resp.Rooms = append(resp.Rooms, info)
+	}
+	data, err := proto.Marshal(resp)
+	if err != nil {
+		logger.Error(fmt.Sprintf("SearchRooms: failed to marshal response: %v", err))
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+	return ls.responses.Success(ctx, req.Header, "success", data), nil
+}
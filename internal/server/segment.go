@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/errcode"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/segment"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// newSegments 创建玩家分群服务
+func (bs *BaseServer) newSegments() *segment.Service {
+	return segment.NewService(&bs.config.Segment, database.NewUserRepository(bs.mongoManager), bs.redisManager)
+}
+
+// GetFeatureFlags 获取玩家的功能开关和实验分组
+func (ls *LobbyService) GetFeatureFlags(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	userID := req.Header.GetUserId()
+	if userID == 0 {
+		return ls.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
+	}
+
+	flags, err := ls.server.segments.Flags(userID)
+	if err != nil {
+		logger.Error(fmt.Sprintf("GetFeatureFlags: failed to get flags of user %d: %v", userID, err))
+		return ls.responses.Error(ctx, req.Header, errcode.UserNotFound), nil
+	}
+	variants, err := ls.server.segments.Variants(userID)
+	if err != nil {
+		logger.Error(fmt.Sprintf("GetFeatureFlags: failed to get variants of user %d: %v", userID, err))
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+
+	data, err := proto.Marshal(&proto.FeatureFlagsResponse{
+		Flags:    flags,
+		Variants: variants,
+	})
+	if err != nil {
+		logger.Error(fmt.Sprintf("GetFeatureFlags: failed to marshal response: %v", err))
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+	return ls.responses.Success(ctx, req.Header, "success", data), nil
+}
+
+// segmentReport 玩家的分群、功能开关和实验分组
+func (gs *GMServer) segmentReport(userID uint64) (string, error) {
+	segments, err := gs.segments.Segments(userID)
+	if err != nil {
+		return "", err
+	}
+	flags, err := gs.segments.Flags(userID)
+	if err != nil {
+		return "", err
+	}
+	variants, err := gs.segments.Variants(userID)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"user_id":  userID,
+		"segments": segments,
+		"flags":    flags,
+		"variants": variants,
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// startCampaign 在后台向分群内的所有玩家发送，完成后写入GM操作日志
+func (gs *GMServer) startCampaign(gmUserID uint64, action, name, batchID string, send func(userID uint64) error) error {
+	if !gs.segments.Has(name) {
+		return fmt.Errorf("分群 %s 不存在", name)
+	}
+
+	go func() {
+		var sent, failed int
+		err := gs.segments.Members(name, func(userID uint64) error {
+			if err := send(userID); err != nil {
+				logger.Warn(fmt.Sprintf("Campaign %s failed for user %d: %v", batchID, userID, err))
+				failed++
+				return nil
+			}
+			sent++
+			return nil
+		})
+		if err != nil {
+			logger.Error(fmt.Sprintf("Campaign %s to segment %s stopped: %v", batchID, name, err))
+		}
+		logger.Info(fmt.Sprintf("Campaign %s to segment %s finished: %d sent, %d failed", batchID, name, sent, failed))
+		gs.gmRepo.LogGMAction(gmUserID, action, 0,
+			fmt.Sprintf("批次: %s, 分群: %s, 成功: %d, 失败: %d", batchID, name, sent, failed))
+	}()
+	return nil
+}
+
+// sendCampaignMail 创建向分群发送的邮件活动，由邮件服分批发送
+func (gs *GMServer) sendCampaignMail(gmUserID uint64, name, title, content string) (*database.MailCampaign, error) {
+	mailCampaign := &database.MailCampaign{
+		Segment:   name,
+		Templates: map[string]database.MailTemplate{gs.campaigns.Language(): {Title: title, Content: content}},
+		CreatedBy: gmUserID,
+	}
+	if err := gs.campaigns.Create(mailCampaign); err != nil {
+		return nil, err
+	}
+	gs.gmRepo.LogGMAction(gmUserID, "segment_mail", 0,
+		fmt.Sprintf("活动: %s, 分群: %s, 标题: %s", mailCampaign.ID.Hex(), name, title))
+	return mailCampaign, nil
+}
+
+// sendCampaignNotice 向分群发送定向公告，离线玩家在下次登录时收到
+func (gs *GMServer) sendCampaignNotice(gmUserID uint64, name, content string) (string, error) {
+	batchID := fmt.Sprintf("notice:%d", time.Now().UnixNano())
+	err := gs.startCampaign(gmUserID, "segment_notice", name, batchID, func(userID uint64) error {
+		return gs.notifier.Notify(&database.Notification{
+			UserID:   userID,
+			Type:     NotifyNotice,
+			DedupKey: batchID,
+			Data:     map[string]interface{}{"content": content},
+		})
+	})
+	return batchID, err
+}
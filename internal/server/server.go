@@ -5,19 +5,69 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strconv"
 	"sync"
 	"syscall"
 	"time"
 
 	"github.com/spf13/viper"
 
+	"github.com/phuhao00/lufy/internal/activity"
 	"github.com/phuhao00/lufy/internal/actor"
+	"github.com/phuhao00/lufy/internal/announcement"
+	"github.com/phuhao00/lufy/internal/autoscale"
+	"github.com/phuhao00/lufy/internal/backup"
+	"github.com/phuhao00/lufy/internal/campaign"
+	"github.com/phuhao00/lufy/internal/chaos"
+	"github.com/phuhao00/lufy/internal/chatpolicy"
+	"github.com/phuhao00/lufy/internal/compensation"
+	"github.com/phuhao00/lufy/internal/console"
+	"github.com/phuhao00/lufy/internal/crash"
 	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/denorm"
 	"github.com/phuhao00/lufy/internal/discovery"
+	"github.com/phuhao00/lufy/internal/entitlement"
+	"github.com/phuhao00/lufy/internal/eventbus"
+	"github.com/phuhao00/lufy/internal/exchange"
+	"github.com/phuhao00/lufy/internal/fraud"
+	"github.com/phuhao00/lufy/internal/gameplay"
+	"github.com/phuhao00/lufy/internal/geo"
+	"github.com/phuhao00/lufy/internal/hotreload"
+	"github.com/phuhao00/lufy/internal/lane"
 	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/matchmaking"
+	"github.com/phuhao00/lufy/internal/monitoring"
 	"github.com/phuhao00/lufy/internal/mq"
+	"github.com/phuhao00/lufy/internal/naming"
 	"github.com/phuhao00/lufy/internal/network"
+	"github.com/phuhao00/lufy/internal/news"
+	"github.com/phuhao00/lufy/internal/party"
+	"github.com/phuhao00/lufy/internal/privacy"
+	"github.com/phuhao00/lufy/internal/probe"
+	"github.com/phuhao00/lufy/internal/profiling"
+	"github.com/phuhao00/lufy/internal/progression"
+	"github.com/phuhao00/lufy/internal/push"
+	"github.com/phuhao00/lufy/internal/quickchat"
+	"github.com/phuhao00/lufy/internal/quota"
+	"github.com/phuhao00/lufy/internal/realtime"
+	"github.com/phuhao00/lufy/internal/redeem"
+	"github.com/phuhao00/lufy/internal/rollup"
 	"github.com/phuhao00/lufy/internal/rpc"
+	"github.com/phuhao00/lufy/internal/saga"
+	"github.com/phuhao00/lufy/internal/scheduler"
+	"github.com/phuhao00/lufy/internal/search"
+	"github.com/phuhao00/lufy/internal/security"
+	"github.com/phuhao00/lufy/internal/segment"
+	"github.com/phuhao00/lufy/internal/settings"
+	"github.com/phuhao00/lufy/internal/settlement"
+	"github.com/phuhao00/lufy/internal/support"
+	"github.com/phuhao00/lufy/internal/survey"
+	"github.com/phuhao00/lufy/internal/timeline"
+	"github.com/phuhao00/lufy/internal/transfer"
+	"github.com/phuhao00/lufy/internal/trigger"
+	"github.com/phuhao00/lufy/internal/tuning"
+	"github.com/phuhao00/lufy/internal/version"
+	"github.com/phuhao00/lufy/internal/world"
 )
 
 // ServerConfig 服务器配置
@@ -35,6 +85,9 @@ type ServerConfig struct {
 		MaxConnections int `yaml:"max_connections"`
 		ReadTimeout    int `yaml:"read_timeout"`
 		WriteTimeout   int `yaml:"write_timeout"`
+
+		// 实时玩法的可靠UDP传输
+		KCP network.KCPConfig `yaml:"kcp"`
 	} `yaml:"network"`
 
 	Database struct {
@@ -42,7 +95,13 @@ type ServerConfig struct {
 		MongoDB database.MongoConfig `yaml:"mongodb"`
 	} `yaml:"database"`
 
-	NSQ mq.NSQConfig `yaml:"nsq"`
+	MessageQueue struct {
+		Backend   string `yaml:"backend"`   // nsq(默认), kafka
+		Namespace string `yaml:"namespace"` // 主题命名空间，多个环境共用消息集群时设置，如prod、staging
+	} `yaml:"message_queue"`
+
+	NSQ   mq.NSQConfig   `yaml:"nsq"`
+	Kafka mq.KafkaConfig `yaml:"kafka"`
 
 	ETCD discovery.ETCDConfig `yaml:"etcd"`
 
@@ -60,10 +119,133 @@ type ServerConfig struct {
 	} `yaml:"object_pool"`
 
 	RPC struct {
-		PoolSize    int `yaml:"pool_size"`
-		MaxIdle     int `yaml:"max_idle"`
-		IdleTimeout int `yaml:"idle_timeout"`
+		PoolSize    int                 `yaml:"pool_size"`
+		MaxIdle     int                 `yaml:"max_idle"`
+		IdleTimeout int                 `yaml:"idle_timeout"`
+		Logging     rpc.LoggingConfig   `yaml:"logging"`
+		Admin       rpc.AdminAuthConfig `yaml:"admin"`
+		Transport   rpc.TransportConfig `yaml:"transport"`
+		Lanes       lane.Config         `yaml:"lanes"`
 	} `yaml:"rpc"`
+
+	Geo geo.GeoConfig `yaml:"geo"`
+
+	World world.Config `yaml:"world"`
+
+	WorldTransfer transfer.Config `yaml:"world_transfer"`
+
+	Privacy privacy.PrivacyConfig `yaml:"privacy"`
+
+	Settings settings.Config `yaml:"settings"`
+
+	CrashReport crash.Config `yaml:"crash_report"`
+
+	Profiling profiling.ProfilingConfig `yaml:"profiling"`
+
+	Chaos chaos.Config `yaml:"chaos"`
+
+	RuntimeParams tuning.Config `yaml:"runtime_params"`
+
+	Lifecycle LifecycleConfig `yaml:"lifecycle"`
+
+	Probe probe.Config `yaml:"probe"`
+
+	Gameplay gameplay.GameplayConfig `yaml:"gameplay"`
+
+	QuickChat quickchat.Config `yaml:"quick_chat"`
+
+	ChatPolicy chatpolicy.Config `yaml:"chat_policy"`
+
+	Naming naming.Config `yaml:"naming"`
+
+	Search search.Config `yaml:"search"`
+
+	Quota quota.Config `yaml:"quota"`
+
+	VIP entitlement.Config `yaml:"vip"`
+
+	Scheduler scheduler.Config `yaml:"scheduler"`
+
+	Backup backup.Config `yaml:"backup"`
+
+	Settlement settlement.Config `yaml:"settlement"`
+
+	Saga saga.Config `yaml:"saga"`
+
+	RoomMigration RoomMigrationConfig `yaml:"room_migration"`
+
+	Compensation compensation.Config `yaml:"compensation"`
+
+	Startup StartupConfig `yaml:"startup"`
+
+	Warmup WarmupConfig `yaml:"warmup"`
+
+	GMConsole console.Config `yaml:"gm_console"`
+
+	HotReload hotreload.Config `yaml:"hot_reload"`
+
+	Canary CanaryConfig `yaml:"canary"`
+
+	Autoscale autoscale.Config `yaml:"autoscale"`
+
+	Notification NotificationConfig `yaml:"notification"`
+
+	Push push.Config `yaml:"push"`
+
+	AsyncGame AsyncGameConfig `yaml:"async_game"`
+
+	Activity activity.Config `yaml:"activity"`
+
+	Progression progression.Config `yaml:"progression"`
+
+	Exchange exchange.Config `yaml:"exchange"`
+
+	Fraud fraud.Config `yaml:"fraud"`
+
+	Timeline timeline.Config `yaml:"timeline"`
+
+	Segment segment.Config `yaml:"segment"`
+
+	MailCampaign campaign.Config `yaml:"campaign"`
+
+	Announcement announcement.Config `yaml:"announcement"`
+
+	News news.Config `yaml:"news"`
+
+	Survey survey.Config `yaml:"survey"`
+
+	Support support.Config `yaml:"support"`
+
+	Redeem redeem.Config `yaml:"redeem"`
+
+	Triggers trigger.Config `yaml:"triggers"`
+
+	Denorm denorm.Config `yaml:"denorm"`
+
+	Realtime realtime.Config `yaml:"realtime"`
+
+	Rollup rollup.Config `yaml:"rollup"`
+
+	Matchmaking matchmaking.Config `yaml:"matchmaking"`
+
+	Party party.Config `yaml:"party"`
+
+	Spectate struct {
+		MaxSpectators int `yaml:"max_spectators"` // 每个房间最多的观战人数，0为不允许观战
+	} `yaml:"spectate"`
+
+	Security struct {
+		FrameIntegrity   string `yaml:"frame_integrity"`   // none, crc, hmac
+		ReplayProtection bool   `yaml:"replay_protection"` // 校验消息序号单调递增
+
+		FieldEncryption security.FieldEncryptionConfig `yaml:"field_encryption"`
+		PasswordPolicy  security.PasswordPolicyConfig  `yaml:"password_policy"`
+		LoginGuard      security.LoginGuardConfig      `yaml:"login_guard"`
+		RoomGuard       security.RoomGuardConfig       `yaml:"room_guard"`
+		InputValidation security.InputValidationConfig `yaml:"input_validation"`
+		GuestLogin      bool                           `yaml:"guest_login"` // 允许游客登录，游客账号之后可绑定用户名密码或第三方账号
+		OAuth           security.OAuthConfig           `yaml:"oauth"`
+	} `yaml:"security"`
 }
 
 // Server 服务器接口
@@ -77,23 +259,55 @@ type Server interface {
 
 // BaseServer 基础服务器实现
 type BaseServer struct {
-	config   *ServerConfig
-	nodeType string
-	nodeID   string
-	status   string
+	config     *ServerConfig
+	configFile string
+	nodeType   string
+	nodeID     string
+	status     string
 
 	// 组件
 	actorSystem   *actor.ActorSystem
 	tcpServer     *network.TCPServer
 	rpcServer     *rpc.RPCServer
 	rpcClient     *rpc.RPCClient
+	requestLogger *rpc.RequestLogger
+	adminAuth     *rpc.AdminAuth
+	callStats     *rpc.CallStats
+	configReload  *hotreload.HotReloadManager
+	readiness     readinessTracker
+	crashReporter *crash.Reporter
+	chaos         *chaos.Injector
+	tuner         *tuning.Tuner
+	entitlements  *entitlement.Service
+	triggers      *trigger.Service
+	denorm        *denorm.Service
+	realtime      *realtime.Counters
+	lanes         *lane.Lanes
+	profiler      *profiling.Profiler
+	scheduler     *scheduler.Scheduler
+	calendar      *activity.Calendar
 	redisManager  *database.RedisManager
 	mongoManager  *database.MongoManager
-	nsqManager    *mq.NSQManager
+	ipBlacklist   *security.IPBlacklist // 所有节点共享的IP黑名单
+	eventBus      eventbus.Bus
 	messageBroker *mq.MessageBroker
+	systemHandler *mq.SystemMessageHandler // 节点可注册额外的系统命令
+	roomCount     func() int               // 节点上进行中的房间数，游戏节点设置，随指标上报
 	discovery     *discovery.ServiceDiscovery
 	registry      *discovery.ETCDRegistry
 
+	// 节点指标，在network.http_port导出，各节点的组件指标注册到这里
+	nodeMonitoring *monitoring.MonitoringManager
+
+	// 节点组件的启动和停止钩子
+	lifecycle lifecycle
+
+	// 预热任务和到对端节点的RPC连接池
+	warmups     []warmupTask
+	warmupMutex sync.Mutex
+	peerPools   map[string]*peerEntry
+	peerMutex   sync.Mutex
+
 	// 上下文
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -112,15 +326,22 @@ func NewBaseServer(configFile, nodeType, nodeID string) (*BaseServer, error) {
 	// 初始化日志
 	logger.InitGlobalLogger(&config.Log)
 
+	if err := config.World.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid world config: %v", err)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	server := &BaseServer{
-		config:   config,
-		nodeType: nodeType,
-		nodeID:   nodeID,
-		status:   "initializing",
-		ctx:      ctx,
-		cancel:   cancel,
+		config:     config,
+		configFile: configFile,
+		nodeType:   nodeType,
+		nodeID:     nodeID,
+		status:     "initializing",
+		calendar:   activity.NewCalendar(&config.Activity),
+		peerPools:  make(map[string]*peerEntry),
+		ctx:        ctx,
+		cancel:     cancel,
 	}
 
 	// 初始化组件
@@ -155,34 +376,84 @@ func (bs *BaseServer) initComponents() error {
 	// 初始化Actor系统
 	bs.actorSystem = actor.NewActorSystem(fmt.Sprintf("%s-%s", bs.nodeType, bs.nodeID))
 
+	// 依赖按顺序连接，暂时不可用时按startup配置重试
 	// 初始化Redis
-	redisManager, err := database.NewRedisManager(&bs.config.Database.Redis)
-	if err != nil {
+	if _, err := bs.connectDependency(DependencyRedis, func() error {
+		redisManager, err := database.NewRedisManager(&bs.config.Database.Redis)
+		if err != nil {
+			return err
+		}
+		bs.redisManager = redisManager
+		return nil
+	}); err != nil {
 		return fmt.Errorf("failed to init redis: %v", err)
 	}
-	bs.redisManager = redisManager
+	bs.ipBlacklist = security.NewSharedIPBlacklist(bs.redisManager)
 
 	// 初始化MongoDB
-	mongoManager, err := database.NewMongoManager(&bs.config.Database.MongoDB)
-	if err != nil {
+	if _, err := bs.connectDependency(DependencyMongoDB, func() error {
+		mongoManager, err := database.NewMongoManager(&bs.config.Database.MongoDB)
+		if err != nil {
+			return err
+		}
+		bs.mongoManager = mongoManager
+		return nil
+	}); err != nil {
 		return fmt.Errorf("failed to init mongodb: %v", err)
 	}
-	bs.mongoManager = mongoManager
+	mongoManager := bs.mongoManager
+
+	// 初始化敏感字段加密
+	if bs.config.Security.FieldEncryption.Enabled {
+		keyRing, err := security.NewKeyRing(&bs.config.Security.FieldEncryption)
+		if err != nil {
+			return fmt.Errorf("failed to init field encryption: %v", err)
+		}
+		mongoManager.SetFieldEncryptor(keyRing)
+	}
 
-	// 初始化NSQ
-	nsqManager, err := mq.NewNSQManager(&bs.config.NSQ)
+	// 初始化消息队列，连接前的订阅先登记，配置为非关键依赖时可以先启动再在后台连接
+	switch bs.config.MessageQueue.Backend {
+	case "", "nsq", "kafka":
+	default:
+		return fmt.Errorf("unknown message queue backend: %s", bs.config.MessageQueue.Backend)
+	}
+	// 故障注入在订阅时包装消息处理器，系统命令不注入，保证可以通过命令清除规则
+	bs.chaos = chaos.NewInjector(&bs.config.Chaos,
+		mq.NamespacedTopic(bs.config.MessageQueue.Namespace, mq.SystemMessagesTopic.Name))
+	deferredBus := eventbus.NewDeferredBus()
+	deferredBus.Intercept(bs.chaos.WrapHandler)
+	bs.eventBus = deferredBus
+	messageBroker, err := mq.NewMessageBroker(bs.eventBus, bs.nodeID, bs.config.MessageQueue.Namespace)
 	if err != nil {
-		return fmt.Errorf("failed to init nsq: %v", err)
+		return err
+	}
+	bs.messageBroker = messageBroker
+	// 运行时参数，GM通过set_runtime_param命令调整，到期自动恢复
+	bs.tuner = tuning.NewTuner(&bs.config.RuntimeParams)
+	bs.registerRuntimeParams()
+	if _, err := bs.connectDependency(DependencyMessageQueue, func() error {
+		bus, err := bs.newMessageQueue()
+		if err != nil {
+			return err
+		}
+		return deferredBus.Attach(bus)
+	}); err != nil {
+		return fmt.Errorf("failed to init message queue: %v", err)
 	}
-	bs.nsqManager = nsqManager
-	bs.messageBroker = mq.NewMessageBroker(nsqManager, bs.nodeID)
 
 	// 初始化ETCD服务注册
-	registry, err := discovery.NewETCDRegistry(&bs.config.ETCD)
-	if err != nil {
+	if _, err := bs.connectDependency(DependencyETCD, func() error {
+		registry, err := discovery.NewETCDRegistry(&bs.config.ETCD)
+		if err != nil {
+			return err
+		}
+		bs.registry = registry
+		return nil
+	}); err != nil {
 		return fmt.Errorf("failed to init etcd registry: %v", err)
 	}
-	bs.registry = registry
+	registry := bs.registry
 
 	// 初始化服务发现
 	bs.discovery = discovery.NewServiceDiscovery(
@@ -191,15 +462,144 @@ func (bs *BaseServer) initComponents() error {
 		discovery.NewWeightedLoadBalancer(),
 	)
 
+	// 初始化崩溃上报
+	crashReporter, err := crash.NewReporter(&bs.config.CrashReport, bs.nodeType, bs.nodeID)
+	if err != nil {
+		return fmt.Errorf("failed to init crash reporter: %v", err)
+	}
+	bs.crashReporter = crashReporter
+
+	// 初始化profile采集
+	profiler, err := profiling.NewProfiler(&bs.config.Profiling, bs.nodeID)
+	if err != nil {
+		return fmt.Errorf("failed to init profiler: %v", err)
+	}
+	bs.profiler = profiler
+
+	// 初始化定时任务调度，执行锁使用Redis，执行记录写入MongoDB
+	bs.scheduler = scheduler.NewScheduler(&bs.config.Scheduler, bs.nodeType, bs.nodeID,
+		bs.redisManager, database.NewJobRunRepository(mongoManager, bs.config.Scheduler.Retention))
+	bs.scheduler.SetPanicHandler(func(jobName string, value interface{}, stack []byte) {
+		bs.crashReporter.Capture("scheduler", value, stack, map[string]string{"job": jobName})
+	})
+
+	// 初始化VIP权益，玩家等级从用户文档读取
+	bs.entitlements = entitlement.NewService(&bs.config.VIP, database.NewUserRepository(mongoManager))
+
+	// 初始化生命周期奖励触发，奖励邮件按玩家的VIP权益调整
+	bs.triggers = trigger.NewService(&bs.config.Triggers, database.NewTriggerGrantRepository(mongoManager),
+		bs.entitlements.Mails(database.NewMailRepository(mongoManager)), bs.generateMailID)
+	bs.RegisterBackground("triggers", bs.triggers.Run)
+
+	// 初始化昵称和等级副本更新，玩家资料变化时更新房间和游戏记录中的副本
+	bs.denorm = denorm.NewService(&bs.config.Denorm, database.NewDenormRepository(mongoManager),
+		database.NewUserRepository(mongoManager))
+
+	// 初始化集群实时计数，在线人数由网关维护，房间和游戏计数由大厅和游戏节点维护
+	bs.realtime = realtime.NewCounters(&bs.config.Realtime, bs.redisManager)
+
 	// 初始化RPC服务器
 	rpcServer := rpc.NewRPCServer("0.0.0.0", bs.config.Network.RPCPort)
+	rpcServer.SetTransportConfig(bs.rpcTransportConfig(bs.config))
+	bs.requestLogger = rpc.NewRequestLogger(&bs.config.RPC.Logging)
+	bs.adminAuth = rpc.NewAdminAuth(&bs.config.RPC.Admin)
+	bs.callStats = rpc.NewCallStats()
+	bs.lanes = lane.NewLanes(&bs.config.RPC.Lanes)
+	bs.lanes.SetLocker(newUserLocker(bs.redisManager, bs.nodeID))
+	rpcServer.Use(bs.requestLogger.Interceptor())
+	rpcServer.Use(bs.adminAuth.Interceptor())
+	rpcServer.Use(bs.callStats.Interceptor())
+	rpcServer.Use(bs.chaos.Interceptor())
+	rpcServer.Use(rpc.RecoveryInterceptor(func(call *rpc.CallInfo, value interface{}, stack []byte) {
+		bs.crashReporter.Capture("rpc", value, stack, map[string]string{
+			"service": call.Service,
+			"method":  call.Method,
+		})
+	}))
+	rpcServer.Use(bs.laneInterceptor())
 	bs.rpcServer = rpcServer
 
+	// 初始化节点指标导出
+	if err := bs.initNodeMonitoring(); err != nil {
+		return err
+	}
+
+	// 初始化配置热更新，关闭自动应用时配置文件变化只校验和输出差异，由GM分阶段提交
+	configReload, err := hotreload.NewHotReloadManager()
+	if err != nil {
+		return fmt.Errorf("failed to init config reload: %v", err)
+	}
+	configReload.SetAutoApply(bs.config.HotReload.AutoApply)
+	if err := configReload.RegisterConfig(bs.configFile, &serverConfigParser{}); err != nil {
+		configReload.Close()
+		return fmt.Errorf("failed to register config reload: %v", err)
+	}
+	configReload.RegisterCallback(bs.configFile, func(name string, oldData, newData interface{}) error {
+		return bs.reloadConfig()
+	})
+	bs.configReload = configReload
+
+	return nil
+}
+
+// rpcTransportConfig RPC连接的超时和保活设置，空闲回收时间取rpc.idle_timeout(秒)
+func (bs *BaseServer) rpcTransportConfig(config *ServerConfig) *rpc.TransportConfig {
+	transport := config.RPC.Transport
+	transport.IdleTimeout = time.Duration(config.RPC.IdleTimeout) * time.Second
+	return &transport
+}
+
+// newMessageQueue 按配置的后端连接消息中间件
+func (bs *BaseServer) newMessageQueue() (eventbus.Bus, error) {
+	if bs.config.MessageQueue.Backend == "kafka" {
+		return mq.NewKafkaBroker(&bs.config.Kafka)
+	}
+	return mq.NewNSQManager(&bs.config.NSQ)
+}
+
+// reloadConfig 重新读取配置文件，更新支持运行时调整的组件
+func (bs *BaseServer) reloadConfig() error {
+	config, err := loadConfig(bs.configFile)
+	if err != nil {
+		return fmt.Errorf("failed to reload config: %v", err)
+	}
+
+	bs.requestLogger.UpdateConfig(&config.RPC.Logging)
+	bs.adminAuth.UpdateConfig(&config.RPC.Admin)
+	bs.rpcServer.SetTransportConfig(bs.rpcTransportConfig(config))
+	bs.calendar.Update(&config.Activity)
+	bs.triggers.Update(&config.Triggers)
+	bs.lanes.Update(&config.RPC.Lanes)
+
+	bs.mutex.Lock()
+	bs.config.RPC.Logging = config.RPC.Logging
+	bs.config.RPC.Admin = config.RPC.Admin
+	bs.config.RPC.IdleTimeout = config.RPC.IdleTimeout
+	bs.config.RPC.Transport = config.RPC.Transport
+	bs.config.RPC.Lanes = config.RPC.Lanes
+	bs.config.Activity = config.Activity
+	bs.config.Fraud = config.Fraud
+	bs.config.Triggers = config.Triggers
+	bs.mutex.Unlock()
+
+	logger.Info(fmt.Sprintf("Config reloaded for %s", bs.nodeID))
 	return nil
 }
 
-// Start 启动服务器
+// Start 启动服务器，核心组件启动后按顺序执行生命周期钩子，钩子失败时停止节点
 func (bs *BaseServer) Start() error {
+	if err := bs.startCore(); err != nil {
+		return err
+	}
+	if err := bs.runStartHooks(); err != nil {
+		bs.Stop()
+		return err
+	}
+	return nil
+}
+
+// startCore 启动RPC服务，注册节点，启动负载上报、定时任务和预热
+func (bs *BaseServer) startCore() error {
 	bs.mutex.Lock()
 	defer bs.mutex.Unlock()
 
@@ -207,7 +607,7 @@ func (bs *BaseServer) Start() error {
 		return fmt.Errorf("server already started")
 	}
 
-	logger.Info(fmt.Sprintf("Starting server %s/%s", bs.nodeType, bs.nodeID))
+	logger.Info(fmt.Sprintf("Starting server %s/%s, version %s", bs.nodeType, bs.nodeID, version.Get()))
 
 	// 启动RPC服务器
 	if err := bs.rpcServer.Start(); err != nil {
@@ -221,11 +621,50 @@ func (bs *BaseServer) Start() error {
 		Address:    "0.0.0.0",
 		Port:       bs.config.Network.RPCPort,
 		Load:       0,
-		Status:     "online",
+		Status:     discovery.StatusOnline,
 		Metadata:   map[string]string{},
 		UpdateTime: time.Now().Unix(),
 	}
 
+	// 区域标签，用于就近路由
+	if bs.config.Geo.Region != "" {
+		serviceInfo.Metadata[discovery.MetadataRegion] = bs.config.Geo.Region
+	}
+	if bs.nodeType == "gateway" {
+		serviceInfo.Metadata[discovery.MetadataTCPPort] = strconv.Itoa(bs.config.Network.TCPPort)
+	}
+
+	// 只服务部分世界的节点，路由时只把这些世界的玩家分配过来
+	if len(bs.config.World.Serve) > 0 {
+		serviceInfo.Metadata[discovery.MetadataWorlds] = world.FormatIDs(bs.config.World.Serve)
+	}
+
+	// 构建信息，中心服据此检查集群中的版本是否一致
+	build := version.Get()
+	serviceInfo.Metadata[discovery.MetadataVersion] = build.Version
+	serviceInfo.Metadata[discovery.MetadataGitCommit] = build.GitCommit
+	serviceInfo.Metadata[discovery.MetadataBuildTime] = build.BuildTime
+	serviceInfo.Metadata[discovery.MetadataProtoSchema] = strconv.FormatUint(uint64(build.ProtoSchema), 10)
+
+	// 灰度节点只分到按权重计算的一部分新流量
+	if bs.config.Canary.Enabled {
+		weight := bs.config.Canary.Weight
+		if weight <= 0 {
+			weight = defaultCanaryWeight
+		}
+		for key, value := range canaryMetadata(weight) {
+			serviceInfo.Metadata[key] = value
+		}
+		logger.Info(fmt.Sprintf("Server %s registered as canary with weight %d%%", bs.nodeID, weight))
+	}
+
+	// 非关键依赖未就绪或预热未完成时以starting注册，就绪后转为online
+	warmup := bs.beginWarmup()
+	if !bs.readiness.snapshot().Ready {
+		serviceInfo.Status = discovery.StatusStarting
+		logger.Warn(fmt.Sprintf("Server %s registered as %s until all dependencies are ready", bs.nodeID, discovery.StatusStarting))
+	}
+
 	if err := bs.registry.Register(serviceInfo); err != nil {
 		return fmt.Errorf("failed to register service: %v", err)
 	}
@@ -234,6 +673,13 @@ func (bs *BaseServer) Start() error {
 	bs.wg.Add(1)
 	go bs.loadUpdateLoop()
 
+	// 启动定时任务调度
+	bs.wg.Add(1)
+	go func() {
+		defer bs.wg.Done()
+		bs.scheduler.Run(bs.ctx)
+	}()
+
 	// 监听系统信号
 	bs.wg.Add(1)
 	go bs.signalHandler()
@@ -241,11 +687,26 @@ func (bs *BaseServer) Start() error {
 	bs.status = "running"
 	logger.Info(fmt.Sprintf("Server %s/%s started", bs.nodeType, bs.nodeID))
 
+	// 预热在节点运行后进行，完成时需要读取运行状态
+	if warmup != nil {
+		bs.wg.Add(1)
+		go bs.runWarmup(warmup)
+	}
+
 	return nil
 }
 
-// Stop 停止服务器
+// Stop 停止服务器，先按相反顺序执行生命周期钩子，再关闭核心组件
 func (bs *BaseServer) Stop() error {
+	if bs.GetStatus() != "running" {
+		return nil
+	}
+	bs.runStopHooks()
+	return bs.stopCore()
+}
+
+// stopCore 关闭RPC服务、连接池、消息队列和数据库连接
+func (bs *BaseServer) stopCore() error {
 	bs.mutex.Lock()
 	defer bs.mutex.Unlock()
 
@@ -267,12 +728,18 @@ func (bs *BaseServer) Stop() error {
 		bs.rpcServer.Stop()
 	}
 
+	bs.closePeerPools()
+
 	if bs.actorSystem != nil {
 		bs.actorSystem.Shutdown()
 	}
 
-	if bs.nsqManager != nil {
-		bs.nsqManager.Close()
+	if bs.configReload != nil {
+		bs.configReload.Close()
+	}
+
+	if bs.eventBus != nil {
+		bs.eventBus.Close()
 	}
 
 	if bs.registry != nil {
@@ -333,6 +800,9 @@ func (bs *BaseServer) loadUpdateLoop() {
 				logger.Error(fmt.Sprintf("Failed to update load: %v", err))
 			}
 
+			// 上报RPC指标，中心服据此比较灰度节点
+			bs.reportMetrics()
+
 		case <-bs.ctx.Done():
 			return
 		}
@@ -392,11 +862,33 @@ func (bs *BaseServer) GetMongoManager() *database.MongoManager {
 	return bs.mongoManager
 }
 
+// GetEventBus 获取事件总线
+func (bs *BaseServer) GetEventBus() eventbus.Bus {
+	return bs.eventBus
+}
+
+// ConsumerStats 消息订阅的处理统计，消息中间件未连接或不提供统计时返回nil
+func (bs *BaseServer) ConsumerStats() []mq.ConsumerStats {
+	bus := bs.eventBus
+	if deferred, ok := bus.(*eventbus.DeferredBus); ok {
+		bus = deferred.Attached()
+	}
+	if provider, ok := bus.(mq.ConsumerStatsProvider); ok {
+		return provider.ConsumerStats()
+	}
+	return nil
+}
+
 // GetMessageBroker 获取消息代理
 func (bs *BaseServer) GetMessageBroker() *mq.MessageBroker {
 	return bs.messageBroker
 }
 
+// GetScheduler 获取定时任务调度器
+func (bs *BaseServer) GetScheduler() *scheduler.Scheduler {
+	return bs.scheduler
+}
+
 // GetDiscovery 获取服务发现
 func (bs *BaseServer) GetDiscovery() *discovery.ServiceDiscovery {
 	return bs.discovery
@@ -425,6 +917,8 @@ func NewServer(configFile, nodeType, nodeID string) Server {
 		return NewGMServer(configFile, nodeID)
 	case "center":
 		return NewCenterServer(configFile, nodeID)
+	case "probe":
+		return NewProbeServer(configFile, nodeID)
 	default:
 		logger.Fatal(fmt.Sprintf("Unknown node type: %s", nodeType))
 		return nil
@@ -435,7 +929,7 @@ func NewServer(configFile, nodeType, nodeID string) Server {
 func RegisterCommonServices(server *BaseServer) error {
 	// 注册系统服务
 	systemService := NewSystemService(server)
-	if err := server.rpcServer.RegisterService(systemService); err != nil {
+	if err := RegisterSystemService(server.rpcServer, systemService); err != nil {
 		return fmt.Errorf("failed to register system service: %v", err)
 	}
 
@@ -445,10 +939,24 @@ func RegisterCommonServices(server *BaseServer) error {
 	systemHandler.RegisterHandler(mq.SYS_CMD_UPDATE_LOAD, systemService.HandleUpdateLoad)
 	systemHandler.RegisterHandler(mq.SYS_CMD_SHUTDOWN, systemService.HandleShutdown)
 	systemHandler.RegisterHandler(mq.SYS_CMD_HOT_UPDATE, systemService.HandleHotUpdate)
-
-	if err := server.messageBroker.SubscribeSystemMessages(systemHandler); err != nil {
-		return fmt.Errorf("failed to subscribe system messages: %v", err)
-	}
+	systemHandler.RegisterHandler(mq.SYS_CMD_CAPTURE_PROFILE, systemService.HandleCaptureProfile)
+	systemHandler.RegisterHandler(mq.SYS_CMD_DRAIN, systemService.HandleDrain)
+	systemHandler.RegisterHandler(mq.SYS_CMD_CANARY, systemService.HandleCanary)
+	systemHandler.RegisterHandler(mq.SYS_CMD_CONFIG_PREPARE, systemService.HandleConfigPrepare)
+	systemHandler.RegisterHandler(mq.SYS_CMD_CONFIG_COMMIT, systemService.HandleConfigCommit)
+	systemHandler.RegisterHandler(mq.SYS_CMD_CONFIG_ABORT, systemService.HandleConfigAbort)
+	systemHandler.RegisterHandler(mq.SYS_CMD_ACTIVITY, systemService.HandleActivity)
+	systemHandler.RegisterHandler(mq.SYS_CMD_BROADCAST_NOTICE, systemService.HandleBroadcastNotice)
+	systemHandler.RegisterHandler(mq.SYS_CMD_CHAOS, systemService.HandleChaos)
+	systemHandler.RegisterHandler(mq.SYS_CMD_RUNTIME_PARAM, systemService.HandleRuntimeParam)
+	server.systemHandler = systemHandler
+
+	server.RegisterOnStart("system_messages", OrderSubscriptions, func(ctx context.Context) error {
+		return server.messageBroker.SubscribeSystemMessages(systemHandler)
+	})
+	server.RegisterOnStop("system_messages", OrderSubscriptions, func(ctx context.Context) error {
+		return server.messageBroker.UnsubscribeSystemMessages()
+	})
 
 	return nil
 }
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/errcode"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/settings"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// newSettings 创建玩家隐私设置和通知偏好服务
+func (bs *BaseServer) newSettings() *settings.Service {
+	return settings.NewService(&bs.config.Settings, database.NewUserSettingsRepository(bs.mongoManager), bs.redisManager)
+}
+
+// settingsResponse 构造隐私设置响应
+func (ls *LobbyService) settingsResponse(ctx context.Context, header *proto.MessageHeader, s *database.UserSettings) (*proto.BaseResponse, error) {
+	data, err := proto.Marshal(&proto.PrivacySettings{
+		OnlineStatus:   s.OnlineStatus,
+		FriendRequests: s.FriendRequests,
+		Spectate:       s.Spectate,
+		Whisper:        s.Whisper,
+		Searchable:     s.Searchable,
+	})
+	if err != nil {
+		logger.Error(fmt.Sprintf("settingsResponse: failed to marshal response: %v", err))
+		return ls.responses.Error(ctx, header, errcode.Internal), nil
+	}
+	return ls.responses.Success(ctx, header, "success", data), nil
+}
+
+// GetSettings 获取自己的隐私设置
+func (ls *LobbyService) GetSettings(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	userID := req.Header.GetUserId()
+	if userID == 0 {
+		return ls.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
+	}
+
+	s, err := ls.server.settings.Get(userID)
+	if err != nil {
+		logger.Error(fmt.Sprintf("GetSettings: failed to get settings of user %d: %v", userID, err))
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+	return ls.settingsResponse(ctx, req.Header, s)
+}
+
+// UpdateSettings 修改隐私设置，为空的项保持不变
+func (ls *LobbyService) UpdateSettings(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	userID := req.Header.GetUserId()
+	if userID == 0 {
+		return ls.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
+	}
+
+	update, err := Bind[proto.PrivacySettings](req.Data)
+	if err != nil {
+		logger.Error(fmt.Sprintf("UpdateSettings: failed to unmarshal request: %v", err))
+		return ls.responses.Error(ctx, req.Header, errcode.InvalidRequest), nil
+	}
+
+	changes := make(map[string]string)
+	for action, value := range map[string]string{
+		settings.ActionOnlineStatus:   update.GetOnlineStatus(),
+		settings.ActionFriendRequests: update.GetFriendRequests(),
+		settings.ActionSpectate:       update.GetSpectate(),
+		settings.ActionWhisper:        update.GetWhisper(),
+		settings.ActionSearchable:     update.GetSearchable(),
+	} {
+		if value != "" {
+			changes[action] = value
+		}
+	}
+
+	s, err := ls.server.settings.Update(userID, changes)
+	if err != nil {
+		if errors.Is(err, settings.ErrInvalid) {
+			return ls.responses.Error(ctx, req.Header, errcode.InvalidSettings.WithDetail(err.Error())), nil
+		}
+		logger.Error(fmt.Sprintf("UpdateSettings: failed to update settings of user %d: %v", userID, err))
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+
+	logger.Info(fmt.Sprintf("User %d updated privacy settings: %v", userID, changes))
+	return ls.settingsResponse(ctx, req.Header, s)
+}
+
+// notificationPrefsResponse 构造通知偏好响应，包含所有通知频道
+func (ls *LobbyService) notificationPrefsResponse(ctx context.Context, header *proto.MessageHeader, s *database.UserSettings) (*proto.BaseResponse, error) {
+	data, err := proto.Marshal(&proto.NotificationPrefs{Muted: settings.Mutes(s)})
+	if err != nil {
+		logger.Error(fmt.Sprintf("notificationPrefsResponse: failed to marshal response: %v", err))
+		return ls.responses.Error(ctx, header, errcode.Internal), nil
+	}
+	return ls.responses.Success(ctx, header, "success", data), nil
+}
+
+// GetNotificationPrefs 获取通知偏好
+func (ls *LobbyService) GetNotificationPrefs(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	userID := req.Header.GetUserId()
+	if userID == 0 {
+		return ls.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
+	}
+
+	s, err := ls.server.settings.Get(userID)
+	if err != nil {
+		logger.Error(fmt.Sprintf("GetNotificationPrefs: failed to get settings of user %d: %v", userID, err))
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+	return ls.notificationPrefsResponse(ctx, req.Header, s)
+}
+
+// UpdateNotificationPrefs 关闭或打开通知频道，未包含的频道保持不变
+func (ls *LobbyService) UpdateNotificationPrefs(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	userID := req.Header.GetUserId()
+	if userID == 0 {
+		return ls.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
+	}
+
+	update, err := Bind[proto.NotificationPrefs](req.Data)
+	if err != nil {
+		logger.Error(fmt.Sprintf("UpdateNotificationPrefs: failed to unmarshal request: %v", err))
+		return ls.responses.Error(ctx, req.Header, errcode.InvalidRequest), nil
+	}
+
+	s, err := ls.server.settings.UpdateMutes(userID, update.GetMuted())
+	if err != nil {
+		if errors.Is(err, settings.ErrInvalid) {
+			return ls.responses.Error(ctx, req.Header, errcode.InvalidSettings.WithDetail(err.Error())), nil
+		}
+		logger.Error(fmt.Sprintf("UpdateNotificationPrefs: failed to update settings of user %d: %v", userID, err))
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+
+	logger.Info(fmt.Sprintf("User %d updated notification preferences: %v", userID, update.GetMuted()))
+	return ls.notificationPrefsResponse(ctx, req.Header, s)
+}
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// setShadowBan 设置或解除玩家的影子封禁并记录GM操作日志。
+// 影子封禁的玩家聊天消息只有自己可见，匹配时优先与其他影子封禁的玩家组局，玩家本人无感知
+func (gs *GMServer) setShadowBan(gmUserID, userID uint64, banned bool, reason string) (string, error) {
+	if _, err := gs.userRepo.GetByUserID(userID); err != nil {
+		return "", fmt.Errorf("用户不存在: %d", userID)
+	}
+
+	changed, err := gs.userRepo.SetShadowBanned(userID, banned)
+	if err != nil {
+		return "", err
+	}
+	if !changed {
+		if banned {
+			return fmt.Sprintf("用户 %d 已处于影子封禁状态", userID), nil
+		}
+		return fmt.Sprintf("用户 %d 未被影子封禁", userID), nil
+	}
+
+	if banned {
+		gs.gmRepo.LogGMAction(gmUserID, "shadow_ban", userID, fmt.Sprintf("原因: %s", reason))
+		logger.Info(fmt.Sprintf("User %d shadow banned by GM %d: %s", userID, gmUserID, reason))
+		return fmt.Sprintf("用户 %d 已被影子封禁，原因: %s", userID, reason), nil
+	}
+	gs.gmRepo.LogGMAction(gmUserID, "shadow_unban", userID, "")
+	logger.Info(fmt.Sprintf("User %d shadow ban lifted by GM %d", userID, gmUserID))
+	return fmt.Sprintf("用户 %d 已解除影子封禁", userID), nil
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/errcode"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/settings"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// canSpectate 好友所在的房间是否允许该玩家观战，私有房间不允许观战
+func (ls *LobbyServer) canSpectate(viewerID, friendID uint64, room *database.Room) bool {
+	if room.IsPrivate {
+		return false
+	}
+	allowed, err := ls.settings.Allows(friendID, settings.ActionSpectate, viewerID, true)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to check spectate setting of user %d: %v", friendID, err))
+		return false
+	}
+	return allowed
+}
+
+// SpectateFriend 观战好友所在的房间。通过玩家所在房间找到好友，检查权限和观战人数后加入观战，
+// 返回房间信息和运行游戏的节点，客户端直接到该节点获取游戏状态。同一时间只能观战一个房间
+func (ls *LobbyService) SpectateFriend(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	userID := req.Header.GetUserId()
+	if userID == 0 {
+		return ls.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
+	}
+
+	spectateReq, err := Bind[proto.SpectateRequest](req.Data)
+	if err != nil {
+		logger.Error(fmt.Sprintf("SpectateFriend: failed to unmarshal request: %v", err))
+		return ls.responses.Error(ctx, req.Header, errcode.InvalidRequest), nil
+	}
+	friendID := spectateReq.GetFriendId()
+	if friendID == 0 || friendID == userID {
+		return ls.responses.Error(ctx, req.Header, errcode.InvalidRequest), nil
+	}
+
+	maxSpectators := ls.server.config.Spectate.MaxSpectators
+	if maxSpectators <= 0 {
+		return ls.responses.Error(ctx, req.Header, errcode.SpectateDenied), nil
+	}
+
+	isFriend, err := database.NewFriendRepository(ls.server.mongoManager).IsFriend(userID, friendID)
+	if err != nil {
+		logger.Error(fmt.Sprintf("SpectateFriend: %v", err))
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+	if !isFriend {
+		return ls.responses.Error(ctx, req.Header, errcode.NotFriends), nil
+	}
+
+	room, err := ls.server.roomRepo.FindRoomByPlayer(friendID)
+	if err != nil {
+		logger.Error(fmt.Sprintf("SpectateFriend: %v", err))
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+	if room == nil {
+		return ls.responses.Error(ctx, req.Header, errcode.FriendNotInRoom), nil
+	}
+	for _, player := range room.Players {
+		if player.UserID == userID {
+			return ls.responses.Error(ctx, req.Header, errcode.AlreadyInRoom), nil
+		}
+	}
+	if !ls.server.canSpectate(userID, friendID, room) {
+		logger.Debug(fmt.Sprintf("SpectateFriend: user %d cannot spectate room %d", userID, room.RoomID))
+		return ls.responses.Error(ctx, req.Header, errcode.SpectateDenied), nil
+	}
+
+	user, err := database.NewUserRepository(ls.server.mongoManager).GetByUserID(userID)
+	if err != nil {
+		logger.Error(fmt.Sprintf("SpectateFriend: failed to get user %d: %v", userID, err))
+		return ls.responses.Error(ctx, req.Header, errcode.UserNotFound), nil
+	}
+
+	// 先离开正在观战的房间
+	if _, err := ls.server.roomRepo.RemoveSpectator(userID); err != nil {
+		logger.Error(fmt.Sprintf("SpectateFriend: %v", err))
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+	added, err := ls.server.roomRepo.AddSpectator(room.RoomID, database.RoomPlayer{
+		UserID:   userID,
+		Nickname: user.Nickname,
+		Level:    user.Level,
+		JoinTime: time.Now().Unix(),
+	}, maxSpectators)
+	if err != nil {
+		logger.Error(fmt.Sprintf("SpectateFriend: %v", err))
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+	if !added {
+		return ls.responses.Error(ctx, req.Header, errcode.SpectatorsFull), nil
+	}
+
+	// 重新获取房间，游戏可能在观战前刚刚开始
+	if updated, err := ls.server.roomRepo.GetRoomByID(room.RoomID); err == nil {
+		room = updated
+	}
+
+	var players []*proto.GamePlayerInfo
+	for _, p := range room.Players {
+		players = append(players, &proto.GamePlayerInfo{
+			UserId:   p.UserID,
+			Nickname: p.Nickname,
+			Level:    p.Level,
+			Status:   p.Status,
+			PartyId:  p.PartyID,
+		})
+	}
+	resp := &proto.SpectateResponse{
+		Room: &proto.RoomInfo{
+			RoomId:         room.RoomID,
+			RoomName:       room.RoomName,
+			GameType:       room.GameType,
+			CurrentPlayers: room.CurrentPlayers,
+			MaxPlayers:     room.MaxPlayers,
+			Status:         room.Status,
+			IsPrivate:      room.IsPrivate,
+			Players:        players,
+			CreatedTime:    uint32(room.CreatedAt.Unix()),
+			Region:         room.Region,
+		},
+		GameId: room.GameID,
+		Node:   room.Node,
+	}
+
+	data, err := proto.Marshal(resp)
+	if err != nil {
+		logger.Error(fmt.Sprintf("SpectateFriend: failed to marshal response: %v", err))
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+
+	logger.Info(fmt.Sprintf("User %d is spectating room %d of friend %d", userID, room.RoomID, friendID))
+	return ls.responses.Success(ctx, req.Header, "success", data), nil
+}
+
+// StopSpectating 停止观战
+func (ls *LobbyService) StopSpectating(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	userID := req.Header.GetUserId()
+	if userID == 0 {
+		return ls.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
+	}
+
+	if _, err := ls.server.roomRepo.RemoveSpectator(userID); err != nil {
+		logger.Error(fmt.Sprintf("StopSpectating: %v", err))
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+	return ls.responses.Success(ctx, req.Header, "success", nil), nil
+}
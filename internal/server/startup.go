@@ -0,0 +1,253 @@
+package server
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/discovery"
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// 启动依赖，initComponents按此顺序连接
+const (
+	DependencyRedis        = "redis"
+	DependencyMongoDB      = "mongodb"
+	DependencyMessageQueue = "message_queue"
+	DependencyETCD         = "etcd"
+)
+
+// deferrableDependencies 支持延迟连接的依赖，其余依赖被大量组件直接使用，即使配置为optional也必须在启动时连接成功
+var deferrableDependencies = map[string]bool{
+	DependencyMessageQueue: true,
+}
+
+// 默认重试设置
+const (
+	defaultStartupAttempts       = 5
+	defaultStartupInitialBackoff = time.Second
+	defaultStartupMaxBackoff     = 30 * time.Second
+)
+
+// RetryConfig 启动依赖的重试设置
+type RetryConfig struct {
+	MaxAttempts    int           `yaml:"max_attempts"`    // 启动时最多尝试次数
+	InitialBackoff time.Duration `yaml:"initial_backoff"` // 首次重试间隔，之后每次翻倍
+	MaxBackoff     time.Duration `yaml:"max_backoff"`     // 重试间隔上限
+}
+
+// DependencyConfig 单个依赖的启动设置，未配置的重试参数使用startup.retry
+type DependencyConfig struct {
+	RetryConfig `yaml:",inline"`
+	Optional    bool `yaml:"optional"` // 非关键依赖，重试用尽后节点先启动，后台继续连接
+}
+
+// StartupConfig 启动设置
+type StartupConfig struct {
+	Retry        RetryConfig                 `yaml:"retry"`        // 所有依赖的默认重试设置
+	Dependencies map[string]DependencyConfig `yaml:"dependencies"` // 按依赖覆盖，键为redis、mongodb、message_queue、etcd
+}
+
+// dependency 合并默认值后的依赖设置
+func (c *StartupConfig) dependency(name string) DependencyConfig {
+	result := DependencyConfig{RetryConfig: c.Retry}
+	if override, exists := c.Dependencies[name]; exists {
+		if override.MaxAttempts > 0 {
+			result.MaxAttempts = override.MaxAttempts
+		}
+		if override.InitialBackoff > 0 {
+			result.InitialBackoff = override.InitialBackoff
+		}
+		if override.MaxBackoff > 0 {
+			result.MaxBackoff = override.MaxBackoff
+		}
+		result.Optional = override.Optional
+	}
+
+	if result.MaxAttempts <= 0 {
+		result.MaxAttempts = defaultStartupAttempts
+	}
+	if result.InitialBackoff <= 0 {
+		result.InitialBackoff = defaultStartupInitialBackoff
+	}
+	if result.MaxBackoff <= 0 {
+		result.MaxBackoff = defaultStartupMaxBackoff
+	}
+	if result.MaxBackoff < result.InitialBackoff {
+		result.MaxBackoff = result.InitialBackoff
+	}
+	if result.Optional && !deferrableDependencies[name] {
+		logger.Warn(fmt.Sprintf("Dependency %s cannot start deferred, treating it as required", name))
+		result.Optional = false
+	}
+	return result
+}
+
+// DependencyStatus 启动依赖的连接状态
+type DependencyStatus struct {
+	Name      string `json:"name"`
+	Ready     bool   `json:"ready"`
+	Optional  bool   `json:"optional"`
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"last_error,omitempty"`
+	ReadyAt   int64  `json:"ready_at,omitempty"`
+}
+
+// Readiness 节点就绪状态，所有依赖连接成功后才算就绪
+type Readiness struct {
+	Ready        bool                `json:"ready"`
+	Dependencies []*DependencyStatus `json:"dependencies"`
+}
+
+// readinessTracker 记录各依赖的连接状态
+type readinessTracker struct {
+	dependencies []*DependencyStatus
+	mutex        sync.RWMutex
+}
+
+// track 开始记录依赖
+func (rt *readinessTracker) track(name string, optional bool) *DependencyStatus {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+
+	status := &DependencyStatus{Name: name, Optional: optional}
+	rt.dependencies = append(rt.dependencies, status)
+	return status
+}
+
+// record 记录一次连接结果
+func (rt *readinessTracker) record(status *DependencyStatus, err error) {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+
+	status.Attempts++
+	if err != nil {
+		status.LastError = err.Error()
+		return
+	}
+	status.Ready = true
+	status.LastError = ""
+	status.ReadyAt = time.Now().Unix()
+}
+
+// snapshot 获取当前就绪状态
+func (rt *readinessTracker) snapshot() *Readiness {
+	rt.mutex.RLock()
+	defer rt.mutex.RUnlock()
+
+	readiness := &Readiness{Ready: true, Dependencies: make([]*DependencyStatus, 0, len(rt.dependencies))}
+	for _, status := range rt.dependencies {
+		copied := *status
+		readiness.Dependencies = append(readiness.Dependencies, &copied)
+		if !status.Ready {
+			readiness.Ready = false
+		}
+	}
+	return readiness
+}
+
+// GetReadiness 获取节点就绪状态
+func (bs *BaseServer) GetReadiness() *Readiness {
+	return bs.readiness.snapshot()
+}
+
+// connectDependency 按重试设置连接依赖
+//
+// 重试用尽后，必需的依赖返回错误，非关键依赖返回false并在后台继续连接，连接成功后节点转为就绪
+func (bs *BaseServer) connectDependency(name string, connect func() error) (bool, error) {
+	config := bs.config.Startup.dependency(name)
+	status := bs.readiness.track(name, config.Optional)
+
+	backoff := config.InitialBackoff
+	var lastErr error
+	for attempt := 1; attempt <= config.MaxAttempts; attempt++ {
+		lastErr = connect()
+		bs.readiness.record(status, lastErr)
+		if lastErr == nil {
+			logger.Info(fmt.Sprintf("Dependency %s ready (attempt %d)", name, attempt))
+			return true, nil
+		}
+		if attempt == config.MaxAttempts {
+			break
+		}
+
+		logger.Warn(fmt.Sprintf("Dependency %s unavailable (attempt %d/%d): %v, retrying in %v",
+			name, attempt, config.MaxAttempts, lastErr, backoff))
+		select {
+		case <-time.After(backoff):
+		case <-bs.ctx.Done():
+			return false, fmt.Errorf("%s: %v", name, bs.ctx.Err())
+		}
+		backoff = nextBackoff(backoff, config.MaxBackoff)
+	}
+
+	if !config.Optional {
+		return false, fmt.Errorf("%s unavailable after %d attempts: %v", name, config.MaxAttempts, lastErr)
+	}
+
+	logger.Warn(fmt.Sprintf("Dependency %s unavailable after %d attempts, starting without it and retrying in background: %v",
+		name, config.MaxAttempts, lastErr))
+
+	bs.wg.Add(1)
+	go bs.retryDependency(name, status, connect, nextBackoff(backoff, config.MaxBackoff), config.MaxBackoff)
+	return false, nil
+}
+
+// retryDependency 后台持续连接非关键依赖，直到成功或节点停止
+func (bs *BaseServer) retryDependency(name string, status *DependencyStatus, connect func() error, backoff, maxBackoff time.Duration) {
+	defer bs.wg.Done()
+
+	for {
+		select {
+		case <-time.After(backoff):
+		case <-bs.ctx.Done():
+			return
+		}
+
+		err := connect()
+		bs.readiness.record(status, err)
+		if err == nil {
+			logger.Info(fmt.Sprintf("Dependency %s ready after background retry", name))
+			bs.onDependencyReady()
+			return
+		}
+
+		logger.Warn(fmt.Sprintf("Dependency %s still unavailable: %v, retrying in %v", name, err, backoff))
+		backoff = nextBackoff(backoff, maxBackoff)
+	}
+}
+
+// onDependencyReady 后台依赖连接成功，所有依赖就绪后把注册状态从starting改为online
+//
+// 节点尚未启动时由Start按就绪状态注册，排空等其他状态不受影响
+func (bs *BaseServer) onDependencyReady() {
+	if !bs.readiness.snapshot().Ready {
+		return
+	}
+
+	bs.mutex.RLock()
+	running := bs.status == "running"
+	bs.mutex.RUnlock()
+	if !running {
+		return
+	}
+
+	service, err := bs.registry.GetService(bs.nodeID)
+	if err != nil || service.Status != discovery.StatusStarting {
+		return
+	}
+	if err := bs.registry.UpdateStatus(bs.nodeID, discovery.StatusOnline); err != nil {
+		logger.Error(fmt.Sprintf("Failed to mark %s online: %v", bs.nodeID, err))
+		return
+	}
+	logger.Info(fmt.Sprintf("All dependencies ready, %s is now online", bs.nodeID))
+}
+
+// nextBackoff 重试间隔翻倍，不超过上限
+func nextBackoff(backoff, maxBackoff time.Duration) time.Duration {
+	backoff *= 2
+	if backoff > maxBackoff {
+		return maxBackoff
+	}
+	return backoff
+}
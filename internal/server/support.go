@@ -0,0 +1,244 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/errcode"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/quota"
+	"github.com/phuhao00/lufy/internal/support"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// newSupport 创建客服工单服务，notifier为空时不通知玩家收到回复
+func (bs *BaseServer) newSupport(notifier *Notifier) *support.Service {
+	var notify support.NotifyFunc
+	if notifier != nil {
+		notify = func(userID, mailID uint64, title string) {
+			if err := notifier.Notify(&database.Notification{
+				UserID:   userID,
+				Type:     NotifyMail,
+				DedupKey: fmt.Sprintf("%s:%d", NotifyMail, mailID),
+				Data:     map[string]interface{}{"mail_id": strconv.FormatUint(mailID, 10), "title": title},
+			}); err != nil {
+				logger.Warn(fmt.Sprintf("Failed to notify user %d of ticket reply %d: %v", userID, mailID, err))
+			}
+		}
+	}
+
+	return support.NewService(&bs.config.Support, database.NewSupportTicketRepository(bs.mongoManager),
+		database.NewMailRepository(bs.mongoManager), bs.generateMailID, notify)
+}
+
+// ticketError 把GM处理工单的错误转换为错误码
+func ticketError(err error) error {
+	switch {
+	case errors.Is(err, support.ErrNotFound):
+		return errcode.TicketNotFound
+	case errors.Is(err, support.ErrState):
+		return errcode.TicketStateConflict
+	case errors.Is(err, support.ErrInvalid):
+		return errcode.TicketRequestInvalid.WithDetail(err.Error())
+	}
+	return errcode.Internal.Wrap(err)
+}
+
+// ticketInfo 转换为客户端使用的工单信息，不包含处理工单的GM
+func ticketInfo(ticket *database.SupportTicket) *proto.SupportTicketInfo {
+	info := &proto.SupportTicketInfo{
+		Id:          ticket.ID.Hex(),
+		Category:    ticket.Category,
+		Subject:     ticket.Subject,
+		Text:        ticket.Text,
+		Attachments: ticket.Attachments,
+		Status:      ticket.Status,
+		CreatedAt:   ticket.CreatedAt.Unix(),
+		UpdatedAt:   ticket.UpdatedAt.Unix(),
+	}
+	for _, reply := range ticket.Replies {
+		info.Replies = append(info.Replies, &proto.TicketReplyInfo{
+			Content:   reply.Content,
+			CreatedAt: reply.CreatedAt.Unix(),
+		})
+	}
+	return info
+}
+
+// SubmitTicket 提交客服工单，每个玩家同时未解决的工单数有上限
+func (ls *LobbyService) SubmitTicket(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	userID := req.Header.GetUserId()
+	if userID == 0 {
+		return ls.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
+	}
+
+	ticketReq, err := Bind[proto.SupportTicketRequest](req.Data)
+	if err != nil {
+		logger.Error(fmt.Sprintf("SubmitTicket: failed to unmarshal request: %v", err))
+		return ls.responses.Error(ctx, req.Header, errcode.InvalidRequest), nil
+	}
+
+	ticket := &database.SupportTicket{
+		UserID:      userID,
+		Category:    ticketReq.GetCategory(),
+		Subject:     ticketReq.GetSubject(),
+		Text:        ticketReq.GetText(),
+		Attachments: ticketReq.GetAttachments(),
+		Language:    req.Header.GetLanguage(),
+	}
+	if resp := consumeQuota(ctx, ls.server.quotas, ls.responses, req.Header, quota.OpReport); resp != nil {
+		return resp, nil
+	}
+	if err := ls.server.support.Submit(ticket); err != nil {
+		ls.server.quotas.Refund(userID, quota.OpReport)
+		switch {
+		case errors.Is(err, support.ErrInvalid):
+			return ls.responses.Error(ctx, req.Header, errcode.TicketInvalid.WithDetail(err.Error())), nil
+		case errors.Is(err, support.ErrTooMany):
+			return ls.responses.Error(ctx, req.Header, errcode.TicketLimitReached), nil
+		}
+		logger.Error(fmt.Sprintf("SubmitTicket: user %d failed: %v", userID, err))
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+
+	logger.Info(fmt.Sprintf("User %d submitted %s ticket %s", userID, ticket.Category, ticket.ID.Hex()))
+	data, err := proto.Marshal(ticketInfo(ticket))
+	if err != nil {
+		logger.Error(fmt.Sprintf("SubmitTicket: failed to marshal response: %v", err))
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+	return ls.responses.Success(ctx, req.Header, "success", data), nil
+}
+
+// GetTickets 获取玩家最近提交的工单和客服回复，同时返回可选的工单分类
+func (ls *LobbyService) GetTickets(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	userID := req.Header.GetUserId()
+	if userID == 0 {
+		return ls.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
+	}
+
+	tickets, err := ls.server.support.List(database.TicketFilter{UserID: userID}, 20)
+	if err != nil {
+		logger.Error(fmt.Sprintf("GetTickets: failed to list tickets of user %d: %v", userID, err))
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+
+	resp := &proto.SupportTicketsResponse{Categories: ls.server.support.Categories()}
+	for _, ticket := range tickets {
+		resp.Tickets = append(resp.Tickets, ticketInfo(ticket))
+	}
+
+	data, err := proto.Marshal(resp)
+	if err != nil {
+		logger.Error(fmt.Sprintf("GetTickets: failed to marshal response: %v", err))
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+	return ls.responses.Success(ctx, req.Header, "success", data), nil
+}
+
+// ticketsJSON 工单详情，未指定工单时为按条件获取的最近工单
+func (gs *GMServer) ticketsJSON(ticketID string, filter database.TicketFilter) ([]byte, error) {
+	if ticketID != "" {
+		ticket, err := gs.support.Get(ticketID)
+		if err != nil {
+			return nil, ticketError(err)
+		}
+		return json.Marshal(ticket)
+	}
+
+	tickets, err := gs.support.List(filter, 50)
+	if err != nil {
+		return nil, ticketError(err)
+	}
+	return json.Marshal(tickets)
+}
+
+// updateTicket 分配、解决或重新打开工单并写入GM操作日志，分配时未指定GM则分配给自己
+func (gs *GMServer) updateTicket(gmUserID uint64, ticketID, action string, assignee uint64) error {
+	var err error
+	switch strings.ToLower(action) {
+	case "assign":
+		if assignee == 0 {
+			assignee = gmUserID
+		}
+		err = gs.support.Assign(ticketID, assignee)
+	case "resolve":
+		err = gs.support.Resolve(ticketID)
+	case "reopen":
+		err = gs.support.Reopen(ticketID)
+	default:
+		return errcode.InvalidRequest.WithDetail(fmt.Sprintf("unknown action: %s", action))
+	}
+	if err != nil {
+		return ticketError(err)
+	}
+
+	details := fmt.Sprintf("工单: %s, 操作: %s", ticketID, action)
+	if strings.ToLower(action) == "assign" {
+		details = fmt.Sprintf("%s, 负责人: %d", details, assignee)
+	}
+	gs.gmRepo.LogGMAction(gmUserID, "update_ticket", 0, details)
+	return nil
+}
+
+// respondTicket 回复工单并写入GM操作日志
+func (gs *GMServer) respondTicket(gmUserID uint64, ticketID, content string, resolve bool) (*database.TicketReply, error) {
+	reply, err := gs.support.Respond(ticketID, gmUserID, content, resolve)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Failed to respond to ticket %s: %v", ticketID, err))
+		return nil, ticketError(err)
+	}
+	gs.gmRepo.LogGMAction(gmUserID, "respond_ticket", 0,
+		fmt.Sprintf("工单: %s, 邮件: %d, 解决: %v", ticketID, reply.MailID, resolve))
+	return reply, nil
+}
+
+// ListTickets 获取工单详情，未指定工单时按状态、玩家和负责人获取最近的工单
+func (gs *GMService) ListTickets(ctx context.Context, req *proto.SupportTicketQuery) (*proto.CommonResponse, error) {
+	if ctx.Value("user_id") == nil {
+		return gs.responses.CommonError(ctx, errcode.NotLoggedIn), nil
+	}
+
+	data, err := gs.server.ticketsJSON(req.GetTicketId(), database.TicketFilter{
+		UserID:     req.GetUserId(),
+		Status:     req.GetStatus(),
+		AssignedTo: req.GetAssignedTo(),
+	})
+	if err != nil {
+		return gs.responses.CommonError(ctx, err), nil
+	}
+	return gs.responses.CommonSuccess(ctx, "success", data), nil
+}
+
+// UpdateTicket 分配、解决或重新打开工单
+func (gs *GMService) UpdateTicket(ctx context.Context, req *proto.SupportTicketAction) (*proto.CommonResponse, error) {
+	gmUserID := ctx.Value("user_id")
+	if gmUserID == nil {
+		return gs.responses.CommonError(ctx, errcode.NotLoggedIn), nil
+	}
+
+	if err := gs.server.updateTicket(gmUserID.(uint64), req.GetTicketId(), req.GetAction(), req.GetAssignee()); err != nil {
+		return gs.responses.CommonError(ctx, err), nil
+	}
+	return gs.responses.CommonSuccess(ctx, "工单已更新", nil), nil
+}
+
+// RespondTicket 回复工单，回复作为邮件发给玩家，resolve时同时标记为已解决
+func (gs *GMService) RespondTicket(ctx context.Context, req *proto.SupportTicketReply) (*proto.CommonResponse, error) {
+	gmUserID := ctx.Value("user_id")
+	if gmUserID == nil {
+		return gs.responses.CommonError(ctx, errcode.NotLoggedIn), nil
+	}
+
+	reply, err := gs.server.respondTicket(gmUserID.(uint64), req.GetTicketId(), req.GetContent(), req.GetResolve())
+	if err != nil {
+		return gs.responses.CommonError(ctx, err), nil
+	}
+	return gs.responses.CommonSuccess(ctx, "回复已发送",
+		[]byte(fmt.Sprintf("{\"ticket_id\":\"%s\",\"mail_id\":\"%d\"}", req.GetTicketId(), reply.MailID))), nil
+}
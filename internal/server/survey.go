@@ -0,0 +1,213 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/errcode"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/survey"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// newSurveys 创建问卷服务
+func (bs *BaseServer) newSurveys(segments survey.Segments) *survey.Service {
+	return survey.NewService(&bs.config.Survey, database.NewSurveyRepository(bs.mongoManager), segments)
+}
+
+// surveyError 把问卷的错误转换为错误码
+func surveyError(err error) error {
+	switch {
+	case errors.Is(err, survey.ErrNotFound):
+		return errcode.SurveyNotFound
+	case errors.Is(err, survey.ErrClosed):
+		return errcode.SurveyClosed
+	case errors.Is(err, survey.ErrNotEligible):
+		return errcode.SurveyNotEligible
+	case errors.Is(err, survey.ErrAnswered):
+		return errcode.SurveyAnswered
+	case errors.Is(err, survey.ErrInvalid):
+		return errcode.SurveyAnswerInvalid.WithDetail(err.Error())
+	}
+	return errcode.Internal.Wrap(err)
+}
+
+// GetSurveys 获取玩家可以回答的问卷，已经回答过的问卷不再返回
+func (ls *LobbyService) GetSurveys(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	userID := req.Header.GetUserId()
+	if userID == 0 {
+		return ls.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
+	}
+
+	now := time.Now()
+	surveys, err := ls.server.surveys.Available(userID, now)
+	if err != nil {
+		logger.Error(fmt.Sprintf("GetSurveys: failed to load surveys of user %d: %v", userID, err))
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+
+	resp := &proto.SurveysResponse{ServerTime: now.Unix()}
+	for _, s := range surveys {
+		info := &proto.SurveyInfo{
+			Id:      s.ID.Hex(),
+			Title:   s.Title,
+			EndTime: s.EndAt.Unix(),
+		}
+		for _, question := range s.Questions {
+			info.Questions = append(info.Questions, &proto.SurveyQuestion{
+				Id:       question.ID,
+				Type:     question.Type,
+				Text:     question.Text,
+				Options:  question.Options,
+				Required: question.Required,
+			})
+		}
+		resp.Surveys = append(resp.Surveys, info)
+	}
+
+	data, err := proto.Marshal(resp)
+	if err != nil {
+		logger.Error(fmt.Sprintf("GetSurveys: failed to marshal response: %v", err))
+		return ls.responses.Error(ctx, req.Header, errcode.Internal), nil
+	}
+	return ls.responses.Success(ctx, req.Header, "success", data), nil
+}
+
+// SubmitSurvey 提交问卷回答，每个玩家对同一问卷只能提交一次
+func (ls *LobbyService) SubmitSurvey(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
+	userID := req.Header.GetUserId()
+	if userID == 0 {
+		return ls.responses.Error(ctx, req.Header, errcode.NotLoggedIn), nil
+	}
+
+	submitReq, err := Bind[proto.SubmitSurveyRequest](req.Data)
+	if err != nil {
+		logger.Error(fmt.Sprintf("SubmitSurvey: failed to unmarshal request: %v", err))
+		return ls.responses.Error(ctx, req.Header, errcode.InvalidRequest), nil
+	}
+
+	answers := make(map[string][]string, len(submitReq.GetAnswers()))
+	for _, answer := range submitReq.GetAnswers() {
+		answers[answer.GetQuestionId()] = append(answers[answer.GetQuestionId()], answer.GetValues()...)
+	}
+
+	if err := ls.server.surveys.Submit(userID, submitReq.GetSurveyId(), answers, time.Now()); err != nil {
+		codeErr := surveyError(err)
+		if errcode.From(codeErr) == errcode.Internal {
+			logger.Error(fmt.Sprintf("SubmitSurvey: user %d survey %s failed: %v", userID, submitReq.GetSurveyId(), err))
+		}
+		return ls.responses.Error(ctx, req.Header, codeErr), nil
+	}
+
+	logger.Info(fmt.Sprintf("User %d submitted survey %s", userID, submitReq.GetSurveyId()))
+	return ls.responses.Success(ctx, req.Header, "success", nil), nil
+}
+
+// surveysJSON 问卷和回答数，未指定问卷时为最近创建的问卷列表
+func (gs *GMServer) surveysJSON(surveyID string) ([]byte, error) {
+	if surveyID == "" {
+		summaries, err := gs.surveys.List(20)
+		if err != nil {
+			return nil, errcode.Internal.Wrap(err)
+		}
+		return json.Marshal(summaries)
+	}
+
+	summary, err := gs.surveys.Get(surveyID)
+	if err != nil {
+		if errors.Is(err, survey.ErrNotFound) {
+			return nil, errcode.SurveyNotFound
+		}
+		return nil, errcode.SurveyInvalid.WithDetail(err.Error())
+	}
+	return json.Marshal(summary)
+}
+
+// exportSurvey 导出问卷回答并写入GM操作日志
+func (gs *GMServer) exportSurvey(gmUserID uint64, surveyID string) (string, int, error) {
+	path, count, err := gs.surveys.Export(surveyID)
+	if err != nil {
+		if errors.Is(err, survey.ErrNotFound) {
+			return "", 0, errcode.SurveyNotFound
+		}
+		logger.Error(fmt.Sprintf("Failed to export survey %s: %v", surveyID, err))
+		return "", 0, errcode.SurveyExportFailed
+	}
+	gs.gmRepo.LogGMAction(gmUserID, "export_survey", 0, fmt.Sprintf("问卷: %s, 回答数: %d, 文件: %s", surveyID, count, path))
+	return path, count, nil
+}
+
+// CreateSurvey 创建问卷，开始时间和结束时间为Unix秒，未指定分群时面向所有玩家
+func (gs *GMService) CreateSurvey(ctx context.Context, req *proto.SurveyRequest) (*proto.CommonResponse, error) {
+	gmUserID := ctx.Value("user_id")
+	if gmUserID == nil {
+		return gs.responses.CommonError(ctx, errcode.NotLoggedIn), nil
+	}
+
+	s := &database.Survey{
+		Title:     req.GetTitle(),
+		Segment:   req.GetSegment(),
+		CreatedBy: gmUserID.(uint64),
+	}
+	for _, question := range req.GetQuestions() {
+		s.Questions = append(s.Questions, database.SurveyQuestion{
+			ID:       question.GetId(),
+			Type:     question.GetType(),
+			Text:     question.GetText(),
+			Options:  question.GetOptions(),
+			Required: question.GetRequired(),
+		})
+	}
+	if req.GetStartTime() > 0 {
+		s.StartAt = time.Unix(req.GetStartTime(), 0)
+	}
+	if req.GetEndTime() > 0 {
+		s.EndAt = time.Unix(req.GetEndTime(), 0)
+	}
+
+	if err := gs.server.surveys.Create(s); err != nil {
+		return gs.responses.CommonError(ctx, errcode.SurveyInvalid.WithDetail(err.Error())), nil
+	}
+
+	gs.server.gmRepo.LogGMAction(s.CreatedBy, "create_survey", 0,
+		fmt.Sprintf("问卷: %s, 分群: %s, 题目数: %d, 标题: %s", s.ID.Hex(), s.Segment, len(s.Questions), s.Title))
+	return gs.responses.CommonSuccess(ctx, "问卷创建成功",
+		[]byte(fmt.Sprintf("{\"survey_id\":\"%s\"}", s.ID.Hex()))), nil
+}
+
+// ListSurveys 获取问卷和回答数，未指定问卷时获取最近创建的问卷
+func (gs *GMService) ListSurveys(ctx context.Context, req *proto.SurveyQuery) (*proto.CommonResponse, error) {
+	if ctx.Value("user_id") == nil {
+		return gs.responses.CommonError(ctx, errcode.NotLoggedIn), nil
+	}
+
+	data, err := gs.server.surveysJSON(req.GetSurveyId())
+	if err != nil {
+		return gs.responses.CommonError(ctx, err), nil
+	}
+	return gs.responses.CommonSuccess(ctx, "success", data), nil
+}
+
+// ExportSurvey 把问卷回答导出为CSV文件，返回文件路径
+func (gs *GMService) ExportSurvey(ctx context.Context, req *proto.SurveyQuery) (*proto.CommonResponse, error) {
+	gmUserID := ctx.Value("user_id")
+	if gmUserID == nil {
+		return gs.responses.CommonError(ctx, errcode.NotLoggedIn), nil
+	}
+
+	path, count, err := gs.server.exportSurvey(gmUserID.(uint64), req.GetSurveyId())
+	if err != nil {
+		return gs.responses.CommonError(ctx, err), nil
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"survey_id":   req.GetSurveyId(),
+		"export_path": path,
+		"responses":   count,
+	})
+	return gs.responses.CommonSuccess(ctx, "问卷导出成功", data), nil
+}
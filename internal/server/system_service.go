@@ -3,13 +3,16 @@ package server
 import (
 	"context"
 	"fmt"
-	"reflect"
 	"runtime"
 	"time"
 
+	"github.com/phuhao00/lufy/internal/chaos"
+	"github.com/phuhao00/lufy/internal/discovery"
+	"github.com/phuhao00/lufy/internal/errcode"
 	"github.com/phuhao00/lufy/internal/logger"
 	"github.com/phuhao00/lufy/internal/mq"
 	"github.com/phuhao00/lufy/internal/pool"
+	"github.com/phuhao00/lufy/internal/version"
 	"github.com/phuhao00/lufy/pkg/proto"
 )
 
@@ -25,26 +28,6 @@ func NewSystemService(server *BaseServer) *SystemService {
 	}
 }
 
-// GetName 获取服务名称
-func (ss *SystemService) GetName() string {
-	return "SystemService"
-}
-
-// RegisterMethods 注册方法
-func (ss *SystemService) RegisterMethods() map[string]reflect.Value {
-	methods := make(map[string]reflect.Value)
-
-	methods["GetServerInfo"] = reflect.ValueOf(ss.GetServerInfo)
-	methods["GetServerStats"] = reflect.ValueOf(ss.GetServerStats)
-	methods["ReloadConfig"] = reflect.ValueOf(ss.ReloadConfig)
-	methods["UpdateLoad"] = reflect.ValueOf(ss.UpdateLoad)
-	methods["Shutdown"] = reflect.ValueOf(ss.Shutdown)
-	methods["GetActorStats"] = reflect.ValueOf(ss.GetActorStats)
-	methods["GetPoolStats"] = reflect.ValueOf(ss.GetPoolStats)
-
-	return methods
-}
-
 // GetServerInfo 获取服务器信息
 func (ss *SystemService) GetServerInfo(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
 	info := &proto.NodeInfo{
@@ -52,7 +35,7 @@ func (ss *SystemService) GetServerInfo(ctx context.Context, req *proto.BaseReque
 		NodeType:   ss.server.nodeType,
 		Address:    "0.0.0.0",
 		Port:       int32(ss.server.config.Network.RPCPort),
-		Online:     ss.server.status == "running",
+		Online:     ss.server.status == "running" && ss.server.GetReadiness().Ready,
 		Load:       int32(ss.server.calculateLoad()),
 		UpdateTime: uint32(time.Now().Unix()),
 	}
@@ -83,6 +66,8 @@ type ServerStats struct {
 	ActorCount     int                 `json:"actor_count"`
 	RPCConnections int64               `json:"rpc_connections"`
 	PoolStats      map[string]PoolStat `json:"pool_stats"`
+	Readiness      *Readiness          `json:"readiness"`
+	Build          version.Info        `json:"build"`
 }
 
 // MemoryStats 内存统计
@@ -123,6 +108,8 @@ func (ss *SystemService) GetServerStats(ctx context.Context, req *proto.BaseRequ
 			NumGC:      memStats.NumGC,
 		},
 		PoolStats: poolStats,
+		Readiness: ss.server.GetReadiness(),
+		Build:     version.Get(),
 	}
 
 	if ss.server.tcpServer != nil {
@@ -158,8 +145,10 @@ func (ss *SystemService) GetServerStats(ctx context.Context, req *proto.BaseRequ
 func (ss *SystemService) ReloadConfig(ctx context.Context, req *proto.BaseRequest) (*proto.BaseResponse, error) {
 	logger.Info(fmt.Sprintf("Reloading config for %s", ss.server.nodeID))
 
-	// 这里可以实现配置重新加载逻辑
-	// 目前只是记录日志
+	if _, err := ss.server.configReload.Reload(ss.server.configFile); err != nil {
+		logger.Error(fmt.Sprintf("Failed to reload config: %v", err))
+		return errcode.Response(req.Header, errcode.Internal.Wrap(err)), nil
+	}
 
 	return &proto.BaseResponse{
 		Header: req.Header,
@@ -268,13 +257,59 @@ func (ss *SystemService) GetPoolStats(ctx context.Context, req *proto.BaseReques
 
 // 系统消息处理器
 
-// HandleReloadConfig 处理重新加载配置消息
+// HandleReloadConfig 处理重新加载配置消息，立即校验并应用配置文件
 func (ss *SystemService) HandleReloadConfig(msg *mq.SystemMessage) error {
 	logger.Info(fmt.Sprintf("Received reload config command for %s", ss.server.nodeID))
 
-	// 这里可以实现具体的配置重新加载逻辑
-	// 比如重新读取配置文件，更新相关组件等
+	_, err := ss.server.configReload.Reload(ss.server.configFile)
+	return err
+}
+
+// HandleConfigPrepare 处理分阶段更新的校验消息，校验本节点的配置文件并暂存，结果写回Redis
+func (ss *SystemService) HandleConfigPrepare(msg *mq.SystemMessage) error {
+	rolloutID, _ := msg.Args["rollout_id"].(string)
+	if rolloutID == "" {
+		return fmt.Errorf("rollout_id is required")
+	}
+
+	result := &ConfigRolloutResult{NodeID: ss.server.nodeID, Phase: rolloutPhasePrepare}
+	staged, err := ss.server.configReload.Prepare(ss.server.configFile)
+	if err != nil {
+		result.Error = err.Error()
+		logger.Error(fmt.Sprintf("Config rollout %s validation failed: %v", rolloutID, err))
+	} else {
+		result.Success = true
+		result.Version = staged.Version
+		result.Changes = changeLines(staged.Changes)
+	}
+
+	return ss.server.reportRolloutResult(rolloutID, result)
+}
+
+// HandleConfigCommit 处理分阶段更新的提交消息，只应用校验阶段暂存的版本
+func (ss *SystemService) HandleConfigCommit(msg *mq.SystemMessage) error {
+	rolloutID, _ := msg.Args["rollout_id"].(string)
+	version, _ := msg.Args["version"].(string)
+	if rolloutID == "" || version == "" {
+		return fmt.Errorf("rollout_id and version are required")
+	}
 
+	result := &ConfigRolloutResult{NodeID: ss.server.nodeID, Phase: rolloutPhaseCommit, Version: version}
+	if _, err := ss.server.configReload.Commit(ss.server.configFile, version); err != nil {
+		result.Error = err.Error()
+		logger.Error(fmt.Sprintf("Config rollout %s commit failed: %v", rolloutID, err))
+	} else {
+		result.Success = true
+		logger.Info(fmt.Sprintf("Config rollout %s committed on %s (version: %s)", rolloutID, ss.server.nodeID, version))
+	}
+
+	return ss.server.reportRolloutResult(rolloutID, result)
+}
+
+// HandleConfigAbort 处理分阶段更新的中止消息，丢弃暂存的配置
+func (ss *SystemService) HandleConfigAbort(msg *mq.SystemMessage) error {
+	ss.server.configReload.Discard(ss.server.configFile)
+	logger.Info(fmt.Sprintf("Config rollout %v discarded on %s", msg.Args["rollout_id"], ss.server.nodeID))
 	return nil
 }
 
@@ -304,6 +339,138 @@ func (ss *SystemService) HandleShutdown(msg *mq.SystemMessage) error {
 	return nil
 }
 
+// HandleDrain 处理排空消息，排空后负载均衡不再把新请求路由到本节点，resume为true时恢复
+func (ss *SystemService) HandleDrain(msg *mq.SystemMessage) error {
+	resume, _ := msg.Args["resume"].(bool)
+
+	status := discovery.StatusDraining
+	if resume {
+		status = discovery.StatusOnline
+	}
+
+	if err := ss.server.registry.UpdateStatus(ss.server.nodeID, status); err != nil {
+		return fmt.Errorf("failed to update service status: %v", err)
+	}
+
+	logger.Info(fmt.Sprintf("Service %s is now %s", ss.server.nodeID, status))
+	return nil
+}
+
+// HandleActivity 处理活动开始或结束的广播，活动倍率按配置的时间窗口生效，这里只记录日志
+func (ss *SystemService) HandleActivity(msg *mq.SystemMessage) error {
+	id, _ := msg.Args["id"].(string)
+	state, _ := msg.Args["state"].(string)
+	logger.Info(fmt.Sprintf("Activity %s %s", id, state))
+	return nil
+}
+
+// HandleBroadcastNotice 处理跑马灯公告的广播，只有网关推送给在线玩家，这里只记录日志
+func (ss *SystemService) HandleBroadcastNotice(msg *mq.SystemMessage) error {
+	id, _ := msg.Args["id"].(string)
+	logger.Info(fmt.Sprintf("Announcement %s started", id))
+	return nil
+}
+
+// HandleCanary 处理灰度消息，canary为true时按weight设置灰度权重，为false时取消灰度标记
+func (ss *SystemService) HandleCanary(msg *mq.SystemMessage) error {
+	canary, _ := msg.Args["canary"].(bool)
+	weight, _ := msg.Args["weight"].(float64) // JSON数字解析为float64
+	reason, _ := msg.Args["reason"].(string)
+
+	metadata := map[string]string{
+		discovery.MetadataCanary:       "",
+		discovery.MetadataCanaryWeight: "",
+	}
+	if canary {
+		metadata = canaryMetadata(int(weight))
+	}
+
+	if err := ss.server.registry.UpdateMetadata(ss.server.nodeID, metadata); err != nil {
+		return fmt.Errorf("failed to update canary metadata: %v", err)
+	}
+
+	switch {
+	case !canary:
+		logger.Info(fmt.Sprintf("Service %s is no longer a canary", ss.server.nodeID))
+	case reason != "":
+		logger.Warn(fmt.Sprintf("Service %s canary weight set to %s%%: %s", ss.server.nodeID, metadata[discovery.MetadataCanaryWeight], reason))
+	default:
+		logger.Info(fmt.Sprintf("Service %s canary weight set to %s%%", ss.server.nodeID, metadata[discovery.MetadataCanaryWeight]))
+	}
+	return nil
+}
+
+// HandleChaos 处理故障注入消息，设置或清除本节点的注入规则，节点未开启chaos时拒绝
+func (ss *SystemService) HandleChaos(msg *mq.SystemMessage) error {
+	command, err := mq.Command[proto.ChaosCommand](msg)
+	if err != nil {
+		return err
+	}
+
+	rules := &chaos.Rules{
+		LatencyPercent:    int(command.LatencyPercent),
+		Latency:           time.Duration(command.LatencyMs) * time.Millisecond,
+		ErrorPercent:      int(command.ErrorPercent),
+		DropPercent:       int(command.DropPercent),
+		DisconnectPercent: int(command.DisconnectPercent),
+		Services:          command.Services,
+		Topics:            command.Topics,
+		Duration:          time.Duration(command.DurationSec) * time.Second,
+	}
+	expiresAt, err := ss.server.chaos.Apply(rules)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Rejected chaos command from %s: %v", command.Operator, err))
+		return err
+	}
+
+	if !rules.Active() {
+		logger.Info(fmt.Sprintf("Chaos rules cleared on %s by %s", ss.server.nodeID, command.Operator))
+		return nil
+	}
+	logger.Warn(fmt.Sprintf("Chaos rules applied on %s by %s until %s: %s",
+		ss.server.nodeID, command.Operator, expiresAt.Format(time.RFC3339), rules))
+	return nil
+}
+
+// HandleRuntimeParam 处理运行时参数调整消息，调整本节点的参数或立即恢复，到期后自动恢复
+func (ss *SystemService) HandleRuntimeParam(msg *mq.SystemMessage) error {
+	command, err := mq.Command[proto.RuntimeParamCommand](msg)
+	if err != nil {
+		return err
+	}
+
+	if command.Revert {
+		return ss.server.tuner.Reset(command.Name, command.Operator)
+	}
+	duration := time.Duration(command.DurationSec) * time.Second
+	_, err = ss.server.tuner.Set(command.Name, command.Value, duration, command.Operator)
+	return err
+}
+
+// HandleCaptureProfile 处理profile采集消息，采集在后台进行，完成后上传到对象存储
+func (ss *SystemService) HandleCaptureProfile(msg *mq.SystemMessage) error {
+	profileType, _ := msg.Args["type"].(string)
+	seconds, _ := msg.Args["seconds"].(float64) // JSON数字解析为float64
+	key, _ := msg.Args["key"].(string)
+	if key == "" {
+		return fmt.Errorf("profile object key is required")
+	}
+
+	if err := ss.server.profiler.ValidateRequest(profileType, int(seconds)); err != nil {
+		return err
+	}
+
+	logger.Info(fmt.Sprintf("Capturing %s profile for %s (%ds)", profileType, ss.server.nodeID, int(seconds)))
+
+	go func() {
+		if err := ss.server.profiler.CaptureAndUpload(profileType, int(seconds), key); err != nil {
+			logger.Error(fmt.Sprintf("Failed to capture %s profile: %v", profileType, err))
+		}
+	}()
+
+	return nil
+}
+
 // HandleHotUpdate 处理热更新消息
 func (ss *SystemService) HandleHotUpdate(msg *mq.SystemMessage) error {
 	logger.Info(fmt.Sprintf("Received hot update command for %s", ss.server.nodeID))
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"encoding/json"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/mq"
+	"github.com/phuhao00/lufy/internal/timeline"
+)
+
+// 进入房间的方式
+const (
+	roomJoinCreate = "create"
+	roomJoinJoin   = "join"
+	roomJoinMatch  = "match"
+)
+
+// 时间线中的聊天频道
+const (
+	chatChannelParty = "party"
+)
+
+// chatChannelNames 消息队列聊天频道在时间线中的名称
+var chatChannelNames = map[int32]string{
+	mq.CHAT_CHANNEL_WORLD:  "world",
+	mq.CHAT_CHANNEL_ROOM:   "room",
+	mq.CHAT_CHANNEL_FRIEND: "friend",
+	mq.CHAT_CHANNEL_GUILD:  "guild",
+}
+
+// newTimeline 创建玩家活动时间线
+func (bs *BaseServer) newTimeline() *timeline.Recorder {
+	return timeline.NewRecorder(&bs.config.Timeline,
+		database.NewActivityRepository(bs.mongoManager), database.NewLedgerRepository(bs.mongoManager))
+}
+
+// timelineJSON 玩家最近days天的活动时间线
+func (gs *GMServer) timelineJSON(userID uint64, days int) (string, error) {
+	report, err := gs.timeline.Report(userID, days)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
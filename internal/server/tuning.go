@@ -0,0 +1,95 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/eventbus"
+	"github.com/phuhao00/lufy/internal/mq"
+	"github.com/phuhao00/lufy/internal/tuning"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// registerRuntimeParams 注册依赖节点组件的运行时参数
+func (bs *BaseServer) registerRuntimeParams() {
+	// 订阅的处理协程数，键为 主题/channel，channel默认为节点ID
+	bs.tuner.Register(tuning.Param{
+		Name:        "consumer_concurrency",
+		Description: "handler goroutines of a subscription, key is topic[/channel]",
+		Keyed:       true,
+		Get: func(key string) (string, error) {
+			controller, topic, channel, err := bs.concurrencyController(key)
+			if err != nil {
+				return "", err
+			}
+			concurrency, err := controller.Concurrency(topic, channel)
+			if err != nil {
+				return "", err
+			}
+			return strconv.Itoa(concurrency), nil
+		},
+		Set: func(key, value string) error {
+			controller, topic, channel, err := bs.concurrencyController(key)
+			if err != nil {
+				return err
+			}
+			concurrency, err := strconv.Atoi(value)
+			if err != nil || concurrency <= 0 || concurrency > maxConsumerConcurrency {
+				return fmt.Errorf("consumer_concurrency must be between 1 and %d", maxConsumerConcurrency)
+			}
+			return controller.SetConcurrency(topic, channel, concurrency)
+		},
+	})
+}
+
+// maxConsumerConcurrency 运行时调整订阅处理协程数的上限
+const maxConsumerConcurrency = 256
+
+// concurrencyController 解析consumer_concurrency的键并获取能调整并发的事件总线。
+// 系统消息的订阅不能调整，调整需要等待处理中的消息完成，而调整命令本身就在其中处理
+func (bs *BaseServer) concurrencyController(key string) (mq.ConcurrencyController, string, string, error) {
+	topic, channel, ok := strings.Cut(key, "/")
+	if !ok {
+		channel = bs.nodeID
+	}
+	if topic == mq.SystemMessagesTopic.Name {
+		return nil, "", "", fmt.Errorf("concurrency of %s cannot be changed at runtime", topic)
+	}
+
+	bus := bs.eventBus
+	if deferred, ok := bus.(*eventbus.DeferredBus); ok {
+		bus = deferred.Attached()
+	}
+	controller, ok := bus.(mq.ConcurrencyController)
+	if !ok {
+		return nil, "", "", fmt.Errorf("message queue does not support changing concurrency")
+	}
+	return controller, topic, channel, nil
+}
+
+// parseRuntimeParamCommand 解析GM的set_runtime_param命令参数，如 log_level debug 10m，
+// consumer_concurrency:game_events 4，<参数> reset 立即恢复
+func parseRuntimeParamCommand(args []string) (*proto.RuntimeParamCommand, error) {
+	if len(args) < 2 || len(args) > 3 {
+		return nil, fmt.Errorf("参数格式为 <参数> <值|reset> [持续时间]")
+	}
+	command := &proto.RuntimeParamCommand{Name: args[0]}
+	if strings.ToLower(args[1]) == "reset" {
+		if len(args) == 3 {
+			return nil, fmt.Errorf("reset不需要持续时间")
+		}
+		command.Revert = true
+		return command, nil
+	}
+	command.Value = args[1]
+	if len(args) == 3 {
+		d, err := time.ParseDuration(args[2])
+		if err != nil || d < time.Second {
+			return nil, fmt.Errorf("无效的持续时间: %s", args[2])
+		}
+		command.DurationSec = int32(d / time.Second)
+	}
+	return command, nil
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/entitlement"
+	"github.com/phuhao00/lufy/internal/errcode"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/trigger"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// vipStatus 玩家的VIP等级和当前生效的权益
+type vipStatus struct {
+	UserID    uint64           `json:"user_id"`
+	Tier      int32            `json:"tier"` // 用户文档中的等级
+	ExpiresAt *time.Time       `json:"expires_at,omitempty"`
+	Active    int32            `json:"active"` // 当前有效的等级，已过期时为0
+	Benefits  entitlement.Tier `json:"benefits"`
+}
+
+// setVIP 设置玩家的VIP等级，返回是否生效，同一订单重复设置时不做修改。
+// 权益缓存只在本节点清除，其他节点在缓存到期后生效
+func (gs *GMServer) setVIP(gmUserID, userID uint64, tier int32, expiresAt time.Time, orderID string) (bool, error) {
+	if userID == 0 || tier < 0 || orderID == "" {
+		return false, errcode.VIPRequestInvalid.WithDetail("user_id, order_id and a non-negative tier are required")
+	}
+	if tier > 0 && !expiresAt.IsZero() && !expiresAt.After(time.Now()) {
+		return false, errcode.VIPRequestInvalid.WithDetail("expires_at is in the past")
+	}
+
+	userRepo := database.NewUserRepository(gs.mongoManager)
+	updated, err := userRepo.SetVIP(userID, tier, expiresAt, orderID)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to set vip tier of user %d: %v", userID, err))
+		return false, errcode.Internal.Wrap(err)
+	}
+	if !updated {
+		if _, err := userRepo.GetByUserID(userID); err != nil {
+			return false, errcode.UserNotFound
+		}
+		logger.Info(fmt.Sprintf("VIP order %s of user %d already applied", orderID, userID))
+		return false, nil
+	}
+	gs.entitlements.Invalidate(userID)
+
+	expires := "永久"
+	if !expiresAt.IsZero() {
+		expires = expiresAt.Format(time.RFC3339)
+	}
+	gs.gmRepo.LogGMAction(gmUserID, "set_vip", userID, fmt.Sprintf("等级: %d, 到期: %s, 订单: %s", tier, expires, orderID))
+	logger.Info(fmt.Sprintf("VIP tier of user %d set to %d until %s (order %s)", userID, tier, expires, orderID))
+	return true, nil
+}
+
+// vipJSON 玩家的VIP等级和权益
+func (gs *GMServer) vipJSON(userID uint64) (string, error) {
+	user, err := database.NewUserRepository(gs.mongoManager).GetByUserID(userID)
+	if err != nil {
+		return "", fmt.Errorf("玩家不存在: %d", userID)
+	}
+	status := vipStatus{
+		UserID:   userID,
+		Tier:     user.VIPTier,
+		Active:   user.ActiveVIPTier(time.Now()),
+		Benefits: gs.entitlements.Benefits(userID),
+	}
+	if !user.VIPExpiresAt.IsZero() {
+		status.ExpiresAt = &user.VIPExpiresAt
+	}
+	data, err := json.Marshal(status)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// vipCommand 查看或设置玩家的VIP等级: vip <玩家ID> [等级] [时长]，
+// 未指定时长时不过期，如 vip 10001 2 720h
+func (gs *GMServer) vipCommand(gmUserID uint64, args []string) (string, error) {
+	if len(args) < 1 || len(args) > 3 {
+		return "", fmt.Errorf("参数格式为 <玩家ID> [等级] [时长]")
+	}
+	userID, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("无效的玩家ID: %s", args[0])
+	}
+	if len(args) == 1 {
+		return gs.vipJSON(userID)
+	}
+
+	tier, err := strconv.ParseInt(args[1], 10, 32)
+	if err != nil || tier < 0 {
+		return "", fmt.Errorf("无效的VIP等级: %s", args[1])
+	}
+	var expiresAt time.Time
+	if len(args) == 3 {
+		d, err := time.ParseDuration(args[2])
+		if err != nil || d <= 0 {
+			return "", fmt.Errorf("无效的时长: %s", args[2])
+		}
+		expiresAt = time.Now().Add(d)
+	}
+
+	orderID := fmt.Sprintf("gm:%d:%d", gmUserID, time.Now().UnixNano())
+	if _, err := gs.setVIP(gmUserID, userID, int32(tier), expiresAt, orderID); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("玩家 %d 的VIP等级已设置为 %d，其他节点在缓存到期后生效", userID, tier), nil
+}
+
+// SetVIP 设置玩家的VIP等级，计费系统在开通、续费和退款时以订单号调用，重复通知不会重复生效。
+// 开通和续费视为购买，触发首次购买奖励，GM命令设置的等级不触发
+func (gs *GMService) SetVIP(ctx context.Context, req *proto.SetVIPRequest) (*proto.CommonResponse, error) {
+	gmUserID := ctx.Value("user_id")
+	if gmUserID == nil {
+		return gs.responses.CommonError(ctx, errcode.NotLoggedIn), nil
+	}
+
+	var expiresAt time.Time
+	if req.GetExpiresAt() > 0 {
+		expiresAt = time.Unix(req.GetExpiresAt(), 0)
+	}
+	applied, err := gs.server.setVIP(gmUserID.(uint64), req.GetUserId(), req.GetTier(), expiresAt, req.GetOrderId())
+	if err != nil {
+		return gs.responses.CommonError(ctx, err), nil
+	}
+	if applied && req.GetTier() > 0 {
+		gs.server.triggers.Fire(trigger.Event{Type: trigger.EventFirstPurchase, UserID: req.GetUserId(), Ref: req.GetOrderId()})
+	}
+	return gs.responses.CommonSuccess(ctx, "VIP等级已更新", nil), nil
+}
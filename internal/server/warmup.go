@@ -0,0 +1,209 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/discovery"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/rpc"
+)
+
+// 预热默认设置
+const (
+	defaultWarmupTimeout = 30 * time.Second
+	defaultPeerPoolSize  = 8
+)
+
+// DependencyWarmup 预热阶段，和启动依赖一起计入就绪状态，预热完成前节点以starting注册
+const DependencyWarmup = "warmup"
+
+// WarmupConfig 冷启动预热设置。节点启动后先以starting注册，预先建立数据库和RPC连接、加载热点缓存后才转为online，
+// 避免新节点带着空连接池和空缓存直接接收流量
+type WarmupConfig struct {
+	Enabled          bool          `yaml:"enabled"`
+	Timeout          time.Duration `yaml:"timeout"`           // 预热总时限，超时或失败时不再等待，节点直接上线，未配置时为30秒
+	MongoConnections int           `yaml:"mongo_connections"` // 预先建立的MongoDB连接数，未配置时为database.mongodb.min_pool_size
+	RedisConnections int           `yaml:"redis_connections"` // 预先建立的Redis连接数，未配置时为database.redis.pool_size的四分之一
+	Peers            []string      `yaml:"peers"`             // 预先建立RPC连接的节点类型
+	PeerConnections  int           `yaml:"peer_connections"`  // 对每个对端节点预先建立的RPC连接数，未配置时为1
+}
+
+// warmupTask 预热任务
+type warmupTask struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// AddWarmup 添加预热任务，需在Start前调用；各任务在预热阶段并发执行，失败只记录日志
+func (bs *BaseServer) AddWarmup(name string, fn func(ctx context.Context) error) {
+	bs.warmupMutex.Lock()
+	defer bs.warmupMutex.Unlock()
+	bs.warmups = append(bs.warmups, warmupTask{name: name, fn: fn})
+}
+
+// beginWarmup 启用预热时开始记录预热状态，返回nil表示不预热
+func (bs *BaseServer) beginWarmup() *DependencyStatus {
+	if !bs.config.Warmup.Enabled {
+		return nil
+	}
+	return bs.readiness.track(DependencyWarmup, false)
+}
+
+// runWarmup 执行内置和节点注册的预热任务，结束后节点转为online
+func (bs *BaseServer) runWarmup(status *DependencyStatus) {
+	defer bs.wg.Done()
+
+	config := bs.config.Warmup
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = defaultWarmupTimeout
+	}
+	ctx, cancel := context.WithTimeout(bs.ctx, timeout)
+	defer cancel()
+
+	tasks := []warmupTask{
+		{name: "mongodb", fn: bs.warmMongo},
+		{name: "redis", fn: bs.warmRedis},
+		{name: "rpc_peers", fn: bs.warmPeers},
+	}
+	bs.warmupMutex.Lock()
+	tasks = append(tasks, bs.warmups...)
+	bs.warmupMutex.Unlock()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for _, task := range tasks {
+		wg.Add(1)
+		go func(task warmupTask) {
+			defer wg.Done()
+			taskStart := time.Now()
+			if err := task.fn(ctx); err != nil {
+				logger.Warn(fmt.Sprintf("Warmup %s failed after %v: %v", task.name, time.Since(taskStart), err))
+				return
+			}
+			logger.Debug(fmt.Sprintf("Warmup %s finished in %v", task.name, time.Since(taskStart)))
+		}(task)
+	}
+
+	// 超时后不再等待未完成的任务，它们在ctx取消后自行结束
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		logger.Info(fmt.Sprintf("Warmup of %s finished in %v", bs.nodeID, time.Since(start)))
+	case <-ctx.Done():
+		if bs.ctx.Err() != nil {
+			return
+		}
+		logger.Warn(fmt.Sprintf("Warmup of %s timed out after %v, going online anyway", bs.nodeID, timeout))
+	}
+
+	bs.readiness.record(status, nil)
+	bs.onDependencyReady()
+}
+
+// warmMongo 预先建立MongoDB连接
+func (bs *BaseServer) warmMongo(ctx context.Context) error {
+	if bs.mongoManager == nil {
+		return nil
+	}
+	n := bs.config.Warmup.MongoConnections
+	if n <= 0 {
+		n = int(bs.config.Database.MongoDB.MinPoolSize)
+	}
+	if n <= 0 {
+		return nil
+	}
+	return bs.mongoManager.Warmup(ctx, n)
+}
+
+// warmRedis 预先建立Redis连接
+func (bs *BaseServer) warmRedis(ctx context.Context) error {
+	if bs.redisManager == nil {
+		return nil
+	}
+	n := bs.config.Warmup.RedisConnections
+	if n <= 0 {
+		n = bs.config.Database.Redis.PoolSize / 4
+	}
+	if n <= 0 {
+		return nil
+	}
+	return bs.redisManager.Warmup(ctx, n)
+}
+
+// warmPeers 预先建立到对端节点的RPC连接
+func (bs *BaseServer) warmPeers(ctx context.Context) error {
+	n := bs.config.Warmup.PeerConnections
+	if n <= 0 {
+		n = 1
+	}
+
+	var failed []string
+	for _, nodeType := range bs.config.Warmup.Peers {
+		for _, service := range bs.discovery.GetAllServices(nodeType) {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if service.NodeID == bs.nodeID {
+				continue
+			}
+			if _, err := bs.peerPool(service).Warm(n); err != nil {
+				failed = append(failed, service.NodeID)
+				logger.Debug(fmt.Sprintf("Failed to warm rpc connections to %s: %v", service.NodeID, err))
+			}
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to connect to %v", failed)
+	}
+	return nil
+}
+
+// peerPool 到对端节点的RPC连接池，节点地址变化时重建
+func (bs *BaseServer) peerPool(service *discovery.ServiceInfo) *rpc.RPCConnectionPool {
+	address := fmt.Sprintf("%s:%d", service.Address, service.Port)
+
+	bs.peerMutex.Lock()
+	defer bs.peerMutex.Unlock()
+
+	if peer, exists := bs.peerPools[service.NodeID]; exists {
+		if peer.address == address {
+			return peer.pool
+		}
+		// 节点重启后地址变化，旧连接已失效
+		peer.pool.Close()
+	}
+
+	size := bs.config.RPC.MaxIdle
+	if size <= 0 {
+		size = defaultPeerPoolSize
+	}
+	pool := rpc.NewRPCConnectionPool(service.Address, service.Port, size)
+	pool.SetTransportConfig(bs.rpcTransportConfig(bs.config))
+	bs.peerPools[service.NodeID] = &peerEntry{address: address, pool: pool}
+	return pool
+}
+
+// closePeerPools 关闭所有对端连接池
+func (bs *BaseServer) closePeerPools() {
+	bs.peerMutex.Lock()
+	defer bs.peerMutex.Unlock()
+
+	for nodeID, peer := range bs.peerPools {
+		peer.pool.Close()
+		delete(bs.peerPools, nodeID)
+	}
+}
+
+// peerEntry 对端节点的连接池和建立时的地址
+type peerEntry struct {
+	address string
+	pool    *rpc.RPCConnectionPool
+}
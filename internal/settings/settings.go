@@ -0,0 +1,289 @@
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/push"
+)
+
+// ErrInvalid 设置项、取值或通知频道无效
+var ErrInvalid = errors.New("invalid setting")
+
+// 隐私设置允许的范围
+const (
+	AudienceEveryone = "everyone"
+	AudienceFriends  = "friends"
+	AudienceNobody   = "nobody"
+)
+
+// 隐私设置项
+const (
+	ActionOnlineStatus   = "online_status"
+	ActionFriendRequests = "friend_requests"
+	ActionSpectate       = "spectate"
+	ActionWhisper        = "whisper"
+	ActionSearchable     = "searchable" // 谁可以按名称搜索到玩家
+)
+
+// audiences 各设置项可选的范围，好友请求只能来自非好友，不能设置为friends
+var audiences = map[string][]string{
+	ActionOnlineStatus:   {AudienceEveryone, AudienceFriends, AudienceNobody},
+	ActionFriendRequests: {AudienceEveryone, AudienceNobody},
+	ActionSpectate:       {AudienceEveryone, AudienceFriends, AudienceNobody},
+	ActionWhisper:        {AudienceEveryone, AudienceFriends, AudienceNobody},
+	ActionSearchable:     {AudienceEveryone, AudienceFriends, AudienceNobody},
+}
+
+// 可以关闭的通知频道
+const (
+	ChannelWorldChat = "world_chat"
+	ChannelGuildChat = "guild_chat"
+	ChannelMailBadge = "mail_badge" // 新邮件和礼物的红点通知
+)
+
+// channels 所有通知频道，移动推送按推送类型分别关闭
+var channels = []string{
+	ChannelWorldChat,
+	ChannelGuildChat,
+	ChannelMailBadge,
+	push.Channel(push.KindMail),
+	push.Channel(push.KindGift),
+	push.Channel(push.KindFriendRequest),
+	push.Channel(push.KindTurnReminder),
+}
+
+// knownChannel 是否为可以关闭的通知频道
+func knownChannel(channel string) bool {
+	for _, known := range channels {
+		if known == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultCacheTTL 玩家设置默认缓存时间
+const defaultCacheTTL = 10 * time.Minute
+
+// Config 玩家设置配置
+type Config struct {
+	Cache    time.Duration     `yaml:"cache"`    // 玩家设置的缓存时间，未配置时为10分钟
+	Defaults map[string]string `yaml:"defaults"` // 玩家未修改的设置项使用的范围，未配置的项为everyone
+	Muted    []string          `yaml:"muted"`    // 玩家未修改时默认关闭的通知频道
+}
+
+// Validate 检查默认设置
+func (c *Config) Validate() error {
+	for action, audience := range c.Defaults {
+		if !valid(action, audience) {
+			return fmt.Errorf("invalid default privacy setting %s: %s", action, audience)
+		}
+	}
+	for _, channel := range c.Muted {
+		if !knownChannel(channel) {
+			return fmt.Errorf("unknown notification channel: %s", channel)
+		}
+	}
+	return nil
+}
+
+// valid 检查设置项的取值
+func valid(action, audience string) bool {
+	for _, allowed := range audiences[action] {
+		if allowed == audience {
+			return true
+		}
+	}
+	return false
+}
+
+// Store 玩家设置存储，database.UserSettingsRepository实现了该接口
+type Store interface {
+	Get(userID uint64) (*database.UserSettings, error)
+	Save(settings *database.UserSettings) error
+}
+
+// Service 玩家的隐私设置和通知偏好，设置缓存在Redis中，修改后删除缓存
+type Service struct {
+	config Config
+	store  Store
+	redis  *database.RedisManager
+	ttl    time.Duration
+}
+
+// NewService 创建玩家设置服务
+func NewService(config *Config, store Store, redis *database.RedisManager) *Service {
+	s := &Service{
+		config: *config,
+		store:  store,
+		redis:  redis,
+		ttl:    config.Cache,
+	}
+	if s.ttl <= 0 {
+		s.ttl = defaultCacheTTL
+	}
+	return s
+}
+
+// cacheKey 玩家设置的缓存键
+func cacheKey(userID uint64) string {
+	return fmt.Sprintf("settings:%d", userID)
+}
+
+// Get 获取玩家设置，未修改的项为默认值
+func (s *Service) Get(userID uint64) (*database.UserSettings, error) {
+	var cached database.UserSettings
+	if err := s.redis.GetObject(cacheKey(userID), &cached); err == nil {
+		return &cached, nil
+	}
+
+	settings, err := s.store.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		settings = &database.UserSettings{UserID: userID}
+	}
+	s.fillDefaults(settings)
+
+	if err := s.redis.Set(cacheKey(userID), settings, s.ttl); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to cache settings of user %d: %v", userID, err))
+	}
+	return settings, nil
+}
+
+// Update 修改玩家的隐私设置，changes中未出现的项保持不变
+func (s *Service) Update(userID uint64, changes map[string]string) (*database.UserSettings, error) {
+	for action, audience := range changes {
+		if !valid(action, audience) {
+			return nil, fmt.Errorf("%w: %s=%s", ErrInvalid, action, audience)
+		}
+	}
+
+	return s.modify(userID, func(settings *database.UserSettings) {
+		for action, audience := range changes {
+			*field(settings, action) = audience
+		}
+	})
+}
+
+// UpdateMutes 关闭或打开通知频道，changes中未出现的频道保持不变
+func (s *Service) UpdateMutes(userID uint64, changes map[string]bool) (*database.UserSettings, error) {
+	for channel := range changes {
+		if !knownChannel(channel) {
+			return nil, fmt.Errorf("%w: unknown notification channel %s", ErrInvalid, channel)
+		}
+	}
+
+	return s.modify(userID, func(settings *database.UserSettings) {
+		if settings.Mutes == nil {
+			settings.Mutes = make(map[string]bool, len(changes))
+		}
+		for channel, muted := range changes {
+			settings.Mutes[channel] = muted
+		}
+	})
+}
+
+// modify 读取玩家设置，修改后保存并删除缓存
+func (s *Service) modify(userID uint64, apply func(settings *database.UserSettings)) (*database.UserSettings, error) {
+	settings, err := s.store.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		settings = &database.UserSettings{UserID: userID}
+	}
+	s.fillDefaults(settings)
+	apply(settings)
+
+	if err := s.store.Save(settings); err != nil {
+		return nil, err
+	}
+	if err := s.redis.Delete(cacheKey(userID)); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to invalidate settings of user %d: %v", userID, err))
+	}
+	return settings, nil
+}
+
+// Allows 检查玩家的设置是否允许viewer执行操作，friend为viewer是否是玩家的好友
+func (s *Service) Allows(userID uint64, action string, viewerID uint64, friend bool) (bool, error) {
+	if userID == viewerID {
+		return true, nil
+	}
+	settings, err := s.Get(userID)
+	if err != nil {
+		return false, err
+	}
+	return Allows(settings, action, friend), nil
+}
+
+// Allows 检查设置是否允许好友或非好友执行操作
+func Allows(settings *database.UserSettings, action string, friend bool) bool {
+	switch *field(settings, action) {
+	case AudienceEveryone:
+		return true
+	case AudienceFriends:
+		return friend
+	}
+	return false
+}
+
+// Muted 玩家是否关闭了通知频道
+func (s *Service) Muted(userID uint64, channel string) (bool, error) {
+	settings, err := s.Get(userID)
+	if err != nil {
+		return false, err
+	}
+	return settings.Mutes[channel], nil
+}
+
+// Mutes 所有通知频道的开关，true为已关闭
+func Mutes(settings *database.UserSettings) map[string]bool {
+	mutes := make(map[string]bool, len(channels))
+	for _, channel := range channels {
+		mutes[channel] = settings.Mutes[channel]
+	}
+	return mutes
+}
+
+// fillDefaults 补齐未修改的设置项和通知频道
+func (s *Service) fillDefaults(settings *database.UserSettings) {
+	for action := range audiences {
+		if value := field(settings, action); *value == "" {
+			*value = s.config.Defaults[action]
+			if *value == "" {
+				*value = AudienceEveryone
+			}
+		}
+	}
+	for _, channel := range s.config.Muted {
+		if _, exists := settings.Mutes[channel]; !exists {
+			if settings.Mutes == nil {
+				settings.Mutes = make(map[string]bool)
+			}
+			settings.Mutes[channel] = true
+		}
+	}
+}
+
+// field 设置项对应的字段，未知的设置项返回空的临时值
+func field(settings *database.UserSettings, action string) *string {
+	switch action {
+	case ActionOnlineStatus:
+		return &settings.OnlineStatus
+	case ActionFriendRequests:
+		return &settings.FriendRequests
+	case ActionSpectate:
+		return &settings.Spectate
+	case ActionWhisper:
+		return &settings.Whisper
+	case ActionSearchable:
+		return &settings.Searchable
+	}
+	return new(string)
+}
@@ -0,0 +1,151 @@
+package settlement
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// 对账发现的问题类型
+const (
+	IssueMissing = "missing" // 没有结算记录，消息丢失或未投递
+	IssueFailed  = "failed"  // 结算失败且未被重试成功
+	IssueStuck   = "stuck"   // 处理超时，领取的节点可能已故障
+)
+
+// Discrepancy 对账发现的未结算步骤
+type Discrepancy struct {
+	GameID      uint64 `json:"game_id"`
+	Step        string `json:"step"`
+	Issue       string `json:"issue"`
+	Attempts    int32  `json:"attempts"`
+	Error       string `json:"error,omitempty"`
+	Repaired    bool   `json:"repaired"`
+	RepairError string `json:"repair_error,omitempty"`
+}
+
+// ReconcileReport 对账报告
+type ReconcileReport struct {
+	Since         time.Time     `json:"since"`
+	Until         time.Time     `json:"until"`
+	Checked       int           `json:"checked"`   // 检查的游戏数
+	Settled       int           `json:"settled"`   // 所有步骤均已结算的游戏数
+	Truncated     bool          `json:"truncated"` // 达到单次检查上限，剩余游戏下次对账处理
+	Discrepancies []Discrepancy `json:"discrepancies"`
+}
+
+// Reconcile 检查时间范围内结束的游戏是否所有步骤都已结算，repair为true时重新执行未结算的步骤
+func (s *Settler) Reconcile(ctx context.Context, since, until time.Time, repair bool) (*ReconcileReport, error) {
+	records, err := s.records.GetEndedRecords(since, until, s.config.ReconcileLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.RLock()
+	steps := append([]step(nil), s.steps...)
+	s.mutex.RUnlock()
+
+	report := &ReconcileReport{
+		Since:     since,
+		Until:     until,
+		Truncated: int64(len(records)) >= s.config.ReconcileLimit,
+	}
+
+	for _, record := range records {
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+
+		settlements, err := s.store.GetSettlements(record.GameID)
+		if err != nil {
+			return report, err
+		}
+		byStep := make(map[string]*database.Settlement, len(settlements))
+		for _, settlement := range settlements {
+			byStep[settlement.Step] = settlement
+		}
+
+		report.Checked++
+		complete := true
+		for _, step := range steps {
+			discrepancy, ok := s.check(record.GameID, step.name, byStep[step.name])
+			if ok {
+				continue
+			}
+			complete = false
+
+			if repair {
+				if err := s.settleStep(ctx, record, step); err != nil {
+					discrepancy.RepairError = err.Error()
+				} else {
+					discrepancy.Repaired = true
+				}
+			}
+			report.Discrepancies = append(report.Discrepancies, discrepancy)
+		}
+		if complete {
+			report.Settled++
+		}
+	}
+
+	return report, nil
+}
+
+// check 检查单个步骤的结算记录，已结算或仍在处理时限内返回true
+func (s *Settler) check(gameID uint64, stepName string, settlement *database.Settlement) (Discrepancy, bool) {
+	discrepancy := Discrepancy{GameID: gameID, Step: stepName}
+	if settlement == nil {
+		discrepancy.Issue = IssueMissing
+		return discrepancy, false
+	}
+
+	discrepancy.Attempts = settlement.Attempts
+	discrepancy.Error = settlement.Error
+	switch settlement.Status {
+	case database.SettlementSettled:
+		return discrepancy, true
+	case database.SettlementFailed:
+		discrepancy.Issue = IssueFailed
+		return discrepancy, false
+	default:
+		if time.Now().Before(settlement.LeaseUntil) {
+			return discrepancy, true
+		}
+		discrepancy.Issue = IssueStuck
+		return discrepancy, false
+	}
+}
+
+// ReconcileRecent 对账最近结束的游戏并修复遗漏，跳过仍可能在投递中的游戏，供定时任务使用
+func (s *Settler) ReconcileRecent(ctx context.Context) error {
+	now := time.Now()
+	report, err := s.Reconcile(ctx, now.Add(-s.config.ReconcileWindow), now.Add(-s.config.ReconcileDelay), true)
+	if err != nil {
+		return fmt.Errorf("failed to reconcile settlements: %v", err)
+	}
+
+	unrepaired := 0
+	for _, discrepancy := range report.Discrepancies {
+		if !discrepancy.Repaired {
+			unrepaired++
+		}
+	}
+
+	if len(report.Discrepancies) > 0 {
+		logger.Warn(fmt.Sprintf("Settlement reconcile checked %d games, found %d unsettled steps, %d could not be repaired",
+			report.Checked, len(report.Discrepancies), unrepaired))
+	} else {
+		logger.Debug(fmt.Sprintf("Settlement reconcile checked %d games, all settled", report.Checked))
+	}
+	if report.Truncated {
+		logger.Warn(fmt.Sprintf("Settlement reconcile reached limit of %d games", s.config.ReconcileLimit))
+	}
+
+	if unrepaired > 0 {
+		return fmt.Errorf("%d settlement steps could not be repaired", unrepaired)
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package settlement
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// Config 游戏结算配置
+type Config struct {
+	Lease           time.Duration `yaml:"lease"`            // 单个结算步骤的处理时限，超时后可被其他节点接管
+	ReconcileWindow time.Duration `yaml:"reconcile_window"` // 对账检查最近多长时间内结束的游戏
+	ReconcileDelay  time.Duration `yaml:"reconcile_delay"`  // 游戏结束后等待消息投递的时间，超过后仍未结算视为遗漏
+	ReconcileLimit  int64         `yaml:"reconcile_limit"`  // 单次对账检查的最大游戏数
+	WinReward       Reward        `yaml:"win_reward"`       // 胜者奖励
+	LoseReward      Reward        `yaml:"lose_reward"`      // 其他玩家奖励
+}
+
+// Reward 结算奖励
+type Reward struct {
+	Experience int64 `yaml:"experience"`
+	Gold       int64 `yaml:"gold"`
+}
+
+// Store 结算记录存储，database.SettlementRepository实现了该接口
+type Store interface {
+	Claim(gameID uint64, step, nodeID string, lease time.Duration) (bool, error)
+	Complete(gameID uint64, step, nodeID string) error
+	Fail(gameID uint64, step, nodeID, reason string) error
+	GetSettlements(gameID uint64) ([]*database.Settlement, error)
+}
+
+// RecordSource 游戏记录来源，database.GameRecordRepository实现了该接口
+type RecordSource interface {
+	GetRecord(gameID uint64) (*database.GameRecord, error)
+	GetEndedRecords(since, until time.Time, limit int64) ([]*database.GameRecord, error)
+}
+
+// StepFunc 结算步骤，对一局已结束的游戏执行一次下游处理(积分、奖励、统计等)
+type StepFunc func(ctx context.Context, record *database.GameRecord) error
+
+// step 已注册的结算步骤
+type step struct {
+	name string
+	fn   StepFunc
+}
+
+// Settler 游戏结算器
+// 每个步骤执行前以游戏ID和步骤名条件插入结算记录，消息重复投递或多个节点同时收到时只有一个能领取，
+// 已结算的步骤直接跳过；失败或节点故障导致超时的步骤由重新投递或对账重试。
+// 步骤完成后、标记结算前节点故障时该步骤会被再次执行，步骤本身应尽量做到可重入
+type Settler struct {
+	config  Config
+	nodeID  string
+	store   Store
+	records RecordSource
+	steps   []step
+	mutex   sync.RWMutex
+}
+
+// NewSettler 创建游戏结算器
+func NewSettler(config *Config, nodeID string, store Store, records RecordSource) *Settler {
+	cfg := *config
+	if cfg.Lease <= 0 {
+		cfg.Lease = time.Minute
+	}
+	if cfg.ReconcileWindow <= 0 {
+		cfg.ReconcileWindow = 24 * time.Hour
+	}
+	if cfg.ReconcileDelay <= 0 {
+		cfg.ReconcileDelay = 5 * time.Minute
+	}
+	if cfg.ReconcileLimit <= 0 {
+		cfg.ReconcileLimit = 1000
+	}
+
+	return &Settler{
+		config:  cfg,
+		nodeID:  nodeID,
+		store:   store,
+		records: records,
+	}
+}
+
+// Config 获取生效的结算配置
+func (s *Settler) Config() Config {
+	return s.config
+}
+
+// Register 注册结算步骤，步骤名作为去重键的一部分，上线后不应修改
+func (s *Settler) Register(name string, fn StepFunc) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, existing := range s.steps {
+		if existing.name == name {
+			return fmt.Errorf("settlement step %s already registered", name)
+		}
+	}
+	s.steps = append(s.steps, step{name: name, fn: fn})
+
+	logger.Info(fmt.Sprintf("Settlement step %s registered", name))
+	return nil
+}
+
+// Steps 获取已注册的步骤名
+func (s *Settler) Steps() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	names := make([]string, len(s.steps))
+	for i, step := range s.steps {
+		names[i] = step.name
+	}
+	return names
+}
+
+// SettleGame 结算指定游戏
+func (s *Settler) SettleGame(ctx context.Context, gameID uint64) error {
+	record, err := s.records.GetRecord(gameID)
+	if err != nil {
+		return fmt.Errorf("failed to load game %d: %v", gameID, err)
+	}
+	return s.Settle(ctx, record)
+}
+
+// Settle 依次执行所有步骤，已结算或其他节点正在处理的步骤跳过，返回第一个失败的步骤错误
+func (s *Settler) Settle(ctx context.Context, record *database.GameRecord) error {
+	if record.Status != 1 {
+		return fmt.Errorf("game %d has not ended", record.GameID)
+	}
+
+	s.mutex.RLock()
+	steps := append([]step(nil), s.steps...)
+	s.mutex.RUnlock()
+
+	var firstErr error
+	for _, step := range steps {
+		if err := s.settleStep(ctx, record, step); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// settleStep 领取并执行单个步骤
+func (s *Settler) settleStep(ctx context.Context, record *database.GameRecord, step step) error {
+	claimed, err := s.store.Claim(record.GameID, step.name, s.nodeID, s.config.Lease)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		logger.Debug(fmt.Sprintf("Settlement %d/%s already settled or in progress", record.GameID, step.name))
+		return nil
+	}
+
+	if err := s.invoke(ctx, record, step); err != nil {
+		if failErr := s.store.Fail(record.GameID, step.name, s.nodeID, err.Error()); failErr != nil {
+			logger.Error(fmt.Sprintf("Failed to mark settlement %d/%s failed: %v", record.GameID, step.name, failErr))
+		}
+		return fmt.Errorf("settlement %d/%s failed: %v", record.GameID, step.name, err)
+	}
+
+	if err := s.store.Complete(record.GameID, step.name, s.nodeID); err != nil {
+		return err
+	}
+
+	logger.Debug(fmt.Sprintf("Settlement %d/%s completed", record.GameID, step.name))
+	return nil
+}
+
+// invoke 在处理时限内执行步骤，panic视为失败
+func (s *Settler) invoke(ctx context.Context, record *database.GameRecord, step step) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, s.config.Lease)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error(fmt.Sprintf("Settlement %d/%s panic: %v\n%s", record.GameID, step.name, r, debug.Stack()))
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return step.fn(ctx, record)
+}
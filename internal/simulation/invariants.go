@@ -0,0 +1,119 @@
+package simulation
+
+import (
+	"fmt"
+
+	"github.com/phuhao00/lufy/internal/gameplay"
+)
+
+// cardsPerDeck 一副牌的张数
+const cardsPerDeck = 52
+
+// DefaultInvariants 各玩法默认检查的不变量，所有玩法都检查通用不变量
+func DefaultInvariants(gameType string) []Invariant {
+	invariants := []Invariant{
+		{Name: "player_count", Check: checkPlayerCount},
+		{Name: "score_non_negative", Check: checkScores},
+	}
+	switch gameType {
+	case "card_game":
+		invariants = append(invariants,
+			Invariant{Name: "card_conservation", Check: checkCardConservation},
+			Invariant{Name: "hand_limit", Check: checkHandLimit},
+			Invariant{Name: "turn_order", Check: checkTurnOrder},
+		)
+	}
+	return invariants
+}
+
+// checkPlayerCount 玩家数不超过房间上限
+func checkPlayerCount(room *gameplay.GameRoom) error {
+	if max := room.Config.MaxPlayers; max > 0 && room.GetPlayerCount() > max {
+		return fmt.Errorf("%d players exceed max %d", room.GetPlayerCount(), max)
+	}
+	return nil
+}
+
+// checkScores 玩家得分不为负
+func checkScores(room *gameplay.GameRoom) error {
+	for _, player := range room.Players {
+		if player.Score < 0 {
+			return fmt.Errorf("player %d has negative score %d", player.UserID, player.Score)
+		}
+	}
+	return nil
+}
+
+// cardData 卡牌房间的游戏数据
+func cardData(room *gameplay.GameRoom) (*gameplay.CardGameData, error) {
+	data, ok := room.GameData.(*gameplay.CardGameData)
+	if !ok {
+		return nil, fmt.Errorf("unexpected game data %T", room.GameData)
+	}
+	return data, nil
+}
+
+// checkCardConservation 牌堆、手牌和桌面上的牌总数等于副数乘52，且没有重复的牌
+func checkCardConservation(room *gameplay.GameRoom) error {
+	data, err := cardData(room)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[int]bool)
+	count := func(cards []gameplay.Card, where string) error {
+		for _, card := range cards {
+			if seen[card.ID] {
+				return fmt.Errorf("card %d duplicated in %s", card.ID, where)
+			}
+			seen[card.ID] = true
+		}
+		return nil
+	}
+	if err := count(data.Deck, "deck"); err != nil {
+		return err
+	}
+	if err := count(data.Board, "board"); err != nil {
+		return err
+	}
+	for userID, hand := range data.Hands {
+		if err := count(hand, fmt.Sprintf("hand of player %d", userID)); err != nil {
+			return err
+		}
+	}
+
+	if expected := room.Config.IntRule("decks", 1) * cardsPerDeck; len(seen) != expected {
+		return fmt.Errorf("%d cards in play, expected %d", len(seen), expected)
+	}
+	return nil
+}
+
+// checkHandLimit 手牌数不超过房间规则的上限
+func checkHandLimit(room *gameplay.GameRoom) error {
+	data, err := cardData(room)
+	if err != nil {
+		return err
+	}
+	maxHand := room.Config.IntRule("max_hand", 10)
+	for userID, hand := range data.Hands {
+		if len(hand) > maxHand {
+			return fmt.Errorf("player %d holds %d cards, max %d", userID, len(hand), maxHand)
+		}
+	}
+	return nil
+}
+
+// checkTurnOrder 当前回合的玩家在房间中
+func checkTurnOrder(room *gameplay.GameRoom) error {
+	data, err := cardData(room)
+	if err != nil {
+		return err
+	}
+	if data.Turn == 0 {
+		return nil
+	}
+	if _, ok := room.GetPlayer(data.Turn); !ok {
+		return fmt.Errorf("turn holder %d is not in room", data.Turn)
+	}
+	return nil
+}
@@ -0,0 +1,297 @@
+package simulation
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/gameplay"
+)
+
+// 默认配置
+const (
+	defaultPlayers  = 2
+	defaultSteps    = 1000
+	maxRandomInt    = 100                    // 无上限的int字段随机取值的范围
+	simulatedTick   = 100 * time.Millisecond // 相邻两步之间的模拟时间
+	maxViolations   = 100                    // 记录的违规上限，超出后只计数
+	maxStringLength = 16
+)
+
+// epoch 模拟时钟的起点，固定值保证相同种子的操作时间戳一致
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Step 脚本中的一步操作
+type Step struct {
+	PlayerID uint64      `json:"player_id"`
+	Type     string      `json:"type"`
+	Data     interface{} `json:"data,omitempty"`
+}
+
+// Invariant 每步操作后检查的房间不变量，返回错误表示状态已被破坏
+type Invariant struct {
+	Name  string
+	Check func(room *gameplay.GameRoom) error
+}
+
+// Config 模拟配置。Script不为空时按脚本执行，否则用固定种子的随机数按模块的操作表生成Steps步操作
+type Config struct {
+	GameType    string
+	Players     int                    // 玩家数，玩家ID从1开始
+	Seed        int64                  // 随机种子，相同种子产生相同的操作序列
+	Steps       int                    // 随机操作的步数
+	Rules       map[string]interface{} // 房间自定义规则
+	Script      []Step
+	InvalidRate float64 // 随机操作中故意构造不合法载荷的比例，用于检查模块对错误输入的处理
+	Invariants  []Invariant
+}
+
+// Violation 一次不变量违规
+type Violation struct {
+	Step      int    `json:"step"`
+	Invariant string `json:"invariant"`
+	Error     string `json:"error"`
+	Action    *Step  `json:"action"`
+}
+
+// Result 模拟结果
+type Result struct {
+	GameType    string         `json:"game_type"`
+	Seed        int64          `json:"seed"`
+	Steps       int            `json:"steps"`
+	Accepted    int            `json:"accepted"`
+	Rejected    int            `json:"rejected"` // 被操作表或模块校验拒绝的操作
+	Actions     map[string]int `json:"actions"`  // 各操作类型被接受的次数
+	Violations  []*Violation   `json:"violations,omitempty"`
+	Dropped     int            `json:"dropped,omitempty"` // 超出记录上限未记录的违规数
+	Fingerprint string         `json:"fingerprint"`       // 最终房间状态的摘要，相同种子两次运行应一致
+}
+
+// Failed 是否出现了不变量违规
+func (r *Result) Failed() bool {
+	return len(r.Violations) > 0 || r.Dropped > 0
+}
+
+// Run 在不依赖网络和存储的玩法管理器中驱动玩法模块执行一次模拟，
+// 每步操作后检查配置的不变量和DefaultInvariants中该玩法的不变量。go test中可直接调用并断言Result.Failed
+func Run(module gameplay.GameplayModule, config *Config) (*Result, error) {
+	cfg := *config
+	if cfg.GameType == "" {
+		cfg.GameType = module.GetName()
+	}
+	if cfg.Players <= 0 {
+		cfg.Players = defaultPlayers
+	}
+	if cfg.Steps <= 0 {
+		cfg.Steps = defaultSteps
+	}
+	if len(cfg.Script) > 0 {
+		cfg.Steps = len(cfg.Script)
+	}
+	invariants := append(DefaultInvariants(cfg.GameType), cfg.Invariants...)
+
+	manager := gameplay.NewGameplayManager(&gameplay.GameplayConfig{}, nil)
+	if err := manager.RegisterModule(module); err != nil {
+		return nil, err
+	}
+	room, err := manager.CreateRoom(cfg.GameType, &gameplay.RoomConfig{
+		MaxPlayers:   cfg.Players,
+		MinPlayers:   cfg.Players,
+		CustomConfig: cfg.Rules,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer manager.Shutdown()
+
+	playerIDs := make([]uint64, 0, cfg.Players)
+	for i := 1; i <= cfg.Players; i++ {
+		player := &gameplay.Player{UserID: uint64(i), Nickname: fmt.Sprintf("sim_%d", i), JoinTime: epoch}
+		if err := manager.JoinRoom(room.ID, player); err != nil {
+			return nil, err
+		}
+		player.Status = gameplay.PlayerStatusPlaying
+		playerIDs = append(playerIDs, player.UserID)
+	}
+	room.SetState(gameplay.GameStateRunning)
+
+	generator := newGenerator(module.GetActionSchemas(), cfg.Seed, cfg.InvalidRate)
+	result := &Result{
+		GameType: cfg.GameType,
+		Seed:     cfg.Seed,
+		Actions:  make(map[string]int),
+	}
+
+	for i := 0; i < cfg.Steps; i++ {
+		var step Step
+		if len(cfg.Script) > 0 {
+			step = cfg.Script[i]
+		} else {
+			step = generator.next(playerIDs)
+		}
+		result.Steps++
+
+		action := &gameplay.GameAction{
+			Type:      step.Type,
+			PlayerID:  step.PlayerID,
+			Data:      step.Data,
+			Timestamp: epoch.Add(time.Duration(i) * simulatedTick),
+		}
+		if _, err := manager.ProcessAction(room.ID, action); err != nil {
+			result.Rejected++
+		} else {
+			result.Accepted++
+			result.Actions[step.Type]++
+		}
+
+		for _, invariant := range invariants {
+			if err := invariant.Check(room); err != nil {
+				result.addViolation(&Violation{Step: i, Invariant: invariant.Name, Error: err.Error(), Action: &step})
+			}
+		}
+		if room.GetState() == gameplay.GameStateEnded {
+			break
+		}
+	}
+
+	result.Fingerprint, err = fingerprint(module.GetRoomState(room))
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// addViolation 记录违规
+func (r *Result) addViolation(v *Violation) {
+	if len(r.Violations) >= maxViolations {
+		r.Dropped++
+		return
+	}
+	r.Violations = append(r.Violations, v)
+}
+
+// fingerprint 房间状态的摘要
+func fingerprint(state interface{}) (string, error) {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode room state: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// generator 按操作表生成随机操作
+type generator struct {
+	schemas gameplay.ActionSchemas
+	types   []string // 排序后的操作类型，保证相同种子的选择顺序一致
+	rng     *rand.Rand
+	invalid float64
+}
+
+// newGenerator 创建随机操作生成器
+func newGenerator(schemas gameplay.ActionSchemas, seed int64, invalidRate float64) *generator {
+	types := make([]string, 0, len(schemas))
+	for actionType := range schemas {
+		types = append(types, actionType)
+	}
+	sort.Strings(types)
+	return &generator{
+		schemas: schemas,
+		types:   types,
+		rng:     rand.New(rand.NewSource(seed)),
+		invalid: invalidRate,
+	}
+}
+
+// next 生成下一步操作
+func (g *generator) next(playerIDs []uint64) Step {
+	step := Step{PlayerID: playerIDs[g.rng.Intn(len(playerIDs))]}
+	if len(g.types) == 0 {
+		return step
+	}
+	step.Type = g.types[g.rng.Intn(len(g.types))]
+
+	schema := g.schemas[step.Type]
+	if schema.Message != nil {
+		// protobuf载荷只生成空消息
+		step.Data = []byte{}
+		return step
+	}
+	data := make(map[string]interface{}, len(schema.Fields))
+	for _, field := range schema.Fields {
+		if !field.Required && g.rng.Intn(2) == 0 {
+			continue
+		}
+		data[field.Name] = g.value(field)
+	}
+	if g.invalid > 0 && g.rng.Float64() < g.invalid {
+		g.corrupt(data, schema.Fields)
+	}
+	step.Data = data
+	return step
+}
+
+// value 生成字段的合法取值
+func (g *generator) value(field gameplay.ActionField) interface{} {
+	switch field.Type {
+	case gameplay.FieldTypeInt:
+		max := field.Max
+		if max == 0 {
+			max = field.Min + maxRandomInt
+		}
+		return field.Min + g.rng.Intn(max-field.Min+1)
+	case gameplay.FieldTypeNumber:
+		return g.rng.Float64() * maxRandomInt
+	case gameplay.FieldTypeBool:
+		return g.rng.Intn(2) == 0
+	case gameplay.FieldTypeString:
+		if len(field.Options) > 0 {
+			return field.Options[g.rng.Intn(len(field.Options))]
+		}
+		return g.string(field)
+	case gameplay.FieldTypeObject:
+		return map[string]interface{}{}
+	case gameplay.FieldTypeArray:
+		return make([]interface{}, field.Min)
+	}
+	return nil
+}
+
+// string 生成长度在字段限制内的字符串
+func (g *generator) string(field gameplay.ActionField) string {
+	max := field.Max
+	if max == 0 || max > maxStringLength {
+		max = maxStringLength
+	}
+	if max < field.Min {
+		max = field.Min
+	}
+	n := field.Min + g.rng.Intn(max-field.Min+1)
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte('a' + g.rng.Intn(26))
+	}
+	return string(b)
+}
+
+// corrupt 把载荷改为不合法：删除一个必填字段、写入类型错误的值或加入未声明的字段
+func (g *generator) corrupt(data map[string]interface{}, fields []gameplay.ActionField) {
+	if len(fields) == 0 || g.rng.Intn(3) == 0 {
+		data["undeclared"] = true
+		return
+	}
+	field := fields[g.rng.Intn(len(fields))]
+	if field.Required && g.rng.Intn(2) == 0 {
+		delete(data, field.Name)
+		return
+	}
+	if field.Type == gameplay.FieldTypeString {
+		data[field.Name] = g.rng.Intn(maxRandomInt)
+	} else {
+		data[field.Name] = "invalid"
+	}
+}
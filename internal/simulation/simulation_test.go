@@ -0,0 +1,93 @@
+package simulation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/phuhao00/lufy/internal/gameplay"
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+func init() {
+	logger.InitGlobalLogger(&logger.LogConfig{
+		Level:  "error",
+		Format: "console",
+		Output: "stderr",
+	})
+}
+
+// TestSimulation 按固定种子运行卡牌玩法，检查不变量不被破坏、每步操作都有结果且同一种子的结果一致
+func TestSimulation(t *testing.T) {
+	cases := []struct {
+		name    string
+		players int
+		invalid float64
+	}{
+		{"two_players", 2, 0},
+		{"four_players", 4, 0},
+		{"invalid_payloads", 4, 0.2},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &Config{
+				GameType:    "card_game",
+				Players:     tc.players,
+				Seed:        42,
+				Steps:       2000,
+				InvalidRate: tc.invalid,
+			}
+			result, err := Run(gameplay.NewCardGameModule(), config)
+			if err != nil {
+				t.Fatalf("simulation failed: %v", err)
+			}
+			for _, v := range result.Violations {
+				t.Errorf("step %d %s: %s (player %d %s %v)", v.Step, v.Invariant, v.Error, v.Action.PlayerID, v.Action.Type, v.Action.Data)
+			}
+			if result.Failed() {
+				t.Fatalf("%d invariant violations", len(result.Violations)+result.Dropped)
+			}
+			if result.Accepted+result.Rejected != result.Steps {
+				t.Errorf("accepted %d + rejected %d != steps %d", result.Accepted, result.Rejected, result.Steps)
+			}
+			if result.Accepted == 0 {
+				t.Errorf("no action accepted in %d steps", result.Steps)
+			}
+			if tc.invalid > 0 && result.Rejected == 0 {
+				t.Errorf("no action rejected with invalid rate %v", tc.invalid)
+			}
+
+			again, err := Run(gameplay.NewCardGameModule(), config)
+			if err != nil {
+				t.Fatalf("simulation failed: %v", err)
+			}
+			if again.Fingerprint != result.Fingerprint || again.Accepted != result.Accepted {
+				t.Errorf("seed %d is not deterministic: fingerprint %s != %s", config.Seed, again.Fingerprint, result.Fingerprint)
+			}
+		})
+	}
+}
+
+// TestSimulationReportsViolations 自定义不变量失败时记录违规，超出上限的只计数
+func TestSimulationReportsViolations(t *testing.T) {
+	result, err := Run(gameplay.NewCardGameModule(), &Config{
+		Seed:  7,
+		Steps: maxViolations + 10,
+		Invariants: []Invariant{{
+			Name:  "always_fails",
+			Check: func(room *gameplay.GameRoom) error { return errors.New("broken") },
+		}},
+	})
+	if err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+	if !result.Failed() {
+		t.Fatal("expected violations")
+	}
+	if len(result.Violations) != maxViolations {
+		t.Errorf("recorded %d violations, want %d", len(result.Violations), maxViolations)
+	}
+	if got := len(result.Violations) + result.Dropped; got != result.Steps {
+		t.Errorf("counted %d violations in %d steps", got, result.Steps)
+	}
+}
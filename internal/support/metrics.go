@@ -0,0 +1,91 @@
+package support
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metricsCacheTTL 工单指标的缓存时间，避免每次抓取都查询数据库
+const metricsCacheTTL = 30 * time.Second
+
+// Collector 工单指标，实现prometheus.Collector接口，抓取时按分类汇总全集群的工单
+type Collector struct {
+	service *Service
+
+	tickets    *prometheus.Desc
+	created    *prometheus.Desc
+	resolved   *prometheus.Desc
+	resolution *prometheus.Desc
+
+	mutex    sync.Mutex
+	stats    *Stats
+	loadedAt time.Time
+}
+
+// NewCollector 创建工单指标
+func NewCollector(service *Service) *Collector {
+	return &Collector{
+		service: service,
+		tickets: prometheus.NewDesc("lufy_support_tickets",
+			"Number of support tickets by category and status",
+			[]string{"category", "status"}, nil),
+		created: prometheus.NewDesc("lufy_support_tickets_created",
+			"Number of support tickets submitted within the metrics window",
+			[]string{"category"}, nil),
+		resolved: prometheus.NewDesc("lufy_support_tickets_resolved",
+			"Number of support tickets resolved within the metrics window",
+			[]string{"category"}, nil),
+		resolution: prometheus.NewDesc("lufy_support_resolution_seconds",
+			"Average time from submission to resolution of tickets resolved within the metrics window",
+			[]string{"category"}, nil),
+	}
+}
+
+// Describe 实现prometheus.Collector接口
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.tickets
+	ch <- c.created
+	ch <- c.resolved
+	ch <- c.resolution
+}
+
+// Collect 实现prometheus.Collector接口，查询失败时使用上次的结果
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.load(time.Now())
+	if stats == nil {
+		return
+	}
+
+	for _, count := range stats.Tickets {
+		ch <- prometheus.MustNewConstMetric(c.tickets, prometheus.GaugeValue, float64(count.Count), count.Category, count.Status)
+	}
+	for _, count := range stats.Created {
+		ch <- prometheus.MustNewConstMetric(c.created, prometheus.GaugeValue, float64(count.Count), count.Category)
+	}
+	for _, resolution := range stats.Resolutions {
+		ch <- prometheus.MustNewConstMetric(c.resolved, prometheus.GaugeValue, float64(resolution.Resolved), resolution.Category)
+		ch <- prometheus.MustNewConstMetric(c.resolution, prometheus.GaugeValue, resolution.AvgMs/1000, resolution.Category)
+	}
+}
+
+// load 获取缓存的统计结果，过期时重新查询
+func (c *Collector) load(now time.Time) *Stats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.stats != nil && now.Sub(c.loadedAt) < metricsCacheTTL {
+		return c.stats
+	}
+
+	stats, err := c.service.Stats(now)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Failed to collect support ticket metrics: %v", err))
+		return c.stats
+	}
+	c.stats, c.loadedAt = stats, now
+	return stats
+}
@@ -0,0 +1,319 @@
+package support
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// 工单操作的错误
+var (
+	ErrNotFound = errors.New("support ticket not found")
+	ErrState    = errors.New("support ticket state does not allow this operation")
+	ErrInvalid  = errors.New("invalid support ticket")
+	ErrTooMany  = errors.New("too many unresolved support tickets")
+)
+
+// 工单默认设置
+const (
+	defaultLength      = 2000
+	defaultAttachments = 5
+	defaultUnresolved  = 3
+	defaultExpire      = 30 * 24 * time.Hour
+	defaultWindow      = 24 * time.Hour
+	maxSubjectLength   = 100
+	maxAttachmentURL   = 512
+)
+
+// defaultCategories 未配置时可选的工单分类
+var defaultCategories = []string{"account", "payment", "bug", "report", "other"}
+
+// Config 客服工单设置
+type Config struct {
+	Categories  []string      `yaml:"categories"`  // 可选的工单分类，未配置时为account、payment、bug、report、other
+	Length      int           `yaml:"length"`      // 工单内容的最大字数，未配置时为2000
+	Attachments int           `yaml:"attachments"` // 最多附件数，未配置时为5
+	Unresolved  int           `yaml:"unresolved"`  // 每个玩家最多同时有几个未解决的工单，未配置时为3
+	Expire      time.Duration `yaml:"expire"`      // 回复邮件的有效期，未配置时为30天
+	Window      time.Duration `yaml:"window"`      // 统计平均处理时长的时间范围，未配置时为24小时
+	Metrics     int           `yaml:"metrics"`     // GM服导出工单指标的Prometheus端口，0为不导出
+}
+
+// withDefaults 补齐未配置的设置
+func (c Config) withDefaults() Config {
+	if len(c.Categories) == 0 {
+		c.Categories = defaultCategories
+	}
+	if c.Length <= 0 {
+		c.Length = defaultLength
+	}
+	if c.Attachments <= 0 {
+		c.Attachments = defaultAttachments
+	}
+	if c.Unresolved <= 0 {
+		c.Unresolved = defaultUnresolved
+	}
+	if c.Expire <= 0 {
+		c.Expire = defaultExpire
+	}
+	if c.Window <= 0 {
+		c.Window = defaultWindow
+	}
+	return c
+}
+
+// Store 工单存储，database.SupportTicketRepository实现了该接口
+type Store interface {
+	Create(ticket *database.SupportTicket) error
+	Get(ticketID string) (*database.SupportTicket, error)
+	List(filter database.TicketFilter, limit int64) ([]*database.SupportTicket, error)
+	CountUnresolved(userID uint64) (int64, error)
+	Assign(ticketID primitive.ObjectID, gmUserID uint64) (bool, error)
+	AddReply(ticketID primitive.ObjectID, reply *database.TicketReply, resolve bool) (bool, error)
+	Resolve(ticketID primitive.ObjectID) (bool, error)
+	Reopen(ticket *database.SupportTicket) (bool, error)
+	CountByStatus() ([]database.TicketCount, error)
+	CountCreated(since time.Time) ([]database.TicketCount, error)
+	Resolutions(since time.Time) ([]database.TicketResolution, error)
+}
+
+// NotifyFunc 通知玩家收到回复邮件
+type NotifyFunc func(userID, mailID uint64, title string)
+
+// Service 客服工单服务
+type Service struct {
+	config  Config
+	store   Store
+	mails   database.MailStore
+	mailIDs database.MailIDGenerator
+	notify  NotifyFunc
+}
+
+// NewService 创建客服工单服务，notify为空时不通知玩家
+func NewService(config *Config, store Store, mails database.MailStore, mailIDs database.MailIDGenerator, notify NotifyFunc) *Service {
+	return &Service{
+		config:  config.withDefaults(),
+		store:   store,
+		mails:   mails,
+		mailIDs: mailIDs,
+		notify:  notify,
+	}
+}
+
+// Categories 可选的工单分类
+func (s *Service) Categories() []string {
+	return s.config.Categories
+}
+
+// Submit 检查并创建玩家的工单
+func (s *Service) Submit(ticket *database.SupportTicket) error {
+	if !s.hasCategory(ticket.Category) {
+		return fmt.Errorf("%w: unknown category %s", ErrInvalid, ticket.Category)
+	}
+	ticket.Subject = strings.TrimSpace(ticket.Subject)
+	ticket.Text = strings.TrimSpace(ticket.Text)
+	if ticket.Text == "" {
+		return fmt.Errorf("%w: text is required", ErrInvalid)
+	}
+	if len([]rune(ticket.Text)) > s.config.Length {
+		return fmt.Errorf("%w: text exceeds %d characters", ErrInvalid, s.config.Length)
+	}
+	if len([]rune(ticket.Subject)) > maxSubjectLength {
+		return fmt.Errorf("%w: subject exceeds %d characters", ErrInvalid, maxSubjectLength)
+	}
+	if len(ticket.Attachments) > s.config.Attachments {
+		return fmt.Errorf("%w: at most %d attachments", ErrInvalid, s.config.Attachments)
+	}
+	for _, attachment := range ticket.Attachments {
+		if attachment == "" || len(attachment) > maxAttachmentURL {
+			return fmt.Errorf("%w: invalid attachment reference", ErrInvalid)
+		}
+	}
+
+	unresolved, err := s.store.CountUnresolved(ticket.UserID)
+	if err != nil {
+		return err
+	}
+	if unresolved >= int64(s.config.Unresolved) {
+		return ErrTooMany
+	}
+	return s.store.Create(ticket)
+}
+
+// hasCategory 检查分类是否可选
+func (s *Service) hasCategory(category string) bool {
+	for _, c := range s.config.Categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// Get 获取工单
+func (s *Service) Get(ticketID string) (*database.SupportTicket, error) {
+	ticket, err := s.store.Get(ticketID)
+	if err != nil {
+		return nil, err
+	}
+	if ticket == nil {
+		return nil, ErrNotFound
+	}
+	return ticket, nil
+}
+
+// List 按条件获取最近提交的工单
+func (s *Service) List(filter database.TicketFilter, limit int64) ([]*database.SupportTicket, error) {
+	switch filter.Status {
+	case "", database.TicketStatusOpen, database.TicketStatusAssigned, database.TicketStatusResolved:
+	default:
+		return nil, fmt.Errorf("%w: unknown status %s", ErrInvalid, filter.Status)
+	}
+	return s.store.List(filter, limit)
+}
+
+// Assign 把工单分配给GM，已分配的工单会转给新的GM
+func (s *Service) Assign(ticketID string, gmUserID uint64) error {
+	ticket, err := s.Get(ticketID)
+	if err != nil {
+		return err
+	}
+	ok, err := s.store.Assign(ticket.ID, gmUserID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrState
+	}
+	return nil
+}
+
+// Respond 把GM的回复作为邮件发给玩家并记录在工单中，未分配的工单分配给回复的GM，resolve时同时标记为已解决
+func (s *Service) Respond(ticketID string, gmUserID uint64, content string, resolve bool) (*database.TicketReply, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, fmt.Errorf("%w: reply is required", ErrInvalid)
+	}
+
+	ticket, err := s.Get(ticketID)
+	if err != nil {
+		return nil, err
+	}
+	if ticket.Status == database.TicketStatusResolved {
+		return nil, ErrState
+	}
+	if ticket.AssignedTo == 0 {
+		if _, err := s.store.Assign(ticket.ID, gmUserID); err != nil {
+			return nil, err
+		}
+	}
+
+	mailID, err := s.mailIDs()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	title := replyTitle(ticket)
+	if err := s.mails.CreateMail(&database.Mail{
+		MailID:   mailID,
+		ToUserID: ticket.UserID,
+		Title:    title,
+		Content:  content,
+		ExpireAt: now.Add(s.config.Expire),
+	}); err != nil {
+		return nil, fmt.Errorf("failed to send reply mail: %v", err)
+	}
+	if s.notify != nil {
+		s.notify(ticket.UserID, mailID, title)
+	}
+
+	reply := &database.TicketReply{
+		GMUserID:  gmUserID,
+		Content:   content,
+		MailID:    mailID,
+		CreatedAt: now,
+	}
+	ok, err := s.store.AddReply(ticket.ID, reply, resolve)
+	if err != nil {
+		return nil, fmt.Errorf("reply mail %d sent but not recorded: %v", mailID, err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("reply mail %d sent but ticket was resolved concurrently: %w", mailID, ErrState)
+	}
+	return reply, nil
+}
+
+// replyTitle 回复邮件的标题
+func replyTitle(ticket *database.SupportTicket) string {
+	if ticket.Subject != "" {
+		return "Re: " + ticket.Subject
+	}
+	return fmt.Sprintf("Re: [%s] #%s", ticket.Category, ticket.ID.Hex()[18:])
+}
+
+// Resolve 标记工单已解决
+func (s *Service) Resolve(ticketID string) error {
+	ticket, err := s.Get(ticketID)
+	if err != nil {
+		return err
+	}
+	ok, err := s.store.Resolve(ticket.ID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrState
+	}
+	return nil
+}
+
+// Reopen 重新打开已解决的工单
+func (s *Service) Reopen(ticketID string) error {
+	ticket, err := s.Get(ticketID)
+	if err != nil {
+		return err
+	}
+	ok, err := s.store.Reopen(ticket)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrState
+	}
+	return nil
+}
+
+// Stats 工单统计，Created和Resolutions为统计时间范围内的数据
+type Stats struct {
+	Window      string                      `json:"window"`
+	Tickets     []database.TicketCount      `json:"tickets"`
+	Created     []database.TicketCount      `json:"created"`
+	Resolutions []database.TicketResolution `json:"resolutions"`
+}
+
+// Stats 按分类统计工单数量、提交量和平均处理时长
+func (s *Service) Stats(now time.Time) (*Stats, error) {
+	since := now.Add(-s.config.Window)
+	tickets, err := s.store.CountByStatus()
+	if err != nil {
+		return nil, err
+	}
+	created, err := s.store.CountCreated(since)
+	if err != nil {
+		return nil, err
+	}
+	resolutions, err := s.store.Resolutions(since)
+	if err != nil {
+		return nil, err
+	}
+	return &Stats{
+		Window:      s.config.Window.String(),
+		Tickets:     tickets,
+		Created:     created,
+		Resolutions: resolutions,
+	}, nil
+}
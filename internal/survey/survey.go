@@ -0,0 +1,360 @@
+package survey
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/segment"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// 提交问卷的错误
+var (
+	ErrNotFound    = errors.New("survey not found")
+	ErrClosed      = errors.New("survey is not open")
+	ErrNotEligible = errors.New("survey is not available to user")
+	ErrAnswered    = errors.New("survey already answered")
+	ErrInvalid     = errors.New("invalid survey answer")
+)
+
+// 问卷默认设置
+const (
+	defaultDuration    = 7 * 24 * time.Hour
+	defaultExportDir   = "data/exports"
+	defaultRatingScale = 5
+	maxTextLength      = 1000
+)
+
+// Config 问卷设置
+type Config struct {
+	Duration time.Duration `yaml:"duration"` // 未指定结束时间时的开放时长，未配置时为7天
+	Exports  string        `yaml:"exports"`  // CSV导出目录，未配置时为data/exports
+}
+
+// Store 问卷存储，database.SurveyRepository实现了该接口
+type Store interface {
+	Create(survey *database.Survey) error
+	Get(surveyID string) (*database.Survey, error)
+	Active(now time.Time) ([]*database.Survey, error)
+	List(limit int64) ([]*database.Survey, error)
+	SaveResponse(response *database.SurveyResponse) (bool, error)
+	Answered(userID uint64, surveyIDs []primitive.ObjectID) (map[primitive.ObjectID]bool, error)
+	CountResponses(surveyID primitive.ObjectID) (int64, error)
+	ForEachResponse(surveyID primitive.ObjectID, fn func(response *database.SurveyResponse) error) error
+}
+
+// Segments 玩家分群，segment.Service实现了该接口
+type Segments interface {
+	Has(name string) bool
+	InSegment(userID uint64, name string) (bool, error)
+}
+
+// Summary 问卷和回答数
+type Summary struct {
+	*database.Survey
+	Responses int64 `json:"responses"`
+}
+
+// Service 问卷服务
+type Service struct {
+	config   Config
+	store    Store
+	segments Segments
+}
+
+// NewService 创建问卷服务
+func NewService(config *Config, store Store, segments Segments) *Service {
+	s := &Service{
+		config:   *config,
+		store:    store,
+		segments: segments,
+	}
+	if s.config.Duration <= 0 {
+		s.config.Duration = defaultDuration
+	}
+	if s.config.Exports == "" {
+		s.config.Exports = defaultExportDir
+	}
+	return s
+}
+
+// Create 检查并创建问卷，未指定分群时面向所有玩家，未指定开始时间时立即开始
+func (s *Service) Create(survey *database.Survey) error {
+	if survey.Title == "" {
+		return fmt.Errorf("survey title is required")
+	}
+	if survey.Segment == "" {
+		survey.Segment = segment.SegmentAll
+	}
+	if !s.segments.Has(survey.Segment) {
+		return fmt.Errorf("unknown segment: %s", survey.Segment)
+	}
+	if len(survey.Questions) == 0 {
+		return fmt.Errorf("survey requires at least one question")
+	}
+
+	ids := make(map[string]bool, len(survey.Questions))
+	for i := range survey.Questions {
+		question := &survey.Questions[i]
+		if question.ID == "" {
+			question.ID = strconv.Itoa(i + 1)
+		}
+		if ids[question.ID] {
+			return fmt.Errorf("duplicate question id: %s", question.ID)
+		}
+		ids[question.ID] = true
+
+		if question.Text == "" {
+			return fmt.Errorf("question %s text is required", question.ID)
+		}
+		switch question.Type {
+		case database.SurveyQuestionSingle, database.SurveyQuestionMultiple:
+			if len(question.Options) < 2 {
+				return fmt.Errorf("question %s requires at least 2 options", question.ID)
+			}
+		case database.SurveyQuestionRating, database.SurveyQuestionText:
+		default:
+			return fmt.Errorf("question %s has unknown type: %s", question.ID, question.Type)
+		}
+	}
+
+	if survey.StartAt.IsZero() {
+		survey.StartAt = time.Now()
+	}
+	if survey.EndAt.IsZero() {
+		survey.EndAt = survey.StartAt.Add(s.config.Duration)
+	}
+	if !survey.EndAt.After(survey.StartAt) {
+		return fmt.Errorf("survey ends before it starts")
+	}
+	return s.store.Create(survey)
+}
+
+// Available 获取玩家可以回答的问卷，不包括已经回答过和不在目标分群内的问卷
+func (s *Service) Available(userID uint64, now time.Time) ([]*database.Survey, error) {
+	active, err := s.store.Active(now)
+	if err != nil {
+		return nil, err
+	}
+
+	var eligible []*database.Survey
+	var ids []primitive.ObjectID
+	for _, survey := range active {
+		member, err := s.segments.InSegment(userID, survey.Segment)
+		if err != nil || !member {
+			continue // 分群已从配置中删除的问卷不再展示
+		}
+		eligible = append(eligible, survey)
+		ids = append(ids, survey.ID)
+	}
+
+	answered, err := s.store.Answered(userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	var available []*database.Survey
+	for _, survey := range eligible {
+		if !answered[survey.ID] {
+			available = append(available, survey)
+		}
+	}
+	return available, nil
+}
+
+// Submit 检查并保存玩家的回答，每个玩家对同一问卷只能提交一次
+func (s *Service) Submit(userID uint64, surveyID string, answers map[string][]string, now time.Time) error {
+	survey, err := s.store.Get(surveyID)
+	if err != nil {
+		return err
+	}
+	if survey == nil {
+		return ErrNotFound
+	}
+	if now.Before(survey.StartAt) || !now.Before(survey.EndAt) {
+		return ErrClosed
+	}
+	member, err := s.segments.InSegment(userID, survey.Segment)
+	if err != nil || !member {
+		return ErrNotEligible
+	}
+
+	if err := validateAnswers(survey, answers); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	saved, err := s.store.SaveResponse(&database.SurveyResponse{
+		SurveyID: survey.ID,
+		UserID:   userID,
+		Answers:  answers,
+	})
+	if err != nil {
+		return err
+	}
+	if !saved {
+		return ErrAnswered
+	}
+	return nil
+}
+
+// validateAnswers 检查回答是否符合题目要求，去掉未回答的题目
+func validateAnswers(survey *database.Survey, answers map[string][]string) error {
+	questions := make(map[string]*database.SurveyQuestion, len(survey.Questions))
+	for i := range survey.Questions {
+		questions[survey.Questions[i].ID] = &survey.Questions[i]
+	}
+	for id := range answers {
+		if _, exists := questions[id]; !exists {
+			return fmt.Errorf("unknown question: %s", id)
+		}
+	}
+
+	for id, question := range questions {
+		values := answers[id]
+		if len(values) == 1 && strings.TrimSpace(values[0]) == "" {
+			values = nil
+		}
+		if len(values) == 0 {
+			if question.Required {
+				return fmt.Errorf("question %s is required", id)
+			}
+			delete(answers, id)
+			continue
+		}
+		if question.Type != database.SurveyQuestionMultiple && len(values) > 1 {
+			return fmt.Errorf("question %s accepts a single answer", id)
+		}
+
+		switch question.Type {
+		case database.SurveyQuestionSingle, database.SurveyQuestionMultiple:
+			seen := make(map[string]bool, len(values))
+			for _, value := range values {
+				if !hasOption(question, value) {
+					return fmt.Errorf("question %s has no option %s", id, value)
+				}
+				if seen[value] {
+					return fmt.Errorf("question %s has duplicate option %s", id, value)
+				}
+				seen[value] = true
+			}
+		case database.SurveyQuestionRating:
+			scale := len(question.Options)
+			if scale == 0 {
+				scale = defaultRatingScale
+			}
+			rating, err := strconv.Atoi(values[0])
+			if err != nil || rating < 1 || rating > scale {
+				return fmt.Errorf("question %s rating must be between 1 and %d", id, scale)
+			}
+		case database.SurveyQuestionText:
+			if len([]rune(values[0])) > maxTextLength {
+				return fmt.Errorf("question %s answer exceeds %d characters", id, maxTextLength)
+			}
+		}
+	}
+	return nil
+}
+
+// hasOption 检查选项是否属于题目
+func hasOption(question *database.SurveyQuestion, value string) bool {
+	for _, option := range question.Options {
+		if option == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Get 获取问卷和回答数
+func (s *Service) Get(surveyID string) (*Summary, error) {
+	survey, err := s.store.Get(surveyID)
+	if err != nil {
+		return nil, err
+	}
+	if survey == nil {
+		return nil, ErrNotFound
+	}
+	return s.summarize(survey)
+}
+
+// List 获取最近创建的问卷和回答数
+func (s *Service) List(limit int64) ([]*Summary, error) {
+	surveys, err := s.store.List(limit)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]*Summary, 0, len(surveys))
+	for _, survey := range surveys {
+		summary, err := s.summarize(survey)
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+// summarize 统计问卷的回答数
+func (s *Service) summarize(survey *database.Survey) (*Summary, error) {
+	count, err := s.store.CountResponses(survey.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &Summary{Survey: survey, Responses: count}, nil
+}
+
+// Export 把问卷的所有回答导出为CSV，每个玩家一行，每道题一列，多选的答案用|分隔，返回文件路径和回答数
+func (s *Service) Export(surveyID string) (string, int, error) {
+	survey, err := s.store.Get(surveyID)
+	if err != nil {
+		return "", 0, err
+	}
+	if survey == nil {
+		return "", 0, ErrNotFound
+	}
+
+	if err := os.MkdirAll(s.config.Exports, 0700); err != nil {
+		return "", 0, fmt.Errorf("failed to create export dir: %v", err)
+	}
+	path := filepath.Join(s.config.Exports, fmt.Sprintf("survey_%s_%d.csv", surveyID, time.Now().Unix()))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create export file: %v", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	header := []string{"user_id", "submitted_at"}
+	for _, question := range survey.Questions {
+		header = append(header, fmt.Sprintf("%s. %s", question.ID, question.Text))
+	}
+	if err := writer.Write(header); err != nil {
+		return "", 0, fmt.Errorf("failed to write export file: %v", err)
+	}
+
+	count := 0
+	err = s.store.ForEachResponse(survey.ID, func(response *database.SurveyResponse) error {
+		row := []string{strconv.FormatUint(response.UserID, 10), response.CreatedAt.UTC().Format(time.RFC3339)}
+		for _, question := range survey.Questions {
+			row = append(row, strings.Join(response.Answers[question.ID], "|"))
+		}
+		count++
+		return writer.Write(row)
+	})
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to export survey responses: %v", err)
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return "", 0, fmt.Errorf("failed to write export file: %v", err)
+	}
+	return path, count, nil
+}
@@ -0,0 +1,252 @@
+package timeline
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// 默认配置
+const (
+	defaultRetention = 30 * 24 * time.Hour
+	defaultDays      = 7
+)
+
+// 单次查询读取的记录上限，超出时只保留最近的记录
+const (
+	maxEvents        = 5000
+	maxLedgerEntries = 1000
+)
+
+// kindCurrency 时间线中的大额货币变动，查询时从账本读取，不单独记录
+const kindCurrency = "currency"
+
+// dayLayout 时间线按UTC日期分组
+const dayLayout = "2006-01-02"
+
+// Config 玩家活动时间线配置
+type Config struct {
+	Enabled           bool          `yaml:"enabled"`            // 记录玩家活动，关闭后仍可查询已记录的时间线
+	Retention         time.Duration `yaml:"retention"`          // 活动记录的保留时间，未配置时为30天
+	CurrencyThreshold int64         `yaml:"currency_threshold"` // 时间线展示的货币变动金额下限，0为不展示货币变动
+}
+
+// Validate 检查时间线配置
+func (c *Config) Validate() error {
+	if c.Retention < 0 {
+		return fmt.Errorf("timeline retention must not be negative")
+	}
+	if c.CurrencyThreshold < 0 {
+		return fmt.Errorf("timeline currency threshold must not be negative")
+	}
+	return nil
+}
+
+// retention 活动记录的保留时间
+func (c *Config) retention() time.Duration {
+	if c.Retention > 0 {
+		return c.Retention
+	}
+	return defaultRetention
+}
+
+// Store 活动记录存储，database.ActivityRepository实现了该接口
+type Store interface {
+	Insert(event *database.ActivityEvent) error
+	IncrementDaily(userID uint64, kind, day, detail string, delta int64, expireAt time.Time) error
+	GetSince(userID uint64, since time.Time, limit int64) ([]*database.ActivityEvent, error)
+}
+
+// Ledger 货币账本，database.LedgerRepository实现了该接口
+type Ledger interface {
+	GetLargeByUser(userID uint64, since time.Time, minAmount, limit int64) ([]*database.LedgerEntry, error)
+}
+
+// Recorder 记录玩家的登录、进入房间和聊天，供GM调查作弊和欺诈时查询。记录失败只打印日志，不影响玩家操作
+type Recorder struct {
+	config Config
+	store  Store
+	ledger Ledger
+}
+
+// NewRecorder 创建玩家活动时间线
+func NewRecorder(config *Config, store Store, ledger Ledger) *Recorder {
+	return &Recorder{
+		config: *config,
+		store:  store,
+		ledger: ledger,
+	}
+}
+
+// Login 记录登录的IP和设备
+func (r *Recorder) Login(userID uint64, ip, deviceID, platform string) {
+	r.insert(&database.ActivityEvent{
+		UserID:   userID,
+		Kind:     database.ActivityLogin,
+		IP:       ip,
+		DeviceID: deviceID,
+		Platform: platform,
+	})
+}
+
+// RoomJoined 记录进入房间，source为创建、加入或匹配等进入方式
+func (r *Recorder) RoomJoined(userID, roomID uint64, source string) {
+	r.insert(&database.ActivityEvent{
+		UserID: userID,
+		Kind:   database.ActivityRoomJoin,
+		RoomID: roomID,
+		Detail: source,
+	})
+}
+
+// Chat 累加玩家当天在频道中发送的聊天消息数
+func (r *Recorder) Chat(userID uint64, channel string) {
+	if !r.config.Enabled {
+		return
+	}
+	now := time.Now()
+	day := now.UTC().Format(dayLayout)
+	if err := r.store.IncrementDaily(userID, database.ActivityChat, day, channel, 1, now.Add(r.config.retention())); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to record chat activity of user %d: %v", userID, err))
+	}
+}
+
+// insert 写入活动事件
+func (r *Recorder) insert(event *database.ActivityEvent) {
+	if !r.config.Enabled {
+		return
+	}
+	event.CreatedAt = time.Now()
+	event.ExpireAt = event.CreatedAt.Add(r.config.retention())
+	if err := r.store.Insert(event); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to record %s activity of user %d: %v", event.Kind, event.UserID, err))
+	}
+}
+
+// Entry 时间线中的一条记录
+type Entry struct {
+	Time         time.Time `json:"time"`
+	Kind         string    `json:"kind"` // login, room_join, currency
+	IP           string    `json:"ip,omitempty"`
+	DeviceID     string    `json:"device_id,omitempty"`
+	Platform     string    `json:"platform,omitempty"`
+	RoomID       uint64    `json:"room_id,omitempty"`
+	Detail       string    `json:"detail,omitempty"` // 进入房间的方式或货币变动原因
+	Currency     string    `json:"currency,omitempty"`
+	Amount       int64     `json:"amount,omitempty"`
+	Counterparty uint64    `json:"counterparty,omitempty"`
+}
+
+// Day 一天的活动汇总和明细
+type Day struct {
+	Date         string           `json:"date"`
+	Logins       int              `json:"logins"`
+	RoomsJoined  int              `json:"rooms_joined"`
+	ChatMessages map[string]int64 `json:"chat_messages,omitempty"` // 各频道的聊天消息数
+	Entries      []*Entry         `json:"entries"`                 // 按时间倒序
+}
+
+// Report 玩家最近若干天的活动时间线
+type Report struct {
+	UserID  uint64         `json:"user_id"`
+	Since   time.Time      `json:"since"`
+	IPs     map[string]int `json:"ips"`     // 各IP的登录次数
+	Devices map[string]int `json:"devices"` // 各设备的登录次数
+	Days    []*Day         `json:"days"`    // 按日期倒序，没有活动的日期不列出
+}
+
+// Report 查询玩家最近days天的活动时间线，days不大于0时为7天，最多回看保留时间
+func (r *Recorder) Report(userID uint64, days int) (*Report, error) {
+	if days <= 0 {
+		days = defaultDays
+	}
+	if limit := int(r.config.retention() / (24 * time.Hour)); limit > 0 && days > limit {
+		days = limit
+	}
+	today, _ := time.Parse(dayLayout, time.Now().UTC().Format(dayLayout))
+	since := today.AddDate(0, 0, 1-days)
+
+	events, err := r.store.GetSince(userID, since, maxEvents)
+	if err != nil {
+		return nil, err
+	}
+	var ledger []*database.LedgerEntry
+	if r.config.CurrencyThreshold > 0 {
+		if ledger, err = r.ledger.GetLargeByUser(userID, since, r.config.CurrencyThreshold, maxLedgerEntries); err != nil {
+			return nil, err
+		}
+	}
+
+	report := &Report{
+		UserID:  userID,
+		Since:   since,
+		IPs:     make(map[string]int),
+		Devices: make(map[string]int),
+	}
+	byDate := make(map[string]*Day)
+	day := func(date string) *Day {
+		if d, exists := byDate[date]; exists {
+			return d
+		}
+		d := &Day{Date: date, ChatMessages: make(map[string]int64)}
+		byDate[date] = d
+		return d
+	}
+
+	for _, event := range events {
+		if event.Kind == database.ActivityChat {
+			day(event.Day).ChatMessages[event.Detail] += event.Count
+			continue
+		}
+
+		d := day(event.CreatedAt.UTC().Format(dayLayout))
+		switch event.Kind {
+		case database.ActivityLogin:
+			d.Logins++
+			if event.IP != "" {
+				report.IPs[event.IP]++
+			}
+			if event.DeviceID != "" {
+				report.Devices[event.DeviceID]++
+			}
+		case database.ActivityRoomJoin:
+			d.RoomsJoined++
+		}
+		d.Entries = append(d.Entries, &Entry{
+			Time:     event.CreatedAt,
+			Kind:     event.Kind,
+			IP:       event.IP,
+			DeviceID: event.DeviceID,
+			Platform: event.Platform,
+			RoomID:   event.RoomID,
+			Detail:   event.Detail,
+		})
+	}
+	for _, entry := range ledger {
+		d := day(entry.CreatedAt.UTC().Format(dayLayout))
+		d.Entries = append(d.Entries, &Entry{
+			Time:         entry.CreatedAt,
+			Kind:         kindCurrency,
+			Detail:       entry.Reason,
+			Currency:     entry.Currency,
+			Amount:       entry.Amount,
+			Counterparty: entry.Counterparty,
+		})
+	}
+
+	for date := today; !date.Before(since); date = date.AddDate(0, 0, -1) {
+		d, exists := byDate[date.Format(dayLayout)]
+		if !exists {
+			continue
+		}
+		entries := d.Entries
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Time.After(entries[j].Time)
+		})
+		report.Days = append(report.Days, d)
+	}
+	return report, nil
+}
@@ -0,0 +1,500 @@
+package transfer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/world"
+)
+
+// 冲突类型
+const (
+	ConflictNickname = "nickname" // 目标世界已有同名玩家
+	ConflictFriend   = "friend"   // 好友关系跨世界且不允许跨世界好友
+	ConflictOnline   = "online"   // 玩家在线
+	ConflictRoom     = "room"     // 玩家在未结束的房间中
+)
+
+// 冲突处理方式
+const (
+	ResolutionRenamed = "renamed" // 在目标世界使用新昵称
+	ResolutionDropped = "dropped" // 删除关系
+	ResolutionBlocked = "blocked" // 无法自动处理，需要先解决后再迁移
+)
+
+// maxNicknameAttempts 生成不冲突昵称的最大尝试次数，超过后使用用户ID作为后缀
+const maxNicknameAttempts = 100
+
+// Config 跨世界迁移配置
+type Config struct {
+	ArchiveDir string        `yaml:"archive_dir"` // 迁移前导出的数据归档目录，用于人工恢复
+	LockTTL    time.Duration `yaml:"lock_ttl"`    // 迁移锁的最长持有时间，迁移期间玩家无法登录
+}
+
+// Conflict 迁移冲突及处理方式
+type Conflict struct {
+	Type       string `json:"type"`
+	Detail     string `json:"detail"`
+	Resolution string `json:"resolution"`
+}
+
+// Archive 迁移前导出的玩家数据，按数据库中的原样保存
+type Archive struct {
+	User       *database.User          `json:"user"`
+	Friends    []*database.Friend      `json:"friends"`
+	Mails      []*database.Mail        `json:"mails"`
+	Ledger     []*database.LedgerEntry `json:"ledger"`
+	FromWorld  uint32                  `json:"from_world"`
+	ToWorld    uint32                  `json:"to_world"`
+	ExportedAt time.Time               `json:"exported_at"`
+}
+
+// Report 迁移报告
+type Report struct {
+	UserID      uint64           `json:"user_id"`
+	FromWorld   uint32           `json:"from_world"`
+	ToWorld     uint32           `json:"to_world"`
+	DryRun      bool             `json:"dry_run"`
+	Nickname    string           `json:"nickname"`               // 目标世界中的昵称
+	Conflicts   []Conflict       `json:"conflicts,omitempty"`    // 发现的冲突
+	Carried     map[string]int64 `json:"carried"`                // 随玩家迁移的数据
+	Applied     map[string]int64 `json:"applied,omitempty"`      // 各步骤修改的文档数
+	ArchivePath string           `json:"archive_path,omitempty"` // 迁移前的数据归档
+	RolledBack  bool             `json:"rolled_back,omitempty"`  // 迁移失败并已撤销已执行的步骤
+}
+
+// Blocked 是否存在无法自动处理的冲突
+func (r *Report) Blocked() bool {
+	for _, conflict := range r.Conflicts {
+		if conflict.Resolution == ResolutionBlocked {
+			return true
+		}
+	}
+	return false
+}
+
+// plan 迁移计划
+type plan struct {
+	archive      *Archive
+	nickname     string
+	droppedLinks []*database.Friend
+}
+
+// step 迁移步骤，失败时按相反顺序撤销已执行的步骤
+type step struct {
+	name string
+	run  func(ctx context.Context) (int64, error)
+	undo func(ctx context.Context) error
+}
+
+// Transfer 跨世界迁移工具，把玩家的资料、背包货币、邮件和好友迁移到另一个世界
+// 用户ID和邮件ID在集群内全局唯一，迁移后保持不变；与目标世界冲突的昵称重新分配，
+// 不允许跨世界的好友关系被删除。迁移期间持有玩家的迁移锁，登录服务拒绝该玩家登录
+type Transfer struct {
+	config    Config
+	worlds    *world.Config
+	mongo     *database.MongoManager
+	redis     *database.RedisManager
+	userCache *database.UserCache
+}
+
+// NewTransfer 创建跨世界迁移工具
+func NewTransfer(config *Config, worlds *world.Config, mm *database.MongoManager, rm *database.RedisManager) *Transfer {
+	cfg := *config
+	if cfg.ArchiveDir == "" {
+		cfg.ArchiveDir = "data/world_transfers"
+	}
+	if cfg.LockTTL <= 0 {
+		cfg.LockTTL = 10 * time.Minute
+	}
+
+	return &Transfer{
+		config:    cfg,
+		worlds:    worlds,
+		mongo:     mm,
+		redis:     rm,
+		userCache: database.NewUserCache(rm),
+	}
+}
+
+// LockKey 玩家迁移锁的键
+func LockKey(userID uint64) string {
+	return fmt.Sprintf("world_transfer:%d", userID)
+}
+
+// InProgress 检查玩家是否正在迁移
+func InProgress(rm *database.RedisManager, userID uint64) (bool, error) {
+	return rm.IsLocked(LockKey(userID))
+}
+
+// Run 把玩家迁移到目标世界，dryRun时只导出计划和冲突不修改数据
+// 存在需要人工处理的冲突时不执行迁移；执行中任一步骤失败会撤销已执行的步骤
+func (t *Transfer) Run(userID uint64, toWorld uint32, dryRun bool) (*Report, error) {
+	if toWorld == 0 {
+		return nil, fmt.Errorf("target world is required")
+	}
+	if err := t.worlds.ValidateRegistration(toWorld); err != nil {
+		return nil, err
+	}
+
+	if !dryRun {
+		locked, err := t.redis.Lock(LockKey(userID), t.config.LockTTL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire transfer lock: %v", err)
+		}
+		if !locked {
+			return nil, fmt.Errorf("user %d is already being transferred", userID)
+		}
+		defer t.redis.Unlock(LockKey(userID))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), t.config.LockTTL)
+	defer cancel()
+
+	p, report, err := t.plan(ctx, userID, toWorld)
+	if err != nil {
+		return nil, err
+	}
+	report.DryRun = dryRun
+	if dryRun || report.Blocked() {
+		return report, nil
+	}
+
+	archivePath, err := t.writeArchive(p.archive)
+	if err != nil {
+		return report, err
+	}
+	report.ArchivePath = archivePath
+
+	if err := t.apply(ctx, p, report); err != nil {
+		return report, err
+	}
+
+	if t.userCache != nil {
+		t.userCache.DeleteUserInfo(userID)
+	}
+
+	logger.Info(fmt.Sprintf("Transferred user %d from world %d to world %d: nickname %s, applied %v, archive %s",
+		userID, report.FromWorld, report.ToWorld, report.Nickname, report.Applied, archivePath))
+	return report, nil
+}
+
+// plan 导出玩家数据并解决冲突
+func (t *Transfer) plan(ctx context.Context, userID uint64, toWorld uint32) (*plan, *Report, error) {
+	archive, err := t.export(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fromWorld := t.worlds.Resolve(archive.User.WorldID)
+	if fromWorld == toWorld {
+		return nil, nil, fmt.Errorf("user %d is already in world %d", userID, toWorld)
+	}
+	archive.FromWorld = fromWorld
+	archive.ToWorld = toWorld
+
+	p := &plan{archive: archive}
+	report := &Report{
+		UserID:    userID,
+		FromWorld: fromWorld,
+		ToWorld:   toWorld,
+		Carried: map[string]int64{
+			"mails":   int64(len(archive.Mails)),
+			"ledger":  int64(len(archive.Ledger)),
+			"gold":    archive.User.Gold,
+			"diamond": archive.User.Diamond,
+		},
+	}
+
+	// 在线玩家的网关连接仍属于原世界，需要先踢下线
+	if nodeID, err := t.userCache.GetUserOnline(userID); err == nil && nodeID != "" {
+		report.Conflicts = append(report.Conflicts, Conflict{
+			Type:       ConflictOnline,
+			Detail:     fmt.Sprintf("online on %s", nodeID),
+			Resolution: ResolutionBlocked,
+		})
+	}
+
+	// 房间只属于一个世界，等待中或游戏中的房间需要先结束或离开
+	rooms, err := t.mongo.GetCollection("rooms").CountDocuments(ctx, bson.M{
+		"players.user_id": userID,
+		"status":          bson.M{"$ne": 2},
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query rooms: %v", err)
+	}
+	if rooms > 0 {
+		report.Conflicts = append(report.Conflicts, Conflict{
+			Type:       ConflictRoom,
+			Detail:     fmt.Sprintf("in %d open rooms", rooms),
+			Resolution: ResolutionBlocked,
+		})
+	}
+
+	nickname, err := t.resolveNickname(ctx, archive.User, toWorld)
+	if err != nil {
+		return nil, nil, err
+	}
+	p.nickname = nickname
+	report.Nickname = nickname
+	if nickname != archive.User.Nickname {
+		report.Conflicts = append(report.Conflicts, Conflict{
+			Type:       ConflictNickname,
+			Detail:     fmt.Sprintf("%s -> %s", archive.User.Nickname, nickname),
+			Resolution: ResolutionRenamed,
+		})
+	}
+
+	kept, dropped, err := t.resolveFriends(ctx, userID, archive.Friends, toWorld)
+	if err != nil {
+		return nil, nil, err
+	}
+	p.droppedLinks = dropped
+	report.Carried["friends"] = kept
+	for _, link := range dropped {
+		report.Conflicts = append(report.Conflicts, Conflict{
+			Type:       ConflictFriend,
+			Detail:     fmt.Sprintf("%d -> %d", link.UserID, link.FriendID),
+			Resolution: ResolutionDropped,
+		})
+	}
+
+	return p, report, nil
+}
+
+// export 读取玩家在各集合中需要迁移的文档，敏感字段保持加密存储的原样
+func (t *Transfer) export(ctx context.Context, userID uint64) (*Archive, error) {
+	archive := &Archive{
+		User:       &database.User{},
+		ExportedAt: time.Now(),
+	}
+
+	err := t.mongo.GetCollection("users").FindOne(ctx, bson.M{"user_id": userID}).Decode(archive.User)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user: %v", err)
+	}
+
+	queries := []struct {
+		collection string
+		filter     bson.M
+		dest       interface{}
+	}{
+		{"friends", bson.M{"$or": []bson.M{{"user_id": userID}, {"friend_id": userID}}}, &archive.Friends},
+		{"mails", bson.M{"to_user_id": userID}, &archive.Mails},
+		{"ledger", bson.M{"user_id": userID}, &archive.Ledger},
+	}
+
+	for _, q := range queries {
+		cursor, err := t.mongo.GetCollection(q.collection).Find(ctx, q.filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query %s: %v", q.collection, err)
+		}
+		err = cursor.All(ctx, q.dest)
+		cursor.Close(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %v", q.collection, err)
+		}
+	}
+
+	return archive, nil
+}
+
+// resolveNickname 目标世界已有同名玩家时依次尝试添加数字后缀
+func (t *Transfer) resolveNickname(ctx context.Context, user *database.User, toWorld uint32) (string, error) {
+	users := t.mongo.GetCollection("users")
+	candidate := user.Nickname
+	for attempt := 2; attempt <= maxNicknameAttempts+1; attempt++ {
+		filter := t.worldFilter(toWorld)
+		filter["nickname"] = candidate
+		filter["user_id"] = bson.M{"$ne": user.UserID}
+
+		count, err := users.CountDocuments(ctx, filter)
+		if err != nil {
+			return "", fmt.Errorf("failed to check nickname: %v", err)
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s_%d", user.Nickname, attempt)
+	}
+	return fmt.Sprintf("%s_%d", user.Nickname, user.UserID), nil
+}
+
+// resolveFriends 按目标世界检查好友关系，返回保留的好友数和需要删除的关系
+func (t *Transfer) resolveFriends(ctx context.Context, userID uint64, links []*database.Friend, toWorld uint32) (int64, []*database.Friend, error) {
+	others := make([]uint64, 0, len(links))
+	for _, link := range links {
+		others = append(others, otherParty(link, userID))
+	}
+
+	worlds := make(map[uint64]uint32, len(others))
+	if len(others) > 0 {
+		opts := options.Find().SetProjection(bson.M{"user_id": 1, "world_id": 1})
+		cursor, err := t.mongo.GetCollection("users").Find(ctx, bson.M{"user_id": bson.M{"$in": others}}, opts)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to query friends: %v", err)
+		}
+		var users []*database.User
+		err = cursor.All(ctx, &users)
+		cursor.Close(ctx)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to decode friends: %v", err)
+		}
+		for _, user := range users {
+			worlds[user.UserID] = t.worlds.Resolve(user.WorldID)
+		}
+	}
+
+	kept := make(map[uint64]bool)
+	var dropped []*database.Friend
+	for _, link := range links {
+		other := otherParty(link, userID)
+		otherWorld, exists := worlds[other]
+		if exists && t.worlds.Allows(world.ActionFriend, toWorld, otherWorld) {
+			if link.Status == 1 {
+				kept[other] = true
+			}
+			continue
+		}
+		dropped = append(dropped, link)
+	}
+	return int64(len(kept)), dropped, nil
+}
+
+// apply 依次执行迁移步骤，失败时撤销已执行的步骤
+func (t *Transfer) apply(ctx context.Context, p *plan, report *Report) error {
+	userID := p.archive.User.UserID
+	users := t.mongo.GetCollection("users")
+	friends := t.mongo.GetCollection("friends")
+
+	steps := []step{
+		{
+			name: "friends",
+			run: func(ctx context.Context) (int64, error) {
+				if len(p.droppedLinks) == 0 {
+					return 0, nil
+				}
+				ids := make([]interface{}, 0, len(p.droppedLinks))
+				for _, link := range p.droppedLinks {
+					ids = append(ids, link.ID)
+				}
+				result, err := friends.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+				if err != nil {
+					return 0, err
+				}
+				return result.DeletedCount, nil
+			},
+			undo: func(ctx context.Context) error {
+				if len(p.droppedLinks) == 0 {
+					return nil
+				}
+				docs := make([]interface{}, 0, len(p.droppedLinks))
+				for _, link := range p.droppedLinks {
+					docs = append(docs, link)
+				}
+				_, err := friends.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+				if mongo.IsDuplicateKeyError(err) {
+					return nil
+				}
+				return err
+			},
+		},
+		{
+			name: "users",
+			run: func(ctx context.Context) (int64, error) {
+				// 只在玩家仍属于原世界时更新，避免覆盖并发的修改
+				filter := t.worldFilter(report.FromWorld)
+				filter["user_id"] = userID
+				result, err := users.UpdateOne(ctx, filter, bson.M{"$set": bson.M{
+					"world_id":   report.ToWorld,
+					"nickname":   p.nickname,
+					"updated_at": time.Now(),
+				}})
+				if err != nil {
+					return 0, err
+				}
+				if result.MatchedCount == 0 {
+					return 0, fmt.Errorf("user %d is no longer in world %d", userID, report.FromWorld)
+				}
+				return result.ModifiedCount, nil
+			},
+			undo: func(ctx context.Context) error {
+				_, err := users.UpdateOne(ctx, bson.M{"user_id": userID, "world_id": report.ToWorld}, bson.M{"$set": bson.M{
+					"world_id":   p.archive.User.WorldID,
+					"nickname":   p.archive.User.Nickname,
+					"updated_at": time.Now(),
+				}})
+				return err
+			},
+		},
+	}
+
+	report.Applied = make(map[string]int64, len(steps))
+	for i, s := range steps {
+		count, err := s.run(ctx)
+		if err == nil {
+			report.Applied[s.name] = count
+			continue
+		}
+
+		err = fmt.Errorf("failed to transfer %s: %v", s.name, err)
+		for j := i - 1; j >= 0; j-- {
+			if undoErr := steps[j].undo(ctx); undoErr != nil {
+				logger.Error(fmt.Sprintf("Failed to undo %s for user %d, restore from %s: %v",
+					steps[j].name, userID, report.ArchivePath, undoErr))
+				return fmt.Errorf("%v (undo of %s failed, restore from %s)", err, steps[j].name, report.ArchivePath)
+			}
+		}
+		report.RolledBack = true
+		return err
+	}
+	return nil
+}
+
+// worldFilter 属于世界的文档，默认世界包括划分世界之前未设置world_id的文档
+func (t *Transfer) worldFilter(worldID uint32) bson.M {
+	if worldID == t.worlds.Default() {
+		return bson.M{"world_id": bson.M{"$in": []uint32{0, worldID}}}
+	}
+	return bson.M{"world_id": worldID}
+}
+
+// writeArchive 写入迁移前的数据归档
+func (t *Transfer) writeArchive(archive *Archive) (string, error) {
+	if err := os.MkdirAll(t.config.ArchiveDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create archive dir: %v", err)
+	}
+
+	data, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal archive: %v", err)
+	}
+
+	archivePath := filepath.Join(t.config.ArchiveDir,
+		fmt.Sprintf("user_%d_%d_to_%d_%d.json", archive.User.UserID, archive.FromWorld, archive.ToWorld, archive.ExportedAt.Unix()))
+	if err := os.WriteFile(archivePath, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write archive: %v", err)
+	}
+	return archivePath, nil
+}
+
+// otherParty 好友关系中的另一方
+func otherParty(link *database.Friend, userID uint64) uint64 {
+	if link.UserID == userID {
+		return link.FriendID
+	}
+	return link.UserID
+}
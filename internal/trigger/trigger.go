@@ -0,0 +1,360 @@
+package trigger
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// 触发奖励的生命周期事件
+const (
+	EventFirstPurchase = "first_purchase" // 计费系统通知的购买，每个玩家只发放一次
+	EventReturn        = "return"         // 离开一段时间后登录，每次回归都可发放
+	EventLevel         = "level"          // 等级达到里程碑，每个玩家只发放一次
+)
+
+// 默认设置
+const (
+	defaultExpire     = 7 * 24 * time.Hour
+	defaultAbsence    = 7 * 24 * time.Hour
+	defaultQueue      = 1024
+	defaultRetries    = 5
+	defaultBackoff    = time.Second
+	defaultMaxBackoff = time.Minute
+)
+
+// Config 奖励触发配置，支持热更新
+type Config struct {
+	Expire  time.Duration `yaml:"expire"`  // 奖励邮件的有效期，未配置时为7天
+	Queue   int           `yaml:"queue"`   // 等待处理的事件数上限，队列满时丢弃事件，未配置时为1024，修改后重启生效
+	Retries int           `yaml:"retries"` // 处理失败后的最多重试次数，未配置时为5
+	Backoff time.Duration `yaml:"backoff"` // 首次重试的等待时间，之后每次翻倍，最长1分钟，未配置时为1秒
+	Rules   []Rule        `yaml:"rules"`
+}
+
+// Rule 触发规则，事件满足条件时通过邮件发放奖励。规则ID用于发放记录去重，修改奖励时不要修改ID
+type Rule struct {
+	ID       string        `yaml:"id"`
+	Event    string        `yaml:"event"`
+	Level    int32         `yaml:"level"`   // level: 达到的等级，一次升多级时跨过该等级也会发放
+	Absence  time.Duration `yaml:"absence"` // return: 离开的最短时长，未配置时为7天
+	Title    string        `yaml:"title"`
+	Content  string        `yaml:"content"`
+	Items    []Item        `yaml:"items"`
+	Disabled bool          `yaml:"disabled"`
+}
+
+// Item 奖励物品，对应邮件附件
+type Item struct {
+	Type  int32  `yaml:"type"`
+	ID    int32  `yaml:"id"`
+	Count int64  `yaml:"count"`
+	Name  string `yaml:"name"`
+}
+
+// Validate 检查触发规则
+func (c *Config) Validate() error {
+	if c.Queue < 0 || c.Retries < 0 || c.Backoff < 0 {
+		return fmt.Errorf("trigger queue, retries and backoff must not be negative")
+	}
+	ids := make(map[string]bool, len(c.Rules))
+	for _, rule := range c.Rules {
+		if rule.ID == "" {
+			return fmt.Errorf("trigger rule id is required")
+		}
+		if ids[rule.ID] {
+			return fmt.Errorf("duplicate trigger rule: %s", rule.ID)
+		}
+		ids[rule.ID] = true
+
+		switch rule.Event {
+		case EventFirstPurchase, EventReturn:
+		case EventLevel:
+			if rule.Level <= 1 {
+				return fmt.Errorf("trigger rule %s: level must be above 1", rule.ID)
+			}
+		default:
+			return fmt.Errorf("trigger rule %s: unknown event %s", rule.ID, rule.Event)
+		}
+		if rule.Absence < 0 {
+			return fmt.Errorf("trigger rule %s: absence must not be negative", rule.ID)
+		}
+		if rule.Title == "" || len(rule.Items) == 0 {
+			return fmt.Errorf("trigger rule %s: title and items are required", rule.ID)
+		}
+		for _, item := range rule.Items {
+			if item.Count <= 0 {
+				return fmt.Errorf("trigger rule %s: item count must be positive", rule.ID)
+			}
+		}
+	}
+	return nil
+}
+
+// Event 玩家的生命周期事件
+type Event struct {
+	Type    string
+	UserID  uint64
+	Level   int32         // level: 升级后的等级
+	Absence time.Duration // return: 距上次登录的时长
+	Ref     string        // 事件标识，如订单号、上次登录时间，可重复的规则按它去重
+}
+
+// GrantStore 发放记录存储，database.TriggerGrantRepository实现了该接口
+type GrantStore interface {
+	Claim(grant *database.TriggerGrant) (bool, error)
+	Complete(key string, mailID uint64) error
+	Release(key string) error
+	ListByUser(userID uint64, limit int64) ([]*database.TriggerGrant, error)
+}
+
+// Service 奖励触发服务。事件满足规则时先以去重键占用发放记录再发送奖励邮件，
+// 多个节点处理同一事件或事件重复投递时只发放一次；邮件发送失败时删除记录，按退避间隔重试
+type Service struct {
+	config  Config
+	grants  GrantStore
+	mails   database.MailStore
+	mailIDs database.MailIDGenerator
+	queue   chan Event
+	mutex   sync.RWMutex
+}
+
+// retry 等待重试的事件
+type retry struct {
+	event    Event
+	attempts int
+	due      time.Time
+}
+
+// NewService 创建奖励触发服务，Fire提交的事件由Run处理
+func NewService(config *Config, grants GrantStore, mails database.MailStore, mailIDs database.MailIDGenerator) *Service {
+	size := config.Queue
+	if size <= 0 {
+		size = defaultQueue
+	}
+	s := &Service{grants: grants, mails: mails, mailIDs: mailIDs, queue: make(chan Event, size)}
+	s.Update(config)
+	return s
+}
+
+// Update 配置热更新后替换规则，已发放的记录不受影响
+func (s *Service) Update(config *Config) {
+	c := *config
+	if c.Expire <= 0 {
+		c.Expire = defaultExpire
+	}
+	if c.Retries <= 0 {
+		c.Retries = defaultRetries
+	}
+	if c.Backoff <= 0 {
+		c.Backoff = defaultBackoff
+	}
+	c.Rules = make([]Rule, len(config.Rules))
+	copy(c.Rules, config.Rules)
+	for i := range c.Rules {
+		if c.Rules[i].Event == EventReturn && c.Rules[i].Absence <= 0 {
+			c.Rules[i].Absence = defaultAbsence
+		}
+	}
+
+	s.mutex.Lock()
+	s.config = c
+	s.mutex.Unlock()
+}
+
+// Fire 提交事件，不阻塞调用方，队列满时丢弃事件
+func (s *Service) Fire(event Event) {
+	select {
+	case s.queue <- event:
+	default:
+		logger.Error(fmt.Sprintf("Trigger queue full, dropped %s event of user %d", event.Type, event.UserID))
+	}
+}
+
+// Run 处理提交的事件，失败的事件按退避间隔重试，直到ctx取消。
+// 退出前把队列中和等待重试的事件各处理一次，仍失败的事件丢弃
+func (s *Service) Run(ctx context.Context) {
+	var retries []*retry
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case event := <-s.queue:
+			if r := s.attempt(&retry{event: event}); r != nil {
+				retries = append(retries, r)
+			}
+
+		case <-timer.C:
+			now := time.Now()
+			pending := retries[:0]
+			for _, r := range retries {
+				if r.due.After(now) {
+					pending = append(pending, r)
+				} else if r = s.attempt(r); r != nil {
+					pending = append(pending, r)
+				}
+			}
+			retries = pending
+
+		case <-ctx.Done():
+			s.drain(retries)
+			return
+		}
+
+		timer.Stop()
+		if len(retries) > 0 {
+			next := retries[0].due
+			for _, r := range retries[1:] {
+				if r.due.Before(next) {
+					next = r.due
+				}
+			}
+			timer.Reset(time.Until(next))
+		}
+	}
+}
+
+// attempt 处理一次事件，失败且未超过重试次数时返回下次重试
+func (s *Service) attempt(r *retry) *retry {
+	_, err := s.Handle(r.event)
+	if err == nil {
+		return nil
+	}
+
+	s.mutex.RLock()
+	retries, backoff := s.config.Retries, s.config.Backoff
+	s.mutex.RUnlock()
+
+	r.attempts++
+	if r.attempts > retries {
+		logger.Error(fmt.Sprintf("Failed to handle %s trigger of user %d after %d attempts: %v",
+			r.event.Type, r.event.UserID, r.attempts, err))
+		return nil
+	}
+	delay := backoff << (r.attempts - 1)
+	if delay <= 0 || delay > defaultMaxBackoff {
+		delay = defaultMaxBackoff
+	}
+	r.due = time.Now().Add(delay)
+	logger.Warn(fmt.Sprintf("Failed to handle %s trigger of user %d, retry in %v: %v", r.event.Type, r.event.UserID, delay, err))
+	return r
+}
+
+// drain 停止时处理队列中和等待重试的事件
+func (s *Service) drain(retries []*retry) {
+	for {
+		select {
+		case event := <-s.queue:
+			retries = append(retries, &retry{event: event})
+			continue
+		default:
+		}
+		break
+	}
+	for _, r := range retries {
+		if _, err := s.Handle(r.event); err != nil {
+			logger.Error(fmt.Sprintf("Dropped %s trigger of user %d on shutdown: %v", r.event.Type, r.event.UserID, err))
+		}
+	}
+}
+
+// Handle 处理事件，发放所有满足条件且未发放过的规则的奖励，返回本次发放的记录
+func (s *Service) Handle(event Event) ([]*database.TriggerGrant, error) {
+	s.mutex.RLock()
+	config := s.config
+	s.mutex.RUnlock()
+
+	var granted []*database.TriggerGrant
+	var failed error
+	for i := range config.Rules {
+		rule := &config.Rules[i]
+		if !matches(rule, &event) {
+			continue
+		}
+		grant, err := s.grant(rule, &event, config.Expire)
+		if err != nil {
+			failed = err
+			continue
+		}
+		if grant != nil {
+			granted = append(granted, grant)
+		}
+	}
+	return granted, failed
+}
+
+// Grants 玩家最近的发放记录
+func (s *Service) Grants(userID uint64, limit int64) ([]*database.TriggerGrant, error) {
+	return s.grants.ListByUser(userID, limit)
+}
+
+// grant 占用发放记录并发送奖励邮件，已发放过时返回nil
+func (s *Service) grant(rule *Rule, event *Event, expire time.Duration) (*database.TriggerGrant, error) {
+	key := rule.ID + ":" + strconv.FormatUint(event.UserID, 10)
+	if event.Type == EventReturn {
+		key += ":" + event.Ref
+	}
+	grant := &database.TriggerGrant{
+		Key:    key,
+		RuleID: rule.ID,
+		UserID: event.UserID,
+		Event:  event.Type,
+		Ref:    event.Ref,
+	}
+	claimed, err := s.grants.Claim(grant)
+	if err != nil || !claimed {
+		return nil, err
+	}
+
+	rewards := make([]database.MailReward, len(rule.Items))
+	for i, item := range rule.Items {
+		rewards[i] = database.MailReward{Type: item.Type, ItemID: item.ID, Count: item.Count, Name: item.Name}
+	}
+	mailID, err := s.mailIDs()
+	if err == nil {
+		err = s.mails.CreateMail(&database.Mail{
+			MailID:   mailID,
+			ToUserID: event.UserID,
+			Title:    rule.Title,
+			Content:  rule.Content,
+			Rewards:  rewards,
+			BatchID:  "trigger:" + rule.ID,
+			ExpireAt: time.Now().Add(expire),
+		})
+	}
+	if err != nil {
+		if releaseErr := s.grants.Release(key); releaseErr != nil {
+			logger.Error(fmt.Sprintf("Failed to release trigger grant %s: %v", key, releaseErr))
+		}
+		return nil, fmt.Errorf("failed to send %s reward: %v", rule.ID, err)
+	}
+
+	if err := s.grants.Complete(key, mailID); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to complete trigger grant %s: %v", key, err))
+	}
+	grant.Status = database.TriggerGrantGranted
+	grant.MailID = mailID
+	logger.Info(fmt.Sprintf("Trigger %s granted to user %d, mail %d", rule.ID, event.UserID, mailID))
+	return grant, nil
+}
+
+// matches 事件是否满足规则
+func matches(rule *Rule, event *Event) bool {
+	if rule.Disabled || rule.Event != event.Type {
+		return false
+	}
+	switch event.Type {
+	case EventLevel:
+		return event.Level >= rule.Level
+	case EventReturn:
+		return event.Absence >= rule.Absence
+	}
+	return true
+}
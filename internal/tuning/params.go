@@ -0,0 +1,100 @@
+package tuning
+
+import (
+	"fmt"
+	"math"
+	"runtime/debug"
+	"runtime/metrics"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// 参数的取值范围
+const (
+	minGCPercent     = 10
+	maxGCPercent     = 1000
+	minRateLimitMult = 0.1
+	maxRateLimitMult = 10.0
+)
+
+// rateLimitMultiplier 限流上限的倍数，保存float64的位表示，0表示未调整
+var rateLimitMultiplier atomic.Uint64
+
+// RateLimitMultiplier 当前的限流倍数，未调整时为1
+func RateLimitMultiplier() float64 {
+	bits := rateLimitMultiplier.Load()
+	if bits == 0 {
+		return 1
+	}
+	return math.Float64frombits(bits)
+}
+
+// ScaleLimit 按限流倍数调整上限，大于0的上限调整后至少为1，0和负数表示不限制，原样返回
+func ScaleLimit(limit int64) int64 {
+	if limit <= 0 {
+		return limit
+	}
+	return max(int64(math.Round(float64(limit)*RateLimitMultiplier())), 1)
+}
+
+// builtinParams 所有节点都支持的参数
+func builtinParams() []Param {
+	return []Param{
+		{
+			Name:        "log_level",
+			Description: "debug, info, warn or error",
+			Get: func(string) (string, error) {
+				return logger.GlobalLevel(), nil
+			},
+			Set: func(_, value string) error {
+				return logger.SetGlobalLevel(value)
+			},
+		},
+		{
+			Name:        "gc_percent",
+			Description: fmt.Sprintf("GOGC between %d and %d, or -1 to disable GC", minGCPercent, maxGCPercent),
+			Get: func(string) (string, error) {
+				return strconv.Itoa(gcPercent()), nil
+			},
+			Set: func(_, value string) error {
+				percent, err := strconv.Atoi(value)
+				if err != nil || (percent != -1 && (percent < minGCPercent || percent > maxGCPercent)) {
+					return fmt.Errorf("gc_percent must be -1 or between %d and %d", minGCPercent, maxGCPercent)
+				}
+				debug.SetGCPercent(percent)
+				return nil
+			},
+		},
+		{
+			Name:        "rate_limit_multiplier",
+			Description: fmt.Sprintf("multiplier of chat, push and request rate limits between %g and %g", minRateLimitMult, maxRateLimitMult),
+			Get: func(string) (string, error) {
+				return strconv.FormatFloat(RateLimitMultiplier(), 'g', -1, 64), nil
+			},
+			Set: func(_, value string) error {
+				multiplier, err := strconv.ParseFloat(value, 64)
+				if err != nil || multiplier < minRateLimitMult || multiplier > maxRateLimitMult {
+					return fmt.Errorf("rate_limit_multiplier must be between %g and %g", minRateLimitMult, maxRateLimitMult)
+				}
+				rateLimitMultiplier.Store(math.Float64bits(multiplier))
+				return nil
+			},
+		},
+	}
+}
+
+// gcPercent 当前的GOGC
+func gcPercent() int {
+	sample := []metrics.Sample{{Name: "/gc/gogc:percent"}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 100
+	}
+	// 关闭GC时为-1，按uint64读出时为最大值
+	if value := sample[0].Value.Uint64(); value <= math.MaxInt32 {
+		return int(value)
+	}
+	return -1
+}
@@ -0,0 +1,252 @@
+package tuning
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// 调整的默认时长
+const (
+	defaultDuration    = 30 * time.Minute
+	defaultMaxDuration = 24 * time.Hour
+)
+
+// Config 运行时参数调整配置，调整只影响当前节点，到期后自动恢复为调整前的值
+type Config struct {
+	DefaultDuration time.Duration `yaml:"default_duration"` // 未指定时长时多久后恢复，未配置时为30分钟
+	MaxDuration     time.Duration `yaml:"max_duration"`     // 允许的最长时长，未配置时为24小时
+}
+
+// Param 可在运行时调整的参数。带键的参数按 名称:键 调整，如 consumer_concurrency:game_events/lobby
+type Param struct {
+	Name        string
+	Description string
+	Keyed       bool
+	Get         func(key string) (string, error)
+	Set         func(key, value string) error // 检查并应用新值，值无效时返回错误且不做任何修改
+}
+
+// Override 调整中的参数
+type Override struct {
+	Name      string    `json:"name"`
+	Value     string    `json:"value"`
+	Original  string    `json:"original"` // 调整前的值，到期或重置时恢复
+	Operator  string    `json:"operator"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	timer *time.Timer
+}
+
+// Tuner 节点的运行时参数，每次调整和恢复都记录审计日志
+type Tuner struct {
+	config    Config
+	params    map[string]*Param
+	overrides map[string]*Override
+	mutex     sync.Mutex
+}
+
+// NewTuner 创建运行时参数调整器，内置日志级别、GC比例和限流倍数
+func NewTuner(config *Config) *Tuner {
+	c := *config
+	if c.MaxDuration <= 0 {
+		c.MaxDuration = defaultMaxDuration
+	}
+	if c.DefaultDuration <= 0 || c.DefaultDuration > c.MaxDuration {
+		c.DefaultDuration = min(defaultDuration, c.MaxDuration)
+	}
+	t := &Tuner{
+		config:    c,
+		params:    make(map[string]*Param),
+		overrides: make(map[string]*Override),
+	}
+	for _, param := range builtinParams() {
+		t.Register(param)
+	}
+	return t
+}
+
+// Register 注册参数，同名参数替换之前的注册
+func (t *Tuner) Register(param Param) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	p := param
+	t.params[param.Name] = &p
+}
+
+// Names 已注册的参数名，带键的参数后跟 :<键>
+func (t *Tuner) Names() []string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	names := make([]string, 0, len(t.params))
+	for name, param := range t.params {
+		if param.Keyed {
+			name += ":<key>"
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Set 调整参数，duration为0时使用默认时长，超过最长时长时截断，返回调整的记录。
+// 参数已在调整中时替换值并重新计时，到期后仍恢复为第一次调整前的值
+func (t *Tuner) Set(name, value string, duration time.Duration, operator string) (*Override, error) {
+	override, err := t.set(name, value, duration, operator)
+	if err != nil {
+		audit(operator, "set_runtime_param", name, false, map[string]interface{}{
+			"value": value,
+			"error": err.Error(),
+		})
+		return nil, err
+	}
+	audit(operator, "set_runtime_param", name, true, map[string]interface{}{
+		"value":      override.Value,
+		"original":   override.Original,
+		"expires_at": override.ExpiresAt,
+	})
+	logger.Warn(fmt.Sprintf("Runtime param %s set to %s by %s until %s (was %s)",
+		name, override.Value, operator, override.ExpiresAt.Format(time.RFC3339), override.Original))
+	return override, nil
+}
+
+// set 检查并应用新值，启动恢复计时
+func (t *Tuner) set(name, value string, duration time.Duration, operator string) (*Override, error) {
+	if duration < 0 {
+		return nil, fmt.Errorf("duration must not be negative")
+	}
+	if duration == 0 {
+		duration = t.config.DefaultDuration
+	}
+	if duration > t.config.MaxDuration {
+		duration = t.config.MaxDuration
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	param, key, err := t.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	override, exists := t.overrides[name]
+	original := ""
+	if exists {
+		original = override.Original
+	} else if original, err = param.Get(key); err != nil {
+		return nil, err
+	}
+	if err := param.Set(key, value); err != nil {
+		return nil, err
+	}
+	// 按参数读回规范化后的值，如日志级别warning读回为warn
+	if current, err := param.Get(key); err == nil {
+		value = current
+	}
+
+	if exists {
+		override.timer.Stop()
+	}
+	override = &Override{
+		Name:      name,
+		Value:     value,
+		Original:  original,
+		Operator:  operator,
+		ExpiresAt: time.Now().Add(duration),
+	}
+	override.timer = time.AfterFunc(duration, func() { t.expire(override) })
+	t.overrides[name] = override
+
+	copied := *override
+	return &copied, nil
+}
+
+// Reset 立即恢复参数为调整前的值，参数不在调整中时返回错误
+func (t *Tuner) Reset(name, operator string) error {
+	t.mutex.Lock()
+	override, exists := t.overrides[name]
+	t.mutex.Unlock()
+	if !exists {
+		return fmt.Errorf("runtime param %s is not overridden", name)
+	}
+	return t.revert(override, operator)
+}
+
+// Overrides 调整中的参数，按名称排序
+func (t *Tuner) Overrides() []Override {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	overrides := make([]Override, 0, len(t.overrides))
+	for _, override := range t.overrides {
+		copied := *override
+		copied.timer = nil
+		overrides = append(overrides, copied)
+	}
+	sort.Slice(overrides, func(i, j int) bool { return overrides[i].Name < overrides[j].Name })
+	return overrides
+}
+
+// expire 到期后恢复参数
+func (t *Tuner) expire(override *Override) {
+	if err := t.revert(override, "system"); err != nil {
+		logger.Error(fmt.Sprintf("Failed to revert runtime param %s: %v", override.Name, err))
+	}
+}
+
+// revert 恢复参数为调整前的值，参数已被重新调整或恢复时不做处理
+func (t *Tuner) revert(override *Override, operator string) error {
+	t.mutex.Lock()
+	if t.overrides[override.Name] != override {
+		t.mutex.Unlock()
+		return nil
+	}
+	override.timer.Stop()
+	delete(t.overrides, override.Name)
+	param, key, err := t.lookup(override.Name)
+	if err == nil {
+		err = param.Set(key, override.Original)
+	}
+	t.mutex.Unlock()
+
+	audit(operator, "revert_runtime_param", override.Name, err == nil, map[string]interface{}{
+		"value":    override.Original,
+		"override": override.Value,
+	})
+	if err != nil {
+		return err
+	}
+	logger.Warn(fmt.Sprintf("Runtime param %s reverted to %s by %s", override.Name, override.Original, operator))
+	return nil
+}
+
+// lookup 按名称查找参数，带键的参数返回键，需持有锁
+func (t *Tuner) lookup(name string) (*Param, string, error) {
+	base, key, keyed := strings.Cut(name, ":")
+	param, exists := t.params[base]
+	if !exists {
+		return nil, "", fmt.Errorf("unknown runtime param: %s", base)
+	}
+	if param.Keyed && (!keyed || key == "") {
+		return nil, "", fmt.Errorf("runtime param %s requires a key, e.g. %s:<key>", base, base)
+	}
+	if !param.Keyed && keyed {
+		return nil, "", fmt.Errorf("runtime param %s does not take a key", base)
+	}
+	return param, key, nil
+}
+
+// audit 记录审计日志，operator为GM用户ID，自动恢复时为system
+func audit(operator, action, name string, success bool, details map[string]interface{}) {
+	userID, _ := strconv.ParseUint(operator, 10, 64)
+	details["operator"] = operator
+	logger.NewAuditLogger().LogAuditEvent(userID, action, name, success, details)
+}
@@ -0,0 +1,82 @@
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// 构建信息，通过-ldflags注入:
+//
+//	go build -ldflags "-X github.com/phuhao00/lufy/internal/version.Version=v1.2.0
+//	  -X github.com/phuhao00/lufy/internal/version.GitCommit=$(git rev-parse --short HEAD)
+//	  -X github.com/phuhao00/lufy/internal/version.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// 未注入时从Go工具链记录的VCS信息中读取提交和时间
+var (
+	Version   = "dev"
+	GitCommit = ""
+	BuildTime = ""
+)
+
+// Info 构建信息
+type Info struct {
+	Version     string `json:"version"`
+	GitCommit   string `json:"git_commit"`
+	BuildTime   string `json:"build_time"`
+	GoVersion   string `json:"go_version"`
+	ProtoSchema uint32 `json:"proto_schema"`
+}
+
+// Get 获取当前二进制的构建信息
+func Get() Info {
+	info := Info{
+		Version:     Version,
+		GitCommit:   GitCommit,
+		BuildTime:   BuildTime,
+		GoVersion:   runtime.Version(),
+		ProtoSchema: proto.SchemaVersion,
+	}
+
+	if info.GitCommit == "" || info.BuildTime == "" {
+		if buildInfo, ok := debug.ReadBuildInfo(); ok {
+			settings := make(map[string]string, len(buildInfo.Settings))
+			for _, setting := range buildInfo.Settings {
+				settings[setting.Key] = setting.Value
+			}
+			if info.GitCommit == "" && settings["vcs.revision"] != "" {
+				info.GitCommit = shortCommit(settings["vcs.revision"])
+				if settings["vcs.modified"] == "true" {
+					info.GitCommit += "-dirty"
+				}
+			}
+			if info.BuildTime == "" {
+				info.BuildTime = settings["vcs.time"]
+			}
+		}
+	}
+
+	if info.GitCommit == "" {
+		info.GitCommit = "unknown"
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = "unknown"
+	}
+	return info
+}
+
+// String 单行描述
+func (i Info) String() string {
+	return fmt.Sprintf("%s (commit %s, built %s, %s, proto schema %d)",
+		i.Version, i.GitCommit, i.BuildTime, i.GoVersion, i.ProtoSchema)
+}
+
+// shortCommit 截取提交哈希的前12位
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
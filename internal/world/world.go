@@ -0,0 +1,143 @@
+package world
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultWorldID 未配置default_world时的默认世界
+const DefaultWorldID uint32 = 1
+
+// 跨世界操作，按操作类型分别控制是否允许
+const (
+	ActionFriend = "friend" // 好友请求
+	ActionChat   = "chat"   // 私聊
+	ActionRoom   = "room"   // 加入房间，房间始终属于单个世界
+)
+
+// Config 游戏世界配置，一个集群可以承载多个逻辑上互相隔离的世界
+type Config struct {
+	Worlds       []Info           `yaml:"worlds"`        // 集群中的世界，为空时只有default_world一个世界
+	DefaultWorld uint32           `yaml:"default_world"` // 注册时未指定世界使用的世界，world_id为0的旧数据也属于该世界
+	Serve        []uint32         `yaml:"serve"`         // 本节点服务的世界，为空时服务所有世界
+	CrossWorld   CrossWorldConfig `yaml:"cross_world"`
+}
+
+// Info 世界信息
+type Info struct {
+	ID     uint32 `yaml:"id"`
+	Name   string `yaml:"name"`
+	Closed bool   `yaml:"closed"` // 关闭新注册，已有玩家不受影响
+}
+
+// CrossWorldConfig 跨世界操作开关，默认不允许
+type CrossWorldConfig struct {
+	Friend bool `yaml:"friend"`
+	Chat   bool `yaml:"chat"`
+}
+
+// Default 默认世界
+func (c *Config) Default() uint32 {
+	if c.DefaultWorld == 0 {
+		return DefaultWorldID
+	}
+	return c.DefaultWorld
+}
+
+// Resolve 把未指定的世界(0)解析为默认世界
+func (c *Config) Resolve(worldID uint32) uint32 {
+	if worldID == 0 {
+		return c.Default()
+	}
+	return worldID
+}
+
+// Lookup 查找世界，未配置worlds时只有默认世界
+func (c *Config) Lookup(worldID uint32) (Info, bool) {
+	worldID = c.Resolve(worldID)
+	if len(c.Worlds) == 0 {
+		return Info{ID: c.Default()}, worldID == c.Default()
+	}
+	for _, info := range c.Worlds {
+		if info.ID == worldID {
+			return info, true
+		}
+	}
+	return Info{}, false
+}
+
+// ValidateRegistration 检查新玩家能否注册到指定世界
+func (c *Config) ValidateRegistration(worldID uint32) error {
+	info, exists := c.Lookup(worldID)
+	if !exists {
+		return fmt.Errorf("unknown world: %d", c.Resolve(worldID))
+	}
+	if info.Closed {
+		return fmt.Errorf("world %d is closed for registration", info.ID)
+	}
+	return nil
+}
+
+// Allows 检查两个世界之间能否执行操作，同一世界内总是允许
+func (c *Config) Allows(action string, from, to uint32) bool {
+	if c.Resolve(from) == c.Resolve(to) {
+		return true
+	}
+	switch action {
+	case ActionFriend:
+		return c.CrossWorld.Friend
+	case ActionChat:
+		return c.CrossWorld.Chat
+	default:
+		return false
+	}
+}
+
+// Validate 验证配置
+func (c *Config) Validate() error {
+	seen := make(map[uint32]bool, len(c.Worlds))
+	for _, info := range c.Worlds {
+		if info.ID == 0 {
+			return fmt.Errorf("world id must be greater than 0")
+		}
+		if seen[info.ID] {
+			return fmt.Errorf("duplicate world id: %d", info.ID)
+		}
+		seen[info.ID] = true
+	}
+	if _, exists := c.Lookup(c.Default()); !exists {
+		return fmt.Errorf("default world %d is not configured", c.Default())
+	}
+	for _, worldID := range c.Serve {
+		if _, exists := c.Lookup(worldID); !exists {
+			return fmt.Errorf("served world %d is not configured", worldID)
+		}
+	}
+	return nil
+}
+
+// FormatIDs 把世界ID列表格式化为逗号分隔的字符串，用于服务注册元数据
+func FormatIDs(worldIDs []uint32) string {
+	parts := make([]string, 0, len(worldIDs))
+	for _, worldID := range worldIDs {
+		parts = append(parts, strconv.FormatUint(uint64(worldID), 10))
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParseIDs 解析逗号分隔的世界ID列表
+func ParseIDs(value string) ([]uint32, error) {
+	worldIDs := make([]uint32, 0)
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		worldID, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid world id %q: %v", part, err)
+		}
+		worldIDs = append(worldIDs, uint32(worldID))
+	}
+	return worldIDs, nil
+}
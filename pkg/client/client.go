@@ -0,0 +1,395 @@
+package client
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/phuhao00/lufy/internal/network"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// 网关消息ID
+const (
+	MsgHandshake = 1000
+	MsgLogin     = 1001
+	MsgHeartbeat = 1002
+	MsgLogout    = 1003
+	MsgTransport = 1004
+	MsgNewsFeed  = 1005
+)
+
+// Config 客户端配置
+type Config struct {
+	Address           string        // 网关地址
+	ProtocolVersion   uint32        // 请求的协议版本，默认为当前版本
+	ClientVersion     string        // 客户端版本号
+	Platform          string        // 平台
+	Language          string        // 语言
+	Codec             string        // 请求的编解码格式，默认protobuf
+	DialTimeout       time.Duration // 连接超时
+	RequestTimeout    time.Duration // 请求超时
+	HeartbeatInterval time.Duration // 心跳间隔，为0时不自动发送心跳
+}
+
+// PushHandler 服务器推送处理函数
+type PushHandler func(msgID uint32, response *proto.BaseResponse)
+
+// ResponseError 服务器返回的错误响应
+type ResponseError struct {
+	MsgID uint32
+	Code  int32
+	Msg   string
+}
+
+// Error 实现error接口
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("message %d failed with code %d: %s", e.MsgID, e.Code, e.Msg)
+}
+
+// Client 网关客户端，负责连接、握手、登录、请求响应关联和推送分发
+// 请求按消息头中的seq关联响应，未匹配到请求的消息作为推送交给OnPush注册的处理函数
+type Client struct {
+	config    *Config
+	conn      net.Conn
+	codec     network.Codec
+	integrity *network.FrameIntegrity
+
+	sessionKey      []byte
+	protocolVersion uint32
+	userID          uint64
+	token           string
+
+	seq         uint32
+	nonce       uint64 // 由writeMutex保护
+	clientMsgID uint64
+	instanceID  string // 客户端实例标识，避免重连后的消息ID与网关去重缓存冲突
+
+	pending      map[uint32]chan *proto.BaseResponse
+	pushHandlers map[uint32]PushHandler
+	defaultPush  PushHandler
+
+	writeMutex sync.Mutex
+	mutex      sync.RWMutex
+	closed     chan struct{}
+	closeOnce  sync.Once
+	closeErr   error
+}
+
+// NewClient 创建客户端
+func NewClient(config *Config) *Client {
+	if config.ProtocolVersion == 0 {
+		config.ProtocolVersion = network.ProtocolVersionCurrent
+	}
+	if config.Codec == "" {
+		config.Codec = network.CodecProtobuf
+	}
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = 5 * time.Second
+	}
+	if config.RequestTimeout <= 0 {
+		config.RequestTimeout = 10 * time.Second
+	}
+
+	integrity, _ := network.NewFrameIntegrity(network.IntegrityNone, false, nil, nil)
+
+	return &Client{
+		config:       config,
+		codec:        network.DefaultCodec(),
+		integrity:    integrity,
+		instanceID:   fmt.Sprintf("%x", time.Now().UnixNano()),
+		pending:      make(map[uint32]chan *proto.BaseResponse),
+		pushHandlers: make(map[uint32]PushHandler),
+		closed:       make(chan struct{}),
+	}
+}
+
+// Connect 连接网关并启动接收循环
+func (c *Client) Connect() error {
+	conn, err := net.DialTimeout("tcp", c.config.Address, c.config.DialTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %v", c.config.Address, err)
+	}
+	c.conn = conn
+
+	go c.readLoop()
+	if c.config.HeartbeatInterval > 0 {
+		go c.heartbeatLoop()
+	}
+
+	return nil
+}
+
+// Close 关闭连接，等待中的请求返回错误
+func (c *Client) Close() error {
+	c.shutdown(fmt.Errorf("client closed"))
+	return nil
+}
+
+// Done 连接关闭时关闭的通道
+func (c *Client) Done() <-chan struct{} {
+	return c.closed
+}
+
+// Err 获取连接关闭的原因
+func (c *Client) Err() error {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.closeErr
+}
+
+// UserID 获取登录后的用户ID
+func (c *Client) UserID() uint64 {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.userID
+}
+
+// Token 获取登录后的会话令牌
+func (c *Client) Token() string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.token
+}
+
+// OnPush 注册推送处理函数，msgID为0时处理所有未单独注册的推送
+func (c *Client) OnPush(msgID uint32, handler PushHandler) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if msgID == 0 {
+		c.defaultPush = handler
+		return
+	}
+	c.pushHandlers[msgID] = handler
+}
+
+// Request 发送请求并把响应数据解析到resp，resp为nil时忽略响应数据，错误码非0时返回*ResponseError
+func (c *Client) Request(msgID uint32, req proto.Message, resp proto.Message) error {
+	response, err := c.Call(msgID, req)
+	if err != nil {
+		return err
+	}
+	if response.Code != 0 {
+		return &ResponseError{MsgID: msgID, Code: response.Code, Msg: response.Msg}
+	}
+	if resp != nil && len(response.Data) > 0 {
+		if err := c.getCodec().Unmarshal(response.Data, resp); err != nil {
+			return fmt.Errorf("failed to unmarshal response for message %d: %v", msgID, err)
+		}
+	}
+	return nil
+}
+
+// Call 发送请求并等待原始响应，req可以为nil
+func (c *Client) Call(msgID uint32, req proto.Message) (*proto.BaseResponse, error) {
+	seq := atomic.AddUint32(&c.seq, 1)
+	responseChan := make(chan *proto.BaseResponse, 1)
+
+	c.mutex.Lock()
+	c.pending[seq] = responseChan
+	c.mutex.Unlock()
+
+	defer func() {
+		c.mutex.Lock()
+		delete(c.pending, seq)
+		c.mutex.Unlock()
+	}()
+
+	if err := c.send(msgID, seq, req); err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(c.config.RequestTimeout)
+	defer timer.Stop()
+
+	select {
+	case response := <-responseChan:
+		return response, nil
+	case <-timer.C:
+		return nil, fmt.Errorf("message %d timed out after %v", msgID, c.config.RequestTimeout)
+	case <-c.closed:
+		return nil, fmt.Errorf("connection closed: %v", c.Err())
+	}
+}
+
+// Send 发送消息，不等待响应
+func (c *Client) Send(msgID uint32, req proto.Message) error {
+	return c.send(msgID, atomic.AddUint32(&c.seq, 1), req)
+}
+
+// send 编码并发送请求帧
+func (c *Client) send(msgID, seq uint32, req proto.Message) error {
+	codec := c.getCodec()
+
+	var data []byte
+	if req != nil {
+		encoded, err := codec.Marshal(req)
+		if err != nil {
+			return fmt.Errorf("failed to marshal message %d: %v", msgID, err)
+		}
+		data = encoded
+	}
+
+	// 持有写锁分配nonce，保证nonce按发送顺序递增
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	c.mutex.RLock()
+	header := &proto.MessageHeader{
+		MsgId:           msgID,
+		Seq:             seq,
+		UserId:          c.userID,
+		Timestamp:       uint32(time.Now().Unix()),
+		SessionId:       c.token,
+		ClientMsgId:     fmt.Sprintf("%s-%d", c.instanceID, atomic.AddUint64(&c.clientMsgID, 1)),
+		ProtocolVersion: c.protocolVersion,
+		Nonce:           c.nextNonce(),
+	}
+	integrity := c.integrity
+	sessionKey := c.sessionKey
+	c.mutex.RUnlock()
+
+	body, err := codec.Marshal(&proto.BaseRequest{Header: header, Data: data})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request %d: %v", msgID, err)
+	}
+
+	frame := make([]byte, network.FrameMsgIDSize+len(body))
+	binary.BigEndian.PutUint32(frame[:network.FrameMsgIDSize], msgID)
+	copy(frame[network.FrameMsgIDSize:], body)
+
+	// 握手前不签名，握手后按协商的模式追加校验尾部
+	if msgID != MsgHandshake {
+		frame = integrity.Sign(sessionKey, frame)
+	}
+
+	message, err := network.EncodeFrame(frame)
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.conn.Write(message); err != nil {
+		c.shutdown(err)
+		return fmt.Errorf("failed to send message %d: %v", msgID, err)
+	}
+	return nil
+}
+
+// readLoop 接收响应和推送
+func (c *Client) readLoop() {
+	lengthBuf := make([]byte, network.FrameLengthSize)
+	for {
+		if _, err := io.ReadFull(c.conn, lengthBuf); err != nil {
+			c.shutdown(err)
+			return
+		}
+
+		length, err := network.ParseFrameLength(lengthBuf)
+		if err != nil {
+			c.shutdown(err)
+			return
+		}
+
+		body := make([]byte, length)
+		if _, err := io.ReadFull(c.conn, body); err != nil {
+			c.shutdown(err)
+			return
+		}
+
+		var response proto.BaseResponse
+		if err := c.getCodec().Unmarshal(body, &response); err != nil {
+			continue
+		}
+		c.dispatch(&response)
+	}
+}
+
+// dispatch 将响应交给等待的请求，未匹配的作为推送处理
+func (c *Client) dispatch(response *proto.BaseResponse) {
+	seq := response.Header.GetSeq()
+	msgID := response.Header.GetMsgId()
+
+	c.mutex.RLock()
+	responseChan, waiting := c.pending[seq]
+	handler, exists := c.pushHandlers[msgID]
+	if !exists {
+		handler = c.defaultPush
+	}
+	c.mutex.RUnlock()
+
+	if seq != 0 && waiting {
+		responseChan <- response
+		return
+	}
+	if handler != nil {
+		handler(msgID, response)
+	}
+}
+
+// heartbeatLoop 定时发送心跳
+func (c *Client) heartbeatLoop() {
+	ticker := time.NewTicker(c.config.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.Heartbeat(); err != nil {
+				select {
+				case <-c.closed:
+					return
+				default:
+				}
+			}
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+// shutdown 关闭连接并记录原因
+func (c *Client) shutdown(err error) {
+	c.closeOnce.Do(func() {
+		c.mutex.Lock()
+		c.closeErr = err
+		c.mutex.Unlock()
+
+		close(c.closed)
+		if c.conn != nil {
+			c.conn.Close()
+		}
+	})
+}
+
+// nextNonce 获取下一个消息序号，调用方需持有writeMutex
+func (c *Client) nextNonce() uint64 {
+	c.nonce++
+	return c.nonce
+}
+
+// getCodec 获取当前使用的编解码器
+func (c *Client) getCodec() network.Codec {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.codec
+}
+
+// signWithKey 使用会话密钥计算HMAC签名，与网关的签名算法一致
+func signWithKey(key, data []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyWithKey 使用会话密钥校验HMAC签名
+func verifyWithKey(key, data []byte, signature string) bool {
+	return hmac.Equal([]byte(signature), []byte(signWithKey(key, data)))
+}
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/phuhao00/lufy/internal/network"
+	"github.com/phuhao00/lufy/pkg/proto"
+)
+
+// Handshake 握手，协商协议版本、编解码格式和帧校验模式，HMAC模式下按双方公钥派生会话密钥
+func (c *Client) Handshake() (*proto.HandshakeResponse, error) {
+	kx, err := network.NewKeyExchange()
+	if err != nil {
+		return nil, err
+	}
+
+	var handshakeResp proto.HandshakeResponse
+	err = c.Request(MsgHandshake, &proto.HandshakeRequest{
+		ProtocolVersion: c.config.ProtocolVersion,
+		ClientVersion:   c.config.ClientVersion,
+		Platform:        c.config.Platform,
+		Language:        c.config.Language,
+		Codec:           c.config.Codec,
+		PublicKey:       kx.PublicKey(),
+	}, &handshakeResp)
+	if err != nil {
+		return nil, fmt.Errorf("handshake failed: %v", err)
+	}
+
+	codec, exists := network.GetCodec(handshakeResp.Codec)
+	if !exists {
+		return nil, fmt.Errorf("server negotiated unknown codec %s", handshakeResp.Codec)
+	}
+
+	integrity, err := network.NewFrameIntegrity(handshakeResp.FrameIntegrity, false, signWithKey, verifyWithKey)
+	if err != nil {
+		return nil, err
+	}
+	var sessionKey []byte
+	if integrity.RequiresSessionKey() {
+		if sessionKey, err = kx.SessionKey(handshakeResp.PublicKey); err != nil {
+			return nil, fmt.Errorf("handshake failed: %v", err)
+		}
+	}
+
+	// 握手响应使用原编解码器，之后的消息切换到协商的格式
+	c.mutex.Lock()
+	c.protocolVersion = handshakeResp.ProtocolVersion
+	c.codec = codec
+	c.integrity = integrity
+	c.sessionKey = sessionKey
+	c.mutex.Unlock()
+
+	return &handshakeResp, nil
+}
+
+// Login 登录，成功后后续请求自动携带用户ID和会话令牌
+func (c *Client) Login(username, password string) (*proto.LoginResponse, error) {
+	var loginResp proto.LoginResponse
+	err := c.Request(MsgLogin, &proto.LoginRequest{
+		Username: username,
+		Password: password,
+		Platform: c.config.Platform,
+		Version:  c.config.ClientVersion,
+	}, &loginResp)
+	if err != nil {
+		return nil, fmt.Errorf("login failed: %v", err)
+	}
+
+	c.mutex.Lock()
+	c.userID = loginResp.UserId
+	c.token = loginResp.Token
+	c.mutex.Unlock()
+
+	return &loginResp, nil
+}
+
+// Heartbeat 发送心跳并等待响应
+func (c *Client) Heartbeat() error {
+	return c.Request(MsgHeartbeat, nil, nil)
+}
+
+// Logout 登出，服务器处理后关闭连接，不等待响应
+func (c *Client) Logout() error {
+	return c.Send(MsgLogout, nil)
+}
+
+// NegotiateTransport 为玩法协商传输方式，返回tcp时继续使用当前连接
+func (c *Client) NegotiateTransport(gameType string) (*proto.TransportResponse, error) {
+	var transportResp proto.TransportResponse
+	if err := c.Request(MsgTransport, &proto.TransportRequest{GameType: gameType}, &transportResp); err != nil {
+		return nil, fmt.Errorf("transport negotiation failed: %v", err)
+	}
+	return &transportResp, nil
+}
+
+// Dial 创建客户端并完成连接、握手和登录
+func Dial(config *Config, username, password string) (*Client, error) {
+	c := NewClient(config)
+	if err := c.Connect(); err != nil {
+		return nil, err
+	}
+	if _, err := c.Handshake(); err != nil {
+		c.Close()
+		return nil, err
+	}
+	if _, err := c.Login(username, password); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
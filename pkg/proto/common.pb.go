@@ -28,6 +28,11 @@ type MessageHeader struct {
 	UserId               uint64   `protobuf:"varint,3,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
 	Timestamp            uint32   `protobuf:"varint,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
 	SessionId            string   `protobuf:"bytes,5,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	ClientMsgId          string   `protobuf:"bytes,6,opt,name=client_msg_id,json=clientMsgId,proto3" json:"client_msg_id,omitempty"`
+	ProtocolVersion      uint32   `protobuf:"varint,7,opt,name=protocol_version,json=protocolVersion,proto3" json:"protocol_version,omitempty"`
+	Nonce                uint64   `protobuf:"varint,8,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	TraceId              string   `protobuf:"bytes,9,opt,name=trace_id,json=traceId,proto3" json:"trace_id,omitempty"`
+	Language             string   `protobuf:"bytes,10,opt,name=language,proto3" json:"language,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -72,6 +77,41 @@ func (m *MessageHeader) GetSessionId() string {
 	return ""
 }
 
+func (m *MessageHeader) GetClientMsgId() string {
+	if m != nil {
+		return m.ClientMsgId
+	}
+	return ""
+}
+
+func (m *MessageHeader) GetProtocolVersion() uint32 {
+	if m != nil {
+		return m.ProtocolVersion
+	}
+	return 0
+}
+
+func (m *MessageHeader) GetNonce() uint64 {
+	if m != nil {
+		return m.Nonce
+	}
+	return 0
+}
+
+func (m *MessageHeader) GetTraceId() string {
+	if m != nil {
+		return m.TraceId
+	}
+	return ""
+}
+
+func (m *MessageHeader) GetLanguage() string {
+	if m != nil {
+		return m.Language
+	}
+	return ""
+}
+
 // 基础请求消息
 type BaseRequest struct {
 	Header               *MessageHeader `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
@@ -105,6 +145,11 @@ type BaseResponse struct {
 	Code                 int32          `protobuf:"varint,2,opt,name=code,proto3" json:"code,omitempty"`
 	Msg                  string         `protobuf:"bytes,3,opt,name=msg,proto3" json:"msg,omitempty"`
 	Data                 []byte         `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	Category             string         `protobuf:"bytes,5,opt,name=category,proto3" json:"category,omitempty"`
+	RequestId            string         `protobuf:"bytes,6,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	TraceId              string         `protobuf:"bytes,7,opt,name=trace_id,json=traceId,proto3" json:"trace_id,omitempty"`
+	ServerTime           int64          `protobuf:"varint,8,opt,name=server_time,json=serverTime,proto3" json:"server_time,omitempty"`
+	ErrorInfo            *ErrorInfo     `protobuf:"bytes,9,opt,name=error_info,json=errorInfo,proto3" json:"error_info,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
 	XXX_unrecognized     []byte         `json:"-"`
 	XXX_sizecache        int32          `json:"-"`
@@ -142,6 +187,41 @@ func (m *BaseResponse) GetData() []byte {
 	return nil
 }
 
+func (m *BaseResponse) GetCategory() string {
+	if m != nil {
+		return m.Category
+	}
+	return ""
+}
+
+func (m *BaseResponse) GetRequestId() string {
+	if m != nil {
+		return m.RequestId
+	}
+	return ""
+}
+
+func (m *BaseResponse) GetTraceId() string {
+	if m != nil {
+		return m.TraceId
+	}
+	return ""
+}
+
+func (m *BaseResponse) GetServerTime() int64 {
+	if m != nil {
+		return m.ServerTime
+	}
+	return 0
+}
+
+func (m *BaseResponse) GetErrorInfo() *ErrorInfo {
+	if m != nil {
+		return m.ErrorInfo
+	}
+	return nil
+}
+
 // 用户登录请求
 type LoginRequest struct {
 	Username             string   `protobuf:"bytes,1,opt,name=username,proto3" json:"username,omitempty"`
@@ -149,6 +229,14 @@ type LoginRequest struct {
 	DeviceId             string   `protobuf:"bytes,3,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
 	Platform             string   `protobuf:"bytes,4,opt,name=platform,proto3" json:"platform,omitempty"`
 	Version              string   `protobuf:"bytes,5,opt,name=version,proto3" json:"version,omitempty"`
+	ClientIp             string   `protobuf:"bytes,6,opt,name=client_ip,json=clientIp,proto3" json:"client_ip,omitempty"`
+	WorldId              uint32   `protobuf:"varint,7,opt,name=world_id,json=worldId,proto3" json:"world_id,omitempty"`
+	PushToken            string   `protobuf:"bytes,8,opt,name=push_token,json=pushToken,proto3" json:"push_token,omitempty"`
+	Language             string   `protobuf:"bytes,9,opt,name=language,proto3" json:"language,omitempty"`
+	UtcOffset            int32    `protobuf:"varint,10,opt,name=utc_offset,json=utcOffset,proto3" json:"utc_offset,omitempty"`
+	GuestId              string   `protobuf:"bytes,11,opt,name=guest_id,json=guestId,proto3" json:"guest_id,omitempty"`
+	OauthProvider        string   `protobuf:"bytes,12,opt,name=oauth_provider,json=oauthProvider,proto3" json:"oauth_provider,omitempty"`
+	OauthToken           string   `protobuf:"bytes,13,opt,name=oauth_token,json=oauthToken,proto3" json:"oauth_token,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -193,6 +281,62 @@ func (m *LoginRequest) GetVersion() string {
 	return ""
 }
 
+func (m *LoginRequest) GetClientIp() string {
+	if m != nil {
+		return m.ClientIp
+	}
+	return ""
+}
+
+func (m *LoginRequest) GetWorldId() uint32 {
+	if m != nil {
+		return m.WorldId
+	}
+	return 0
+}
+
+func (m *LoginRequest) GetPushToken() string {
+	if m != nil {
+		return m.PushToken
+	}
+	return ""
+}
+
+func (m *LoginRequest) GetLanguage() string {
+	if m != nil {
+		return m.Language
+	}
+	return ""
+}
+
+func (m *LoginRequest) GetUtcOffset() int32 {
+	if m != nil {
+		return m.UtcOffset
+	}
+	return 0
+}
+
+func (m *LoginRequest) GetGuestId() string {
+	if m != nil {
+		return m.GuestId
+	}
+	return ""
+}
+
+func (m *LoginRequest) GetOauthProvider() string {
+	if m != nil {
+		return m.OauthProvider
+	}
+	return ""
+}
+
+func (m *LoginRequest) GetOauthToken() string {
+	if m != nil {
+		return m.OauthToken
+	}
+	return ""
+}
+
 // 用户登录响应
 type LoginResponse struct {
 	UserId               uint64   `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
@@ -202,6 +346,10 @@ type LoginResponse struct {
 	Exp                  int64    `protobuf:"varint,5,opt,name=exp,proto3" json:"exp,omitempty"`
 	Gold                 int64    `protobuf:"varint,6,opt,name=gold,proto3" json:"gold,omitempty"`
 	Diamond              int64    `protobuf:"varint,7,opt,name=diamond,proto3" json:"diamond,omitempty"`
+	Region               string   `protobuf:"bytes,8,opt,name=region,proto3" json:"region,omitempty"`
+	GatewayAddr          string   `protobuf:"bytes,9,opt,name=gateway_addr,json=gatewayAddr,proto3" json:"gateway_addr,omitempty"`
+	WorldId              uint32   `protobuf:"varint,10,opt,name=world_id,json=worldId,proto3" json:"world_id,omitempty"`
+	Guest                bool     `protobuf:"varint,11,opt,name=guest,proto3" json:"guest,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -260,6 +408,34 @@ func (m *LoginResponse) GetDiamond() int64 {
 	return 0
 }
 
+func (m *LoginResponse) GetRegion() string {
+	if m != nil {
+		return m.Region
+	}
+	return ""
+}
+
+func (m *LoginResponse) GetGatewayAddr() string {
+	if m != nil {
+		return m.GatewayAddr
+	}
+	return ""
+}
+
+func (m *LoginResponse) GetWorldId() uint32 {
+	if m != nil {
+		return m.WorldId
+	}
+	return 0
+}
+
+func (m *LoginResponse) GetGuest() bool {
+	if m != nil {
+		return m.Guest
+	}
+	return false
+}
+
 // 服务器节点信息
 type NodeInfo struct {
 	NodeId               string   `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
@@ -455,6 +631,8 @@ func (m *FriendListResponse) GetFriends() []*FriendInfo {
 type StartGameRequest struct {
 	RoomId               uint64   `protobuf:"varint,1,opt,name=room_id,json=roomId,proto3" json:"room_id,omitempty"`
 	GameType             int32    `protobuf:"varint,2,opt,name=game_type,json=gameType,proto3" json:"game_type,omitempty"`
+	Async                bool     `protobuf:"varint,3,opt,name=async,proto3" json:"async,omitempty"`
+	TurnTimeout          int64    `protobuf:"varint,4,opt,name=turn_timeout,json=turnTimeout,proto3" json:"turn_timeout,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -478,6 +656,20 @@ func (m *StartGameRequest) GetGameType() int32 {
 	return 0
 }
 
+func (m *StartGameRequest) GetAsync() bool {
+	if m != nil {
+		return m.Async
+	}
+	return false
+}
+
+func (m *StartGameRequest) GetTurnTimeout() int64 {
+	if m != nil {
+		return m.TurnTimeout
+	}
+	return 0
+}
+
 // 结束游戏请求
 type EndGameRequest struct {
 	GameId               uint64   `protobuf:"varint,1,opt,name=game_id,json=gameId,proto3" json:"game_id,omitempty"`
@@ -543,6 +735,7 @@ func (m *PlayerActionRequest) GetActionData() []byte {
 // 游戏状态请求
 type GameStateRequest struct {
 	GameId               uint64   `protobuf:"varint,1,opt,name=game_id,json=gameId,proto3" json:"game_id,omitempty"`
+	KnownVersion         uint64   `protobuf:"varint,2,opt,name=known_version,json=knownVersion,proto3" json:"known_version,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -559,6 +752,13 @@ func (m *GameStateRequest) GetGameId() uint64 {
 	return 0
 }
 
+func (m *GameStateRequest) GetKnownVersion() uint64 {
+	if m != nil {
+		return m.KnownVersion
+	}
+	return 0
+}
+
 // 游戏状态响应
 type GameStateResponse struct {
 	GameId               uint64      `protobuf:"varint,1,opt,name=game_id,json=gameId,proto3" json:"game_id,omitempty"`
@@ -566,6 +766,10 @@ type GameStateResponse struct {
 	CurrentPlayer        uint64      `protobuf:"varint,3,opt,name=current_player,json=currentPlayer,proto3" json:"current_player,omitempty"`
 	Players              []*GamePlayerInfo `protobuf:"bytes,4,rep,name=players,proto3" json:"players,omitempty"`
 	GameData             []byte      `protobuf:"bytes,5,opt,name=game_data,json=gameData,proto3" json:"game_data,omitempty"`
+	GameDataType         string      `protobuf:"bytes,6,opt,name=game_data_type,json=gameDataType,proto3" json:"game_data_type,omitempty"`
+	Version              uint64      `protobuf:"varint,7,opt,name=version,proto3" json:"version,omitempty"`
+	Delta                bool        `protobuf:"varint,8,opt,name=delta,proto3" json:"delta,omitempty"`
+	TurnDeadline         int64       `protobuf:"varint,9,opt,name=turn_deadline,json=turnDeadline,proto3" json:"turn_deadline,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
 	XXX_unrecognized     []byte      `json:"-"`
 	XXX_sizecache        int32       `json:"-"`
@@ -610,6 +814,34 @@ func (m *GameStateResponse) GetGameData() []byte {
 	return nil
 }
 
+func (m *GameStateResponse) GetGameDataType() string {
+	if m != nil {
+		return m.GameDataType
+	}
+	return ""
+}
+
+func (m *GameStateResponse) GetVersion() uint64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *GameStateResponse) GetDelta() bool {
+	if m != nil {
+		return m.Delta
+	}
+	return false
+}
+
+func (m *GameStateResponse) GetTurnDeadline() int64 {
+	if m != nil {
+		return m.TurnDeadline
+	}
+	return 0
+}
+
 // 游戏玩家信息
 type GamePlayerInfo struct {
 	UserId               uint64   `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
@@ -617,6 +849,7 @@ type GamePlayerInfo struct {
 	Level                int32    `protobuf:"varint,3,opt,name=level,proto3" json:"level,omitempty"`
 	Score                int64    `protobuf:"varint,4,opt,name=score,proto3" json:"score,omitempty"`
 	Status               int32    `protobuf:"varint,5,opt,name=status,proto3" json:"status,omitempty"`
+	PartyId              uint64   `protobuf:"varint,6,opt,name=party_id,json=partyId,proto3" json:"party_id,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -661,6 +894,91 @@ func (m *GamePlayerInfo) GetStatus() int32 {
 	return 0
 }
 
+func (m *GamePlayerInfo) GetPartyId() uint64 {
+	if m != nil {
+		return m.PartyId
+	}
+	return 0
+}
+
+// 游戏操作
+type GameAction struct {
+	UserId               uint64   `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	ActionType           int32    `protobuf:"varint,2,opt,name=action_type,json=actionType,proto3" json:"action_type,omitempty"`
+	ActionData           []byte   `protobuf:"bytes,3,opt,name=action_data,json=actionData,proto3" json:"action_data,omitempty"`
+	Timestamp            uint32   `protobuf:"varint,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GameAction) Reset()         { *m = GameAction{} }
+func (m *GameAction) String() string { return proto.CompactTextString(m) }
+func (*GameAction) ProtoMessage()    {}
+
+func (m *GameAction) GetUserId() uint64 {
+	if m != nil {
+		return m.UserId
+	}
+	return 0
+}
+
+func (m *GameAction) GetActionType() int32 {
+	if m != nil {
+		return m.ActionType
+	}
+	return 0
+}
+
+func (m *GameAction) GetActionData() []byte {
+	if m != nil {
+		return m.ActionData
+	}
+	return nil
+}
+
+func (m *GameAction) GetTimestamp() uint32 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+// 卡牌对战游戏状态
+type CardBattleState struct {
+	Round                int32         `protobuf:"varint,1,opt,name=round,proto3" json:"round,omitempty"`
+	Turn                 int32         `protobuf:"varint,2,opt,name=turn,proto3" json:"turn,omitempty"`
+	RecentActions        []*GameAction `protobuf:"bytes,3,rep,name=recent_actions,json=recentActions,proto3" json:"recent_actions,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *CardBattleState) Reset()         { *m = CardBattleState{} }
+func (m *CardBattleState) String() string { return proto.CompactTextString(m) }
+func (*CardBattleState) ProtoMessage()    {}
+
+func (m *CardBattleState) GetRound() int32 {
+	if m != nil {
+		return m.Round
+	}
+	return 0
+}
+
+func (m *CardBattleState) GetTurn() int32 {
+	if m != nil {
+		return m.Turn
+	}
+	return 0
+}
+
+func (m *CardBattleState) GetRecentActions() []*GameAction {
+	if m != nil {
+		return m.RecentActions
+	}
+	return nil
+}
+
 // 创建房间请求
 type CreateRoomRequest struct {
 	RoomName             string   `protobuf:"bytes,1,opt,name=room_name,json=roomName,proto3" json:"room_name,omitempty"`
@@ -668,6 +986,7 @@ type CreateRoomRequest struct {
 	MaxPlayers           int32    `protobuf:"varint,3,opt,name=max_players,json=maxPlayers,proto3" json:"max_players,omitempty"`
 	IsPrivate            bool     `protobuf:"varint,4,opt,name=is_private,json=isPrivate,proto3" json:"is_private,omitempty"`
 	Password             string   `protobuf:"bytes,5,opt,name=password,proto3" json:"password,omitempty"`
+	EphemeralChat        bool     `protobuf:"varint,6,opt,name=ephemeral_chat,json=ephemeralChat,proto3" json:"ephemeral_chat,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -712,6 +1031,13 @@ func (m *CreateRoomRequest) GetPassword() string {
 	return ""
 }
 
+func (m *CreateRoomRequest) GetEphemeralChat() bool {
+	if m != nil {
+		return m.EphemeralChat
+	}
+	return false
+}
+
 // 加入房间请求
 type JoinRoomRequest struct {
 	RoomId               uint64   `protobuf:"varint,1,opt,name=room_id,json=roomId,proto3" json:"room_id,omitempty"`
@@ -751,6 +1077,7 @@ type RoomInfo struct {
 	Owner                *GamePlayerInfo   `protobuf:"bytes,8,opt,name=owner,proto3" json:"owner,omitempty"`
 	Players              []*GamePlayerInfo `protobuf:"bytes,9,rep,name=players,proto3" json:"players,omitempty"`
 	CreatedTime          uint32            `protobuf:"varint,10,opt,name=created_time,json=createdTime,proto3" json:"created_time,omitempty"`
+	Region               string            `protobuf:"bytes,11,opt,name=region,proto3" json:"region,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
 	XXX_unrecognized     []byte            `json:"-"`
 	XXX_sizecache        int32             `json:"-"`
@@ -830,6 +1157,13 @@ func (m *RoomInfo) GetCreatedTime() uint32 {
 	return 0
 }
 
+func (m *RoomInfo) GetRegion() string {
+	if m != nil {
+		return m.Region
+	}
+	return ""
+}
+
 // 房间列表响应
 type RoomListResponse struct {
 	Rooms                []*RoomInfo `protobuf:"bytes,1,rep,name=rooms,proto3" json:"rooms,omitempty"`
@@ -953,18 +1287,22 @@ func (m *ChatHistoryRequest) GetOffset() int32 {
 
 // 聊天消息
 type ChatMessage struct {
-	MessageId            uint64   `protobuf:"varint,1,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
-	FromUserId           uint64   `protobuf:"varint,2,opt,name=from_user_id,json=fromUserId,proto3" json:"from_user_id,omitempty"`
-	FromNickname         string   `protobuf:"bytes,3,opt,name=from_nickname,json=fromNickname,proto3" json:"from_nickname,omitempty"`
-	ToUserId             uint64   `protobuf:"varint,4,opt,name=to_user_id,json=toUserId,proto3" json:"to_user_id,omitempty"`
-	ChannelType          int32    `protobuf:"varint,5,opt,name=channel_type,json=channelType,proto3" json:"channel_type,omitempty"`
-	ChannelId            uint64   `protobuf:"varint,6,opt,name=channel_id,json=channelId,proto3" json:"channel_id,omitempty"`
-	MessageType          int32    `protobuf:"varint,7,opt,name=message_type,json=messageType,proto3" json:"message_type,omitempty"`
-	Content              string   `protobuf:"bytes,8,opt,name=content,proto3" json:"content,omitempty"`
-	SendTime             uint32   `protobuf:"varint,9,opt,name=send_time,json=sendTime,proto3" json:"send_time,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	MessageId            uint64           `protobuf:"varint,1,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	FromUserId           uint64           `protobuf:"varint,2,opt,name=from_user_id,json=fromUserId,proto3" json:"from_user_id,omitempty"`
+	FromNickname         string           `protobuf:"bytes,3,opt,name=from_nickname,json=fromNickname,proto3" json:"from_nickname,omitempty"`
+	ToUserId             uint64           `protobuf:"varint,4,opt,name=to_user_id,json=toUserId,proto3" json:"to_user_id,omitempty"`
+	ChannelType          int32            `protobuf:"varint,5,opt,name=channel_type,json=channelType,proto3" json:"channel_type,omitempty"`
+	ChannelId            uint64           `protobuf:"varint,6,opt,name=channel_id,json=channelId,proto3" json:"channel_id,omitempty"`
+	MessageType          int32            `protobuf:"varint,7,opt,name=message_type,json=messageType,proto3" json:"message_type,omitempty"`
+	Content              string           `protobuf:"bytes,8,opt,name=content,proto3" json:"content,omitempty"`
+	SendTime             uint32           `protobuf:"varint,9,opt,name=send_time,json=sendTime,proto3" json:"send_time,omitempty"`
+	EditTime             uint32           `protobuf:"varint,10,opt,name=edit_time,json=editTime,proto3" json:"edit_time,omitempty"`
+	Recalled             bool             `protobuf:"varint,11,opt,name=recalled,proto3" json:"recalled,omitempty"`
+	Reactions            []*ReactionCount `protobuf:"bytes,12,rep,name=reactions,proto3" json:"reactions,omitempty"`
+	MyReaction           int32            `protobuf:"varint,13,opt,name=my_reaction,json=myReaction,proto3" json:"my_reaction,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
 }
 
 func (m *ChatMessage) Reset()         { *m = ChatMessage{} }
@@ -1034,6 +1372,34 @@ func (m *ChatMessage) GetSendTime() uint32 {
 	return 0
 }
 
+func (m *ChatMessage) GetEditTime() uint32 {
+	if m != nil {
+		return m.EditTime
+	}
+	return 0
+}
+
+func (m *ChatMessage) GetRecalled() bool {
+	if m != nil {
+		return m.Recalled
+	}
+	return false
+}
+
+func (m *ChatMessage) GetReactions() []*ReactionCount {
+	if m != nil {
+		return m.Reactions
+	}
+	return nil
+}
+
+func (m *ChatMessage) GetMyReaction() int32 {
+	if m != nil {
+		return m.MyReaction
+	}
+	return 0
+}
+
 // 聊天历史响应
 type ChatHistoryResponse struct {
 	Messages             []*ChatMessage `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
@@ -1470,26 +1836,123 @@ func (m *UnbanUserRequest) GetTargetUserId() uint64 {
 	return 0
 }
 
-// 发送公告请求
-type SendNoticeRequest struct {
-	Title                string   `protobuf:"bytes,1,opt,name=title,proto3" json:"title,omitempty"`
-	Content              string   `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
-	NoticeType           int32    `protobuf:"varint,3,opt,name=notice_type,json=noticeType,proto3" json:"notice_type,omitempty"`
-	TargetUsers          []uint64 `protobuf:"varint,4,rep,packed,name=target_users,json=targetUsers,proto3" json:"target_users,omitempty"`
+// 用户数据导出/删除请求
+type UserDataRequest struct {
+	TargetUserId         uint64   `protobuf:"varint,1,opt,name=target_user_id,json=targetUserId,proto3" json:"target_user_id,omitempty"`
+	Reason               string   `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *SendNoticeRequest) Reset()         { *m = SendNoticeRequest{} }
-func (m *SendNoticeRequest) String() string { return proto.CompactTextString(m) }
-func (*SendNoticeRequest) ProtoMessage()    {}
+func (m *UserDataRequest) Reset()         { *m = UserDataRequest{} }
+func (m *UserDataRequest) String() string { return proto.CompactTextString(m) }
+func (*UserDataRequest) ProtoMessage()    {}
 
-func (m *SendNoticeRequest) GetTitle() string {
+func (m *UserDataRequest) GetTargetUserId() uint64 {
 	if m != nil {
-		return m.Title
+		return m.TargetUserId
 	}
-	return ""
+	return 0
+}
+
+func (m *UserDataRequest) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+// 发放回滚请求
+type RollbackGrantRequest struct {
+	BatchId              string   `protobuf:"bytes,1,opt,name=batch_id,json=batchId,proto3" json:"batch_id,omitempty"`
+	Reason               string   `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	DryRun               bool     `protobuf:"varint,3,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	SkipNotify           bool     `protobuf:"varint,4,opt,name=skip_notify,json=skipNotify,proto3" json:"skip_notify,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RollbackGrantRequest) Reset()         { *m = RollbackGrantRequest{} }
+func (m *RollbackGrantRequest) String() string { return proto.CompactTextString(m) }
+func (*RollbackGrantRequest) ProtoMessage()    {}
+
+func (m *RollbackGrantRequest) GetBatchId() string {
+	if m != nil {
+		return m.BatchId
+	}
+	return ""
+}
+
+func (m *RollbackGrantRequest) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+func (m *RollbackGrantRequest) GetDryRun() bool {
+	if m != nil {
+		return m.DryRun
+	}
+	return false
+}
+
+func (m *RollbackGrantRequest) GetSkipNotify() bool {
+	if m != nil {
+		return m.SkipNotify
+	}
+	return false
+}
+
+// 配置分阶段更新请求
+type ConfigRolloutRequest struct {
+	Nodes                []string `protobuf:"bytes,1,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	DryRun               bool     `protobuf:"varint,2,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ConfigRolloutRequest) Reset()         { *m = ConfigRolloutRequest{} }
+func (m *ConfigRolloutRequest) String() string { return proto.CompactTextString(m) }
+func (*ConfigRolloutRequest) ProtoMessage()    {}
+
+func (m *ConfigRolloutRequest) GetNodes() []string {
+	if m != nil {
+		return m.Nodes
+	}
+	return nil
+}
+
+func (m *ConfigRolloutRequest) GetDryRun() bool {
+	if m != nil {
+		return m.DryRun
+	}
+	return false
+}
+
+// 发送公告请求
+type SendNoticeRequest struct {
+	Title                string   `protobuf:"bytes,1,opt,name=title,proto3" json:"title,omitempty"`
+	Content              string   `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	NoticeType           int32    `protobuf:"varint,3,opt,name=notice_type,json=noticeType,proto3" json:"notice_type,omitempty"`
+	TargetUsers          []uint64 `protobuf:"varint,4,rep,packed,name=target_users,json=targetUsers,proto3" json:"target_users,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SendNoticeRequest) Reset()         { *m = SendNoticeRequest{} }
+func (m *SendNoticeRequest) String() string { return proto.CompactTextString(m) }
+func (*SendNoticeRequest) ProtoMessage()    {}
+
+func (m *SendNoticeRequest) GetTitle() string {
+	if m != nil {
+		return m.Title
+	}
+	return ""
 }
 
 func (m *SendNoticeRequest) GetContent() string {
@@ -1521,6 +1984,12 @@ type ServiceInfo struct {
 	Port                 int32    `protobuf:"varint,4,opt,name=port,proto3" json:"port,omitempty"`
 	Status               string   `protobuf:"bytes,5,opt,name=status,proto3" json:"status,omitempty"`
 	LastHeartbeat        uint32   `protobuf:"varint,6,opt,name=last_heartbeat,json=lastHeartbeat,proto3" json:"last_heartbeat,omitempty"`
+	Version              string   `protobuf:"bytes,7,opt,name=version,proto3" json:"version,omitempty"`
+	GitCommit            string   `protobuf:"bytes,8,opt,name=git_commit,json=gitCommit,proto3" json:"git_commit,omitempty"`
+	BuildTime            string   `protobuf:"bytes,9,opt,name=build_time,json=buildTime,proto3" json:"build_time,omitempty"`
+	ProtoSchema          uint32   `protobuf:"varint,10,opt,name=proto_schema,json=protoSchema,proto3" json:"proto_schema,omitempty"`
+	Canary               bool     `protobuf:"varint,11,opt,name=canary,proto3" json:"canary,omitempty"`
+	CanaryWeight         int32    `protobuf:"varint,12,opt,name=canary_weight,json=canaryWeight,proto3" json:"canary_weight,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -1572,6 +2041,48 @@ func (m *ServiceInfo) GetLastHeartbeat() uint32 {
 	return 0
 }
 
+func (m *ServiceInfo) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+func (m *ServiceInfo) GetGitCommit() string {
+	if m != nil {
+		return m.GitCommit
+	}
+	return ""
+}
+
+func (m *ServiceInfo) GetBuildTime() string {
+	if m != nil {
+		return m.BuildTime
+	}
+	return ""
+}
+
+func (m *ServiceInfo) GetProtoSchema() uint32 {
+	if m != nil {
+		return m.ProtoSchema
+	}
+	return 0
+}
+
+func (m *ServiceInfo) GetCanary() bool {
+	if m != nil {
+		return m.Canary
+	}
+	return false
+}
+
+func (m *ServiceInfo) GetCanaryWeight() int32 {
+	if m != nil {
+		return m.CanaryWeight
+	}
+	return 0
+}
+
 // 服务列表响应
 type ServiceListResponse struct {
 	Services             []*ServiceInfo `protobuf:"bytes,1,rep,name=services,proto3" json:"services,omitempty"`
@@ -1605,6 +2116,8 @@ type ClusterStatusResponse struct {
 	OnlineServices       int32                      `protobuf:"varint,2,opt,name=online_services,json=onlineServices,proto3" json:"online_services,omitempty"`
 	ServiceStats         map[string]int32           `protobuf:"bytes,3,rep,name=service_stats,json=serviceStats,proto3" json:"service_stats,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
 	SystemInfo           *SystemInfo                `protobuf:"bytes,4,opt,name=system_info,json=systemInfo,proto3" json:"system_info,omitempty"`
+	Versions             map[string]int32           `protobuf:"bytes,5,rep,name=versions,proto3" json:"versions,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	VersionWarnings      []string                   `protobuf:"bytes,6,rep,name=version_warnings,json=versionWarnings,proto3" json:"version_warnings,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}                   `json:"-"`
 	XXX_unrecognized     []byte                     `json:"-"`
 	XXX_sizecache        int32                      `json:"-"`
@@ -1642,6 +2155,20 @@ func (m *ClusterStatusResponse) GetSystemInfo() *SystemInfo {
 	return nil
 }
 
+func (m *ClusterStatusResponse) GetVersions() map[string]int32 {
+	if m != nil {
+		return m.Versions
+	}
+	return nil
+}
+
+func (m *ClusterStatusResponse) GetVersionWarnings() []string {
+	if m != nil {
+		return m.VersionWarnings
+	}
+	return nil
+}
+
 // 系统信息
 type SystemInfo struct {
 	CpuUsage             float32  `protobuf:"fixed32,1,opt,name=cpu_usage,json=cpuUsage,proto3" json:"cpu_usage,omitempty"`
@@ -1755,46 +2282,3394 @@ func (m *ServiceOperationRequest) GetOperation() string {
 	return ""
 }
 
-// 通用消息接口
-type Message interface {
-	proto.Message
+// 灰度设置请求
+type CanaryRequest struct {
+	ServiceId            string   `protobuf:"bytes,1,opt,name=service_id,json=serviceId,proto3" json:"service_id,omitempty"`
+	Canary               bool     `protobuf:"varint,2,opt,name=canary,proto3" json:"canary,omitempty"`
+	Weight               int32    `protobuf:"varint,3,opt,name=weight,proto3" json:"weight,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-// 通用响应消息
-type CommonResponse struct {
-	Code                 int32    `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+func (m *CanaryRequest) Reset()         { *m = CanaryRequest{} }
+func (m *CanaryRequest) String() string { return proto.CompactTextString(m) }
+func (*CanaryRequest) ProtoMessage()    {}
+
+func (m *CanaryRequest) GetServiceId() string {
+	if m != nil {
+		return m.ServiceId
+	}
+	return ""
+}
+
+func (m *CanaryRequest) GetCanary() bool {
+	if m != nil {
+		return m.Canary
+	}
+	return false
+}
+
+func (m *CanaryRequest) GetWeight() int32 {
+	if m != nil {
+		return m.Weight
+	}
+	return 0
+}
+
+// 维护模式请求
+type MaintenanceRequest struct {
+	Enabled              bool     `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`
 	Message              string   `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
-	Data                 []byte   `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *CommonResponse) Reset()         { *m = CommonResponse{} }
-func (m *CommonResponse) String() string { return proto.CompactTextString(m) }
-func (*CommonResponse) ProtoMessage()    {}
+func (m *MaintenanceRequest) Reset()         { *m = MaintenanceRequest{} }
+func (m *MaintenanceRequest) String() string { return proto.CompactTextString(m) }
+func (*MaintenanceRequest) ProtoMessage()    {}
 
-func (m *CommonResponse) GetCode() int32 {
+func (m *MaintenanceRequest) GetEnabled() bool {
 	if m != nil {
-		return m.Code
+		return m.Enabled
 	}
-	return 0
+	return false
 }
 
-func (m *CommonResponse) GetMessage() string {
+func (m *MaintenanceRequest) GetMessage() string {
 	if m != nil {
 		return m.Message
 	}
 	return ""
 }
 
-func (m *CommonResponse) GetData() []byte {
+// 握手请求
+type HandshakeRequest struct {
+	ProtocolVersion      uint32   `protobuf:"varint,1,opt,name=protocol_version,json=protocolVersion,proto3" json:"protocol_version,omitempty"`
+	ClientVersion        string   `protobuf:"bytes,2,opt,name=client_version,json=clientVersion,proto3" json:"client_version,omitempty"`
+	Platform             string   `protobuf:"bytes,3,opt,name=platform,proto3" json:"platform,omitempty"`
+	Language             string   `protobuf:"bytes,4,opt,name=language,proto3" json:"language,omitempty"`
+	Codec                string   `protobuf:"bytes,5,opt,name=codec,proto3" json:"codec,omitempty"`
+	PublicKey            []byte   `protobuf:"bytes,6,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *HandshakeRequest) Reset()         { *m = HandshakeRequest{} }
+func (m *HandshakeRequest) String() string { return proto.CompactTextString(m) }
+func (*HandshakeRequest) ProtoMessage()    {}
+
+func (m *HandshakeRequest) GetProtocolVersion() uint32 {
 	if m != nil {
-		return m.Data
+		return m.ProtocolVersion
+	}
+	return 0
+}
+
+func (m *HandshakeRequest) GetClientVersion() string {
+	if m != nil {
+		return m.ClientVersion
+	}
+	return ""
+}
+
+func (m *HandshakeRequest) GetPlatform() string {
+	if m != nil {
+		return m.Platform
+	}
+	return ""
+}
+
+func (m *HandshakeRequest) GetLanguage() string {
+	if m != nil {
+		return m.Language
+	}
+	return ""
+}
+
+func (m *HandshakeRequest) GetCodec() string {
+	if m != nil {
+		return m.Codec
+	}
+	return ""
+}
+
+func (m *HandshakeRequest) GetPublicKey() []byte {
+	if m != nil {
+		return m.PublicKey
+	}
+	return nil
+}
+
+// 握手响应
+type HandshakeResponse struct {
+	ProtocolVersion      uint32   `protobuf:"varint,1,opt,name=protocol_version,json=protocolVersion,proto3" json:"protocol_version,omitempty"`
+	MinProtocolVersion   uint32   `protobuf:"varint,2,opt,name=min_protocol_version,json=minProtocolVersion,proto3" json:"min_protocol_version,omitempty"`
+	MaxProtocolVersion   uint32   `protobuf:"varint,3,opt,name=max_protocol_version,json=maxProtocolVersion,proto3" json:"max_protocol_version,omitempty"`
+	Codec                string   `protobuf:"bytes,4,opt,name=codec,proto3" json:"codec,omitempty"`
+	FrameIntegrity       string   `protobuf:"bytes,6,opt,name=frame_integrity,json=frameIntegrity,proto3" json:"frame_integrity,omitempty"`
+	PublicKey            []byte   `protobuf:"bytes,7,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *HandshakeResponse) Reset()         { *m = HandshakeResponse{} }
+func (m *HandshakeResponse) String() string { return proto.CompactTextString(m) }
+func (*HandshakeResponse) ProtoMessage()    {}
+
+func (m *HandshakeResponse) GetProtocolVersion() uint32 {
+	if m != nil {
+		return m.ProtocolVersion
+	}
+	return 0
+}
+
+func (m *HandshakeResponse) GetMinProtocolVersion() uint32 {
+	if m != nil {
+		return m.MinProtocolVersion
+	}
+	return 0
+}
+
+func (m *HandshakeResponse) GetMaxProtocolVersion() uint32 {
+	if m != nil {
+		return m.MaxProtocolVersion
+	}
+	return 0
+}
+
+func (m *HandshakeResponse) GetCodec() string {
+	if m != nil {
+		return m.Codec
+	}
+	return ""
+}
+
+func (m *HandshakeResponse) GetFrameIntegrity() string {
+	if m != nil {
+		return m.FrameIntegrity
+	}
+	return ""
+}
+
+func (m *HandshakeResponse) GetPublicKey() []byte {
+	if m != nil {
+		return m.PublicKey
 	}
 	return nil
 }
 
+// 传输协商请求
+type TransportRequest struct {
+	GameType             string   `protobuf:"bytes,1,opt,name=game_type,json=gameType,proto3" json:"game_type,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TransportRequest) Reset()         { *m = TransportRequest{} }
+func (m *TransportRequest) String() string { return proto.CompactTextString(m) }
+func (*TransportRequest) ProtoMessage()    {}
+
+func (m *TransportRequest) GetGameType() string {
+	if m != nil {
+		return m.GameType
+	}
+	return ""
+}
+
+// 传输协商响应
+type TransportResponse struct {
+	Transport            string   `protobuf:"bytes,1,opt,name=transport,proto3" json:"transport,omitempty"`
+	Port                 uint32   `protobuf:"varint,2,opt,name=port,proto3" json:"port,omitempty"`
+	Conv                 uint32   `protobuf:"varint,3,opt,name=conv,proto3" json:"conv,omitempty"`
+	Token                []byte   `protobuf:"bytes,4,opt,name=token,proto3" json:"token,omitempty"`
+	Mtu                  uint32   `protobuf:"varint,5,opt,name=mtu,proto3" json:"mtu,omitempty"`
+	BindTimeoutMs        uint32   `protobuf:"varint,6,opt,name=bind_timeout_ms,json=bindTimeoutMs,proto3" json:"bind_timeout_ms,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TransportResponse) Reset()         { *m = TransportResponse{} }
+func (m *TransportResponse) String() string { return proto.CompactTextString(m) }
+func (*TransportResponse) ProtoMessage()    {}
+
+func (m *TransportResponse) GetTransport() string {
+	if m != nil {
+		return m.Transport
+	}
+	return ""
+}
+
+func (m *TransportResponse) GetPort() uint32 {
+	if m != nil {
+		return m.Port
+	}
+	return 0
+}
+
+func (m *TransportResponse) GetConv() uint32 {
+	if m != nil {
+		return m.Conv
+	}
+	return 0
+}
+
+func (m *TransportResponse) GetToken() []byte {
+	if m != nil {
+		return m.Token
+	}
+	return nil
+}
+
+func (m *TransportResponse) GetMtu() uint32 {
+	if m != nil {
+		return m.Mtu
+	}
+	return 0
+}
+
+func (m *TransportResponse) GetBindTimeoutMs() uint32 {
+	if m != nil {
+		return m.BindTimeoutMs
+	}
+	return 0
+}
+
+// 通用消息接口
+type Message interface {
+	proto.Message
+}
+
+// 通用响应消息
+type CommonResponse struct {
+	Code                 int32      `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message              string     `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Data                 []byte     `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	Category             string     `protobuf:"bytes,4,opt,name=category,proto3" json:"category,omitempty"`
+	RequestId            string     `protobuf:"bytes,5,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	TraceId              string     `protobuf:"bytes,6,opt,name=trace_id,json=traceId,proto3" json:"trace_id,omitempty"`
+	ServerTime           int64      `protobuf:"varint,7,opt,name=server_time,json=serverTime,proto3" json:"server_time,omitempty"`
+	ErrorInfo            *ErrorInfo `protobuf:"bytes,8,opt,name=error_info,json=errorInfo,proto3" json:"error_info,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *CommonResponse) Reset()         { *m = CommonResponse{} }
+func (m *CommonResponse) String() string { return proto.CompactTextString(m) }
+func (*CommonResponse) ProtoMessage()    {}
+
+func (m *CommonResponse) GetCode() int32 {
+	if m != nil {
+		return m.Code
+	}
+	return 0
+}
+
+func (m *CommonResponse) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+func (m *CommonResponse) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func (m *CommonResponse) GetCategory() string {
+	if m != nil {
+		return m.Category
+	}
+	return ""
+}
+
+func (m *CommonResponse) GetRequestId() string {
+	if m != nil {
+		return m.RequestId
+	}
+	return ""
+}
+
+func (m *CommonResponse) GetTraceId() string {
+	if m != nil {
+		return m.TraceId
+	}
+	return ""
+}
+
+func (m *CommonResponse) GetServerTime() int64 {
+	if m != nil {
+		return m.ServerTime
+	}
+	return 0
+}
+
+func (m *CommonResponse) GetErrorInfo() *ErrorInfo {
+	if m != nil {
+		return m.ErrorInfo
+	}
+	return nil
+}
+
+// 用户通知
+type Notification struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type                 string   `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	FromUserId           uint64   `protobuf:"varint,3,opt,name=from_user_id,json=fromUserId,proto3" json:"from_user_id,omitempty"`
+	Data                 []byte   `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	CreatedAt            int64    `protobuf:"varint,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Notification) Reset()         { *m = Notification{} }
+func (m *Notification) String() string { return proto.CompactTextString(m) }
+func (*Notification) ProtoMessage()    {}
+
+func (m *Notification) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Notification) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *Notification) GetFromUserId() uint64 {
+	if m != nil {
+		return m.FromUserId
+	}
+	return 0
+}
+
+func (m *Notification) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func (m *Notification) GetCreatedAt() int64 {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return 0
+}
+
+// 通知推送
+type NotificationPush struct {
+	Notifications        []*Notification `protobuf:"bytes,1,rep,name=notifications,proto3" json:"notifications,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *NotificationPush) Reset()         { *m = NotificationPush{} }
+func (m *NotificationPush) String() string { return proto.CompactTextString(m) }
+func (*NotificationPush) ProtoMessage()    {}
+
+func (m *NotificationPush) GetNotifications() []*Notification {
+	if m != nil {
+		return m.Notifications
+	}
+	return nil
+}
+
+// 异步对局信息
+type AsyncGameInfo struct {
+	GameId               uint64            `protobuf:"varint,1,opt,name=game_id,json=gameId,proto3" json:"game_id,omitempty"`
+	RoomId               uint64            `protobuf:"varint,2,opt,name=room_id,json=roomId,proto3" json:"room_id,omitempty"`
+	GameType             int32             `protobuf:"varint,3,opt,name=game_type,json=gameType,proto3" json:"game_type,omitempty"`
+	CurrentPlayer        uint64            `protobuf:"varint,4,opt,name=current_player,json=currentPlayer,proto3" json:"current_player,omitempty"`
+	TurnDeadline         int64             `protobuf:"varint,5,opt,name=turn_deadline,json=turnDeadline,proto3" json:"turn_deadline,omitempty"`
+	YourTurn             bool              `protobuf:"varint,6,opt,name=your_turn,json=yourTurn,proto3" json:"your_turn,omitempty"`
+	Players              []*GamePlayerInfo `protobuf:"bytes,7,rep,name=players,proto3" json:"players,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *AsyncGameInfo) Reset()         { *m = AsyncGameInfo{} }
+func (m *AsyncGameInfo) String() string { return proto.CompactTextString(m) }
+func (*AsyncGameInfo) ProtoMessage()    {}
+
+func (m *AsyncGameInfo) GetGameId() uint64 {
+	if m != nil {
+		return m.GameId
+	}
+	return 0
+}
+
+func (m *AsyncGameInfo) GetRoomId() uint64 {
+	if m != nil {
+		return m.RoomId
+	}
+	return 0
+}
+
+func (m *AsyncGameInfo) GetGameType() int32 {
+	if m != nil {
+		return m.GameType
+	}
+	return 0
+}
+
+func (m *AsyncGameInfo) GetCurrentPlayer() uint64 {
+	if m != nil {
+		return m.CurrentPlayer
+	}
+	return 0
+}
+
+func (m *AsyncGameInfo) GetTurnDeadline() int64 {
+	if m != nil {
+		return m.TurnDeadline
+	}
+	return 0
+}
+
+func (m *AsyncGameInfo) GetYourTurn() bool {
+	if m != nil {
+		return m.YourTurn
+	}
+	return false
+}
+
+func (m *AsyncGameInfo) GetPlayers() []*GamePlayerInfo {
+	if m != nil {
+		return m.Players
+	}
+	return nil
+}
+
+// 进行中的异步对局列表
+type AsyncGameListResponse struct {
+	Games                []*AsyncGameInfo `protobuf:"bytes,1,rep,name=games,proto3" json:"games,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *AsyncGameListResponse) Reset()         { *m = AsyncGameListResponse{} }
+func (m *AsyncGameListResponse) String() string { return proto.CompactTextString(m) }
+func (*AsyncGameListResponse) ProtoMessage()    {}
+
+func (m *AsyncGameListResponse) GetGames() []*AsyncGameInfo {
+	if m != nil {
+		return m.Games
+	}
+	return nil
+}
+
+// 限时活动信息
+type ActivityInfo struct {
+	Id                   string             `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name                 string             `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Description          string             `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	StartTime            int64              `protobuf:"varint,4,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
+	EndTime              int64              `protobuf:"varint,5,opt,name=end_time,json=endTime,proto3" json:"end_time,omitempty"`
+	Multipliers          map[string]float64 `protobuf:"bytes,6,rep,name=multipliers,proto3" json:"multipliers,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"fixed64,2,opt,name=value,proto3"`
+	GameTypes            []int32            `protobuf:"varint,7,rep,packed,name=game_types,json=gameTypes,proto3" json:"game_types,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *ActivityInfo) Reset()         { *m = ActivityInfo{} }
+func (m *ActivityInfo) String() string { return proto.CompactTextString(m) }
+func (*ActivityInfo) ProtoMessage()    {}
+
+func (m *ActivityInfo) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *ActivityInfo) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *ActivityInfo) GetDescription() string {
+	if m != nil {
+		return m.Description
+	}
+	return ""
+}
+
+func (m *ActivityInfo) GetStartTime() int64 {
+	if m != nil {
+		return m.StartTime
+	}
+	return 0
+}
+
+func (m *ActivityInfo) GetEndTime() int64 {
+	if m != nil {
+		return m.EndTime
+	}
+	return 0
+}
+
+func (m *ActivityInfo) GetMultipliers() map[string]float64 {
+	if m != nil {
+		return m.Multipliers
+	}
+	return nil
+}
+
+func (m *ActivityInfo) GetGameTypes() []int32 {
+	if m != nil {
+		return m.GameTypes
+	}
+	return nil
+}
+
+// 进行中的活动列表
+type ActiveEventsResponse struct {
+	Events               []*ActivityInfo `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"`
+	ServerTime           int64           `protobuf:"varint,2,opt,name=server_time,json=serverTime,proto3" json:"server_time,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *ActiveEventsResponse) Reset()         { *m = ActiveEventsResponse{} }
+func (m *ActiveEventsResponse) String() string { return proto.CompactTextString(m) }
+func (*ActiveEventsResponse) ProtoMessage()    {}
+
+func (m *ActiveEventsResponse) GetEvents() []*ActivityInfo {
+	if m != nil {
+		return m.Events
+	}
+	return nil
+}
+
+func (m *ActiveEventsResponse) GetServerTime() int64 {
+	if m != nil {
+		return m.ServerTime
+	}
+	return 0
+}
+
+// 活动开始或结束推送
+type ActivityStatePush struct {
+	State                string        `protobuf:"bytes,1,opt,name=state,proto3" json:"state,omitempty"`
+	Activity             *ActivityInfo `protobuf:"bytes,2,opt,name=activity,proto3" json:"activity,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *ActivityStatePush) Reset()         { *m = ActivityStatePush{} }
+func (m *ActivityStatePush) String() string { return proto.CompactTextString(m) }
+func (*ActivityStatePush) ProtoMessage()    {}
+
+func (m *ActivityStatePush) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *ActivityStatePush) GetActivity() *ActivityInfo {
+	if m != nil {
+		return m.Activity
+	}
+	return nil
+}
+
+// 玩家等级进度
+type ProgressionResponse struct {
+	Level                int32            `protobuf:"varint,1,opt,name=level,proto3" json:"level,omitempty"`
+	Experience           int64            `protobuf:"varint,2,opt,name=experience,proto3" json:"experience,omitempty"`
+	LevelExperience      int64            `protobuf:"varint,3,opt,name=level_experience,json=levelExperience,proto3" json:"level_experience,omitempty"`
+	NextLevelExperience  int64            `protobuf:"varint,4,opt,name=next_level_experience,json=nextLevelExperience,proto3" json:"next_level_experience,omitempty"`
+	MaxLevel             int32            `protobuf:"varint,5,opt,name=max_level,json=maxLevel,proto3" json:"max_level,omitempty"`
+	Features             map[string]int32 `protobuf:"bytes,6,rep,name=features,proto3" json:"features,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *ProgressionResponse) Reset()         { *m = ProgressionResponse{} }
+func (m *ProgressionResponse) String() string { return proto.CompactTextString(m) }
+func (*ProgressionResponse) ProtoMessage()    {}
+
+func (m *ProgressionResponse) GetLevel() int32 {
+	if m != nil {
+		return m.Level
+	}
+	return 0
+}
+
+func (m *ProgressionResponse) GetExperience() int64 {
+	if m != nil {
+		return m.Experience
+	}
+	return 0
+}
+
+func (m *ProgressionResponse) GetLevelExperience() int64 {
+	if m != nil {
+		return m.LevelExperience
+	}
+	return 0
+}
+
+func (m *ProgressionResponse) GetNextLevelExperience() int64 {
+	if m != nil {
+		return m.NextLevelExperience
+	}
+	return 0
+}
+
+func (m *ProgressionResponse) GetMaxLevel() int32 {
+	if m != nil {
+		return m.MaxLevel
+	}
+	return 0
+}
+
+func (m *ProgressionResponse) GetFeatures() map[string]int32 {
+	if m != nil {
+		return m.Features
+	}
+	return nil
+}
+
+// 货币兑换请求
+type ExchangeCurrencyRequest struct {
+	From                 string   `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	To                   string   `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+	Amount               int64    `protobuf:"varint,3,opt,name=amount,proto3" json:"amount,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ExchangeCurrencyRequest) Reset()         { *m = ExchangeCurrencyRequest{} }
+func (m *ExchangeCurrencyRequest) String() string { return proto.CompactTextString(m) }
+func (*ExchangeCurrencyRequest) ProtoMessage()    {}
+
+func (m *ExchangeCurrencyRequest) GetFrom() string {
+	if m != nil {
+		return m.From
+	}
+	return ""
+}
+
+func (m *ExchangeCurrencyRequest) GetTo() string {
+	if m != nil {
+		return m.To
+	}
+	return ""
+}
+
+func (m *ExchangeCurrencyRequest) GetAmount() int64 {
+	if m != nil {
+		return m.Amount
+	}
+	return 0
+}
+
+// 货币兑换结果
+type ExchangeCurrencyResponse struct {
+	Spent                int64    `protobuf:"varint,1,opt,name=spent,proto3" json:"spent,omitempty"`
+	Received             int64    `protobuf:"varint,2,opt,name=received,proto3" json:"received,omitempty"`
+	Tax                  int64    `protobuf:"varint,3,opt,name=tax,proto3" json:"tax,omitempty"`
+	DailyUsed            int64    `protobuf:"varint,4,opt,name=daily_used,json=dailyUsed,proto3" json:"daily_used,omitempty"`
+	DailyLimit           int64    `protobuf:"varint,5,opt,name=daily_limit,json=dailyLimit,proto3" json:"daily_limit,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ExchangeCurrencyResponse) Reset()         { *m = ExchangeCurrencyResponse{} }
+func (m *ExchangeCurrencyResponse) String() string { return proto.CompactTextString(m) }
+func (*ExchangeCurrencyResponse) ProtoMessage()    {}
+
+func (m *ExchangeCurrencyResponse) GetSpent() int64 {
+	if m != nil {
+		return m.Spent
+	}
+	return 0
+}
+
+func (m *ExchangeCurrencyResponse) GetReceived() int64 {
+	if m != nil {
+		return m.Received
+	}
+	return 0
+}
+
+func (m *ExchangeCurrencyResponse) GetTax() int64 {
+	if m != nil {
+		return m.Tax
+	}
+	return 0
+}
+
+func (m *ExchangeCurrencyResponse) GetDailyUsed() int64 {
+	if m != nil {
+		return m.DailyUsed
+	}
+	return 0
+}
+
+func (m *ExchangeCurrencyResponse) GetDailyLimit() int64 {
+	if m != nil {
+		return m.DailyLimit
+	}
+	return 0
+}
+
+// 功能开关和实验分组
+type FeatureFlagsResponse struct {
+	Flags                map[string]bool   `protobuf:"bytes,1,rep,name=flags,proto3" json:"flags,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	Variants             map[string]string `protobuf:"bytes,2,rep,name=variants,proto3" json:"variants,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *FeatureFlagsResponse) Reset()         { *m = FeatureFlagsResponse{} }
+func (m *FeatureFlagsResponse) String() string { return proto.CompactTextString(m) }
+func (*FeatureFlagsResponse) ProtoMessage()    {}
+
+func (m *FeatureFlagsResponse) GetFlags() map[string]bool {
+	if m != nil {
+		return m.Flags
+	}
+	return nil
+}
+
+func (m *FeatureFlagsResponse) GetVariants() map[string]string {
+	if m != nil {
+		return m.Variants
+	}
+	return nil
+}
+
+// 邮件模板
+type MailTemplate struct {
+	Title                string   `protobuf:"bytes,1,opt,name=title,proto3" json:"title,omitempty"`
+	Content              string   `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MailTemplate) Reset()         { *m = MailTemplate{} }
+func (m *MailTemplate) String() string { return proto.CompactTextString(m) }
+func (*MailTemplate) ProtoMessage()    {}
+
+func (m *MailTemplate) GetTitle() string {
+	if m != nil {
+		return m.Title
+	}
+	return ""
+}
+
+func (m *MailTemplate) GetContent() string {
+	if m != nil {
+		return m.Content
+	}
+	return ""
+}
+
+// 创建邮件活动请求
+type MailCampaignRequest struct {
+	Segment              string                   `protobuf:"bytes,1,opt,name=segment,proto3" json:"segment,omitempty"`
+	Language             string                   `protobuf:"bytes,2,opt,name=language,proto3" json:"language,omitempty"`
+	Templates            map[string]*MailTemplate `protobuf:"bytes,3,rep,name=templates,proto3" json:"templates,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Rewards              []*Reward                `protobuf:"bytes,4,rep,name=rewards,proto3" json:"rewards,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                 `json:"-"`
+	XXX_unrecognized     []byte                   `json:"-"`
+	XXX_sizecache        int32                    `json:"-"`
+}
+
+func (m *MailCampaignRequest) Reset()         { *m = MailCampaignRequest{} }
+func (m *MailCampaignRequest) String() string { return proto.CompactTextString(m) }
+func (*MailCampaignRequest) ProtoMessage()    {}
+
+func (m *MailCampaignRequest) GetSegment() string {
+	if m != nil {
+		return m.Segment
+	}
+	return ""
+}
+
+func (m *MailCampaignRequest) GetLanguage() string {
+	if m != nil {
+		return m.Language
+	}
+	return ""
+}
+
+func (m *MailCampaignRequest) GetTemplates() map[string]*MailTemplate {
+	if m != nil {
+		return m.Templates
+	}
+	return nil
+}
+
+func (m *MailCampaignRequest) GetRewards() []*Reward {
+	if m != nil {
+		return m.Rewards
+	}
+	return nil
+}
+
+// 邮件活动查询或操作请求
+type MailCampaignQuery struct {
+	CampaignId           string   `protobuf:"bytes,1,opt,name=campaign_id,json=campaignId,proto3" json:"campaign_id,omitempty"`
+	Action               string   `protobuf:"bytes,2,opt,name=action,proto3" json:"action,omitempty"` // pause, resume, cancel
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MailCampaignQuery) Reset()         { *m = MailCampaignQuery{} }
+func (m *MailCampaignQuery) String() string { return proto.CompactTextString(m) }
+func (*MailCampaignQuery) ProtoMessage()    {}
+
+func (m *MailCampaignQuery) GetCampaignId() string {
+	if m != nil {
+		return m.CampaignId
+	}
+	return ""
+}
+
+func (m *MailCampaignQuery) GetAction() string {
+	if m != nil {
+		return m.Action
+	}
+	return ""
+}
+
+// 公告内容
+type AnnouncementContent struct {
+	Title                string   `protobuf:"bytes,1,opt,name=title,proto3" json:"title,omitempty"`
+	Content              string   `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AnnouncementContent) Reset()         { *m = AnnouncementContent{} }
+func (m *AnnouncementContent) String() string { return proto.CompactTextString(m) }
+func (*AnnouncementContent) ProtoMessage()    {}
+
+func (m *AnnouncementContent) GetTitle() string {
+	if m != nil {
+		return m.Title
+	}
+	return ""
+}
+
+func (m *AnnouncementContent) GetContent() string {
+	if m != nil {
+		return m.Content
+	}
+	return ""
+}
+
+// 创建公告请求
+type AnnouncementRequest struct {
+	Channels             []string                        `protobuf:"bytes,1,rep,name=channels,proto3" json:"channels,omitempty"`
+	Language             string                          `protobuf:"bytes,2,opt,name=language,proto3" json:"language,omitempty"`
+	Contents             map[string]*AnnouncementContent `protobuf:"bytes,3,rep,name=contents,proto3" json:"contents,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Priority             int32                           `protobuf:"varint,4,opt,name=priority,proto3" json:"priority,omitempty"`
+	StartTime            int64                           `protobuf:"varint,5,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
+	EndTime              int64                           `protobuf:"varint,6,opt,name=end_time,json=endTime,proto3" json:"end_time,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                        `json:"-"`
+	XXX_unrecognized     []byte                          `json:"-"`
+	XXX_sizecache        int32                           `json:"-"`
+}
+
+func (m *AnnouncementRequest) Reset()         { *m = AnnouncementRequest{} }
+func (m *AnnouncementRequest) String() string { return proto.CompactTextString(m) }
+func (*AnnouncementRequest) ProtoMessage()    {}
+
+func (m *AnnouncementRequest) GetChannels() []string {
+	if m != nil {
+		return m.Channels
+	}
+	return nil
+}
+
+func (m *AnnouncementRequest) GetLanguage() string {
+	if m != nil {
+		return m.Language
+	}
+	return ""
+}
+
+func (m *AnnouncementRequest) GetContents() map[string]*AnnouncementContent {
+	if m != nil {
+		return m.Contents
+	}
+	return nil
+}
+
+func (m *AnnouncementRequest) GetPriority() int32 {
+	if m != nil {
+		return m.Priority
+	}
+	return 0
+}
+
+func (m *AnnouncementRequest) GetStartTime() int64 {
+	if m != nil {
+		return m.StartTime
+	}
+	return 0
+}
+
+func (m *AnnouncementRequest) GetEndTime() int64 {
+	if m != nil {
+		return m.EndTime
+	}
+	return 0
+}
+
+// 公告查询或删除请求
+type AnnouncementQuery struct {
+	AnnouncementId       string   `protobuf:"bytes,1,opt,name=announcement_id,json=announcementId,proto3" json:"announcement_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AnnouncementQuery) Reset()         { *m = AnnouncementQuery{} }
+func (m *AnnouncementQuery) String() string { return proto.CompactTextString(m) }
+func (*AnnouncementQuery) ProtoMessage()    {}
+
+func (m *AnnouncementQuery) GetAnnouncementId() string {
+	if m != nil {
+		return m.AnnouncementId
+	}
+	return ""
+}
+
+// 按玩家语言选择内容后的公告，也用于跑马灯推送
+type AnnouncementInfo struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Title                string   `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	Content              string   `protobuf:"bytes,3,opt,name=content,proto3" json:"content,omitempty"`
+	Channels             []string `protobuf:"bytes,4,rep,name=channels,proto3" json:"channels,omitempty"`
+	Priority             int32    `protobuf:"varint,5,opt,name=priority,proto3" json:"priority,omitempty"`
+	StartTime            int64    `protobuf:"varint,6,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
+	EndTime              int64    `protobuf:"varint,7,opt,name=end_time,json=endTime,proto3" json:"end_time,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AnnouncementInfo) Reset()         { *m = AnnouncementInfo{} }
+func (m *AnnouncementInfo) String() string { return proto.CompactTextString(m) }
+func (*AnnouncementInfo) ProtoMessage()    {}
+
+func (m *AnnouncementInfo) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *AnnouncementInfo) GetTitle() string {
+	if m != nil {
+		return m.Title
+	}
+	return ""
+}
+
+func (m *AnnouncementInfo) GetContent() string {
+	if m != nil {
+		return m.Content
+	}
+	return ""
+}
+
+func (m *AnnouncementInfo) GetChannels() []string {
+	if m != nil {
+		return m.Channels
+	}
+	return nil
+}
+
+func (m *AnnouncementInfo) GetPriority() int32 {
+	if m != nil {
+		return m.Priority
+	}
+	return 0
+}
+
+func (m *AnnouncementInfo) GetStartTime() int64 {
+	if m != nil {
+		return m.StartTime
+	}
+	return 0
+}
+
+func (m *AnnouncementInfo) GetEndTime() int64 {
+	if m != nil {
+		return m.EndTime
+	}
+	return 0
+}
+
+// 获取公告请求
+type AnnouncementsRequest struct {
+	Channel              string   `protobuf:"bytes,1,opt,name=channel,proto3" json:"channel,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AnnouncementsRequest) Reset()         { *m = AnnouncementsRequest{} }
+func (m *AnnouncementsRequest) String() string { return proto.CompactTextString(m) }
+func (*AnnouncementsRequest) ProtoMessage()    {}
+
+func (m *AnnouncementsRequest) GetChannel() string {
+	if m != nil {
+		return m.Channel
+	}
+	return ""
+}
+
+// 进行中的公告
+type AnnouncementsResponse struct {
+	Announcements        []*AnnouncementInfo `protobuf:"bytes,1,rep,name=announcements,proto3" json:"announcements,omitempty"`
+	ServerTime           int64               `protobuf:"varint,2,opt,name=server_time,json=serverTime,proto3" json:"server_time,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
+}
+
+func (m *AnnouncementsResponse) Reset()         { *m = AnnouncementsResponse{} }
+func (m *AnnouncementsResponse) String() string { return proto.CompactTextString(m) }
+func (*AnnouncementsResponse) ProtoMessage()    {}
+
+func (m *AnnouncementsResponse) GetAnnouncements() []*AnnouncementInfo {
+	if m != nil {
+		return m.Announcements
+	}
+	return nil
+}
+
+func (m *AnnouncementsResponse) GetServerTime() int64 {
+	if m != nil {
+		return m.ServerTime
+	}
+	return 0
+}
+
+// 问卷题目，类型为single、multiple、rating或text
+type SurveyQuestion struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type                 string   `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Text                 string   `protobuf:"bytes,3,opt,name=text,proto3" json:"text,omitempty"`
+	Options              []string `protobuf:"bytes,4,rep,name=options,proto3" json:"options,omitempty"`
+	Required             bool     `protobuf:"varint,5,opt,name=required,proto3" json:"required,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SurveyQuestion) Reset()         { *m = SurveyQuestion{} }
+func (m *SurveyQuestion) String() string { return proto.CompactTextString(m) }
+func (*SurveyQuestion) ProtoMessage()    {}
+
+func (m *SurveyQuestion) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *SurveyQuestion) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *SurveyQuestion) GetText() string {
+	if m != nil {
+		return m.Text
+	}
+	return ""
+}
+
+func (m *SurveyQuestion) GetOptions() []string {
+	if m != nil {
+		return m.Options
+	}
+	return nil
+}
+
+func (m *SurveyQuestion) GetRequired() bool {
+	if m != nil {
+		return m.Required
+	}
+	return false
+}
+
+// 玩家可以回答的问卷
+type SurveyInfo struct {
+	Id                   string            `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Title                string            `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	Questions            []*SurveyQuestion `protobuf:"bytes,3,rep,name=questions,proto3" json:"questions,omitempty"`
+	EndTime              int64             `protobuf:"varint,4,opt,name=end_time,json=endTime,proto3" json:"end_time,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *SurveyInfo) Reset()         { *m = SurveyInfo{} }
+func (m *SurveyInfo) String() string { return proto.CompactTextString(m) }
+func (*SurveyInfo) ProtoMessage()    {}
+
+func (m *SurveyInfo) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *SurveyInfo) GetTitle() string {
+	if m != nil {
+		return m.Title
+	}
+	return ""
+}
+
+func (m *SurveyInfo) GetQuestions() []*SurveyQuestion {
+	if m != nil {
+		return m.Questions
+	}
+	return nil
+}
+
+func (m *SurveyInfo) GetEndTime() int64 {
+	if m != nil {
+		return m.EndTime
+	}
+	return 0
+}
+
+// 可以回答的问卷
+type SurveysResponse struct {
+	Surveys              []*SurveyInfo `protobuf:"bytes,1,rep,name=surveys,proto3" json:"surveys,omitempty"`
+	ServerTime           int64         `protobuf:"varint,2,opt,name=server_time,json=serverTime,proto3" json:"server_time,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *SurveysResponse) Reset()         { *m = SurveysResponse{} }
+func (m *SurveysResponse) String() string { return proto.CompactTextString(m) }
+func (*SurveysResponse) ProtoMessage()    {}
+
+func (m *SurveysResponse) GetSurveys() []*SurveyInfo {
+	if m != nil {
+		return m.Surveys
+	}
+	return nil
+}
+
+func (m *SurveysResponse) GetServerTime() int64 {
+	if m != nil {
+		return m.ServerTime
+	}
+	return 0
+}
+
+// 题目的回答，多选题可以有多个值，评分题为1开始的分数
+type SurveyAnswer struct {
+	QuestionId           string   `protobuf:"bytes,1,opt,name=question_id,json=questionId,proto3" json:"question_id,omitempty"`
+	Values               []string `protobuf:"bytes,2,rep,name=values,proto3" json:"values,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SurveyAnswer) Reset()         { *m = SurveyAnswer{} }
+func (m *SurveyAnswer) String() string { return proto.CompactTextString(m) }
+func (*SurveyAnswer) ProtoMessage()    {}
+
+func (m *SurveyAnswer) GetQuestionId() string {
+	if m != nil {
+		return m.QuestionId
+	}
+	return ""
+}
+
+func (m *SurveyAnswer) GetValues() []string {
+	if m != nil {
+		return m.Values
+	}
+	return nil
+}
+
+// 提交问卷请求
+type SubmitSurveyRequest struct {
+	SurveyId             string          `protobuf:"bytes,1,opt,name=survey_id,json=surveyId,proto3" json:"survey_id,omitempty"`
+	Answers              []*SurveyAnswer `protobuf:"bytes,2,rep,name=answers,proto3" json:"answers,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *SubmitSurveyRequest) Reset()         { *m = SubmitSurveyRequest{} }
+func (m *SubmitSurveyRequest) String() string { return proto.CompactTextString(m) }
+func (*SubmitSurveyRequest) ProtoMessage()    {}
+
+func (m *SubmitSurveyRequest) GetSurveyId() string {
+	if m != nil {
+		return m.SurveyId
+	}
+	return ""
+}
+
+func (m *SubmitSurveyRequest) GetAnswers() []*SurveyAnswer {
+	if m != nil {
+		return m.Answers
+	}
+	return nil
+}
+
+// 创建问卷请求
+type SurveyRequest struct {
+	Title                string            `protobuf:"bytes,1,opt,name=title,proto3" json:"title,omitempty"`
+	Segment              string            `protobuf:"bytes,2,opt,name=segment,proto3" json:"segment,omitempty"`
+	Questions            []*SurveyQuestion `protobuf:"bytes,3,rep,name=questions,proto3" json:"questions,omitempty"`
+	StartTime            int64             `protobuf:"varint,4,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
+	EndTime              int64             `protobuf:"varint,5,opt,name=end_time,json=endTime,proto3" json:"end_time,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *SurveyRequest) Reset()         { *m = SurveyRequest{} }
+func (m *SurveyRequest) String() string { return proto.CompactTextString(m) }
+func (*SurveyRequest) ProtoMessage()    {}
+
+func (m *SurveyRequest) GetTitle() string {
+	if m != nil {
+		return m.Title
+	}
+	return ""
+}
+
+func (m *SurveyRequest) GetSegment() string {
+	if m != nil {
+		return m.Segment
+	}
+	return ""
+}
+
+func (m *SurveyRequest) GetQuestions() []*SurveyQuestion {
+	if m != nil {
+		return m.Questions
+	}
+	return nil
+}
+
+func (m *SurveyRequest) GetStartTime() int64 {
+	if m != nil {
+		return m.StartTime
+	}
+	return 0
+}
+
+func (m *SurveyRequest) GetEndTime() int64 {
+	if m != nil {
+		return m.EndTime
+	}
+	return 0
+}
+
+// 问卷查询或导出请求
+type SurveyQuery struct {
+	SurveyId             string   `protobuf:"bytes,1,opt,name=survey_id,json=surveyId,proto3" json:"survey_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SurveyQuery) Reset()         { *m = SurveyQuery{} }
+func (m *SurveyQuery) String() string { return proto.CompactTextString(m) }
+func (*SurveyQuery) ProtoMessage()    {}
+
+func (m *SurveyQuery) GetSurveyId() string {
+	if m != nil {
+		return m.SurveyId
+	}
+	return ""
+}
+
+// 提交客服工单请求，附件为客户端上传后的地址
+type SupportTicketRequest struct {
+	Category             string   `protobuf:"bytes,1,opt,name=category,proto3" json:"category,omitempty"`
+	Subject              string   `protobuf:"bytes,2,opt,name=subject,proto3" json:"subject,omitempty"`
+	Text                 string   `protobuf:"bytes,3,opt,name=text,proto3" json:"text,omitempty"`
+	Attachments          []string `protobuf:"bytes,4,rep,name=attachments,proto3" json:"attachments,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SupportTicketRequest) Reset()         { *m = SupportTicketRequest{} }
+func (m *SupportTicketRequest) String() string { return proto.CompactTextString(m) }
+func (*SupportTicketRequest) ProtoMessage()    {}
+
+func (m *SupportTicketRequest) GetCategory() string {
+	if m != nil {
+		return m.Category
+	}
+	return ""
+}
+
+func (m *SupportTicketRequest) GetSubject() string {
+	if m != nil {
+		return m.Subject
+	}
+	return ""
+}
+
+func (m *SupportTicketRequest) GetText() string {
+	if m != nil {
+		return m.Text
+	}
+	return ""
+}
+
+func (m *SupportTicketRequest) GetAttachments() []string {
+	if m != nil {
+		return m.Attachments
+	}
+	return nil
+}
+
+// 客服回复
+type TicketReplyInfo struct {
+	Content              string   `protobuf:"bytes,1,opt,name=content,proto3" json:"content,omitempty"`
+	CreatedAt            int64    `protobuf:"varint,2,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TicketReplyInfo) Reset()         { *m = TicketReplyInfo{} }
+func (m *TicketReplyInfo) String() string { return proto.CompactTextString(m) }
+func (*TicketReplyInfo) ProtoMessage()    {}
+
+func (m *TicketReplyInfo) GetContent() string {
+	if m != nil {
+		return m.Content
+	}
+	return ""
+}
+
+func (m *TicketReplyInfo) GetCreatedAt() int64 {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return 0
+}
+
+// 客服工单
+type SupportTicketInfo struct {
+	Id                   string             `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Category             string             `protobuf:"bytes,2,opt,name=category,proto3" json:"category,omitempty"`
+	Subject              string             `protobuf:"bytes,3,opt,name=subject,proto3" json:"subject,omitempty"`
+	Text                 string             `protobuf:"bytes,4,opt,name=text,proto3" json:"text,omitempty"`
+	Attachments          []string           `protobuf:"bytes,5,rep,name=attachments,proto3" json:"attachments,omitempty"`
+	Status               string             `protobuf:"bytes,6,opt,name=status,proto3" json:"status,omitempty"`
+	Replies              []*TicketReplyInfo `protobuf:"bytes,7,rep,name=replies,proto3" json:"replies,omitempty"`
+	CreatedAt            int64              `protobuf:"varint,8,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt            int64              `protobuf:"varint,9,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *SupportTicketInfo) Reset()         { *m = SupportTicketInfo{} }
+func (m *SupportTicketInfo) String() string { return proto.CompactTextString(m) }
+func (*SupportTicketInfo) ProtoMessage()    {}
+
+func (m *SupportTicketInfo) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *SupportTicketInfo) GetCategory() string {
+	if m != nil {
+		return m.Category
+	}
+	return ""
+}
+
+func (m *SupportTicketInfo) GetSubject() string {
+	if m != nil {
+		return m.Subject
+	}
+	return ""
+}
+
+func (m *SupportTicketInfo) GetText() string {
+	if m != nil {
+		return m.Text
+	}
+	return ""
+}
+
+func (m *SupportTicketInfo) GetAttachments() []string {
+	if m != nil {
+		return m.Attachments
+	}
+	return nil
+}
+
+func (m *SupportTicketInfo) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *SupportTicketInfo) GetReplies() []*TicketReplyInfo {
+	if m != nil {
+		return m.Replies
+	}
+	return nil
+}
+
+func (m *SupportTicketInfo) GetCreatedAt() int64 {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return 0
+}
+
+func (m *SupportTicketInfo) GetUpdatedAt() int64 {
+	if m != nil {
+		return m.UpdatedAt
+	}
+	return 0
+}
+
+// 玩家的客服工单
+type SupportTicketsResponse struct {
+	Tickets              []*SupportTicketInfo `protobuf:"bytes,1,rep,name=tickets,proto3" json:"tickets,omitempty"`
+	Categories           []string             `protobuf:"bytes,2,rep,name=categories,proto3" json:"categories,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *SupportTicketsResponse) Reset()         { *m = SupportTicketsResponse{} }
+func (m *SupportTicketsResponse) String() string { return proto.CompactTextString(m) }
+func (*SupportTicketsResponse) ProtoMessage()    {}
+
+func (m *SupportTicketsResponse) GetTickets() []*SupportTicketInfo {
+	if m != nil {
+		return m.Tickets
+	}
+	return nil
+}
+
+func (m *SupportTicketsResponse) GetCategories() []string {
+	if m != nil {
+		return m.Categories
+	}
+	return nil
+}
+
+// GM查询工单请求，指定工单ID时获取详情，否则按条件获取最近的工单
+type SupportTicketQuery struct {
+	TicketId             string   `protobuf:"bytes,1,opt,name=ticket_id,json=ticketId,proto3" json:"ticket_id,omitempty"`
+	Status               string   `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	UserId               uint64   `protobuf:"varint,3,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	AssignedTo           uint64   `protobuf:"varint,4,opt,name=assigned_to,json=assignedTo,proto3" json:"assigned_to,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SupportTicketQuery) Reset()         { *m = SupportTicketQuery{} }
+func (m *SupportTicketQuery) String() string { return proto.CompactTextString(m) }
+func (*SupportTicketQuery) ProtoMessage()    {}
+
+func (m *SupportTicketQuery) GetTicketId() string {
+	if m != nil {
+		return m.TicketId
+	}
+	return ""
+}
+
+func (m *SupportTicketQuery) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *SupportTicketQuery) GetUserId() uint64 {
+	if m != nil {
+		return m.UserId
+	}
+	return 0
+}
+
+func (m *SupportTicketQuery) GetAssignedTo() uint64 {
+	if m != nil {
+		return m.AssignedTo
+	}
+	return 0
+}
+
+// GM更新工单请求，操作为assign、resolve或reopen
+type SupportTicketAction struct {
+	TicketId             string   `protobuf:"bytes,1,opt,name=ticket_id,json=ticketId,proto3" json:"ticket_id,omitempty"`
+	Action               string   `protobuf:"bytes,2,opt,name=action,proto3" json:"action,omitempty"`
+	Assignee             uint64   `protobuf:"varint,3,opt,name=assignee,proto3" json:"assignee,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SupportTicketAction) Reset()         { *m = SupportTicketAction{} }
+func (m *SupportTicketAction) String() string { return proto.CompactTextString(m) }
+func (*SupportTicketAction) ProtoMessage()    {}
+
+func (m *SupportTicketAction) GetTicketId() string {
+	if m != nil {
+		return m.TicketId
+	}
+	return ""
+}
+
+func (m *SupportTicketAction) GetAction() string {
+	if m != nil {
+		return m.Action
+	}
+	return ""
+}
+
+func (m *SupportTicketAction) GetAssignee() uint64 {
+	if m != nil {
+		return m.Assignee
+	}
+	return 0
+}
+
+// GM回复工单请求，回复作为邮件发给玩家
+type SupportTicketReply struct {
+	TicketId             string   `protobuf:"bytes,1,opt,name=ticket_id,json=ticketId,proto3" json:"ticket_id,omitempty"`
+	Content              string   `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	Resolve              bool     `protobuf:"varint,3,opt,name=resolve,proto3" json:"resolve,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SupportTicketReply) Reset()         { *m = SupportTicketReply{} }
+func (m *SupportTicketReply) String() string { return proto.CompactTextString(m) }
+func (*SupportTicketReply) ProtoMessage()    {}
+
+func (m *SupportTicketReply) GetTicketId() string {
+	if m != nil {
+		return m.TicketId
+	}
+	return ""
+}
+
+func (m *SupportTicketReply) GetContent() string {
+	if m != nil {
+		return m.Content
+	}
+	return ""
+}
+
+func (m *SupportTicketReply) GetResolve() bool {
+	if m != nil {
+		return m.Resolve
+	}
+	return false
+}
+
+// 开始匹配请求，latencies为客户端测量的到各游戏节点的延迟
+type MatchRequest struct {
+	GameType             int32            `protobuf:"varint,1,opt,name=game_type,json=gameType,proto3" json:"game_type,omitempty"`
+	Latencies            map[string]int32 `protobuf:"bytes,2,rep,name=latencies,proto3" json:"latencies,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *MatchRequest) Reset()         { *m = MatchRequest{} }
+func (m *MatchRequest) String() string { return proto.CompactTextString(m) }
+func (*MatchRequest) ProtoMessage()    {}
+
+func (m *MatchRequest) GetGameType() int32 {
+	if m != nil {
+		return m.GameType
+	}
+	return 0
+}
+
+func (m *MatchRequest) GetLatencies() map[string]int32 {
+	if m != nil {
+		return m.Latencies
+	}
+	return nil
+}
+
+// 匹配状态
+type MatchStatusResponse struct {
+	Status               string   `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	WaitSeconds          int32    `protobuf:"varint,2,opt,name=wait_seconds,json=waitSeconds,proto3" json:"wait_seconds,omitempty"`
+	RoomId               uint64   `protobuf:"varint,3,opt,name=room_id,json=roomId,proto3" json:"room_id,omitempty"`
+	Node                 string   `protobuf:"bytes,4,opt,name=node,proto3" json:"node,omitempty"`
+	GameType             int32    `protobuf:"varint,5,opt,name=game_type,json=gameType,proto3" json:"game_type,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MatchStatusResponse) Reset()         { *m = MatchStatusResponse{} }
+func (m *MatchStatusResponse) String() string { return proto.CompactTextString(m) }
+func (*MatchStatusResponse) ProtoMessage()    {}
+
+func (m *MatchStatusResponse) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *MatchStatusResponse) GetWaitSeconds() int32 {
+	if m != nil {
+		return m.WaitSeconds
+	}
+	return 0
+}
+
+func (m *MatchStatusResponse) GetRoomId() uint64 {
+	if m != nil {
+		return m.RoomId
+	}
+	return 0
+}
+
+func (m *MatchStatusResponse) GetNode() string {
+	if m != nil {
+		return m.Node
+	}
+	return ""
+}
+
+func (m *MatchStatusResponse) GetGameType() int32 {
+	if m != nil {
+		return m.GameType
+	}
+	return 0
+}
+
+// 队伍请求，邀请时为被邀请的玩家，加入时为邀请的队伍
+type PartyRequest struct {
+	PartyId              uint64   `protobuf:"varint,1,opt,name=party_id,json=partyId,proto3" json:"party_id,omitempty"`
+	UserId               uint64   `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PartyRequest) Reset()         { *m = PartyRequest{} }
+func (m *PartyRequest) String() string { return proto.CompactTextString(m) }
+func (*PartyRequest) ProtoMessage()    {}
+
+func (m *PartyRequest) GetPartyId() uint64 {
+	if m != nil {
+		return m.PartyId
+	}
+	return 0
+}
+
+func (m *PartyRequest) GetUserId() uint64 {
+	if m != nil {
+		return m.UserId
+	}
+	return 0
+}
+
+// 队伍成员
+type PartyMember struct {
+	UserId               uint64   `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Nickname             string   `protobuf:"bytes,2,opt,name=nickname,proto3" json:"nickname,omitempty"`
+	Level                int32    `protobuf:"varint,3,opt,name=level,proto3" json:"level,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PartyMember) Reset()         { *m = PartyMember{} }
+func (m *PartyMember) String() string { return proto.CompactTextString(m) }
+func (*PartyMember) ProtoMessage()    {}
+
+func (m *PartyMember) GetUserId() uint64 {
+	if m != nil {
+		return m.UserId
+	}
+	return 0
+}
+
+func (m *PartyMember) GetNickname() string {
+	if m != nil {
+		return m.Nickname
+	}
+	return ""
+}
+
+func (m *PartyMember) GetLevel() int32 {
+	if m != nil {
+		return m.Level
+	}
+	return 0
+}
+
+// 队伍信息
+type PartyInfo struct {
+	PartyId              uint64         `protobuf:"varint,1,opt,name=party_id,json=partyId,proto3" json:"party_id,omitempty"`
+	LeaderId             uint64         `protobuf:"varint,2,opt,name=leader_id,json=leaderId,proto3" json:"leader_id,omitempty"`
+	Members              []*PartyMember `protobuf:"bytes,3,rep,name=members,proto3" json:"members,omitempty"`
+	Queued               bool           `protobuf:"varint,4,opt,name=queued,proto3" json:"queued,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *PartyInfo) Reset()         { *m = PartyInfo{} }
+func (m *PartyInfo) String() string { return proto.CompactTextString(m) }
+func (*PartyInfo) ProtoMessage()    {}
+
+func (m *PartyInfo) GetPartyId() uint64 {
+	if m != nil {
+		return m.PartyId
+	}
+	return 0
+}
+
+func (m *PartyInfo) GetLeaderId() uint64 {
+	if m != nil {
+		return m.LeaderId
+	}
+	return 0
+}
+
+func (m *PartyInfo) GetMembers() []*PartyMember {
+	if m != nil {
+		return m.Members
+	}
+	return nil
+}
+
+func (m *PartyInfo) GetQueued() bool {
+	if m != nil {
+		return m.Queued
+	}
+	return false
+}
+
+// 队伍聊天请求
+type PartyChatRequest struct {
+	Content              string   `protobuf:"bytes,1,opt,name=content,proto3" json:"content,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PartyChatRequest) Reset()         { *m = PartyChatRequest{} }
+func (m *PartyChatRequest) String() string { return proto.CompactTextString(m) }
+func (*PartyChatRequest) ProtoMessage()    {}
+
+func (m *PartyChatRequest) GetContent() string {
+	if m != nil {
+		return m.Content
+	}
+	return ""
+}
+
+// 观战好友请求
+type SpectateRequest struct {
+	FriendId             uint64   `protobuf:"varint,1,opt,name=friend_id,json=friendId,proto3" json:"friend_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SpectateRequest) Reset()         { *m = SpectateRequest{} }
+func (m *SpectateRequest) String() string { return proto.CompactTextString(m) }
+func (*SpectateRequest) ProtoMessage()    {}
+
+func (m *SpectateRequest) GetFriendId() uint64 {
+	if m != nil {
+		return m.FriendId
+	}
+	return 0
+}
+
+// 观战响应，客户端到运行游戏的节点获取游戏状态
+type SpectateResponse struct {
+	Room                 *RoomInfo `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	GameId               uint64    `protobuf:"varint,2,opt,name=game_id,json=gameId,proto3" json:"game_id,omitempty"`
+	Node                 string    `protobuf:"bytes,3,opt,name=node,proto3" json:"node,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *SpectateResponse) Reset()         { *m = SpectateResponse{} }
+func (m *SpectateResponse) String() string { return proto.CompactTextString(m) }
+func (*SpectateResponse) ProtoMessage()    {}
+
+func (m *SpectateResponse) GetRoom() *RoomInfo {
+	if m != nil {
+		return m.Room
+	}
+	return nil
+}
+
+func (m *SpectateResponse) GetGameId() uint64 {
+	if m != nil {
+		return m.GameId
+	}
+	return 0
+}
+
+func (m *SpectateResponse) GetNode() string {
+	if m != nil {
+		return m.Node
+	}
+	return ""
+}
+
+// 隐私设置，各项为everyone、friends或nobody，修改时为空的项保持不变
+type PrivacySettings struct {
+	OnlineStatus         string   `protobuf:"bytes,1,opt,name=online_status,json=onlineStatus,proto3" json:"online_status,omitempty"`
+	FriendRequests       string   `protobuf:"bytes,2,opt,name=friend_requests,json=friendRequests,proto3" json:"friend_requests,omitempty"`
+	Spectate             string   `protobuf:"bytes,3,opt,name=spectate,proto3" json:"spectate,omitempty"`
+	Whisper              string   `protobuf:"bytes,4,opt,name=whisper,proto3" json:"whisper,omitempty"`
+	Searchable           string   `protobuf:"bytes,5,opt,name=searchable,proto3" json:"searchable,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PrivacySettings) Reset()         { *m = PrivacySettings{} }
+func (m *PrivacySettings) String() string { return proto.CompactTextString(m) }
+func (*PrivacySettings) ProtoMessage()    {}
+
+func (m *PrivacySettings) GetOnlineStatus() string {
+	if m != nil {
+		return m.OnlineStatus
+	}
+	return ""
+}
+
+func (m *PrivacySettings) GetFriendRequests() string {
+	if m != nil {
+		return m.FriendRequests
+	}
+	return ""
+}
+
+func (m *PrivacySettings) GetSpectate() string {
+	if m != nil {
+		return m.Spectate
+	}
+	return ""
+}
+
+func (m *PrivacySettings) GetWhisper() string {
+	if m != nil {
+		return m.Whisper
+	}
+	return ""
+}
+
+func (m *PrivacySettings) GetSearchable() string {
+	if m != nil {
+		return m.Searchable
+	}
+	return ""
+}
+
+// 通知偏好，修改时只包含要修改的频道
+type NotificationPrefs struct {
+	Muted                map[string]bool `protobuf:"bytes,1,rep,name=muted,proto3" json:"muted,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *NotificationPrefs) Reset()         { *m = NotificationPrefs{} }
+func (m *NotificationPrefs) String() string { return proto.CompactTextString(m) }
+func (*NotificationPrefs) ProtoMessage()    {}
+
+func (m *NotificationPrefs) GetMuted() map[string]bool {
+	if m != nil {
+		return m.Muted
+	}
+	return nil
+}
+
+// 编辑聊天消息请求
+type EditMessageRequest struct {
+	MessageId            uint64   `protobuf:"varint,1,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	Content              string   `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *EditMessageRequest) Reset()         { *m = EditMessageRequest{} }
+func (m *EditMessageRequest) String() string { return proto.CompactTextString(m) }
+func (*EditMessageRequest) ProtoMessage()    {}
+
+func (m *EditMessageRequest) GetMessageId() uint64 {
+	if m != nil {
+		return m.MessageId
+	}
+	return 0
+}
+
+func (m *EditMessageRequest) GetContent() string {
+	if m != nil {
+		return m.Content
+	}
+	return ""
+}
+
+// 撤回聊天消息请求
+type RecallMessageRequest struct {
+	MessageId            uint64   `protobuf:"varint,1,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RecallMessageRequest) Reset()         { *m = RecallMessageRequest{} }
+func (m *RecallMessageRequest) String() string { return proto.CompactTextString(m) }
+func (*RecallMessageRequest) ProtoMessage()    {}
+
+func (m *RecallMessageRequest) GetMessageId() uint64 {
+	if m != nil {
+		return m.MessageId
+	}
+	return 0
+}
+
+// 聊天消息上某个表情的回应人数
+type ReactionCount struct {
+	EmojiId              int32    `protobuf:"varint,1,opt,name=emoji_id,json=emojiId,proto3" json:"emoji_id,omitempty"`
+	Count                int32    `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ReactionCount) Reset()         { *m = ReactionCount{} }
+func (m *ReactionCount) String() string { return proto.CompactTextString(m) }
+func (*ReactionCount) ProtoMessage()    {}
+
+func (m *ReactionCount) GetEmojiId() int32 {
+	if m != nil {
+		return m.EmojiId
+	}
+	return 0
+}
+
+func (m *ReactionCount) GetCount() int32 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+// 回应聊天消息请求
+type ReactMessageRequest struct {
+	MessageId            uint64   `protobuf:"varint,1,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	EmojiId              int32    `protobuf:"varint,2,opt,name=emoji_id,json=emojiId,proto3" json:"emoji_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ReactMessageRequest) Reset()         { *m = ReactMessageRequest{} }
+func (m *ReactMessageRequest) String() string { return proto.CompactTextString(m) }
+func (*ReactMessageRequest) ProtoMessage()    {}
+
+func (m *ReactMessageRequest) GetMessageId() uint64 {
+	if m != nil {
+		return m.MessageId
+	}
+	return 0
+}
+
+func (m *ReactMessageRequest) GetEmojiId() int32 {
+	if m != nil {
+		return m.EmojiId
+	}
+	return 0
+}
+
+// 对局结束事件，游戏服据此结算奖励
+type GameEndedEvent struct {
+	GameId               uint64   `protobuf:"varint,1,opt,name=game_id,json=gameId,proto3" json:"game_id,omitempty"`
+	Winner               uint64   `protobuf:"varint,2,opt,name=winner,proto3" json:"winner,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GameEndedEvent) Reset()         { *m = GameEndedEvent{} }
+func (m *GameEndedEvent) String() string { return proto.CompactTextString(m) }
+func (*GameEndedEvent) ProtoMessage()    {}
+
+func (m *GameEndedEvent) GetGameId() uint64 {
+	if m != nil {
+		return m.GameId
+	}
+	return 0
+}
+
+func (m *GameEndedEvent) GetWinner() uint64 {
+	if m != nil {
+		return m.Winner
+	}
+	return 0
+}
+
+// 断开玩家连接的系统命令，发给玩家所在网关
+type KickUserCommand struct {
+	UserId               uint64   `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Reason               string   `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *KickUserCommand) Reset()         { *m = KickUserCommand{} }
+func (m *KickUserCommand) String() string { return proto.CompactTextString(m) }
+func (*KickUserCommand) ProtoMessage()    {}
+
+func (m *KickUserCommand) GetUserId() uint64 {
+	if m != nil {
+		return m.UserId
+	}
+	return 0
+}
+
+func (m *KickUserCommand) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+// 结算对账的系统命令
+type ReconcileCommand struct {
+	Hours                float64  `protobuf:"fixed64,1,opt,name=hours,proto3" json:"hours,omitempty"`
+	Repair               bool     `protobuf:"varint,2,opt,name=repair,proto3" json:"repair,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ReconcileCommand) Reset()         { *m = ReconcileCommand{} }
+func (m *ReconcileCommand) String() string { return proto.CompactTextString(m) }
+func (*ReconcileCommand) ProtoMessage()    {}
+
+func (m *ReconcileCommand) GetHours() float64 {
+	if m != nil {
+		return m.Hours
+	}
+	return 0
+}
+
+func (m *ReconcileCommand) GetRepair() bool {
+	if m != nil {
+		return m.Repair
+	}
+	return false
+}
+
+// 迁移中的游戏玩家
+type GameSnapshotPlayer struct {
+	UserId               uint64   `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Nickname             string   `protobuf:"bytes,2,opt,name=nickname,proto3" json:"nickname,omitempty"`
+	Level                int32    `protobuf:"varint,3,opt,name=level,proto3" json:"level,omitempty"`
+	Score                int64    `protobuf:"varint,4,opt,name=score,proto3" json:"score,omitempty"`
+	Status               int32    `protobuf:"varint,5,opt,name=status,proto3" json:"status,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GameSnapshotPlayer) Reset()         { *m = GameSnapshotPlayer{} }
+func (m *GameSnapshotPlayer) String() string { return proto.CompactTextString(m) }
+func (*GameSnapshotPlayer) ProtoMessage()    {}
+
+func (m *GameSnapshotPlayer) GetUserId() uint64 {
+	if m != nil {
+		return m.UserId
+	}
+	return 0
+}
+
+func (m *GameSnapshotPlayer) GetNickname() string {
+	if m != nil {
+		return m.Nickname
+	}
+	return ""
+}
+
+func (m *GameSnapshotPlayer) GetLevel() int32 {
+	if m != nil {
+		return m.Level
+	}
+	return 0
+}
+
+func (m *GameSnapshotPlayer) GetScore() int64 {
+	if m != nil {
+		return m.Score
+	}
+	return 0
+}
+
+func (m *GameSnapshotPlayer) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+// 游戏实例的完整状态，房间在游戏节点之间迁移时传输
+type GameSnapshot struct {
+	GameId               uint64                `protobuf:"varint,1,opt,name=game_id,json=gameId,proto3" json:"game_id,omitempty"`
+	RoomId               uint64                `protobuf:"varint,2,opt,name=room_id,json=roomId,proto3" json:"room_id,omitempty"`
+	GameType             int32                 `protobuf:"varint,3,opt,name=game_type,json=gameType,proto3" json:"game_type,omitempty"`
+	Status               int32                 `protobuf:"varint,4,opt,name=status,proto3" json:"status,omitempty"`
+	CurrentPlayer        uint64                `protobuf:"varint,5,opt,name=current_player,json=currentPlayer,proto3" json:"current_player,omitempty"`
+	StartTime            int64                 `protobuf:"varint,6,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
+	Winner               uint64                `protobuf:"varint,7,opt,name=winner,proto3" json:"winner,omitempty"`
+	State                []byte                `protobuf:"bytes,8,opt,name=state,proto3" json:"state,omitempty"`
+	Players              []*GameSnapshotPlayer `protobuf:"bytes,9,rep,name=players,proto3" json:"players,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
+	XXX_unrecognized     []byte                `json:"-"`
+	XXX_sizecache        int32                 `json:"-"`
+}
+
+func (m *GameSnapshot) Reset()         { *m = GameSnapshot{} }
+func (m *GameSnapshot) String() string { return proto.CompactTextString(m) }
+func (*GameSnapshot) ProtoMessage()    {}
+
+func (m *GameSnapshot) GetGameId() uint64 {
+	if m != nil {
+		return m.GameId
+	}
+	return 0
+}
+
+func (m *GameSnapshot) GetRoomId() uint64 {
+	if m != nil {
+		return m.RoomId
+	}
+	return 0
+}
+
+func (m *GameSnapshot) GetGameType() int32 {
+	if m != nil {
+		return m.GameType
+	}
+	return 0
+}
+
+func (m *GameSnapshot) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+func (m *GameSnapshot) GetCurrentPlayer() uint64 {
+	if m != nil {
+		return m.CurrentPlayer
+	}
+	return 0
+}
+
+func (m *GameSnapshot) GetStartTime() int64 {
+	if m != nil {
+		return m.StartTime
+	}
+	return 0
+}
+
+func (m *GameSnapshot) GetWinner() uint64 {
+	if m != nil {
+		return m.Winner
+	}
+	return 0
+}
+
+func (m *GameSnapshot) GetState() []byte {
+	if m != nil {
+		return m.State
+	}
+	return nil
+}
+
+func (m *GameSnapshot) GetPlayers() []*GameSnapshotPlayer {
+	if m != nil {
+		return m.Players
+	}
+	return nil
+}
+
+// 迁入游戏请求，由迁出节点发给目标节点
+type ImportGameRequest struct {
+	Snapshot             *GameSnapshot `protobuf:"bytes,1,opt,name=snapshot,proto3" json:"snapshot,omitempty"`
+	SourceNode           string        `protobuf:"bytes,2,opt,name=source_node,json=sourceNode,proto3" json:"source_node,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *ImportGameRequest) Reset()         { *m = ImportGameRequest{} }
+func (m *ImportGameRequest) String() string { return proto.CompactTextString(m) }
+func (*ImportGameRequest) ProtoMessage()    {}
+
+func (m *ImportGameRequest) GetSnapshot() *GameSnapshot {
+	if m != nil {
+		return m.Snapshot
+	}
+	return nil
+}
+
+func (m *ImportGameRequest) GetSourceNode() string {
+	if m != nil {
+		return m.SourceNode
+	}
+	return ""
+}
+
+// 游戏已迁移到新节点，网关据此更新路由
+type GameMigratedEvent struct {
+	GameId               uint64   `protobuf:"varint,1,opt,name=game_id,json=gameId,proto3" json:"game_id,omitempty"`
+	RoomId               uint64   `protobuf:"varint,2,opt,name=room_id,json=roomId,proto3" json:"room_id,omitempty"`
+	Node                 string   `protobuf:"bytes,3,opt,name=node,proto3" json:"node,omitempty"`
+	PlayerIds            []uint64 `protobuf:"varint,4,rep,packed,name=player_ids,json=playerIds,proto3" json:"player_ids,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GameMigratedEvent) Reset()         { *m = GameMigratedEvent{} }
+func (m *GameMigratedEvent) String() string { return proto.CompactTextString(m) }
+func (*GameMigratedEvent) ProtoMessage()    {}
+
+func (m *GameMigratedEvent) GetGameId() uint64 {
+	if m != nil {
+		return m.GameId
+	}
+	return 0
+}
+
+func (m *GameMigratedEvent) GetRoomId() uint64 {
+	if m != nil {
+		return m.RoomId
+	}
+	return 0
+}
+
+func (m *GameMigratedEvent) GetNode() string {
+	if m != nil {
+		return m.Node
+	}
+	return ""
+}
+
+func (m *GameMigratedEvent) GetPlayerIds() []uint64 {
+	if m != nil {
+		return m.PlayerIds
+	}
+	return nil
+}
+
+// 修改昵称或用户名，修改昵称时只填nickname，修改用户名时只填username
+type RenameRequest struct {
+	Nickname             string   `protobuf:"bytes,1,opt,name=nickname,proto3" json:"nickname,omitempty"`
+	Username             string   `protobuf:"bytes,2,opt,name=username,proto3" json:"username,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RenameRequest) Reset()         { *m = RenameRequest{} }
+func (m *RenameRequest) String() string { return proto.CompactTextString(m) }
+func (*RenameRequest) ProtoMessage()    {}
+
+func (m *RenameRequest) GetNickname() string {
+	if m != nil {
+		return m.Nickname
+	}
+	return ""
+}
+
+func (m *RenameRequest) GetUsername() string {
+	if m != nil {
+		return m.Username
+	}
+	return ""
+}
+
+// 改名的结果
+type RenameResponse struct {
+	Nickname             string   `protobuf:"bytes,1,opt,name=nickname,proto3" json:"nickname,omitempty"`
+	NextRenameAt         int64    `protobuf:"varint,2,opt,name=next_rename_at,json=nextRenameAt,proto3" json:"next_rename_at,omitempty"`
+	Username             string   `protobuf:"bytes,3,opt,name=username,proto3" json:"username,omitempty"`
+	Currency             string   `protobuf:"bytes,4,opt,name=currency,proto3" json:"currency,omitempty"`
+	Cost                 int64    `protobuf:"varint,5,opt,name=cost,proto3" json:"cost,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RenameResponse) Reset()         { *m = RenameResponse{} }
+func (m *RenameResponse) String() string { return proto.CompactTextString(m) }
+func (*RenameResponse) ProtoMessage()    {}
+
+func (m *RenameResponse) GetNickname() string {
+	if m != nil {
+		return m.Nickname
+	}
+	return ""
+}
+
+func (m *RenameResponse) GetNextRenameAt() int64 {
+	if m != nil {
+		return m.NextRenameAt
+	}
+	return 0
+}
+
+func (m *RenameResponse) GetUsername() string {
+	if m != nil {
+		return m.Username
+	}
+	return ""
+}
+
+func (m *RenameResponse) GetCurrency() string {
+	if m != nil {
+		return m.Currency
+	}
+	return ""
+}
+
+func (m *RenameResponse) GetCost() int64 {
+	if m != nil {
+		return m.Cost
+	}
+	return 0
+}
+
+// 玩家昵称已修改的系统命令，各大厅节点据此更新内存中的昵称
+type UserRenamedEvent struct {
+	UserId               uint64   `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	OldNickname          string   `protobuf:"bytes,2,opt,name=old_nickname,json=oldNickname,proto3" json:"old_nickname,omitempty"`
+	Nickname             string   `protobuf:"bytes,3,opt,name=nickname,proto3" json:"nickname,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UserRenamedEvent) Reset()         { *m = UserRenamedEvent{} }
+func (m *UserRenamedEvent) String() string { return proto.CompactTextString(m) }
+func (*UserRenamedEvent) ProtoMessage()    {}
+
+func (m *UserRenamedEvent) GetUserId() uint64 {
+	if m != nil {
+		return m.UserId
+	}
+	return 0
+}
+
+func (m *UserRenamedEvent) GetOldNickname() string {
+	if m != nil {
+		return m.OldNickname
+	}
+	return ""
+}
+
+func (m *UserRenamedEvent) GetNickname() string {
+	if m != nil {
+		return m.Nickname
+	}
+	return ""
+}
+
+// 按名称搜索玩家或房间
+type SearchRequest struct {
+	Query                string   `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	Offset               int32    `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	Limit                int32    `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SearchRequest) Reset()         { *m = SearchRequest{} }
+func (m *SearchRequest) String() string { return proto.CompactTextString(m) }
+func (*SearchRequest) ProtoMessage()    {}
+
+func (m *SearchRequest) GetQuery() string {
+	if m != nil {
+		return m.Query
+	}
+	return ""
+}
+
+func (m *SearchRequest) GetOffset() int32 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+func (m *SearchRequest) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+// 搜索到的玩家
+type PlayerSearchResult struct {
+	UserId               uint64   `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Nickname             string   `protobuf:"bytes,2,opt,name=nickname,proto3" json:"nickname,omitempty"`
+	Level                int32    `protobuf:"varint,3,opt,name=level,proto3" json:"level,omitempty"`
+	Avatar               string   `protobuf:"bytes,4,opt,name=avatar,proto3" json:"avatar,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PlayerSearchResult) Reset()         { *m = PlayerSearchResult{} }
+func (m *PlayerSearchResult) String() string { return proto.CompactTextString(m) }
+func (*PlayerSearchResult) ProtoMessage()    {}
+
+func (m *PlayerSearchResult) GetUserId() uint64 {
+	if m != nil {
+		return m.UserId
+	}
+	return 0
+}
+
+func (m *PlayerSearchResult) GetNickname() string {
+	if m != nil {
+		return m.Nickname
+	}
+	return ""
+}
+
+func (m *PlayerSearchResult) GetLevel() int32 {
+	if m != nil {
+		return m.Level
+	}
+	return 0
+}
+
+func (m *PlayerSearchResult) GetAvatar() string {
+	if m != nil {
+		return m.Avatar
+	}
+	return ""
+}
+
+// 玩家搜索结果
+type PlayerSearchResponse struct {
+	Players              []*PlayerSearchResult `protobuf:"bytes,1,rep,name=players,proto3" json:"players,omitempty"`
+	HasMore              bool                  `protobuf:"varint,2,opt,name=has_more,json=hasMore,proto3" json:"has_more,omitempty"`
+	NextOffset           int32                 `protobuf:"varint,3,opt,name=next_offset,json=nextOffset,proto3" json:"next_offset,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
+	XXX_unrecognized     []byte                `json:"-"`
+	XXX_sizecache        int32                 `json:"-"`
+}
+
+func (m *PlayerSearchResponse) Reset()         { *m = PlayerSearchResponse{} }
+func (m *PlayerSearchResponse) String() string { return proto.CompactTextString(m) }
+func (*PlayerSearchResponse) ProtoMessage()    {}
+
+func (m *PlayerSearchResponse) GetPlayers() []*PlayerSearchResult {
+	if m != nil {
+		return m.Players
+	}
+	return nil
+}
+
+func (m *PlayerSearchResponse) GetHasMore() bool {
+	if m != nil {
+		return m.HasMore
+	}
+	return false
+}
+
+func (m *PlayerSearchResponse) GetNextOffset() int32 {
+	if m != nil {
+		return m.NextOffset
+	}
+	return 0
+}
+
+// 房间搜索结果，只包含等待中的公开房间
+type RoomSearchResponse struct {
+	Rooms                []*RoomInfo `protobuf:"bytes,1,rep,name=rooms,proto3" json:"rooms,omitempty"`
+	HasMore              bool        `protobuf:"varint,2,opt,name=has_more,json=hasMore,proto3" json:"has_more,omitempty"`
+	NextOffset           int32       `protobuf:"varint,3,opt,name=next_offset,json=nextOffset,proto3" json:"next_offset,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *RoomSearchResponse) Reset()         { *m = RoomSearchResponse{} }
+func (m *RoomSearchResponse) String() string { return proto.CompactTextString(m) }
+func (*RoomSearchResponse) ProtoMessage()    {}
+
+func (m *RoomSearchResponse) GetRooms() []*RoomInfo {
+	if m != nil {
+		return m.Rooms
+	}
+	return nil
+}
+
+func (m *RoomSearchResponse) GetHasMore() bool {
+	if m != nil {
+		return m.HasMore
+	}
+	return false
+}
+
+func (m *RoomSearchResponse) GetNextOffset() int32 {
+	if m != nil {
+		return m.NextOffset
+	}
+	return 0
+}
+
+// 故障注入规则的系统命令，各比例都为0时清除本节点的规则
+type ChaosCommand struct {
+	LatencyPercent       int32    `protobuf:"varint,1,opt,name=latency_percent,json=latencyPercent,proto3" json:"latency_percent,omitempty"`
+	LatencyMs            int32    `protobuf:"varint,2,opt,name=latency_ms,json=latencyMs,proto3" json:"latency_ms,omitempty"`
+	ErrorPercent         int32    `protobuf:"varint,3,opt,name=error_percent,json=errorPercent,proto3" json:"error_percent,omitempty"`
+	DropPercent          int32    `protobuf:"varint,4,opt,name=drop_percent,json=dropPercent,proto3" json:"drop_percent,omitempty"`
+	DisconnectPercent    int32    `protobuf:"varint,5,opt,name=disconnect_percent,json=disconnectPercent,proto3" json:"disconnect_percent,omitempty"`
+	DurationSec          int32    `protobuf:"varint,6,opt,name=duration_sec,json=durationSec,proto3" json:"duration_sec,omitempty"`
+	Services             []string `protobuf:"bytes,7,rep,name=services,proto3" json:"services,omitempty"`
+	Topics               []string `protobuf:"bytes,8,rep,name=topics,proto3" json:"topics,omitempty"`
+	Operator             string   `protobuf:"bytes,9,opt,name=operator,proto3" json:"operator,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ChaosCommand) Reset()         { *m = ChaosCommand{} }
+func (m *ChaosCommand) String() string { return proto.CompactTextString(m) }
+func (*ChaosCommand) ProtoMessage()    {}
+
+func (m *ChaosCommand) GetLatencyPercent() int32 {
+	if m != nil {
+		return m.LatencyPercent
+	}
+	return 0
+}
+
+func (m *ChaosCommand) GetLatencyMs() int32 {
+	if m != nil {
+		return m.LatencyMs
+	}
+	return 0
+}
+
+func (m *ChaosCommand) GetErrorPercent() int32 {
+	if m != nil {
+		return m.ErrorPercent
+	}
+	return 0
+}
+
+func (m *ChaosCommand) GetDropPercent() int32 {
+	if m != nil {
+		return m.DropPercent
+	}
+	return 0
+}
+
+func (m *ChaosCommand) GetDisconnectPercent() int32 {
+	if m != nil {
+		return m.DisconnectPercent
+	}
+	return 0
+}
+
+func (m *ChaosCommand) GetDurationSec() int32 {
+	if m != nil {
+		return m.DurationSec
+	}
+	return 0
+}
+
+func (m *ChaosCommand) GetServices() []string {
+	if m != nil {
+		return m.Services
+	}
+	return nil
+}
+
+func (m *ChaosCommand) GetTopics() []string {
+	if m != nil {
+		return m.Topics
+	}
+	return nil
+}
+
+func (m *ChaosCommand) GetOperator() string {
+	if m != nil {
+		return m.Operator
+	}
+	return ""
+}
+
+// 调整节点运行时参数的系统命令，revert为true时立即恢复调整前的值
+type RuntimeParamCommand struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Value                string   `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	DurationSec          int32    `protobuf:"varint,3,opt,name=duration_sec,json=durationSec,proto3" json:"duration_sec,omitempty"`
+	Revert               bool     `protobuf:"varint,4,opt,name=revert,proto3" json:"revert,omitempty"`
+	Operator             string   `protobuf:"bytes,5,opt,name=operator,proto3" json:"operator,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RuntimeParamCommand) Reset()         { *m = RuntimeParamCommand{} }
+func (m *RuntimeParamCommand) String() string { return proto.CompactTextString(m) }
+func (*RuntimeParamCommand) ProtoMessage()    {}
+
+func (m *RuntimeParamCommand) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *RuntimeParamCommand) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
+}
+
+func (m *RuntimeParamCommand) GetDurationSec() int32 {
+	if m != nil {
+		return m.DurationSec
+	}
+	return 0
+}
+
+func (m *RuntimeParamCommand) GetRevert() bool {
+	if m != nil {
+		return m.Revert
+	}
+	return false
+}
+
+func (m *RuntimeParamCommand) GetOperator() string {
+	if m != nil {
+		return m.Operator
+	}
+	return ""
+}
+
+// 玩家某一操作当天的配额用量，配额用完时作为错误响应的数据返回
+type QuotaInfo struct {
+	Operation            string   `protobuf:"bytes,1,opt,name=operation,proto3" json:"operation,omitempty"`
+	Used                 int64    `protobuf:"varint,2,opt,name=used,proto3" json:"used,omitempty"`
+	Limit                int64    `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	ResetAt              int64    `protobuf:"varint,4,opt,name=reset_at,json=resetAt,proto3" json:"reset_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *QuotaInfo) Reset()         { *m = QuotaInfo{} }
+func (m *QuotaInfo) String() string { return proto.CompactTextString(m) }
+func (*QuotaInfo) ProtoMessage()    {}
+
+func (m *QuotaInfo) GetOperation() string {
+	if m != nil {
+		return m.Operation
+	}
+	return ""
+}
+
+func (m *QuotaInfo) GetUsed() int64 {
+	if m != nil {
+		return m.Used
+	}
+	return 0
+}
+
+func (m *QuotaInfo) GetLimit() int64 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *QuotaInfo) GetResetAt() int64 {
+	if m != nil {
+		return m.ResetAt
+	}
+	return 0
+}
+
+// 设置玩家VIP等级请求，由计费系统在开通、续费和退款时调用
+type SetVIPRequest struct {
+	UserId               uint64   `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Tier                 int32    `protobuf:"varint,2,opt,name=tier,proto3" json:"tier,omitempty"`
+	ExpiresAt            int64    `protobuf:"varint,3,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	OrderId              string   `protobuf:"bytes,4,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SetVIPRequest) Reset()         { *m = SetVIPRequest{} }
+func (m *SetVIPRequest) String() string { return proto.CompactTextString(m) }
+func (*SetVIPRequest) ProtoMessage()    {}
+
+func (m *SetVIPRequest) GetUserId() uint64 {
+	if m != nil {
+		return m.UserId
+	}
+	return 0
+}
+
+func (m *SetVIPRequest) GetTier() int32 {
+	if m != nil {
+		return m.Tier
+	}
+	return 0
+}
+
+func (m *SetVIPRequest) GetExpiresAt() int64 {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return 0
+}
+
+func (m *SetVIPRequest) GetOrderId() string {
+	if m != nil {
+		return m.OrderId
+	}
+	return ""
+}
+
+// 兑换码兑换请求
+type RedeemCodeRequest struct {
+	Code                 string   `protobuf:"bytes,1,opt,name=code,proto3" json:"code,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RedeemCodeRequest) Reset()         { *m = RedeemCodeRequest{} }
+func (m *RedeemCodeRequest) String() string { return proto.CompactTextString(m) }
+func (*RedeemCodeRequest) ProtoMessage()    {}
+
+func (m *RedeemCodeRequest) GetCode() string {
+	if m != nil {
+		return m.Code
+	}
+	return ""
+}
+
+// 兑换结果，奖励通过邮件发放
+type RedeemCodeResponse struct {
+	MailId               uint64    `protobuf:"varint,1,opt,name=mail_id,json=mailId,proto3" json:"mail_id,omitempty"`
+	Title                string    `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	Rewards              []*Reward `protobuf:"bytes,3,rep,name=rewards,proto3" json:"rewards,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *RedeemCodeResponse) Reset()         { *m = RedeemCodeResponse{} }
+func (m *RedeemCodeResponse) String() string { return proto.CompactTextString(m) }
+func (*RedeemCodeResponse) ProtoMessage()    {}
+
+func (m *RedeemCodeResponse) GetMailId() uint64 {
+	if m != nil {
+		return m.MailId
+	}
+	return 0
+}
+
+func (m *RedeemCodeResponse) GetTitle() string {
+	if m != nil {
+		return m.Title
+	}
+	return ""
+}
+
+func (m *RedeemCodeResponse) GetRewards() []*Reward {
+	if m != nil {
+		return m.Rewards
+	}
+	return nil
+}
+
+// GM创建兑换码批次请求，指定code时批次只有这一个多人共用的兑换码，否则生成count个兑换码
+type RedeemBatchRequest struct {
+	Name                 string    `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Title                string    `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	Content              string    `protobuf:"bytes,3,opt,name=content,proto3" json:"content,omitempty"`
+	Rewards              []*Reward `protobuf:"bytes,4,rep,name=rewards,proto3" json:"rewards,omitempty"`
+	Count                int64     `protobuf:"varint,5,opt,name=count,proto3" json:"count,omitempty"`
+	Code                 string    `protobuf:"bytes,6,opt,name=code,proto3" json:"code,omitempty"`
+	MaxUses              int64     `protobuf:"varint,7,opt,name=max_uses,json=maxUses,proto3" json:"max_uses,omitempty"`
+	PerUser              int64     `protobuf:"varint,8,opt,name=per_user,json=perUser,proto3" json:"per_user,omitempty"`
+	StartTime            int64     `protobuf:"varint,9,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
+	EndTime              int64     `protobuf:"varint,10,opt,name=end_time,json=endTime,proto3" json:"end_time,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *RedeemBatchRequest) Reset()         { *m = RedeemBatchRequest{} }
+func (m *RedeemBatchRequest) String() string { return proto.CompactTextString(m) }
+func (*RedeemBatchRequest) ProtoMessage()    {}
+
+func (m *RedeemBatchRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *RedeemBatchRequest) GetTitle() string {
+	if m != nil {
+		return m.Title
+	}
+	return ""
+}
+
+func (m *RedeemBatchRequest) GetContent() string {
+	if m != nil {
+		return m.Content
+	}
+	return ""
+}
+
+func (m *RedeemBatchRequest) GetRewards() []*Reward {
+	if m != nil {
+		return m.Rewards
+	}
+	return nil
+}
+
+func (m *RedeemBatchRequest) GetCount() int64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *RedeemBatchRequest) GetCode() string {
+	if m != nil {
+		return m.Code
+	}
+	return ""
+}
+
+func (m *RedeemBatchRequest) GetMaxUses() int64 {
+	if m != nil {
+		return m.MaxUses
+	}
+	return 0
+}
+
+func (m *RedeemBatchRequest) GetPerUser() int64 {
+	if m != nil {
+		return m.PerUser
+	}
+	return 0
+}
+
+func (m *RedeemBatchRequest) GetStartTime() int64 {
+	if m != nil {
+		return m.StartTime
+	}
+	return 0
+}
+
+func (m *RedeemBatchRequest) GetEndTime() int64 {
+	if m != nil {
+		return m.EndTime
+	}
+	return 0
+}
+
+// 查询或停用兑换码批次，未指定批次时查询最近创建的批次
+type RedeemBatchQuery struct {
+	BatchId              string   `protobuf:"bytes,1,opt,name=batch_id,json=batchId,proto3" json:"batch_id,omitempty"`
+	Limit                int32    `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	Disabled             bool     `protobuf:"varint,3,opt,name=disabled,proto3" json:"disabled,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RedeemBatchQuery) Reset()         { *m = RedeemBatchQuery{} }
+func (m *RedeemBatchQuery) String() string { return proto.CompactTextString(m) }
+func (*RedeemBatchQuery) ProtoMessage()    {}
+
+func (m *RedeemBatchQuery) GetBatchId() string {
+	if m != nil {
+		return m.BatchId
+	}
+	return ""
+}
+
+func (m *RedeemBatchQuery) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *RedeemBatchQuery) GetDisabled() bool {
+	if m != nil {
+		return m.Disabled
+	}
+	return false
+}
+
+// 某个语言的资讯内容
+type NewsContent struct {
+	Language             string   `protobuf:"bytes,1,opt,name=language,proto3" json:"language,omitempty"`
+	Title                string   `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	Summary              string   `protobuf:"bytes,3,opt,name=summary,proto3" json:"summary,omitempty"`
+	Body                 string   `protobuf:"bytes,4,opt,name=body,proto3" json:"body,omitempty"`
+	Image                string   `protobuf:"bytes,5,opt,name=image,proto3" json:"image,omitempty"`
+	Link                 string   `protobuf:"bytes,6,opt,name=link,proto3" json:"link,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NewsContent) Reset()         { *m = NewsContent{} }
+func (m *NewsContent) String() string { return proto.CompactTextString(m) }
+func (*NewsContent) ProtoMessage()    {}
+
+func (m *NewsContent) GetLanguage() string {
+	if m != nil {
+		return m.Language
+	}
+	return ""
+}
+
+func (m *NewsContent) GetTitle() string {
+	if m != nil {
+		return m.Title
+	}
+	return ""
+}
+
+func (m *NewsContent) GetSummary() string {
+	if m != nil {
+		return m.Summary
+	}
+	return ""
+}
+
+func (m *NewsContent) GetBody() string {
+	if m != nil {
+		return m.Body
+	}
+	return ""
+}
+
+func (m *NewsContent) GetImage() string {
+	if m != nil {
+		return m.Image
+	}
+	return ""
+}
+
+func (m *NewsContent) GetLink() string {
+	if m != nil {
+		return m.Link
+	}
+	return ""
+}
+
+// 创建资讯请求
+type NewsItemRequest struct {
+	Kind                 string         `protobuf:"bytes,1,opt,name=kind,proto3" json:"kind,omitempty"`
+	Language             string         `protobuf:"bytes,2,opt,name=language,proto3" json:"language,omitempty"`
+	Contents             []*NewsContent `protobuf:"bytes,3,rep,name=contents,proto3" json:"contents,omitempty"`
+	Segment              string         `protobuf:"bytes,4,opt,name=segment,proto3" json:"segment,omitempty"`
+	Priority             int32          `protobuf:"varint,5,opt,name=priority,proto3" json:"priority,omitempty"`
+	StartTime            int64          `protobuf:"varint,6,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
+	EndTime              int64          `protobuf:"varint,7,opt,name=end_time,json=endTime,proto3" json:"end_time,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *NewsItemRequest) Reset()         { *m = NewsItemRequest{} }
+func (m *NewsItemRequest) String() string { return proto.CompactTextString(m) }
+func (*NewsItemRequest) ProtoMessage()    {}
+
+func (m *NewsItemRequest) GetKind() string {
+	if m != nil {
+		return m.Kind
+	}
+	return ""
+}
+
+func (m *NewsItemRequest) GetLanguage() string {
+	if m != nil {
+		return m.Language
+	}
+	return ""
+}
+
+func (m *NewsItemRequest) GetContents() []*NewsContent {
+	if m != nil {
+		return m.Contents
+	}
+	return nil
+}
+
+func (m *NewsItemRequest) GetSegment() string {
+	if m != nil {
+		return m.Segment
+	}
+	return ""
+}
+
+func (m *NewsItemRequest) GetPriority() int32 {
+	if m != nil {
+		return m.Priority
+	}
+	return 0
+}
+
+func (m *NewsItemRequest) GetStartTime() int64 {
+	if m != nil {
+		return m.StartTime
+	}
+	return 0
+}
+
+func (m *NewsItemRequest) GetEndTime() int64 {
+	if m != nil {
+		return m.EndTime
+	}
+	return 0
+}
+
+// 资讯查询或删除请求
+type NewsQuery struct {
+	ItemId               string   `protobuf:"bytes,1,opt,name=item_id,json=itemId,proto3" json:"item_id,omitempty"`
+	Limit                int32    `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NewsQuery) Reset()         { *m = NewsQuery{} }
+func (m *NewsQuery) String() string { return proto.CompactTextString(m) }
+func (*NewsQuery) ProtoMessage()    {}
+
+func (m *NewsQuery) GetItemId() string {
+	if m != nil {
+		return m.ItemId
+	}
+	return ""
+}
+
+func (m *NewsQuery) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+// 获取资讯请求
+type NewsFeedRequest struct {
+	Kind                 string   `protobuf:"bytes,1,opt,name=kind,proto3" json:"kind,omitempty"`
+	Version              string   `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NewsFeedRequest) Reset()         { *m = NewsFeedRequest{} }
+func (m *NewsFeedRequest) String() string { return proto.CompactTextString(m) }
+func (*NewsFeedRequest) ProtoMessage()    {}
+
+func (m *NewsFeedRequest) GetKind() string {
+	if m != nil {
+		return m.Kind
+	}
+	return ""
+}
+
+func (m *NewsFeedRequest) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+// 按玩家语言选择内容后的资讯
+type NewsItemInfo struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Kind                 string   `protobuf:"bytes,2,opt,name=kind,proto3" json:"kind,omitempty"`
+	Title                string   `protobuf:"bytes,3,opt,name=title,proto3" json:"title,omitempty"`
+	Summary              string   `protobuf:"bytes,4,opt,name=summary,proto3" json:"summary,omitempty"`
+	Body                 string   `protobuf:"bytes,5,opt,name=body,proto3" json:"body,omitempty"`
+	ImageUrl             string   `protobuf:"bytes,6,opt,name=image_url,json=imageUrl,proto3" json:"image_url,omitempty"`
+	Link                 string   `protobuf:"bytes,7,opt,name=link,proto3" json:"link,omitempty"`
+	Priority             int32    `protobuf:"varint,8,opt,name=priority,proto3" json:"priority,omitempty"`
+	StartTime            int64    `protobuf:"varint,9,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
+	EndTime              int64    `protobuf:"varint,10,opt,name=end_time,json=endTime,proto3" json:"end_time,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NewsItemInfo) Reset()         { *m = NewsItemInfo{} }
+func (m *NewsItemInfo) String() string { return proto.CompactTextString(m) }
+func (*NewsItemInfo) ProtoMessage()    {}
+
+func (m *NewsItemInfo) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *NewsItemInfo) GetKind() string {
+	if m != nil {
+		return m.Kind
+	}
+	return ""
+}
+
+func (m *NewsItemInfo) GetTitle() string {
+	if m != nil {
+		return m.Title
+	}
+	return ""
+}
+
+func (m *NewsItemInfo) GetSummary() string {
+	if m != nil {
+		return m.Summary
+	}
+	return ""
+}
+
+func (m *NewsItemInfo) GetBody() string {
+	if m != nil {
+		return m.Body
+	}
+	return ""
+}
+
+func (m *NewsItemInfo) GetImageUrl() string {
+	if m != nil {
+		return m.ImageUrl
+	}
+	return ""
+}
+
+func (m *NewsItemInfo) GetLink() string {
+	if m != nil {
+		return m.Link
+	}
+	return ""
+}
+
+func (m *NewsItemInfo) GetPriority() int32 {
+	if m != nil {
+		return m.Priority
+	}
+	return 0
+}
+
+func (m *NewsItemInfo) GetStartTime() int64 {
+	if m != nil {
+		return m.StartTime
+	}
+	return 0
+}
+
+func (m *NewsItemInfo) GetEndTime() int64 {
+	if m != nil {
+		return m.EndTime
+	}
+	return 0
+}
+
+// 获取资讯响应
+type NewsFeedResponse struct {
+	Items                []*NewsItemInfo `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	Version              string          `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	NotModified          bool            `protobuf:"varint,3,opt,name=not_modified,json=notModified,proto3" json:"not_modified,omitempty"`
+	ServerTime           int64           `protobuf:"varint,4,opt,name=server_time,json=serverTime,proto3" json:"server_time,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *NewsFeedResponse) Reset()         { *m = NewsFeedResponse{} }
+func (m *NewsFeedResponse) String() string { return proto.CompactTextString(m) }
+func (*NewsFeedResponse) ProtoMessage()    {}
+
+func (m *NewsFeedResponse) GetItems() []*NewsItemInfo {
+	if m != nil {
+		return m.Items
+	}
+	return nil
+}
+
+func (m *NewsFeedResponse) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+func (m *NewsFeedResponse) GetNotModified() bool {
+	if m != nil {
+		return m.NotModified
+	}
+	return false
+}
+
+func (m *NewsFeedResponse) GetServerTime() int64 {
+	if m != nil {
+		return m.ServerTime
+	}
+	return 0
+}
+
+// CapacityReportQuery 容量规划周报查询
+type CapacityReportQuery struct {
+	Week                 string   `protobuf:"bytes,1,opt,name=week,proto3" json:"week,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CapacityReportQuery) Reset()         { *m = CapacityReportQuery{} }
+func (m *CapacityReportQuery) String() string { return proto.CompactTextString(m) }
+func (*CapacityReportQuery) ProtoMessage()    {}
+
+func (m *CapacityReportQuery) GetWeek() string {
+	if m != nil {
+		return m.Week
+	}
+	return ""
+}
+
+// 结构化的错误信息，客户端可按消息ID和参数自行本地化和格式化消息
+type ErrorInfo struct {
+	MessageId            string            `protobuf:"bytes,1,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	Params               map[string]string `protobuf:"bytes,2,rep,name=params,proto3" json:"params,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Retryable            bool              `protobuf:"varint,3,opt,name=retryable,proto3" json:"retryable,omitempty"`
+	Detail               string            `protobuf:"bytes,4,opt,name=detail,proto3" json:"detail,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *ErrorInfo) Reset()         { *m = ErrorInfo{} }
+func (m *ErrorInfo) String() string { return proto.CompactTextString(m) }
+func (*ErrorInfo) ProtoMessage()    {}
+
+func (m *ErrorInfo) GetMessageId() string {
+	if m != nil {
+		return m.MessageId
+	}
+	return ""
+}
+
+func (m *ErrorInfo) GetParams() map[string]string {
+	if m != nil {
+		return m.Params
+	}
+	return nil
+}
+
+func (m *ErrorInfo) GetRetryable() bool {
+	if m != nil {
+		return m.Retryable
+	}
+	return false
+}
+
+func (m *ErrorInfo) GetDetail() string {
+	if m != nil {
+		return m.Detail
+	}
+	return ""
+}
+
 // Protobuf marshaling functions
 func Marshal(m interface{}) ([]byte, error) {
 	return proto.Marshal(m.(proto.Message))
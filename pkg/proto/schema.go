@@ -0,0 +1,6 @@
+package proto
+
+// SchemaVersion 节点间消息定义的版本，修改已有消息的字段编号或类型等不兼容变更时递增
+//
+// 中心服会对比集群中各节点的SchemaVersion，不一致时输出告警
+const SchemaVersion uint32 = 1
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/phuhao00/lufy/internal/backup"
+	"github.com/phuhao00/lufy/internal/database"
+	"github.com/phuhao00/lufy/internal/logger"
+)
+
+// fileConfig 配置文件中备份用到的部分
+type fileConfig struct {
+	Database struct {
+		MongoDB database.MongoConfig `yaml:"mongodb"`
+	} `yaml:"database"`
+	Backup backup.Config `yaml:"backup"`
+}
+
+// 导出游戏数据到对象存储，或从备份恢复，使用服务器配置文件中的database.mongodb和backup
+//
+//	go run ./tools/backup -config config/config.yaml
+//	go run ./tools/backup -collections users,ledger,mails
+//	go run ./tools/backup -restore 20240101T040000Z -collections users
+//	go run ./tools/backup -restore 20240101T040000Z -collections users -confirm -prune
+//
+// 恢复默认只输出差异，带-confirm时才写入
+func main() {
+	configFile := flag.String("config", "config/config.yaml", "服务器配置文件")
+	collections := flag.String("collections", "", "集合，逗号分隔，备份时默认按配置，恢复时默认为备份中的所有集合")
+	restore := flag.String("restore", "", "从该ID的备份恢复，不指定时执行备份")
+	manifest := flag.Bool("manifest", false, "只输出-restore指定的备份的清单")
+	confirm := flag.Bool("confirm", false, "恢复时写入数据，否则只输出差异")
+	prune := flag.Bool("prune", false, "恢复时删除备份中没有的文档")
+	flag.Parse()
+
+	logger.InitGlobalLogger(&logger.LogConfig{
+		Level:  "info",
+		Format: "console",
+		Output: "stderr",
+	})
+
+	data, err := os.ReadFile(*configFile)
+	if err != nil {
+		fail(2, "failed to read config: %v", err)
+	}
+	var config fileConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		fail(2, "invalid config: %v", err)
+	}
+
+	mongo, err := database.NewMongoManager(&config.Database.MongoDB)
+	if err != nil {
+		fail(1, "%v", err)
+	}
+	defer mongo.Close()
+
+	service, err := backup.NewService(&config.Backup, mongo)
+	if err != nil {
+		fail(2, "%v", err)
+	}
+
+	var names []string
+	if *collections != "" {
+		names = strings.Split(*collections, ",")
+	}
+
+	ctx := context.Background()
+	switch {
+	case *restore == "":
+		result, err := service.Backup(ctx, names)
+		if err != nil {
+			fail(1, "backup failed: %v", err)
+		}
+		printJSON(result)
+
+	case *manifest:
+		result, err := service.Manifest(*restore)
+		if err != nil {
+			fail(1, "%v", err)
+		}
+		printJSON(result)
+
+	default:
+		diffs, err := service.Restore(ctx, &backup.RestoreOptions{
+			ID:          *restore,
+			Collections: names,
+			DryRun:      !*confirm,
+			Prune:       *prune,
+		})
+		printJSON(diffs)
+		if err != nil {
+			fail(1, "restore failed: %v", err)
+		}
+		if !*confirm {
+			fmt.Fprintln(os.Stderr, "dry run, add -confirm to write the changes")
+		}
+	}
+}
+
+// printJSON 输出结果
+func printJSON(v interface{}) {
+	data, _ := json.MarshalIndent(v, "", "  ")
+	fmt.Println(string(data))
+}
+
+// fail 输出错误并退出
+func fail(code int, format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(code)
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 比较的指标
+var metrics = []string{"ns/op", "B/op", "allocs/op"}
+
+// procsSuffix 基准测试名称中的GOMAXPROCS后缀
+var procsSuffix = regexp.MustCompile(`-\d+$`)
+
+// benchStats 单个基准测试多次运行的累计值
+type benchStats struct {
+	sums   map[string]float64
+	counts map[string]int
+}
+
+// mean 获取指标平均值
+func (bs *benchStats) mean(metric string) (float64, bool) {
+	if bs == nil || bs.counts[metric] == 0 {
+		return 0, false
+	}
+	return bs.sums[metric] / float64(bs.counts[metric]), true
+}
+
+// 比较两次基准测试结果，输入为go test -bench的输出，不同包的同名基准测试分别比较
+//
+//	go run ./tools/benchdiff -threshold 10 old.txt new.txt
+//
+// 任一基准测试的ns/op或allocs/op增长超过阈值时以状态码1退出，可用于PR检查
+func main() {
+	threshold := flag.Float64("threshold", 10, "判定为性能回退的增长百分比")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: benchdiff [-threshold percent] old.txt new.txt\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	oldResults, err := parseFile(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	newResults, err := parseFile(flag.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	names := make([]string, 0, len(newResults))
+	for name := range newResults {
+		names = append(names, name)
+	}
+	for name := range oldResults {
+		if _, exists := newResults[name]; !exists {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	regressions := 0
+	fmt.Printf("%-45s %-10s %14s %14s %9s\n", "benchmark", "metric", "old", "new", "delta")
+	for _, name := range names {
+		for _, metric := range metrics {
+			oldValue, oldOK := oldResults[name].mean(metric)
+			newValue, newOK := newResults[name].mean(metric)
+			if !oldOK && !newOK {
+				continue
+			}
+
+			var delta, mark string
+			switch {
+			case !oldOK:
+				delta = "new"
+			case !newOK:
+				delta = "removed"
+			case oldValue == 0 && newValue == 0:
+				delta = "0.00%"
+			case oldValue == 0:
+				delta = "+inf"
+				if metric != "B/op" {
+					mark = " REGRESSION"
+					regressions++
+				}
+			default:
+				change := (newValue - oldValue) / oldValue * 100
+				delta = fmt.Sprintf("%+.2f%%", change)
+				if change > *threshold && metric != "B/op" {
+					mark = " REGRESSION"
+					regressions++
+				}
+			}
+
+			fmt.Printf("%-45s %-10s %14s %14s %9s%s\n", name, metric,
+				formatValue(oldValue, oldOK), formatValue(newValue, newOK), delta, mark)
+		}
+	}
+
+	if regressions > 0 {
+		fmt.Printf("\n%d regression(s) over %.1f%%\n", regressions, *threshold)
+		os.Exit(1)
+	}
+}
+
+// parseFile 解析基准测试输出，同名基准测试的多次结果取平均
+func parseFile(path string) (map[string]*benchStats, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	results := make(map[string]*benchStats)
+	scanner := bufio.NewScanner(file)
+	pkg := ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "pkg: ") {
+			pkg = strings.TrimSpace(strings.TrimPrefix(line, "pkg: "))
+			pkg = pkg[strings.LastIndex(pkg, "/")+1:]
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+		if _, err := strconv.ParseInt(fields[1], 10, 64); err != nil {
+			continue
+		}
+
+		name := procsSuffix.ReplaceAllString(strings.TrimPrefix(fields[0], "Benchmark"), "")
+		if pkg != "" {
+			name = pkg + "." + name
+		}
+		stats, exists := results[name]
+		if !exists {
+			stats = &benchStats{
+				sums:   make(map[string]float64),
+				counts: make(map[string]int),
+			}
+			results[name] = stats
+		}
+
+		// 迭代次数之后为“值 单位”对
+		for i := 2; i+1 < len(fields); i += 2 {
+			value, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				continue
+			}
+			stats.sums[fields[i+1]] += value
+			stats.counts[fields[i+1]]++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	return results, nil
+}
+
+// formatValue 格式化指标值
+func formatValue(value float64, ok bool) string {
+	if !ok {
+		return "-"
+	}
+	return strconv.FormatFloat(value, 'f', 2, 64)
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// serviceDirective 标记需要生成代码的服务接口，写在接口的文档注释中
+//
+//	// LobbyServiceAPI 大厅服务接口
+//	//
+//	//rpcgen:service LobbyService
+//	type LobbyServiceAPI interface { ... }
+const serviceDirective = "//rpcgen:service "
+
+// rpcImportPath RPC包路径
+const rpcImportPath = "github.com/phuhao00/lufy/internal/rpc"
+
+// serviceDef 服务定义
+type serviceDef struct {
+	Name      string // RPC服务名
+	Interface string // Go接口名
+	Methods   []methodDef
+}
+
+// methodDef 方法定义
+type methodDef struct {
+	Name     string
+	Request  string // 请求类型，如*proto.BaseRequest
+	Response string // 响应类型，如*proto.BaseResponse
+}
+
+// 根据服务接口生成注册函数和类型化客户端，通常由go:generate调用
+//
+//	//go:generate go run ../../tools/rpcgen -output rpc_services_gen.go
+//
+// 未指定-input时读取go:generate所在的文件($GOFILE)
+func main() {
+	input := flag.String("input", os.Getenv("GOFILE"), "包含服务接口的Go文件")
+	output := flag.String("output", "", "生成的Go文件，默认为<input>_gen.go")
+	flag.Parse()
+
+	if *input == "" {
+		fmt.Fprintln(os.Stderr, "usage: rpcgen -input services.go [-output services_gen.go]")
+		os.Exit(2)
+	}
+	if *output == "" {
+		*output = strings.TrimSuffix(*input, ".go") + "_gen.go"
+	}
+
+	source, err := generate(*input)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(*output, source, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write %s: %v\n", *output, err)
+		os.Exit(1)
+	}
+}
+
+// generate 解析输入文件中的服务接口并生成代码
+func generate(input string) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, input, nil, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", input, err)
+	}
+
+	imports := make(map[string]string) // 包名 -> 导入路径
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		name := filepath.Base(path)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = path
+	}
+
+	var services []serviceDef
+	used := make(map[string]bool) // 消息类型引用的包名
+
+	for _, decl := range file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			iface, ok := typeSpec.Type.(*ast.InterfaceType)
+			if !ok {
+				continue
+			}
+
+			doc := typeSpec.Doc
+			if doc == nil {
+				doc = genDecl.Doc
+			}
+			serviceName := directiveName(doc)
+			if serviceName == "" {
+				continue
+			}
+
+			service, err := parseService(fset, serviceName, typeSpec.Name.Name, iface, used)
+			if err != nil {
+				return nil, err
+			}
+			services = append(services, service)
+		}
+	}
+
+	if len(services) == 0 {
+		return nil, fmt.Errorf("no interface marked with %s in %s", strings.TrimSpace(serviceDirective), input)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by rpcgen from %s. DO NOT EDIT.\n\n", filepath.Base(input))
+	fmt.Fprintf(&buf, "package %s\n\n", file.Name.Name)
+
+	// 标准库和其他包分组导入
+	paths := []string{rpcImportPath}
+	for name := range used {
+		if path, exists := imports[name]; exists && path != "context" && path != "time" {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	buf.WriteString("import (\n\t\"context\"\n\t\"time\"\n\n")
+	for _, path := range paths {
+		fmt.Fprintf(&buf, "\t%q\n", path)
+	}
+	buf.WriteString(")\n")
+
+	for _, service := range services {
+		writeService(&buf, service)
+	}
+
+	source, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated code: %v", err)
+	}
+	return source, nil
+}
+
+// directiveName 从文档注释中读取服务名
+func directiveName(doc *ast.CommentGroup) string {
+	if doc == nil {
+		return ""
+	}
+	for _, comment := range doc.List {
+		if strings.HasPrefix(comment.Text, serviceDirective) {
+			return strings.TrimSpace(strings.TrimPrefix(comment.Text, serviceDirective))
+		}
+	}
+	return ""
+}
+
+// parseService 解析服务接口，方法签名必须为 (context.Context, *Req) (*Resp, error)
+func parseService(fset *token.FileSet, name, ifaceName string, iface *ast.InterfaceType, used map[string]bool) (serviceDef, error) {
+	service := serviceDef{Name: name, Interface: ifaceName}
+
+	for _, field := range iface.Methods.List {
+		funcType, ok := field.Type.(*ast.FuncType)
+		if !ok || len(field.Names) != 1 {
+			return service, fmt.Errorf("%s: %s must only declare methods", fset.Position(field.Pos()), ifaceName)
+		}
+		methodName := field.Names[0].Name
+
+		params := flattenFields(funcType.Params)
+		results := flattenFields(funcType.Results)
+		if len(params) != 2 || types.ExprString(params[0]) != "context.Context" {
+			return service, fmt.Errorf("%s: %s.%s must take (context.Context, *Request)",
+				fset.Position(field.Pos()), ifaceName, methodName)
+		}
+		if len(results) != 2 || types.ExprString(results[1]) != "error" {
+			return service, fmt.Errorf("%s: %s.%s must return (*Response, error)",
+				fset.Position(field.Pos()), ifaceName, methodName)
+		}
+
+		for _, expr := range []ast.Expr{params[1], results[0]} {
+			star, ok := expr.(*ast.StarExpr)
+			if !ok {
+				return service, fmt.Errorf("%s: %s.%s messages must be pointer types",
+					fset.Position(field.Pos()), ifaceName, methodName)
+			}
+			if selector, ok := star.X.(*ast.SelectorExpr); ok {
+				if pkg, ok := selector.X.(*ast.Ident); ok {
+					used[pkg.Name] = true
+				}
+			}
+		}
+
+		service.Methods = append(service.Methods, methodDef{
+			Name:     methodName,
+			Request:  types.ExprString(params[1]),
+			Response: types.ExprString(results[0]),
+		})
+	}
+
+	return service, nil
+}
+
+// flattenFields 展开参数列表，a, b T 视为两个参数
+func flattenFields(fields *ast.FieldList) []ast.Expr {
+	if fields == nil {
+		return nil
+	}
+	var exprs []ast.Expr
+	for _, field := range fields.List {
+		count := len(field.Names)
+		if count == 0 {
+			count = 1
+		}
+		for i := 0; i < count; i++ {
+			exprs = append(exprs, field.Type)
+		}
+	}
+	return exprs
+}
+
+// writeService 生成服务注册函数和客户端
+func writeService(buf *bytes.Buffer, service serviceDef) {
+	clientName := service.Name + "Client"
+
+	fmt.Fprintf(buf, "\n// Register%s 注册%s服务\n", service.Name, service.Name)
+	fmt.Fprintf(buf, "func Register%s(server *rpc.RPCServer, impl %s) error {\n", service.Name, service.Interface)
+	buf.WriteString("\treturn server.RegisterServiceDesc(&rpc.ServiceDesc{\n")
+	fmt.Fprintf(buf, "\t\tName: %q,\n", service.Name)
+	buf.WriteString("\t\tMethods: map[string]*rpc.MethodDesc{\n")
+	for _, method := range service.Methods {
+		fmt.Fprintf(buf, "\t\t\t%q: rpc.NewMethod(impl.%s),\n", method.Name, method.Name)
+	}
+	buf.WriteString("\t\t},\n\t})\n}\n")
+
+	fmt.Fprintf(buf, "\n// %s %s服务客户端\n", clientName, service.Name)
+	fmt.Fprintf(buf, "type %s struct {\n\tclient  *rpc.RPCClient\n\ttimeout time.Duration\n}\n", clientName)
+
+	fmt.Fprintf(buf, "\n// New%s 创建%s服务客户端\n", clientName, service.Name)
+	fmt.Fprintf(buf, "func New%s(client *rpc.RPCClient, timeout time.Duration) *%s {\n", clientName, clientName)
+	fmt.Fprintf(buf, "\treturn &%s{client: client, timeout: timeout}\n}\n", clientName)
+
+	for _, method := range service.Methods {
+		fmt.Fprintf(buf, "\n// %s 调用%s.%s\n", method.Name, service.Name, method.Name)
+		fmt.Fprintf(buf, "func (c *%s) %s(ctx context.Context, req %s) (%s, error) {\n",
+			clientName, method.Name, method.Request, method.Response)
+		fmt.Fprintf(buf, "\tresp := new(%s)\n", strings.TrimPrefix(method.Response, "*"))
+		fmt.Fprintf(buf, "\tif err := c.client.Invoke(ctx, %q, %q, req, resp, c.timeout); err != nil {\n", service.Name, method.Name)
+		buf.WriteString("\t\treturn nil, err\n\t}\n\treturn resp, nil\n}\n")
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/phuhao00/lufy/internal/gameplay"
+	"github.com/phuhao00/lufy/internal/logger"
+	"github.com/phuhao00/lufy/internal/simulation"
+)
+
+// modules 可模拟的玩法模块
+var modules = map[string]func() gameplay.GameplayModule{
+	"card_game": func() gameplay.GameplayModule { return gameplay.NewCardGameModule() },
+}
+
+// 无界面运行玩法模块的确定性模拟，按固定种子生成随机操作或执行脚本，每步检查不变量
+//
+//	go run ./tools/simulate -game card_game -players 4 -steps 5000 -seed 42
+//	go run ./tools/simulate -script actions.json -verify
+//
+// 出现不变量违规或-verify时两次运行结果不一致以状态码1退出
+func main() {
+	game := flag.String("game", "card_game", "玩法类型")
+	players := flag.Int("players", 2, "玩家数")
+	steps := flag.Int("steps", 1000, "随机操作的步数")
+	seed := flag.Int64("seed", 1, "随机种子")
+	runs := flag.Int("runs", 1, "运行次数，第i次使用种子seed+i")
+	invalid := flag.Float64("invalid", 0, "随机操作中不合法载荷的比例")
+	rules := flag.String("rules", "", "房间自定义规则，JSON对象")
+	script := flag.String("script", "", "操作脚本文件，JSON数组，每项为{player_id, type, data}")
+	verify := flag.Bool("verify", false, "每个种子运行两次，检查最终状态一致")
+	flag.Parse()
+
+	newModule, ok := modules[*game]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown game type: %s\n", *game)
+		os.Exit(2)
+	}
+
+	// 模拟过程中只输出错误日志
+	logger.InitGlobalLogger(&logger.LogConfig{
+		Level:  "error",
+		Format: "console",
+		Output: "stderr",
+	})
+
+	config := &simulation.Config{
+		GameType:    *game,
+		Players:     *players,
+		Steps:       *steps,
+		InvalidRate: *invalid,
+	}
+	if *rules != "" {
+		if err := json.Unmarshal([]byte(*rules), &config.Rules); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid rules: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if *script != "" {
+		data, err := os.ReadFile(*script)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to read script: %v\n", err)
+			os.Exit(2)
+		}
+		if err := json.Unmarshal(data, &config.Script); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid script: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	failed := false
+	for i := 0; i < *runs; i++ {
+		config.Seed = *seed + int64(i)
+		result, err := simulation.Run(newModule(), config)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "simulation failed: %v\n", err)
+			os.Exit(1)
+		}
+		if *verify {
+			again, err := simulation.Run(newModule(), config)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "simulation failed: %v\n", err)
+				os.Exit(1)
+			}
+			if again.Fingerprint != result.Fingerprint {
+				fmt.Printf("seed %d: nondeterministic result, fingerprint %s != %s\n", config.Seed, result.Fingerprint, again.Fingerprint)
+				failed = true
+			}
+		}
+
+		fmt.Printf("seed %d: %d steps, %d accepted, %d rejected, %d violations, fingerprint %s\n",
+			result.Seed, result.Steps, result.Accepted, result.Rejected, len(result.Violations)+result.Dropped, result.Fingerprint)
+		for _, v := range result.Violations {
+			fmt.Printf("  step %d %s: %s (player %d %s %v)\n", v.Step, v.Invariant, v.Error, v.Action.PlayerID, v.Action.Type, v.Action.Data)
+		}
+		if result.Failed() {
+			failed = true
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}